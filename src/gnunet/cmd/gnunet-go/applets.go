@@ -0,0 +1,89 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//----------------------------------------------------------------------
+// Multi-call binary: if built with the "multicall" tag, gnunet-go
+// includes the other commands (services and tools) as applets. An applet
+// is selected by the program name (symlink to gnunet-go) or by the first
+// argument. Groups of applets are excluded by tags ("nodht", "nogns",
+// "nopeerstore", "norest", "notools").
+//----------------------------------------------------------------------
+
+// applet is a command included in the multi-call binary.
+type applet struct {
+	descr string              // short description
+	run   func(args []string) // entry point of command
+}
+
+// applets included in the build (by name)
+var appletList = make(map[string]*applet)
+
+// addApplet registers a command as applet.
+func addApplet(name, descr string, run func(args []string)) {
+	appletList[name] = &applet{descr: descr, run: run}
+}
+
+// findApplet returns the applet and its arguments for a command line
+// (nil if no applet is selected).
+func findApplet(args []string) (*applet, []string) {
+	if a, ok := appletList[filepath.Base(args[0])]; ok {
+		return a, args[1:]
+	}
+	if len(args) > 1 {
+		if a, ok := appletList[args[1]]; ok {
+			return a, args[2:]
+		}
+	}
+	return nil, nil
+}
+
+// applets lists the applets included in the binary; returns the exit
+// code. Packaging scripts create symlinks for the listed names.
+func applets(args []string) int {
+	fs := flag.NewFlagSet("applets", flag.ExitOnError)
+	names := fs.Bool("names", false, "list names only")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	var list []string
+	for name := range appletList {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	for _, name := range list {
+		if *names {
+			fmt.Println(name)
+			continue
+		}
+		fmt.Printf("%-30s %s\n", name, appletList[name].descr)
+	}
+	if len(list) == 0 && !*names {
+		fmt.Fprintln(os.Stderr, "no applets (not a multi-call build)")
+	}
+	return 0
+}
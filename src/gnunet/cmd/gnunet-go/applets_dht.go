@@ -0,0 +1,34 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build multicall && !nodht
+
+package main
+
+import (
+	"gnunet/cmd/internal/benchdht"
+	"gnunet/cmd/internal/dhtcli"
+	"gnunet/cmd/internal/dhtsrv"
+)
+
+// applets of group "dht" (excluded by tag "nodht")
+func init() {
+	addApplet("gnunet-bench-dht", "DHT benchmark", benchdht.Main)
+	addApplet("gnunet-dht-go", "DHT client", dhtcli.Main)
+	addApplet("gnunet-service-dht-go", "DHT service", dhtsrv.Main)
+}
@@ -0,0 +1,46 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build multicall && !nogns
+
+package main
+
+import (
+	"gnunet/cmd/internal/dns2gns"
+	"gnunet/cmd/internal/gnscli"
+	"gnunet/cmd/internal/gnssrv"
+	"gnunet/cmd/internal/identitysrv"
+	"gnunet/cmd/internal/namecachesrv"
+	"gnunet/cmd/internal/revocationsrv"
+	"gnunet/cmd/internal/revokezonekey"
+	"gnunet/cmd/internal/signzone"
+	"gnunet/cmd/internal/zonemastersrv"
+)
+
+// applets of group "gns" (excluded by tag "nogns")
+func init() {
+	addApplet("dns2gns", "DNS-to-GNS gateway", dns2gns.Main)
+	addApplet("gnunet-gns-go", "GNS client", gnscli.Main)
+	addApplet("gnunet-service-gns-go", "GNS service", gnssrv.Main)
+	addApplet("gnunet-service-identity-go", "IDENTITY service", identitysrv.Main)
+	addApplet("gnunet-service-namecache-go", "NAMECACHE service", namecachesrv.Main)
+	addApplet("gnunet-service-revocation-go", "REVOCATION service", revocationsrv.Main)
+	addApplet("revoke-zonekey", "zone key revocation", revokezonekey.Main)
+	addApplet("sign-zone", "offline zone signing", signzone.Main)
+	addApplet("zonemaster-go", "zonemaster service", zonemastersrv.Main)
+}
@@ -0,0 +1,32 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build multicall && !notools
+
+package main
+
+import (
+	"gnunet/cmd/internal/peermockup"
+	"gnunet/cmd/internal/vanityid"
+)
+
+// applets of group "tools" (excluded by tag "notools")
+func init() {
+	addApplet("peer_mockup", "peer mockup for tests", peermockup.Main)
+	addApplet("vanityid", "vanity peer ID generator", vanityid.Main)
+}
@@ -0,0 +1,90 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gnunet/service"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "config": Show the configuration a running service uses or
+// the differences to its configuration file.
+//----------------------------------------------------------------------
+
+// showConfig prints the running configuration of a service; returns the
+// exit code.
+func showConfig(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		diff     bool
+	)
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.BoolVar(&diff, "diff", false, "show differences to the configuration file")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if diff {
+		reply := new(service.ConfigDiffResponse)
+		if err = rpcCall(endpoint, "Config.Diff", new(service.ConfigRequest), reply); err != nil {
+			fmt.Fprintf(os.Stderr, "can't compare configuration: %s\n", err.Error())
+			return 1
+		}
+		list := make([]string, len(reply.Diffs))
+		for i, d := range reply.Diffs {
+			list[i] = "  " + d.String() + "\n"
+		}
+		err = out.Emit(reply, "%s: %d difference(s)\n%s", reply.File, len(reply.Diffs), strings.Join(list, ""))
+	} else {
+		reply := new(service.ConfigEffectiveResponse)
+		if err = rpcCall(endpoint, "Config.Effective", new(service.ConfigRequest), reply); err != nil {
+			fmt.Fprintf(os.Stderr, "can't get configuration: %s\n", err.Error())
+			return 1
+		}
+		var buf []byte
+		if buf, err = json.MarshalIndent(reply.Config, "", "    "); err == nil {
+			err = out.Emit(reply, "%s\n", buf)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
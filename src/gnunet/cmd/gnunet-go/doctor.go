@@ -0,0 +1,567 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/transport"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Command "doctor": Check the environment of a node (configuration,
+// sockets, ports, clock, crypto and bootstrap peers) and print a
+// diagnosis. Nothing is changed by the checks.
+//----------------------------------------------------------------------
+
+// Status of a check
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Check is the result of a single diagnostic check.
+type Check struct {
+	Topic  string `json:"topic"`  // checked item
+	Status string `json:"status"` // result status
+	Detail string `json:"detail"` // explanation
+}
+
+// Report is the diagnosis of all checks.
+type Report struct {
+	Checks   []*Check `json:"checks"`   // results of checks
+	Failures int      `json:"failures"` // number of failed checks
+	Warnings int      `json:"warnings"` // number of warnings
+}
+
+// add a check result to the report
+func (r *Report) add(topic, status, format string, args ...any) {
+	r.Checks = append(r.Checks, &Check{
+		Topic:  topic,
+		Status: status,
+		Detail: fmt.Sprintf(format, args...),
+	})
+	switch status {
+	case StatusFail:
+		r.Failures++
+	case StatusWarn:
+		r.Warnings++
+	}
+}
+
+// doctor runs all checks; returns the exit code (1 if a check failed).
+func doctor(args []string) int {
+	var (
+		cfgFile  string
+		format   string
+		deadline time.Duration
+	)
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.DurationVar(&deadline, "timeout", 5*time.Second, "timeout for connectivity checks")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.SetLogLevel(logger.WARN)
+
+	// run checks: checks depending on the configuration are skipped if
+	// it can't be parsed.
+	rpt := new(Report)
+	cfgOK := checkConfig(rpt, cfgFile)
+	checkClock(rpt, cfgFile)
+	checkCrypto(rpt)
+	if cfgOK {
+		checkNodeKey(rpt)
+		checkSockets(rpt)
+		checkPorts(rpt)
+		checkBootstrap(rpt, deadline)
+	}
+
+	// print diagnosis
+	if out.IsJSON() {
+		err = out.Emit(rpt, "")
+	} else {
+		for _, c := range rpt.Checks {
+			if err = out.Emit(nil, "[%-4s] %-24s %s\n", strings.ToUpper(c.Status), c.Topic, c.Detail); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			diag := "no problems found"
+			if rpt.Failures > 0 {
+				diag = "the node will not work properly"
+			} else if rpt.Warnings > 0 {
+				diag = "the node should work, but check the warnings"
+			}
+			err = out.Emit(nil, "\nDiagnosis: %d failure(s), %d warning(s) -- %s.\n", rpt.Failures, rpt.Warnings, diag)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if rpt.Failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+//----------------------------------------------------------------------
+// Configuration
+//----------------------------------------------------------------------
+
+// checkConfig parses the configuration file and checks for missing
+// sections. Returns false if the configuration is unusable.
+func checkConfig(rpt *Report, cfgFile string) bool {
+	if err := config.ParseConfig(cfgFile); err != nil {
+		rpt.add("config", StatusFail, "can't parse '%s': %s", cfgFile, err.Error())
+		return false
+	}
+	cfg := config.Cfg
+	if cfg.Local == nil {
+		rpt.add("config", StatusFail, "section 'local' missing")
+		return false
+	}
+	if len(cfg.Local.Endpoints) == 0 {
+		rpt.add("config", StatusFail, "no endpoints defined in section 'local'")
+	}
+	sections := []struct {
+		name    string
+		defined bool
+	}{
+		{"network", cfg.Network != nil},
+		{"dht", cfg.DHT != nil},
+		{"gns", cfg.GNS != nil},
+		{"namecache", cfg.Namecache != nil},
+		{"revocation", cfg.Revocation != nil},
+		{"zonemaster", cfg.ZoneMaster != nil},
+		{"rpc", cfg.RPC != nil},
+	}
+	missing := make([]string, 0)
+	for _, s := range sections {
+		if !s.defined {
+			missing = append(missing, s.name)
+		}
+	}
+	if len(missing) > 0 {
+		rpt.add("config", StatusWarn, "'%s' parsed; missing sections: %s", cfgFile, strings.Join(missing, ", "))
+	} else {
+		rpt.add("config", StatusOK, "'%s' parsed", cfgFile)
+	}
+	return true
+}
+
+// checkNodeKey checks the private key of the node.
+func checkNodeKey(rpt *Report) {
+	peer, err := core.NewLocalPeer(config.Cfg.Local)
+	if err != nil {
+		rpt.add("node key", StatusFail, "invalid private seed: %s", err.Error())
+		return
+	}
+	data := util.NewRndArray(64)
+	sig, err := peer.Sign(data)
+	if err == nil {
+		var ok bool
+		if ok, err = peer.PubKey().EdVerify(data, sig); err == nil && !ok {
+			err = fmt.Errorf("signature mismatch")
+		}
+	}
+	if err != nil {
+		rpt.add("node key", StatusFail, "signing failed: %s", err.Error())
+		return
+	}
+	rpt.add("node key", StatusOK, "peer id %s", peer.GetIDString())
+}
+
+//----------------------------------------------------------------------
+// Clock
+//----------------------------------------------------------------------
+
+// earliest plausible system time
+var minTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// checkClock checks the system clock for plausibility: peers reject
+// HELLOs and blocks from nodes with skewed clocks.
+func checkClock(rpt *Report, cfgFile string) {
+	now := time.Now()
+	if now.Before(minTime) {
+		rpt.add("clock", StatusFail, "system time %s is in the past", now.Format(time.RFC3339))
+		return
+	}
+	// GNUnet time (microseconds since epoch) must match system time
+	at := util.AbsoluteTimeNow()
+	if diff := time.UnixMicro(int64(at.Val)).Sub(now); diff > time.Second || diff < -time.Second {
+		rpt.add("clock", StatusFail, "GNUnet time differs from system time by %s", diff)
+		return
+	}
+	// files modified "in the future" hint at a clock that was set back
+	if fi, err := os.Stat(cfgFile); err == nil && fi.ModTime().After(now.Add(time.Minute)) {
+		rpt.add("clock", StatusWarn, "configuration file modified in the future (%s): clock set back?",
+			fi.ModTime().Format(time.RFC3339))
+		return
+	}
+	rpt.add("clock", StatusOK, "system time %s", now.Format(time.RFC3339))
+}
+
+//----------------------------------------------------------------------
+// Crypto self-tests
+//----------------------------------------------------------------------
+
+// known-answer tests for zone keys (LSD0001 test vectors): private key,
+// zone key and derived zone key for label "testdelegation".
+var zoneKATs = []struct {
+	ztype enums.GNSType
+	zprv  string
+	zkey  string
+	dzkey string
+}{
+	{
+		enums.GNS_TYPE_PKEY,
+		"50d7b652a4efeadff37396909785e5952171a02178c8e7d450fa907925fafd98",
+		"00010000677c477d2d93097c85b195c6f96d84ff61f5982c2c4fe02d5a11fedfb0c2901f",
+		"182bb636eda79f795711bc2708adbb242a60446ad3c30803121d03d348b7ceb6",
+	},
+	{
+		enums.GNS_TYPE_EDKEY,
+		"5af7020ee19160328832352bbc6a68a8d71a7cbe1b929969a7c66d415a0d8f65",
+		"000100143cf4b924032022f0dc50581453b85d93b047b63d446c5845cb48445ddb96688f",
+		"9bf233198c6d53bbdbac495cabd91049a684af3f4051bacab0dcf21c8cf27a1a",
+	},
+}
+
+// checkCrypto runs known-answer tests for zone keys and checks signing
+// and encryption of GNS blocks.
+func checkCrypto(rpt *Report) {
+	for _, kat := range zoneKATs {
+		topic := "crypto " + kat.ztype.String()
+		if err := checkZoneKAT(kat.ztype, kat.zprv, kat.zkey, kat.dzkey); err != nil {
+			rpt.add(topic, StatusFail, "known-answer test failed: %s", err.Error())
+			continue
+		}
+		if err := checkBlockRoundtrip(kat.ztype); err != nil {
+			rpt.add(topic, StatusFail, "block test failed: %s", err.Error())
+			continue
+		}
+		rpt.add(topic, StatusOK, "key derivation, signatures and encryption work")
+	}
+}
+
+// checkZoneKAT checks key generation and derivation against test vectors.
+func checkZoneKAT(ztype enums.GNSType, zprv, zkey, dzkey string) error {
+	d, _ := hex.DecodeString(zprv)
+	zp, err := crypto.NewZonePrivate(ztype, d)
+	if err != nil {
+		return err
+	}
+	zk := zp.Public()
+	if hex.EncodeToString(zk.Bytes()) != zkey {
+		return fmt.Errorf("zone key mismatch")
+	}
+	dzk, _, err := zk.Derive("testdelegation", blocks.GNSContext)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(dzk.KeyData) != dzkey {
+		return fmt.Errorf("derived zone key mismatch")
+	}
+	return nil
+}
+
+// checkBlockRoundtrip signs and encrypts a record set with a random zone
+// key and checks that the block verifies and decrypts.
+func checkBlockRoundtrip(ztype enums.GNSType) error {
+	zp, err := crypto.NewZonePrivate(ztype, util.NewRndArray(32))
+	if err != nil {
+		return err
+	}
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	data := util.WriteCString("doctor")
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   uint16(len(data)),
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   data,
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "test", rs, expire)
+	if err != nil {
+		return err
+	}
+	if blk, err = blocks.NewGNSBlockFromRRBLOCK(blk.RRBLOCK()); err != nil {
+		return err
+	}
+	dec, err := blk.Records(zp.Public(), "test")
+	if err != nil {
+		return err
+	}
+	if dec.Count != 1 || !bytes.Equal(dec.Records[0].Data, data) {
+		return fmt.Errorf("record mismatch")
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Service sockets
+//----------------------------------------------------------------------
+
+// checkSockets checks that the sockets of all configured services can be
+// created (or are used by running services).
+func checkSockets(rpt *Report) {
+	cfg := config.Cfg
+	srvs := []struct {
+		name string
+		cfg  *config.ServiceConfig
+	}{
+		{"core", nil},
+		{"dht", nil},
+		{"gns", nil},
+		{"namecache", nil},
+		{"revocation", nil},
+		{"zonemaster", nil},
+		{"peerstore", nil},
+	}
+	if cfg.Core != nil {
+		srvs[0].cfg = cfg.Core.Service
+	}
+	if cfg.DHT != nil {
+		srvs[1].cfg = cfg.DHT.Service
+	}
+	if cfg.GNS != nil {
+		srvs[2].cfg = cfg.GNS.Service
+	}
+	if cfg.Namecache != nil {
+		srvs[3].cfg = cfg.Namecache.Service
+	}
+	if cfg.Revocation != nil {
+		srvs[4].cfg = cfg.Revocation.Service
+	}
+	if cfg.ZoneMaster != nil {
+		srvs[5].cfg = cfg.ZoneMaster.Service
+	}
+	if cfg.Peerstore != nil {
+		srvs[6].cfg = cfg.Peerstore.Service
+	}
+	for _, srv := range srvs {
+		topic := "socket " + srv.name
+		if srv.cfg == nil || len(srv.cfg.Socket) == 0 {
+			rpt.add(topic, StatusSkip, "no socket configured")
+			continue
+		}
+		status, detail := checkSocket(srv.cfg.Socket)
+		rpt.add(topic, status, "%s", detail)
+	}
+}
+
+// checkSocket checks a single socket path.
+func checkSocket(path string) (status, detail string) {
+	// socket file exists: service running or stale file?
+	if _, err := os.Stat(path); err == nil {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err != nil {
+			return StatusFail, fmt.Sprintf("stale socket '%s' (remove it before starting the service)", path)
+		}
+		conn.Close()
+		return StatusOK, fmt.Sprintf("'%s' in use (service running)", path)
+	}
+	// directory must exist and be writable
+	dir := filepath.Dir(path)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return StatusFail, fmt.Sprintf("directory '%s' for socket does not exist", dir)
+	}
+	if !fi.IsDir() {
+		return StatusFail, fmt.Sprintf("'%s' is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return StatusFail, fmt.Sprintf("directory '%s' not writable", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return StatusOK, fmt.Sprintf("'%s' can be created", path)
+}
+
+//----------------------------------------------------------------------
+// Network ports
+//----------------------------------------------------------------------
+
+// checkPorts checks that the ports of all network endpoints (and of the
+// RPC and GUI servers) can be bound.
+func checkPorts(rpt *Report) {
+	for _, ep := range config.Cfg.Local.Endpoints {
+		topic := "endpoint " + ep.ID
+		if strings.HasPrefix(ep.Address, "upnp:") {
+			rpt.add(topic, StatusSkip, "port %d is forwarded by UPnP", ep.Port)
+			continue
+		}
+		proto := protocol(ep.Network)
+		if len(proto) == 0 {
+			rpt.add(topic, StatusFail, "unknown network '%s'", ep.Network)
+			continue
+		}
+		addr := ep.Address
+		if proto != "unix" {
+			addr = net.JoinHostPort(ep.Address, fmt.Sprintf("%d", ep.Port))
+		}
+		status, detail := checkBind(proto, addr)
+		rpt.add(topic, status, "%s", detail)
+	}
+	if rc := config.Cfg.RPC; rc != nil && len(rc.Endpoint) > 0 {
+		status, detail := checkBind("tcp", strings.TrimPrefix(rc.Endpoint, "tcp:"))
+		rpt.add("rpc", status, "%s", detail)
+	}
+	if zc := config.Cfg.ZoneMaster; zc != nil && len(zc.GUI) > 0 {
+		status, detail := checkBind("tcp", zc.GUI)
+		rpt.add("zonemaster gui", status, "%s", detail)
+	}
+}
+
+// checkBind tries to listen on an address.
+func checkBind(proto, addr string) (status, detail string) {
+	var err error
+	switch proto {
+	case "udp":
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket(proto, addr); err == nil {
+			conn.Close()
+		}
+	default:
+		var l net.Listener
+		if l, err = net.Listen(proto, addr); err == nil {
+			l.Close()
+		}
+	}
+	if err != nil {
+		return StatusFail, fmt.Sprintf("can't bind %s/%s: %s (node already running?)", addr, proto, err.Error())
+	}
+	return StatusOK, fmt.Sprintf("%s/%s can be bound", addr, proto)
+}
+
+// protocol returns the transport protocol for an extended network name
+// (like "r5n+ip+udp").
+func protocol(netw string) string {
+	for len(netw) > 0 {
+		if proto := transport.EpProtocol(netw); len(proto) > 0 {
+			return proto
+		}
+		idx := strings.Index(netw, "+")
+		if idx < 0 {
+			break
+		}
+		netw = netw[idx+1:]
+	}
+	return ""
+}
+
+//----------------------------------------------------------------------
+// Bootstrap peers
+//----------------------------------------------------------------------
+
+// checkBootstrap checks the bootstrap entries: HELLO URLs must be valid
+// and bootstrap addresses must be reachable.
+func checkBootstrap(rpt *Report, deadline time.Duration) {
+	nc := config.Cfg.Network
+	if nc == nil || len(nc.Bootstrap) == 0 {
+		if nc != nil && len(nc.BootCache) > 0 {
+			if _, err := os.Stat(nc.BootCache); err == nil {
+				rpt.add("bootstrap", StatusWarn, "no bootstrap peers configured (using cache '%s')", nc.BootCache)
+				return
+			}
+		}
+		rpt.add("bootstrap", StatusFail, "no bootstrap peers configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	for _, bs := range nc.Bootstrap {
+		var addrs []*util.Address
+		if strings.HasPrefix(bs, uri.PrefixHello) {
+			hb, err := blocks.ParseHelloBlockFromURL(bs, true)
+			if err != nil {
+				rpt.add("bootstrap", StatusFail, "invalid HELLO URL: %s", err.Error())
+				continue
+			}
+			if ok, err := hb.Verify(); err != nil || !ok {
+				rpt.add("bootstrap", StatusFail, "HELLO of peer %s has an invalid signature", hb.PeerID.Short())
+				continue
+			}
+			addrs = hb.Addresses()
+		} else {
+			addr, err := util.ParseAddress(bs)
+			if err != nil {
+				rpt.add("bootstrap", StatusFail, "invalid address '%s': %s", bs, err.Error())
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+		for _, addr := range addrs {
+			// don't contact the network in local-only mode
+			if nc.LocalOnly && !transport.IsLocalAddress(addr) {
+				rpt.add("bootstrap", StatusWarn, "%s: non-local address ignored (local-only mode)", addr.URI())
+				continue
+			}
+			status, detail := checkReach(ctx, addr)
+			rpt.add("bootstrap", status, "%s", detail)
+		}
+	}
+}
+
+// checkReach checks if a bootstrap address is reachable: stream addresses
+// are connected, for packet addresses only a route to the peer is checked.
+func checkReach(ctx context.Context, addr *util.Address) (status, detail string) {
+	proto := protocol(addr.Network())
+	if len(proto) == 0 {
+		return StatusSkip, fmt.Sprintf("%s: unknown network", addr.URI())
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, proto, addr.String())
+	if err != nil {
+		return StatusWarn, fmt.Sprintf("%s: unreachable: %s", addr.URI(), err.Error())
+	}
+	conn.Close()
+	if proto == "udp" {
+		return StatusOK, fmt.Sprintf("%s: route available", addr.URI())
+	}
+	return StatusOK, fmt.Sprintf("%s: connected", addr.URI())
+}
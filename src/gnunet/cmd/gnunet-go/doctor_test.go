@@ -0,0 +1,198 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gnunet/config"
+)
+
+// writeConfig writes a configuration file to a temp. directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), "gnunet-config.json")
+	if err := os.WriteFile(fn, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestReport(t *testing.T) {
+	rpt := new(Report)
+	rpt.add("a", StatusOK, "fine")
+	rpt.add("b", StatusWarn, "%d warning", 1)
+	rpt.add("c", StatusFail, "failed")
+	rpt.add("d", StatusSkip, "skipped")
+	rpt.add("e", StatusFail, "failed again")
+	if rpt.Failures != 2 || rpt.Warnings != 1 || len(rpt.Checks) != 5 {
+		t.Fatalf("unexpected report: %d failures, %d warnings, %d checks", rpt.Failures, rpt.Warnings, len(rpt.Checks))
+	}
+	if c := rpt.Checks[1]; c.Topic != "b" || c.Status != StatusWarn || c.Detail != "1 warning" {
+		t.Fatalf("unexpected check %v", c)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	local := `"local": {
+		"name": "test",
+		"privateSeed": "YGoe6XFH3XdvFRl+agx9gIzPTvxA229WFdkazEMdcOs=",
+		"endpoints": [{"id": "udp", "network": "ip+udp", "address": "127.0.0.1", "port": 0}]
+	}`
+	cases := []struct {
+		name    string
+		path    string // existing configuration file
+		content string // content of configuration file (if no path)
+		ok      bool   // configuration usable
+		status  string // status of check
+	}{
+		{"missing file", "missing.json", "", false, StatusFail},
+		{"invalid JSON", "", "{", false, StatusFail},
+		{"no local section", "", "{}", false, StatusFail},
+		{"no endpoints", "", `{"local": {"name": "test"}}`, true, StatusFail},
+		{"missing sections", "", "{" + local + "}", true, StatusWarn},
+		{"complete", "../../config/gnunet-config.json", "", true, StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := tc.path
+			if len(fn) == 0 {
+				fn = writeConfig(t, tc.content)
+			}
+			rpt := new(Report)
+			if ok := checkConfig(rpt, fn); ok != tc.ok {
+				t.Fatalf("usable: got %v, expected %v", ok, tc.ok)
+			}
+			if len(rpt.Checks) == 0 || rpt.Checks[0].Status != tc.status {
+				t.Fatalf("unexpected checks %v", rpt.Checks)
+			}
+		})
+	}
+	// the node key of a usable configuration is checked
+	if !checkConfig(new(Report), writeConfig(t, "{"+local+"}")) {
+		t.Fatal("configuration not usable")
+	}
+	rpt := new(Report)
+	checkNodeKey(rpt)
+	if rpt.Failures != 0 {
+		t.Fatalf("node key check failed: %v", rpt.Checks[0])
+	}
+}
+
+func TestCheckSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	// socket can be created in a writable directory
+	if status, detail := checkSocket(filepath.Join(dir, "free.sock")); status != StatusOK {
+		t.Fatalf("free socket: %s", detail)
+	}
+	// socket used by a running service
+	path := filepath.Join(dir, "running.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if status, detail := checkSocket(path); status != StatusOK {
+		t.Fatalf("running service: %s", detail)
+	}
+	// stale socket file
+	path = filepath.Join(dir, "stale.sock")
+	if err = os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := checkSocket(path); status != StatusFail {
+		t.Fatal("stale socket not detected")
+	}
+	// missing directory or not a directory
+	if status, _ := checkSocket(filepath.Join(dir, "missing", "x.sock")); status != StatusFail {
+		t.Fatal("missing directory not detected")
+	}
+	if status, _ := checkSocket(filepath.Join(path, "x.sock")); status != StatusFail {
+		t.Fatal("file as directory not detected")
+	}
+}
+
+func TestCheckSocketPermission(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced for root")
+	}
+	dir := filepath.Join(t.TempDir(), "ro")
+	if err := os.Mkdir(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := checkSocket(filepath.Join(dir, "x.sock")); status != StatusFail {
+		t.Fatal("read-only directory not detected")
+	}
+}
+
+func TestCheckSockets(t *testing.T) {
+	dir := t.TempDir()
+	config.Cfg = &config.Config{
+		DHT: &config.DHTConfig{
+			Service: &config.ServiceConfig{Socket: filepath.Join(dir, "dht.sock")},
+		},
+		GNS: &config.GNSConfig{
+			Service: &config.ServiceConfig{Socket: filepath.Join(dir, "missing", "gns.sock")},
+		},
+	}
+	rpt := new(Report)
+	checkSockets(rpt)
+	status := make(map[string]string)
+	for _, c := range rpt.Checks {
+		status[c.Topic] = c.Status
+	}
+	if status["socket dht"] != StatusOK || status["socket gns"] != StatusFail || status["socket core"] != StatusSkip {
+		t.Fatalf("unexpected checks %v", status)
+	}
+	if rpt.Failures != 1 || rpt.Warnings != 0 {
+		t.Fatalf("%d failures, %d warnings", rpt.Failures, rpt.Warnings)
+	}
+}
+
+func TestCheckBind(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if status, _ := checkBind("tcp", l.Addr().String()); status != StatusFail {
+		t.Fatal("used port not detected")
+	}
+	if status, detail := checkBind("udp", "127.0.0.1:0"); status != StatusOK {
+		t.Fatalf("free port: %s", detail)
+	}
+	if proto := protocol("r5n+ip+udp"); proto != "udp" {
+		t.Fatalf("protocol '%s'", proto)
+	}
+	if proto := protocol("bogus"); len(proto) != 0 {
+		t.Fatalf("protocol '%s' for unknown network", proto)
+	}
+}
+
+func TestDoctorExitCode(t *testing.T) {
+	// an unusable configuration fails the diagnosis
+	fn := filepath.Join(t.TempDir(), "missing.json")
+	if rc := doctor([]string{"-c", fn, "-output", "json"}); rc != 1 {
+		t.Fatalf("exit code %d for missing configuration", rc)
+	}
+}
@@ -0,0 +1,96 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	coreSrv "gnunet/service/core"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "endpoints": Show the state of the transport endpoints of a
+// running node (address, traffic counters, errors and restarts).
+//----------------------------------------------------------------------
+
+// endpoints lists the state of the transport endpoints; returns the exit
+// code.
+func endpoints(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+	)
+	fs := flag.NewFlagSet("endpoints", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of node (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	reply := new(coreSrv.EndpointsResponse)
+	if err = rpcCall(endpoint, "Core.Endpoints", &coreSrv.EndpointsRequest{}, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "can't get endpoints: %s\n", err.Error())
+		return 1
+	}
+	if out.IsJSON() {
+		err = out.Emit(reply, "")
+	} else {
+		for _, ep := range reply.Endpoints {
+			state := "down"
+			if ep.Listening {
+				state = "up"
+			}
+			if err = out.Emit(nil, "%-12s %-4s %s\n", ep.ID, state, ep.Address); err != nil {
+				break
+			}
+			if len(ep.External) > 0 {
+				if err = out.Emit(nil, "    external: %s\n", ep.External); err != nil {
+					break
+				}
+			}
+			if err = out.Emit(nil, "    in:  %d msgs, %d bytes\n    out: %d msgs, %d bytes\n    restarts: %d\n",
+				ep.MsgsIn, ep.BytesIn, ep.MsgsOut, ep.BytesOut, ep.Restarts); err != nil {
+				break
+			}
+			if len(ep.LastError) > 0 {
+				if err = out.Emit(nil, "    last error: %s (%s)\n", ep.LastError, ep.ErrorTime); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
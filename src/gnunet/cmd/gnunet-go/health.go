@@ -0,0 +1,171 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gnunet/service"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "health": Show internal health details of a running service
+// (alerts, goroutines, queues, result handlers, client sessions and
+// caches) and switch its profiling endpoint on or off.
+//----------------------------------------------------------------------
+
+// health prints the health details of a service; returns the exit code.
+func health(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		modules  string
+		stacks   bool
+		pprof    string
+	)
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.StringVar(&modules, "m", "", "comma-separated list of modules (default: all)")
+	fs.BoolVar(&stacks, "stacks", false, "include stack traces of all goroutines")
+	fs.StringVar(&pprof, "pprof", "", "switch profiling endpoint 'on' or 'off'")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// switch profiling
+	if len(pprof) > 0 {
+		if pprof != "on" && pprof != "off" {
+			fmt.Fprintf(os.Stderr, "invalid profiling switch '%s'\n", pprof)
+			return 1
+		}
+		req := &service.ProfilingRequest{Enable: pprof == "on"}
+		reply := new(service.ProfilingResponse)
+		if err = rpcCall(endpoint, "Health.Profiling", req, reply); err != nil {
+			fmt.Fprintf(os.Stderr, "can't switch profiling: %s\n", err.Error())
+			return 1
+		}
+		if out.IsJSON() {
+			err = out.Emit(reply, "")
+		} else if reply.Active {
+			err = out.Emit(nil, "profiling on http://%s/debug/pprof/\n", reply.Endpoint)
+		} else {
+			err = out.Emit(nil, "profiling off\n")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	// get health details
+	req := &service.HealthDumpRequest{Stacks: stacks}
+	if len(modules) > 0 {
+		req.Modules = strings.Split(modules, ",")
+	}
+	reply := new(service.HealthDumpResponse)
+	if err = rpcCall(endpoint, "Health.Dump", req, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "can't get health details: %s\n", err.Error())
+		return 1
+	}
+	if out.IsJSON() {
+		err = out.Emit(reply, "")
+	} else {
+		err = printHealth(out, reply)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printHealth emits a health report as text.
+func printHealth(out *util.Output, r *service.HealthDumpResponse) (err error) {
+	emit := func(format string, args ...any) {
+		if err == nil {
+			err = out.Emit(nil, format, args...)
+		}
+	}
+	status := "ok"
+	if r.Degraded {
+		status = "degraded"
+	}
+	emit("status: %s\n", status)
+	for _, a := range r.Alerts {
+		state := "ok"
+		if a.Firing {
+			state = "FIRING since " + a.Since
+		}
+		emit("  alert %-18s %s (value %g): %s\n", a.Name, a.Rule, a.Value, state)
+	}
+	emit("goroutines: %d (profiling: %v)\n", r.Goroutines, r.Profiling)
+	for _, k := range sortedKeys(r.Subsystems) {
+		emit("  %-24s %6d\n", k, r.Subsystems[k])
+	}
+	for _, m := range r.Modules {
+		emit("module %s: handlers=%d, clients=%d, peers=%d\n", m.Module, m.Handlers, m.Clients, m.Peers)
+		for _, k := range sortedKeys(m.Queues) {
+			emit("  queue %-18s %6d\n", k, m.Queues[k])
+		}
+		for _, k := range sortedKeys(m.Caches) {
+			emit("  cache %-18s %6d\n", k, m.Caches[k])
+		}
+		for _, k := range sortedKeys(m.Disconnects) {
+			emit("  disconnect %-13s %6d\n", k, m.Disconnects[k])
+		}
+		if len(m.ClockOffset) > 0 {
+			emit("  clock offset %s\n", m.ClockOffset)
+		}
+	}
+	for _, l := range r.Limits {
+		emit("limits %s: sessions=%d/%d, requests=%d/%d, cache=%d/%d\n", l.Service,
+			l.Sessions.Active, l.Sessions.Max, l.Requests.Active, l.Requests.Max,
+			l.Cache.Active, l.Cache.Max)
+	}
+	if len(r.Stacks) > 0 {
+		emit("\n%s", r.Stacks)
+	}
+	return
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gnunet/service/dht"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "hellos": Export the HELLOs of the neighbors of a running
+// DHT service as a list of HELLO URLs (bootstrap entries for another
+// node).
+//----------------------------------------------------------------------
+
+// hellosBootstrap is the JSON output: a snippet of the "network"
+// section of a configuration file.
+type hellosBootstrap struct {
+	Bootstrap []string `json:"bootstrap"`
+}
+
+// hellos exports neighbor HELLOs as URLs; returns the exit code.
+func hellos(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		all      bool
+		self     bool
+	)
+	fs := flag.NewFlagSet("hellos", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of DHT service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.BoolVar(&all, "all", false, "all validated HELLOs (not only routing table)")
+	fs.BoolVar(&self, "self", false, "include own HELLO")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	reply := new(dht.HellosResponse)
+	if err = rpcCall(endpoint, "DHT.Hellos", &dht.HellosRequest{All: all, Self: self}, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "can't export HELLOs: %s\n", err.Error())
+		return 1
+	}
+	if out.IsJSON() {
+		err = out.Emit(&hellosBootstrap{Bootstrap: reply.Hellos}, "")
+	} else {
+		for _, u := range reply.Hellos {
+			if err = out.Emit(nil, "%s\n", u); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
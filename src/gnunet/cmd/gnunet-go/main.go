@@ -0,0 +1,81 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+//----------------------------------------------------------------------
+// gnunet-go: general node management commands
+//----------------------------------------------------------------------
+
+// commands available (name and handler)
+var commands = map[string]func(args []string) int{
+	"applets":   applets,
+	"config":    showConfig,
+	"doctor":    doctor,
+	"endpoints": endpoints,
+	"health":    health,
+	"hellos":    hellos,
+	"peers":     peers,
+	"services":  services,
+	"talkers":   talkers,
+	"version":   version,
+}
+
+func main() {
+	// run applet in a multi-call binary
+	if a, args := findApplet(os.Args); a != nil {
+		a.run(args)
+		return
+	}
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s <command> [options]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "commands:")
+		fmt.Fprintln(flag.CommandLine.Output(), "  applets   list the commands included in a multi-call build")
+		fmt.Fprintln(flag.CommandLine.Output(), "  config    show the running configuration of a service (or its diff)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  doctor    check the environment of a node and print a diagnosis")
+		fmt.Fprintln(flag.CommandLine.Output(), "  endpoints show the state of the transport endpoints of a node")
+		fmt.Fprintln(flag.CommandLine.Output(), "  health    show internal health details of a running service")
+		fmt.Fprintln(flag.CommandLine.Output(), "  hellos    export HELLO URLs of DHT neighbors (bootstrap list)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  peers     list the peers in the DHT routing table")
+		fmt.Fprintln(flag.CommandLine.Output(), "  services  list, stop, start or restart the services of a node")
+		fmt.Fprintln(flag.CommandLine.Output(), "  talkers   list the connected peers of a node sorted by bandwidth")
+		fmt.Fprintln(flag.CommandLine.Output(), "  version   show version, subsystems and source code link of a node")
+		fmt.Fprintf(flag.CommandLine.Output(), "\nUse '%s <command> -h' for command options.\n", os.Args[0])
+		if len(appletList) > 0 {
+			fmt.Fprintf(flag.CommandLine.Output(), "Use '%s applets' to list the included applets.\n", os.Args[0])
+		}
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(1)
+	}
+	os.Exit(cmd(flag.Args()[1:]))
+}
@@ -0,0 +1,92 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gnunet/service/dht"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "peers": List the peers in the routing table of a running
+// DHT service (with their aliases and classified addresses).
+//----------------------------------------------------------------------
+
+// peers lists the peers in the DHT routing table; returns the exit code.
+func peers(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+	)
+	fs := flag.NewFlagSet("peers", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of DHT service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	reply, err := listPeers(endpoint)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't list peers: %s\n", err.Error())
+		return 1
+	}
+	if out.IsJSON() {
+		err = out.Emit(reply, "")
+	} else {
+		for _, p := range reply.Peers {
+			if err = out.Emit(nil, "%-24s %s\n", p.Short, p.ID); err != nil {
+				break
+			}
+			for _, a := range p.Addrs {
+				if err = out.Emit(nil, "    %-8s %s\n", a.Class, a.URI); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// listPeers queries the DHT service for the peers in its routing table.
+func listPeers(endpoint string) (reply *dht.PeersResponse, err error) {
+	reply = new(dht.PeersResponse)
+	err = rpcCall(endpoint, "DHT.Peers", &dht.PeersRequest{}, reply)
+	return
+}
@@ -0,0 +1,67 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gnunet/config"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+//----------------------------------------------------------------------
+// JSON-RPC helpers
+//----------------------------------------------------------------------
+
+// rpcEndpoint returns the JSON-RPC endpoint to use: the endpoint given
+// on the command line or the endpoint from the configuration file.
+func rpcEndpoint(cfgFile, endpoint string) (string, error) {
+	if len(endpoint) > 0 {
+		return endpoint, nil
+	}
+	if err := config.ParseConfig(cfgFile); err != nil {
+		return "", fmt.Errorf("invalid configuration: %s", err.Error())
+	}
+	if config.Cfg.RPC == nil || len(config.Cfg.RPC.Endpoint) == 0 {
+		return "", errors.New("no JSON-RPC endpoint configured")
+	}
+	return config.Cfg.RPC.Endpoint, nil
+}
+
+// rpcCall sends a JSON-RPC request to a service and decodes the reply.
+func rpcCall(endpoint, method string, req, reply any) (err error) {
+	url := "http://" + strings.TrimPrefix(endpoint, "tcp:") + "/"
+	var buf []byte
+	if buf, err = json2.EncodeClientRequest(method, req); err != nil {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	var resp *http.Response
+	if resp, err = client.Post(url, "application/json", bytes.NewReader(buf)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	return json2.DecodeClientResponse(resp.Body, reply)
+}
@@ -0,0 +1,123 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gnunet/service"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "services": List the services of a running node and stop,
+// start or restart individual services.
+//----------------------------------------------------------------------
+
+// services lists or controls the services of a node; returns the exit code.
+func services(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		start    string
+		stop     string
+		restart  string
+	)
+	fs := flag.NewFlagSet("services", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of node (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.StringVar(&start, "start", "", "start a stopped service")
+	fs.StringVar(&stop, "stop", "", "stop a running service")
+	fs.StringVar(&restart, "restart", "", "restart a running service")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// control a service
+	var op, method, name string
+	switch {
+	case len(start) > 0:
+		op, method, name = "start", "Services.Start", start
+	case len(stop) > 0:
+		op, method, name = "stop", "Services.Stop", stop
+	case len(restart) > 0:
+		op, method, name = "restart", "Services.Restart", restart
+	}
+	var list []service.UnitStatus
+	if len(method) > 0 {
+		reply := new(service.ServiceResponse)
+		if err = rpcCall(endpoint, method, &service.ServiceRequest{Service: name}, reply); err != nil {
+			fmt.Fprintf(os.Stderr, "can't %s service '%s': %s\n", op, name, err.Error())
+			return 1
+		}
+		list = []service.UnitStatus{reply.Status}
+	} else {
+		// list services
+		reply := new(service.ServicesListResponse)
+		if err = rpcCall(endpoint, "Services.List", &service.ServicesListRequest{}, reply); err != nil {
+			fmt.Fprintf(os.Stderr, "can't list services: %s\n", err.Error())
+			return 1
+		}
+		list = reply.Services
+	}
+	if out.IsJSON() {
+		err = out.Emit(list, "")
+	} else {
+		for _, st := range list {
+			if err != nil {
+				break
+			}
+			err = out.Emit(nil, "%s\n", formatUnit(st))
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// formatUnit returns a line describing the state of a service.
+func formatUnit(st service.UnitStatus) string {
+	state := "stopped"
+	if st.Running {
+		state = "running"
+	}
+	line := fmt.Sprintf("%-12s %-8s since %s (%d starts)", st.Name, state, st.Since, st.Starts)
+	if len(st.Needs) > 0 {
+		line += ", needs " + strings.Join(st.Needs, ",")
+	}
+	if len(st.LastErr) > 0 {
+		line += ", error: " + st.LastErr
+	}
+	return line
+}
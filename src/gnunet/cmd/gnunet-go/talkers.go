@@ -0,0 +1,101 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	coreSrv "gnunet/service/core"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "talkers": Show the connected peers of a running node sorted
+// by bandwidth (with traffic by message type, invalid messages and the
+// time of the last message received).
+//----------------------------------------------------------------------
+
+// talkers lists the top talkers among the connected peers; returns the
+// exit code.
+func talkers(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		limit    int
+		types    bool
+	)
+	fs := flag.NewFlagSet("talkers", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of node (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.IntVar(&limit, "n", 10, "max. number of peers listed (0 = all)")
+	fs.BoolVar(&types, "types", false, "show traffic by message type")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	reply := new(coreSrv.TalkersResponse)
+	if err = rpcCall(endpoint, "Core.Talkers", &coreSrv.TalkersRequest{Limit: limit}, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "can't get top talkers: %s\n", err.Error())
+		return 1
+	}
+	if out.IsJSON() {
+		err = out.Emit(reply, "")
+	} else {
+		for _, p := range reply.Peers {
+			idle := "never"
+			if !p.LastSeen.IsZero() {
+				idle = time.Since(p.LastSeen).Round(time.Second).String() + " ago"
+			}
+			if err = out.Emit(nil, "%-24s %8d B/s  in: %d msgs, %d bytes  out: %d msgs, %d bytes  invalid: %d  violations: %d  last: %s\n",
+				p.Short, p.Bandwidth, p.MsgsIn, p.BytesIn, p.MsgsOut, p.BytesOut, p.Invalid, p.Violations, idle); err != nil {
+				break
+			}
+			if !types {
+				continue
+			}
+			for _, t := range p.Types {
+				if err = out.Emit(nil, "    %-32s in: %d msgs, %d bytes  out: %d msgs, %d bytes  invalid: %d\n",
+					t.Type, t.MsgsIn, t.BytesIn, t.MsgsOut, t.BytesOut, t.Invalid); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gnunet/message"
+	"gnunet/service"
+	coreSrv "gnunet/service/core"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Command "version": Show implementation, version, enabled subsystems
+// and source code link of the local node or of a connected peer; ask a
+// service socket for its source code link (REQUEST_AGPL).
+//----------------------------------------------------------------------
+
+// version prints version information; returns the exit code.
+func version(args []string) int {
+	var (
+		cfgFile  string
+		endpoint string
+		format   string
+		peer     string
+		socket   string
+	)
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of DHT service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.StringVar(&peer, "peer", "", "query a connected peer (default: local node)")
+	fs.StringVar(&socket, "s", "", "ask a service socket for its source code link")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(socket) > 0 {
+		var link string
+		if link, err = agplLink(socket); err != nil {
+			fmt.Fprintf(os.Stderr, "can't get source code link: %s\n", err.Error())
+			return 1
+		}
+		if err = out.Emit(map[string]string{"source": link}, "%s\n", link); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if endpoint, err = rpcEndpoint(cfgFile, endpoint); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	reply := new(coreSrv.VersionResponse)
+	if err = rpcCall(endpoint, "Core.Version", &coreSrv.VersionRequest{Peer: peer}, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "can't get version: %s\n", err.Error())
+		return 1
+	}
+	err = out.Emit(reply, "%s: %s %s\n  subsystems: %s\n  source: %s\n",
+		reply.Peer, reply.Implementation, reply.Version,
+		strings.Join(reply.Subsystems, ", "), reply.Source)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// agplLink asks a service for the link to its source code.
+func agplLink(socket string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, socket)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if err = conn.Send(ctx, message.NewAGPLRequestMsg()); err != nil {
+		return "", err
+	}
+	msg, err := conn.Receive(ctx)
+	if err != nil {
+		return "", err
+	}
+	res, ok := msg.(*message.AGPLResponseMsg)
+	if !ok {
+		return "", fmt.Errorf("unexpected response %s", msg)
+	}
+	return res.Link(), nil
+}
@@ -0,0 +1,125 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package benchdht
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// Main runs the DHT benchmark with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-bench-dht", flag.ExitOnError)
+	// handle command line arguments
+	var (
+		cfgFile string
+		socket  string
+		format  string
+		w       = new(workload)
+	)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "service socket of the DHT service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.IntVar(&w.Keys, "keys", 1000, "number of distinct keys")
+	fs.StringVar(&w.Dist, "dist", DistUniform, "key distribution (uniform, zipf, sequential)")
+	fs.Float64Var(&w.Skew, "skew", 1.1, "exponent of zipf distribution (> 1)")
+	fs.Float64Var(&w.Puts, "puts", 0.5, "share of PUT requests (0..1)")
+	fs.IntVar(&w.Size, "size", 256, "payload size of PUT requests in bytes")
+	fs.Float64Var(&w.Rate, "rate", 0, "requests per second (0 = as fast as possible)")
+	fs.IntVar(&w.Workers, "workers", 4, "number of concurrent clients")
+	fs.DurationVar(&w.Duration, "duration", 30*time.Second, "run time of benchmark")
+	fs.DurationVar(&w.Timeout, "timeout", 10*time.Second, "time limit for a request")
+	fs.BoolVar(&w.Prefill, "prefill", false, "store all keys before the run")
+	fs.BoolVar(&w.Confirm, "confirm", false, "PUTs wait for a confirmation of the stored block")
+	fs.UintVar(&w.BType, "type", uint(enums.BLOCK_TYPE_TEST), "block type")
+	fs.UintVar(&w.Repl, "repl", 0, "replication level (0 = service default)")
+	fs.DurationVar(&w.Expire, "expire", time.Hour, "expiration of stored blocks")
+	fs.Int64Var(&w.Seed, "seed", 0, "seed of random generator (0 = current time)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if w.Seed == 0 {
+		w.Seed = time.Now().UnixNano()
+	}
+	if err = w.check(); err != nil {
+		log.Fatal(err)
+	}
+	// get service socket
+	if len(socket) == 0 {
+		if err = config.ParseConfig(cfgFile); err != nil {
+			log.Fatalf("invalid configuration file: %s", err.Error())
+		}
+		if config.Cfg.DHT == nil || config.Cfg.DHT.Service == nil {
+			log.Fatal("no DHT service socket configured (-s)")
+		}
+		socket = config.Cfg.DHT.Service.Socket
+	}
+
+	// stop benchmark on interrupt (and report the results so far)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	b := newBench(w, socket)
+	var pre *OpStats
+	if w.Prefill {
+		if !out.IsJSON() {
+			fmt.Fprintf(os.Stderr, "storing %d keys...\n", w.Keys)
+		}
+		pre = b.prefill(ctx)
+	}
+	if !out.IsJSON() {
+		fmt.Fprintf(os.Stderr, "running benchmark for %s...\n", w.Duration)
+	}
+	rep := b.run(ctx)
+	rep.Prefill = pre
+
+	// print report
+	text := fmt.Sprintf("workload: %d keys (%s), %.0f%% PUTs of %d bytes, %d workers, rate %s, seed %d\n",
+		w.Keys, w.Dist, 100*w.Puts, w.Size, w.Workers, rate(w.Rate), w.Seed)
+	if pre != nil {
+		text += fmt.Sprintf("prefill: %s\n", pre)
+	}
+	text += fmt.Sprintf("elapsed: %s\nput: %s\nget: %s\n", rep.Elapsed, rep.Put, rep.Get)
+	if err = out.Emit(rep, "%s", text); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// rate returns a human-readable request rate.
+func rate(r float64) string {
+	if r <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%g/s", r)
+}
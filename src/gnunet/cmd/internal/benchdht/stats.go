@@ -0,0 +1,135 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package benchdht
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Outcome of a benchmark request
+const (
+	resOK      = iota // request succeeded
+	resMiss           // GET finished without result
+	resTimeout        // no response within the request timeout
+	resError          // request failed
+)
+
+// recorder collects the outcomes and latencies of requests of one kind.
+type recorder struct {
+	sync.Mutex
+	counts [4]int          // number of requests per outcome
+	lat    []time.Duration // latencies of successful requests
+}
+
+// add the outcome of a request.
+func (r *recorder) add(res int, lat time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.counts[res]++
+	if res == resOK {
+		r.lat = append(r.lat, lat)
+	}
+}
+
+// OpStats are the statistics for requests of one kind.
+type OpStats struct {
+	Requests    int      `json:"requests"`    // number of requests
+	Succeeded   int      `json:"succeeded"`   // number of successful requests
+	Missed      int      `json:"missed"`      // number of GETs without result
+	TimedOut    int      `json:"timedOut"`    // number of timed-out requests
+	Failed      int      `json:"failed"`      // number of failed requests
+	FailureRate float64  `json:"failureRate"` // share of unsuccessful requests
+	Throughput  float64  `json:"throughput"`  // successful requests per second
+	Latency     *Latency `json:"latency"`     // latencies of successful requests
+}
+
+// Latency distribution of successful requests
+type Latency struct {
+	Min  string `json:"min"`
+	Mean string `json:"mean"`
+	P50  string `json:"p50"`
+	P90  string `json:"p90"`
+	P99  string `json:"p99"`
+	Max  string `json:"max"`
+}
+
+// stats returns the statistics of the recorded requests for a benchmark
+// running for the given time.
+func (r *recorder) stats(elapsed time.Duration) *OpStats {
+	r.Lock()
+	defer r.Unlock()
+	st := &OpStats{
+		Succeeded: r.counts[resOK],
+		Missed:    r.counts[resMiss],
+		TimedOut:  r.counts[resTimeout],
+		Failed:    r.counts[resError],
+	}
+	for _, n := range r.counts {
+		st.Requests += n
+	}
+	if st.Requests > 0 {
+		st.FailureRate = float64(st.Requests-st.Succeeded) / float64(st.Requests)
+	}
+	if elapsed > 0 {
+		st.Throughput = float64(st.Succeeded) / elapsed.Seconds()
+	}
+	if n := len(r.lat); n > 0 {
+		sort.Slice(r.lat, func(i, j int) bool { return r.lat[i] < r.lat[j] })
+		var sum time.Duration
+		for _, d := range r.lat {
+			sum += d
+		}
+		st.Latency = &Latency{
+			Min:  r.lat[0].String(),
+			Mean: (sum / time.Duration(n)).String(),
+			P50:  percentile(r.lat, 50).String(),
+			P90:  percentile(r.lat, 90).String(),
+			P99:  percentile(r.lat, 99).String(),
+			Max:  r.lat[n-1].String(),
+		}
+	}
+	return st
+}
+
+// percentile of a sorted list of latencies (nearest rank); 0 for an
+// empty list.
+func percentile(list []time.Duration, p int) time.Duration {
+	if len(list) == 0 {
+		return 0
+	}
+	rank := (p*len(list) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return list[rank-1]
+}
+
+// String returns a human-readable summary of the statistics.
+func (st *OpStats) String() string {
+	s := fmt.Sprintf("%d requests: %d ok, %d missed, %d timed out, %d failed (failure rate %.2f%%), %.1f ops/s",
+		st.Requests, st.Succeeded, st.Missed, st.TimedOut, st.Failed, 100*st.FailureRate, st.Throughput)
+	if l := st.Latency; l != nil {
+		s += fmt.Sprintf("\n    latency: min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s",
+			l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package benchdht
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ten := make([]time.Duration, 10)
+	for i := range ten {
+		ten[i] = time.Duration(i+1) * time.Millisecond
+	}
+	cases := []struct {
+		name string
+		list []time.Duration
+		p    int
+		want time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"single p0", []time.Duration{time.Second}, 0, time.Second},
+		{"single p50", []time.Duration{time.Second}, 50, time.Second},
+		{"single p99", []time.Duration{time.Second}, 99, time.Second},
+		{"ten p0", ten, 0, time.Millisecond},
+		{"ten p50", ten, 50, 5 * time.Millisecond},
+		{"ten p90", ten, 90, 9 * time.Millisecond},
+		{"ten p99", ten, 99, 10 * time.Millisecond},
+		{"ten p100", ten, 100, 10 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := percentile(tc.list, tc.p); got != tc.want {
+				t.Fatalf("got %s, expected %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecorderStats(t *testing.T) {
+	// no requests: no latencies
+	r := new(recorder)
+	if st := r.stats(time.Second); st.Requests != 0 || st.FailureRate != 0 || st.Latency != nil {
+		t.Fatalf("unexpected statistics %v", st)
+	}
+	// single successful request
+	r.add(resOK, 3*time.Millisecond)
+	r.add(resMiss, 0)
+	r.add(resTimeout, 0)
+	r.add(resError, 0)
+	st := r.stats(time.Second)
+	if st.Requests != 4 || st.Succeeded != 1 || st.FailureRate != 0.75 || st.Throughput != 1 {
+		t.Fatalf("unexpected statistics %v", st)
+	}
+	if l := st.Latency; l == nil || l.Min != "3ms" || l.P50 != "3ms" || l.P99 != "3ms" || l.Max != "3ms" {
+		t.Fatalf("unexpected latencies %v", st.Latency)
+	}
+}
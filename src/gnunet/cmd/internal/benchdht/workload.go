@@ -0,0 +1,370 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package benchdht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/util"
+)
+
+// Key distributions
+const (
+	DistUniform    = "uniform"    // all keys are equally likely
+	DistZipf       = "zipf"       // few keys are requested very often
+	DistSequential = "sequential" // keys are requested in turn
+)
+
+// workload describes the requests of a benchmark run.
+type workload struct {
+	Keys     int           `json:"keys"`     // number of distinct keys
+	Dist     string        `json:"dist"`     // key distribution
+	Skew     float64       `json:"skew"`     // exponent of zipf distribution
+	Puts     float64       `json:"puts"`     // share of PUT requests
+	Size     int           `json:"size"`     // payload size of PUTs
+	Rate     float64       `json:"rate"`     // requests per second (0 = unlimited)
+	Workers  int           `json:"workers"`  // number of concurrent clients
+	Duration time.Duration `json:"duration"` // run time of benchmark
+	Timeout  time.Duration `json:"timeout"`  // time limit for a request
+	Prefill  bool          `json:"prefill"`  // store all keys before the run
+	Confirm  bool          `json:"confirm"`  // PUTs wait for a confirmation
+	BType    uint          `json:"type"`     // block type
+	Repl     uint          `json:"repl"`     // replication level
+	Expire   time.Duration `json:"expire"`   // expiration of stored blocks
+	Seed     int64         `json:"seed"`     // seed of random generator
+}
+
+// check the workload parameters.
+func (w *workload) check() error {
+	switch {
+	case w.Keys < 1:
+		return errors.New("number of keys must be positive")
+	case w.Puts < 0 || w.Puts > 1:
+		return errors.New("share of PUTs must be between 0 and 1")
+	case w.Size < 0 || w.Size > 60000:
+		return errors.New("payload size must be between 0 and 60000")
+	case w.Rate < 0:
+		return errors.New("request rate must not be negative")
+	case w.Workers < 1:
+		return errors.New("number of workers must be positive")
+	case w.Duration <= 0 || w.Timeout <= 0 || w.Expire <= 0:
+		return errors.New("durations must be positive")
+	}
+	switch w.Dist {
+	case DistUniform, DistSequential:
+	case DistZipf:
+		if w.Skew <= 1 {
+			return errors.New("zipf exponent must be greater than 1")
+		}
+	default:
+		return fmt.Errorf("unknown key distribution '%s'", w.Dist)
+	}
+	return nil
+}
+
+// keyChooser returns the next key index for a request.
+type keyChooser func() int
+
+// chooser returns a key chooser for the workload (using its own random
+// generator; not safe for concurrent use).
+func (w *workload) chooser(rnd *rand.Rand, seq *uint64) keyChooser {
+	switch w.Dist {
+	case DistZipf:
+		z := rand.NewZipf(rnd, w.Skew, 1, uint64(w.Keys-1))
+		return func() int { return int(z.Uint64()) }
+	case DistSequential:
+		return func() int { return int((atomic.AddUint64(seq, 1) - 1) % uint64(w.Keys)) }
+	}
+	return func() int { return rnd.Intn(w.Keys) }
+}
+
+// benchKey returns the DHT key for a key index: the key string is hashed
+// like in gnunet-dht-go.
+func benchKey(n int) *crypto.HashCode {
+	return crypto.Hash([]byte(fmt.Sprintf("gnunet-bench-dht:%d", n)))
+}
+
+// Report is the result of a benchmark run.
+type Report struct {
+	Workload *workload `json:"workload"`          // benchmark parameters
+	Elapsed  string    `json:"elapsed"`           // actual run time
+	Prefill  *OpStats  `json:"prefill,omitempty"` // storing all keys before the run
+	Put      *OpStats  `json:"put"`               // PUT requests
+	Get      *OpStats  `json:"get"`               // GET requests
+}
+
+// bench runs a workload against the DHT service.
+type bench struct {
+	w      *workload
+	socket string
+	seq    uint64    // key counter (sequential distribution)
+	id     uint64    // request identifiers (GET)
+	put    *recorder // PUT statistics
+	get    *recorder // GET statistics
+}
+
+// newBench creates a benchmark for a workload.
+func newBench(w *workload, socket string) *bench {
+	return &bench{
+		w:      w,
+		socket: socket,
+		put:    new(recorder),
+		get:    new(recorder),
+	}
+}
+
+// prefill stores all keys of the workload (with unlimited rate).
+func (b *bench) prefill(ctx context.Context) *OpStats {
+	rec := new(recorder)
+	keys := make(chan int)
+	go func() {
+		defer close(keys)
+		for n := 0; n < b.w.Keys; n++ {
+			select {
+			case keys <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < b.w.Workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wk := b.worker(i)
+			defer wk.close()
+			for n := range keys {
+				wk.do(ctx, true, n, rec)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return rec.stats(time.Since(start))
+}
+
+// run the workload for its duration.
+func (b *bench) run(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, b.w.Duration)
+	defer cancel()
+
+	// dispatch requests (rate-limited if a rate is set)
+	ticks := make(chan struct{}, b.w.Workers)
+	go func() {
+		defer close(ticks)
+		var tick <-chan time.Time
+		if b.w.Rate > 0 {
+			t := time.NewTicker(time.Duration(float64(time.Second) / b.w.Rate))
+			defer t.Stop()
+			tick = t.C
+		}
+		for {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case ticks <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < b.w.Workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wk := b.worker(i)
+			defer wk.close()
+			for range ticks {
+				isPut := wk.rnd.Float64() < b.w.Puts
+				rec := b.get
+				if isPut {
+					rec = b.put
+				}
+				wk.do(ctx, isPut, wk.next(), rec)
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	return &Report{
+		Workload: b.w,
+		Elapsed:  elapsed.String(),
+		Put:      b.put.stats(elapsed),
+		Get:      b.get.stats(elapsed),
+	}
+}
+
+//----------------------------------------------------------------------
+
+// worker is a client of the DHT service issuing requests in turn.
+type worker struct {
+	b    *bench
+	rnd  *rand.Rand
+	next keyChooser
+	data []byte
+	conn *service.Connection
+}
+
+// worker returns a new worker for the benchmark.
+func (b *bench) worker(i int) *worker {
+	rnd := rand.New(rand.NewSource(b.w.Seed + int64(i)))
+	return &worker{
+		b:    b,
+		rnd:  rnd,
+		next: b.w.chooser(rnd, &b.seq),
+		data: make([]byte, b.w.Size),
+	}
+}
+
+// payload returns the block data for the key with given index. The data
+// depends on the key only, so repeated PUTs store the same block.
+func (wk *worker) payload(key *crypto.HashCode) []byte {
+	for pos := 0; pos < len(wk.data); pos += len(key.Data) {
+		copy(wk.data[pos:], key.Data)
+	}
+	return wk.data
+}
+
+// close the connection of the worker.
+func (wk *worker) close() {
+	if wk.conn != nil {
+		wk.conn.Close()
+		wk.conn = nil
+	}
+}
+
+// do a request and record its outcome. Requests already started when
+// the benchmark ends are completed (within the request timeout).
+func (wk *worker) do(ctx context.Context, isPut bool, n int, rec *recorder) {
+	rctx, cancel := context.WithTimeout(context.Background(), wk.b.w.Timeout)
+	defer cancel()
+	start := time.Now()
+	var (
+		res int
+		err error
+	)
+	if wk.conn == nil {
+		if wk.conn, err = service.NewConnection(rctx, wk.b.socket); err != nil {
+			wk.conn = nil
+			rec.add(resError, 0)
+			// don't hammer an unreachable service
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			return
+		}
+	}
+	if isPut {
+		res, err = wk.put(rctx, n)
+	} else {
+		res, err = wk.get(rctx, n)
+	}
+	if err != nil {
+		res = resError
+		if errors.Is(err, service.ErrConnectionInterrupted) || rctx.Err() != nil {
+			res = resTimeout
+		}
+		// the state of the connection is unknown: start a new one (which
+		// also ends pending requests in the service)
+		wk.close()
+	}
+	rec.add(res, time.Since(start))
+}
+
+// put a block under the key with given index. Without confirmation the
+// request is done when the service accepted it.
+func (wk *worker) put(ctx context.Context, n int) (int, error) {
+	w := wk.b.w
+	key := benchKey(n)
+	msg := message.NewDHTClientPutMsg(key, enums.BlockType(w.BType), wk.payload(key))
+	msg.Options = enums.DHT_RO_RELATIVE_EXPIRATION
+	msg.Expire = util.AbsoluteTime{Val: uint64(w.Expire.Microseconds())}
+	msg.ReplLevel = uint32(w.Repl)
+	if w.Confirm {
+		if err := wk.conn.Send(ctx, message.NewDHTClientPutVerifyMsg(msg.Key)); err != nil {
+			return resError, err
+		}
+	}
+	if err := wk.conn.Send(ctx, msg); err != nil {
+		return resError, err
+	}
+	if !w.Confirm {
+		return resOK, nil
+	}
+	for {
+		in, err := wk.conn.Receive(ctx)
+		if err != nil {
+			return resError, err
+		}
+		if cm, ok := in.(*message.DHTClientPutConfirmMsg); ok && cm.Key.Equal(msg.Key) {
+			if cm.Samples > 0 && cm.Confirmed == 0 {
+				return resMiss, nil
+			}
+			return resOK, nil
+		}
+	}
+}
+
+// get the first exact result for the key with given index.
+func (wk *worker) get(ctx context.Context, n int) (int, error) {
+	w := wk.b.w
+	msg := message.NewDHTClientGetMsg(benchKey(n))
+	msg.BType = enums.BlockType(w.BType)
+	msg.Options = enums.DHT_RO_FIRST_RESULT
+	msg.ReplLevel = uint32(w.Repl)
+	msg.ID = atomic.AddUint64(&wk.b.id, 1)
+	if err := wk.conn.Send(ctx, msg); err != nil {
+		return resError, err
+	}
+	for {
+		in, err := wk.conn.Receive(ctx)
+		if err != nil {
+			return resError, err
+		}
+		switch res := in.(type) {
+		case *message.DHTClientResultMsg:
+			if res.ID == msg.ID {
+				return resOK, nil
+			}
+		case *message.DHTClientGetDoneMsg:
+			if res.ID == msg.ID {
+				return resMiss, nil
+			}
+		}
+	}
+}
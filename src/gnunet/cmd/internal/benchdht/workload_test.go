@@ -0,0 +1,123 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package benchdht
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// testWorkload returns a valid workload.
+func testWorkload() *workload {
+	return &workload{
+		Keys:     100,
+		Dist:     DistUniform,
+		Skew:     1.1,
+		Puts:     0.5,
+		Size:     64,
+		Workers:  4,
+		Duration: time.Minute,
+		Timeout:  time.Second,
+		Expire:   time.Hour,
+	}
+}
+
+func TestWorkloadCheck(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(w *workload)
+		ok     bool
+	}{
+		{"valid", func(w *workload) {}, true},
+		{"no keys", func(w *workload) { w.Keys = 0 }, false},
+		{"puts > 1", func(w *workload) { w.Puts = 1.5 }, false},
+		{"negative size", func(w *workload) { w.Size = -1 }, false},
+		{"negative rate", func(w *workload) { w.Rate = -1 }, false},
+		{"no workers", func(w *workload) { w.Workers = 0 }, false},
+		{"no timeout", func(w *workload) { w.Timeout = 0 }, false},
+		{"sequential", func(w *workload) { w.Dist = DistSequential }, true},
+		{"zipf", func(w *workload) { w.Dist = DistZipf }, true},
+		{"zipf skew 1", func(w *workload) { w.Dist, w.Skew = DistZipf, 1 }, false},
+		{"unknown distribution", func(w *workload) { w.Dist = "normal" }, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := testWorkload()
+			tc.modify(w)
+			if err := w.check(); (err == nil) != tc.ok {
+				t.Fatalf("check: %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkloadChooser(t *testing.T) {
+	const draws = 10000
+	cases := []struct {
+		dist string
+		keys int
+	}{
+		{DistUniform, 1},
+		{DistUniform, 100},
+		{DistSequential, 1},
+		{DistSequential, 100},
+		{DistZipf, 1},
+		{DistZipf, 100},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s,keys=%d", tc.dist, tc.keys), func(t *testing.T) {
+			w := testWorkload()
+			w.Dist, w.Keys = tc.dist, tc.keys
+			var seq uint64
+			next := w.chooser(rand.New(rand.NewSource(1)), &seq)
+			counts := make([]int, tc.keys)
+			for i := 0; i < draws; i++ {
+				n := next()
+				if n < 0 || n >= tc.keys {
+					t.Fatalf("key index %d out of range", n)
+				}
+				if tc.dist == DistSequential && n != i%tc.keys {
+					t.Fatalf("draw %d: key index %d", i, n)
+				}
+				counts[n]++
+			}
+			if tc.keys == 1 {
+				return
+			}
+			switch tc.dist {
+			case DistUniform, DistSequential:
+				// every key is used about equally often
+				for n, c := range counts {
+					if c < draws/tc.keys/2 || c > 2*draws/tc.keys {
+						t.Fatalf("key %d used %d times", n, c)
+					}
+				}
+			case DistZipf:
+				// the first key is the most popular one
+				for n, c := range counts[1:] {
+					if c > counts[0] {
+						t.Fatalf("key %d used more often than key 0 (%d > %d)", n+1, c, counts[0])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dhtcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/path"
+	"gnunet/util"
+)
+
+// clientOptions are the command line options for put and get requests
+type clientOptions struct {
+	btype  uint          // block type
+	repl   uint          // replication level
+	expire time.Duration // block expiration (put; relative)
+	expAt  string        // block expiration (put; absolute, RFC3339)
+	limit  uint          // max. number of results (get, traces)
+	route  bool          // DHT_RO_RECORD_ROUTE
+	demux  bool          // DHT_RO_DEMULTIPLEX_EVERYWHERE
+	approx bool          // DHT_RO_FIND_APPROXIMATE
+	first  bool          // DHT_RO_FIRST_RESULT
+	verify bool          // confirm stored block (put)
+	kind   string        // request kind (traces)
+}
+
+// flags returns the route options for a request
+func (o *clientOptions) flags() (flags uint32) {
+	if o.route {
+		flags |= enums.DHT_RO_RECORD_ROUTE
+	}
+	if o.demux {
+		flags |= enums.DHT_RO_DEMULTIPLEX_EVERYWHERE
+	}
+	if o.approx {
+		flags |= enums.DHT_RO_FIND_APPROXIMATE
+	}
+	if o.first {
+		flags |= enums.DHT_RO_FIRST_RESULT
+	}
+	return
+}
+
+// PutResponse is the output of a put command
+type PutResponse struct {
+	Key     string      `json:"key"`               // DHT key (hash of key string)
+	Type    string      `json:"type"`              // block type
+	Flags   string      `json:"flags"`             // route options
+	Confirm *PutConfirm `json:"confirm,omitempty"` // outcome of confirmation
+}
+
+// PutConfirm is the outcome of a confirmed put command
+type PutConfirm struct {
+	Samples    int     `json:"samples"`    // number of sample GETs
+	Confirmed  int     `json:"confirmed"`  // number of samples returning the block
+	Retries    int     `json:"retries"`    // number of repeated PUTs
+	Confidence float64 `json:"confidence"` // share of confirmed samples
+}
+
+// GetResponse is the output for a result of a get command
+type GetResponse struct {
+	Key       string   `json:"key"`                 // DHT key of result
+	Type      string   `json:"type"`                // block type
+	Expire    string   `json:"expire"`              // block expiration
+	Flags     string   `json:"flags"`               // route options of result
+	TruncOrig string   `json:"truncated,omitempty"` // origin of truncated path
+	PutPath   []string `json:"putPath,omitempty"`   // recorded put path
+	GetPath   []string `json:"getPath,omitempty"`   // recorded get path
+	Data      string   `json:"data"`                // block data
+}
+
+// peers returns the signers of path entries
+func peers(list []*path.Entry) (ids []string) {
+	for _, pe := range list {
+		ids = append(ids, pe.Signer.String())
+	}
+	return
+}
+
+// put a block into the DHT: the key string is hashed to get the DHT key
+// (like gnunet-dht-put does).
+func put(ctx context.Context, socket, key, value string, opts *clientOptions, out *util.Output) error {
+	conn, err := service.NewConnection(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	msg := message.NewDHTClientPutMsg(crypto.Hash([]byte(key)), enums.BlockType(opts.btype), []byte(value))
+	msg.Options = opts.flags() &^ (enums.DHT_RO_FIND_APPROXIMATE | enums.DHT_RO_FIRST_RESULT)
+	msg.ReplLevel = uint32(opts.repl)
+	if len(opts.expAt) > 0 {
+		t, err := time.Parse(time.RFC3339, opts.expAt)
+		if err != nil {
+			return err
+		}
+		msg.Expire = util.NewAbsoluteTime(t)
+	} else {
+		// the service converts (and caps) the relative expiration
+		if opts.expire <= 0 {
+			return errors.New("expiration must be positive")
+		}
+		msg.Options |= enums.DHT_RO_RELATIVE_EXPIRATION
+		msg.Expire = util.AbsoluteTime{Val: uint64(opts.expire.Microseconds())}
+	}
+	if opts.verify {
+		// ask for a confirmation of the upcoming request
+		if err = conn.Send(ctx, message.NewDHTClientPutVerifyMsg(msg.Key)); err != nil {
+			return err
+		}
+	}
+	if err = conn.Send(ctx, msg); err != nil {
+		return err
+	}
+	res := &PutResponse{
+		Key:   msg.Key.String(),
+		Type:  msg.BType.String(),
+		Flags: message.DHTFlags(uint16(msg.Options)),
+	}
+	text := fmt.Sprintf("stored %d bytes under key %s (%s, flags %s)\n", len(value), res.Key, res.Type, res.Flags)
+	if opts.verify {
+		var cm *message.DHTClientPutConfirmMsg
+		for cm == nil {
+			in, err := conn.Receive(ctx)
+			if err != nil {
+				return err
+			}
+			if m, ok := in.(*message.DHTClientPutConfirmMsg); ok && m.Key.Equal(msg.Key) {
+				cm = m
+			}
+		}
+		res.Confirm = &PutConfirm{
+			Samples:    int(cm.Samples),
+			Confirmed:  int(cm.Confirmed),
+			Retries:    int(cm.Retries),
+			Confidence: cm.Confidence(),
+		}
+		text += fmt.Sprintf("    confirmed by %d of %d samples (%d retries, confidence %.2f)\n",
+			cm.Confirmed, cm.Samples, cm.Retries, res.Confirm.Confidence)
+	}
+	if err = out.Emit(res, "%s", text); err != nil {
+		return err
+	}
+	if c := res.Confirm; c != nil && c.Samples > 0 && c.Confirmed == 0 {
+		return errors.New("stored block not confirmed")
+	}
+	return nil
+}
+
+// get blocks from the DHT until the timeout is reached (or the requested
+// number of ordered results or the first exact result is received).
+func get(ctx context.Context, socket, key string, opts *clientOptions, out *util.Output) error {
+	cl, err := service.NewClient(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+
+	// a restarted DHT service gets the request again
+	cl.SetReconnect(true, func(int) {
+		fmt.Fprintln(os.Stderr, "reconnected to DHT service -- request resumed")
+	})
+
+	// start request (with optional limit)
+	const id = 1
+	msg := message.NewDHTClientGetMsg(crypto.Hash([]byte(key)))
+	msg.BType = enums.BlockType(opts.btype)
+	msg.Options = opts.flags()
+	msg.ReplLevel = uint32(opts.repl)
+	msg.ID = id
+	if opts.limit > 0 {
+		var window util.RelativeTime
+		if dl, ok := ctx.Deadline(); ok {
+			// leave time for receiving the results
+			window = util.NewRelativeTime(time.Until(dl) * 9 / 10)
+		}
+		if err = cl.Subscribe(ctx, message.NewDHTClientGetLimitMsg(id, uint32(opts.limit), window)); err != nil {
+			return err
+		}
+	}
+	if err = cl.Subscribe(ctx, msg); err != nil {
+		return err
+	}
+	defer func() {
+		stop := message.NewDHTClientGetStopMsg(msg.Key)
+		stop.ID = id
+		sctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = cl.Unsubscribe(sctx, msg, stop)
+	}()
+
+	// receive results
+	for {
+		in, err := cl.ReceiveResponse(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+		switch res := in.(type) {
+		case *message.DHTClientResultMsg:
+			if res.ID != id {
+				continue
+			}
+			rsp := &GetResponse{
+				Key:     res.Key.String(),
+				Type:    res.BType.String(),
+				Expire:  res.Expire.String(),
+				Flags:   message.DHTFlags(uint16(res.Options)),
+				PutPath: peers(res.PutPath),
+				GetPath: peers(res.GetPath),
+				Data:    string(res.Data),
+			}
+			if res.TruncOrigin != nil {
+				rsp.TruncOrig = res.TruncOrigin.String()
+			}
+			text := fmt.Sprintf("result %s (%s, expires %s, flags %s):\n    %s\n",
+				rsp.Key, rsp.Type, rsp.Expire, rsp.Flags, rsp.Data)
+			if len(rsp.TruncOrig) > 0 {
+				text += fmt.Sprintf("    truncated at: %s\n", rsp.TruncOrig)
+			}
+			if len(rsp.PutPath) > 0 {
+				text += fmt.Sprintf("    put path: %s\n", strings.Join(rsp.PutPath, " -> "))
+			}
+			if len(rsp.GetPath) > 0 {
+				text += fmt.Sprintf("    get path: %s\n", strings.Join(rsp.GetPath, " -> "))
+			}
+			if err = out.Emit(rsp, "%s", text); err != nil {
+				return err
+			}
+		case *message.DHTClientGetDoneMsg:
+			if res.ID == id {
+				return nil
+			}
+		}
+	}
+}
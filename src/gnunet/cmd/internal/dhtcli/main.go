@@ -0,0 +1,206 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dhtcli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht"
+	"gnunet/util"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+// Main runs the DHT client with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-dht-go", flag.ExitOnError)
+	// handle command line arguments
+	var (
+		cfgFile  string
+		endpoint string
+		socket   string
+		format   string
+		deadline time.Duration
+		opts     clientOptions
+	)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&endpoint, "R", "", "JSON-RPC endpoint of the DHT service (default: from configuration)")
+	fs.StringVar(&socket, "s", "", "service socket of the DHT service (default: from configuration)")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.DurationVar(&deadline, "timeout", time.Minute, "request timeout")
+	fs.UintVar(&opts.btype, "type", uint(enums.BLOCK_TYPE_TEST), "block type (put, get)")
+	fs.UintVar(&opts.repl, "repl", 0, "replication level (put, get; 0 = service default)")
+	fs.DurationVar(&opts.expire, "expire", time.Hour, "block expiration relative to now (put)")
+	fs.StringVar(&opts.expAt, "expire-at", "", "absolute block expiration in RFC3339 format (put; overrides -expire)")
+	fs.UintVar(&opts.limit, "limit", 0, "max. number of ordered results (get) or traces (traces); 0 = unlimited")
+	fs.BoolVar(&opts.route, "record-route", false, "record the route of the request (put, get)")
+	fs.BoolVar(&opts.demux, "demux", false, "process request on every peer along the route (put, get)")
+	fs.BoolVar(&opts.approx, "approx", false, "accept results for keys close to the query key (get)")
+	fs.BoolVar(&opts.first, "first", false, "parallel lookup ending with the first exact result (get)")
+	fs.BoolVar(&opts.verify, "verify", false, "confirm the stored block can be retrieved (put)")
+	fs.StringVar(&opts.kind, "kind", "", "request kind 'get' or 'put' (traces; default: both)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [options] export|import <file>\n", fs.Name())
+		fmt.Fprintf(fs.Output(), "       %s [options] put <key> <value>\n", fs.Name())
+		fmt.Fprintf(fs.Output(), "       %s [options] get <key>\n", fs.Name())
+		fmt.Fprintf(fs.Output(), "       %s [options] traces [<key>]\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	nargs := map[string][2]int{"export": {2, 2}, "import": {2, 2}, "put": {3, 3}, "get": {2, 2}, "traces": {1, 2}}
+	if n, ok := nargs[fs.Arg(0)]; !ok || fs.NArg() < n[0] || fs.NArg() > n[1] {
+		fs.Usage()
+		os.Exit(1)
+	}
+	// read configuration if required
+	if len(endpoint) == 0 || len(socket) == 0 {
+		if err = config.ParseConfig(cfgFile); err != nil {
+			log.Fatalf("invalid configuration file: %s", err.Error())
+		}
+	}
+
+	// execute client commands (service socket)
+	switch fs.Arg(0) {
+	case "put", "get":
+		if len(socket) == 0 {
+			if config.Cfg.DHT == nil || config.Cfg.DHT.Service == nil {
+				log.Fatal("no DHT service socket configured (-s)")
+			}
+			socket = config.Cfg.DHT.Service.Socket
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), deadline)
+		defer cancel()
+		if fs.Arg(0) == "put" {
+			err = put(ctx, socket, fs.Arg(1), fs.Arg(2), &opts, out)
+		} else {
+			err = get(ctx, socket, fs.Arg(1), &opts, out)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// get RPC endpoint
+	if len(endpoint) == 0 {
+		if config.Cfg.RPC == nil || len(config.Cfg.RPC.Endpoint) == 0 {
+			log.Fatal("no JSON-RPC endpoint configured (-R)")
+		}
+		endpoint = config.Cfg.RPC.Endpoint
+	}
+	endpoint = "http://" + strings.TrimPrefix(endpoint, "tcp:") + "/"
+
+	// execute command
+	if fs.Arg(0) == "traces" {
+		req := &dht.TracesRequest{
+			Kind:  opts.kind,
+			Limit: int(opts.limit),
+		}
+		if fs.NArg() > 1 {
+			req.Key = crypto.Hash([]byte(fs.Arg(1))).String()
+		}
+		reply := new(dht.TracesResponse)
+		if err = call(endpoint, "DHT.Traces", req, reply, deadline); err != nil {
+			log.Fatal(err)
+		}
+		if err = printTraces(out, reply.Traces); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// the dump file is accessed by the DHT service
+	fname, err := filepath.Abs(fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch fs.Arg(0) {
+	case "export":
+		reply := new(dht.ExportResponse)
+		if err = call(endpoint, "DHT.Export", &dht.ExportRequest{File: fname}, reply, deadline); err != nil {
+			log.Fatal(err)
+		}
+		err = out.Emit(reply, "%d blocks exported to '%s'\n", reply.Count, fname)
+	case "import":
+		reply := new(dht.ImportResponse)
+		if err = call(endpoint, "DHT.Import", &dht.ImportRequest{File: fname}, reply, deadline); err != nil {
+			log.Fatal(err)
+		}
+		err = out.Emit(reply, "%d blocks imported from '%s' (%d skipped)\n", reply.Count, fname, reply.Skipped)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// printTraces emits request traces.
+func printTraces(out *util.Output, traces []*dht.TraceInfo) (err error) {
+	if out.IsJSON() {
+		return out.Emit(traces, "")
+	}
+	emit := func(format string, args ...any) {
+		if err == nil {
+			err = out.Emit(nil, format, args...)
+		}
+	}
+	for _, tr := range traces {
+		emit("#%d %s %s (type %s, flags=%s) from %s at %s\n",
+			tr.ID, strings.ToUpper(tr.Kind), tr.Key, tr.Type, tr.Flags, tr.From, tr.Started)
+		for _, step := range tr.Steps {
+			emit("  %12s %-8s %-10s %s\n", step.At, step.Step, step.Peer, step.Info)
+		}
+		if tr.Dropped > 0 {
+			emit("  (%d more steps)\n", tr.Dropped)
+		}
+	}
+	return
+}
+
+// call a JSON-RPC method of the DHT service
+func call(endpoint, method string, args, reply any, deadline time.Duration) error {
+	buf, err := json2.EncodeClientRequest(method, args)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: deadline}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json2.DecodeClientResponse(resp.Body, reply)
+}
@@ -0,0 +1,340 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dhtsrv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/script"
+	"gnunet/service"
+	"gnunet/service/cadet"
+	coreSrv "gnunet/service/core"
+	"gnunet/service/dht"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/metrics"
+	"gnunet/service/nse"
+	"gnunet/service/statistics"
+	"gnunet/transport"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the DHT service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-service-dht-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[dht] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	// intro
+	logger.SetLogLevel(logger.DBG)
+	logger.Println(logger.INFO, "[dht] Starting service...")
+
+	var (
+		cfgFile  string
+		socket   string
+		param    string
+		err      error
+		logLevel int
+		rpcEndp  string
+		local    bool
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "GNS service socket")
+	fs.StringVar(&param, "p", "", "socket parameters (<key>=<value>,...)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "DHT log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	fs.BoolVar(&local, "local", false, "local-only mode: no traffic to non-local addresses")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[dht] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+
+	// apply configuration
+	if config.Cfg.Logging.Level > 0 {
+		logLevel = config.Cfg.Logging.Level
+	}
+	logger.SetLogLevel(logLevel)
+	if len(socket) == 0 {
+		socket = config.Cfg.DHT.Service.Socket
+	}
+	if transport.LocalOnly = local || config.Cfg.Network.LocalOnly; transport.LocalOnly {
+		logger.Println(logger.INFO, "[dht] Local-only mode: no traffic to non-local addresses")
+	}
+	if err = script.Setup(config.Cfg.Scripts); err != nil {
+		logger.Printf(logger.ERROR, "[dht] Failed to load scripts: %s\n", err.Error())
+		return
+	}
+	params := make(map[string]string)
+	if len(param) > 0 {
+		for _, p := range strings.Split(param, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			params[kv[0]] = kv[1]
+		}
+	} else {
+		params = config.Cfg.DHT.Service.Params
+	}
+
+	// instantiate core service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var c *core.Core
+	if c, err = core.NewCore(ctx, config.Cfg.Local); err != nil {
+		logger.Printf(logger.ERROR, "[dht] core failed: %s\n", err.Error())
+		return
+	}
+	defer c.Shutdown()
+	source := core.SourceLink(config.Cfg)
+	c.SetSource(source)
+	service.RegisterIntrospector("core", func() *service.Introspection {
+		in := &service.Introspection{
+			Queues:      c.Pending(),
+			Peers:       len(c.Connected()),
+			Disconnects: c.Disconnects(),
+		}
+		if off, ok := c.ClockOffset(); ok {
+			in.ClockOffset = off.String()
+		}
+		return in
+	})
+
+	// services in this process are controlled by a supervisor, so they
+	// can be stopped and (re-)started individually.
+	sup := service.NewSupervisor(ctx)
+
+	// expose core on a service socket (if configured)
+	if cc := config.Cfg.Core; cc != nil && cc.Service != nil && len(cc.Service.Socket) > 0 {
+		coreUnit := service.SocketUnit("core", nil, cc.Service, source, func(ctx context.Context) (service.Service, error) {
+			return coreSrv.NewService(ctx, c), nil
+		})
+		if err = sup.Add(coreUnit); err != nil {
+			logger.Printf(logger.ERROR, "[dht] Failed to add core service: '%s'", err.Error())
+			return
+		}
+	}
+
+	// start a new DHT service
+	var dhtSrv *dht.Service
+	if dhtSrv, err = dht.NewService(ctx, c, config.Cfg.DHT); err != nil {
+		logger.Printf(logger.ERROR, "[dht] failed to create DHT service: %s\n", err.Error())
+		return
+	}
+	dhtCfg := &config.ServiceConfig{
+		Socket: socket,
+		Params: params,
+		Limits: config.Cfg.DHT.Service.Limits,
+	}
+	dhtUnit := service.SocketUnit("dht", nil, dhtCfg, source, func(context.Context) (service.Service, error) {
+		return dhtSrv, nil
+	})
+	if err = sup.Add(dhtUnit); err != nil {
+		logger.Printf(logger.ERROR, "[dht] Failed to add DHT service: '%s'", err.Error())
+		return
+	}
+
+	// hande network size estimation: if a fixed number of peers are present
+	// in the network config, use that value; otherwise utilize the NSE
+	// algorithm and follow its estimates.
+	var nseSrv *nse.Service
+	numPeers := config.Cfg.Network.NumPeers
+	if numPeers != 0 {
+		dhtSrv.SetNetworkSize(numPeers)
+	} else {
+		nseSrv = nse.NewService(ctx, c, config.Cfg.NSE)
+		nseSrv.Subscribe(ctx, func(est *nse.Estimate) {
+			logger.Printf(logger.DBG, "[dht] network size estimate: %.0f peers (l2nse=%.3f)", est.Size(), est.L2NSE)
+			dhtSrv.SetL2NSE(est.L2NSE)
+		})
+		// expose NSE on a service socket (if configured)
+		if nc := config.Cfg.NSE; nc != nil && nc.Service != nil && len(nc.Service.Socket) > 0 {
+			nseUnit := service.SocketUnit("nse", nil, nc.Service, source, func(context.Context) (service.Service, error) {
+				return nseSrv, nil
+			})
+			if err = sup.Add(nseUnit); err != nil {
+				logger.Printf(logger.ERROR, "[dht] Failed to add NSE service: '%s'", err.Error())
+				return
+			}
+		}
+	}
+
+	// start CADET (if configured)
+	var cadetSrv *cadet.Service
+	if cc := config.Cfg.Cadet; cc != nil {
+		cadetSrv = cadet.NewService(ctx, c)
+		if cc.Service != nil && len(cc.Service.Socket) > 0 {
+			cadetUnit := service.SocketUnit("cadet", nil, cc.Service, source, func(context.Context) (service.Service, error) {
+				return cadetSrv, nil
+			})
+			if err = sup.Add(cadetUnit); err != nil {
+				logger.Printf(logger.ERROR, "[dht] Failed to add CADET service: '%s'", err.Error())
+				return
+			}
+		}
+	}
+	// start all services
+	if err = sup.StartAll(); err != nil {
+		logger.Printf(logger.ERROR, "[dht] Failed to start services: '%s'", err.Error())
+		sup.StopAll()
+		return
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[dht] RPC must have a TCP/IP endpoint")
+			return
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if ep := config.Cfg.RPC.Endpoint; len(ep) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, ep); err != nil {
+			logger.Printf(logger.ERROR, "[dht] RPC failed to start: %s", err.Error())
+			return
+		}
+		dhtSrv.InitRPC(rpc)
+		if nseSrv != nil {
+			nseSrv.InitRPC(rpc)
+		}
+		if cadetSrv != nil {
+			cadetSrv.InitRPC(rpc)
+		}
+		coreSrv.InitRPC(rpc, c)
+		service.InitLimitsRPC(rpc)
+		service.InitMaintenanceRPC(rpc)
+		service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+		service.InitConfigRPC(rpc, config.Cfg)
+		service.InitServicesRPC(rpc, sup)
+	}
+
+	// handle bootstrap: collect known addresses (cached peers first)
+	bsList := make([]*util.Address, 0)
+	bootCache := config.Cfg.Network.BootCache
+	if len(bootCache) > 0 {
+		var cached []*util.Address
+		if cached, err = dhtSrv.LoadBootCache(ctx, bootCache); err != nil {
+			logger.Printf(logger.ERROR, "[dht] failed to load bootstrap cache: %s", err.Error())
+		}
+		bsList = append(bsList, cached...)
+	}
+	for _, bs := range config.Cfg.Network.Bootstrap {
+		// check for HELLO URL
+		if strings.HasPrefix(bs, uri.PrefixHello) {
+			var hb *blocks.HelloBlock
+			if hb, err = blocks.ParseHelloBlockFromURL(bs, true); err != nil {
+				logger.Printf(logger.ERROR, "[dht] failed bootstrap HELLO URL %s: %s", bs, err.Error())
+				continue
+			}
+			// append HELLO addresses
+			bsList = append(bsList, hb.Addresses()...)
+		} else {
+			// parse address directly
+			var addr *util.Address
+			if addr, err = util.ParseAddress(bs); err != nil {
+				logger.Printf(logger.ERROR, "[dht] failed bootstrap address %s: %s", bs, err.Error())
+				continue
+			}
+			bsList = append(bsList, addr)
+		}
+	}
+	// send HELLO to all bootstrap addresses
+	for _, addr := range bsList {
+		if err := dhtSrv.SendHello(ctx, addr, "bootstrap"); err != nil {
+			if err != transport.ErrEndpMaybeSent {
+				logger.Printf(logger.ERROR, "[bootstrap] send HELLO failed: %s", err.Error())
+			}
+		}
+	}
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "dht:stats", service.StatsPeriod, service.StatsJob("dht")); err != nil {
+		logger.Printf(logger.ERROR, "[dht] statistics not scheduled: %s", err.Error())
+	}
+	// check alert rules periodically
+	if err = service.StartAlerts(ctx, "dht", config.Cfg.Alerts); err != nil {
+		logger.Printf(logger.ERROR, "[dht] alerts not started: %s", err.Error())
+	}
+	// serve metrics (if configured)
+	if err = metrics.Start(ctx, "dht", config.Cfg.Metrics); err != nil {
+		logger.Printf(logger.ERROR, "[dht] metrics not served: %s", err.Error())
+	}
+	// publish counters to the statistics service (if configured)
+	if err = statistics.StartPublisher(ctx, "dht", config.Cfg.Statistics); err != nil {
+		logger.Printf(logger.ERROR, "[dht] statistics not published: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[dht] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[dht] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[dht] Unhandled signal: "+sig.String())
+			}
+			// print some system statistics
+			logger.Printf(logger.INFO, "[dht] Number of Go routines: %15d", runtime.NumGoroutine())
+			mem := new(runtime.MemStats)
+			runtime.ReadMemStats(mem)
+			logger.Printf(logger.INFO, "[dht]        Allocated heap: %15d", mem.HeapAlloc)
+			logger.Printf(logger.INFO, "[dht]             Idle heap: %15d", mem.HeapIdle)
+			logger.Printf(logger.INFO, "[dht]      Total allocation: %15d", mem.TotalAlloc)
+		}
+	}
+
+	// save HELLOs of known peers for the next start
+	if len(bootCache) > 0 {
+		if err := dhtSrv.SaveBootCache(bootCache, config.Cfg.Network.BootCacheSize); err != nil {
+			logger.Printf(logger.ERROR, "[dht] failed to save bootstrap cache: %s", err.Error())
+		}
+	}
+	// terminating service
+	sup.StopAll()
+	cancel()
+}
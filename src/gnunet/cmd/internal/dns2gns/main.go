@@ -0,0 +1,129 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dns2gns
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/service/dns2gns"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the DNS-to-GNS gateway with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("dns2gns", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[dns2gns] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[dns2gns] Starting gateway...")
+
+	var (
+		cfgFile  string
+		socket   string
+		listen   string
+		upstream string
+		tlds     string
+		err      error
+		logLevel int
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "GNS service socket (default: from configuration)")
+	fs.StringVar(&listen, "l", "", "listen address for DNS (default: from configuration or ':53')")
+	fs.StringVar(&upstream, "u", "", "upstream DNS server for non-GNS names (default: from configuration)")
+	fs.StringVar(&tlds, "t", "", "TLDs resolved in GNS (<tld>,...; default: from configuration)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "DNS2GNS log level (default: INFO)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[dns2gns] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	logger.SetLogLevel(logLevel)
+	if config.Cfg.GNS == nil {
+		config.Cfg.GNS = new(config.GNSConfig)
+	}
+	if len(socket) > 0 {
+		config.Cfg.GNS.Service = &config.ServiceConfig{Socket: socket}
+	} else if config.Cfg.GNS.Service == nil {
+		logger.Println(logger.ERROR, "[dns2gns] No GNS service configured")
+		return
+	}
+	if config.Cfg.DNS2GNS == nil {
+		config.Cfg.DNS2GNS = new(config.DNS2GNSConfig)
+	}
+	cfg := config.Cfg.DNS2GNS
+	if len(listen) > 0 {
+		cfg.Listen = listen
+	} else if len(cfg.Listen) == 0 {
+		cfg.Listen = ":53"
+	}
+	if len(upstream) > 0 {
+		cfg.Upstream = upstream
+	}
+	if len(tlds) > 0 {
+		cfg.TLDs = strings.Split(tlds, ",")
+	}
+
+	// start gateway
+	gw := dns2gns.NewGateway(config.Cfg)
+	if err = gw.Start(cfg.Listen); err != nil {
+		logger.Printf(logger.ERROR, "[dns2gns] Error: '%s'\n", err.Error())
+		return
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[dns2gns] Terminating gateway (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[dns2gns] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[dns2gns] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating gateway
+	if err = gw.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[dns2gns] Failed to stop gateway: %s", err.Error())
+	}
+}
@@ -0,0 +1,321 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gnscli
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/gns/rr"
+	"gnunet/util"
+)
+
+// Record is the JSON output schema for a resource record
+type Record struct {
+	Type   string `json:"type"`   // record type
+	Flags  uint16 `json:"flags"`  // record flags
+	Expire string `json:"expire"` // expiration
+	Data   string `json:"data"`   // hex-encoded record data
+	Value  string `json:"value"`  // record data (text)
+}
+
+// QueryKey is the JSON output schema for a DHT query key
+type QueryKey struct {
+	Zone  string `json:"zone"`  // zone (zTLD)
+	Label string `json:"label"` // label
+	Key   string `json:"key"`   // DHT query key
+}
+
+// Step is the JSON output schema for a resolution step
+type Step struct {
+	Kind       string `json:"kind"`       // kind of step
+	Zone       string `json:"zone"`       // zone ID
+	Label      string `json:"label"`      // label (or DNS name)
+	Query      string `json:"query"`      // DHT query key
+	ElapsedUs  uint64 `json:"elapsedUs"`  // start of step (in µs since start)
+	DurationUs uint64 `json:"durationUs"` // duration of step (in µs)
+}
+
+// Provenance is the JSON output schema for the provenance of a result
+type Provenance struct {
+	Source   string `json:"source"`             // source of the result
+	Expire   string `json:"expire"`             // earliest expiration of blocks used
+	Verified string `json:"verified,omitempty"` // signature validation of answering block
+	Shadow   bool   `json:"shadow"`             // shadow records used
+}
+
+// Main runs the GNS client with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-gns-go", flag.ExitOnError)
+	// handle command line arguments
+	var (
+		cfgFile  string
+		socket   string
+		name     string
+		zone     string
+		rtype    string
+		format   string
+		trace    bool
+		prov     bool
+		noNeg    bool
+		noDHT    bool
+		raw      bool
+		deadline time.Duration
+	)
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "GNS service socket (default: from configuration)")
+	fs.StringVar(&name, "u", "", "name to look up")
+	fs.StringVar(&zone, "z", "", "zone key (default: start zone for the TLD of the name)")
+	fs.StringVar(&rtype, "t", "ANY", "record type to look up")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.BoolVar(&trace, "trace", false, "show resolution steps")
+	fs.BoolVar(&prov, "provenance", false, "show source and freshness of the result")
+	fs.BoolVar(&noNeg, "no-negcache", false, "bypass the negative cache")
+	fs.BoolVar(&noDHT, "no-dht", false, "don't look up names in the DHT")
+	fs.BoolVar(&raw, "r", false, "print only the record values (text output)")
+	fs.DurationVar(&deadline, "timeout", 30*time.Second, "lookup timeout")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// handle commands
+	if fs.NArg() > 0 {
+		switch fs.Arg(0) {
+		case "query-key":
+			if fs.NArg() != 3 {
+				log.Fatal("usage: gnunet-gns-go query-key <zone> <label>")
+			}
+			err = emitQueryKey(out, fs.Arg(1), fs.Arg(2))
+		default:
+			err = fmt.Errorf("unknown command '%s'", fs.Arg(0))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(name) == 0 {
+		log.Fatal("no name specified (-u)")
+	}
+	kind, err := rr.ParseType(rtype)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// get zone key and relative name
+	n, err := names.Parse(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	zk := n.Zone
+	if zk != nil {
+		name = names.Join(n.Labels)
+	} else if len(zone) > 0 {
+		if zk = names.ZoneKey(zone); zk == nil {
+			log.Fatalf("invalid zone key '%s'", zone)
+		}
+	} else {
+		// the service maps the TLD to a start zone
+		zk, _ = crypto.NullZoneKey(enums.GNS_TYPE_PKEY)
+	}
+	// get service socket
+	if len(socket) == 0 {
+		if err = config.ParseConfig(cfgFile); err != nil {
+			log.Fatalf("invalid configuration file: %s", err.Error())
+		}
+		socket = config.Cfg.GNS.Service.Socket
+	}
+
+	// assemble lookup request
+	req := message.NewGNSLookupMsg()
+	req.ID = uint32(util.NextID())
+	req.Zone = zk
+	req.RType = kind
+	req.SetName(name)
+	if noDHT {
+		req.Options = enums.GNS_LO_NO_DHT
+	}
+	if noNeg {
+		req.Options |= enums.GNS_LO_NO_NEGCACHE
+	}
+	if trace {
+		req.Options |= enums.GNS_LO_TRACE
+	}
+	if prov {
+		req.Options |= enums.GNS_LO_PROVENANCE
+	}
+
+	// send request and wait for response(s)
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	cl, err := service.NewClient(ctx, socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cl.Close()
+
+	// a restarted GNS service gets the lookup again (the request yields
+	// trace and provenance messages before the result, so it is a
+	// subscription)
+	cl.SetReconnect(true, func(int) {
+		fmt.Fprintln(os.Stderr, "reconnected to GNS service -- lookup resumed")
+	})
+	if err = cl.Subscribe(ctx, req); err != nil {
+		log.Fatal(err)
+	}
+	for {
+		var msg message.Message
+		if msg, err = cl.ReceiveResponse(ctx); err != nil {
+			log.Fatal(err)
+		}
+		switch m := msg.(type) {
+		case *message.LookupTraceMsg:
+			if m.ID != req.ID {
+				continue
+			}
+			if err = emitTrace(out, m); err != nil {
+				log.Fatal(err)
+			}
+		case *message.LookupProvenanceMsg:
+			if m.ID != req.ID {
+				continue
+			}
+			if err = emitProvenance(out, m); err != nil {
+				log.Fatal(err)
+			}
+		case *message.LookupResultMsg:
+			if m.ID != req.ID {
+				continue
+			}
+			if err = emitRecords(out, m, raw); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+}
+
+// emitQueryKey writes the DHT query key for a label in a zone (zTLD)
+func emitQueryKey(out *util.Output, zone, label string) error {
+	zk := names.ZoneKey(zone)
+	if zk == nil {
+		return fmt.Errorf("invalid zone key '%s'", zone)
+	}
+	key, err := blocks.GNSQueryKey(zk, label)
+	if err != nil {
+		return err
+	}
+	res := &QueryKey{
+		Zone:  zone,
+		Label: label,
+		Key:   key.String(),
+	}
+	return out.Emit(res, "%s\n", res.Key)
+}
+
+// emitTrace writes the resolution steps
+func emitTrace(out *util.Output, m *message.LookupTraceMsg) error {
+	steps := make([]*Step, len(m.Steps))
+	for i, s := range m.Steps {
+		steps[i] = &Step{
+			Kind:       s.KindString(),
+			Zone:       string(s.Zone),
+			Label:      string(s.Label),
+			Query:      s.Query.String(),
+			ElapsedUs:  s.Elapsed,
+			DurationUs: s.Duration,
+		}
+		if !out.IsJSON() {
+			if err := out.Emit(nil, ";; %s\n", s.String()); err != nil {
+				return err
+			}
+		}
+	}
+	if out.IsJSON() {
+		return out.Emit(map[string]any{"trace": steps}, "")
+	}
+	return nil
+}
+
+// emitProvenance writes the source and freshness of the result
+func emitProvenance(out *util.Output, m *message.LookupProvenanceMsg) error {
+	res := &Provenance{
+		Source: m.SourceString(),
+		Expire: m.Expire.String(),
+		Shadow: m.Shadowed(),
+	}
+	verified := "-"
+	if m.Verified.Val != 0 {
+		res.Verified = m.Verified.String()
+		verified = res.Verified
+	}
+	if out.IsJSON() {
+		return out.Emit(map[string]any{"provenance": res}, "")
+	}
+	return out.Emit(nil, ";; source=%s expire=%s verified=%s shadow=%v\n",
+		res.Source, res.Expire, verified, res.Shadow)
+}
+
+// emitRecords writes the records of a lookup result (only the record
+// values in raw text output)
+func emitRecords(out *util.Output, m *message.LookupResultMsg, raw bool) error {
+	recs := make([]*Record, len(m.Records))
+	for i, rec := range m.Records {
+		recs[i] = &Record{
+			Type:   rec.RType.String(),
+			Flags:  uint16(rec.Flags),
+			Expire: rec.Expire.String(),
+			Data:   hex.EncodeToString(rec.Data),
+			Value:  rr.ToText(rec.RType, rec.Data),
+		}
+		if !out.IsJSON() {
+			var err error
+			if raw {
+				err = out.Emit(nil, "%s\n", recs[i].Value)
+			} else {
+				err = out.Emit(nil, "%s %d %s %s\n", recs[i].Type, recs[i].Flags, recs[i].Expire, recs[i].Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if out.IsJSON() {
+		return out.Emit(map[string]any{"records": recs}, "")
+	}
+	if len(recs) == 0 && !raw {
+		return out.Emit(nil, "no records found\n")
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gnssrv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/service"
+	"gnunet/service/gns"
+	"gnunet/service/metrics"
+	"gnunet/service/statistics"
+	"gnunet/transport"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the GNS service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-service-gns-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[gns] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[gns] Starting service...")
+
+	var (
+		cfgFile  string
+		socket   string
+		param    string
+		err      error
+		logLevel int
+		rpcEndp  string
+		local    bool
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "GNS service socket")
+	fs.StringVar(&param, "p", "", "socket parameters (<key>=<value>,...)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "GNS log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	fs.BoolVar(&local, "local", false, "local-only mode: no DNS queries to non-local servers")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[gns] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+
+	// apply configuration (from file and command-line)
+	logger.SetLogLevel(logLevel)
+	if len(socket) == 0 {
+		socket = config.Cfg.GNS.Service.Socket
+	}
+	if transport.LocalOnly = local || (config.Cfg.Network != nil && config.Cfg.Network.LocalOnly); transport.LocalOnly {
+		logger.Println(logger.INFO, "[gns] Local-only mode: no DNS queries to non-local servers")
+	}
+	params := make(map[string]string)
+	if len(param) > 0 {
+		for _, p := range strings.Split(param, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			params[kv[0]] = kv[1]
+		}
+	} else {
+		params = config.Cfg.GNS.Service.Params
+	}
+
+	// start a new GNS service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gns := gns.NewService(ctx, nil, config.Cfg)
+	srv := service.NewSocketHandler("gns", gns)
+	srv.SetLimits(config.Cfg.GNS.Service.Limits)
+	srv.SetSource(core.SourceLink(config.Cfg))
+	if err = srv.Start(ctx, socket, params); err != nil {
+		logger.Printf(logger.ERROR, "[gns] Error: '%s'", err.Error())
+		return
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[gns] RPC must have a TCP/IP endpoint")
+			return
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if ep := config.Cfg.RPC.Endpoint; len(ep) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, ep); err != nil {
+			logger.Printf(logger.ERROR, "[gns] RPC failed to start: %s", err.Error())
+			return
+		}
+		gns.InitRPC(rpc)
+		service.InitLimitsRPC(rpc)
+		service.InitMaintenanceRPC(rpc)
+		service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+		service.InitConfigRPC(rpc, config.Cfg)
+	}
+
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "gns:stats", service.StatsPeriod, service.StatsJob("gns")); err != nil {
+		logger.Printf(logger.ERROR, "[gns] statistics not scheduled: %s", err.Error())
+	}
+	// check alert rules periodically
+	if err = service.StartAlerts(ctx, "gns", config.Cfg.Alerts); err != nil {
+		logger.Printf(logger.ERROR, "[gns] alerts not started: %s", err.Error())
+	}
+	// serve metrics (if configured)
+	if err = metrics.Start(ctx, "gns", config.Cfg.Metrics); err != nil {
+		logger.Printf(logger.ERROR, "[gns] metrics not served: %s", err.Error())
+	}
+	// publish counters to the statistics service (if configured)
+	if err = statistics.StartPublisher(ctx, "gns", config.Cfg.Statistics); err != nil {
+		logger.Printf(logger.ERROR, "[gns] statistics not published: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[gns] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[gns] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[gns] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating service
+	cancel()
+	if err = srv.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[gns] Failed to stop service: %s", err.Error())
+	}
+}
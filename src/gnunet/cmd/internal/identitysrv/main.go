@@ -0,0 +1,174 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identitysrv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/service"
+	"gnunet/service/identity"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the IDENTITY service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-service-identity-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[identity] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[identity] Starting service...")
+
+	var (
+		cfgFile  string
+		socket   string
+		param    string
+		egoDir   string
+		err      error
+		logLevel int
+		rpcEndp  string
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "IDENTITY service socket")
+	fs.StringVar(&param, "p", "", "socket parameters (<key>=<value>,...)")
+	fs.StringVar(&egoDir, "d", "", "directory of ego files (default: from configuration)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "IDENTITY log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[identity] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+	if config.Cfg.Identity == nil || config.Cfg.Identity.Service == nil {
+		logger.Println(logger.ERROR, "[identity] No identity service configured")
+		return
+	}
+
+	// apply configuration
+	logger.SetLogLevel(logLevel)
+	if len(socket) == 0 {
+		socket = config.Cfg.Identity.Service.Socket
+	}
+	if len(egoDir) > 0 {
+		config.Cfg.Identity.EgoDir = egoDir
+	}
+	params := config.Cfg.Identity.Service.Params
+	if len(param) > 0 {
+		params = make(map[string]string)
+		for _, p := range strings.Split(param, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+	}
+
+	// start a new IDENTITY service
+	ctx, cancel := context.WithCancel(context.Background())
+	ids := identity.NewService(ctx, config.Cfg.Identity)
+	if ids == nil {
+		cancel()
+		return
+	}
+	srv := service.NewSocketHandler("identity", ids)
+	srv.SetLimits(config.Cfg.Identity.Service.Limits)
+	srv.SetSource(core.SourceLink(config.Cfg))
+	if err = srv.Start(ctx, socket, params); err != nil {
+		logger.Printf(logger.ERROR, "[identity] Error: '%s'\n", err.Error())
+		cancel()
+		return
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[identity] RPC must have a TCP/IP endpoint")
+			cancel()
+			return
+		}
+		if config.Cfg.RPC == nil {
+			config.Cfg.RPC = new(config.RPCConfig)
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if config.Cfg.RPC != nil && len(config.Cfg.RPC.Endpoint) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, config.Cfg.RPC.Endpoint); err != nil {
+			logger.Printf(logger.ERROR, "[identity] RPC failed to start: %s", err.Error())
+			cancel()
+			return
+		}
+		ids.InitRPC(rpc)
+		service.InitLimitsRPC(rpc)
+		service.InitMaintenanceRPC(rpc)
+		service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+		service.InitConfigRPC(rpc, config.Cfg)
+	}
+
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "identity:stats", service.StatsPeriod, service.StatsJob("identity")); err != nil {
+		logger.Printf(logger.ERROR, "[identity] statistics not scheduled: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[identity] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[identity] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[identity] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating service
+	cancel()
+	if err := srv.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[identity] Failed to stop service: %s", err.Error())
+	}
+}
@@ -0,0 +1,112 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package restsrv
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/service/rest"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the REST service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-rest-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[rest] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[rest] Starting gateway...")
+
+	var (
+		cfgFile  string
+		listen   string
+		origin   string
+		err      error
+		logLevel int
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&listen, "l", "", "listen address for HTTP (default: from configuration or '127.0.0.1:7776')")
+	fs.StringVar(&origin, "o", "", "allowed origin of cross-origin requests (default: from configuration)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "REST log level (default: INFO)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[rest] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	logger.SetLogLevel(logLevel)
+	if config.Cfg.REST == nil {
+		config.Cfg.REST = new(config.RESTConfig)
+	}
+	cfg := config.Cfg.REST
+	if len(listen) > 0 {
+		cfg.Listen = listen
+	} else if len(cfg.Listen) == 0 {
+		cfg.Listen = "127.0.0.1:7776"
+	}
+	if len(origin) > 0 {
+		cfg.Origin = origin
+	}
+
+	// start gateway
+	gw := rest.NewGateway(config.Cfg)
+	if err = gw.Start(cfg.Listen); err != nil {
+		logger.Printf(logger.ERROR, "[rest] Error: '%s'\n", err.Error())
+		return
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[rest] Terminating gateway (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[rest] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[rest] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating gateway
+	if err = gw.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[rest] Failed to stop gateway: %s", err.Error())
+	}
+}
@@ -0,0 +1,179 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocationsrv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/service"
+	"gnunet/service/metrics"
+	"gnunet/service/revocation"
+	"gnunet/service/statistics"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the REVOCATION service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-service-revocation-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[revocation] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[revocation] Starting service...")
+
+	var (
+		cfgFile  string
+		socket   string
+		param    string
+		err      error
+		logLevel int
+		rpcEndp  string
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "REVOCATION service socket")
+	fs.StringVar(&param, "p", "", "socket parameters (<key>=<value>,...)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "REVOCATION log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+
+	// apply configuration
+	logger.SetLogLevel(logLevel)
+	if len(socket) == 0 {
+		socket = config.Cfg.Revocation.Service.Socket
+	}
+	params := config.Cfg.Revocation.Service.Params
+	if len(param) > 0 {
+		params = make(map[string]string)
+		for _, p := range strings.Split(param, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+	}
+
+	// instantiate core service
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var c *core.Core
+	if c, err = core.NewCore(ctx, config.Cfg.Local); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] core failed: %s\n", err.Error())
+		return
+	}
+	defer c.Shutdown()
+	c.SetSource(core.SourceLink(config.Cfg))
+
+	// start a new REVOCATION service
+	rvc := revocation.NewService(ctx, c, config.Cfg.Revocation)
+	srv := service.NewSocketHandler("revocation", rvc)
+	srv.SetLimits(config.Cfg.Revocation.Service.Limits)
+	srv.SetSource(core.SourceLink(config.Cfg))
+	if err = srv.Start(ctx, socket, params); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] Error: '%s'\n", err.Error())
+		return
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[revocation] RPC must have a TCP/IP endpoint")
+			return
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if ep := config.Cfg.RPC.Endpoint; len(ep) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, ep); err != nil {
+			logger.Printf(logger.ERROR, "[revocation] RPC failed to start: %s", err.Error())
+			return
+		}
+		rvc.InitRPC(rpc)
+		service.InitLimitsRPC(rpc)
+		service.InitMaintenanceRPC(rpc)
+		service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+		service.InitConfigRPC(rpc, config.Cfg)
+	}
+
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "revocation:stats", service.StatsPeriod, service.StatsJob("revocation")); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] statistics not scheduled: %s", err.Error())
+	}
+	// check alert rules periodically
+	if err = service.StartAlerts(ctx, "revocation", config.Cfg.Alerts); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] alerts not started: %s", err.Error())
+	}
+	// serve metrics (if configured)
+	if err = metrics.Start(ctx, "revocation", config.Cfg.Metrics); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] metrics not served: %s", err.Error())
+	}
+	// publish counters to the statistics service (if configured)
+	if err = statistics.StartPublisher(ctx, "revocation", config.Cfg.Statistics); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] statistics not published: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[revocation] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[revocation] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[revocation] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating service
+	cancel()
+	if err := srv.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] Failed to stop service: %s", err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revokezonekey
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/service/revocation"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+// getPolicy returns the difficulty policy for revocations and a
+// description of its source: the policy is queried from a running
+// revocation service (JSON-RPC endpoint), read from a configuration file
+// or set to built-in defaults.
+func getPolicy(endpoint, cfgFile string) (p *revocation.Policy, src string, err error) {
+	switch {
+	case len(endpoint) > 0:
+		reply := new(revocation.PolicyResponse)
+		if err = rpcCall(endpoint, "Revocation.Policy", &revocation.PolicyRequest{}, reply); err != nil {
+			return
+		}
+		p, src = &reply.Policy, "service at "+endpoint
+
+	case len(cfgFile) > 0:
+		if err = config.ParseConfig(cfgFile); err != nil {
+			return
+		}
+		p, src = revocation.CurrentPolicy(config.Cfg), "configuration "+cfgFile
+
+	default:
+		p, src = revocation.NewPolicy(nil), "defaults"
+	}
+	return
+}
+
+// rpcCall sends a JSON-RPC request to the revocation service.
+func rpcCall(endpoint, method string, req, reply any) error {
+	url := "http://" + strings.TrimPrefix(endpoint, "tcp:") + "/"
+	buf, err := json2.EncodeClientRequest(method, req)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json2.DecodeClientResponse(resp.Body, reply)
+}
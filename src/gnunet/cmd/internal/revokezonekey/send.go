@@ -0,0 +1,77 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revokezonekey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gnunet/service/revocation"
+)
+
+// SendStatus is the JSON output schema for a revocation sent to the
+// revocation service.
+type SendStatus struct {
+	Socket      string                  `json:"socket"`                // revocation service socket
+	Accepted    bool                    `json:"accepted"`              // revocation accepted by service
+	Revoked     bool                    `json:"revoked"`               // zone key reported as revoked
+	Propagation *revocation.Propagation `json:"propagation,omitempty"` // flooding to connected peers
+}
+
+// sendRevocation submits the signed revocation data to the revocation
+// service listening on 'socket' and checks that the zone key is revoked.
+// If a JSON-RPC endpoint of the service is known, the propagation of the
+// revocation to connected peers is watched for at most 'wait'.
+func sendRevocation(ctx context.Context, socket, endpoint string, rd *revocation.RevData, wait time.Duration) (st *SendStatus, err error) {
+	st = &SendStatus{Socket: socket}
+	if st.Accepted, err = revocation.Publish(ctx, "revoke-zonekey", socket, rd); err != nil {
+		return
+	}
+	if !st.Accepted {
+		return st, errors.New("revocation rejected by service")
+	}
+	zk := &rd.ZoneKeySig.ZoneKey
+	if st.Revoked, err = revocation.IsRevoked(ctx, "revoke-zonekey", socket, zk); err != nil {
+		return
+	}
+	if len(endpoint) == 0 {
+		return
+	}
+	// watch propagation of the revocation
+	req := &revocation.StatusRequest{Zone: zk.ID()}
+	timeout := time.After(wait)
+	for {
+		reply := new(revocation.StatusResponse)
+		if err = rpcCall(endpoint, "Revocation.Status", req, reply); err != nil {
+			return
+		}
+		st.Propagation = reply.Propagation
+		if st.Propagation == nil || st.Propagation.Done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		case <-timeout:
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
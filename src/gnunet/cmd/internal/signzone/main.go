@@ -0,0 +1,323 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package signzone
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/rr"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Offline zone signer: reads a zone private key and a records file,
+// creates signed GNS blocks (RRBLOCKs) for all labels and writes them
+// to files (for transfer to an online machine) or stores them in a
+// running DHT service.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrNoValue   = errors.New("missing record value")
+	ErrBadFlag   = errors.New("unknown record flag")
+	ErrNoRecords = errors.New("no records to sign")
+)
+
+// Record is the JSON schema of a resource record in a records file.
+type Record struct {
+	Label  string   `json:"label"`            // label in zone
+	Type   string   `json:"type"`             // record type
+	Value  string   `json:"value,omitempty"`  // record value (textual)
+	Data   string   `json:"data,omitempty"`   // record data (hex-encoded)
+	Expire string   `json:"expire,omitempty"` // expiration (RFC3339)
+	TTL    string   `json:"ttl,omitempty"`    // lifetime from signing time
+	Flags  []string `json:"flags,omitempty"`  // record flags
+}
+
+// SignedBlock is the JSON output schema for a signed block
+type SignedBlock struct {
+	Label   string `json:"label"`             // label in zone
+	Key     string `json:"key,omitempty"`     // DHT query key
+	Records int    `json:"records"`           // number of records in block
+	Expire  string `json:"expire,omitempty"`  // expiration of block
+	File    string `json:"file,omitempty"`    // RRBLOCK file
+	RRBLOCK string `json:"rrblock,omitempty"` // block (base64-encoded)
+	Stored  bool   `json:"stored"`            // stored in DHT service
+}
+
+// Main runs the offline zone signing with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("sign-zone", flag.ExitOnError)
+	// handle command line arguments
+	var (
+		keyFile  string
+		cfgFile  string
+		socket   string
+		dumpFile string
+		outDir   string
+		format   string
+		put      bool
+		ttl      time.Duration
+		deadline time.Duration
+	)
+	fs.StringVar(&keyFile, "k", "", "file with private zone key (GNUnet identifier)")
+	fs.StringVar(&dumpFile, "o", "", "write blocks to DHT dump file (for 'gnunet-dht-go import')")
+	fs.StringVar(&outDir, "d", "", "write blocks to '<label>.rrblock' files in directory")
+	fs.BoolVar(&put, "put", false, "store blocks in a running DHT service")
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file (with '-put')")
+	fs.StringVar(&socket, "s", "", "DHT service socket (default: from configuration)")
+	fs.DurationVar(&ttl, "ttl", 24*time.Hour, "default lifetime of records")
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	fs.DurationVar(&deadline, "timeout", 30*time.Second, "timeout for storing blocks")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [options] <records file>\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() != 1 || len(keyFile) == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	// read private zone key
+	buf, err := os.ReadFile(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	zp, err := crypto.NewZonePrivateFromID(strings.TrimSpace(string(buf)))
+	if err != nil {
+		log.Fatalf("invalid zone key: %s", err.Error())
+	}
+	// read records and assemble record sets for labels
+	sets, err := readRecords(fs.Arg(0), ttl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// get DHT service socket
+	if put && len(socket) == 0 {
+		if err = config.ParseConfig(cfgFile); err != nil {
+			log.Fatalf("invalid configuration file: %s", err.Error())
+		}
+		if config.Cfg.DHT == nil || config.Cfg.DHT.Service == nil {
+			log.Fatal("no DHT service configured (-s)")
+		}
+		socket = config.Cfg.DHT.Service.Socket
+	}
+	// create dump file
+	var dump *json.Encoder
+	if len(dumpFile) > 0 {
+		f, err := os.Create(dumpFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		dump = json.NewEncoder(f)
+	}
+	// sign blocks for labels in sorted order
+	labels := make([]string, 0, len(sets))
+	for label := range sets {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		rs := sets[label]
+		if rs.Count == 0 {
+			if err = out.Emit(&SignedBlock{Label: label}, "%s: no public records -- skipped\n", label); err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		expire := rs.Expire()
+		blk, err := blocks.NewGNSBlockFromRecords(zp, label, rs, expire)
+		if err != nil {
+			log.Fatalf("%s: %s", label, err.Error())
+		}
+		key, err := blocks.GNSQueryKey(zp.Public(), label)
+		if err != nil {
+			log.Fatalf("%s: %s", label, err.Error())
+		}
+		rrblock := blk.RRBLOCK()
+		res := &SignedBlock{
+			Label:   label,
+			Key:     key.String(),
+			Records: int(rs.Count),
+			Expire:  expire.String(),
+			RRBLOCK: base64.StdEncoding.EncodeToString(rrblock),
+		}
+		// write RRBLOCK file
+		if len(outDir) > 0 {
+			res.File = filepath.Join(outDir, label+".rrblock")
+			if err = os.WriteFile(res.File, rrblock, 0o644); err != nil {
+				log.Fatal(err)
+			}
+		}
+		// write dump entry
+		if dump != nil {
+			err = dump.Encode(&dht.DumpEntry{
+				Key:    key.String(),
+				Type:   enums.BLOCK_TYPE_GNS_NAMERECORD,
+				Expire: expire.Val,
+				Block:  base64.StdEncoding.EncodeToString(blk.Bytes()),
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		// store in DHT
+		if put {
+			if err = storeDHT(socket, key, blk, deadline); err != nil {
+				log.Fatalf("%s: %s", label, err.Error())
+			}
+			res.Stored = true
+		}
+		if err = out.Emit(res, "%s: %d records, expires %s, key %s\n",
+			label, rs.Count, expire, key.Short()); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// storeDHT sends a block to the DHT service.
+func storeDHT(socket string, key *crypto.HashCode, blk *blocks.GNSBlock, deadline time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	req := message.NewDHTClientPutMsg(key, enums.BLOCK_TYPE_GNS_NAMERECORD, blk.Bytes())
+	req.Expire = blk.Expire()
+	_, err := service.RequestResponse(ctx, "sign-zone", "dht", socket, req, false)
+	return err
+}
+
+//----------------------------------------------------------------------
+// Records file
+//----------------------------------------------------------------------
+
+// readRecords reads a records file (JSON list of records) and returns
+// the record sets for all labels. Private records are not published and
+// are skipped. Records with relative expiration expire 'ttl' after
+// signing.
+func readRecords(fname string, ttl time.Duration) (sets map[string]*blocks.RecordSet, err error) {
+	var buf []byte
+	if buf, err = os.ReadFile(fname); err != nil {
+		return
+	}
+	var list []*Record
+	if err = json.Unmarshal(buf, &list); err != nil {
+		return
+	}
+	if len(list) == 0 {
+		return nil, ErrNoRecords
+	}
+	sets = make(map[string]*blocks.RecordSet)
+	for i, r := range list {
+		rs, ok := sets[r.Label]
+		if !ok {
+			rs = blocks.NewRecordSet()
+			sets[r.Label] = rs
+		}
+		var rec *blocks.ResourceRecord
+		if rec, err = r.resourceRecord(ttl); err != nil {
+			return nil, fmt.Errorf("record #%d (%s): %w", i+1, r.Label, err)
+		}
+		if rec.Flags&enums.GNS_FLAG_PRIVATE != 0 {
+			continue
+		}
+		rs.AddRecord(rec)
+	}
+	return
+}
+
+// resourceRecord converts a record from file to a resource record.
+func (r *Record) resourceRecord(ttl time.Duration) (rec *blocks.ResourceRecord, err error) {
+	rec = new(blocks.ResourceRecord)
+	if rec.RType, err = rr.ParseType(r.Type); err != nil {
+		return
+	}
+	// record data
+	switch {
+	case len(r.Data) > 0:
+		rec.Data, err = hex.DecodeString(r.Data)
+	case len(r.Value) == 0:
+		err = ErrNoValue
+	default:
+		rec.Data, err = rr.FromText(rec.RType, r.Value)
+	}
+	if err != nil {
+		return
+	}
+	rec.Size = uint16(len(rec.Data))
+
+	// expiration
+	switch {
+	case len(r.Expire) > 0:
+		var ts time.Time
+		if ts, err = time.Parse(time.RFC3339, r.Expire); err != nil {
+			return
+		}
+		rec.Expire = util.NewAbsoluteTime(ts)
+	case len(r.TTL) > 0:
+		var d time.Duration
+		if d, err = time.ParseDuration(r.TTL); err != nil {
+			return
+		}
+		rec.Expire = util.AbsoluteTimeNow().Add(d)
+	default:
+		rec.Expire = util.AbsoluteTimeNow().Add(ttl)
+	}
+	// flags
+	for _, f := range r.Flags {
+		switch strings.ToLower(f) {
+		case "private":
+			rec.Flags |= enums.GNS_FLAG_PRIVATE
+		case "shadow":
+			rec.Flags |= enums.GNS_FLAG_SHADOW
+		case "supplemental":
+			rec.Flags |= enums.GNS_FLAG_SUPPLEMENTAL
+		case "critical":
+			rec.Flags |= enums.GNS_FLAG_CRITICAL
+		default:
+			return nil, ErrBadFlag
+		}
+	}
+	return
+}
@@ -0,0 +1,170 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statisticssrv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/service"
+	"gnunet/service/statistics"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the STATISTICS service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("gnunet-service-statistics-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[statistics] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	logger.Println(logger.INFO, "[statistics] Starting service...")
+
+	var (
+		cfgFile  string
+		socket   string
+		param    string
+		file     string
+		err      error
+		logLevel int
+		rpcEndp  string
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&socket, "s", "", "STATISTICS service socket")
+	fs.StringVar(&param, "p", "", "socket parameters (<key>=<value>,...)")
+	fs.StringVar(&file, "f", "", "file for persistent values (default: from configuration)")
+	fs.IntVar(&logLevel, "L", logger.INFO, "STATISTICS log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+	if config.Cfg.Statistics == nil || config.Cfg.Statistics.Service == nil {
+		logger.Println(logger.ERROR, "[statistics] No statistics service configured")
+		return
+	}
+
+	// apply configuration
+	logger.SetLogLevel(logLevel)
+	if len(socket) == 0 {
+		socket = config.Cfg.Statistics.Service.Socket
+	}
+	if len(file) > 0 {
+		config.Cfg.Statistics.File = file
+	}
+	params := config.Cfg.Statistics.Service.Params
+	if len(param) > 0 {
+		params = make(map[string]string)
+		for _, p := range strings.Split(param, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 {
+				params[kv[0]] = kv[1]
+			}
+		}
+	}
+
+	// start a new STATISTICS service
+	ctx, cancel := context.WithCancel(context.Background())
+	sts := statistics.NewService(ctx, config.Cfg.Statistics)
+	srv := service.NewSocketHandler("statistics", sts)
+	srv.SetLimits(config.Cfg.Statistics.Service.Limits)
+	srv.SetSource(core.SourceLink(config.Cfg))
+	if err = srv.Start(ctx, socket, params); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] Error: '%s'\n", err.Error())
+		cancel()
+		return
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[statistics] RPC must have a TCP/IP endpoint")
+			cancel()
+			return
+		}
+		if config.Cfg.RPC == nil {
+			config.Cfg.RPC = new(config.RPCConfig)
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if config.Cfg.RPC != nil && len(config.Cfg.RPC.Endpoint) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, config.Cfg.RPC.Endpoint); err != nil {
+			logger.Printf(logger.ERROR, "[statistics] RPC failed to start: %s", err.Error())
+			cancel()
+			return
+		}
+		sts.InitRPC(rpc)
+		service.InitLimitsRPC(rpc)
+		service.InitMaintenanceRPC(rpc)
+		service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+		service.InitConfigRPC(rpc, config.Cfg)
+	}
+
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "statistics:stats", service.StatsPeriod, service.StatsJob("statistics")); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] statistics not scheduled: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[statistics] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[statistics] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[statistics] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+
+	// terminating service
+	cancel()
+	if err := srv.Stop(); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] Failed to stop service: %s", err.Error())
+	}
+}
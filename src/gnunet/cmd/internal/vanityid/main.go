@@ -0,0 +1,80 @@
+package vanityid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+)
+
+// Match is the JSON output schema for a matching key
+type Match struct {
+	ID        string `json:"id"`        // peer id
+	Seed      string `json:"seed"`      // hex-encoded seed
+	Scalar    string `json:"scalar"`    // hex-encoded private scalar
+	Tries     int    `json:"tries"`     // number of keys generated
+	ElapsedMs int64  `json:"elapsedMs"` // time elapsed (in milliseconds)
+}
+
+// Main runs the vanity peer ID generator with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("vanityid", flag.ExitOnError)
+	// get arguments
+	var format string
+	fs.StringVar(&format, "output", util.OutputText, "output format (text, json)")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+	out, err := util.NewOutput(format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prefixes := fs.Args()
+	num := len(prefixes)
+	if num == 0 {
+		log.Println("No prefixes specified -- done.")
+		return
+	}
+
+	// pre-compile regexp
+	reg := make([]*regexp.Regexp, num)
+	for i, p := range prefixes {
+		reg[i] = regexp.MustCompile(p)
+	}
+
+	// generate new keys in a loop
+	seed := make([]byte, 32)
+	start := time.Now()
+	for i := 0; ; i++ {
+		_, _ = rand.Read(seed)
+		prv := ed25519.NewPrivateKeyFromSeed(seed)
+		pub := prv.Public().Bytes()
+		id := util.EncodeBinaryToString(pub)
+		for _, r := range reg {
+			if r.MatchString(id) {
+				elapsed := time.Since(start)
+				s1 := hex.EncodeToString(seed)
+				s2 := hex.EncodeToString(prv.D.Bytes())
+				m := &Match{
+					ID:        id,
+					Seed:      s1,
+					Scalar:    s2,
+					Tries:     i,
+					ElapsedMs: elapsed.Milliseconds(),
+				}
+				if err = out.Emit(m, "%s [%s][%s] (%d tries, %s elapsed)\n", id, s1, s2, i, elapsed); err != nil {
+					log.Fatal(err)
+				}
+				i = 0
+				start = time.Now()
+			}
+		}
+	}
+}
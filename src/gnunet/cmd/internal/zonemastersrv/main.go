@@ -0,0 +1,262 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemastersrv
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/script"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/rr"
+	"gnunet/service/metrics"
+	"gnunet/service/statistics"
+	"gnunet/service/store"
+	"gnunet/service/zonemaster"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Main runs the zonemaster service with given command line arguments.
+func Main(args []string) {
+	fs := flag.NewFlagSet("zonemaster-go", flag.ExitOnError)
+	defer func() {
+		logger.Println(logger.INFO, "[zonemaster] Bye.")
+		// flush last messages
+		logger.Flush()
+	}()
+	// intro
+	logger.SetLogLevel(logger.DBG)
+	logger.Println(logger.INFO, "[zonemaster] Starting service...")
+
+	var (
+		cfgFile  string
+		gui      string
+		err      error
+		logLevel int
+		rpcEndp  string
+		export   string
+		imp      string
+		zoneFile string
+		zone     string
+		add      string
+		line     string
+		list     string
+	)
+	// handle command line arguments
+	fs.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
+	fs.StringVar(&gui, "g", "", "GUI listen address")
+	fs.IntVar(&logLevel, "L", logger.INFO, "zonemaster log level (default: INFO)")
+	fs.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
+	fs.StringVar(&export, "export", "", "export zone to zonefile and exit")
+	fs.StringVar(&imp, "import", "", "import zonefile and exit")
+	fs.StringVar(&zoneFile, "f", "", "zonefile for export (default: <zone>.zone)")
+	fs.StringVar(&zone, "z", "", "zone for import (default: $ORIGIN of zonefile) or added record")
+	fs.StringVar(&add, "add", "", "add record (-r) to label in zone (-z) and exit")
+	fs.StringVar(&line, "r", "", "record line for -add (e.g. \"A 1.2.3.4 3600 [private]\")")
+	fs.StringVar(&list, "list", "", "list records of zone (as record lines) and exit")
+	if err := fs.Parse(args); err != nil {
+		return
+	}
+
+	// read configuration file and set missing arguments.
+	if err = config.ParseConfig(cfgFile); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] Invalid configuration file: %s\n", err.Error())
+		return
+	}
+	service.Maintenance.Configure(config.Cfg.Maintenance)
+
+	// apply configuration
+	if config.Cfg.Logging.Level > 0 {
+		logLevel = config.Cfg.Logging.Level
+	}
+	logger.SetLogLevel(logLevel)
+	if len(gui) > 0 {
+		config.Cfg.ZoneMaster.GUI = gui
+	}
+	if err = script.Setup(config.Cfg.Scripts); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] Failed to load scripts: %s\n", err.Error())
+		return
+	}
+
+	// start services under zonemaster umbrella
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := zonemaster.NewService(ctx, nil, config.Cfg, config.Cfg.ZoneMaster.PlugIns)
+
+	// handle zonefile import/export (no service started)
+	if len(export) > 0 || len(imp) > 0 {
+		if err = zonefile(srv, export, imp, zoneFile, zone); err != nil {
+			logger.Printf(logger.ERROR, "[zonemaster] zonefile: %s", err.Error())
+		}
+		return
+	}
+	// handle record commands (no service started)
+	if len(add) > 0 || len(list) > 0 {
+		if err = records(srv, add, line, zone, list); err != nil {
+			logger.Printf(logger.ERROR, "[zonemaster] records: %s", err.Error())
+		}
+		return
+	}
+	go srv.Run(ctx)
+
+	// start UDS listener if service is specified
+	if config.Cfg.ZoneMaster.Service != nil {
+		sockHdlr := service.NewSocketHandler("zonemaster", srv)
+		sockHdlr.SetLimits(config.Cfg.ZoneMaster.Service.Limits)
+		sockHdlr.SetSource(core.SourceLink(config.Cfg))
+		if err = sockHdlr.Start(ctx, config.Cfg.ZoneMaster.Service.Socket, config.Cfg.ZoneMaster.Service.Params); err != nil {
+			logger.Printf(logger.ERROR, "[zonemaster] Error: '%s'", err.Error())
+			_ = sockHdlr.Stop()
+		}
+	}
+
+	// handle command-line arguments for RPC
+	if len(rpcEndp) > 0 {
+		parts := strings.Split(rpcEndp, ":")
+		if parts[0] != "tcp" {
+			logger.Println(logger.ERROR, "[zonemaster] RPC must have a TCP/IP endpoint")
+			return
+		}
+		config.Cfg.RPC.Endpoint = parts[1]
+	}
+	// start JSON-RPC server on request
+	if ep := config.Cfg.RPC.Endpoint; len(ep) > 0 {
+		var rpc *service.JRPCServer
+		if rpc, err = service.RunRPCServer(ctx, ep); err != nil {
+			logger.Printf(logger.ERROR, "[zonemaster] RPC failed to start: %s", err.Error())
+		} else {
+			srv.InitRPC(rpc)
+			service.InitLimitsRPC(rpc)
+			service.InitMaintenanceRPC(rpc)
+			service.InitHealthRPC(rpc, config.Cfg.RPC.Pprof)
+			service.InitConfigRPC(rpc, config.Cfg)
+		}
+	}
+	// log service statistics periodically
+	if err = service.Schedule(ctx, "zonemaster:stats", service.StatsPeriod, service.StatsJob("zonemaster")); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] statistics not scheduled: %s", err.Error())
+	}
+	// check alert rules periodically
+	if err = service.StartAlerts(ctx, "zonemaster", config.Cfg.Alerts); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] alerts not started: %s", err.Error())
+	}
+	// serve metrics (if configured)
+	if err = metrics.Start(ctx, "zonemaster", config.Cfg.Metrics); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] metrics not served: %s", err.Error())
+	}
+	// publish counters to the statistics service (if configured)
+	if err = statistics.StartPublisher(ctx, "zonemaster", config.Cfg.Statistics); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] statistics not published: %s", err.Error())
+	}
+
+	// handle OS signals
+	sigCh := make(chan os.Signal, 5)
+	signal.Notify(sigCh)
+
+loop:
+	for {
+		select {
+		// handle OS signals
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
+				logger.Printf(logger.INFO, "[zonemaster] Terminating service (on signal '%s')\n", sig)
+				break loop
+			case syscall.SIGHUP:
+				logger.Println(logger.INFO, "[zonemaster] SIGHUP")
+			case syscall.SIGURG:
+				// TODO: https://github.com/golang/go/issues/37942
+			default:
+				logger.Println(logger.INFO, "[zonemaster] Unhandled signal: "+sig.String())
+			}
+		}
+	}
+	// terminating service
+	cancel()
+}
+
+// zonefile exports a zone to a zonefile or imports a zonefile into a
+// zone in the zone database.
+func zonefile(srv *zonemaster.ZoneMaster, export, imp, fname, zone string) (err error) {
+	if err = srv.OpenDatabase(); err != nil {
+		return
+	}
+	defer srv.CloseDatabase()
+
+	var n int
+	if len(export) > 0 {
+		// (log messages go to stdout)
+		if len(fname) == 0 {
+			fname = export + ".zone"
+		}
+		var out *os.File
+		if out, err = os.Create(fname); err != nil {
+			return
+		}
+		defer out.Close()
+		if n, err = srv.ExportZone(export, out); err == nil {
+			logger.Printf(logger.INFO, "[zonemaster] %d records of zone '%s' exported to '%s'", n, export, fname)
+		}
+		return
+	}
+	var in *os.File
+	if in, err = os.Open(imp); err != nil {
+		return
+	}
+	defer in.Close()
+	if n, err = srv.ImportZone(zone, in); err == nil {
+		logger.Printf(logger.INFO, "[zonemaster] %d records imported from '%s'", n, imp)
+	}
+	return
+}
+
+// records adds a record (given as record line) to a label in a zone or
+// lists the records of a zone (as record lines).
+func records(srv *zonemaster.ZoneMaster, label, line, zone, list string) (err error) {
+	if err = srv.OpenDatabase(); err != nil {
+		return
+	}
+	defer srv.CloseDatabase()
+
+	if len(list) > 0 {
+		_, err = srv.ListZone(list, os.Stdout)
+		return
+	}
+	if len(zone) == 0 {
+		return errors.New("no zone (-z) for record")
+	}
+	var rec *blocks.ResourceRecord
+	if rec, err = rr.ParseRecordLine(line); err != nil {
+		return
+	}
+	var n int
+	if n, err = srv.AddRecords(zone, label, store.NewRecord(rec.Expire, rec.RType, rec.Flags, rec.Data)); err == nil {
+		logger.Printf(logger.INFO, "[zonemaster] %d record(s) added to '%s' in zone '%s'", n, label, zone)
+	}
+	return
+}
@@ -1,51 +1,33 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build !multicall
+
 package main
 
 import (
-	"crypto/rand"
-	"encoding/hex"
-	"flag"
-	"fmt"
-	"regexp"
-	"time"
-
-	"gnunet/util"
+	"os"
 
-	"github.com/bfix/gospel/crypto/ed25519"
+	"gnunet/cmd/internal/vanityid"
 )
 
+// vanityid: vanity peer ID generator (see package 'gnunet/cmd/internal/vanityid').
+// The command is an applet of 'gnunet-go' in multi-call builds.
 func main() {
-	// get arguments
-	flag.Parse()
-	prefixes := flag.Args()
-	num := len(prefixes)
-	if num == 0 {
-		fmt.Println("No prefixes specified -- done.")
-		return
-	}
-
-	// pre-compile regexp
-	reg := make([]*regexp.Regexp, num)
-	for i, p := range prefixes {
-		reg[i] = regexp.MustCompile(p)
-	}
-
-	// generate new keys in a loop
-	seed := make([]byte, 32)
-	start := time.Now()
-	for i := 0; ; i++ {
-		_, _ = rand.Read(seed)
-		prv := ed25519.NewPrivateKeyFromSeed(seed)
-		pub := prv.Public().Bytes()
-		id := util.EncodeBinaryToString(pub)
-		for _, r := range reg {
-			if r.MatchString(id) {
-				elapsed := time.Since(start)
-				s1 := hex.EncodeToString(seed)
-				s2 := hex.EncodeToString(prv.D.Bytes())
-				fmt.Printf("%s [%s][%s] (%d tries, %s elapsed)\n", id, s1, s2, i, elapsed)
-				i = 0
-				start = time.Now()
-			}
-		}
-	}
+	vanityid.Main(os.Args[1:])
 }
@@ -16,123 +16,18 @@
 //
 // SPDX-License-Identifier: AGPL3.0-or-later
 
+//go:build !multicall
+
 package main
 
 import (
-	"context"
-	"flag"
 	"os"
-	"os/signal"
-	"strings"
-	"syscall"
-	"time"
-
-	"gnunet/config"
-	"gnunet/service"
-	"gnunet/service/zonemaster"
 
-	"github.com/bfix/gospel/logger"
+	"gnunet/cmd/internal/zonemastersrv"
 )
 
+// zonemaster-go: zonemaster service (see package 'gnunet/cmd/internal/zonemastersrv').
+// The command is an applet of 'gnunet-go' in multi-call builds.
 func main() {
-	defer func() {
-		logger.Println(logger.INFO, "[zonemaster] Bye.")
-		// flush last messages
-		logger.Flush()
-	}()
-	// intro
-	logger.SetLogLevel(logger.DBG)
-	logger.Println(logger.INFO, "[zonemaster] Starting service...")
-
-	var (
-		cfgFile  string
-		gui      string
-		err      error
-		logLevel int
-		rpcEndp  string
-	)
-	// handle command line arguments
-	flag.StringVar(&cfgFile, "c", "gnunet-config.json", "GNUnet configuration file")
-	flag.StringVar(&gui, "g", "", "GUI listen address")
-	flag.IntVar(&logLevel, "L", logger.INFO, "zonemaster log level (default: INFO)")
-	flag.StringVar(&rpcEndp, "R", "", "JSON-RPC endpoint (default: none)")
-	flag.Parse()
-
-	// read configuration file and set missing arguments.
-	if err = config.ParseConfig(cfgFile); err != nil {
-		logger.Printf(logger.ERROR, "[zonemaster] Invalid configuration file: %s\n", err.Error())
-		return
-	}
-
-	// apply configuration
-	if config.Cfg.Logging.Level > 0 {
-		logLevel = config.Cfg.Logging.Level
-	}
-	logger.SetLogLevel(logLevel)
-	if len(gui) > 0 {
-		config.Cfg.ZoneMaster.GUI = gui
-	}
-
-	// start services under zonemaster umbrella
-	ctx, cancel := context.WithCancel(context.Background())
-	srv := zonemaster.NewService(ctx, nil, config.Cfg.ZoneMaster.PlugIns)
-	go srv.Run(ctx)
-
-	// start UDS listener if service is specified
-	if config.Cfg.ZoneMaster.Service != nil {
-		sockHdlr := service.NewSocketHandler("zonemaster", srv)
-		if err = sockHdlr.Start(ctx, config.Cfg.ZoneMaster.Service.Socket, config.Cfg.ZoneMaster.Service.Params); err != nil {
-			logger.Printf(logger.ERROR, "[zonemaster] Error: '%s'", err.Error())
-			_ = sockHdlr.Stop()
-		}
-	}
-
-	// handle command-line arguments for RPC
-	if len(rpcEndp) > 0 {
-		parts := strings.Split(rpcEndp, ":")
-		if parts[0] != "tcp" {
-			logger.Println(logger.ERROR, "[zonemaster] RPC must have a TCP/IP endpoint")
-			return
-		}
-		config.Cfg.RPC.Endpoint = parts[1]
-	}
-	// start JSON-RPC server on request
-	if ep := config.Cfg.RPC.Endpoint; len(ep) > 0 {
-		var rpc *service.JRPCServer
-		if rpc, err = service.RunRPCServer(ctx, ep); err != nil {
-			logger.Printf(logger.ERROR, "[zonemaster] RPC failed to start: %s", err.Error())
-		} else {
-			srv.InitRPC(rpc)
-		}
-	}
-	// handle OS signals
-	sigCh := make(chan os.Signal, 5)
-	signal.Notify(sigCh)
-
-	// heart beat
-	tick := time.NewTicker(5 * time.Minute)
-
-loop:
-	for {
-		select {
-		// handle OS signals
-		case sig := <-sigCh:
-			switch sig {
-			case syscall.SIGKILL, syscall.SIGINT, syscall.SIGTERM:
-				logger.Printf(logger.INFO, "[zonemaster] Terminating service (on signal '%s')\n", sig)
-				break loop
-			case syscall.SIGHUP:
-				logger.Println(logger.INFO, "[zonemaster] SIGHUP")
-			case syscall.SIGURG:
-				// TODO: https://github.com/golang/go/issues/37942
-			default:
-				logger.Println(logger.INFO, "[zonemaster] Unhandled signal: "+sig.String())
-			}
-		// handle heart beat
-		case now := <-tick.C:
-			logger.Println(logger.INFO, "[zonemaster] Heart beat at "+now.String())
-		}
-	}
-	// terminating service
-	cancel()
+	zonemastersrv.Main(os.Args[1:])
 }
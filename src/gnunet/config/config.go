@@ -41,19 +41,72 @@ type EndpointConfig struct {
 	Address string `json:"address"` // address to listen on
 	Port    int    `json:"port"`    // port for listening to network
 	TTL     int    `json:"ttl"`     // time-to-live for address (in seconds)
+	Class   string `json:"class"`   // address class (default: derived from address)
+
+	// optional fault injection on endpoint (chaos testing)
+	Faults *util.FaultConfig `json:"faults,omitempty"`
+
+	// optional certificate settings (HTTPS endpoints)
+	TLS *util.TLSConfig `json:"tls,omitempty"`
+
+	// optional fragmentation settings (UDP endpoints)
+	Fragments *util.FragmentConfig `json:"fragments,omitempty"`
 }
 
 // Addr returns an address string for endpoint configuration; it does NOT
-// handle special cases like UPNP and such.
+// handle special cases like UPNP and such. Unix domain socket endpoints
+// have a path as address (and no port).
 func (c *EndpointConfig) Addr() string {
+	if c.Network == "unix" {
+		return "unix://" + c.Address
+	}
 	return fmt.Sprintf("%s://%s:%d", c.Network, c.Address, c.Port)
 }
 
+// AddressClassConfig holds lifetime parameters for a class of addresses
+// (like "loopback", "private" or "public").
+type AddressClassConfig struct {
+	HelloTTL int `json:"helloTTL"` // lifetime of own addresses in HELLOs (in seconds)
+	MaxTTL   int `json:"maxTTL"`   // max. lifetime of learned addresses (in seconds)
+}
+
+// AddressPolicyConfig holds parameters for the address lifetime policy.
+type AddressPolicyConfig struct {
+	MaxSkew int                            `json:"maxSkew"` // tolerated clock skew of peers (in seconds)
+	Refresh float64                        `json:"refresh"` // refresh if remaining lifetime is below this fraction
+	Classes map[string]*AddressClassConfig `json:"classes"` // per-class lifetimes
+	Relays  []string                       `json:"relays"`  // networks (CIDR) or IPs of relays/proxies
+}
+
+// ConnectionConfig holds limits for connections to other peers. Peers
+// in the same subnet are those with a common address prefix.
+type ConnectionConfig struct {
+	MaxPeers     int `json:"maxPeers"`     // max. number of connected peers (0 = unlimited)
+	MaxPerSubnet int `json:"maxPerSubnet"` // max. number of connected peers per subnet (0 = unlimited)
+	PrefixV4     int `json:"prefixV4"`     // prefix length of IPv4 subnets (default: 24)
+	PrefixV6     int `json:"prefixV6"`     // prefix length of IPv6 subnets (default: 48)
+	IdleTimeout  int `json:"idleTimeout"`  // disconnect peers without traffic (seconds; 0 = never)
+}
+
+// BandwidthConfig holds the default bandwidth quotas for connected peers
+// (in bytes per second). The inbound quota is announced to peers; a
+// peer can announce a lower quota for traffic sent to it.
+type BandwidthConfig struct {
+	QuotaIn       int `json:"quotaIn"`       // max. inbound rate per peer (0 = unlimited)
+	QuotaOut      int `json:"quotaOut"`      // max. outbound rate per peer (0 = unlimited)
+	Burst         int `json:"burst"`         // traffic at full quota allowed in a burst (seconds; default: 5)
+	MaxViolations int `json:"maxViolations"` // disconnect peers after dropped messages (0 = never)
+}
+
 // NodeConfig holds parameters for the local node instance
 type NodeConfig struct {
-	Name        string            `json:"name"`        // (short) name for local node
-	PrivateSeed string            `json:"privateSeed"` // Node private key seed (base64)
-	Endpoints   []*EndpointConfig `json:"endpoints"`   // list of endpoints available
+	Name        string               `json:"name"`                  // (short) name for local node
+	PrivateSeed string               `json:"privateSeed"`           // Node private key seed (base64)
+	Endpoints   []*EndpointConfig    `json:"endpoints"`             // list of endpoints available
+	Policy      *AddressPolicyConfig `json:"policy,omitempty"`      // address lifetime policy
+	Connections *ConnectionConfig    `json:"connections,omitempty"` // connection limits
+	Bandwidth   *BandwidthConfig     `json:"bandwidth,omitempty"`   // bandwidth quotas
+	NAT         *util.NATConfig      `json:"nat,omitempty"`         // NAT traversal
 }
 
 //----------------------------------------------------------------------
@@ -62,8 +115,20 @@ type NodeConfig struct {
 
 // NetworkConfig holds parameters for the initial connection to the network.
 type NetworkConfig struct {
-	Bootstrap []string `json:"bootstrap"` // bootstrap nodes
-	NumPeers  int      `json:"numPeers"`  // estimated number of peers (0 = use NSE)
+	Bootstrap     []string `json:"bootstrap"`               // bootstrap nodes
+	NumPeers      int      `json:"numPeers"`                // estimated number of peers (0 = use NSE)
+	BootCache     string   `json:"bootCache,omitempty"`     // file for cached HELLOs of other peers
+	BootCacheSize int      `json:"bootCacheSize,omitempty"` // max. number of cached HELLOs
+	LocalOnly     bool     `json:"localOnly,omitempty"`     // no traffic to non-local addresses
+}
+
+//----------------------------------------------------------------------
+// Core configuration
+//----------------------------------------------------------------------
+
+// CoreConfig contains parameters for the core service
+type CoreConfig struct {
+	Service *ServiceConfig `json:"service"` // socket for Core service
 }
 
 //----------------------------------------------------------------------
@@ -72,7 +137,8 @@ type NetworkConfig struct {
 
 // RPCConfig contains parameters for the JSON-RPC service
 type RPCConfig struct {
-	Endpoint string `json:"endpoint"` // endpoint for JSON-RPC service
+	Endpoint string `json:"endpoint"`        // endpoint for JSON-RPC service
+	Pprof    string `json:"pprof,omitempty"` // endpoint for profiling (switched on by RPC)
 }
 
 //----------------------------------------------------------------------
@@ -80,8 +146,56 @@ type RPCConfig struct {
 //----------------------------------------------------------------------
 
 type ServiceConfig struct {
-	Socket string            `json:"socket"` // socket file name
-	Params map[string]string `json:"params"` // socket parameters
+	Socket string            `json:"socket"`           // socket file name
+	Params map[string]string `json:"params"`           // socket parameters
+	Limits *LimitConfig      `json:"limits,omitempty"` // resource limits (unlimited if undefined)
+}
+
+// LimitConfig holds soft limits on resources used by a service. A limit
+// of zero (or less) means "unlimited". If a limit is reached, new sessions
+// or requests are either rejected or queued until resources are available.
+type LimitConfig struct {
+	MaxSessions int    `json:"maxSessions"` // max. number of client sessions (goroutines)
+	MaxRequests int    `json:"maxRequests"` // max. number of active requests
+	MaxCache    int    `json:"maxCache"`    // max. memory for in-memory caches (in kB)
+	Mode        string `json:"mode"`        // enforcement: "reject" (default) or "queue"
+}
+
+// MaintenanceConfig holds settings for periodic maintenance jobs of the
+// services. Job periods (in seconds) override the defaults of the named
+// jobs; a period of zero (or less) disables a job.
+type MaintenanceConfig struct {
+	Jitter  float64        `json:"jitter"`  // random deviation of periods (fraction, default 0.1)
+	Periods map[string]int `json:"periods"` // period overrides by job name
+}
+
+// AlertConfig holds alert rules on service metrics. Rules are checked
+// every 'period' seconds; violations (and their end) are logged and
+// posted to the webhook URL (if set).
+type AlertConfig struct {
+	Period  int          `json:"period"`            // check period (seconds, default 60)
+	Webhook string       `json:"webhook,omitempty"` // URL for notifications
+	Rules   []*AlertRule `json:"rules"`             // alert rules
+}
+
+// AlertRule defines a threshold for a metric: the rule is violated if
+// the metric compares ('<' or '>') to the threshold for the whole window
+// (in seconds). If 'per' names a counter, the metric is the ratio of the
+// increments of both counters within the window.
+type AlertRule struct {
+	Name      string  `json:"name"`          // name of alert
+	Metric    string  `json:"metric"`        // name of metric (like "core:peers")
+	Per       string  `json:"per,omitempty"` // counter for ratio (like "dht:get")
+	Op        string  `json:"op"`            // comparison: "<" or ">"
+	Threshold float64 `json:"threshold"`     // threshold value
+	Window    int     `json:"window"`        // observation window (seconds)
+}
+
+// MetricsConfig holds the listen addresses of the metrics endpoints of
+// services (like "dht": "127.0.0.1:9101"); metrics are served in the
+// Prometheus text format on path "/metrics".
+type MetricsConfig struct {
+	Endpoints map[string]string `json:"endpoints"` // service name -> listen address
 }
 
 //----------------------------------------------------------------------
@@ -90,9 +204,55 @@ type ServiceConfig struct {
 
 // GNSConfig contains parameters for the GNU Name System service
 type GNSConfig struct {
-	Service   *ServiceConfig `json:"service"`   // socket for GNS service
-	ReplLevel int            `json:"replLevel"` // DHT replication level
-	MaxDepth  int            `json:"maxDepth"`  // maximum recursion depth in resolution
+	Service   *ServiceConfig  `json:"service"`             // socket for GNS service
+	ReplLevel int             `json:"replLevel"`           // DHT replication level
+	MaxDepth  int             `json:"maxDepth"`            // maximum recursion depth in resolution
+	NegCache  *NegCacheConfig `json:"negCache,omitempty"`  // cache for failed lookups
+	CacheSize int             `json:"cacheSize,omitempty"` // number of resolved blocks kept in memory (0 = none)
+
+	// start zones for names without zTLD: maps a TLD to a zone key
+	// (zTLD); with 'egos' set, names of local egos are TLDs too.
+	StartZones map[string]string `json:"startZones,omitempty"`
+	Egos       bool              `json:"egos,omitempty"`
+
+	// resolution in DNS (GNS2DNS delegation and names outside of GNS)
+	DNS *DNSConfig `json:"dns,omitempty"`
+}
+
+// DNSConfig contains parameters for queries to DNS servers: names outside
+// of GNS are resolved by the listed upstream resolvers; names delegated to
+// DNS (GNS2DNS) by the nameservers named in the records. Answers from all
+// queried servers are merged.
+type DNSConfig struct {
+	Resolvers []string `json:"resolvers,omitempty"` // upstream resolvers ("addr[:port]")
+	Timeout   int      `json:"timeout,omitempty"`   // time limit for a resolution (seconds)
+	Retries   int      `json:"retries,omitempty"`   // attempts per server
+}
+
+// NegCacheConfig contains parameters for the GNS negative cache
+type NegCacheConfig struct {
+	TTL     int               `json:"ttl"`     // lifetime of entries (in seconds)
+	Storage util.ParameterSet `json:"storage"` // persistence mechanism (in-memory if undefined)
+}
+
+// DNS2GNSConfig contains parameters for the DNS-to-GNS gateway: DNS
+// queries for names under the listed TLDs (and for zTLDs) are resolved
+// in GNS; other queries are forwarded to the upstream DNS server (or
+// refused if none is set).
+type DNS2GNSConfig struct {
+	Listen   string   `json:"listen"`             // listen address (UDP and TCP)
+	TLDs     []string `json:"tlds"`               // TLDs resolved in GNS
+	Upstream string   `json:"upstream,omitempty"` // DNS server for other names
+	Timeout  int      `json:"timeout,omitempty"`  // GNS lookup timeout (seconds)
+}
+
+// RESTConfig contains parameters for the REST gateway: GNS lookups,
+// namestore records and identities (egos) are accessible over HTTP with
+// JSON-encoded data.
+type RESTConfig struct {
+	Listen  string `json:"listen"`            // listen address (HTTP)
+	Timeout int    `json:"timeout,omitempty"` // time limit for service requests (seconds)
+	Origin  string `json:"origin,omitempty"`  // allowed origin of cross-origin requests
 }
 
 // ZoneMasterConfig contains parameters for the GNS ZoneMaster process
@@ -102,6 +262,23 @@ type ZoneMasterConfig struct {
 	Storage util.ParameterSet `json:"storage"` // persistence mechanism for zone data
 	GUI     string            `json:"gui"`     // listen address for HTTP GUI
 	PlugIns []string          `json:"plugins"` // list of plugins to load
+	Verify  bool              `json:"verify"`  // confirm blocks stored in the DHT
+
+	// default lifetime (in seconds) of records stored without expiration
+	// by record type name ("A", "PKEY", ...; "*" for all other types)
+	DefaultTTL map[string]int `json:"defaultTTL,omitempty"`
+
+	// adaptive republish intervals (optional)
+	Adaptive *AdaptiveConfig `json:"adaptive,omitempty"`
+}
+
+// AdaptiveConfig holds the bounds for adaptive republish intervals: the
+// interval grows while published blocks are still found in the DHT when
+// they are due, and shrinks if they are not.
+type AdaptiveConfig struct {
+	MinPeriod int     `json:"minPeriod"` // shortest republish interval (seconds)
+	MaxPeriod int     `json:"maxPeriod"` // longest republish interval (seconds)
+	Target    float64 `json:"target"`    // availability target (0..1)
 }
 
 //----------------------------------------------------------------------
@@ -110,16 +287,53 @@ type ZoneMasterConfig struct {
 
 // DHTConfig contains parameters for the distributed hash table (DHT)
 type DHTConfig struct {
-	Service   *ServiceConfig    `json:"service"`   // socket for DHT service
-	Storage   util.ParameterSet `json:"storage"`   // filesystem storage location
-	Routing   *RoutingConfig    `json:"routing"`   // routing table configuration
-	Heartbeat int               `json:"heartbeat"` // heartbeat intervall
+	Service     *ServiceConfig     `json:"service"`              // socket for DHT service
+	Storage     util.ParameterSet  `json:"storage"`              // filesystem storage location
+	Routing     *RoutingConfig     `json:"routing"`              // routing table configuration
+	Replication *ReplicationConfig `json:"replication"`          // block replication to new peers
+	Heartbeat   int                `json:"heartbeat"`            // heartbeat intervall
+	MaxTTL      int                `json:"maxTTL,omitempty"`     // max. expiration of client PUTs (seconds)
+	GC          *DHTGCConfig       `json:"gc,omitempty"`         // garbage collection of stored blocks
+	Tracing     *DHTTraceConfig    `json:"tracing,omitempty"`    // sampled request traces
+	HelloCache  *HelloCacheConfig  `json:"helloCache,omitempty"` // tiered HELLO cache
+}
+
+// HelloCacheConfig holds parameters for the tiered HELLO cache: up to
+// 'size' HELLOs are kept in memory; less recently used HELLOs are moved
+// to the (optional) storage and survive restarts.
+type HelloCacheConfig struct {
+	Size    int               `json:"size,omitempty"` // max. number of HELLOs in memory
+	Storage util.ParameterSet `json:"storage"`        // persistent tier (none if undefined)
+}
+
+// DHTTraceConfig holds parameters for the sampling of GET and PUT request
+// traces. A rate of 0.01 traces one request in a hundred (no tracing if
+// the rate is zero).
+type DHTTraceConfig struct {
+	Rate float64 `json:"rate"` // fraction of traced requests
+	Size int     `json:"size"` // max. number of kept traces
 }
 
 // RoutingConfig holds parameters for routing tables
 type RoutingConfig struct {
-	PeerTTL   int `json:"peerTTL"`   // time-out for peers in table
-	ReplLevel int `json:"replLevel"` // replication level
+	PeerTTL   int `json:"peerTTL"`         // time-out for peers in table
+	ReplLevel int `json:"replLevel"`       // replication level
+	Alpha     int `json:"alpha,omitempty"` // parallel branches of first-result lookups
+}
+
+// ReplicationConfig holds parameters for the replication of stored blocks
+// to new peers that are closer to the block keys than the local node.
+type ReplicationConfig struct {
+	BatchSize int `json:"batchSize"` // number of blocks sent in one batch
+	Rate      int `json:"rate"`      // max. number of blocks sent per second
+}
+
+// DHTGCConfig holds parameters for the removal of expired blocks from the
+// DHT store. Lifetimes are keyed by block type (number or name like
+// "GNS_NAMERECORD") and limit the time a block is kept after it was stored.
+type DHTGCConfig struct {
+	Period    int            `json:"period"`              // time between GC runs (seconds)
+	Lifetimes map[string]int `json:"lifetimes,omitempty"` // max. lifetime per block type (seconds)
 }
 
 //----------------------------------------------------------------------
@@ -132,14 +346,111 @@ type NamecacheConfig struct {
 	Storage util.ParameterSet `json:"storage"` // key/value cache
 }
 
+//----------------------------------------------------------------------
+// Peerstore configuration
+//----------------------------------------------------------------------
+
+// PeerstoreConfig contains parameters for the store of peer metadata
+// (HELLOs, addresses, connection metrics) shared between services.
+type PeerstoreConfig struct {
+	Service *ServiceConfig    `json:"service"` // socket for Peerstore service
+	Storage util.ParameterSet `json:"storage"` // persistence backend for records
+}
+
+//----------------------------------------------------------------------
+// Resolver configuration
+//----------------------------------------------------------------------
+
+// ResolverConfig contains parameters for the RESOLVER service (DNS stub
+// backed by the system resolver). Results are cached for 'ttl' seconds,
+// failed lookups for 'negTTL' seconds.
+type ResolverConfig struct {
+	Service *ServiceConfig `json:"service"`           // socket for RESOLVER service
+	Timeout int            `json:"timeout,omitempty"` // time limit for a lookup (seconds)
+	TTL     int            `json:"ttl,omitempty"`     // lifetime of cached results (seconds)
+	NegTTL  int            `json:"negTTL,omitempty"`  // lifetime of cached failures (seconds)
+}
+
+//----------------------------------------------------------------------
+// Statistics configuration
+//----------------------------------------------------------------------
+
+// StatisticsConfig contains parameters for the STATISTICS service.
+// Persistent values are kept in a file across restarts. Services publish
+// their event counters to the statistics service every 'publish' seconds
+// (if set).
+type StatisticsConfig struct {
+	Service *ServiceConfig `json:"service"`           // socket for STATISTICS service
+	File    string         `json:"file,omitempty"`    // file for persistent values
+	Publish int            `json:"publish,omitempty"` // period for publishing counters (seconds; 0 = off)
+}
+
 //----------------------------------------------------------------------
 // Revocation configuration
 //----------------------------------------------------------------------
 
 // RevocationConfig contains parameters for the key revocation service
 type RevocationConfig struct {
-	Service *ServiceConfig    `json:"service"` // socket for Revocation service
-	Storage util.ParameterSet `json:"storage"` // persistence mechanism for revocation data
+	Service *ServiceConfig    `json:"service"`          // socket for Revocation service
+	Storage util.ParameterSet `json:"storage"`          // persistence mechanism for revocation data
+	Filter  string            `json:"filter,omitempty"` // file for bloomfilter of revoked keys (shared with GNS)
+	Policy  *RevocationPolicy `json:"policy,omitempty"` // difficulty policy for revocations
+}
+
+// RevocationPolicy defines the difficulty (number of leading zero bits
+// of the PoWs) of revocations. Tools select the difficulty of new
+// revocations as max(minDifficulty,avgDifficulty) + margin.
+type RevocationPolicy struct {
+	MinDifficulty int `json:"minDifficulty"` // minimum average difficulty accepted
+	AvgDifficulty int `json:"avgDifficulty"` // typical difficulty of published revocations
+	Margin        int `json:"margin"`        // safety margin (bits) for new revocations
+}
+
+//----------------------------------------------------------------------
+// Network size estimation configuration
+//----------------------------------------------------------------------
+
+// NSEConfig contains parameters for the network size estimation. If no
+// number of peers is configured for the network, the DHT service runs
+// NSE to estimate it.
+type NSEConfig struct {
+	Service   *ServiceConfig `json:"service,omitempty"`   // socket for NSE clients (optional)
+	Interval  int            `json:"interval,omitempty"`  // length of a round (seconds; default: 3600)
+	WorkBits  int            `json:"workBits,omitempty"`  // leading zero bits of proof-of-work (default: 15)
+	WorkDelay int            `json:"workDelay,omitempty"` // pause between proof-of-work batches (ms; default: 5)
+	ProofFile string         `json:"proofFile,omitempty"` // file for own proof-of-work
+}
+
+//----------------------------------------------------------------------
+// CADET configuration
+//----------------------------------------------------------------------
+
+// CadetConfig contains parameters for the CADET service (end-to-end
+// encrypted channels between applications on peers).
+type CadetConfig struct {
+	Service *ServiceConfig `json:"service,omitempty"` // socket for CADET clients (optional)
+}
+
+//----------------------------------------------------------------------
+// Identity configuration
+//----------------------------------------------------------------------
+
+// IdentityConfig contains parameters for the IDENTITY service. Egos are
+// stored in the ego directory in the format of the GNUnet C service.
+type IdentityConfig struct {
+	Service *ServiceConfig `json:"service"` // socket for IDENTITY service
+	EgoDir  string         `json:"egoDir"`  // directory of ego files
+}
+
+//----------------------------------------------------------------------
+// Scripting configuration
+//----------------------------------------------------------------------
+
+// ScriptConfig contains parameters for event hook scripts
+type ScriptConfig struct {
+	Files    []string `json:"files"`    // list of script files
+	Timeout  int      `json:"timeout"`  // max. run time of a hook (in milliseconds)
+	MaxSteps uint64   `json:"maxSteps"` // max. number of execution steps of a hook
 }
 
 //----------------------------------------------------------------------
@@ -161,21 +472,39 @@ type Environment map[string]string
 
 // Config is the aggregated configuration for GNUnet.
 type Config struct {
-	Local      *NodeConfig       `json:"local"`
-	Network    *NetworkConfig    `json:"network"`
-	Env        Environment       `json:"environ"`
-	RPC        *RPCConfig        `json:"rpc"`
-	DHT        *DHTConfig        `json:"dht"`
-	GNS        *GNSConfig        `json:"gns"`
-	Namecache  *NamecacheConfig  `json:"namecache"`
-	ZoneMaster *ZoneMasterConfig `json:"zonemaster"`
-	Revocation *RevocationConfig `json:"revocation"`
-	Logging    *LoggingConfig    `json:"logging"`
+	Local       *NodeConfig        `json:"local"`
+	Network     *NetworkConfig     `json:"network"`
+	Core        *CoreConfig        `json:"core"`
+	Env         Environment        `json:"environ"`
+	RPC         *RPCConfig         `json:"rpc"`
+	DHT         *DHTConfig         `json:"dht"`
+	GNS         *GNSConfig         `json:"gns"`
+	DNS2GNS     *DNS2GNSConfig     `json:"dns2gns,omitempty"`
+	REST        *RESTConfig        `json:"rest,omitempty"`
+	Namecache   *NamecacheConfig   `json:"namecache"`
+	Peerstore   *PeerstoreConfig   `json:"peerstore,omitempty"`
+	Resolver    *ResolverConfig    `json:"resolver,omitempty"`
+	Statistics  *StatisticsConfig  `json:"statistics,omitempty"`
+	ZoneMaster  *ZoneMasterConfig  `json:"zonemaster"`
+	Revocation  *RevocationConfig  `json:"revocation"`
+	NSE         *NSEConfig         `json:"nse,omitempty"`
+	Cadet       *CadetConfig       `json:"cadet,omitempty"`
+	Identity    *IdentityConfig    `json:"identity,omitempty"`
+	Scripts     *ScriptConfig      `json:"scripts"`
+	Logging     *LoggingConfig     `json:"logging"`
+	Maintenance *MaintenanceConfig `json:"maintenance,omitempty"`
+	Alerts      *AlertConfig       `json:"alerts,omitempty"`
+	Metrics     *MetricsConfig     `json:"metrics,omitempty"`
+	Aliases     map[string]string  `json:"aliases,omitempty"` // peer ID -> alias (for logs)
+	Source      string             `json:"source,omitempty"`  // link to source code (AGPL)
 }
 
 var (
 	// Cfg is the global configuration
 	Cfg *Config
+
+	// name of the parsed configuration file
+	cfgFile string
 )
 
 // ParseConfig converts a JSON-encoded configuration file and maps it to
@@ -186,23 +515,63 @@ func ParseConfig(fileName string) (err error) {
 	if err != nil {
 		return
 	}
-	return ParseConfigBytes(file, true)
+	if err = ParseConfigBytes(file, true); err == nil {
+		cfgFile = fileName
+	}
+	return
 }
 
 // ParseConfigBytes reads a configuration from binary data. The data is
 // a JSON-encoded content. If 'subst' is true, the configuration strings
 // are subsituted
 func ParseConfigBytes(data []byte, subst bool) (err error) {
+	if Cfg, err = parseConfig(data); err == nil {
+		err = applyAliases(Cfg.Aliases)
+	}
+	return
+}
+
+// LoadConfig reads a configuration file without changing the global
+// configuration (e.g. to compare it with the running configuration).
+func LoadConfig(fileName string) (*Config, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+// FileName returns the name of the parsed configuration file (or an
+// empty string if the configuration was not read from a file).
+func FileName() string {
+	return cfgFile
+}
+
+// parseConfig unmarshals a JSON-encoded configuration and applies the
+// string substitutions.
+func parseConfig(data []byte) (cfg *Config, err error) {
 	// unmarshal to Config data structure
-	Cfg = new(Config)
-	if err = json.Unmarshal(data, Cfg); err == nil {
+	cfg = new(Config)
+	if err = json.Unmarshal(data, cfg); err == nil {
 		// process all string-based config settings and apply
 		// string substitutions.
-		applySubstitutions(Cfg, Cfg.Env)
+		applySubstitutions(cfg, cfg.Env)
 	}
 	return
 }
 
+// applyAliases registers configured peer aliases.
+func applyAliases(list map[string]string) error {
+	for id, alias := range list {
+		data, err := util.DecodeStringToBinary(id, util.PeerPublicKeySize)
+		if err != nil {
+			return fmt.Errorf("alias '%s': invalid peer ID '%s'", alias, id)
+		}
+		util.SetPeerAlias(util.NewPeerID(data), alias)
+	}
+	return nil
+}
+
 var (
 	rx = regexp.MustCompile(`\$\{([^\}]*)\}`)
 )
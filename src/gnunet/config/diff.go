@@ -0,0 +1,176 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//----------------------------------------------------------------------
+// Effective configuration: the running configuration (after environment
+// substitutions and changes by command-line options) is compared with
+// the configuration file on disk, so operators can verify the settings
+// a node actually uses.
+//----------------------------------------------------------------------
+
+// Redacted replaces the values of secret settings in reports.
+const Redacted = "<redacted>"
+
+// SecretSettings are the paths of settings that are redacted in reports.
+var SecretSettings = []string{"local.privateSeed"}
+
+// Difference between the running configuration and the configuration
+// file: a nil value denotes a missing setting.
+type Difference struct {
+	Path    string `json:"path"`    // path of setting (like "rpc.endpoint")
+	Running any    `json:"running"` // value in running configuration
+	File    any    `json:"file"`    // value in configuration file
+}
+
+// String returns a human-readable representation of a difference.
+func (d *Difference) String() string {
+	return fmt.Sprintf("%s: running=%s, file=%s", d.Path, diffValue(d.Running), diffValue(d.File))
+}
+
+// Effective returns the configuration as a generic JSON object with
+// secret settings redacted.
+func Effective(cfg *Config) (map[string]any, error) {
+	obj, err := toJSON(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range SecretSettings {
+		redact(obj, path)
+	}
+	return obj, nil
+}
+
+// Diff returns the differences between a running configuration and a
+// configuration read from file (sorted by path). Changed secret settings
+// are reported with redacted values.
+func Diff(running, file *Config) (list []*Difference, err error) {
+	var a, b map[string]any
+	if a, err = toJSON(running); err != nil {
+		return
+	}
+	if b, err = toJSON(file); err != nil {
+		return
+	}
+	list = diffValues("", a, b, nil)
+	for _, d := range list {
+		for _, path := range SecretSettings {
+			if d.Path == path {
+				d.Running, d.File = redactValue(d.Running), redactValue(d.File)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return
+}
+
+// toJSON returns the configuration as a generic JSON object.
+func toJSON(cfg *Config) (obj map[string]any, err error) {
+	var buf []byte
+	if buf, err = json.Marshal(cfg); err != nil {
+		return
+	}
+	err = json.Unmarshal(buf, &obj)
+	return
+}
+
+// diffValues compares two JSON values and appends the differences.
+func diffValues(path string, a, b any, list []*Difference) []*Difference {
+	switch va := a.(type) {
+	case map[string]any:
+		if vb, ok := b.(map[string]any); ok {
+			keys := make(map[string]bool)
+			for k := range va {
+				keys[k] = true
+			}
+			for k := range vb {
+				keys[k] = true
+			}
+			for k := range keys {
+				list = diffValues(joinPath(path, k), va[k], vb[k], list)
+			}
+			return list
+		}
+	case []any:
+		if vb, ok := b.([]any); ok && len(va) == len(vb) {
+			for i := range va {
+				list = diffValues(fmt.Sprintf("%s[%d]", path, i), va[i], vb[i], list)
+			}
+			return list
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		list = append(list, &Difference{Path: path, Running: a, File: b})
+	}
+	return list
+}
+
+// joinPath appends a key to a path.
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// redact the (non-empty) setting at given path.
+func redact(obj map[string]any, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]any)
+		if !ok {
+			return
+		}
+		obj = next
+	}
+	last := keys[len(keys)-1]
+	if v, ok := obj[last]; ok {
+		obj[last] = redactValue(v)
+	}
+}
+
+// redactValue hides a (non-empty) value.
+func redactValue(v any) any {
+	if v == nil || v == "" {
+		return v
+	}
+	return Redacted
+}
+
+// diffValue returns a compact representation of a value.
+func diffValue(v any) string {
+	if v == nil {
+		return "(unset)"
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(buf)
+}
@@ -0,0 +1,68 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package config
+
+import (
+	"testing"
+
+	"github.com/bfix/gospel/logger"
+)
+
+func TestConfigDiff(t *testing.T) {
+	logger.SetLogLevel(logger.WARN)
+
+	// running and on-disk configuration
+	file, err := LoadConfig("./gnunet-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := LoadConfig("./gnunet-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, err := Diff(running, file); err != nil || len(list) != 0 {
+		t.Fatalf("unexpected diff %v (%v)", list, err)
+	}
+	// change settings at runtime
+	running.RPC.Endpoint = "127.0.0.1:9999"
+	running.Local.Endpoints[0].Port++
+	running.Local.PrivateSeed = "changed"
+	running.Metrics = &MetricsConfig{Endpoints: map[string]string{"dht": "127.0.0.1:9101"}}
+
+	list, err := Diff(running, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"local.endpoints[0].port", "local.privateSeed", "metrics.endpoints.dht", "rpc.endpoint"}
+	if len(list) != len(want) {
+		t.Fatalf("unexpected diff %v", list)
+	}
+	for i, d := range list {
+		if d.Path != want[i] {
+			t.Fatalf("unexpected path '%s' (expected '%s')", d.Path, want[i])
+		}
+	}
+	// secrets are not reported
+	if list[1].Running != Redacted || list[1].File != Redacted {
+		t.Fatalf("secret not redacted: %s", list[1])
+	}
+	if list[2].File != nil {
+		t.Fatalf("unexpected file value: %s", list[2])
+	}
+}
@@ -0,0 +1,348 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Bandwidth accounting
+//
+// Core counts the traffic to and from every connected peer and enforces
+// bandwidth quotas (TRANSPORT_SESSION_QUOTA semantics): the inbound
+// quota is announced to a peer after connecting; messages from a peer
+// exceeding it are dropped and counted as violations. Outgoing messages
+// are limited by the quota a peer announced (or the default outbound
+// quota, whichever is lower). Short bursts above a quota are allowed.
+// Services query the statistics of a peer (PeerStats) to prefer peers
+// that respect their quota.
+//----------------------------------------------------------------------
+
+// DefaultBurst is the default time of traffic at full quota allowed in
+// a burst.
+var DefaultBurst = 5 * time.Second
+
+// BandwidthPolicy holds the default bandwidth quotas of a node.
+type BandwidthPolicy struct {
+	QuotaIn       uint32        // max. inbound rate per peer (bytes/s; 0 = unlimited)
+	QuotaOut      uint32        // max. outbound rate per peer (bytes/s; 0 = unlimited)
+	Burst         time.Duration // traffic at full quota allowed in a burst
+	MaxViolations int           // disconnect peer after dropped messages (0 = never)
+}
+
+// NewBandwidthPolicy creates a bandwidth policy from configuration (the
+// default policy has no quotas).
+func NewBandwidthPolicy(cfg *config.BandwidthConfig) *BandwidthPolicy {
+	p := &BandwidthPolicy{
+		Burst: DefaultBurst,
+	}
+	if cfg == nil {
+		return p
+	}
+	if cfg.QuotaIn > 0 {
+		p.QuotaIn = uint32(cfg.QuotaIn)
+	}
+	if cfg.QuotaOut > 0 {
+		p.QuotaOut = uint32(cfg.QuotaOut)
+	}
+	if cfg.Burst > 0 {
+		p.Burst = time.Duration(cfg.Burst) * time.Second
+	}
+	p.MaxViolations = cfg.MaxViolations
+	return p
+}
+
+//----------------------------------------------------------------------
+
+// rateLimiter is a token bucket: tokens (bytes) are added at the quota
+// rate up to the burst size. A zero rate is unlimited.
+type rateLimiter struct {
+	rate   uint32    // quota (bytes per second)
+	tokens float64   // available tokens
+	last   time.Time // last update of tokens
+}
+
+// newRateLimiter creates a (full) bucket for given rate.
+func newRateLimiter(rate uint32, burst time.Duration) *rateLimiter {
+	rl := &rateLimiter{last: time.Now()}
+	rl.setRate(rate, burst)
+	return rl
+}
+
+// max. number of tokens in the bucket
+func (rl *rateLimiter) size(burst time.Duration) float64 {
+	return float64(rl.rate) * burst.Seconds()
+}
+
+// setRate changes the quota of the bucket.
+func (rl *rateLimiter) setRate(rate uint32, burst time.Duration) {
+	rl.rate = rate
+	rl.tokens = rl.size(burst)
+}
+
+// refill the bucket with tokens for the time elapsed since the last
+// update (up to the burst size).
+func (rl *rateLimiter) refill(burst time.Duration) {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * float64(rl.rate)
+	if size := rl.size(burst); rl.tokens > size {
+		rl.tokens = size
+	}
+	rl.last = now
+}
+
+// ready returns true if n bytes can be transferred under the quota
+// (without taking tokens). A message larger than the bucket is allowed
+// if the bucket is full; the balance turns negative when it is taken.
+func (rl *rateLimiter) ready(n int, burst time.Duration) bool {
+	if rl.rate == 0 {
+		return true
+	}
+	rl.refill(burst)
+	return rl.tokens >= float64(n) || rl.tokens >= rl.size(burst)
+}
+
+// take n bytes from the bucket.
+func (rl *rateLimiter) take(n int) {
+	if rl.rate > 0 {
+		rl.tokens -= float64(n)
+	}
+}
+
+// allow returns true (and takes the tokens) if n bytes can be
+// transferred under the quota.
+func (rl *rateLimiter) allow(n int, burst time.Duration) bool {
+	if !rl.ready(n, burst) {
+		return false
+	}
+	rl.take(n)
+	return true
+}
+
+//----------------------------------------------------------------------
+
+// PeerStats holds the traffic statistics of a connected peer.
+type PeerStats struct {
+	Since      time.Time `json:"since"`      // start of connection
+	BytesIn    uint64    `json:"bytesIn"`    // bytes received from peer
+	BytesOut   uint64    `json:"bytesOut"`   // bytes sent to peer
+	MsgsIn     uint64    `json:"msgsIn"`     // messages received from peer
+	MsgsOut    uint64    `json:"msgsOut"`    // messages sent to peer
+	QuotaIn    uint32    `json:"quotaIn"`    // inbound quota (bytes/s; 0 = unlimited)
+	QuotaOut   uint32    `json:"quotaOut"`   // effective outbound quota (bytes/s; 0 = unlimited)
+	Violations uint64    `json:"violations"` // messages from peer dropped (over quota)
+	Throttled  uint64    `json:"throttled"`  // messages to peer not sent (over quota)
+	Invalid    uint64    `json:"invalid"`    // invalid messages from peer
+	LastSeen   time.Time `json:"lastSeen"`   // last message from peer
+}
+
+// RateIn returns the average inbound rate (bytes per second).
+func (s *PeerStats) RateIn() float64 {
+	return s.rate(s.BytesIn)
+}
+
+// RateOut returns the average outbound rate (bytes per second).
+func (s *PeerStats) RateOut() float64 {
+	return s.rate(s.BytesOut)
+}
+
+// average rate since connect
+func (s *PeerStats) rate(n uint64) float64 {
+	secs := time.Since(s.Since).Seconds()
+	if secs < 1 {
+		secs = 1
+	}
+	return float64(n) / secs
+}
+
+// WellBehaved returns true if the peer never exceeded its inbound quota.
+// Peers without statistics (nil) are considered well-behaved.
+func (s *PeerStats) WellBehaved() bool {
+	return s == nil || s.Violations == 0
+}
+
+// bandwidth accounting for a connected peer
+type peerBandwidth struct {
+	sync.Mutex
+
+	peer  *util.PeerID
+	stats PeerStats
+	types map[enums.MsgType]*TypeStats // traffic by message type
+	in    *rateLimiter                 // inbound quota
+	out   *rateLimiter                 // outbound quota
+}
+
+// get the traffic statistics for a message type (caller holds the lock)
+func (bw *peerBandwidth) typeStats(mt enums.MsgType) *TypeStats {
+	ts, ok := bw.types[mt]
+	if !ok {
+		ts = &TypeStats{Type: mt.String()}
+		bw.types[mt] = ts
+	}
+	return ts
+}
+
+// effective outbound quota: the lower of the announced and the default
+// quota (zero quotas are unlimited).
+func effectiveQuota(announced, local uint32) uint32 {
+	if announced == 0 || (local > 0 && local < announced) {
+		return local
+	}
+	return announced
+}
+
+//----------------------------------------------------------------------
+
+// PeerStats returns the traffic statistics of a connected peer (or nil
+// if the peer is not connected).
+func (c *Core) PeerStats(peer *util.PeerID) *PeerStats {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return nil
+	}
+	bw.Lock()
+	defer bw.Unlock()
+	stats := bw.stats
+	return &stats
+}
+
+// startAccounting of traffic for a newly connected peer.
+func (c *Core) startAccounting(peer *util.PeerID) {
+	p := c.bwPolicy
+	bw := &peerBandwidth{
+		peer: peer,
+		stats: PeerStats{
+			Since:    time.Now(),
+			QuotaIn:  p.QuotaIn,
+			QuotaOut: p.QuotaOut,
+		},
+		types: make(map[enums.MsgType]*TypeStats),
+		in:    newRateLimiter(p.QuotaIn, p.Burst),
+		out:   newRateLimiter(p.QuotaOut, p.Burst),
+	}
+	c.bandwidth.Put(peer.String(), bw, 0)
+}
+
+// stopAccounting of traffic for a disconnected peer.
+func (c *Core) stopAccounting(peer *util.PeerID) {
+	c.bandwidth.Delete(peer.String(), 0)
+}
+
+// accountIn counts a message received from a peer. Returns false if the
+// message exceeds the inbound quota (and must be dropped).
+func (c *Core) accountIn(peer *util.PeerID, msg message.Message) bool {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return true
+	}
+	bw.Lock()
+	n := int(msg.Size())
+	if !bw.in.allow(n, c.bwPolicy.Burst) {
+		bw.stats.Violations++
+		violations := bw.stats.Violations
+		bw.Unlock()
+		logger.Printf(logger.DBG, "[core] %s from %s dropped: inbound quota exceeded", msg.Type(), peer.Short())
+		if limit := c.bwPolicy.MaxViolations; limit > 0 && violations >= uint64(limit) {
+			c.disconnect(peer, DR_QUOTA, "inbound quota exceeded")
+		}
+		return false
+	}
+	bw.stats.BytesIn += uint64(n)
+	bw.stats.MsgsIn++
+	bw.stats.LastSeen = time.Now()
+	ts := bw.typeStats(msg.Type())
+	ts.BytesIn += uint64(n)
+	ts.MsgsIn++
+	bw.Unlock()
+	return true
+}
+
+// checkOut returns false if a message to a peer exceeds the outbound
+// quota (and must not be sent). The quota is only charged by accountOut
+// after the message is sent.
+func (c *Core) checkOut(peer *util.PeerID, msg message.Message) bool {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return true
+	}
+	bw.Lock()
+	defer bw.Unlock()
+	if !bw.out.ready(int(msg.Size()), c.bwPolicy.Burst) {
+		bw.stats.Throttled++
+		return false
+	}
+	return true
+}
+
+// accountOut counts a message sent to a peer (and charges the outbound
+// quota).
+func (c *Core) accountOut(peer *util.PeerID, msg message.Message) {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return
+	}
+	bw.Lock()
+	defer bw.Unlock()
+	n := int(msg.Size())
+	bw.out.take(n)
+	bw.stats.BytesOut += uint64(n)
+	bw.stats.MsgsOut++
+	ts := bw.typeStats(msg.Type())
+	ts.BytesOut += uint64(n)
+	ts.MsgsOut++
+}
+
+// sendQuota announces the inbound quota to a newly connected peer.
+func (c *Core) sendQuota(ctx context.Context, peer *util.PeerID) {
+	if c.bwPolicy.QuotaIn == 0 {
+		return
+	}
+	msg := message.NewSessionQuotaMsg(c.bwPolicy.QuotaIn)
+	if err := c.Send(ctx, peer, msg); err != nil {
+		logger.Printf(logger.WARN, "[core] Failed to send quota to %s: %s", peer.Short(), err.Error())
+	}
+}
+
+// handleQuota sets the outbound quota announced by a peer.
+func (c *Core) handleQuota(peer *util.PeerID, msg *message.SessionQuotaMsg) {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return
+	}
+	bw.Lock()
+	defer bw.Unlock()
+	bw.stats.QuotaOut = effectiveQuota(msg.Quota, c.bwPolicy.QuotaOut)
+	bw.out.setRate(bw.stats.QuotaOut, c.bwPolicy.Burst)
+	logger.Printf(logger.DBG, "[core] Quota of %s: %s", peer.Short(), msg)
+}
+
+// quotaMsg returns true for quota announcements (handled by core).
+func quotaMsg(mt enums.MsgType) bool {
+	return mt == enums.MSG_TRANSPORT_SESSION_QUOTA
+}
@@ -0,0 +1,126 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/util"
+)
+
+func TestRateLimiter(t *testing.T) {
+	burst := time.Second
+	rl := newRateLimiter(1000, burst)
+	if !rl.allow(800, burst) || rl.allow(300, burst) {
+		t.Fatal("burst not limited")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if !rl.allow(300, burst) {
+		t.Fatal("bucket not refilled")
+	}
+	// a message larger than the bucket passes if the bucket is full
+	rl = newRateLimiter(1000, burst)
+	if !rl.allow(1500, burst) {
+		t.Fatal("large message not allowed with full bucket")
+	}
+	if rl.allow(1, burst) {
+		t.Fatal("negative balance not enforced")
+	}
+	// zero rate is unlimited
+	rl.setRate(0, burst)
+	if !rl.allow(1<<20, burst) {
+		t.Fatal("unlimited rate limited")
+	}
+}
+
+func TestEffectiveQuota(t *testing.T) {
+	for _, tc := range []struct{ announced, local, quota uint32 }{
+		{0, 0, 0},
+		{0, 1000, 1000},
+		{500, 0, 500},
+		{500, 1000, 500},
+		{2000, 1000, 1000},
+	} {
+		if q := effectiveQuota(tc.announced, tc.local); q != tc.quota {
+			t.Errorf("quota(%d,%d) = %d, expected %d", tc.announced, tc.local, q, tc.quota)
+		}
+	}
+}
+
+func TestPeerQuota(t *testing.T) {
+	c, peer, ch := newDisconnectCore(0)
+	c.bwPolicy = &BandwidthPolicy{
+		QuotaIn:       1000,
+		QuotaOut:      2000,
+		Burst:         time.Second,
+		MaxViolations: 2,
+	}
+	c.startAccounting(peer)
+	msg := message.NewCadetChannelAppDataMsg(0, 0, make([]byte, 588))
+
+	// outbound quota announced by peer
+	c.handleQuota(peer, message.NewSessionQuotaMsg(1000))
+	if !c.checkOut(peer, msg) {
+		t.Fatal("outbound message throttled")
+	}
+	// the quota is only charged for sent messages
+	if !c.checkOut(peer, msg) {
+		t.Fatal("outbound quota charged for unsent message")
+	}
+	c.accountOut(peer, msg)
+	if c.checkOut(peer, msg) {
+		t.Fatal("outbound quota not enforced")
+	}
+	// inbound quota: messages over quota are dropped
+	if !c.accountIn(peer, msg) || c.accountIn(peer, msg) {
+		t.Fatal("inbound quota not enforced")
+	}
+	stats := c.PeerStats(peer)
+	if stats.BytesIn != 600 || stats.MsgsIn != 1 || stats.BytesOut != 600 || stats.MsgsOut != 1 {
+		t.Fatalf("unexpected counters %v", stats)
+	}
+	if stats.QuotaIn != 1000 || stats.QuotaOut != 1000 || stats.Throttled != 1 || stats.Violations != 1 {
+		t.Fatalf("unexpected quotas %v", stats)
+	}
+	if stats.WellBehaved() {
+		t.Fatal("peer over quota is well-behaved")
+	}
+	// too many violations disconnect the peer
+	if c.accountIn(peer, msg) {
+		t.Fatal("inbound quota not enforced")
+	}
+	select {
+	case ev := <-ch:
+		if !ev.Peer.Equal(peer) || ev.Reason != DR_QUOTA {
+			t.Fatalf("unexpected event %s", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer over quota not disconnected")
+	}
+	if c.PeerStats(peer) != nil {
+		t.Fatal("statistics of disconnected peer")
+	}
+	// unknown peers are not limited
+	other := util.NewPeerID(util.NewRndArray(32))
+	if !c.accountIn(other, msg) || !c.PeerStats(other).WellBehaved() {
+		t.Fatal("unknown peer limited")
+	}
+}
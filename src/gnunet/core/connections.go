@@ -0,0 +1,191 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"gnunet/config"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Connection limits
+//
+// A node can limit the number of connected peers in total and per
+// subnet (peers with a common address prefix), so a small node can't
+// be exhausted by connections and a single network can't occupy all
+// connection slots. If the total limit is reached, a new peer is only
+// accepted if it makes the set of peers more diverse: a peer of the
+// most crowded subnet is evicted for it. Within that subnet, peers with
+// a high round-trip time and short-lived connections are evicted first.
+//----------------------------------------------------------------------
+
+// Default subnet prefix lengths
+var (
+	DefaultPrefixV4 = 24
+	DefaultPrefixV6 = 48
+)
+
+// RTTStep is the granularity of round-trip times when ranking peers:
+// peers with similar latency are ranked by connection age.
+var RTTStep = 50 * time.Millisecond
+
+// ConnPolicy holds the connection limits of a node.
+type ConnPolicy struct {
+	MaxPeers     int // max. number of connected peers (0 = unlimited)
+	MaxPerSubnet int // max. number of peers per subnet (0 = unlimited)
+	PrefixV4     int // prefix length of IPv4 subnets
+	PrefixV6     int // prefix length of IPv6 subnets
+
+	IdleTimeout time.Duration // disconnect peers without traffic (0 = never)
+}
+
+// NewConnPolicy creates a connection policy from configuration (the
+// default policy has no limits).
+func NewConnPolicy(cfg *config.ConnectionConfig) *ConnPolicy {
+	p := &ConnPolicy{
+		PrefixV4: DefaultPrefixV4,
+		PrefixV6: DefaultPrefixV6,
+	}
+	if cfg == nil {
+		return p
+	}
+	p.MaxPeers = cfg.MaxPeers
+	p.MaxPerSubnet = cfg.MaxPerSubnet
+	if cfg.PrefixV4 > 0 && cfg.PrefixV4 <= 32 {
+		p.PrefixV4 = cfg.PrefixV4
+	}
+	if cfg.PrefixV6 > 0 && cfg.PrefixV6 <= 128 {
+		p.PrefixV6 = cfg.PrefixV6
+	}
+	if cfg.IdleTimeout > 0 {
+		p.IdleTimeout = time.Duration(cfg.IdleTimeout) * time.Second
+	}
+	return p
+}
+
+// Subnet returns the subnet of an IP address (empty for nil).
+func (p *ConnPolicy) Subnet(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(p.PrefixV4, 32)).String() + "/" + util.CastToString(p.PrefixV4)
+	}
+	return ip.Mask(net.CIDRMask(p.PrefixV6, 128)).String() + "/" + util.CastToString(p.PrefixV6)
+}
+
+// limited returns true if any limit is set.
+func (p *ConnPolicy) limited() bool {
+	return p.MaxPeers > 0 || p.MaxPerSubnet > 0
+}
+
+//----------------------------------------------------------------------
+
+// connection to a peer (for ranking)
+type connEntry struct {
+	peer   *util.PeerID
+	since  time.Time     // start of connection
+	subnet string        // subnet of best address (or empty)
+	rtt    time.Duration // round-trip time of best address
+}
+
+// worse returns true if connection 'a' is less valuable than 'b'.
+func (a *connEntry) worse(b *connEntry) bool {
+	ra, rb := a.rtt/RTTStep, b.rtt/RTTStep
+	if ra != rb {
+		return ra > rb
+	}
+	return a.since.After(b.since)
+}
+
+// peerInfo returns the subnet and round-trip time of the best known
+// address of a peer.
+func (c *Core) peerInfo(peer *util.PeerID) (subnet string, rtt time.Duration) {
+	rtt = DefaultRTT
+	list := c.rankAddresses(peer, c.peers.Get(peer, ""))
+	if len(list) == 0 {
+		return
+	}
+	subnet = c.conns.Subnet(addrIP(list[0]))
+	if v, ok := c.validations.Get(validationKey(peer, list[0]), 0); ok {
+		v.Lock()
+		if v.rtt > 0 {
+			rtt = v.rtt
+		}
+		v.Unlock()
+	}
+	return
+}
+
+// admit checks if a new peer can be connected under the connection
+// limits. If a connected peer needs to be evicted to make room, it is
+// returned.
+func (c *Core) admit(peer *util.PeerID) (ok bool, evict *util.PeerID) {
+	p := c.conns
+	if !p.limited() {
+		return true, nil
+	}
+	// collect connections by subnet
+	subnet, _ := c.peerInfo(peer)
+	bySubnet := make(map[string][]*connEntry)
+	total := 0
+	_ = c.connected.ProcessRange(func(key string, since time.Time, _ int) error {
+		data, err := util.DecodeStringToBinary(key, 32)
+		if err != nil {
+			return nil
+		}
+		e := &connEntry{
+			peer:  util.NewPeerID(data),
+			since: since,
+		}
+		e.subnet, e.rtt = c.peerInfo(e.peer)
+		bySubnet[e.subnet] = append(bySubnet[e.subnet], e)
+		total++
+		return nil
+	}, true)
+
+	// check subnet limit (peers of unknown subnet are not limited)
+	count := len(bySubnet[subnet])
+	if p.MaxPerSubnet > 0 && len(subnet) > 0 && count >= p.MaxPerSubnet {
+		return false, nil
+	}
+	// check total limit
+	if p.MaxPeers == 0 || total < p.MaxPeers {
+		return true, nil
+	}
+	// find the most crowded (known) subnet; only evict if the new peer
+	// makes the set more diverse.
+	var crowded []*connEntry
+	for sn, list := range bySubnet {
+		if len(sn) > 0 && len(list) > len(crowded) {
+			crowded = list
+		}
+	}
+	if len(subnet) == 0 || len(crowded) <= count+1 {
+		return false, nil
+	}
+	sort.Slice(crowded, func(i, j int) bool {
+		return crowded[i].worse(crowded[j])
+	})
+	return true, crowded[0].peer
+}
@@ -0,0 +1,117 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/util"
+)
+
+func TestConnPolicySubnet(t *testing.T) {
+	p := NewConnPolicy(&config.ConnectionConfig{PrefixV4: 16})
+	if p.PrefixV6 != DefaultPrefixV6 {
+		t.Fatalf("unexpected IPv6 prefix %d", p.PrefixV6)
+	}
+	cases := map[string]string{
+		"1.2.3.4":        "1.2.0.0/16",
+		"2001:db8:1:2::": "2001:db8:1::/48",
+	}
+	for ip, subnet := range cases {
+		if s := p.Subnet(net.ParseIP(ip)); s != subnet {
+			t.Errorf("%s: expected %s, got %s", ip, subnet, s)
+		}
+	}
+}
+
+func TestConnAdmit(t *testing.T) {
+	c := &Core{
+		peers:       util.NewPeerAddrList(),
+		connected:   util.NewMap[string, time.Time](),
+		validations: util.NewMap[string, *addrValidation](),
+		policy:      NewAddrPolicy(nil),
+		conns: NewConnPolicy(&config.ConnectionConfig{
+			MaxPeers:     4,
+			MaxPerSubnet: 3,
+		}),
+	}
+	// add a peer with address (and optional measured RTT)
+	newPeer := func(s string, rtt time.Duration) *util.PeerID {
+		peer := util.NewPeerID(util.NewRndArray(32))
+		addr, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr.Expire = util.AbsoluteTimeNever()
+		c.peers.Add(peer, addr)
+		if rtt > 0 {
+			c.validations.Put(validationKey(peer, addr), &addrValidation{
+				peer: peer,
+				addr: addr,
+				rtt:  rtt,
+			}, 0)
+		}
+		return peer
+	}
+	connect := func(peer *util.PeerID, age time.Duration) {
+		c.connected.Put(peer.String(), time.Now().Add(-age), 0)
+	}
+	// three peers in the same subnet
+	slow := newPeer("ip+udp://10.1.1.1:2086", time.Second)
+	young := newPeer("ip+udp://10.1.1.2:2086", 10*time.Millisecond)
+	old := newPeer("ip+udp://10.1.1.3:2086", 20*time.Millisecond)
+	connect(slow, time.Hour)
+	connect(young, time.Minute)
+	connect(old, time.Hour)
+
+	// subnet is full
+	if ok, _ := c.admit(newPeer("ip+udp://10.1.1.4:2086", 0)); ok {
+		t.Fatal("peer admitted to full subnet")
+	}
+	// other subnet is fine
+	other := newPeer("ip+udp://10.2.1.1:2086", 0)
+	if ok, evict := c.admit(other); !ok || evict != nil {
+		t.Fatal("peer from other subnet not admitted")
+	}
+	connect(other, 0)
+
+	// limit reached: evict slow peer from crowded subnet
+	ok, evict := c.admit(newPeer("ip+udp://10.3.1.1:2086", 0))
+	if !ok || evict == nil || !evict.Equal(slow) {
+		t.Fatal("slow peer not evicted")
+	}
+	c.connected.Delete(slow.String(), 0)
+	connect(newPeer("ip+udp://10.3.1.1:2086", 0), 0)
+
+	// no gain in diversity (subnet would be as crowded): reject
+	if ok, _ = c.admit(newPeer("ip+udp://10.2.1.2:2086", 0)); ok {
+		t.Fatal("peer admitted without gain in diversity")
+	}
+	// with equal RTT, the younger connection is evicted
+	c.conns.MaxPerSubnet = 0
+	c.connected.Delete(other.String(), 0)
+	connect(newPeer("ip+udp://10.1.1.5:2086", 30*time.Millisecond), time.Hour)
+	ok, evict = c.admit(newPeer("ip+udp://10.5.1.1:2086", 0))
+	if !ok || evict == nil || !evict.Equal(young) {
+		t.Fatal("young peer not evicted")
+	}
+}
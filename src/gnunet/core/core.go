@@ -21,13 +21,19 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"gnunet/config"
 	"gnunet/crypto"
 	"gnunet/message"
+	"gnunet/script"
+	"gnunet/service/metrics"
 	"gnunet/transport"
+	"gnunet/transport/nat"
 	"gnunet/util"
 	"net"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bfix/gospel/logger"
@@ -39,8 +45,16 @@ var (
 	ErrCoreNoUpnpDyn  = errors.New("no dynamic port with UPnP")
 	ErrCoreNoEndpAddr = errors.New("no endpoint for address")
 	ErrCoreNotSent    = errors.New("message not sent")
+	ErrCoreNotHandled = errors.New("message type not handled by peer")
+	ErrCoreConnLimit  = errors.New("connection limit reached")
+	ErrCoreAddrClass  = errors.New("unknown address class")
+	ErrCoreQuota      = errors.New("bandwidth quota exceeded")
 )
 
+// messages exchanged with peers (by type and direction)
+var msgCounter = metrics.NewCounter("gnunet_core_messages_total",
+	"Messages exchanged with peers by type and direction.", "type", "direction")
+
 // CtxKey is a value-context key
 type CtxKey string
 
@@ -50,6 +64,7 @@ type EndpointRef struct {
 	id     string             // endpoint identifier in configuration
 	ep     transport.Endpoint // reference to endpoint
 	addr   *util.Address      // public endpoint address
+	ttl    time.Duration      // lifetime of address (0 = policy default)
 	upnpID string             // UPNP identifier (empty if unused)
 }
 
@@ -67,15 +82,54 @@ type Core struct {
 
 	// registered signal listeners
 	listeners map[string]*Listener
+	lmtx      sync.RWMutex
 
 	// list of known peers with addresses
 	peers *util.PeerAddrList
 
-	// list of connected peers
-	connected *util.Map[string, bool]
+	// list of connected peers (with start of connection and time of
+	// last message) and limits
+	connected *util.Map[string, time.Time]
+	activity  *util.Map[string, time.Time]
+	conns     *ConnPolicy
+
+	// traffic accounting and quotas for connected peers
+	bandwidth *util.Map[string, *peerBandwidth]
+	bwPolicy  *BandwidthPolicy
+
+	// number of disconnected peers per reason
+	disconnects util.Counter[DisconnectReason]
+	dmtx        sync.Mutex
+
+	// validation records for peer addresses
+	validations *util.Map[string, *addrValidation]
+
+	// address lifetime policy
+	policy *AddrPolicy
+
+	// offset of local clock (from expirations in received data)
+	clock *util.ClockMonitor
 
 	// List of registered endpoints
 	endpoints map[string]*EndpointRef
+
+	// NAT traversal (optional) and external addresses of endpoints
+	nat      *nat.Manager
+	external *util.Map[string, *util.Address]
+
+	// type map of local peer (guarded by lmtx) and signal for changes
+	typeMap  *TypeMap
+	tmUpdate chan struct{}
+
+	// type maps received from peers
+	typeMaps *util.Map[string, *TypeMap]
+
+	// pending version queries and link to source code (guarded by lmtx)
+	versions *util.Map[string, chan *message.VersionInfo]
+	source   string
+
+	// signing workers
+	signer *Signer
 }
 
 //----------------------------------------------------------------------
@@ -91,14 +145,29 @@ func NewCore(ctx context.Context, node *config.NodeConfig) (c *Core, err error)
 
 	// create new core instance
 	incoming := make(chan *transport.Message)
+	policy := NewAddrPolicy(node.Policy)
 	c = &Core{
-		local:     peer,
-		incoming:  incoming,
-		listeners: make(map[string]*Listener),
-		trans:     transport.NewTransport(ctx, node.Name, incoming),
-		peers:     util.NewPeerAddrList(),
-		connected: util.NewMap[string, bool](),
-		endpoints: make(map[string]*EndpointRef),
+		local:       peer,
+		incoming:    incoming,
+		listeners:   make(map[string]*Listener),
+		trans:       transport.NewTransport(ctx, node.Name, incoming),
+		peers:       util.NewPeerAddrList(),
+		connected:   util.NewMap[string, time.Time](),
+		activity:    util.NewMap[string, time.Time](),
+		disconnects: make(util.Counter[DisconnectReason]),
+		conns:       NewConnPolicy(node.Connections),
+		bandwidth:   util.NewMap[string, *peerBandwidth](),
+		bwPolicy:    NewBandwidthPolicy(node.Bandwidth),
+		validations: util.NewMap[string, *addrValidation](),
+		policy:      policy,
+		clock:       util.NewClockMonitor(policy.MaxSkew),
+		endpoints:   make(map[string]*EndpointRef),
+		external:    util.NewMap[string, *util.Address](),
+		typeMap:     NewTypeMap(),
+		tmUpdate:    make(chan struct{}, 1),
+		typeMaps:    util.NewMap[string, *TypeMap](),
+		versions:    util.NewMap[string, chan *message.VersionInfo](),
+		signer:      NewSigner(ctx, peer.prv, SignWorkers, SignQueueSize),
 	}
 	// add all local peer endpoints to transport.
 	for _, epCfg := range node.Endpoints {
@@ -136,8 +205,16 @@ func NewCore(ctx context.Context, node *config.NodeConfig) (c *Core, err error)
 			remote = local
 			upnpID = ""
 		}
+		// tag address with its class (configured or derived)
+		if len(epCfg.Class) > 0 {
+			if !IsAddrClass(epCfg.Class) {
+				err = fmt.Errorf("%w '%s' for endpoint %s", ErrCoreAddrClass, epCfg.Class, epCfg.ID)
+				return
+			}
+			remote.Class = epCfg.Class
+		}
 		// add endpoint for address
-		if ep, err = c.trans.AddEndpoint(ctx, local); err != nil {
+		if ep, err = c.trans.AddEndpoint(ctx, local, epCfg.Faults, epCfg.TLS, epCfg.Fragments); err != nil {
 			return
 		}
 		// if port is set to 0, replace it with port assigned dynamically.
@@ -152,12 +229,19 @@ func NewCore(ctx context.Context, node *config.NodeConfig) (c *Core, err error)
 		c.endpoints[epCfg.ID] = &EndpointRef{
 			id:     epCfg.ID,
 			ep:     ep,
-			addr:   remote,
+			addr:   c.policy.Tag(remote),
+			ttl:    time.Duration(epCfg.TTL) * time.Second,
 			upnpID: upnpID,
 		}
 	}
-	// run message pump
+	// NAT traversal (not in local-only mode)
+	if node.NAT != nil && !transport.LocalOnly {
+		c.startNAT(ctx, node)
+	}
+	// run message pump and address re-validation
 	go c.pump(ctx)
+	go c.revalidate(ctx)
+	go c.watchIdle(ctx)
 	return
 }
 
@@ -169,20 +253,63 @@ func (c *Core) pump(ctx context.Context) {
 		// get (next) message from transport
 		case tm := <-c.incoming:
 			logger.Printf(logger.DBG, "[core] Message received from %s: %s", tm.Peer.Short(), tm.Msg)
+			// keep track of traffic from connected peers
+			if _, ok := c.connected.Get(tm.Peer.String(), 0); ok {
+				c.activity.Put(tm.Peer.String(), time.Now(), 0)
+			}
+
+			// handle address validation messages
+			switch msg := tm.Msg.(type) {
+			case *message.TransportPingMsg:
+				go c.handlePing(ctx, tm, msg)
+				continue
+			case *message.TransportPongMsg:
+				go c.handlePong(ctx, tm, msg)
+				continue
+			case *message.CoreTypeMapMsg:
+				go c.handleTypeMap(ctx, tm.Peer, msg)
+				continue
+			case *message.CoreConfirmTypeMapMsg:
+				go c.handleTypeMapConfirm(ctx, tm.Peer, msg)
+				continue
+			}
 
 			// check if peer is already connected (has an entry in PeerAddrist)
 			_, connected := c.connected.Get(tm.Peer.String(), 0)
 			if !connected {
-				// no: mark connected
-				c.connected.Put(tm.Peer.String(), true, 0)
+				// check connection limits
+				ok, evict := c.admit(tm.Peer)
+				if !ok {
+					logger.Printf(logger.DBG, "[core] Peer %s rejected: connection limit", tm.Peer.Short())
+					continue
+				}
+				if evict != nil {
+					c.disconnect(evict, DR_EVICTED, "connection limit, replaced by "+tm.Peer.Short())
+				}
+				// mark connected
+				c.connected.Put(tm.Peer.String(), time.Now(), 0)
+				c.activity.Put(tm.Peer.String(), time.Now(), 0)
+				c.startAccounting(tm.Peer)
 				// generate EV_CONNECT event
 				c.dispatch(&Event{
 					ID:   EV_CONNECT,
 					Peer: tm.Peer,
 				})
+				// notify event scripts
+				go script.Run(script.HookPeerConnect, map[string]any{
+					"peer": tm.Peer.String(),
+				})
+				// announce our type map and quota
+				go c.sendTypeMap(ctx, tm.Peer)
+				go c.sendQuota(ctx, tm.Peer)
 				// grace period for connection signal
 				time.Sleep(time.Second)
 			}
+			// enforce inbound quota
+			if !c.accountIn(tm.Peer, tm.Msg) {
+				continue
+			}
+			msgCounter.Inc(tm.Msg.Type().String(), "in")
 
 			// set default responder (core) if no custom responder
 			// is defined by the receiving endpoint.
@@ -193,6 +320,15 @@ func (c *Core) pump(ctx context.Context) {
 					SendFcn: c.Send,
 				}
 			}
+			// handle version and AGPL requests and quotas from peers
+			switch msg := tm.Msg.(type) {
+			case *message.CoreVersionQueryMsg, *message.CoreVersionReplyMsg, *message.AGPLRequestMsg:
+				go c.handleVersion(ctx, tm.Peer, tm.Msg, resp)
+				continue
+			case *message.SessionQuotaMsg:
+				c.handleQuota(tm.Peer, msg)
+				continue
+			}
 			// generate EV_MESSAGE event
 			c.dispatch(&Event{
 				ID:   EV_MESSAGE,
@@ -201,6 +337,12 @@ func (c *Core) pump(ctx context.Context) {
 				Resp: resp,
 			})
 
+		// announce changed type map to connected peers
+		case <-c.tmUpdate:
+			for _, peer := range c.Connected() {
+				go c.sendTypeMap(ctx, peer)
+			}
+
 		// wait for termination
 		case <-ctx.Done():
 			return
@@ -210,6 +352,7 @@ func (c *Core) pump(ctx context.Context) {
 
 // Shutdown all core-related processes.
 func (c *Core) Shutdown() {
+	c.disconnectAll(DR_SHUTDOWN)
 	c.trans.Shutdown()
 	c.local.Shutdown()
 }
@@ -226,29 +369,55 @@ func (c *Core) Send(ctx context.Context, peer *util.PeerID, msg message.Message)
 			label = s
 		}
 	}
+	// don't connect to new peers beyond the connection limit
+	if _, ok := c.connected.Get(peer.String(), 0); !ok && c.conns.MaxPeers > 0 && c.connected.Size() >= c.conns.MaxPeers {
+		logger.Printf(logger.DBG, "[%s] %s not connected: connection limit reached", label, peer.Short())
+		return ErrCoreConnLimit
+	}
+	// don't send messages the peer doesn't process
+	if !c.Handles(peer, msg.Type()) {
+		logger.Printf(logger.DBG, "[%s] %s not handled by %s -- dropped", label, msg.Type(), peer.Short())
+		return ErrCoreNotHandled
+	}
+	// don't exceed the bandwidth quota for the peer
+	if !c.checkOut(peer, msg) {
+		logger.Printf(logger.DBG, "[%s] %s to %s dropped: quota exceeded", label, msg.Type(), peer.Short())
+		return ErrCoreQuota
+	}
+	msgCounter.Inc(msg.Type().String(), "out")
 
-	// TODO: select best endpoint protocol for transport; now fixed to IP+UDP
-	netw := "ip+udp"
-
-	// try all addresses for peer
-	aList := c.peers.Get(peer, netw)
+	// try all (validated) addresses for peer: best addresses first,
+	// falling back to other transport classes. Addresses are validated
+	// in the background; until then only HELLOs are sent to them.
+	aList := c.rankAddresses(peer, c.peers.Get(peer, ""))
 	maybe := false // message may be sent...
 	for _, addr := range aList {
+		if !c.trans.CanSendTo(addr) {
+			continue
+		}
+		if !c.validate(ctx, peer, addr, 0) && !helloMsg(msg.Type()) {
+			logger.Printf(logger.INFO, "[%s] Address %s not validated (yet) -- skipped", label, addr.URI())
+			continue
+		}
 		logger.Printf(logger.INFO, "[%s] Trying to send to %s", label, addr.URI())
 		// send message to address
 		if err = c.SendToAddr(ctx, addr, msg); err != nil {
 			// if it is possible that the message was not sent, try next address
 			if err != transport.ErrEndpMaybeSent {
 				logger.Printf(logger.WARN, "[%s] Failed to send to %s: %s", label, addr.URI(), err.Error())
+				c.record(peer, addr, false)
 			} else {
 				maybe = true
 			}
 			continue
 		}
+		c.record(peer, addr, true)
+		c.accountOut(peer, msg)
 		// one successful send is enough
 		return
 	}
 	if maybe {
+		c.accountOut(peer, msg)
 		err = nil
 	} else {
 		err = ErrCoreNotSent
@@ -275,10 +444,27 @@ func (c *Core) Learn(ctx context.Context, peer *util.PeerID, addrs []*util.Addre
 		if !transport.CanHandleAddress(addr) {
 			continue
 		}
+		// apply address lifetime policy (the expiration is checked
+		// against our clock before)
+		c.clock.Observe(addr.Expire, c.policy.class(addr).MaxTTL)
+		expire, ok := c.policy.LearnedExpire(addr, util.AbsoluteTimeNow())
+		if !ok {
+			logger.Printf(logger.INFO, "[%s] Address %s for %s expired -- ignored", label, addr.URI(), peer.Short())
+			continue
+		}
+		addr = c.policy.Tag(addr)
+		addr.Expire = expire
+
 		// learn address
-		logger.Printf(logger.INFO, "[%s] Learning %s for %s (expires %s)",
-			label, addr.URI(), peer.Short(), addr.Expire)
-		newPeer = (c.peers.Add(peer, addr) == 1) || newPeer
+		logger.Printf(logger.INFO, "[%s] Learning %s (%s) for %s (expires %s)",
+			label, addr.URI(), addr.Class, peer.Short(), addr.Expire)
+		mode := c.peers.Add(peer, addr)
+		newPeer = (mode == 1) || newPeer
+
+		// validate new address before it is used
+		if mode != 0 {
+			c.validate(ctx, peer, addr, 0)
+		}
 	}
 	return
 }
@@ -291,6 +477,112 @@ func (c *Core) Addresses() (list []*util.Address, err error) {
 	return
 }
 
+// EndpointState is the state of an endpoint (with its configuration ID).
+type EndpointState struct {
+	ID       string `json:"id"`
+	External string `json:"external,omitempty"` // external address (NAT)
+	transport.EndpointStatus
+}
+
+// EndpointStates returns the state of all configured endpoints (ordered
+// by identifier).
+func (c *Core) EndpointStates() (list []*EndpointState) {
+	for _, epRef := range c.endpoints {
+		st := &EndpointState{ID: epRef.id}
+		if mon, ok := epRef.ep.(*transport.MonitoredEndpoint); ok {
+			st.EndpointStatus = *mon.Status()
+		} else {
+			addr := epRef.ep.Address()
+			st.Address = util.URI(addr.Network(), []byte(addr.String()))
+			st.Listening = true
+		}
+		if ext, ok := c.external.Get(epRef.id, 0); ok {
+			st.External = ext.URI()
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return
+}
+
+// HelloAddresses returns the list of endpoint addresses advertised in
+// HELLOs: loopback addresses are never advertised (except in local-only
+// mode), public addresses are listed first.
+func (c *Core) HelloAddresses() (list []*util.Address, err error) {
+	for _, epRef := range c.advertised() {
+		list = append(list, epRef.addr)
+	}
+	return
+}
+
+// advertised returns the endpoints with advertised addresses in order
+// of preference. Endpoints behind a NAT are advertised with their
+// external address as well.
+func (c *Core) advertised() (list []*EndpointRef) {
+	for _, epRef := range c.endpoints {
+		if ext, ok := c.external.Get(epRef.id, 0); ok {
+			list = append(list, &EndpointRef{
+				id:   epRef.id,
+				ep:   epRef.ep,
+				addr: ext,
+				ttl:  epRef.ttl,
+			})
+		}
+		if epRef.addr.Class == AddrClassLoopback && !transport.LocalOnly {
+			continue
+		}
+		list = append(list, epRef)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		ri, rj := advertiseRank(list[i].addr), advertiseRank(list[j].addr)
+		if ri != rj {
+			return ri < rj
+		}
+		return list[i].id < list[j].id
+	})
+	return
+}
+
+// ClockOffset returns the estimated offset of the local clock (positive
+// if ahead of the network); 'ok' is false if there are not enough
+// observations for an estimate.
+func (c *Core) ClockOffset() (offset time.Duration, ok bool) {
+	offset, _, ok = c.clock.Offset()
+	return
+}
+
+// PeerAddresses returns the (unexpired) known addresses of a peer
+// tagged with their address class.
+func (c *Core) PeerAddresses(peer *util.PeerID) []*util.Address {
+	return c.peers.Get(peer, "")
+}
+
+// HelloTTL returns the lifetime of a HELLO with our own addresses: a
+// HELLO has a single expiration, so the shortest lifetime of all
+// advertised endpoint addresses is used.
+func (c *Core) HelloTTL() (ttl time.Duration) {
+	for _, epRef := range c.advertised() {
+		t := c.policy.HelloTTL(epRef.addr)
+		if epRef.ttl > 0 && epRef.ttl < t {
+			t = epRef.ttl
+		}
+		if ttl == 0 || t < ttl {
+			ttl = t
+		}
+	}
+	if ttl == 0 {
+		ttl = c.policy.HelloTTL(new(util.Address))
+	}
+	return
+}
+
+// Policy returns the address lifetime policy
+func (c *Core) Policy() *AddrPolicy {
+	return c.policy
+}
+
 //----------------------------------------------------------------------
 
 // Peer returns the local peer
@@ -303,16 +595,34 @@ func (c *Core) PeerID() *util.PeerID {
 	return c.local.GetID()
 }
 
+// Connected returns the list of currently connected peers.
+func (c *Core) Connected() (list []*util.PeerID) {
+	_ = c.connected.ProcessRange(func(key string, _ time.Time, _ int) error {
+		data, err := util.DecodeStringToBinary(key, 32)
+		if err == nil {
+			list = append(list, util.NewPeerID(data))
+		}
+		return nil
+	}, true)
+	return
+}
+
 //----------------------------------------------------------------------
 
 // Sign a signable onject with private peer key
 func (c *Core) Sign(obj crypto.Signable) error {
-	sd := obj.SignedData()
-	sig, err := c.local.prv.EdSign(sd)
-	if err != nil {
-		return err
-	}
-	return obj.SetSignature(util.NewPeerSignature(sig.Bytes()))
+	return c.signer.Sign(obj)
+}
+
+// SignAsync queues an object for signing by the pool of signing workers;
+// 'done' is called with the result (see Signer.SignAsync).
+func (c *Core) SignAsync(ctx context.Context, obj crypto.Signable, done func(error)) error {
+	return c.signer.SignAsync(ctx, obj, done)
+}
+
+// SignBatch signs a list of objects in parallel (see Signer.SignBatch).
+func (c *Core) SignBatch(ctx context.Context, objs ...crypto.Signable) error {
+	return c.signer.SignBatch(ctx, objs...)
 }
 
 //----------------------------------------------------------------------
@@ -353,33 +663,55 @@ func (c *Core) Drop(peer *util.PeerID) {
 
 // Register a named event listener.
 func (c *Core) Register(name string, l *Listener) {
+	c.lmtx.Lock()
+	defer c.lmtx.Unlock()
 	c.listeners[name] = l
+	c.updateTypeMap()
 }
 
 // Unregister named event listener.
 func (c *Core) Unregister(name string) *Listener {
+	c.lmtx.Lock()
+	defer c.lmtx.Unlock()
 	if l, ok := c.listeners[name]; ok {
 		delete(c.listeners, name)
+		c.updateTypeMap()
 		return l
 	}
 	return nil
 }
 
+// Pending returns the number of undelivered events per listener and the
+// number of queued signing requests ("sign").
+func (c *Core) Pending() map[string]int {
+	c.lmtx.RLock()
+	defer c.lmtx.RUnlock()
+	out := make(map[string]int)
+	for name, l := range c.listeners {
+		out[name] = len(l.ch)
+	}
+	out["sign"] = c.signer.Pending()
+	return out
+}
+
 // internal: dispatch event to listeners
 func (c *Core) dispatch(ev *Event) {
+	c.lmtx.RLock()
+	defer c.lmtx.RUnlock()
+
 	// dispatch event to listeners
 	for _, l := range c.listeners {
 		if l.filter.CheckEvent(ev.ID) {
 			if ev.ID == EV_MESSAGE {
 				mt := ev.Msg.Type()
 				if mt != 0 && !l.filter.CheckMsgType(mt) {
-					// skip event
-					return
+					// skip listener
+					continue
 				}
 			}
-			go func() {
+			go func(l *Listener) {
 				l.ch <- ev
-			}()
+			}(l)
 		}
 	}
 }
@@ -23,6 +23,7 @@ import (
 	"context"
 	"encoding/hex"
 	"gnunet/config"
+	"gnunet/message"
 	"gnunet/transport"
 	"gnunet/util"
 	"log"
@@ -96,6 +97,81 @@ func TestCoreSimple(t *testing.T) {
 	time.Sleep(5 * time.Second)
 }
 
+//----------------------------------------------------------------------
+// Address validation (PING/PONG) between two local nodes
+//----------------------------------------------------------------------
+
+// TestAddressValidation checks that an address is only validated if the
+// peer at that address answers a PING with a signed PONG.
+func TestAddressValidation(t *testing.T) {
+	cfg := func(name, seed string) *config.NodeConfig {
+		return &config.NodeConfig{
+			Name:        name,
+			PrivateSeed: seed,
+			Endpoints: []*config.EndpointConfig{
+				{
+					ID:      name,
+					Network: "ip+udp",
+					Address: "127.0.0.1",
+					Port:    0,
+					TTL:     86400,
+				},
+			},
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		time.Sleep(time.Second)
+	}()
+
+	node1, err := NewTestNode(ctx, t, cfg("v1", "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node1.Shutdown()
+	node2, err := NewTestNode(ctx, t, cfg("v2", "Bv9umksEO51jjWWrOGEH+4r8wl9Vi+LItpdBpTOi2PE="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node2.Shutdown()
+	peer2 := node2.peer.GetID()
+
+	// address of node2 is validated
+	if node1.core.Validated(peer2, node2.addr) {
+		t.Fatal("address validated before PING")
+	}
+	if !node1.core.validate(ctx, peer2, node2.addr, 5*time.Second) {
+		t.Fatal("address not validated")
+	}
+	if !node1.core.Validated(peer2, node2.addr) {
+		t.Fatal("validation not recorded")
+	}
+	if v, ok := node1.core.validations.Get(validationKey(peer2, node2.addr), 0); !ok || v.rtt == 0 {
+		t.Fatal("round-trip time not measured")
+	}
+
+	// address of node2 claimed by another peer is not validated
+	other := util.NewPeerID(util.NewRndArray(32))
+	if node1.core.validate(ctx, other, node2.addr, 2*time.Second) {
+		t.Fatal("foreign address validated")
+	}
+
+	// sending to an address that is not validated doesn't wait for the
+	// validation; only HELLOs are sent.
+	node1.core.peers.Add(other, node2.addr)
+	start := time.Now()
+	if err = node1.core.Send(ctx, other, message.NewSessionKeepAliveMsg()); err != ErrCoreNotSent {
+		t.Fatalf("message sent to unvalidated address: %v", err)
+	}
+	if time.Since(start) >= message.AcceptablePingDelay {
+		t.Fatal("send blocked by address validation")
+	}
+	if err = node1.core.Send(ctx, other, message.NewDHTP2PHelloMsg()); err != nil {
+		t.Fatalf("HELLO not sent: %v", err)
+	}
+}
+
 //----------------------------------------------------------------------
 // Two node GNUnet both running locally, but exchanging messages over
 // the internet (UPNP router required).
@@ -0,0 +1,161 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"gnunet/script"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Peer disconnects
+//
+// Every disconnect of a peer carries a reason: it is logged, counted and
+// passed on in the EV_DISCONNECT event, so operators and tests can tell
+// network issues (keep-alive timeouts) from policy enforcement (eviction,
+// quota and protocol violations).
+//----------------------------------------------------------------------
+
+// DisconnectReason tells why a peer was disconnected.
+type DisconnectReason int
+
+// Disconnect reasons
+//
+//nolint:stylecheck // allow non-camel-case in constants
+const (
+	DR_UNKNOWN   DisconnectReason = iota // unspecified reason
+	DR_EVICTED                           // evicted for a new peer (connection limit)
+	DR_QUOTA                             // peer exceeded a quota
+	DR_PROTOCOL                          // peer violated the protocol
+	DR_KEEPALIVE                         // no traffic from peer (keep-alive timeout)
+	DR_SHUTDOWN                          // local node is shutting down
+)
+
+// String returns the name of a disconnect reason.
+func (r DisconnectReason) String() string {
+	switch r {
+	case DR_EVICTED:
+		return "evicted"
+	case DR_QUOTA:
+		return "quota"
+	case DR_PROTOCOL:
+		return "protocol"
+	case DR_KEEPALIVE:
+		return "keepalive"
+	case DR_SHUTDOWN:
+		return "shutdown"
+	}
+	return "unknown"
+}
+
+// Disconnect a peer for a given reason (with optional details for the
+// log). Services use it to drop peers that violate quotas or the protocol.
+func (c *Core) Disconnect(peer *util.PeerID, reason DisconnectReason, detail string) {
+	c.disconnect(peer, reason, detail)
+}
+
+// Disconnects returns the number of disconnected peers per reason.
+func (c *Core) Disconnects() map[string]int {
+	c.dmtx.Lock()
+	defer c.dmtx.Unlock()
+	out := make(map[string]int)
+	for r, n := range c.disconnects {
+		out[r.String()] = n
+	}
+	return out
+}
+
+// disconnect a peer: remove it from the list of connected peers, count
+// the reason and notify listeners.
+func (c *Core) disconnect(peer *util.PeerID, reason DisconnectReason, detail string) {
+	key := peer.String()
+	if _, ok := c.connected.Get(key, 0); !ok {
+		return
+	}
+	c.connected.Delete(key, 0)
+	c.typeMaps.Delete(key, 0)
+	c.activity.Delete(key, 0)
+	c.stopAccounting(peer)
+
+	c.dmtx.Lock()
+	c.disconnects.Add(reason)
+	c.dmtx.Unlock()
+
+	msg := reason.String()
+	if len(detail) > 0 {
+		msg += ": " + detail
+	}
+	logger.Printf(logger.INFO, "[core] Peer %s disconnected (%s)", peer.Short(), msg)
+	c.dispatch(&Event{
+		ID:     EV_DISCONNECT,
+		Peer:   peer,
+		Reason: reason,
+	})
+	// notify event scripts
+	go script.Run(script.HookPeerDisconnect, map[string]any{
+		"peer":   peer.String(),
+		"reason": reason.String(),
+	})
+}
+
+// disconnectAll disconnects all connected peers for a given reason.
+func (c *Core) disconnectAll(reason DisconnectReason) {
+	for _, peer := range c.Connected() {
+		c.disconnect(peer, reason, "")
+	}
+}
+
+// watchIdle disconnects peers that sent no messages within the idle
+// timeout of the connection policy.
+func (c *Core) watchIdle(ctx context.Context) {
+	idle := c.conns.IdleTimeout
+	if idle == 0 {
+		return
+	}
+	period := idle / 4
+	if period < time.Second {
+		period = time.Second
+	}
+	tick := time.NewTicker(period)
+	defer tick.Stop()
+	for {
+		select {
+		case now := <-tick.C:
+			var expired []*util.PeerID
+			_ = c.activity.ProcessRange(func(key string, seen time.Time, _ int) error {
+				if now.Sub(seen) > idle {
+					if data, err := util.DecodeStringToBinary(key, 32); err == nil {
+						expired = append(expired, util.NewPeerID(data))
+					}
+				}
+				return nil
+			}, true)
+			for _, peer := range expired {
+				c.disconnect(peer, DR_KEEPALIVE, "idle for more than "+idle.String())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
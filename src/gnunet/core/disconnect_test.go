@@ -0,0 +1,118 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/script"
+	"gnunet/service/metrics"
+	"gnunet/util"
+)
+
+// core instance with a connected peer and a listener for disconnects
+func newDisconnectCore(idle time.Duration) (*Core, *util.PeerID, chan *Event) {
+	c := &Core{
+		listeners:   make(map[string]*Listener),
+		connected:   util.NewMap[string, time.Time](),
+		activity:    util.NewMap[string, time.Time](),
+		typeMaps:    util.NewMap[string, *TypeMap](),
+		bandwidth:   util.NewMap[string, *peerBandwidth](),
+		disconnects: make(util.Counter[DisconnectReason]),
+		conns:       &ConnPolicy{IdleTimeout: idle},
+	}
+	peer := util.NewPeerID(util.NewRndArray(32))
+	c.connected.Put(peer.String(), time.Now(), 0)
+	c.activity.Put(peer.String(), time.Now(), 0)
+
+	ch := make(chan *Event, 1)
+	f := NewEventFilter()
+	f.AddEvent(EV_DISCONNECT)
+	c.listeners["test"] = NewListener(ch, f)
+	return c, peer, ch
+}
+
+func TestDisconnectReason(t *testing.T) {
+	c, peer, ch := newDisconnectCore(0)
+	c.Disconnect(peer, DR_PROTOCOL, "test")
+	select {
+	case ev := <-ch:
+		if ev.ID != EV_DISCONNECT || !ev.Peer.Equal(peer) || ev.Reason != DR_PROTOCOL {
+			t.Fatalf("unexpected event %s", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no disconnect event")
+	}
+	// peers are only disconnected (and counted) once
+	c.Disconnect(peer, DR_QUOTA, "")
+	if n := c.Disconnects(); len(n) != 1 || n["protocol"] != 1 {
+		t.Fatalf("unexpected counts %v", n)
+	}
+	if len(c.Connected()) != 0 {
+		t.Fatal("peer still connected")
+	}
+}
+
+func TestDisconnectIdle(t *testing.T) {
+	c, peer, ch := newDisconnectCore(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.watchIdle(ctx)
+
+	select {
+	case ev := <-ch:
+		if !ev.Peer.Equal(peer) || ev.Reason != DR_KEEPALIVE {
+			t.Fatalf("unexpected event %s", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle peer not disconnected")
+	}
+	if n := c.Disconnects(); n["keepalive"] != 1 {
+		t.Fatalf("unexpected counts %v", n)
+	}
+}
+
+func TestDisconnectScript(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "disconnect.star")
+	src := "def on_peer_disconnect(ev):\n    return ev[\"reason\"] != \"protocol\"\n"
+	if err := os.WriteFile(fname, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := script.Setup(&config.ScriptConfig{Files: []string{fname}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = script.Setup(nil) }()
+
+	// the hook vetoes protocol disconnects (counted in the metrics)
+	runs := metrics.NewCounter("gnunet_script_hooks_total", "", "hook", "result")
+	before := runs.Value(script.HookPeerDisconnect, "veto")
+	c, peer, _ := newDisconnectCore(0)
+	c.Disconnect(peer, DR_PROTOCOL, "test")
+	for i := 0; i < 100 && runs.Value(script.HookPeerDisconnect, "veto") == before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runs.Value(script.HookPeerDisconnect, "veto") != before+1 {
+		t.Fatal("disconnect hook not run")
+	}
+}
@@ -37,6 +37,7 @@ const (
 	EV_CONNECT    = iota // peer connected
 	EV_DISCONNECT        // peer disconnected
 	EV_MESSAGE           // incoming message
+	EV_ADDRESSES         // local (advertised) addresses changed
 )
 
 // EventFilter is a filter for events a listener is interested in.
@@ -95,6 +96,8 @@ type Event struct {
 	Msg   message.Message     // GNUnet message (can be nil)
 	Resp  transport.Responder // reply handler (can be nil)
 	Label string              // event label (can be empty)
+
+	Reason DisconnectReason // reason of a disconnect (EV_DISCONNECT)
 }
 
 // String returns a human-readable representation of an event.
@@ -107,6 +110,9 @@ func (e *Event) String() string {
 	if e.Msg != nil {
 		s += fmt.Sprintf(",msg=%s", e.Msg.Type())
 	}
+	if e.ID == EV_DISCONNECT {
+		s += ",reason=" + e.Reason.String()
+	}
 	return s + "}"
 }
 
@@ -0,0 +1,57 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gnunet/config"
+)
+
+// Test that events are dispatched to all registered listeners.
+func TestDispatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := NewCore(ctx, &config.NodeConfig{
+		Name:        "test",
+		PrivateSeed: "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// register listeners for connect events
+	chs := make([]chan *Event, 4)
+	for i := range chs {
+		chs[i] = make(chan *Event, 1)
+		f := NewEventFilter()
+		f.AddEvent(EV_CONNECT)
+		c.Register(fmt.Sprintf("l%d", i), NewListener(chs[i], f))
+	}
+	c.dispatch(&Event{ID: EV_CONNECT})
+	for i, ch := range chs {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("listener #%d: no event", i)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"gnunet/config"
+	"gnunet/transport"
+	"gnunet/transport/nat"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// NAT traversal
+//----------------------------------------------------------------------
+
+// startNAT runs NAT traversal for the endpoints on IP addresses (without
+// UPnP forwarding configured on the endpoint): the external addresses
+// are advertised in addition to the endpoint addresses.
+func (c *Core) startNAT(ctx context.Context, node *config.NodeConfig) {
+	c.nat = nat.NewManager(node.Name, node.NAT)
+	for _, epRef := range c.endpoints {
+		if len(epRef.upnpID) > 0 {
+			continue
+		}
+		switch epRef.addr.Class {
+		case AddrClassPublic, AddrClassPrivate:
+		default:
+			continue
+		}
+		addr := epRef.ep.Address()
+		_, p, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+		c.nat.Add(epRef.id, transport.EpProtocol(addr.Network()), port)
+	}
+	c.nat.Run(ctx, c.natChanged)
+}
+
+// natChanged is called if the external address or a port mapping
+// changed: the external addresses of endpoints are updated and an
+// EV_ADDRESSES event is sent to listeners (to republish HELLOs).
+func (c *Core) natChanged(ip net.IP, maps []nat.Mapping) {
+	for _, mp := range maps {
+		epRef, ok := c.endpoints[mp.ID]
+		if !ok {
+			continue
+		}
+		if mp.ExtPort == 0 {
+			c.external.Delete(mp.ID, 0)
+			continue
+		}
+		ext := util.NewAddress(epRef.addr.Network(), net.JoinHostPort(ip.String(), strconv.Itoa(mp.ExtPort)))
+		if ext.String() == epRef.addr.String() {
+			// endpoint is directly reachable
+			c.external.Delete(mp.ID, 0)
+			continue
+		}
+		ext = c.policy.Tag(ext)
+		logger.Printf(logger.INFO, "[core] External address of endpoint %s is %s", mp.ID, ext.URI())
+		c.external.Put(mp.ID, ext, 0)
+	}
+	c.dispatch(&Event{
+		ID:    EV_ADDRESSES,
+		Peer:  c.PeerID(),
+		Label: "nat",
+	})
+}
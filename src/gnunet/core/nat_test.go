@@ -0,0 +1,77 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/transport/nat"
+	"gnunet/util"
+)
+
+func TestNATAddresses(t *testing.T) {
+	local, err := NewLocalPeer(peerCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Core{
+		local:     local,
+		listeners: make(map[string]*Listener),
+		policy:    NewAddrPolicy(nil),
+		endpoints: make(map[string]*EndpointRef),
+		external:  util.NewMap[string, *util.Address](),
+	}
+	for id, s := range map[string]string{
+		"udp": "ip+udp://192.168.1.1:2086",
+		"tcp": "ip+tcp://192.168.1.1:2087",
+	} {
+		a, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.endpoints[id] = &EndpointRef{id: id, addr: c.policy.Tag(a)}
+	}
+	ch := make(chan *Event, 1)
+	f := NewEventFilter()
+	f.AddEvent(EV_ADDRESSES)
+	c.listeners["test"] = NewListener(ch, f)
+
+	// external address for UDP endpoint only (TCP port not mapped)
+	c.natChanged(net.IPv4(198, 51, 100, 7), []nat.Mapping{
+		{ID: "udp", Network: "udp", Port: 2086, ExtPort: 40000},
+		{ID: "tcp", Network: "tcp", Port: 2087},
+	})
+	select {
+	case ev := <-ch:
+		if ev.ID != EV_ADDRESSES {
+			t.Fatalf("unexpected event %s", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no address event")
+	}
+	list, err := c.HelloAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].URI() != "ip+udp://198.51.100.7:40000" || list[0].Class != AddrClassPublic {
+		t.Fatalf("unexpected HELLO addresses %v", list)
+	}
+}
@@ -0,0 +1,244 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Address lifetime policy
+//
+// The policy decides how long addresses learned from other peers are
+// considered valid, when addresses and HELLOs need to be refreshed and
+// which expiration is used for our own addresses in HELLOs we emit.
+// Lifetimes depend on the class of an address: a loopback address is
+// only meaningful for a short time (tests, local setups), addresses in
+// private networks change more often than public ones; addresses on a
+// relay or proxy are leased and change even more often.
+//
+// Expiration dates set by other peers are based on their clocks; the
+// policy tolerates a limited clock skew and caps lifetimes so a peer
+// with a clock running ahead can't make us keep its addresses forever.
+//----------------------------------------------------------------------
+
+// Address classes
+const (
+	AddrClassLoopback = "loopback" // loopback addresses (127.0.0.0/8, ::1)
+	AddrClassPrivate  = "private"  // private and link-local addresses (LAN)
+	AddrClassPublic   = "public"   // globally routable addresses (WAN)
+	AddrClassRelay    = "relay"    // addresses on a relay or proxy
+	AddrClassOther    = "other"    // non-IP addresses
+)
+
+// Default policy settings
+var (
+	DefaultMaxSkew = util.DefaultClockSkew // tolerated clock skew
+	DefaultRefresh = 0.25                  // refresh below 1/4 of lifetime
+
+	// default lifetimes per address class
+	DefaultAddrClasses = map[string]*AddrClassPolicy{
+		AddrClassLoopback: {HelloTTL: time.Hour, MaxTTL: time.Hour},
+		AddrClassPrivate:  {HelloTTL: 6 * time.Hour, MaxTTL: 12 * time.Hour},
+		AddrClassPublic:   {HelloTTL: 12 * time.Hour, MaxTTL: 24 * time.Hour},
+		AddrClassRelay:    {HelloTTL: 6 * time.Hour, MaxTTL: 12 * time.Hour},
+		AddrClassOther:    {HelloTTL: 12 * time.Hour, MaxTTL: 24 * time.Hour},
+	}
+)
+
+// addrIP returns the IP of an address (or nil for non-IP addresses).
+func addrIP(addr *util.Address) net.IP {
+	s := addr.String()
+	if idx := strings.LastIndex(s, ":"); idx != -1 {
+		s = s[:idx]
+	}
+	return net.ParseIP(strings.Trim(s, "[]"))
+}
+
+// IsAddrClass returns true if the name is a known address class.
+func IsAddrClass(name string) bool {
+	_, ok := DefaultAddrClasses[name]
+	return ok
+}
+
+// AddrClass returns the class of an address: the class an address is
+// tagged with or the class derived from the address itself. Unix domain
+// socket addresses are loopback addresses.
+func AddrClass(addr *util.Address) string {
+	if len(addr.Class) > 0 {
+		return addr.Class
+	}
+	if strings.HasPrefix(addr.Netw, "unix") {
+		return AddrClassLoopback
+	}
+	ip := addrIP(addr)
+	switch {
+	case ip == nil:
+		return AddrClassOther
+	case ip.IsLoopback():
+		return AddrClassLoopback
+	case ip.IsPrivate() || ip.IsLinkLocalUnicast():
+		return AddrClassPrivate
+	}
+	return AddrClassPublic
+}
+
+//----------------------------------------------------------------------
+
+// AddrClassPolicy holds the lifetimes for a class of addresses.
+type AddrClassPolicy struct {
+	HelloTTL time.Duration // lifetime of own addresses in HELLOs
+	MaxTTL   time.Duration // max. lifetime of learned addresses
+}
+
+// AddrPolicy governs address lifetimes.
+type AddrPolicy struct {
+	MaxSkew time.Duration               // tolerated clock skew
+	Refresh float64                     // refresh fraction of lifetime
+	Classes map[string]*AddrClassPolicy // lifetimes per address class
+	Relays  []*net.IPNet                // networks of relays and proxies
+}
+
+// NewAddrPolicy creates a new policy from configuration; missing
+// settings are taken from the defaults.
+func NewAddrPolicy(cfg *config.AddressPolicyConfig) *AddrPolicy {
+	p := &AddrPolicy{
+		MaxSkew: DefaultMaxSkew,
+		Refresh: DefaultRefresh,
+		Classes: make(map[string]*AddrClassPolicy),
+	}
+	for name, cp := range DefaultAddrClasses {
+		p.Classes[name] = &AddrClassPolicy{
+			HelloTTL: cp.HelloTTL,
+			MaxTTL:   cp.MaxTTL,
+		}
+	}
+	if cfg == nil {
+		return p
+	}
+	if cfg.MaxSkew > 0 {
+		p.MaxSkew = time.Duration(cfg.MaxSkew) * time.Second
+	}
+	if cfg.Refresh > 0 && cfg.Refresh < 1 {
+		p.Refresh = cfg.Refresh
+	}
+	for _, r := range cfg.Relays {
+		if n := parseNet(r); n != nil {
+			p.Relays = append(p.Relays, n)
+		}
+	}
+	for name, cc := range cfg.Classes {
+		cp, ok := p.Classes[name]
+		if !ok || cc == nil {
+			continue
+		}
+		if cc.HelloTTL > 0 {
+			cp.HelloTTL = time.Duration(cc.HelloTTL) * time.Second
+		}
+		if cc.MaxTTL > 0 {
+			cp.MaxTTL = time.Duration(cc.MaxTTL) * time.Second
+		}
+	}
+	return p
+}
+
+// parseNet parses a network in CIDR notation or a single IP address.
+func parseNet(s string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	bits := 8 * len(ip)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// Class returns the class of an address. Untagged addresses on a
+// configured relay network are in the relay class.
+func (p *AddrPolicy) Class(addr *util.Address) string {
+	if len(addr.Class) == 0 {
+		if ip := addrIP(addr); ip != nil {
+			for _, n := range p.Relays {
+				if n.Contains(ip) {
+					return AddrClassRelay
+				}
+			}
+		}
+	}
+	return AddrClass(addr)
+}
+
+// Tag returns a copy of an address tagged with its class.
+func (p *AddrPolicy) Tag(addr *util.Address) *util.Address {
+	return &util.Address{
+		Netw:    addr.Netw,
+		Options: addr.Options,
+		Expire:  addr.Expire,
+		Address: addr.Address,
+		Class:   p.Class(addr),
+	}
+}
+
+// class returns the lifetimes for an address.
+func (p *AddrPolicy) class(addr *util.Address) *AddrClassPolicy {
+	if cp, ok := p.Classes[p.Class(addr)]; ok {
+		return cp
+	}
+	return p.Classes[AddrClassOther]
+}
+
+// LearnedExpire returns the expiration of a learned address as seen by
+// the local peer. Expiration dates in the past are accepted within the
+// tolerated clock skew; the lifetime of an address is capped by its
+// class. Returns false if the address has expired.
+func (p *AddrPolicy) LearnedExpire(addr *util.Address, now util.AbsoluteTime) (util.AbsoluteTime, bool) {
+	exp := addr.Expire
+	if exp.Compare(util.AbsoluteTimeNever()) != 0 {
+		exp = exp.Add(p.MaxSkew)
+	}
+	if exp.Compare(now) <= 0 {
+		return exp, false
+	}
+	if limit := now.Add(p.class(addr).MaxTTL); exp.Compare(limit) > 0 {
+		exp = limit
+	}
+	return exp, true
+}
+
+// HelloTTL returns the lifetime of an own address in HELLOs we emit.
+func (p *AddrPolicy) HelloTTL(addr *util.Address) time.Duration {
+	return p.class(addr).HelloTTL
+}
+
+// NeedsRefresh returns true if an object with given lifetime expiring at
+// 'exp' should be refreshed (remaining lifetime below refresh fraction).
+func (p *AddrPolicy) NeedsRefresh(exp util.AbsoluteTime, ttl time.Duration, now util.AbsoluteTime) bool {
+	margin := time.Duration(float64(ttl) * p.Refresh)
+	return now.Add(margin).Compare(exp) >= 0
+}
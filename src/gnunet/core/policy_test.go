@@ -0,0 +1,160 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/util"
+)
+
+func TestAddrClass(t *testing.T) {
+	cases := map[string]string{
+		"ip+udp://127.0.0.1:2086":    AddrClassLoopback,
+		"ip+udp://[::1]:2086":        AddrClassLoopback,
+		"ip+udp://192.168.1.2:2086":  AddrClassPrivate,
+		"ip+udp://10.0.0.1:2086":     AddrClassPrivate,
+		"ip+udp://[fe80::1]:2086":    AddrClassPrivate,
+		"ip+udp://8.8.8.8:2086":      AddrClassPublic,
+		"ip+udp://[2001:db8::1]:80":  AddrClassPublic,
+		"gnunet+tcp://some.host:123": AddrClassOther,
+	}
+	for s, class := range cases {
+		addr, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := AddrClass(addr); c != class {
+			t.Errorf("%s: got class %s, expected %s", s, c, class)
+		}
+	}
+}
+
+func TestAddrPolicyClass(t *testing.T) {
+	p := NewAddrPolicy(&config.AddressPolicyConfig{
+		Relays: []string{"10.1.2.0/24", "8.8.4.4", "no-network"},
+	})
+	if len(p.Relays) != 2 {
+		t.Fatalf("expected 2 relay networks, got %d", len(p.Relays))
+	}
+	cases := map[string]string{
+		"ip+udp://10.1.2.3:2086": AddrClassRelay,
+		"ip+udp://10.1.3.3:2086": AddrClassPrivate,
+		"ip+udp://8.8.4.4:2086":  AddrClassRelay,
+		"ip+udp://8.8.8.8:2086":  AddrClassPublic,
+	}
+	for s, class := range cases {
+		addr, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagged := p.Tag(addr)
+		if tagged.Class != class {
+			t.Errorf("%s: got class %s, expected %s", s, tagged.Class, class)
+		}
+		if !tagged.Equal(addr) {
+			t.Errorf("%s: tagged address differs", s)
+		}
+	}
+	// tags are not re-classified
+	addr, _ := util.ParseAddress("ip+udp://8.8.4.4:2086")
+	addr.Class = AddrClassPublic
+	if c := p.Class(addr); c != AddrClassPublic {
+		t.Errorf("tagged address re-classified as %s", c)
+	}
+}
+
+func TestAddrPolicyConfig(t *testing.T) {
+	p := NewAddrPolicy(&config.AddressPolicyConfig{
+		MaxSkew: 60,
+		Refresh: 2, // invalid: default is used
+		Classes: map[string]*config.AddressClassConfig{
+			AddrClassPublic: {HelloTTL: 3600},
+			"unknown":       {HelloTTL: 1},
+		},
+	})
+	if p.MaxSkew != time.Minute {
+		t.Errorf("wrong clock skew %s", p.MaxSkew)
+	}
+	if p.Refresh != DefaultRefresh {
+		t.Errorf("wrong refresh fraction %f", p.Refresh)
+	}
+	pub := p.Classes[AddrClassPublic]
+	if pub.HelloTTL != time.Hour || pub.MaxTTL != DefaultAddrClasses[AddrClassPublic].MaxTTL {
+		t.Errorf("wrong public class policy %v", pub)
+	}
+	if _, ok := p.Classes["unknown"]; ok {
+		t.Error("unknown class added")
+	}
+	// defaults are not modified by configuration
+	if DefaultAddrClasses[AddrClassPublic].HelloTTL == time.Hour {
+		t.Error("default policy modified")
+	}
+}
+
+func TestAddrPolicyLearnedExpire(t *testing.T) {
+	p := NewAddrPolicy(nil)
+	now := util.AbsoluteTimeNow()
+	addr, err := util.ParseAddress("ip+udp://8.8.8.8:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxTTL := p.Classes[AddrClassPublic].MaxTTL
+
+	cases := []struct {
+		name   string
+		expire util.AbsoluteTime // expiration set by remote peer
+		ok     bool              // address accepted?
+		result util.AbsoluteTime // expected expiration
+	}{
+		{"regular", now.Add(time.Hour), true, now.Add(time.Hour + p.MaxSkew)},
+		{"clock ahead", now.Add(10 * maxTTL), true, now.Add(maxTTL)},
+		{"never", util.AbsoluteTimeNever(), true, now.Add(maxTTL)},
+		{"clock behind", now.Add(-p.MaxSkew / 2), true, now.Add(p.MaxSkew / 2)},
+		{"expired", now.Add(-2 * p.MaxSkew), false, util.AbsoluteTime{}},
+	}
+	for _, c := range cases {
+		addr.Expire = c.expire
+		exp, ok := p.LearnedExpire(addr, now)
+		if ok != c.ok {
+			t.Errorf("%s: accepted=%v", c.name, ok)
+			continue
+		}
+		if ok && exp.Compare(c.result) != 0 {
+			t.Errorf("%s: expires %s, expected %s", c.name, exp, c.result)
+		}
+	}
+}
+
+func TestAddrPolicyRefresh(t *testing.T) {
+	p := NewAddrPolicy(nil)
+	now := util.AbsoluteTimeNow()
+	ttl := 4 * time.Hour
+	if p.NeedsRefresh(now.Add(2*time.Hour), ttl, now) {
+		t.Error("refresh with half the lifetime remaining")
+	}
+	if !p.NeedsRefresh(now.Add(30*time.Minute), ttl, now) {
+		t.Error("no refresh below refresh fraction")
+	}
+	if !p.NeedsRefresh(now.Add(-time.Minute), ttl, now) {
+		t.Error("no refresh for expired object")
+	}
+}
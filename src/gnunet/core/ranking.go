@@ -0,0 +1,183 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Address ranking
+//
+// If multiple addresses are known for a peer, connection attempts are
+// ordered by address class first (direct addresses before relayed ones;
+// LAN addresses before WAN addresses if the local peer is on a LAN), by
+// transport class next (packet transports are cheaper than streams,
+// streams are cheaper than HTTPS) and by the measured quality of an
+// address within a class: the round-trip time of PING/PONG exchanges
+// and the ratio of failed sends. Addresses without
+// measurements are assumed to have an average round-trip time, so they
+// are tried before addresses that are known to be slow or unreliable.
+//----------------------------------------------------------------------
+
+// TransportOrder lists transport classes in the order they are tried.
+// Addresses of other classes are tried last.
+var TransportOrder = []string{"udp", "tcp", "https"}
+
+// DefaultRTT is the assumed round-trip time of unmeasured addresses.
+var DefaultRTT = 250 * time.Millisecond
+
+// ConnectOrder lists address classes in the order they are tried when
+// connecting to a peer. LAN addresses are tried after WAN addresses if
+// the local peer has no LAN endpoint.
+var ConnectOrder = []string{
+	AddrClassLoopback, AddrClassPrivate, AddrClassPublic, AddrClassOther, AddrClassRelay,
+}
+
+// AdvertiseOrder lists address classes in the order they appear in our
+// own HELLOs (and HELLO URLs). Loopback addresses are not advertised.
+var AdvertiseOrder = []string{
+	AddrClassPublic, AddrClassOther, AddrClassPrivate, AddrClassRelay,
+}
+
+// classRank returns the position of a class in an order list.
+func classRank(order []string, class string) int {
+	for i, c := range order {
+		if c == class {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// advertiseRank returns the position of an own address in HELLOs.
+func advertiseRank(addr *util.Address) int {
+	return classRank(AdvertiseOrder, AddrClass(addr))
+}
+
+// connectRank returns the position of a peer address for connection
+// attempts.
+func (c *Core) connectRank(addr *util.Address, lan bool) int {
+	class := c.policy.Class(addr)
+	if class == AddrClassPrivate && !lan {
+		// after WAN addresses, but before relays
+		return 2*classRank(ConnectOrder, AddrClassOther) + 1
+	}
+	return 2 * classRank(ConnectOrder, class)
+}
+
+// onLAN returns true if the local peer has an endpoint on a LAN.
+func (c *Core) onLAN() bool {
+	for _, epRef := range c.endpoints {
+		if epRef.addr.Class == AddrClassPrivate {
+			return true
+		}
+	}
+	return false
+}
+
+// transportRank returns the position of an address class in the
+// transport order.
+func transportRank(addr *util.Address) int {
+	class := transport.EpProtocol(addr.Netw)
+	if strings.HasSuffix(addr.Netw, "http") || strings.HasSuffix(addr.Netw, "https") {
+		class = "https"
+	}
+	for i, c := range TransportOrder {
+		if c == class {
+			return i
+		}
+	}
+	return len(TransportOrder)
+}
+
+// addrScore returns the expected cost of using an address: the round-trip
+// time plus the time lost waiting for a response if the address fails
+// (weighted by the observed failure rate).
+func addrScore(rtt time.Duration, success, failure uint32) time.Duration {
+	if rtt == 0 {
+		rtt = DefaultRTT
+	}
+	rate := float64(failure) / float64(success+failure+1)
+	return rtt + time.Duration(rate*float64(message.AcceptablePingDelay))
+}
+
+// rankAddresses sorts the addresses of a peer for connection attempts.
+// The order of the input list is kept for addresses with equal rank.
+func (c *Core) rankAddresses(peer *util.PeerID, list []*util.Address) []*util.Address {
+	type ranked struct {
+		addr   *util.Address
+		aclass int
+		class  int
+		score  time.Duration
+	}
+	lan := c.onLAN()
+	rl := make([]*ranked, len(list))
+	for i, addr := range list {
+		r := &ranked{
+			addr:   addr,
+			aclass: c.connectRank(addr, lan),
+			class:  transportRank(addr),
+		}
+		var rtt time.Duration
+		var success, failure uint32
+		if v, ok := c.validations.Get(validationKey(peer, addr), 0); ok {
+			v.Lock()
+			rtt, success, failure = v.rtt, v.success, v.failure
+			v.Unlock()
+		}
+		r.score = addrScore(rtt, success, failure)
+		rl[i] = r
+	}
+	sort.SliceStable(rl, func(i, j int) bool {
+		if rl[i].aclass != rl[j].aclass {
+			return rl[i].aclass < rl[j].aclass
+		}
+		if rl[i].class != rl[j].class {
+			return rl[i].class < rl[j].class
+		}
+		return rl[i].score < rl[j].score
+	})
+	res := make([]*util.Address, len(rl))
+	for i, r := range rl {
+		res[i] = r.addr
+	}
+	return res
+}
+
+// record the outcome of using an address for a peer
+func (c *Core) record(peer *util.PeerID, addr *util.Address, ok bool) {
+	v, found := c.validations.Get(validationKey(peer, addr), 0)
+	if !found {
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	if ok {
+		v.success++
+	} else {
+		v.failure++
+	}
+}
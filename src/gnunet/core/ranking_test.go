@@ -0,0 +1,168 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+func TestTransportRank(t *testing.T) {
+	cases := map[string]int{
+		"ip+udp://1.2.3.4:2086":       0,
+		"udp://1.2.3.4:2086":          0,
+		"tcp://1.2.3.4:2086":          1,
+		"https://gnunet.example.org/": 2,
+		"bt://00:11:22:33:44:55":      3,
+	}
+	for s, rank := range cases {
+		addr, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := transportRank(addr); r != rank {
+			t.Errorf("%s: expected rank %d, got %d", s, rank, r)
+		}
+	}
+}
+
+func TestRankAddresses(t *testing.T) {
+	c := &Core{
+		validations: util.NewMap[string, *addrValidation](),
+		policy:      NewAddrPolicy(nil),
+	}
+	peer := util.NewPeerID(util.NewRndArray(32))
+	addr := func(s string) *util.Address {
+		a, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	stats := func(a *util.Address, rtt time.Duration, success, failure uint32) {
+		c.validations.Put(validationKey(peer, a), &addrValidation{
+			peer:    peer,
+			addr:    a,
+			rtt:     rtt,
+			success: success,
+			failure: failure,
+		}, 0)
+	}
+	var (
+		tcp     = addr("tcp://1.2.3.4:2086")
+		slow    = addr("ip+udp://1.2.3.5:2086")
+		fast    = addr("ip+udp://1.2.3.6:2086")
+		unknown = addr("ip+udp://1.2.3.7:2086")
+		flaky   = addr("ip+udp://1.2.3.8:2086")
+	)
+	stats(tcp, 10*time.Millisecond, 10, 0)
+	stats(slow, time.Second, 10, 0)
+	stats(fast, 20*time.Millisecond, 10, 0)
+	stats(flaky, 20*time.Millisecond, 1, 20)
+
+	list := c.rankAddresses(peer, []*util.Address{tcp, slow, flaky, unknown, fast})
+	expect := []*util.Address{fast, unknown, flaky, slow, tcp}
+	for i, a := range list {
+		if a != expect[i] {
+			t.Fatalf("#%d: expected %s, got %s", i, expect[i].URI(), a.URI())
+		}
+	}
+
+	// failures change the order
+	for i := 0; i < 100; i++ {
+		c.record(peer, fast, false)
+	}
+	if list = c.rankAddresses(peer, list); list[0] != unknown {
+		t.Fatalf("expected %s first, got %s", unknown.URI(), list[0].URI())
+	}
+}
+
+func TestRankAddressClasses(t *testing.T) {
+	c := &Core{
+		validations: util.NewMap[string, *addrValidation](),
+		policy: NewAddrPolicy(&config.AddressPolicyConfig{
+			Relays: []string{"5.6.7.8"},
+		}),
+		endpoints: make(map[string]*EndpointRef),
+	}
+	peer := util.NewPeerID(util.NewRndArray(32))
+	addr := func(s string) *util.Address {
+		a, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	var (
+		relay = addr("ip+udp://5.6.7.8:2086")
+		lan   = addr("tcp://192.168.1.2:2086")
+		wan   = addr("tcp://1.2.3.4:2086")
+	)
+	check := func(expect ...*util.Address) {
+		t.Helper()
+		list := c.rankAddresses(peer, []*util.Address{relay, lan, wan})
+		for i, a := range list {
+			if a != expect[i] {
+				t.Fatalf("#%d: expected %s, got %s", i, expect[i].URI(), a.URI())
+			}
+		}
+	}
+	// without a LAN endpoint: WAN before LAN, relays last
+	check(wan, lan, relay)
+
+	// on a LAN: LAN before WAN
+	c.endpoints["lan"] = &EndpointRef{addr: c.policy.Tag(addr("ip+udp://192.168.1.1:2086"))}
+	check(lan, wan, relay)
+}
+
+func TestHelloAddresses(t *testing.T) {
+	c := &Core{
+		policy:    NewAddrPolicy(nil),
+		endpoints: make(map[string]*EndpointRef),
+		external:  util.NewMap[string, *util.Address](),
+	}
+	for id, s := range map[string]string{
+		"lo":  "ip+udp://127.0.0.1:2086",
+		"lan": "ip+udp://192.168.1.1:2086",
+		"wan": "ip+udp://1.2.3.4:2086",
+	} {
+		a, err := util.ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.endpoints[id] = &EndpointRef{id: id, addr: c.policy.Tag(a)}
+	}
+	list, err := c.HelloAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Class != AddrClassPublic || list[1].Class != AddrClassPrivate {
+		t.Fatalf("unexpected HELLO addresses %v", list)
+	}
+	// loopback addresses are advertised in local-only mode
+	transport.LocalOnly = true
+	defer func() { transport.LocalOnly = false }()
+	if list, _ = c.HelloAddresses(); len(list) != 3 {
+		t.Fatalf("unexpected HELLO addresses %v", list)
+	}
+}
@@ -0,0 +1,145 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"runtime"
+
+	"gnunet/crypto"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Asynchronous signing: Path elements of forwarded DHT messages must be
+// signed with the key of the local peer. Signing on the message handling
+// path would delay the processing of unrelated messages, so signatures
+// are created by a bounded pool of workers. If the request queue is full,
+// callers are blocked until there is room again (back-pressure).
+//----------------------------------------------------------------------
+
+// Signing pool parameters
+var (
+	SignWorkers   = runtime.NumCPU() // number of signing workers
+	SignQueueSize = 256              // max. number of queued requests
+)
+
+// signRequest is a queued request to sign an object.
+type signRequest struct {
+	obj  crypto.Signable // object to be signed
+	done func(error)     // completion callback
+}
+
+// Signer signs objects with a private key, either synchronously or
+// queued for a pool of workers.
+type Signer struct {
+	prv   *ed25519.PrivateKey // signing key
+	queue chan *signRequest   // pending requests
+}
+
+// NewSigner creates a signer with 'workers' workers and a queue of
+// 'size' requests. The workers run until the context is done.
+func NewSigner(ctx context.Context, prv *ed25519.PrivateKey, workers, size int) *Signer {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &Signer{
+		prv:   prv,
+		queue: make(chan *signRequest, size),
+	}
+	for i := 0; i < workers; i++ {
+		go s.run(ctx)
+	}
+	return s
+}
+
+// worker loop: sign queued objects and report completion.
+func (s *Signer) run(ctx context.Context) {
+	for {
+		select {
+		case req := <-s.queue:
+			req.done(s.Sign(req.obj))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sign an object synchronously.
+func (s *Signer) Sign(obj crypto.Signable) error {
+	sig, err := s.prv.EdSign(obj.SignedData())
+	if err != nil {
+		return err
+	}
+	return obj.SetSignature(util.NewPeerSignature(sig.Bytes()))
+}
+
+// SignAsync queues an object for signing; 'done' is called by a worker
+// with the result of the operation (and should not block for long). If
+// the queue is full, SignAsync blocks until the request is queued or the
+// context is done (in which case 'done' is never called).
+func (s *Signer) SignAsync(ctx context.Context, obj crypto.Signable, done func(error)) error {
+	req := &signRequest{obj: obj, done: done}
+	select {
+	case s.queue <- req:
+		return nil
+	default:
+	}
+	logger.Println(logger.DBG, "[core] signing queue full -- waiting")
+	select {
+	case s.queue <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SignBatch signs a list of objects in parallel and waits until all
+// signatures are created or the context is done. The first error
+// encountered is returned.
+func (s *Signer) SignBatch(ctx context.Context, objs ...crypto.Signable) (err error) {
+	// buffered: late completions never block a worker
+	res := make(chan error, len(objs))
+	n := 0
+	for _, obj := range objs {
+		if err = s.SignAsync(ctx, obj, func(e error) { res <- e }); err != nil {
+			return
+		}
+		n++
+	}
+	for ; n > 0; n-- {
+		select {
+		case e := <-res:
+			if e != nil && err == nil {
+				err = e
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return
+}
+
+// Pending returns the number of queued requests.
+func (s *Signer) Pending() int {
+	return len(s.queue)
+}
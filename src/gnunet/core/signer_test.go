@@ -0,0 +1,102 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+)
+
+// newSignable returns an unsigned HELLO block of a new peer and its key.
+func newSignable(t *testing.T) (*blocks.HelloBlock, *ed25519.PrivateKey) {
+	t.Helper()
+	pk, sk := ed25519.NewKeypair()
+	addr, err := util.ParseAddress("ip+udp://127.0.0.1:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blocks.InitHelloBlock(util.NewPeerID(pk.Bytes()), []*util.Address{addr}, time.Hour), sk
+}
+
+func TestSignerAsync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hb, sk := newSignable(t)
+	s := NewSigner(ctx, sk, 2, 4)
+
+	// single asynchronous request
+	ch := make(chan error, 1)
+	if err := s.SignAsync(ctx, hb, func(err error) { ch <- err }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no completion")
+	}
+	if ok, err := hb.Verify(); !ok || err != nil {
+		t.Fatal("signature not verified")
+	}
+
+	// batch of requests (more than the queue size)
+	list := make([]*blocks.HelloBlock, 10)
+	objs := make([]crypto.Signable, len(list))
+	for i := range list {
+		list[i], _ = newSignable(t)
+		objs[i] = list[i]
+	}
+	s2 := NewSigner(ctx, sk, 2, 4)
+	if err := s2.SignBatch(ctx, objs...); err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range list {
+		if obj.Signature == nil {
+			t.Fatalf("object #%d not signed", i)
+		}
+	}
+}
+
+func TestSignerBackPressure(t *testing.T) {
+	hb, sk := newSignable(t)
+
+	// signer without workers: the queue fills up
+	s := &Signer{prv: sk, queue: make(chan *signRequest, 1)}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.SignAsync(ctx, hb, func(error) {}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending() != 1 {
+		t.Fatalf("expected 1 pending request, got %d", s.Pending())
+	}
+	if err := s.SignAsync(ctx, hb, func(error) {}); err != context.DeadlineExceeded {
+		t.Fatalf("expected blocked request, got %v", err)
+	}
+}
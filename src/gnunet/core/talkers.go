@@ -0,0 +1,112 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"sort"
+
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Top talkers
+//
+// Besides the totals used for quota enforcement, core keeps the traffic
+// of a connected peer by message type and counts the invalid messages
+// received from the peer (reported by core itself or by the services
+// handling the messages). The "top talkers" report lists the connected
+// peers sorted by bandwidth to identify abusive or broken peers.
+//----------------------------------------------------------------------
+
+// TypeStats holds the traffic statistics of a peer for a message type.
+type TypeStats struct {
+	Type     string `json:"type"`     // message type
+	BytesIn  uint64 `json:"bytesIn"`  // bytes received from peer
+	BytesOut uint64 `json:"bytesOut"` // bytes sent to peer
+	MsgsIn   uint64 `json:"msgsIn"`   // messages received from peer
+	MsgsOut  uint64 `json:"msgsOut"`  // messages sent to peer
+	Invalid  uint64 `json:"invalid"`  // invalid messages from peer
+}
+
+// PeerTraffic is the traffic report for a connected peer.
+type PeerTraffic struct {
+	Peer      string `json:"peer"`      // peer identifier
+	Short     string `json:"short"`     // display name
+	Bandwidth uint64 `json:"bandwidth"` // average bandwidth (bytes/s, in+out)
+	PeerStats
+	Types []*TypeStats `json:"types"` // traffic by message type (by bytes)
+}
+
+// TopTalkers returns the traffic reports for the connected peers sorted
+// by bandwidth (highest first). At most n reports are returned (n <= 0
+// returns all peers).
+func (c *Core) TopTalkers(n int) (list []*PeerTraffic) {
+	_ = c.bandwidth.ProcessRange(func(_ string, bw *peerBandwidth, _ int) error {
+		bw.Lock()
+		defer bw.Unlock()
+		pt := &PeerTraffic{
+			Peer:      bw.peer.String(),
+			Short:     bw.peer.Short(),
+			PeerStats: bw.stats,
+		}
+		pt.Bandwidth = uint64(pt.RateIn() + pt.RateOut())
+		for _, ts := range bw.types {
+			t := *ts
+			pt.Types = append(pt.Types, &t)
+		}
+		sort.Slice(pt.Types, func(i, j int) bool {
+			bi := pt.Types[i].BytesIn + pt.Types[i].BytesOut
+			bj := pt.Types[j].BytesIn + pt.Types[j].BytesOut
+			if bi != bj {
+				return bi > bj
+			}
+			return pt.Types[i].Type < pt.Types[j].Type
+		})
+		list = append(list, pt)
+		return nil
+	}, true)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bandwidth != list[j].Bandwidth {
+			return list[i].Bandwidth > list[j].Bandwidth
+		}
+		return list[i].Peer < list[j].Peer
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return
+}
+
+// Invalid counts an invalid message received from a connected peer
+// (malformed, failed validation or signature check). Messages from
+// peers that are not connected are ignored.
+func (c *Core) Invalid(peer *util.PeerID, msg message.Message) {
+	bw, ok := c.bandwidth.Get(peer.String(), 0)
+	if !ok {
+		return
+	}
+	bw.Lock()
+	defer bw.Unlock()
+	bw.stats.Invalid++
+	bw.typeStats(msg.Type()).Invalid++
+	logger.Printf(logger.DBG, "[core] Invalid %s from %s (%d invalid messages)", msg.Type(), peer.Short(), bw.stats.Invalid)
+}
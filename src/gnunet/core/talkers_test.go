@@ -0,0 +1,72 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"testing"
+
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+)
+
+func TestTopTalkers(t *testing.T) {
+	c, peer, _ := newDisconnectCore(0)
+	c.bwPolicy = &BandwidthPolicy{}
+	other := util.NewPeerID(util.NewRndArray(32))
+	c.startAccounting(peer)
+	c.startAccounting(other)
+
+	data := message.NewCadetChannelAppDataMsg(0, 0, make([]byte, 988))
+	quota := message.NewSessionQuotaMsg(1000)
+	for i := 0; i < 3; i++ {
+		c.accountIn(peer, data)
+	}
+	c.accountOut(peer, quota)
+	c.accountIn(other, quota)
+	c.Invalid(other, quota)
+	c.Invalid(other, quota)
+
+	list := c.TopTalkers(0)
+	if len(list) != 2 || list[0].Peer != peer.String() || list[1].Peer != other.String() {
+		t.Fatalf("unexpected report %v", list)
+	}
+	pt := list[0]
+	if pt.MsgsIn != 3 || pt.BytesIn != 3000 || pt.MsgsOut != 1 || pt.LastSeen.IsZero() || pt.Invalid != 0 {
+		t.Fatalf("unexpected statistics %v", pt.PeerStats)
+	}
+	// types sorted by bytes
+	if len(pt.Types) != 2 ||
+		pt.Types[0].Type != enums.MSG_CADET_CHANNEL_APP_DATA.String() || pt.Types[0].MsgsIn != 3 ||
+		pt.Types[1].Type != enums.MSG_TRANSPORT_SESSION_QUOTA.String() || pt.Types[1].MsgsOut != 1 {
+		t.Fatalf("unexpected types %v", pt.Types)
+	}
+	if pt = list[1]; pt.Invalid != 2 || len(pt.Types) != 1 || pt.Types[0].Invalid != 2 {
+		t.Fatalf("unexpected invalid counts %v", pt.PeerStats)
+	}
+	// limited report
+	if list = c.TopTalkers(1); len(list) != 1 || list[0].Peer != peer.String() {
+		t.Fatal("report not limited")
+	}
+	// unknown peers are ignored
+	c.Invalid(util.NewPeerID(util.NewRndArray(32)), quota)
+	if len(c.TopTalkers(0)) != 2 {
+		t.Fatal("unknown peer reported")
+	}
+}
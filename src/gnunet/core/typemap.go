@@ -0,0 +1,197 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Type maps: peers advertise the message types they handle, so core
+// doesn't send messages a remote peer would not process anyway.
+//----------------------------------------------------------------------
+
+// TypeMap is a bitmap of message types handled by a peer. The bit for
+// message type 't' is bit 't%8' in byte 't/8' (compatible with the
+// uint32 array used by GNUnet on little-endian hosts).
+type TypeMap struct {
+	bits []byte
+}
+
+// NewTypeMap creates an empty type map.
+func NewTypeMap() *TypeMap {
+	return &TypeMap{
+		bits: make([]byte, message.TypeMapSize),
+	}
+}
+
+// NewTypeMapFromBytes creates a type map from a bitmap.
+func NewTypeMapFromBytes(bitmap []byte) *TypeMap {
+	tm := NewTypeMap()
+	copy(tm.bits, bitmap)
+	return tm
+}
+
+// Add a message type to the map.
+func (tm *TypeMap) Add(mt enums.MsgType) {
+	tm.bits[mt/8] |= 1 << (mt % 8)
+}
+
+// AddAll adds all message types to the map.
+func (tm *TypeMap) AddAll() {
+	for i := range tm.bits {
+		tm.bits[i] = 0xff
+	}
+}
+
+// Has returns true if the message type is in the map.
+func (tm *TypeMap) Has(mt enums.MsgType) bool {
+	return tm.bits[mt/8]&(1<<(mt%8)) != 0
+}
+
+// Equal returns true if both maps contain the same message types.
+func (tm *TypeMap) Equal(t *TypeMap) bool {
+	return bytes.Equal(tm.bits, t.bits)
+}
+
+// Bytes returns the binary representation of the map.
+func (tm *TypeMap) Bytes() []byte {
+	return util.Clone(tm.bits)
+}
+
+// Hash returns the hash of the binary type map.
+func (tm *TypeMap) Hash() *crypto.HashCode {
+	return crypto.Hash(tm.bits)
+}
+
+// typeMapMsg returns true if the message type is part of the type map
+// exchange (always sent; not listed in type maps).
+func typeMapMsg(mt enums.MsgType) bool {
+	switch mt {
+	case enums.MSG_CORE_BINARY_TYPE_MAP,
+		enums.MSG_CORE_COMPRESSED_TYPE_MAP,
+		enums.MSG_CORE_CONFIRM_TYPE_MAP:
+		return true
+	}
+	return false
+}
+
+//----------------------------------------------------------------------
+
+// TypeMap returns the type map of the local peer: the message types
+// listeners have registered for. A listener without a message type
+// filter handles all messages.
+func (c *Core) TypeMap() *TypeMap {
+	c.lmtx.RLock()
+	defer c.lmtx.RUnlock()
+	return c.typeMap
+}
+
+// PeerTypeMap returns the type map received from a peer (or nil if the
+// peer has not sent one yet).
+func (c *Core) PeerTypeMap(peer *util.PeerID) *TypeMap {
+	tm, _ := c.typeMaps.Get(peer.String(), 0)
+	return tm
+}
+
+// Handles returns true if a peer processes messages of given type. Peers
+// that haven't sent a type map are assumed to handle all messages;
+// type maps and quotas (handled by core) are always accepted.
+func (c *Core) Handles(peer *util.PeerID, mt enums.MsgType) bool {
+	if typeMapMsg(mt) || quotaMsg(mt) {
+		return true
+	}
+	tm := c.PeerTypeMap(peer)
+	return tm == nil || tm.Has(mt)
+}
+
+// update the local type map from the listener filters; announce a
+// changed map to connected peers. Must be called with lmtx locked.
+func (c *Core) updateTypeMap() {
+	tm := NewTypeMap()
+	// messages handled by core itself
+	tm.Add(enums.MSG_CORE_VERSION_QUERY)
+	tm.Add(enums.MSG_CORE_VERSION_REPLY)
+	tm.Add(enums.MSG_REQUEST_AGPL)
+	for _, l := range c.listeners {
+		if !l.filter.CheckEvent(EV_MESSAGE) {
+			continue
+		}
+		if len(l.filter.msgTypes) == 0 {
+			tm.AddAll()
+			break
+		}
+		for mt := range l.filter.msgTypes {
+			tm.Add(mt)
+		}
+	}
+	if c.typeMap != nil && c.typeMap.Equal(tm) {
+		return
+	}
+	c.typeMap = tm
+
+	// signal message pump (non-blocking; one pending update is enough)
+	select {
+	case c.tmUpdate <- struct{}{}:
+	default:
+	}
+}
+
+// send the local type map to a peer
+func (c *Core) sendTypeMap(ctx context.Context, peer *util.PeerID) {
+	msg := message.NewCoreTypeMapMsg(c.TypeMap().Bytes())
+	if err := c.Send(ctx, peer, msg); err != nil {
+		logger.Printf(logger.WARN, "[core] Failed to send type map to %s: %s", peer.Short(), err.Error())
+	}
+}
+
+// handle a type map received from a peer: store and confirm it.
+func (c *Core) handleTypeMap(ctx context.Context, peer *util.PeerID, msg *message.CoreTypeMapMsg) {
+	bitmap, err := msg.Bitmap()
+	if err != nil {
+		logger.Printf(logger.WARN, "[core] Invalid type map from %s: %s", peer.Short(), err.Error())
+		c.Invalid(peer, msg)
+		c.disconnect(peer, DR_PROTOCOL, "invalid type map")
+		return
+	}
+	tm := NewTypeMapFromBytes(bitmap)
+	c.typeMaps.Put(peer.String(), tm, 0)
+	logger.Printf(logger.DBG, "[core] Type map of %s received", peer.Short())
+
+	if err = c.Send(ctx, peer, message.NewCoreConfirmTypeMapMsg(tm.Hash())); err != nil {
+		logger.Printf(logger.WARN, "[core] Failed to confirm type map of %s: %s", peer.Short(), err.Error())
+	}
+}
+
+// handle a type map confirmation: if the peer confirmed an outdated (or
+// corrupted) map, the current map is sent again.
+func (c *Core) handleTypeMapConfirm(ctx context.Context, peer *util.PeerID, msg *message.CoreConfirmTypeMapMsg) {
+	if c.TypeMap().Hash().Equal(msg.Hash) {
+		return
+	}
+	logger.Printf(logger.DBG, "[core] Type map of %s outdated -- resending", peer.Short())
+	c.sendTypeMap(ctx, peer)
+}
@@ -0,0 +1,118 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+	"testing"
+
+	"github.com/bfix/gospel/data"
+)
+
+func TestTypeMapMsg(t *testing.T) {
+	tm := NewTypeMap()
+	tm.Add(enums.MSG_DHT_P2P_GET)
+	tm.Add(enums.MSG_GNS_LOOKUP)
+
+	// sparse maps are compressed
+	msg := message.NewCoreTypeMapMsg(tm.Bytes())
+	if msg.MsgType != enums.MSG_CORE_COMPRESSED_TYPE_MAP {
+		t.Fatalf("unexpected message type %s", msg.MsgType)
+	}
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := message.NewEmptyMessage(msg.MsgType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = data.Unmarshal(out, buf); err != nil {
+		t.Fatal(err)
+	}
+	bitmap, err := out.(*message.CoreTypeMapMsg).Bitmap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm2 := NewTypeMapFromBytes(bitmap)
+	if !tm.Equal(tm2) || !tm2.Hash().Equal(tm.Hash()) {
+		t.Fatal("type map mismatch")
+	}
+	if !tm2.Has(enums.MSG_DHT_P2P_GET) || tm2.Has(enums.MSG_DHT_P2P_PUT) {
+		t.Fatal("wrong message types in map")
+	}
+	// corrupted maps are rejected
+	msg.Data = msg.Data[:len(msg.Data)/2]
+	if _, err = msg.Bitmap(); err == nil {
+		t.Fatal("truncated type map accepted")
+	}
+}
+
+func TestTypeMapListeners(t *testing.T) {
+	c := &Core{
+		listeners: make(map[string]*Listener),
+		typeMap:   NewTypeMap(),
+		tmUpdate:  make(chan struct{}, 1),
+		typeMaps:  util.NewMap[string, *TypeMap](),
+	}
+	// listener for DHT messages
+	f := NewEventFilter()
+	f.AddEvent(EV_CONNECT)
+	f.AddMsgType(enums.MSG_DHT_P2P_PUT)
+	c.Register("dht", NewListener(nil, f))
+	if !c.TypeMap().Has(enums.MSG_DHT_P2P_PUT) || c.TypeMap().Has(enums.MSG_GNS_LOOKUP) {
+		t.Fatal("wrong local type map")
+	}
+	select {
+	case <-c.tmUpdate:
+	default:
+		t.Fatal("type map change not signalled")
+	}
+	// listener without messages doesn't change the map
+	f = NewEventFilter()
+	f.AddEvent(EV_CONNECT)
+	c.Register("connect", NewListener(nil, f))
+	if len(c.tmUpdate) != 0 {
+		t.Fatal("unchanged type map signalled")
+	}
+	// listener for all messages
+	c.Register("all", NewListener(nil, nil))
+	if !c.TypeMap().Has(enums.MSG_GNS_LOOKUP) {
+		t.Fatal("type map not complete")
+	}
+	c.Unregister("all")
+	if c.TypeMap().Has(enums.MSG_GNS_LOOKUP) {
+		t.Fatal("type map not updated")
+	}
+
+	// peers without type map handle everything
+	peer := util.NewPeerID(util.NewRndArray(32))
+	if !c.Handles(peer, enums.MSG_DHT_P2P_GET) {
+		t.Fatal("unknown peer rejected message")
+	}
+	c.typeMaps.Put(peer.String(), c.TypeMap(), 0)
+	if c.Handles(peer, enums.MSG_DHT_P2P_GET) || !c.Handles(peer, enums.MSG_DHT_P2P_PUT) {
+		t.Fatal("peer type map not applied")
+	}
+	if !c.Handles(peer, enums.MSG_CORE_CONFIRM_TYPE_MAP) {
+		t.Fatal("type map exchange blocked")
+	}
+}
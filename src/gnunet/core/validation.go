@@ -0,0 +1,295 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Address validation
+//
+// An address learned for a peer (e.g. from a HELLO) is only used after
+// the peer has proven that it can be reached at that address: a
+// TRANSPORT_PING with a random challenge is sent to the address and
+// the peer must answer with a TRANSPORT_PONG carrying the challenge and
+// a signature over the address. A validated address stays valid until
+// the PONG signature expires; validated addresses are re-validated
+// periodically so their validity is extended as long as the peer
+// responds.
+//
+// Messages are only sent to validated addresses; HELLOs are exempt, so
+// peers can bootstrap while addresses are validated in the background.
+//----------------------------------------------------------------------
+
+// Validation states of an address
+//
+//nolint:stylecheck // allow non-camel-case in constants
+const (
+	ADDR_UNVALIDATED = iota // address not validated (yet)
+	ADDR_PENDING            // PING sent, waiting for PONG
+	ADDR_VALID              // address validated by PONG
+)
+
+// addrValidation is the validation record for a peer address.
+type addrValidation struct {
+	sync.Mutex
+
+	peer      *util.PeerID      // peer
+	addr      *util.Address     // address to be validated
+	state     int               // validation state
+	challenge uint32            // challenge of last PING
+	pinged    util.AbsoluteTime // time of last PING
+	expire    util.AbsoluteTime // end of validity (from PONG)
+	done      chan struct{}     // closed when a pending validation succeeds
+
+	// statistics for address ranking
+	rtt     time.Duration // smoothed round-trip time of PING/PONG
+	success uint32        // number of successful sends
+	failure uint32        // number of failed sends/validations
+}
+
+// valid returns true if the address is validated and not expired
+// (caller must hold the lock).
+func (v *addrValidation) valid() bool {
+	return v.state == ADDR_VALID && !v.expire.Expired()
+}
+
+// helloMsg returns true for HELLO messages (sent to addresses that are
+// not validated yet).
+func helloMsg(mt enums.MsgType) bool {
+	switch mt {
+	case enums.MSG_HELLO, enums.MSG_HELLO_LEGACY, enums.MSG_DHT_P2P_HELLO:
+		return true
+	}
+	return false
+}
+
+// key for validation record of a peer address
+func validationKey(peer *util.PeerID, addr *util.Address) string {
+	return peer.String() + " " + addr.URI()
+}
+
+//----------------------------------------------------------------------
+
+// Validated returns true if the address of a peer has been validated.
+func (c *Core) Validated(peer *util.PeerID, addr *util.Address) bool {
+	v, ok := c.validations.Get(validationKey(peer, addr), 0)
+	if !ok {
+		return false
+	}
+	v.Lock()
+	defer v.Unlock()
+	return v.valid()
+}
+
+// validate an address for a peer: if the address is not validated yet,
+// a PING is sent in the background (unless a recent PING is still
+// pending). The function waits up to 'wait' for a pending validation to
+// succeed and returns true if the address is validated.
+func (c *Core) validate(ctx context.Context, peer *util.PeerID, addr *util.Address, wait time.Duration) bool {
+	k := validationKey(peer, addr)
+	v, ok := c.validations.Get(k, 0)
+	if !ok {
+		v = &addrValidation{
+			peer:  peer,
+			addr:  addr,
+			state: ADDR_UNVALIDATED,
+		}
+		c.validations.Put(k, v, 0)
+	}
+	v.Lock()
+	if v.valid() {
+		v.Unlock()
+		return true
+	}
+	// (re-)send PING if no recent request is pending
+	if v.state != ADDR_PENDING || v.pinged.Add(message.UnvalidatedPingKeepAlive).Expired() {
+		v.state = ADDR_PENDING
+		v.done = make(chan struct{})
+		go c.ping(ctx, v, c.newPing(v))
+	}
+	done := v.done
+	v.Unlock()
+
+	// wait for validation to finish
+	if wait <= 0 {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-time.After(wait):
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// assemble a PING for address validation (caller must hold the lock)
+func (c *Core) newPing(v *addrValidation) *message.TransportPingMsg {
+	msg := message.NewTransportPingMsg(v.peer, v.addr)
+	v.challenge = msg.Challenge
+	v.pinged = util.AbsoluteTimeNow()
+	return msg
+}
+
+// send PING for address validation; a pending validation is reset if
+// the PING can't be sent.
+func (c *Core) ping(ctx context.Context, v *addrValidation, msg *message.TransportPingMsg) {
+	logger.Printf(logger.DBG, "[core] Validating %s for %s", v.addr.URI(), v.peer.Short())
+	if err := c.SendToAddr(ctx, v.addr, msg); err != nil && err != transport.ErrEndpMaybeSent {
+		logger.Printf(logger.WARN, "[core] PING to %s failed: %s", v.addr.URI(), err.Error())
+		v.Lock()
+		if v.state == ADDR_PENDING && v.challenge == msg.Challenge {
+			v.state = ADDR_UNVALIDATED
+		}
+		v.Unlock()
+	}
+}
+
+// handle an incoming PING: respond with a signed PONG if the address
+// to be validated is one of our own.
+func (c *Core) handlePing(ctx context.Context, tm *transport.Message, msg *message.TransportPingMsg) {
+	if !msg.Target.Equal(c.PeerID()) {
+		logger.Printf(logger.WARN, "[core] PING from %s for other peer -- ignored", tm.Peer.Short())
+		return
+	}
+	addr, err := msg.Addr()
+	if err != nil || addr == nil {
+		logger.Printf(logger.WARN, "[core] PING from %s without valid address -- ignored", tm.Peer.Short())
+		return
+	}
+	own := false
+	for _, epRef := range c.endpoints {
+		if epRef.addr.URI() == addr.URI() {
+			own = true
+			break
+		}
+	}
+	if !own {
+		logger.Printf(logger.WARN, "[core] PING from %s for foreign address %s -- ignored", tm.Peer.Short(), addr.URI())
+		return
+	}
+	if tm.Addr == nil {
+		logger.Printf(logger.WARN, "[core] PING from %s without return address -- ignored", tm.Peer.Short())
+		return
+	}
+	// assemble and sign PONG
+	pong := message.NewTransportPongMsg(msg.Challenge, addr)
+	pong.SignedBlock.ExpireOn = util.AbsoluteTimeNow().Add(message.PongSignatureLifetime)
+	if err := pong.Sign(c.local.prv); err != nil {
+		logger.Printf(logger.ERROR, "[core] Failed to sign PONG: %s", err.Error())
+		return
+	}
+	if err := c.SendToAddr(ctx, tm.Addr, pong); err != nil && err != transport.ErrEndpMaybeSent {
+		logger.Printf(logger.WARN, "[core] Failed to send PONG to %s: %s", tm.Addr.URI(), err.Error())
+	}
+}
+
+// handle an incoming PONG: validate the address if the PONG matches a
+// pending PING and is signed by the peer.
+func (c *Core) handlePong(ctx context.Context, tm *transport.Message, msg *message.TransportPongMsg) {
+	addr, err := msg.Addr()
+	if err != nil || addr == nil {
+		logger.Printf(logger.WARN, "[core] PONG from %s without valid address -- ignored", tm.Peer.Short())
+		return
+	}
+	v, ok := c.validations.Get(validationKey(tm.Peer, addr), 0)
+	if !ok {
+		logger.Printf(logger.WARN, "[core] Unexpected PONG from %s -- ignored", tm.Peer.Short())
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	if v.challenge != msg.Challenge {
+		logger.Printf(logger.WARN, "[core] PONG from %s with wrong challenge -- ignored", tm.Peer.Short())
+		return
+	}
+	if msg.SignedBlock.ExpireOn.Expired() {
+		logger.Printf(logger.WARN, "[core] Expired PONG from %s -- ignored", tm.Peer.Short())
+		return
+	}
+	pub := ed25519.NewPublicKeyFromBytes(tm.Peer.Data)
+	if ok, err := msg.Verify(pub); !ok || err != nil {
+		logger.Printf(logger.WARN, "[core] PONG from %s with invalid signature -- ignored", tm.Peer.Short())
+		c.Invalid(tm.Peer, msg)
+		return
+	}
+	// update round-trip time (exponential moving average)
+	if dt, ok := v.pinged.Diff(util.AbsoluteTimeNow()); ok {
+		rtt := time.Duration(dt.Val) * time.Microsecond
+		if v.rtt == 0 {
+			v.rtt = rtt
+		} else {
+			v.rtt = (7*v.rtt + rtt) / 8
+		}
+	}
+	// address is validated
+	logger.Printf(logger.INFO, "[core] Address %s of %s validated (rtt %s)", addr.URI(), tm.Peer.Short(), v.rtt)
+	v.expire = msg.SignedBlock.ExpireOn
+	v.challenge = 0
+	if v.state == ADDR_PENDING {
+		close(v.done)
+		// session setup was skipped for a connected peer without a
+		// validated address: announce our type map and quota now.
+		if _, ok := c.connected.Get(tm.Peer.String(), 0); ok {
+			go c.sendTypeMap(ctx, tm.Peer)
+			go c.sendQuota(ctx, tm.Peer)
+		}
+	}
+	v.state = ADDR_VALID
+}
+
+// revalidate addresses periodically: validated addresses are PINGed
+// again before their validation expires, records for addresses that
+// failed validation are dropped.
+func (c *Core) revalidate(ctx context.Context) {
+	tick := time.NewTicker(message.UnvalidatedPingKeepAlive)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			_ = c.validations.ProcessRange(func(key string, v *addrValidation, pid int) error {
+				v.Lock()
+				defer v.Unlock()
+				switch {
+				case v.valid():
+					if v.pinged.Add(message.ValidatedPingFrequency).Expired() {
+						go c.ping(ctx, v, c.newPing(v))
+					}
+				case v.pinged.Add(message.UnvalidatedPingKeepAlive).Expired():
+					c.validations.Delete(key, pid)
+				}
+				return nil
+			}, false)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
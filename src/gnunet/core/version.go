@@ -0,0 +1,150 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Version and capability report
+//
+// Peers (and local clients) can ask a node for its implementation,
+// version and enabled subsystems (the names of the registered core
+// listeners) with CORE_VERSION_QUERY (gnunet-go only). A REQUEST_AGPL
+// is answered with the link to the source code, as required by the
+// AGPL for software offered over a network.
+//----------------------------------------------------------------------
+
+// Implementation details of this node software. The version can be set
+// at build time with '-ldflags "-X gnunet/core.Version=..."'.
+var (
+	Implementation = "gnunet-go"
+	Version        = "0.1.0-dev"
+	SourceURL      = "https://git.gnunet.org/gnunet-go.git"
+)
+
+// VersionTimeout is the time to wait for a version reply from a peer.
+var VersionTimeout = 10 * time.Second
+
+// Error codes
+var (
+	ErrCoreNoVersion = errors.New("no version reply from peer")
+)
+
+// SourceLink returns the link to the source code of a node with given
+// configuration: operators running modified code must set their own link
+// in the configuration.
+func SourceLink(cfg *config.Config) string {
+	if cfg != nil && len(cfg.Source) > 0 {
+		return cfg.Source
+	}
+	return SourceURL
+}
+
+// SetSource sets the link to the source code of the node (SourceURL if
+// not set).
+func (c *Core) SetSource(link string) {
+	c.lmtx.Lock()
+	defer c.lmtx.Unlock()
+	c.source = link
+}
+
+// sourceLink returns the link to the source code of the node.
+func (c *Core) sourceLink() string {
+	c.lmtx.RLock()
+	defer c.lmtx.RUnlock()
+	if len(c.source) > 0 {
+		return c.source
+	}
+	return SourceURL
+}
+
+// VersionInfo returns the version information of the local node.
+func (c *Core) VersionInfo() *message.VersionInfo {
+	c.lmtx.RLock()
+	subs := make([]string, 0, len(c.listeners))
+	for name := range c.listeners {
+		subs = append(subs, name)
+	}
+	c.lmtx.RUnlock()
+	sort.Strings(subs)
+	return &message.VersionInfo{
+		Implementation: Implementation,
+		Version:        Version,
+		Source:         c.sourceLink(),
+		Subsystems:     subs,
+	}
+}
+
+// QueryVersion asks a connected peer for its version information.
+func (c *Core) QueryVersion(ctx context.Context, peer *util.PeerID) (*message.VersionInfo, error) {
+	key := peer.String()
+	ch := make(chan *message.VersionInfo, 1)
+	c.versions.Put(key, ch, 0)
+	defer c.versions.Delete(key, 0)
+
+	if err := c.Send(ctx, peer, message.NewCoreVersionQueryMsg()); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, VersionTimeout)
+	defer cancel()
+	select {
+	case info := <-ch:
+		return info, nil
+	case <-ctx.Done():
+		return nil, ErrCoreNoVersion
+	}
+}
+
+// handle version query, version reply and AGPL request from a peer
+func (c *Core) handleVersion(ctx context.Context, peer *util.PeerID, msg message.Message, back transport.Responder) {
+	var err error
+	switch m := msg.(type) {
+	case *message.CoreVersionQueryMsg:
+		err = back.Send(ctx, message.NewCoreVersionReplyMsg(c.VersionInfo()))
+	case *message.AGPLRequestMsg:
+		err = back.Send(ctx, message.NewAGPLResponseMsg(c.sourceLink()))
+	case *message.CoreVersionReplyMsg:
+		var info *message.VersionInfo
+		if info, err = m.Info(); err != nil {
+			break
+		}
+		logger.Printf(logger.DBG, "[core] %s runs %s %s", peer.Short(), info.Implementation, info.Version)
+		if ch, ok := c.versions.Get(peer.String(), 0); ok {
+			select {
+			case ch <- info:
+			default:
+			}
+		}
+	}
+	if err != nil {
+		logger.Printf(logger.WARN, "[core] version exchange with %s failed: %s", peer.Short(), err.Error())
+	}
+}
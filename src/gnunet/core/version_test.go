@@ -0,0 +1,117 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+)
+
+func TestVersionReplyMsg(t *testing.T) {
+	info := &message.VersionInfo{
+		Implementation: Implementation,
+		Version:        Version,
+		Source:         SourceURL,
+		Subsystems:     []string{"dht", "gns"},
+	}
+	msg := message.NewCoreVersionReplyMsg(info)
+	out, err := msg.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Implementation != info.Implementation || out.Version != info.Version ||
+		out.Source != info.Source || len(out.Subsystems) != 2 || out.Subsystems[1] != "gns" {
+		t.Fatalf("version info mismatch: %+v", out)
+	}
+	if _, err = message.NewCoreVersionReplyMsg(nil).Info(); err == nil {
+		t.Fatal("empty version reply accepted")
+	}
+	if link := message.NewAGPLResponseMsg(SourceURL).Link(); link != SourceURL {
+		t.Fatalf("wrong source link '%s'", link)
+	}
+}
+
+func TestSourceLink(t *testing.T) {
+	// nodes in one process can have different links
+	for _, cfg := range []*config.Config{nil, {Source: "https://example.org/node.git"}} {
+		c := new(Core)
+		c.SetSource(SourceLink(cfg))
+		want := SourceURL
+		if cfg != nil {
+			want = cfg.Source
+		}
+		if link := c.VersionInfo().Source; link != want {
+			t.Fatalf("source link '%s' (expected '%s')", link, want)
+		}
+	}
+}
+
+func TestVersionQuery(t *testing.T) {
+	cfg := func(name, seed string) *config.NodeConfig {
+		return &config.NodeConfig{
+			Name:        name,
+			PrivateSeed: seed,
+			Endpoints: []*config.EndpointConfig{
+				{
+					ID:      name,
+					Network: "ip+udp",
+					Address: "127.0.0.1",
+					Port:    0,
+					TTL:     86400,
+				},
+			},
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		time.Sleep(time.Second)
+	}()
+
+	node1, err := NewTestNode(ctx, t, cfg("q1", "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node1.Shutdown()
+	node2, err := NewTestNode(ctx, t, cfg("q2", "Bv9umksEO51jjWWrOGEH+4r8wl9Vi+LItpdBpTOi2PE="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node2.Shutdown()
+	// loopback addresses are not learned; add directly
+	node1.core.peers.Add(node2.peer.GetID(), node2.addr)
+	node2.core.peers.Add(node1.peer.GetID(), node1.addr)
+	// messages are only sent to validated addresses
+	if !node1.core.validate(ctx, node2.peer.GetID(), node2.addr, 5*time.Second) ||
+		!node2.core.validate(ctx, node1.peer.GetID(), node1.addr, 5*time.Second) {
+		t.Fatal("addresses not validated")
+	}
+
+	info, err := node1.core.QueryVersion(ctx, node2.peer.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Implementation != Implementation || len(info.Subsystems) != 1 || info.Subsystems[0] != "q2" {
+		t.Fatalf("unexpected version info: %+v", info)
+	}
+}
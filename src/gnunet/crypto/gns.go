@@ -251,6 +251,22 @@ func NewZonePrivate(ztype enums.GNSType, zdata []byte) (zp *ZonePrivate, err err
 	return
 }
 
+// NewZonePrivateFromID returns the private zone key for a GNUnet
+// identifier (as returned by ZonePrivate.ID()).
+func NewZonePrivateFromID(id string) (zp *ZonePrivate, err error) {
+	var buf []byte
+	if buf, err = util.DecodeStringToBinary(id, 36); err != nil {
+		return
+	}
+	ztype := enums.GNSType(binary.BigEndian.Uint32(buf[:4]))
+	zdata := buf[4:]
+	if ztype == enums.GNS_TYPE_PKEY {
+		// PKEY identifiers use the little-endian representation
+		zdata = util.Reverse(zdata)
+	}
+	return NewZonePrivate(ztype, zdata)
+}
+
 // Init is called to setup internal state after unmarshalling object
 func (zp *ZonePrivate) Init() (err error) {
 	// check for initialized key
@@ -188,7 +188,7 @@ func (pk *PKEYPrivateImpl) Init(data []byte) error {
 func (pk *PKEYPrivateImpl) Prepare(rnd []byte) []byte {
 	md := sha256.Sum256(rnd)
 	d := math.NewIntFromBytes(md[:]).Mod(ed25519.GetCurve().N)
-	return util.Reverse(d.Bytes())
+	return util.Reverse(d.FixedBytes(32))
 }
 
 // Bytes returns a binary representation of the instance suitable for
@@ -245,7 +245,7 @@ func (pk *PKEYPrivateImpl) Sign(data []byte) (sig *ZoneSignature, err error) {
 func (pk *PKEYPrivateImpl) ID() string {
 	return util.EncodeBinaryToString(asBytes(
 		enums.GNS_TYPE_PKEY,
-		util.Reverse(pk.prv.D.Bytes())))
+		util.Reverse(pk.prv.D.FixedBytes(32))))
 }
 
 //----------------------------------------------------------------------
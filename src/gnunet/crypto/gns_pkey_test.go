@@ -20,7 +20,9 @@ package crypto
 
 import (
 	"bytes"
+	"encoding/hex"
 	"gnunet/enums"
+	"gnunet/util"
 	"testing"
 )
 
@@ -46,3 +48,28 @@ func TestDerivePKEY(t *testing.T) {
 		t.Fatal("derive mismatch")
 	}
 }
+
+// Private scalars with leading zero bytes are encoded at full key size
+// (in prepared key data and in the identifier of a private key).
+func TestPKEYShortScalar(t *testing.T) {
+	// sha256("leading zero 64") mod N is less than 2^248
+	data := new(PKEYPrivateImpl).Prepare([]byte("leading zero 64"))
+	want, _ := hex.DecodeString("ca5287de7fb6cb179607455fddc52777ce3c9db0093e52396200b545a6b27e00")
+	if !bytes.Equal(data, want) {
+		t.Fatalf("prepared key data: %x", data)
+	}
+	// private scalar with leading zero byte (big-endian)
+	for _, zdata := range [][]byte{want, util.Reverse(want)} {
+		zp, err := NewZonePrivate(enums.GNS_TYPE_PKEY, zdata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zp2, err := NewZonePrivateFromID(zp.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(zp2.Public().Bytes(), zp.Public().Bytes()) {
+			t.Fatalf("key %x: identifier mismatch", zdata)
+		}
+	}
+}
@@ -199,6 +199,22 @@ func TestVerifyBlock(t *testing.T) {
 	}
 }
 
+func TestZonePrivateID(t *testing.T) {
+	for _, ztype := range []enums.GNSType{enums.GNS_TYPE_PKEY, enums.GNS_TYPE_EDKEY} {
+		zp, err := NewZonePrivate(ztype, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zp2, err := NewZonePrivateFromID(zp.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !zp.Public().Equal(zp2.Public()) || zp.ID() != zp2.ID() {
+			t.Fatalf("%s: key mismatch", ztype)
+		}
+	}
+}
+
 func TestDeriveH(t *testing.T) {
 	var (
 		D = []byte{
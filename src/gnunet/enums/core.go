@@ -0,0 +1,30 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//nolint:stylecheck // allow non-camel-case for constants
+package enums
+
+// CORE client options (sent in CORE_INIT)
+const (
+	CORE_OPTION_NOTHING            = 0  // No special options.
+	CORE_OPTION_SEND_STATUS_CHANGE = 4  // Client wants all peer status changes.
+	CORE_OPTION_SEND_FULL_INBOUND  = 8  // Client wants all inbound messages in full.
+	CORE_OPTION_SEND_HDR_INBOUND   = 16 // Client wants only the headers of inbound messages.
+	CORE_OPTION_SEND_FULL_OUTBOUND = 32 // Client wants all outbound messages in full.
+	CORE_OPTION_SEND_HDR_OUTBOUND  = 64 // Client wants only the headers of outbound messages.
+)
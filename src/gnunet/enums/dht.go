@@ -27,5 +27,7 @@ const (
 	DHT_RO_FIND_APPROXIMATE       = 4 // Approximate results are fine.
 	DHT_RO_TRUNCATED              = 8 // Flag if path is truncated
 
-	DHT_RO_DISCOVERY = 32768 // Peer discovery
+	DHT_RO_FIRST_RESULT        = 8192  // Client GET ends with the first exact result (gnunet-go only)
+	DHT_RO_RELATIVE_EXPIRATION = 16384 // Client PUT expiration is relative (gnunet-go only)
+	DHT_RO_DISCOVERY           = 32768 // Peer discovery
 )
@@ -52,6 +52,30 @@ const (
 	GNS_LO_NO_DHT       = 1 // Never look in the DHT, keep request to local cache.
 	GNS_LO_LOCAL_MASTER = 2 // For the rightmost label, only look in the cache.
 
+	// GNS_LocalOptions flags (gnunet-go extension)
+	GNS_LO_NO_NEGCACHE = 0x100 // Don't use the negative cache for this request.
+	GNS_LO_TRACE       = 0x200 // Return the resolution steps with the result.
+	GNS_LO_PROVENANCE  = 0x400 // Return the provenance of the result.
+
+	// GNS lookup trace steps (gnunet-go extension)
+	GNS_TRACE_LOCAL    = 1 // Block found in local cache
+	GNS_TRACE_NEGCACHE = 2 // Query failed recently (negative cache)
+	GNS_TRACE_DHT      = 3 // Block found in DHT
+	GNS_TRACE_NOTFOUND = 4 // Block not found
+	GNS_TRACE_FAILED   = 5 // Lookup failed with error
+	GNS_TRACE_DNS      = 6 // Name delegated to DNS
+
+	// GNS lookup result sources (gnunet-go extension)
+	GNS_SOURCE_NONE      = 0 // No result
+	GNS_SOURCE_ZONE      = 1 // Block of a local zone (ego)
+	GNS_SOURCE_CACHE     = 2 // Block from the block cache of the service
+	GNS_SOURCE_NAMECACHE = 3 // Block from the namecache
+	GNS_SOURCE_DHT       = 4 // Block from the DHT
+	GNS_SOURCE_DNS       = 5 // Records from DNS
+
+	// GNS lookup provenance flags (gnunet-go extension)
+	GNS_PROV_SHADOW = 1 // Shadow records replaced expired records
+
 	GNS_MAX_BLOCK_SIZE = (63 * 1024) // Maximum size of a value that can be stored in a GNS block.
 
 	GNS_REPLICATION_LEVEL = 10
@@ -73,3 +73,77 @@ GNS_TYPE_MESSENGER_ROOM_DETAILS GNSType = 65560 // Record type to store details
 GNS_TYPE_DID_DOCUMENT GNSType = 65561 // Record type to store DID Documents
 
 )
+
+// GNSTypeInfo describes a registered record type.
+type GNSTypeInfo struct {
+	Type    GNSType // record type
+	Name    string  // short name (mnemonic)
+	Comment string  // description
+}
+
+// GNSTypes lists all registered record types (DNS and GNS).
+var GNSTypes = []*GNSTypeInfo{
+	{GNS_TYPE_DNS_A, "A", "[RFC1035] IPv4 Address record"},
+	{GNS_TYPE_DNS_NS, "NS", "[RFC1035] Name Server record"},
+	{GNS_TYPE_DNS_CNAME, "CNAME", "[RFC1035] Canonical Name record"},
+	{GNS_TYPE_DNS_SOA, "SOA", "[RFC2308] Start Of [a zone of] Authority"},
+	{GNS_TYPE_DNS_PTR, "PTR", "[RFC1035] Pointer record"},
+	{GNS_TYPE_DNS_MX, "MX", "[RFC7505] Mail eXchange record"},
+	{GNS_TYPE_DNS_TXT, "TXT", "[RFC1035] Text record"},
+	{GNS_TYPE_DNS_RP, "RP", "[RFC1183] Responsible Person"},
+	{GNS_TYPE_DNS_AFSDB, "AFSDB", "[RFC1183] AFS Database Record"},
+	{GNS_TYPE_DNS_SIG, "SIG", "[RFC2535] Signature"},
+	{GNS_TYPE_DNS_KEY, "KEY", "[RFC2930] Key record"},
+	{GNS_TYPE_DNS_AAAA, "AAAA", "[RFC3596] IPv6 Address record"},
+	{GNS_TYPE_DNS_LOC, "LOC", "[RFC1876] Location record"},
+	{GNS_TYPE_DNS_SRV, "SRV", "[RFC2782] Service locator"},
+	{GNS_TYPE_DNS_NAPTR, "NAPTR", "[RFC3403] Naming Authority Pointer"},
+	{GNS_TYPE_DNS_KX, "KX", "[RFC2230] Key eXchanger record"},
+	{GNS_TYPE_DNS_CERT, "CERT", "[RFC4398] Certificate record"},
+	{GNS_TYPE_DNS_DNAME, "DNAME", "[RFC2672] Delegation Name"},
+	{GNS_TYPE_DNS_APL, "APL", "[RFC3123] Address Prefix List"},
+	{GNS_TYPE_DNS_DS, "DS", "[RFC4034] Delegation Signer"},
+	{GNS_TYPE_DNS_SSHFP, "SSHFP", "[RFC4255] SSH public key Fingerprint"},
+	{GNS_TYPE_DNS_IPSECKEY, "IPSECKEY", "[RFC4025] IPsec Key"},
+	{GNS_TYPE_DNS_RRSIG, "RRSIG", "[RFC4034] DNSSEC Signature"},
+	{GNS_TYPE_DNS_NSEC, "NSEC", "[RFC4034] Next-Secure record"},
+	{GNS_TYPE_DNS_DNSKEY, "DNSKEY", "[RFC4034] DNS Key record"},
+	{GNS_TYPE_DNS_DHCID, "DHCID", "[RFC4701] DHCP Identifier"},
+	{GNS_TYPE_DNS_NSEC3, "NSEC3", "[RFC5155] NSEC record version 3 or NSEC hashed"},
+	{GNS_TYPE_DNS_NSEC3PARAM, "NSEC3PARAM", "[RFC5155] NSEC3 Parameters"},
+	{GNS_TYPE_DNS_TLSA, "TLSA", "[RFC6698] TLSA certificate association"},
+	{GNS_TYPE_DNS_HIP, "HIP", "[RFC5205] Host Identity Protocol"},
+	{GNS_TYPE_DNS_CDS, "CDS", "[RFC7344] Child DS"},
+	{GNS_TYPE_DNS_CDNSKEY, "CDNSKEY", "[RFC7344] Child DNSKEY"},
+	{GNS_TYPE_DNS_TKEY, "TKEY", "[RFC2930] Secret Key"},
+	{GNS_TYPE_DNS_TSIG, "TSIG", "[RFC2845] Transaction Signature"},
+	{GNS_TYPE_DNS_URI, "URI", "[RFC7553] Uniform Resource Identifier"},
+	{GNS_TYPE_DNS_CAA, "CAA", "[RFC6844] Certification Authority Authorization"},
+	{GNS_TYPE_DNS_TA, "TA", "[–] DNSSEC Trust Authorities"},
+	{GNS_TYPE_DNS_DLV, "DLV", "[RFC4431] DNSSEC Lookaside Validation record"},
+{GNS_TYPE_PKEY, "PKEY", "GNS zone transfer"},
+{GNS_TYPE_NICK, "NICK", "GNS nick names"},
+{GNS_TYPE_LEHO, "LEHO", "legacy hostnames"},
+{GNS_TYPE_VPN, "VPN", "VPN resolution"},
+{GNS_TYPE_GNS2DNS, "GNS2DNS", "Delegation to DNS"},
+{GNS_TYPE_BOX, "BOX", "Boxed records (see TLSA/SRV handling in GNS)"},
+{GNS_TYPE_PLACE, "PLACE", "social place for SecuShare"},
+{GNS_TYPE_PHONE, "PHONE", "Endpoint for conversation"},
+{GNS_TYPE_RECLAIM_ATTRIBUTE, "RECLAIM_ATTRIBUTE", "identity attribute"},
+{GNS_TYPE_RECLAIM_TICKET, "RECLAIM_TICKET", "local ticket reference"},
+{GNS_TYPE_DELEGATE, "DELEGATE", "For ABD policies"},
+{GNS_TYPE_ATTRIBUTE, "ATTRIBUTE", "For ABD reverse lookups"},
+{GNS_TYPE_RECLAIM_ATTRIBUTE_REF, "RECLAIM_ATTRIBUTE_REF", "for reclaim records"},
+{GNS_TYPE_REDIRECT, "REDIRECT", "Resolver redirects"},
+{GNS_TYPE_RECLAIM_OIDC_CLIENT, "RECLAIM_OIDC_CLIENT", "For reclaim OIDC client names."},
+{GNS_TYPE_RECLAIM_OIDC_REDIRECT, "RECLAIM_OIDC_REDIRECT", "Used reclaimID OIDC client redirect URIs."},
+{GNS_TYPE_RECLAIM_CREDENTIAL, "RECLAIM_CREDENTIAL", "Record type for an attribute attestation (e.g. JWT)."},
+{GNS_TYPE_RECLAIM_PRESENTATION, "RECLAIM_PRESENTATION", "Record type for a presentation of a credential."},
+{GNS_TYPE_EDKEY, "EDKEY", "Record type for EDKEY zone delegations."},
+{GNS_TYPE_ERIS_READ_CAPABILITY, "ERIS_READ_CAPABILITY", "Encoding for Robust Immutable Storage (ERIS) binary read capability"},
+{GNS_TYPE_MESSENGER_ROOM_ENTRY, "MESSENGER_ROOM_ENTRY", "Record type to share an entry of a messenger room"},
+{GNS_TYPE_TOMBSTONE, "TOMBSTONE", "Record type to indicate a previously delete record (PRIVATE only)"},
+{GNS_TYPE_MESSENGER_ROOM_DETAILS, "MESSENGER_ROOM_DETAILS", "Record type to store details about a messenger room"},
+{GNS_TYPE_DID_DOCUMENT, "DID_DOCUMENT", "Record type to store DID Documents"},
+
+}
@@ -50,3 +50,54 @@ const (
 {{ range $i, $kv := . }}GNS_TYPE_{{.Name}} GNSType = {{.Number}} // {{.Comment}}
 {{ end }}
 )
+
+// GNSTypeInfo describes a registered record type.
+type GNSTypeInfo struct {
+	Type    GNSType // record type
+	Name    string  // short name (mnemonic)
+	Comment string  // description
+}
+
+// GNSTypes lists all registered record types (DNS and GNS).
+var GNSTypes = []*GNSTypeInfo{
+	{GNS_TYPE_DNS_A, "A", "[RFC1035] IPv4 Address record"},
+	{GNS_TYPE_DNS_NS, "NS", "[RFC1035] Name Server record"},
+	{GNS_TYPE_DNS_CNAME, "CNAME", "[RFC1035] Canonical Name record"},
+	{GNS_TYPE_DNS_SOA, "SOA", "[RFC2308] Start Of [a zone of] Authority"},
+	{GNS_TYPE_DNS_PTR, "PTR", "[RFC1035] Pointer record"},
+	{GNS_TYPE_DNS_MX, "MX", "[RFC7505] Mail eXchange record"},
+	{GNS_TYPE_DNS_TXT, "TXT", "[RFC1035] Text record"},
+	{GNS_TYPE_DNS_RP, "RP", "[RFC1183] Responsible Person"},
+	{GNS_TYPE_DNS_AFSDB, "AFSDB", "[RFC1183] AFS Database Record"},
+	{GNS_TYPE_DNS_SIG, "SIG", "[RFC2535] Signature"},
+	{GNS_TYPE_DNS_KEY, "KEY", "[RFC2930] Key record"},
+	{GNS_TYPE_DNS_AAAA, "AAAA", "[RFC3596] IPv6 Address record"},
+	{GNS_TYPE_DNS_LOC, "LOC", "[RFC1876] Location record"},
+	{GNS_TYPE_DNS_SRV, "SRV", "[RFC2782] Service locator"},
+	{GNS_TYPE_DNS_NAPTR, "NAPTR", "[RFC3403] Naming Authority Pointer"},
+	{GNS_TYPE_DNS_KX, "KX", "[RFC2230] Key eXchanger record"},
+	{GNS_TYPE_DNS_CERT, "CERT", "[RFC4398] Certificate record"},
+	{GNS_TYPE_DNS_DNAME, "DNAME", "[RFC2672] Delegation Name"},
+	{GNS_TYPE_DNS_APL, "APL", "[RFC3123] Address Prefix List"},
+	{GNS_TYPE_DNS_DS, "DS", "[RFC4034] Delegation Signer"},
+	{GNS_TYPE_DNS_SSHFP, "SSHFP", "[RFC4255] SSH public key Fingerprint"},
+	{GNS_TYPE_DNS_IPSECKEY, "IPSECKEY", "[RFC4025] IPsec Key"},
+	{GNS_TYPE_DNS_RRSIG, "RRSIG", "[RFC4034] DNSSEC Signature"},
+	{GNS_TYPE_DNS_NSEC, "NSEC", "[RFC4034] Next-Secure record"},
+	{GNS_TYPE_DNS_DNSKEY, "DNSKEY", "[RFC4034] DNS Key record"},
+	{GNS_TYPE_DNS_DHCID, "DHCID", "[RFC4701] DHCP Identifier"},
+	{GNS_TYPE_DNS_NSEC3, "NSEC3", "[RFC5155] NSEC record version 3 or NSEC hashed"},
+	{GNS_TYPE_DNS_NSEC3PARAM, "NSEC3PARAM", "[RFC5155] NSEC3 Parameters"},
+	{GNS_TYPE_DNS_TLSA, "TLSA", "[RFC6698] TLSA certificate association"},
+	{GNS_TYPE_DNS_HIP, "HIP", "[RFC5205] Host Identity Protocol"},
+	{GNS_TYPE_DNS_CDS, "CDS", "[RFC7344] Child DS"},
+	{GNS_TYPE_DNS_CDNSKEY, "CDNSKEY", "[RFC7344] Child DNSKEY"},
+	{GNS_TYPE_DNS_TKEY, "TKEY", "[RFC2930] Secret Key"},
+	{GNS_TYPE_DNS_TSIG, "TSIG", "[RFC2845] Transaction Signature"},
+	{GNS_TYPE_DNS_URI, "URI", "[RFC7553] Uniform Resource Identifier"},
+	{GNS_TYPE_DNS_CAA, "CAA", "[RFC6844] Certification Authority Authorization"},
+	{GNS_TYPE_DNS_TA, "TA", "[–] DNSSEC Trust Authorities"},
+	{GNS_TYPE_DNS_DLV, "DLV", "[RFC4431] DNSSEC Lookaside Validation record"},
+{{ range $i, $kv := . }}{GNS_TYPE_{{.Name}}, "{{.Name}}", "{{.Comment}}"},
+{{ end }}
+}
@@ -841,6 +841,26 @@ const (
 	MSG_RPS_CS_DEBUG_STREAM_REPLY   MsgType = 1134 // Send peer of biased stream
 	MSG_RPS_CS_DEBUG_STREAM_CANCEL  MsgType = 1135 // Cancel getting biased stream
 
+	//------------------------------------------------------------------
+	// gnunet-go private message types
+	//
+	// Message types used only by gnunet-go (between its clients, services
+	// and peers). They are not registered with GANA; the range 64000-64999
+	// is reserved for them, so they don't collide with message types that
+	// are allocated for the GNUnet C implementation in the future.
+	//------------------------------------------------------------------
+
+	MSG_CORE_VERSION_QUERY           MsgType = 64000 // Request for implementation, version and subsystems of a peer
+	MSG_CORE_VERSION_REPLY           MsgType = 64001 // Implementation, version and subsystems of a peer
+	MSG_DHT_CLIENT_GET_CREDIT        MsgType = 64010 // Client grants credits for results of a GET request
+	MSG_DHT_CLIENT_GET_LIMIT         MsgType = 64011 // Client limits the number of (ordered) results of a GET request
+	MSG_DHT_CLIENT_GET_DONE          MsgType = 64012 // Service signals the end of a limited GET request
+	MSG_DHT_CLIENT_PUT_VERIFY        MsgType = 64013 // Client asks for confirmation of an upcoming PUT request
+	MSG_DHT_CLIENT_PUT_CONFIRM       MsgType = 64014 // Service reports how well a PUT request was confirmed
+	MSG_GNS_LOOKUP_TRACE             MsgType = 64020 // Resolution steps for a traced lookup
+	MSG_GNS_LOOKUP_PROVENANCE        MsgType = 64021 // Provenance of a lookup result
+	MSG_NAMESTORE_RECORD_STORE_KEYED MsgType = 64030 // Client to service: store records with idempotency key
+
 	//------------------------------------------------------------------
 	// CATCH-ALL_DEBUG message
 	//------------------------------------------------------------------
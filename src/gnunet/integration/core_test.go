@@ -0,0 +1,107 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+)
+
+// TestCoreClientProtocol talks to the core service socket like a GNUnet
+// C service using CORE as its underlay: INIT (with a type map), SEND
+// request/ready handshake and delivery of inbound messages.
+func TestCoreClientProtocol(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.Core.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	receive := func() message.Message {
+		t.Helper()
+		msg, err := conn.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	// register for keep-alive messages; the reply is the local peer id
+	types := []enums.MsgType{enums.MSG_TRANSPORT_SESSION_KEEPALIVE}
+	if err = conn.Send(ctx, message.NewCoreInitMsg(0, types)); err != nil {
+		t.Fatal(err)
+	}
+	reply, ok := receive().(*message.CoreInitReplyMsg)
+	if !ok {
+		t.Fatal("no init reply")
+	}
+	self := tb.core.PeerID()
+	if !reply.Peer.Equal(self) {
+		t.Fatalf("wrong peer id %s", reply.Peer)
+	}
+
+	// send request is confirmed with the same request id
+	if err = conn.Send(ctx, message.NewCoreSendRequestMsg(self, 8, 4711)); err != nil {
+		t.Fatal(err)
+	}
+	ready, ok := receive().(*message.CoreSendReadyMsg)
+	if !ok {
+		t.Fatal("no send ready")
+	}
+	if ready.SmrID != 4711 || ready.MsgSize != 8 || !ready.Peer.Equal(self) {
+		t.Fatalf("wrong send ready %s", ready)
+	}
+
+	// the local peer sends a message to itself: the client is notified
+	// about the new connection and receives the message.
+	addrs, err := tb.core.Addresses()
+	if err != nil || len(addrs) == 0 {
+		t.Fatal("no local address")
+	}
+	err = tb.core.SendToAddr(ctx, addrs[0], message.NewSessionKeepAliveMsg())
+	if err != nil && err != transport.ErrEndpMaybeSent {
+		t.Fatal(err)
+	}
+	conn1, ok := receive().(*message.CoreNotifyMsg)
+	if !ok || conn1.MsgType != enums.MSG_CORE_NOTIFY_CONNECT || !conn1.Peer.Equal(self) {
+		t.Fatalf("expected connect notification, got %v", conn1)
+	}
+	in, ok := receive().(*message.CoreNotifyTrafficMsg)
+	if !ok || in.MsgType != enums.MSG_CORE_NOTIFY_INBOUND {
+		t.Fatalf("expected inbound message, got %v", in)
+	}
+	mh, err := message.GetMsgHeader(in.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mh.MsgType != enums.MSG_TRANSPORT_SESSION_KEEPALIVE || int(mh.MsgSize) != len(in.Payload) {
+		t.Fatalf("wrong inbound message %v", mh)
+	}
+}
@@ -0,0 +1,276 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// TestDHTClientProtocol talks to the DHT service socket with the client
+// messages used by the C tools (gnunet-dht-put/get): PUT (no ack),
+// GET with a unique ID, results with matching ID and GET-STOP.
+func TestDHTClientProtocol(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.DHT.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// PUT a test block (no response expected)
+	payload := []byte("interop test block")
+	key := crypto.Hash(payload)
+	put := message.NewDHTClientPutMsg(key, enums.BLOCK_TYPE_TEST, payload)
+	put.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	if err = conn.Send(ctx, put); err != nil {
+		t.Fatal(err)
+	}
+
+	// GET the block: the first message received must be the result
+	get := func(id uint64) *message.DHTClientResultMsg {
+		t.Helper()
+		msg := message.NewDHTClientGetMsg(key)
+		msg.BType = enums.BLOCK_TYPE_TEST
+		msg.ID = id
+		if err := conn.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+		in, err := conn.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, ok := in.(*message.DHTClientResultMsg)
+		if !ok {
+			t.Fatalf("unexpected message %s", in)
+		}
+		// check wire size of message
+		buf, err := data.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) != int(res.MsgSize) {
+			t.Fatalf("message size mismatch: %d != %d", len(buf), res.MsgSize)
+		}
+		return res
+	}
+	const id1, id2 = 0x0102030405060708, 0x1112131415161718
+	res := get(id1)
+	if res.ID != id1 {
+		t.Fatalf("wrong request ID %x", res.ID)
+	}
+	if !res.Key.Equal(key) || res.BType != enums.BLOCK_TYPE_TEST {
+		t.Fatalf("wrong result %s", res)
+	}
+	if !bytes.Equal(res.Data, payload) {
+		t.Fatalf("wrong result data %v", res.Data)
+	}
+
+	// stop the request; a new request gets results with its own ID.
+	stop := message.NewDHTClientGetStopMsg(key)
+	stop.ID = id1
+	if err = conn.Send(ctx, stop); err != nil {
+		t.Fatal(err)
+	}
+	if res = get(id2); res.ID != id2 {
+		t.Fatalf("wrong request ID %x", res.ID)
+	}
+}
+
+// TestDHTClientCredits checks flow control for client GET requests:
+// results for a request without credits are held back until the client
+// grants credits.
+func TestDHTClientCredits(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.DHT.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// PUT a test block
+	payload := []byte("flow control test block")
+	key := crypto.Hash(payload)
+	put := message.NewDHTClientPutMsg(key, enums.BLOCK_TYPE_TEST, payload)
+	put.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	if err = conn.Send(ctx, put); err != nil {
+		t.Fatal(err)
+	}
+	// send messages to the service
+	send := func(msgs ...message.Message) {
+		t.Helper()
+		for _, msg := range msgs {
+			if err := conn.Send(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	get := func(id uint64) *message.DHTClientGetMsg {
+		msg := message.NewDHTClientGetMsg(key)
+		msg.BType = enums.BLOCK_TYPE_TEST
+		msg.ID = id
+		return msg
+	}
+	// receive the next result and check its ID
+	expect := func(id uint64) {
+		t.Helper()
+		in, err := conn.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, ok := in.(*message.DHTClientResultMsg)
+		if !ok {
+			t.Fatalf("unexpected message %s", in)
+		}
+		if res.ID != id {
+			t.Fatalf("result for #%x, expected #%x", res.ID, id)
+		}
+	}
+
+	// GET without credits (flow control enabled in advance): no result
+	const id1, id2 = 0x0102030405060708, 0x1112131415161718
+	send(message.NewDHTClientGetCreditMsg(id1, 0), get(id1))
+	time.Sleep(500 * time.Millisecond)
+
+	// a request without flow control gets its result first
+	send(get(id2))
+	expect(id2)
+
+	// granting a credit releases the held back result
+	send(message.NewDHTClientGetCreditMsg(id1, 1))
+	expect(id1)
+}
+
+// TestDHTClientLimit checks limited client GET requests: the results are
+// sent ordered (latest expiration first for the same key) and bounded,
+// followed by a completion message. Blocks are stored under different
+// keys and found by an approximate GET (local results are returned for
+// the query key).
+func TestDHTClientLimit(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.DHT.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// PUT three test blocks with different expiration
+	for i := 1; i <= 3; i++ {
+		payload := []byte{byte(i)}
+		put := message.NewDHTClientPutMsg(crypto.Hash(payload), enums.BLOCK_TYPE_TEST, payload)
+		put.Expire = util.AbsoluteTimeNow().Add(time.Duration(i) * time.Hour)
+		if err = conn.Send(ctx, put); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	// approximate GET with a limit of two results
+	const id = 0x2122232425262728
+	limit := message.NewDHTClientGetLimitMsg(id, 2, util.NewRelativeTime(500*time.Millisecond))
+	get := message.NewDHTClientGetMsg(crypto.Hash([]byte("limit test")))
+	get.BType = enums.BLOCK_TYPE_TEST
+	get.Options = uint32(enums.DHT_RO_FIND_APPROXIMATE)
+	get.ID = id
+	for _, msg := range []message.Message{limit, get} {
+		if err = conn.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// expect the two latest blocks (ordered) and the completion
+	for _, tag := range []byte{3, 2} {
+		in, err := conn.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, ok := in.(*message.DHTClientResultMsg)
+		if !ok || res.ID != id {
+			t.Fatalf("unexpected message %s", in)
+		}
+		if !bytes.Equal(res.Data, []byte{tag}) {
+			t.Fatalf("wrong result order: got %v, expected %d", res.Data, tag)
+		}
+	}
+	in, err := conn.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, ok := in.(*message.DHTClientGetDoneMsg)
+	if !ok || done.ID != id || done.Count != 2 {
+		t.Fatalf("unexpected message %s", in)
+	}
+}
+
+// TestDHTClientPutVerify checks confirmed PUT requests: a PUT announced
+// with PUT-VERIFY is answered with a confirmation (without samples on a
+// node without neighbors).
+func TestDHTClientPutVerify(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.DHT.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	payload := []byte("verified test block")
+	key := crypto.Hash(payload)
+	put := message.NewDHTClientPutMsg(key, enums.BLOCK_TYPE_TEST, payload)
+	put.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	if err = conn.Send(ctx, message.NewDHTClientPutVerifyMsg(key)); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Send(ctx, put); err != nil {
+		t.Fatal(err)
+	}
+	in, err := conn.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm, ok := in.(*message.DHTClientPutConfirmMsg)
+	if !ok {
+		t.Fatalf("unexpected message %s", in)
+	}
+	if !cm.Key.Equal(key) || cm.Samples != 0 || cm.Confirmed != 0 || cm.Retries != 0 {
+		t.Fatalf("unexpected confirmation %s", cm)
+	}
+}
@@ -0,0 +1,193 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// testResponder collects result messages for a (remote) peer.
+type testResponder struct {
+	sync.Mutex
+	peer *util.PeerID
+	msgs []*message.DHTP2PResultMsg
+}
+
+// Send a message to the peer.
+func (r *testResponder) Send(ctx context.Context, msg message.Message) error {
+	if res, ok := msg.(*message.DHTP2PResultMsg); ok {
+		r.Lock()
+		r.msgs = append(r.msgs, res)
+		r.Unlock()
+	}
+	return nil
+}
+
+// Receiver of results
+func (r *testResponder) Receiver() *util.PeerID {
+	return r.peer
+}
+
+// results received so far
+func (r *testResponder) results() []*message.DHTP2PResultMsg {
+	r.Lock()
+	defer r.Unlock()
+	return r.msgs
+}
+
+// wait for (at least) n results; results with recorded routes are sent
+// after the path element is signed asynchronously.
+func (r *testResponder) wait(n int, timeout time.Duration) []*message.DHTP2PResultMsg {
+	deadline := time.Now().Add(timeout)
+	for {
+		list := r.results()
+		if len(list) >= n || time.Now().After(deadline) {
+			return list
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestDHTTestBlock sends a TEST block through the P2P pipeline: a PUT
+// from a remote peer is stored, a GET from another remote peer gets a
+// RESULT for it and the block can be retrieved with the module API.
+func TestDHTTestBlock(t *testing.T) {
+	tb := NewTestBed(t)
+
+	payload := []byte("arbitrary test payload")
+	key := crypto.Hash([]byte("test key"))
+	blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNow().Add(time.Hour), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// PUT from a remote peer
+	sender := util.NewPeerID(util.NewRndArray(32))
+	put := message.NewDHTP2PPutMsg(blk)
+	put.Key = key
+	put.PeerFilter.Add(sender)
+	if !tb.dht.HandleMessage(tb.ctx, sender, put, &testResponder{peer: sender}) {
+		t.Fatal("PUT not handled")
+	}
+
+	// GET from a remote peer
+	resp := &testResponder{peer: util.NewPeerID(util.NewRndArray(32))}
+	get := message.NewDHTP2PGetMsg()
+	get.BType = enums.BLOCK_TYPE_TEST
+	get.Query = key
+	get.PeerFilter.Add(resp.peer)
+	if !tb.dht.HandleMessage(tb.ctx, resp.peer, get, resp) {
+		t.Fatal("GET not handled")
+	}
+	list := resp.results()
+	if len(list) != 1 {
+		t.Fatalf("expected one result, got %d", len(list))
+	}
+	if res := list[0]; res.BType != enums.BLOCK_TYPE_TEST || !res.Query.Equal(key) || !bytes.Equal(res.Block, payload) {
+		t.Fatalf("wrong result %s", res)
+	}
+
+	// known results are filtered out
+	rf := blocks.BlockHandlers[enums.BLOCK_TYPE_TEST].SetupResultFilter(128, util.RndUInt32())
+	rf.Add(blk)
+	resp = &testResponder{peer: util.NewPeerID(util.NewRndArray(32))}
+	get.ResFilter = rf.Bytes()
+	get.RfSize = uint16(len(get.ResFilter))
+	get.PeerFilter.Add(resp.peer)
+	if !tb.dht.HandleMessage(tb.ctx, resp.peer, get, resp) {
+		t.Fatal("GET not handled")
+	}
+	if n := len(resp.results()); n != 0 {
+		t.Fatalf("expected no result for filtered GET, got %d", n)
+	}
+
+	// GET with module API
+	query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0)
+	query.Params()["timeout"] = 2 * time.Second
+	for res := range tb.dht.Get(tb.ctx, query) {
+		tblk, ok := res.(*blocks.TestBlock)
+		if !ok {
+			t.Fatalf("unexpected block %s", res)
+		}
+		if !bytes.Equal(tblk.Data, payload) {
+			t.Fatalf("wrong block data %v", tblk.Data)
+		}
+		return
+	}
+	t.Fatal("no result for GET")
+}
+
+// TestDHTRecordRoute checks the RECORD_ROUTE flag for a block stored by
+// the local peer: a remote GET asking for the route gets a result with a
+// valid last hop signature of the local peer.
+func TestDHTRecordRoute(t *testing.T) {
+	tb := NewTestBed(t)
+
+	payload := []byte("record route payload")
+	key := crypto.Hash(payload)
+	blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNow().Add(time.Hour), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PUT from the local peer (as a client would do)
+	put := message.NewDHTP2PPutMsg(blk)
+	put.Key = key
+	put.Flags = enums.DHT_RO_RECORD_ROUTE | enums.DHT_RO_DEMULTIPLEX_EVERYWHERE
+	put.PeerFilter.Add(tb.core.PeerID())
+	if !tb.dht.HandleMessage(tb.ctx, nil, put, &testResponder{}) {
+		t.Fatal("PUT not handled")
+	}
+
+	// GET from a remote peer with route recording
+	resp := &testResponder{peer: util.NewPeerID(util.NewRndArray(32))}
+	get := message.NewDHTP2PGetMsg()
+	get.BType = enums.BLOCK_TYPE_TEST
+	get.Flags = enums.DHT_RO_RECORD_ROUTE
+	get.Query = key
+	get.PeerFilter.Add(resp.peer)
+	if !tb.dht.HandleMessage(tb.ctx, resp.peer, get, resp) {
+		t.Fatal("GET not handled")
+	}
+	list := resp.wait(1, 5*time.Second)
+	if len(list) != 1 {
+		t.Fatalf("expected one result, got %d", len(list))
+	}
+	res := list[0]
+	if res.Flags&enums.DHT_RO_RECORD_ROUTE == 0 || res.LastSig == nil {
+		t.Fatalf("no route recorded in result %s", res)
+	}
+	// the last hop signature must verify for the receiving peer
+	pth := res.Path(tb.core.PeerID())
+	pth.Verify(resp.peer)
+	if pth.LastSig == nil {
+		t.Fatal("invalid last hop signature")
+	}
+}
@@ -0,0 +1,90 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/service/dns2gns"
+
+	"github.com/miekg/dns"
+)
+
+// TestDNS2GNS resolves a published GNS name with DNS queries (UDP and
+// TCP) through the DNS-to-GNS gateway.
+func TestDNS2GNS(t *testing.T) {
+	tb := NewTestBed(t)
+
+	zp := newZoneKey(t)
+	addr := []byte{10, 0, 0, 3}
+	tb.addZone(t, "test", zp, "www", enums.GNS_TYPE_DNS_A, addr)
+	tb.RunZoneMaster()
+	if set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_A, 10*time.Second); set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+
+	// start gateway on a dynamic port
+	tb.cfg.DNS2GNS = &config.DNS2GNSConfig{
+		TLDs: []string{"alt"},
+	}
+	gw := dns2gns.NewGateway(tb.cfg)
+	if err := gw.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Stop()
+
+	query := func(network, name string, rcode int) *dns.Msg {
+		t.Helper()
+		q := new(dns.Msg)
+		q.SetQuestion(dns.Fqdn(name), dns.TypeA)
+		cl := &dns.Client{Net: network, Timeout: 10 * time.Second}
+		resp, _, err := cl.Exchange(q, gw.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Rcode != rcode {
+			t.Fatalf("%s '%s': got rcode %s, expected %s", network, name,
+				dns.RcodeToString[resp.Rcode], dns.RcodeToString[rcode])
+		}
+		return resp
+	}
+	ztld := zp.Public().ID()
+	for _, network := range []string{"udp", "tcp"} {
+		for _, name := range []string{"www." + ztld, "www." + ztld + ".alt"} {
+			resp := query(network, name, dns.RcodeSuccess)
+			if len(resp.Answer) != 1 {
+				t.Fatalf("%s '%s': expected one answer, got %v", network, name, resp.Answer)
+			}
+			a, ok := resp.Answer[0].(*dns.A)
+			if !ok || !a.A.Equal(net.IP(addr)) || a.Hdr.Name != dns.Fqdn(name) {
+				t.Fatalf("%s '%s': wrong answer %s", network, name, resp.Answer[0])
+			}
+		}
+	}
+	// unknown label in GNS
+	query("udp", "ftp."+ztld, dns.RcodeNameError)
+	// DNS names are refused without upstream server
+	query("udp", "www.example.com", dns.RcodeRefused)
+}
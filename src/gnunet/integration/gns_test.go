@@ -0,0 +1,157 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// TestPublishResolve publishes a zone with the zonemaster (via the DHT
+// service socket) and resolves a name in that zone with GNS (via the DHT).
+func TestPublishResolve(t *testing.T) {
+	tb := NewTestBed(t)
+
+	// create zone with an A record for "www"
+	zp := newZoneKey(t)
+	addr := []byte{10, 0, 0, 1}
+	tb.addZone(t, "test", zp, "www", enums.GNS_TYPE_DNS_A, addr)
+
+	// publish zone and resolve name
+	tb.RunZoneMaster()
+	set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_A, 10*time.Second)
+	if set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+	rec := set.Records[0]
+	if rec.RType != enums.GNS_TYPE_DNS_A {
+		t.Fatalf("wrong record type %s", rec.RType)
+	}
+	if !bytes.Equal(rec.Data, addr) {
+		t.Fatalf("wrong record data %v", rec.Data)
+	}
+
+	// unknown label does not resolve
+	if set = tb.Resolve(t, "ftp", zp.Public(), enums.GNS_TYPE_DNS_A, 0); set != nil && set.Count > 0 {
+		t.Fatalf("unexpected records for unknown label: %v", set)
+	}
+}
+
+// TestPutSignedBlock stores a GNS block signed offline (like the
+// sign-zone tool does) with a DHT client PUT and resolves the name.
+func TestPutSignedBlock(t *testing.T) {
+	tb := NewTestBed(t)
+
+	// sign block for label "www"
+	zp := newZoneKey(t)
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   6,
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   util.WriteCString("hello"),
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := blocks.GNSQueryKey(zp.Public(), "www")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// store block in DHT
+	put := message.NewDHTClientPutMsg(key, enums.BLOCK_TYPE_GNS_NAMERECORD, blk.Bytes())
+	put.Expire = blk.Expire()
+	if _, err = service.RequestResponse(tb.ctx, "test", "dht", tb.cfg.DHT.Service.Socket, put, false); err != nil {
+		t.Fatal(err)
+	}
+	// resolve name
+	set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_TXT, 10*time.Second)
+	if set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+	if !bytes.Equal(set.Records[0].Data, util.WriteCString("hello")) {
+		t.Fatalf("wrong record data %v", set.Records[0].Data)
+	}
+}
+
+// TestRestartServices restarts GNS and the namecache individually and
+// resolves a name after each restart.
+func TestRestartServices(t *testing.T) {
+	tb := NewTestBed(t)
+
+	zp := newZoneKey(t)
+	tb.addZone(t, "test", zp, "www", enums.GNS_TYPE_DNS_A, []byte{10, 0, 0, 2})
+	tb.RunZoneMaster()
+	resolve := func(wait time.Duration) {
+		t.Helper()
+		set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_A, wait)
+		if set == nil || set.Count != 1 {
+			t.Fatalf("expected one record, got %v", set)
+		}
+	}
+	resolve(10 * time.Second)
+
+	// restart GNS (nothing depends on it)
+	if err := tb.sup.Restart("gns"); err != nil {
+		t.Fatal(err)
+	}
+	resolve(0)
+
+	// the namecache can't be stopped while GNS is running
+	if err := tb.sup.Restart("namecache"); !errors.Is(err, service.ErrUnitRequired) {
+		t.Fatalf("expected required service error, got %v", err)
+	}
+	for _, op := range []struct {
+		fn   func(string) error
+		name string
+	}{
+		{tb.sup.Stop, "gns"},
+		{tb.sup.Stop, "zonemaster"},
+		{tb.sup.Restart, "namecache"},
+		{tb.sup.Start, "zonemaster"},
+		{tb.sup.Start, "gns"},
+	} {
+		if err := op.fn(op.name); err != nil {
+			t.Fatalf("%s: %s", op.name, err.Error())
+		}
+	}
+	resolve(0)
+
+	// restarted services accept clients on their sockets
+	for _, cfg := range []*config.ServiceConfig{tb.cfg.GNS.Service, tb.cfg.Namecache.Service} {
+		cl, err := service.NewClient(tb.ctx, cfg.Socket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl.Close()
+	}
+}
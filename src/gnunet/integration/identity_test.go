@@ -0,0 +1,74 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/identity"
+)
+
+// TestIdentity creates an ego on the identity service socket and
+// resolves its name through the client helpers and the GNS service.
+func TestIdentity(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	socket := tb.cfg.Identity.Service.Socket
+
+	zk, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := service.RequestResponse(ctx, "test", "identity", socket, message.NewIdentityCreateMsg(zk, "home"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc, ok := resp.(*message.IdentityResultCodeMsg); !ok || rc.ResultCode != 0 {
+		t.Fatalf("create: unexpected response %s", resp)
+	}
+	// list and lookup egos
+	egos, err := identity.ListEgos(ctx, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(egos) != 1 || egos[0].Name != "home" || !egos[0].Key.Public().Equal(zk.Public()) {
+		t.Fatalf("unexpected egos %v", egos)
+	}
+	if _, err = identity.LookupEgo(ctx, "test", socket, "work"); err != identity.ErrEgoUnknown {
+		t.Fatalf("unknown ego: got %v", err)
+	}
+	// GNS resolves the ego name to its zone key
+	zkey, err := tb.gns.LookupIdentity(ctx, "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zkey == nil || !zkey.Equal(zk.Public()) {
+		t.Fatal("GNS ego lookup failed")
+	}
+}
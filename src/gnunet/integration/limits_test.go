@@ -0,0 +1,96 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+	"gnunet/service"
+)
+
+// TestSessionLimit serves the revocation service on a socket that allows
+// only one client session: a second session is rejected while the first
+// is active.
+func TestSessionLimit(t *testing.T) {
+	tb := NewTestBed(t)
+
+	cfg := &config.ServiceConfig{
+		Socket: filepath.Join(tb.dir, "limited.sock"),
+		Params: map[string]string{"perm": "0770"},
+		Limits: &config.LimitConfig{MaxSessions: 1},
+	}
+	tb.serve(t, "limited", tb.rev, cfg)
+	query := func(cl *service.Client) error {
+		ctx, cancel := context.WithTimeout(tb.ctx, 5*time.Second)
+		defer cancel()
+		if err := cl.SendRequest(ctx, message.NewRevocationQueryMsg(newZoneKey(t).Public())); err != nil {
+			return err
+		}
+		_, err := cl.ReceiveResponse(ctx)
+		return err
+	}
+	// first session is served
+	cl1, err := service.NewClient(tb.ctx, cfg.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = query(cl1); err != nil {
+		t.Fatal(err)
+	}
+	// second session is rejected
+	cl2, err := service.NewClient(tb.ctx, cfg.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = query(cl2); err == nil {
+		t.Fatal("second session not rejected")
+	}
+	cl2.Close()
+
+	// after the first session ended, a new session is served
+	cl1.Close()
+	time.Sleep(250 * time.Millisecond)
+	cl3, err := service.NewClient(tb.ctx, cfg.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl3.Close()
+	if err = query(cl3); err != nil {
+		t.Fatal(err)
+	}
+	// check statistics
+	rpc := new(service.LimitsRPC)
+	reply := new(service.LimitStatsResponse)
+	if err = rpc.Stats(nil, &service.LimitStatsRequest{Services: []string{"limited"}}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Stats) != 1 {
+		t.Fatalf("no statistics for limited service: %v", reply.Stats)
+	}
+	if s := reply.Stats[0].Sessions; s.Rejected != 1 || s.Peak != 1 {
+		t.Fatalf("wrong session statistics: %+v", s)
+	}
+}
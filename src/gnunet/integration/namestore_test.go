@@ -0,0 +1,219 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// TestNamestoreLookup checks the "found" semantics and record filters of
+// namestore lookups and the edit locks of labels.
+func TestNamestoreLookup(t *testing.T) {
+	tb := NewTestBed(t)
+
+	// publish zone: once the name resolves, the zonemaster is running.
+	zp := newZoneKey(t)
+	tb.addZone(t, "store", zp, "www", enums.GNS_TYPE_DNS_TXT, []byte("public"))
+	tb.RunZoneMaster()
+	if set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_TXT, 10*time.Second); set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+	cl1 := tb.namestoreClient(t)
+	cl2 := tb.namestoreClient(t)
+
+	// add a private record to the label
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: util.AbsoluteTimeNow().Add(time.Hour),
+		Size:   7,
+		Flags:  enums.GNS_FLAG_PRIVATE,
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   []byte("private"),
+	})
+	if ec := cl1.store(t, zp, "www", rs); ec != enums.EC_NONE {
+		t.Fatalf("store failed: %s", ec)
+	}
+
+	// lookup with and without private records
+	if resp := cl1.lookup(t, zp, "www", false, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_YES) || resp.RdCount != 2 {
+		t.Fatalf("expected two records, got %v", resp)
+	}
+	if resp := cl1.lookup(t, zp, "www", false, enums.GNS_FILTER_OMIT_PRIVATE); resp.Found != int16(enums.RC_YES) || resp.RdCount != 1 {
+		t.Fatalf("expected one public record, got %v", resp)
+	}
+	// unknown label and zone
+	if resp := cl1.lookup(t, zp, "ftp", false, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_NO) || resp.RdCount != 0 {
+		t.Fatalf("unknown label found: %v", resp)
+	}
+	if resp := cl1.lookup(t, newZoneKey(t), "www", false, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_NO) {
+		t.Fatalf("label in unknown zone found: %v", resp)
+	}
+
+	// edit lock by first client
+	if resp := cl1.lookup(t, zp, "www", true, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_YES) {
+		t.Fatalf("edit lookup failed: %v", resp)
+	}
+	// second client can't edit or store the locked label
+	if resp := cl2.lookup(t, zp, "www", true, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_SYSERR) {
+		t.Fatalf("edit lookup on locked label: %v", resp)
+	}
+	if ec := cl2.store(t, zp, "www", rs); ec != enums.EC_NAMESTORE_STORE_FAILED {
+		t.Fatalf("store on locked label: %s", ec)
+	}
+	// plain lookups are not affected by the lock
+	if resp := cl2.lookup(t, zp, "www", false, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_YES) {
+		t.Fatalf("lookup on locked label failed: %v", resp)
+	}
+
+	// end of transaction releases the lock
+	if ec := cl1.txControl(t, message.NamestoreTxCommit); ec != enums.EC_NONE {
+		t.Fatalf("commit failed: %s", ec)
+	}
+	if resp := cl2.lookup(t, zp, "www", true, enums.GNS_FILTER_NONE); resp.Found != int16(enums.RC_YES) {
+		t.Fatalf("edit lookup after commit failed: %v", resp)
+	}
+
+	// client disconnect releases the lock
+	cl2.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := cl1.lookup(t, zp, "www", true, enums.GNS_FILTER_NONE)
+		if resp.Found == int16(enums.RC_YES) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lock not released on disconnect")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestNamestoreRecordSize checks that records are rejected if a label
+// would no longer fit into a GNS block.
+func TestNamestoreRecordSize(t *testing.T) {
+	tb := NewTestBed(t)
+	zp := newZoneKey(t)
+	tb.addZone(t, "size", zp, "www", enums.GNS_TYPE_DNS_TXT, []byte("public"))
+	tb.RunZoneMaster()
+	if set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_TXT, 10*time.Second); set == nil {
+		t.Fatal("zone not published")
+	}
+	cl := tb.namestoreClient(t)
+	big := func() *blocks.RecordSet {
+		rs := blocks.NewRecordSet()
+		rs.AddRecord(&blocks.ResourceRecord{
+			Expire: util.AbsoluteTimeNow().Add(time.Hour),
+			Size:   20000,
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   make([]byte, 20000),
+		})
+		return rs
+	}
+	if ec := cl.store(t, zp, "big", big()); ec != enums.EC_NONE {
+		t.Fatalf("store failed: %s", ec)
+	}
+	if ec := cl.store(t, zp, "big", big()); ec != enums.EC_NAMESTORE_RECORD_TOO_BIG {
+		t.Fatalf("oversized label accepted: %s", ec)
+	}
+	if resp := cl.lookup(t, zp, "big", false, enums.GNS_FILTER_NONE); resp.RdCount != 1 {
+		t.Fatalf("expected one record, got %d", resp.RdCount)
+	}
+}
+
+//----------------------------------------------------------------------
+// namestore client helpers
+//----------------------------------------------------------------------
+
+// nsClient is a client session with the namestore (zonemaster) service.
+type nsClient struct {
+	*service.Client
+	tb *TestBed
+	id uint32
+}
+
+// namestoreClient connects to the namestore service.
+func (tb *TestBed) namestoreClient(t *testing.T) *nsClient {
+	t.Helper()
+	cl, err := service.NewClient(tb.ctx, tb.cfg.ZoneMaster.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cl.Close() })
+	return &nsClient{Client: cl, tb: tb}
+}
+
+// request sends a message and returns the response.
+func (c *nsClient) request(t *testing.T, req message.Message) message.Message {
+	t.Helper()
+	if err := c.SendRequest(c.tb.ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.ReceiveResponse(c.tb.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// lookup records for a label
+func (c *nsClient) lookup(t *testing.T, zp *crypto.ZonePrivate, label string, edit bool, filter enums.GNSFilter) *message.NamestoreRecordLookupRespMsg {
+	t.Helper()
+	c.id++
+	req := message.NewNamestoreRecordLookupMsg(c.id, zp, label, edit)
+	req.Filter = uint16(filter)
+	resp, ok := c.request(t, req).(*message.NamestoreRecordLookupRespMsg)
+	if !ok {
+		t.Fatal("wrong response type")
+	}
+	return resp
+}
+
+// store records under a label
+func (c *nsClient) store(t *testing.T, zp *crypto.ZonePrivate, label string, rs *blocks.RecordSet) enums.ErrorCode {
+	t.Helper()
+	c.id++
+	req := message.NewNamestoreRecordStoreMsg(c.id, zp)
+	req.AddRecordSet(label, rs)
+	resp, ok := c.request(t, req).(*message.NamestoreRecordStoreRespMsg)
+	if !ok {
+		t.Fatal("wrong response type")
+	}
+	return enums.ErrorCode(resp.Status)
+}
+
+// txControl sends a transaction control code
+func (c *nsClient) txControl(t *testing.T, ctrl uint16) enums.ErrorCode {
+	t.Helper()
+	c.id++
+	resp, ok := c.request(t, message.NewNamestoreTxControlMsg(c.id, ctrl)).(*message.NamestoreTxControlResultMsg)
+	if !ok {
+		t.Fatal("wrong response type")
+	}
+	return resp.Result
+}
@@ -0,0 +1,68 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service"
+	"gnunet/util"
+)
+
+// TestIndependentNodes runs two unconnected nodes in one process: each
+// node resolves the names of its own zone, but not the names of the
+// other node.
+func TestIndependentNodes(t *testing.T) {
+	var (
+		nodes [2]*TestBed
+		zones [2]*crypto.ZonePrivate
+	)
+	for i := range nodes {
+		nodes[i] = newTestBed(t, base64.StdEncoding.EncodeToString(util.NewRndArray(32)))
+		zones[i] = newZoneKey(t)
+		nodes[i].addZone(t, "test", zones[i], "www", enums.GNS_TYPE_DNS_A, []byte{10, 0, 1, byte(i)})
+	}
+	if nodes[0].core.PeerID().Equal(nodes[1].core.PeerID()) {
+		t.Fatal("nodes share a peer identity")
+	}
+	for i, tb := range nodes {
+		tb.RunZoneMaster()
+		// each node has its own maintenance jobs
+		s, _ := tb.ctx.Value(service.CtxScheduler).(*service.Scheduler)
+		if s == nil || len(s.Jobs("namecache:collect")) != 1 {
+			t.Fatalf("node %d: maintenance job not scheduled", i)
+		}
+	}
+	for i, tb := range nodes {
+		set := tb.Resolve(t, "www", zones[i].Public(), enums.GNS_TYPE_DNS_A, 10*time.Second)
+		if set == nil || set.Count != 1 || set.Records[0].Data[3] != byte(i) {
+			t.Fatalf("node %d: expected own record, got %v", i, set)
+		}
+		other := zones[1-i].Public()
+		if set = tb.Resolve(t, "www", other, enums.GNS_TYPE_DNS_A, 0); set != nil && set.Count > 0 {
+			t.Fatalf("node %d: resolved name of other node: %v", i, set)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+)
+
+// TestPeerstore stores, iterates and watches records on the peerstore
+// service socket; HELLOs received by the DHT are shared in the peerstore.
+func TestPeerstore(t *testing.T) {
+	tb := NewTestBed(t)
+
+	ctx, cancel := context.WithTimeout(tb.ctx, 10*time.Second)
+	defer cancel()
+	conn, err := service.NewConnection(ctx, tb.cfg.Peerstore.Service.Socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// iterate records: returns the received records
+	iterate := func(subSystem string, peer *util.PeerID, key string) (list []*message.PeerstoreIterateRecordMsg) {
+		t.Helper()
+		if err := conn.Send(ctx, message.NewPeerstoreIterateMsg(subSystem, peer, key)); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			in, err := conn.Receive(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch msg := in.(type) {
+			case *message.PeerstoreIterateRecordMsg:
+				list = append(list, msg)
+			case *message.PeerstoreIterateEndMsg:
+				return
+			default:
+				t.Fatalf("unexpected message %s", in)
+			}
+		}
+	}
+
+	// watch a key, then store a record for it
+	peer := util.NewPeerID(util.NewRndArray(32))
+	if err = conn.Send(ctx, message.NewPeerstoreWatchMsg(message.PeerstoreKeyHash("transport", peer, "rtt"))); err != nil {
+		t.Fatal(err)
+	}
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	store := message.NewPeerstoreStoreMsg("transport", peer, "rtt", []byte("42"), expire, message.PeerstoreReplace)
+	if err = conn.Send(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	in, err := conn.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, ok := in.(*message.PeerstoreWatchRecordMsg); !ok || !rec.PeerID().Equal(peer) || string(rec.Value) != "42" {
+		t.Fatalf("unexpected watch notification %s", in)
+	}
+	if list := iterate("transport", peer, ""); len(list) != 1 || list[0].Key() != "rtt" {
+		t.Fatalf("unexpected records %v", list)
+	}
+
+	// a HELLO received by the DHT is stored in the peerstore
+	pk, sk := ed25519.NewKeypair()
+	sender := util.NewPeerID(pk.Bytes())
+	addr, err := util.ParseAddress("ip+udp://127.0.0.1:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr.Expire = util.NewAbsoluteTimeEpoch(uint64(time.Now().Add(time.Hour).Unix()))
+	hello := message.NewDHTP2PHelloMsg()
+	hello.SetAddresses([]*util.Address{addr})
+	sig, err := sk.EdSign(hello.SignedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = hello.SetSignature(util.NewPeerSignature(sig.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err = hello.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if !tb.dht.HandleMessage(ctx, sender, hello, &testResponder{peer: sender}) {
+		t.Fatal("HELLO not handled")
+	}
+	list := iterate("dht", sender, "hello")
+	if len(list) != 1 {
+		t.Fatalf("expected 1 HELLO record, got %d", len(list))
+	}
+	hb, err := blocks.ParseHelloBlockFromURL(string(list[0].Value), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hb.PeerID.Equal(sender) {
+		t.Fatalf("HELLO of wrong peer %s", hb.PeerID)
+	}
+}
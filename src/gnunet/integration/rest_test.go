@@ -0,0 +1,121 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gnunet/enums"
+	"gnunet/service/rest"
+)
+
+// TestREST accesses GNS, namestore and identities through the REST
+// gateway.
+func TestREST(t *testing.T) {
+	tb := NewTestBed(t)
+
+	zp := newZoneKey(t)
+	tb.addZone(t, "test", zp, "www", enums.GNS_TYPE_DNS_A, []byte{10, 0, 0, 4})
+	tb.RunZoneMaster()
+	if set := tb.Resolve(t, "www", zp.Public(), enums.GNS_TYPE_DNS_A, 10*time.Second); set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+
+	// start gateway on a dynamic port
+	gw := rest.NewGateway(tb.cfg)
+	if err := gw.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Stop()
+	base := "http://" + gw.Addr().String()
+
+	call := func(method, path, body string, status int, out any) {
+		t.Helper()
+		req, err := http.NewRequest(method, base+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			buf := new(bytes.Buffer)
+			_, _ = buf.ReadFrom(resp.Body)
+			t.Fatalf("%s %s: got status %d (%s), expected %d", method, path, resp.StatusCode, buf.String(), status)
+		}
+		if out != nil {
+			if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	ztld := zp.Public().ID()
+
+	// GNS lookup
+	rs := new(rest.RecordSet)
+	call(http.MethodGet, "/gns/www."+ztld+"?record_type=A", "", http.StatusOK, rs)
+	if len(rs.Data) != 1 || rs.Data[0].Value != "10.0.0.4" || rs.Data[0].Type != "A" {
+		t.Fatalf("unexpected lookup result: %+v", rs)
+	}
+	call(http.MethodGet, "/gns/ftp."+ztld, "", http.StatusNotFound, nil)
+
+	// identities
+	var egos []*rest.Ego
+	call(http.MethodGet, "/identity/all", "", http.StatusOK, &egos)
+	if len(egos) != 1 || egos[0].Name != "test" || egos[0].PubKey != ztld {
+		t.Fatalf("unexpected egos: %+v", egos)
+	}
+	ego := new(rest.Ego)
+	call(http.MethodPost, "/identity", `{"name":"rest"}`, http.StatusCreated, ego)
+	call(http.MethodPost, "/identity", `{"name":"rest"}`, http.StatusConflict, nil)
+	call(http.MethodPut, "/identity/name/rest", `{"newname":"rest2"}`, http.StatusNoContent, nil)
+	got := new(rest.Ego)
+	call(http.MethodGet, "/identity/name/rest2", "", http.StatusOK, got)
+	if got.PubKey != ego.PubKey {
+		t.Fatalf("renamed ego has key %s, expected %s", got.PubKey, ego.PubKey)
+	}
+	call(http.MethodDelete, "/identity/name/rest2", "", http.StatusNoContent, nil)
+	call(http.MethodGet, "/identity/name/rest2", "", http.StatusNotFound, nil)
+
+	// namestore
+	var list []*rest.RecordSet
+	call(http.MethodGet, "/namestore/test", "", http.StatusOK, &list)
+	if len(list) != 1 || list[0].Name != "www" || len(list[0].Data) != 1 {
+		t.Fatalf("unexpected zone listing: %+v", list)
+	}
+	body := `{"record_name":"mail","data":[{"value":"hello","record_type":"TXT",` +
+		`"expiration_time":3600000000,"is_relative_expiration":true}]}`
+	call(http.MethodPost, "/namestore/test", body, http.StatusNoContent, nil)
+	call(http.MethodGet, "/namestore/test/mail", "", http.StatusOK, rs)
+	if len(rs.Data) != 1 || rs.Data[0].Value != "hello" || !rs.Data[0].Relative {
+		t.Fatalf("unexpected record set: %+v", rs)
+	}
+	call(http.MethodGet, "/namestore/test/ftp", "", http.StatusNotFound, nil)
+	call(http.MethodGet, "/namestore/unknown", "", http.StatusNotFound, nil)
+	call(http.MethodPost, "/namestore/test", `{"record_name":"bad","data":[]}`, http.StatusBadRequest, nil)
+}
@@ -0,0 +1,157 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/revocation"
+
+	"github.com/bfix/gospel/data"
+)
+
+// Revocation test vector (LSD0001): private key and revocation data
+// with a sufficient proof-of-work.
+const (
+	revD     = "6fea32c05af58bfa979553d188605fd57d8bf9cc263b78d5f7478c07b998ed70"
+	revProof = "0005feb46d865c1c" +
+		"0000395d1827c000" +
+		"e66a570bccd4b393" +
+		"e66a570bccd4b3ea" +
+		"e66a570bccd4b536" +
+		"e66a570bccd4b542" +
+		"e66a570bccd4b613" +
+		"e66a570bccd4b65f" +
+		"e66a570bccd4b672" +
+		"e66a570bccd4b70a" +
+		"e66a570bccd4b71a" +
+		"e66a570bccd4b723" +
+		"e66a570bccd4b747" +
+		"e66a570bccd4b777" +
+		"e66a570bccd4b785" +
+		"e66a570bccd4b789" +
+		"e66a570bccd4b7cf" +
+		"e66a570bccd4b7dc" +
+		"e66a570bccd4b93a" +
+		"e66a570bccd4b956" +
+		"e66a570bccd4ba4a" +
+		"e66a570bccd4ba9d" +
+		"e66a570bccd4bb28" +
+		"e66a570bccd4bb5a" +
+		"e66a570bccd4bb92" +
+		"e66a570bccd4bba2" +
+		"e66a570bccd4bbd8" +
+		"e66a570bccd4bbe2" +
+		"e66a570bccd4bc93" +
+		"e66a570bccd4bc94" +
+		"e66a570bccd4bd0f" +
+		"e66a570bccd4bdce" +
+		"e66a570bccd4be6a" +
+		"e66a570bccd4be73" +
+		"000100002ca223e879ecc4bbdeb5da17319281d63b2e3b6955f1c3775c804a98d5f8ddaa" +
+		"044a878a158b40f0c841d9f978cb1372eaee5199a3d87e5e2bdbc72a6c8c73d0" +
+		"00181dfc39c3aaa481667b165b5844e450713d8ab6a3b2ba8fef447b65076a0f"
+)
+
+// TestRevokeZoneKey publishes a zone, revokes its key through the
+// revocation service socket and checks the revocation status as seen
+// by GNS.
+func TestRevokeZoneKey(t *testing.T) {
+	tb := NewTestBed(t)
+
+	// the test vector has a low difficulty: lower the acceptance level
+	// of the revocation service for this test.
+	minDiff := revocation.MinAvgDifficulty
+	revocation.MinAvgDifficulty = 5
+	defer func() { revocation.MinAvgDifficulty = minDiff }()
+
+	// reconstruct zone key and revocation data from test vector
+	d, err := hex.DecodeString(revD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := hex.DecodeString(revProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd := new(revocation.RevData)
+	if err = data.Unmarshal(rd, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = rd.ZoneKeySig.Init(); err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+
+	// publish zone and resolve a name in it
+	tb.addZone(t, "revoked", zp, "www", enums.GNS_TYPE_DNS_TXT, []byte("hello"))
+	tb.RunZoneMaster()
+	if set := tb.Resolve(t, "www", zk, enums.GNS_TYPE_DNS_TXT, 10*time.Second); set == nil || set.Count != 1 {
+		t.Fatalf("expected one record, got %v", set)
+	}
+
+	// zone key is not revoked yet
+	valid, err := tb.gns.QueryKeyRevocation(tb.ctx, zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Fatal("zone key revoked before revocation")
+	}
+
+	// revoke zone key
+	ok, err := tb.gns.RevokeKey(tb.ctx, rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("revocation rejected")
+	}
+
+	// zone key is revoked now
+	if valid, err = tb.gns.QueryKeyRevocation(tb.ctx, zk); err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Fatal("zone key still valid after revocation")
+	}
+
+	// the DHT no longer serves blocks of the revoked zone
+	ctx, cancel := context.WithTimeout(tb.ctx, 5*time.Second)
+	defer cancel()
+	blk, err := tb.lookupDHT(ctx, blocks.NewGNSQuery(zk, "www"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blk != nil {
+		t.Fatal("DHT served block of revoked zone")
+	}
+}
@@ -0,0 +1,412 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service"
+	coreSrv "gnunet/service/core"
+	"gnunet/service/dht"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns"
+	"gnunet/service/identity"
+	"gnunet/service/namecache"
+	"gnunet/service/peerstore"
+	"gnunet/service/revocation"
+	"gnunet/service/store"
+	"gnunet/service/zonemaster"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Test bed: a single node running all services in one process.
+// Services are reachable through sockets in a temporary directory
+// (ephemeral per test); the node listens on a dynamic UDP port.
+//----------------------------------------------------------------------
+
+// TestBed holds references to all running services
+type TestBed struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	dir    string
+	cfg    *config.Config
+
+	core  *core.Core
+	dht   *dht.Service
+	rev   service.Service
+	ident service.Service
+	nc    service.Service
+	gns   *gns.Service
+	zm    *zonemaster.ZoneMaster
+
+	sup   *service.Supervisor
+	hdlrs []*service.SocketHandler
+}
+
+// NewTestBed sets up configuration and starts all services.
+func NewTestBed(t *testing.T) *TestBed {
+	t.Helper()
+	return newTestBed(t, "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24=")
+}
+
+// newTestBed starts a node with given private seed (base64-encoded). The
+// node has its own configuration and maintenance scheduler, so several
+// nodes can run in one process.
+func newTestBed(t *testing.T, seed string) *TestBed {
+	t.Helper()
+	logger.SetLogLevel(logger.WARN)
+
+	tb := new(TestBed)
+	tb.dir = t.TempDir()
+	tb.ctx, tb.cancel = context.WithCancel(context.Background())
+	t.Cleanup(tb.Close)
+
+	sock := func(name string) *config.ServiceConfig {
+		return &config.ServiceConfig{
+			Socket: filepath.Join(tb.dir, name+".sock"),
+			Params: map[string]string{"perm": "0770"},
+		}
+	}
+	tb.cfg = &config.Config{
+		Local: &config.NodeConfig{
+			Name:        "integration",
+			PrivateSeed: seed,
+			Endpoints: []*config.EndpointConfig{
+				{
+					ID:      "test",
+					Network: "ip+udp",
+					Address: "127.0.0.1",
+					Port:    0,
+					TTL:     86400,
+				},
+			},
+		},
+		Network: &config.NetworkConfig{
+			NumPeers:  1,
+			LocalOnly: true,
+		},
+		Core: &config.CoreConfig{
+			Service: sock("core"),
+		},
+		RPC: &config.RPCConfig{},
+		DHT: &config.DHTConfig{
+			Service: sock("dht"),
+			Storage: util.ParameterSet{
+				"mode":  "file",
+				"cache": false,
+				"path":  filepath.Join(tb.dir, "dht"),
+				"maxGB": 1,
+			},
+			Routing: &config.RoutingConfig{
+				PeerTTL:   10800,
+				ReplLevel: 5,
+			},
+			Heartbeat: 900,
+		},
+		GNS: &config.GNSConfig{
+			Service:   sock("gns"),
+			ReplLevel: 10,
+			MaxDepth:  250,
+		},
+		Namecache: &config.NamecacheConfig{
+			Service: sock("namecache"),
+			Storage: util.ParameterSet{
+				"file": filepath.Join(tb.dir, "namecache.sqlite3"),
+				"num":  100,
+			},
+		},
+		Revocation: &config.RevocationConfig{
+			Service: sock("revocation"),
+			Storage: util.ParameterSet{
+				"mode":    "sql",
+				"connect": "sqlite3:" + filepath.Join(tb.dir, "revocation.db"),
+			},
+			Filter: filepath.Join(tb.dir, "revocation.filter"),
+		},
+		ZoneMaster: &config.ZoneMasterConfig{
+			Service: sock("zonemaster"),
+			Period:  300,
+			Storage: util.ParameterSet{
+				"mode": "sqlite3",
+				"file": filepath.Join(tb.dir, "zonemaster.db"),
+			},
+			GUI: "127.0.0.1:0",
+		},
+		Peerstore: &config.PeerstoreConfig{
+			Service: sock("peerstore"),
+			Storage: util.ParameterSet{
+				"file": filepath.Join(tb.dir, "peerstore.sqlite3"),
+			},
+		},
+		Identity: &config.IdentityConfig{
+			Service: sock("identity"),
+			EgoDir:  filepath.Join(tb.dir, "egos"),
+		},
+		Logging: &config.LoggingConfig{},
+	}
+
+	tb.ctx = context.WithValue(tb.ctx, service.CtxScheduler, service.NewScheduler(tb.cfg.Maintenance))
+
+	// prepare storage for the revocation service
+	if err := initKVStore(filepath.Join(tb.dir, "revocation.db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.EnforceDirExists(filepath.Join(tb.dir, "dht")); err != nil {
+		t.Fatal(err)
+	}
+
+	// start core (and expose it on the core service socket)
+	var err error
+	// hermetic test run: no traffic to the network
+	transport.LocalOnly = tb.cfg.Network.LocalOnly
+	if tb.core, err = core.NewCore(tb.ctx, tb.cfg.Local); err != nil {
+		t.Fatal(err)
+	}
+	tb.core.SetSource(core.SourceLink(tb.cfg))
+	tb.sup = service.NewSupervisor(tb.ctx)
+	tb.unit(t, "core", nil, tb.cfg.Core.Service, func(ctx context.Context) (service.Service, error) {
+		return coreSrv.NewService(ctx, tb.core), nil
+	})
+
+	// start DHT service
+	if tb.dht, err = dht.NewService(tb.ctx, tb.core, tb.cfg.DHT); err != nil {
+		t.Fatal(err)
+	}
+	tb.dht.SetNetworkSize(tb.cfg.Network.NumPeers)
+	tb.unit(t, "dht", nil, tb.cfg.DHT.Service, func(context.Context) (service.Service, error) {
+		return tb.dht, nil
+	})
+
+	// start revocation service: the DHT module rejects GNS blocks of
+	// revoked zones through the in-process revocation module.
+	tb.unit(t, "revocation", nil, tb.cfg.Revocation.Service, func(ctx context.Context) (service.Service, error) {
+		tb.rev = revocation.NewService(ctx, tb.core, tb.cfg.Revocation)
+		fcn := make(map[string]any)
+		tb.rev.(*revocation.Service).Export(fcn)
+		tb.dht.Import(fcn)
+		return tb.rev, nil
+	})
+
+	// start identity service
+	tb.unit(t, "identity", nil, tb.cfg.Identity.Service, func(ctx context.Context) (service.Service, error) {
+		if tb.ident = identity.NewService(ctx, tb.cfg.Identity); tb.ident == nil {
+			return nil, errors.New("can't instantiate identity service")
+		}
+		return tb.ident, nil
+	})
+
+	// start namecache service
+	tb.unit(t, "namecache", nil, tb.cfg.Namecache.Service, func(ctx context.Context) (service.Service, error) {
+		if tb.nc = namecache.NewService(ctx, tb.cfg.Namecache); tb.nc == nil {
+			return nil, errors.New("can't instantiate namecache service")
+		}
+		return tb.nc, nil
+	})
+
+	// start peerstore service: the DHT module shares HELLOs through the
+	// in-process peerstore module.
+	tb.unit(t, "peerstore", nil, tb.cfg.Peerstore.Service, func(ctx context.Context) (service.Service, error) {
+		ps := peerstore.NewService(ctx, tb.cfg.Peerstore)
+		if ps == nil {
+			return nil, errors.New("can't instantiate peerstore service")
+		}
+		fcn := make(map[string]any)
+		ps.Export(fcn)
+		tb.dht.Import(fcn)
+		return ps, nil
+	})
+
+	// start GNS service: the GNS resolver uses the in-process DHT module
+	// for remote lookups; namecache, revocation and identity requests are
+	// routed through the service sockets.
+	gnsNeeds := []string{"dht", "namecache", "revocation", "identity"}
+	tb.unit(t, "gns", gnsNeeds, tb.cfg.GNS.Service, func(ctx context.Context) (service.Service, error) {
+		var ok bool
+		if tb.gns, ok = gns.NewService(ctx, nil, tb.cfg).(*gns.Service); !ok {
+			return nil, errors.New("can't instantiate GNS service")
+		}
+		tb.gns.LookupRemote = tb.lookupDHT
+		return tb.gns, nil
+	})
+
+	// start zonemaster (publishes to the DHT service socket); namestore
+	// requests are served once the zonemaster is running.
+	tb.zm = zonemaster.NewService(tb.ctx, nil, tb.cfg, nil)
+	zmNeeds := []string{"dht", "namecache", "identity"}
+	tb.unit(t, "zonemaster", zmNeeds, tb.cfg.ZoneMaster.Service, func(context.Context) (service.Service, error) {
+		return tb.zm, nil
+	})
+	if err = tb.sup.StartAll(); err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+// Close the test bed and all running services.
+func (tb *TestBed) Close() {
+	for _, hdlr := range tb.hdlrs {
+		_ = hdlr.Stop()
+	}
+	if tb.sup != nil {
+		tb.sup.StopAll()
+	}
+	tb.cancel()
+	if tb.core != nil {
+		tb.core.Shutdown()
+	}
+	// grace period for services to terminate
+	time.Sleep(500 * time.Millisecond)
+}
+
+// serve a service on its socket.
+func (tb *TestBed) serve(t *testing.T, name string, srv service.Service, cfg *config.ServiceConfig) {
+	t.Helper()
+	hdlr := service.NewSocketHandler(name, srv)
+	hdlr.SetLimits(cfg.Limits)
+	hdlr.SetSource(core.SourceLink(tb.cfg))
+	if err := hdlr.Start(tb.ctx, cfg.Socket, cfg.Params); err != nil {
+		t.Fatalf("%s: %s", name, err.Error())
+	}
+	tb.hdlrs = append(tb.hdlrs, hdlr)
+}
+
+// unit adds a service served on its socket to the supervisor.
+func (tb *TestBed) unit(t *testing.T, name string, needs []string, cfg *config.ServiceConfig, create func(context.Context) (service.Service, error)) {
+	t.Helper()
+	if err := tb.sup.Add(service.SocketUnit(name, needs, cfg, core.SourceLink(tb.cfg), create)); err != nil {
+		t.Fatalf("%s: %s", name, err.Error())
+	}
+}
+
+// RunZoneMaster starts the zonemaster; it publishes all zones in its
+// database on start-up.
+func (tb *TestBed) RunZoneMaster() {
+	go tb.zm.Run(tb.ctx)
+}
+
+// lookupDHT returns the first (verified and decrypted) GNS block for
+// a query from the DHT.
+func (tb *TestBed) lookupDHT(ctx context.Context, query blocks.Query) (blocks.Block, error) {
+	gq, ok := query.(*blocks.GNSQuery)
+	if !ok {
+		return nil, gns.ErrInvalidResponseType
+	}
+	gq.Params()["timeout"] = 2 * time.Second
+	for blk := range tb.dht.Get(ctx, query) {
+		block := new(blocks.GNSBlock)
+		if err := blocks.Unwrap(blk, block); err != nil {
+			return nil, err
+		}
+		if err := gq.Verify(block); err != nil {
+			return nil, err
+		}
+		if err := gq.Decrypt(block); err != nil {
+			return nil, err
+		}
+		return block, nil
+	}
+	return nil, nil
+}
+
+// Resolve a name relative to a zone; retries until records are found
+// or the timeout is reached.
+func (tb *TestBed) Resolve(t *testing.T, name string, zkey *crypto.ZoneKey, rtype enums.GNSType, timeout time.Duration) *blocks.RecordSet {
+	t.Helper()
+	kind := gns.NewRRTypeList(rtype)
+	deadline := time.Now().Add(timeout)
+	for {
+		set, err := tb.gns.Resolve(tb.ctx, name, zkey, kind, enums.GNS_LO_DEFAULT, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (set != nil && set.Count > 0) || time.Now().After(deadline) {
+			return set
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+//----------------------------------------------------------------------
+// helpers
+//----------------------------------------------------------------------
+
+// initKVStore creates an SQLite3 database suitable for a SQL key/value store.
+func initKVStore(fname string) error {
+	db, err := sql.Open("sqlite3", fname)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("create table store(key text primary key, value text)")
+	return err
+}
+
+// addZone creates a zone with a single label and record in the zone database
+// used by the zonemaster.
+func (tb *TestBed) addZone(t *testing.T, name string, zp *crypto.ZonePrivate, label string, rtype enums.GNSType, data []byte) {
+	t.Helper()
+	dbFile, _ := util.GetParam[string](tb.cfg.ZoneMaster.Storage, "file")
+	zdb, err := store.OpenZoneDB(dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+
+	zone := store.NewZone(name, zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	lbl := store.NewLabel(label)
+	if err = lbl.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	if err = zdb.SetLabel(lbl); err != nil {
+		t.Fatal(err)
+	}
+	rec := store.NewRecord(util.AbsoluteTimeNow().Add(time.Hour), rtype, 0, data)
+	rec.Label = lbl.ID
+	if err = zdb.SetRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newZoneKey creates a new random zone key pair.
+func newZoneKey(t *testing.T) *crypto.ZonePrivate {
+	t.Helper()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zp
+}
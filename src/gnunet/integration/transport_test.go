@@ -0,0 +1,152 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+// TestTCPTransport runs two cores with TCP endpoints: an address is
+// validated (PING/PONG) and a signed HELLO is exchanged over TCP.
+func TestTCPTransport(t *testing.T) {
+	testTransport(t, "tcp")
+}
+
+// TestHTTPSTransport exchanges messages between two cores over HTTPS
+// (with self-signed certificates).
+func TestHTTPSTransport(t *testing.T) {
+	testTransport(t, "https")
+}
+
+// TestUnixTransport exchanges messages between two cores over unix
+// domain sockets.
+func TestUnixTransport(t *testing.T) {
+	testTransport(t, "unix")
+}
+
+// testTransport runs two cores with endpoints for a network: an address
+// is validated (PING/PONG) and a signed HELLO is exchanged.
+func testTransport(t *testing.T, netw string) {
+	defer func(lo bool) { transport.LocalOnly = lo }(transport.LocalOnly)
+	transport.LocalOnly = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var nodes [2]*core.Core
+	for i := range nodes {
+		// unix domain sockets listen on a file
+		addr := "127.0.0.1"
+		if netw == "unix" {
+			addr = fmt.Sprintf("%s/node%d.sock", t.TempDir(), i)
+		}
+		cfg := &config.NodeConfig{
+			Name:        netw,
+			PrivateSeed: base64.StdEncoding.EncodeToString(util.NewRndArray(32)),
+			Endpoints: []*config.EndpointConfig{
+				{
+					ID:      netw,
+					Network: netw,
+					Address: addr,
+					Port:    0,
+					TTL:     86400,
+				},
+			},
+		}
+		c, err := core.NewCore(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Shutdown()
+		nodes[i] = c
+	}
+	// listen for HELLOs on the second node
+	events := make(chan *core.Event, 16)
+	filter := core.NewEventFilter()
+	filter.AddMsgType(enums.MSG_DHT_P2P_HELLO)
+	nodes[1].Register("test", core.NewListener(events, filter))
+
+	// learn (and validate) the address of the second node
+	peer1, peer2 := nodes[0].PeerID(), nodes[1].PeerID()
+	addrs, err := nodes[1].Addresses()
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("no address for second node: %v", err)
+	}
+	addr := addrs[0]
+	if addr.Network() != netw {
+		t.Fatalf("unexpected address %s", addr.URI())
+	}
+	nodes[0].Learn(ctx, peer2, []*util.Address{addr}, "test")
+	for !nodes[0].Validated(peer2, addr) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("address not validated")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// send a signed HELLO of the first node
+	own, err := nodes[0].Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := message.NewDHTP2PHelloMsg()
+	hello.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	hello.SetAddresses(own)
+	if err = nodes[0].Sign(hello); err != nil {
+		t.Fatal(err)
+	}
+	if err = nodes[0].Send(ctx, peer2, hello); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case ev := <-events:
+			if ev.ID != core.EV_MESSAGE {
+				continue
+			}
+			in, ok := ev.Msg.(*message.DHTP2PHelloMsg)
+			if !ok || !ev.Peer.Equal(peer1) {
+				t.Fatalf("unexpected message %v", ev.Msg)
+			}
+			if ok, err := in.Verify(peer1); !ok || err != nil {
+				t.Fatalf("HELLO not verified: %v", err)
+			}
+			list, err := in.Addresses()
+			if err != nil || len(list) != 1 || list[0].URI() != own[0].URI() {
+				t.Fatalf("unexpected HELLO addresses %v", list)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("HELLO not received")
+		}
+	}
+}
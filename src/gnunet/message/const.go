@@ -22,6 +22,13 @@ import (
 	"time"
 )
 
+// MaxSize is the maximum size of a GNUnet message (including header).
+const MaxSize = 65535
+
+// DefaultReplLevel is the replication level of new DHT PUT messages
+// (senders set the configured level).
+const DefaultReplLevel = 5
+
 // Time constants
 var (
 	// How long is a PONG signature valid?  We'll recycle a signature until
@@ -21,6 +21,7 @@ package message
 import (
 	"fmt"
 	"gnunet/enums"
+	"gnunet/util"
 )
 
 // NewEmptyMessage creates a new empty message object for the given type.
@@ -50,6 +51,10 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 		return NewTransportPongMsg(0, nil), nil
 	case enums.MSG_TRANSPORT_SESSION_KEEPALIVE:
 		return NewSessionKeepAliveMsg(), nil
+	case enums.MSG_FRAGMENT:
+		return NewFragmentMsg(0, 0, 0, nil), nil
+	case enums.MSG_FRAGMENT_ACK:
+		return NewFragmentAckMsg(0, 0), nil
 
 	//------------------------------------------------------------------
 	// Core
@@ -57,6 +62,36 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 
 	case enums.MSG_CORE_EPHEMERAL_KEY:
 		return NewEphemeralKeyMsg(), nil
+	case enums.MSG_CORE_INIT:
+		return NewCoreInitMsg(0, nil), nil
+	case enums.MSG_CORE_INIT_REPLY:
+		return NewCoreInitReplyMsg(nil), nil
+	case enums.MSG_CORE_NOTIFY_CONNECT:
+		return NewCoreNotifyConnectMsg(nil), nil
+	case enums.MSG_CORE_NOTIFY_DISCONNECT:
+		return NewCoreNotifyDisconnectMsg(nil), nil
+	case enums.MSG_CORE_NOTIFY_INBOUND:
+		return NewCoreNotifyTrafficMsg(false, nil, nil), nil
+	case enums.MSG_CORE_NOTIFY_OUTBOUND:
+		return NewCoreNotifyTrafficMsg(true, nil, nil), nil
+	case enums.MSG_CORE_SEND_REQUEST:
+		return NewCoreSendRequestMsg(nil, 0, 0), nil
+	case enums.MSG_CORE_SEND_READY:
+		return NewCoreSendReadyMsg(nil, 0, 0), nil
+	case enums.MSG_CORE_SEND:
+		return NewCoreSendMsg(nil, nil), nil
+	case enums.MSG_CORE_BINARY_TYPE_MAP, enums.MSG_CORE_COMPRESSED_TYPE_MAP:
+		return &CoreTypeMapMsg{MsgHeader: MsgHeader{4, msgType}}, nil
+	case enums.MSG_CORE_CONFIRM_TYPE_MAP:
+		return NewCoreConfirmTypeMapMsg(nil), nil
+	case enums.MSG_CORE_VERSION_QUERY:
+		return NewCoreVersionQueryMsg(), nil
+	case enums.MSG_CORE_VERSION_REPLY:
+		return NewCoreVersionReplyMsg(nil), nil
+	case enums.MSG_REQUEST_AGPL:
+		return NewAGPLRequestMsg(), nil
+	case enums.MSG_RESPONSE_AGPL:
+		return &AGPLResponseMsg{MsgHeader: MsgHeader{4, enums.MSG_RESPONSE_AGPL}}, nil
 
 	//------------------------------------------------------------------
 	// DHT
@@ -72,6 +107,16 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 		return NewDHTClientResultMsg(nil), nil
 	case enums.MSG_DHT_CLIENT_GET_RESULTS_KNOWN:
 		return NewDHTClientGetResultsKnownMsg(nil), nil
+	case enums.MSG_DHT_CLIENT_GET_CREDIT:
+		return NewDHTClientGetCreditMsg(0, 0), nil
+	case enums.MSG_DHT_CLIENT_GET_LIMIT:
+		return NewDHTClientGetLimitMsg(0, 0, util.RelativeTime{}), nil
+	case enums.MSG_DHT_CLIENT_GET_DONE:
+		return NewDHTClientGetDoneMsg(0, 0), nil
+	case enums.MSG_DHT_CLIENT_PUT_VERIFY:
+		return NewDHTClientPutVerifyMsg(nil), nil
+	case enums.MSG_DHT_CLIENT_PUT_CONFIRM:
+		return NewDHTClientPutConfirmMsg(nil, 0, 0, 0), nil
 
 	//------------------------------------------------------------------
 	// DHT-P2P
@@ -94,6 +139,10 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 		return NewGNSLookupMsg(), nil
 	case enums.MSG_GNS_LOOKUP_RESULT:
 		return NewGNSLookupResultMsg(0), nil
+	case enums.MSG_GNS_LOOKUP_TRACE:
+		return NewGNSLookupTraceMsg(0), nil
+	case enums.MSG_GNS_LOOKUP_PROVENANCE:
+		return NewGNSLookupProvenanceMsg(0), nil
 
 	//------------------------------------------------------------------
 	// Namecache
@@ -108,6 +157,55 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 	case enums.MSG_NAMECACHE_BLOCK_CACHE_RESPONSE:
 		return NewNamecacheCacheResponseMsg(), nil
 
+	//------------------------------------------------------------------
+	// Peerstore
+	//------------------------------------------------------------------
+
+	case enums.MSG_PEERSTORE_STORE:
+		return NewPeerstoreStoreMsg("", nil, "", nil, util.AbsoluteTimeNever(), 0), nil
+	case enums.MSG_PEERSTORE_ITERATE:
+		return NewPeerstoreIterateMsg("", nil, ""), nil
+	case enums.MSG_PEERSTORE_ITERATE_RECORD:
+		return NewPeerstoreIterateRecordMsg("", nil, "", nil, util.AbsoluteTimeNever()), nil
+	case enums.MSG_PEERSTORE_ITERATE_END:
+		return NewPeerstoreIterateEndMsg(), nil
+	case enums.MSG_PEERSTORE_WATCH:
+		return NewPeerstoreWatchMsg(nil), nil
+	case enums.MSG_PEERSTORE_WATCH_RECORD:
+		return NewPeerstoreWatchRecordMsg("", nil, "", nil, util.AbsoluteTimeNever()), nil
+	case enums.MSG_PEERSTORE_WATCH_CANCEL:
+		return NewPeerstoreWatchCancelMsg(nil), nil
+
+	//------------------------------------------------------------------
+	// Resolver
+	//------------------------------------------------------------------
+
+	case enums.MSG_RESOLVER_REQUEST:
+		return &ResolverRequestMsg{MsgHeader: MsgHeader{16, msgType}}, nil
+	case enums.MSG_RESOLVER_RESPONSE:
+		return &ResolverResponseMsg{MsgHeader: MsgHeader{8, msgType}}, nil
+
+	//------------------------------------------------------------------
+	// Statistics
+	//------------------------------------------------------------------
+
+	case enums.MSG_STATISTICS_SET:
+		return &StatisticsSetMsg{MsgHeader: MsgHeader{16, msgType}}, nil
+	case enums.MSG_STATISTICS_GET:
+		return &StatisticsGetMsg{MsgHeader: MsgHeader{4, msgType}}, nil
+	case enums.MSG_STATISTICS_VALUE:
+		return &StatisticsValueMsg{MsgHeader: MsgHeader{16, msgType}}, nil
+	case enums.MSG_STATISTICS_END:
+		return NewStatisticsEndMsg(), nil
+	case enums.MSG_STATISTICS_WATCH:
+		return &StatisticsWatchMsg{StatisticsGetMsg{MsgHeader: MsgHeader{4, msgType}}}, nil
+	case enums.MSG_STATISTICS_WATCH_VALUE:
+		return NewStatisticsWatchValueMsg(0, 0, false), nil
+	case enums.MSG_STATISTICS_DISCONNECT:
+		return NewStatisticsDisconnectMsg(), nil
+	case enums.MSG_STATISTICS_DISCONNECT_CONFIRM:
+		return NewStatisticsDisconnectConfirmMsg(), nil
+
 	//------------------------------------------------------------------
 	// Revocation
 	//------------------------------------------------------------------
@@ -121,6 +219,56 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 	case enums.MSG_REVOCATION_REVOKE_RESPONSE:
 		return NewRevocationRevokeResponseMsg(false), nil
 
+	//------------------------------------------------------------------
+	// Network size estimation
+	//------------------------------------------------------------------
+
+	case enums.MSG_NSE_START:
+		return NewNSEStartMsg(), nil
+	case enums.MSG_NSE_P2P_FLOOD:
+		return NewNSEFloodMsg(util.AbsoluteTimeNow(), 0, nil, 0), nil
+	case enums.MSG_NSE_ESTIMATE:
+		return NewNSEEstimateMsg(util.AbsoluteTimeNow(), 0, 0), nil
+
+	//------------------------------------------------------------------
+	// CADET
+	//------------------------------------------------------------------
+
+	case enums.MSG_CADET_CONNECTION_CREATE:
+		return NewCadetConnectionCreateMsg(nil, nil), nil
+	case enums.MSG_CADET_CONNECTION_CREATE_ACK:
+		return NewCadetConnectionMsg(false, nil), nil
+	case enums.MSG_CADET_CONNECTION_DESTROY:
+		return NewCadetConnectionMsg(true, nil), nil
+	case enums.MSG_CADET_TUNNEL_KX:
+		return NewCadetTunnelKXMsg(nil, nil, nil), nil
+	case enums.MSG_CADET_TUNNEL_KX_AUTH:
+		return NewCadetTunnelKXAuthMsg(nil, nil, nil, nil), nil
+	case enums.MSG_CADET_TUNNEL_ENCRYPTED:
+		return NewCadetTunnelEncryptedMsg(nil, nil, nil, nil), nil
+	case enums.MSG_CADET_CHANNEL_OPEN:
+		return NewCadetChannelOpenMsg(0, nil, 0), nil
+	case enums.MSG_CADET_CHANNEL_OPEN_ACK:
+		return NewCadetChannelOpenAckMsg(0, nil), nil
+	case enums.MSG_CADET_CHANNEL_DESTROY:
+		return NewCadetChannelDestroyMsg(0), nil
+	case enums.MSG_CADET_CHANNEL_APP_DATA:
+		return NewCadetChannelAppDataMsg(0, 0, nil), nil
+	case enums.MSG_CADET_CHANNEL_APP_DATA_ACK:
+		return NewCadetChannelDataAckMsg(0, 0), nil
+	case enums.MSG_CADET_LOCAL_PORT_OPEN:
+		return NewCadetLocalPortMsg(true, nil), nil
+	case enums.MSG_CADET_LOCAL_PORT_CLOSE:
+		return NewCadetLocalPortMsg(false, nil), nil
+	case enums.MSG_CADET_LOCAL_CHANNEL_CREATE:
+		return NewCadetLocalChannelCreateMsg(0, nil, nil), nil
+	case enums.MSG_CADET_LOCAL_CHANNEL_DESTROY:
+		return NewCadetLocalChannelDestroyMsg(0), nil
+	case enums.MSG_CADET_LOCAL_DATA:
+		return NewCadetLocalDataMsg(0, nil), nil
+	case enums.MSG_CADET_LOCAL_ACK:
+		return NewCadetLocalAckMsg(0), nil
+
 	//------------------------------------------------------------------
 	// Identity service
 	//------------------------------------------------------------------
@@ -130,7 +278,9 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 	case enums.MSG_IDENTITY_RESULT_CODE:
 		return NewIdentityResultCodeMsg(0), nil
 	case enums.MSG_IDENTITY_UPDATE:
-		return NewIdentityUpdateMsg("", nil), nil
+		msg := NewIdentityUpdateMsg("", nil)
+		msg.Name_ = nil // size given by message
+		return msg, nil
 	case enums.MSG_IDENTITY_CREATE:
 		return NewIdentityCreateMsg(nil, ""), nil
 	case enums.MSG_IDENTITY_RENAME:
@@ -154,6 +304,8 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 		return NewNamestoreZoneIterEndMsg(0), nil
 	case enums.MSG_NAMESTORE_RECORD_STORE:
 		return NewNamestoreRecordStoreMsg(0, nil), nil
+	case enums.MSG_NAMESTORE_RECORD_STORE_KEYED:
+		return NewNamestoreRecordStoreKeyedMsg(0, nil, nil), nil
 	case enums.MSG_NAMESTORE_RECORD_STORE_RESPONSE:
 		return NewNamestoreRecordStoreRespMsg(0, 0), nil
 	case enums.MSG_NAMESTORE_RECORD_LOOKUP:
@@ -161,7 +313,8 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 	case enums.MSG_NAMESTORE_RECORD_LOOKUP_RESPONSE:
 		return NewNamestoreRecordLookupRespMsg(0, nil, ""), nil
 	case enums.MSG_NAMESTORE_RECORD_RESULT:
-		return NewNamestoreRecordResultMsg(0, nil, ""), nil
+		// no preset label: the unmarshaller fails on a pre-filled name
+		return new(NamestoreRecordResultMsg), nil
 	case enums.MSG_NAMESTORE_ZONE_TO_NAME:
 		return NewNamestoreZoneToNameMsg(0, nil, nil), nil
 	case enums.MSG_NAMESTORE_ZONE_TO_NAME_RESPONSE:
@@ -171,6 +324,10 @@ func NewEmptyMessage(msgType enums.MsgType) (Message, error) {
 	case enums.MSG_NAMESTORE_MONITOR_NEXT:
 		return NewNamestoreMonitorNextMsg(0, 0), nil
 	case enums.MSG_NAMESTORE_MONITOR_SYNC:
+	case enums.MSG_NAMESTORE_TX_CONTROL:
+		return NewNamestoreTxControlMsg(0, 0), nil
+	case enums.MSG_NAMESTORE_TX_CONTROL_RESULT:
+		return NewNamestoreTxControlResultMsg(0, 0), nil
 	}
 	return nil, fmt.Errorf("unknown message type %d", msgType)
 }
@@ -20,6 +20,7 @@ package message
 
 import (
 	"errors"
+	"fmt"
 	"gnunet/enums"
 
 	"github.com/bfix/gospel/data"
@@ -28,6 +29,7 @@ import (
 // Error codes
 var (
 	ErrMsgHeaderTooSmall = errors.New("Message header too small")
+	ErrMsgSize           = errors.New("message size mismatch")
 )
 
 //----------------------------------------------------------------------
@@ -77,3 +79,27 @@ func GetMsgHeader(b []byte) (mh *MsgHeader, err error) {
 	err = data.Unmarshal(mh, b)
 	return
 }
+
+// Parse reconstructs a message from its binary representation (as the
+// serialized form). The size of the message in its header must match the
+// size of the byte array.
+func Parse(b []byte) (msg Message, err error) {
+	var mh *MsgHeader
+	if mh, err = GetMsgHeader(b); err != nil {
+		return
+	}
+	if int(mh.MsgSize) != len(b) {
+		return nil, ErrMsgSize
+	}
+	if msg, err = NewEmptyMessage(mh.MsgType); err != nil {
+		return
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("message{%d} is nil", mh.MsgType)
+	}
+	if err = data.Unmarshal(msg, b); err != nil {
+		return nil, err
+	}
+	err = msg.Init()
+	return
+}
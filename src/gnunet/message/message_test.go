@@ -0,0 +1,60 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/bfix/gospel/data"
+)
+
+func TestParse(t *testing.T) {
+	msg := NewResolverRequestMsg(7, "gnunet.org", ResolverAfUnspec)
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// round trip
+	out, err := Parse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, ok := out.(*ResolverRequestMsg)
+	if !ok || req.ClientID != 7 || req.Size() != msg.Size() {
+		t.Fatalf("unexpected message %v", out)
+	}
+	// size in header must match size of data
+	if _, err = Parse(append(buf, 0)); err != ErrMsgSize {
+		t.Fatalf("extended message: %v", err)
+	}
+	if _, err = Parse(buf[:len(buf)-1]); err != ErrMsgSize {
+		t.Fatalf("truncated message: %v", err)
+	}
+	if _, err = Parse(buf[:3]); err != ErrMsgHeaderTooSmall {
+		t.Fatalf("truncated header: %v", err)
+	}
+	// unknown message type
+	unknown := make([]byte, 4)
+	binary.BigEndian.PutUint16(unknown, 4)
+	binary.BigEndian.PutUint16(unknown[2:], 0xffff)
+	if _, err = Parse(unknown); err == nil {
+		t.Fatal("unknown message type parsed")
+	}
+}
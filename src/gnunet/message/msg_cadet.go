@@ -0,0 +1,526 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// CadetCIDSize is the size of a connection identifier.
+const CadetCIDSize = 32
+
+// cid returns a (new or cloned) connection identifier.
+func cid(id []byte) []byte {
+	if id == nil {
+		return make([]byte, CadetCIDSize)
+	}
+	return util.Clone(id)
+}
+
+//----------------------------------------------------------------------
+// CADET_CONNECTION_CREATE
+//----------------------------------------------------------------------
+
+// CadetConnectionCreateMsg requests a connection along a path of peers
+// (starting with the originating peer).
+type CadetConnectionCreateMsg struct {
+	MsgHeader
+	Options uint32         `order:"big"`      // connection options
+	CID     []byte         `size:"32"`        // connection identifier
+	Path    []*util.PeerID `size:"(PathLen)"` // path from origin to destination
+}
+
+// NewCadetConnectionCreateMsg creates a new message for a connection.
+func NewCadetConnectionCreateMsg(id []byte, path []*util.PeerID) *CadetConnectionCreateMsg {
+	return &CadetConnectionCreateMsg{
+		MsgHeader: MsgHeader{uint16(40 + 32*len(path)), enums.MSG_CADET_CONNECTION_CREATE},
+		CID:       cid(id),
+		Path:      path,
+	}
+}
+
+// PathLen returns the number of peers in the path (derived from the
+// message size).
+func (m *CadetConnectionCreateMsg) PathLen() uint16 {
+	return (m.MsgSize - 40) / 32
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetConnectionCreateMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetConnectionCreateMsg) String() string {
+	return fmt.Sprintf("CadetConnectionCreateMsg{cid=%s,path=%d}", hex.EncodeToString(m.CID[:8]), len(m.Path))
+}
+
+//----------------------------------------------------------------------
+// CADET_CONNECTION_CREATE_ACK, CADET_CONNECTION_DESTROY
+//----------------------------------------------------------------------
+
+// CadetConnectionMsg acknowledges (or destroys) a connection.
+type CadetConnectionMsg struct {
+	MsgHeader
+	Reserved uint32 `order:"big"` // reserved for future use
+	CID      []byte `size:"32"`   // connection identifier
+}
+
+// NewCadetConnectionMsg creates an ACK (or DESTROY) for a connection.
+func NewCadetConnectionMsg(destroy bool, id []byte) *CadetConnectionMsg {
+	mt := enums.MSG_CADET_CONNECTION_CREATE_ACK
+	if destroy {
+		mt = enums.MSG_CADET_CONNECTION_DESTROY
+	}
+	return &CadetConnectionMsg{
+		MsgHeader: MsgHeader{40, mt},
+		CID:       cid(id),
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetConnectionMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetConnectionMsg) String() string {
+	op := "ack"
+	if m.MsgType == enums.MSG_CADET_CONNECTION_DESTROY {
+		op = "destroy"
+	}
+	return fmt.Sprintf("CadetConnectionMsg{%s,cid=%s}", op, hex.EncodeToString(m.CID[:8]))
+}
+
+//----------------------------------------------------------------------
+// CADET_TUNNEL_KX, CADET_TUNNEL_KX_AUTH
+//----------------------------------------------------------------------
+
+// CadetKXFlagForceReply asks the receiver of a KX to reply (even if it
+// has derived the keys already).
+const CadetKXFlagForceReply = 1
+
+// CadetTunnelKXMsg starts the Axolotl key exchange of a tunnel: it
+// announces the ephemeral key and the first ratchet key of the sender.
+type CadetTunnelKXMsg struct {
+	MsgHeader
+	Flags        uint32              `order:"big"` // KX flags
+	CID          []byte              `size:"32"`   // connection identifier
+	EphemeralKey *util.PeerPublicKey // ephemeral key of sender
+	RatchetKey   *util.PeerPublicKey // initial ratchet key of sender
+}
+
+// NewCadetTunnelKXMsg creates a new key exchange message.
+func NewCadetTunnelKXMsg(id []byte, eph, ratchet *util.PeerPublicKey) *CadetTunnelKXMsg {
+	if eph == nil {
+		eph = util.NewPeerPublicKey(nil)
+	}
+	if ratchet == nil {
+		ratchet = util.NewPeerPublicKey(nil)
+	}
+	return &CadetTunnelKXMsg{
+		MsgHeader:    MsgHeader{104, enums.MSG_CADET_TUNNEL_KX},
+		CID:          cid(id),
+		EphemeralKey: eph,
+		RatchetKey:   ratchet,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetTunnelKXMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetTunnelKXMsg) String() string {
+	return fmt.Sprintf("CadetTunnelKXMsg{cid=%s,flags=%d}", hex.EncodeToString(m.CID[:8]), m.Flags)
+}
+
+// CadetTunnelKXAuthMsg is the response to a key exchange: it carries the
+// keys of the responder and proves knowledge of the derived root key.
+type CadetTunnelKXAuthMsg struct {
+	MsgHeader
+	Flags        uint32              `order:"big"` // KX flags
+	CID          []byte              `size:"32"`   // connection identifier
+	EphemeralKey *util.PeerPublicKey // ephemeral key of sender
+	RatchetKey   *util.PeerPublicKey // initial ratchet key of sender
+	Auth         *crypto.HashCode    // hash of derived root key
+}
+
+// NewCadetTunnelKXAuthMsg creates a new key exchange response.
+func NewCadetTunnelKXAuthMsg(id []byte, eph, ratchet *util.PeerPublicKey, auth *crypto.HashCode) *CadetTunnelKXAuthMsg {
+	kx := NewCadetTunnelKXMsg(id, eph, ratchet)
+	if auth == nil {
+		auth = crypto.NewHashCode(nil)
+	}
+	return &CadetTunnelKXAuthMsg{
+		MsgHeader:    MsgHeader{168, enums.MSG_CADET_TUNNEL_KX_AUTH},
+		CID:          kx.CID,
+		EphemeralKey: kx.EphemeralKey,
+		RatchetKey:   kx.RatchetKey,
+		Auth:         auth,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetTunnelKXAuthMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetTunnelKXAuthMsg) String() string {
+	return fmt.Sprintf("CadetTunnelKXAuthMsg{cid=%s,flags=%d}", hex.EncodeToString(m.CID[:8]), m.Flags)
+}
+
+//----------------------------------------------------------------------
+// CADET_TUNNEL_ENCRYPTED
+//----------------------------------------------------------------------
+
+// CadetAxHeaderSize is the size of the (encrypted) Axolotl header:
+// message number, number of messages in previous chain, ratchet key.
+const CadetAxHeaderSize = 40
+
+// CadetTunnelEncryptedMsg carries an encrypted channel message.
+type CadetTunnelEncryptedMsg struct {
+	MsgHeader
+	Reserved uint32 `order:"big"` // reserved for future use
+	CID      []byte `size:"32"`   // connection identifier
+	HMAC     []byte `size:"32"`   // HMAC of header and payload
+	AxHeader []byte `size:"40"`   // encrypted Axolotl header
+	Payload  []byte `size:"*"`    // encrypted message
+}
+
+// NewCadetTunnelEncryptedMsg creates a new message for encrypted data.
+func NewCadetTunnelEncryptedMsg(id, hmac, hdr, payload []byte) *CadetTunnelEncryptedMsg {
+	if hmac == nil {
+		hmac = make([]byte, 32)
+	}
+	if hdr == nil {
+		hdr = make([]byte, CadetAxHeaderSize)
+	}
+	return &CadetTunnelEncryptedMsg{
+		MsgHeader: MsgHeader{uint16(112 + len(payload)), enums.MSG_CADET_TUNNEL_ENCRYPTED},
+		CID:       cid(id),
+		HMAC:      hmac,
+		AxHeader:  hdr,
+		Payload:   payload,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetTunnelEncryptedMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetTunnelEncryptedMsg) String() string {
+	return fmt.Sprintf("CadetTunnelEncryptedMsg{cid=%s,size=%d}", hex.EncodeToString(m.CID[:8]), len(m.Payload))
+}
+
+//----------------------------------------------------------------------
+// CADET_CHANNEL_OPEN, CADET_CHANNEL_OPEN_ACK, CADET_CHANNEL_DESTROY
+// (sent encrypted through a tunnel)
+//----------------------------------------------------------------------
+
+// CadetChannelOpenMsg asks the remote peer to open a channel to a port.
+// The port is hashed with the identity of the receiving peer.
+type CadetChannelOpenMsg struct {
+	MsgHeader
+	Options uint32           `order:"big"` // channel options
+	Port    *crypto.HashCode // hashed port
+	CTN     uint32           `order:"big"` // channel number in tunnel
+}
+
+// NewCadetChannelOpenMsg creates a new channel request.
+func NewCadetChannelOpenMsg(ctn uint32, port *crypto.HashCode, opt uint32) *CadetChannelOpenMsg {
+	if port == nil {
+		port = crypto.NewHashCode(nil)
+	}
+	return &CadetChannelOpenMsg{
+		MsgHeader: MsgHeader{76, enums.MSG_CADET_CHANNEL_OPEN},
+		Options:   opt,
+		Port:      port,
+		CTN:       ctn,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetChannelOpenMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetChannelOpenMsg) String() string {
+	return fmt.Sprintf("CadetChannelOpenMsg{ctn=%d,port=%s}", m.CTN, m.Port.Short())
+}
+
+// CadetChannelOpenAckMsg confirms an opened channel.
+type CadetChannelOpenAckMsg struct {
+	MsgHeader
+	Reserved uint32           `order:"big"` // reserved for future use
+	CTN      uint32           `order:"big"` // channel number in tunnel
+	Port     *crypto.HashCode // hashed port
+}
+
+// NewCadetChannelOpenAckMsg creates a new channel confirmation.
+func NewCadetChannelOpenAckMsg(ctn uint32, port *crypto.HashCode) *CadetChannelOpenAckMsg {
+	if port == nil {
+		port = crypto.NewHashCode(nil)
+	}
+	return &CadetChannelOpenAckMsg{
+		MsgHeader: MsgHeader{76, enums.MSG_CADET_CHANNEL_OPEN_ACK},
+		CTN:       ctn,
+		Port:      port,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetChannelOpenAckMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetChannelOpenAckMsg) String() string {
+	return fmt.Sprintf("CadetChannelOpenAckMsg{ctn=%d}", m.CTN)
+}
+
+// CadetChannelDestroyMsg closes a channel (or rejects a channel request).
+type CadetChannelDestroyMsg struct {
+	MsgHeader
+	Reserved uint32 `order:"big"` // reserved for future use
+	CTN      uint32 `order:"big"` // channel number in tunnel
+}
+
+// NewCadetChannelDestroyMsg creates a new message to close a channel.
+func NewCadetChannelDestroyMsg(ctn uint32) *CadetChannelDestroyMsg {
+	return &CadetChannelDestroyMsg{
+		MsgHeader: MsgHeader{12, enums.MSG_CADET_CHANNEL_DESTROY},
+		CTN:       ctn,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetChannelDestroyMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetChannelDestroyMsg) String() string {
+	return fmt.Sprintf("CadetChannelDestroyMsg{ctn=%d}", m.CTN)
+}
+
+//----------------------------------------------------------------------
+// CADET_CHANNEL_APP_DATA, CADET_CHANNEL_APP_DATA_ACK
+// (sent encrypted through a tunnel)
+//----------------------------------------------------------------------
+
+// CadetChannelAppDataMsg carries application data on a channel.
+type CadetChannelAppDataMsg struct {
+	MsgHeader
+	MID  uint32 `order:"big"` // message identifier
+	CTN  uint32 `order:"big"` // channel number in tunnel
+	Data []byte `size:"*"`    // application data
+}
+
+// NewCadetChannelAppDataMsg creates a new data message for a channel.
+func NewCadetChannelAppDataMsg(ctn, mid uint32, data []byte) *CadetChannelAppDataMsg {
+	return &CadetChannelAppDataMsg{
+		MsgHeader: MsgHeader{uint16(12 + len(data)), enums.MSG_CADET_CHANNEL_APP_DATA},
+		MID:       mid,
+		CTN:       ctn,
+		Data:      data,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetChannelAppDataMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetChannelAppDataMsg) String() string {
+	return fmt.Sprintf("CadetChannelAppDataMsg{ctn=%d,mid=%d,size=%d}", m.CTN, m.MID, len(m.Data))
+}
+
+// CadetChannelDataAckMsg acknowledges all data messages of a channel up
+// to (and including) a message identifier.
+type CadetChannelDataAckMsg struct {
+	MsgHeader
+	CTN     uint32 `order:"big"` // channel number in tunnel
+	Futures uint64 `order:"big"` // bitmask of received future messages
+	MID     uint32 `order:"big"` // last message received in order
+}
+
+// NewCadetChannelDataAckMsg creates a new data acknowledgement.
+func NewCadetChannelDataAckMsg(ctn, mid uint32) *CadetChannelDataAckMsg {
+	return &CadetChannelDataAckMsg{
+		MsgHeader: MsgHeader{20, enums.MSG_CADET_CHANNEL_APP_DATA_ACK},
+		CTN:       ctn,
+		MID:       mid,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetChannelDataAckMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetChannelDataAckMsg) String() string {
+	return fmt.Sprintf("CadetChannelDataAckMsg{ctn=%d,mid=%d}", m.CTN, m.MID)
+}
+
+//----------------------------------------------------------------------
+// CADET_LOCAL_PORT_OPEN, CADET_LOCAL_PORT_CLOSE
+//----------------------------------------------------------------------
+
+// CadetLocalPortMsg is sent by a client to start (or stop) listening on
+// a port.
+type CadetLocalPortMsg struct {
+	MsgHeader
+	Port *crypto.HashCode // port
+}
+
+// NewCadetLocalPortMsg creates a new port request.
+func NewCadetLocalPortMsg(open bool, port *crypto.HashCode) *CadetLocalPortMsg {
+	mt := enums.MSG_CADET_LOCAL_PORT_CLOSE
+	if open {
+		mt = enums.MSG_CADET_LOCAL_PORT_OPEN
+	}
+	if port == nil {
+		port = crypto.NewHashCode(nil)
+	}
+	return &CadetLocalPortMsg{
+		MsgHeader: MsgHeader{68, mt},
+		Port:      port,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetLocalPortMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetLocalPortMsg) String() string {
+	op := "close"
+	if m.MsgType == enums.MSG_CADET_LOCAL_PORT_OPEN {
+		op = "open"
+	}
+	return fmt.Sprintf("CadetLocalPortMsg{%s,port=%s}", op, m.Port.Short())
+}
+
+//----------------------------------------------------------------------
+// CADET_LOCAL_CHANNEL_CREATE, CADET_LOCAL_CHANNEL_DESTROY
+//----------------------------------------------------------------------
+
+// CadetLocalChannelIDCli is the lowest channel number allocated by the
+// service (for incoming channels); clients use smaller numbers.
+const CadetLocalChannelIDCli = 0x80000000
+
+// CadetLocalChannelCreateMsg is sent by a client to open a channel to a
+// port of a peer (or by the service to announce an incoming channel).
+type CadetLocalChannelCreateMsg struct {
+	MsgHeader
+	CCN     uint32           `order:"big"` // client channel number
+	Peer    *util.PeerID     // remote peer
+	Port    *crypto.HashCode // port
+	Options uint32           `order:"big"` // channel options
+}
+
+// NewCadetLocalChannelCreateMsg creates a new channel request.
+func NewCadetLocalChannelCreateMsg(ccn uint32, peer *util.PeerID, port *crypto.HashCode) *CadetLocalChannelCreateMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	if port == nil {
+		port = crypto.NewHashCode(nil)
+	}
+	return &CadetLocalChannelCreateMsg{
+		MsgHeader: MsgHeader{108, enums.MSG_CADET_LOCAL_CHANNEL_CREATE},
+		CCN:       ccn,
+		Peer:      peer,
+		Port:      port,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetLocalChannelCreateMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetLocalChannelCreateMsg) String() string {
+	return fmt.Sprintf("CadetLocalChannelCreateMsg{ccn=%d,peer=%s,port=%s}", m.CCN, m.Peer.Short(), m.Port.Short())
+}
+
+// CadetLocalChannelDestroyMsg closes a channel (client or service).
+type CadetLocalChannelDestroyMsg struct {
+	MsgHeader
+	CCN uint32 `order:"big"` // client channel number
+}
+
+// NewCadetLocalChannelDestroyMsg creates a new message to close a channel.
+func NewCadetLocalChannelDestroyMsg(ccn uint32) *CadetLocalChannelDestroyMsg {
+	return &CadetLocalChannelDestroyMsg{
+		MsgHeader: MsgHeader{8, enums.MSG_CADET_LOCAL_CHANNEL_DESTROY},
+		CCN:       ccn,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetLocalChannelDestroyMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetLocalChannelDestroyMsg) String() string {
+	return fmt.Sprintf("CadetLocalChannelDestroyMsg{ccn=%d}", m.CCN)
+}
+
+//----------------------------------------------------------------------
+// CADET_LOCAL_DATA, CADET_LOCAL_ACK
+//----------------------------------------------------------------------
+
+// CadetLocalDataMsg carries channel data between client and service.
+type CadetLocalDataMsg struct {
+	MsgHeader
+	CCN      uint32 `order:"big"` // client channel number
+	Priority uint32 `order:"big"` // priority and preferences
+	Data     []byte `size:"*"`    // application data
+}
+
+// NewCadetLocalDataMsg creates a new data message for a channel.
+func NewCadetLocalDataMsg(ccn uint32, data []byte) *CadetLocalDataMsg {
+	return &CadetLocalDataMsg{
+		MsgHeader: MsgHeader{uint16(12 + len(data)), enums.MSG_CADET_LOCAL_DATA},
+		CCN:       ccn,
+		Data:      data,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetLocalDataMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetLocalDataMsg) String() string {
+	return fmt.Sprintf("CadetLocalDataMsg{ccn=%d,size=%d}", m.CCN, len(m.Data))
+}
+
+// CadetLocalAckMsg allows the receiver to send the next data message on
+// a channel.
+type CadetLocalAckMsg struct {
+	MsgHeader
+	CCN uint32 `order:"big"` // client channel number
+}
+
+// NewCadetLocalAckMsg creates a new local acknowledgement.
+func NewCadetLocalAckMsg(ccn uint32) *CadetLocalAckMsg {
+	return &CadetLocalAckMsg{
+		MsgHeader: MsgHeader{8, enums.MSG_CADET_LOCAL_ACK},
+		CCN:       ccn,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CadetLocalAckMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CadetLocalAckMsg) String() string {
+	return fmt.Sprintf("CadetLocalAckMsg{ccn=%d}", m.CCN)
+}
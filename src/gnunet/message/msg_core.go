@@ -20,7 +20,12 @@ package message
 
 import (
 	//"encoding/hex"
+	"bytes"
+	"compress/zlib"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"gnunet/crypto"
@@ -120,3 +125,504 @@ func NewEphemeralKey(peerID []byte, ltPrv *ed25519.PrivateKey) (*ed25519.Private
 
 	return prv, msg, nil
 }
+
+//----------------------------------------------------------------------
+// CORE_INIT
+//----------------------------------------------------------------------
+
+// CoreInitMsg is sent by a client to register with the core service. It
+// lists the message types the client wants to receive from other peers.
+type CoreInitMsg struct {
+	MsgHeader
+	Options uint32          `order:"big"`                   // client options
+	Types   []enums.MsgType `size:"(NumTypes)" order:"big"` // message types of interest
+}
+
+// NewCoreInitMsg creates a new init message for the given message types.
+func NewCoreInitMsg(opts uint32, types []enums.MsgType) *CoreInitMsg {
+	return &CoreInitMsg{
+		MsgHeader: MsgHeader{uint16(8 + 2*len(types)), enums.MSG_CORE_INIT},
+		Options:   opts,
+		Types:     types,
+	}
+}
+
+// NumTypes returns the number of message types (derived from the
+// message size).
+func (m *CoreInitMsg) NumTypes() uint16 {
+	return (m.MsgSize - 8) / 2
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreInitMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreInitMsg) String() string {
+	return fmt.Sprintf("CoreInitMsg{options=%d,types=%v}", m.Options, m.Types)
+}
+
+//----------------------------------------------------------------------
+// CORE_INIT_REPLY
+//----------------------------------------------------------------------
+
+// CoreInitReplyMsg is the response of the core service to a client
+// init request; it carries the identity of the local peer.
+type CoreInitReplyMsg struct {
+	MsgHeader
+	Reserved uint32       `order:"big"` // always zero
+	Peer     *util.PeerID ``            // identity of local peer
+}
+
+// NewCoreInitReplyMsg creates a reply with the local peer identity.
+func NewCoreInitReplyMsg(peer *util.PeerID) *CoreInitReplyMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreInitReplyMsg{
+		MsgHeader: MsgHeader{40, enums.MSG_CORE_INIT_REPLY},
+		Peer:      peer,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreInitReplyMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreInitReplyMsg) String() string {
+	return fmt.Sprintf("CoreInitReplyMsg{peer=%s}", m.Peer)
+}
+
+//----------------------------------------------------------------------
+// CORE_NOTIFY_CONNECT, CORE_NOTIFY_DISCONNECT
+//----------------------------------------------------------------------
+
+// CoreNotifyMsg informs a client about a peer that connected to or
+// disconnected from the local peer. The message type distinguishes
+// both cases.
+type CoreNotifyMsg struct {
+	MsgHeader
+	Reserved uint32       `order:"big"` // always zero
+	Peer     *util.PeerID ``            // identity of remote peer
+}
+
+// NewCoreNotifyConnectMsg creates a notification for a connected peer.
+func NewCoreNotifyConnectMsg(peer *util.PeerID) *CoreNotifyMsg {
+	return newCoreNotifyMsg(enums.MSG_CORE_NOTIFY_CONNECT, peer)
+}
+
+// NewCoreNotifyDisconnectMsg creates a notification for a disconnected peer.
+func NewCoreNotifyDisconnectMsg(peer *util.PeerID) *CoreNotifyMsg {
+	return newCoreNotifyMsg(enums.MSG_CORE_NOTIFY_DISCONNECT, peer)
+}
+
+// create a new notification of given type
+func newCoreNotifyMsg(mt enums.MsgType, peer *util.PeerID) *CoreNotifyMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreNotifyMsg{
+		MsgHeader: MsgHeader{40, mt},
+		Peer:      peer,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreNotifyMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreNotifyMsg) String() string {
+	kind := "connect"
+	if m.MsgType == enums.MSG_CORE_NOTIFY_DISCONNECT {
+		kind = "disconnect"
+	}
+	return fmt.Sprintf("CoreNotifyMsg{%s,peer=%s}", kind, m.Peer)
+}
+
+//----------------------------------------------------------------------
+// CORE_NOTIFY_INBOUND, CORE_NOTIFY_OUTBOUND
+//----------------------------------------------------------------------
+
+// CoreNotifyTrafficMsg delivers a message received from (or sent to) a
+// peer to a client. The payload is the serialized message (or just its
+// header if the client requested headers only).
+type CoreNotifyTrafficMsg struct {
+	MsgHeader
+	Peer    *util.PeerID ``         // remote peer
+	Payload []byte       `size:"*"` // serialized message
+}
+
+// NewCoreNotifyTrafficMsg creates a traffic notification for a message
+// exchanged with a peer. If outbound is set, the message was sent by the
+// local peer.
+func NewCoreNotifyTrafficMsg(outbound bool, peer *util.PeerID, payload []byte) *CoreNotifyTrafficMsg {
+	mt := enums.MSG_CORE_NOTIFY_INBOUND
+	if outbound {
+		mt = enums.MSG_CORE_NOTIFY_OUTBOUND
+	}
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreNotifyTrafficMsg{
+		MsgHeader: MsgHeader{uint16(36 + len(payload)), mt},
+		Peer:      peer,
+		Payload:   payload,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreNotifyTrafficMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreNotifyTrafficMsg) String() string {
+	dir := "inbound"
+	if m.MsgType == enums.MSG_CORE_NOTIFY_OUTBOUND {
+		dir = "outbound"
+	}
+	return fmt.Sprintf("CoreNotifyTrafficMsg{%s,peer=%s,size=%d}", dir, m.Peer, len(m.Payload))
+}
+
+//----------------------------------------------------------------------
+// CORE_SEND_REQUEST
+//----------------------------------------------------------------------
+
+// CoreSendRequestMsg is sent by a client to announce a message it wants
+// to transmit to a peer. The client waits for a CORE_SEND_READY response
+// with the same request id before sending the message.
+type CoreSendRequestMsg struct {
+	MsgHeader
+	Priority uint32            `order:"big"` // message priority
+	Deadline util.AbsoluteTime ``            // deadline for transmission
+	Peer     *util.PeerID      ``            // receiving peer
+	Reserved uint32            `order:"big"` // always zero
+	MsgSize  uint16            `order:"big"` // size of message to be sent
+	SmrID    uint16            `order:"big"` // request identifier
+}
+
+// NewCoreSendRequestMsg creates a send request for a message of given
+// size to a peer.
+func NewCoreSendRequestMsg(peer *util.PeerID, size, smrID uint16) *CoreSendRequestMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreSendRequestMsg{
+		MsgHeader: MsgHeader{56, enums.MSG_CORE_SEND_REQUEST},
+		Deadline:  util.AbsoluteTimeNever(),
+		Peer:      peer,
+		MsgSize:   size,
+		SmrID:     smrID,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreSendRequestMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreSendRequestMsg) String() string {
+	return fmt.Sprintf("CoreSendRequestMsg{peer=%s,size=%d,id=%d}", m.Peer, m.MsgSize, m.SmrID)
+}
+
+//----------------------------------------------------------------------
+// CORE_SEND_READY
+//----------------------------------------------------------------------
+
+// CoreSendReadyMsg confirms a send request: the client can now transmit
+// the message.
+type CoreSendReadyMsg struct {
+	MsgHeader
+	MsgSize uint16       `order:"big"` // size of message to be sent
+	SmrID   uint16       `order:"big"` // request identifier
+	Peer    *util.PeerID ``            // receiving peer
+}
+
+// NewCoreSendReadyMsg creates a confirmation for a send request.
+func NewCoreSendReadyMsg(peer *util.PeerID, size, smrID uint16) *CoreSendReadyMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreSendReadyMsg{
+		MsgHeader: MsgHeader{40, enums.MSG_CORE_SEND_READY},
+		MsgSize:   size,
+		SmrID:     smrID,
+		Peer:      peer,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreSendReadyMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreSendReadyMsg) String() string {
+	return fmt.Sprintf("CoreSendReadyMsg{peer=%s,size=%d,id=%d}", m.Peer, m.MsgSize, m.SmrID)
+}
+
+//----------------------------------------------------------------------
+// CORE_SEND
+//----------------------------------------------------------------------
+
+// CoreSendMsg carries a (serialized) message a client wants to send to
+// a peer.
+type CoreSendMsg struct {
+	MsgHeader
+	Priority uint32            `order:"big"` // message priority
+	Deadline util.AbsoluteTime ``            // deadline for transmission
+	Peer     *util.PeerID      ``            // receiving peer
+	Payload  []byte            `size:"*"`    // serialized message
+}
+
+// NewCoreSendMsg creates a new message for sending a serialized message
+// to a peer.
+func NewCoreSendMsg(peer *util.PeerID, payload []byte) *CoreSendMsg {
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	return &CoreSendMsg{
+		MsgHeader: MsgHeader{uint16(48 + len(payload)), enums.MSG_CORE_SEND},
+		Deadline:  util.AbsoluteTimeNever(),
+		Peer:      peer,
+		Payload:   payload,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreSendMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreSendMsg) String() string {
+	return fmt.Sprintf("CoreSendMsg{peer=%s,size=%d}", m.Peer, len(m.Payload))
+}
+
+//----------------------------------------------------------------------
+// CORE_BINARY_TYPE_MAP, CORE_COMPRESSED_TYPE_MAP
+//----------------------------------------------------------------------
+
+// TypeMapSize is the size of an (uncompressed) type map: one bit for
+// each of the 65536 message types.
+const TypeMapSize = 8192
+
+// CoreTypeMapMsg announces the message types handled by the sending
+// peer. The type map is sent zlib-compressed (if that saves space) or as
+// a plain bitmap; the message type distinguishes both cases.
+type CoreTypeMapMsg struct {
+	MsgHeader
+	Data []byte `size:"*"` // (compressed) type map
+}
+
+// NewCoreTypeMapMsg creates a type map message for a bitmap of handled
+// message types. The bitmap is compressed if possible.
+func NewCoreTypeMapMsg(bitmap []byte) *CoreTypeMapMsg {
+	mt := enums.MSG_CORE_BINARY_TYPE_MAP
+	body := bitmap
+	buf := new(bytes.Buffer)
+	wrt := zlib.NewWriter(buf)
+	if _, err := wrt.Write(bitmap); err == nil && wrt.Close() == nil {
+		if buf.Len() < len(bitmap) {
+			mt = enums.MSG_CORE_COMPRESSED_TYPE_MAP
+			body = buf.Bytes()
+		}
+	}
+	return &CoreTypeMapMsg{
+		MsgHeader: MsgHeader{uint16(4 + len(body)), mt},
+		Data:      body,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreTypeMapMsg) Init() error { return nil }
+
+// Bitmap returns the (uncompressed) type map of the message.
+func (m *CoreTypeMapMsg) Bitmap() (bitmap []byte, err error) {
+	bitmap = m.Data
+	if m.MsgType == enums.MSG_CORE_COMPRESSED_TYPE_MAP {
+		var rdr io.ReadCloser
+		if rdr, err = zlib.NewReader(bytes.NewReader(m.Data)); err != nil {
+			return
+		}
+		defer rdr.Close()
+		// read at most one byte more than a valid type map
+		if bitmap, err = io.ReadAll(io.LimitReader(rdr, TypeMapSize+1)); err != nil {
+			return
+		}
+	}
+	if len(bitmap) != TypeMapSize {
+		err = fmt.Errorf("invalid type map size %d", len(bitmap))
+	}
+	return
+}
+
+// String returns a human-readable representation of the message.
+func (m *CoreTypeMapMsg) String() string {
+	kind := "binary"
+	if m.MsgType == enums.MSG_CORE_COMPRESSED_TYPE_MAP {
+		kind = "compressed"
+	}
+	return fmt.Sprintf("CoreTypeMapMsg{%s,size=%d}", kind, len(m.Data))
+}
+
+//----------------------------------------------------------------------
+// CORE_CONFIRM_TYPE_MAP
+//----------------------------------------------------------------------
+
+// CoreConfirmTypeMapMsg confirms the reception of a type map; it carries
+// the hash of the (uncompressed) type map received.
+type CoreConfirmTypeMapMsg struct {
+	MsgHeader
+	Reserved uint32           `order:"big"` // always zero
+	Hash     *crypto.HashCode ``            // hash of received type map
+}
+
+// NewCoreConfirmTypeMapMsg creates a confirmation for a type map with
+// given hash.
+func NewCoreConfirmTypeMapMsg(hash *crypto.HashCode) *CoreConfirmTypeMapMsg {
+	if hash == nil {
+		hash = crypto.NewHashCode(nil)
+	}
+	return &CoreConfirmTypeMapMsg{
+		MsgHeader: MsgHeader{72, enums.MSG_CORE_CONFIRM_TYPE_MAP},
+		Hash:      hash,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreConfirmTypeMapMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreConfirmTypeMapMsg) String() string {
+	return fmt.Sprintf("CoreConfirmTypeMapMsg{hash=%s}", m.Hash.Short())
+}
+
+//----------------------------------------------------------------------
+// REQUEST_AGPL, RESPONSE_AGPL
+//----------------------------------------------------------------------
+
+// AGPLRequestMsg asks a service for the link to its source code.
+type AGPLRequestMsg struct {
+	MsgHeader
+}
+
+// NewAGPLRequestMsg creates a request for the source code link.
+func NewAGPLRequestMsg() *AGPLRequestMsg {
+	return &AGPLRequestMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_REQUEST_AGPL},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *AGPLRequestMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *AGPLRequestMsg) String() string {
+	return "AGPLRequestMsg{}"
+}
+
+// AGPLResponseMsg returns the (zero-terminated) link to the source code
+// of a service.
+type AGPLResponseMsg struct {
+	MsgHeader
+	URL []byte `size:"*"` // link to source code
+}
+
+// NewAGPLResponseMsg creates a response with the source code link.
+func NewAGPLResponseMsg(url string) *AGPLResponseMsg {
+	buf := append([]byte(url), 0)
+	return &AGPLResponseMsg{
+		MsgHeader: MsgHeader{uint16(4 + len(buf)), enums.MSG_RESPONSE_AGPL},
+		URL:       buf,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *AGPLResponseMsg) Init() error { return nil }
+
+// Link returns the source code link.
+func (m *AGPLResponseMsg) Link() string {
+	return string(bytes.TrimRight(m.URL, "\x00"))
+}
+
+// String returns a human-readable representation of the message.
+func (m *AGPLResponseMsg) String() string {
+	return fmt.Sprintf("AGPLResponseMsg{%s}", m.Link())
+}
+
+//----------------------------------------------------------------------
+// CORE_VERSION_QUERY, CORE_VERSION_REPLY (gnunet-go)
+//----------------------------------------------------------------------
+
+// VersionInfo describes the implementation running on a node.
+type VersionInfo struct {
+	Implementation string   `json:"implementation"` // name of implementation
+	Version        string   `json:"version"`        // version of implementation
+	Source         string   `json:"source"`         // link to source code (AGPL)
+	Subsystems     []string `json:"subsystems"`     // enabled subsystems
+}
+
+// CoreVersionQueryMsg asks a peer for its version information.
+type CoreVersionQueryMsg struct {
+	MsgHeader
+}
+
+// NewCoreVersionQueryMsg creates a version query.
+func NewCoreVersionQueryMsg() *CoreVersionQueryMsg {
+	return &CoreVersionQueryMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_CORE_VERSION_QUERY},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreVersionQueryMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *CoreVersionQueryMsg) String() string {
+	return "CoreVersionQueryMsg{}"
+}
+
+// CoreVersionReplyMsg carries the version information of a peer as a
+// list of zero-terminated strings: implementation, version, source link
+// and the names of enabled subsystems.
+type CoreVersionReplyMsg struct {
+	MsgHeader
+	Data []byte `size:"*"` // zero-terminated strings
+}
+
+// NewCoreVersionReplyMsg creates a reply for given version information.
+func NewCoreVersionReplyMsg(info *VersionInfo) *CoreVersionReplyMsg {
+	var buf []byte
+	if info != nil {
+		list := append([]string{info.Implementation, info.Version, info.Source}, info.Subsystems...)
+		for _, s := range list {
+			buf = append(append(buf, []byte(s)...), 0)
+		}
+	}
+	return &CoreVersionReplyMsg{
+		MsgHeader: MsgHeader{uint16(4 + len(buf)), enums.MSG_CORE_VERSION_REPLY},
+		Data:      buf,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *CoreVersionReplyMsg) Init() error { return nil }
+
+// Info returns the version information from the message.
+func (m *CoreVersionReplyMsg) Info() (*VersionInfo, error) {
+	if len(m.Data) == 0 || m.Data[len(m.Data)-1] != 0 {
+		return nil, errors.New("version reply not zero-terminated")
+	}
+	list := strings.Split(string(m.Data[:len(m.Data)-1]), "\x00")
+	if len(list) < 3 {
+		return nil, errors.New("version reply incomplete")
+	}
+	return &VersionInfo{
+		Implementation: list[0],
+		Version:        list[1],
+		Source:         list[2],
+		Subsystems:     list[3:],
+	}, nil
+}
+
+// String returns a human-readable representation of the message.
+func (m *CoreVersionReplyMsg) String() string {
+	info, err := m.Info()
+	if err != nil {
+		return "CoreVersionReplyMsg{invalid}"
+	}
+	return fmt.Sprintf("CoreVersionReplyMsg{%s %s,subsystems=%v}", info.Implementation, info.Version, info.Subsystems)
+}
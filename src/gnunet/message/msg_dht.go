@@ -23,6 +23,7 @@ import (
 
 	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/service/dht/path"
 	"gnunet/util"
 )
 
@@ -126,15 +127,17 @@ func (m *DHTClientGetMsg) Init() error { return nil }
 // DHTClientResultMsg is a message for DHT results
 type DHTClientResultMsg struct {
 	MsgHeader
-	BType      enums.BlockType   `order:"big"` // The type for the data
-	PutPathLen uint32            `order:"big"` // Number of peers recorded in outgoing path
-	GetPathLen uint32            `order:"big"` // Number of peers recorded from storage location
-	ID         uint64            `order:"big"` // Unique ID of the matching GET request
-	Expire     util.AbsoluteTime // Expiration time
-	Key        *crypto.HashCode  // The key that was searched for
-	PutPath    []*util.PeerID    `size:"PutPathLen"` // put path
-	GetPath    []*util.PeerID    `size:"GetPathLen"` // get path
-	Data       []byte            `size:"*"`          // data returned for query
+	BType       enums.BlockType   `order:"big"`       // The type for the data
+	PutPathLen  uint32            `order:"big"`       // Number of peers recorded in outgoing path
+	GetPathLen  uint32            `order:"big"`       // Number of peers recorded from storage location
+	Options     uint32            `order:"big"`       // Route options (DHT_RO_???)
+	ID          uint64            `order:"big"`       // Unique ID of the matching GET request
+	Expire      util.AbsoluteTime ``                  // Expiration time
+	Key         *crypto.HashCode  ``                  // The key that was searched for
+	TruncOrigin *util.PeerID      `opt:"(IsUsed)"`    // truncated origin (if TRUNCATED option set)
+	PutPath     []*path.Entry     `size:"PutPathLen"` // put path
+	GetPath     []*path.Entry     `size:"GetPathLen"` // get path
+	Data        []byte            `size:"*"`          // data returned for query
 }
 
 // NewDHTClientResultMsg creates a new default DHTClientResultMsg object.
@@ -143,17 +146,65 @@ func NewDHTClientResultMsg(key *crypto.HashCode) *DHTClientResultMsg {
 		key = crypto.NewHashCode(nil)
 	}
 	return &DHTClientResultMsg{
-		MsgHeader:  MsgHeader{64, enums.MSG_DHT_CLIENT_RESULT},
-		BType:      0,
-		PutPathLen: 0,
-		GetPathLen: 0,
-		ID:         0,
-		Expire:     *new(util.AbsoluteTime),
-		Key:        key,
-		Data:       make([]byte, 0),
+		MsgHeader:   MsgHeader{100, enums.MSG_DHT_CLIENT_RESULT},
+		BType:       0,
+		PutPathLen:  0,
+		GetPathLen:  0,
+		Options:     uint32(enums.DHT_RO_NONE),
+		ID:          0,
+		Expire:      *new(util.AbsoluteTime),
+		Key:         key,
+		TruncOrigin: nil,
+		PutPath:     make([]*path.Entry, 0),
+		GetPath:     make([]*path.Entry, 0),
+		Data:        make([]byte, 0),
 	}
 }
 
+// NewDHTClientResultFromP2P creates a result message for the client GET
+// request with given unique ID from a DHT-P2P-RESULT message.
+func NewDHTClientResultFromP2P(id uint64, res *DHTP2PResultMsg) *DHTClientResultMsg {
+	m := NewDHTClientResultMsg(res.Query)
+	m.ID = id
+	m.BType = res.BType
+	m.Options = uint32(res.Flags)
+	m.Expire = res.Expire
+	m.Data = util.Clone(res.Block)
+	m.MsgSize += uint16(len(m.Data))
+
+	// copy recorded route
+	if res.Flags&enums.DHT_RO_RECORD_ROUTE != 0 && len(res.PathList) > 0 {
+		pes := res.PathList[0].Size()
+		if res.Flags&enums.DHT_RO_TRUNCATED != 0 && res.TruncOrigin != nil {
+			m.TruncOrigin = res.TruncOrigin
+			m.MsgSize += uint16(m.TruncOrigin.Size())
+		} else {
+			m.Options &^= uint32(enums.DHT_RO_TRUNCATED)
+		}
+		split := int(res.PutPathL)
+		if split > len(res.PathList) {
+			split = len(res.PathList)
+		}
+		m.PutPath = util.Clone(res.PathList[:split])
+		m.GetPath = util.Clone(res.PathList[split:])
+		m.PutPathLen = uint32(len(m.PutPath))
+		m.GetPathLen = uint32(len(m.GetPath))
+		m.MsgSize += uint16(uint(len(res.PathList)) * pes)
+	} else {
+		m.Options &^= uint32(enums.DHT_RO_TRUNCATED)
+	}
+	return m
+}
+
+// IsUsed returns true if an optional field is used
+func (m *DHTClientResultMsg) IsUsed(field string) bool {
+	switch field {
+	case "TruncOrigin":
+		return m.Options&uint32(enums.DHT_RO_TRUNCATED) != 0
+	}
+	return false
+}
+
 // String returns a human-readable representation of the message.
 func (m *DHTClientResultMsg) String() string {
 	return fmt.Sprintf("DHTClientResultMsg{id:%d,type=%s,expire=%s}", m.ID, m.BType, m.Expire)
@@ -199,7 +250,8 @@ func (m *DHTClientGetStopMsg) Init() error { return nil }
 // DHT_CLIENT_GET_RESULTS_KNOWN
 //----------------------------------------------------------------------
 
-// DHTClientGetResultsKnownMsg is the message for putting values into the DHT
+// DHTClientGetResultsKnownMsg tells the service about results (hashes of
+// block data) already known to the client for a pending GET request.
 type DHTClientGetResultsKnownMsg struct {
 	MsgHeader
 	Reserved uint32             `order:"big"` // Reserved for further use
@@ -208,7 +260,7 @@ type DHTClientGetResultsKnownMsg struct {
 	Known    []*crypto.HashCode `size:"*"`    // list of known results
 }
 
-// NewDHTClientPutMsg creates a new default DHTClientPutMsg object.
+// NewDHTClientGetResultsKnownMsg creates a new default DHTClientGetResultsKnownMsg object.
 func NewDHTClientGetResultsKnownMsg(key *crypto.HashCode) *DHTClientGetResultsKnownMsg {
 	if key == nil {
 		key = new(crypto.HashCode)
@@ -235,3 +287,180 @@ func (m *DHTClientGetResultsKnownMsg) String() string {
 
 // Init called after unmarshalling a message to setup internal state
 func (m *DHTClientGetResultsKnownMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// DHT_CLIENT_GET_CREDIT (gnunet-go)
+//----------------------------------------------------------------------
+
+// DHTClientGetCreditMsg grants credits for results of a GET request: the
+// service sends one result per credit and queues further results until
+// the client grants more credits. Flow control is enabled for a request
+// with the first credit message; sent before the GET request, no results
+// are sent without credits.
+type DHTClientGetCreditMsg struct {
+	MsgHeader
+	Credits uint32 `order:"big"` // number of additional results accepted
+	ID      uint64 `order:"big"` // Unique ID identifying the GET request
+}
+
+// NewDHTClientGetCreditMsg creates a new credit message for a GET request.
+func NewDHTClientGetCreditMsg(id uint64, credits uint32) *DHTClientGetCreditMsg {
+	return &DHTClientGetCreditMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_DHT_CLIENT_GET_CREDIT},
+		Credits:   credits,
+		ID:        id,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *DHTClientGetCreditMsg) String() string {
+	return fmt.Sprintf("DHTClientGetCreditMsg{Id:%d,Credits=%d}", m.ID, m.Credits)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *DHTClientGetCreditMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// DHT_CLIENT_GET_LIMIT (gnunet-go)
+//----------------------------------------------------------------------
+
+// DHTClientGetLimitMsg limits the number of results for a GET request.
+// It must be sent before the GET request: the service collects results
+// for the duration of the timeout, sends the best 'MaxResults' of them
+// ordered by distance to the query key (and by expiration for equal
+// distance) and ends the request with a DHTClientGetDoneMsg.
+type DHTClientGetLimitMsg struct {
+	MsgHeader
+	MaxResults uint32            `order:"big"` // max. number of results
+	ID         uint64            `order:"big"` // Unique ID identifying the GET request
+	Timeout    util.RelativeTime ``            // collection window (0 = default)
+}
+
+// NewDHTClientGetLimitMsg creates a new limit message for a GET request.
+func NewDHTClientGetLimitMsg(id uint64, maxResults uint32, timeout util.RelativeTime) *DHTClientGetLimitMsg {
+	return &DHTClientGetLimitMsg{
+		MsgHeader:  MsgHeader{24, enums.MSG_DHT_CLIENT_GET_LIMIT},
+		MaxResults: maxResults,
+		ID:         id,
+		Timeout:    timeout,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *DHTClientGetLimitMsg) String() string {
+	return fmt.Sprintf("DHTClientGetLimitMsg{Id:%d,Max=%d,Timeout=%s}", m.ID, m.MaxResults, m.Timeout)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *DHTClientGetLimitMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// DHT_CLIENT_GET_DONE (gnunet-go)
+//----------------------------------------------------------------------
+
+// DHTClientGetDoneMsg is sent by the service after the last result of a
+// limited GET request; the request is finished and no further results
+// will be sent for it.
+type DHTClientGetDoneMsg struct {
+	MsgHeader
+	Count uint32 `order:"big"` // number of results sent for the request
+	ID    uint64 `order:"big"` // Unique ID identifying the GET request
+}
+
+// NewDHTClientGetDoneMsg creates a new completion message for a GET request.
+func NewDHTClientGetDoneMsg(id uint64, count uint32) *DHTClientGetDoneMsg {
+	return &DHTClientGetDoneMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_DHT_CLIENT_GET_DONE},
+		Count:     count,
+		ID:        id,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *DHTClientGetDoneMsg) String() string {
+	return fmt.Sprintf("DHTClientGetDoneMsg{Id:%d,Count=%d}", m.ID, m.Count)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *DHTClientGetDoneMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// DHT_CLIENT_PUT_VERIFY (gnunet-go)
+//----------------------------------------------------------------------
+
+// DHTClientPutVerifyMsg asks for the confirmation of a PUT request. It
+// must be sent before the PUT request for the key: after storing the
+// block, the service checks with sample GETs through different neighbors
+// if the block can be retrieved (repeating the PUT through other
+// neighbors if not) and reports the outcome in a DHTClientPutConfirmMsg.
+type DHTClientPutVerifyMsg struct {
+	MsgHeader
+	Reserved uint32           `order:"big"` // Reserved for further use
+	Key      *crypto.HashCode ``            // key of the upcoming PUT request
+}
+
+// NewDHTClientPutVerifyMsg creates a new verification request for a key.
+func NewDHTClientPutVerifyMsg(key *crypto.HashCode) *DHTClientPutVerifyMsg {
+	if key == nil {
+		key = new(crypto.HashCode)
+	}
+	return &DHTClientPutVerifyMsg{
+		MsgHeader: MsgHeader{72, enums.MSG_DHT_CLIENT_PUT_VERIFY},
+		Key:       key,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *DHTClientPutVerifyMsg) String() string {
+	return fmt.Sprintf("DHTClientPutVerifyMsg{Key=%s}", m.Key.Short())
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *DHTClientPutVerifyMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// DHT_CLIENT_PUT_CONFIRM (gnunet-go)
+//----------------------------------------------------------------------
+
+// DHTClientPutConfirmMsg reports the confirmation of a PUT request: the
+// number of sample GETs (in the last round), the number of samples that
+// returned the block and the number of repeated PUTs.
+type DHTClientPutConfirmMsg struct {
+	MsgHeader
+	Samples   uint16           `order:"big"` // number of sample GETs
+	Confirmed uint16           `order:"big"` // number of samples returning the block
+	Retries   uint16           `order:"big"` // number of repeated PUTs
+	Reserved  uint16           `order:"big"` // Reserved for further use
+	Key       *crypto.HashCode ``            // key of the PUT request
+}
+
+// NewDHTClientPutConfirmMsg creates a new confirmation for a PUT request.
+func NewDHTClientPutConfirmMsg(key *crypto.HashCode, samples, confirmed, retries uint16) *DHTClientPutConfirmMsg {
+	if key == nil {
+		key = new(crypto.HashCode)
+	}
+	return &DHTClientPutConfirmMsg{
+		MsgHeader: MsgHeader{76, enums.MSG_DHT_CLIENT_PUT_CONFIRM},
+		Samples:   samples,
+		Confirmed: confirmed,
+		Retries:   retries,
+		Key:       key,
+	}
+}
+
+// Confidence returns the share of samples that returned the block.
+func (m *DHTClientPutConfirmMsg) Confidence() float64 {
+	if m.Samples == 0 {
+		return 0
+	}
+	return float64(m.Confirmed) / float64(m.Samples)
+}
+
+// String returns a human-readable representation of the message.
+func (m *DHTClientPutConfirmMsg) String() string {
+	return fmt.Sprintf("DHTClientPutConfirmMsg{Key=%s,Samples=%d,Confirmed=%d,Retries=%d}",
+		m.Key.Short(), m.Samples, m.Confirmed, m.Retries)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *DHTClientPutConfirmMsg) Init() error { return nil }
@@ -24,7 +24,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"gnunet/config"
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/service/dht/blocks"
@@ -148,7 +147,7 @@ func NewDHTP2PPutMsg(block blocks.Block) *DHTP2PPutMsg {
 		msg.BType = block.Type()
 		msg.HopCount = 0
 		msg.PeerFilter = blocks.NewPeerFilter()
-		msg.ReplLvl = uint16(config.Cfg.GNS.ReplLevel)
+		msg.ReplLvl = DefaultReplLevel
 		msg.Expire = block.Expire()
 		msg.Block = block.Bytes()
 		msg.TruncOrigin = nil
@@ -180,16 +179,16 @@ func (m *DHTP2PPutMsg) Init() (err error) {
 // Update message (forwarding)
 func (m *DHTP2PPutMsg) Update(p *path.Path, pf *blocks.PeerFilter, hop uint16) *DHTP2PPutMsg {
 	msg := NewDHTP2PPutMsg(nil)
+	msg.BType = m.BType
 	msg.Flags = m.Flags
 	msg.HopCount = hop
-	msg.PathL = p.NumList
+	msg.ReplLvl = m.ReplLvl
 	msg.Expire = m.Expire
 	msg.PeerFilter = pf
 	msg.Key = m.Key.Clone()
-	msg.TruncOrigin = p.TruncOrigin
-	msg.PutPath = util.Clone(p.List)
-	msg.LastSig = p.LastSig
 	msg.Block = util.Clone(m.Block)
+	msg.MsgSize += uint16(len(msg.Block))
+	// path fields are set (and accounted for in the size) by SetPath
 	msg.SetPath(p)
 	return msg
 }
@@ -210,7 +209,7 @@ func (m *DHTP2PPutMsg) Path(sender *util.PeerID) *path.Path {
 	}
 
 	// handle truncate origin
-	if pth.Flags&enums.DHT_RO_TRUNCATED == 1 {
+	if pth.Flags&enums.DHT_RO_TRUNCATED != 0 {
 		if m.TruncOrigin == nil {
 			logger.Printf(logger.WARN, "[path] truncated but no origin - flag reset")
 			pth.Flags &^= enums.DHT_RO_TRUNCATED
@@ -223,9 +222,12 @@ func (m *DHTP2PPutMsg) Path(sender *util.PeerID) *path.Path {
 	pth.List = util.Clone(m.PutPath)
 	pth.NumList = uint16(len(pth.List))
 
-	// handle last hop signature
+	// handle last hop signature (only missing for messages originating
+	// from the local peer)
 	if m.LastSig == nil {
-		logger.Printf(logger.WARN, "[path]  - last hop signature missing - path reset")
+		if sender != nil {
+			logger.Printf(logger.WARN, "[path]  - last hop signature missing - path reset")
+		}
 		return path.NewPath(crypto.Hash(m.Block), m.Expire)
 	}
 	pth.LastSig = m.LastSig
@@ -358,8 +360,8 @@ func (m *DHTP2PResultMsg) Path(sender *util.PeerID) *path.Path {
 		return pth
 	}
 	// handle truncate origin
-	if pth.Flags&enums.DHT_RO_TRUNCATED == 1 {
-		if pth.TruncOrigin == nil {
+	if pth.Flags&enums.DHT_RO_TRUNCATED != 0 {
+		if m.TruncOrigin == nil {
 			logger.Printf(logger.WARN, "[path] truncated but no origin - flag reset")
 			pth.Flags &^= enums.DHT_RO_TRUNCATED
 		} else {
@@ -382,9 +384,12 @@ func (m *DHTP2PResultMsg) Path(sender *util.PeerID) *path.Path {
 	} else {
 		pth.SplitPos = pth.NumList - m.PutPathL
 	}
-	// handle last hop signature
+	// handle last hop signature (only missing for messages originating
+	// from the local peer)
 	if m.LastSig == nil {
-		logger.Printf(logger.WARN, "[path]  - last hop signature missing - path reset")
+		if sender != nil {
+			logger.Printf(logger.WARN, "[path]  - last hop signature missing - path reset")
+		}
 		return path.NewPath(crypto.Hash(m.Block), m.Expire)
 	}
 	pth.LastSig = m.LastSig
@@ -20,6 +20,7 @@ package message
 
 import (
 	"fmt"
+	"time"
 
 	"gnunet/crypto"
 	"gnunet/enums"
@@ -47,7 +48,7 @@ type LookupMsg struct {
 // NewGNSLookupMsg creates a new default message.
 func NewGNSLookupMsg() *LookupMsg {
 	return &LookupMsg{
-		MsgHeader: MsgHeader{48, enums.MSG_GNS_LOOKUP},
+		MsgHeader: MsgHeader{52, enums.MSG_GNS_LOOKUP},
 		ID:        0,
 		Zone:      nil,
 		Options:   uint16(enums.GNS_LO_DEFAULT),
@@ -60,12 +61,15 @@ func NewGNSLookupMsg() *LookupMsg {
 // SetName appends the name to lookup to the message
 func (m *LookupMsg) SetName(name string) {
 	m.Name = util.Clone(append([]byte(name), 0))
-	m.MsgSize = uint16(48 + len(m.Name))
+	m.MsgSize = uint16(52 + len(m.Name))
 }
 
 // GetName returns the name to lookup from the message
 func (m *LookupMsg) GetName() string {
 	size := len(m.Name)
+	if size == 0 {
+		return ""
+	}
 	if m.Name[size-1] != 0 {
 		logger.Println(logger.WARN, "GNS_LOOKUP name not NULL-terminated")
 	} else {
@@ -106,11 +110,13 @@ func NewGNSLookupResultMsg(id uint32) *LookupResultMsg {
 	}
 }
 
-// AddRecord adds a GNS resource recordto the response message.
+// AddRecord adds a GNS resource recordto the response message. The limit
+// is the message size (not the block size), so the records of a
+// maximum-size GNS block fit unless it holds very many small records.
 func (m *LookupResultMsg) AddRecord(rec *blocks.ResourceRecord) error {
-	recSize := 20 + int(rec.Size)
-	if int(m.MsgSize)+recSize > enums.GNS_MAX_BLOCK_SIZE {
-		return fmt.Errorf("gns.AddRecord(): MAX_BLOCK_SIZE reached")
+	recSize := 16 + int(rec.Size)
+	if int(m.MsgSize)+recSize > MaxSize {
+		return fmt.Errorf("gns.AddRecord(): maximum message size reached")
 	}
 	m.Records = append(m.Records, rec)
 	m.MsgSize += uint16(recSize)
@@ -130,3 +136,174 @@ func (m *LookupResultMsg) Header() *MsgHeader {
 
 // Init called after unmarshalling a message to setup internal state
 func (m *LookupResultMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// GNS_LOOKUP_TRACE (gnunet-go extension)
+//----------------------------------------------------------------------
+
+// GNSTraceStep is a single step in the resolution of a name.
+type GNSTraceStep struct {
+	Kind     uint16           `order:"big"`     // kind of step (GNS_TRACE_*)
+	ZoneLen  uint16           `order:"big"`     // length of zone ID
+	LabelLen uint16           `order:"big"`     // length of label
+	Reserved uint16           `order:"big"`     // always 0
+	Elapsed  uint64           `order:"big"`     // start of step since start of lookup (in µs)
+	Duration uint64           `order:"big"`     // duration of step (in µs)
+	Query    *crypto.HashCode ``                // DHT query key (zero for DNS)
+	Zone     []byte           `size:"ZoneLen"`  // zone ID
+	Label    []byte           `size:"LabelLen"` // label (or DNS name)
+}
+
+// NewGNSTraceStep creates a new trace step.
+func NewGNSTraceStep(kind int, zone, label string, query *crypto.HashCode, elapsed, duration time.Duration) *GNSTraceStep {
+	if query == nil {
+		query = crypto.NewHashCode(nil)
+	}
+	return &GNSTraceStep{
+		Kind:     uint16(kind),
+		ZoneLen:  uint16(len(zone)),
+		LabelLen: uint16(len(label)),
+		Elapsed:  uint64(elapsed.Microseconds()),
+		Duration: uint64(duration.Microseconds()),
+		Query:    query,
+		Zone:     []byte(zone),
+		Label:    []byte(label),
+	}
+}
+
+// Size of the binary representation of a trace step
+func (s *GNSTraceStep) Size() uint16 {
+	return 88 + s.ZoneLen + s.LabelLen
+}
+
+// KindString returns a human-readable name of the step kind.
+func (s *GNSTraceStep) KindString() string {
+	switch s.Kind {
+	case enums.GNS_TRACE_LOCAL:
+		return "local"
+	case enums.GNS_TRACE_NEGCACHE:
+		return "negcache"
+	case enums.GNS_TRACE_DHT:
+		return "dht"
+	case enums.GNS_TRACE_NOTFOUND:
+		return "notfound"
+	case enums.GNS_TRACE_FAILED:
+		return "failed"
+	case enums.GNS_TRACE_DNS:
+		return "dns"
+	}
+	return fmt.Sprintf("kind(%d)", s.Kind)
+}
+
+// String returns a human-readable representation of the step.
+func (s *GNSTraceStep) String() string {
+	elapsed := time.Duration(s.Elapsed) * time.Microsecond
+	duration := time.Duration(s.Duration) * time.Microsecond
+	if s.Kind == enums.GNS_TRACE_DNS {
+		return fmt.Sprintf("+%s %-8s %s (%s)", elapsed, s.KindString(), s.Label, duration)
+	}
+	return fmt.Sprintf("+%s %-8s %s in %s [%s] (%s)",
+		elapsed, s.KindString(), s.Label, s.Zone, s.Query.Short(), duration)
+}
+
+// LookupTraceMsg carries the resolution steps of a GNS lookup that
+// requested tracing (GNS_LO_TRACE). It is sent before the corresponding
+// LookupResultMsg.
+type LookupTraceMsg struct {
+	MsgHeader
+	ID    uint32          `order:"big"`  // Identifier of lookup request
+	Count uint32          `order:"big"`  // Number of trace steps
+	Steps []*GNSTraceStep `size:"Count"` // resolution steps
+}
+
+// NewGNSLookupTraceMsg returns a new (empty) lookup trace message
+func NewGNSLookupTraceMsg(id uint32) *LookupTraceMsg {
+	return &LookupTraceMsg{
+		MsgHeader: MsgHeader{12, enums.MSG_GNS_LOOKUP_TRACE},
+		ID:        id,
+		Count:     0,
+		Steps:     make([]*GNSTraceStep, 0),
+	}
+}
+
+// AddStep appends a trace step to the message. Returns false if the
+// message size limit is reached.
+func (m *LookupTraceMsg) AddStep(s *GNSTraceStep) bool {
+	size := int(s.Size())
+	if int(m.MsgSize)+size > enums.GNS_MAX_BLOCK_SIZE {
+		return false
+	}
+	m.Steps = append(m.Steps, s)
+	m.MsgSize += uint16(size)
+	m.Count++
+	return true
+}
+
+// String returns a human-readable representation of the message.
+func (m *LookupTraceMsg) String() string {
+	return fmt.Sprintf("GNSLookupTraceMsg{Id=%d,Count=%d}", m.ID, m.Count)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *LookupTraceMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// GNS_LOOKUP_PROVENANCE (gnunet-go extension)
+//----------------------------------------------------------------------
+
+// LookupProvenanceMsg carries the provenance and freshness of a GNS
+// lookup result for lookups that requested it (GNS_LO_PROVENANCE). It
+// is sent before the corresponding LookupResultMsg.
+type LookupProvenanceMsg struct {
+	MsgHeader
+	ID       uint32            `order:"big"` // Identifier of lookup request
+	Source   uint16            `order:"big"` // source of the result (GNS_SOURCE_*)
+	Flags    uint16            `order:"big"` // provenance flags (GNS_PROV_*)
+	Expire   util.AbsoluteTime ``            // earliest expiration of the blocks used
+	Verified util.AbsoluteTime ``            // signature validation of the answering block
+}
+
+// NewGNSLookupProvenanceMsg returns a new lookup provenance message
+func NewGNSLookupProvenanceMsg(id uint32) *LookupProvenanceMsg {
+	return &LookupProvenanceMsg{
+		MsgHeader: MsgHeader{28, enums.MSG_GNS_LOOKUP_PROVENANCE},
+		ID:        id,
+		Source:    enums.GNS_SOURCE_NONE,
+		Flags:     0,
+		Expire:    util.AbsoluteTimeNever(),
+		Verified:  util.NewAbsoluteTimeEpoch(0),
+	}
+}
+
+// SourceString returns a human-readable name of the result source.
+func (m *LookupProvenanceMsg) SourceString() string {
+	switch m.Source {
+	case enums.GNS_SOURCE_NONE:
+		return "none"
+	case enums.GNS_SOURCE_ZONE:
+		return "zone"
+	case enums.GNS_SOURCE_CACHE:
+		return "cache"
+	case enums.GNS_SOURCE_NAMECACHE:
+		return "namecache"
+	case enums.GNS_SOURCE_DHT:
+		return "dht"
+	case enums.GNS_SOURCE_DNS:
+		return "dns"
+	}
+	return fmt.Sprintf("source(%d)", m.Source)
+}
+
+// Shadowed returns true if shadow records were used in the resolution.
+func (m *LookupProvenanceMsg) Shadowed() bool {
+	return m.Flags&enums.GNS_PROV_SHADOW != 0
+}
+
+// String returns a human-readable representation of the message.
+func (m *LookupProvenanceMsg) String() string {
+	return fmt.Sprintf("GNSLookupProvenanceMsg{Id=%d,Source=%s,Flags=%d,Expire=%s,Verified=%s}",
+		m.ID, m.SourceString(), m.Flags, m.Expire, m.Verified)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *LookupProvenanceMsg) Init() error { return nil }
@@ -0,0 +1,73 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// The size in the header of GNS lookup messages must match the size
+// of the marshalled message.
+func TestGNSLookupMsgSize(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := NewGNSLookupMsg()
+	msg.Zone = zp.Public()
+	msg.SetName("www.gnunet.org")
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.MsgSize) {
+		t.Fatalf("size mismatch (%d != %d)", len(buf), msg.MsgSize)
+	}
+}
+
+// The size in the header of GNS lookup results must match the size
+// of the marshalled message.
+func TestGNSLookupResultMsgSize(t *testing.T) {
+	msg := NewGNSLookupResultMsg(23)
+	for _, size := range []int{0, 4, 100} {
+		rec := &blocks.ResourceRecord{
+			Expire: util.AbsoluteTimeNever(),
+			Size:   uint16(size),
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   make([]byte, size),
+		}
+		if err := msg.AddRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.MsgSize) {
+		t.Fatalf("size mismatch (%d != %d)", len(buf), msg.MsgSize)
+	}
+}
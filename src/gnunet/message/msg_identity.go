@@ -193,11 +193,13 @@ func NewIdentityCreateMsg(zk *crypto.ZonePrivate, name string) *IdentityCreateMs
 			MsgSize: size + 8,
 			MsgType: enums.MSG_IDENTITY_CREATE,
 		},
+		KeyLen:  size,
 		ZoneKey: zk,
 	}
 	if len(name) > 0 {
 		msg.Name_ = util.WriteCString(name)
-		msg.MsgSize += uint16(len(msg.Name_))
+		msg.NameLen = uint16(len(msg.Name_))
+		msg.MsgSize += msg.NameLen
 		msg.name = name
 	}
 	return msg
@@ -250,12 +252,14 @@ func NewIdentityRenameMsg(oldName, newName string) *IdentityRenameMsg {
 	}
 	if len(oldName) > 0 {
 		msg.OldName_ = util.WriteCString(oldName)
-		msg.MsgSize += uint16(len(msg.OldName_))
+		msg.OldNameLen = uint16(len(msg.OldName_))
+		msg.MsgSize += msg.OldNameLen
 		msg.oldName = oldName
 	}
 	if len(newName) > 0 {
 		msg.NewName_ = util.WriteCString(newName)
-		msg.MsgSize += uint16(len(msg.NewName_))
+		msg.NewNameLen = uint16(len(msg.NewName_))
+		msg.MsgSize += msg.NewNameLen
 		msg.newName = newName
 	}
 	return msg
@@ -310,7 +314,8 @@ func NewIdentityDeleteMsg(name string) *IdentityDeleteMsg {
 	}
 	if len(name) > 0 {
 		msg.Name_ = util.WriteCString(name)
-		msg.MsgSize += uint16(len(msg.Name_))
+		msg.NameLen = uint16(len(msg.Name_))
+		msg.MsgSize += msg.NameLen
 		msg.name = name
 	}
 	return msg
@@ -344,12 +349,12 @@ func (msg *IdentityLookupMsg) Init() error {
 	return nil
 }
 
-// NewIdentityLookupMsg renames an identity
+// NewIdentityLookupMsg looks up an identity by name
 func NewIdentityLookupMsg(name string) *IdentityLookupMsg {
 	return &IdentityLookupMsg{
 		MsgHeader: MsgHeader{
-			MsgSize: uint16(len(name) + 9),
-			MsgType: enums.MSG_IDENTITY_DELETE,
+			MsgSize: uint16(len(name) + 5),
+			MsgType: enums.MSG_IDENTITY_LOOKUP,
 		},
 		Name: name,
 	}
@@ -19,6 +19,7 @@
 package message
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"gnunet/crypto"
@@ -92,16 +93,29 @@ type NamecacheLookupResultMsg struct {
 // Init called after unmarshalling a message to setup internal state
 func (m *NamecacheLookupResultMsg) Init() error { return nil }
 
-// NewNamecacheLookupResultMsg creates a new default message.
+// NewNamecacheLookupResultMsg creates a new default message (without a
+// block: the signature is empty).
 func NewNamecacheLookupResultMsg() *NamecacheLookupResultMsg {
+	// empty signature (EDKEY)
+	buf := make([]byte, 100)
+	binary.BigEndian.PutUint32(buf, uint32(enums.GNS_TYPE_EDKEY))
+	sig, _ := crypto.NewZoneSignature(buf)
 	return &NamecacheLookupResultMsg{
-		GenericNamecacheMsg: newGenericNamecacheMsg(112, enums.MSG_NAMECACHE_LOOKUP_BLOCK_RESPONSE),
+		GenericNamecacheMsg: newGenericNamecacheMsg(116, enums.MSG_NAMECACHE_LOOKUP_BLOCK_RESPONSE),
 		Expire:              util.AbsoluteTimeNever(),
-		DerivedKeySig:       nil,
+		DerivedKeySig:       sig,
 		EncData:             nil,
 	}
 }
 
+// SetBlock sets the block in a lookup response.
+func (m *NamecacheLookupResultMsg) SetBlock(blk *blocks.GNSBlock) {
+	m.Expire = blk.Body.Expire
+	m.DerivedKeySig = blk.DerivedKeySig
+	m.EncData = util.Clone(blk.Body.Data)
+	m.MsgSize = uint16(20 + blk.DerivedKeySig.KeySize() + blk.DerivedKeySig.SigSize() + uint(len(m.EncData)))
+}
+
 // String returns a human-readable representation of the message.
 func (m *NamecacheLookupResultMsg) String() string {
 	return fmt.Sprintf("NamecacheLookupResultMsg{id=%d,expire=%s}",
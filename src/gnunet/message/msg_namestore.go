@@ -24,8 +24,6 @@ import (
 	"gnunet/enums"
 	"gnunet/service/dht/blocks"
 	"gnunet/util"
-
-	"github.com/bfix/gospel/data"
 )
 
 //======================================================================
@@ -61,7 +59,7 @@ type NamestoreZoneIterStartMsg struct {
 
 // NewNamecacheCacheMsg creates a new default message.
 func NewNamestoreZoneIterStartMsg(id uint32, filter int, zone *crypto.ZonePrivate) *NamestoreZoneIterStartMsg {
-	var size uint16 = 16
+	var size uint16 = 12
 	var kl uint16 = 0
 	if zone != nil {
 		kl = uint16(zone.KeySize()) + 4
@@ -202,8 +200,9 @@ func NewNamestoreRecordResultMsg(id uint32, zk *crypto.ZonePrivate, label string
 // Init called after unmarshalling a message to setup internal state
 func (m *NamestoreRecordResultMsg) Init() error {
 	if m.recset == nil {
-		m.recset = new(blocks.RecordSet)
-		return data.Unmarshal(m.recset, m.Records)
+		var err error
+		m.recset, err = blocks.NewRecordSetFromRDATA(uint32(m.RdCount), m.Records)
+		return err
 	}
 	return nil
 }
@@ -243,14 +242,25 @@ func (m *NamestoreRecordResultMsg) String() string {
 // MSG_NAMESTORE_RECORD_STORE
 //----------------------------------------------------------------------
 
+// LabelVersion maps the version of a label record set to the 16-bit
+// version field in namestore messages (the field was "reserved" in the
+// original protocol). 0 is never used for a version: it is sent by
+// clients not aware of label versions.
+func LabelVersion(v int64) uint16 {
+	if v < 1 {
+		return 0
+	}
+	return uint16((v-1)%0xffff + 1)
+}
+
 // NamestoreRecordSet for a label
 type NamestoreRecordSet struct {
-	NameLen  uint16 `order:"big"`    // Length of label
-	RdLen    uint16 `order:"big"`    // length of record data
-	RdCount  uint16 `order:"big"`    // number of records
-	Reserved uint16 `order:"big"`    // reserved
-	Name     []byte `size:"NameLen"` // label name
-	RecData  []byte `size:"RdLen"`   // record data
+	NameLen uint16 `order:"big"`    // Length of label
+	RdLen   uint16 `order:"big"`    // length of record data
+	RdCount uint16 `order:"big"`    // number of records
+	Version uint16 `order:"big"`    // expected label version (0 = any)
+	Name    []byte `size:"NameLen"` // label name
+	RecData []byte `size:"RdLen"`   // record data
 }
 
 // NewNamestoreRecordSet for label and resource records.
@@ -288,7 +298,7 @@ func NewNamestoreRecordStoreMsg(id uint32, zk *crypto.ZonePrivate) *NamestoreRec
 	if zk != nil {
 		kl = uint16(zk.KeySize() + 4)
 	}
-	size := kl + 14
+	size := kl + 12
 	return &NamestoreRecordStoreMsg{
 		GenericNamestoreMsg: newGenericNamestoreMsg(id, size, enums.MSG_NAMESTORE_RECORD_STORE),
 		ZoneKey:             zk,
@@ -302,11 +312,14 @@ func (m *NamestoreRecordStoreMsg) Init() error {
 	return nil
 }
 
-// AddRecords adds the record data to the message
-func (m *NamestoreRecordStoreMsg) AddRecordSet(label string, rr *blocks.RecordSet) {
+// AddRecordSet adds a labeled record set to the message. The expected
+// label version (see LabelVersion) can be set in the returned record set.
+func (m *NamestoreRecordStoreMsg) AddRecordSet(label string, rr *blocks.RecordSet) *NamestoreRecordSet {
 	rs, size := NewNamestoreRecordSet(label, rr)
 	m.RSets = append(m.RSets, rs)
+	m.Count++
 	m.MsgSize += size
+	return rs
 }
 
 // String returns a human-readable representation of the message.
@@ -315,6 +328,65 @@ func (m *NamestoreRecordStoreMsg) String() string {
 		m.ID, m.ZoneKey.ID(), m.Count)
 }
 
+//----------------------------------------------------------------------
+// MSG_NAMESTORE_RECORD_STORE_KEYED (gnunet-go)
+//----------------------------------------------------------------------
+
+// NamestoreRecordStoreKeyedMsg is a store request with an idempotency
+// key: if a client repeats the request (e.g. after a timeout), the
+// service detects the known key and returns the original result without
+// storing the records again. The response is a RECORD_STORE_RESPONSE.
+type NamestoreRecordStoreKeyedMsg struct {
+	GenericNamestoreMsg
+
+	Key     *crypto.HashCode      ``             // idempotency key
+	Count   uint16                `order:"big"`  // number of RecordSets
+	KeyLen  uint16                `order:"big"`  // length of zone key
+	ZoneKey *crypto.ZonePrivate   `init:"Init"`  // private zone key
+	RSets   []*NamestoreRecordSet `size:"Count"` // list of label record sets
+}
+
+// NewNamestoreRecordStoreKeyedMsg creates an initialized message (without
+// records) for a given idempotency key.
+func NewNamestoreRecordStoreKeyedMsg(id uint32, key *crypto.HashCode, zk *crypto.ZonePrivate) *NamestoreRecordStoreKeyedMsg {
+	if key == nil {
+		key = crypto.NewHashCode(nil)
+	}
+	var kl uint16
+	if zk != nil {
+		kl = uint16(zk.KeySize() + 4)
+	}
+	size := kl + 12 + uint16(key.Size())
+	return &NamestoreRecordStoreKeyedMsg{
+		GenericNamestoreMsg: newGenericNamestoreMsg(id, size, enums.MSG_NAMESTORE_RECORD_STORE_KEYED),
+		Key:                 key,
+		ZoneKey:             zk,
+		Count:               0,
+		KeyLen:              kl,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *NamestoreRecordStoreKeyedMsg) Init() error {
+	return nil
+}
+
+// AddRecordSet adds a labeled record set to the message. The expected
+// label version (see LabelVersion) can be set in the returned record set.
+func (m *NamestoreRecordStoreKeyedMsg) AddRecordSet(label string, rr *blocks.RecordSet) *NamestoreRecordSet {
+	rs, size := NewNamestoreRecordSet(label, rr)
+	m.RSets = append(m.RSets, rs)
+	m.Count++
+	m.MsgSize += size
+	return rs
+}
+
+// String returns a human-readable representation of the message.
+func (m *NamestoreRecordStoreKeyedMsg) String() string {
+	return fmt.Sprintf("NamestoreRecordStoreKeyedMsg{id=%d,key=%s,zone=%s,%d record sets}",
+		m.ID, m.Key.Short(), m.ZoneKey.ID(), m.Count)
+}
+
 //----------------------------------------------------------------------
 // MSG_NAMESTORE_RECORD_STORE_RESP
 //----------------------------------------------------------------------
@@ -396,15 +468,15 @@ func (m *NamestoreRecordLookupMsg) String() string {
 type NamestoreRecordLookupRespMsg struct {
 	GenericNamestoreMsg
 
-	LblLen   uint16              `order:"big"`   // Length of label
-	RdLen    uint16              `order:"big"`   // size of record data
-	RdCount  uint16              `order:"big"`   // number of records
-	Found    int16               `order:"big"`   // label found?
-	Reserved uint16              `order:"big"`   // reserved
-	KeyLen   uint16              `order:"big"`   // length of key
-	ZoneKey  *crypto.ZonePrivate `init:"Init"`   // private zone key
-	Label    []byte              `size:"LblLen"` // label string
-	Records  []byte              `size:"RdLen"`  // serialized record data
+	LblLen  uint16              `order:"big"`   // Length of label
+	RdLen   uint16              `order:"big"`   // size of record data
+	RdCount uint16              `order:"big"`   // number of records
+	Found   int16               `order:"big"`   // label found?
+	Version uint16              `order:"big"`   // label version (0 = unknown)
+	KeyLen  uint16              `order:"big"`   // length of key
+	ZoneKey *crypto.ZonePrivate `init:"Init"`   // private zone key
+	Label   []byte              `size:"LblLen"` // label string
+	Records []byte              `size:"RdLen"`  // serialized record data
 
 	// transient state
 	recset *blocks.RecordSet
@@ -431,8 +503,9 @@ func NewNamestoreRecordLookupRespMsg(id uint32, zk *crypto.ZonePrivate, label st
 // Init called after unmarshalling a message to setup internal state
 func (m *NamestoreRecordLookupRespMsg) Init() error {
 	if m.recset == nil {
-		m.recset = new(blocks.RecordSet)
-		return data.Unmarshal(m.recset, m.Records)
+		var err error
+		m.recset, err = blocks.NewRecordSetFromRDATA(uint32(m.RdCount), m.Records)
+		return err
 	}
 	return nil
 }
@@ -550,8 +623,9 @@ func NewNamestoreZoneToNameRespMsg(id uint32, zk *crypto.ZonePrivate, label stri
 // Init called after unmarshalling a message to setup internal state
 func (m *NamestoreZoneToNameRespMsg) Init() error {
 	if m.recset == nil {
-		m.recset = new(blocks.RecordSet)
-		return data.Unmarshal(m.recset, m.Records)
+		var err error
+		m.recset, err = blocks.NewRecordSetFromRDATA(uint32(m.RdCount), m.Records)
+		return err
 	}
 	return nil
 }
@@ -583,6 +657,13 @@ func (m *NamestoreZoneToNameRespMsg) String() string {
 // MSG_NAMESTORE_TX_CONTROL
 //----------------------------------------------------------------------
 
+// Tx control codes
+const (
+	NamestoreTxBegin    = 0 // begin transaction
+	NamestoreTxCommit   = 1 // commit transaction
+	NamestoreTxRollback = 2 // roll back transaction
+)
+
 // NamestoreTxControlMsg to initiate a Tx control
 type NamestoreTxControlMsg struct {
 	GenericNamestoreMsg
@@ -0,0 +1,176 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"fmt"
+	"math"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// NSE_START
+//----------------------------------------------------------------------
+
+// NSEStartMsg is sent by a client to start receiving network size
+// estimates from the service.
+type NSEStartMsg struct {
+	MsgHeader
+}
+
+// NewNSEStartMsg creates a new start message.
+func NewNSEStartMsg() *NSEStartMsg {
+	return &NSEStartMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_NSE_START},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *NSEStartMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *NSEStartMsg) String() string {
+	return "NSEStartMsg{}"
+}
+
+//----------------------------------------------------------------------
+// NSE_P2P_FLOOD
+//----------------------------------------------------------------------
+
+// NSEFloodBlock is the signed part of a flood message. The proof-of-work
+// is kept in host byte order (little-endian) like in the C implementation.
+type NSEFloodBlock struct {
+	Purpose      *crypto.SignaturePurpose // signature purpose: SIG_NSE_SEND
+	Timestamp    util.AbsoluteTime        // start of the round
+	MatchingBits uint32                   `order:"big"` // matching bits of peer and round
+	Origin       *util.PeerID             // originating peer
+	PoW          uint64                   // proof-of-work for origin
+}
+
+// NSEFloodMsg is flooded through the network once per round by the
+// peers closest to the (hashed) round timestamp.
+type NSEFloodMsg struct {
+	MsgHeader
+	HopCount    uint32              `order:"big"` // number of hops so far
+	SignedBlock *NSEFloodBlock      // signed data
+	Signature   *util.PeerSignature // signature of the origin
+}
+
+// NewNSEFloodMsg creates a new (unsigned) flood message for a round.
+func NewNSEFloodMsg(ts util.AbsoluteTime, bits uint32, origin *util.PeerID, pow uint64) *NSEFloodMsg {
+	if origin == nil {
+		origin = util.NewPeerID(nil)
+	}
+	return &NSEFloodMsg{
+		MsgHeader: MsgHeader{132, enums.MSG_NSE_P2P_FLOOD},
+		HopCount:  0,
+		SignedBlock: &NSEFloodBlock{
+			Purpose: &crypto.SignaturePurpose{
+				Size:    60,
+				Purpose: enums.SIG_NSE_SEND,
+			},
+			Timestamp:    ts,
+			MatchingBits: bits,
+			Origin:       origin,
+			PoW:          pow,
+		},
+		Signature: util.NewPeerSignature(nil),
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *NSEFloodMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *NSEFloodMsg) String() string {
+	return fmt.Sprintf("NSEFloodMsg{origin=%s,round=%s,bits=%d,hops=%d}",
+		m.SignedBlock.Origin.Short(), m.SignedBlock.Timestamp,
+		m.SignedBlock.MatchingBits, m.HopCount)
+}
+
+// SignedData returns the data block to be signed by the origin.
+func (m *NSEFloodMsg) SignedData() []byte {
+	buf, err := data.Marshal(m.SignedBlock)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[NSEFloodMsg.SignedData] failed: %s", err.Error())
+	}
+	return buf
+}
+
+// SetSignature stores the signature of the origin.
+func (m *NSEFloodMsg) SetSignature(sig *util.PeerSignature) error {
+	m.Signature = sig
+	return nil
+}
+
+// Verify the signature of the flood message with the origin key.
+func (m *NSEFloodMsg) Verify() (bool, error) {
+	pub := ed25519.NewPublicKeyFromBytes(m.SignedBlock.Origin.Data)
+	sig, err := ed25519.NewEdSignatureFromBytes(m.Signature.Data)
+	if err != nil {
+		return false, err
+	}
+	return pub.EdVerify(m.SignedData(), sig)
+}
+
+//----------------------------------------------------------------------
+// NSE_ESTIMATE
+//----------------------------------------------------------------------
+
+// NSEEstimateMsg reports the current network size estimate to a client.
+// Both estimate and standard deviation are log2 values; they are sent as
+// IEEE 754 doubles in network byte order.
+type NSEEstimateMsg struct {
+	MsgHeader
+	Reserved     uint32            `order:"big"` // reserved for future use
+	Timestamp    util.AbsoluteTime // time of estimate
+	SizeEstimate uint64            `order:"big"` // log2 of network size
+	StdDeviation uint64            `order:"big"` // standard deviation
+}
+
+// NewNSEEstimateMsg creates a new estimate message.
+func NewNSEEstimateMsg(ts util.AbsoluteTime, estimate, stddev float64) *NSEEstimateMsg {
+	return &NSEEstimateMsg{
+		MsgHeader:    MsgHeader{32, enums.MSG_NSE_ESTIMATE},
+		Timestamp:    ts,
+		SizeEstimate: math.Float64bits(estimate),
+		StdDeviation: math.Float64bits(stddev),
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *NSEEstimateMsg) Init() error { return nil }
+
+// Estimate returns log2 of the network size and its standard deviation.
+func (m *NSEEstimateMsg) Estimate() (float64, float64) {
+	return math.Float64frombits(m.SizeEstimate), math.Float64frombits(m.StdDeviation)
+}
+
+// String returns a human-readable representation of the message.
+func (m *NSEEstimateMsg) String() string {
+	est, dev := m.Estimate()
+	return fmt.Sprintf("NSEEstimateMsg{l2nse=%.3f,stddev=%.3f,time=%s}", est, dev, m.Timestamp)
+}
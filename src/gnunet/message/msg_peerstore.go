@@ -0,0 +1,298 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"bytes"
+	"fmt"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// Store options for PEERSTORE_STORE
+const (
+	PeerstoreMultiple = 0 // add value to existing values of the key
+	PeerstoreReplace  = 1 // replace existing values of the key
+)
+
+// PeerstoreKeyHash returns the hash identifying a (sub-system,peer,key)
+// triple in watch requests.
+func PeerstoreKeyHash(subSystem string, peer *util.PeerID, key string) *crypto.HashCode {
+	buf := new(bytes.Buffer)
+	buf.Write(util.WriteCString(subSystem))
+	if peer == nil {
+		peer = util.NewPeerID(nil)
+	}
+	buf.Write(peer.Bytes())
+	buf.Write(util.WriteCString(key))
+	return crypto.Hash(buf.Bytes())
+}
+
+//----------------------------------------------------------------------
+// Generic peerstore record message (used for store and iterate requests
+// and for records sent to clients).
+//----------------------------------------------------------------------
+
+// PeerstoreRecordMsg is the common layout of record messages
+type PeerstoreRecordMsg struct {
+	MsgHeader
+
+	PeerSet    uint16            `order:"big"`      // peer field is set
+	SubSysLen  uint16            `order:"big"`      // size of sub-system name
+	Peer       *util.PeerID      ``                 // peer identity
+	Expire     util.AbsoluteTime ``                 // expiration of record
+	KeyLen     uint16            `order:"big"`      // size of key
+	ValueLen   uint16            `order:"big"`      // size of value
+	Options    uint32            `order:"big"`      // store options
+	SubSystem_ []byte            `size:"SubSysLen"` // sub-system name
+	Key_       []byte            `size:"KeyLen"`    // key
+	Value      []byte            `size:"ValueLen"`  // value
+
+	// transient state
+	subSystem string
+	key       string
+}
+
+// newPeerstoreRecordMsg creates a record message of given type: an empty
+// key or a nil peer are not set.
+func newPeerstoreRecordMsg(mtype enums.MsgType, subSystem string, peer *util.PeerID, key string, value []byte) PeerstoreRecordMsg {
+	msg := PeerstoreRecordMsg{
+		MsgHeader: MsgHeader{56, mtype},
+		Peer:      peer,
+		Expire:    util.AbsoluteTimeNever(),
+		Value:     util.Clone(value),
+		subSystem: subSystem,
+		key:       key,
+	}
+	if peer != nil {
+		msg.PeerSet = 1
+	} else {
+		msg.Peer = util.NewPeerID(nil)
+	}
+	if len(subSystem) > 0 {
+		msg.SubSystem_ = util.WriteCString(subSystem)
+	}
+	if len(key) > 0 {
+		msg.Key_ = util.WriteCString(key)
+	}
+	msg.SubSysLen = uint16(len(msg.SubSystem_))
+	msg.KeyLen = uint16(len(msg.Key_))
+	msg.ValueLen = uint16(len(msg.Value))
+	msg.MsgSize += msg.SubSysLen + msg.KeyLen + msg.ValueLen
+	return msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *PeerstoreRecordMsg) Init() error {
+	m.subSystem, _ = util.ReadCString(m.SubSystem_, 0)
+	m.key, _ = util.ReadCString(m.Key_, 0)
+	return nil
+}
+
+// SubSystem returns the name of the sub-system of the record.
+func (m *PeerstoreRecordMsg) SubSystem() string {
+	return m.subSystem
+}
+
+// Key returns the record key (or an empty string if not set).
+func (m *PeerstoreRecordMsg) Key() string {
+	return m.key
+}
+
+// PeerID returns the peer of the record (or nil if not set).
+func (m *PeerstoreRecordMsg) PeerID() *util.PeerID {
+	if m.PeerSet == 0 {
+		return nil
+	}
+	return m.Peer
+}
+
+// String returns a human-readable representation of the message.
+func (m *PeerstoreRecordMsg) String() string {
+	peer := "*"
+	if p := m.PeerID(); p != nil {
+		peer = p.Short()
+	}
+	return fmt.Sprintf("%s{%s,%s,'%s',size=%d,expire=%s}",
+		m.MsgType, m.subSystem, peer, m.key, len(m.Value), m.Expire)
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_STORE
+//----------------------------------------------------------------------
+
+// PeerstoreStoreMsg is a request to store a record
+type PeerstoreStoreMsg struct {
+	PeerstoreRecordMsg
+}
+
+// NewPeerstoreStoreMsg creates a new store request for a value.
+func NewPeerstoreStoreMsg(subSystem string, peer *util.PeerID, key string, value []byte, expire util.AbsoluteTime, options uint32) *PeerstoreStoreMsg {
+	msg := &PeerstoreStoreMsg{
+		PeerstoreRecordMsg: newPeerstoreRecordMsg(enums.MSG_PEERSTORE_STORE, subSystem, peer, key, value),
+	}
+	msg.Expire = expire
+	msg.Options = options
+	return msg
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_ITERATE
+//----------------------------------------------------------------------
+
+// PeerstoreIterateMsg is a request for all records of a sub-system
+// (optionally restricted to a peer and/or a key).
+type PeerstoreIterateMsg struct {
+	PeerstoreRecordMsg
+}
+
+// NewPeerstoreIterateMsg creates a new iteration request: a nil peer or
+// an empty key match all records.
+func NewPeerstoreIterateMsg(subSystem string, peer *util.PeerID, key string) *PeerstoreIterateMsg {
+	return &PeerstoreIterateMsg{
+		PeerstoreRecordMsg: newPeerstoreRecordMsg(enums.MSG_PEERSTORE_ITERATE, subSystem, peer, key, nil),
+	}
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_ITERATE_RECORD
+//----------------------------------------------------------------------
+
+// PeerstoreIterateRecordMsg is a record sent in response to an
+// iteration request.
+type PeerstoreIterateRecordMsg struct {
+	PeerstoreRecordMsg
+}
+
+// NewPeerstoreIterateRecordMsg creates a new record message.
+func NewPeerstoreIterateRecordMsg(subSystem string, peer *util.PeerID, key string, value []byte, expire util.AbsoluteTime) *PeerstoreIterateRecordMsg {
+	msg := &PeerstoreIterateRecordMsg{
+		PeerstoreRecordMsg: newPeerstoreRecordMsg(enums.MSG_PEERSTORE_ITERATE_RECORD, subSystem, peer, key, value),
+	}
+	msg.Expire = expire
+	return msg
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_ITERATE_END
+//----------------------------------------------------------------------
+
+// PeerstoreIterateEndMsg ends the records of an iteration.
+type PeerstoreIterateEndMsg struct {
+	MsgHeader
+}
+
+// NewPeerstoreIterateEndMsg creates a new message.
+func NewPeerstoreIterateEndMsg() *PeerstoreIterateEndMsg {
+	return &PeerstoreIterateEndMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_PEERSTORE_ITERATE_END},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *PeerstoreIterateEndMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *PeerstoreIterateEndMsg) String() string {
+	return "PeerstoreIterateEndMsg{}"
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_WATCH_RECORD
+//----------------------------------------------------------------------
+
+// PeerstoreWatchRecordMsg is a record sent to a watching client.
+type PeerstoreWatchRecordMsg struct {
+	PeerstoreRecordMsg
+}
+
+// NewPeerstoreWatchRecordMsg creates a new record message.
+func NewPeerstoreWatchRecordMsg(subSystem string, peer *util.PeerID, key string, value []byte, expire util.AbsoluteTime) *PeerstoreWatchRecordMsg {
+	msg := &PeerstoreWatchRecordMsg{
+		PeerstoreRecordMsg: newPeerstoreRecordMsg(enums.MSG_PEERSTORE_WATCH_RECORD, subSystem, peer, key, value),
+	}
+	msg.Expire = expire
+	return msg
+}
+
+//----------------------------------------------------------------------
+// Generic peerstore key hash message (watch requests)
+//----------------------------------------------------------------------
+
+// PeerstoreKeyHashMsg is the common layout of watch requests
+type PeerstoreKeyHashMsg struct {
+	MsgHeader
+
+	Reserved uint32           `order:"big"` // reserved
+	KeyHash  *crypto.HashCode ``            // hash of (sub-system,peer,key)
+}
+
+// newPeerstoreKeyHashMsg creates a key hash message of given type.
+func newPeerstoreKeyHashMsg(mtype enums.MsgType, kh *crypto.HashCode) PeerstoreKeyHashMsg {
+	if kh == nil {
+		kh = crypto.NewHashCode(nil)
+	}
+	return PeerstoreKeyHashMsg{
+		MsgHeader: MsgHeader{72, mtype},
+		KeyHash:   kh,
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *PeerstoreKeyHashMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *PeerstoreKeyHashMsg) String() string {
+	return fmt.Sprintf("%s{%s}", m.MsgType, m.KeyHash.Short())
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_WATCH
+//----------------------------------------------------------------------
+
+// PeerstoreWatchMsg asks for notifications on new records for a key.
+type PeerstoreWatchMsg struct {
+	PeerstoreKeyHashMsg
+}
+
+// NewPeerstoreWatchMsg creates a new watch request for a key hash
+// (see PeerstoreKeyHash).
+func NewPeerstoreWatchMsg(kh *crypto.HashCode) *PeerstoreWatchMsg {
+	return &PeerstoreWatchMsg{
+		PeerstoreKeyHashMsg: newPeerstoreKeyHashMsg(enums.MSG_PEERSTORE_WATCH, kh),
+	}
+}
+
+//----------------------------------------------------------------------
+// PEERSTORE_WATCH_CANCEL
+//----------------------------------------------------------------------
+
+// PeerstoreWatchCancelMsg ends the notifications for a key.
+type PeerstoreWatchCancelMsg struct {
+	PeerstoreKeyHashMsg
+}
+
+// NewPeerstoreWatchCancelMsg creates a new cancel request for a key hash.
+func NewPeerstoreWatchCancelMsg(kh *crypto.HashCode) *PeerstoreWatchCancelMsg {
+	return &PeerstoreWatchCancelMsg{
+		PeerstoreKeyHashMsg: newPeerstoreKeyHashMsg(enums.MSG_PEERSTORE_WATCH_CANCEL, kh),
+	}
+}
@@ -0,0 +1,194 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"fmt"
+	"net"
+
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// Address families in RESOLVER_REQUEST messages (values as on Linux)
+const (
+	ResolverAfUnspec = 0  // any address family
+	ResolverAfInet   = 2  // IPv4 addresses
+	ResolverAfInet6  = 10 // IPv6 addresses
+)
+
+//----------------------------------------------------------------------
+// RESOLVER_REQUEST
+//----------------------------------------------------------------------
+
+// ResolverRequestMsg asks for the IP addresses of a hostname or (in
+// reverse direction) for the hostname of an IP address.
+type ResolverRequestMsg struct {
+	MsgHeader
+
+	Direction uint32 `order:"big"` // 1 = IP to hostname, 0 = hostname to IP
+	Af        uint32 `order:"big"` // address family (ResolverAf*)
+	ClientID  uint32 `order:"big"` // request identifier of client
+	Data      []byte `size:"*"`    // hostname (0-terminated) or IP address
+}
+
+// NewResolverRequestMsg creates a request for the addresses of a hostname
+// (in given address family).
+func NewResolverRequestMsg(id uint32, hostname string, af uint32) *ResolverRequestMsg {
+	msg := &ResolverRequestMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_RESOLVER_REQUEST},
+		Af:        af,
+		ClientID:  id,
+		Data:      util.WriteCString(hostname),
+	}
+	msg.MsgSize += uint16(len(msg.Data))
+	return msg
+}
+
+// NewResolverReverseMsg creates a request for the hostname of an IP
+// address.
+func NewResolverReverseMsg(id uint32, ip net.IP) *ResolverRequestMsg {
+	af := uint32(ResolverAfInet6)
+	if ip4 := ip.To4(); ip4 != nil {
+		af, ip = ResolverAfInet, ip4
+	}
+	msg := &ResolverRequestMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_RESOLVER_REQUEST},
+		Direction: 1,
+		Af:        af,
+		ClientID:  id,
+		Data:      util.Clone([]byte(ip)),
+	}
+	msg.MsgSize += uint16(len(msg.Data))
+	return msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *ResolverRequestMsg) Init() error { return nil }
+
+// Reverse returns true for a request for the hostname of an IP address.
+func (m *ResolverRequestMsg) Reverse() bool {
+	return m.Direction != 0
+}
+
+// Hostname returns the hostname to be resolved.
+func (m *ResolverRequestMsg) Hostname() (string, error) {
+	name, pos := util.ReadCString(m.Data, 0)
+	if m.Reverse() || pos != len(m.Data) {
+		return "", fmt.Errorf("invalid hostname")
+	}
+	return name, nil
+}
+
+// IP returns the IP address to be resolved (reverse direction).
+func (m *ResolverRequestMsg) IP() (net.IP, error) {
+	if !m.Reverse() {
+		return nil, fmt.Errorf("not a reverse request")
+	}
+	switch {
+	case m.Af == ResolverAfInet && len(m.Data) == net.IPv4len:
+	case m.Af == ResolverAfInet6 && len(m.Data) == net.IPv6len:
+	default:
+		return nil, fmt.Errorf("invalid IP address")
+	}
+	return net.IP(util.Clone(m.Data)), nil
+}
+
+// String returns a human-readable representation of the message.
+func (m *ResolverRequestMsg) String() string {
+	if m.Reverse() {
+		ip, _ := m.IP()
+		return fmt.Sprintf("ResolverRequestMsg{id=%d,reverse,ip=%s}", m.ClientID, ip)
+	}
+	name, _ := m.Hostname()
+	return fmt.Sprintf("ResolverRequestMsg{id=%d,af=%d,name='%s'}", m.ClientID, m.Af, name)
+}
+
+//----------------------------------------------------------------------
+// RESOLVER_RESPONSE
+//----------------------------------------------------------------------
+
+// ResolverResponseMsg carries a single result (IP address or hostname)
+// of a request. A response without data ends the list of results.
+type ResolverResponseMsg struct {
+	MsgHeader
+
+	ClientID uint32 `order:"big"` // request identifier of client
+	Data     []byte `size:"*"`    // IP address or hostname (0-terminated)
+}
+
+// NewResolverResponseMsg creates a response with given result data.
+func NewResolverResponseMsg(id uint32, data []byte) *ResolverResponseMsg {
+	msg := &ResolverResponseMsg{
+		MsgHeader: MsgHeader{8, enums.MSG_RESOLVER_RESPONSE},
+		ClientID:  id,
+		Data:      data,
+	}
+	msg.MsgSize += uint16(len(msg.Data))
+	return msg
+}
+
+// NewResolverAddressMsg creates a response with an IP address.
+func NewResolverAddressMsg(id uint32, ip net.IP) *ResolverResponseMsg {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return NewResolverResponseMsg(id, util.Clone([]byte(ip)))
+}
+
+// NewResolverHostnameMsg creates a response with a hostname.
+func NewResolverHostnameMsg(id uint32, hostname string) *ResolverResponseMsg {
+	return NewResolverResponseMsg(id, util.WriteCString(hostname))
+}
+
+// NewResolverEndMsg creates the final response of a request.
+func NewResolverEndMsg(id uint32) *ResolverResponseMsg {
+	return NewResolverResponseMsg(id, nil)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *ResolverResponseMsg) Init() error { return nil }
+
+// End returns true for the final response of a request.
+func (m *ResolverResponseMsg) End() bool {
+	return len(m.Data) == 0
+}
+
+// IP returns the IP address in a response to a forward request (or nil).
+func (m *ResolverResponseMsg) IP() net.IP {
+	if n := len(m.Data); n != net.IPv4len && n != net.IPv6len {
+		return nil
+	}
+	return net.IP(util.Clone(m.Data))
+}
+
+// Hostname returns the hostname in a response to a reverse request (or an
+// empty string).
+func (m *ResolverResponseMsg) Hostname() string {
+	name, pos := util.ReadCString(m.Data, 0)
+	if pos != len(m.Data) {
+		return ""
+	}
+	return name
+}
+
+// String returns a human-readable representation of the message.
+func (m *ResolverResponseMsg) String() string {
+	return fmt.Sprintf("ResolverResponseMsg{id=%d,size=%d}", m.ClientID, len(m.Data))
+}
@@ -39,8 +39,13 @@ type RevocationQueryMsg struct {
 
 // NewRevocationQueryMsg creates a new message for a given zone.
 func NewRevocationQueryMsg(zkey *crypto.ZoneKey) *RevocationQueryMsg {
+	// size of message depends on the zone key type
+	size := uint16(8)
+	if zkey != nil {
+		size += uint16(4 + zkey.KeySize())
+	}
 	return &RevocationQueryMsg{
-		MsgHeader: MsgHeader{40, enums.MSG_REVOCATION_QUERY},
+		MsgHeader: MsgHeader{size, enums.MSG_REVOCATION_QUERY},
 		Reserved:  0,
 		Zone:      zkey,
 	}
@@ -99,8 +104,13 @@ type RevocationRevokeMsg struct {
 
 // NewRevocationRevokeMsg creates a new message for a given zone.
 func NewRevocationRevokeMsg(zsig *crypto.ZoneSignature) *RevocationRevokeMsg {
+	// size of message depends on the zone key type
+	size := uint16(276)
+	if zsig != nil {
+		size += uint16(4 + zsig.KeySize() + zsig.SigSize())
+	}
 	return &RevocationRevokeMsg{
-		MsgHeader:  MsgHeader{364, enums.MSG_REVOCATION_REVOKE},
+		MsgHeader:  MsgHeader{size, enums.MSG_REVOCATION_REVOKE},
 		Timestamp:  util.AbsoluteTimeNow(),
 		TTL:        util.RelativeTime{},
 		PoWs:       make([]uint64, 32),
@@ -134,7 +144,7 @@ func NewRevocationRevokeResponseMsg(success bool) *RevocationRevokeResponseMsg {
 		status = 1
 	}
 	return &RevocationRevokeResponseMsg{
-		MsgHeader: MsgHeader{8, enums.MSG_REVOCATION_QUERY_RESPONSE},
+		MsgHeader: MsgHeader{8, enums.MSG_REVOCATION_REVOKE_RESPONSE},
 		Success:   uint32(status),
 	}
 }
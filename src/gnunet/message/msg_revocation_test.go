@@ -0,0 +1,72 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// Test that the size in the message header matches the serialized
+// revocation messages for all zone key types.
+func TestRevocationMsgSize(t *testing.T) {
+	for _, ztype := range []enums.GNSType{enums.GNS_TYPE_PKEY, enums.GNS_TYPE_EDKEY} {
+		zp, err := crypto.NewZonePrivate(ztype, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// query message
+		qm := NewRevocationQueryMsg(zp.Public())
+		buf, err := data.Marshal(qm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) != int(qm.Size()) {
+			t.Fatalf("%s: query size mismatch (%d != %d)", ztype, len(buf), qm.Size())
+		}
+		// revoke message
+		zsig, err := zp.Sign([]byte("revoke"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rm := NewRevocationRevokeMsg(zsig)
+		if buf, err = data.Marshal(rm); err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) != int(rm.Size()) {
+			t.Fatalf("%s: revoke size mismatch (%d != %d)", ztype, len(buf), rm.Size())
+		}
+	}
+}
+
+// Test the message type of revoke responses.
+func TestRevocationRevokeResponseMsg(t *testing.T) {
+	msg := NewRevocationRevokeResponseMsg(true)
+	if msg.Type() != enums.MSG_REVOCATION_REVOKE_RESPONSE {
+		t.Fatalf("wrong message type %s", msg.Type())
+	}
+	if msg.Success != 1 {
+		t.Fatal("wrong status")
+	}
+}
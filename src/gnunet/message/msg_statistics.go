@@ -0,0 +1,344 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"bytes"
+	"fmt"
+
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// Flags in STATISTICS_SET requests
+const (
+	StatisticsSetAbsolute   = 0 // set absolute value
+	StatisticsSetRelative   = 1 // add (signed) value to current value
+	StatisticsSetPersistent = 2 // value is kept across restarts
+)
+
+// StatisticsPersistBit is set in the identifier of persistent values in
+// STATISTICS_VALUE and in the flags of STATISTICS_WATCH_VALUE messages.
+const StatisticsPersistBit = uint32(1) << 31
+
+// statisticsNames returns the binary representation of sub-system and
+// name (both 0-terminated).
+func statisticsNames(subSystem, name string) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(util.WriteCString(subSystem))
+	buf.Write(util.WriteCString(name))
+	return buf.Bytes()
+}
+
+// parseStatisticsNames returns sub-system and name from their binary
+// representation.
+func parseStatisticsNames(buf []byte) (subSystem, name string, err error) {
+	pos := 0
+	if subSystem, pos = util.ReadCString(buf, pos); pos < 0 {
+		return "", "", fmt.Errorf("invalid sub-system name")
+	}
+	if name, pos = util.ReadCString(buf, pos); pos != len(buf) {
+		return "", "", fmt.Errorf("invalid statistics name")
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// STATISTICS_SET
+//----------------------------------------------------------------------
+
+// StatisticsSetMsg is a request to set (or update) a value.
+type StatisticsSetMsg struct {
+	MsgHeader
+
+	Flags  uint32 `order:"big"` // set flags (StatisticsSet*)
+	Value  uint64 `order:"big"` // value (signed if relative)
+	Names_ []byte `size:"*"`    // sub-system and name (0-terminated)
+
+	// transient state
+	subSystem string
+	name      string
+}
+
+// NewStatisticsSetMsg creates a new request to set a value.
+func NewStatisticsSetMsg(subSystem, name string, value uint64, flags uint32) *StatisticsSetMsg {
+	msg := &StatisticsSetMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_STATISTICS_SET},
+		Flags:     flags,
+		Value:     value,
+		Names_:    statisticsNames(subSystem, name),
+		subSystem: subSystem,
+		name:      name,
+	}
+	msg.MsgSize += uint16(len(msg.Names_))
+	return msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsSetMsg) Init() (err error) {
+	m.subSystem, m.name, err = parseStatisticsNames(m.Names_)
+	return
+}
+
+// SubSystem returns the name of the sub-system.
+func (m *StatisticsSetMsg) SubSystem() string {
+	return m.subSystem
+}
+
+// Name returns the name of the value.
+func (m *StatisticsSetMsg) Name() string {
+	return m.name
+}
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsSetMsg) String() string {
+	return fmt.Sprintf("StatisticsSetMsg{%s,'%s',value=%d,flags=%d}",
+		m.subSystem, m.name, int64(m.Value), m.Flags)
+}
+
+//----------------------------------------------------------------------
+// STATISTICS_GET and STATISTICS_WATCH
+//----------------------------------------------------------------------
+
+// StatisticsGetMsg is a request for values: an empty sub-system or
+// name matches all values.
+type StatisticsGetMsg struct {
+	MsgHeader
+
+	Names_ []byte `size:"*"` // sub-system and name (0-terminated)
+
+	// transient state
+	subSystem string
+	name      string
+}
+
+// newStatisticsGetMsg creates a request of given type.
+func newStatisticsGetMsg(mtype enums.MsgType, subSystem, name string) StatisticsGetMsg {
+	msg := StatisticsGetMsg{
+		MsgHeader: MsgHeader{4, mtype},
+		Names_:    statisticsNames(subSystem, name),
+		subSystem: subSystem,
+		name:      name,
+	}
+	msg.MsgSize += uint16(len(msg.Names_))
+	return msg
+}
+
+// NewStatisticsGetMsg creates a new request for values.
+func NewStatisticsGetMsg(subSystem, name string) *StatisticsGetMsg {
+	msg := newStatisticsGetMsg(enums.MSG_STATISTICS_GET, subSystem, name)
+	return &msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsGetMsg) Init() (err error) {
+	m.subSystem, m.name, err = parseStatisticsNames(m.Names_)
+	return
+}
+
+// SubSystem returns the name of the sub-system.
+func (m *StatisticsGetMsg) SubSystem() string {
+	return m.subSystem
+}
+
+// Name returns the name of the value.
+func (m *StatisticsGetMsg) Name() string {
+	return m.name
+}
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsGetMsg) String() string {
+	return fmt.Sprintf("%s{%s,'%s'}", m.MsgType, m.subSystem, m.name)
+}
+
+// StatisticsWatchMsg is a request to watch a value (sub-system and
+// name must be set).
+type StatisticsWatchMsg struct {
+	StatisticsGetMsg
+}
+
+// NewStatisticsWatchMsg creates a new request to watch a value.
+func NewStatisticsWatchMsg(subSystem, name string) *StatisticsWatchMsg {
+	return &StatisticsWatchMsg{
+		StatisticsGetMsg: newStatisticsGetMsg(enums.MSG_STATISTICS_WATCH, subSystem, name),
+	}
+}
+
+//----------------------------------------------------------------------
+// STATISTICS_VALUE
+//----------------------------------------------------------------------
+
+// StatisticsValueMsg is a value sent in response to a GET request.
+type StatisticsValueMsg struct {
+	MsgHeader
+
+	UID    uint32 `order:"big"` // identifier of value (with persist bit)
+	Value  uint64 `order:"big"` // current value
+	Names_ []byte `size:"*"`    // sub-system and name (0-terminated)
+
+	// transient state
+	subSystem string
+	name      string
+}
+
+// NewStatisticsValueMsg creates a new value message.
+func NewStatisticsValueMsg(subSystem, name string, value uint64, uid uint32, persistent bool) *StatisticsValueMsg {
+	if persistent {
+		uid |= StatisticsPersistBit
+	}
+	msg := &StatisticsValueMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_STATISTICS_VALUE},
+		UID:       uid,
+		Value:     value,
+		Names_:    statisticsNames(subSystem, name),
+		subSystem: subSystem,
+		name:      name,
+	}
+	msg.MsgSize += uint16(len(msg.Names_))
+	return msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsValueMsg) Init() (err error) {
+	m.subSystem, m.name, err = parseStatisticsNames(m.Names_)
+	return
+}
+
+// SubSystem returns the name of the sub-system.
+func (m *StatisticsValueMsg) SubSystem() string {
+	return m.subSystem
+}
+
+// Name returns the name of the value.
+func (m *StatisticsValueMsg) Name() string {
+	return m.name
+}
+
+// Persistent returns true if the value is kept across restarts.
+func (m *StatisticsValueMsg) Persistent() bool {
+	return m.UID&StatisticsPersistBit != 0
+}
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsValueMsg) String() string {
+	return fmt.Sprintf("StatisticsValueMsg{%s,'%s',value=%d,persistent=%v}",
+		m.subSystem, m.name, m.Value, m.Persistent())
+}
+
+//----------------------------------------------------------------------
+// STATISTICS_WATCH_VALUE
+//----------------------------------------------------------------------
+
+// StatisticsWatchValueMsg notifies a client about the change of a
+// watched value.
+type StatisticsWatchValueMsg struct {
+	MsgHeader
+
+	Flags    uint32 `order:"big"` // persist bit
+	WID      uint32 `order:"big"` // watch identifier (by order of requests)
+	Reserved uint32 `order:"big"` // reserved
+	Value    uint64 `order:"big"` // current value
+}
+
+// NewStatisticsWatchValueMsg creates a new notification.
+func NewStatisticsWatchValueMsg(wid uint32, value uint64, persistent bool) *StatisticsWatchValueMsg {
+	msg := &StatisticsWatchValueMsg{
+		MsgHeader: MsgHeader{24, enums.MSG_STATISTICS_WATCH_VALUE},
+		WID:       wid,
+		Value:     value,
+	}
+	if persistent {
+		msg.Flags = StatisticsPersistBit
+	}
+	return msg
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsWatchValueMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsWatchValueMsg) String() string {
+	return fmt.Sprintf("StatisticsWatchValueMsg{wid=%d,value=%d}", m.WID, m.Value)
+}
+
+//----------------------------------------------------------------------
+// STATISTICS_END, STATISTICS_DISCONNECT and STATISTICS_DISCONNECT_CONFIRM
+//----------------------------------------------------------------------
+
+// StatisticsEndMsg ends the values sent in response to a GET request.
+type StatisticsEndMsg struct {
+	MsgHeader
+}
+
+// NewStatisticsEndMsg creates a new message.
+func NewStatisticsEndMsg() *StatisticsEndMsg {
+	return &StatisticsEndMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_STATISTICS_END},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsEndMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsEndMsg) String() string {
+	return "StatisticsEndMsg{}"
+}
+
+// StatisticsDisconnectMsg announces that a client is done sending
+// requests.
+type StatisticsDisconnectMsg struct {
+	MsgHeader
+}
+
+// NewStatisticsDisconnectMsg creates a new message.
+func NewStatisticsDisconnectMsg() *StatisticsDisconnectMsg {
+	return &StatisticsDisconnectMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_STATISTICS_DISCONNECT},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsDisconnectMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsDisconnectMsg) String() string {
+	return "StatisticsDisconnectMsg{}"
+}
+
+// StatisticsDisconnectConfirmMsg confirms that all requests of a
+// client are processed.
+type StatisticsDisconnectConfirmMsg struct {
+	MsgHeader
+}
+
+// NewStatisticsDisconnectConfirmMsg creates a new message.
+func NewStatisticsDisconnectConfirmMsg() *StatisticsDisconnectConfirmMsg {
+	return &StatisticsDisconnectConfirmMsg{
+		MsgHeader: MsgHeader{4, enums.MSG_STATISTICS_DISCONNECT_CONFIRM},
+	}
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *StatisticsDisconnectConfirmMsg) Init() error { return nil }
+
+// String returns a human-readable representation of the message.
+func (m *StatisticsDisconnectConfirmMsg) String() string {
+	return "StatisticsDisconnectConfirmMsg{}"
+}
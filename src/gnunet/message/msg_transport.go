@@ -19,6 +19,8 @@
 package message
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"time"
 
@@ -28,7 +30,6 @@ import (
 
 	"github.com/bfix/gospel/crypto/ed25519"
 	"github.com/bfix/gospel/data"
-	"github.com/bfix/gospel/logger"
 )
 
 //----------------------------------------------------------------------
@@ -67,12 +68,16 @@ func (m *TransportTCPWelcomeMsg) Init() error { return nil }
 // from a HELLO).  Followed by the address we are trying to validate,
 // or an empty address if we are just sending a PING to confirm that a
 // connection which the receiver (of the PING) initiated is still valid.
+//
+// Addresses in PING and PONG messages are encoded like in the C
+// implementation: the (0-terminated) name of the transport followed by
+// the transport-specific address.
 //----------------------------------------------------------------------
 
 // TransportPingMsg is a PING request message
 type TransportPingMsg struct {
 	MsgHeader
-	Challenge uint32       // Challenge code (to ensure fresh reply)
+	Challenge uint32       `order:"big"` // Challenge code (to ensure fresh reply)
 	Target    *util.PeerID // EdDSA public key (long-term) of target peer
 	Address   []byte       `size:"*"` // encoded address
 }
@@ -90,22 +95,26 @@ func NewTransportPingMsg(target *util.PeerID, a *util.Address) *TransportPingMsg
 		Address:   nil,
 	}
 	if a != nil {
-		if addrData, err := data.Marshal(a); err == nil {
-			m.Address = addrData
-			m.MsgSize += uint16(len(addrData))
-		}
+		m.Address = encodeAddress(a)
+		m.MsgSize += uint16(len(m.Address))
 	}
 	return m
 }
 
+// Addr returns the address to be validated (or nil if no address is
+// included).
+func (m *TransportPingMsg) Addr() (*util.Address, error) {
+	return decodeAddress(m.Address)
+}
+
 // String returns a human-readable representation of the message.
 func (m *TransportPingMsg) String() string {
-	a := new(util.Address)
-	if err := data.Unmarshal(a, m.Address); err != nil {
-		logger.Printf(logger.ERROR, "[TransportPingMsg.String] failed: %s", err.Error())
+	addr := "<none>"
+	if a, err := m.Addr(); err == nil && a != nil {
+		addr = a.URI()
 	}
 	return fmt.Sprintf("TransportPingMsg{target=%s,addr=%s,challenge=%d}",
-		m.Target, a, m.Challenge)
+		m.Target, addr, m.Challenge)
 }
 
 // Init called after unmarshalling a message to setup internal state
@@ -135,7 +144,7 @@ type SignedAddress struct {
 // NewSignedAddress creates a new (signable) data block from an address.
 func NewSignedAddress(a *util.Address) *SignedAddress {
 	// serialize address
-	addrData, _ := data.Marshal(a)
+	addrData := encodeAddress(a)
 	alen := len(addrData)
 	addr := &SignedAddress{
 		Purpose: &crypto.SignaturePurpose{
@@ -153,8 +162,8 @@ func NewSignedAddress(a *util.Address) *SignedAddress {
 // TransportPongMsg is a response message for a PING request
 type TransportPongMsg struct {
 	MsgHeader
-	Challenge   uint32         // Challenge code (to ensure fresh reply)
-	Signature   []byte         `size:"64"` // Signature of address
+	Challenge   uint32         `order:"big"` // Challenge code (to ensure fresh reply)
+	Signature   []byte         `size:"64"`   // Signature of address
 	SignedBlock *SignedAddress // signed block of data
 }
 
@@ -175,12 +184,19 @@ func NewTransportPongMsg(challenge uint32, a *util.Address) *TransportPongMsg {
 	return m
 }
 
+// Addr returns the signed address (or nil if no address is included).
+func (m *TransportPongMsg) Addr() (*util.Address, error) {
+	if m.SignedBlock == nil {
+		return nil, nil
+	}
+	return decodeAddress(m.SignedBlock.Address)
+}
+
 // String returns a human-readable representation of the message.
 func (m *TransportPongMsg) String() string {
-	a := new(util.Address)
-	if err := data.Unmarshal(a, m.SignedBlock.Address); err == nil {
+	if a, err := m.Addr(); err == nil && a != nil {
 		return fmt.Sprintf("TransportPongMsg{addr=%s,challenge=%d}",
-			a, m.Challenge)
+			a.URI(), m.Challenge)
 	}
 	return fmt.Sprintf("TransportPongMsg{addr=<unknown>,%d}", m.Challenge)
 }
@@ -215,6 +231,28 @@ func (m *TransportPongMsg) Verify(pub *ed25519.PublicKey) (bool, error) {
 // Init called after unmarshalling a message to setup internal state
 func (m *TransportPongMsg) Init() error { return nil }
 
+// encodeAddress returns the binary representation of an address in
+// PING and PONG messages: "<transport>\0<address>"
+func encodeAddress(a *util.Address) []byte {
+	buf := make([]byte, 0, len(a.Netw)+1+len(a.Address))
+	buf = append(buf, []byte(a.Netw)...)
+	buf = append(buf, 0)
+	return append(buf, a.Address...)
+}
+
+// decodeAddress reconstructs an address from its binary representation
+// in PING and PONG messages (nil if no address is included).
+func decodeAddress(buf []byte) (*util.Address, error) {
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	pos := bytes.IndexByte(buf, 0)
+	if pos < 1 {
+		return nil, errors.New("address without transport name")
+	}
+	return util.NewAddress(string(buf[:pos]), string(buf[pos+1:])), nil
+}
+
 //----------------------------------------------------------------------
 // TRANSPORT_SESSION_ACK
 //----------------------------------------------------------------------
@@ -377,3 +415,69 @@ func (m *SessionKeepAliveRespMsg) String() string {
 
 // Init called after unmarshalling a message to setup internal state
 func (m *SessionKeepAliveRespMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// FRAGMENT
+//----------------------------------------------------------------------
+
+// FragmentMsg carries a fragment of a message that is too large to be
+// sent in a single packet.
+type FragmentMsg struct {
+	MsgHeader
+	ID     uint32 `order:"big"` // fragment identifier (same for all fragments)
+	Total  uint16 `order:"big"` // total size of the fragmented message
+	Offset uint16 `order:"big"` // offset of fragment data in the message
+	Data   []byte `size:"*"`    // fragment data
+}
+
+// NewFragmentMsg creates a new fragment of a message.
+func NewFragmentMsg(id uint32, total, offset uint16, data []byte) *FragmentMsg {
+	if data == nil {
+		data = make([]byte, 0)
+	}
+	return &FragmentMsg{
+		MsgHeader: MsgHeader{uint16(12 + len(data)), enums.MSG_FRAGMENT},
+		ID:        id,
+		Total:     total,
+		Offset:    offset,
+		Data:      data,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *FragmentMsg) String() string {
+	return fmt.Sprintf("FragmentMsg{id=%d,total=%d,offset=%d,size=%d}",
+		m.ID, m.Total, m.Offset, len(m.Data))
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *FragmentMsg) Init() error { return nil }
+
+//----------------------------------------------------------------------
+// FRAGMENT_ACK
+//----------------------------------------------------------------------
+
+// FragmentAckMsg acknowledges the fragments of a message received so far.
+type FragmentAckMsg struct {
+	MsgHeader
+	ID   uint32 `order:"big"` // fragment identifier
+	Bits uint64 `order:"big"` // set bits mark fragments not yet received
+}
+
+// NewFragmentAckMsg creates a new acknowledgement for fragments; 'missing'
+// has a bit set for every fragment (by index) not received yet.
+func NewFragmentAckMsg(id uint32, missing uint64) *FragmentAckMsg {
+	return &FragmentAckMsg{
+		MsgHeader: MsgHeader{16, enums.MSG_FRAGMENT_ACK},
+		ID:        id,
+		Bits:      missing,
+	}
+}
+
+// String returns a human-readable representation of the message.
+func (m *FragmentAckMsg) String() string {
+	return fmt.Sprintf("FragmentAckMsg{id=%d,missing=%016x}", m.ID, m.Bits)
+}
+
+// Init called after unmarshalling a message to setup internal state
+func (m *FragmentAckMsg) Init() error { return nil }
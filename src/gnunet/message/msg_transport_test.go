@@ -0,0 +1,109 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package message
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"gnunet/enums"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+)
+
+// Test the wire format of PING messages (as defined by the C
+// implementation).
+func TestTransportPingMsg(t *testing.T) {
+	target := util.NewPeerID(util.NewRndArray(32))
+	addr := util.NewAddress("ip+udp", "127.0.0.1:2086")
+	msg := NewTransportPingMsg(target, addr)
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.Size()) {
+		t.Fatalf("size mismatch (%d != %d)", len(buf), msg.Size())
+	}
+	if binary.BigEndian.Uint32(buf[4:8]) != msg.Challenge {
+		t.Fatal("challenge not in network byte order")
+	}
+	if !bytes.Equal(buf[8:40], target.Data) {
+		t.Fatal("wrong target")
+	}
+	if string(buf[40:]) != "ip+udp\x00127.0.0.1:2086" {
+		t.Fatalf("wrong address encoding %q", buf[40:])
+	}
+	// decode message
+	msg2 := NewTransportPingMsg(nil, nil)
+	if err = data.Unmarshal(msg2, buf); err != nil {
+		t.Fatal(err)
+	}
+	a, err := msg2.Addr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg2.Challenge != msg.Challenge || !a.Equal(addr) {
+		t.Fatalf("decoded %s", msg2)
+	}
+}
+
+// Test the wire format and signature of PONG messages (as defined by
+// the C implementation).
+func TestTransportPongMsg(t *testing.T) {
+	pub, prv := ed25519.NewKeypair()
+	addr := util.NewAddress("ip+udp", "127.0.0.1:2086")
+	msg := NewTransportPongMsg(4711, addr)
+	if err := msg.Sign(prv); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.Size()) {
+		t.Fatalf("size mismatch (%d != %d)", len(buf), msg.Size())
+	}
+	alen := uint32(len("ip+udp\x00127.0.0.1:2086"))
+	switch {
+	case binary.BigEndian.Uint32(buf[4:8]) != 4711:
+		t.Fatal("challenge not in network byte order")
+	case binary.BigEndian.Uint32(buf[72:76]) != 20+alen:
+		t.Fatal("wrong size of signed block")
+	case binary.BigEndian.Uint32(buf[76:80]) != uint32(enums.SIG_TRANSPORT_PONG_OWN):
+		t.Fatal("wrong signature purpose")
+	case binary.BigEndian.Uint32(buf[88:92]) != alen:
+		t.Fatal("wrong address length")
+	case string(buf[92:]) != "ip+udp\x00127.0.0.1:2086":
+		t.Fatalf("wrong address encoding %q", buf[92:])
+	}
+	// decode and verify message
+	msg2 := NewTransportPongMsg(0, nil)
+	if err = data.Unmarshal(msg2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := msg2.Verify(pub); !ok || err != nil {
+		t.Fatalf("signature not verified: %v", err)
+	}
+	if a, err := msg2.Addr(); err != nil || !a.Equal(addr) {
+		t.Fatalf("decoded %s", msg2)
+	}
+}
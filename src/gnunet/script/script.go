@@ -0,0 +1,299 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package script implements hooks for operator-defined scripts that react
+// to node events. Scripts are written in Starlark (a Python dialect
+// designed for embedding) and define functions named after the events
+// they want to handle:
+//
+//	def on_peer_connect(ev):
+//	    log("peer %s connected" % ev["peer"])
+//
+//	def on_peer_disconnect(ev):
+//	    log("peer %s disconnected (%s)" % (ev["peer"], ev["reason"]))
+//
+//	def on_result(ev):
+//	    # drop large results
+//	    return ev["size"] < 32768
+//
+//	def on_zone_publish(ev):
+//	    return ev["label"] != "secret"
+//
+// Scripts run in a sandbox: they can't access files, the network or
+// other modules ("load" is disabled) and each hook invocation is limited
+// in run time and execution steps. A hook that returns False vetoes the
+// action it was called for (if the event allows it); failing hooks are
+// logged and do not veto.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/service/metrics"
+
+	"github.com/bfix/gospel/logger"
+	"go.starlark.net/starlark"
+)
+
+// Hook names
+const (
+	HookPeerConnect    = "on_peer_connect"    // peer connected (core)
+	HookPeerDisconnect = "on_peer_disconnect" // peer disconnected (core)
+	HookResult         = "on_result"          // DHT result received
+	HookZonePublish    = "on_zone_publish"    // zone label published (zonemaster)
+)
+
+// Default limits for hook execution
+var (
+	DefaultTimeout  = 100 * time.Millisecond
+	DefaultMaxSteps = uint64(100000)
+)
+
+// Error codes
+var (
+	ErrScriptLoad = errors.New("load() not available in scripts")
+)
+
+// hook runs (by hook and result)
+var hookRuns = metrics.NewCounter("gnunet_script_hooks_total",
+	"Script hook runs by hook and result.", "hook", "result")
+
+//----------------------------------------------------------------------
+
+// Script is a loaded script with its hook functions
+type Script struct {
+	name  string                       // script name
+	hooks map[string]starlark.Callable // hook functions by name
+}
+
+// Engine manages scripts and runs hooks.
+type Engine struct {
+	sync.RWMutex
+
+	scripts  []*Script     // loaded scripts
+	timeout  time.Duration // max. run time of a hook
+	maxSteps uint64        // max. execution steps of a hook
+}
+
+// NewEngine creates a new engine and loads all scripts referenced in the
+// configuration.
+func NewEngine(cfg *config.ScriptConfig) (e *Engine, err error) {
+	e = &Engine{
+		timeout:  DefaultTimeout,
+		maxSteps: DefaultMaxSteps,
+	}
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		e.timeout = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+	if cfg.MaxSteps > 0 {
+		e.maxSteps = cfg.MaxSteps
+	}
+	for _, fname := range cfg.Files {
+		var src []byte
+		if src, err = os.ReadFile(fname); err != nil {
+			return
+		}
+		if err = e.Load(filepath.Base(fname), string(src)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Load a script from source. The top-level statements of the script are
+// executed (with the same limits as hooks); the functions defined for
+// known hooks are registered.
+func (e *Engine) Load(name, src string) error {
+	thread, stop := e.newThread(name)
+	defer stop()
+	globals, err := starlark.ExecFile(thread, name, src, builtins)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", name, err)
+	}
+	globals.Freeze()
+
+	s := &Script{
+		name:  name,
+		hooks: make(map[string]starlark.Callable),
+	}
+	for _, hook := range []string{HookPeerConnect, HookPeerDisconnect, HookResult, HookZonePublish} {
+		if fcn, ok := globals[hook].(starlark.Callable); ok {
+			s.hooks[hook] = fcn
+		}
+	}
+	logger.Printf(logger.INFO, "[script] %s loaded (%d hooks)", name, len(s.hooks))
+
+	e.Lock()
+	defer e.Unlock()
+	e.scripts = append(e.scripts, s)
+	return nil
+}
+
+// Run a hook in all scripts that define it. The event attributes are
+// passed to the hook as a dictionary. Returns false if any script vetoed
+// the action by returning False.
+func (e *Engine) Run(hook string, ev map[string]any) bool {
+	e.RLock()
+	defer e.RUnlock()
+
+	allow := true
+	for _, s := range e.scripts {
+		fcn, ok := s.hooks[hook]
+		if !ok {
+			continue
+		}
+		res, err := e.call(s.name, fcn, ev)
+		if err != nil {
+			logger.Printf(logger.WARN, "[script] %s.%s failed: %s", s.name, hook, err.Error())
+			hookRuns.Inc(hook, "error")
+			continue
+		}
+		if res == starlark.False {
+			logger.Printf(logger.DBG, "[script] %s.%s vetoed event", s.name, hook)
+			hookRuns.Inc(hook, "veto")
+			allow = false
+			continue
+		}
+		hookRuns.Inc(hook, "allow")
+	}
+	return allow
+}
+
+// call a hook function with event attributes.
+func (e *Engine) call(name string, fcn starlark.Callable, ev map[string]any) (starlark.Value, error) {
+	// convert event to (frozen) dictionary
+	dict := starlark.NewDict(len(ev))
+	keys := make([]string, 0, len(ev))
+	for k := range ev {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := toValue(ev[k])
+		if err != nil {
+			return nil, err
+		}
+		if err = dict.SetKey(starlark.String(k), v); err != nil {
+			return nil, err
+		}
+	}
+	dict.Freeze()
+
+	thread, stop := e.newThread(name)
+	defer stop()
+	return starlark.Call(thread, fcn, starlark.Tuple{dict}, nil)
+}
+
+// newThread creates a sandboxed thread for script execution that is
+// cancelled when the time limit is reached. The returned function must
+// be called when execution has finished.
+func (e *Engine) newThread(name string) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			logger.Printf(logger.INFO, "[script:%s] %s", name, msg)
+		},
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, ErrScriptLoad
+		},
+	}
+	thread.SetMaxExecutionSteps(e.maxSteps)
+	timer := time.AfterFunc(e.timeout, func() {
+		thread.Cancel("timeout")
+	})
+	return thread, func() { timer.Stop() }
+}
+
+// toValue converts an event attribute to a Starlark value.
+func toValue(v any) (starlark.Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(x), nil
+	case string:
+		return starlark.String(x), nil
+	case int:
+		return starlark.MakeInt(x), nil
+	case int64:
+		return starlark.MakeInt64(x), nil
+	case uint16:
+		return starlark.MakeUint(uint(x)), nil
+	case uint32:
+		return starlark.MakeUint(uint(x)), nil
+	case uint64:
+		return starlark.MakeUint64(x), nil
+	case fmt.Stringer:
+		return starlark.String(x.String()), nil
+	}
+	return nil, fmt.Errorf("unsupported event attribute type %T", v)
+}
+
+//----------------------------------------------------------------------
+// Builtin functions available to scripts
+//----------------------------------------------------------------------
+
+var builtins = starlark.StringDict{
+	"log": starlark.NewBuiltin("log", func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var msg string
+		if err := starlark.UnpackPositionalArgs("log", args, kwargs, 1, &msg); err != nil {
+			return nil, err
+		}
+		logger.Printf(logger.INFO, "[script:%s] %s", thread.Name, msg)
+		return starlark.None, nil
+	}),
+}
+
+//----------------------------------------------------------------------
+// Global hook engine
+//----------------------------------------------------------------------
+
+var (
+	engine *Engine // engine used by Run (nil = no scripts)
+)
+
+// Setup the global engine from configuration (nil to disable scripts).
+func Setup(cfg *config.ScriptConfig) (err error) {
+	var e *Engine
+	if cfg != nil {
+		if e, err = NewEngine(cfg); err != nil {
+			return
+		}
+	}
+	engine = e
+	return
+}
+
+// Run a hook on the global engine; returns true if the action is allowed
+// (always true if no scripts are configured).
+func Run(hook string, ev map[string]any) bool {
+	if e := engine; e != nil {
+		return e.Run(hook, ev)
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+)
+
+// newEngine creates an engine with a single script loaded.
+func newEngine(t *testing.T, src string) *Engine {
+	t.Helper()
+	e, err := NewEngine(&config.ScriptConfig{
+		Timeout:  200,
+		MaxSteps: 1000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Load("test.star", src); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestScriptHook(t *testing.T) {
+	e := newEngine(t, `
+seen = []
+
+def on_result(ev):
+    seen.append(ev["size"])  # globals are frozen: fails
+    return True
+
+def on_zone_publish(ev):
+    log("publishing %s in %s" % (ev["label"], ev["zone"]))
+    return ev["label"] != "secret"
+
+def on_peer_disconnect(ev):
+    return ev["reason"] != "protocol"
+`)
+	// a failing hook does not veto
+	if !e.Run(HookResult, map[string]any{"size": 42}) {
+		t.Fatal("failing hook vetoed")
+	}
+	// hook with veto
+	if !e.Run(HookZonePublish, map[string]any{"zone": "test", "label": "www"}) {
+		t.Fatal("label 'www' rejected")
+	}
+	if e.Run(HookZonePublish, map[string]any{"zone": "test", "label": "secret"}) {
+		t.Fatal("label 'secret' not rejected")
+	}
+	if e.Run(HookPeerDisconnect, map[string]any{"peer": "XYZ", "reason": "protocol"}) {
+		t.Fatal("disconnect hook not run")
+	}
+	// undefined hook
+	if !e.Run(HookPeerConnect, map[string]any{"peer": "XYZ"}) {
+		t.Fatal("undefined hook vetoed")
+	}
+}
+
+func TestScriptLimits(t *testing.T) {
+	// endless loop is stopped by the step limit
+	e := newEngine(t, `
+def on_result(ev):
+    for i in range(1000000000):
+        pass
+    return False
+`)
+	start := time.Now()
+	if !e.Run(HookResult, nil) {
+		t.Fatal("aborted hook vetoed")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("hook not terminated")
+	}
+
+	// timeout with unlimited steps
+	e.maxSteps = 0
+	start = time.Now()
+	if !e.Run(HookResult, nil) {
+		t.Fatal("aborted hook vetoed")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("hook not terminated by timeout (%s)", d)
+	}
+
+	// loading other modules is not allowed
+	if err := e.Load("load.star", `load("other.star", "x")`); err == nil {
+		t.Fatal("load() succeeded")
+	}
+	// no access to the environment
+	if err := e.Load("open.star", `f = open("/etc/passwd")`); err == nil {
+		t.Fatal("open() succeeded")
+	}
+}
+
+func TestScriptSetup(t *testing.T) {
+	// no scripts: everything allowed
+	if err := Setup(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !Run(HookResult, map[string]any{"size": 1}) {
+		t.Fatal("vetoed without scripts")
+	}
+	// load script from file
+	fname := filepath.Join(t.TempDir(), "veto.star")
+	if err := os.WriteFile(fname, []byte("def on_result(ev):\n    return False\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Setup(&config.ScriptConfig{Files: []string{fname}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = Setup(nil) }()
+	if Run(HookResult, map[string]any{"size": 1}) {
+		t.Fatal("result not vetoed")
+	}
+	// missing script file
+	if err := Setup(&config.ScriptConfig{Files: []string{fname + ".missing"}}); err == nil {
+		t.Fatal("missing script loaded")
+	}
+}
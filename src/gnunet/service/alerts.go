@@ -0,0 +1,377 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/service/metrics"
+	"gnunet/transport"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrAlertRule    = errors.New("invalid alert rule")
+	ErrAlertWebhook = errors.New("alert webhook failed")
+)
+
+//----------------------------------------------------------------------
+// Alerts: Operators define thresholds on service metrics (event counters
+// and the health details of modules). Metrics are sampled periodically;
+// a rule that is violated for its whole observation window raises an
+// alert: a warning is logged, a notification is posted to a webhook
+// and the health status of the service is degraded until the rule is
+// met again.
+//----------------------------------------------------------------------
+
+// DefaultAlertPeriod is the time between alert checks (if not configured).
+var DefaultAlertPeriod = time.Minute
+
+// WebhookTimeout is the time-out for posting a notification.
+var WebhookTimeout = 10 * time.Second
+
+// event counters
+var (
+	counters     = make(map[string]int64)
+	countersLock sync.Mutex
+)
+
+// Count adds 'n' to a named event counter (like "dht:get").
+func Count(name string, n int64) {
+	countersLock.Lock()
+	counters[name] += n
+	countersLock.Unlock()
+}
+
+// Metrics returns the current values of all metrics: event counters and
+// the health details of modules ("<module>:peers", "<module>:handlers",
+// "<module>:clients", "<module>:queue:<name>", "<module>:cache:<name>" and
+// "<module>:disconnect:<reason>").
+func Metrics() map[string]float64 {
+	m := make(map[string]float64)
+	countersLock.Lock()
+	for k, v := range counters {
+		m[k] = float64(v)
+	}
+	countersLock.Unlock()
+	for _, in := range Introspect() {
+		m[in.Module+":peers"] = float64(in.Peers)
+		m[in.Module+":handlers"] = float64(in.Handlers)
+		m[in.Module+":clients"] = float64(in.Clients)
+		for k, v := range in.Queues {
+			m[in.Module+":queue:"+k] = float64(v)
+		}
+		for k, v := range in.Caches {
+			m[in.Module+":cache:"+k] = float64(v)
+		}
+		for k, v := range in.Disconnects {
+			m[in.Module+":disconnect:"+k] = float64(v)
+		}
+	}
+	return m
+}
+
+// event counters and health details are exported on the metrics endpoint
+var _ = metrics.NewGaugeVecFunc("gnunet_service_metric",
+	"Service event counters and module health details.", "name", Metrics)
+
+//----------------------------------------------------------------------
+
+// AlertStatus is the state of an alert rule.
+type AlertStatus struct {
+	Name   string  `json:"name"`            // name of alert
+	Rule   string  `json:"rule"`            // rule in text form
+	Value  float64 `json:"value"`           // last observed value
+	Firing bool    `json:"firing"`          // rule is violated
+	Since  string  `json:"since,omitempty"` // start of violation
+}
+
+// alert is a rule with its state
+type alert struct {
+	rule   *config.AlertRule
+	window time.Duration
+	status AlertStatus
+}
+
+// sample of all metrics at a given time
+type sample struct {
+	t time.Time
+	m map[string]float64
+}
+
+// Alerter checks alert rules on the metrics of a service.
+type Alerter struct {
+	sync.Mutex
+
+	name    string        // service name
+	webhook string        // notification URL (or empty)
+	alerts  []*alert      // alert rules with state
+	samples []*sample     // metric history (oldest first)
+	span    time.Duration // longest window of all rules
+	notify  func(ctx context.Context, st *AlertStatus) error
+}
+
+// NewAlerter creates an alerter for the named service from the
+// configuration.
+func NewAlerter(name string, cfg *config.AlertConfig) (a *Alerter, err error) {
+	a = &Alerter{
+		name:    name,
+		webhook: cfg.Webhook,
+	}
+	a.notify = a.post
+	for _, r := range cfg.Rules {
+		if len(r.Name) == 0 || len(r.Metric) == 0 || (r.Op != "<" && r.Op != ">") || r.Window < 0 {
+			return nil, fmt.Errorf("%w: %+v", ErrAlertRule, *r)
+		}
+		al := &alert{
+			rule:   r,
+			window: time.Duration(r.Window) * time.Second,
+		}
+		al.status.Name = r.Name
+		al.status.Rule = fmt.Sprintf("%s %s %g over %s", r.Metric, r.Op, r.Threshold, al.window)
+		if len(r.Per) > 0 {
+			al.status.Rule = fmt.Sprintf("%s/%s %s %g over %s", r.Metric, r.Per, r.Op, r.Threshold, al.window)
+		}
+		if al.window > a.span {
+			a.span = al.window
+		}
+		a.alerts = append(a.alerts, al)
+	}
+	return
+}
+
+// Check samples the metrics and evaluates all rules.
+func (a *Alerter) Check(ctx context.Context) error {
+	return a.check(ctx, &sample{t: time.Now(), m: Metrics()})
+}
+
+// check rules with a new sample
+func (a *Alerter) check(ctx context.Context, s *sample) error {
+	a.Lock()
+	// add sample to history; keep the newest sample older than the
+	// longest window as reference.
+	a.samples = append(a.samples, s)
+	for len(a.samples) > 1 && !a.samples[1].t.After(s.t.Add(-a.span)) {
+		a.samples = a.samples[1:]
+	}
+	var changed []AlertStatus
+	for _, al := range a.alerts {
+		val, violated, ok := a.evaluate(al, s.t)
+		if !ok {
+			continue
+		}
+		al.status.Value = val
+		if violated == al.status.Firing {
+			continue
+		}
+		al.status.Firing = violated
+		al.status.Since = ""
+		if violated {
+			al.status.Since = s.t.UTC().Format(time.RFC3339)
+			logger.Printf(logger.WARN, "[%s] alert '%s': %s (value %g)", a.name, al.status.Name, al.status.Rule, val)
+		} else {
+			logger.Printf(logger.INFO, "[%s] alert '%s' resolved (value %g)", a.name, al.status.Name, val)
+		}
+		changed = append(changed, al.status)
+	}
+	a.Unlock()
+
+	// send notifications
+	var err error
+	for i := range changed {
+		if nErr := a.notify(ctx, &changed[i]); nErr != nil {
+			logger.Printf(logger.ERROR, "[%s] alert '%s': %s", a.name, changed[i].Name, nErr.Error())
+			err = nErr
+		}
+	}
+	return err
+}
+
+// evaluate a rule on the metric history; returns false if there is not
+// enough data (history shorter than the window, no events for a ratio or
+// unknown metric).
+func (a *Alerter) evaluate(al *alert, now time.Time) (val float64, violated, ok bool) {
+	r := al.rule
+	cmp := func(v float64) bool {
+		if r.Op == "<" {
+			return v < r.Threshold
+		}
+		return v > r.Threshold
+	}
+	// find reference sample (start of window)
+	from := now.Add(-al.window)
+	ref := -1
+	for i, s := range a.samples {
+		if s.t.After(from) {
+			break
+		}
+		ref = i
+	}
+	if ref < 0 {
+		return
+	}
+	cur := a.samples[len(a.samples)-1]
+	if len(r.Per) > 0 {
+		// ratio of counter increments within window
+		base := a.samples[ref]
+		n := cur.m[r.Per] - base.m[r.Per]
+		if n <= 0 {
+			return
+		}
+		val = (cur.m[r.Metric] - base.m[r.Metric]) / n
+		return val, cmp(val), true
+	}
+	// metric value must violate the threshold for the whole window
+	// (metrics of modules not running in this process are skipped)
+	if _, ok = cur.m[r.Metric]; !ok {
+		return
+	}
+	violated = true
+	for _, s := range a.samples[ref:] {
+		if !cmp(s.m[r.Metric]) {
+			violated = false
+			break
+		}
+	}
+	return cur.m[r.Metric], violated, true
+}
+
+// Status returns the state of all alert rules (sorted by name).
+func (a *Alerter) Status() []AlertStatus {
+	a.Lock()
+	defer a.Unlock()
+	out := make([]AlertStatus, 0, len(a.alerts))
+	for _, al := range a.alerts {
+		out = append(out, al.status)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Degraded returns true if an alert is firing.
+func (a *Alerter) Degraded() bool {
+	a.Lock()
+	defer a.Unlock()
+	for _, al := range a.alerts {
+		if al.status.Firing {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookNotification is posted to the webhook on alert state changes.
+type webhookNotification struct {
+	Service string `json:"service"`
+	AlertStatus
+}
+
+// post an alert state change to the webhook (if configured). In
+// local-only mode only local webhooks are used.
+func (a *Alerter) post(ctx context.Context, st *AlertStatus) error {
+	if len(a.webhook) == 0 {
+		return nil
+	}
+	u, err := url.Parse(a.webhook)
+	if err != nil {
+		return err
+	}
+	if transport.LocalOnly {
+		ip, err := net.ResolveIPAddr("ip", u.Hostname())
+		if err != nil || !transport.IsLocalAddress(ip) {
+			return transport.ErrTransNotLocal
+		}
+	}
+	body, err := json.Marshal(&webhookNotification{Service: a.name, AlertStatus: *st})
+	if err != nil {
+		return err
+	}
+	hctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(hctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%w: %s", ErrAlertWebhook, resp.Status)
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// alerter of this process (or nil)
+var (
+	alerter     *Alerter
+	alerterLock sync.Mutex
+)
+
+// StartAlerts checks the configured alert rules periodically (job
+// "<name>:alerts") until the context is cancelled. Does nothing if no
+// rules are configured.
+func StartAlerts(ctx context.Context, name string, cfg *config.AlertConfig) error {
+	if cfg == nil || len(cfg.Rules) == 0 {
+		return nil
+	}
+	a, err := NewAlerter(name, cfg)
+	if err != nil {
+		return err
+	}
+	period := DefaultAlertPeriod
+	if cfg.Period > 0 {
+		period = time.Duration(cfg.Period) * time.Second
+	}
+	alerterLock.Lock()
+	alerter = a
+	alerterLock.Unlock()
+	return Schedule(ctx, name+":alerts", period, a.Check)
+}
+
+// Alerts returns the state of the alert rules of this process and
+// whether the service is degraded (an alert is firing).
+func Alerts() (list []AlertStatus, degraded bool) {
+	alerterLock.Lock()
+	a := alerter
+	alerterLock.Unlock()
+	if a == nil {
+		return nil, false
+	}
+	return a.Status(), a.Degraded()
+}
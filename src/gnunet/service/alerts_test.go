@@ -0,0 +1,112 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"gnunet/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAlertRules(t *testing.T) {
+	cfg := &config.AlertConfig{
+		Rules: []*config.AlertRule{
+			{Name: "get-success", Metric: "test:get-found", Per: "test:get", Op: "<", Threshold: 0.8, Window: 600},
+			{Name: "no-peers", Metric: "test:peers", Op: "<", Threshold: 1, Window: 300},
+			{Name: "other", Metric: "other:peers", Op: "<", Threshold: 1, Window: 0},
+		},
+	}
+	a, err := NewAlerter("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified []AlertStatus
+	a.notify = func(_ context.Context, st *AlertStatus) error {
+		notified = append(notified, *st)
+		return nil
+	}
+	start := time.Now()
+	step := func(min int, get, found, peers float64) {
+		s := &sample{
+			t: start.Add(time.Duration(min) * time.Minute),
+			m: map[string]float64{"test:get": get, "test:get-found": found, "test:peers": peers},
+		}
+		if err := a.check(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// no peers for less than the window: no alert
+	for i := 0; i < 5; i++ {
+		step(i, 0, 0, 0)
+	}
+	if a.Degraded() || len(notified) != 0 {
+		t.Fatalf("early alert: %v", notified)
+	}
+	// no peers for the whole window
+	step(5, 0, 0, 0)
+	if !a.Degraded() || len(notified) != 1 || notified[0].Name != "no-peers" {
+		t.Fatalf("no alert for missing peers: %v", notified)
+	}
+	// peers connected; GET success rate over the last 10 minutes (60%)
+	for i := 6; i < 11; i++ {
+		step(i, float64(10*(i-5)), float64(6*(i-5)), 3)
+	}
+	list := a.Status()
+	if len(list) != 3 || !list[0].Firing || list[1].Firing || list[2].Firing {
+		t.Fatalf("unexpected status %v", list)
+	}
+	if list[0].Value != 0.6 {
+		t.Fatalf("unexpected success rate %g", list[0].Value)
+	}
+	// success rate recovers
+	step(25, 150, 130, 3)
+	if a.Degraded() || len(notified) != 4 {
+		t.Fatalf("alerts not resolved: %v", notified)
+	}
+}
+
+func TestAlertWebhook(t *testing.T) {
+	got := make(chan *webhookNotification, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := new(webhookNotification)
+		if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got <- n
+	}))
+	defer srv.Close()
+
+	a, err := NewAlerter("test", &config.AlertConfig{Webhook: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &AlertStatus{Name: "no-peers", Rule: "core:peers < 1 over 5m0s", Firing: true}
+	if err = a.post(context.Background(), st); err != nil {
+		t.Fatal(err)
+	}
+	n := <-got
+	if n.Service != "test" || n.Name != "no-peers" || !n.Firing {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+}
@@ -0,0 +1,317 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+//----------------------------------------------------------------------
+// Axolotl (double ratchet with header keys) for tunnel encryption
+//----------------------------------------------------------------------
+
+// Limits for keys of skipped (lost or re-ordered) messages
+const (
+	MaxKeyGap      = 256 // max. number of skipped messages in a chain
+	MaxSkippedKeys = 64  // max. number of stored keys of skipped messages
+)
+
+// key of a skipped message
+type skippedKey struct {
+	hk []byte // header key
+	n  uint32 // message number
+	mk []byte // message key
+}
+
+// decrypted Axolotl header
+type axHeader struct {
+	ns   uint32              // message number
+	pns  uint32              // number of messages in previous chain
+	dhrs *util.PeerPublicKey // ratchet key of sender
+}
+
+// axolotl holds the key material of a tunnel. The local peer is "Alice"
+// if its identity is larger than the identity of the remote peer; Alice
+// has to do a DH ratchet step before sending the first message.
+type axolotl struct {
+	ltPrv *ed25519.PrivateKey // long-term key of local peer
+	alice bool                // role in key exchange
+
+	kx0    *ed25519.PrivateKey // own ephemeral key
+	dhrs   *ed25519.PrivateKey // own ratchet key
+	dhrr   *ed25519.PublicKey  // remote ratchet key
+	kxPeer []byte              // remote ephemeral key of last derivation
+	auth   *crypto.HashCode    // proof of derived root key (KX_AUTH)
+
+	rk         []byte        // root key
+	hks, hkr   []byte        // header keys (send, receive)
+	nhks, nhkr []byte        // next header keys (send, receive)
+	cks, ckr   []byte        // chain keys (send, receive)
+	ns, nr     uint32        // message numbers (send, receive)
+	pns        uint32        // number of messages in previous send chain
+	ratchet    bool          // DH ratchet step before next send
+	skipped    []*skippedKey // keys of skipped messages
+}
+
+// newAxolotl creates the key material for a tunnel between two peers.
+func newAxolotl(prv *ed25519.PrivateKey, local, remote *util.PeerID) *axolotl {
+	_, kx0 := ed25519.NewKeypair()
+	_, dhrs := ed25519.NewKeypair()
+	return &axolotl{
+		ltPrv: prv,
+		alice: bytes.Compare(local.Data, remote.Data) > 0,
+		kx0:   kx0,
+		dhrs:  dhrs,
+	}
+}
+
+// keys returns the own ephemeral and ratchet key for KX messages.
+func (ax *axolotl) keys() (eph, ratchet *util.PeerPublicKey) {
+	eph = util.NewPeerPublicKey(ax.kx0.Public().Bytes())
+	ratchet = util.NewPeerPublicKey(ax.dhrs.Public().Bytes())
+	return
+}
+
+// ready returns true if keys have been derived.
+func (ax *axolotl) ready() bool {
+	return ax.rk != nil
+}
+
+// derive the tunnel keys from the long-term and ephemeral keys of both
+// peers (3-DH). Returns false if the keys have been derived from the
+// same remote ephemeral key before.
+func (ax *axolotl) derive(remote *util.PeerID, eph, ratchet []byte) (bool, error) {
+	if bytes.Equal(eph, ax.kxPeer) {
+		return false, nil
+	}
+	if len(eph) != 32 || len(ratchet) != 32 {
+		return false, ErrInvalidKey
+	}
+	ePub := ed25519.NewPublicKeyFromBytes(eph)
+	rPub := ed25519.NewPublicKeyFromBytes(ratchet)
+	lPub := ed25519.NewPublicKeyFromBytes(remote.Data)
+	if ePub == nil || rPub == nil || lPub == nil {
+		return false, ErrInvalidKey
+	}
+	var m0, m1 *crypto.HashCode
+	if ax.alice {
+		m0 = crypto.SharedSecret(ax.ltPrv, ePub)
+		m1 = crypto.SharedSecret(ax.kx0, lPub)
+	} else {
+		m0 = crypto.SharedSecret(ax.kx0, lPub)
+		m1 = crypto.SharedSecret(ax.ltPrv, ePub)
+	}
+	m2 := crypto.SharedSecret(ax.kx0, ePub)
+	ikm := bytes.Join([][]byte{m0.Data, m1.Data, m2.Data}, nil)
+	keys := kdf(ikm, nil, "gnunet-cadet-axolotl-kx", 5)
+
+	ax.rk = keys[0]
+	ax.hks, ax.hkr, ax.cks, ax.ckr = nil, nil, nil, nil
+	if ax.alice {
+		ax.hkr, ax.nhks, ax.nhkr, ax.ckr = keys[1], keys[2], keys[3], keys[4]
+	} else {
+		ax.hks, ax.nhkr, ax.nhks, ax.cks = keys[1], keys[2], keys[3], keys[4]
+	}
+	ax.ratchet = ax.alice
+	ax.dhrr = rPub
+	ax.ns, ax.nr, ax.pns = 0, 0, 0
+	ax.skipped = nil
+	ax.kxPeer = util.Clone(eph)
+	ax.auth = crypto.Hash(ax.rk)
+	return true, nil
+}
+
+// encrypt a message: returns HMAC, encrypted header and payload.
+func (ax *axolotl) encrypt(payload []byte) (mac, hdr, enc []byte, err error) {
+	if !ax.ready() {
+		err = ErrTunnelNotReady
+		return
+	}
+	if ax.ratchet {
+		// DH ratchet step with new own ratchet key
+		_, ax.dhrs = ed25519.NewKeypair()
+		ax.pns, ax.ns = ax.ns, 0
+		ax.hks = ax.nhks
+		ax.rk, ax.nhks, ax.cks = ratchetKeys(ax.rk, ax.dhrs, ax.dhrr)
+		ax.ratchet = false
+	}
+	var mk []byte
+	mk, ax.cks = chainStep(ax.cks)
+	enc = aesCTR(mk, make([]byte, aes.BlockSize), payload)
+
+	// header (encrypted with synthetic IV from HMAC)
+	plain := make([]byte, message.CadetAxHeaderSize)
+	binary.BigEndian.PutUint32(plain[0:], ax.ns)
+	binary.BigEndian.PutUint32(plain[4:], ax.pns)
+	copy(plain[8:], ax.dhrs.Public().Bytes())
+	ax.ns++
+	mac = hmacSum(ax.hks, plain, enc)
+	hdr = aesCTR(ax.hks, mac[:aes.BlockSize], plain)
+	return
+}
+
+// decrypt a message. The key state is only changed if the message was
+// decrypted successfully.
+func (ax *axolotl) decrypt(mac, hdr, enc []byte) ([]byte, error) {
+	// try keys of skipped messages first
+	for i, sk := range ax.skipped {
+		if h := openHeader(sk.hk, mac, hdr, enc); h != nil && h.ns == sk.n {
+			ax.skipped = append(ax.skipped[:i:i], ax.skipped[i+1:]...)
+			return aesCTR(sk.mk, make([]byte, aes.BlockSize), enc), nil
+		}
+	}
+	// work on a copy of the key state
+	tmp := *ax
+	tmp.skipped = append([]*skippedKey(nil), ax.skipped...)
+
+	h := openHeader(tmp.hkr, mac, hdr, enc)
+	if h == nil {
+		// DH ratchet step with new remote ratchet key
+		if h = openHeader(tmp.nhkr, mac, hdr, enc); h == nil {
+			return nil, ErrUndecryptable
+		}
+		if err := tmp.skip(h.pns); err != nil {
+			return nil, err
+		}
+		dhrr := ed25519.NewPublicKeyFromBytes(h.dhrs.Data)
+		if dhrr == nil {
+			return nil, ErrInvalidKey
+		}
+		tmp.hkr = tmp.nhkr
+		tmp.rk, tmp.nhkr, tmp.ckr = ratchetKeys(tmp.rk, tmp.dhrs, dhrr)
+		tmp.dhrr = dhrr
+		tmp.nr = 0
+		tmp.ratchet = true
+	}
+	if h.ns < tmp.nr {
+		// replayed message (key not stored as skipped)
+		return nil, ErrUndecryptable
+	}
+	if err := tmp.skip(h.ns); err != nil {
+		return nil, err
+	}
+	var mk []byte
+	mk, tmp.ckr = chainStep(tmp.ckr)
+	tmp.nr++
+	*ax = tmp
+	return aesCTR(mk, make([]byte, aes.BlockSize), enc), nil
+}
+
+// skip messages in the current receive chain up to a message number
+// (exclusive) and store their keys.
+func (ax *axolotl) skip(until uint32) error {
+	if ax.ckr == nil || until <= ax.nr {
+		return nil
+	}
+	if until-ax.nr > MaxKeyGap {
+		return ErrKeyGap
+	}
+	for ; ax.nr < until; ax.nr++ {
+		var mk []byte
+		mk, ax.ckr = chainStep(ax.ckr)
+		ax.skipped = append(ax.skipped, &skippedKey{hk: ax.hkr, n: ax.nr, mk: mk})
+	}
+	if n := len(ax.skipped); n > MaxSkippedKeys {
+		ax.skipped = ax.skipped[n-MaxSkippedKeys:]
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Helpers
+//----------------------------------------------------------------------
+
+// openHeader decrypts and authenticates a header with a header key.
+// Returns nil if the message was not sent with this header key.
+func openHeader(hk, mac, hdr, enc []byte) *axHeader {
+	if hk == nil || len(mac) < aes.BlockSize || len(hdr) != message.CadetAxHeaderSize {
+		return nil
+	}
+	plain := aesCTR(hk, mac[:aes.BlockSize], hdr)
+	if !hmac.Equal(mac, hmacSum(hk, plain, enc)) {
+		return nil
+	}
+	return &axHeader{
+		ns:   binary.BigEndian.Uint32(plain[0:]),
+		pns:  binary.BigEndian.Uint32(plain[4:]),
+		dhrs: util.NewPeerPublicKey(plain[8:]),
+	}
+}
+
+// kdf derives a number of 256-bit keys from key material.
+func kdf(ikm, salt []byte, info string, n int) [][]byte {
+	r := hkdf.New(sha512.New, ikm, salt, []byte(info))
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, 32)
+		if _, err := io.ReadFull(r, keys[i]); err != nil {
+			// can't happen for the small number of keys we need
+			panic(err)
+		}
+	}
+	return keys
+}
+
+// ratchetKeys returns new root, next header and chain keys for a DH
+// ratchet step.
+func ratchetKeys(rk []byte, prv *ed25519.PrivateKey, pub *ed25519.PublicKey) (rkNew, nhk, ck []byte) {
+	ss := crypto.SharedSecret(prv, pub)
+	keys := kdf(ss.Data, rk, "gnunet-cadet-axolotl-ratchet", 3)
+	return keys[0], keys[1], keys[2]
+}
+
+// chainStep returns the message key and the next chain key.
+func chainStep(ck []byte) (mk, next []byte) {
+	return hmacSum(ck, []byte("0")), hmacSum(ck, []byte("1"))
+}
+
+// hmacSum computes a HMAC-SHA256 over data blocks.
+func hmacSum(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// aesCTR en- or decrypts data with AES-256 in counter mode.
+func aesCTR(key, iv, data []byte) []byte {
+	blk, err := aes.NewCipher(key)
+	if err != nil {
+		// keys are always 256 bits
+		panic(err)
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(blk, iv).XORKeyStream(out, data)
+	return out
+}
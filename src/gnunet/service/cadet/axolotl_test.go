@@ -0,0 +1,160 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+)
+
+// newPeer creates a peer key and identity.
+func newPeer() (*ed25519.PrivateKey, *util.PeerID) {
+	_, prv := ed25519.NewKeypair()
+	return prv, util.NewPeerID(prv.Public().Bytes())
+}
+
+// newAxolotlPair returns the key material of both ends of a tunnel after
+// the key exchange.
+func newAxolotlPair(t *testing.T) (a, b *axolotl) {
+	t.Helper()
+	prvA, idA := newPeer()
+	prvB, idB := newPeer()
+	a = newAxolotl(prvA, idA, idB)
+	b = newAxolotl(prvB, idB, idA)
+	ea, ra := a.keys()
+	eb, rb := b.keys()
+	if ok, err := a.derive(idB, eb.Data, rb.Data); !ok || err != nil {
+		t.Fatalf("derive: %v, %v", ok, err)
+	}
+	if ok, err := b.derive(idA, ea.Data, ra.Data); !ok || err != nil {
+		t.Fatalf("derive: %v, %v", ok, err)
+	}
+	if a.alice == b.alice {
+		t.Fatal("both peers have the same role")
+	}
+	return
+}
+
+// transfer a message between two peers.
+func transfer(t *testing.T, from, to *axolotl, text string) {
+	t.Helper()
+	mac, hdr, enc, err := from.encrypt([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := to.decrypt(mac, hdr, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != text {
+		t.Fatalf("decrypted '%s' != '%s'", out, text)
+	}
+}
+
+func TestAxolotlKX(t *testing.T) {
+	a, b := newAxolotlPair(t)
+	if !bytes.Equal(a.rk, b.rk) || !a.auth.Equal(b.auth) {
+		t.Fatal("derived keys differ")
+	}
+	// derivation from the same ephemeral key is a no-op
+	prv, id := newPeer()
+	c := newAxolotl(prv, id, id)
+	eph, ratchet := c.keys()
+	if ok, err := c.derive(id, eph.Data, ratchet.Data); !ok || err != nil {
+		t.Fatalf("derive: %v, %v", ok, err)
+	}
+	if ok, _ := c.derive(id, eph.Data, ratchet.Data); ok {
+		t.Fatal("keys derived twice")
+	}
+	// invalid keys are rejected
+	if _, err := c.derive(id, make([]byte, 31), ratchet.Data); err != ErrInvalidKey {
+		t.Fatalf("invalid key accepted: %v", err)
+	}
+}
+
+func TestAxolotlExchange(t *testing.T) {
+	a, b := newAxolotlPair(t)
+	// both directions with ratchet steps in between
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 3; j++ {
+			transfer(t, a, b, fmt.Sprintf("a->b %d/%d", i, j))
+		}
+		for j := 0; j < 2; j++ {
+			transfer(t, b, a, fmt.Sprintf("b->a %d/%d", i, j))
+		}
+	}
+}
+
+func TestAxolotlOutOfOrder(t *testing.T) {
+	a, b := newAxolotlPair(t)
+	type msg struct{ mac, hdr, enc []byte }
+	var list []msg
+	for i := 0; i < 3; i++ {
+		mac, hdr, enc, err := a.encrypt([]byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, msg{mac, hdr, enc})
+	}
+	for _, i := range []int{2, 0, 1} {
+		out, err := b.decrypt(list[i].mac, list[i].hdr, list[i].enc)
+		if err != nil {
+			t.Fatalf("message %d: %s", i, err.Error())
+		}
+		if out[0] != byte(i) {
+			t.Fatalf("message %d decrypted as %d", i, out[0])
+		}
+	}
+	// replayed messages are rejected
+	if _, err := b.decrypt(list[0].mac, list[0].hdr, list[0].enc); err == nil {
+		t.Fatal("replayed message accepted")
+	}
+	// lost messages of the previous chain survive a ratchet step
+	transfer(t, b, a, "ratchet")
+	mac, hdr, enc, _ := a.encrypt([]byte("lost"))
+	transfer(t, b, a, "ratchet again")
+	transfer(t, a, b, "next chain")
+	out, err := b.decrypt(mac, hdr, enc)
+	if err != nil || string(out) != "lost" {
+		t.Fatalf("lost message: '%s', %v", out, err)
+	}
+}
+
+func TestAxolotlTamper(t *testing.T) {
+	a, b := newAxolotlPair(t)
+	mac, hdr, enc, err := a.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := util.Clone(enc)
+	bad[0] ^= 1
+	if _, err = b.decrypt(mac, hdr, bad); err != ErrUndecryptable {
+		t.Fatalf("tampered message: %v", err)
+	}
+	// key state is unchanged
+	out, err := b.decrypt(mac, hdr, enc)
+	if err != nil || string(out) != "payload" {
+		t.Fatalf("message: '%s', %v", out, err)
+	}
+}
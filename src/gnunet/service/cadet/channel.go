@@ -0,0 +1,259 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Channels: reliable, ordered message streams in a tunnel
+//----------------------------------------------------------------------
+
+// Channel parameters
+var (
+	ChannelWindow   = 64          // max. number of unacknowledged messages
+	RetransmitDelay = time.Second // time before unacknowledged data is sent again
+)
+
+// unacknowledged data message
+type pending struct {
+	data []byte    // application data
+	sent time.Time // time of last transmission
+}
+
+// Channel to a port of a remote peer. All fields (except the channels
+// used for signalling) are guarded by the module lock.
+type Channel struct {
+	m    *Module          // reference to CADET module
+	t    *Tunnel          // tunnel of channel
+	ctn  uint32           // channel number in tunnel
+	port *crypto.HashCode // (hashed) port
+	out  bool             // channel opened by local peer
+
+	opened chan struct{} // closed when the channel is open
+	done   chan struct{} // closed when the channel is destroyed
+	window chan struct{} // slots for unacknowledged messages
+	notify chan struct{} // signals received data
+	isOpen bool          // channel is open
+	err    error         // reason for destruction (nil = closed)
+
+	lastMID uint32              // last message identifier sent
+	unacked map[uint32]*pending // unacknowledged messages
+	recvMID uint32              // last message identifier delivered
+	future  map[uint32][]byte   // messages received out of order
+	queue   [][]byte            // delivered messages not yet read
+}
+
+// newChannel creates a channel in a tunnel.
+func (m *Module) newChannel(t *Tunnel, ctn uint32, port *crypto.HashCode) *Channel {
+	ch := &Channel{
+		m:       m,
+		t:       t,
+		ctn:     ctn,
+		port:    port,
+		opened:  make(chan struct{}),
+		done:    make(chan struct{}),
+		window:  make(chan struct{}, ChannelWindow),
+		notify:  make(chan struct{}, 1),
+		unacked: make(map[uint32]*pending),
+		future:  make(map[uint32][]byte),
+	}
+	t.channels[ctn] = ch
+	return ch
+}
+
+// Peer returns the remote peer of the channel.
+func (ch *Channel) Peer() *util.PeerID {
+	return ch.t.peer
+}
+
+// Send data on the channel. Blocks if the window of unacknowledged
+// messages is full.
+func (ch *Channel) Send(ctx context.Context, data []byte) error {
+	select {
+	case <-ch.opened:
+	case <-ch.done:
+		return ch.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case ch.window <- struct{}{}:
+	case <-ch.done:
+		return ch.closedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var b batch
+	ch.m.mtx.Lock()
+	if ch.isClosed() {
+		ch.m.mtx.Unlock()
+		return ch.closedErr()
+	}
+	ch.lastMID++
+	ch.unacked[ch.lastMID] = &pending{data: util.Clone(data), sent: time.Now()}
+	ch.m.send(ch.t, message.NewCadetChannelAppDataMsg(ch.ctn, ch.lastMID, data), &b)
+	ch.m.mtx.Unlock()
+
+	ch.m.flush(ctx, b)
+	return nil
+}
+
+// Recv returns the next message received on the channel (io.EOF if the
+// channel was closed).
+func (ch *Channel) Recv(ctx context.Context) ([]byte, error) {
+	for {
+		ch.m.mtx.Lock()
+		if len(ch.queue) > 0 {
+			data := ch.queue[0]
+			ch.queue = ch.queue[1:]
+			// messages held back by a full queue can be delivered
+			// (and acknowledged) now.
+			var b batch
+			if ch.deliver() {
+				ch.m.send(ch.t, message.NewCadetChannelDataAckMsg(ch.ctn, ch.recvMID), &b)
+			}
+			ch.m.mtx.Unlock()
+			ch.m.flush(ctx, b)
+			return data, nil
+		}
+		closed := ch.isClosed()
+		ch.m.mtx.Unlock()
+		if closed {
+			return nil, ch.closedErr()
+		}
+		select {
+		case <-ch.notify:
+		case <-ch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close the channel.
+func (ch *Channel) Close() {
+	var b batch
+	ch.m.mtx.Lock()
+	if !ch.isClosed() {
+		ch.m.send(ch.t, message.NewCadetChannelDestroyMsg(ch.ctn), &b)
+		ch.closeLocked(nil)
+	}
+	ch.m.mtx.Unlock()
+	ch.m.flush(context.Background(), b)
+}
+
+// isClosed returns true if the channel is destroyed.
+func (ch *Channel) isClosed() bool {
+	select {
+	case <-ch.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// closedErr returns the reason for a destroyed channel.
+func (ch *Channel) closedErr() error {
+	ch.m.mtx.Lock()
+	defer ch.m.mtx.Unlock()
+	if ch.err != nil {
+		return ch.err
+	}
+	return io.EOF
+}
+
+// closeLocked destroys the channel (module locked).
+func (ch *Channel) closeLocked(reason error) {
+	if ch.isClosed() {
+		return
+	}
+	ch.err = reason
+	close(ch.done)
+	delete(ch.t.channels, ch.ctn)
+}
+
+// setOpen marks the channel as open (module locked).
+func (ch *Channel) setOpen() {
+	if !ch.isOpen {
+		ch.isOpen = true
+		close(ch.opened)
+	}
+}
+
+// receive a data message: messages are delivered in order; the last
+// message delivered is acknowledged (module locked).
+func (ch *Channel) receive(msg *message.CadetChannelAppDataMsg, b *batch) {
+	mid := msg.MID
+	if mid > ch.recvMID && mid <= ch.recvMID+uint32(ChannelWindow) {
+		ch.future[mid] = msg.Data
+		ch.deliver()
+	}
+	ch.m.send(ch.t, message.NewCadetChannelDataAckMsg(ch.ctn, ch.recvMID), b)
+}
+
+// deliver messages received in order to the queue while it has room;
+// returns true if messages were delivered (module locked).
+func (ch *Channel) deliver() bool {
+	n := 0
+	for len(ch.queue) < ChannelWindow {
+		data, ok := ch.future[ch.recvMID+1]
+		if !ok {
+			break
+		}
+		delete(ch.future, ch.recvMID+1)
+		ch.recvMID++
+		ch.queue = append(ch.queue, data)
+		n++
+	}
+	if n > 0 {
+		select {
+		case ch.notify <- struct{}{}:
+		default:
+		}
+	}
+	return n > 0
+}
+
+// ack removes acknowledged messages (module locked).
+func (ch *Channel) ack(mid uint32) {
+	for id := range ch.unacked {
+		if id <= mid {
+			delete(ch.unacked, id)
+			<-ch.window
+		}
+	}
+}
+
+// retransmit unacknowledged messages (module locked).
+func (ch *Channel) retransmit(now time.Time, b *batch) {
+	for mid, p := range ch.unacked {
+		if now.Sub(p.sent) >= RetransmitDelay {
+			p.sent = now
+			ch.m.send(ch.t, message.NewCadetChannelAppDataMsg(ch.ctn, mid, p.data), b)
+		}
+	}
+}
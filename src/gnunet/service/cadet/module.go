@@ -0,0 +1,490 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/logger"
+)
+
+//======================================================================
+// "GNUnet CADET" implementation
+//======================================================================
+
+// Error codes
+var (
+	ErrInvalidKey     = errors.New("invalid public key")
+	ErrTunnelNotReady = errors.New("tunnel keys not available")
+	ErrUndecryptable  = errors.New("message can't be decrypted")
+	ErrKeyGap         = errors.New("too many skipped messages")
+	ErrPortInUse      = errors.New("port already open")
+	ErrChannelRefused = errors.New("channel refused by peer")
+	ErrTunnelLost     = errors.New("tunnel to peer lost")
+	ErrSelf           = errors.New("can't open channel to self")
+)
+
+// Core is the set of core services used by the CADET module. It is
+// implemented by core.Core (and by mocks in unit tests).
+type Core interface {
+	PeerID() *util.PeerID
+	Send(ctx context.Context, peer *util.PeerID, msg message.Message) error
+	Register(name string, l *core.Listener)
+}
+
+// open port with handler for incoming channels
+type port struct {
+	port   *crypto.HashCode // port (as opened by the application)
+	accept func(*Channel)   // handler for incoming channels
+}
+
+// PortHash returns the port identifier used in CHANNEL_OPEN messages:
+// the application port is bound to the destination peer.
+func PortHash(port *crypto.HashCode, peer *util.PeerID) *crypto.HashCode {
+	return crypto.Hash(append(util.Clone(port.Data), peer.Data...))
+}
+
+// Module maintains end-to-end encrypted tunnels to connected peers and
+// the channels between applications running on the peers.
+type Module struct {
+	service.ModuleImpl
+
+	core Core                // reference to core services
+	prv  *ed25519.PrivateKey // long-term key of peer
+
+	mtx     *sync.Mutex        // lock for tunnels, connections and ports
+	tunnels map[string]*Tunnel // tunnels by peer
+	conns   map[string]*Tunnel // tunnels by connection identifier
+	ports   map[string]*port   // open ports by hashed port
+}
+
+// NewModule returns a new CADET module.
+func NewModule(ctx context.Context, c *core.Core) *Module {
+	m := newModule(c, c.Peer().PrvKey())
+
+	// register as listener for core events
+	listener := m.Run(ctx, m.event, m.Filter())
+	c.Register("cadet", listener)
+
+	// retransmit unacknowledged messages
+	if err := service.Schedule(ctx, "cadet:retransmit", RetransmitDelay, m.retransmit); err != nil {
+		logger.Printf(logger.ERROR, "[cadet] job 'cadet:retransmit' not scheduled: %s", err.Error())
+	}
+	return m
+}
+
+// newModule assembles a module without registering it with core or
+// scheduling jobs.
+func newModule(c Core, prv *ed25519.PrivateKey) *Module {
+	return &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		core:       c,
+		prv:        prv,
+		mtx:        new(sync.Mutex),
+		tunnels:    make(map[string]*Tunnel),
+		conns:      make(map[string]*Tunnel),
+		ports:      make(map[string]*port),
+	}
+}
+
+// tunnel returns the tunnel to a peer (created if missing).
+func (m *Module) tunnel(peer *util.PeerID) *Tunnel {
+	key := peer.String()
+	t, ok := m.tunnels[key]
+	if !ok {
+		t = m.newTunnel(peer)
+		m.tunnels[key] = t
+	}
+	return t
+}
+
+// flush sends a batch of messages (module not locked).
+func (m *Module) flush(ctx context.Context, b batch) {
+	for _, out := range b {
+		if err := m.core.Send(ctx, out.peer, out.msg); err != nil {
+			logger.Printf(logger.WARN, "[cadet] failed to send %s to %s: %s", out.msg.Type(), out.peer.Short(), err.Error())
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Channel API
+//----------------------------------------------------------------------
+
+// OpenPort listens for incoming channels on a port ["cadet:port"]. The
+// accept handler is called for every new channel.
+func (m *Module) OpenPort(p *crypto.HashCode, accept func(*Channel)) error {
+	key := PortHash(p, m.core.PeerID()).String()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.ports[key]; ok {
+		return ErrPortInUse
+	}
+	m.ports[key] = &port{port: p, accept: accept}
+	logger.Printf(logger.INFO, "[cadet] port %s opened", p.Short())
+	return nil
+}
+
+// ClosePort stops listening on a port. Open channels are not affected.
+func (m *Module) ClosePort(p *crypto.HashCode) {
+	key := PortHash(p, m.core.PeerID()).String()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.ports[key]; ok {
+		delete(m.ports, key)
+		logger.Printf(logger.INFO, "[cadet] port %s closed", p.Short())
+	}
+}
+
+// Open a channel to a port on a peer ["cadet:open"]. Returns when the
+// channel is confirmed by the peer.
+func (m *Module) Open(ctx context.Context, peer *util.PeerID, p *crypto.HashCode) (*Channel, error) {
+	if peer.Equal(m.core.PeerID()) {
+		return nil, ErrSelf
+	}
+	var b batch
+	m.mtx.Lock()
+	t := m.tunnel(peer)
+	ch := m.newChannel(t, t.nextCTN(), PortHash(p, peer))
+	ch.out = true
+	m.send(t, message.NewCadetChannelOpenMsg(ch.ctn, ch.port, 0), &b)
+	m.mtx.Unlock()
+	m.flush(ctx, b)
+
+	select {
+	case <-ch.opened:
+		logger.Printf(logger.INFO, "[cadet] channel %08x to %s opened", ch.ctn, peer.Short())
+		return ch, nil
+	case <-ch.done:
+		return nil, ch.closedErr()
+	case <-ctx.Done():
+		ch.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// TunnelInfo describes a tunnel to a peer.
+type TunnelInfo struct {
+	Peer     *util.PeerID // remote peer
+	State    string       // tunnel state
+	Channels int          // number of open channels
+}
+
+// Tunnels returns information on all tunnels (sorted by peer).
+func (m *Module) Tunnels() []*TunnelInfo {
+	m.mtx.Lock()
+	list := make([]*TunnelInfo, 0, len(m.tunnels))
+	for _, t := range m.tunnels {
+		list = append(list, &TunnelInfo{
+			Peer:     t.peer,
+			State:    TunnelStateName(t.state),
+			Channels: len(t.channels),
+		})
+	}
+	m.mtx.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Peer.String() < list[j].Peer.String()
+	})
+	return list
+}
+
+//----------------------------------------------------------------------
+// Event handling
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module
+func (m *Module) Filter() *core.EventFilter {
+	f := core.NewEventFilter()
+	f.AddEvent(core.EV_DISCONNECT)
+	f.AddMsgType(enums.MSG_CADET_CONNECTION_CREATE)
+	f.AddMsgType(enums.MSG_CADET_CONNECTION_CREATE_ACK)
+	f.AddMsgType(enums.MSG_CADET_CONNECTION_DESTROY)
+	f.AddMsgType(enums.MSG_CADET_TUNNEL_KX)
+	f.AddMsgType(enums.MSG_CADET_TUNNEL_KX_AUTH)
+	f.AddMsgType(enums.MSG_CADET_TUNNEL_ENCRYPTED)
+	return f
+}
+
+// Event handler for infrastructure signals
+func (m *Module) event(ctx context.Context, ev *core.Event) {
+	switch ev.ID {
+	case core.EV_DISCONNECT:
+		m.mtx.Lock()
+		if t, ok := m.tunnels[ev.Peer.String()]; ok {
+			logger.Printf(logger.INFO, "[cadet] peer %s disconnected (%s): tunnel destroyed", ev.Peer.Short(), ev.Reason)
+			m.destroy(t, ErrTunnelLost)
+		}
+		m.mtx.Unlock()
+	case core.EV_MESSAGE:
+		if !m.handleMessage(ctx, ev.Peer, ev.Msg) {
+			logger.Printf(logger.WARN, "[cadet] %s message from %s dropped", ev.Msg.Type(), ev.Peer.Short())
+		}
+	}
+}
+
+// handleMessage processes a message from a peer. Returns false if the
+// message was invalid.
+func (m *Module) handleMessage(ctx context.Context, sender *util.PeerID, msg message.Message) (ok bool) {
+	var (
+		b      batch
+		accept func()
+	)
+	m.mtx.Lock()
+	switch mt := msg.(type) {
+	case *message.CadetConnectionCreateMsg:
+		// only direct connections: the path is [sender, self]
+		if len(mt.Path) != 2 || !mt.Path[0].Equal(sender) || !mt.Path[1].Equal(m.core.PeerID()) {
+			b.add(sender, message.NewCadetConnectionMsg(true, mt.CID))
+			break
+		}
+		t := m.tunnel(sender)
+		key := hex.EncodeToString(mt.CID)
+		if tt, found := m.conns[key]; found && tt != t {
+			break
+		}
+		m.conns[key] = t
+		if t.cid == nil {
+			t.cid = mt.CID
+		}
+		b.add(sender, message.NewCadetConnectionMsg(false, mt.CID))
+		if t.state < TunnelKX {
+			m.startKX(t, &b)
+		}
+		ok = true
+
+	case *message.CadetConnectionMsg:
+		t := m.lookup(sender, mt.CID)
+		if t == nil {
+			break
+		}
+		if mt.Type() == enums.MSG_CADET_CONNECTION_DESTROY {
+			logger.Printf(logger.INFO, "[cadet] connection to %s destroyed by peer", sender.Short())
+			m.destroy(t, ErrTunnelLost)
+		} else if t.state == TunnelConnecting {
+			m.startKX(t, &b)
+		}
+		ok = true
+
+	case *message.CadetTunnelKXMsg:
+		ok = m.handleKX(sender, mt.CID, mt.EphemeralKey, mt.RatchetKey, nil, &b)
+
+	case *message.CadetTunnelKXAuthMsg:
+		ok = m.handleKX(sender, mt.CID, mt.EphemeralKey, mt.RatchetKey, mt.Auth, &b)
+
+	case *message.CadetTunnelEncryptedMsg:
+		accept, ok = m.handleEncrypted(sender, mt, &b)
+	}
+	m.mtx.Unlock()
+
+	m.flush(ctx, b)
+	if accept != nil {
+		accept()
+	}
+	return
+}
+
+// lookup the tunnel for a connection of a peer (module locked).
+func (m *Module) lookup(peer *util.PeerID, cid []byte) *Tunnel {
+	t, ok := m.conns[hex.EncodeToString(cid)]
+	if !ok || !t.peer.Equal(peer) {
+		return nil
+	}
+	return t
+}
+
+// handleKX derives the tunnel keys from a KX (auth == nil) or KX_AUTH
+// message (module locked).
+func (m *Module) handleKX(sender *util.PeerID, cid []byte, eph, ratchet *util.PeerPublicKey, auth *crypto.HashCode, b *batch) bool {
+	t := m.lookup(sender, cid)
+	if t == nil {
+		return false
+	}
+	fresh, err := t.ax.derive(sender, eph.Data, ratchet.Data)
+	if err != nil {
+		logger.Printf(logger.WARN, "[cadet] key exchange with %s failed: %s", sender.Short(), err.Error())
+		return false
+	}
+	if fresh && t.state == TunnelReady {
+		// the peer has restarted its end of the tunnel
+		logger.Printf(logger.INFO, "[cadet] tunnel to %s re-keyed by peer", sender.Short())
+		for _, ch := range t.channels {
+			ch.closeLocked(ErrTunnelLost)
+		}
+		t.state = TunnelKXAuth
+	}
+	own, rk := t.ax.keys()
+	if auth == nil {
+		// KX: confirm the derived keys
+		b.add(sender, message.NewCadetTunnelKXAuthMsg(t.cid, own, rk, t.ax.auth))
+		if t.state < TunnelKXAuth {
+			t.state = TunnelKXAuth
+		}
+		return true
+	}
+	// KX_AUTH: check confirmation (restart key exchange on mismatch)
+	if !auth.Equal(t.ax.auth) {
+		logger.Printf(logger.WARN, "[cadet] key confirmation from %s failed", sender.Short())
+		m.startKX(t, b)
+		return true
+	}
+	if t.state != TunnelReady {
+		// confirm our keys to the peer as well
+		b.add(sender, message.NewCadetTunnelKXAuthMsg(t.cid, own, rk, t.ax.auth))
+		m.ready(t, b)
+	}
+	return true
+}
+
+// handleEncrypted decrypts a tunnel message and processes the contained
+// channel message. Returns the accept handler of a port for a new
+// channel (module locked).
+func (m *Module) handleEncrypted(sender *util.PeerID, msg *message.CadetTunnelEncryptedMsg, b *batch) (func(), bool) {
+	t := m.lookup(sender, msg.CID)
+	if t == nil || !t.ax.ready() {
+		return nil, false
+	}
+	buf, err := t.ax.decrypt(msg.HMAC, msg.AxHeader, msg.Payload)
+	if err != nil {
+		logger.Printf(logger.WARN, "[cadet] tunnel message from %s: %s", sender.Short(), err.Error())
+		return nil, false
+	}
+	inner, err := message.Parse(buf)
+	if err != nil {
+		logger.Printf(logger.WARN, "[cadet] invalid channel message from %s: %s", sender.Short(), err.Error())
+		return nil, false
+	}
+	m.ready(t, b)
+
+	switch cm := inner.(type) {
+	case *message.CadetChannelOpenMsg:
+		if ch, ok := t.channels[cm.CTN]; ok {
+			// repeated request: confirm again
+			if !ch.out {
+				m.send(t, message.NewCadetChannelOpenAckMsg(ch.ctn, ch.port), b)
+			}
+			break
+		}
+		p, ok := m.ports[cm.Port.String()]
+		if !ok {
+			logger.Printf(logger.INFO, "[cadet] channel from %s to closed port refused", sender.Short())
+			m.send(t, message.NewCadetChannelDestroyMsg(cm.CTN), b)
+			break
+		}
+		ch := m.newChannel(t, cm.CTN, cm.Port)
+		ch.setOpen()
+		m.send(t, message.NewCadetChannelOpenAckMsg(ch.ctn, ch.port), b)
+		logger.Printf(logger.INFO, "[cadet] channel %08x from %s accepted on port %s", ch.ctn, sender.Short(), p.port.Short())
+		return func() { p.accept(ch) }, true
+
+	case *message.CadetChannelOpenAckMsg:
+		if ch, ok := t.channels[cm.CTN]; ok && ch.out {
+			ch.setOpen()
+		}
+
+	case *message.CadetChannelDestroyMsg:
+		if ch, ok := t.channels[cm.CTN]; ok {
+			var reason error
+			if !ch.isOpen {
+				reason = ErrChannelRefused
+			}
+			ch.closeLocked(reason)
+		}
+
+	case *message.CadetChannelAppDataMsg:
+		ch, ok := t.channels[cm.CTN]
+		if !ok || !ch.isOpen {
+			m.send(t, message.NewCadetChannelDestroyMsg(cm.CTN), b)
+			break
+		}
+		ch.receive(cm, b)
+
+	case *message.CadetChannelDataAckMsg:
+		if ch, ok := t.channels[cm.CTN]; ok {
+			ch.ack(cm.MID)
+		}
+
+	default:
+		logger.Printf(logger.WARN, "[cadet] unexpected %s message in tunnel from %s", inner.Type(), sender.Short())
+		return nil, false
+	}
+	return nil, true
+}
+
+//----------------------------------------------------------------------
+
+// retransmit unconfirmed connection and key exchange requests and
+// unacknowledged channel messages.
+func (m *Module) retransmit(ctx context.Context) error {
+	var b batch
+	now := time.Now()
+	m.mtx.Lock()
+	for _, t := range m.tunnels {
+		if t.state == TunnelReady {
+			for _, ch := range t.channels {
+				if ch.out && !ch.isOpen {
+					m.send(t, message.NewCadetChannelOpenMsg(ch.ctn, ch.port, 0), &b)
+				}
+				ch.retransmit(now, &b)
+			}
+			continue
+		}
+		if now.Sub(t.lastKX) < RetransmitDelay {
+			continue
+		}
+		switch t.state {
+		case TunnelConnecting:
+			t.state = TunnelNew
+			m.connect(t, &b)
+		case TunnelKX, TunnelKXAuth:
+			m.startKX(t, &b)
+		}
+	}
+	m.mtx.Unlock()
+	m.flush(ctx, b)
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Inter-module linkage helpers
+//----------------------------------------------------------------------
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["cadet:open"] = m.Open
+	fcn["cadet:port"] = m.OpenPort
+}
+
+// Import functions
+func (m *Module) Import(fcn map[string]any) {
+	// nothing to import for now.
+}
@@ -0,0 +1,279 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+//----------------------------------------------------------------------
+// Test harness: CADET modules connected by an in-memory network.
+//----------------------------------------------------------------------
+
+// message in transit
+type delivery struct {
+	sender *util.PeerID
+	msg    message.Message
+}
+
+// mockNet delivers messages in order to the modules.
+type mockNet struct {
+	sync.Mutex
+	inbox map[string]chan *delivery
+}
+
+// mockCore sends via the mock network.
+type mockCore struct {
+	id  *util.PeerID
+	net *mockNet
+}
+
+func (c *mockCore) PeerID() *util.PeerID {
+	return c.id
+}
+
+// Send a message (marshalled and parsed like on the wire).
+func (c *mockCore) Send(ctx context.Context, peer *util.PeerID, msg message.Message) error {
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	in, err := message.Parse(buf)
+	if err != nil {
+		return err
+	}
+	c.net.Lock()
+	inbox, ok := c.net.inbox[peer.String()]
+	c.net.Unlock()
+	if !ok {
+		return fmt.Errorf("peer %s not connected", peer.Short())
+	}
+	inbox <- &delivery{sender: c.id, msg: in}
+	return nil
+}
+
+func (c *mockCore) Register(name string, l *core.Listener) {}
+
+// newTestModule creates a module connected to the mock network.
+func newTestModule(ctx context.Context, n *mockNet) *Module {
+	prv, id := newPeer()
+	m := newModule(&mockCore{id: id, net: n}, prv)
+	inbox := make(chan *delivery, 1024)
+	n.Lock()
+	n.inbox[id.String()] = inbox
+	n.Unlock()
+	go func() {
+		for {
+			select {
+			case d := <-inbox:
+				m.handleMessage(ctx, d.sender, d.msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return m
+}
+
+// newTestPair returns two connected modules; the second listens on a
+// port and delivers incoming channels.
+func newTestPair(ctx context.Context) (a, b *Module, port *crypto.HashCode, accepted chan *Channel) {
+	n := &mockNet{inbox: make(map[string]chan *delivery)}
+	a = newTestModule(ctx, n)
+	b = newTestModule(ctx, n)
+	port = crypto.Hash([]byte("test-port"))
+	accepted = make(chan *Channel, 1)
+	_ = b.OpenPort(port, func(ch *Channel) { accepted <- ch })
+	return
+}
+
+//----------------------------------------------------------------------
+
+func TestChannel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, b, port, accepted := newTestPair(ctx)
+	if err := b.OpenPort(port, nil); err != ErrPortInUse {
+		t.Fatalf("port opened twice: %v", err)
+	}
+	chA, err := a.Open(ctx, b.core.PeerID(), port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chB *Channel
+	select {
+	case chB = <-accepted:
+	case <-ctx.Done():
+		t.Fatal("channel not accepted")
+	}
+	if !chB.Peer().Equal(a.core.PeerID()) {
+		t.Fatal("wrong peer on accepted channel")
+	}
+	// more messages than fit into the window (in both directions)
+	num := 2*ChannelWindow + 1
+	errCh := make(chan error, 1)
+	go func() {
+		for i := 0; i < num; i++ {
+			if err := chA.Send(ctx, []byte(fmt.Sprintf("a%d", i))); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- nil
+	}()
+	for i := 0; i < num; i++ {
+		buf, err := chB.Recv(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != fmt.Sprintf("a%d", i) {
+			t.Fatalf("received '%s' as message %d", buf, i)
+		}
+	}
+	if err = <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if err = chB.Send(ctx, []byte("reply")); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := chA.Recv(ctx); err != nil || string(buf) != "reply" {
+		t.Fatalf("reply: '%s', %v", buf, err)
+	}
+	// closing the channel ends the remote side
+	chA.Close()
+	if _, err = chB.Recv(ctx); err != io.EOF {
+		t.Fatalf("closed channel: %v", err)
+	}
+	if err = chA.Send(ctx, []byte("late")); err != io.EOF {
+		t.Fatalf("send on closed channel: %v", err)
+	}
+	if list := a.Tunnels(); len(list) != 1 || list[0].State != "ready" || list[0].Channels != 0 {
+		t.Fatalf("unexpected tunnel list: %v", list)
+	}
+}
+
+func TestChannelRefused(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, b, port, _ := newTestPair(ctx)
+	if _, err := a.Open(ctx, b.core.PeerID(), crypto.Hash([]byte("other"))); err != ErrChannelRefused {
+		t.Fatalf("channel to closed port: %v", err)
+	}
+	b.ClosePort(port)
+	if _, err := a.Open(ctx, b.core.PeerID(), port); err != ErrChannelRefused {
+		t.Fatalf("channel to closed port: %v", err)
+	}
+	if _, err := a.Open(ctx, a.core.PeerID(), port); err != ErrSelf {
+		t.Fatalf("channel to self: %v", err)
+	}
+}
+
+func TestChannelDisconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, b, port, accepted := newTestPair(ctx)
+	chA, err := a.Open(ctx, b.core.PeerID(), port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-accepted
+	a.event(ctx, &core.Event{ID: core.EV_DISCONNECT, Peer: b.core.PeerID()})
+	if _, err = chA.Recv(ctx); !errors.Is(err, ErrTunnelLost) {
+		t.Fatalf("channel after disconnect: %v", err)
+	}
+	if n := len(a.Tunnels()); n != 0 {
+		t.Fatalf("%d tunnels after disconnect", n)
+	}
+}
+
+func TestChannelHeldBack(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a, b, port, accepted := newTestPair(ctx)
+	chA, err := a.Open(ctx, b.core.PeerID(), port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chB := <-accepted
+
+	// without reading, the receive queue fills up; the next window of
+	// messages is held back (and not acknowledged).
+	num := 2*ChannelWindow + 1
+	errCh := make(chan error, 1)
+	go func() {
+		for i := 0; i < num; i++ {
+			if err := chA.Send(ctx, []byte(fmt.Sprintf("a%d", i))); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- nil
+	}()
+	state := func() (queued, held, unacked int) {
+		a.mtx.Lock()
+		unacked = len(chA.unacked)
+		a.mtx.Unlock()
+		b.mtx.Lock()
+		queued, held = len(chB.queue), len(chB.future)
+		b.mtx.Unlock()
+		return
+	}
+	queued, held, unacked := state()
+	for i := 0; i < 100 && held < ChannelWindow; i++ {
+		time.Sleep(10 * time.Millisecond)
+		queued, held, unacked = state()
+	}
+	if queued != ChannelWindow || held != ChannelWindow || unacked != ChannelWindow {
+		t.Fatalf("full queue: %d queued, %d held back, %d unacknowledged", queued, held, unacked)
+	}
+	// reading drains the queue: held-back messages are delivered and
+	// acknowledged, so the sender can finish.
+	for i := 0; i < num; i++ {
+		buf, err := chB.Recv(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != fmt.Sprintf("a%d", i) {
+			t.Fatalf("received '%s' as message %d", buf, i)
+		}
+	}
+	if err = <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && unacked > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, _, unacked = state()
+	}
+	if unacked != 0 {
+		t.Fatalf("%d messages not acknowledged", unacked)
+	}
+}
@@ -0,0 +1,76 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"net/http"
+
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for CADET-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to CADET module
+}
+
+//----------------------------------------------------------------------
+// Command "CADET.Tunnels"
+//----------------------------------------------------------------------
+
+// TunnelsRequest asks for the list of tunnels.
+type TunnelsRequest struct{}
+
+// TunnelEntry describes a tunnel to a peer.
+type TunnelEntry struct {
+	Peer     string `json:"peer"`     // remote peer
+	State    string `json:"state"`    // tunnel state
+	Channels int    `json:"channels"` // number of open channels
+}
+
+// TunnelsResponse lists the tunnels of the peer.
+type TunnelsResponse struct {
+	Tunnels []*TunnelEntry `json:"tunnels"`
+}
+
+// Tunnels returns the list of tunnels.
+func (s *RPCService) Tunnels(r *http.Request, req *TunnelsRequest, reply *TunnelsResponse) error {
+	list := make([]*TunnelEntry, 0)
+	for _, t := range s.m.Tunnels() {
+		list = append(list, &TunnelEntry{
+			Peer:     t.Peer.String(),
+			State:    t.State,
+			Channels: t.Channels,
+		})
+	}
+	*reply = TunnelsResponse{Tunnels: list}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "CADET"); err != nil {
+		logger.Printf(logger.ERROR, "[cadet] Failed to init RPC: %s", err.Error())
+	}
+}
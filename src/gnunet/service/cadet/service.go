@@ -0,0 +1,313 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet CADET" service implementation
+//----------------------------------------------------------------------
+
+// Service implements the CADET service for local clients
+type Service struct {
+	*Module
+}
+
+// NewService creates a new CADET service instance
+func NewService(ctx context.Context, c *core.Core) *Service {
+	return &Service{
+		Module: NewModule(ctx, c),
+	}
+}
+
+// client channel: data from the client is sent by a writer goroutine
+// that acknowledges each message to the client.
+type clientChannel struct {
+	ch  *Channel
+	out chan []byte
+}
+
+// session of a client: channel numbers are local to a session; channels
+// created by the service (incoming) use numbers with the high bit set.
+// A session is the responder for client messages; sends to the client
+// are serialized.
+type session struct {
+	mc  *service.Connection // client connection
+	ctx context.Context     // session context
+
+	smtx     *sync.Mutex                 // lock for sending to client
+	mtx      *sync.Mutex                 // lock for ports and channels
+	ports    map[string]*crypto.HashCode // ports opened by client
+	channels map[uint32]*clientChannel   // channels by client number
+	lastCCN  uint32                      // last service-allocated number
+}
+
+// Send a message to the client (Responder interface).
+func (s *session) Send(ctx context.Context, msg message.Message) error {
+	s.smtx.Lock()
+	defer s.smtx.Unlock()
+	return s.mc.Send(ctx, msg)
+}
+
+// Receiver returns nil for local clients (Responder interface).
+func (s *session) Receiver() *util.PeerID {
+	return nil
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	sess := &session{
+		mc:       mc,
+		ctx:      ctx,
+		smtx:     new(sync.Mutex),
+		mtx:      new(sync.Mutex),
+		ports:    make(map[string]*crypto.HashCode),
+		channels: make(map[uint32]*clientChannel),
+		lastCCN:  message.CadetLocalChannelIDCli,
+	}
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[cadet:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[cadet:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[cadet:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[cadet:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[cadet:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		s.HandleMessage(valueCtx, nil, msg, sess)
+	}
+	// close client connection
+	mc.Close()
+
+	// close ports and channels of the client
+	logger.Printf(logger.INFO, "[cadet:%d] Start closing session...\n", id)
+	cancel()
+	sess.mtx.Lock()
+	for _, p := range sess.ports {
+		s.ClosePort(p)
+	}
+	for _, cc := range sess.channels {
+		cc.ch.Close()
+	}
+	sess.mtx.Unlock()
+}
+
+// HandleMessage handles a single incoming client message.
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	sess, ok := back.(*session)
+	if !ok {
+		logger.Printf(logger.ERROR, "[cadet%s] Message not from a client session\n", label)
+		return false
+	}
+	switch m := msg.(type) {
+	case *message.CadetLocalPortMsg:
+		//----------------------------------------------------------
+		// LOCAL_PORT_OPEN / LOCAL_PORT_CLOSE
+		//----------------------------------------------------------
+		if m.MsgType != enums.MSG_CADET_LOCAL_PORT_OPEN {
+			sess.mtx.Lock()
+			delete(sess.ports, m.Port.String())
+			sess.mtx.Unlock()
+			s.ClosePort(m.Port)
+			break
+		}
+		port := m.Port
+		err := s.OpenPort(port, func(ch *Channel) {
+			s.accept(sess, ch, port, label)
+		})
+		if err != nil {
+			logger.Printf(logger.WARN, "[cadet%s] Port %s not opened: %s\n", label, port.Short(), err.Error())
+			break
+		}
+		sess.mtx.Lock()
+		sess.ports[port.String()] = port
+		sess.mtx.Unlock()
+
+	case *message.CadetLocalChannelCreateMsg:
+		//----------------------------------------------------------
+		// LOCAL_CHANNEL_CREATE: open channel to a peer
+		//----------------------------------------------------------
+		go func() {
+			ch, err := s.Open(sess.ctx, m.Peer, m.Port)
+			if err != nil {
+				logger.Printf(logger.WARN, "[cadet%s] Channel to %s failed: %s\n", label, m.Peer.Short(), err.Error())
+				if err = sess.Send(sess.ctx, message.NewCadetLocalChannelDestroyMsg(m.CCN)); err != nil {
+					logger.Printf(logger.ERROR, "[cadet%s] Failed to send response: %s\n", label, err.Error())
+				}
+				return
+			}
+			s.attach(sess, ch, m.CCN, label)
+			// client is allowed to send data
+			if err = sess.Send(sess.ctx, message.NewCadetLocalAckMsg(m.CCN)); err != nil {
+				logger.Printf(logger.ERROR, "[cadet%s] Failed to send response: %s\n", label, err.Error())
+			}
+		}()
+
+	case *message.CadetLocalChannelDestroyMsg:
+		//----------------------------------------------------------
+		// LOCAL_CHANNEL_DESTROY: close channel
+		//----------------------------------------------------------
+		sess.mtx.Lock()
+		cc, ok := sess.channels[m.CCN]
+		delete(sess.channels, m.CCN)
+		sess.mtx.Unlock()
+		if ok {
+			cc.ch.Close()
+		}
+
+	case *message.CadetLocalDataMsg:
+		//----------------------------------------------------------
+		// LOCAL_DATA: send data on channel
+		//----------------------------------------------------------
+		sess.mtx.Lock()
+		cc, ok := sess.channels[m.CCN]
+		sess.mtx.Unlock()
+		if !ok {
+			logger.Printf(logger.WARN, "[cadet%s] Data for unknown channel %08x\n", label, m.CCN)
+			break
+		}
+		select {
+		case cc.out <- m.Data:
+		default:
+			logger.Printf(logger.WARN, "[cadet%s] Data on channel %08x without ACK dropped\n", label, m.CCN)
+		}
+
+	case *message.CadetLocalAckMsg:
+		// client is ready for more data: data is forwarded as received.
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[cadet%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	return true
+}
+
+// accept an incoming channel on a port opened by the client.
+func (s *Service) accept(sess *session, ch *Channel, port *crypto.HashCode, label string) {
+	sess.mtx.Lock()
+	sess.lastCCN++
+	if sess.lastCCN < message.CadetLocalChannelIDCli {
+		sess.lastCCN = message.CadetLocalChannelIDCli
+	}
+	ccn := sess.lastCCN
+	sess.mtx.Unlock()
+
+	s.attach(sess, ch, ccn, label)
+	msg := message.NewCadetLocalChannelCreateMsg(ccn, ch.Peer(), port)
+	if err := sess.Send(sess.ctx, msg); err != nil {
+		logger.Printf(logger.ERROR, "[cadet%s] Failed to notify client: %s\n", label, err.Error())
+		ch.Close()
+		return
+	}
+	if err := sess.Send(sess.ctx, message.NewCadetLocalAckMsg(ccn)); err != nil {
+		logger.Printf(logger.ERROR, "[cadet%s] Failed to send ACK: %s\n", label, err.Error())
+	}
+}
+
+// attach a channel to a client session: start the goroutines that
+// forward data between client and channel.
+func (s *Service) attach(sess *session, ch *Channel, ccn uint32, label string) {
+	cc := &clientChannel{
+		ch:  ch,
+		out: make(chan []byte, 1),
+	}
+	sess.mtx.Lock()
+	sess.channels[ccn] = cc
+	sess.mtx.Unlock()
+
+	// data from client: send on channel and allow the next message
+	go func() {
+		for {
+			select {
+			case buf := <-cc.out:
+				if err := ch.Send(sess.ctx, buf); err != nil {
+					return
+				}
+				if err := sess.Send(sess.ctx, message.NewCadetLocalAckMsg(ccn)); err != nil {
+					logger.Printf(logger.ERROR, "[cadet%s] Failed to send ACK: %s\n", label, err.Error())
+				}
+			case <-ch.done:
+				return
+			case <-sess.ctx.Done():
+				return
+			}
+		}
+	}()
+	// data from channel: forward to client; notify client on close
+	go func() {
+		for {
+			buf, err := ch.Recv(sess.ctx)
+			if err != nil {
+				if sess.ctx.Err() != nil {
+					return
+				}
+				sess.mtx.Lock()
+				_, ok := sess.channels[ccn]
+				delete(sess.channels, ccn)
+				sess.mtx.Unlock()
+				if ok {
+					if err = sess.Send(sess.ctx, message.NewCadetLocalChannelDestroyMsg(ccn)); err != nil {
+						logger.Printf(logger.ERROR, "[cadet%s] Failed to notify client: %s\n", label, err.Error())
+					}
+				}
+				return
+			}
+			if err = sess.Send(sess.ctx, message.NewCadetLocalDataMsg(ccn, buf)); err != nil {
+				logger.Printf(logger.ERROR, "[cadet%s] Failed to forward data: %s\n", label, err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,187 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package cadet
+
+import (
+	"encoding/hex"
+	"time"
+
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Tunnels: end-to-end encrypted links to peers
+//----------------------------------------------------------------------
+
+// Tunnel states
+const (
+	TunnelNew        = iota // no connection
+	TunnelConnecting        // connection requested
+	TunnelKX                // key exchange started
+	TunnelKXAuth            // keys derived, waiting for confirmation
+	TunnelReady             // keys confirmed
+)
+
+// TunnelStateName returns a human-readable tunnel state.
+func TunnelStateName(state int) string {
+	switch state {
+	case TunnelNew:
+		return "new"
+	case TunnelConnecting:
+		return "connecting"
+	case TunnelKX:
+		return "kx"
+	case TunnelKXAuth:
+		return "kx-auth"
+	case TunnelReady:
+		return "ready"
+	}
+	return "unknown"
+}
+
+// outgoing message for a peer
+type outMsg struct {
+	peer *util.PeerID
+	msg  message.Message
+}
+
+// batch of outgoing messages collected while the module is locked; the
+// messages are sent after the lock is released.
+type batch []*outMsg
+
+// add a message to the batch
+func (b *batch) add(peer *util.PeerID, msg message.Message) {
+	*b = append(*b, &outMsg{peer: peer, msg: msg})
+}
+
+// Tunnel to a peer: a direct connection carries the key exchange and the
+// encrypted channel messages. All fields are guarded by the module lock.
+type Tunnel struct {
+	peer     *util.PeerID        // remote peer
+	cid      []byte              // connection used for sending (or nil)
+	state    int                 // tunnel state
+	ax       *axolotl            // key material
+	queue    []message.Message   // channel messages waiting for keys
+	channels map[uint32]*Channel // channels by number
+	lastCTN  uint32              // last allocated channel number
+	lastKX   time.Time           // time of last connection or KX request
+}
+
+// newTunnel creates a tunnel to a peer.
+func (m *Module) newTunnel(peer *util.PeerID) *Tunnel {
+	return &Tunnel{
+		peer:     peer,
+		ax:       newAxolotl(m.prv, m.core.PeerID(), peer),
+		channels: make(map[uint32]*Channel),
+	}
+}
+
+// connect the tunnel (request a connection if there is none).
+func (m *Module) connect(t *Tunnel, b *batch) {
+	if t.state != TunnelNew {
+		return
+	}
+	if t.cid == nil {
+		t.cid = util.NewRndArray(message.CadetCIDSize)
+		m.conns[hex.EncodeToString(t.cid)] = t
+	}
+	path := []*util.PeerID{m.core.PeerID(), t.peer}
+	b.add(t.peer, message.NewCadetConnectionCreateMsg(t.cid, path))
+	t.state = TunnelConnecting
+	t.lastKX = time.Now()
+}
+
+// startKX sends the own keys to the peer.
+func (m *Module) startKX(t *Tunnel, b *batch) {
+	eph, ratchet := t.ax.keys()
+	msg := message.NewCadetTunnelKXMsg(t.cid, eph, ratchet)
+	msg.Flags = message.CadetKXFlagForceReply
+	b.add(t.peer, msg)
+	if t.state < TunnelKX {
+		t.state = TunnelKX
+	}
+	t.lastKX = time.Now()
+}
+
+// nextCTN allocates a channel number: the peer with the larger identity
+// uses numbers with the high bit set.
+func (t *Tunnel) nextCTN() uint32 {
+	for {
+		t.lastCTN = (t.lastCTN + 1) & 0x7fffffff
+		ctn := t.lastCTN
+		if t.ax.alice {
+			ctn |= 0x80000000
+		}
+		if _, ok := t.channels[ctn]; !ok && t.lastCTN != 0 {
+			return ctn
+		}
+	}
+}
+
+// send a channel message through the tunnel (queued until the keys are
+// confirmed).
+func (m *Module) send(t *Tunnel, msg message.Message, b *batch) {
+	if t.state != TunnelReady {
+		t.queue = append(t.queue, msg)
+		m.connect(t, b)
+		return
+	}
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[cadet] failed to marshal %s: %s", msg.Type(), err.Error())
+		return
+	}
+	mac, hdr, enc, err := t.ax.encrypt(buf)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[cadet] failed to encrypt %s: %s", msg.Type(), err.Error())
+		return
+	}
+	b.add(t.peer, message.NewCadetTunnelEncryptedMsg(t.cid, mac, hdr, enc))
+}
+
+// ready marks the tunnel as usable and sends queued messages.
+func (m *Module) ready(t *Tunnel, b *batch) {
+	if t.state == TunnelReady {
+		return
+	}
+	logger.Printf(logger.INFO, "[cadet] tunnel to %s ready", t.peer.Short())
+	t.state = TunnelReady
+	queue := t.queue
+	t.queue = nil
+	for _, msg := range queue {
+		m.send(t, msg, b)
+	}
+}
+
+// destroy the tunnel and all its channels.
+func (m *Module) destroy(t *Tunnel, reason error) {
+	for _, ch := range t.channels {
+		ch.closeLocked(reason)
+	}
+	for key, tt := range m.conns {
+		if tt == t {
+			delete(m.conns, key)
+		}
+	}
+	delete(m.tunnels, t.peer.String())
+}
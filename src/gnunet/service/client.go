@@ -20,14 +20,49 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gnunet/enums"
 	"gnunet/message"
 
 	"github.com/bfix/gospel/logger"
 )
 
+// Reconnection parameters
+var (
+	ReconnectDelay    = time.Second      // delay before first reconnect attempt
+	MaxReconnectDelay = 30 * time.Second // max. delay between attempts
+)
+
+// ReplaySafe lists the request types that can be sent again after a
+// reconnect while their response is pending (lookups without side
+// effects on the service).
+var ReplaySafe = map[enums.MsgType]bool{
+	enums.MSG_DHT_CLIENT_GET:          true,
+	enums.MSG_GNS_LOOKUP:              true,
+	enums.MSG_NAMECACHE_LOOKUP_BLOCK:  true,
+	enums.MSG_NAMESTORE_RECORD_LOOKUP: true,
+	enums.MSG_IDENTITY_LOOKUP:         true,
+	enums.MSG_REVOCATION_QUERY:        true,
+}
+
 // Client type: Use to perform client-side interactions with GNUnet services.
+// With reconnection enabled, a broken connection (e.g. after a service
+// restart) is re-established transparently: active subscriptions are
+// replayed in order, followed by pending requests that are safe to
+// replay (see ReplaySafe). A pending request is resolved by the next
+// response received.
 type Client struct {
-	ch *Connection // channel for message exchange
+	sync.Mutex
+
+	ch        *Connection       // channel for message exchange
+	path      string            // socket path of service
+	reconnect bool              // reconnect on broken connection
+	notify    func(int)         // reconnection callback (or nil)
+	subs      []message.Message // active subscriptions
+	pending   []message.Message // requests waiting for a response
 }
 
 // NewClient connects to a socket with given path
@@ -39,26 +74,166 @@ func NewClient(ctx context.Context, path string) (*Client, error) {
 	}
 	// wrap into a message channel for the client.
 	return &Client{
-		ch: ch,
+		ch:   ch,
+		path: path,
 	}, nil
 }
 
+// SetReconnect enables (or disables) transparent reconnection. The
+// callback (if not nil) is called after a reconnect with the number of
+// replayed messages.
+func (c *Client) SetReconnect(on bool, notify func(replayed int)) {
+	c.Lock()
+	defer c.Unlock()
+	c.reconnect = on
+	c.notify = notify
+}
+
 // SendRequest sends a message to the service.
 func (c *Client) SendRequest(ctx context.Context, req message.Message) error {
-	return c.ch.Send(ctx, req)
+	if err := c.send(ctx, req); err != nil {
+		return err
+	}
+	if ReplaySafe[req.Type()] {
+		c.Lock()
+		c.pending = append(c.pending, req)
+		c.Unlock()
+	}
+	return nil
+}
+
+// Subscribe sends a request with a long-lived answer (monitors, watches,
+// GET requests with multiple results). The request is replayed on every
+// reconnect until it is unsubscribed.
+func (c *Client) Subscribe(ctx context.Context, req message.Message) error {
+	if err := c.send(ctx, req); err != nil {
+		return err
+	}
+	c.Lock()
+	c.subs = append(c.subs, req)
+	c.Unlock()
+	return nil
+}
+
+// Unsubscribe ends a subscription. The stop message (if not nil) is sent
+// to the service.
+func (c *Client) Unsubscribe(ctx context.Context, req, stop message.Message) error {
+	c.Lock()
+	for i, sub := range c.subs {
+		if sub == req {
+			c.subs = append(c.subs[:i], c.subs[i+1:]...)
+			break
+		}
+	}
+	c.Unlock()
+	if stop == nil {
+		return nil
+	}
+	return c.send(ctx, stop)
 }
 
 // ReceiveResponse waits for a response from the service; it can be interrupted
 // by sending "false" to the cmd channel.
 func (c *Client) ReceiveResponse(ctx context.Context) (message.Message, error) {
-	return c.ch.Receive(ctx)
+	for {
+		c.Lock()
+		ch := c.ch
+		c.Unlock()
+		msg, err := ch.Receive(ctx)
+		if err == nil {
+			c.Lock()
+			if len(c.pending) > 0 {
+				c.pending = c.pending[1:]
+			}
+			c.Unlock()
+			return msg, nil
+		}
+		if !c.canRedial(ctx, err) {
+			return nil, err
+		}
+		if err = c.redial(ctx, ch); err != nil {
+			return nil, err
+		}
+	}
 }
 
 // Close a client; no further message exchange is possible.
 func (c *Client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+	c.reconnect = false
 	return c.ch.Close()
 }
 
+// send a message (reconnect and retry once if the connection is broken)
+func (c *Client) send(ctx context.Context, msg message.Message) error {
+	c.Lock()
+	ch := c.ch
+	c.Unlock()
+	err := ch.Send(ctx, msg)
+	if err == nil || !c.canRedial(ctx, err) {
+		return err
+	}
+	if err = c.redial(ctx, ch); err != nil {
+		return err
+	}
+	c.Lock()
+	ch = c.ch
+	c.Unlock()
+	return ch.Send(ctx, msg)
+}
+
+// canRedial returns true if a failed operation should be retried on a
+// new connection.
+func (c *Client) canRedial(ctx context.Context, err error) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.reconnect && ctx.Err() == nil && !errors.Is(err, ErrConnectionInterrupted)
+}
+
+// redial replaces a broken connection and replays subscriptions and
+// pending requests. Attempts are repeated (with increasing delays) until
+// the context is done.
+func (c *Client) redial(ctx context.Context, broken *Connection) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.ch != broken {
+		// already replaced by a concurrent operation
+		return nil
+	}
+	broken.Close()
+	delay := ReconnectDelay
+	for {
+		logger.Printf(logger.WARN, "[client] connection to %s lost -- reconnecting in %s", c.path, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		ch, err := NewConnection(ctx, c.path)
+		if err == nil {
+			replay := append(append([]message.Message{}, c.subs...), c.pending...)
+			for _, msg := range replay {
+				if err = ch.Send(ctx, msg); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				c.ch = ch
+				logger.Printf(logger.INFO, "[client] reconnected to %s (%d messages replayed)", c.path, len(replay))
+				if c.notify != nil {
+					go c.notify(len(replay))
+				}
+				return nil
+			}
+			ch.Close()
+		}
+		if delay *= 2; delay > MaxReconnectDelay {
+			delay = MaxReconnectDelay
+		}
+	}
+}
+
 // RequestResponse is a helper method for a one request - one response
 // secenarios of client/serice interactions.
 func RequestResponse(
@@ -0,0 +1,93 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientReconnect(t *testing.T) {
+	delay := ReconnectDelay
+	ReconnectDelay = 10 * time.Millisecond
+	defer func() { ReconnectDelay = delay }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// start service
+	path := filepath.Join(t.TempDir(), "test.sock")
+	serve := func() (*ConnectionManager, chan *Connection) {
+		hdlr := make(chan *Connection)
+		cm, err := NewConnectionManager(ctx, path, nil, hdlr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cm, hdlr
+	}
+	cm, hdlr := serve()
+
+	// subscribe with reconnection enabled
+	cl, err := NewClient(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	replayed := make(chan int, 1)
+	cl.SetReconnect(true, func(n int) { replayed <- n })
+	get := message.NewDHTClientGetMsg(crypto.Hash([]byte("key")))
+	get.ID = 7
+	if err = cl.Subscribe(ctx, get); err != nil {
+		t.Fatal(err)
+	}
+	conn := <-hdlr
+	if _, err = conn.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// restart service
+	conn.Close()
+	cm.Close()
+	cm, hdlr = serve()
+	defer cm.Close()
+
+	// the subscription is replayed on the new connection
+	go func() {
+		conn := <-hdlr
+		msg, err := conn.Receive(ctx)
+		if err != nil || msg.Type() != enums.MSG_DHT_CLIENT_GET {
+			return
+		}
+		_ = conn.Send(ctx, message.NewDHTClientGetDoneMsg(msg.(*message.DHTClientGetMsg).ID, 0))
+	}()
+	resp, err := cl.ReceiveResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done, ok := resp.(*message.DHTClientGetDoneMsg); !ok || done.ID != 7 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	if n := <-replayed; n != 1 {
+		t.Fatalf("%d messages replayed", n)
+	}
+}
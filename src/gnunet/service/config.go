@@ -0,0 +1,94 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"gnunet/config"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrConfigNoFile = errors.New("configuration not read from file")
+)
+
+//----------------------------------------------------------------------
+// Commands "Config.Effective" and "Config.Diff": report the configuration
+// a running service uses (after environment substitutions and changes
+// by command-line options) and compare it with the configuration file.
+//----------------------------------------------------------------------
+
+// ConfigRPC is a type for JSON-RPC requests on the configuration.
+type ConfigRPC struct {
+	cfg *config.Config // running configuration
+}
+
+// ConfigRequest asks for the running configuration.
+type ConfigRequest struct{}
+
+// ConfigEffectiveResponse holds the running configuration (with secret
+// settings redacted) and the name of the configuration file.
+type ConfigEffectiveResponse struct {
+	File   string         `json:"file"`   // configuration file
+	Config map[string]any `json:"config"` // running configuration
+}
+
+// ConfigDiffResponse lists the differences between the running
+// configuration and the configuration file.
+type ConfigDiffResponse struct {
+	File  string               `json:"file"`  // configuration file
+	Diffs []*config.Difference `json:"diffs"` // differences (sorted by path)
+}
+
+// Effective returns the running configuration.
+func (s *ConfigRPC) Effective(r *http.Request, req *ConfigRequest, reply *ConfigEffectiveResponse) (err error) {
+	reply.File = config.FileName()
+	reply.Config, err = config.Effective(s.cfg)
+	return
+}
+
+// Diff compares the running configuration with the configuration file.
+func (s *ConfigRPC) Diff(r *http.Request, req *ConfigRequest, reply *ConfigDiffResponse) error {
+	reply.File = config.FileName()
+	if len(reply.File) == 0 {
+		return ErrConfigNoFile
+	}
+	file, err := config.LoadConfig(reply.File)
+	if err != nil {
+		return err
+	}
+	if reply.Diffs, err = config.Diff(s.cfg, file); err != nil {
+		return err
+	}
+	if reply.Diffs == nil {
+		reply.Diffs = make([]*config.Difference, 0)
+	}
+	return nil
+}
+
+// InitConfigRPC registers the RPC commands for the running configuration.
+func InitConfigRPC(srv *JRPCServer, cfg *config.Config) {
+	if err := srv.RegisterService(&ConfigRPC{cfg: cfg}, "Config"); err != nil {
+		logger.Printf(logger.ERROR, "[config] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,103 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+)
+
+// recvService receives messages from clients until the session ends.
+type recvService struct {
+	testService
+}
+
+func (s *recvService) ServeClient(ctx context.Context, id int, mc *Connection) {
+	for {
+		if _, err := mc.Receive(ctx); err != nil {
+			return
+		}
+	}
+}
+
+// Two nodes in one process use their own configuration.
+func TestNodeConfig(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	run := func(context.Context) error { return nil }
+	for i := 0; i < 2; i++ {
+		// node 0 disables the job, node 1 runs it
+		cfg := &config.Config{
+			Source: fmt.Sprintf("https://example.org/node%d.git", i),
+			Maintenance: &config.MaintenanceConfig{
+				Periods: map[string]int{"test:job": i * 3600},
+			},
+		}
+		// maintenance jobs
+		s := NewScheduler(cfg.Maintenance)
+		nctx := context.WithValue(ctx, CtxScheduler, s)
+		if err := Schedule(nctx, "test:job", time.Minute, run); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(s.Jobs("test:job")); n != i {
+			t.Fatalf("node %d: %d jobs scheduled", i, n)
+		}
+		// running configuration
+		var reply ConfigEffectiveResponse
+		if err := (&ConfigRPC{cfg: cfg}).Effective(nil, nil, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Config["source"] != cfg.Source {
+			t.Fatalf("node %d: running configuration %v", i, reply.Config)
+		}
+		// source code link on service sockets
+		h := NewSocketHandler(fmt.Sprintf("node%d", i), new(recvService))
+		h.SetSource(core.SourceLink(cfg))
+		socket := filepath.Join(t.TempDir(), "test.sock")
+		if err := h.Start(nctx, socket, nil); err != nil {
+			t.Fatal(err)
+		}
+		conn, err := NewConnection(ctx, socket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = conn.Send(ctx, message.NewAGPLRequestMsg()); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := conn.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp, ok := msg.(*message.AGPLResponseMsg); !ok || resp.Link() != cfg.Source {
+			t.Fatalf("node %d: source link %v", i, msg)
+		}
+		conn.Close()
+		if err = h.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -22,11 +22,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"gnunet/core"
+	"gnunet/enums"
 	"gnunet/message"
 	"gnunet/util"
 	"net"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/bfix/gospel/data"
 	"github.com/bfix/gospel/logger"
@@ -44,10 +47,12 @@ var (
 // based on Unix domain sockets. It is used locally by services and
 // clients in the standard GNUnet environment.
 type Connection struct {
-	id   int      // connection identifier
-	path string   // file name of Unix socket
-	conn net.Conn // associated connection
-	buf  []byte   // read/write buffer
+	id     int      // connection identifier
+	path   string   // file name of Unix socket
+	conn   net.Conn // associated connection
+	buf    []byte   // read/write buffer
+	served bool     // connection accepted by a service
+	source string   // link to source code (served connections)
 }
 
 // NewConnection creates a new connection to a socket with given path.
@@ -99,8 +104,27 @@ func (s *Connection) Send(ctx context.Context, msg message.Message) error {
 	return nil
 }
 
-// Receive GNUnet messages from socket.
+// Receive GNUnet messages from socket. On service connections requests
+// for the source code link (REQUEST_AGPL) are answered directly and are
+// not passed to the service.
 func (s *Connection) Receive(ctx context.Context) (message.Message, error) {
+	for {
+		msg, err := s.receive(ctx)
+		if err != nil || !s.served || msg.Type() != enums.MSG_REQUEST_AGPL {
+			return msg, err
+		}
+		link := s.source
+		if len(link) == 0 {
+			link = core.SourceURL
+		}
+		if err = s.Send(ctx, message.NewAGPLResponseMsg(link)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// receive the next GNUnet message from socket.
+func (s *Connection) receive(ctx context.Context) (message.Message, error) {
 	// get bytes from socket
 	get := func(pos, count int) error {
 		n, err := s.read(ctx, s.buf[pos:pos+count])
@@ -212,7 +236,8 @@ func (s *Connection) write(ctx context.Context, buf []byte) (int, error) {
 // ConnectionManager to handle client connections on a socket.
 type ConnectionManager struct {
 	listener net.Listener // reference to listener object
-	running  bool         // server running?
+	stop     sync.Once    // close listener only once
+	err      error        // result of closing the listener
 }
 
 // NewConnectionManager creates a new socket connection manager. Incoming
@@ -224,10 +249,7 @@ func NewConnectionManager(
 	hdlr chan *Connection, // handler for incoming connections
 ) (cs *ConnectionManager, err error) {
 	// instantiate channel server
-	cs = &ConnectionManager{
-		listener: nil,
-		running:  false,
-	}
+	cs = new(ConnectionManager)
 	// create listener
 	var lc net.ListenConfig
 	if cs.listener, err = lc.Listen(ctx, "unix", path); err != nil {
@@ -253,23 +275,27 @@ func NewConnectionManager(
 		}
 	}
 	// run go routine to handle channel requests from clients
-	cs.running = true
 	go func() {
-		for cs.running {
+		// the accept loop terminates when the listener is closed
+		defer cs.Close()
+		for {
 			conn, err := cs.listener.Accept()
 			if err != nil {
-				break
+				return
 			}
 			// handle connection
 			c := &Connection{
-				conn: conn,
-				path: path,
-				buf:  make([]byte, 65536),
+				conn:   conn,
+				path:   path,
+				buf:    make([]byte, 65536),
+				served: true,
+			}
+			select {
+			case hdlr <- c:
+			case <-ctx.Done():
+				conn.Close()
+				return
 			}
-			hdlr <- c
-		}
-		if cs.listener != nil {
-			cs.listener.Close()
 		}
 	}()
 	return cs, nil
@@ -277,11 +303,8 @@ func NewConnectionManager(
 
 // Close a network channel server (= stop the server)
 func (s *ConnectionManager) Close() error {
-	s.running = false
-	if s.listener != nil {
-		err := s.listener.Close()
-		s.listener = nil
-		return err
-	}
-	return nil
+	s.stop.Do(func() {
+		s.err = s.listener.Close()
+	})
+	return s.err
 }
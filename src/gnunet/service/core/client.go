@@ -0,0 +1,219 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gnunet/core"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/transport"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Handle CORE client messages (service socket)
+//
+//   * A client registers with CORE_INIT, listing the message types it
+//     wants to receive from other peers. The service replies with the
+//     identity of the local peer and notifies the client about all
+//     peers currently connected.
+//   * Connects and disconnects of peers are reported to the client as
+//     long as it is connected to the service.
+//   * Messages of registered types received from peers are delivered
+//     as CORE_NOTIFY_INBOUND; with the SEND_FULL_INBOUND (or
+//     SEND_HDR_INBOUND) option the client receives all messages (or
+//     the headers of all messages).
+//   * A client requests to send a message with CORE_SEND_REQUEST and
+//     transmits it after CORE_SEND_READY was received. Transmission is
+//     not flow-controlled, so a request is always confirmed at once.
+//   * Monitoring of outbound traffic is not supported.
+//----------------------------------------------------------------------
+
+// Client is a client session on the core service socket.
+type Client struct {
+	sync.Mutex
+
+	id    int                    // client identifier
+	back  transport.Responder    // client connection
+	opts  uint32                 // client options
+	types map[enums.MsgType]bool // message types of interest
+	ch    chan *core.Event       // core event channel (nil before INIT)
+}
+
+// NewClient creates a new client session on a connection
+func NewClient(id int, back transport.Responder) *Client {
+	return &Client{
+		id:    id,
+		back:  back,
+		types: make(map[enums.MsgType]bool),
+	}
+}
+
+// Name of the core listener for the client
+func (c *Client) Name() string {
+	return fmt.Sprintf("core-client:%d", c.id)
+}
+
+// Listening returns true if the client has registered for core events.
+func (c *Client) Listening() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.ch != nil
+}
+
+// Filter returns the core event filter for the client.
+func (c *Client) Filter() *core.EventFilter {
+	f := core.NewEventFilter()
+	f.AddEvent(core.EV_CONNECT)
+	f.AddEvent(core.EV_DISCONNECT)
+	if c.opts&(enums.CORE_OPTION_SEND_FULL_INBOUND|enums.CORE_OPTION_SEND_HDR_INBOUND) != 0 {
+		// all messages are of interest
+		f.AddEvent(core.EV_MESSAGE)
+	} else {
+		for mt := range c.types {
+			f.AddMsgType(mt)
+		}
+	}
+	return f
+}
+
+// Notify the client about a core event.
+func (c *Client) Notify(ctx context.Context, ev *core.Event) (err error) {
+	var msg message.Message
+	switch ev.ID {
+	case core.EV_CONNECT:
+		msg = message.NewCoreNotifyConnectMsg(ev.Peer)
+	case core.EV_DISCONNECT:
+		msg = message.NewCoreNotifyDisconnectMsg(ev.Peer)
+	case core.EV_MESSAGE:
+		var buf []byte
+		if buf, err = data.Marshal(ev.Msg); err != nil {
+			return
+		}
+		// deliver only the header if the message type was not requested
+		// and the client is not monitoring all messages in full.
+		if !c.types[ev.Msg.Type()] {
+			switch {
+			case c.opts&enums.CORE_OPTION_SEND_FULL_INBOUND != 0:
+			case c.opts&enums.CORE_OPTION_SEND_HDR_INBOUND != 0:
+				buf = buf[:4]
+			default:
+				return
+			}
+		}
+		msg = message.NewCoreNotifyTrafficMsg(false, ev.Peer, buf)
+	default:
+		return
+	}
+	return c.back.Send(ctx, msg)
+}
+
+//----------------------------------------------------------------------
+
+// HandleClientMessage handles CORE client messages. Returns false if the
+// message was not handled.
+func (s *Service) HandleClientMessage(ctx context.Context, cl *Client, msg message.Message) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	switch m := msg.(type) {
+	case *message.CoreInitMsg:
+		//----------------------------------------------------------
+		// CORE_INIT
+		//----------------------------------------------------------
+		if cl.Listening() {
+			logger.Printf(logger.WARN, "[core%s] Client already initialized -- ignored", label)
+			return true
+		}
+		logger.Printf(logger.INFO, "[core%s] Client init (options=%d, %d types)", label, m.Options, len(m.Types))
+		cl.Lock()
+		cl.opts = m.Options
+		for _, mt := range m.Types {
+			cl.types[mt] = true
+		}
+		cl.ch = make(chan *core.Event, 32)
+		cl.Unlock()
+
+		// reply with local peer identity and report connected peers
+		if err := cl.back.Send(ctx, message.NewCoreInitReplyMsg(s.core.PeerID())); err != nil {
+			logger.Printf(logger.ERROR, "[core%s] Failed to send init reply: %s", label, err.Error())
+			return true
+		}
+		for _, peer := range s.core.Connected() {
+			if err := cl.back.Send(ctx, message.NewCoreNotifyConnectMsg(peer)); err != nil {
+				logger.Printf(logger.ERROR, "[core%s] Failed to send connect notification: %s", label, err.Error())
+				return true
+			}
+		}
+		// relay core events to client
+		s.core.Register(cl.Name(), core.NewListener(cl.ch, cl.Filter()))
+		go func() {
+			for {
+				select {
+				case ev := <-cl.ch:
+					if err := cl.Notify(ctx, ev); err != nil {
+						logger.Printf(logger.ERROR, "[core%s] Failed to notify client: %s", label, err.Error())
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+	case *message.CoreSendRequestMsg:
+		//----------------------------------------------------------
+		// CORE_SEND_REQUEST
+		//----------------------------------------------------------
+		resp := message.NewCoreSendReadyMsg(m.Peer, m.MsgSize, m.SmrID)
+		if err := cl.back.Send(ctx, resp); err != nil {
+			logger.Printf(logger.ERROR, "[core%s] Failed to send ready: %s", label, err.Error())
+		}
+
+	case *message.CoreSendMsg:
+		//----------------------------------------------------------
+		// CORE_SEND
+		//----------------------------------------------------------
+		out, err := message.Parse(m.Payload)
+		if err != nil {
+			logger.Printf(logger.WARN, "[core%s] Invalid message to %s: %s", label, m.Peer.Short(), err.Error())
+			return true
+		}
+		if m.Peer.Equal(s.core.PeerID()) {
+			logger.Printf(logger.WARN, "[core%s] Can't send %s to local peer -- dropped", label, out.Type())
+			return true
+		}
+		go func() {
+			if err := s.core.Send(ctx, m.Peer, out); err != nil {
+				logger.Printf(logger.WARN, "[core%s] Failed to send %s to %s: %s", label, out.Type(), m.Peer.Short(), err.Error())
+			}
+		}()
+
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for core-related JSON-RPC requests
+type RPCService struct {
+	c *core.Core // reference to the local core
+}
+
+//----------------------------------------------------------------------
+// Command "Core.Version"
+//----------------------------------------------------------------------
+
+// VersionRequest asks for the version information of a connected peer
+// (or of the local node if no peer is given).
+type VersionRequest struct {
+	Peer string `json:"peer,omitempty"`
+}
+
+// VersionResponse returns the version information.
+type VersionResponse struct {
+	Peer string `json:"peer"`
+	message.VersionInfo
+}
+
+// Version returns the version information of the local node or a peer.
+func (s *RPCService) Version(r *http.Request, req *VersionRequest, reply *VersionResponse) error {
+	if len(req.Peer) == 0 {
+		*reply = VersionResponse{
+			Peer:        s.c.PeerID().String(),
+			VersionInfo: *s.c.VersionInfo(),
+		}
+		return nil
+	}
+	data, err := util.DecodeStringToBinary(req.Peer, util.PeerPublicKeySize)
+	if err != nil {
+		return fmt.Errorf("invalid peer ID '%s'", req.Peer)
+	}
+	peer := util.NewPeerID(data)
+	info, err := s.c.QueryVersion(r.Context(), peer)
+	if err != nil {
+		return err
+	}
+	*reply = VersionResponse{
+		Peer:        peer.String(),
+		VersionInfo: *info,
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "Core.Endpoints"
+//----------------------------------------------------------------------
+
+// EndpointsRequest asks for the state of the transport endpoints.
+type EndpointsRequest struct{}
+
+// EndpointsResponse lists the state of all configured endpoints.
+type EndpointsResponse struct {
+	Endpoints []*core.EndpointState `json:"endpoints"`
+}
+
+// Endpoints returns the state of the transport endpoints of the local node.
+func (s *RPCService) Endpoints(r *http.Request, req *EndpointsRequest, reply *EndpointsResponse) error {
+	reply.Endpoints = s.c.EndpointStates()
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "Core.Talkers"
+//----------------------------------------------------------------------
+
+// TalkersRequest asks for the traffic reports of connected peers.
+type TalkersRequest struct {
+	Limit int `json:"limit,omitempty"` // max. number of peers (0 = all)
+}
+
+// TalkersResponse lists the connected peers sorted by bandwidth.
+type TalkersResponse struct {
+	Peers []*core.PeerTraffic `json:"peers"`
+}
+
+// Talkers returns the "top talkers" among the connected peers: their
+// traffic by message type, invalid messages and last activity.
+func (s *RPCService) Talkers(r *http.Request, req *TalkersRequest, reply *TalkersResponse) error {
+	reply.Peers = s.c.TopTalkers(req.Limit)
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the local core.
+func InitRPC(srv *service.JRPCServer, c *core.Core) {
+	if err := srv.RegisterService(&RPCService{c: c}, "Core"); err != nil {
+		logger.Printf(logger.ERROR, "[core] Failed to init RPC: %s", err.Error())
+	}
+}
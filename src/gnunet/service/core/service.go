@@ -0,0 +1,124 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Core" service implementation
+//
+// The service exposes the local core (peer identity, connections and
+// message exchange with other peers) to external processes through a
+// service socket. The client protocol is compatible with the GNUnet C
+// implementation, so C services can use the Go core as their underlay.
+//----------------------------------------------------------------------
+
+// Service implements the core service
+type Service struct {
+	core *core.Core // reference to the local core
+}
+
+// NewService creates a new core service instance
+func NewService(ctx context.Context, c *core.Core) service.Service {
+	return &Service{
+		core: c,
+	}
+}
+
+// Export functions by name (nothing to export)
+func (s *Service) Export(fcn map[string]any) {}
+
+// Import functions by name (nothing to import)
+func (s *Service) Import(fcn map[string]any) {}
+
+// InitRPC registers RPC commands for the service
+func (s *Service) InitRPC(rpc *service.JRPCServer) {
+	InitRPC(rpc, s.core)
+}
+
+// Filter returns the event filter for the service: the service itself
+// does not listen for core events; its clients do.
+func (s *Service) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	cl := NewClient(id, mc)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[core:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[core:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[core:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[core:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[core:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		if !s.HandleClientMessage(valueCtx, cl, msg) {
+			s.HandleMessage(valueCtx, nil, msg, mc)
+		}
+	}
+	// stop event delivery and close client connection
+	if cl.Listening() {
+		s.core.Unregister(cl.Name())
+	}
+	mc.Close()
+
+	// cancel all tasks running for this session/connection
+	logger.Printf(logger.INFO, "[core:%d] Start closing session...\n", id)
+	cancel()
+}
+
+// HandleMessage handles a single incoming message. The core service only
+// handles client messages, so all messages are rejected.
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	logger.Printf(logger.ERROR, "[core%s] Unhandled message of type (%s)\n", label, msg.Type())
+	return false
+}
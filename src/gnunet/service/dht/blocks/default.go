@@ -31,8 +31,8 @@ import (
 
 // TestBlock (BLOCK_TYPE_TEST) is a block for testing the DHT with non-HELLO
 // blocks. Applications using the DHT are encouraged to define custom blocks
-// with appropriate internal logic. TestBlocks are just a pile of bits that
-// never expire...
+// with appropriate internal logic. TestBlocks are just a pile of bits with
+// an expiration (that defaults to "never").
 type TestBlock struct {
 	expire util.AbsoluteTime ``         // expiry (transient!)
 	Data   []byte            `size:"*"` // block data
@@ -85,18 +85,21 @@ func (t *TestBlock) Verify() (bool, error) {
 // TEST block handler
 //----------------------------------------------------------------------
 
-// TestBlockHandler methods related to HELLO blocks
+// TestBlockHandler methods related to TEST blocks. Validation is trivial:
+// any payload is accepted under any key, so arbitrary data can be sent
+// through the PUT/GET/RESULT pipeline.
 type TestBlockHandler struct{}
 
 // Parse a block instance from binary data
 func (bh *TestBlockHandler) ParseBlock(buf []byte) (Block, error) {
 	return &TestBlock{
-		Data: util.Clone(buf),
+		expire: util.AbsoluteTimeNever(),
+		Data:   util.Clone(buf),
 	}, nil
 }
 
-// ValidateHelloBlockQuery validates query parameters for a
-// DHT-GET request for HELLO blocks.
+// ValidateBlockQuery validates query parameters for a DHT-GET request
+// for TEST blocks (extended queries are ignored).
 func (bh *TestBlockHandler) ValidateBlockQuery(key *crypto.HashCode, xquery []byte) bool {
 	// no internal logic
 	return true
@@ -113,16 +116,14 @@ func (bh *TestBlockHandler) ValidateBlockKey(b Block, key *crypto.HashCode) bool
 // payload as part of PutMessage and ResultMessage processing. The special
 // return value of 'nil' implies that this block type does not permit
 // deriving the key from the block. A Key may be returned for a block that
-// is ill-formed.
+// is ill-formed. The key of a TEST block is chosen by the application,
+// so it can't be derived.
 func (bh *TestBlockHandler) DeriveBlockKey(b Block) *crypto.HashCode {
 	return nil
 }
 
 // ValidateBlockStoreRequest is used to evaluate a block payload as part of
-// PutMessage and ResultMessage processing.
-// To validate a block store request is to verify the EdDSA SIGNATURE over
-// the hashed ADDRESSES against the public key from the peer ID field. If the
-// signature is valid true is returned.
+// PutMessage and ResultMessage processing. TEST blocks are always valid.
 func (bh *TestBlockHandler) ValidateBlockStoreRequest(b Block) bool {
 	// no internal logic
 	return true
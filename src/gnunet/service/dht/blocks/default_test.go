@@ -0,0 +1,75 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package blocks
+
+import (
+	"bytes"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+	"testing"
+	"time"
+)
+
+// TestTestBlock checks the handling of TEST blocks by the block factory
+// and the registered block handler.
+func TestTestBlock(t *testing.T) {
+	payload := util.NewRndArray(64)
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	blk, err := NewBlock(enums.BLOCK_TYPE_TEST, expire, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blk.(*TestBlock); !ok {
+		t.Fatalf("wrong block instance %s", blk)
+	}
+	if blk.Type() != enums.BLOCK_TYPE_TEST || !bytes.Equal(blk.Bytes(), payload) || blk.Expire().Compare(expire) != 0 {
+		t.Fatalf("wrong block %s", blk)
+	}
+
+	// check block handler
+	hdlr, ok := BlockHandlers[enums.BLOCK_TYPE_TEST]
+	if !ok {
+		t.Fatal("no handler for TEST blocks")
+	}
+	key := crypto.Hash([]byte("any key"))
+	pblk, err := hdlr.ParseBlock(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pblk.Bytes(), payload) || pblk.Expire().Expired() {
+		t.Fatalf("wrong parsed block %s", pblk)
+	}
+	if !hdlr.ValidateBlockQuery(key, nil) ||
+		!hdlr.ValidateBlockKey(pblk, key) ||
+		!hdlr.ValidateBlockStoreRequest(pblk) {
+		t.Fatal("TEST block not valid")
+	}
+	if hdlr.DeriveBlockKey(pblk) != nil {
+		t.Fatal("key derived from TEST block")
+	}
+	// result filtering
+	rf := hdlr.SetupResultFilter(128, util.RndUInt32())
+	if rc := hdlr.FilterResult(blk, key, rf, nil); rc != RF_LAST {
+		t.Fatalf("unexpected filter result %d", rc)
+	}
+	if rc := hdlr.FilterResult(blk, key, hdlr.ParseResultFilter(rf.Bytes()), nil); rc != RF_DUPLICATE {
+		t.Fatalf("duplicate not filtered (%d)", rc)
+	}
+}
@@ -0,0 +1,105 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package blocks_test
+
+import (
+	"fmt"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// A zone signer creates a GNS block for a label offline; an auditor
+// verifies the block (in RRBLOCK format) and reads its records.
+func ExampleNewGNSBlockFromRecords() {
+	// zone key of the signer
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// records for label "www"
+	expire := util.AbsoluteTimeNow().Add(24 * time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   4,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{10, 0, 0, 1},
+	})
+	// create signed block and export it in RRBLOCK format
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", rs, expire)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	rrblock := blk.RRBLOCK()
+
+	// auditor: parse and verify block with public zone key and label
+	blk2, err := blocks.NewGNSBlockFromRRBLOCK(rrblock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	recs, err := blk2.Records(zp.Public(), "www")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, rec := range recs.Records {
+		fmt.Println(rec.RType, rec.Data)
+	}
+	// Output:
+	// GNS_TYPE_DNS_A [10 0 0 1]
+}
+
+// Record sets can be encrypted and decrypted without a GNS block, e.g.
+// to inspect the payload of a block from another source.
+func ExampleDecryptRecordSet() {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, util.NewRndArray(32))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	zk := zp.Public()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   5,
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   []byte("hello"),
+	})
+	payload, err := blocks.EncryptRecordSet(zk, "info", rs, expire)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	rs2, err := blocks.DecryptRecordSet(zk, "info", expire, payload)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(rs2.Count, string(rs2.Records[0].Data))
+	// Output:
+	// 1 hello
+}
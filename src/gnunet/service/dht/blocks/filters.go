@@ -166,21 +166,14 @@ func NewGenericResultFilterFromBytes(data []byte) *GenericResultFilter {
 	return rf
 }
 
-// Add a HELLO block to th result filter
+// Add a block to the result filter
 func (rf *GenericResultFilter) Add(b Block) {
-	if hb, ok := b.(*HelloBlock); ok {
-		hAddr := sha512.Sum512(hb.AddrBin)
-		rf.bf.Add(hAddr[:])
-	}
+	rf.bf.Add(filterHash(b))
 }
 
 // Contains checks if a block is contained in the result filter
 func (rf *GenericResultFilter) Contains(b Block) bool {
-	if hb, ok := b.(*HelloBlock); ok {
-		hAddr := sha512.Sum512(hb.AddrBin)
-		return rf.bf.Contains(hAddr[:])
-	}
-	return false
+	return rf.bf.Contains(filterHash(b))
 }
 
 // ContainsHash checks if a block hash is contained in the result filter
@@ -211,6 +204,17 @@ func (rf *GenericResultFilter) Merge(t ResultFilter) bool {
 	return rf.bf.Merge(trf.bf)
 }
 
+// filterHash returns the hash of a block used in a generic result filter:
+// HELLO blocks are identified by their addresses, all other blocks by
+// their content (see ContainsHash).
+func filterHash(b Block) []byte {
+	if hb, ok := b.(*HelloBlock); ok {
+		hAddr := sha512.Sum512(hb.AddrBin)
+		return hAddr[:]
+	}
+	return crypto.Hash(b.Bytes()).Data
+}
+
 //======================================================================
 // Generic bloom filter with mutator
 //======================================================================
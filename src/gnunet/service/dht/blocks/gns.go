@@ -37,6 +37,8 @@ var (
 	ErrBlockInvalidSig      = errors.New("invalid signature key for GNS Block")
 	ErrBlockTypeNotVerified = errors.New("can't verify block type")
 	ErrBlockCantDecrypt     = errors.New("can't decrypt block type")
+	ErrBlockRRBLOCK         = errors.New("invalid RRBLOCK data")
+	ErrBlockTooLarge        = errors.New("GNS block exceeds maximum size")
 )
 
 // GNSContext for key derivation
@@ -77,7 +79,9 @@ func (q *GNSQuery) Verify(b Block) (err error) {
 		if buf, err = data.Marshal(blk.Body); err != nil {
 			return
 		}
-		blk.verified, err = blk.DerivedKeySig.Verify(buf)
+		if blk.verified, err = blk.DerivedKeySig.Verify(buf); blk.verified {
+			blk.verifiedAt = util.AbsoluteTimeNow()
+		}
 
 	default:
 		err = ErrBlockTypeNotVerified
@@ -101,24 +105,43 @@ func (q *GNSQuery) Decrypt(b Block) (err error) {
 }
 
 // NewGNSQuery assembles a new Query object for the given zone and label.
+// The label is normalized first, so identical labels (e.g. different
+// Unicode representations) result in identical query keys.
 func NewGNSQuery(zkey *crypto.ZoneKey, label string) *GNSQuery {
-	// derive a public key from (pkey,label) and set the repository
-	// key as the SHA512 hash of the binary key representation.
-	// (key blinding)
-	pd, _, err := zkey.Derive(label, GNSContext)
+	nlabel, pd, err := deriveQueryKey(zkey, label)
 	if err != nil {
-		logger.Printf(logger.ERROR, "[NewGNSQuery] failed: %s", err.Error())
+		logger.Printf(logger.ERROR, "[NewGNSQuery] label '%s': %s", label, err.Error())
 		return nil
 	}
 	gq := crypto.Hash(pd.Bytes())
 	return &GNSQuery{
 		GenericQuery: *NewGenericQuery(gq, enums.BLOCK_TYPE_GNS_NAMERECORD, 0),
 		Zone:         zkey,
-		Label:        label,
+		Label:        nlabel,
 		derived:      pd,
 	}
 }
 
+// GNSQueryKey returns the DHT query key for a zone and label as defined
+// in LSD0001 (SHA512 hash of the derived zone key).
+func GNSQueryKey(zkey *crypto.ZoneKey, label string) (*crypto.HashCode, error) {
+	_, pd, err := deriveQueryKey(zkey, label)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Hash(pd.Bytes()), nil
+}
+
+// deriveQueryKey normalizes the label and derives a public key from
+// (zkey,label) that is the base of the repository key (key blinding).
+func deriveQueryKey(zkey *crypto.ZoneKey, label string) (nlabel string, pd *crypto.ZoneKey, err error) {
+	if nlabel, err = util.NormalizeLabel(label); err != nil {
+		return
+	}
+	pd, _, err = zkey.Derive(nlabel, GNSContext)
+	return
+}
+
 //----------------------------------------------------------------------
 // GNS blocks
 //----------------------------------------------------------------------
@@ -140,10 +163,17 @@ type GNSBlock struct {
 	Body          *SignedGNSBlockData
 
 	// transient data (not serialized)
-	checked   bool   // block integrity checked
-	verified  bool   // block signature verified (internal)
-	decrypted bool   // block decrypted (internal)
-	data      []byte // decrypted data
+	checked    bool              // block integrity checked
+	verified   bool              // block signature verified (internal)
+	verifiedAt util.AbsoluteTime // time of signature verification
+	decrypted  bool              // block decrypted (internal)
+	data       []byte            // decrypted data
+}
+
+// Verified returns the time the block signature was verified in a query
+// (zero if the block is not verified).
+func (b *GNSBlock) Verified() util.AbsoluteTime {
+	return b.verifiedAt
 }
 
 // Payload returns the decrypted block data (or nil)
@@ -242,6 +272,113 @@ func (b *GNSBlock) Verify() (ok bool, err error) {
 	return b.DerivedKeySig.Verify(buf)
 }
 
+//----------------------------------------------------------------------
+// Offline block handling: create and verify GNS blocks without any
+// running services (e.g. in zone signers or auditors).
+//----------------------------------------------------------------------
+
+// EncryptRecordSet returns the encrypted (and padded) RDATA of a record
+// set for a label in a zone as used in the payload of a GNS block. The
+// label is normalized before use.
+func EncryptRecordSet(zk *crypto.ZoneKey, label string, rs *RecordSet, expire util.AbsoluteTime) ([]byte, error) {
+	nlabel, err := util.NormalizeLabel(label)
+	if err != nil {
+		return nil, err
+	}
+	rs.SetPadding()
+	return zk.Encrypt(rs.RDATA(), nlabel, expire)
+}
+
+// DecryptRecordSet returns the record set from the encrypted payload of
+// a GNS block for a label in a zone.
+func DecryptRecordSet(zk *crypto.ZoneKey, label string, expire util.AbsoluteTime, payload []byte) (*RecordSet, error) {
+	nlabel, err := util.NormalizeLabel(label)
+	if err != nil {
+		return nil, err
+	}
+	rdata, err := zk.Decrypt(payload, nlabel, expire)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordSetFromRDATA(0, rdata)
+}
+
+// NewGNSBlockFromRecords assembles a signed GNS block for a label in a
+// zone: the record set is encrypted with the public zone key and the
+// block is signed with the private key derived from zone and label.
+// All records in the set are published; private records must be
+// removed by the caller.
+func NewGNSBlockFromRecords(zp *crypto.ZonePrivate, label string, rs *RecordSet, expire util.AbsoluteTime) (blk *GNSBlock, err error) {
+	if err = rs.CheckSize(); err != nil {
+		return
+	}
+	var nlabel string
+	if nlabel, err = util.NormalizeLabel(label); err != nil {
+		return
+	}
+	blk, _ = NewGNSBlock().(*GNSBlock)
+	blk.Body.Expire = expire
+	var rdata []byte
+	if rdata, err = EncryptRecordSet(zp.Public(), nlabel, rs, expire); err != nil {
+		return
+	}
+	blk.SetData(rdata)
+	var dzp *crypto.ZonePrivate
+	if dzp, _, err = zp.Derive(nlabel, GNSContext); err != nil {
+		return
+	}
+	err = blk.Sign(dzp)
+	return
+}
+
+// NewGNSBlockFromRRBLOCK parses a GNS block in RRBLOCK format (see
+// GNSBlock.RRBLOCK). The block is neither verified nor decrypted.
+func NewGNSBlockFromRRBLOCK(buf []byte) (blk *GNSBlock, err error) {
+	// check size of block
+	if len(buf) < 16 || int(binary.BigEndian.Uint32(buf[:4])) != len(buf) {
+		return nil, ErrBlockRRBLOCK
+	}
+	// read signature (with derived zone key)
+	sig := new(crypto.ZoneSignature)
+	if err = data.Unmarshal(sig, buf[4:]); err != nil {
+		return nil, ErrBlockRRBLOCK
+	}
+	if err = sig.Init(); err != nil {
+		return
+	}
+	pos := 8 + int(sig.KeySize()+sig.SigSize())
+	if pos+8 > len(buf) {
+		return nil, ErrBlockRRBLOCK
+	}
+	// assemble block
+	blk, _ = NewGNSBlock().(*GNSBlock)
+	blk.DerivedKeySig = sig
+	blk.Body.Expire.Val = binary.BigEndian.Uint64(buf[pos : pos+8])
+	blk.SetData(util.Clone(buf[pos+8:]))
+	return
+}
+
+// Records verifies a GNS block for a label in a zone and returns the
+// decrypted record set. The block must be signed with the key derived
+// from zone and label.
+func (b *GNSBlock) Records(zk *crypto.ZoneKey, label string) (*RecordSet, error) {
+	nlabel, err := util.NormalizeLabel(label)
+	if err != nil {
+		return nil, err
+	}
+	query := &GNSQuery{Zone: zk, Label: nlabel}
+	if err = query.Verify(b); err != nil {
+		return nil, err
+	}
+	if !b.verified {
+		return nil, ErrBlockInvalidSig
+	}
+	if err = query.Decrypt(b); err != nil {
+		return nil, err
+	}
+	return NewRecordSetFromRDATA(0, b.data)
+}
+
 //----------------------------------------------------------------------
 // Resource record
 //----------------------------------------------------------------------
@@ -270,15 +407,16 @@ func NewRecordSetFromRDATA(count uint32, rdata []byte) (rs *RecordSet, err error
 
 	// do we know the number of records?
 	if count == 0 {
-		// no: try to compute from rdata
-		var size uint16
-		for pos := 8; pos < len(rdata); {
-			if err = binary.Read(bytes.NewReader(rdata[pos:pos+2]), binary.BigEndian, &size); err != nil {
-				err = nil
+		// no: try to compute from rdata. Records are followed by
+		// (zeroed) padding; a record type of 0 marks its start.
+		for pos := 0; pos+16 <= len(rdata); {
+			size := int(binary.BigEndian.Uint16(rdata[pos+8 : pos+10]))
+			rtype := binary.BigEndian.Uint32(rdata[pos+12 : pos+16])
+			if rtype == 0 || pos+16+size > len(rdata) {
 				break
 			}
 			count++
-			pos += int(size) + 16
+			pos += size + 16
 		}
 	}
 	if count == 0 {
@@ -302,21 +440,63 @@ func (rs *RecordSet) AddRecord(rec *ResourceRecord) {
 
 // SetPadding (re-)calculates and allocates the padding.
 func (rs *RecordSet) SetPadding() {
-	// do not add padding to single delegation record
-	typ := rs.Records[0].RType
-	if len(rs.Records) == 1 && (typ == enums.GNS_TYPE_PKEY || typ == enums.GNS_TYPE_EDKEY) {
+	// no padding for empty record sets and single delegation records
+	switch len(rs.Records) {
+	case 0:
+		rs.Padding = nil
 		return
+	case 1:
+		if typ := rs.Records[0].RType; typ == enums.GNS_TYPE_PKEY || typ == enums.GNS_TYPE_EDKEY {
+			return
+		}
 	}
-	// compute padding size
-	size := 0
+	size, padded := rs.sizes()
+	rs.Padding = make([]byte, padded-size)
+}
+
+// sizes returns the size of the records and of the padded record set.
+func (rs *RecordSet) sizes() (size, padded int) {
 	for _, rr := range rs.Records {
 		size += int(rr.Size) + 16
 	}
-	n := 1
-	for n < size {
-		n <<= 1
+	if len(rs.Records) == 1 {
+		if typ := rs.Records[0].RType; typ == enums.GNS_TYPE_PKEY || typ == enums.GNS_TYPE_EDKEY {
+			return size, size
+		}
+	}
+	padded = 1
+	for padded < size {
+		padded <<= 1
+	}
+	return
+}
+
+// Size limits of GNS blocks: the RRBLOCK (size, zone key type, derived
+// key, signature, expiration and the encrypted record set) must not exceed
+// the maximum block size; encryption may add an authentication tag.
+const (
+	rrblockOverhead  = 4 + 4 + 32 + 64 + 8 + 16
+	MaxRecordSetSize = enums.GNS_MAX_BLOCK_SIZE - rrblockOverhead
+)
+
+// Size returns the size of the record set in a GNS block (RDATA with
+// padding). Padding to a power of two limits the records of a label to
+// about half the maximum block size.
+func (rs *RecordSet) Size() int {
+	if len(rs.Records) == 0 {
+		return 0
+	}
+	_, padded := rs.sizes()
+	return padded
+}
+
+// CheckSize returns ErrBlockTooLarge if the record set doesn't fit into a
+// GNS block.
+func (rs *RecordSet) CheckSize() error {
+	if size := rs.Size(); size > MaxRecordSetSize {
+		return fmt.Errorf("%w: record set of %d bytes (max. %d)", ErrBlockTooLarge, size, MaxRecordSetSize)
 	}
-	rs.Padding = make([]byte, n-size)
+	return nil
 }
 
 // Expire returns the earliest expiration timestamp for the records.
@@ -0,0 +1,116 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package blocks
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"gnunet/enums"
+)
+
+//----------------------------------------------------------------------
+// BOX records
+//
+// A BOX record embeds a record (TLSA, SRV, ...) for a protocol and a
+// service port, so that names like "_443._tcp.example" can be stored
+// under the label "example". The record data is the protocol number
+// (16 bit), the service port (16 bit) and the type of the embedded record
+// (32 bit), followed by the data of the embedded record (all numbers in
+// network byte order).
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrNoBoxRecord  = errors.New("not a BOX record")
+	ErrBoxTooShort  = errors.New("BOX record too short")
+	ErrBoxTooLarge  = errors.New("embedded record too large for BOX")
+	ErrBoxRecursion = errors.New("BOX records can't be nested")
+)
+
+// size of the BOX header (protocol, service and record type)
+const boxHeaderSize = 8
+
+// NewBoxRecord returns a BOX record that embeds a record for a protocol
+// and a service port. The BOX inherits expiration and flags of the
+// embedded record.
+func NewBoxRecord(proto, svc uint16, rec *ResourceRecord) (*ResourceRecord, error) {
+	if rec.RType == enums.GNS_TYPE_BOX {
+		return nil, ErrBoxRecursion
+	}
+	size := boxHeaderSize + len(rec.Data)
+	if size > 0xffff {
+		return nil, ErrBoxTooLarge
+	}
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint16(buf[0:2], proto)
+	binary.BigEndian.PutUint16(buf[2:4], svc)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(rec.RType))
+	copy(buf[boxHeaderSize:], rec.Data)
+	return &ResourceRecord{
+		Expire: rec.Expire,
+		Size:   uint16(size),
+		Flags:  rec.Flags,
+		RType:  enums.GNS_TYPE_BOX,
+		Data:   buf,
+	}, nil
+}
+
+// Unbox returns protocol, service port and the embedded record of a BOX
+// record. The embedded record inherits expiration and flags of the BOX.
+func (r *ResourceRecord) Unbox() (proto, svc uint16, rec *ResourceRecord, err error) {
+	if r.RType != enums.GNS_TYPE_BOX {
+		err = ErrNoBoxRecord
+		return
+	}
+	if len(r.Data) < boxHeaderSize {
+		err = ErrBoxTooShort
+		return
+	}
+	proto = binary.BigEndian.Uint16(r.Data[0:2])
+	svc = binary.BigEndian.Uint16(r.Data[2:4])
+	rdata := r.Data[boxHeaderSize:]
+	rec = &ResourceRecord{
+		Expire: r.Expire,
+		Size:   uint16(len(rdata)),
+		Flags:  r.Flags,
+		RType:  enums.GNSType(binary.BigEndian.Uint32(r.Data[4:8])),
+		Data:   append([]byte{}, rdata...),
+	}
+	if rec.RType == enums.GNS_TYPE_BOX {
+		err = ErrBoxRecursion
+	}
+	return
+}
+
+// Unbox returns the records embedded in the BOX records of the set for a
+// protocol and service port. Invalid BOX records are skipped.
+func (rs *RecordSet) Unbox(proto, svc uint16) *RecordSet {
+	out := NewRecordSet()
+	for _, r := range rs.Records {
+		if r.RType != enums.GNS_TYPE_BOX {
+			continue
+		}
+		p, s, rec, err := r.Unbox()
+		if err == nil && p == proto && s == svc {
+			out.AddRecord(rec)
+		}
+	}
+	return out
+}
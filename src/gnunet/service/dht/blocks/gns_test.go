@@ -21,10 +21,12 @@ package blocks
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/util"
 	"testing"
+	"time"
 
 	"github.com/bfix/gospel/data"
 )
@@ -95,6 +97,9 @@ func TestGNSBlock(t *testing.T) {
 		t.Logf("got: %s", hex.EncodeToString(qkey))
 		t.Fatal("query key mismatch")
 	}
+	if qk, err := GNSQueryKey(zk, LABEL); err != nil || !bytes.Equal(QKEY, qk.Data) {
+		t.Fatal("query key mismatch (GNSQueryKey)")
+	}
 
 	// check derived public key (form zone key and label)
 	dkey2, _, err := zk.Derive(LABEL, "gns")
@@ -123,6 +128,158 @@ func TestGNSBlock(t *testing.T) {
 
 }
 
+// TestGNSQueryLabel checks that different representations of the same
+// label result in the same query key.
+func TestGNSQueryLabel(t *testing.T) {
+	zk, err := crypto.NewZoneKey(append([]byte{0, 1, 0, 0}, util.NewRndArray(32)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "Café" with composed and decomposed "é"
+	q1 := NewGNSQuery(zk, "caf\u00e9")
+	for _, label := range []string{"Caf\u00e9", "cafe\u0301", "CAFE\u0301"} {
+		q2 := NewGNSQuery(zk, label)
+		if !q1.Key().Equal(q2.Key()) {
+			t.Fatalf("query key mismatch for '%s'", label)
+		}
+	}
+	// invalid labels
+	if q := NewGNSQuery(zk, ""); q != nil {
+		t.Fatal("query for empty label")
+	}
+}
+
+// TestGNSBlockRRBLOCK checks the offline assembly and verification of
+// GNS blocks in RRBLOCK format.
+func TestGNSBlockRRBLOCK(t *testing.T) {
+	for _, ztype := range []enums.GNSType{enums.GNS_TYPE_PKEY, enums.GNS_TYPE_EDKEY} {
+		zp, err := crypto.NewZonePrivate(ztype, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zk := zp.Public()
+		expire := util.AbsoluteTimeNow().Add(time.Hour)
+		rs := NewRecordSet()
+		rs.AddRecord(&ResourceRecord{
+			Expire: expire,
+			Size:   5,
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   []byte("hello"),
+		})
+		blk, err := NewGNSBlockFromRecords(zp, "Www", rs, expire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := blk.RRBLOCK()
+
+		// round-trip (label is normalized)
+		blk2, err := NewGNSBlockFromRRBLOCK(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, blk2.RRBLOCK()) {
+			t.Fatalf("%s: RRBLOCK mismatch", ztype)
+		}
+		rs2, err := blk2.Records(zk, "www")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs2.Count != 1 || !bytes.Equal(rs2.Records[0].Data, []byte("hello")) {
+			t.Fatalf("%s: record mismatch", ztype)
+		}
+		// wrong label
+		if _, err = blk2.Records(zk, "ftp"); err != ErrBlockInvalidSig {
+			t.Fatalf("%s: wrong label accepted (%v)", ztype, err)
+		}
+		// modified payload
+		buf[len(buf)-1] ^= 0xff
+		if blk2, err = NewGNSBlockFromRRBLOCK(buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = blk2.Records(zk, "www"); err != ErrBlockInvalidSig {
+			t.Fatalf("%s: modified block accepted (%v)", ztype, err)
+		}
+		// truncated block
+		if _, err = NewGNSBlockFromRRBLOCK(buf[:len(buf)-1]); err != ErrBlockRRBLOCK {
+			t.Fatalf("%s: truncated block accepted (%v)", ztype, err)
+		}
+	}
+}
+
+// TestGNSBlockMaxSize checks the size limit of record sets in GNS blocks.
+func TestGNSBlockMaxSize(t *testing.T) {
+	for _, ztype := range []enums.GNSType{enums.GNS_TYPE_PKEY, enums.GNS_TYPE_EDKEY} {
+		zp, err := crypto.NewZonePrivate(ztype, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expire := util.AbsoluteTimeNow().Add(time.Hour)
+		records := func(size int) *RecordSet {
+			rs := NewRecordSet()
+			rs.AddRecord(&ResourceRecord{
+				Expire: expire,
+				Size:   uint16(size),
+				RType:  enums.GNS_TYPE_DNS_TXT,
+				Data:   make([]byte, size),
+			})
+			return rs
+		}
+		// largest record set (padded to 32 KiB)
+		blk, err := NewGNSBlockFromRecords(zp, "big", records(32768-16), expire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := blk.RRBLOCK()
+		if len(buf) > enums.GNS_MAX_BLOCK_SIZE {
+			t.Fatalf("%s: block size %d exceeds limit", ztype, len(buf))
+		}
+		blk2, err := NewGNSBlockFromRRBLOCK(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs, err := blk2.Records(zp.Public(), "big")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs.Count != 1 || len(rs.Records[0].Data) != 32768-16 {
+			t.Fatalf("%s: record mismatch", ztype)
+		}
+		// one more byte doubles the padded size
+		if _, err = NewGNSBlockFromRecords(zp, "big", records(32768-15), expire); !errors.Is(err, ErrBlockTooLarge) {
+			t.Fatalf("%s: oversized record set accepted (%v)", ztype, err)
+		}
+	}
+}
+
+// TestRecordsetRDATA checks the RDATA round-trip of (padded) record sets.
+func TestRecordsetRDATA(t *testing.T) {
+	// empty record set has no RDATA
+	rs := NewRecordSet()
+	rs.SetPadding()
+	if buf := rs.RDATA(); len(buf) != 0 {
+		t.Fatalf("RDATA for empty record set: %d bytes", len(buf))
+	}
+	// padded record set
+	rs.AddRecord(&ResourceRecord{
+		Expire: util.AbsoluteTimeNever(),
+		Size:   5,
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   []byte("hello"),
+	})
+	rs.SetPadding()
+	buf := rs.RDATA()
+	if len(buf) != 32 {
+		t.Fatalf("wrong RDATA size %d", len(buf))
+	}
+	rs2, err := NewRecordSetFromRDATA(0, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs2.Count != 1 || !bytes.Equal(rs2.Records[0].Data, []byte("hello")) {
+		t.Fatal("record set mismatch")
+	}
+}
+
 // TestRecordsetPKEY implements the test case as defined in the GNS draft
 // (see section 13. Test vectors, case "PKEY")
 func TestRecordsetPKEY(t *testing.T) {
@@ -445,3 +602,59 @@ func TestRecordsetEDKEY(t *testing.T) {
 		t.Fatal("BDATA mismatch")
 	}
 }
+
+func TestBoxRecord(t *testing.T) {
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	tlsa := &ResourceRecord{
+		Expire: expire,
+		Size:   7,
+		Flags:  enums.GNS_FLAG_CRITICAL,
+		RType:  enums.GNS_TYPE_DNS_TLSA,
+		Data:   []byte{3, 1, 1, 0x0b, 0xad, 0xc0, 0xde},
+	}
+	box, err := NewBoxRecord(6, 443, tlsa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check wire format: protocol, service, record type and record data
+	if hex.EncodeToString(box.Data) != "000601bb000000340301010badc0de" {
+		t.Fatalf("unexpected BOX data %s", hex.EncodeToString(box.Data))
+	}
+	if box.Expire.Compare(expire) != 0 || box.Flags != tlsa.Flags || int(box.Size) != len(box.Data) {
+		t.Fatalf("unexpected BOX record %s", box)
+	}
+	if _, err = NewBoxRecord(6, 443, box); err != ErrBoxRecursion {
+		t.Fatalf("nested BOX accepted: %v", err)
+	}
+	// unbox after transport in a record set
+	rs := NewRecordSet()
+	rs.AddRecord(box)
+	if box, err = NewBoxRecord(17, 5060, &ResourceRecord{RType: enums.GNS_TYPE_DNS_SRV, Data: []byte("sip\x00")}); err != nil {
+		t.Fatal(err)
+	}
+	rs.AddRecord(box)
+	rs.AddRecord(&ResourceRecord{RType: enums.GNS_TYPE_DNS_A, Size: 4, Data: []byte{192, 0, 2, 1}})
+	rs, err = NewRecordSetFromRDATA(0, rs.RDATA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rs.Unbox(6, 443)
+	if out.Count != 1 {
+		t.Fatalf("expected 1 unboxed record, got %d", out.Count)
+	}
+	rec := out.Records[0]
+	if rec.RType != tlsa.RType || rec.Flags != tlsa.Flags || !bytes.Equal(rec.Data, tlsa.Data) || rec.Size != tlsa.Size {
+		t.Fatalf("unexpected unboxed record %s", rec)
+	}
+	if out = rs.Unbox(6, 80); out.Count != 0 {
+		t.Fatalf("unexpected records for port 80: %d", out.Count)
+	}
+	// invalid BOX records
+	if _, _, _, err = rs.Records[2].Unbox(); err != ErrNoBoxRecord {
+		t.Fatalf("A record unboxed: %v", err)
+	}
+	short := &ResourceRecord{RType: enums.GNS_TYPE_BOX, Size: 4, Data: make([]byte, 4)}
+	if _, _, _, err = short.Unbox(); err != ErrBoxTooShort {
+		t.Fatalf("short BOX unboxed: %v", err)
+	}
+}
@@ -19,6 +19,7 @@
 package blocks
 
 import (
+	"context"
 	"gnunet/crypto"
 	"gnunet/enums"
 )
@@ -73,6 +74,11 @@ type BlockHandler interface {
 	FilterResult(b Block, key *crypto.HashCode, rf ResultFilter, xQuery []byte) int
 }
 
+// BlockCheck is an additional validation of blocks of a given type that
+// depends on state outside of the block itself (like revoked zone keys).
+// It returns false if a block stored under the given key must be rejected.
+type BlockCheck func(ctx context.Context, key *crypto.HashCode) bool
+
 // BlockHandlers is a map of block query validation implementations
 // for supported block types.
 var BlockHandlers map[enums.BlockType]BlockHandler
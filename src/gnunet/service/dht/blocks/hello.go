@@ -25,9 +25,7 @@ import (
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/util"
-	"net/url"
-	"strconv"
-	"strings"
+	"gnunet/util/uri"
 	"time"
 
 	"github.com/bfix/gospel/crypto/ed25519"
@@ -46,8 +44,6 @@ var (
 // outside of GNUnet message exchange (e.g. command-line tools)
 //----------------------------------------------------------------------
 
-const helloPrefix = "gnunet://hello/"
-
 // HelloBlock is the DHT-managed block type for HELLO information.
 // It is used to create and parse HELLO URLs.
 // All addresses expire at the same time /this different from HELLO
@@ -104,69 +100,22 @@ func (h *HelloBlock) Addresses() []*util.Address {
 // gnunet://hello/<PeerID>/<signature>/<expire>?<addrs>
 // The addresses are encoded.
 func ParseHelloBlockFromURL(u string, checkExpiry bool) (h *HelloBlock, err error) {
-	// check and trim prefix
-	if !strings.HasPrefix(u, helloPrefix) {
-		err = fmt.Errorf("invalid HELLO-URL prefix: '%s'", u)
-		return
-	}
-	u = u[len(helloPrefix):]
-
-	// split remainder into parts
-	p := strings.Split(u, "/")
-	if len(p) != 3 {
-		err = fmt.Errorf("invalid HELLO-URL: '%s'", u)
-		return
-	}
-
-	// assemble HELLO data
-	h = new(HelloBlock)
-
-	// (1) parse peer public key (peer ID)
-	var buf []byte
-	if buf, err = util.DecodeStringToBinary(p[0], util.PeerPublicKeySize); err != nil {
-		return
-	}
-	h.PeerID = util.NewPeerID(buf)
-
-	// (2) parse signature
-	if buf, err = util.DecodeStringToBinary(p[1], util.PeerSignatureSize); err != nil {
-		return
-	}
-	h.Signature = util.NewPeerSignature(buf)
-
-	// (3) split last element into parts
-	q := strings.SplitN(p[2], "?", 2)
-
-	// (4) parse expiration date
-	var exp uint64
-	if exp, err = strconv.ParseUint(q[0], 10, 64); err != nil {
+	var hu *uri.HelloURI
+	if hu, err = uri.ParseHello(u); err != nil {
 		return
 	}
-	h.Expire_ = util.NewAbsoluteTimeEpoch(exp)
-	if checkExpiry && h.Expire_.Expired() {
+	if checkExpiry && hu.Expire.ExpiredSkew(util.DefaultClockSkew) {
 		err = ErrHelloExpired
 		return
 	}
-
-	// (5) process addresses.
-	h.addrs = make([]*util.Address, 0)
-	for _, a := range strings.Split(q[1], "&") {
-		// reformat to standard address format
-		ap := strings.SplitN(a, "=", 2)
-		var q string
-		if q, err = url.QueryUnescape(ap[1]); err != nil {
-			return
-		}
-		as := ap[0] + "://" + q
-		// parse address and append it to list
-		var addr *util.Address
-		if addr, err = util.ParseAddress(as); err != nil {
-			return
-		}
-		h.addrs = append(h.addrs, addr)
+	// assemble HELLO data
+	h = &HelloBlock{
+		PeerID:    hu.Peer,
+		Signature: hu.Signature,
+		Expire_:   hu.Expire,
+		addrs:     hu.Addrs,
 	}
-
-	// (6) generate raw address data so block is complete
+	// generate raw address data so block is complete
 	if err = h.finalize(); err != nil {
 		return
 	}
@@ -257,21 +206,13 @@ func (h *HelloBlock) String() string {
 
 // URL returns the HELLO URL for the data.
 func (h *HelloBlock) URL() string {
-	u := fmt.Sprintf("%s%s/%s/%d?",
-		helloPrefix,
-		h.PeerID.String(),
-		util.EncodeBinaryToString(h.Signature.Data),
-		h.Expire_.Epoch(),
-	)
-	for i, a := range h.addrs {
-		if i > 0 {
-			u += "&"
-		}
-		au := a.URI()
-		p := strings.SplitN(au, "://", 2)
-		u += p[0] + "=" + url.QueryEscape(p[1])
+	hu := &uri.HelloURI{
+		Peer:      h.PeerID,
+		Signature: h.Signature,
+		Expire:    h.Expire_,
+		Addrs:     h.Addresses(),
 	}
-	return u
+	return hu.String()
 }
 
 // Equal returns true if two HELLOs are the same. The expiration
@@ -0,0 +1,66 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package blocks
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+func TestRecordsetFromRDATA(t *testing.T) {
+	// assemble record set with padding
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := &RecordSet{
+		Count: 2,
+		Records: []*ResourceRecord{
+			{
+				Expire: expire,
+				Size:   4,
+				RType:  enums.GNS_TYPE_DNS_A,
+				Data:   []byte{10, 0, 0, 1},
+			},
+			{
+				Expire: expire,
+				Size:   5,
+				RType:  enums.GNS_TYPE_DNS_TXT,
+				Data:   []byte("hello"),
+			},
+		},
+	}
+	rs.SetPadding()
+	rdata := rs.RDATA()
+
+	// parse RDATA without known number of records
+	rs2, err := NewRecordSetFromRDATA(0, rdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs2.Count != 2 || len(rs2.Records) != 2 {
+		t.Fatalf("wrong number of records: %d", rs2.Count)
+	}
+	for i, rr := range rs2.Records {
+		if rr.RType != rs.Records[i].RType || !bytes.Equal(rr.Data, rs.Records[i].Data) {
+			t.Fatalf("record #%d mismatch", i)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Bootstrap cache: On shutdown the best validated HELLOs of other peers
+// are written to a file; on start-up these HELLOs are used to contact
+// peers before the configured bootstrap nodes are tried. A node can
+// re-join the network even if no bootstrap node is available.
+//----------------------------------------------------------------------
+
+// Bootstrap cache file format
+const (
+	BootCacheMagic   = 0x474e4243 // "GNBC"
+	BootCacheVersion = 1          // current file format version
+
+	DefaultBootCacheSize = 64 // default number of cached HELLOs
+)
+
+// Error codes
+var (
+	ErrBootCacheFormat  = errors.New("not a bootstrap cache file")
+	ErrBootCacheVersion = errors.New("unsupported bootstrap cache version")
+)
+
+// bootCacheHeader is the (version-independent) start of a bootstrap
+// cache file.
+type bootCacheHeader struct {
+	Magic   uint32 `order:"big"` // file magic
+	Version uint16 `order:"big"` // file format version
+}
+
+// bootCacheFile is the layout of a version 1 cache file.
+type bootCacheFile struct {
+	Magic   uint32            `order:"big"`  // file magic
+	Version uint16            `order:"big"`  // file format version
+	Count   uint16            `order:"big"`  // number of entries
+	Entries []*bootCacheEntry `size:"Count"` // list of HELLOs
+}
+
+// bootCacheEntry is a single HELLO block in a cache file.
+type bootCacheEntry struct {
+	Size  uint16 `order:"big"` // size of HELLO block
+	Hello []byte `size:"Size"` // binary HELLO block
+}
+
+// SaveBootCache writes up to n (or DefaultBootCacheSize if n <= 0) HELLOs
+// of other peers to a file. Only unexpired HELLOs with validated addresses
+// are stored; HELLOs of peers in the routing table and HELLOs with longer
+// lifetime are preferred.
+func (m *Module) SaveBootCache(fname string, n int) error {
+	if n <= 0 {
+		n = DefaultBootCacheSize
+	}
+	// collect candidates
+	type candidate struct {
+		hb     *blocks.HelloBlock
+		active bool
+	}
+	var list []*candidate
+	_ = m.rtable.helloCache.ProcessRange(func(key string, hb *blocks.HelloBlock, _ int) error {
+		if hb.Expire().Expired() || !m.helloValidated(hb) {
+			return nil
+		}
+		_, active := m.rtable.list.Get(NewPeerAddress(hb.PeerID).String(), 0)
+		list = append(list, &candidate{hb, active})
+		return nil
+	}, true)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].active != list[j].active {
+			return list[i].active
+		}
+		return list[i].hb.Expire().Compare(list[j].hb.Expire()) > 0
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	hellos := make([]*blocks.HelloBlock, len(list))
+	for i, c := range list {
+		hellos[i] = c.hb
+	}
+	if err := WriteBootCache(fname, hellos); err != nil {
+		return err
+	}
+	logger.Printf(logger.INFO, "[dht] %d HELLOs saved to bootstrap cache", len(hellos))
+	return nil
+}
+
+// LoadBootCache reads HELLOs from a bootstrap cache file. Valid HELLOs are
+// added to the HELLO cache and their addresses are passed to core; the
+// addresses are returned so the caller can contact the peers. A missing
+// file is not an error.
+func (m *Module) LoadBootCache(ctx context.Context, fname string) (addrs []*util.Address, err error) {
+	var hellos []*blocks.HelloBlock
+	if hellos, err = ReadBootCache(fname); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	// process cached HELLOs
+	for _, hb := range hellos {
+		if hb.Expire().Expired() || hb.PeerID.Equal(m.core.PeerID()) {
+			continue
+		}
+		if ok, err := hb.Verify(); !ok || err != nil {
+			logger.Printf(logger.WARN, "[dht] bootstrap cache: HELLO of %s not verified", hb.PeerID.Short())
+			continue
+		}
+		m.rtable.CacheHello(hb)
+		m.core.Learn(ctx, hb.PeerID, hb.Addresses(), "dht-bootcache")
+		addrs = append(addrs, hb.Addresses()...)
+	}
+	logger.Printf(logger.INFO, "[dht] %d addresses loaded from bootstrap cache", len(addrs))
+	return addrs, nil
+}
+
+// WriteBootCache writes a list of HELLO blocks to a bootstrap cache file.
+// The data is written to a temporary file first that is renamed on
+// success, so an existing cache survives a failed write.
+func WriteBootCache(fname string, hellos []*blocks.HelloBlock) error {
+	bc := &bootCacheFile{
+		Magic:   BootCacheMagic,
+		Version: BootCacheVersion,
+		Count:   uint16(len(hellos)),
+		Entries: make([]*bootCacheEntry, len(hellos)),
+	}
+	for i, hb := range hellos {
+		buf := hb.Bytes()
+		bc.Entries[i] = &bootCacheEntry{
+			Size:  uint16(len(buf)),
+			Hello: buf,
+		}
+	}
+	buf, err := data.Marshal(bc)
+	if err != nil {
+		return err
+	}
+	tmp := fname + ".tmp"
+	if err = os.WriteFile(tmp, buf, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+// ReadBootCache returns the HELLO blocks from a bootstrap cache file.
+// Invalid entries are skipped.
+func ReadBootCache(fname string) (hellos []*blocks.HelloBlock, err error) {
+	var buf []byte
+	if buf, err = os.ReadFile(fname); err != nil {
+		return
+	}
+	// check file format and version
+	hdr := new(bootCacheHeader)
+	if len(buf) < 6 {
+		return nil, ErrBootCacheFormat
+	}
+	if err = data.Unmarshal(hdr, buf[:6]); err != nil {
+		return
+	}
+	if hdr.Magic != BootCacheMagic {
+		return nil, ErrBootCacheFormat
+	}
+	if hdr.Version != BootCacheVersion {
+		return nil, ErrBootCacheVersion
+	}
+	// read entries
+	bc := new(bootCacheFile)
+	if err = data.Unmarshal(bc, buf); err != nil {
+		return
+	}
+	for _, e := range bc.Entries {
+		var hb *blocks.HelloBlock
+		if hb, err = blocks.ParseHelloBlockFromBytes(e.Hello); err != nil {
+			logger.Printf(logger.WARN, "[dht] bootstrap cache: invalid HELLO: %s", err.Error())
+			continue
+		}
+		hellos = append(hellos, hb)
+	}
+	return hellos, nil
+}
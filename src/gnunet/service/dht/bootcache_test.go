@@ -0,0 +1,101 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+)
+
+// newTestHello creates a signed HELLO block with a single address.
+func newTestHello(t *testing.T, addr string) *blocks.HelloBlock {
+	t.Helper()
+	pk, sk := ed25519.NewKeypair()
+	a, err := util.ParseAddress(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb := blocks.InitHelloBlock(util.NewPeerID(pk.Bytes()), []*util.Address{a}, time.Hour)
+	sig, err := sk.EdSign(hb.SignedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb.Signature = util.NewPeerSignature(sig.Bytes())
+	return hb
+}
+
+func TestBootCache(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "bootstrap.cache")
+
+	// missing file
+	if _, err := ReadBootCache(fname); !os.IsNotExist(err) {
+		t.Fatalf("unexpected error for missing file: %v", err)
+	}
+
+	// write and read cache
+	hellos := []*blocks.HelloBlock{
+		newTestHello(t, "ip+udp://1.2.3.4:2086"),
+		newTestHello(t, "ip+udp://[2001:db8::1]:2086"),
+	}
+	if err := WriteBootCache(fname, hellos); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ReadBootCache(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(hellos) {
+		t.Fatalf("expected %d HELLOs, got %d", len(hellos), len(list))
+	}
+	for i, hb := range list {
+		if !hb.Equal(hellos[i]) {
+			t.Fatalf("HELLO #%d mismatch", i)
+		}
+		if ok, err := hb.Verify(); !ok || err != nil {
+			t.Fatalf("HELLO #%d not verified", i)
+		}
+	}
+
+	// unsupported version
+	buf, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[5] = BootCacheVersion + 1
+	if err = os.WriteFile(fname, buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadBootCache(fname); err != ErrBootCacheVersion {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	// not a cache file
+	if err = os.WriteFile(fname, []byte("some text"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadBootCache(fname); err != ErrBootCacheFormat {
+		t.Fatalf("expected format error, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"sync"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// MaxKnownZones is the number of GNS zones remembered for revocation checks.
+const MaxKnownZones = 1024
+
+// blockChecks holds the registered block checks by block type.
+type blockChecks struct {
+	sync.RWMutex
+	list map[enums.BlockType][]blocks.BlockCheck
+}
+
+// newBlockChecks returns an empty list of block checks.
+func newBlockChecks() *blockChecks {
+	return &blockChecks{
+		list: make(map[enums.BlockType][]blocks.BlockCheck),
+	}
+}
+
+// AddBlockCheck registers an additional check for blocks of given type.
+// Checks are run on PUT and RESULT messages and on blocks served from
+// local storage; a block is rejected if any check fails.
+func (m *Module) AddBlockCheck(btype enums.BlockType, chk blocks.BlockCheck) {
+	m.checks.Lock()
+	defer m.checks.Unlock()
+	m.checks.list[btype] = append(m.checks.list[btype], chk)
+}
+
+// checkBlock returns true if a block of given type and key passes all
+// registered checks.
+func (m *Module) checkBlock(ctx context.Context, btype enums.BlockType, key *crypto.HashCode) bool {
+	m.checks.RLock()
+	list := m.checks.list[btype]
+	m.checks.RUnlock()
+	for _, chk := range list {
+		if !chk(ctx, key) {
+			return false
+		}
+	}
+	return true
+}
+
+//----------------------------------------------------------------------
+// Revocation checks for GNS blocks
+//----------------------------------------------------------------------
+
+// zoneIndex maps GNS query keys to the zone keys they are derived from.
+// A GNS block only contains the blinded (derived) key, so the zone of a
+// block is only known if it was used in a local query.
+type zoneIndex struct {
+	sync.Mutex
+	zones map[string]*crypto.ZoneKey
+	order []string
+}
+
+// newZoneIndex returns an empty zone index.
+func newZoneIndex() *zoneIndex {
+	return &zoneIndex{
+		zones: make(map[string]*crypto.ZoneKey),
+	}
+}
+
+// add the zone for a query key; the oldest entry is dropped if the
+// index is full.
+func (zi *zoneIndex) add(key *crypto.HashCode, zkey *crypto.ZoneKey) {
+	zi.Lock()
+	defer zi.Unlock()
+	k := string(key.Data)
+	if _, ok := zi.zones[k]; ok {
+		return
+	}
+	if len(zi.order) == MaxKnownZones {
+		delete(zi.zones, zi.order[0])
+		zi.order = zi.order[1:]
+	}
+	zi.zones[k] = zkey
+	zi.order = append(zi.order, k)
+}
+
+// get the zone for a query key (or nil if unknown)
+func (zi *zoneIndex) get(key *crypto.HashCode) *crypto.ZoneKey {
+	zi.Lock()
+	defer zi.Unlock()
+	return zi.zones[string(key.Data)]
+}
+
+// learnZone remembers the zone of a local GNS query.
+func (m *Module) learnZone(query blocks.Query) {
+	if gq, ok := query.(*blocks.GNSQuery); ok && gq.Zone != nil {
+		m.zones.add(gq.Key(), gq.Zone)
+	}
+}
+
+// checkRevocation is the block check for GNS blocks: a block is rejected
+// if its zone is known and the zone key is revoked.
+func (m *Module) checkRevocation(ctx context.Context, key *crypto.HashCode) bool {
+	zkey := m.zones.get(key)
+	if zkey == nil {
+		return true
+	}
+	valid, err := m.RevocationQuery(ctx, zkey)
+	if err != nil {
+		logger.Printf(logger.WARN, "[dht] revocation check for zone %s failed: %s", zkey.ID(), err.Error())
+		return true
+	}
+	if !valid {
+		logger.Printf(logger.INFO, "[dht] GNS block of revoked zone %s rejected", zkey.ID())
+	}
+	return valid
+}
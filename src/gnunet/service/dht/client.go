@@ -0,0 +1,686 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/transport"
+	"gnunet/util"
+	"gnunet/util/keyspace"
+
+	"github.com/bfix/gospel/logger"
+	"github.com/bfix/gospel/math"
+)
+
+//----------------------------------------------------------------------
+// Handle DHT client messages (service socket)
+//
+// The client protocol is compatible with the GNUnet C implementation,
+// so the C tools (gnunet-dht-put/get/monitor) can be used with this
+// service:
+//   * PUT requests are not acknowledged by the service (the C client
+//     API reports completion as soon as the message is sent).
+//   * Route options (RECORD_ROUTE, DEMULTIPLEX_EVERYWHERE and
+//     FIND_APPROXIMATE) of PUT and GET requests are passed into the P2P
+//     messages; other flags are managed by the peers and dropped.
+//   * The expiration of a PUT request is either an absolute time or a
+//     relative TTL (flag DHT_RO_RELATIVE_EXPIRATION, gnunet-go only).
+//     Expirations beyond the configured max. TTL are capped; expired
+//     PUTs and a TTL of zero are rejected (and logged).
+//   * GET requests are identified by a unique ID chosen by the client.
+//     The ID is opaque to the service and is returned as-is in every
+//     result for the request.
+//   * A GET request is active until the client stops it (GET_STOP) or
+//     the client connection is closed; no results are sent for a
+//     stopped request.
+//   * Clients can enable flow control for a GET request by granting
+//     credits (GET_CREDIT, gnunet-go only): each result consumes a credit;
+//     without credits results are queued (up to MaxClientQueue, further
+//     results are dropped) until more credits are granted.
+//   * Clients can limit the number of results for a GET request
+//     (GET_LIMIT, gnunet-go only): results are collected for a time
+//     window; the best results (closest to the query key, then latest
+//     expiration) are sent in that order and the request is ended with
+//     a GET_DONE message. Flow control applies to limited requests too.
+//   * Clients can ask for the first exact result only (option flag
+//     DHT_RO_FIRST_RESULT, gnunet-go only): the request is forwarded to
+//     the 'alpha' closest peers in parallel; the first exact result is
+//     sent followed by a GET_DONE message. All forwarded branches are
+//     torn down locally (results arriving later are dropped), as R5N has
+//     no way to stop a GET on other peers. A result limit takes
+//     precedence over the flag.
+//   * Clients can ask for a confirmation of the next PUT request for a
+//     key (PUT_VERIFY, gnunet-go only): after storing, the block is
+//     sampled through neighbors (see confirm()) and the outcome is sent
+//     back in a PUT_CONFIRM message.
+//----------------------------------------------------------------------
+
+// clientFlags are the route options accepted from clients
+const clientFlags = enums.DHT_RO_DEMULTIPLEX_EVERYWHERE | enums.DHT_RO_RECORD_ROUTE | enums.DHT_RO_FIND_APPROXIMATE
+
+// DefaultAlpha is the number of parallel branches of first-result
+// lookups if not configured.
+var DefaultAlpha = 3
+
+// CtxAlpha is the context key for the number of parallel branches of a
+// client lookup (value is int).
+const CtxAlpha = core.CtxKey("dht:alpha")
+
+// DefaultMaxTTL is the max. expiration of client PUTs if not configured.
+var DefaultMaxTTL = 7 * 24 * time.Hour
+
+// MaxClientQueue is the max. number of results queued for a flow-controlled
+// GET request without credits.
+var MaxClientQueue = 64
+
+// Collection window for limited GET requests: the default is used if the
+// client specifies no timeout; longer timeouts are capped.
+var (
+	DefaultClientWindow = 10 * time.Second
+	MaxClientWindow     = 5 * time.Minute
+)
+
+// clientResult is a collected result of a limited GET request
+type clientResult struct {
+	out  *message.DHTClientResultMsg // result message
+	dist *math.Int                   // distance to query key
+	hash []byte                      // hash of block (tie-breaker)
+}
+
+// orderResults sorts collected results deterministically: closest to the
+// query key first, then by latest expiration and finally by block hash.
+func orderResults(list []*clientResult) {
+	sort.Slice(list, func(i, j int) bool {
+		if c := list[i].dist.Cmp(list[j].dist); c != 0 {
+			return c < 0
+		}
+		if c := list[i].out.Expire.Compare(list[j].out.Expire); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(list[i].hash, list[j].hash) < 0
+	})
+}
+
+// ClientResponder relays results for a client GET request as
+// DHT-CLIENT-RESULT messages. Results already known to the client are
+// filtered out.
+type ClientResponder struct {
+	sync.Mutex
+
+	id       uint64              // unique request ID (client-chosen)
+	back     transport.Responder // client connection
+	known    map[string]bool     // hashes of known results
+	stopped  bool                // request stopped by client
+	window   bool                // flow control enabled
+	credits  uint32              // number of results client accepts
+	queue    []message.Message   // results waiting for credits
+	limit    uint32              // max. number of results (0 = unlimited)
+	key      *crypto.HashCode    // query key (ordering of limited results)
+	results  []*clientResult     // collected results of a limited request
+	finished bool                // limited (or first-result) request finished
+	first    func()              // called after the first exact result (or nil)
+	found    bool                // a result was received
+}
+
+// NewClientResponder creates a new responder for a client request
+func NewClientResponder(id uint64, back transport.Responder) *ClientResponder {
+	return &ClientResponder{
+		id:    id,
+		back:  back,
+		known: make(map[string]bool),
+	}
+}
+
+// Send interface method: translate a DHT-P2P-RESULT message into a
+// DHT-CLIENT-RESULT message and relay it to the client.
+func (r *ClientResponder) Send(ctx context.Context, msg message.Message) error {
+	res, ok := msg.(*message.DHTP2PResultMsg)
+	if !ok {
+		logger.Printf(logger.WARN, "[dht-client] %d not a DHT-RESULT -- skipped", msg.Type())
+		return nil
+	}
+	// check if the result is (still) required by the client
+	hash := crypto.Hash(res.Block)
+	key := hash.String()
+	r.Lock()
+	if r.stopped || r.finished || r.known[key] {
+		r.Unlock()
+		return nil
+	}
+	r.known[key] = true
+	if !r.found {
+		// count successful requests (statistics)
+		r.found = true
+		service.Count("dht:get-found", 1)
+	}
+	out := message.NewDHTClientResultFromP2P(r.id, res)
+	if r.limit > 0 {
+		// collect result; keep only the best results
+		r.results = append(r.results, &clientResult{
+			out:  out,
+			dist: keyspace.Distance(r.key.Data, out.Key.Data),
+			hash: hash.Data,
+		})
+		orderResults(r.results)
+		if len(r.results) > int(r.limit) {
+			r.results = r.results[:r.limit]
+		}
+		r.Unlock()
+		return nil
+	}
+	list := make([]message.Message, 0, 2)
+	if r.admit(out) {
+		list = append(list, out)
+	}
+	// end a first-result request with an exact result
+	var first func()
+	if r.first != nil && res.Flags&enums.DHT_RO_FIND_APPROXIMATE == 0 {
+		r.finished = true
+		first = r.first
+		if done := message.NewDHTClientGetDoneMsg(r.id, 1); r.admit(done) {
+			list = append(list, done)
+		}
+	}
+	r.Unlock()
+
+	// ending a first-result request cancels the request context, so the
+	// messages are sent before.
+	var err error
+	for _, out := range list {
+		if err = r.back.Send(ctx, out); err != nil {
+			break
+		}
+	}
+	if first != nil {
+		first()
+	}
+	return err
+}
+
+// admit a message for sending (called with lock held): returns false if
+// the message was queued (or dropped) because of missing credits. Only
+// results consume credits; a completion message is never dropped and
+// never overtakes queued results.
+func (r *ClientResponder) admit(out message.Message) bool {
+	if !r.window {
+		return true
+	}
+	_, done := out.(*message.DHTClientGetDoneMsg)
+	if len(r.queue) == 0 && (done || r.credits > 0) {
+		if !done {
+			r.credits--
+		}
+		return true
+	}
+	// queue message until client grants credits
+	if done || len(r.queue) < MaxClientQueue {
+		r.queue = append(r.queue, out)
+	} else {
+		logger.Printf(logger.WARN, "[dht-client] queue for #%d full -- result dropped", r.id)
+	}
+	return false
+}
+
+// SetLimit turns the responder into a limited responder: results are
+// collected (and ordered by distance to the query key) until Finish is
+// called.
+func (r *ClientResponder) SetLimit(key *crypto.HashCode, maxResults uint32) {
+	r.Lock()
+	defer r.Unlock()
+	r.key = key
+	r.limit = maxResults
+}
+
+// SetFirst ends the request with the first exact result: the function is
+// called (once) when it is received.
+func (r *ClientResponder) SetFirst(f func()) {
+	r.Lock()
+	defer r.Unlock()
+	r.first = f
+}
+
+// Finish a limited request: send the collected results in order, followed
+// by a completion message. Returns true if all messages were sent (and
+// none are waiting for credits).
+func (r *ClientResponder) Finish(ctx context.Context) (drained bool, err error) {
+	r.Lock()
+	if r.stopped || r.finished {
+		r.Unlock()
+		return true, nil
+	}
+	r.finished = true
+	var list []message.Message
+	for _, res := range r.results {
+		if r.admit(res.out) {
+			list = append(list, res.out)
+		}
+	}
+	done := message.NewDHTClientGetDoneMsg(r.id, uint32(len(r.results)))
+	if r.admit(done) {
+		list = append(list, done)
+	}
+	r.results = nil
+	drained = len(r.queue) == 0
+	r.Unlock()
+
+	for _, out := range list {
+		if err = r.back.Send(ctx, out); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Drained returns true if a limited (or first-result) request is finished
+// and all messages are sent to the client.
+func (r *ClientResponder) Drained() bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.finished && len(r.queue) == 0
+}
+
+// Grant credits for results: enables flow control and sends queued
+// results as long as there are credits.
+func (r *ClientResponder) Grant(ctx context.Context, credits uint32) (err error) {
+	r.Lock()
+	r.window = true
+	r.credits += credits
+	var list []message.Message
+	for len(r.queue) > 0 && !r.stopped {
+		out := r.queue[0]
+		if _, done := out.(*message.DHTClientGetDoneMsg); !done {
+			if r.credits == 0 {
+				break
+			}
+			r.credits--
+		}
+		list = append(list, out)
+		r.queue = r.queue[1:]
+	}
+	r.Unlock()
+
+	for _, out := range list {
+		if err = r.back.Send(ctx, out); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Receiver is nil for local responders.
+func (r *ClientResponder) Receiver() *util.PeerID {
+	return nil
+}
+
+// AddKnown adds hashes of results known to the client.
+func (r *ClientResponder) AddKnown(list []*crypto.HashCode) {
+	r.Lock()
+	defer r.Unlock()
+	for _, hc := range list {
+		r.known[hc.String()] = true
+	}
+}
+
+// Stop the responder: no more results are relayed.
+func (r *ClientResponder) Stop() {
+	r.Lock()
+	defer r.Unlock()
+	r.stopped = true
+	r.queue = nil
+	r.results = nil
+}
+
+//----------------------------------------------------------------------
+
+// clientGet is a pending GET request of a client
+type clientGet struct {
+	key    *crypto.HashCode   // query key
+	resp   *ClientResponder   // result responder
+	cancel context.CancelFunc // cancel request processing
+}
+
+// ClientSession holds the state of a client connection
+type ClientSession struct {
+	sync.Mutex
+
+	gets    map[uint64]*clientGet                    // pending GET requests
+	credits map[uint64]uint32                        // credits granted before a GET request
+	limits  map[uint64]*message.DHTClientGetLimitMsg // limits set before a GET request
+	verify  map[string]bool                          // keys of PUT requests to be confirmed
+}
+
+// NewClientSession creates a new (empty) client session
+func NewClientSession() *ClientSession {
+	return &ClientSession{
+		gets:    make(map[uint64]*clientGet),
+		credits: make(map[uint64]uint32),
+		limits:  make(map[uint64]*message.DHTClientGetLimitMsg),
+		verify:  make(map[string]bool),
+	}
+}
+
+// stop a pending GET request (if it matches the given key)
+func (cs *ClientSession) stop(id uint64, key *crypto.HashCode) bool {
+	cs.Lock()
+	defer cs.Unlock()
+	get, ok := cs.gets[id]
+	if !ok || (key != nil && !get.key.Equal(key)) {
+		return false
+	}
+	get.resp.Stop()
+	get.cancel()
+	delete(cs.gets, id)
+	return true
+}
+
+// remove a finished request (if it is still registered)
+func (cs *ClientSession) remove(id uint64, get *clientGet) {
+	cs.Lock()
+	defer cs.Unlock()
+	if cs.gets[id] == get {
+		delete(cs.gets, id)
+	}
+}
+
+// finish a limited GET request after its collection window: processing
+// is cancelled and the collected results are sent. The request is removed
+// from the session once all results are delivered. A request stopped
+// earlier (processing context done) is not finished.
+func (cs *ClientSession) finish(ctx, lctx context.Context, id uint64, get *clientGet, window time.Duration) {
+	select {
+	case <-lctx.Done():
+		return
+	case <-time.After(window):
+	}
+	get.cancel()
+	drained, err := get.resp.Finish(ctx)
+	if err != nil {
+		logger.Printf(logger.WARN, "[dht-client] sending results for #%d failed: %s", id, err.Error())
+	}
+	if drained {
+		cs.remove(id, get)
+	}
+}
+
+// Close the session and stop all pending requests.
+func (cs *ClientSession) Close() {
+	cs.Lock()
+	defer cs.Unlock()
+	for id, get := range cs.gets {
+		get.resp.Stop()
+		get.cancel()
+		delete(cs.gets, id)
+	}
+}
+
+// putExpiration returns the absolute expiration of a client PUT from the
+// requested expiration (absolute time or relative TTL in microseconds).
+// Expirations beyond the max. TTL are capped.
+func (m *Module) putExpiration(val uint64, relative bool, now util.AbsoluteTime) (exp util.AbsoluteTime, err error) {
+	maxTTL := DefaultMaxTTL
+	if m.cfg.MaxTTL > 0 {
+		maxTTL = time.Duration(m.cfg.MaxTTL) * time.Second
+	}
+	limit := now.Add(maxTTL)
+	if relative {
+		if val == 0 {
+			return exp, ErrInvalidExpiration
+		}
+		if val >= uint64(maxTTL.Microseconds()) {
+			return limit, nil
+		}
+		return now.AddRelative(util.RelativeTime{Val: val}), nil
+	}
+	exp = util.AbsoluteTime{Val: val}
+	if exp.Compare(now) <= 0 {
+		return exp, ErrBlockExpired
+	}
+	if exp.Compare(limit) > 0 {
+		exp = limit
+	}
+	return
+}
+
+// put a block from a local client into the DHT: it is handled like a
+// DHT-P2P-PUT from the local peer.
+func (m *Module) put(ctx context.Context, key *crypto.HashCode, btype enums.BlockType, expire util.AbsoluteTime, data []byte, flags, repl uint16, back transport.Responder) error {
+	blk, err := blocks.NewBlock(btype, expire, data)
+	if err != nil {
+		return err
+	}
+	put := message.NewDHTP2PPutMsg(blk)
+	put.Flags = flags & clientFlags
+	put.ReplLvl = uint16(m.cfg.Routing.ReplLevel)
+	if repl > 0 {
+		put.ReplLvl = repl
+	}
+	put.Key = key.Clone()
+	put.PeerFilter.Add(m.core.PeerID())
+	m.HandleMessage(ctx, nil, put, back)
+	return nil
+}
+
+// confirmPut samples a stored client PUT and reports the outcome to the
+// client. A rejected request is reported without samples.
+func (m *Module) confirmPut(ctx context.Context, label string, msg *message.DHTClientPutMsg, expire util.AbsoluteTime, err error, back transport.Responder) {
+	rep := new(PutReport)
+	if err == nil {
+		var blk blocks.Block
+		if blk, err = blocks.NewBlock(msg.BType, expire, msg.Data); err == nil {
+			query := blocks.NewGenericQuery(msg.Key, msg.BType, uint16(msg.Options)&clientFlags)
+			rep = m.confirm(ctx, query, blk)
+		}
+	}
+	out := message.NewDHTClientPutConfirmMsg(msg.Key, uint16(rep.Samples), uint16(rep.Confirmed), uint16(rep.Retries))
+	if err = back.Send(ctx, out); err != nil {
+		logger.Printf(logger.WARN, "[%s] sending PUT confirmation failed: %s", label, err.Error())
+	}
+}
+
+// HandleClientMessage handles a DHT client message received on the
+// service socket. Returns false if the message is not a client message.
+func (s *Service) HandleClientMessage(ctx context.Context, cs *ClientSession, msgIn message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := "dht"
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		if str, ok := v.(string); ok && len(str) > 0 {
+			label = "dht" + str
+		}
+	}
+	switch msg := msgIn.(type) {
+
+	case *message.DHTClientPutMsg:
+		//----------------------------------------------------------
+		// DHT PUT: handled like a DHT-P2P-PUT from the local peer
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] DHT-CLIENT-PUT (type %s, key %s)", label, msg.BType, msg.Key.Short())
+		relative := msg.Options&enums.DHT_RO_RELATIVE_EXPIRATION != 0
+		expire, err := s.putExpiration(msg.Expire.Val, relative, util.AbsoluteTimeNow())
+		if err == nil {
+			err = s.put(ctx, msg.Key, msg.BType, expire, msg.Data, uint16(msg.Options), uint16(msg.ReplLevel), back)
+		}
+		if err != nil {
+			logger.Printf(logger.ERROR, "[%s] DHT-CLIENT-PUT rejected: %s", label, err.Error())
+		}
+		// confirm the PUT if requested by the client (a rejected
+		// request is reported as not sampled)
+		k := msg.Key.String()
+		cs.Lock()
+		confirm := cs.verify[k]
+		delete(cs.verify, k)
+		cs.Unlock()
+		if confirm {
+			go s.confirmPut(ctx, label, msg, expire, err, back)
+		}
+
+	case *message.DHTClientGetMsg:
+		//----------------------------------------------------------
+		// DHT GET: start a new request
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] DHT-CLIENT-GET #%d (type %s, key %s)", label, msg.ID, msg.BType, msg.Key.Short())
+
+		// a request with the same ID replaces a pending one
+		cs.stop(msg.ID, nil)
+		service.Count("dht:get", 1)
+
+		// assemble GET message
+		query := blocks.NewGenericQuery(msg.Key, msg.BType, uint16(msg.Options)&clientFlags)
+		if len(msg.XQuery) > 0 {
+			query.Params()["xquery"] = util.Clone(msg.XQuery)
+		}
+		get := s.newGetMsg(query)
+		if msg.ReplLevel > 0 {
+			get.ReplLevel = uint16(msg.ReplLevel)
+		}
+		// register request and process it (with flow control if the
+		// client granted credits in advance and with a result limit if
+		// the client requested one)
+		lctx, cancel := context.WithCancel(ctx)
+		resp := NewClientResponder(msg.ID, back)
+		entry := &clientGet{
+			key:    msg.Key,
+			resp:   resp,
+			cancel: cancel,
+		}
+		cs.Lock()
+		if credits, ok := cs.credits[msg.ID]; ok {
+			resp.window = true
+			resp.credits = credits
+			delete(cs.credits, msg.ID)
+		}
+		limit, limited := cs.limits[msg.ID]
+		delete(cs.limits, msg.ID)
+		cs.gets[msg.ID] = entry
+		cs.Unlock()
+		if limited {
+			resp.SetLimit(msg.Key, limit.MaxResults)
+			window := DefaultClientWindow
+			if limit.Timeout.Val > 0 {
+				window = MaxClientWindow
+				if limit.Timeout.Val < uint64(MaxClientWindow.Microseconds()) {
+					window = time.Duration(limit.Timeout.Val) * time.Microsecond
+				}
+			}
+			go cs.finish(ctx, lctx, msg.ID, entry, window)
+		} else if msg.Options&enums.DHT_RO_FIRST_RESULT != 0 {
+			// parallel lookup: cancel processing (and tear down the
+			// forwarded branches) after the first exact result.
+			alpha := s.cfg.Routing.Alpha
+			if alpha <= 0 {
+				alpha = DefaultAlpha
+			}
+			lctx = context.WithValue(lctx, CtxAlpha, alpha)
+			resp.SetFirst(func() {
+				cancel()
+				if resp.Drained() {
+					cs.remove(msg.ID, entry)
+				}
+			})
+		}
+		go s.HandleMessage(lctx, nil, get, resp)
+
+	case *message.DHTClientGetResultsKnownMsg:
+		//----------------------------------------------------------
+		// DHT GET-RESULTS-KNOWN: filter known results
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] DHT-CLIENT-GET-RESULTS-KNOWN #%d (%d results)", label, msg.ID, len(msg.Known))
+		cs.Lock()
+		get, ok := cs.gets[msg.ID]
+		cs.Unlock()
+		if !ok || !get.key.Equal(msg.Key) {
+			logger.Printf(logger.WARN, "[%s] no pending request #%d for key %s", label, msg.ID, msg.Key.Short())
+			return true
+		}
+		get.resp.AddKnown(msg.Known)
+
+	case *message.DHTClientGetCreditMsg:
+		//----------------------------------------------------------
+		// DHT GET-CREDIT: grant credits for results
+		//----------------------------------------------------------
+		logger.Printf(logger.DBG, "[%s] DHT-CLIENT-GET-CREDIT #%d (%d credits)", label, msg.ID, msg.Credits)
+		cs.Lock()
+		get, ok := cs.gets[msg.ID]
+		if !ok {
+			// credits for an upcoming request
+			cs.credits[msg.ID] += msg.Credits
+		}
+		cs.Unlock()
+		if ok {
+			if err := get.resp.Grant(ctx, msg.Credits); err != nil {
+				logger.Printf(logger.WARN, "[%s] sending queued results for #%d failed: %s", label, msg.ID, err.Error())
+			}
+			// remove a finished request once all results are delivered
+			if get.resp.Drained() {
+				cs.remove(msg.ID, get)
+			}
+		}
+
+	case *message.DHTClientGetLimitMsg:
+		//----------------------------------------------------------
+		// DHT GET-LIMIT: limit results of an upcoming request
+		//----------------------------------------------------------
+		logger.Printf(logger.DBG, "[%s] DHT-CLIENT-GET-LIMIT #%d (max %d)", label, msg.ID, msg.MaxResults)
+		if msg.MaxResults == 0 {
+			logger.Printf(logger.WARN, "[%s] invalid limit for #%d -- ignored", label, msg.ID)
+			return true
+		}
+		cs.Lock()
+		_, ok := cs.gets[msg.ID]
+		if !ok {
+			cs.limits[msg.ID] = msg
+		}
+		cs.Unlock()
+		if ok {
+			logger.Printf(logger.WARN, "[%s] request #%d already running -- limit ignored", label, msg.ID)
+		}
+
+	case *message.DHTClientPutVerifyMsg:
+		//----------------------------------------------------------
+		// DHT PUT-VERIFY: confirm an upcoming PUT request
+		//----------------------------------------------------------
+		logger.Printf(logger.DBG, "[%s] DHT-CLIENT-PUT-VERIFY (key %s)", label, msg.Key.Short())
+		cs.Lock()
+		cs.verify[msg.Key.String()] = true
+		cs.Unlock()
+
+	case *message.DHTClientGetStopMsg:
+		//----------------------------------------------------------
+		// DHT GET-STOP: stop a pending request
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] DHT-CLIENT-GET-STOP #%d", label, msg.ID)
+		if !cs.stop(msg.ID, msg.Key) {
+			logger.Printf(logger.WARN, "[%s] no pending request #%d for key %s", label, msg.ID, msg.Key.Short())
+		}
+
+	default:
+		return false
+	}
+	return true
+}
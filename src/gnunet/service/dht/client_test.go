@@ -0,0 +1,328 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+)
+
+// testClient collects messages sent to a client
+type testClient struct {
+	msgs []message.Message
+}
+
+func (c *testClient) Send(ctx context.Context, msg message.Message) error {
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *testClient) Receiver() *util.PeerID {
+	return nil
+}
+
+// newTestResult creates a result message with random block data
+func newTestResult() *message.DHTP2PResultMsg {
+	res := message.NewDHTP2PResultMsg()
+	res.BType = enums.BLOCK_TYPE_TEST
+	res.Query = crypto.Hash([]byte("key"))
+	res.Block = util.NewRndArray(32)
+	return res
+}
+
+func TestClientWindow(t *testing.T) {
+	ctx := context.Background()
+
+	// no flow control: all results are sent
+	c := new(testClient)
+	r := NewClientResponder(1, c)
+	for i := 0; i < 3; i++ {
+		if err := r.Send(ctx, newTestResult()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.msgs) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(c.msgs))
+	}
+
+	// flow control: results are queued without credits
+	c = new(testClient)
+	r = NewClientResponder(2, c)
+	if err := r.Grant(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxClientQueue+10; i++ {
+		if err := r.Send(ctx, newTestResult()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.msgs) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(c.msgs))
+	}
+	// granting credits sends queued results
+	if err := r.Grant(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != 6 {
+		t.Fatalf("expected 6 results, got %d", len(c.msgs))
+	}
+	// excess results were dropped
+	if err := r.Grant(ctx, uint32(2*MaxClientQueue)); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != MaxClientQueue+1 {
+		t.Fatalf("expected %d results, got %d", MaxClientQueue+1, len(c.msgs))
+	}
+	// remaining credits are used for new results
+	if err := r.Send(ctx, newTestResult()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != MaxClientQueue+2 {
+		t.Fatalf("expected %d results, got %d", MaxClientQueue+2, len(c.msgs))
+	}
+	for _, msg := range c.msgs {
+		if res, ok := msg.(*message.DHTClientResultMsg); !ok || res.ID != 2 {
+			t.Fatalf("unexpected message %s", msg)
+		}
+	}
+}
+
+func TestClientLimit(t *testing.T) {
+	ctx := context.Background()
+	key := crypto.Hash([]byte("key"))
+	now := util.AbsoluteTimeNow()
+
+	// results: two for the query key (different expiration) and one for
+	// a different key (approximate match)
+	far := newTestResult()
+	far.Query = crypto.Hash([]byte("other key"))
+	far.Expire = now.Add(3 * time.Hour)
+	early := newTestResult()
+	early.Expire = now.Add(time.Hour)
+	late := newTestResult()
+	late.Expire = now.Add(2 * time.Hour)
+
+	c := new(testClient)
+	r := NewClientResponder(1, c)
+	r.SetLimit(key, 2)
+	for _, res := range []*message.DHTP2PResultMsg{far, early, late} {
+		if err := r.Send(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.msgs) != 0 {
+		t.Fatalf("expected no results before finish, got %d", len(c.msgs))
+	}
+	if drained, err := r.Finish(ctx); err != nil || !drained {
+		t.Fatalf("finish failed (drained=%v): %v", drained, err)
+	}
+	if len(c.msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(c.msgs))
+	}
+	for i, res := range []*message.DHTP2PResultMsg{late, early} {
+		out, ok := c.msgs[i].(*message.DHTClientResultMsg)
+		if !ok || !bytes.Equal(out.Data, res.Block) {
+			t.Fatalf("unexpected result #%d: %s", i, c.msgs[i])
+		}
+	}
+	if done, ok := c.msgs[2].(*message.DHTClientGetDoneMsg); !ok || done.ID != 1 || done.Count != 2 {
+		t.Fatalf("unexpected completion %s", c.msgs[2])
+	}
+	// no results after completion
+	if err := r.Send(ctx, newTestResult()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != 3 {
+		t.Fatalf("result sent after completion")
+	}
+
+	// limited request with flow control: completion follows the last result
+	c = new(testClient)
+	r = NewClientResponder(2, c)
+	r.SetLimit(key, 2)
+	if err := r.Grant(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.Send(ctx, newTestResult()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if drained, err := r.Finish(ctx); err != nil || drained {
+		t.Fatalf("finish failed (drained=%v): %v", drained, err)
+	}
+	if err := r.Grant(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != 1 || r.Drained() {
+		t.Fatalf("expected 1 pending result, got %d messages", len(c.msgs))
+	}
+	if err := r.Grant(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != 3 || !r.Drained() {
+		t.Fatalf("expected 3 messages, got %d", len(c.msgs))
+	}
+	if _, ok := c.msgs[2].(*message.DHTClientGetDoneMsg); !ok {
+		t.Fatalf("unexpected completion %s", c.msgs[2])
+	}
+}
+
+func TestPutExpiration(t *testing.T) {
+	m, _ := newTestModule(t, 0)
+	m.cfg.MaxTTL = 3600
+	now := util.AbsoluteTimeNow()
+	hour := uint64(time.Hour.Microseconds())
+	tests := []struct {
+		name     string
+		val      uint64
+		relative bool
+		want     util.AbsoluteTime
+		err      error
+	}{
+		{"relative", hour / 2, true, now.Add(30 * time.Minute), nil},
+		{"relative capped", 2 * hour, true, now.Add(time.Hour), nil},
+		{"relative never", util.AbsoluteTimeNever().Val, true, now.Add(time.Hour), nil},
+		{"relative zero", 0, true, util.AbsoluteTime{}, ErrInvalidExpiration},
+		{"absolute", now.Val + hour/4, false, now.Add(15 * time.Minute), nil},
+		{"absolute capped", now.Val + 2*hour, false, now.Add(time.Hour), nil},
+		{"absolute never", util.AbsoluteTimeNever().Val, false, now.Add(time.Hour), nil},
+		{"absolute expired", now.Val - 1, false, util.AbsoluteTime{}, ErrBlockExpired},
+	}
+	for _, tc := range tests {
+		exp, err := m.putExpiration(tc.val, tc.relative, now)
+		if err != tc.err {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.err, err)
+		}
+		if err == nil && exp.Compare(tc.want) != 0 {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, exp)
+		}
+	}
+}
+
+func TestClientFirstResult(t *testing.T) {
+	ctx := context.Background()
+
+	// responder: approximate results are relayed, the first exact result
+	// ends the request.
+	c := new(testClient)
+	r := NewClientResponder(1, c)
+	calls := 0
+	r.SetFirst(func() { calls++ })
+	approx := newTestResult()
+	approx.Flags = enums.DHT_RO_FIND_APPROXIMATE
+	for _, res := range []*message.DHTP2PResultMsg{approx, newTestResult(), newTestResult()} {
+		if err := r.Send(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.msgs) != 3 || calls != 1 || !r.Drained() {
+		t.Fatalf("expected 3 messages and 1 call, got %d/%d", len(c.msgs), calls)
+	}
+	if done, ok := c.msgs[2].(*message.DHTClientGetDoneMsg); !ok || done.ID != 1 || done.Count != 1 {
+		t.Fatalf("unexpected completion %s", c.msgs[2])
+	}
+
+	// service: the request is forwarded to 'alpha' peers; the first
+	// result tears down all branches.
+	m, mc := newTestModule(t, 8)
+	s := &Service{Module: *m}
+	key := queryKey(&s.Module, false)
+	get := message.NewDHTClientGetMsg(key)
+	get.ID = 7
+	get.BType = enums.BLOCK_TYPE_TEST
+	get.Options = enums.DHT_RO_FIRST_RESULT
+	cs := NewClientSession()
+	back := &mockResponder{}
+	if !s.HandleClientMessage(ctx, cs, get, back) {
+		t.Fatal("GET not handled")
+	}
+	var fwd []*sentMsg
+	for i := 0; i < 50 && len(fwd) < DefaultAlpha; i++ {
+		time.Sleep(10 * time.Millisecond)
+		fwd = mc.Sent(enums.MSG_DHT_P2P_GET)
+	}
+	if len(fwd) != DefaultAlpha {
+		t.Fatalf("expected %d forwarded GETs, got %d", DefaultAlpha, len(fwd))
+	}
+	// all branches share a result handler
+	if n := s.reshdlrs.Size(); n != 1 {
+		t.Fatalf("expected 1 result handler, got %d", n)
+	}
+	res := newTestResult()
+	res.Query = key
+	res.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	if !s.HandleMessage(ctx, fwd[0].peer, res, nil) {
+		t.Fatal("RESULT not handled")
+	}
+	for i := 0; i < 50 && s.reshdlrs.Size() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := s.reshdlrs.Size(); n != 0 {
+		t.Fatalf("expected no active result handlers, got %d", n)
+	}
+	back.Lock()
+	defer back.Unlock()
+	if len(back.msgs) != 2 {
+		t.Fatalf("expected result and completion, got %d messages", len(back.msgs))
+	}
+	cs.Lock()
+	defer cs.Unlock()
+	if len(cs.gets) != 0 {
+		t.Fatal("finished request still pending")
+	}
+}
+
+// cancelClient rejects messages sent with a canceled context
+type cancelClient struct {
+	testClient
+}
+
+func (c *cancelClient) Send(ctx context.Context, msg message.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.testClient.Send(ctx, msg)
+}
+
+// Ending a first-result request cancels the request context; the result
+// and the completion message must still reach the client.
+func TestClientFirstResultCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := new(cancelClient)
+	r := NewClientResponder(1, c)
+	r.SetFirst(cancel)
+	if err := r.Send(ctx, newTestResult()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.msgs) != 2 {
+		t.Fatalf("expected result and completion, got %d messages", len(c.msgs))
+	}
+	if _, ok := c.msgs[1].(*message.DHTClientGetDoneMsg); !ok {
+		t.Fatalf("unexpected completion %s", c.msgs[1])
+	}
+}
@@ -0,0 +1,135 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Dump and import the content of the local DHT store (e.g. to seed test
+// networks or to migrate a node to another machine). A dump is a
+// sequence of JSON objects (one per line), each describing a stored
+// block. Put paths are not exported.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrDumpKeyMismatch = errors.New("block does not match key")
+	ErrDumpInvalid     = errors.New("invalid block")
+	ErrDumpExpired     = errors.New("block expired")
+)
+
+// DumpEntry is a single block in a DHT store dump.
+type DumpEntry struct {
+	Key    string          `json:"key"`    // query key (hex-encoded)
+	Type   enums.BlockType `json:"type"`   // block type
+	Expire uint64          `json:"expire"` // expiration (µs since epoch)
+	Block  string          `json:"block"`  // block data (base64-encoded)
+}
+
+// ExportStore writes all (unexpired) blocks of the local store to w.
+// Returns the number of exported blocks.
+func (m *Module) ExportStore(w io.Writer) (n int, err error) {
+	enc := json.NewEncoder(w)
+	var errWrite error
+	err = m.store.Traverse(nil, func(key *crypto.HashCode, entry *store.DHTEntry) bool {
+		e := &DumpEntry{
+			Key:    key.String(),
+			Type:   entry.Blk.Type(),
+			Expire: entry.Blk.Expire().Val,
+			Block:  base64.StdEncoding.EncodeToString(entry.Blk.Bytes()),
+		}
+		if errWrite = enc.Encode(e); errWrite != nil {
+			return false
+		}
+		n++
+		return true
+	})
+	if err == nil {
+		err = errWrite
+	}
+	return
+}
+
+// ImportStore reads a dump from r and adds the blocks to the local store.
+// Expired or invalid blocks are skipped. Returns the number of imported
+// and skipped blocks.
+func (m *Module) ImportStore(r io.Reader) (n, skipped int, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		e := new(DumpEntry)
+		if err = dec.Decode(e); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		if errEntry := m.importEntry(e); errEntry != nil {
+			logger.Printf(logger.WARN, "[dht] import of %s skipped: %s", e.Key, errEntry.Error())
+			skipped++
+			continue
+		}
+		n++
+	}
+}
+
+// importEntry validates a dumped block and stores it.
+func (m *Module) importEntry(e *DumpEntry) (err error) {
+	// reconstruct key and block
+	var buf []byte
+	if buf, err = hex.DecodeString(e.Key); err != nil {
+		return
+	}
+	key := crypto.NewHashCode(buf)
+	if buf, err = base64.StdEncoding.DecodeString(e.Block); err != nil {
+		return
+	}
+	expire := util.AbsoluteTime{Val: e.Expire}
+	if expire.Expired() {
+		return ErrDumpExpired
+	}
+	var blk blocks.Block
+	if blk, err = blocks.NewBlock(e.Type, expire, buf); err != nil {
+		return
+	}
+	// validate block (if we know how)
+	if hdlr, ok := blocks.BlockHandlers[e.Type]; ok {
+		if !hdlr.ValidateBlockStoreRequest(blk) {
+			return ErrDumpInvalid
+		}
+		if !hdlr.ValidateBlockKey(blk, key) {
+			return ErrDumpKeyMismatch
+		}
+	}
+	query := blocks.NewGenericQuery(key, e.Type, 0)
+	return m.store.Put(query, &store.DHTEntry{Blk: blk})
+}
@@ -0,0 +1,117 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+)
+
+// newTestStore creates a DHT store in a temporary directory.
+func newTestStore(t *testing.T) store.DHTStore {
+	t.Helper()
+	cfg := make(util.ParameterSet)
+	cfg["mode"] = "file"
+	cfg["cache"] = false
+	cfg["path"] = t.TempDir()
+	cfg["maxGB"] = 1
+	fs, err := store.NewDHTStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	return fs
+}
+
+func TestStoreDump(t *testing.T) {
+	src := &Module{store: newTestStore(t)}
+	dst := &Module{store: newTestStore(t)}
+
+	// populate source store
+	keys := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNow().Add(time.Hour), util.NewRndArray(64))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := crypto.Hash(blk.Bytes())
+		if err = src.store.Put(blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0), &store.DHTEntry{Blk: blk}); err != nil {
+			t.Fatal(err)
+		}
+		keys[key.String()] = true
+	}
+
+	// export and import
+	buf := new(bytes.Buffer)
+	n, err := src.ExportStore(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(keys) {
+		t.Fatalf("expected %d exported blocks, got %d", len(keys), n)
+	}
+	dump := buf.String()
+	n, skipped, err := dst.ImportStore(strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(keys) || skipped != 0 {
+		t.Fatalf("expected %d imported blocks, got %d (%d skipped)", len(keys), n, skipped)
+	}
+	// check content of destination store
+	num := 0
+	err = dst.store.Traverse(nil, func(key *crypto.HashCode, entry *store.DHTEntry) bool {
+		if !keys[key.String()] {
+			t.Errorf("unknown key %s", key.Short())
+		}
+		if entry.Blk.Type() != enums.BLOCK_TYPE_TEST {
+			t.Errorf("wrong block type %s", entry.Blk.Type())
+		}
+		num++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num != len(keys) {
+		t.Fatalf("expected %d blocks, got %d", len(keys), num)
+	}
+
+	// expired and malformed entries are skipped
+	expired := `{"key":"` + strings.Repeat("00", 64) + `","type":8,"expire":1,"block":""}` + "\n"
+	badKey := `{"key":"xyz","type":8,"expire":0,"block":""}` + "\n"
+	if n, skipped, err = dst.ImportStore(strings.NewReader(expired + badKey)); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || skipped != 2 {
+		t.Fatalf("expected 2 skipped blocks, got %d imported / %d skipped", n, skipped)
+	}
+	// broken dump
+	if _, _, err = dst.ImportStore(strings.NewReader("not a dump")); err == nil {
+		t.Fatal("broken dump accepted")
+	}
+}
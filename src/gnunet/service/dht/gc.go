@@ -0,0 +1,187 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Garbage collection of the DHT store: stored blocks are removed when
+// they are expired or older than the configured lifetime for their
+// block type. Evictions are counted per block type.
+//----------------------------------------------------------------------
+
+// DefaultGCPeriod is the time between GC runs (if not configured).
+var DefaultGCPeriod = time.Hour
+
+// Error codes
+var (
+	ErrUnknownBlockType = errors.New("unknown block type")
+)
+
+// GCStats are the counters of the store garbage collection.
+type GCStats struct {
+	Runs    uint64            `json:"runs"`    // number of GC runs
+	Evicted uint64            `json:"evicted"` // total number of removed blocks
+	Errors  uint64            `json:"errors"`  // number of failed runs
+	PerType map[string]uint64 `json:"perType"` // removed blocks per block type
+}
+
+// String returns a human-readable representation of the counters.
+func (s GCStats) String() string {
+	out := fmt.Sprintf("runs=%d,evicted=%d,errors=%d", s.Runs, s.Evicted, s.Errors)
+	types := make([]string, 0, len(s.PerType))
+	for name := range s.PerType {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		out += fmt.Sprintf(",%s=%d", name, s.PerType[name])
+	}
+	return out
+}
+
+// storeGC holds the GC settings and counters of a module.
+type storeGC struct {
+	sync.Mutex
+
+	period    time.Duration                     // time between runs
+	lifetimes map[enums.BlockType]time.Duration // max. lifetime per type
+	stats     GCStats                           // eviction counters
+}
+
+// newStoreGC creates the GC settings from configuration (nil for
+// defaults). Lifetimes for unknown block types are ignored.
+func newStoreGC(cfg *config.DHTGCConfig) *storeGC {
+	gc := &storeGC{
+		period:    DefaultGCPeriod,
+		lifetimes: make(map[enums.BlockType]time.Duration),
+		stats: GCStats{
+			PerType: make(map[string]uint64),
+		},
+	}
+	if cfg == nil {
+		return gc
+	}
+	if cfg.Period > 0 {
+		gc.period = time.Duration(cfg.Period) * time.Second
+	}
+	for name, secs := range cfg.Lifetimes {
+		btype, err := parseBlockType(name)
+		if err != nil {
+			logger.Printf(logger.WARN, "[dht-gc] lifetime for '%s' ignored: %s", name, err.Error())
+			continue
+		}
+		gc.lifetimes[btype] = time.Duration(secs) * time.Second
+	}
+	return gc
+}
+
+// Stats returns a copy of the current counters.
+func (gc *storeGC) Stats() GCStats {
+	gc.Lock()
+	defer gc.Unlock()
+	s := gc.stats
+	s.PerType = make(map[string]uint64, len(gc.stats.PerType))
+	for k, v := range gc.stats.PerType {
+		s.PerType[k] = v
+	}
+	return s
+}
+
+// collectGarbage removes expired blocks from the store (maintenance job).
+func (m *Module) collectGarbage(ctx context.Context) error {
+	evicted, err := m.store.Collect(m.gc.lifetimes)
+
+	m.gc.Lock()
+	defer m.gc.Unlock()
+	m.gc.stats.Runs++
+	total := 0
+	for btype, n := range evicted {
+		name := blockTypeName(btype)
+		m.gc.stats.PerType[name] += uint64(n)
+		service.Count("dht:gc-evicted:"+name, int64(n))
+		total += n
+	}
+	m.gc.stats.Evicted += uint64(total)
+	service.Count("dht:gc-evicted", int64(total))
+	if total > 0 {
+		logger.Printf(logger.INFO, "[dht-gc] %d expired blocks removed", total)
+	}
+	if err != nil {
+		m.gc.stats.Errors++
+		logger.Printf(logger.ERROR, "[dht-gc] collection failed: %s", err.Error())
+	}
+	return err
+}
+
+//----------------------------------------------------------------------
+
+// blockTypeName returns the short name of a block type ("GNS_NAMERECORD").
+func blockTypeName(btype enums.BlockType) string {
+	return strings.TrimPrefix(btype.String(), "BLOCK_TYPE_")
+}
+
+// parseBlockType returns the block type for a number or a (short) name.
+func parseBlockType(s string) (enums.BlockType, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return enums.BlockType(n), nil
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "BLOCK_TYPE_")
+	for _, btype := range blockTypes {
+		if blockTypeName(btype) == name {
+			return btype, nil
+		}
+	}
+	return enums.BLOCK_TYPE_ANY, ErrUnknownBlockType
+}
+
+// known block types (for name lookup)
+var blockTypes = []enums.BlockType{
+	enums.BLOCK_TYPE_FS_DBLOCK,
+	enums.BLOCK_TYPE_FS_IBLOCK,
+	enums.BLOCK_TYPE_FS_ONDEMAND,
+	enums.BLOCK_TYPE_LEGACY_HELLO,
+	enums.BLOCK_TYPE_TEST,
+	enums.BLOCK_TYPE_FS_UBLOCK,
+	enums.BLOCK_TYPE_DNS,
+	enums.BLOCK_TYPE_GNS_NAMERECORD,
+	enums.BLOCK_TYPE_REVOCATION,
+	enums.BLOCK_TYPE_DHT_HELLO,
+	enums.BLOCK_TYPE_REGEX,
+	enums.BLOCK_TYPE_REGEX_ACCEPT,
+	enums.BLOCK_TYPE_SET_TEST,
+	enums.BLOCK_TYPE_CONSENSUS_ELEMENT,
+	enums.BLOCK_TYPE_SETI_TEST,
+	enums.BLOCK_TYPE_SETU_TEST,
+}
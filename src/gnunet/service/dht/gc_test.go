@@ -0,0 +1,86 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+)
+
+func TestParseBlockType(t *testing.T) {
+	cases := []struct {
+		in    string
+		btype enums.BlockType
+		ok    bool
+	}{
+		{"11", enums.BLOCK_TYPE_GNS_NAMERECORD, true},
+		{"GNS_NAMERECORD", enums.BLOCK_TYPE_GNS_NAMERECORD, true},
+		{"dht_hello", enums.BLOCK_TYPE_DHT_HELLO, true},
+		{"BLOCK_TYPE_TEST", enums.BLOCK_TYPE_TEST, true},
+		{"NO_SUCH_TYPE", enums.BLOCK_TYPE_ANY, false},
+	}
+	for _, tc := range cases {
+		btype, err := parseBlockType(tc.in)
+		if (err == nil) != tc.ok || btype != tc.btype {
+			t.Errorf("'%s': got %s (%v)", tc.in, btype, err)
+		}
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	m := &Module{
+		store: newTestStore(t),
+		gc: newStoreGC(&config.DHTGCConfig{
+			Period:    60,
+			Lifetimes: map[string]int{"TEST": 0, "unknown": 10},
+		}),
+	}
+	if m.gc.period != time.Minute || len(m.gc.lifetimes) != 1 {
+		t.Fatalf("unexpected GC settings: %v, %v", m.gc.period, m.gc.lifetimes)
+	}
+	// store an expired and an unexpired block
+	for _, expire := range []util.AbsoluteTime{
+		util.AbsoluteTimeNow().Add(-time.Minute),
+		util.AbsoluteTimeNow().Add(time.Hour),
+	} {
+		blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, expire, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := blocks.NewGenericQuery(crypto.Hash(blk.Bytes()), enums.BLOCK_TYPE_TEST, 0)
+		if err = m.store.Put(query, &store.DHTEntry{Blk: blk}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.collectGarbage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats := m.gc.Stats()
+	if stats.Runs != 1 || stats.Evicted != 1 || stats.PerType["TEST"] != 1 {
+		t.Fatalf("unexpected GC stats: %s", stats)
+	}
+}
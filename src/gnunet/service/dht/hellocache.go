@@ -0,0 +1,311 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gnunet/config"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Tiered HELLO cache: a small "hot" tier keeps recently used HELLOs in
+// memory; the least recently used entries are demoted to a persistent
+// "cold" tier (a key/value store holding HELLO URLs) when the hot tier
+// is full. A HELLO found in the cold tier is promoted back to the hot
+// tier on access. Iterating the cache (Process, ProcessRange) only sees
+// the hot tier; lookups by key see both tiers.
+//----------------------------------------------------------------------
+
+// DefaultHelloCacheSize is the max. number of HELLOs in the hot tier if
+// not configured otherwise.
+const DefaultHelloCacheSize = 1024
+
+// helloColdPrefix is prepended to keys in the cold tier (the store can
+// be shared with other users).
+const helloColdPrefix = "hello:"
+
+// HelloCacheStats are the counters of the tiered HELLO cache.
+type HelloCacheStats struct {
+	Hot        int    `json:"hot"`        // number of HELLOs in hot tier
+	Cold       int    `json:"cold"`       // number of HELLOs in cold tier
+	HotHits    uint64 `json:"hotHits"`    // lookups answered from hot tier
+	ColdHits   uint64 `json:"coldHits"`   // lookups answered from cold tier
+	Misses     uint64 `json:"misses"`     // failed lookups
+	Promotions uint64 `json:"promotions"` // HELLOs moved from cold to hot tier
+	Demotions  uint64 `json:"demotions"`  // HELLOs moved from hot to cold tier
+}
+
+// HitRate returns the fraction of lookups answered from the given tier
+// (hot or cold) or from any tier.
+func (s HelloCacheStats) HitRate(hot, cold bool) float64 {
+	total := s.HotHits + s.ColdHits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	var hits uint64
+	if hot {
+		hits += s.HotHits
+	}
+	if cold {
+		hits += s.ColdHits
+	}
+	return float64(hits) / float64(total)
+}
+
+// String returns a human-readable representation of the counters.
+func (s HelloCacheStats) String() string {
+	return fmt.Sprintf("hot=%d,cold=%d,hotHits=%d,coldHits=%d,misses=%d,hitRate=%.3f,promoted=%d,demoted=%d",
+		s.Hot, s.Cold, s.HotHits, s.ColdHits, s.Misses, s.HitRate(true, true), s.Promotions, s.Demotions)
+}
+
+// HelloCache is a two-tier cache of HELLO blocks keyed by peer ID. The
+// embedded map is the hot tier.
+type HelloCache struct {
+	*util.Map[string, *blocks.HelloBlock]
+
+	mtx    sync.Mutex        // lock for tier management
+	size   int               // max. number of HELLOs in hot tier
+	cold   store.KVStore     // cold tier (or nil)
+	seq    uint64            // access sequence number
+	access map[string]uint64 // last access of hot entries
+	stats  HelloCacheStats   // cache counters
+}
+
+// NewHelloCache creates a HELLO cache from configuration. Without a
+// configured storage evicted HELLOs are dropped.
+func NewHelloCache(cfg *config.HelloCacheConfig) (hc *HelloCache, err error) {
+	hc = &HelloCache{
+		Map:    util.NewMap[string, *blocks.HelloBlock](),
+		size:   DefaultHelloCacheSize,
+		access: make(map[string]uint64),
+	}
+	if cfg == nil {
+		return
+	}
+	if cfg.Size > 0 {
+		hc.size = cfg.Size
+	}
+	if cfg.Storage != nil {
+		if hc.cold, err = store.NewKVStore(cfg.Storage); err != nil {
+			return
+		}
+		// count persisted HELLOs
+		var keys []string
+		if keys, err = hc.cold.List(); err != nil {
+			return
+		}
+		for _, key := range keys {
+			if !strings.HasPrefix(key, helloColdPrefix) {
+				continue
+			}
+			if val, err := hc.cold.Get(key); err == nil && len(val) > 0 {
+				hc.stats.Cold++
+			}
+		}
+	}
+	return
+}
+
+// Put a HELLO into the hot tier. If the hot tier is full, the least
+// recently used entry is demoted to the cold tier.
+func (hc *HelloCache) Put(key string, hb *blocks.HelloBlock, pid int) {
+	hc.Map.Put(key, hb, pid)
+
+	// select an entry for demotion
+	victim := ""
+	hc.mtx.Lock()
+	hc.touch(key)
+	if hc.Map.Size() > hc.size {
+		var oldest uint64
+		for k, seq := range hc.access {
+			if k != key && (len(victim) == 0 || seq < oldest) {
+				victim, oldest = k, seq
+			}
+		}
+		delete(hc.access, victim)
+	}
+	hc.mtx.Unlock()
+
+	// demote entry (without holding the tier lock)
+	if len(victim) > 0 {
+		if old, ok := hc.Map.Get(victim, pid); ok {
+			hc.Map.Delete(victim, pid)
+			hc.demote(victim, old)
+		}
+	}
+}
+
+// Get a HELLO with given key. A HELLO found in the cold tier is promoted
+// to the hot tier unless the call is made from within a processing
+// function (pid != 0).
+func (hc *HelloCache) Get(key string, pid int) (hb *blocks.HelloBlock, ok bool) {
+	if hb, ok = hc.Map.Get(key, pid); ok {
+		hc.mtx.Lock()
+		hc.touch(key)
+		hc.stats.HotHits++
+		hc.mtx.Unlock()
+		return
+	}
+	if hb = hc.getCold(key); hb == nil {
+		hc.mtx.Lock()
+		hc.stats.Misses++
+		hc.mtx.Unlock()
+		return nil, false
+	}
+	hc.mtx.Lock()
+	hc.stats.ColdHits++
+	hc.mtx.Unlock()
+	if pid == 0 {
+		hc.promote(key, hb)
+	}
+	return hb, true
+}
+
+// Delete a HELLO from both tiers.
+func (hc *HelloCache) Delete(key string, pid int) {
+	hc.Map.Delete(key, pid)
+	hc.mtx.Lock()
+	delete(hc.access, key)
+	hc.mtx.Unlock()
+	hc.dropCold(key)
+}
+
+// Stats returns a copy of the current counters.
+func (hc *HelloCache) Stats() HelloCacheStats {
+	hc.mtx.Lock()
+	defer hc.mtx.Unlock()
+	s := hc.stats
+	s.Hot = hc.Map.Size()
+	return s
+}
+
+// PruneCold removes expired (or unparsable) HELLOs from the cold tier;
+// signatures are verified on promotion only. Returns the number of
+// removed entries.
+func (hc *HelloCache) PruneCold() (n int) {
+	if hc.cold == nil {
+		return
+	}
+	keys, err := hc.cold.List()
+	if err != nil {
+		logger.Printf(logger.WARN, "[dht-hello] cold HELLO cache not listed: %s", err.Error())
+		return
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, helloColdPrefix) {
+			continue
+		}
+		val, err := hc.cold.Get(key)
+		if err != nil || len(val) == 0 {
+			continue
+		}
+		if hu, err := uri.ParseHello(val); err == nil && !hu.Expire.Expired() {
+			continue
+		}
+		if hc.dropCold(strings.TrimPrefix(key, helloColdPrefix)) {
+			n++
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+
+// touch records an access to a hot entry (tier lock must be held).
+func (hc *HelloCache) touch(key string) {
+	hc.seq++
+	hc.access[key] = hc.seq
+}
+
+// promote a HELLO from the cold to the hot tier.
+func (hc *HelloCache) promote(key string, hb *blocks.HelloBlock) {
+	if hc.dropCold(key) {
+		hc.mtx.Lock()
+		hc.stats.Promotions++
+		hc.mtx.Unlock()
+	}
+	hc.Put(key, hb, 0)
+}
+
+// demote a HELLO from the hot to the cold tier (dropped if no cold
+// tier is available).
+func (hc *HelloCache) demote(key string, hb *blocks.HelloBlock) {
+	if hc.cold == nil {
+		return
+	}
+	ckey := helloColdPrefix + key
+	val, _ := hc.cold.Get(ckey)
+	if err := hc.cold.Put(ckey, hb.URL()); err != nil {
+		logger.Printf(logger.WARN, "[dht-hello] HELLO of %s not demoted: %s", hb.PeerID.Short(), err.Error())
+		return
+	}
+	hc.mtx.Lock()
+	hc.stats.Demotions++
+	if len(val) == 0 {
+		hc.stats.Cold++
+	}
+	hc.mtx.Unlock()
+}
+
+// getCold returns an unexpired and valid HELLO from the cold tier (or nil).
+func (hc *HelloCache) getCold(key string) *blocks.HelloBlock {
+	if hc.cold == nil {
+		return nil
+	}
+	val, err := hc.cold.Get(helloColdPrefix + key)
+	if err != nil || len(val) == 0 {
+		return nil
+	}
+	hb, err := blocks.ParseHelloBlockFromURL(val, true)
+	if err != nil {
+		logger.Printf(logger.DBG, "[dht-hello] cold HELLO for %s dropped: %s", key, err.Error())
+		return nil
+	}
+	return hb
+}
+
+// dropCold removes a HELLO from the cold tier (the key/value store has
+// no delete operation; the entry is emptied instead). Returns true if
+// an entry was removed.
+func (hc *HelloCache) dropCold(key string) bool {
+	if hc.cold == nil {
+		return false
+	}
+	ckey := helloColdPrefix + key
+	if val, err := hc.cold.Get(ckey); err != nil || len(val) == 0 {
+		return false
+	}
+	if err := hc.cold.Put(ckey, ""); err != nil {
+		logger.Printf(logger.WARN, "[dht-hello] cold HELLO for %s not removed: %s", key, err.Error())
+		return false
+	}
+	hc.mtx.Lock()
+	hc.stats.Cold--
+	hc.mtx.Unlock()
+	return true
+}
@@ -0,0 +1,112 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+func TestHelloCacheTiers(t *testing.T) {
+	// prepare cold storage
+	fname := filepath.Join(t.TempDir(), "hellos.db")
+	db, err := sql.Open("sqlite3", fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("create table store(key text primary key, value text)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	cfg := &config.HelloCacheConfig{
+		Size: 2,
+		Storage: util.ParameterSet{
+			"mode":    "sql",
+			"connect": "sqlite3:" + fname,
+		},
+	}
+	hc, err := NewHelloCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the least recently used HELLO is demoted
+	h1 := newTestHello(t, "ip+udp://1.2.3.4:2086")
+	h2 := newTestHello(t, "ip+udp://1.2.3.5:2086")
+	h3 := newTestHello(t, "ip+udp://1.2.3.6:2086")
+	for _, hb := range []*blocks.HelloBlock{h1, h2, h3} {
+		hc.Put(hb.PeerID.String(), hb, 0)
+	}
+	if s := hc.Stats(); s.Hot != 2 || s.Cold != 1 || s.Demotions != 1 {
+		t.Fatalf("unexpected tiers after demotion: %s", s)
+	}
+
+	// access promotes a cold HELLO (and demotes the next one)
+	hb, ok := hc.Get(h1.PeerID.String(), 0)
+	if !ok || !hb.Equal(h1) {
+		t.Fatal("demoted HELLO not found")
+	}
+	if _, ok = hc.Map.Get(h1.PeerID.String(), 0); !ok {
+		t.Fatal("HELLO not promoted")
+	}
+	if _, ok = hc.Get(h3.PeerID.String(), 0); !ok {
+		t.Fatal("hot HELLO not found")
+	}
+	if _, ok = hc.Get(util.NewPeerID(util.NewRndArray(32)).String(), 0); ok {
+		t.Fatal("unknown HELLO found")
+	}
+	s := hc.Stats()
+	if s.Hot != 2 || s.Cold != 1 || s.HotHits != 1 || s.ColdHits != 1 || s.Misses != 1 || s.Promotions != 1 || s.Demotions != 2 {
+		t.Fatalf("unexpected counters: %s", s)
+	}
+
+	// cold HELLOs survive a restart
+	if hc, err = NewHelloCache(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if s = hc.Stats(); s.Hot != 0 || s.Cold != 1 {
+		t.Fatalf("unexpected tiers after restart: %s", s)
+	}
+	if hb, ok = hc.Get(h2.PeerID.String(), 0); !ok || !hb.Equal(h2) {
+		t.Fatal("persisted HELLO not found")
+	}
+	hc.Delete(h2.PeerID.String(), 0)
+	if _, ok = hc.Get(h2.PeerID.String(), 0); ok {
+		t.Fatal("deleted HELLO found")
+	}
+
+	// expired HELLOs are pruned from the cold tier
+	old := newTestHello(t, "ip+udp://1.2.3.7:2086")
+	old.Expire_ = util.AbsoluteTimeNow().Add(-time.Hour)
+	hc.Put(old.PeerID.String(), old, 0)
+	hc.Put(h1.PeerID.String(), h1, 0)
+	hc.Put(h3.PeerID.String(), h3, 0)
+	if s = hc.Stats(); s.Cold != 1 {
+		t.Fatalf("expired HELLO not demoted: %s", s)
+	}
+	if n := hc.PruneCold(); n != 1 || hc.Stats().Cold != 0 {
+		t.Fatalf("expected 1 pruned HELLO, got %d", n)
+	}
+}
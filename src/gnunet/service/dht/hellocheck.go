@@ -0,0 +1,156 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/service/dht/blocks"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Background verification of cached HELLOs: cached entries are checked
+// in small batches (round-robin by key) so a long-running node keeps
+// only HELLOs with a valid signature that are not expired. Invalid and
+// expired entries are evicted and counted.
+//----------------------------------------------------------------------
+
+// Background HELLO verification settings
+var (
+	HelloCheckPeriod = time.Minute // time between verification runs
+	HelloCheckBatch  = 32          // max. number of HELLOs verified per run
+)
+
+// HelloCheckStats are the counters of the background HELLO verification.
+type HelloCheckStats struct {
+	Runs    uint64 `json:"runs"`    // number of verification runs
+	Checked uint64 `json:"checked"` // number of verified HELLOs
+	Invalid uint64 `json:"invalid"` // HELLOs evicted for invalid signatures
+	Expired uint64 `json:"expired"` // HELLOs evicted for expiration
+	Errors  uint64 `json:"errors"`  // HELLOs evicted for verification errors
+}
+
+// String returns a human-readable representation of the counters.
+func (s HelloCheckStats) String() string {
+	return fmt.Sprintf("runs=%d,checked=%d,invalid=%d,expired=%d,errors=%d",
+		s.Runs, s.Checked, s.Invalid, s.Expired, s.Errors)
+}
+
+// helloChecker verifies cached HELLOs round-robin.
+type helloChecker struct {
+	sync.Mutex
+
+	last  string          // key of last verified HELLO
+	stats HelloCheckStats // anomaly counters
+}
+
+// Stats returns a copy of the current counters.
+func (hc *helloChecker) Stats() HelloCheckStats {
+	hc.Lock()
+	defer hc.Unlock()
+	return hc.stats
+}
+
+// CheckHellos verifies up to 'num' cached HELLOs (continuing after the
+// last HELLO checked in the previous run) and evicts invalid or expired
+// entries. Returns the number of evicted entries.
+func (rt *RoutingTable) CheckHellos(num int) (evicted int) {
+	hc := rt.helloCheck
+	hc.Lock()
+	defer hc.Unlock()
+	hc.stats.Runs++
+
+	// select the next batch of cached HELLOs (ordered by key)
+	var keys []string
+	_ = rt.helloCache.ProcessRange(func(key string, _ *blocks.HelloBlock, _ int) error {
+		keys = append(keys, key)
+		return nil
+	}, true)
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	start := sort.SearchStrings(keys, hc.last)
+	if start < len(keys) && keys[start] == hc.last {
+		start++
+	}
+	if num > len(keys) {
+		num = len(keys)
+	}
+	for i := 0; i < num; i++ {
+		key := keys[(start+i)%len(keys)]
+		hc.last = key
+		hb, ok := rt.helloCache.Get(key, 0)
+		if !ok {
+			continue
+		}
+		hc.stats.Checked++
+
+		// check expiration and signature (verification is done without
+		// holding the cache lock)
+		reason := ""
+		if hb.Expire_.ExpiredSkew(rt.skew) {
+			hc.stats.Expired++
+			reason = "expired"
+		} else if valid, err := hb.Verify(); err != nil {
+			hc.stats.Errors++
+			reason = "verification failed: " + err.Error()
+		} else if !valid {
+			hc.stats.Invalid++
+			reason = "invalid signature"
+		}
+		if len(reason) == 0 {
+			continue
+		}
+		logger.Printf(logger.WARN, "[dht-hello] evicting cached HELLO of %s: %s", hb.PeerID.Short(), reason)
+		evicted++
+
+		// only evict the checked entry (it might have been replaced
+		// by a newer HELLO in the meantime)
+		_ = rt.helloCache.Process(func(pid int) error {
+			if cur, ok := rt.helloCache.Get(key, pid); ok && cur == hb {
+				rt.helloCache.Delete(key, pid)
+			}
+			return nil
+		}, false)
+	}
+	return
+}
+
+// HelloCheckStats returns the counters of the background verification.
+func (rt *RoutingTable) HelloCheckStats() HelloCheckStats {
+	return rt.helloCheck.Stats()
+}
+
+// checkHellos verifies the next batch of cached HELLOs (maintenance job).
+func (m *Module) checkHellos(ctx context.Context) error {
+	if n := m.rtable.CheckHellos(HelloCheckBatch); n > 0 {
+		logger.Printf(logger.INFO, "[dht-hello] %d cached HELLOs evicted (%s)", n, m.rtable.HelloCheckStats())
+	}
+	if n := m.rtable.helloCache.PruneCold(); n > 0 {
+		logger.Printf(logger.INFO, "[dht-hello] %d expired HELLOs removed from cold cache", n)
+	}
+	return nil
+}
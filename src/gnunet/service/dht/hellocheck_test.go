@@ -0,0 +1,61 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"testing"
+	"time"
+
+	"gnunet/util"
+)
+
+func TestCheckHellos(t *testing.T) {
+	rt := NewRoutingTable(NewPeerAddress(util.NewPeerID(util.NewRndArray(32))), nil)
+
+	// three valid HELLOs, one with a broken signature, one expired
+	for i := 0; i < 3; i++ {
+		rt.CacheHello(newTestHello(t, "ip+udp://1.2.3.4:2086"))
+	}
+	bad := newTestHello(t, "ip+udp://1.2.3.5:2086")
+	bad.Signature.Data[0] ^= 0xff
+	rt.CacheHello(bad)
+	old := newTestHello(t, "ip+udp://1.2.3.6:2086")
+	old.Expire_ = util.AbsoluteTimeNow().Add(-time.Hour) // beyond clock skew
+	rt.CacheHello(old)
+
+	// verify in batches: all entries are checked exactly once
+	evicted := 0
+	for _, n := range []int{2, 2, 1} {
+		evicted += rt.CheckHellos(n)
+	}
+	if evicted != 2 || rt.helloCache.Size() != 3 {
+		t.Fatalf("expected 2 evicted and 3 cached HELLOs, got %d/%d", evicted, rt.helloCache.Size())
+	}
+	stats := rt.HelloCheckStats()
+	if stats.Runs != 3 || stats.Checked != 5 || stats.Invalid != 1 || stats.Expired != 1 || stats.Errors != 0 {
+		t.Fatalf("unexpected counters %s", stats)
+	}
+	// the remaining entries are valid
+	if n := rt.CheckHellos(10); n != 0 {
+		t.Fatalf("valid HELLOs evicted: %d", n)
+	}
+	if _, ok := rt.GetHello(bad.PeerID.String()); ok {
+		t.Fatal("invalid HELLO still cached")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"gnunet/enums"
 	"gnunet/service/dht/blocks"
 	"gnunet/service/store"
+	"gnunet/transport"
 
 	"github.com/bfix/gospel/logger"
 	"github.com/bfix/gospel/math"
@@ -31,7 +32,25 @@ import (
 func (m *Module) lookupHelloCache(label string, addr *PeerAddress, rf blocks.ResultFilter, approx bool) (results []*store.DHTResult) {
 	logger.Printf(logger.DBG, "[%s] GET message for HELLO: check cache", label)
 	// find best cached HELLO
-	return m.rtable.LookupHello(addr, rf, approx, label)
+	return m.rtable.LookupHello(addr, rf, approx, m.helloValidated, label)
+}
+
+// helloValidated returns true if a cached HELLO can be published on behalf
+// of its peer: all addresses usable by our transport must have been
+// validated. HELLO blocks are signed, so unvalidated addresses can't be
+// removed from the block; instead the whole block is withheld.
+func (m *Module) helloValidated(hb *blocks.HelloBlock) bool {
+	n := 0
+	for _, addr := range hb.Addresses() {
+		if !transport.CanHandleAddress(addr) {
+			continue
+		}
+		if !m.core.Validated(hb.PeerID, addr) {
+			return false
+		}
+		n++
+	}
+	return n > 0
 }
 
 // getLocalStorage tries to find the requested block in local storage
@@ -53,12 +72,14 @@ func (m *Module) getLocalStorage(label string, query blocks.Query, rf blocks.Res
 		// add to result filter
 		rf.Add(entry.Blk)
 	}
-	// if we have no exact match, find approximate block if requested
-	if len(results) == 0 || query.Flags()&enums.DHT_RO_FIND_APPROXIMATE != 0 {
-		// no exact match: find approximate (9.4.3.3b)
-		if results, err = m.store.GetApprox(label, query, rf); err != nil {
+	// find approximate blocks if requested (9.4.3.3b); exact matches are
+	// already in the result filter, so they are kept in front.
+	if query.Flags()&enums.DHT_RO_FIND_APPROXIMATE != 0 {
+		var approx []*store.DHTResult
+		if approx, err = m.store.GetApprox(label, query, rf); err != nil {
 			logger.Printf(logger.ERROR, "[%s] Failed to get (approx.) DHT blocks from storage: %s", label, err.Error())
 		}
+		results = append(results, approx...)
 	}
 	return
 }
@@ -0,0 +1,183 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+// newLocalModule returns a DHT module for a peer without endpoints.
+func newLocalModule(ctx context.Context, t *testing.T) *Module {
+	t.Helper()
+	c, err := core.NewCore(ctx, &config.NodeConfig{
+		Name:        "test",
+		PrivateSeed: "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DHTConfig{
+		Storage: util.ParameterSet{
+			"mode":  "file",
+			"cache": false,
+			"path":  t.TempDir(),
+			"maxGB": 1,
+		},
+		Routing: &config.RoutingConfig{
+			PeerTTL:   10800,
+			ReplLevel: 5,
+		},
+		Heartbeat: 900,
+	}
+	m, err := NewModule(ctx, c, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// storeTestBlock stores a random block of given type and returns the
+// query for it.
+func storeTestBlock(t *testing.T, m *Module, btype enums.BlockType) blocks.Query {
+	t.Helper()
+	buf := util.NewRndArray(128)
+	blk, err := blocks.NewBlock(btype, util.AbsoluteTimeNever(), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := blocks.NewGenericQuery(crypto.Hash(buf), btype, 0)
+	if err = m.store.Put(query, &store.DHTEntry{Blk: blk}); err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+// Test exact and approximate lookups in local storage.
+func TestLocalStorage(t *testing.T) {
+	// create file store
+	cfg := util.ParameterSet{
+		"mode":  "file",
+		"cache": false,
+		"path":  t.TempDir(),
+		"maxGB": 1,
+	}
+	st, err := store.NewDHTStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Module{store: st}
+
+	// store a block
+	buf := util.NewRndArray(128)
+	blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNever(), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := blocks.NewGenericQuery(crypto.Hash(buf), enums.BLOCK_TYPE_TEST, 0)
+	if err = st.Put(key, &store.DHTEntry{Blk: blk}); err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(q blocks.Query) int {
+		t.Helper()
+		rf := blocks.NewGenericResultFilter(128, util.RndUInt32())
+		res, err := m.getLocalStorage("test", q, rf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(res)
+	}
+	// exact match
+	if n := lookup(key); n != 1 {
+		t.Fatalf("exact lookup: %d results", n)
+	}
+	// no exact match and no approximate lookup requested
+	other := crypto.Hash(util.NewRndArray(32))
+	if n := lookup(blocks.NewGenericQuery(other, enums.BLOCK_TYPE_TEST, 0)); n != 0 {
+		t.Fatalf("exact lookup of unknown key: %d results", n)
+	}
+	// approximate lookup
+	flags := uint16(enums.DHT_RO_FIND_APPROXIMATE)
+	if n := lookup(blocks.NewGenericQuery(other, enums.BLOCK_TYPE_TEST, flags)); n != 1 {
+		t.Fatalf("approximate lookup: %d results", n)
+	}
+}
+
+// Test local GET requests for block types without a block handler.
+func TestGetUnknownType(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := newLocalModule(ctx, t)
+
+	query := storeTestBlock(t, m, enums.BLOCK_TYPE_DNS)
+	query.Params()["timeout"] = time.Second
+	n := 0
+	for blk := range m.Get(ctx, query) {
+		if blk.Type() != enums.BLOCK_TYPE_DNS {
+			t.Fatalf("unexpected block type %s", blk.Type())
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("%d results", n)
+	}
+}
+
+// Test GET requests from local clients (without sender).
+func TestGetLocalClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := newLocalModule(ctx, t)
+
+	query := storeTestBlock(t, m, enums.BLOCK_TYPE_TEST)
+	msg := message.NewDHTP2PGetMsg()
+	msg.BType = query.Type()
+	msg.Query = query.Key()
+	msg.ReplLevel = 1
+	msg.PeerFilter = blocks.NewPeerFilter()
+
+	// collect results sent back to the client
+	var results []message.Message
+	back := &transport.TransportResponder{
+		SendFcn: func(_ context.Context, _ *util.PeerID, msg message.Message) error {
+			results = append(results, msg)
+			return nil
+		},
+	}
+	if !m.HandleMessage(ctx, nil, msg, back) {
+		t.Fatal("GET not handled")
+	}
+	if len(results) != 1 {
+		t.Fatalf("%d results", len(results))
+	}
+	if _, ok := results[0].(*message.DHTP2PResultMsg); !ok {
+		t.Fatalf("unexpected response %v", results[0])
+	}
+}
@@ -24,11 +24,15 @@ import (
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/message"
+	"gnunet/script"
+	"gnunet/service"
 	"gnunet/service/dht/blocks"
 	"gnunet/service/dht/path"
 	"gnunet/service/store"
 	"gnunet/transport"
 	"gnunet/util"
+	"strings"
+	"time"
 
 	"github.com/bfix/gospel/logger"
 )
@@ -54,6 +58,13 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 	}
 	local := m.core.PeerID()
 
+	// messages from local clients (service socket) are handled as if
+	// they originated from the local peer.
+	origin := sender == nil
+	if origin {
+		sender = local
+	}
+
 	// process message
 	switch msg := msgIn.(type) {
 
@@ -66,6 +77,9 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		//--------------------------------------------------------------
 		logger.Printf(logger.INFO, "[%s] DHT-P2P-GET from %s (type %s, flags=%s)",
 			label, sender.Short(), msg.BType, message.DHTFlags(msg.Flags))
+		var trace *Trace
+		ctx, trace = m.trace(ctx, "get", msg.Query, msg.BType, msg.Flags, sender, origin)
+		defer trace.Add(TraceDone, nil, "")
 
 		// assemble query and initialize (cache) results
 		query := blocks.NewGenericQuery(msg.Query, msg.BType, msg.Flags)
@@ -79,6 +93,8 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			// validate block query
 			if !blockHdlr.ValidateBlockQuery(msg.Query, msg.XQuery) {
 				logger.Printf(logger.WARN, "[%s] invalid query -- message discarded", label)
+				trace.Add(TraceDrop, sender, "invalid query")
+				m.core.Invalid(sender, msgIn)
 				return false
 			}
 		} else {
@@ -96,8 +112,8 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			if blockHdlr != nil {
 				rf = blockHdlr.ParseResultFilter(msg.ResFilter)
 			} else {
-				logger.Printf(logger.WARN, "[%s] unknown result filter implementation -- message discarded", label)
-				return false
+				logger.Printf(logger.WARN, "[%s] unknown result filter implementation -- using default", label)
+				rf = blocks.NewGenericResultFilterFromBytes(msg.ResFilter)
 			}
 		} else {
 			// ... or create a new one
@@ -127,14 +143,22 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		}
 		// enforced actions
 		doResult, doForward := getActions(closest, demux, approx)
+		// a local sample GET is only sent through the given neighbor
+		viaPeer := via(ctx, sender, local)
+		if viaPeer != nil {
+			doResult, doForward = false, true
+		}
 		logger.Printf(logger.DBG, "[%s] Actions: closest=%v, demux=%v, approx=%v --> result=%v, forward=%v",
 			label, closest, demux, approx, doResult, doForward)
+		trace.Add(TraceDecision, nil, "closest=%v, demux=%v, approx=%v --> result=%v, forward=%v",
+			closest, demux, approx, doResult, doForward)
 
 		//------------------------------------------------------
 		// query for a HELLO? (9.4.3.3a)
 		if btype == enums.BLOCK_TYPE_DHT_HELLO {
 			// try to find results in HELLO cache
 			results = m.lookupHelloCache(label, addr, rf, approx)
+			trace.Add(TraceLocal, nil, "%d results in HELLO cache", len(results))
 			// DEBUG:
 			for i, res := range results {
 				logger.Printf(logger.DBG, "[%s] cache #%d = %s", label, i, res)
@@ -149,7 +173,10 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			if len(results) == 0 || approx {
 				// get results from local storage
 				lclResults, err := m.getLocalStorage(label, query, rf)
-				if err == nil {
+				if err != nil {
+					trace.Add(TraceLocal, nil, "storage lookup failed: %s", err.Error())
+				} else {
+					trace.Add(TraceLocal, nil, "%d results in storage", len(lclResults))
 					// DEBUG:
 					for i, res := range lclResults {
 						logger.Printf(logger.DBG, "[%s] local #%d = %s", label, i, res)
@@ -175,55 +202,110 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 					}
 				}
 			}
+			// local results must pass the additional block checks
+			if len(results) > 0 && !m.checkBlock(ctx, btype, query.Key()) {
+				logger.Printf(logger.WARN, "[%s] local results rejected by block check", label)
+				trace.Add(TraceDrop, nil, "local results rejected by block check")
+				results = nil
+			}
 			// if we have results, send them as response on the back channel
 			rcv := "local caller"
 			if back.Receiver() != nil {
 				rcv = back.Receiver().Short()
 			}
+			send := func(blk blocks.Block, pth *path.Path) {
+				logger.Printf(logger.INFO, "[%s] sending result message to %s", label, rcv)
+				if err := m.sendResult(ctx, query, blk, pth, back); err != nil {
+					logger.Printf(logger.ERROR, "[%s] Failed to send result message: %s", label, err.Error())
+					trace.Add(TraceDrop, back.Receiver(), "result not sent: %s", err.Error())
+					return
+				}
+				trace.Add(TraceResult, back.Receiver(), "local result sent to %s", rcv)
+			}
 			for _, result := range results {
 				var pth *path.Path
+				blk := result.Entry.Blk
 				// check if record the route
 				if msg.Flags&enums.DHT_RO_RECORD_ROUTE != 0 && result.Entry.Path != nil {
-					// update get path
+					// update get path; a local caller receives the stored
+					// path (no signature for the last hop required)
 					pth = result.Entry.Path.Clone()
 					pth.SplitPos = pth.NumList
-					pe := pth.NewElement(pth.LastHop, local, back.Receiver())
-					if err := m.core.Sign(pe); err != nil {
-						logger.Printf(logger.ERROR, "[%s] failed to sign path element: %s", label, err.Error())
-					} else {
-						pth.Add(pe)
+					if back.Receiver() != nil {
+						// the result is sent when the path element is signed
+						pe := pth.NewElement(originPeer(pth.LastHop), local, back.Receiver())
+						err := m.core.SignAsync(ctx, pe, func(err error) {
+							if err != nil {
+								logger.Printf(logger.ERROR, "[%s] failed to sign path element: %s", label, err.Error())
+							} else {
+								pth.Add(pe)
+							}
+							send(blk, pth)
+						})
+						if err != nil {
+							logger.Printf(logger.ERROR, "[%s] result not sent: %s", label, err.Error())
+						}
+						continue
 					}
 				}
-
-				logger.Printf(logger.INFO, "[%s] sending result message to %s", label, rcv)
-				if err := m.sendResult(ctx, query, result.Entry.Blk, pth, back); err != nil {
-					logger.Printf(logger.ERROR, "[%s] Failed to send result message: %s", label, err.Error())
-				}
+				send(blk, pth)
 			}
 		}
 		//--------------------------------------------------------------
 		// query flags demand a result
+		if doForward && ctx.Err() != nil {
+			// request cancelled (e.g. satisfied by a local result)
+			logger.Printf(logger.INFO, "[%s] request cancelled -- not forwarded", label)
+			trace.Add(TraceDrop, nil, "request cancelled -- not forwarded")
+			doForward = false
+		}
 		if doForward {
 			// build updated GET message
 			pf.Add(local)
 			msgOut := msg.Update(pf, rf, msg.HopCount+1)
 
-			// forward to number of peers
+			// forward to number of peers: a parallel lookup of a local
+			// client is forwarded to the 'alpha' closest peers.
 			numForward := m.rtable.ComputeOutDegree(msg.ReplLevel, msg.HopCount)
+			alpha, parallel := ctx.Value(CtxAlpha).(int)
+			parallel = parallel && origin && alpha > 0
+			if parallel {
+				numForward = alpha
+			}
+			if viaPeer != nil {
+				numForward = 1
+			}
 			for n := 0; n < numForward; n++ {
-				if p := m.rtable.SelectPeer(addr, msg.HopCount, pf, 0); p != nil {
+				var p *PeerAddress
+				switch {
+				case viaPeer != nil:
+					p = NewPeerAddress(viaPeer)
+				case parallel:
+					p = m.selectPeer(pf, func(f *blocks.PeerFilter) *PeerAddress {
+						return m.rtable.SelectClosestPeer(addr, f, 0)
+					})
+				default:
+					p = m.selectPeer(pf, func(f *blocks.PeerFilter) *PeerAddress {
+						return m.rtable.SelectPeer(addr, msg.HopCount, f, 0)
+					})
+				}
+				if p != nil {
 					// forward message to peer
 					logger.Printf(logger.INFO, "[%s] forward GET message to %s", label, p.Peer.Short())
 					if err := m.core.Send(ctx, p.Peer, msgOut); err != nil {
 						logger.Printf(logger.ERROR, "[%s] Failed to forward GET message: %s", label, err.Error())
+						trace.Add(TraceDrop, p.Peer, "GET not forwarded: %s", err.Error())
+					} else {
+						trace.Add(TraceForward, p.Peer, "GET forwarded (hop %d)", msgOut.HopCount)
 					}
 					pf.Add(p.Peer)
 					// create open get-forward result handler
-					rh := NewResultHandler(msg, rf, back, m.core)
+					rh := NewResultHandler(ctx, msg, rf, back, m.core)
 					logger.Printf(logger.INFO, "[%s] result handler task #%d (key %s) started",
 						label, rh.ID(), rh.Key().Short())
 					m.reshdlrs.Add(rh)
 				} else {
+					trace.Add(TraceForward, nil, "no more peers (%d of %d)", n, numForward)
 					break
 				}
 			}
@@ -239,12 +321,17 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		//----------------------------------------------------------
 		logger.Printf(logger.INFO, "[%s] DHT-P2P-PUT from %s (type %s, flags=%s)",
 			label, sender.Short(), msg.BType, message.DHTFlags(msg.Flags))
+		var trace *Trace
+		ctx, trace = m.trace(ctx, "put", msg.Key, msg.BType, msg.Flags, sender, origin)
+		defer trace.Add(TraceDone, nil, "")
 
 		// assemble query and entry
 		query := blocks.NewGenericQuery(msg.Key, msg.BType, msg.Flags)
 		blk, err := blocks.NewBlock(msg.BType, msg.Expire, msg.Block)
 		if err != nil {
 			logger.Printf(logger.ERROR, "[%s] message block problem: %s", label, err.Error())
+			trace.Add(TraceDrop, sender, "invalid block: %s", err.Error())
+			m.core.Invalid(sender, msgIn)
 			return false
 		}
 		entry := &store.DHTEntry{
@@ -254,8 +341,9 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 
 		//--------------------------------------------------------------
 		// check if request is expired (9.3.2.1)
-		if msg.Expire.Expired() {
+		if msg.Expire.ExpiredSkew(m.core.Policy().MaxSkew) {
 			logger.Printf(logger.WARN, "[%s] PUT message expired (%s) -- ignored", label, msg.Expire)
+			trace.Add(TraceDrop, sender, "expired (%s)", msg.Expire)
 			return false
 		}
 		blockHdlr, ok := blocks.BlockHandlers[msg.BType]
@@ -266,12 +354,16 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 				// validate block key (9.3.2.3)
 				if !blockHdlr.ValidateBlockKey(block, msg.Key) {
 					logger.Printf(logger.WARN, "[%s] PUT invalid key -- discarded", label)
+					trace.Add(TraceDrop, sender, "invalid key")
+					m.core.Invalid(sender, msgIn)
 					return false
 				}
 
 				// validate block payload (9.3.2.4)
 				if !blockHdlr.ValidateBlockStoreRequest(block) {
 					logger.Printf(logger.WARN, "[%s] PUT invalid payload -- discarded", label)
+					trace.Add(TraceDrop, sender, "invalid payload")
+					m.core.Invalid(sender, msgIn)
 					return false
 				}
 			}
@@ -279,6 +371,12 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			logger.Printf(logger.INFO, "[%s] No validator defined for block type %s", label, msg.BType)
 			blockHdlr = nil
 		}
+		// run additional block checks
+		if !m.checkBlock(ctx, msg.BType, msg.Key) {
+			logger.Printf(logger.WARN, "[%s] PUT rejected by block check -- discarded", label)
+			trace.Add(TraceDrop, sender, "rejected by block check")
+			return false
+		}
 		// clone peer filter
 		pf := msg.PeerFilter.Clone()
 
@@ -288,8 +386,15 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		closest := m.rtable.IsClosestPeer(nil, addr, msg.PeerFilter, 0)
 		demux := int(msg.Flags)&enums.DHT_RO_DEMULTIPLEX_EVERYWHERE != 0
 		doStore, doForward := putActions(closest, demux)
+		// a repeated local PUT is only sent through the given neighbor
+		viaPeer := via(ctx, sender, local)
+		if viaPeer != nil {
+			doStore, doForward = false, true
+		}
 		logger.Printf(logger.DBG, "[%s] Actions: closest=%v, demux=%v => doStore=%v, doForward=%v",
 			label, closest, demux, doStore, doForward)
+		trace.Add(TraceDecision, nil, "closest=%v, demux=%v --> store=%v, forward=%v",
+			closest, demux, doStore, doForward)
 
 		//--------------------------------------------------------------
 		// check if sender is in peer filter (9.3.2.5)
@@ -301,8 +406,18 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		// 'entry.Path' will be used as path in stored and forwarded messages.
 		// The resulting path is always valid; it is truncated/reset on
 		// signature failure.
-		entry.Path = msg.Path(sender)
+		// A message originating from the local peer has no predecessor.
+		prev := sender
+		if origin {
+			prev = nil
+		}
+		entry.Path = msg.Path(prev)
 		entry.Path.Verify(local)
+		// drop replayed messages (same signed last hop element)
+		if pe := entry.Path.LastElement(local); pe != nil && m.replayed("put", pe.ReplayID(), msg.Expire, label) {
+			trace.Add(TraceDrop, sender, "replayed")
+			return false
+		}
 
 		//--------------------------------------------------------------
 		// store locally if we are closest peer or demux is set (9.3.2.8)
@@ -310,6 +425,9 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			// store in local storage
 			if err := m.store.Put(query, entry); err != nil {
 				logger.Printf(logger.ERROR, "[%s] failed to store DHT entry: %s", label, err.Error())
+				trace.Add(TraceStore, nil, "not stored: %s", err.Error())
+			} else {
+				trace.Add(TraceStore, nil, "stored (path length %d)", entry.Path.NumList)
 			}
 		}
 		//--------------------------------------------------------------
@@ -326,27 +444,48 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 
 			// forward to computed number of peers
 			numForward := m.rtable.ComputeOutDegree(msg.ReplLvl, msg.HopCount)
+			if viaPeer != nil {
+				numForward = 1
+			}
 			for n := 0; n < numForward; n++ {
-				if p := m.rtable.SelectPeer(addr, msg.HopCount, pf, 0); p != nil {
-					// check if route is recorded (9.3.2.6)
-					var pp *path.Path
-					if msg.Flags&enums.DHT_RO_RECORD_ROUTE != 0 {
-						// yes: add path element
-						pp = entry.Path.Clone()
-						pe := pp.NewElement(sender, local, p.Peer)
-						if err := m.core.Sign(pe); err != nil {
-							logger.Printf(logger.ERROR, "[%s] failed to sign path element: %s", label, err.Error())
+				p := m.selectPeer(pf, func(f *blocks.PeerFilter) *PeerAddress {
+					return m.rtable.SelectPeer(addr, msg.HopCount, f, 0)
+				})
+				if viaPeer != nil {
+					p = NewPeerAddress(viaPeer)
+				}
+				if p != nil {
+					// forward updated PUT message to peer
+					forward := func(pp *path.Path, pf *blocks.PeerFilter) {
+						msgOut := msg.Update(pp, pf, msg.HopCount+1)
+						logger.Printf(logger.INFO, "[%s] forward PUT message to %s", label, p.Peer.Short())
+						if err := m.core.Send(ctx, p.Peer, msgOut); err != nil {
+							logger.Printf(logger.ERROR, "[%s] Failed to forward PUT message: %s", label, err.Error())
+							trace.Add(TraceDrop, p.Peer, "PUT not forwarded: %s", err.Error())
 						} else {
-							pp.Add(pe)
+							trace.Add(TraceForward, p.Peer, "PUT forwarded (hop %d)", msgOut.HopCount)
 						}
 					}
-					// build updated PUT message
-					msgOut := msg.Update(pp, pf, msg.HopCount+1)
-
-					// forward message to peer
-					logger.Printf(logger.INFO, "[%s] forward PUT message to %s", label, p.Peer.Short())
-					if err := m.core.Send(ctx, p.Peer, msgOut); err != nil {
-						logger.Printf(logger.ERROR, "[%s] Failed to forward PUT message: %s", label, err.Error())
+					// check if route is recorded (9.3.2.6)
+					if msg.Flags&enums.DHT_RO_RECORD_ROUTE != 0 {
+						// yes: add path element; the message is forwarded
+						// (with the current peer filter) when it is signed.
+						pp := entry.Path.Clone()
+						pe := pp.NewElement(originPeer(prev), local, p.Peer)
+						pfOut := pf.Clone()
+						err := m.core.SignAsync(ctx, pe, func(err error) {
+							if err != nil {
+								logger.Printf(logger.ERROR, "[%s] failed to sign path element: %s", label, err.Error())
+							} else {
+								pp.Add(pe)
+							}
+							forward(pp, pfOut)
+						})
+						if err != nil {
+							logger.Printf(logger.ERROR, "[%s] PUT not forwarded: %s", label, err.Error())
+						}
+					} else {
+						forward(nil, pf)
 					}
 					// add forward node to filter
 					pf.Add(p.Peer)
@@ -369,7 +508,7 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 
 		//--------------------------------------------------------------
 		// check if request is expired (9.5.2.1)
-		if msg.Expire.Expired() {
+		if msg.Expire.ExpiredSkew(m.core.Policy().MaxSkew) {
 			logger.Printf(logger.WARN, "[%s] message expired (%s) -- ignoring",
 				label, msg.Expire.String())
 			return false
@@ -384,6 +523,7 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 				// validate block (9.5.2.2)
 				if !blockHdlr.ValidateBlockStoreRequest(block) {
 					logger.Printf(logger.WARN, "[%s] RESULT invalid block -- discarded", label)
+					m.core.Invalid(sender, msgIn)
 					return false
 				}
 				// Compute block key (9.5.2.4)
@@ -393,6 +533,11 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			logger.Printf(logger.INFO, "[%s] No validator defined for block type %s", label, btype.String())
 			blockHdlr = nil
 		}
+		// run additional block checks
+		if !m.checkBlock(ctx, btype, msg.Query) {
+			logger.Printf(logger.WARN, "[%s] RESULT rejected by block check -- discarded", label)
+			return false
+		}
 		//--------------------------------------------------------------
 		// verify path (9.5.2.3)
 		var pth *path.Path
@@ -406,6 +551,16 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		if btype == enums.BLOCK_TYPE_DHT_HELLO {
 			m.addSender(msg.Block, label, sender)
 		}
+		// ask event scripts if the result is acceptable
+		if !script.Run(script.HookResult, map[string]any{
+			"query": msg.Query.String(),
+			"type":  uint32(btype),
+			"size":  len(msg.Block),
+			"from":  sender.String(),
+		}) {
+			logger.Printf(logger.INFO, "[%s] RESULT rejected by script -- discarded", label)
+			return false
+		}
 		// message forwarding to responder
 		logger.Printf(logger.DBG, "[%s] result key = %s", label, msg.Query.Short())
 		handled := false
@@ -414,6 +569,12 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 			for _, rh := range list {
 				logger.Printf(logger.DBG, "[%s] Result handler task #%d found (receiver %s)", label, rh.ID(), rh.Receiver().Short())
 
+				// skip handlers of finished or cancelled requests
+				if rh.Done() {
+					logger.Printf(logger.DBG, "[%s] Result handler task #%d done -- skipped", label, rh.ID())
+					continue
+				}
+
 				// check if the handler can really handle the result
 				if rh.Type() != btype {
 					// this is another block type, we don't handle it
@@ -464,11 +625,18 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		// verify integrity of message
 		if ok, err := msg.Verify(sender); !ok || err != nil {
 			logger.Printf(logger.WARN, "[%s] Received invalid HELLO message", label)
+			m.core.Invalid(sender, msgIn)
 			if err != nil {
 				logger.Printf(logger.ERROR, "[%s] --> %s", label, err.Error())
 			}
 			return false
 		}
+		// drop replayed HELLOs (older than a HELLO already seen)
+		if m.replay.Older("hello", sender.Bytes(), msg.Expire) {
+			logger.Printf(logger.WARN, "[%s] outdated HELLO from %s -- dropped", label, sender.Short())
+			service.Count("dht:replay-hello", 1)
+			return false
+		}
 		// keep peer addresses in core for transports
 		aList, err := msg.Addresses()
 		if err != nil {
@@ -499,16 +667,19 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		// we need to cache a new(er) HELLO
 		if isNew {
 			logger.Printf(logger.INFO, "[%s] caching HELLO from %s", label, sender.Short())
-			m.rtable.CacheHello(&blocks.HelloBlock{
+			hb := &blocks.HelloBlock{
 				PeerID:    sender,
 				Signature: msg.Signature,
 				Expire_:   msg.Expire,
 				AddrBin:   util.Clone(msg.AddrList),
-			})
+			}
+			m.rtable.CacheHello(hb)
+			m.shareHello(ctx, hb)
 		}
 
 	//==================================================================
-	// Legacy message types (not implemented)
+	// Client message types: handled on the service socket (see
+	// HandleClientMessage); ignored if received from the network.
 	//==================================================================
 
 	case *message.DHTClientPutMsg:
@@ -535,6 +706,36 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 		//----------------------------------------------------------
 		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientGetStop message", label)
 
+	case *message.DHTClientGetCreditMsg:
+		//----------------------------------------------------------
+		// DHT GET-CREDIT
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientGetCredit message", label)
+
+	case *message.DHTClientGetLimitMsg:
+		//----------------------------------------------------------
+		// DHT GET-LIMIT
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientGetLimit message", label)
+
+	case *message.DHTClientGetDoneMsg:
+		//----------------------------------------------------------
+		// DHT GET-DONE
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientGetDone message", label)
+
+	case *message.DHTClientPutVerifyMsg:
+		//----------------------------------------------------------
+		// DHT PUT-VERIFY
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientPutVerify message", label)
+
+	case *message.DHTClientPutConfirmMsg:
+		//----------------------------------------------------------
+		// DHT PUT-CONFIRM
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[%s] Ignoring DHTClientPutConfirm message", label)
+
 	case *message.DHTClientResultMsg:
 		//----------------------------------------------------------
 		// DHT RESULT
@@ -555,6 +756,23 @@ func (m *Module) HandleMessage(ctx context.Context, sender *util.PeerID, msgIn m
 // Helpers
 //----------------------------------------------------------------------
 
+// trace returns the trace of a request: either the trace in the context
+// or a new trace if the request is sampled (nil if not traced).
+func (m *Module) trace(ctx context.Context, kind string, key *crypto.HashCode, btype enums.BlockType, flags uint16, sender *util.PeerID, origin bool) (context.Context, *Trace) {
+	if t := traceFromContext(ctx); t != nil {
+		return ctx, t
+	}
+	from := sender
+	if origin {
+		from = nil
+	}
+	t := m.tracer.sample(kind, key, btype, flags, from)
+	if t == nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, CtxTrace, t), t
+}
+
 // add a HELLO block sender to routing table
 func (m *Module) addSender(block []byte, label string, sender *util.PeerID) {
 	// get addresses from HELLO block
@@ -599,6 +817,58 @@ func (m *Module) sendResult(ctx context.Context, query blocks.Query, blk blocks.
 	return back.Send(ctx, out)
 }
 
+// PathReplayWindow is the time a signed path element is remembered for
+// replay detection (at most until the message expires).
+var PathReplayWindow = 10 * time.Minute
+
+// replayed returns true if a signed path element was seen before within
+// the replay window (the drop is logged and counted).
+func (m *Module) replayed(kind string, id []byte, expire util.AbsoluteTime, label string) bool {
+	if until := util.AbsoluteTimeNow().Add(PathReplayWindow); until.Compare(expire) < 0 {
+		expire = until
+	}
+	if !m.replay.Seen(kind, id, expire) {
+		return false
+	}
+	logger.Printf(logger.WARN, "[%s] replayed %s message -- dropped", label, strings.ToUpper(kind))
+	service.Count("dht:replay-"+kind, 1)
+	return true
+}
+
+// maxReselect is the max. number of alternatives tried if a selected
+// peer is not well-behaved.
+const maxReselect = 4
+
+// selectPeer selects a peer for forwarding a message: a peer exceeding
+// its bandwidth quota is only selected if no well-behaved alternative is
+// found. The peer filter of the message is not changed.
+func (m *Module) selectPeer(pf *blocks.PeerFilter, sel func(*blocks.PeerFilter) *PeerAddress) *PeerAddress {
+	p := sel(pf)
+	if p == nil || m.core.PeerStats(p.Peer).WellBehaved() {
+		return p
+	}
+	tried := pf.Clone()
+	for i, q := 0, p; i < maxReselect; i++ {
+		tried.Add(q.Peer)
+		if q = sel(tried); q == nil {
+			break
+		}
+		if m.core.PeerStats(q.Peer).WellBehaved() {
+			return q
+		}
+	}
+	return p
+}
+
+// originPeer returns the predecessor for a path element: a message
+// originating from the local peer has the zero peer as predecessor.
+func originPeer(pred *util.PeerID) *util.PeerID {
+	if pred == nil {
+		return util.NewPeerID(nil)
+	}
+	return pred
+}
+
 // get enforced action for GET message
 func getActions(closest, demux, approx bool) (doResult, doForward bool) {
 	return closest || (demux && approx), !closest || (demux && !approx)
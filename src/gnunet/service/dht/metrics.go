@@ -0,0 +1,80 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/service/metrics"
+	"gnunet/service/store"
+)
+
+// StoreStatsPeriod is the time between counting the blocks in the store.
+var StoreStatsPeriod = 5 * time.Minute
+
+// DHT metrics
+var (
+	storeBlocks = metrics.NewGauge("gnunet_dht_store_blocks",
+		"Blocks in the DHT store by block type.", "type")
+)
+
+// registerMetrics exports the routing table occupancy on the metrics
+// endpoint.
+func (m *Module) registerMetrics() {
+	metrics.NewGaugeFunc("gnunet_dht_routing_peers",
+		"Peers in the routing table.", func() float64 {
+			return float64(m.rtable.list.Size())
+		})
+	metrics.NewGaugeVecFunc("gnunet_dht_routing_bucket_peers",
+		"Peers in non-empty routing table buckets.", "bucket", m.rtable.Occupancy)
+}
+
+// storeStats counts the blocks in the store by type (maintenance job).
+func (m *Module) storeStats(ctx context.Context) error {
+	count := make(map[string]int)
+	err := m.store.Traverse(nil, func(_ *crypto.HashCode, e *store.DHTEntry) bool {
+		count[blockTypeName(e.Blk.Type())]++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	storeBlocks.Reset()
+	for name, n := range count {
+		storeBlocks.Set(float64(n), name)
+	}
+	return nil
+}
+
+// Occupancy returns the number of peers in non-empty buckets (keyed by
+// bucket index).
+func (rt *RoutingTable) Occupancy() map[string]float64 {
+	occ := make(map[string]float64)
+	for i, b := range rt.buckets {
+		b.RLock()
+		if n := len(b.list); n > 0 {
+			occ[strconv.Itoa(i)] = float64(n)
+		}
+		b.RUnlock()
+	}
+	return occ
+}
@@ -0,0 +1,61 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/service/metrics"
+)
+
+func TestDHTMetrics(t *testing.T) {
+	m, _ := newTestModule(t, 8)
+	for i := 0; i < 3; i++ {
+		testBlock(t, m, crypto.Hash([]byte{byte(i)}), true)
+	}
+	if err := m.storeStats(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m.registerMetrics()
+
+	// occupancy of buckets adds up to the routing table size
+	total := 0.
+	for _, n := range m.rtable.Occupancy() {
+		total += n
+	}
+	if int(total) != m.rtable.list.Size() {
+		t.Fatalf("occupancy mismatch: %.0f != %d", total, m.rtable.list.Size())
+	}
+	buf := new(bytes.Buffer)
+	if err := metrics.Default.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`gnunet_dht_store_blocks{type="TEST"} 3`,
+		"gnunet_dht_routing_peers 8",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Fatalf("missing line '%s' in:\n%s", line, buf.String())
+		}
+	}
+}
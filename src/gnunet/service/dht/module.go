@@ -30,8 +30,12 @@ import (
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
 	"gnunet/service/store"
+	"gnunet/transport"
 	"gnunet/util"
 	gmath "math"
+	"net"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bfix/gospel/logger"
@@ -46,17 +50,24 @@ import (
 //----------------------------------------------------------------------
 
 // LocalBlockResponder is a message handler used to handle results for
-// locally initiated GET calls
+// locally initiated GET calls. The back-channel is closed (after all
+// pending results are delivered or dropped) when the responder is closed.
 type LocalBlockResponder struct {
-	ch chan blocks.Block   // out-going channel for incoming block results
-	rf blocks.ResultFilter // filter out duplicates
+	sync.Mutex
+
+	ch     chan blocks.Block   // out-going channel for incoming block results
+	rf     blocks.ResultFilter // filter out duplicates
+	done   chan struct{}       // closed when the responder is closed
+	closed bool                // responder closed?
+	wg     sync.WaitGroup      // pending result deliveries
 }
 
 // NewLocalBlockResponder returns a new instance
 func NewLocalBlockResponder() *LocalBlockResponder {
 	return &LocalBlockResponder{
-		ch: make(chan blocks.Block),
-		rf: blocks.NewGenericResultFilter(128, util.RndUInt32()),
+		ch:   make(chan blocks.Block),
+		rf:   blocks.NewGenericResultFilter(128, util.RndUInt32()),
+		done: make(chan struct{}),
 	}
 }
 
@@ -70,11 +81,25 @@ func (lr *LocalBlockResponder) Send(ctx context.Context, msg message.Message) er
 	// check if incoming message is a DHT-RESULT
 	switch res := msg.(type) {
 	case *message.DHTP2PResultMsg:
+		// results arriving after close are dropped
+		lr.Lock()
+		if lr.closed {
+			lr.Unlock()
+			logger.Println(logger.DBG, "[local] DHT-RESULT after close -- dropped")
+			return nil
+		}
+		lr.wg.Add(1)
+		lr.Unlock()
+
 		// deliver incoming blocks
 		go func() {
+			defer lr.wg.Done()
 			blk, err := blocks.NewBlock(res.BType, res.Expire, res.Block)
 			if err == nil {
-				lr.ch <- blk
+				select {
+				case lr.ch <- blk:
+				case <-lr.done:
+				}
 			} else {
 				logger.Println(logger.WARN, "[local] DHT-RESULT block problem: "+err.Error())
 				// DEBUG:
@@ -94,97 +119,184 @@ func (lr *LocalBlockResponder) Receiver() *util.PeerID {
 	return nil
 }
 
-// Close back-channel
+// Close the responder: pending deliveries are aborted and the back-channel
+// is closed once they have finished.
 func (lr *LocalBlockResponder) Close() {
-	close(lr.ch)
+	lr.Lock()
+	defer lr.Unlock()
+	if lr.closed {
+		return
+	}
+	lr.closed = true
+	close(lr.done)
+	go func() {
+		lr.wg.Wait()
+		close(lr.ch)
+	}()
 }
 
 //----------------------------------------------------------------------
 // Put and get blocks into/from a DHT.
 //----------------------------------------------------------------------
 
+// Core is the set of core services used by the DHT module. It is
+// implemented by core.Core (and by mocks in unit tests).
+type Core interface {
+	PeerID() *util.PeerID
+	Sign(obj crypto.Signable) error
+	SignAsync(ctx context.Context, obj crypto.Signable, done func(error)) error
+	Send(ctx context.Context, peer *util.PeerID, msg message.Message) error
+	SendToAddr(ctx context.Context, addr *util.Address, msg message.Message) error
+	TryConnect(peer *util.PeerID, addr net.Addr) error
+	Learn(ctx context.Context, peer *util.PeerID, addrs []*util.Address, label string) bool
+	Validated(peer *util.PeerID, addr *util.Address) bool
+	HelloAddresses() ([]*util.Address, error)
+	PeerAddresses(peer *util.PeerID) []*util.Address
+	HelloTTL() time.Duration
+	Policy() *core.AddrPolicy
+	Register(name string, l *core.Listener)
+	PeerStats(peer *util.PeerID) *core.PeerStats
+	Invalid(peer *util.PeerID, msg message.Message)
+}
+
 // Module handles the permanent storage of blocks under a query key.
 type Module struct {
 	service.ModuleImpl
 
 	cfg   *config.DHTConfig // configuraion parameters
-	store *store.DHTStore   // reference to the block storage mechanism
-	core  *core.Core        // reference to core services
+	store store.DHTStore    // reference to the block storage mechanism
+	core  Core              // reference to core services
 
 	rtable    *RoutingTable           // routing table
-	lastHello *message.DHTP2PHelloMsg // last own HELLO message used; re-create if expired
+	lastHello *message.DHTP2PHelloMsg // last own HELLO message used; re-create if about to expire
 	reshdlrs  *ResultHandlerList      // list of open tasks
+	gc        *storeGC                // store garbage collection
+	replay    *util.ReplayCache       // seen signed messages (replay protection)
+	tracer    *tracer                 // sampled request traces
+	checks    *blockChecks            // additional block checks
+	zones     *zoneIndex              // zones of local GNS queries
+
+	// store records in the peerstore (if linked)
+	PeerstoreStore func(ctx context.Context, rec *store.PeerstoreRecord, replace bool) error
+
+	// check if a zone key is revoked (if linked)
+	RevocationQuery func(ctx context.Context, zkey *crypto.ZoneKey) (valid bool, err error)
 }
 
 // NewModule returns a new module instance. It initializes the storage
 // mechanism for persistence.
-func NewModule(ctx context.Context, c *core.Core, cfg *config.DHTConfig) (m *Module, err error) {
+func NewModule(ctx context.Context, c Core, cfg *config.DHTConfig) (m *Module, err error) {
 	// create permanent storage handler
-	var storage *store.DHTStore
+	var storage store.DHTStore
 	if storage, err = store.NewDHTStore(cfg.Storage); err != nil {
 		return
 	}
 	// create routing table
 	rt := NewRoutingTable(NewPeerAddress(c.PeerID()), cfg.Routing)
+	rt.skew = c.Policy().MaxSkew
+	if cfg.HelloCache != nil {
+		if rt.helloCache, err = NewHelloCache(cfg.HelloCache); err != nil {
+			return
+		}
+	}
 
-	// return module instance
-	m = &Module{
+	// assemble module instance
+	m = newModule(c, cfg, storage, rt)
+
+	// register as listener for core events
+	listener := m.Run(ctx, m.event, m.Filter())
+	c.Register("dht", listener)
+	service.RegisterIntrospector("dht", m.Introspect)
+	m.registerMetrics()
+
+	// register maintenance jobs
+	jobs := []struct {
+		name   string
+		period time.Duration
+		run    service.Job
+	}{
+		{"dht:maintenance", time.Duration(cfg.Heartbeat) * time.Second, m.heartbeat},
+		{"dht:discovery", DiscoveryPeriod, m.discover},
+		{"dht:hello-check", HelloCheckPeriod, m.checkHellos},
+		{"dht:gc", m.gc.period, m.collectGarbage},
+		{"dht:store-stats", StoreStatsPeriod, m.storeStats},
+	}
+	for _, job := range jobs {
+		if err = service.Schedule(ctx, job.name, job.period, job.run); err != nil {
+			logger.Printf(logger.ERROR, "[dht] job '%s' not scheduled: %s", job.name, err.Error())
+			err = nil
+		}
+	}
+	return
+}
+
+// newModule assembles a module from its parts without registering it
+// with core or scheduling jobs.
+func newModule(c Core, cfg *config.DHTConfig, storage store.DHTStore, rt *RoutingTable) *Module {
+	return &Module{
 		ModuleImpl: *service.NewModuleImpl(),
 		cfg:        cfg,
 		store:      storage,
 		core:       c,
 		rtable:     rt,
 		reshdlrs:   NewResultHandlerList(),
+		gc:         newStoreGC(cfg.GC),
+		replay:     util.NewReplayCache(0),
+		tracer:     newTracer(cfg.Tracing),
+		checks:     newBlockChecks(),
+		zones:      newZoneIndex(),
 	}
-	// register as listener for core events
-	pulse := time.Duration(cfg.Heartbeat) * time.Second
-	listener := m.Run(ctx, m.event, m.Filter(), pulse, m.heartbeat)
-	c.Register("dht", listener)
+}
+
+// Introspect returns health details of the module: the number of active
+// result handlers and the sizes of routing table and HELLO cache tiers.
+func (m *Module) Introspect() *service.Introspection {
+	hs := m.rtable.HelloCacheStats()
+	return &service.Introspection{
+		Handlers: m.reshdlrs.Size(),
+		Caches: map[string]int{
+			"routing":    m.rtable.list.Size(),
+			"hello":      hs.Hot,
+			"hello-cold": hs.Cold,
+		},
+	}
+}
 
-	// run periodic tasks (8.2. peer discovery)
-	ticker := time.NewTicker(DiscoveryPeriod)
+// discover peers (8.2): query the DHT for our own HELLO block to learn
+// about peers close to us.
+func (m *Module) discover(ctx context.Context) error {
 	key := crypto.Hash(m.core.PeerID().Bytes())
 	flags := uint16(enums.DHT_RO_FIND_APPROXIMATE | enums.DHT_RO_DEMULTIPLEX_EVERYWHERE | enums.DHT_RO_DISCOVERY)
-	var resCh <-chan blocks.Block
-	go func() {
-		for {
-			select {
-			// initiate peer discovery
-			case <-ticker.C:
-				// query DHT for our own HELLO block
-				query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_DHT_HELLO, flags)
-				logger.Printf(logger.DBG, "[dht-discovery] own HELLO key %s", query.Key().Short())
-				resCh = m.Get(ctx, query)
-
-			// handle peer discover results
-			case res := <-resCh:
-				// check for correct type
-				btype := res.Type()
-				if btype == enums.BLOCK_TYPE_DHT_HELLO {
-					hb, ok := res.(*blocks.HelloBlock)
-					if !ok {
-						logger.Println(logger.WARN, "[dht-discovery] received invalid block data")
-						logger.Printf(logger.DBG, "[dht-discovery] -> %s", hex.EncodeToString(res.Bytes()))
-					} else if !hb.PeerID.Equal(m.core.PeerID()) {
-						// cache HELLO block
-						m.rtable.CacheHello(hb)
-						// add sender to routing table
-						m.rtable.Add(NewPeerAddress(hb.PeerID), "dht-discovery")
-						// learn addresses
-						m.core.Learn(ctx, hb.PeerID, hb.Addresses(), "dht-discovery")
-					}
-				} else {
-					logger.Printf(logger.WARN, "[dht-discovery] received invalid block type %s", btype)
-				}
-
-			// termination
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			}
+	query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_DHT_HELLO, flags)
+	if dl, ok := ctx.Deadline(); ok {
+		// finish the run well before the next one is due
+		query.Params()["timeout"] = time.Until(dl) / 2
+	}
+	logger.Printf(logger.DBG, "[dht-discovery] own HELLO key %s", query.Key().Short())
+
+	// handle peer discovery results
+	for res := range m.Get(ctx, query) {
+		// check for correct type
+		btype := res.Type()
+		if btype != enums.BLOCK_TYPE_DHT_HELLO {
+			logger.Printf(logger.WARN, "[dht-discovery] received invalid block type %s", btype)
+			continue
 		}
-	}()
-	return
+		hb, ok := res.(*blocks.HelloBlock)
+		if !ok {
+			logger.Println(logger.WARN, "[dht-discovery] received invalid block data")
+			logger.Printf(logger.DBG, "[dht-discovery] -> %s", hex.EncodeToString(res.Bytes()))
+		} else if !hb.PeerID.Equal(m.core.PeerID()) {
+			// cache HELLO block
+			m.rtable.CacheHello(hb)
+			m.shareHello(ctx, hb)
+			// add sender to routing table
+			m.addPeer(ctx, NewPeerAddress(hb.PeerID), "dht-discovery")
+			// learn addresses
+			m.core.Learn(ctx, hb.PeerID, hb.Addresses(), "dht-discovery")
+		}
+	}
+	return nil
 }
 
 //----------------------------------------------------------------------
@@ -196,16 +308,45 @@ func NewModule(ctx context.Context, c *core.Core, cfg *config.DHTConfig) (m *Mod
 // returned results to the caller; the channel is closed if no further blocks
 // are expected or the query times out.
 func (m *Module) Get(ctx context.Context, query blocks.Query) <-chan blocks.Block {
+	m.learnZone(query)
+
+	// assemble a new GET message
+	msg := m.newGetMsg(query)
+
+	// compose a response channel and handler
+	hdlr := NewLocalBlockResponder()
+
+	// time-out handling
+	ttl, ok := util.GetParam[time.Duration](query.Params(), "timeout")
+	if !ok {
+		// defaults to 10 minutes
+		ttl = DefaultGetTTL
+	}
+	lctx, cancel := context.WithTimeout(ctx, ttl)
+
+	// send message
+	go m.HandleMessage(lctx, m.core.PeerID(), msg, hdlr)
+	go func() {
+		<-lctx.Done()
+		hdlr.Close()
+		cancel()
+	}()
+	return hdlr.C()
+}
+
+// newGetMsg assembles a DHT-P2P-GET message for a locally initiated query.
+func (m *Module) newGetMsg(query blocks.Query) *message.DHTP2PGetMsg {
 	// get the block handler for given block type to construct an empty
-	// result filter. If no handler is defined, a default PassResultFilter
+	// result filter. If no handler is defined, a default GenericResultFilter
 	// is created.
-	var rf blocks.ResultFilter = new(blocks.GenericResultFilter)
+	var rf blocks.ResultFilter
 	blockHdlr, ok := blocks.BlockHandlers[query.Type()]
 	if ok {
 		// create result filter
 		rf = blockHdlr.SetupResultFilter(128, util.RndUInt32())
 	} else {
-		logger.Println(logger.WARN, "[dht] unknown result filter implementation -- skipped")
+		logger.Println(logger.WARN, "[dht] unknown result filter implementation -- using default")
+		rf = blocks.NewGenericResultFilter(128, util.RndUInt32())
 	}
 	// get additional query parameters
 	xquery, _ := util.GetParam[[]byte](query.Params(), "xquery")
@@ -222,34 +363,17 @@ func (m *Module) Get(ctx context.Context, query blocks.Query) <-chan blocks.Bloc
 	msg.RfSize = uint16(len(msg.ResFilter))
 	msg.XQuery = xquery
 	msg.MsgSize += msg.RfSize + uint16(len(xquery))
-
-	// compose a response channel and handler
-	hdlr := NewLocalBlockResponder()
-
-	// time-out handling
-	ttl, ok := util.GetParam[time.Duration](query.Params(), "timeout")
-	if !ok {
-		// defaults to 10 minutes
-		ttl = DefaultGetTTL
-	}
-	lctx, cancel := context.WithTimeout(ctx, ttl)
-
-	// send message
-	self := m.core.PeerID()
-	msg.PeerFilter.Add(self)
-	go m.HandleMessage(lctx, self, msg, hdlr)
-	go func() {
-		<-lctx.Done()
-		hdlr.Close()
-		cancel()
-	}()
-	return hdlr.C()
+	msg.PeerFilter.Add(m.core.PeerID())
+	return msg
 }
 
 // Put a block into the DHT ["dht:put"]
 func (m *Module) Put(ctx context.Context, query blocks.Query, block blocks.Block) error {
+	m.learnZone(query)
+
 	// assemble a new PUT message
 	msg := message.NewDHTP2PPutMsg(block)
+	msg.ReplLvl = uint16(m.cfg.Routing.ReplLevel)
 	msg.Flags = query.Flags()
 	msg.Key = query.Key().Clone()
 
@@ -271,6 +395,7 @@ func (m *Module) Filter() *core.EventFilter {
 	// events we are interested in
 	f.AddEvent(core.EV_CONNECT)
 	f.AddEvent(core.EV_DISCONNECT)
+	f.AddEvent(core.EV_ADDRESSES)
 
 	// messages we are interested in:
 	// (1) DHT_P2P messages
@@ -278,10 +403,11 @@ func (m *Module) Filter() *core.EventFilter {
 	f.AddMsgType(enums.MSG_DHT_P2P_GET)
 	f.AddMsgType(enums.MSG_DHT_P2P_RESULT)
 	f.AddMsgType(enums.MSG_DHT_P2P_HELLO)
-	// (2) DHT messages (legacy, not implemented)
+	// (2) DHT client messages (ignored if received from the network)
 	f.AddMsgType(enums.MSG_DHT_CLIENT_GET)
 	f.AddMsgType(enums.MSG_DHT_CLIENT_GET_RESULTS_KNOWN)
 	f.AddMsgType(enums.MSG_DHT_CLIENT_GET_STOP)
+	f.AddMsgType(enums.MSG_DHT_CLIENT_GET_CREDIT)
 	f.AddMsgType(enums.MSG_DHT_CLIENT_PUT)
 	f.AddMsgType(enums.MSG_DHT_CLIENT_RESULT)
 
@@ -295,14 +421,20 @@ func (m *Module) event(ctx context.Context, ev *core.Event) {
 	case core.EV_CONNECT:
 		// Add peer to routing table
 		logger.Printf(logger.INFO, "[dht-event] Peer %s connected", ev.Peer.Short())
-		m.rtable.Add(NewPeerAddress(ev.Peer), "dht-event")
+		m.addPeer(ctx, NewPeerAddress(ev.Peer), "dht-event")
 
 	// Peer disconnected:
 	case core.EV_DISCONNECT:
 		// Remove peer from routing table
-		logger.Printf(logger.INFO, "[dht-event] Peer %s disconnected", ev.Peer.Short())
+		logger.Printf(logger.INFO, "[dht-event] Peer %s disconnected (%s)", ev.Peer.Short(), ev.Reason)
 		m.rtable.Remove(NewPeerAddress(ev.Peer), "dht-event", 0)
 
+	// Local addresses changed:
+	case core.EV_ADDRESSES:
+		// send new HELLO to peers in routing table
+		logger.Println(logger.INFO, "[dht-event] Local addresses changed")
+		m.RepublishHello(ctx, "dht-event")
+
 	// Message received.
 	case core.EV_MESSAGE:
 		// generate tracking label
@@ -322,12 +454,13 @@ func (m *Module) event(ctx context.Context, ev *core.Event) {
 
 // ----------------------------------------------------------------------
 // Heartbeat handler for periodic tasks
-func (m *Module) heartbeat(ctx context.Context) {
+func (m *Module) heartbeat(ctx context.Context) error {
 	// run heartbeat for routing table
 	m.rtable.heartbeat(ctx)
 
 	// clean-up task list
 	m.reshdlrs.Cleanup()
+	return nil
 }
 
 //----------------------------------------------------------------------
@@ -345,19 +478,43 @@ func (m *Module) SendHello(ctx context.Context, addr *util.Address, label string
 	return m.core.SendToAddr(ctx, addr, msg)
 }
 
+// RepublishHello creates a new HELLO (with the current addresses of the
+// local node) and sends it to all peers in the routing table.
+func (m *Module) RepublishHello(ctx context.Context, label string) {
+	m.lastHello = nil
+	msg, err := m.getHello(label)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[%s] Failed to create HELLO: %s", label, err.Error())
+		return
+	}
+	var peers []*util.PeerID
+	_ = m.rtable.list.ProcessRange(func(_ string, p *PeerAddress, _ int) error {
+		peers = append(peers, p.Peer)
+		return nil
+	}, true)
+	logger.Printf(logger.INFO, "[%s] Sending new HELLO to %d peers", label, len(peers))
+	for _, peer := range peers {
+		if err = m.core.Send(ctx, peer, msg); err != nil && err != transport.ErrEndpMaybeSent {
+			logger.Printf(logger.WARN, "[%s] Failed to send HELLO to %s: %s", label, peer.Short(), err.Error())
+		}
+	}
+}
+
 // get the recent HELLO if it is defined and not expired;
 // create a new HELLO otherwise.
 func (m *Module) getHello(label string) (msg *message.DHTP2PHelloMsg, err error) {
-	if m.lastHello == nil || m.lastHello.Expire.Expired() {
+	// re-create HELLO if it is about to expire (address lifetime policy)
+	ttl := m.core.HelloTTL()
+	if m.lastHello == nil || m.core.Policy().NeedsRefresh(m.lastHello.Expire, ttl, util.AbsoluteTimeNow()) {
 		// assemble new (signed) HELLO block
 		var addrList []*util.Address
-		if addrList, err = m.core.Addresses(); err != nil {
+		if addrList, err = m.core.HelloAddresses(); err != nil {
 			return
 		}
 		// assemble HELLO data
 		hb := new(blocks.HelloBlock)
 		hb.PeerID = m.core.PeerID()
-		hb.SetExpire(message.HelloAddressExpiration)
+		hb.SetExpire(ttl)
 		hb.SetAddresses(addrList)
 
 		// sign HELLO block
@@ -390,6 +547,47 @@ func (m *Module) getHello(label string) (msg *message.DHTP2PHelloMsg, err error)
 	return m.lastHello, nil
 }
 
+// NeighborHellos returns the unexpired HELLOs of peers in the routing
+// table, sorted by peer identifier. If 'all' is set, all cached HELLOs
+// with validated addresses are returned; if 'self' is set, the own HELLO
+// (if it has addresses) is the first entry in the list.
+func (m *Module) NeighborHellos(all, self bool) (list []*blocks.HelloBlock, err error) {
+	_ = m.rtable.helloCache.ProcessRange(func(key string, hb *blocks.HelloBlock, _ int) error {
+		if hb.Expire().Expired() {
+			return nil
+		}
+		if all {
+			if !m.helloValidated(hb) {
+				return nil
+			}
+		} else if _, ok := m.rtable.list.Get(NewPeerAddress(hb.PeerID).String(), 0); !ok {
+			return nil
+		}
+		list = append(list, hb)
+		return nil
+	}, true)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].PeerID.String() < list[j].PeerID.String()
+	})
+	if self {
+		// convert own HELLO message to block (same signed data)
+		var msg *message.DHTP2PHelloMsg
+		if msg, err = m.getHello("dht-hellos"); err != nil {
+			return
+		}
+		if len(msg.AddrList) > 0 {
+			hb := &blocks.HelloBlock{
+				PeerID:    m.core.PeerID(),
+				Signature: msg.Signature,
+				Expire_:   msg.Expire,
+				AddrBin:   util.Clone(msg.AddrList),
+			}
+			list = append([]*blocks.HelloBlock{hb}, list...)
+		}
+	}
+	return
+}
+
 //----------------------------------------------------------------------
 // Inter-module linkage helpers
 //----------------------------------------------------------------------
@@ -399,16 +597,49 @@ func (m *Module) Export(fcn map[string]any) {
 	// add exported functions from module
 	fcn["dht:get"] = m.Get
 	fcn["dht:put"] = m.Put
+	fcn["dht:put-verified"] = m.PutVerified
 }
 
 // Import functions
 func (m *Module) Import(fcn map[string]any) {
-	// nothing to import for now.
+	// resolve imports from other modules (modules are linked one by one)
+	if f, ok := fcn["peerstore:store"].(func(ctx context.Context, rec *store.PeerstoreRecord, replace bool) error); ok {
+		m.PeerstoreStore = f
+	}
+	if f, ok := fcn["rev:query"].(func(ctx context.Context, zkey *crypto.ZoneKey) (valid bool, err error)); ok {
+		if m.RevocationQuery == nil {
+			m.AddBlockCheck(enums.BLOCK_TYPE_GNS_NAMERECORD, m.checkRevocation)
+		}
+		m.RevocationQuery = f
+	}
+}
+
+// shareHello stores a HELLO of another peer (as URL) in the peerstore,
+// so other modules can use it.
+func (m *Module) shareHello(ctx context.Context, hb *blocks.HelloBlock) {
+	if m.PeerstoreStore == nil {
+		return
+	}
+	rec := &store.PeerstoreRecord{
+		SubSystem: "dht",
+		Peer:      hb.PeerID,
+		Key:       "hello",
+		Value:     []byte(hb.URL()),
+		Expire:    hb.Expire(),
+	}
+	if err := m.PeerstoreStore(ctx, rec, true); err != nil {
+		logger.Printf(logger.WARN, "[dht] HELLO of %s not stored in peerstore: %s", hb.PeerID.Short(), err.Error())
+	}
 }
 
 //----------------------------------------------------------------------
 
 // SetNetworkSize sets a fixed number of peers in the network
 func (m *Module) SetNetworkSize(numPeers int) {
-	m.rtable.l2nse = gmath.Log2(float64(numPeers))
+	m.rtable.SetL2NSE(gmath.Log2(float64(numPeers)))
+}
+
+// SetL2NSE sets the log2 of the estimated network size (from NSE).
+func (m *Module) SetL2NSE(l2nse float64) {
+	m.rtable.SetL2NSE(l2nse)
 }
@@ -0,0 +1,705 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+)
+
+//----------------------------------------------------------------------
+// Test harness: a DHT module with mocked core services and a routing
+// table seeded with deterministic peers.
+//----------------------------------------------------------------------
+
+// sent message (peer is nil for messages sent to an address)
+type sentMsg struct {
+	peer *util.PeerID
+	msg  message.Message
+}
+
+// mockCore records outgoing messages instead of sending them.
+type mockCore struct {
+	sync.Mutex
+	local *core.Peer
+	sent  []*sentMsg
+	addrs []*util.Address // own addresses (for HELLOs)
+	stats map[string]*core.PeerStats
+}
+
+func newMockCore(t *testing.T) *mockCore {
+	t.Helper()
+	local, err := core.NewLocalPeer(nodeCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mockCore{local: local}
+}
+
+func (c *mockCore) PeerID() *util.PeerID {
+	return c.local.GetID()
+}
+
+func (c *mockCore) Sign(obj crypto.Signable) error {
+	sig, err := c.local.Sign(obj.SignedData())
+	if err != nil {
+		return err
+	}
+	return obj.SetSignature(util.NewPeerSignature(sig.Bytes()))
+}
+
+// SignAsync signs synchronously (deterministic tests).
+func (c *mockCore) SignAsync(ctx context.Context, obj crypto.Signable, done func(error)) error {
+	done(c.Sign(obj))
+	return nil
+}
+
+func (c *mockCore) Send(ctx context.Context, peer *util.PeerID, msg message.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.sent = append(c.sent, &sentMsg{peer: peer, msg: msg})
+	return nil
+}
+
+func (c *mockCore) SendToAddr(ctx context.Context, addr *util.Address, msg message.Message) error {
+	return c.Send(ctx, nil, msg)
+}
+
+func (c *mockCore) TryConnect(peer *util.PeerID, addr net.Addr) error {
+	return nil
+}
+
+func (c *mockCore) Learn(ctx context.Context, peer *util.PeerID, addrs []*util.Address, label string) bool {
+	return false
+}
+
+func (c *mockCore) Validated(peer *util.PeerID, addr *util.Address) bool {
+	return true
+}
+
+func (c *mockCore) HelloAddresses() ([]*util.Address, error) {
+	return c.addrs, nil
+}
+
+func (c *mockCore) PeerAddresses(peer *util.PeerID) []*util.Address {
+	return nil
+}
+
+func (c *mockCore) HelloTTL() time.Duration {
+	return time.Hour
+}
+
+func (c *mockCore) Policy() *core.AddrPolicy {
+	return core.NewAddrPolicy(nil)
+}
+
+func (c *mockCore) Register(name string, l *core.Listener) {}
+
+func (c *mockCore) PeerStats(peer *util.PeerID) *core.PeerStats {
+	c.Lock()
+	defer c.Unlock()
+	return c.stats[peer.String()]
+}
+
+func (c *mockCore) Invalid(peer *util.PeerID, msg message.Message) {}
+
+// messages sent so far (of given type)
+func (c *mockCore) Sent(mt enums.MsgType) (list []*sentMsg) {
+	c.Lock()
+	defer c.Unlock()
+	for _, s := range c.sent {
+		if s.msg.Type() == mt {
+			list = append(list, s)
+		}
+	}
+	return
+}
+
+// mockResponder collects messages sent back to a requester.
+type mockResponder struct {
+	sync.Mutex
+	peer *util.PeerID
+	msgs []message.Message
+}
+
+func (r *mockResponder) Send(ctx context.Context, msg message.Message) error {
+	r.Lock()
+	defer r.Unlock()
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func (r *mockResponder) Receiver() *util.PeerID {
+	return r.peer
+}
+
+// testPeer returns a deterministic peer identifier.
+func testPeer(i int) *util.PeerID {
+	h := crypto.Hash([]byte(fmt.Sprintf("peer-%d", i)))
+	return util.NewPeerID(h.Data[:32])
+}
+
+// newTestModule creates a DHT module with mocked core and a routing table
+// seeded with 'n' peers.
+func newTestModule(t *testing.T, n int) (*Module, *mockCore) {
+	t.Helper()
+	c := newMockCore(t)
+	cfg := &config.DHTConfig{
+		Routing: &config.RoutingConfig{
+			PeerTTL:   10800,
+			ReplLevel: 5,
+		},
+	}
+	rt := NewRoutingTable(NewPeerAddress(c.PeerID()), cfg.Routing)
+	// network size estimate (not measured in tests)
+	rt.SetL2NSE(1)
+	for i := 0; i < n; i++ {
+		rt.Add(NewPeerAddress(testPeer(i)), "test")
+	}
+	return newModule(c, cfg, newTestStore(t), rt), c
+}
+
+// testBlock stores a TEST block in the module (if requested) and returns
+// its query key.
+func testBlock(t *testing.T, m *Module, key *crypto.HashCode, stored bool) blocks.Block {
+	t.Helper()
+	blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNow().Add(time.Hour), []byte("test block"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored {
+		query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0)
+		if err = m.store.Put(query, &store.DHTEntry{Blk: blk}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return blk
+}
+
+// queryKey returns a key the local peer is (or is not) closest to.
+func queryKey(m *Module, closest bool) *crypto.HashCode {
+	if closest {
+		return NewPeerAddress(m.core.PeerID()).Key
+	}
+	return NewPeerAddress(testPeer(0)).Key
+}
+
+//----------------------------------------------------------------------
+// HandleMessage decision points (9.3.2, 9.4.3)
+//----------------------------------------------------------------------
+
+func TestHandleGet(t *testing.T) {
+	cases := []struct {
+		closest, demux, approx bool // decision inputs
+		result, forward        bool // expected actions
+	}{
+		{false, false, false, false, true},
+		{true, false, false, true, false},
+		{false, false, true, false, true},
+		{true, false, true, true, false},
+		{false, true, false, false, true},
+		{true, true, false, true, true},
+		{false, true, true, true, true},
+		{true, true, true, true, false},
+	}
+	sender := testPeer(100)
+	for _, tc := range cases {
+		name := fmt.Sprintf("closest=%v,demux=%v,approx=%v", tc.closest, tc.demux, tc.approx)
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestModule(t, 8)
+			key := queryKey(m, tc.closest)
+			testBlock(t, m, key, true)
+
+			msg := m.newGetMsg(blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0))
+			if tc.demux {
+				msg.Flags |= enums.DHT_RO_DEMULTIPLEX_EVERYWHERE
+			}
+			if tc.approx {
+				msg.Flags |= enums.DHT_RO_FIND_APPROXIMATE
+			}
+			msg.PeerFilter = blocks.NewPeerFilter()
+			msg.PeerFilter.Add(sender)
+			back := &mockResponder{peer: sender}
+			m.HandleMessage(context.Background(), sender, msg, back)
+
+			if got := len(back.msgs) > 0; got != tc.result {
+				t.Errorf("result sent: got %v, expected %v", got, tc.result)
+			}
+			fwd := c.Sent(enums.MSG_DHT_P2P_GET)
+			if got := len(fwd) > 0; got != tc.forward {
+				t.Errorf("forwarded: got %v, expected %v", got, tc.forward)
+			}
+			for _, s := range fwd {
+				if s.peer.Equal(sender) {
+					t.Error("GET forwarded to sender")
+				}
+				if out, ok := s.msg.(*message.DHTP2PGetMsg); ok && out.HopCount != 1 {
+					t.Errorf("forwarded GET has hop count %d", out.HopCount)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePut(t *testing.T) {
+	cases := []struct {
+		closest, demux, route bool // decision inputs (and path recording)
+		store, forward        bool // expected actions
+	}{
+		{false, false, false, false, true},
+		{true, false, false, true, false},
+		{false, true, false, true, true},
+		{true, true, false, true, true},
+		{false, false, true, false, true},
+	}
+	sender := testPeer(100)
+	for _, tc := range cases {
+		name := fmt.Sprintf("closest=%v,demux=%v,route=%v", tc.closest, tc.demux, tc.route)
+		t.Run(name, func(t *testing.T) {
+			m, c := newTestModule(t, 8)
+			key := queryKey(m, tc.closest)
+			blk := testBlock(t, m, key, false)
+
+			msg := message.NewDHTP2PPutMsg(nil)
+			msg.BType = blk.Type()
+			msg.Expire = blk.Expire()
+			msg.Block = blk.Bytes()
+			msg.MsgSize += uint16(len(msg.Block))
+			msg.Key = key
+			msg.ReplLvl = 5
+			if tc.demux {
+				msg.Flags |= enums.DHT_RO_DEMULTIPLEX_EVERYWHERE
+			}
+			if tc.route {
+				msg.Flags |= enums.DHT_RO_RECORD_ROUTE
+			}
+			msg.PeerFilter.Add(sender)
+			m.HandleMessage(context.Background(), sender, msg, nil)
+
+			query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0)
+			entries, err := m.store.Get("test", query, blocks.NewGenericResultFilter(128, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(entries) > 0; got != tc.store {
+				t.Errorf("stored: got %v, expected %v", got, tc.store)
+			}
+			fwd := c.Sent(enums.MSG_DHT_P2P_PUT)
+			if got := len(fwd) > 0; got != tc.forward {
+				t.Errorf("forwarded: got %v, expected %v", got, tc.forward)
+			}
+			for _, s := range fwd {
+				if s.peer.Equal(sender) {
+					t.Error("PUT forwarded to sender")
+				}
+				out, ok := s.msg.(*message.DHTP2PPutMsg)
+				if !ok {
+					t.Fatal("forwarded message is not a PUT")
+				}
+				buf, err := data.Marshal(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out.BType != msg.BType || out.HopCount != 1 || int(out.MsgSize) != len(buf) {
+					t.Errorf("forwarded PUT mismatch: %s, size %d/%d", out, out.MsgSize, len(buf))
+				}
+				// first hop: only the last hop signature is set
+				if tc.route && (out.PathL != 0 || out.LastSig == nil) {
+					t.Errorf("forwarded PUT has unexpected path (length %d)", out.PathL)
+				}
+			}
+		})
+	}
+}
+
+//----------------------------------------------------------------------
+// HELLO export
+//----------------------------------------------------------------------
+
+func TestNeighborHellos(t *testing.T) {
+	m, c := newTestModule(t, 0)
+	addr, err := util.ParseAddress("ip+udp://127.0.0.1:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.addrs = []*util.Address{addr}
+
+	// one neighbor and one cached HELLO of a peer not in the routing table
+	neighbor := newTestHello(t, "ip+udp://1.2.3.4:2086")
+	other := newTestHello(t, "ip+udp://5.6.7.8:2086")
+	m.rtable.Add(NewPeerAddress(neighbor.PeerID), "test")
+	m.rtable.CacheHello(neighbor)
+	m.rtable.CacheHello(other)
+
+	cases := []struct {
+		all, self bool
+		num       int
+	}{
+		{false, false, 1},
+		{true, false, 2},
+		{false, true, 2},
+		{true, true, 3},
+	}
+	for _, tc := range cases {
+		list, err := m.NeighborHellos(tc.all, tc.self)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != tc.num {
+			t.Fatalf("all=%v,self=%v: expected %d HELLOs, got %d", tc.all, tc.self, tc.num, len(list))
+		}
+		if tc.self && !list[0].PeerID.Equal(c.PeerID()) {
+			t.Fatal("own HELLO not first in list")
+		}
+		// exported URLs must be usable as bootstrap entries
+		for _, hb := range list {
+			hb2, err := blocks.ParseHelloBlockFromURL(hb.URL(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := hb2.Verify(); !ok || err != nil {
+				t.Fatalf("HELLO of %s not verified", hb.PeerID.Short())
+			}
+			if len(hb2.Addresses()) != 1 {
+				t.Fatalf("HELLO of %s has %d addresses", hb.PeerID.Short(), len(hb2.Addresses()))
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Replay protection
+//----------------------------------------------------------------------
+
+func TestRepublishHello(t *testing.T) {
+	m, c := newTestModule(t, 3)
+	addr, _ := util.ParseAddress("ip+udp://192.0.2.1:2086")
+	c.addrs = []*util.Address{addr}
+	old, err := m.getHello("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// external address changed: new HELLO is sent to all peers
+	ext, _ := util.ParseAddress("ip+udp://198.51.100.7:2086")
+	c.addrs = append(c.addrs, ext)
+	m.event(context.Background(), &core.Event{ID: core.EV_ADDRESSES})
+	sent := c.Sent(enums.MSG_DHT_P2P_HELLO)
+	if len(sent) != 3 {
+		t.Fatalf("HELLO sent to %d peers", len(sent))
+	}
+	msg, ok := sent[0].msg.(*message.DHTP2PHelloMsg)
+	if !ok || msg == old {
+		t.Fatal("HELLO not re-created")
+	}
+	if msg.NumAddr != 2 {
+		t.Fatalf("unexpected addresses in HELLO: %d", msg.NumAddr)
+	}
+}
+
+func TestHelloReplay(t *testing.T) {
+	m, _ := newTestModule(t, 0)
+	pk, sk := ed25519.NewKeypair()
+	sender := util.NewPeerID(pk.Bytes())
+	addr, err := util.ParseAddress("ip+udp://1.2.3.4:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// signed HELLO message expiring after 'ttl'
+	hello := func(ttl time.Duration) *message.DHTP2PHelloMsg {
+		addr.Expire = util.NewAbsoluteTimeEpoch(uint64(time.Now().Add(ttl).Unix()))
+		msg := message.NewDHTP2PHelloMsg()
+		msg.SetAddresses([]*util.Address{addr})
+		sig, err := sk.EdSign(msg.SignedData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = msg.SetSignature(util.NewPeerSignature(sig.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		// parse addresses (as after unmarshalling)
+		if err = msg.Init(); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	older, newer := hello(time.Hour), hello(2*time.Hour)
+	ctx := context.Background()
+	if !m.HandleMessage(ctx, sender, newer, nil) {
+		t.Fatal("HELLO not handled")
+	}
+	if !m.HandleMessage(ctx, sender, newer, nil) {
+		t.Fatal("repeated HELLO not handled")
+	}
+	if m.HandleMessage(ctx, sender, older, nil) {
+		t.Fatal("outdated HELLO handled")
+	}
+	if hb, ok := m.rtable.GetHello(sender.String()); !ok || hb.Expire_ != newer.Expire {
+		t.Fatal("cached HELLO replaced by outdated HELLO")
+	}
+	if n := m.replay.Drops()["hello"]; n != 1 {
+		t.Fatalf("expected 1 dropped HELLO, got %d", n)
+	}
+}
+
+// Test that RESULTs for the same block received over the same hop are
+// not dropped as replays if they answer different GET requests.
+func TestResultNoReplay(t *testing.T) {
+	m, _ := newTestModule(t, 0)
+	ctx := context.Background()
+	sender := testPeer(1)
+
+	// RESULT with a recorded route (same last hop signature)
+	key := crypto.Hash([]byte("result key"))
+	res := message.NewDHTP2PResultMsg()
+	res.BType = enums.BLOCK_TYPE_TEST
+	res.Flags = enums.DHT_RO_RECORD_ROUTE
+	res.Expire = util.AbsoluteTimeNow().Add(time.Hour)
+	res.Query = key
+	res.Block = []byte("test block")
+	res.LastSig = util.NewPeerSignature(util.NewRndArray(64))
+
+	// GET requests from local clients
+	get := message.NewDHTP2PGetMsg()
+	get.BType = enums.BLOCK_TYPE_TEST
+	get.Query = key
+	for i := 0; i < 2; i++ {
+		back := new(mockResponder)
+		rf := blocks.NewGenericResultFilter(128, util.RndUInt32())
+		m.reshdlrs.Add(NewResultHandler(ctx, get, rf, back, m.core))
+		if !m.HandleMessage(ctx, sender, res, nil) {
+			t.Fatalf("RESULT #%d not handled", i+1)
+		}
+		// wait for result to be delivered
+		for j := 0; j < 50; j++ {
+			back.Lock()
+			n := len(back.msgs)
+			back.Unlock()
+			if n > 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		back.Lock()
+		n := len(back.msgs)
+		back.Unlock()
+		if n != 1 {
+			t.Fatalf("GET #%d: %d results", i+1, n)
+		}
+	}
+}
+
+// Test that a PUT with a valid signed last hop is dropped if it is
+// replayed within the replay window.
+func TestPutReplay(t *testing.T) {
+	m, _ := newTestModule(t, 0)
+	ctx := context.Background()
+	pk, sk := ed25519.NewKeypair()
+	sender := util.NewPeerID(pk.Bytes())
+
+	key := queryKey(m, true)
+	blk := testBlock(t, m, key, false)
+	msg := message.NewDHTP2PPutMsg(nil)
+	msg.BType = blk.Type()
+	msg.Expire = blk.Expire()
+	msg.Block = blk.Bytes()
+	msg.MsgSize += uint16(len(msg.Block))
+	msg.Key = key
+	msg.ReplLvl = 5
+	msg.Flags = enums.DHT_RO_RECORD_ROUTE
+	msg.PeerFilter.Add(sender)
+
+	// sign last hop (sender -> local peer)
+	pe := msg.Path(nil).NewElement(util.NewPeerID(nil), sender, m.core.PeerID())
+	sig, err := sk.EdSign(pe.SignedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.LastSig = util.NewPeerSignature(sig.Bytes())
+	msg.MsgSize += uint16(msg.LastSig.Size())
+
+	if !m.HandleMessage(ctx, sender, msg, nil) {
+		t.Fatal("PUT not handled")
+	}
+	if m.HandleMessage(ctx, sender, msg, nil) {
+		t.Fatal("replayed PUT handled")
+	}
+	if n := m.replay.Drops()["put"]; n != 1 {
+		t.Fatalf("expected 1 dropped PUT, got %d", n)
+	}
+	// a PUT with an invalid last hop signature has no path to check
+	msg.LastSig = util.NewPeerSignature(util.NewRndArray(64))
+	if !m.HandleMessage(ctx, sender, msg, nil) {
+		t.Fatal("PUT with invalid signature not handled")
+	}
+}
+
+func TestHelloPeerstore(t *testing.T) {
+	m, _ := newTestModule(t, 0)
+	var stored []*store.PeerstoreRecord
+	m.Import(map[string]any{
+		"peerstore:store": func(ctx context.Context, rec *store.PeerstoreRecord, replace bool) error {
+			if !replace {
+				t.Error("HELLO record not replacing")
+			}
+			stored = append(stored, rec)
+			return nil
+		},
+	})
+	pk, sk := ed25519.NewKeypair()
+	sender := util.NewPeerID(pk.Bytes())
+	addr, err := util.ParseAddress("ip+udp://1.2.3.4:2086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr.Expire = util.NewAbsoluteTimeEpoch(uint64(time.Now().Add(time.Hour).Unix()))
+	msg := message.NewDHTP2PHelloMsg()
+	msg.SetAddresses([]*util.Address{addr})
+	sig, err := sk.EdSign(msg.SignedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = msg.SetSignature(util.NewPeerSignature(sig.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err = msg.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if !m.HandleMessage(context.Background(), sender, msg, nil) {
+		t.Fatal("HELLO not handled")
+	}
+	// the HELLO is shared as URL
+	if len(stored) != 1 {
+		t.Fatalf("expected 1 peerstore record, got %d", len(stored))
+	}
+	rec := stored[0]
+	if rec.SubSystem != "dht" || rec.Key != "hello" || !rec.Peer.Equal(sender) {
+		t.Fatalf("unexpected record %v", rec)
+	}
+	hb, err := blocks.ParseHelloBlockFromURL(string(rec.Value), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hb.PeerID.Equal(sender) || len(hb.Addresses()) != 1 {
+		t.Fatalf("unexpected HELLO %s", hb)
+	}
+}
+
+//----------------------------------------------------------------------
+// Block checks
+//----------------------------------------------------------------------
+
+func TestRevokedZoneBlocks(t *testing.T) {
+	// no neighbors: the local peer is closest to all keys
+	m, _ := newTestModule(t, 0)
+	revoked := false
+	m.Import(map[string]any{
+		"rev:query": func(ctx context.Context, zkey *crypto.ZoneKey) (bool, error) {
+			return !revoked, nil
+		},
+	})
+	// GNS block for 'www' in a new zone
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", blocks.NewRecordSet(), expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := blocks.NewGNSQuery(zp.Public(), "www")
+	put := func() bool {
+		msg := message.NewDHTP2PPutMsg(nil)
+		msg.BType = enums.BLOCK_TYPE_GNS_NAMERECORD
+		msg.Expire = expire
+		msg.Block = blk.Bytes()
+		msg.MsgSize += uint16(len(msg.Block))
+		msg.Key = query.Key()
+		msg.ReplLvl = 5
+		return m.HandleMessage(context.Background(), testPeer(100), msg, nil)
+	}
+	get := func() int {
+		msg := m.newGetMsg(query)
+		back := &mockResponder{peer: testPeer(100)}
+		m.HandleMessage(context.Background(), testPeer(100), msg, back)
+		return len(back.msgs)
+	}
+	// the zone is unknown: blocks can't be checked
+	revoked = true
+	if !put() || get() != 1 {
+		t.Fatal("block of unknown zone not accepted")
+	}
+	// the zone is known from a local query
+	m.learnZone(query)
+	if get() != 0 {
+		t.Fatal("block of revoked zone served")
+	}
+	if put() {
+		t.Fatal("block of revoked zone accepted")
+	}
+	revoked = false
+	if !put() || get() != 1 {
+		t.Fatal("block of valid zone rejected")
+	}
+}
+
+func TestSelectWellBehaved(t *testing.T) {
+	m, c := newTestModule(t, 8)
+	addr := NewQueryAddress(queryKey(m, false))
+	pf := blocks.NewPeerFilter()
+	closest := func(f *blocks.PeerFilter) *PeerAddress {
+		return m.rtable.SelectClosestPeer(addr, f, 0)
+	}
+	best := closest(pf)
+
+	// peer over quota is skipped
+	c.stats = map[string]*core.PeerStats{
+		best.Peer.String(): {Violations: 1},
+	}
+	p := m.selectPeer(pf, closest)
+	if p == nil || p.Equal(best) {
+		t.Fatal("peer over quota selected")
+	}
+	if pf.Contains(best.Peer) {
+		t.Fatal("peer filter changed")
+	}
+	// ... unless there is no alternative
+	for i := 0; i < 8; i++ {
+		c.stats[testPeer(i).String()] = &core.PeerStats{Violations: 1}
+	}
+	if p = m.selectPeer(pf, closest); p == nil || !p.Equal(best) {
+		t.Fatal("no peer selected")
+	}
+}
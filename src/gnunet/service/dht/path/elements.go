@@ -124,6 +124,25 @@ func (pe *Element) Wire() *Entry {
 	return &(pe.Entry)
 }
 
+// ReplayID returns an identifier for a signed path element (signature,
+// predecessor, successor and block hash) used for replay detection.
+func (pe *Element) ReplayID() []byte {
+	var buf []byte
+	if pe.Signature != nil {
+		buf = append(buf, pe.Signature.Bytes()...)
+	}
+	if pe.PeerPredecessor != nil {
+		buf = append(buf, pe.PeerPredecessor.Bytes()...)
+	}
+	if pe.PeerSuccessor != nil {
+		buf = append(buf, pe.PeerSuccessor.Bytes()...)
+	}
+	if pe.BlockHash != nil {
+		buf = append(buf, pe.BlockHash.Data...)
+	}
+	return buf
+}
+
 // Verify signature for a path element. If the signature argument
 // is zero, use the signature store with the element
 func (pe *Element) Verify(sig *util.PeerSignature) (bool, error) {
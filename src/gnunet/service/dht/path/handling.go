@@ -240,6 +240,25 @@ func (p *Path) Verify(local *util.PeerID) {
 	}
 }
 
+// LastElement returns the (signed) last hop path element as received by
+// the local peer or nil if the path has no last hop signature. Call after
+// 'Verify' to get a verified element.
+func (p *Path) LastElement(local *util.PeerID) *Element {
+	if p.LastSig == nil {
+		return nil
+	}
+	// get predecessor (last list entry, truncated origin or 0)
+	pred := util.NewPeerID(nil)
+	if num := len(p.List); num > 0 {
+		pred = p.List[num-1].Signer
+	} else if p.TruncOrigin != nil {
+		pred = p.TruncOrigin
+	}
+	pe := p.NewElement(pred, p.LastHop, local)
+	pe.Signature = p.LastSig
+	return pe
+}
+
 // String returns a human-readable representation
 func (p *Path) String() string {
 	var hops []string
@@ -0,0 +1,113 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/service/store"
+	"gnunet/util/keyspace"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Replication defaults (if not configured)
+const (
+	DefaultReplBatchSize = 10 // blocks per batch
+	DefaultReplRate      = 20 // blocks per second
+)
+
+//----------------------------------------------------------------------
+// Responsibility transfer: if a new peer is added to the routing table,
+// all stored blocks with keys closer to the new peer than to the local
+// node are replicated to the new peer (Kademlia-style).
+//----------------------------------------------------------------------
+
+// addPeer adds a peer to the routing table and starts the replication
+// of stored blocks if the peer was not known before.
+func (m *Module) addPeer(ctx context.Context, p *PeerAddress, label string) {
+	if m.rtable.Add(p, label) {
+		go m.replicate(ctx, p, label)
+	}
+}
+
+// replicate stored blocks to a new peer. Blocks are sent in batches
+// (while traversing the store) with an upper limit on the number of
+// blocks sent per second.
+func (m *Module) replicate(ctx context.Context, p *PeerAddress, label string) {
+	batch, rate := m.replParams()
+	pause := time.Duration(batch) * time.Second / time.Duration(rate)
+
+	// send blocks the new peer is closer to than we are.
+	self := m.rtable.ref
+	closer := func(key *crypto.HashCode) bool {
+		return keyspace.Closer(key.Data, p.Key.Data, self.Key.Data)
+	}
+	n, done := 0, true
+	err := m.store.Traverse(closer, func(key *crypto.HashCode, entry *store.DHTEntry) bool {
+		if n == 0 {
+			logger.Printf(logger.INFO, "[%s] replicating blocks to %s", label, p.Peer.Short())
+		} else if n%batch == 0 {
+			select {
+			case <-ctx.Done():
+				done = false
+				return false
+			case <-time.After(pause):
+			}
+			// stop if peer is gone
+			if !m.rtable.Contains(p, label) {
+				logger.Printf(logger.INFO, "[%s] replication to %s aborted: peer removed", label, p.Peer.Short())
+				done = false
+				return false
+			}
+		}
+		msg := message.NewDHTP2PPutMsg(entry.Blk)
+		msg.Key = key.Clone()
+		msg.ReplLvl = uint16(m.cfg.Routing.ReplLevel)
+		msg.PeerFilter.Add(m.core.PeerID())
+		if err := m.core.Send(ctx, p.Peer, msg); err != nil {
+			logger.Printf(logger.WARN, "[%s] replication of %s to %s failed: %s", label, msg.Key.Short(), p.Peer.Short(), err.Error())
+		}
+		n++
+		return true
+	})
+	if err != nil {
+		logger.Printf(logger.ERROR, "[%s] replication to %s failed: %s", label, p.Peer.Short(), err.Error())
+		return
+	}
+	if done && n > 0 {
+		logger.Printf(logger.INFO, "[%s] replication of %d blocks to %s done", label, n, p.Peer.Short())
+	}
+}
+
+// replParams returns the (sanitized) replication parameters.
+func (m *Module) replParams() (batch, rate int) {
+	batch, rate = DefaultReplBatchSize, DefaultReplRate
+	if cfg := m.cfg.Replication; cfg != nil {
+		if cfg.BatchSize > 0 {
+			batch = cfg.BatchSize
+		}
+		if cfg.Rate > 0 {
+			rate = cfg.Rate
+		}
+	}
+	return
+}
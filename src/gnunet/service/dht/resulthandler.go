@@ -66,11 +66,14 @@ type ResultHandler struct {
 	active    bool                // is the task active?
 	resp      transport.Responder // back-channel to deliver result
 	signer    crypto.Signer       // signing instance
+	cancelled <-chan struct{}     // closed if the request is cancelled
+	trace     *Trace              // trace of sampled request (or nil)
 }
 
 // NewResultHandler creates an instance from a DHT-GET message and a
-// result filter instance.
-func NewResultHandler(msg *message.DHTP2PGetMsg, rf blocks.ResultFilter, back transport.Responder, signer crypto.Signer) *ResultHandler {
+// result filter instance. The handler is torn down when the request
+// context is done.
+func NewResultHandler(ctx context.Context, msg *message.DHTP2PGetMsg, rf blocks.ResultFilter, back transport.Responder, signer crypto.Signer) *ResultHandler {
 	return &ResultHandler{
 		id:        util.NextID(),
 		key:       msg.Query.Clone(),
@@ -82,6 +85,8 @@ func NewResultHandler(msg *message.DHTP2PGetMsg, rf blocks.ResultFilter, back tr
 		active:    true,
 		resp:      back,
 		signer:    signer,
+		cancelled: ctx.Done(),
+		trace:     traceFromContext(ctx),
 	}
 }
 
@@ -112,6 +117,11 @@ func (t *ResultHandler) Flags() uint16 {
 
 // Done returns true if the result handler is no longer active.
 func (t *ResultHandler) Done() bool {
+	select {
+	case <-t.cancelled:
+		return true
+	default:
+	}
 	return !t.active || t.started.Add(time.Hour).Expired()
 }
 
@@ -124,6 +134,11 @@ func (t *ResultHandler) Compare(h *ResultHandler) int {
 		logger.Printf(logger.DBG, "[rh] recipients differ: %v -- %v", hRcv, tRcv)
 		return RHC_DIFFER
 	}
+	// local requests are only the same if they share the responder
+	if tRcv == nil && t.resp != h.resp {
+		logger.Printf(logger.DBG, "[rh] local responders differ")
+		return RHC_DIFFER
+	}
 	// check if base attributes differ
 	if !t.key.Equal(h.key) ||
 		t.btype != h.btype ||
@@ -161,6 +176,7 @@ func (t *ResultHandler) Handle(ctx context.Context, msg *message.DHTP2PResultMsg
 	// don't send result if it is filtered out
 	if !t.Proceed(ctx, msg) {
 		logger.Printf(logger.DBG, "[dht-task-%d] result filtered out -- already known", t.id)
+		t.trace.Add(TraceDrop, sender, "result already known")
 		return false
 	}
 	// check if we are delivering results to remote nodes
@@ -173,7 +189,7 @@ func (t *ResultHandler) Handle(ctx context.Context, msg *message.DHTP2PResultMsg
 			pp = pth.Clone()
 			// yes: add path element
 			pe := pp.NewElement(sender, local, rcv)
-			if err := t.signer.Sign(pe); err == nil {
+			if err := t.signer.Sign(pe); err != nil {
 				logger.Printf(logger.ERROR, "[dht-task-%d] failed to sign path element: %s", t.id, err.Error())
 			} else {
 				pp.Add(pe)
@@ -187,8 +203,10 @@ func (t *ResultHandler) Handle(ctx context.Context, msg *message.DHTP2PResultMsg
 	logger.Printf(logger.INFO, "[dht-task-%d] sending result back %s", t.id, tgt)
 	if err := t.resp.Send(ctx, msg); err != nil && err != transport.ErrEndpMaybeSent {
 		logger.Printf(logger.ERROR, "[dht-task-%d] sending result back %s failed: %s", t.id, tgt, err.Error())
+		t.trace.Add(TraceDrop, sender, "result not sent back %s: %s", tgt, err.Error())
 		return false
 	}
+	t.trace.Add(TraceResult, sender, "result sent back %s", tgt)
 	return true
 }
 
@@ -209,15 +227,16 @@ func NewResultHandlerList() *ResultHandlerList {
 	}
 }
 
-// Add handler to list
-func (t *ResultHandlerList) Add(hdlr *ResultHandler) bool {
-	// get current list of handlers for key
+// Add handler to list. Handlers for the same key can be added
+// concurrently; the list is replaced (not modified in place) so readers
+// of the previous list are not affected.
+func (t *ResultHandlerList) Add(hdlr *ResultHandler) (added bool) {
 	key := hdlr.Key().String()
-	list, ok := t.list.Get(key, 0)
-	modified := false
-	if !ok {
-		list = make([]*ResultHandler, 0)
-	} else {
+	_ = t.list.Process(func(pid int) error {
+		// get current list of handlers for key
+		list, _ := t.list.Get(key, pid)
+		list = util.Clone(list)
+		modified := false
 		// check if handler is already available
 	loop:
 		for i, h := range list {
@@ -225,7 +244,7 @@ func (t *ResultHandlerList) Add(hdlr *ResultHandler) bool {
 			case RHC_SAME:
 				// already in list; no need to add again
 				logger.Println(logger.DBG, "[rhl] resultfilter compare: SAME")
-				return false
+				return nil
 			case RHC_MERGE:
 				// merge the two result handlers
 				oldMod := modified
@@ -243,13 +262,15 @@ func (t *ResultHandlerList) Add(hdlr *ResultHandler) bool {
 				logger.Println(logger.DBG, "[rhl] resultfilter compare: DIFFER")
 			}
 		}
-	}
-	if !modified {
-		// append new handler to list
-		list = append(list, hdlr)
-	}
-	t.list.Put(key, list, 0)
-	return true
+		if !modified {
+			// append new handler to list
+			list = append(list, hdlr)
+		}
+		t.list.Put(key, list, pid)
+		added = true
+		return nil
+	}, false)
+	return
 }
 
 // Get handler list for given key
@@ -257,6 +278,22 @@ func (t *ResultHandlerList) Get(key string) ([]*ResultHandler, bool) {
 	return t.list.Get(key, 0)
 }
 
+// Size returns the number of active handlers in the list.
+func (t *ResultHandlerList) Size() (n int) {
+	err := t.list.ProcessRange(func(key string, list []*ResultHandler, pid int) error {
+		for _, rh := range list {
+			if !rh.Done() {
+				n++
+			}
+		}
+		return nil
+	}, true)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[rh-list] size error: %s", err.Error())
+	}
+	return
+}
+
 // Cleanup removes expired tasks from list
 func (t *ResultHandlerList) Cleanup() {
 	err := t.list.ProcessRange(func(key string, list []*ResultHandler, pid int) error {
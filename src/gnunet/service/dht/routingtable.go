@@ -27,7 +27,10 @@ import (
 	"gnunet/service/dht/blocks"
 	"gnunet/service/store"
 	"gnunet/util"
+	"gnunet/util/keyspace"
+	gmath "math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bfix/gospel/logger"
@@ -36,8 +39,8 @@ import (
 
 // Routing table constants
 const (
-	numK    = 20  // number of entries per k-bucket
-	numBits = 512 // number of bits in SHA-512 value
+	numK    = 20            // number of entries per k-bucket
+	numBits = keyspace.Bits // number of bits in SHA-512 value
 )
 
 //======================================================================
@@ -50,7 +53,7 @@ type PeerAddress struct {
 	Peer     *util.PeerID      // peer identifier
 	Key      *crypto.HashCode  // address key is a sha512 hash
 	lastSeen util.AbsoluteTime // time the peer was last seen
-	lastUsed util.AbsoluteTime // time the peer was last used
+	lastUsed util.AbsoluteTime // time the peer was last used (atomic)
 }
 
 // NewPeerAddress returns the DHT address of a peer.
@@ -73,6 +76,17 @@ func NewQueryAddress(key *crypto.HashCode) *PeerAddress {
 	}
 }
 
+// used marks the peer as used now; peers are selected by concurrent
+// readers of the routing table, so the time is updated atomically.
+func (addr *PeerAddress) used() {
+	atomic.StoreUint64(&addr.lastUsed.Val, util.AbsoluteTimeNow().Val)
+}
+
+// lastUse returns the time the peer was last used.
+func (addr *PeerAddress) lastUse() util.AbsoluteTime {
+	return util.AbsoluteTime{Val: atomic.LoadUint64(&addr.lastUsed.Val)}
+}
+
 // String returns a human-readble representation of an address.
 func (addr *PeerAddress) String() string {
 	return hex.EncodeToString(addr.Key.Data)
@@ -86,8 +100,7 @@ func (addr *PeerAddress) Equal(p *PeerAddress) bool {
 // Distance between two addresses: returns a distance value and a
 // bucket index (smaller index = less distant).
 func (addr *PeerAddress) Distance(p *PeerAddress) (*math.Int, int) {
-	r := util.Distance(addr.Key.Data, p.Key.Data)
-	return r, numBits - r.BitLen()
+	return keyspace.Distance(addr.Key.Data, p.Key.Data), keyspace.BucketIndex(addr.Key.Data, p.Key.Data)
 }
 
 //======================================================================
@@ -101,26 +114,31 @@ func (addr *PeerAddress) Distance(p *PeerAddress) (*math.Int, int) {
 type RoutingTable struct {
 	sync.RWMutex
 
-	ref        *PeerAddress                          // reference address for distance
-	buckets    []*Bucket                             // list of buckets
-	list       *util.Map[string, *PeerAddress]       // keep list of peers
-	l2nse      float64                               // log2 of estimated network size
-	inProcess  map[int]struct{}                      // flag if Process() is running
-	cfg        *config.RoutingConfig                 // routing parameters
-	helloCache *util.Map[string, *blocks.HelloBlock] // HELLO block cache
+	ref        *PeerAddress                    // reference address for distance
+	buckets    []*Bucket                       // list of buckets
+	list       *util.Map[string, *PeerAddress] // keep list of peers
+	l2nse      uint64                          // log2 of estimated network size (float64 bits)
+	inProcess  map[int]struct{}                // flag if Process() is running
+	cfg        *config.RoutingConfig           // routing parameters
+	helloCache *HelloCache                     // HELLO block cache
+	helloCheck *helloChecker                   // HELLO cache verification
+	skew       time.Duration                   // tolerated clock skew for HELLO expiration
 }
 
 // NewRoutingTable creates a new routing table for the reference address.
 func NewRoutingTable(ref *PeerAddress, cfg *config.RoutingConfig) *RoutingTable {
-	// create routing table
+	// create routing table (with an in-memory HELLO cache)
+	hc, _ := NewHelloCache(nil)
 	rt := &RoutingTable{
 		ref:        ref,
 		list:       util.NewMap[string, *PeerAddress](),
 		buckets:    make([]*Bucket, numBits),
-		l2nse:      -1,
+		l2nse:      gmath.Float64bits(-1),
 		inProcess:  make(map[int]struct{}),
 		cfg:        cfg,
-		helloCache: util.NewMap[string, *blocks.HelloBlock](),
+		helloCache: hc,
+		helloCheck: new(helloChecker),
+		skew:       util.DefaultClockSkew,
 	}
 	// fill buckets
 	for i := range rt.buckets {
@@ -136,6 +154,10 @@ func NewRoutingTable(ref *PeerAddress, cfg *config.RoutingConfig) *RoutingTable
 // Add new peer address to routing table.
 // Returns true if the entry was added, false otherwise.
 func (rt *RoutingTable) Add(p *PeerAddress, label string) bool {
+	// never add the local peer
+	if p.Equal(rt.ref) {
+		return false
+	}
 	k := p.String()
 
 	// check if peer is already known
@@ -146,7 +168,7 @@ func (rt *RoutingTable) Add(p *PeerAddress, label string) bool {
 	// compute distance (bucket index) and insert address.
 	_, idx := p.Distance(rt.ref)
 	if rt.buckets[idx].Add(p) {
-		p.lastUsed = util.AbsoluteTimeNow()
+		p.used()
 		rt.list.Put(k, p, 0)
 		logger.Printf(logger.INFO, "[%s] %s added to routing table",
 			label, p.Peer.Short())
@@ -159,6 +181,10 @@ func (rt *RoutingTable) Add(p *PeerAddress, label string) bool {
 // check if peer address is in routing table (=1) or if the corresponding
 // k-bucket has free space (=0) or not (-1).
 func (rt *RoutingTable) Check(p *PeerAddress) int {
+	// the local peer has no bucket
+	if p.Equal(rt.ref) {
+		return -1
+	}
 	k := p.String()
 
 	// check if peer is already known
@@ -251,7 +277,7 @@ func (rt *RoutingTable) SelectClosestPeer(p *PeerAddress, pf *blocks.PeerFilter,
 	}
 	// mark peer as used
 	if n != nil {
-		n.lastUsed = util.AbsoluteTimeNow()
+		n.used()
 	}
 	return
 }
@@ -274,7 +300,7 @@ func (rt *RoutingTable) SelectRandomPeer(pf *blocks.PeerFilter, pid int) (p *Pee
 		}
 	}
 	// mark peer as used
-	p.lastUsed = util.AbsoluteTimeNow()
+	p.used()
 	return
 }
 
@@ -282,7 +308,7 @@ func (rt *RoutingTable) SelectRandomPeer(pf *blocks.PeerFilter, pid int) (p *Pee
 // If hops < NSE this function MUST return SelectRandomPeer() and
 // SelectClosestpeer() otherwise.
 func (rt *RoutingTable) SelectPeer(p *PeerAddress, hops uint16, bf *blocks.PeerFilter, pid int) *PeerAddress {
-	if float64(hops) < rt.l2nse {
+	if float64(hops) < rt.L2NSE() {
 		return rt.SelectRandomPeer(bf, pid)
 	}
 	return rt.SelectClosestPeer(p, bf, pid)
@@ -316,10 +342,11 @@ func (rt *RoutingTable) IsClosestPeer(p, k *PeerAddress, pf *blocks.PeerFilter,
 // underlay. The result is the non-negative number of next hops to select.
 func (rt *RoutingTable) ComputeOutDegree(repl, hop uint16) int {
 	hf := float64(hop)
-	if hf > 4*rt.l2nse {
+	l2nse := rt.L2NSE()
+	if hf > 4*l2nse {
 		return 0
 	}
-	if hf > 2*rt.l2nse {
+	if hf > 2*l2nse {
 		return 1
 	}
 	if repl == 0 {
@@ -328,7 +355,18 @@ func (rt *RoutingTable) ComputeOutDegree(repl, hop uint16) int {
 		repl = 16
 	}
 	rm1 := float64(repl - 1)
-	return 1 + int(rm1/(rt.l2nse+rm1*hf))
+	return 1 + int(rm1/(l2nse+rm1*hf))
+}
+
+// L2NSE returns the log2 of the estimated network size (or -1 if unknown).
+func (rt *RoutingTable) L2NSE() float64 {
+	return gmath.Float64frombits(atomic.LoadUint64(&rt.l2nse))
+}
+
+// SetL2NSE sets the log2 of the estimated network size. It is either
+// derived from a fixed number of peers or updated by NSE.
+func (rt *RoutingTable) SetL2NSE(l2nse float64) {
+	atomic.StoreUint64(&rt.l2nse, gmath.Float64bits(l2nse))
 }
 
 //----------------------------------------------------------------------
@@ -342,8 +380,9 @@ func (rt *RoutingTable) heartbeat(ctx context.Context) {
 	if err := rt.list.ProcessRange(func(k string, p *PeerAddress, pid int) error {
 		// check if we can/need to drop a peer
 		drop := timeout.Compare(p.lastSeen.Elapsed()) < 0
-		if drop || timeout.Compare(p.lastUsed.Elapsed()) < 0 {
-			logger.Printf(logger.DBG, "[dht-rt-hb] removing %s: lastSeen %s, lastUsed %v", p.Peer.Short(), p.lastSeen.Elapsed(), p.lastUsed.Elapsed())
+		lastUsed := p.lastUse()
+		if drop || timeout.Compare(lastUsed.Elapsed()) < 0 {
+			logger.Printf(logger.DBG, "[dht-rt-hb] removing %s: lastSeen %s, lastUsed %v", p.Peer.Short(), p.lastSeen.Elapsed(), lastUsed.Elapsed())
 			rt.Remove(p, "dht-rt-hb", pid)
 		}
 		return nil
@@ -353,24 +392,26 @@ func (rt *RoutingTable) heartbeat(ctx context.Context) {
 
 	// drop expired entries from the HELLO cache
 	_ = rt.helloCache.ProcessRange(func(key string, val *blocks.HelloBlock, pid int) error {
-		if val.Expire_.Expired() {
+		if val.Expire_.ExpiredSkew(rt.skew) {
 			rt.helloCache.Delete(key, pid)
 		}
 		return nil
 	}, false)
-
-	// update the estimated network size
-	// rt.l2nse = ...
 }
 
 //----------------------------------------------------------------------
 
-// LookupHello returns blocks from the HELLO cache for given query.
-func (rt *RoutingTable) LookupHello(addr *PeerAddress, rf blocks.ResultFilter, approx bool, label string) (results []*store.DHTResult) {
+// LookupHello returns blocks from the HELLO cache for given query. Only
+// HELLOs approved by the (optional) accept function are returned.
+func (rt *RoutingTable) LookupHello(addr *PeerAddress, rf blocks.ResultFilter, approx bool, accept func(*blocks.HelloBlock) bool, label string) (results []*store.DHTResult) {
 	// iterate over cached HELLOs to find matches;
 	// approximate search is guided by distance
 	list := store.NewSortedDHTResults(MaxSortResults)
 	_ = rt.helloCache.ProcessRange(func(key string, hb *blocks.HelloBlock, _ int) error {
+		// check if block can be published
+		if accept != nil && !accept(hb) {
+			return nil
+		}
 		// check if block is excluded by result filter
 		if !rf.Contains(hb) {
 			// no: possible result, compute distance
@@ -404,6 +445,11 @@ func (rt *RoutingTable) GetHello(k string) (*blocks.HelloBlock, bool) {
 	return rt.helloCache.Get(k, 0)
 }
 
+// HelloCacheStats returns the counters of the HELLO cache tiers.
+func (rt *RoutingTable) HelloCacheStats() HelloCacheStats {
+	return rt.helloCache.Stats()
+}
+
 //----------------------------------------------------------------------
 
 // lock with given mode (if not in processing function)
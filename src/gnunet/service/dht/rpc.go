@@ -19,8 +19,18 @@
 package dht
 
 import (
+	"fmt"
 	"gnunet/service"
+	"math"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
 
 	"github.com/bfix/gospel/logger"
 )
@@ -28,10 +38,9 @@ import (
 //----------------------------------------------------------------------
 
 // RPCService is a type for DHT-related JSON-RPC requests
-type RPCService struct{}
-
-// local instance of service
-var dhtRPC = &RPCService{}
+type RPCService struct {
+	m *Module // reference to DHT module
+}
 
 //----------------------------------------------------------------------
 // Command "DHT.Status"
@@ -57,6 +66,22 @@ func (s *RPCService) Status(r *http.Request, req *StatusRequest, reply *StatusRe
 		switch topic {
 		case "echo":
 			out[topic] = "echo test"
+		case "peers":
+			// number of peers in the routing table
+			out[topic] = strconv.Itoa(s.m.rtable.list.Size())
+		case "hellos":
+			// HELLO cache tiers and background verification counters
+			out[topic] = fmt.Sprintf("%s,%s", s.m.rtable.HelloCacheStats(), s.m.rtable.HelloCheckStats())
+		case "gc":
+			// store garbage collection counters
+			out[topic] = s.m.gc.Stats().String()
+		case "replay":
+			// dropped replays of HELLO and PUT messages
+			d := s.m.replay.Drops()
+			out[topic] = fmt.Sprintf("hello=%d,put=%d", d["hello"], d["put"])
+		case "tracing":
+			// request trace sampling
+			out[topic] = s.m.tracer.status()
 		}
 	}
 	// set reply
@@ -66,11 +91,248 @@ func (s *RPCService) Status(r *http.Request, req *StatusRequest, reply *StatusRe
 	return nil
 }
 
+//----------------------------------------------------------------------
+// Command "DHT.Put"
+//----------------------------------------------------------------------
+
+// PutRequest stores a block in the DHT. The key string is hashed to get
+// the DHT key. The expiration is either an absolute time (RFC3339) or a
+// relative TTL (like "24h"); without both the max. TTL is used.
+type PutRequest struct {
+	Key    string `json:"key"`              // key string
+	Type   uint32 `json:"type"`             // block type
+	Data   []byte `json:"data"`             // block data (base64)
+	Expire string `json:"expire,omitempty"` // absolute expiration
+	TTL    string `json:"ttl,omitempty"`    // relative expiration
+	Repl   uint16 `json:"repl,omitempty"`   // replication level (0 = default)
+	Flags  uint16 `json:"flags,omitempty"`  // route options
+}
+
+// PutResponse returns the DHT key and the (capped) expiration.
+type PutResponse struct {
+	Key    string `json:"key"`
+	Expire string `json:"expire"`
+}
+
+// Put stores a block in the DHT.
+func (s *RPCService) Put(r *http.Request, req *PutRequest, reply *PutResponse) (err error) {
+	var (
+		val      uint64 = math.MaxUint64
+		relative        = true
+	)
+	switch {
+	case len(req.Expire) > 0 && len(req.TTL) > 0:
+		return fmt.Errorf("%w: both absolute and relative expiration", ErrInvalidExpiration)
+	case len(req.Expire) > 0:
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339, req.Expire); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidExpiration, err.Error())
+		}
+		val, relative = util.NewAbsoluteTime(t).Val, false
+	case len(req.TTL) > 0:
+		var d time.Duration
+		if d, err = time.ParseDuration(req.TTL); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidExpiration, err.Error())
+		}
+		if d <= 0 {
+			return fmt.Errorf("%w: TTL not positive", ErrInvalidExpiration)
+		}
+		val = uint64(d.Microseconds())
+	}
+	expire, err := s.m.putExpiration(val, relative, util.AbsoluteTimeNow())
+	if err != nil {
+		return
+	}
+	key := crypto.Hash([]byte(req.Key))
+	if err = s.m.put(r.Context(), key, enums.BlockType(req.Type), expire, req.Data, req.Flags, req.Repl, nil); err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[dht] RPC put of %d bytes under key %s (expires %s)", len(req.Data), key.Short(), expire)
+	*reply = PutResponse{Key: key.String(), Expire: expire.String()}
+	return
+}
+
+//----------------------------------------------------------------------
+// Command "DHT.Peers"
+//----------------------------------------------------------------------
+
+// PeersRequest asks for the list of peers in the routing table
+type PeersRequest struct{}
+
+// AddrInfo describes a known address of a peer
+type AddrInfo struct {
+	URI   string `json:"uri"`   // address
+	Class string `json:"class"` // address class (loopback, private, public, relay, other)
+}
+
+// PeerInfo describes a peer in the routing table
+type PeerInfo struct {
+	ID    string      `json:"id"`              // peer identifier
+	Alias string      `json:"alias,omitempty"` // alias of peer (if known)
+	Short string      `json:"short"`           // display name
+	Addrs []*AddrInfo `json:"addrs,omitempty"` // known addresses
+}
+
+// PeersResponse lists the peers in the routing table.
+type PeersResponse struct {
+	Peers []*PeerInfo `json:"peers"`
+}
+
+// Peers returns the peers in the routing table.
+func (s *RPCService) Peers(r *http.Request, req *PeersRequest, reply *PeersResponse) error {
+	list := make([]*PeerInfo, 0)
+	_ = s.m.rtable.list.ProcessRange(func(key string, p *PeerAddress, _ int) error {
+		alias, _ := util.PeerAlias(p.Peer)
+		var addrs []*AddrInfo
+		for _, addr := range s.m.core.PeerAddresses(p.Peer) {
+			addrs = append(addrs, &AddrInfo{
+				URI:   addr.URI(),
+				Class: addr.Class,
+			})
+		}
+		list = append(list, &PeerInfo{
+			ID:    p.Peer.String(),
+			Alias: alias,
+			Short: p.Peer.Short(),
+			Addrs: addrs,
+		})
+		return nil
+	}, true)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Short < list[j].Short
+	})
+	*reply = PeersResponse{Peers: list}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "DHT.Hellos"
+//----------------------------------------------------------------------
+
+// HellosRequest asks for the HELLO URLs of the peers in the routing
+// table (or of all peers with validated HELLOs), optionally including
+// the own HELLO.
+type HellosRequest struct {
+	All  bool `json:"all,omitempty"`  // all validated HELLOs in cache
+	Self bool `json:"self,omitempty"` // include own HELLO
+}
+
+// HellosResponse lists HELLO URLs ("gnunet://hello/...") that can be
+// used as bootstrap entries in the configuration of another node.
+type HellosResponse struct {
+	Hellos []string `json:"hellos"`
+}
+
+// Hellos returns the HELLO URLs of neighbors.
+func (s *RPCService) Hellos(r *http.Request, req *HellosRequest, reply *HellosResponse) error {
+	list, err := s.m.NeighborHellos(req.All, req.Self)
+	if err != nil {
+		return err
+	}
+	urls := make([]string, len(list))
+	for i, hb := range list {
+		urls[i] = hb.URL()
+	}
+	*reply = HellosResponse{Hellos: urls}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "DHT.Traces"
+//----------------------------------------------------------------------
+
+// TracesRequest asks for sampled request traces of given kind ("get" or
+// "put") and query key (all traces if empty); at most 'limit' traces are
+// returned (all kept traces if zero).
+type TracesRequest struct {
+	Kind  string `json:"kind,omitempty"`  // request kind
+	Key   string `json:"key,omitempty"`   // query key
+	Limit int    `json:"limit,omitempty"` // max. number of traces
+}
+
+// TracesResponse lists request traces (newest first).
+type TracesResponse struct {
+	Traces []*TraceInfo `json:"traces"`
+}
+
+// Traces returns sampled request traces.
+func (s *RPCService) Traces(r *http.Request, req *TracesRequest, reply *TracesResponse) error {
+	*reply = TracesResponse{
+		Traces: s.m.tracer.list(req.Kind, req.Key, req.Limit),
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "DHT.Export"
+//----------------------------------------------------------------------
+
+// ExportRequest asks for a dump of the local DHT store into a file
+// (path on the node running the DHT service).
+type ExportRequest struct {
+	File string `json:"file"`
+}
+
+// ExportResponse returns the number of exported blocks.
+type ExportResponse struct {
+	Count int `json:"count"`
+}
+
+// Export dumps the local DHT store to a file.
+func (s *RPCService) Export(r *http.Request, req *ExportRequest, reply *ExportResponse) (err error) {
+	var f *os.File
+	if f, err = os.Create(req.File); err != nil {
+		return
+	}
+	n, err := s.m.ExportStore(f)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[dht] exported %d blocks to '%s'", n, req.File)
+	*reply = ExportResponse{Count: n}
+	return
+}
+
+//----------------------------------------------------------------------
+// Command "DHT.Import"
+//----------------------------------------------------------------------
+
+// ImportRequest asks to add the blocks from a dump file (path on the
+// node running the DHT service) to the local DHT store.
+type ImportRequest struct {
+	File string `json:"file"`
+}
+
+// ImportResponse returns the number of imported and skipped blocks.
+type ImportResponse struct {
+	Count   int `json:"count"`
+	Skipped int `json:"skipped"`
+}
+
+// Import adds the blocks from a dump file to the local DHT store.
+func (s *RPCService) Import(r *http.Request, req *ImportRequest, reply *ImportResponse) (err error) {
+	var f *os.File
+	if f, err = os.Open(req.File); err != nil {
+		return
+	}
+	defer f.Close()
+	n, skipped, err := s.m.ImportStore(f)
+	if err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[dht] imported %d blocks from '%s' (%d skipped)", n, req.File, skipped)
+	*reply = ImportResponse{Count: n, Skipped: skipped}
+	return
+}
+
 //----------------------------------------------------------------------
 
 // InitRPC registers RPC commands for the module
 func (m *Module) InitRPC(srv *service.JRPCServer) {
-	if err := srv.RegisterService(dhtRPC, "DHT"); err != nil {
+	if err := srv.RegisterService(&RPCService{m: m}, "DHT"); err != nil {
 		logger.Printf(logger.ERROR, "[dht] Failed to init RPC: %s", err.Error())
 	}
 }
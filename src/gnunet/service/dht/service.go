@@ -35,6 +35,7 @@ import (
 var (
 	ErrInvalidID           = fmt.Errorf("invalid/unassociated ID")
 	ErrBlockExpired        = fmt.Errorf("block expired")
+	ErrInvalidExpiration   = fmt.Errorf("invalid expiration")
 	ErrInvalidResponseType = fmt.Errorf("invald response type")
 )
 
@@ -70,6 +71,7 @@ func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connectio
 	reqID := 0
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
+	cs := NewClientSession()
 
 loop:
 	for {
@@ -91,9 +93,12 @@ loop:
 
 		// handle message
 		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
-		s.HandleMessage(valueCtx, nil, msg, mc)
+		if !s.HandleClientMessage(valueCtx, cs, msg, mc) {
+			s.HandleMessage(valueCtx, nil, msg, mc)
+		}
 	}
-	// close client connection
+	// stop pending client requests and close client connection
+	cs.Close()
 	mc.Close()
 
 	// cancel all tasks running for this session/connection
@@ -0,0 +1,238 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// Request tracing: a configured fraction of GET and PUT requests is
+// traced in full (decisions taken, peers contacted, results received and
+// their timing). Traces are kept in a bounded store (oldest traces are
+// dropped first) and can be queried over JSON-RPC; requests that are not
+// sampled carry no trace and cost nothing but a random number.
+//----------------------------------------------------------------------
+
+// Tracing defaults
+const (
+	DefaultTraceSize = 100 // number of kept traces
+	MaxTraceSteps    = 64  // max. number of steps in a trace
+)
+
+// CtxTrace is the context key for the trace of a request (value is
+// *Trace); steps are only recorded if a trace is present.
+const CtxTrace = core.CtxKey("dht:trace")
+
+// Trace steps
+const (
+	TraceDecision = "decision" // actions derived from routing and flags
+	TraceLocal    = "local"    // results found in cache or storage
+	TraceStore    = "store"    // block stored locally
+	TraceForward  = "forward"  // request forwarded to peer
+	TraceResult   = "result"   // result sent or received
+	TraceDrop     = "drop"     // request or result dropped
+	TraceDone     = "done"     // processing of request finished
+)
+
+// TraceStep is a single step in the processing of a request.
+type TraceStep struct {
+	At   string `json:"at"`             // time since start of request
+	Step string `json:"step"`           // kind of step
+	Peer string `json:"peer,omitempty"` // peer involved in step
+	Info string `json:"info,omitempty"` // details
+}
+
+// Trace records the processing of a sampled GET or PUT request.
+type Trace struct {
+	sync.Mutex
+
+	id      int             // trace identifier
+	kind    string          // request kind ("get" or "put")
+	key     string          // query key
+	btype   enums.BlockType // block type
+	flags   uint16          // route options
+	from    *util.PeerID    // sender (nil for local requests)
+	start   time.Time       // start of request
+	steps   []*TraceStep    // processing steps
+	dropped int             // number of steps exceeding MaxTraceSteps
+}
+
+// Add a step to the trace. The details are formatted only if the request
+// is traced.
+func (t *Trace) Add(step string, peer *util.PeerID, format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	if len(t.steps) >= MaxTraceSteps {
+		t.dropped++
+		return
+	}
+	ts := &TraceStep{
+		At:   time.Since(t.start).String(),
+		Step: step,
+		Info: fmt.Sprintf(format, args...),
+	}
+	if peer != nil {
+		ts.Peer = peer.Short()
+	}
+	t.steps = append(t.steps, ts)
+}
+
+// TraceInfo is a snapshot of a request trace.
+type TraceInfo struct {
+	ID      int          `json:"id"`                // trace identifier
+	Kind    string       `json:"kind"`              // request kind ("get" or "put")
+	Key     string       `json:"key"`               // query key
+	Type    string       `json:"type"`              // block type
+	Flags   string       `json:"flags"`             // route options
+	From    string       `json:"from"`              // sender ("local" for local requests)
+	Started string       `json:"started"`           // start of request
+	Steps   []*TraceStep `json:"steps"`             // processing steps
+	Dropped int          `json:"dropped,omitempty"` // number of dropped steps
+}
+
+// Info returns a snapshot of the trace.
+func (t *Trace) Info() *TraceInfo {
+	t.Lock()
+	defer t.Unlock()
+	from := "local"
+	if t.from != nil {
+		from = t.from.Short()
+	}
+	return &TraceInfo{
+		ID:      t.id,
+		Kind:    t.kind,
+		Key:     t.key,
+		Type:    t.btype.String(),
+		Flags:   message.DHTFlags(t.flags),
+		From:    from,
+		Started: t.start.Format(time.RFC3339Nano),
+		Steps:   append([]*TraceStep{}, t.steps...),
+		Dropped: t.dropped,
+	}
+}
+
+// traceFromContext returns the request trace (or nil).
+func traceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(CtxTrace).(*Trace)
+	return t
+}
+
+//----------------------------------------------------------------------
+
+// tracer samples requests and keeps the most recent traces.
+type tracer struct {
+	sync.Mutex
+
+	rate    float64  // fraction of traced requests
+	traces  []*Trace // ring buffer of traces
+	next    int      // next position in ring buffer
+	sampled uint64   // number of traced requests
+}
+
+// newTracer creates a request tracer from configuration (nil for no
+// tracing).
+func newTracer(cfg *config.DHTTraceConfig) *tracer {
+	t := &tracer{
+		traces: make([]*Trace, DefaultTraceSize),
+	}
+	if cfg == nil {
+		return t
+	}
+	t.rate = cfg.Rate
+	if cfg.Size > 0 {
+		t.traces = make([]*Trace, cfg.Size)
+	}
+	return t
+}
+
+// sample decides if a request is traced and returns a new trace (kept in
+// the tracer) or nil.
+func (t *tracer) sample(kind string, key *crypto.HashCode, btype enums.BlockType, flags uint16, from *util.PeerID) *Trace {
+	if t.rate <= 0 || float64(util.RndUInt32()) >= t.rate*float64(1<<32) {
+		return nil
+	}
+	tr := &Trace{
+		id:    util.NextID(),
+		kind:  kind,
+		key:   key.String(),
+		btype: btype,
+		flags: flags,
+		from:  from,
+		start: time.Now(),
+		steps: make([]*TraceStep, 0),
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.traces[t.next] = tr
+	t.next = (t.next + 1) % len(t.traces)
+	t.sampled++
+	return tr
+}
+
+// list returns snapshots of kept traces (newest first) of given kind and
+// query key (all if empty); at most 'limit' traces are returned (no limit
+// if zero).
+func (t *tracer) list(kind, key string, limit int) []*TraceInfo {
+	t.Lock()
+	defer t.Unlock()
+	out := make([]*TraceInfo, 0)
+	n := len(t.traces)
+	for i := 1; i <= n; i++ {
+		tr := t.traces[(t.next-i+n)%n]
+		if tr == nil {
+			break
+		}
+		if (len(kind) > 0 && tr.kind != kind) || (len(key) > 0 && tr.key != key) {
+			continue
+		}
+		out = append(out, tr.Info())
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// status returns the sampling rate and the number of traced and kept
+// requests.
+func (t *tracer) status() string {
+	t.Lock()
+	defer t.Unlock()
+	kept := 0
+	for _, tr := range t.traces {
+		if tr != nil {
+			kept++
+		}
+	}
+	return fmt.Sprintf("rate=%g,sampled=%d,kept=%d", t.rate, t.sampled, kept)
+}
@@ -0,0 +1,140 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+)
+
+func TestTracerSampling(t *testing.T) {
+	key := crypto.Hash([]byte("key"))
+
+	// no tracing by default
+	tr := newTracer(nil)
+	for i := 0; i < 100; i++ {
+		if tr.sample("get", key, enums.BLOCK_TYPE_TEST, 0, nil) != nil {
+			t.Fatal("request traced without sampling rate")
+		}
+	}
+	// traced requests are kept in a bounded store
+	tr = newTracer(&config.DHTTraceConfig{Rate: 1, Size: 4})
+	for i := 0; i < 6; i++ {
+		kind := "get"
+		if i%2 == 1 {
+			kind = "put"
+		}
+		if tr.sample(kind, key, enums.BLOCK_TYPE_TEST, 0, testPeer(i)) == nil {
+			t.Fatal("request not traced")
+		}
+	}
+	list := tr.list("", "", 0)
+	if len(list) != 4 {
+		t.Fatalf("%d traces kept", len(list))
+	}
+	if list[0].From != testPeer(5).Short() || list[3].From != testPeer(2).Short() {
+		t.Error("traces not listed newest first")
+	}
+	if list = tr.list("put", key.String(), 1); len(list) != 1 || list[0].Kind != "put" {
+		t.Errorf("wrong filtered list: %v", list)
+	}
+	if list = tr.list("", crypto.Hash([]byte("other")).String(), 0); len(list) != 0 {
+		t.Error("traces for other key listed")
+	}
+	// sampling rate is applied
+	tr = newTracer(&config.DHTTraceConfig{Rate: 0.1, Size: 1000})
+	for i := 0; i < 1000; i++ {
+		tr.sample("get", key, enums.BLOCK_TYPE_TEST, 0, nil)
+	}
+	if n := tr.sampled; n < 50 || n > 150 {
+		t.Errorf("%d of 1000 requests traced at rate 0.1", n)
+	}
+}
+
+func TestTraceSteps(t *testing.T) {
+	tr := newTracer(&config.DHTTraceConfig{Rate: 1})
+	trace := tr.sample("get", crypto.Hash([]byte("key")), enums.BLOCK_TYPE_TEST, 0, nil)
+	for i := 0; i < MaxTraceSteps+3; i++ {
+		trace.Add(TraceLocal, nil, "step %d", i)
+	}
+	info := trace.Info()
+	if len(info.Steps) != MaxTraceSteps || info.Dropped != 3 || info.From != "local" {
+		t.Errorf("unexpected trace: %d steps, %d dropped, from %s", len(info.Steps), info.Dropped, info.From)
+	}
+	// steps of untraced requests are ignored
+	var none *Trace
+	none.Add(TraceDone, nil, "")
+}
+
+func TestTraceGet(t *testing.T) {
+	m, c := newTestModule(t, 8)
+	m.tracer = newTracer(&config.DHTTraceConfig{Rate: 1})
+	sender := testPeer(100)
+
+	// GET is forwarded (local peer is not closest)
+	key := queryKey(m, false)
+	msg := m.newGetMsg(blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0))
+	msg.PeerFilter = blocks.NewPeerFilter()
+	msg.PeerFilter.Add(sender)
+	back := &mockResponder{peer: sender}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.HandleMessage(ctx, sender, msg, back)
+	fwd := c.Sent(enums.MSG_DHT_P2P_GET)
+	if len(fwd) == 0 {
+		t.Fatal("GET not forwarded")
+	}
+	// result from a neighbor is sent back
+	blk := testBlock(t, m, key, false)
+	res := message.NewDHTP2PResultMsg()
+	res.BType = blk.Type()
+	res.Query = key
+	res.Expire = blk.Expire()
+	res.Block = blk.Bytes()
+	if !m.HandleMessage(ctx, fwd[0].peer, res, nil) {
+		t.Fatal("result not handled")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	list := m.tracer.list("get", key.String(), 0)
+	if len(list) != 1 {
+		t.Fatalf("%d GET traces", len(list))
+	}
+	trace := list[0]
+	if trace.From != sender.Short() || trace.Type != enums.BLOCK_TYPE_TEST.String() {
+		t.Errorf("wrong request in trace: %+v", trace)
+	}
+	count := make(map[string]int)
+	for _, step := range trace.Steps {
+		count[step.Step]++
+		if _, err := time.ParseDuration(step.At); err != nil {
+			t.Errorf("step '%s': invalid time '%s'", step.Step, step.At)
+		}
+	}
+	if count[TraceDecision] != 1 || count[TraceForward] != len(fwd) || count[TraceDone] != 1 || count[TraceResult] != 1 {
+		t.Errorf("unexpected steps: %v", count)
+	}
+}
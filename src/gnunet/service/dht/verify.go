@@ -0,0 +1,166 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"gnunet/core"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Confirmed PUT requests:
+// After storing a block, sample GETs are sent through different
+// neighbors (closest to the key first). If too few of them return the
+// block, the PUT is repeated through a neighbor not used before and the
+// block is sampled again.
+//----------------------------------------------------------------------
+
+// Parameters for confirmed PUT requests
+var (
+	VerifySamples   = 3                // number of sample GETs per round
+	VerifyRetries   = 2                // max. number of repeated PUTs
+	VerifyThreshold = 0.5              // min. share of samples returning the block
+	VerifyDelay     = 2 * time.Second  // time for a PUT to settle before sampling
+	VerifyTimeout   = 10 * time.Second // duration of a sample GET
+)
+
+// CtxVia is the context key for the neighbor a locally initiated request
+// is sent to (value is *util.PeerID). The request is neither answered
+// from local storage nor routed to other peers.
+const CtxVia = core.CtxKey("dht:via")
+
+// via returns the neighbor for a locally initiated request (or nil).
+func via(ctx context.Context, sender, local *util.PeerID) *util.PeerID {
+	if p, ok := ctx.Value(CtxVia).(*util.PeerID); ok && sender.Equal(local) {
+		return p
+	}
+	return nil
+}
+
+// PutReport is the outcome of a confirmed PUT request.
+type PutReport struct {
+	Samples   int // number of sample GETs (last round)
+	Confirmed int // number of samples that returned the block
+	Retries   int // number of repeated PUTs
+}
+
+// Confidence returns the share of samples that returned the block.
+func (r *PutReport) Confidence() float64 {
+	if r.Samples == 0 {
+		return 0
+	}
+	return float64(r.Confirmed) / float64(r.Samples)
+}
+
+// PutVerified stores a block in the DHT and checks that it can be
+// retrieved ["dht:put-verified"].
+func (m *Module) PutVerified(ctx context.Context, query blocks.Query, block blocks.Block) (*PutReport, error) {
+	if err := m.Put(ctx, query, block); err != nil {
+		return nil, err
+	}
+	return m.confirm(ctx, query, block), nil
+}
+
+// confirm that a stored block can be retrieved through neighbors.
+func (m *Module) confirm(ctx context.Context, query blocks.Query, block blocks.Block) *PutReport {
+	rep := new(PutReport)
+	data := block.Bytes()
+	self := m.core.PeerID()
+	addr := NewQueryAddress(query.Key())
+	tried := blocks.NewPeerFilter()
+	tried.Add(self)
+	for {
+		// wait for the PUT to settle
+		select {
+		case <-ctx.Done():
+			return rep
+		case <-time.After(VerifyDelay):
+		}
+		// sample GETs through different neighbors
+		pf := blocks.NewPeerFilter()
+		pf.Add(self)
+		var peers []*util.PeerID
+		for len(peers) < VerifySamples {
+			p := m.rtable.SelectClosestPeer(addr, pf, 0)
+			if p == nil {
+				break
+			}
+			pf.Add(p.Peer)
+			peers = append(peers, p.Peer)
+		}
+		rep.Samples, rep.Confirmed = len(peers), 0
+		var (
+			wg  sync.WaitGroup
+			mtx sync.Mutex
+		)
+		for _, peer := range peers {
+			wg.Add(1)
+			go func(peer *util.PeerID) {
+				defer wg.Done()
+				if m.sample(ctx, query, data, peer) {
+					mtx.Lock()
+					rep.Confirmed++
+					mtx.Unlock()
+				}
+			}(peer)
+		}
+		wg.Wait()
+		logger.Printf(logger.INFO, "[dht-verify] key %s: %d of %d samples confirmed (%d retries)",
+			query.Key().Short(), rep.Confirmed, rep.Samples, rep.Retries)
+		if rep.Samples == 0 || rep.Confidence() >= VerifyThreshold || rep.Retries >= VerifyRetries {
+			return rep
+		}
+		// repeat PUT through a neighbor not used before
+		p := m.rtable.SelectClosestPeer(addr, tried, 0)
+		if p == nil {
+			return rep
+		}
+		tried.Add(p.Peer)
+		rep.Retries++
+		logger.Printf(logger.INFO, "[dht-verify] repeat PUT for key %s via %s", query.Key().Short(), p.Peer.Short())
+		if err := m.Put(context.WithValue(ctx, CtxVia, p.Peer), query, block); err != nil {
+			logger.Printf(logger.ERROR, "[dht-verify] PUT not repeated: %s", err.Error())
+			return rep
+		}
+	}
+}
+
+// sample GET through a neighbor: returns true if the block was found.
+// The GET is cancelled as soon as the block is returned.
+func (m *Module) sample(ctx context.Context, query blocks.Query, data []byte, peer *util.PeerID) (found bool) {
+	q := blocks.NewGenericQuery(query.Key(), query.Type(), 0)
+	q.Params()["timeout"] = VerifyTimeout
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, CtxVia, peer))
+	defer cancel()
+	for res := range m.Get(ctx, q) {
+		if !found && bytes.Equal(res.Bytes(), data) {
+			found = true
+			cancel()
+		}
+	}
+	return
+}
@@ -0,0 +1,133 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package dht
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+)
+
+// shorten the sampling delays for tests
+func fastVerify(t *testing.T) {
+	t.Helper()
+	delay, timeout := VerifyDelay, VerifyTimeout
+	VerifyDelay, VerifyTimeout = 10*time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() {
+		VerifyDelay, VerifyTimeout = delay, timeout
+	})
+}
+
+// answer sample GETs (sent by the module) with the given block. All
+// GETs are answered repeatedly until the context is done, so a result
+// isn't lost if it arrives before the request is registered.
+func answerGets(ctx context.Context, m *Module, c *mockCore, blk blocks.Block) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+		for _, s := range c.Sent(enums.MSG_DHT_P2P_GET) {
+			get := s.msg.(*message.DHTP2PGetMsg)
+			res := message.NewDHTP2PResultMsg()
+			res.BType = get.BType
+			res.Expire = blk.Expire()
+			res.Query = get.Query
+			res.Block = blk.Bytes()
+			res.MsgSize += uint16(len(res.Block))
+			m.HandleMessage(ctx, s.peer, res, nil)
+		}
+	}
+}
+
+func TestPutUnconfirmed(t *testing.T) {
+	fastVerify(t)
+	m, c := newTestModule(t, 8)
+	key := queryKey(m, true)
+	blk := testBlock(t, m, key, true)
+	query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0)
+
+	rep := m.confirm(context.Background(), query, blk)
+	if rep.Samples != VerifySamples || rep.Confirmed != 0 || rep.Retries != VerifyRetries {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	// samples are sent to distinct neighbors (but never answered
+	// from local storage)
+	gets := c.Sent(enums.MSG_DHT_P2P_GET)
+	if len(gets) != (VerifyRetries+1)*VerifySamples {
+		t.Errorf("got %d sample GETs", len(gets))
+	}
+	seen := make(map[string]bool)
+	for _, s := range gets[:VerifySamples] {
+		seen[s.peer.String()] = true
+	}
+	if len(seen) != VerifySamples {
+		t.Errorf("samples sent to %d neighbors", len(seen))
+	}
+	// repeated PUTs go to a different neighbor each
+	puts := c.Sent(enums.MSG_DHT_P2P_PUT)
+	if len(puts) != VerifyRetries {
+		t.Fatalf("got %d repeated PUTs", len(puts))
+	}
+	if puts[0].peer.Equal(puts[1].peer) {
+		t.Error("PUT repeated through same neighbor")
+	}
+}
+
+func TestPutConfirmed(t *testing.T) {
+	fastVerify(t)
+	// samples end when the block is returned: the timeout is only
+	// reached if an answer is missing.
+	VerifyTimeout = 10 * time.Second
+	m, c := newTestModule(t, 8)
+	key := queryKey(m, false)
+	blk := testBlock(t, m, key, false)
+	query := blocks.NewGenericQuery(key, enums.BLOCK_TYPE_TEST, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go answerGets(ctx, m, c, blk)
+
+	rep, err := m.PutVerified(ctx, query, blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Samples != VerifySamples || rep.Confirmed != VerifySamples || rep.Retries != 0 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if rep.Confidence() != 1 {
+		t.Errorf("confidence %f", rep.Confidence())
+	}
+}
+
+func TestPutReport(t *testing.T) {
+	if c := new(PutReport).Confidence(); c != 0 {
+		t.Errorf("empty report: confidence %f", c)
+	}
+	msg := message.NewDHTClientPutConfirmMsg(crypto.Hash([]byte("key")), 4, 3, 1)
+	if c := msg.Confidence(); c != 0.75 {
+		t.Errorf("confirmation: confidence %f", c)
+	}
+}
@@ -0,0 +1,310 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package dns2gns implements a DNS-to-GNS gateway: DNS queries for names
+// in GNS are resolved by the GNS service and answered with the records
+// converted to DNS; all other queries are forwarded to a DNS server.
+package dns2gns
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/gns/rr"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+	"github.com/miekg/dns"
+)
+
+// Error codes
+var (
+	ErrNoLookupResult = errors.New("no lookup result from GNS")
+)
+
+// Default values
+const (
+	DefaultTimeout = 10 * time.Second // GNS lookup timeout
+	SuffixALT      = "alt"            // special-use TLD for non-DNS names (RFC 9476)
+)
+
+// Gateway answers DNS queries: names under the configured TLDs, under
+// the TLDs of the GNS start zones and under zTLDs are looked up by the
+// GNS service; other names are forwarded to the upstream DNS server.
+// If "alt" is a configured TLD, the suffix ".alt" is removed from names
+// before the GNS lookup.
+type Gateway struct {
+	socket   string          // GNS service socket
+	tlds     map[string]bool // TLDs resolved in GNS
+	upstream string          // upstream DNS server (host:port)
+	timeout  time.Duration   // timeout for GNS lookups and forwarded queries
+
+	udp *dns.Server // DNS server (UDP)
+	tcp *dns.Server // DNS server (TCP)
+}
+
+// NewGateway creates a new gateway for a node configuration (the GNS
+// service section is required).
+func NewGateway(nodeCfg *config.Config) *Gateway {
+	gw := &Gateway{
+		socket:  nodeCfg.GNS.Service.Socket,
+		tlds:    make(map[string]bool),
+		timeout: DefaultTimeout,
+	}
+	if cfg := nodeCfg.DNS2GNS; cfg != nil {
+		for _, tld := range cfg.TLDs {
+			gw.tlds[strings.ToLower(tld)] = true
+		}
+		if len(cfg.Upstream) > 0 {
+			gw.upstream = cfg.Upstream
+			if _, _, err := net.SplitHostPort(gw.upstream); err != nil {
+				gw.upstream = net.JoinHostPort(gw.upstream, "53")
+			}
+		}
+		if cfg.Timeout > 0 {
+			gw.timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+	}
+	for tld := range nodeCfg.GNS.StartZones {
+		gw.tlds[strings.ToLower(tld)] = true
+	}
+	return gw
+}
+
+// Start listening for DNS queries (UDP and TCP) on the given address.
+func (gw *Gateway) Start(addr string) (err error) {
+	var pc net.PacketConn
+	if pc, err = net.ListenPacket("udp", addr); err != nil {
+		return
+	}
+	// TCP listens on the same port (important if the port was chosen
+	// by the system)
+	var l net.Listener
+	if l, err = net.Listen("tcp", pc.LocalAddr().String()); err != nil {
+		pc.Close()
+		return
+	}
+	gw.udp = &dns.Server{PacketConn: pc, Handler: gw}
+	gw.tcp = &dns.Server{Listener: l, Handler: gw}
+	for _, srv := range []*dns.Server{gw.udp, gw.tcp} {
+		go func(srv *dns.Server) {
+			if err := srv.ActivateAndServe(); err != nil {
+				logger.Printf(logger.ERROR, "[dns2gns] Server failed: %s\n", err.Error())
+			}
+		}(srv)
+	}
+	logger.Printf(logger.INFO, "[dns2gns] Listening on %s\n", pc.LocalAddr().String())
+	return
+}
+
+// Addr returns the listen address of the gateway (or nil if not started).
+func (gw *Gateway) Addr() net.Addr {
+	if gw.udp == nil {
+		return nil
+	}
+	return gw.udp.PacketConn.LocalAddr()
+}
+
+// Stop the gateway.
+func (gw *Gateway) Stop() (err error) {
+	for _, srv := range []*dns.Server{gw.udp, gw.tcp} {
+		if srv == nil {
+			continue
+		}
+		if e := srv.Shutdown(); e != nil && err == nil {
+			err = e
+		}
+	}
+	gw.udp, gw.tcp = nil, nil
+	return
+}
+
+// ServeDNS handles a DNS query (dns.Handler interface)
+func (gw *Gateway) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	if len(r.Question) != 1 || r.Opcode != dns.OpcodeQuery {
+		resp.SetRcode(r, dns.RcodeNotImplemented)
+		gw.reply(w, r, resp)
+		return
+	}
+	q := r.Question[0]
+	name, ok := gw.gnsName(q.Name)
+	if !ok {
+		gw.forward(w, r)
+		return
+	}
+	logger.Printf(logger.DBG, "[dns2gns] GNS lookup of '%s' (%s)\n", name, dns.TypeToString[q.Qtype])
+	resp.SetReply(r)
+	resp.Authoritative = true
+	resp.RecursionAvailable = len(gw.upstream) > 0
+	if q.Qclass != dns.ClassINET && q.Qclass != dns.ClassANY {
+		resp.Rcode = dns.RcodeRefused
+		gw.reply(w, r, resp)
+		return
+	}
+	recs, err := gw.lookup(name, q.Qtype)
+	if err != nil {
+		logger.Printf(logger.WARN, "[dns2gns] GNS lookup of '%s' failed: %s\n", name, err.Error())
+		resp.Rcode = dns.RcodeServerFailure
+		gw.reply(w, r, resp)
+		return
+	}
+	if len(recs) == 0 {
+		resp.Rcode = dns.RcodeNameError
+	}
+	for _, rec := range recs {
+		if q.Qtype != dns.TypeANY && uint16(rec.RType) != q.Qtype && rec.RType != enums.GNS_TYPE_DNS_CNAME {
+			continue
+		}
+		ans, err := rr.ToDNS(q.Name, rec.RType, recordTTL(rec), rec.Data)
+		if err != nil {
+			logger.Printf(logger.DBG, "[dns2gns] %s record of '%s' skipped: %s\n", rr.TypeName(rec.RType), name, err.Error())
+			continue
+		}
+		resp.Answer = append(resp.Answer, ans)
+	}
+	gw.reply(w, r, resp)
+}
+
+// gnsName returns the name for a GNS lookup if a (DNS) name is resolved
+// in GNS.
+func (gw *Gateway) gnsName(fqdn string) (string, bool) {
+	name := strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	labels := strings.Split(name, ".")
+	num := len(labels)
+	switch tld := labels[num-1]; {
+	case tld == SuffixALT && gw.tlds[tld]:
+		// names under ".alt" are GNS names without the suffix
+		if num < 2 {
+			return "", false
+		}
+		labels = labels[:num-1]
+	case gw.tlds[tld], names.ZoneKey(tld) != nil:
+	case tld == names.PseudoTLD && num > 1 && names.ZoneKey(labels[num-2]) != nil:
+	default:
+		return "", false
+	}
+	// GNS labels are Unicode
+	name, err := util.NameToUnicode(strings.Join(labels, "."))
+	if err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// lookup a name in GNS (via the GNS service)
+func (gw *Gateway) lookup(name string, qtype uint16) ([]*blocks.ResourceRecord, error) {
+	n, err := names.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	zk := n.Zone
+	if zk != nil {
+		name = n.Path()
+	} else {
+		// the service maps the TLD to a start zone
+		zk, _ = crypto.NullZoneKey(enums.GNS_TYPE_PKEY)
+	}
+	req := message.NewGNSLookupMsg()
+	req.ID = uint32(util.NextID())
+	req.Zone = zk
+	req.RType = enums.GNSType(qtype)
+	if qtype == dns.TypeANY {
+		req.RType = enums.GNS_TYPE_ANY
+	}
+	req.SetName(name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gw.timeout)
+	defer cancel()
+	resp, err := service.RequestResponse(ctx, "dns2gns", "gns", gw.socket, req, true)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := resp.(*message.LookupResultMsg)
+	if !ok || res.ID != req.ID {
+		return nil, ErrNoLookupResult
+	}
+	return res.Records, nil
+}
+
+// forward a query to the upstream DNS server (refused if no upstream
+// server is configured).
+func (gw *Gateway) forward(w dns.ResponseWriter, r *dns.Msg) {
+	if len(gw.upstream) == 0 {
+		resp := new(dns.Msg)
+		resp.SetRcode(r, dns.RcodeRefused)
+		gw.reply(w, r, resp)
+		return
+	}
+	cl := &dns.Client{
+		Net:     w.LocalAddr().Network(),
+		Timeout: gw.timeout,
+	}
+	resp, _, err := cl.Exchange(r, gw.upstream)
+	if err != nil {
+		logger.Printf(logger.WARN, "[dns2gns] Query forwarding failed: %s\n", err.Error())
+		resp = new(dns.Msg)
+		resp.SetRcode(r, dns.RcodeServerFailure)
+	}
+	gw.reply(w, r, resp)
+}
+
+// reply to a query; responses over UDP are truncated to the size
+// accepted by the client.
+func (gw *Gateway) reply(w dns.ResponseWriter, r, resp *dns.Msg) {
+	if w.LocalAddr().Network() == "udp" {
+		size := dns.MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		resp.Truncate(size)
+	}
+	if err := w.WriteMsg(resp); err != nil {
+		logger.Printf(logger.WARN, "[dns2gns] Reply failed: %s\n", err.Error())
+	}
+}
+
+// recordTTL returns the remaining lifetime of a record in seconds
+func recordTTL(rec *blocks.ResourceRecord) uint32 {
+	var dt util.RelativeTime
+	if rec.Flags&enums.GNS_FLAG_RELATIVE_EXPIRATION != 0 {
+		dt = util.RelativeTime{Val: rec.Expire.Val}
+	} else {
+		var elapsed bool
+		if dt, elapsed = rec.Expire.Diff(util.AbsoluteTimeNow()); elapsed {
+			return 0
+		}
+	}
+	// DNS TTLs are limited to 31 bits (RFC 2181)
+	secs := dt.Val / 1000000
+	if secs > math.MaxInt32 {
+		secs = math.MaxInt32
+	}
+	return uint32(secs)
+}
@@ -19,7 +19,6 @@
 package gns
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 
@@ -104,8 +103,9 @@ type BlockHandler interface {
 
 // BlockHandlerList is a list of block handlers instantiated.
 type BlockHandlerList struct {
-	list   map[enums.GNSType]BlockHandler // list of handler instances
-	counts util.Counter[enums.GNSType]    // count number of RRs by type
+	list     map[enums.GNSType]BlockHandler // list of handler instances
+	counts   util.Counter[enums.GNSType]    // count number of RRs by type
+	shadowed bool                           // shadow records replaced expired records
 }
 
 // NewBlockHandlerList instantiates an a list of active block handlers
@@ -138,9 +138,12 @@ func NewBlockHandlerList(records []*blocks.ResourceRecord, labels []string) (*Bl
 			// do we have an associated shadow record?
 			for _, shadow := range shadows {
 				if shadow.RType == rec.RType && !shadow.Expire.Expired() {
-					// deliver un-expired shadow record instead.
-					shadow.Flags &^= enums.GNS_FLAG_SHADOW
-					active = append(active, shadow)
+					// deliver un-expired shadow record instead (as a copy:
+					// the shadow record itself is skipped in this pass).
+					srec := *shadow
+					srec.Flags &^= enums.GNS_FLAG_SHADOW
+					active = append(active, &srec)
+					hl.shadowed = true
 				}
 			}
 		} else {
@@ -206,6 +209,11 @@ func (hl *BlockHandlerList) GetHandler(types ...enums.GNSType) BlockHandler {
 	return nil
 }
 
+// Shadowed returns true if shadow records replaced expired records.
+func (hl *BlockHandlerList) Shadowed() bool {
+	return hl.shadowed
+}
+
 // FinalizeRecord post-processes records
 func (hl *BlockHandlerList) FinalizeRecord(rec *blocks.ResourceRecord) *blocks.ResourceRecord {
 	// no implementation yet
@@ -362,16 +370,13 @@ func (h *Gns2DnsHandler) Name() string {
 // BOX handler
 //----------------------------------------------------------------------
 
-// Box record for handler logic
-type Box struct {
-	rr.BOX
-	key string                 // map key for box instance
-	rec *blocks.ResourceRecord // originating RR
-}
-
-// BoxHandler implementing the BlockHandler interface
+// BoxHandler implementing the BlockHandler interface: it collects the BOX
+// records of a block and unwraps the records embedded for the protocol
+// and service named by the remaining labels ("_service._proto").
 type BoxHandler struct {
-	boxes map[string]*Box // map of found boxes
+	proto uint16            // protocol from labels (0 = no match possible)
+	svc   uint16            // service port from labels
+	boxes *blocks.RecordSet // BOX records of the block
 }
 
 // NewBoxHandler returns a new BlockHandler instance
@@ -380,8 +385,12 @@ func NewBoxHandler(rec *blocks.ResourceRecord, labels []string) (BlockHandler, e
 		return nil, ErrInvalidRecordType
 	}
 	h := &BoxHandler{
-		boxes: make(map[string]*Box),
+		boxes: blocks.NewRecordSet(),
 	}
+	// check if we need to process the BOX records: the remaining labels
+	// must name a protocol and a service.
+	h.proto, h.svc = rr.ServiceLabels(labels)
+	logger.Printf(logger.DBG, "[box-rr] for labels %v: proto=%d, svc=%d\n", labels, h.proto, h.svc)
 	if err := h.AddRecord(rec, labels); err != nil {
 		return nil, err
 	}
@@ -393,27 +402,10 @@ func (h *BoxHandler) AddRecord(rec *blocks.ResourceRecord, labels []string) erro
 	if rec.RType != enums.GNS_TYPE_BOX {
 		return ErrInvalidRecordType
 	}
-	logger.Printf(logger.DBG, "[box-rr] for labels %v\n", labels)
-	// check if we need to process the BOX record:
-	// (1) only two remaining labels
-	if len(labels) != 2 {
-		return nil
-	}
-	// (2) remaining labels must start with '_'
-	if labels[0][0] != '_' || labels[1][0] != '_' {
-		return nil
-	}
-	// (3) check of "svc" and "proto" match values in the BOX
-	hsh := sha256.Sum256(rec.Data)
-	box := &Box{
-		BOX: *rr.NewBOX(rec.Data),
-		key: hex.EncodeToString(hsh[:8]),
-		rec: rec,
-	}
-	if box.Matches(labels) {
-		logger.Println(logger.DBG, "[box-rr] MATCH -- adding record")
-		h.boxes[box.key] = box
+	if _, _, _, err := rec.Unbox(); err != nil {
+		return ErrInvalidRecordBody
 	}
+	h.boxes.AddRecord(rec)
 	return nil
 }
 
@@ -424,21 +416,25 @@ func (h *BoxHandler) Coexist(cm util.Counter[enums.GNSType]) bool {
 	return true
 }
 
-// Records returns a list of RR of the given type associated with this handler
+// Matched returns true if a BOX record matches protocol and service of
+// the labels.
+func (h *BoxHandler) Matched() bool {
+	return h.proto != 0 && h.boxes.Unbox(h.proto, h.svc).Count > 0
+}
+
+// Records returns the embedded records of the given type in BOX records
+// that match protocol and service of the labels.
 func (h *BoxHandler) Records(kind RRTypeList) *blocks.RecordSet {
 	rs := blocks.NewRecordSet()
-	for _, box := range h.boxes {
-		if kind.HasType(box.Type) {
-			// valid box found: assemble new resource record.
-			rr := new(blocks.ResourceRecord)
-			rr.Expire = box.rec.Expire
-			rr.Flags = box.rec.Flags
-			rr.RType = box.Type
-			rr.Size = uint16(len(box.RR))
-			rr.Data = box.RR
-			rs.AddRecord(rr)
+	if h.proto == 0 {
+		return rs
+	}
+	for _, rec := range h.boxes.Unbox(h.proto, h.svc).Records {
+		if kind.HasType(rec.RType) {
+			rs.AddRecord(rec)
 		}
 	}
+	logger.Printf(logger.DBG, "[box-rr] %d records unboxed\n", rs.Count)
 	return rs
 }
 
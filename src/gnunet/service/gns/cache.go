@@ -0,0 +1,105 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"container/list"
+	"sync"
+
+	"gnunet/service/dht/blocks"
+)
+
+//----------------------------------------------------------------------
+// Block cache: Keep recently resolved (verified and decrypted) GNS
+// blocks in memory, so repeated lookups of a name don't need a round
+// trip to the namecache service. The cache holds a limited number of
+// blocks; the least recently used block is dropped first. Blocks are
+// only returned until they expire.
+//----------------------------------------------------------------------
+
+// BlockCache for resolved GNS blocks
+type BlockCache struct {
+	sync.Mutex
+
+	size  int                      // max. number of blocks
+	list  *list.List               // blocks in LRU order (front = most recent)
+	index map[string]*list.Element // query key to list element
+}
+
+// cache entry
+type cacheEntry struct {
+	key   string
+	block *blocks.GNSBlock
+}
+
+// NewBlockCache creates an empty block cache for 'size' blocks.
+func NewBlockCache(size int) *BlockCache {
+	return &BlockCache{
+		size:  size,
+		list:  list.New(),
+		index: make(map[string]*list.Element),
+	}
+}
+
+// Get a cached block for query (nil if not cached or expired).
+func (bc *BlockCache) Get(query blocks.Query) *blocks.GNSBlock {
+	bc.Lock()
+	defer bc.Unlock()
+	key := query.Key().String()
+	e, ok := bc.index[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*cacheEntry)
+	if entry.block.Expire().Expired() {
+		bc.list.Remove(e)
+		delete(bc.index, key)
+		return nil
+	}
+	bc.list.MoveToFront(e)
+	return entry.block
+}
+
+// Add a block for query to the cache.
+func (bc *BlockCache) Add(query blocks.Query, block *blocks.GNSBlock) {
+	if block.Expire().Expired() {
+		return
+	}
+	bc.Lock()
+	defer bc.Unlock()
+	key := query.Key().String()
+	if e, ok := bc.index[key]; ok {
+		e.Value.(*cacheEntry).block = block
+		bc.list.MoveToFront(e)
+		return
+	}
+	bc.index[key] = bc.list.PushFront(&cacheEntry{key: key, block: block})
+	for bc.list.Len() > bc.size {
+		e := bc.list.Back()
+		bc.list.Remove(e)
+		delete(bc.index, e.Value.(*cacheEntry).key)
+	}
+}
+
+// Size returns the number of cached blocks.
+func (bc *BlockCache) Size() int {
+	bc.Lock()
+	defer bc.Unlock()
+	return bc.list.Len()
+}
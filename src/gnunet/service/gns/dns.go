@@ -19,15 +19,21 @@
 package gns
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
 	"strings"
 	"time"
 
+	"gnunet/config"
 	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/message"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/resolver"
+	"gnunet/transport"
 	"gnunet/util"
 
 	"github.com/bfix/gospel/logger"
@@ -115,13 +121,116 @@ func DNSNameFromBytes(b []byte, offset int) (int, string) {
 	return pos + 1, str
 }
 
-// QueryDNS queries the specified DNS server for a given name and expected result types.
-func QueryDNS(id int, name string, server net.IP, kind RRTypeList) *blocks.RecordSet {
-	// get default nameserver if not defined.
-	if server == nil {
-		server = net.IPv4(8, 8, 8, 8)
+//----------------------------------------------------------------------
+// DNS resolver
+//----------------------------------------------------------------------
+
+// Default settings for DNS queries
+const (
+	DefaultDNSResolver = "8.8.8.8"
+	DefaultDNSTimeout  = 10 * time.Second
+	DefaultDNSRetries  = 5
+)
+
+// DNSResolver queries DNS servers for resource records.
+type DNSResolver struct {
+	Resolvers []string      // upstream resolvers for names outside of GNS
+	Timeout   time.Duration // time limit for a resolution
+	Retries   int           // attempts per server
+}
+
+// NewDNSResolver returns a resolver for the given configuration (defaults
+// are used for missing settings).
+func NewDNSResolver(cfg *config.DNSConfig) *DNSResolver {
+	r := &DNSResolver{
+		Resolvers: []string{DefaultDNSResolver},
+		Timeout:   DefaultDNSTimeout,
+		Retries:   DefaultDNSRetries,
+	}
+	if cfg != nil {
+		if len(cfg.Resolvers) > 0 {
+			r.Resolvers = cfg.Resolvers
+		}
+		if cfg.Timeout > 0 {
+			r.Timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		if cfg.Retries > 0 {
+			r.Retries = cfg.Retries
+		}
+	}
+	return r
+}
+
+// dnsServerAddr returns the address of a DNS server ("addr" or "addr:port";
+// port 53 if not specified) as "host:port" and as IP address.
+func dnsServerAddr(server string) (string, net.IP) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = strings.Trim(server, "[]"), "53"
+	}
+	return net.JoinHostPort(host, port), net.ParseIP(host)
+}
+
+// Query asks a DNS server for a name and the expected result types. Each
+// type in the list is queried separately (ANY if no type is filtered);
+// the matching records of all answers are returned.
+func (r *DNSResolver) Query(id int, name string, server string, kind RRTypeList) *blocks.RecordSet {
+	addr, ip := dnsServerAddr(server)
+	if ip == nil {
+		logger.Printf(logger.ERROR, "[dns][%d] Invalid server address '%s'\n", id, server)
+		return nil
+	}
+	// no queries to external servers in local-only mode
+	if transport.LocalOnly && !ip.IsLoopback() {
+		logger.Printf(logger.WARN, "[dns][%d] Query for '%s' on '%s' refused (local-only mode)\n", id, name, addr)
+		return nil
+	}
+	logger.Printf(logger.DBG, "[dns][%d] Starting query for '%s' on '%s'...\n", id, name, addr)
+
+	// collect the DNS types to query
+	var qtypes []uint16
+	if kind.IsAny() {
+		qtypes = append(qtypes, dns.TypeANY)
+	} else {
+		for _, t := range kind {
+			// GNS-only types are not queried in DNS
+			if t <= 0xffff {
+				qtypes = append(qtypes, uint16(t))
+			}
+		}
 	}
-	logger.Printf(logger.DBG, "[dns][%d] Starting query for '%s' on '%s'...\n", id, name, server.String())
+	if len(qtypes) == 0 {
+		logger.Printf(logger.WARN, "[dns][%d] No DNS types to query\n", id)
+		return nil
+	}
+	// each attempt gets an equal share of the time limit
+	retries := r.Retries
+	if retries < 1 {
+		retries = 1
+	}
+	client := &dns.Client{
+		Timeout: r.Timeout / time.Duration(retries),
+	}
+	if client.Timeout < time.Second {
+		client.Timeout = time.Second
+	}
+	var set *blocks.RecordSet
+	for _, qtype := range qtypes {
+		if rs := r.exchange(id, client, addr, name, qtype, kind, retries); rs != nil {
+			if set == nil {
+				set = blocks.NewRecordSet()
+			}
+			mergeRecords(set, rs)
+		}
+	}
+	return set
+}
+
+// exchange sends a query of given type to a DNS server (in retry-loop)
+// and returns the matching records of the answer.
+func (r *DNSResolver) exchange(
+	id int, client *dns.Client, addr, name string,
+	qtype uint16, kind RRTypeList, retries int) *blocks.RecordSet {
 
 	// assemble query
 	m := &dns.Msg{
@@ -136,27 +245,26 @@ func QueryDNS(id int, name string, server net.IP, kind RRTypeList) *blocks.Recor
 	}
 	m.Question[0] = dns.Question{
 		Name:   dns.Fqdn(name),
-		Qtype:  dns.TypeANY,
+		Qtype:  qtype,
 		Qclass: dns.ClassINET,
 	}
 
 	// perform query in retry-loop
-	for retry := 0; retry < 5; retry++ {
+	for retry := 0; retry < retries; retry++ {
 		// send query with new ID when retrying
 		m.Id = dns.Id()
-		in, err := dns.Exchange(m, net.JoinHostPort(server.String(), "53"))
+		in, _, err := client.Exchange(m, addr)
 		// handle DNS fails
 		if err != nil {
-			errMsg := err.Error()
-			if strings.HasSuffix(errMsg, "i/o timeout") {
-				logger.Printf(logger.WARN, "[dns][%d] Query timed-out -- retrying (%d/5)\n", id, retry+1)
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				logger.Printf(logger.WARN, "[dns][%d] Query timed-out -- retrying (%d/%d)\n", id, retry+1, retries)
 				continue
 			}
-			logger.Printf(logger.ERROR, "[dns][%d] Error: %s\n", id, errMsg)
+			logger.Printf(logger.ERROR, "[dns][%d] Error: %s\n", id, err.Error())
 			return nil
 		}
 		// process results
-		logger.Printf(logger.WARN, "[dns][%d] Response from DNS server received (%d/5).\n", id, retry+1)
+		logger.Printf(logger.DBG, "[dns][%d] Response from DNS server received (%d/%d).\n", id, retry+1, retries)
 		if in == nil {
 			logger.Printf(logger.ERROR, "[dns][%d] No results\n", id)
 			return nil
@@ -190,20 +298,99 @@ func QueryDNS(id int, name string, server net.IP, kind RRTypeList) *blocks.Recor
 				set.AddRecord(rr)
 			}
 		}
-		logger.Printf(logger.INFO, "[dns][%d] %d resource records extracted from response (%d/5).\n", id, set.Count, retry+1)
+		logger.Printf(logger.INFO, "[dns][%d] %d resource records extracted from response (%d/%d).\n", id, set.Count, retry+1, retries)
 		return set
 	}
 	logger.Printf(logger.WARN, "[dns][%d] Resolution failed -- giving up...\n", id)
 	return nil
 }
 
+// Resolve queries a list of DNS servers (the upstream resolvers if the
+// list is empty) concurrently for a name. The matching records in all
+// answers received within the time limit are merged into the result.
+func (r *DNSResolver) Resolve(ctx context.Context, name string, servers []string, kind RRTypeList) (set *blocks.RecordSet, err error) {
+	if len(servers) == 0 {
+		servers = r.Resolvers
+	}
+	if len(servers) == 0 {
+		return nil, ErrNoDNSQueries
+	}
+	// start DNS queries concurrently
+	res := make(chan *blocks.RecordSet, len(servers))
+	for _, srv := range servers {
+		go func(srv string) {
+			res <- r.Query(util.NextID(), name, srv, kind)
+		}(srv)
+	}
+	// wait for query results
+	timeout := time.NewTimer(r.Timeout)
+	defer timeout.Stop()
+	for running := len(servers); running > 0; running-- {
+		select {
+		case rs := <-res:
+			if rs != nil {
+				if set == nil {
+					set = blocks.NewRecordSet()
+				}
+				mergeRecords(set, rs)
+			}
+		case <-timeout.C:
+			logger.Println(logger.WARN, "[dns] Queries timed out.")
+			running = 0
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if set == nil {
+		// no results
+		logger.Println(logger.WARN, "[dns] No results received from queries.")
+		return nil, ErrNoDNSResults
+	}
+	logger.Printf(logger.DBG, "[dns] %d records in merged query results.\n", set.Count)
+	return
+}
+
+// mergeRecords adds all records of a set that are not yet in the
+// destination set.
+func mergeRecords(dst, src *blocks.RecordSet) {
+	for _, rec := range src.Records {
+		dup := false
+		for _, r := range dst.Records {
+			if r.RType == rec.RType && bytes.Equal(r.Data, rec.Data) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			dst.AddRecord(rec)
+		}
+	}
+}
+
 //----------------------------------------------------------------------
 // GNSModule methods
 //----------------------------------------------------------------------
 
-// ResolveDNS resolves a name in DNS. Multiple DNS servers are queried in
-// parallel; the first result delivered by any of the servers is returned
-// as the result list of matching resource records.
+// dnsResolver returns the DNS resolver for the configuration of the module.
+func (m *Module) dnsResolver() *DNSResolver {
+	var cfg *config.DNSConfig
+	if m.cfg != nil && m.cfg.GNS != nil {
+		cfg = m.cfg.GNS.DNS
+	}
+	return NewDNSResolver(cfg)
+}
+
+// hostResolver returns the resolver for host names of the module.
+func (m *Module) hostResolver() *resolver.Resolver {
+	if m.hosts == nil {
+		return resolver.NewResolver(nil)
+	}
+	return m.hosts
+}
+
+// ResolveDNS resolves a name in DNS. The nameservers (given by address or
+// by name) are queried in parallel; the matching resource records of all
+// answers are merged into the result.
 func (m *Module) ResolveDNS(
 	ctx context.Context,
 	name string,
@@ -212,70 +399,92 @@ func (m *Module) ResolveDNS(
 	zkey *crypto.ZoneKey,
 	depth int) (set *blocks.RecordSet, err error) {
 
-	// start DNS queries concurrently
+	// DNS requires IDNA-encoded (ASCII) names
+	if name, err = util.NameToASCII(name); err != nil {
+		return
+	}
+	// record delegation in lookup trace (and DNS as source of the result)
+	defer func(started time.Time) {
+		traceFromContext(ctx).Add(enums.GNS_TRACE_DNS, zkey.ID(), name, nil, started)
+		if err == nil {
+			provenanceFromContext(ctx).AddDNS(set)
+		}
+	}(time.Now())
+
+	// get addresses of nameservers
 	logger.Printf(logger.DBG, "[dns] Resolution of '%s' starting...\n", name)
-	res := make(chan *blocks.RecordSet)
-	running := 0
+	addrs := make([]string, 0, len(servers))
 	for _, srv := range servers {
-		// check if srv is an IPv4/IPv6 address
-		addr := net.ParseIP(srv)
-		logger.Printf(logger.DBG, "ParseIP('%s', len=%d) --> %v\n", srv, len(srv), addr)
-		if addr == nil {
-			// no, it is a name... try to resolve an IP address from the name
-			query := NewRRTypeList(enums.GNS_TYPE_DNS_A, enums.GNS_TYPE_DNS_AAAA)
-			if set, err = m.ResolveUnknown(ctx, srv, nil, zkey, query, depth+1); err != nil {
-				logger.Printf(logger.ERROR, "[dns] Can't resolve NS server '%s': %s\n", srv, err.Error())
+		// check if srv is an IPv4/IPv6 address (with optional port)
+		if _, ip := dnsServerAddr(srv); ip != nil {
+			addrs = append(addrs, srv)
+			continue
+		}
+		// no, it is a name: DNS names are looked up by the system resolver
+		// (like in the GNUnet C implementation)...
+		if m.dnsName(ctx, srv) {
+			ips, rerr := m.hostResolver().Lookup(ctx, srv, message.ResolverAfUnspec)
+			if rerr != nil {
+				logger.Printf(logger.ERROR, "[dns] Can't resolve NS server '%s': %s\n", srv, rerr.Error())
 				continue
 			}
-			// traverse resource records for 'A' and 'AAAA' records.
-		rec_loop:
-			for _, rec := range set.Records {
-				switch rec.RType {
-				case enums.GNS_TYPE_DNS_AAAA:
-					addr = net.IP(rec.Data)
-					// we prefer IPv6
-					break rec_loop
-				case enums.GNS_TYPE_DNS_A:
-					addr = net.IP(rec.Data)
-				}
-			}
-			// check if we have an IP address available
-			if addr == nil {
-				logger.Printf(logger.WARN, "[dns] No IP address for nameserver '%s'\n", srv)
-				continue
+			addrs = append(addrs, preferIPv6(ips).String())
+			continue
+		}
+		// ... GNS names are resolved in GNS
+		query := NewRRTypeList(enums.GNS_TYPE_DNS_A, enums.GNS_TYPE_DNS_AAAA)
+		var rs *blocks.RecordSet
+		if rs, err = m.ResolveUnknown(ctx, srv, nil, zkey, query, depth+1); err != nil {
+			logger.Printf(logger.ERROR, "[dns] Can't resolve NS server '%s': %s\n", srv, err.Error())
+			continue
+		}
+		// traverse resource records for 'A' and 'AAAA' records.
+		var addr net.IP
+	rec_loop:
+		for _, rec := range rs.Records {
+			switch rec.RType {
+			case enums.GNS_TYPE_DNS_AAAA:
+				addr = net.IP(rec.Data)
+				// we prefer IPv6
+				break rec_loop
+			case enums.GNS_TYPE_DNS_A:
+				addr = net.IP(rec.Data)
 			}
 		}
-		// query DNS concurrently
-		go func() {
-			res <- QueryDNS(util.NextID(), name, addr, kind)
-		}()
-		running++
-	}
-	// check if we started some queries at all.
-	if running == 0 {
+		// check if we have an IP address available
+		if addr == nil {
+			logger.Printf(logger.WARN, "[dns] No IP address for nameserver '%s'\n", srv)
+			continue
+		}
+		addrs = append(addrs, addr.String())
+	}
+	// check if we can start queries at all.
+	if len(addrs) == 0 {
 		return nil, ErrNoDNSQueries
 	}
-	// wait for query results
-	timeout := time.NewTicker(10 * time.Second)
-	for {
-		select {
-		case set = <-res:
-			running--
-			if set != nil {
-				// we have a result.
-				logger.Println(logger.DBG, "[dns] Query result available.")
-				return
-			}
-			if running == 0 {
-				// no results
-				logger.Println(logger.WARN, "[dns] No results received from queries.")
-				return nil, ErrNoDNSResults
-			}
+	return m.dnsResolver().Resolve(ctx, name, addrs, kind)
+}
 
-		case <-timeout.C:
-			// no results
-			logger.Println(logger.WARN, "[dns] Queries timed out.")
-			return nil, ErrNoDNSResults
+// dnsName returns true if a (nameserver) name is not a GNS name.
+func (m *Module) dnsName(ctx context.Context, name string) bool {
+	n, _ := names.Parse(name)
+	if n == nil {
+		return true
+	}
+	if n.Relative {
+		return false
+	}
+	zone, err := m.StartZone(ctx, n.TLD)
+	return err == nil && zone == nil
+}
+
+// preferIPv6 returns the first IPv6 address in a list (or the first
+// address if the list has no IPv6 addresses).
+func preferIPv6(ips []net.IP) net.IP {
+	for _, ip := range ips {
+		if ip.To4() == nil {
+			return ip
 		}
 	}
+	return ips[0]
 }
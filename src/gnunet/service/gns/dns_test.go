@@ -0,0 +1,133 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+
+	"github.com/miekg/dns"
+)
+
+// startDNSServer runs a local DNS server answering queries for type A
+// with the given addresses.
+func startDNSServer(t *testing.T, addrs ...string) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			if req.Question[0].Qtype == dns.TypeA {
+				for _, addr := range addrs {
+					resp.Answer = append(resp.Answer, &dns.A{
+						Hdr: dns.RR_Header{
+							Name:   req.Question[0].Name,
+							Rrtype: dns.TypeA,
+							Class:  dns.ClassINET,
+							Ttl:    300,
+						},
+						A: net.ParseIP(addr),
+					})
+				}
+			}
+			_ = w.WriteMsg(resp)
+		}),
+	}
+	go func() {
+		_ = srv.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown()
+	})
+	return pc.LocalAddr().String()
+}
+
+func TestDNSResolverDefaults(t *testing.T) {
+	r := NewDNSResolver(nil)
+	if len(r.Resolvers) != 1 || r.Resolvers[0] != DefaultDNSResolver {
+		t.Fatalf("resolvers: %v", r.Resolvers)
+	}
+	if r.Timeout != DefaultDNSTimeout || r.Retries != DefaultDNSRetries {
+		t.Fatalf("timeout/retries: %v/%d", r.Timeout, r.Retries)
+	}
+	r = NewDNSResolver(&config.DNSConfig{Resolvers: []string{"127.0.0.1:5353"}, Timeout: 3, Retries: 1})
+	if r.Resolvers[0] != "127.0.0.1:5353" || r.Timeout != 3*time.Second || r.Retries != 1 {
+		t.Fatalf("configured resolver: %v", r)
+	}
+}
+
+func TestDNSResolverMerge(t *testing.T) {
+	srv1 := startDNSServer(t, "192.0.2.1", "192.0.2.2")
+	srv2 := startDNSServer(t, "192.0.2.2", "192.0.2.3")
+	r := NewDNSResolver(&config.DNSConfig{Resolvers: []string{srv1, srv2}, Timeout: 2, Retries: 1})
+
+	// answers of both servers are merged (without duplicates)
+	set, err := r.Resolve(context.Background(), "www.example.com", nil, NewRRTypeList(enums.GNS_TYPE_DNS_A))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, rec := range set.Records {
+		if rec.RType != enums.GNS_TYPE_DNS_A {
+			t.Fatalf("unexpected record type %s", rec.RType)
+		}
+		found[net.IP(rec.Data).String()] = true
+	}
+	if len(set.Records) != 3 || !found["192.0.2.1"] || !found["192.0.2.2"] || !found["192.0.2.3"] {
+		t.Fatalf("merged records: %v", found)
+	}
+	// types not in the filter are not returned
+	set, err = r.Resolve(context.Background(), "www.example.com", []string{srv1}, NewRRTypeList(enums.GNS_TYPE_DNS_AAAA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Count != 0 {
+		t.Fatalf("unexpected records: %d", set.Count)
+	}
+	// GNS-only types are not queried in DNS
+	if _, err = r.Resolve(context.Background(), "www.example.com", []string{srv1}, NewRRTypeList(enums.GNS_TYPE_PKEY)); err != ErrNoDNSResults {
+		t.Fatalf("expected ErrNoDNSResults, got %v", err)
+	}
+}
+
+func TestHostResolver(t *testing.T) {
+	// each module resolves host names with the settings of its node
+	ctx := context.Background()
+	var mods [2]*Module
+	for i := range mods {
+		mods[i] = NewModule(ctx, nil, &config.Config{
+			Resolver: &config.ResolverConfig{Timeout: i + 1},
+		})
+		if to := mods[i].hosts.Timeout; to != time.Duration(i+1)*time.Second {
+			t.Fatalf("module %d: resolver timeout %s", i, to)
+		}
+	}
+	if mods[0].hosts == mods[1].hosts {
+		t.Fatal("modules share a resolver")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"gnunet/config"
 	"gnunet/core"
@@ -29,6 +30,8 @@ import (
 	"gnunet/enums"
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/resolver"
 	"gnunet/service/revocation"
 	"gnunet/util"
 
@@ -43,6 +46,8 @@ import (
 var (
 	ErrUnknownTLD           = fmt.Errorf("unknown TLD in name")
 	ErrGNSRecursionExceeded = fmt.Errorf("recursion depth exceeded")
+	ErrInvalidLabel         = fmt.Errorf("invalid label in name")
+	ErrInvalidZone          = fmt.Errorf("invalid zone key")
 )
 
 //----------------------------------------------------------------------
@@ -57,8 +62,8 @@ var (
 //  (2) Resolve first label (= root zone, right-most name part, labels[0]) to
 //      a zone public key PKEY:
 //      (a) the label is a string representation of a public key -> (3)
-//      (b) the zone key for the label is stored in the config file -> (3)
-//      (c) a local zone with that given label -> (3)
+//      (b) the zone key for the label is configured as start zone -> (3)
+//      (c) a local zone (ego) with that given label -> (3)
 //      (d) ERROR: "Unknown root zone"
 //  (3) labels = labels[1:]
 //      records = Resolve (labels[0], PKEY)
@@ -93,21 +98,105 @@ type Module struct {
 	LookupRemote     func(ctx context.Context, query blocks.Query) (blocks.Block, error)
 	RevocationQuery  func(ctx context.Context, zkey *crypto.ZoneKey) (valid bool, err error)
 	RevocationRevoke func(ctx context.Context, rd *revocation.RevData) (success bool, err error)
+	LookupEgo        func(ctx context.Context, name string) (*crypto.ZoneKey, error)
+
+	zones     map[string]*crypto.ZoneKey // configured start zones (by TLD)
+	egos      bool                       // names of local egos are TLDs
+	cache     *BlockCache                // cache for resolved blocks (or nil)
+	negCache  *NegativeCache             // cache for failed remote lookups (or nil)
+	revFilter *revocation.Filter         // filter of revoked zone keys (or nil)
+	hosts     *resolver.Resolver         // resolver for host names of name servers
+	cfg       *config.Config             // node configuration
 }
 
-// NewModule instantiates a new GNS module.
-func NewModule(ctx context.Context, c *core.Core) (m *Module) {
+// CtxNoNegCache is the context key to bypass the negative cache for
+// remote lookups (value is bool).
+const CtxNoNegCache = core.CtxKey("gns:noNegCache")
+
+// NewModule instantiates a new GNS module for a node configuration
+// (the GNS section is required).
+func NewModule(ctx context.Context, c *core.Core, cfg *config.Config) (m *Module) {
 	m = &Module{
 		ModuleImpl: *service.NewModuleImpl(),
+		zones:      make(map[string]*crypto.ZoneKey),
+		cfg:        cfg,
+	}
+	// set up start zones (if configured)
+	if cfg != nil && cfg.GNS != nil {
+		for tld, ztld := range cfg.GNS.StartZones {
+			if err := m.AddStartZone(tld, ztld); err != nil {
+				logger.Printf(logger.ERROR, "[gns] start zone '%s' ignored: %s", tld, err.Error())
+			}
+		}
+		m.egos = cfg.GNS.Egos
+	}
+	// set up block cache (if configured)
+	if cfg != nil && cfg.GNS != nil && cfg.GNS.CacheSize > 0 {
+		m.cache = NewBlockCache(cfg.GNS.CacheSize)
+	}
+	// set up negative cache (if configured)
+	if cfg != nil && cfg.GNS != nil && cfg.GNS.NegCache != nil {
+		var err error
+		if m.negCache, err = NewNegativeCache(cfg.GNS.NegCache); err != nil {
+			logger.Printf(logger.ERROR, "[gns] negative cache disabled: %s", err.Error())
+			m.negCache = nil
+		}
+	}
+	// host names are resolved with the resolver settings of the node
+	var rcfg *config.ResolverConfig
+	if cfg != nil {
+		rcfg = cfg.Resolver
+	}
+	m.hosts = resolver.NewResolver(rcfg)
+	// use filter of revoked keys (if shared by the revocation service)
+	if cfg != nil && cfg.Revocation != nil && len(cfg.Revocation.Filter) > 0 {
+		m.revFilter = revocation.NewFilter(cfg.Revocation.Filter)
 	}
 	if c != nil {
 		// register as listener for core events
-		listener := m.ModuleImpl.Run(ctx, m.event, m.Filter(), 0, nil)
+		listener := m.ModuleImpl.Run(ctx, m.event, m.Filter())
 		c.Register("gns", listener)
 	}
+	// drop expired entries from the negative cache periodically
+	if nc := m.negCache; nc != nil {
+		gc := func(context.Context) error {
+			if n := nc.Prune(); n > 0 {
+				logger.Printf(logger.DBG, "[gns] %d expired entries dropped from negative cache", n)
+			}
+			return nil
+		}
+		if err := service.Schedule(ctx, "gns:negcache-gc", nc.ttl, gc); err != nil {
+			logger.Printf(logger.ERROR, "[gns] negative cache clean-up not scheduled: %s", err.Error())
+		}
+	}
+	service.RegisterIntrospector("gns", m.Introspect)
 	return
 }
 
+// Introspect returns health details of the module (sizes of the block
+// cache and the negative cache).
+func (m *Module) Introspect() *service.Introspection {
+	caches := make(map[string]int)
+	if m.cache != nil {
+		caches["blocks"] = m.cache.Size()
+	}
+	if m.negCache != nil {
+		caches["negative"] = m.negCache.Size()
+	}
+	return &service.Introspection{Caches: caches}
+}
+
+// SetLimiter sets the resource limiter for the module; it limits the
+// memory used by the (transient) negative cache.
+func (m *Module) SetLimiter(l *service.Limiter) {
+	m.ModuleImpl.SetLimiter(l)
+	if m.negCache != nil {
+		m.negCache.Lock()
+		m.negCache.lim = l
+		m.negCache.Unlock()
+	}
+}
+
 //----------------------------------------------------------------------
 
 // Filter returns the event filter for the service
@@ -144,6 +233,18 @@ func (m *Module) Import(fcn map[string]any) {
 
 //----------------------------------------------------------------------
 
+// checkRevocation returns true if a zone key is valid (not revoked). The
+// revocation service is only asked if the filter of revoked keys contains
+// the zone key (or if no filter is available).
+func (m *Module) checkRevocation(ctx context.Context, zkey *crypto.ZoneKey) (bool, error) {
+	if m.revFilter != nil && !m.revFilter.Contains(zkey) {
+		return true, nil
+	}
+	return m.RevocationQuery(ctx, zkey)
+}
+
+//----------------------------------------------------------------------
+
 // Resolve a GNS name with multiple labels. If pkey is not nil, the name
 // is interpreted as "relative to current zone".
 func (m *Module) Resolve(
@@ -155,20 +256,24 @@ func (m *Module) Resolve(
 	depth int) (set *blocks.RecordSet, err error) {
 
 	// check for recursion depth
-	if depth > config.Cfg.GNS.MaxDepth {
+	if depth > m.cfg.GNS.MaxDepth {
 		return nil, ErrGNSRecursionExceeded
 	}
-	// get the labels in reverse order
-	names := util.Reverse(strings.Split(path, "."))
-	logger.Printf(logger.DBG, "[gns] Resolver called for %v\n", names)
+	// parse name and get the labels in reverse order
+	var name *names.Name
+	if name, err = names.Parse(path); err != nil {
+		return
+	}
+	labels := util.Reverse(append(util.Clone(name.Labels), name.TLD))
+	logger.Printf(logger.DBG, "[gns] Resolver called for %v\n", labels)
 
 	// check for relative path
 	if zkey != nil {
 		//resolve relative path
-		return m.ResolveRelative(ctx, names, zkey, kind, mode, depth)
+		return m.ResolveRelative(ctx, labels, zkey, kind, mode, depth)
 	}
 	// resolve absolute path
-	return m.ResolveAbsolute(ctx, names, kind, mode, depth)
+	return m.ResolveAbsolute(ctx, labels, kind, mode, depth)
 }
 
 // ResolveAbsolute resolves a fully qualified GNS absolute name
@@ -181,7 +286,10 @@ func (m *Module) ResolveAbsolute(
 	depth int) (set *blocks.RecordSet, err error) {
 
 	// get the zone key for the TLD
-	zkey := m.GetZoneKey(labels[0])
+	var zkey *crypto.ZoneKey
+	if zkey, err = m.StartZone(ctx, labels[0]); err != nil {
+		return
+	}
 	if zkey == nil {
 		// we can't resolve this TLD
 		err = ErrUnknownTLD
@@ -190,7 +298,7 @@ func (m *Module) ResolveAbsolute(
 	// check if zone key has been revoked
 	var valid bool
 	set = blocks.NewRecordSet()
-	if valid, err = m.RevocationQuery(ctx, zkey); err != nil || !valid {
+	if valid, err = m.checkRevocation(ctx, zkey); err != nil || !valid {
 		return
 	}
 	// continue with resolution relative to a zone.
@@ -240,9 +348,19 @@ func (m *Module) ResolveRelative(
 		}
 		// post-process block by inspecting contained resource records for
 		// special GNS types
-		if records, err = m.records(block.Body.Data); err != nil {
+		// (use decrypted payload if available)
+		rdata := block.Payload()
+		if rdata == nil {
+			rdata = block.Body.Data
+		}
+		if records, err = m.records(rdata); err != nil {
 			return
 		}
+		// a NICK record in a zone with an EDKEY (the public key of a peer)
+		// is an alias for that peer.
+		if zkey.Type == enums.GNS_TYPE_EDKEY {
+			learnPeerAlias(zkey, records)
+		}
 		// assemble a list of block handlers for this block: if multiple
 		// block handlers are present, they are consistent with all block
 		// records.
@@ -252,6 +370,9 @@ func (m *Module) ResolveRelative(
 			// of resource records in a single block is considered valid.)
 			return
 		}
+		if hdlrs.Shadowed() {
+			provenanceFromContext(ctx).SetShadowed()
+		}
 
 		//--------------------------------------------------------------
 		// handle special block cases in priority order:
@@ -268,7 +389,7 @@ func (m *Module) ResolveRelative(
 			}
 			// check if zone key has been revoked
 			var valid bool
-			if valid, err = m.RevocationQuery(ctx, inst.zkey); err != nil || !valid {
+			if valid, err = m.checkRevocation(ctx, inst.zkey); err != nil || !valid {
 				// revoked key -> no results!
 				records = make([]*blocks.ResourceRecord, 0)
 				break
@@ -310,11 +431,13 @@ func (m *Module) ResolveRelative(
 			records = set.Records
 			break
 		} else if hdlr := hdlrs.GetHandler(enums.GNS_TYPE_BOX); hdlr != nil {
-			// (3) BOX records:
+			// (3) BOX records: if the remaining labels name a protocol
+			// and service of a BOX, resolution ends with the records
+			// embedded for them (no records of the requested type means
+			// NO_DATA).
 			inst, _ := hdlr.(*BoxHandler)
-			newRecords := inst.Records(kind).Records
-			if len(newRecords) > 0 {
-				records = newRecords
+			if inst.Matched() {
+				records = inst.Records(kind).Records
 				break
 			}
 		} else if hdlr := hdlrs.GetHandler(enums.GNS_TYPE_DNS_CNAME); hdlr != nil {
@@ -386,26 +509,33 @@ func (m *Module) ResolveUnknown(
 	depth int) (set *blocks.RecordSet, err error) {
 
 	// relative GNS-based server name?
-	if strings.HasSuffix(name, ".+") {
+	n, _ := names.Parse(name)
+	if n != nil && n.Relative {
 		// resolve server name relative to current zone
-		name = strings.TrimSuffix(name, ".+")
-		for _, label := range util.Reverse(labels) {
-			name += "." + label
-		}
+		name = names.Join(append(util.Clone(n.Labels), util.Reverse(labels)...))
 		if set, err = m.Resolve(ctx, name, zkey, kind, enums.GNS_LO_DEFAULT, depth+1); err != nil {
 			return
 		}
 	} else {
-		// check for absolute GNS name (with PKEY as TLD)
-		if zk := m.GetZoneKey(name); zk != nil {
-			// resolve absolute GNS name (name ends in a PKEY)
-			if set, err = m.Resolve(ctx, util.StripPathRight(name), zk, kind, enums.GNS_LO_DEFAULT, depth+1); err != nil {
+		// check for absolute GNS name (with zTLD or start zone)
+		var zone *crypto.ZoneKey
+		if n != nil {
+			if zone, err = m.StartZone(ctx, n.TLD); err != nil {
+				return
+			}
+		}
+		if zone != nil {
+			// resolve absolute GNS name in the zone of the TLD
+			if set, err = m.Resolve(ctx, n.Path(), zone, kind, enums.GNS_LO_DEFAULT, depth+1); err != nil {
 				return
 			}
 		} else {
 			// resolve the server name via DNS
-			if set = QueryDNS(util.NextID(), name, nil, kind); set == nil {
-				err = ErrNoDNSResults
+			started := time.Now()
+			set, err = m.dnsResolver().Resolve(ctx, name, nil, kind)
+			traceFromContext(ctx).Add(enums.GNS_TRACE_DNS, "", name, nil, started)
+			if err == nil {
+				provenanceFromContext(ctx).AddDNS(set)
 			}
 		}
 	}
@@ -414,15 +544,48 @@ func (m *Module) ResolveUnknown(
 
 // GetZoneKey returns the zone key (or nil) from an absolute GNS path.
 func (m *Module) GetZoneKey(path string) *crypto.ZoneKey {
-	labels := util.Reverse(strings.Split(path, "."))
-	if len(labels[0]) == 52 {
-		if data, err := util.DecodeStringToBinary(labels[0], 32); err == nil {
-			if zkey, err := crypto.NewZoneKey(data); err == nil {
-				return zkey
-			}
+	n, err := names.Parse(path)
+	if err != nil {
+		return nil
+	}
+	return n.Zone
+}
+
+// AddStartZone maps a TLD to a zone (given as zTLD). Names ending in the
+// TLD are resolved in that zone.
+func (m *Module) AddStartZone(tld, ztld string) (err error) {
+	if tld, err = names.Normalize(tld); err != nil {
+		return
+	}
+	zkey := names.ZoneKey(ztld)
+	if zkey == nil {
+		return ErrInvalidZone
+	}
+	m.zones[tld] = zkey
+	return
+}
+
+// StartZone returns the zone key for a TLD (or nil if the TLD is
+// unknown): the TLD is either a zTLD, a configured start zone or (if
+// enabled) the name of a local ego.
+func (m *Module) StartZone(ctx context.Context, tld string) (zkey *crypto.ZoneKey, err error) {
+	if zkey = names.ZoneKey(tld); zkey != nil {
+		return
+	}
+	if zkey = m.zones[tld]; zkey != nil {
+		return
+	}
+	if m.egos && m.LookupEgo != nil {
+		if zkey, err = m.LookupEgo(ctx, tld); err != nil {
+			// an unavailable identity service is treated like an unknown ego
+			logger.Printf(logger.WARN, "[gns] ego lookup for '%s' failed: %s", tld, err.Error())
+			zkey, err = nil, nil
+		}
+		if zkey != nil {
+			provenanceFromContext(ctx).AddLocalZone(zkey)
 		}
 	}
-	return nil
+	return
 }
 
 // Lookup name in GNS.
@@ -434,15 +597,56 @@ func (m *Module) Lookup(
 
 	// create query (lookup key)
 	query := blocks.NewGNSQuery(zkey, label)
+	if query == nil {
+		err = ErrInvalidLabel
+		return
+	}
+	// record lookup step (if tracing is requested) and the source of
+	// the block (if provenance is requested)
+	trace, started, step := traceFromContext(ctx), time.Now(), enums.GNS_TRACE_NOTFOUND
+	source := enums.GNS_SOURCE_NONE
+	defer func() {
+		if err != nil {
+			step = enums.GNS_TRACE_FAILED
+		} else if block != nil {
+			provenanceFromContext(ctx).AddBlock(source, zkey, block)
+		}
+		trace.Add(step, zkey.ID(), label, query.Key(), started)
+	}()
 
-	// try local lookup first
+	// try cached blocks first
+	if m.cache != nil {
+		if block = m.cache.Get(query); block != nil {
+			step, source = enums.GNS_TRACE_LOCAL, enums.GNS_SOURCE_CACHE
+			return
+		}
+		// cache the block resolved below
+		defer func() {
+			if err == nil && block != nil {
+				m.cache.Add(query, block)
+			}
+		}()
+	}
+	// try local lookup (namecache); an unavailable namecache is treated
+	// like a cache miss.
 	if block, err = m.LookupLocal(ctx, query); err != nil {
-		logger.Printf(logger.ERROR, "[gns] local Lookup: %s\n", err.Error())
-		block = nil
-		return
+		logger.Printf(logger.WARN, "[gns] local Lookup: %s\n", err.Error())
+		block, err = nil, nil
 	}
-	if block == nil {
+	if block != nil {
+		step, source = enums.GNS_TRACE_LOCAL, enums.GNS_SOURCE_NAMECACHE
+	} else {
 		if mode == enums.GNS_LO_DEFAULT {
+			// check if the query failed recently
+			negCache := m.negCache
+			if bypass, _ := ctx.Value(CtxNoNegCache).(bool); bypass {
+				negCache = nil
+			}
+			if negCache != nil && negCache.Contains(query) {
+				logger.Println(logger.DBG, "[gns] remote Lookup: negative cache hit")
+				step = enums.GNS_TRACE_NEGCACHE
+				return
+			}
 			// get the block from a remote lookup
 			var blk blocks.Block
 			if blk, err = m.LookupRemote(ctx, query); err != nil || blk == nil {
@@ -451,25 +655,55 @@ func (m *Module) Lookup(
 					block = nil
 				} else {
 					logger.Println(logger.DBG, "[gns] remote Lookup: no block found")
+					if m.negCache != nil {
+						m.negCache.Add(query)
+					}
 				}
 				// lookup fails completely -- no result
 				return
 			}
-			// convert to GNSBlock
-			if err = blocks.Unwrap(blk, &block); err != nil {
-				logger.Println(logger.DBG, "[gns] remote Lookup: GNS unwrap failed")
-				return
+			// forget a failed lookup if we bypassed the negative cache
+			if negCache == nil && m.negCache != nil {
+				m.negCache.Remove(query)
 			}
-			// store RRs from remote locally.
-			if err = m.StoreLocal(ctx, query, block); err != nil {
-				logger.Printf(logger.DBG, "[gns] store local failed: %s", err.Error())
-				return
+			// convert to GNSBlock (keep transient state like the
+			// decrypted payload if we already have a GNSBlock)
+			var ok bool
+			if block, ok = blk.(*blocks.GNSBlock); !ok {
+				block = new(blocks.GNSBlock)
+				if err = blocks.Unwrap(blk, block); err != nil {
+					logger.Println(logger.DBG, "[gns] remote Lookup: GNS unwrap failed")
+					return
+				}
+			}
+			step, source = enums.GNS_TRACE_DHT, enums.GNS_SOURCE_DHT
+
+			// store RRs from remote locally (the block is valid even
+			// if it can't be cached).
+			if errStore := m.StoreLocal(ctx, query, block); errStore != nil {
+				logger.Printf(logger.DBG, "[gns] store local failed: %s", errStore.Error())
 			}
 		}
 	}
 	return
 }
 
+// learnPeerAlias uses the NICK record in a zone block as alias for the
+// peer with the same (EDKEY) public key.
+func learnPeerAlias(zkey *crypto.ZoneKey, records []*blocks.ResourceRecord) {
+	for _, rec := range records {
+		if rec.RType != enums.GNS_TYPE_NICK || rec.Flags&enums.GNS_FLAG_SHADOW != 0 {
+			continue
+		}
+		peer := util.NewPeerID(zkey.KeyData)
+		nick := strings.TrimRight(string(rec.Data), "\x00")
+		if util.LearnPeerAlias(peer, nick) {
+			logger.Printf(logger.DBG, "[gns] alias '%s' learned for peer %s", nick, peer)
+		}
+		return
+	}
+}
+
 // newLEHORecord creates a new supplemental GNS record of type LEHO.
 func (m *Module) newLEHORecord(name string, expires util.AbsoluteTime) *blocks.ResourceRecord {
 	rr := new(blocks.ResourceRecord)
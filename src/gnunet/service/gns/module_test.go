@@ -0,0 +1,102 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// Test resolution of a name from a block received from the DHT.
+func TestResolveRemote(t *testing.T) {
+	// create zone and a record set for a label
+	zprv, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zkey := zprv.Public()
+	label := "www"
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := &blocks.RecordSet{
+		Count: 1,
+		Records: []*blocks.ResourceRecord{
+			{
+				Expire: expire,
+				Size:   5,
+				RType:  enums.GNS_TYPE_DNS_TXT,
+				Data:   []byte("hello"),
+			},
+		},
+	}
+	rs.SetPadding()
+
+	// build signed GNS block
+	bdata, err := zkey.Encrypt(rs.RDATA(), label, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk := blocks.NewGNSBlock().(*blocks.GNSBlock)
+	blk.Prepare(enums.BLOCK_TYPE_GNS_NAMERECORD, expire)
+	blk.SetData(bdata)
+	dzprv, _, err := zprv.Derive(label, blocks.GNSContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = blk.Sign(dzprv); err != nil {
+		t.Fatal(err)
+	}
+	// the DHT returns verified and decrypted blocks
+	query := blocks.NewGNSQuery(zkey, label)
+	if err = query.Verify(blk); err != nil {
+		t.Fatal(err)
+	}
+	if err = query.Decrypt(blk); err != nil {
+		t.Fatal(err)
+	}
+
+	// resolve label with block from "remote"
+	m := &Module{
+		LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+			return nil, nil
+		},
+		StoreLocal: func(context.Context, *blocks.GNSQuery, *blocks.GNSBlock) error {
+			return nil
+		},
+		LookupRemote: func(context.Context, blocks.Query) (blocks.Block, error) {
+			return blk, nil
+		},
+	}
+	set, err := m.ResolveRelative(context.Background(), []string{label}, zkey, NewRRTypeList(enums.GNS_TYPE_ANY), enums.GNS_LO_DEFAULT, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set == nil || len(set.Records) != 1 {
+		t.Fatalf("unexpected result: %v", set)
+	}
+	if rr := set.Records[0]; rr.RType != enums.GNS_TYPE_DNS_TXT || !bytes.Equal(rr.Data, []byte("hello")) {
+		t.Fatalf("unexpected record: %s", rr)
+	}
+}
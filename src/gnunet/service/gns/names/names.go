@@ -0,0 +1,169 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package names provides the canonical handling of GNS names: validation
+// and normalization of labels, splitting of names and the extraction of
+// zone keys from zTLDs. It is shared by the resolver, the namestore, the
+// zonemaster and command-line tools.
+package names
+
+import (
+	"errors"
+	"strings"
+
+	"gnunet/crypto"
+	"gnunet/util"
+)
+
+// Special labels and suffixes
+const (
+	LabelApex     = "@"   // label for records at the apex of a zone
+	LabelRelative = "+"   // TLD for names relative to the current zone
+	PseudoTLD     = "gnu" // optional suffix after a zTLD
+)
+
+// Error codes
+var (
+	ErrLabelApex  = errors.New("apex label not allowed in name")
+	ErrNameEmpty  = errors.New("empty name")
+	ErrNoZoneTLD  = errors.New("name has no zone TLD")
+	ErrLabelEmpty = util.ErrLabelEmpty
+	ErrLabelChar  = util.ErrLabelChar
+	ErrLabelSize  = util.ErrLabelSize
+	ErrNameSize   = util.ErrNameSize
+)
+
+//----------------------------------------------------------------------
+// Labels
+//----------------------------------------------------------------------
+
+// ValidateLabel checks if a label is valid: it must not be empty or too
+// long and must not contain dots, whitespace or control characters. The
+// special labels "@" and "+" are valid labels.
+func ValidateLabel(label string) error {
+	_, err := util.NormalizeLabel(label)
+	return err
+}
+
+// Normalize returns the canonical form of a label if the label is valid
+// (see util.NormalizeLabel).
+func Normalize(label string) (string, error) {
+	return util.NormalizeLabel(label)
+}
+
+//----------------------------------------------------------------------
+// Names
+//----------------------------------------------------------------------
+
+// Split a name into its normalized labels (in order of appearance).
+func Split(name string) (labels []string, err error) {
+	if len(name) == 0 {
+		return nil, ErrNameEmpty
+	}
+	if len(name) > util.MaxNameSize {
+		return nil, ErrNameSize
+	}
+	labels = strings.Split(name, ".")
+	for i, label := range labels {
+		if labels[i], err = Normalize(label); err != nil {
+			return nil, err
+		}
+		// the apex label is only allowed as a single label
+		if labels[i] == LabelApex && len(labels) > 1 {
+			return nil, ErrLabelApex
+		}
+	}
+	return
+}
+
+// Join labels into a name.
+func Join(labels []string) string {
+	return strings.Join(labels, ".")
+}
+
+// Name is a parsed GNS name
+type Name struct {
+	Labels   []string        // labels (left to right, without TLD)
+	TLD      string          // top-level label
+	Zone     *crypto.ZoneKey // zone key (if TLD is a zTLD)
+	Relative bool            // name is relative to the current zone
+}
+
+// Parse a GNS name: the right-most label (TLD) is either a zTLD (the
+// string representation of a zone key, optionally followed by the
+// pseudo-TLD ".gnu"), the "+" for names relative to the current zone or
+// a name that needs to be mapped to a zone by the caller.
+func Parse(name string) (n *Name, err error) {
+	var labels []string
+	if labels, err = Split(name); err != nil {
+		return
+	}
+	num := len(labels)
+	// strip pseudo-TLD after zTLD
+	if num > 1 && labels[num-1] == PseudoTLD && ZoneKey(labels[num-2]) != nil {
+		num--
+	}
+	n = &Name{
+		Labels: labels[:num-1],
+		TLD:    labels[num-1],
+	}
+	switch n.TLD {
+	case LabelRelative:
+		n.Relative = true
+	default:
+		n.Zone = ZoneKey(n.TLD)
+	}
+	return
+}
+
+// String returns the name (with TLD)
+func (n *Name) String() string {
+	return Join(append(util.Clone(n.Labels), n.TLD))
+}
+
+// Path returns the name without TLD.
+func (n *Name) Path() string {
+	return Join(n.Labels)
+}
+
+//----------------------------------------------------------------------
+// zTLDs
+//----------------------------------------------------------------------
+
+// ZoneKey returns the zone key for a zTLD (or nil if the label is not
+// a valid zTLD).
+func ZoneKey(tld string) *crypto.ZoneKey {
+	// zTLD is the encoding of zone type (4 bytes) and key data
+	data, err := util.DecodeStringToBinary(tld, (len(tld)*5)/8)
+	if err != nil || len(data) < 4 {
+		return nil
+	}
+	zk, err := crypto.NewZoneKey(data)
+	if err != nil || !strings.EqualFold(zk.ID(), tld) {
+		return nil
+	}
+	return zk
+}
+
+// ZoneTLD returns the zTLD for a zone key.
+func ZoneTLD(zk *crypto.ZoneKey) (string, error) {
+	if zk == nil {
+		return "", ErrNoZoneTLD
+	}
+	return strings.ToLower(zk.ID()), nil
+}
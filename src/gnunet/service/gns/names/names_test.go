@@ -0,0 +1,127 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package names
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+func TestValidateLabel(t *testing.T) {
+	for _, tc := range []struct {
+		label string
+		err   error
+	}{
+		{"www", nil},
+		{"_tcp", nil},
+		{"天下無敵", nil},
+		{LabelApex, nil},
+		{LabelRelative, nil},
+		{"", ErrLabelEmpty},
+		{"a.b", ErrLabelChar},
+		{"a b", ErrLabelChar},
+		{"a\tb", ErrLabelChar},
+		{"a\x00b", ErrLabelChar},
+		{strings.Repeat("x", 64), ErrLabelSize},
+	} {
+		if err := ValidateLabel(tc.label); !errors.Is(err, tc.err) {
+			t.Fatalf("'%s': unexpected error %v", tc.label, err)
+		}
+	}
+}
+
+// Labels accepted by Normalize must be accepted for DHT queries (and
+// vice versa).
+func TestNormalizeQuery(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"www", "WWW", "天下無敵", LabelApex, "", "a.b", "a b", "a\x00b", strings.Repeat("x", 64)} {
+		_, err := Normalize(label)
+		if q := blocks.NewGNSQuery(zp.Public(), label); (q != nil) != (err == nil) {
+			t.Fatalf("'%s': normalize error %v, query %v", label, err, q)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	labels, err := Split("WWW.Example.+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Join(labels) != "www.example.+" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	for _, name := range []string{"", "www..gnu", "www.@.gnu", "a b.gnu"} {
+		if _, err = Split(name); err == nil {
+			t.Fatalf("'%s': no error", name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	ztld, err := ZoneTLD(zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// names with zTLD (upper/lower case, with and without pseudo-TLD)
+	for _, name := range []string{
+		"www.example." + ztld,
+		"www.example." + strings.ToUpper(ztld),
+		"www.example." + ztld + "." + PseudoTLD,
+	} {
+		n, err := Parse(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Zone == nil || !n.Zone.Equal(zk) {
+			t.Fatalf("'%s': zone key mismatch", name)
+		}
+		if n.Path() != "www.example" || n.Relative {
+			t.Fatalf("'%s': unexpected result %v", name, n)
+		}
+	}
+	// relative name
+	n, err := Parse("www.+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Relative || n.Zone != nil || n.Path() != "www" {
+		t.Fatalf("unexpected result %v", n)
+	}
+	// name with other TLD
+	if n, err = Parse("www.gnu"); err != nil {
+		t.Fatal(err)
+	}
+	if n.Relative || n.Zone != nil || n.TLD != "gnu" || n.String() != "www.gnu" {
+		t.Fatalf("unexpected result %v", n)
+	}
+}
@@ -0,0 +1,163 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// DefaultNegCacheTTL is the lifetime of negative cache entries if not
+// configured otherwise.
+const DefaultNegCacheTTL = 15 * time.Minute
+
+//----------------------------------------------------------------------
+// Negative cache: Remember GNS queries that could not be resolved in the
+// DHT, so repeated lookups for non-existing names don't trigger expensive
+// DHT searches. Entries are keyed by query hash and hold the expiration
+// time of the entry. If a storage is configured, the cache persists
+// across restarts.
+//----------------------------------------------------------------------
+
+// NegativeCache for failed GNS lookups
+type NegativeCache struct {
+	sync.Mutex
+
+	ttl time.Duration                // lifetime of an entry
+	kvs store.KVStore                // persistent storage (or nil)
+	mem map[string]util.AbsoluteTime // transient storage (if kvs is nil)
+	lim *service.Limiter             // memory limit for transient storage (or nil)
+}
+
+// negEntrySize is the (approximate) memory used by a transient entry
+// in addition to its key.
+const negEntrySize = 16
+
+// NewNegativeCache creates a negative cache from configuration.
+func NewNegativeCache(cfg *config.NegCacheConfig) (nc *NegativeCache, err error) {
+	nc = &NegativeCache{
+		ttl: DefaultNegCacheTTL,
+	}
+	if cfg.TTL > 0 {
+		nc.ttl = time.Duration(cfg.TTL) * time.Second
+	}
+	if cfg.Storage != nil {
+		nc.kvs, err = store.NewKVStore(cfg.Storage)
+	} else {
+		nc.mem = make(map[string]util.AbsoluteTime)
+	}
+	return
+}
+
+// Contains returns true if the query failed recently.
+func (nc *NegativeCache) Contains(query blocks.Query) bool {
+	nc.Lock()
+	defer nc.Unlock()
+
+	key := query.Key().String()
+	var exp util.AbsoluteTime
+	if nc.kvs != nil {
+		val, err := nc.kvs.Get(key)
+		if err != nil {
+			// not in cache (or storage failed)
+			return false
+		}
+		if exp.Val, err = strconv.ParseUint(val, 10, 64); err != nil {
+			return false
+		}
+	} else {
+		var ok bool
+		if exp, ok = nc.mem[key]; !ok {
+			return false
+		}
+		if exp.Expired() {
+			delete(nc.mem, key)
+			nc.lim.Free(len(key) + negEntrySize)
+		}
+	}
+	return !exp.Expired()
+}
+
+// Add a failed query to the cache.
+func (nc *NegativeCache) Add(query blocks.Query) {
+	nc.put(query, util.AbsoluteTimeNow().Add(nc.ttl))
+}
+
+// Remove a query from the cache (the name resolves now).
+func (nc *NegativeCache) Remove(query blocks.Query) {
+	nc.put(query, util.AbsoluteTimeNow())
+}
+
+// Prune removes expired entries from the transient cache (entries in a
+// persistent storage expire on access). Returns the number of removed
+// entries.
+func (nc *NegativeCache) Prune() (n int) {
+	nc.Lock()
+	defer nc.Unlock()
+	for key, exp := range nc.mem {
+		if exp.Expired() {
+			delete(nc.mem, key)
+			nc.lim.Free(len(key) + negEntrySize)
+			n++
+		}
+	}
+	return
+}
+
+// Size returns the number of entries in the transient cache (entries
+// in a persistent storage are not counted).
+func (nc *NegativeCache) Size() int {
+	nc.Lock()
+	defer nc.Unlock()
+	return len(nc.mem)
+}
+
+// put an entry with given expiration into the cache
+func (nc *NegativeCache) put(query blocks.Query, exp util.AbsoluteTime) {
+	nc.Lock()
+	defer nc.Unlock()
+
+	key := query.Key().String()
+	if nc.kvs == nil {
+		_, ok := nc.mem[key]
+		if exp.Compare(util.AbsoluteTimeNow()) <= 0 {
+			if ok {
+				delete(nc.mem, key)
+				nc.lim.Free(len(key) + negEntrySize)
+			}
+		} else if ok || nc.lim.Reserve(len(key)+negEntrySize) {
+			nc.mem[key] = exp
+		} else {
+			logger.Printf(logger.DBG, "[gns] negative cache full: entry dropped")
+		}
+		return
+	}
+	if err := nc.kvs.Put(key, strconv.FormatUint(exp.Val, 10)); err != nil {
+		logger.Printf(logger.WARN, "[gns] negative cache update failed: %s", err.Error())
+	}
+}
@@ -0,0 +1,169 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+func TestNegCacheLookup(t *testing.T) {
+	nc, err := NewNegativeCache(&config.NegCacheConfig{TTL: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// module with stubbed lookups: the name never resolves
+	numRemote := 0
+	m := &Module{
+		LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+			return nil, nil
+		},
+		LookupRemote: func(context.Context, blocks.Query) (blocks.Block, error) {
+			numRemote++
+			return nil, nil
+		},
+		negCache: nc,
+	}
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	lookup := func(ctx context.Context) {
+		blk, err := m.Lookup(ctx, zk, "nonexistent", enums.GNS_LO_DEFAULT)
+		if err != nil || blk != nil {
+			t.Fatalf("unexpected lookup result: %v, %v", blk, err)
+		}
+	}
+	ctx := context.Background()
+
+	// first lookup hits the DHT, the second one doesn't
+	lookup(ctx)
+	lookup(ctx)
+	if numRemote != 1 {
+		t.Fatalf("expected 1 remote lookup, got %d", numRemote)
+	}
+	// bypass the cache
+	lookup(context.WithValue(ctx, CtxNoNegCache, true))
+	if numRemote != 2 {
+		t.Fatalf("expected 2 remote lookups, got %d", numRemote)
+	}
+	// expired entry
+	nc.ttl = 0
+	nc.Add(blocks.NewGNSQuery(zk, "nonexistent"))
+	lookup(ctx)
+	if numRemote != 3 {
+		t.Fatalf("expected 3 remote lookups, got %d", numRemote)
+	}
+}
+
+func TestNegCachePersistent(t *testing.T) {
+	// prepare key/value database
+	fname := filepath.Join(t.TempDir(), "negcache.db")
+	db, err := sql.Open("sqlite3", fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("create table store(key text primary key, value text)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	cfg := &config.NegCacheConfig{
+		TTL: 60,
+		Storage: util.ParameterSet{
+			"mode":    "sql",
+			"connect": "sqlite3:" + fname,
+		},
+	}
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := blocks.NewGNSQuery(zp.Public(), "nonexistent")
+
+	// add entry
+	nc, err := NewNegativeCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc.Contains(query) {
+		t.Fatal("empty cache contains query")
+	}
+	nc.Add(query)
+
+	// entry is visible in a new cache instance
+	if nc, err = NewNegativeCache(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !nc.Contains(query) {
+		t.Fatal("query not in cache")
+	}
+	// remove entry (replaces stored value)
+	nc.Remove(query)
+	time.Sleep(time.Millisecond)
+	if nc.Contains(query) {
+		t.Fatal("removed query still in cache")
+	}
+}
+
+func TestNegCacheLimit(t *testing.T) {
+	nc, err := NewNegativeCache(&config.NegCacheConfig{TTL: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Module{negCache: nc}
+	lim := service.NewLimiter("gns-negcache", &config.LimitConfig{MaxCache: 1})
+	m.SetLimiter(lim)
+
+	// add entries until the cache is full
+	var queries []*blocks.GNSQuery
+	for i := 0; i < 20; i++ {
+		zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := blocks.NewGNSQuery(zp.Public(), "nonexistent")
+		nc.Add(query)
+		queries = append(queries, query)
+	}
+	stats := lim.Stats()
+	if stats.Cache.Rejected == 0 || stats.Cache.Active > 1024 {
+		t.Fatalf("cache limit not enforced: %+v", stats.Cache)
+	}
+	if n := len(nc.mem); n == 0 || n == len(queries) {
+		t.Fatalf("unexpected number of cache entries: %d", n)
+	}
+	// removing entries frees memory
+	for _, query := range queries {
+		nc.Remove(query)
+	}
+	if stats = lim.Stats(); stats.Cache.Active != 0 {
+		t.Fatalf("cache memory not freed: %+v", stats.Cache)
+	}
+}
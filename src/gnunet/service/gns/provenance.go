@@ -0,0 +1,137 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"sync"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+// CtxProvenance is the context key for the provenance of a lookup (value
+// is *Provenance); provenance is only recorded if present.
+const CtxProvenance = core.CtxKey("gns:provenance")
+
+// Provenance records where the result of a lookup came from and how
+// fresh it is: the source of the answering block (or DNS), the earliest
+// expiration of all blocks used in the resolution, the time the
+// signature of the answering block was validated and whether shadow
+// records replaced expired records.
+type Provenance struct {
+	sync.Mutex
+
+	source   int               // source of answering block (GNS_SOURCE_*)
+	flags    int               // provenance flags (GNS_PROV_*)
+	expire   util.AbsoluteTime // earliest expiration of blocks used
+	verified util.AbsoluteTime // signature validation of answering block
+	local    map[string]bool   // local zones (egos) used in resolution
+}
+
+// NewProvenance starts a new provenance record.
+func NewProvenance() *Provenance {
+	return &Provenance{
+		source:   enums.GNS_SOURCE_NONE,
+		expire:   util.AbsoluteTimeNever(),
+		verified: util.NewAbsoluteTimeEpoch(0),
+		local:    make(map[string]bool),
+	}
+}
+
+// AddLocalZone marks a zone as a zone of a local ego; blocks of the
+// zone found in the namecache are reported with source GNS_SOURCE_ZONE.
+func (p *Provenance) AddLocalZone(zkey *crypto.ZoneKey) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.local[zkey.ID()] = true
+}
+
+// AddBlock records a block of a zone used in the resolution. The last
+// block added is the answering block.
+func (p *Provenance) AddBlock(source int, zkey *crypto.ZoneKey, block *blocks.GNSBlock) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	if source == enums.GNS_SOURCE_NAMECACHE && p.local[zkey.ID()] {
+		source = enums.GNS_SOURCE_ZONE
+	}
+	p.source = source
+	p.verified = block.Verified()
+	if block.Expire().Compare(p.expire) < 0 {
+		p.expire = block.Expire()
+	}
+}
+
+// AddDNS records records resolved in DNS as answer.
+func (p *Provenance) AddDNS(set *blocks.RecordSet) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.source = enums.GNS_SOURCE_DNS
+	p.verified = util.NewAbsoluteTimeEpoch(0)
+	if set == nil {
+		return
+	}
+	for _, rec := range set.Records {
+		if rec.Expire.Compare(p.expire) < 0 {
+			p.expire = rec.Expire
+		}
+	}
+}
+
+// SetShadowed records that shadow records replaced expired records.
+func (p *Provenance) SetShadowed() {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.flags |= enums.GNS_PROV_SHADOW
+}
+
+// Message returns the provenance as a response message for lookup
+// request with given identifier.
+func (p *Provenance) Message(id uint32) *message.LookupProvenanceMsg {
+	p.Lock()
+	defer p.Unlock()
+	msg := message.NewGNSLookupProvenanceMsg(id)
+	msg.Source = uint16(p.source)
+	msg.Flags = uint16(p.flags)
+	msg.Expire = p.expire
+	msg.Verified = p.verified
+	return msg
+}
+
+// provenanceFromContext returns the lookup provenance (or nil).
+func provenanceFromContext(ctx context.Context) *Provenance {
+	p, _ := ctx.Value(CtxProvenance).(*Provenance)
+	return p
+}
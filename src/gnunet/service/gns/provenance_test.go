@@ -0,0 +1,118 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+func TestLookupProvenance(t *testing.T) {
+	// zone with an expired A record for 'www' and its shadow record
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: util.AbsoluteTimeNow().Add(-time.Minute),
+		Size:   4,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{192, 0, 2, 1},
+	})
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   4,
+		Flags:  enums.GNS_FLAG_SHADOW,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{192, 0, 2, 2},
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the block is found in the DHT
+	m := &Module{
+		LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+			return nil, nil
+		},
+		StoreLocal: func(context.Context, *blocks.GNSQuery, *blocks.GNSBlock) error {
+			return nil
+		},
+		LookupRemote: func(_ context.Context, q blocks.Query) (blocks.Block, error) {
+			out, _ := blocks.NewGNSBlockFromRRBLOCK(blk.RRBLOCK())
+			gq, _ := q.(*blocks.GNSQuery)
+			if err := gq.Verify(out); err != nil {
+				return nil, err
+			}
+			return out, gq.Decrypt(out)
+		},
+		cache: NewBlockCache(8),
+		cfg:   testConfig,
+	}
+	kind := NewRRTypeList(enums.GNS_TYPE_DNS_A)
+	resolve := func() *Provenance {
+		prov := NewProvenance()
+		ctx := context.WithValue(context.Background(), CtxProvenance, prov)
+		set, err := m.Resolve(ctx, "www", zk, kind, enums.GNS_LO_DEFAULT, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set.Count != 1 || set.Records[0].Data[3] != 2 {
+			t.Fatalf("unexpected result %v", set.Records)
+		}
+		return prov
+	}
+	// first lookup from DHT, second from block cache
+	for _, source := range []int{enums.GNS_SOURCE_DHT, enums.GNS_SOURCE_CACHE} {
+		msg := resolve().Message(42)
+		if int(msg.Source) != source || !msg.Shadowed() {
+			t.Fatalf("unexpected provenance %s", msg)
+		}
+		if msg.Expire.Compare(expire) != 0 || msg.Verified.Val == 0 {
+			t.Fatalf("unexpected freshness %s", msg)
+		}
+		// transfer provenance in a message
+		buf, err := data.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) != int(msg.MsgSize) {
+			t.Fatalf("message size mismatch: %d != %d", len(buf), msg.MsgSize)
+		}
+		msg2, _ := message.NewEmptyMessage(enums.MSG_GNS_LOOKUP_PROVENANCE)
+		if err = data.Unmarshal(msg2, buf); err != nil {
+			t.Fatal(err)
+		}
+		if msg2.String() != msg.String() {
+			t.Fatalf("message mismatch: %s != %s", msg2, msg)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/revocation"
+	"gnunet/util"
+)
+
+// Test the revocation request sent to the revocation service.
+func TestRevokeKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// revocation data for a new zone
+	zprv, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd := &revocation.RevData{
+		Timestamp: util.AbsoluteTimeNow(),
+		TTL:       util.NewRelativeTime(time.Hour),
+		PoWs:      make([]uint64, 32),
+	}
+	sig, err := zprv.Sign([]byte("revoke"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd.ZoneKeySig = &crypto.ZoneSignature{
+		ZoneKey:   *zprv.Public(),
+		Signature: sig.Signature,
+	}
+	if err = rd.ZoneKeySig.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// run a revocation service that checks the request
+	socket := filepath.Join(t.TempDir(), "revocation.sock")
+	cfg := &config.Config{
+		Revocation: &config.RevocationConfig{
+			Service: &config.ServiceConfig{Socket: socket},
+		},
+	}
+	hdlr := make(chan *service.Connection)
+	cm, err := service.NewConnectionManager(ctx, socket, nil, hdlr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+	errCh := make(chan string, 1)
+	go func() {
+		conn := <-hdlr
+		defer conn.Close()
+		msg, err := conn.Receive(ctx)
+		if err != nil {
+			errCh <- err.Error()
+			return
+		}
+		req, ok := msg.(*message.RevocationRevokeMsg)
+		switch {
+		case !ok:
+			errCh <- "unexpected request " + msg.String()
+		case !req.ZoneKeySig.ZoneKey.Equal(rd.ZoneKeySig.Key()):
+			errCh <- "zone key mismatch"
+		case req.TTL.Val != rd.TTL.Val:
+			errCh <- "TTL mismatch"
+		default:
+			errCh <- ""
+		}
+		_ = conn.Send(ctx, message.NewRevocationRevokeResponseMsg(ok))
+	}()
+
+	// send revocation
+	srv := &Service{
+		Module: Module{cfg: cfg},
+	}
+	success, err := srv.RevokeKey(ctx, rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-errCh; len(e) > 0 {
+		t.Fatal(e)
+	}
+	if !success {
+		t.Fatal("revocation failed")
+	}
+}
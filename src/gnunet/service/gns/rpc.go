@@ -18,10 +18,123 @@
 
 package gns
 
-import "gnunet/service"
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/gns/names"
+	"gnunet/service/gns/rr"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for GNS-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to GNS module
+}
+
+//----------------------------------------------------------------------
+// Command "GNS.Lookup"
+//----------------------------------------------------------------------
+
+// LookupRequest resolves a name in GNS. Names without zTLD are resolved
+// in the zone (if given) or in the start zone of their TLD.
+type LookupRequest struct {
+	Name  string `json:"name"`           // name to look up
+	Zone  string `json:"zone,omitempty"` // zone key (zTLD)
+	Type  string `json:"type,omitempty"` // record type (default: ANY)
+	NoDHT bool   `json:"noDHT"`          // don't look up names in the DHT
+}
+
+// LookupRecord is a resource record in a lookup response
+type LookupRecord struct {
+	Type   string `json:"type"`   // record type
+	Flags  uint16 `json:"flags"`  // record flags
+	Expire string `json:"expire"` // expiration
+	Value  string `json:"value"`  // record data (text)
+}
+
+// LookupProvenance describes the source and freshness of a result
+type LookupProvenance struct {
+	Source   string `json:"source"`             // source of the result
+	Expire   string `json:"expire"`             // earliest expiration of blocks used
+	Verified string `json:"verified,omitempty"` // signature validation of answering block
+	Shadow   bool   `json:"shadow"`             // shadow records used
+}
+
+// LookupResponse returns the records and the provenance of a lookup.
+type LookupResponse struct {
+	Records    []*LookupRecord   `json:"records"`
+	Provenance *LookupProvenance `json:"provenance"`
+}
+
+// Lookup resolves a name and returns the records with their provenance.
+func (s *RPCService) Lookup(r *http.Request, req *LookupRequest, reply *LookupResponse) (err error) {
+	kind := NewRRTypeList(enums.GNS_TYPE_ANY)
+	if len(req.Type) > 0 {
+		var t enums.GNSType
+		if t, err = rr.ParseType(req.Type); err != nil {
+			return
+		}
+		kind = NewRRTypeList(t)
+	}
+	zone := names.ZoneKey(req.Zone)
+	if len(req.Zone) > 0 && zone == nil {
+		return fmt.Errorf("%w: '%s'", ErrInvalidZone, req.Zone)
+	}
+	mode := enums.GNS_LO_DEFAULT
+	if req.NoDHT {
+		mode = enums.GNS_LO_NO_DHT
+	}
+	prov := NewProvenance()
+	ctx := context.WithValue(r.Context(), CtxProvenance, prov)
+	set, err := s.m.Resolve(ctx, req.Name, zone, kind, mode, 0)
+	if err != nil {
+		return
+	}
+	recs := make([]*LookupRecord, 0)
+	if set != nil {
+		for _, rec := range set.Records {
+			recs = append(recs, &LookupRecord{
+				Type:   rec.RType.String(),
+				Flags:  uint16(rec.Flags),
+				Expire: rec.Expire.String(),
+				Value:  rr.ToText(rec.RType, rec.Data),
+			})
+		}
+	}
+	*reply = LookupResponse{
+		Records:    recs,
+		Provenance: NewLookupProvenance(prov.Message(0)),
+	}
+	return
+}
+
+// NewLookupProvenance returns the provenance of a lookup result (as
+// received in a provenance message).
+func NewLookupProvenance(m *message.LookupProvenanceMsg) *LookupProvenance {
+	lp := &LookupProvenance{
+		Source: m.SourceString(),
+		Expire: m.Expire.String(),
+		Shadow: m.Shadowed(),
+	}
+	if m.Verified.Val != 0 {
+		lp.Verified = m.Verified.String()
+	}
+	return lp
+}
 
 //----------------------------------------------------------------------
 
 // InitRPC registers RPC commands for the module
 func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "GNS"); err != nil {
+		logger.Printf(logger.ERROR, "[gns] Failed to init RPC: %s", err.Error())
+	}
 }
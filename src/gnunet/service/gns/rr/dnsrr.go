@@ -0,0 +1,112 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"errors"
+	"gnunet/enums"
+	"gnunet/util"
+	"net"
+
+	"github.com/bfix/gospel/data"
+	"github.com/miekg/dns"
+)
+
+//----------------------------------------------------------------------
+// Conversion of GNS resource records to DNS resource records (used by
+// the DNS-to-GNS gateway).
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrNoDNSType = errors.New("record type not available in DNS")
+)
+
+// ToDNS converts GNS record data to a DNS resource record for a name
+// (FQDN) with given TTL (in seconds). Names in the record data are
+// used as they are (GNS names are not expanded). Record types with no
+// DNS representation (like PKEY or BOX) are rejected.
+func ToDNS(name string, t enums.GNSType, ttl uint32, buf []byte) (dns.RR, error) {
+	hdr := dns.RR_Header{
+		Name:   name,
+		Rrtype: uint16(t),
+		Class:  dns.ClassINET,
+		Ttl:    ttl,
+	}
+	switch t {
+	case enums.GNS_TYPE_DNS_A:
+		if len(buf) != net.IPv4len {
+			return nil, ErrBadValue
+		}
+		return &dns.A{Hdr: hdr, A: net.IP(buf)}, nil
+	case enums.GNS_TYPE_DNS_AAAA:
+		if len(buf) != net.IPv6len {
+			return nil, ErrBadValue
+		}
+		return &dns.AAAA{Hdr: hdr, AAAA: net.IP(buf)}, nil
+	case enums.GNS_TYPE_DNS_CNAME:
+		return &dns.CNAME{Hdr: hdr, Target: dnsName(buf)}, nil
+	case enums.GNS_TYPE_DNS_NS:
+		return &dns.NS{Hdr: hdr, Ns: dnsName(buf)}, nil
+	case enums.GNS_TYPE_DNS_PTR:
+		return &dns.PTR{Hdr: hdr, Ptr: dnsName(buf)}, nil
+	case enums.GNS_TYPE_DNS_DNAME:
+		return &dns.DNAME{Hdr: hdr, Target: dnsName(buf)}, nil
+	case enums.GNS_TYPE_DNS_TXT:
+		// DNS character-strings are limited to 255 bytes
+		s, _ := util.ReadCString(buf, 0)
+		txt := &dns.TXT{Hdr: hdr}
+		for len(s) > 255 {
+			txt.Txt = append(txt.Txt, s[:255])
+			s = s[255:]
+		}
+		txt.Txt = append(txt.Txt, s)
+		return txt, nil
+	case enums.GNS_TYPE_DNS_MX:
+		mx := new(MX)
+		if err := data.Unmarshal(mx, buf); err != nil {
+			return nil, err
+		}
+		return &dns.MX{Hdr: hdr, Preference: mx.Prio, Mx: dns.Fqdn(mx.Server)}, nil
+	case enums.GNS_TYPE_DNS_SRV, enums.GNS_TYPE_DNS_SOA:
+		// not stored in DNS wire format
+		return nil, ErrNoDNSType
+	}
+	// other DNS types hold record data in wire format; GNS types are
+	// outside the DNS range.
+	if t == enums.GNS_TYPE_ANY || t > 0xffff {
+		return nil, ErrNoDNSType
+	}
+	hdr.Rdlength = uint16(len(buf))
+	rr, _, err := dns.UnpackRRWithHeader(hdr, buf, 0)
+	if err != nil {
+		return nil, ErrBadValue
+	}
+	return rr, nil
+}
+
+// dnsName returns the FQDN from name record data: the resolver reads
+// names in DNS wire format, text input is stored as a C string.
+func dnsName(buf []byte) string {
+	if s, n, err := dns.UnpackDomainName(buf, 0); err == nil && n == len(buf) {
+		return s
+	}
+	s, _ := util.ReadCString(buf, 0)
+	return dns.Fqdn(s)
+}
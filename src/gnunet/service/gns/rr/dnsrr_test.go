@@ -0,0 +1,63 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"gnunet/enums"
+	"strings"
+	"testing"
+)
+
+func TestToDNS(t *testing.T) {
+	values := []struct {
+		t enums.GNSType
+		v string // text value of GNS record
+		d string // DNS record (zone file format)
+	}{
+		{enums.GNS_TYPE_DNS_A, "10.0.0.1", "www.test.gnu.\t300\tIN\tA\t10.0.0.1"},
+		{enums.GNS_TYPE_DNS_AAAA, "2001:db8::1", "www.test.gnu.\t300\tIN\tAAAA\t2001:db8::1"},
+		{enums.GNS_TYPE_DNS_CNAME, "web.example.com", "www.test.gnu.\t300\tIN\tCNAME\tweb.example.com."},
+		{enums.GNS_TYPE_DNS_TXT, "hello world", "www.test.gnu.\t300\tIN\tTXT\t\"hello world\""},
+		{enums.GNS_TYPE_DNS_MX, "10 mx.example.com", "www.test.gnu.\t300\tIN\tMX\t10 mx.example.com."},
+		{enums.GNS_TYPE_DNS_CAA, "000569737375656361", "www.test.gnu.\t300\tIN\tCAA\t0 issue \"ca\""},
+	}
+	for _, e := range values {
+		buf, err := FromText(e.t, e.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr, err := ToDNS("www.test.gnu.", e.t, 300, buf)
+		if err != nil {
+			t.Fatalf("%s: %s", TypeName(e.t), err.Error())
+		}
+		if s := rr.String(); s != e.d {
+			t.Fatalf("%s: got '%s', expected '%s'", TypeName(e.t), s, e.d)
+		}
+	}
+	// names in DNS wire format
+	rr, err := ToDNS("www.test.gnu.", enums.GNS_TYPE_DNS_CNAME, 300, []byte("\x03web\x07example\x03com\x00"))
+	if err != nil || !strings.HasSuffix(rr.String(), "\tweb.example.com.") {
+		t.Fatalf("CNAME (wire format): got '%v' (%v)", rr, err)
+	}
+	// GNS-only types
+	buf, _ := FromText(enums.GNS_TYPE_NICK, "alice")
+	if _, err = ToDNS("www.test.gnu.", enums.GNS_TYPE_NICK, 300, buf); err != ErrNoDNSType {
+		t.Fatal("NICK record converted to DNS")
+	}
+}
@@ -55,9 +55,7 @@ func NewBOX(buf []byte) *BOX {
 // Matches verifies that the remaining labels comply with the values
 // in the BOX record.
 func (b *BOX) Matches(labels []string) bool {
-	// resolve protocol and service names
-	proto, protoName := GetProtocol(labels[0])
-	svc, _ := GetService(labels[1], protoName)
+	proto, svc := ServiceLabels(labels)
 	// no match on invalid resolution
 	if proto == 0 || svc == 0 {
 		return false
@@ -66,6 +64,24 @@ func (b *BOX) Matches(labels []string) bool {
 	return proto == b.Proto && svc == b.Svc
 }
 
+// ServiceLabels returns protocol number and service port for the remaining
+// labels of a name like "_443._tcp.example" in resolution order (protocol
+// label first). Zero values are returned if the labels don't specify a
+// protocol and a service.
+func ServiceLabels(labels []string) (proto, svc uint16) {
+	if len(labels) != 2 {
+		return 0, 0
+	}
+	proto, protoName := GetProtocol(labels[0])
+	if proto == 0 {
+		return 0, 0
+	}
+	if svc, _ = GetService(labels[1], protoName); svc == 0 {
+		return 0, 0
+	}
+	return
+}
+
 // Coexist checks if a new resource record could coexist with given set
 // of records under a label (can be called with a nil receiver)
 func (b *BOX) Coexist([]*enums.GNSSpec, string) (bool, enums.GNSFlag) {
@@ -212,19 +228,18 @@ func GetProtocols() (protos map[uint16]string) {
 // (e.g. like "_tcp").
 func GetProtocol(name string) (uint16, string) {
 	// check for required prefix
-	if name[0] != '_' {
+	if len(name) < 2 || name[0] != '_' {
 		return 0, ""
 	}
 	name = strings.ToLower(name[1:])
 
 	// if label is an integer value it is the protocol number
 	if val, err := strconv.Atoi(name); err == nil {
-		proto := uint16(val)
-		label := GetProtocolName(proto)
-		if len(label) == 0 {
-			proto = 0
+		if val <= 0 || val > 0xffff {
+			return 0, ""
 		}
-		return proto, label
+		proto := uint16(val)
+		return proto, GetProtocolName(proto)
 	}
 	// try to resolve via protocol map
 	if id, ok := protocols[name]; ok {
@@ -337,34 +352,33 @@ func GetServices() (svcs map[uint16]string) {
 }
 
 // GetService returns the port number and the name of a service (with given
-// protocol).  The name can be an integer value (e.g. "_443" for "https") or
-// a mnemonic name (e.g. like "_https").
+// protocol).  The name can be an integer value (e.g. "_443" for "https";
+// any port number is valid) or a mnemonic name (e.g. like "_https").
 func GetService(name, proto string) (uint16, string) {
 	// check for required prefix
-	if name[0] != '_' {
+	if len(name) < 2 || name[0] != '_' {
 		return 0, ""
 	}
 	name = strings.ToLower(name[1:])
 
 	// get list of services for given protocol
-	svcs, ok := services[proto]
-	if !ok {
-		// no services available for this protocol
-		return 0, ""
-	}
+	svcs := services[proto]
 
 	// if label is an integer value it is the port number
 	if val, err := strconv.Atoi(name); err == nil {
+		if val <= 0 || val > 0xffff {
+			// number out of range
+			return 0, ""
+		}
 		svc := uint16(val)
-		// check for valid number (reverse service lookup)
+		// reverse service lookup for the name
 		for label, id := range svcs {
 			if id == svc {
 				// return found entry
 				return svc, label
 			}
 		}
-		// number out of range
-		return 0, ""
+		return svc, name
 	}
 	// try to resolve via services map
 	if id, ok := svcs[name]; ok {
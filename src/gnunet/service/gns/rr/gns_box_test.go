@@ -0,0 +1,48 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import "testing"
+
+func TestServiceLabels(t *testing.T) {
+	cases := []struct {
+		labels     []string
+		proto, svc uint16
+	}{
+		{[]string{"_tcp", "_443"}, 6, 443},
+		{[]string{"_tcp", "_https"}, 6, 443},
+		{[]string{"_6", "_HTTPS"}, 6, 443},
+		{[]string{"_udp", "_5060"}, 17, 5060},
+		{[]string{"_tcp", "_8443"}, 6, 8443},
+		{[]string{"_132", "_9899"}, 132, 9899},
+		{[]string{"_tcp", "_nosuchservice"}, 0, 0},
+		{[]string{"_tcp", "_70000"}, 0, 0},
+		{[]string{"_tcp", "443"}, 0, 0},
+		{[]string{"tcp", "_443"}, 0, 0},
+		{[]string{"_", "_443"}, 0, 0},
+		{[]string{"", "_443"}, 0, 0},
+		{[]string{"_tcp"}, 0, 0},
+	}
+	for _, c := range cases {
+		proto, svc := ServiceLabels(c.labels)
+		if proto != c.proto || svc != c.svc {
+			t.Errorf("%v: expected %d/%d, got %d/%d", c.labels, c.proto, c.svc, proto, svc)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------
+// Record lines: a one-line text representation of a record for CLIs,
+// editors and zonefiles:
+//
+//     <type> <value> <expiration> [<flags>]
+//
+// like "A 1.2.3.4 3600 [private]". The expiration is 'never', a relative
+// lifetime ("3600" seconds or a duration like "1h30m") or an absolute
+// time (RFC3339). Flags are listed in brackets ("[private,shadow]") and
+// are optional. Values are in text representation (see ToText); values
+// without a (lossless) text representation are written as
+// '\# <size> <hex>' (RFC 3597).
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrBadLine       = errors.New("invalid record line")
+	ErrBadExpiration = errors.New("invalid record expiration")
+	ErrUnknownFlag   = errors.New("unknown record flag")
+)
+
+// record flags (by name)
+var flagNames = []struct {
+	name string
+	flag enums.GNSFlag
+}{
+	{"private", enums.GNS_FLAG_PRIVATE},
+	{"shadow", enums.GNS_FLAG_SHADOW},
+	{"suppl", enums.GNS_FLAG_SUPPLEMENTAL},
+	{"critical", enums.GNS_FLAG_CRITICAL},
+}
+
+// ParseRecordLine returns the record for a record line.
+func ParseRecordLine(s string) (rec *blocks.ResourceRecord, err error) {
+	// record type
+	typ, rest := cutField(strings.TrimSpace(s))
+	var t enums.GNSType
+	if t, err = ParseType(typ); err != nil {
+		return
+	}
+	// optional flags and expiration (from the end of the line)
+	var flags enums.GNSFlag
+	rest, field := cutLastField(rest)
+	if strings.HasPrefix(field, "[") {
+		if flags, err = ParseFlags(field); err != nil {
+			return
+		}
+		rest, field = cutLastField(rest)
+	}
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("%w: '%s'", ErrBadLine, s)
+	}
+	exp, relative, err := ParseExpiration(field)
+	if err != nil {
+		return
+	}
+	flags |= relative
+	// record value
+	var data []byte
+	if data, err = ParseValue(t, rest); err != nil {
+		return
+	}
+	rec = &blocks.ResourceRecord{
+		Expire: exp,
+		Size:   uint16(len(data)),
+		RType:  t,
+		Flags:  flags,
+		Data:   data,
+	}
+	return
+}
+
+// RecordLine returns the record line for a record.
+func RecordLine(rec *blocks.ResourceRecord) string {
+	line := fmt.Sprintf("%s %s %s", TypeName(rec.RType), FormatValue(rec.RType, rec.Data), FormatExpiration(rec.Expire, rec.Flags))
+	if f := FormatFlags(rec.Flags); len(f) > 0 {
+		line += " " + f
+	}
+	return line
+}
+
+// ParseExpiration returns the expiration for its text representation
+// ("never", seconds, duration or RFC3339 time); a relative expiration
+// is flagged (GNS_FLAG_RELATIVE_EXPIRATION).
+func ParseExpiration(s string) (exp util.AbsoluteTime, flags enums.GNSFlag, err error) {
+	relative := func(d time.Duration) (util.AbsoluteTime, enums.GNSFlag, error) {
+		return util.AbsoluteTime{Val: uint64(d.Microseconds())}, enums.GNS_FLAG_RELATIVE_EXPIRATION, nil
+	}
+	if strings.EqualFold(s, "never") {
+		return util.AbsoluteTimeNever(), 0, nil
+	}
+	if secs, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return relative(time.Duration(secs) * time.Second)
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return relative(d)
+	}
+	if ts, err := time.Parse(time.RFC3339, s); err == nil {
+		return util.NewAbsoluteTime(ts), 0, nil
+	}
+	err = fmt.Errorf("%w '%s'", ErrBadExpiration, s)
+	return
+}
+
+// FormatExpiration returns the text representation of an expiration
+// (relative lifetimes in seconds if possible).
+func FormatExpiration(exp util.AbsoluteTime, flags enums.GNSFlag) string {
+	switch {
+	case flags&enums.GNS_FLAG_RELATIVE_EXPIRATION != 0:
+		d := time.Duration(exp.Val) * time.Microsecond
+		if d%time.Second == 0 {
+			return strconv.FormatInt(int64(d/time.Second), 10)
+		}
+		return d.String()
+	case exp.IsNever():
+		return "never"
+	}
+	return time.UnixMicro(int64(exp.Val)).UTC().Format(time.RFC3339)
+}
+
+// ParseFlags returns the flags from a list in brackets
+// ("[private,critical]").
+func ParseFlags(s string) (flags enums.GNSFlag, err error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return 0, fmt.Errorf("%w: flags '%s'", ErrBadLine, s)
+	}
+	list := strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if len(list) == 0 {
+		return
+	}
+loop:
+	for _, name := range strings.Split(list, ",") {
+		for _, fn := range flagNames {
+			if strings.EqualFold(name, fn.name) {
+				flags |= fn.flag
+				continue loop
+			}
+		}
+		return 0, fmt.Errorf("%w '%s'", ErrUnknownFlag, name)
+	}
+	return
+}
+
+// FormatFlags returns the list of flags in brackets (or an empty
+// string if no flags are set). The relative expiration flag is not
+// listed (it is part of the expiration).
+func FormatFlags(flags enums.GNSFlag) string {
+	var list []string
+	for _, fn := range flagNames {
+		if flags&fn.flag != 0 {
+			list = append(list, fn.name)
+		}
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(list, ",") + "]"
+}
+
+// ParseValue returns record data for a value in text representation or
+// in generic format ("\# <size> <hex>").
+func ParseValue(t enums.GNSType, s string) ([]byte, error) {
+	if strings.HasPrefix(s, `\#`) {
+		size, hexData := cutField(strings.TrimSpace(s[2:]))
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("%w: generic value size", ErrBadValue)
+		}
+		buf, err := hex.DecodeString(strings.Join(strings.Fields(hexData), ""))
+		if err != nil || len(buf) != n {
+			return nil, fmt.Errorf("%w: generic value", ErrBadValue)
+		}
+		return buf, nil
+	}
+	return FromText(t, s)
+}
+
+// FormatValue returns the value of record data. Data is written in
+// generic format if its text representation can't be parsed back.
+func FormatValue(t enums.GNSType, buf []byte) string {
+	s := ToText(t, buf)
+	if len(s) > 0 && strings.TrimSpace(s) == s && !strings.ContainsAny(s, "\r\n") && !strings.HasPrefix(s, `\#`) {
+		if data, err := FromText(t, s); err == nil && bytes.Equal(data, buf) {
+			return s
+		}
+	}
+	return fmt.Sprintf(`\# %d %s`, len(buf), hex.EncodeToString(buf))
+}
+
+// cutField splits off the first whitespace-separated field.
+func cutField(s string) (field, rest string) {
+	if pos := strings.IndexAny(s, " \t"); pos != -1 {
+		return s[:pos], strings.TrimLeft(s[pos:], " \t")
+	}
+	return s, ""
+}
+
+// cutLastField splits off the last whitespace-separated field.
+func cutLastField(s string) (rest, field string) {
+	if pos := strings.LastIndexAny(s, " \t"); pos != -1 {
+		return strings.TrimRight(s[:pos], " \t"), s[pos+1:]
+	}
+	return "", s
+}
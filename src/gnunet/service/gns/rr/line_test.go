@@ -0,0 +1,122 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+	"testing"
+	"time"
+)
+
+func TestRecordLineRoundTrip(t *testing.T) {
+	pkey, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edkey, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a line for every type with a text format (and a generic value)
+	lines := []string{
+		"PKEY " + pkey.Public().ID() + " 86400",
+		"EDKEY " + edkey.Public().ID() + " 86400 [critical]",
+		"REDIRECT www.example.gnu 3600",
+		"NICK alice never",
+		"LEHO www.example.com 3600",
+		"CNAME www.example.com 600 [shadow]",
+		"TXT v=spf1 -all 3600",
+		"TXT text ending in private 3600 [private]",
+		"A 1.2.3.4 3600 [private]",
+		"AAAA 2001:db8::1 2030-01-01T00:00:00Z",
+		"MX 10 mx.example.com 3600 [private,suppl]",
+		"GNS2DNS example.com@8.8.8.8 3600",
+		"BOX 6 443 TLSA 3 1 1 0badc0de 3600",
+		"TLSA 3 1 1 0badc0de 3600",
+		"SRV 10 5 5060 sip.example.com 3600",
+		"CAA 0005697373756500 3600",
+		`TXT \# 8 2070616464656420 3600`,
+	}
+	for _, line := range lines {
+		rec, err := ParseRecordLine(line)
+		if err != nil {
+			t.Fatalf("'%s': %s", line, err.Error())
+		}
+		if int(rec.Size) != len(rec.Data) {
+			t.Fatalf("'%s': size mismatch", line)
+		}
+		if s := RecordLine(rec); s != line {
+			t.Fatalf("got '%s', expected '%s'", s, line)
+		}
+	}
+	// every type with a text format has a line in the list
+	for typ := range textFormats {
+		found := false
+		for _, line := range lines {
+			rec, _ := ParseRecordLine(line)
+			if rec.RType == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no record line for type %s", TypeName(typ))
+		}
+	}
+}
+
+func TestRecordLineFields(t *testing.T) {
+	rec, err := ParseRecordLine("  txt hello   world  90m [PRIVATE] ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ToText(rec.RType, rec.Data); v != "hello   world" {
+		t.Fatalf("unexpected value '%s'", v)
+	}
+	if rec.Flags != enums.GNS_FLAG_PRIVATE|enums.GNS_FLAG_RELATIVE_EXPIRATION {
+		t.Fatalf("unexpected flags %v", rec.Flags.List())
+	}
+	if d := time.Duration(rec.Expire.Val) * time.Microsecond; d != 90*time.Minute {
+		t.Fatalf("unexpected expiration %s", d)
+	}
+	if s := RecordLine(rec); s != "TXT hello   world 5400 [private]" {
+		t.Fatalf("unexpected line '%s'", s)
+	}
+}
+
+func TestRecordLineInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"A",
+		"A 1.2.3.4",
+		"A 3600",
+		"FOO bar 3600",
+		"A 1.2.3.4 soon",
+		"A 1.2.3.4 3600 [public]",
+		"A 1.2.3.4 3600 [private",
+		"A 2001:db8::1 3600",
+		`TXT \# 4 0102 3600`,
+	} {
+		if _, err := ParseRecordLine(line); err == nil {
+			t.Errorf("invalid line '%s' accepted", line)
+		}
+	}
+}
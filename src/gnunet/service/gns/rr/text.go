@@ -0,0 +1,301 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/bfix/gospel/data"
+)
+
+//----------------------------------------------------------------------
+// Text representation of resource records (used by CLIs and REST).
+// Adding a record type requires a registry entry (enums/gnunet-gns.rec)
+// and a text format in the list below.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrUnknownType = errors.New("unknown record type")
+	ErrBadValue    = errors.New("invalid record value")
+)
+
+// TextFormat converts record data to and from its text representation.
+type TextFormat struct {
+	Format func(buf []byte) (string, error) // record data to text
+	Parse  func(s string) ([]byte, error)   // text to record data
+}
+
+// text formats for record types
+var textFormats map[enums.GNSType]*TextFormat
+
+// set text formats (the BOX format refers to the list itself)
+func init() {
+	textFormats = map[enums.GNSType]*TextFormat{
+		enums.GNS_TYPE_PKEY:      {formatZoneKey, parseZoneKey(enums.GNS_TYPE_PKEY)},
+		enums.GNS_TYPE_EDKEY:     {formatZoneKey, parseZoneKey(enums.GNS_TYPE_EDKEY)},
+		enums.GNS_TYPE_REDIRECT:  {formatString, parseString},
+		enums.GNS_TYPE_NICK:      {formatString, parseString},
+		enums.GNS_TYPE_LEHO:      {formatString, parseString},
+		enums.GNS_TYPE_DNS_CNAME: {formatString, parseString},
+		enums.GNS_TYPE_DNS_TXT:   {formatString, parseString},
+		enums.GNS_TYPE_DNS_A:     {formatIP, parseIPv4},
+		enums.GNS_TYPE_DNS_AAAA:  {formatIP, parseIPv6},
+		enums.GNS_TYPE_DNS_MX:    {formatMX, parseMX},
+		enums.GNS_TYPE_GNS2DNS:   {formatGNS2DNS, parseGNS2DNS},
+		enums.GNS_TYPE_BOX:       {formatBOX, parseBOX},
+		enums.GNS_TYPE_DNS_TLSA:  {formatTLSA, parseTLSA},
+		enums.GNS_TYPE_DNS_SRV:   {formatString, parseString},
+	}
+}
+
+// RegisterTextFormat sets the text format for a record type (e.g. for
+// record types handled by plugins).
+func RegisterTextFormat(t enums.GNSType, tf *TextFormat) {
+	textFormats[t] = tf
+}
+
+// ToText returns the text representation of record data. Record data
+// of types without a text format is hex-encoded.
+func ToText(t enums.GNSType, buf []byte) string {
+	if tf, ok := textFormats[t]; ok {
+		if s, err := tf.Format(buf); err == nil {
+			return s
+		}
+		return "(invalid)"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// FromText converts a text representation to record data. Values of
+// types without a text format must be hex-encoded.
+func FromText(t enums.GNSType, s string) (buf []byte, err error) {
+	if len(s) == 0 {
+		return nil, ErrBadValue
+	}
+	tf, ok := textFormats[t]
+	if !ok {
+		if buf, err = hex.DecodeString(s); err != nil {
+			err = ErrBadValue
+		}
+		return
+	}
+	if buf, err = tf.Parse(s); err != nil && !errors.Is(err, ErrBadValue) {
+		err = fmt.Errorf("%w: %s", ErrBadValue, err.Error())
+	}
+	return
+}
+
+// TypeName returns the short name of a record type ("A", "PKEY") or
+// its number if the type is not registered.
+func TypeName(t enums.GNSType) string {
+	for _, ti := range enums.GNSTypes {
+		if ti.Type == t {
+			return ti.Name
+		}
+	}
+	return strconv.FormatUint(uint64(t), 10)
+}
+
+// ParseType returns the record type for a name ("A", "DNS_A",
+// "GNS_TYPE_DNS_A"; case-insensitive) or a numeric value.
+func ParseType(s string) (enums.GNSType, error) {
+	if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return enums.GNSType(v), nil
+	}
+	s = strings.TrimPrefix(strings.ToUpper(s), "GNS_TYPE_")
+	if strings.EqualFold(s, "ANY") {
+		return enums.GNS_TYPE_ANY, nil
+	}
+	for _, ti := range enums.GNSTypes {
+		if s == ti.Name || s == "DNS_"+ti.Name {
+			return ti.Type, nil
+		}
+	}
+	return 0, ErrUnknownType
+}
+
+//----------------------------------------------------------------------
+// Text formats
+//----------------------------------------------------------------------
+
+// zone delegation (zone key as zTLD)
+func formatZoneKey(buf []byte) (string, error) {
+	zk, err := crypto.NewZoneKey(buf)
+	if err != nil {
+		return "", err
+	}
+	return zk.ID(), nil
+}
+
+func parseZoneKey(t enums.GNSType) func(string) ([]byte, error) {
+	return func(s string) ([]byte, error) {
+		buf, err := util.DecodeStringToBinary(s, (len(s)*5)/8)
+		if err != nil {
+			return nil, err
+		}
+		zk, err := crypto.NewZoneKey(buf)
+		if err != nil {
+			return nil, err
+		}
+		if zk.Type != t || !strings.EqualFold(zk.ID(), s) {
+			return nil, ErrBadValue
+		}
+		return zk.Bytes(), nil
+	}
+}
+
+// string data
+func formatString(buf []byte) (string, error) {
+	s, _ := util.ReadCString(buf, 0)
+	return s, nil
+}
+
+func parseString(s string) ([]byte, error) {
+	return util.WriteCString(s), nil
+}
+
+// IPv4/IPv6 address
+func formatIP(buf []byte) (string, error) {
+	if len(buf) != net.IPv4len && len(buf) != net.IPv6len {
+		return "", ErrBadValue
+	}
+	return net.IP(buf).String(), nil
+}
+
+func parseIPv4(s string) ([]byte, error) {
+	if ip := net.ParseIP(s).To4(); ip != nil {
+		return ip, nil
+	}
+	return nil, ErrBadValue
+}
+
+func parseIPv6(s string) ([]byte, error) {
+	if ip := net.ParseIP(s); ip != nil && ip.To4() == nil {
+		return ip, nil
+	}
+	return nil, ErrBadValue
+}
+
+// DNS MX ("<prio> <host>")
+func formatMX(buf []byte) (string, error) {
+	mx := new(MX)
+	if err := data.Unmarshal(mx, buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s", mx.Prio, mx.Server), nil
+}
+
+func parseMX(s string) ([]byte, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return nil, ErrBadValue
+	}
+	prio, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	return data.Marshal(&MX{Prio: uint16(prio), Server: parts[1]})
+}
+
+// GNS2DNS ("<name>@<server>")
+func formatGNS2DNS(buf []byte) (string, error) {
+	list := util.StringList(buf)
+	if len(list) < 2 {
+		return "", ErrBadValue
+	}
+	return list[0] + "@" + list[1], nil
+}
+
+func parseGNS2DNS(s string) ([]byte, error) {
+	name, server, ok := strings.Cut(s, "@")
+	if !ok || len(name) == 0 || len(server) == 0 {
+		return nil, ErrBadValue
+	}
+	buf := util.WriteCString(name)
+	return append(buf, util.WriteCString(server)...), nil
+}
+
+// BOX ("<proto> <svc> <type> <value>"); the value is the text
+// representation of the embedded record.
+func formatBOX(buf []byte) (string, error) {
+	box := new(BOX)
+	if err := data.Unmarshal(box, buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %s %s", box.Proto, box.Svc, TypeName(box.Type), ToText(box.Type, box.RR)), nil
+}
+
+func parseBOX(s string) (buf []byte, err error) {
+	parts := strings.SplitN(s, " ", 4)
+	if len(parts) != 4 {
+		return nil, ErrBadValue
+	}
+	box := new(BOX)
+	var v [2]uint64
+	for i := range v {
+		if v[i], err = strconv.ParseUint(parts[i], 10, 16); err != nil {
+			return
+		}
+	}
+	box.Proto, box.Svc = uint16(v[0]), uint16(v[1])
+	if box.Type, err = ParseType(parts[2]); err != nil {
+		return
+	}
+	if box.RR, err = FromText(box.Type, parts[3]); err != nil {
+		return
+	}
+	return data.Marshal(box)
+}
+
+// TLSA ("<usage> <selector> <match> <hex-encoded certificate data>")
+func formatTLSA(buf []byte) (string, error) {
+	tlsa := new(TLSA)
+	if err := data.Unmarshal(tlsa, buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", tlsa.Usage, tlsa.Selector, tlsa.Match, hex.EncodeToString(tlsa.Cert)), nil
+}
+
+func parseTLSA(s string) (buf []byte, err error) {
+	parts := strings.Fields(s)
+	if len(parts) != 4 {
+		return nil, ErrBadValue
+	}
+	tlsa := new(TLSA)
+	var v [3]uint64
+	for i := range v {
+		if v[i], err = strconv.ParseUint(parts[i], 10, 8); err != nil {
+			return
+		}
+	}
+	tlsa.Usage, tlsa.Selector, tlsa.Match = uint8(v[0]), uint8(v[1]), uint8(v[2])
+	if tlsa.Cert, err = hex.DecodeString(parts[3]); err != nil {
+		return
+	}
+	return data.Marshal(tlsa)
+}
@@ -0,0 +1,92 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rr
+
+import (
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+	"testing"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []struct {
+		t enums.GNSType
+		v string
+	}{
+		{enums.GNS_TYPE_PKEY, zp.Public().ID()},
+		{enums.GNS_TYPE_NICK, "alice"},
+		{enums.GNS_TYPE_DNS_A, "10.0.0.1"},
+		{enums.GNS_TYPE_DNS_AAAA, "2001:db8::1"},
+		{enums.GNS_TYPE_DNS_TXT, "hello world"},
+		{enums.GNS_TYPE_DNS_MX, "10 mx.example.com"},
+		{enums.GNS_TYPE_GNS2DNS, "example.com@8.8.8.8"},
+		{enums.GNS_TYPE_BOX, "6 443 TLSA 3 1 1 0badc0de"},
+		{enums.GNS_TYPE_BOX, "17 5060 SRV sip.example.com"},
+		{enums.GNS_TYPE_DNS_CAA, "0005697373756500"},
+	}
+	for _, e := range values {
+		buf, err := FromText(e.t, e.v)
+		if err != nil {
+			t.Fatalf("%s '%s': %s", TypeName(e.t), e.v, err.Error())
+		}
+		if s := ToText(e.t, buf); s != e.v {
+			t.Fatalf("%s: got '%s', expected '%s'", TypeName(e.t), s, e.v)
+		}
+	}
+}
+
+func TestTextInvalid(t *testing.T) {
+	values := []struct {
+		t enums.GNSType
+		v string
+	}{
+		{enums.GNS_TYPE_DNS_A, "2001:db8::1"},
+		{enums.GNS_TYPE_DNS_AAAA, "10.0.0.1"},
+		{enums.GNS_TYPE_DNS_MX, "mx.example.com"},
+		{enums.GNS_TYPE_GNS2DNS, "example.com"},
+		{enums.GNS_TYPE_PKEY, "000G0"},
+		{enums.GNS_TYPE_BOX, "6 443 TLSA 3 1"},
+		{enums.GNS_TYPE_DNS_CAA, "xyz"},
+		{enums.GNS_TYPE_NICK, ""},
+	}
+	for _, e := range values {
+		if _, err := FromText(e.t, e.v); err == nil {
+			t.Fatalf("%s: invalid value '%s' accepted", TypeName(e.t), e.v)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, s := range []string{"A", "dns_a", "GNS_TYPE_DNS_A", "1"} {
+		if rt, err := ParseType(s); err != nil || rt != enums.GNS_TYPE_DNS_A {
+			t.Fatalf("'%s': got %d (%v)", s, rt, err)
+		}
+	}
+	if rt, err := ParseType("pkey"); err != nil || rt != enums.GNS_TYPE_PKEY {
+		t.Fatalf("PKEY: got %d (%v)", rt, err)
+	}
+	if _, err := ParseType("FOO"); err == nil {
+		t.Fatal("unknown type accepted")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"time"
 
 	"gnunet/config"
 	"gnunet/core"
@@ -31,6 +32,8 @@ import (
 	"gnunet/message"
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/identity"
+	"gnunet/service/metrics"
 	"gnunet/service/revocation"
 	"gnunet/transport"
 	"gnunet/util"
@@ -46,6 +49,14 @@ var (
 	ErrInvalidResponseType = fmt.Errorf("invald response type")
 )
 
+// GNS metrics
+var (
+	lookups = metrics.NewCounter("gnunet_gns_lookups_total",
+		"GNS lookup requests by result (found, empty, error, rejected).", "result")
+	lookupTime = metrics.NewHistogram("gnunet_gns_lookup_duration_seconds",
+		"Duration of GNS lookups.", nil)
+)
+
 //----------------------------------------------------------------------
 // "GNUnet Name System" service implementation
 //----------------------------------------------------------------------
@@ -56,9 +67,9 @@ type Service struct {
 }
 
 // NewService creates a new GNS service instance
-func NewService(ctx context.Context, c *core.Core) service.Service {
+func NewService(ctx context.Context, c *core.Core, cfg *config.Config) service.Service {
 	// instantiate service
-	mod := NewModule(ctx, c)
+	mod := NewModule(ctx, c, cfg)
 	srv := &Service{
 		Module: *mod,
 	}
@@ -68,6 +79,7 @@ func NewService(ctx context.Context, c *core.Core) service.Service {
 	srv.LookupRemote = srv.LookupDHT
 	srv.RevocationQuery = srv.QueryKeyRevocation
 	srv.RevocationRevoke = srv.RevokeKey
+	srv.LookupEgo = srv.LookupIdentity
 
 	return srv
 }
@@ -111,7 +123,7 @@ func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connectio
 func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
 	// assemble log label
 	label := ""
-	if v := ctx.Value("label"); v != nil {
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
 		label, _ = v.(string)
 	}
 	// perform lookup
@@ -121,12 +133,37 @@ func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg me
 		// GNS_LOOKUP
 		//----------------------------------------------------------
 
+		// check request limit (waits for a free slot in queue mode); a
+		// rejected lookup gets an empty result.
+		if err := s.Limiter().AcquireRequest(ctx); err != nil {
+			logger.Printf(logger.WARN, "[gns%s] Lookup request rejected: %s\n", label, err.Error())
+			lookups.Inc("rejected")
+			if err = back.Send(ctx, message.NewGNSLookupResultMsg(m.ID)); err != nil {
+				logger.Printf(logger.ERROR, "[gns%s] Failed to send response: %s\n", label, err.Error())
+			}
+			return true
+		}
 		// perform lookup on block (locally and remote)
 		go func(m *message.LookupMsg, label string) {
 			logger.Printf(logger.INFO, "[gns%s] Lookup request received.\n", label)
 			resp := message.NewGNSLookupResultMsg(m.ID)
+			var (
+				trace *Trace
+				prov  *Provenance
+			)
 			defer func() {
-				// send response
+				s.Limiter().ReleaseRequest()
+				// send trace and provenance (if requested) and response
+				if resp != nil && trace != nil {
+					if err := back.Send(ctx, trace.Message(m.ID)); err != nil {
+						logger.Printf(logger.ERROR, "[gns%s] Failed to send trace: %s\n", label, err.Error())
+					}
+				}
+				if resp != nil && prov != nil {
+					if err := back.Send(ctx, prov.Message(m.ID)); err != nil {
+						logger.Printf(logger.ERROR, "[gns%s] Failed to send provenance: %s\n", label, err.Error())
+					}
+				}
 				if resp != nil {
 					if err := back.Send(ctx, resp); err != nil {
 						logger.Printf(logger.ERROR, "[gns%s] Failed to send response: %s\n", label, err.Error())
@@ -136,8 +173,39 @@ func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg me
 				logger.Printf(logger.DBG, "[gns%s] Lookup request finished.\n", label)
 			}()
 
+			// handle option flags
+			mode, rctx := int(m.Options), ctx
+			if mode&enums.GNS_LO_NO_NEGCACHE != 0 {
+				mode &^= enums.GNS_LO_NO_NEGCACHE
+				rctx = context.WithValue(ctx, CtxNoNegCache, true)
+			}
+			if mode&enums.GNS_LO_TRACE != 0 {
+				mode &^= enums.GNS_LO_TRACE
+				trace = NewTrace()
+				rctx = context.WithValue(rctx, CtxTrace, trace)
+			}
+			if mode&enums.GNS_LO_PROVENANCE != 0 {
+				mode &^= enums.GNS_LO_PROVENANCE
+				prov = NewProvenance()
+				rctx = context.WithValue(rctx, CtxProvenance, prov)
+			}
+			// names are resolved relative to the given zone (if any)
+			zone := m.Zone
+			if zone != nil && zone.IsNull() {
+				zone = nil
+			}
 			kind := NewRRTypeList(m.RType)
-			recset, err := s.Resolve(ctx, label, m.Zone, kind, int(m.Options), 0)
+			start := time.Now()
+			recset, err := s.Resolve(rctx, m.GetName(), zone, kind, mode, 0)
+			lookupTime.ObserveSince(start)
+			switch {
+			case err != nil:
+				lookups.Inc("error")
+			case recset == nil || recset.Count == 0:
+				lookups.Inc("empty")
+			default:
+				lookups.Inc("found")
+			}
 			if err != nil {
 				logger.Printf(logger.ERROR, "[gns%s] Failed to lookup block: %s\n", label, err.Error())
 				if err == service.ErrConnectionInterrupted {
@@ -162,7 +230,8 @@ func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg me
 					if rec.RType == m.RType || m.RType == enums.GNS_TYPE_ANY {
 						// add it to the response message
 						if err := resp.AddRecord(rec); err != nil {
-							logger.Printf(logger.ERROR, "[gns%s] failed: %sv", label, err.Error())
+							logger.Printf(logger.WARN, "[gns%s] result truncated: %s", label, err.Error())
+							break
 						}
 					}
 				}
@@ -192,7 +261,7 @@ func (s *Service) QueryKeyRevocation(ctx context.Context, zkey *crypto.ZoneKey)
 
 	// get response from Revocation service
 	var resp message.Message
-	if resp, err = service.RequestResponse(ctx, "gns", "Revocation", config.Cfg.Revocation.Service.Socket, req, true); err != nil {
+	if resp, err = service.RequestResponse(ctx, "gns", "Revocation", s.cfg.Revocation.Service.Socket, req, true); err != nil {
 		return
 	}
 
@@ -211,14 +280,11 @@ func (s *Service) RevokeKey(ctx context.Context, rd *revocation.RevData) (succes
 	logger.Printf(logger.DBG, "[gns] RevokeKey(%s)...\n", rd.ZoneKeySig.ID())
 
 	// assemble request
-	req := message.NewRevocationRevokeMsg(nil)
-	req.Timestamp = rd.Timestamp
-	copy(req.PoWs, rd.PoWs)
-	req.ZoneKeySig = rd.ZoneKeySig
+	req := rd.Message()
 
 	// get response from Revocation service
 	var resp message.Message
-	if resp, err = service.RequestResponse(ctx, "gns", "Revocation", config.Cfg.Revocation.Service.Socket, req, true); err != nil {
+	if resp, err = service.RequestResponse(ctx, "gns", "Revocation", s.cfg.Revocation.Service.Socket, req, true); err != nil {
 		return
 	}
 
@@ -229,6 +295,52 @@ func (s *Service) RevokeKey(ctx context.Context, rd *revocation.RevData) (succes
 	case *message.RevocationRevokeResponseMsg:
 		success = (m.Success == 1)
 	}
+	// the filter of revoked keys has changed
+	if success && s.revFilter != nil {
+		s.revFilter.Refresh()
+	}
+	return
+}
+
+//======================================================================
+// Identity-related methods
+//======================================================================
+
+// LookupIdentity returns the zone key of a local ego (or nil if no ego
+// with that name exists). Egos are served by the identity service (if
+// configured) or by the zonemaster.
+func (s *Service) LookupIdentity(ctx context.Context, name string) (zkey *crypto.ZoneKey, err error) {
+	logger.Printf(logger.DBG, "[gns] LookupIdentity(%s)...\n", name)
+
+	// get ego from identity service
+	if cfg := s.cfg.Identity; cfg != nil && cfg.Service != nil {
+		var zk *crypto.ZonePrivate
+		if zk, err = identity.LookupEgo(ctx, "gns", cfg.Service.Socket, name); err != nil {
+			if err == identity.ErrEgoUnknown {
+				err = nil
+			}
+			return
+		}
+		return zk.Public(), nil
+	}
+	// get response from Identity service (served by the zonemaster)
+	req := message.NewIdentityLookupMsg(name)
+	var resp message.Message
+	if resp, err = service.RequestResponse(ctx, "gns", "Identity", s.cfg.ZoneMaster.Service.Socket, req, true); err != nil {
+		return
+	}
+	// handle message depending on its type
+	switch m := resp.(type) {
+	case *message.IdentityUpdateMsg:
+		if m.ZoneKey != nil && m.Name() == name {
+			zkey = m.ZoneKey.Public()
+		}
+	case *message.IdentityResultCodeMsg:
+		// no ego with that name
+	default:
+		logger.Printf(logger.ERROR, "[gns] Got invalid response type (%s)\n", m.Type())
+		err = ErrInvalidResponseType
+	}
 	return
 }
 
@@ -247,7 +359,7 @@ func (s *Service) LookupNamecache(ctx context.Context, query *blocks.GNSQuery) (
 
 	// get response from Namecache service
 	var resp message.Message
-	if resp, err = service.RequestResponse(ctx, "gns", "Namecache", config.Cfg.Namecache.Service.Socket, req, true); err != nil {
+	if resp, err = service.RequestResponse(ctx, "gns", "Namecache", s.cfg.Namecache.Service.Socket, req, true); err != nil {
 		return
 	}
 
@@ -308,7 +420,7 @@ func (s *Service) StoreNamecache(ctx context.Context, query *blocks.GNSQuery, bl
 
 	// get response from Namecache service
 	var resp message.Message
-	if resp, err = service.RequestResponse(ctx, "gns", "Namecache", config.Cfg.Namecache.Service.Socket, req, true); err != nil {
+	if resp, err = service.RequestResponse(ctx, "gns", "Namecache", s.cfg.Namecache.Service.Socket, req, true); err != nil {
 		return
 	}
 
@@ -345,7 +457,7 @@ func (s *Service) LookupDHT(ctx context.Context, query blocks.Query) (block bloc
 
 	// client-connect to the DHT service
 	logger.Println(logger.DBG, "[gns] Connecting to DHT service...")
-	cl, err := service.NewClient(ctx, config.Cfg.DHT.Service.Socket)
+	cl, err := service.NewClient(ctx, s.cfg.DHT.Service.Socket)
 	if err != nil {
 		return nil, err
 	}
@@ -353,6 +465,8 @@ func (s *Service) LookupDHT(ctx context.Context, query blocks.Query) (block bloc
 		logger.Println(logger.DBG, "[gns] Closing connection to DHT service")
 		cl.Close()
 	}()
+	// a restarted DHT service gets the pending request again
+	cl.SetReconnect(true, nil)
 
 	var (
 		// response received from service
@@ -433,12 +547,6 @@ func (s *Service) LookupDHT(ctx context.Context, query blocks.Query) (block bloc
 		if err = qGNS.Decrypt(block); err != nil {
 			break
 		}
-
-		// we got a result from DHT that was not in the namecache,
-		// so store it there now.
-		if err = s.StoreNamecache(ctx, qGNS, block.(*blocks.GNSBlock)); err != nil {
-			logger.Printf(logger.ERROR, "[gns] can't store block in Namecache: %s\n", err.Error())
-		}
 	}
 	return
 }
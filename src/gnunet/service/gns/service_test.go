@@ -0,0 +1,386 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// responder collecting messages sent back to a client
+type testResponder struct {
+	msgs chan message.Message
+}
+
+func (r *testResponder) Send(ctx context.Context, msg message.Message) error {
+	r.msgs <- msg
+	return nil
+}
+
+func (r *testResponder) Receiver() *util.PeerID {
+	return nil
+}
+
+// configuration of test modules
+var testConfig = &config.Config{
+	GNS: &config.GNSConfig{MaxDepth: 10},
+}
+
+func TestServiceLookup(t *testing.T) {
+	// zone with a single A record for 'www'
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   4,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{192, 0, 2, 1},
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the namecache is unavailable; the block is found in the DHT
+	numRemote := 0
+	srv := &Service{
+		Module: Module{
+			LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+				return nil, errors.New("namecache not available")
+			},
+			StoreLocal: func(context.Context, *blocks.GNSQuery, *blocks.GNSBlock) error {
+				return errors.New("namecache not available")
+			},
+			LookupRemote: func(_ context.Context, q blocks.Query) (blocks.Block, error) {
+				numRemote++
+				if !q.Key().Equal(blocks.NewGNSQuery(zk, "www").Key()) {
+					return nil, nil
+				}
+				out, _ := blocks.NewGNSBlockFromRRBLOCK(blk.RRBLOCK())
+				gq, _ := q.(*blocks.GNSQuery)
+				return out, gq.Decrypt(out)
+			},
+			RevocationQuery: func(context.Context, *crypto.ZoneKey) (bool, error) {
+				return true, nil
+			},
+			cache: NewBlockCache(8),
+			cfg:   testConfig,
+		},
+	}
+	lookup := func(id uint32) *message.LookupResultMsg {
+		req := message.NewGNSLookupMsg()
+		req.ID = id
+		req.Zone = zk
+		req.RType = enums.GNS_TYPE_DNS_A
+		req.SetName("www")
+		back := &testResponder{msgs: make(chan message.Message, 2)}
+		ctx := context.WithValue(context.Background(), core.CtxKey("label"), ":1:1")
+		if !srv.HandleMessage(ctx, nil, req, back) {
+			t.Fatal("lookup not handled")
+		}
+		select {
+		case msg := <-back.msgs:
+			res, ok := msg.(*message.LookupResultMsg)
+			if !ok || res.ID != id {
+				t.Fatalf("unexpected response %v", msg)
+			}
+			return res
+		case <-time.After(5 * time.Second):
+			t.Fatal("no response")
+		}
+		return nil
+	}
+	for i := uint32(1); i <= 2; i++ {
+		res := lookup(i)
+		if res.Count != 1 || res.Records[0].RType != enums.GNS_TYPE_DNS_A || res.Records[0].Data[3] != 1 {
+			t.Fatalf("unexpected result %v", res)
+		}
+	}
+	// second lookup is served from the block cache
+	if numRemote != 1 {
+		t.Fatalf("expected 1 remote lookup, got %d", numRemote)
+	}
+}
+
+func TestStartZones(t *testing.T) {
+	// zone with a single A record for 'www'
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   4,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{192, 0, 2, 1},
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "www", rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Service{
+		Module: Module{
+			LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+				return nil, nil
+			},
+			StoreLocal: func(context.Context, *blocks.GNSQuery, *blocks.GNSBlock) error {
+				return nil
+			},
+			LookupRemote: func(_ context.Context, q blocks.Query) (blocks.Block, error) {
+				if !q.Key().Equal(blocks.NewGNSQuery(zk, "www").Key()) {
+					return nil, nil
+				}
+				out, _ := blocks.NewGNSBlockFromRRBLOCK(blk.RRBLOCK())
+				gq, _ := q.(*blocks.GNSQuery)
+				return out, gq.Decrypt(out)
+			},
+			RevocationQuery: func(context.Context, *crypto.ZoneKey) (bool, error) {
+				return true, nil
+			},
+			LookupEgo: func(_ context.Context, name string) (*crypto.ZoneKey, error) {
+				if name == "alice" {
+					return zk, nil
+				}
+				return nil, nil
+			},
+			zones: make(map[string]*crypto.ZoneKey),
+			cfg:   testConfig,
+		},
+	}
+	ztld, _ := names.ZoneTLD(zk)
+	if err = srv.AddStartZone("Home", ztld); err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.AddStartZone("bad", "foo"); err != ErrInvalidZone {
+		t.Fatalf("invalid zone accepted: %v", err)
+	}
+	// lookup a name without zone (as sent by a client)
+	lookup := func(name string) *message.LookupResultMsg {
+		req := message.NewGNSLookupMsg()
+		req.ID = 1
+		req.Zone, _ = crypto.NullZoneKey(enums.GNS_TYPE_PKEY)
+		req.RType = enums.GNS_TYPE_DNS_A
+		req.SetName(name)
+		buf, err := data.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := message.NewGNSLookupMsg()
+		if err = data.Unmarshal(in, buf); err != nil {
+			t.Fatal(err)
+		}
+		back := &testResponder{msgs: make(chan message.Message, 2)}
+		if !srv.HandleMessage(context.Background(), nil, in, back) {
+			t.Fatal("lookup not handled")
+		}
+		select {
+		case msg := <-back.msgs:
+			res, ok := msg.(*message.LookupResultMsg)
+			if !ok {
+				t.Fatalf("unexpected response %v", msg)
+			}
+			return res
+		case <-time.After(5 * time.Second):
+			t.Fatal("no response")
+		}
+		return nil
+	}
+	for _, tc := range []struct {
+		name  string
+		count uint32
+	}{
+		{"www.home", 1},    // configured start zone
+		{"www.alice", 0},   // ego lookups disabled
+		{"www." + ztld, 1}, // zTLD
+		{"www.nowhere", 0}, // unknown TLD
+	} {
+		if res := lookup(tc.name); res.Count != tc.count {
+			t.Fatalf("%s: expected %d records, got %d", tc.name, tc.count, res.Count)
+		}
+		if tc.name == "www.alice" {
+			// enable ego lookups
+			srv.egos = true
+			if res := lookup(tc.name); res.Count != 1 {
+				t.Fatalf("%s: expected 1 record, got %d", tc.name, res.Count)
+			}
+		}
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	bc := NewBlockCache(2)
+	block := func(expire util.AbsoluteTime) *blocks.GNSBlock {
+		blk, err := blocks.NewGNSBlockFromRecords(zp, "a", blocks.NewRecordSet(), expire)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return blk
+	}
+	valid := util.AbsoluteTimeNow().Add(time.Hour)
+	qa, qb, qc := blocks.NewGNSQuery(zk, "a"), blocks.NewGNSQuery(zk, "b"), blocks.NewGNSQuery(zk, "c")
+	bc.Add(qa, block(valid))
+	bc.Add(qb, block(valid))
+	// 'a' is used, so 'b' is dropped for 'c'
+	if bc.Get(qa) == nil {
+		t.Fatal("cached block not found")
+	}
+	bc.Add(qc, block(valid))
+	if bc.Size() != 2 || bc.Get(qb) != nil || bc.Get(qa) == nil || bc.Get(qc) == nil {
+		t.Fatal("unexpected cache content")
+	}
+	// expired blocks are not returned
+	bc.Add(qb, block(util.AbsoluteTimeNow().Add(time.Millisecond)))
+	time.Sleep(5 * time.Millisecond)
+	if bc.Get(qb) != nil {
+		t.Fatal("expired block returned")
+	}
+}
+
+func TestResolveBox(t *testing.T) {
+	// zone with BOXed TLSA and SRV records (and an A record) for 'example'
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	for _, box := range []struct {
+		proto, svc uint16
+		rtype      enums.GNSType
+		data       []byte
+	}{
+		{6, 443, enums.GNS_TYPE_DNS_TLSA, []byte{3, 1, 1, 0x0b, 0xad, 0xc0, 0xde}},
+		{17, 5060, enums.GNS_TYPE_DNS_SRV, util.WriteCString("sip.example.com")},
+	} {
+		rec, err := blocks.NewBoxRecord(box.proto, box.svc, &blocks.ResourceRecord{
+			Expire: expire,
+			RType:  box.rtype,
+			Data:   box.data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.AddRecord(rec)
+	}
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   4,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{192, 0, 2, 1},
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, "example", rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lookups []string
+	m := &Module{
+		LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+			return nil, nil
+		},
+		StoreLocal: func(context.Context, *blocks.GNSQuery, *blocks.GNSBlock) error {
+			return nil
+		},
+		LookupRemote: func(_ context.Context, q blocks.Query) (blocks.Block, error) {
+			gq, _ := q.(*blocks.GNSQuery)
+			lookups = append(lookups, gq.Label)
+			if !q.Key().Equal(blocks.NewGNSQuery(zk, "example").Key()) {
+				return nil, nil
+			}
+			out, _ := blocks.NewGNSBlockFromRRBLOCK(blk.RRBLOCK())
+			return out, gq.Decrypt(out)
+		},
+		RevocationQuery: func(context.Context, *crypto.ZoneKey) (bool, error) {
+			return true, nil
+		},
+		cfg: testConfig,
+	}
+	cases := []struct {
+		name  string
+		rtype enums.GNSType
+		num   int
+	}{
+		{"_443._tcp.example", enums.GNS_TYPE_DNS_TLSA, 1},
+		{"_https._tcp.example", enums.GNS_TYPE_DNS_TLSA, 1},
+		{"_5060._udp.example", enums.GNS_TYPE_DNS_SRV, 1},
+		{"_443._tcp.example", enums.GNS_TYPE_DNS_A, 0},
+		{"example", enums.GNS_TYPE_BOX, 2},
+		{"example", enums.GNS_TYPE_DNS_TLSA, 0},
+	}
+	for _, c := range cases {
+		lookups = nil
+		set, err := m.Resolve(context.Background(), c.name, zk, NewRRTypeList(c.rtype), enums.GNS_LO_DEFAULT, 0)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if int(set.Count) != c.num {
+			t.Fatalf("%s/%s: expected %d records, got %d", c.name, c.rtype, c.num, set.Count)
+		}
+		for _, rec := range set.Records {
+			if rec.RType != c.rtype {
+				t.Fatalf("%s: unexpected record type %s", c.name, rec.RType)
+			}
+		}
+		// resolution ends at the label with the BOX records
+		if len(lookups) != 1 {
+			t.Fatalf("%s: unexpected lookups %v", c.name, lookups)
+		}
+	}
+	// unboxed TLSA record
+	set, _ := m.Resolve(context.Background(), "_443._tcp.example", zk, NewRRTypeList(enums.GNS_TYPE_DNS_TLSA), enums.GNS_LO_DEFAULT, 0)
+	if rec := set.Records[0]; rec.Data[0] != 3 || len(rec.Data) != 7 || rec.Expire.Compare(expire) != 0 {
+		t.Fatalf("unexpected TLSA record %s", rec)
+	}
+	// no BOX for the service: resolution continues with the labels
+	lookups = nil
+	if set, err = m.Resolve(context.Background(), "_80._tcp.example", zk, NewRRTypeList(enums.GNS_TYPE_DNS_TLSA), enums.GNS_LO_DEFAULT, 0); err != nil {
+		t.Fatal(err)
+	}
+	if set != nil && set.Count != 0 {
+		t.Fatalf("unexpected records for port 80: %d", set.Count)
+	}
+	if len(lookups) != 2 {
+		t.Fatalf("unexpected lookups %v", lookups)
+	}
+}
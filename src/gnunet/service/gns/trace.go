@@ -0,0 +1,86 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/message"
+)
+
+// CtxTrace is the context key for the trace of a lookup (value is
+// *Trace); steps are only recorded if a trace is present.
+const CtxTrace = core.CtxKey("gns:trace")
+
+// Trace records the steps taken during the resolution of a name (zones
+// traversed, query keys, cache hits and timing).
+type Trace struct {
+	sync.Mutex
+
+	start time.Time               // start of resolution
+	steps []*message.GNSTraceStep // list of steps
+}
+
+// NewTrace starts a new trace.
+func NewTrace() *Trace {
+	return &Trace{
+		start: time.Now(),
+		steps: make([]*message.GNSTraceStep, 0),
+	}
+}
+
+// Add a step that started at given time and has finished now.
+func (t *Trace) Add(kind int, zone, label string, query *crypto.HashCode, started time.Time) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	step := message.NewGNSTraceStep(kind, zone, label, query, started.Sub(t.start), time.Since(started))
+	t.steps = append(t.steps, step)
+}
+
+// Steps returns the recorded steps.
+func (t *Trace) Steps() []*message.GNSTraceStep {
+	t.Lock()
+	defer t.Unlock()
+	return t.steps
+}
+
+// Message returns the trace as a response message for lookup request
+// with given identifier. Steps exceeding the message size are dropped.
+func (t *Trace) Message(id uint32) *message.LookupTraceMsg {
+	msg := message.NewGNSLookupTraceMsg(id)
+	for _, step := range t.Steps() {
+		if !msg.AddStep(step) {
+			break
+		}
+	}
+	return msg
+}
+
+// traceFromContext returns the lookup trace (or nil).
+func traceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(CtxTrace).(*Trace)
+	return t
+}
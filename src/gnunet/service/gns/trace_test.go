@@ -0,0 +1,102 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package gns
+
+import (
+	"context"
+	"testing"
+
+	"gnunet/config"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+func TestLookupTrace(t *testing.T) {
+	nc, err := NewNegativeCache(&config.NegCacheConfig{TTL: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Module{
+		LookupLocal: func(context.Context, *blocks.GNSQuery) (*blocks.GNSBlock, error) {
+			return nil, nil
+		},
+		LookupRemote: func(context.Context, blocks.Query) (blocks.Block, error) {
+			return nil, nil
+		},
+		negCache: nc,
+	}
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+
+	// two lookups: DHT (not found), negative cache hit
+	trace := NewTrace()
+	ctx := context.WithValue(context.Background(), CtxTrace, trace)
+	for i := 0; i < 2; i++ {
+		if _, err = m.Lookup(ctx, zk, "www", enums.GNS_LO_DEFAULT); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// lookups without trace are not recorded
+	if _, err = m.Lookup(context.Background(), zk, "www", enums.GNS_LO_DEFAULT); err != nil {
+		t.Fatal(err)
+	}
+	steps := trace.Steps()
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(steps))
+	}
+	query := blocks.NewGNSQuery(zk, "www")
+	for i, kind := range []int{enums.GNS_TRACE_NOTFOUND, enums.GNS_TRACE_NEGCACHE} {
+		s := steps[i]
+		if int(s.Kind) != kind {
+			t.Fatalf("step %d: kind %s", i, s.KindString())
+		}
+		if string(s.Zone) != zk.ID() || string(s.Label) != "www" || !s.Query.Equal(query.Key()) {
+			t.Fatalf("step %d: %s", i, s)
+		}
+	}
+
+	// transfer trace in a message
+	msg := trace.Message(23)
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.MsgSize) {
+		t.Fatalf("message size mismatch: %d != %d", len(buf), msg.MsgSize)
+	}
+	msg2, _ := message.NewEmptyMessage(enums.MSG_GNS_LOOKUP_TRACE)
+	if err = data.Unmarshal(msg2, buf); err != nil {
+		t.Fatal(err)
+	}
+	tm, ok := msg2.(*message.LookupTraceMsg)
+	if !ok || tm.ID != 23 || tm.Count != 2 {
+		t.Fatalf("unexpected trace message %v", msg2)
+	}
+	if tm.Steps[1].String() != steps[1].String() {
+		t.Fatalf("step mismatch: %s != %s", tm.Steps[1], steps[1])
+	}
+}
@@ -0,0 +1,314 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrNoProfiling = errors.New("no profiling endpoint configured")
+)
+
+//----------------------------------------------------------------------
+// Health introspection: Modules (and socket handlers) register functions
+// that report internal state like queue depths, active result handlers,
+// open client sessions and cache sizes. Together with goroutine counts
+// (grouped by the package that started a goroutine) a stuck node can be
+// diagnosed over JSON-RPC without attaching a debugger.
+//----------------------------------------------------------------------
+
+// Introspection holds internal health details of a module.
+type Introspection struct {
+	Module   string         `json:"module"`           // name of module
+	Queues   map[string]int `json:"queues,omitempty"` // pending items per queue
+	Handlers int            `json:"handlers"`         // active result handlers
+	Clients  int            `json:"clients"`          // open client sessions
+	Peers    int            `json:"peers"`            // connected peers
+	Caches   map[string]int `json:"caches,omitempty"` // entries per cache
+
+	Disconnects map[string]int `json:"disconnects,omitempty"` // disconnected peers per reason
+	ClockOffset string         `json:"clockOffset,omitempty"` // estimated offset of local clock
+}
+
+// merge details from another introspection (of the same module)
+func (in *Introspection) merge(o *Introspection) {
+	add := func(dst map[string]int, src map[string]int) map[string]int {
+		if len(src) == 0 {
+			return dst
+		}
+		if dst == nil {
+			dst = make(map[string]int)
+		}
+		for k, v := range src {
+			dst[k] += v
+		}
+		return dst
+	}
+	in.Queues = add(in.Queues, o.Queues)
+	in.Caches = add(in.Caches, o.Caches)
+	in.Disconnects = add(in.Disconnects, o.Disconnects)
+	if len(o.ClockOffset) > 0 {
+		in.ClockOffset = o.ClockOffset
+	}
+	in.Handlers += o.Handlers
+	in.Clients += o.Clients
+	in.Peers += o.Peers
+}
+
+// IntrospectFunc returns the current health details of a module.
+type IntrospectFunc func() *Introspection
+
+// introspectors of all modules (in this process)
+var (
+	introspectors     = make(map[string][]IntrospectFunc)
+	introspectorsLock sync.Mutex
+)
+
+// RegisterIntrospector adds a function reporting health details for the
+// named module. Multiple functions for the same module are merged.
+func RegisterIntrospector(name string, f IntrospectFunc) {
+	introspectorsLock.Lock()
+	defer introspectorsLock.Unlock()
+	introspectors[name] = append(introspectors[name], f)
+}
+
+// Introspect returns the health details of named modules (all modules if
+// no names are given), sorted by name.
+func Introspect(names ...string) []*Introspection {
+	introspectorsLock.Lock()
+	defer introspectorsLock.Unlock()
+	out := make([]*Introspection, 0)
+	for name, list := range introspectors {
+		if len(names) > 0 && !contains(names, name) {
+			continue
+		}
+		in := &Introspection{Module: name}
+		for _, f := range list {
+			if o := f(); o != nil {
+				in.merge(o)
+			}
+		}
+		out = append(out, in)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Module < out[j].Module
+	})
+	return out
+}
+
+//----------------------------------------------------------------------
+// Goroutine statistics
+//----------------------------------------------------------------------
+
+// stacks returns the stack traces of all goroutines.
+func stacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Goroutines returns the number of goroutines grouped by subsystem (the
+// package of the function that started them; gnunet packages without
+// the module prefix). Goroutines not started by another goroutine are
+// counted as "main".
+func Goroutines() map[string]int {
+	return countGoroutines(string(stacks()))
+}
+
+// countGoroutines in a stack dump by subsystem.
+func countGoroutines(dump string) map[string]int {
+	out := make(map[string]int)
+	for _, trace := range strings.Split(dump, "\n\n") {
+		if !strings.HasPrefix(trace, "goroutine ") {
+			continue
+		}
+		sub := "main"
+		for _, line := range strings.Split(trace, "\n") {
+			if fn, ok := strings.CutPrefix(line, "created by "); ok {
+				sub = subsystem(strings.Fields(fn)[0])
+				break
+			}
+		}
+		out[sub]++
+	}
+	return out
+}
+
+// subsystem returns the package name of a (qualified) function name,
+// e.g. "gnunet/service/dht.(*Module).Get" returns "service/dht".
+func subsystem(fn string) string {
+	pkg := fn
+	pos := strings.LastIndex(pkg, "/")
+	if idx := strings.Index(pkg[pos+1:], "."); idx >= 0 {
+		pkg = pkg[:pos+1+idx]
+	}
+	return strings.TrimPrefix(pkg, "gnunet/")
+}
+
+//----------------------------------------------------------------------
+// Profiling: The pprof handlers can be switched on and off at runtime
+// on a separate (configured) endpoint; they are never exposed on the
+// JSON-RPC endpoint.
+//----------------------------------------------------------------------
+
+// profiler serves pprof handlers on demand.
+type profiler struct {
+	sync.Mutex
+
+	endpoint string       // listen address (or empty)
+	srv      *http.Server // running server (or nil)
+}
+
+// set switches the pprof server on or off. Returns true if running.
+func (p *profiler) set(on bool) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.endpoint) == 0 {
+		return false, ErrNoProfiling
+	}
+	if on == (p.srv != nil) {
+		return on, nil
+	}
+	if !on {
+		err := p.srv.Close()
+		p.srv = nil
+		logger.Println(logger.INFO, "[health] profiling stopped")
+		return false, err
+	}
+	lst, err := net.Listen("tcp", p.endpoint)
+	if err != nil {
+		return false, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	p.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func(srv *http.Server) {
+		if err := srv.Serve(lst); err != http.ErrServerClosed {
+			logger.Printf(logger.WARN, "[health] profiling server failed: %s", err.Error())
+		}
+	}(p.srv)
+	logger.Printf(logger.INFO, "[health] profiling on http://%s/debug/pprof/", p.endpoint)
+	return true, nil
+}
+
+//----------------------------------------------------------------------
+// Commands "Health.Dump" and "Health.Profiling"
+//----------------------------------------------------------------------
+
+// HealthRPC is a type for JSON-RPC requests on internal health details.
+type HealthRPC struct {
+	prof *profiler
+}
+
+// HealthDumpRequest asks for health details of named modules (all modules
+// if the list is empty) and optionally for the stack traces of all
+// goroutines.
+type HealthDumpRequest struct {
+	Modules []string `json:"modules"`
+	Stacks  bool     `json:"stacks"`
+}
+
+// HealthDumpResponse lists goroutine counts and module health details.
+type HealthDumpResponse struct {
+	Goroutines int              `json:"goroutines"`       // total number of goroutines
+	Subsystems map[string]int   `json:"subsystems"`       // goroutines by subsystem
+	Modules    []*Introspection `json:"modules"`          // module details
+	Limits     []LimitStats     `json:"limits"`           // resource limits
+	Profiling  bool             `json:"profiling"`        // pprof server running
+	Degraded   bool             `json:"degraded"`         // an alert is firing
+	Alerts     []AlertStatus    `json:"alerts,omitempty"` // state of alert rules
+	Stacks     string           `json:"stacks,omitempty"` // goroutine stack traces
+}
+
+// Dump returns the health details of a running service.
+func (s *HealthRPC) Dump(r *http.Request, req *HealthDumpRequest, reply *HealthDumpResponse) error {
+	dump := string(stacks())
+	reply.Subsystems = countGoroutines(dump)
+	for _, n := range reply.Subsystems {
+		reply.Goroutines += n
+	}
+	reply.Modules = Introspect(req.Modules...)
+	var limits LimitStatsResponse
+	if err := new(LimitsRPC).Stats(r, &LimitStatsRequest{Services: req.Modules}, &limits); err != nil {
+		return err
+	}
+	reply.Limits = limits.Stats
+	reply.Alerts, reply.Degraded = Alerts()
+	s.prof.Lock()
+	reply.Profiling = s.prof.srv != nil
+	s.prof.Unlock()
+	if req.Stacks {
+		reply.Stacks = dump
+	}
+	return nil
+}
+
+// ProfilingRequest switches the pprof endpoint on or off.
+type ProfilingRequest struct {
+	Enable bool `json:"enable"`
+}
+
+// ProfilingResponse reports the state of the pprof endpoint.
+type ProfilingResponse struct {
+	Endpoint string `json:"endpoint"`
+	Active   bool   `json:"active"`
+}
+
+// Profiling switches the pprof endpoint on or off.
+func (s *HealthRPC) Profiling(r *http.Request, req *ProfilingRequest, reply *ProfilingResponse) (err error) {
+	reply.Endpoint = s.prof.endpoint
+	reply.Active, err = s.prof.set(req.Enable)
+	return
+}
+
+// InitHealthRPC registers the RPC commands for health introspection. The
+// pprof handlers are served on 'endpoint' if switched on (profiling is
+// disabled if the endpoint is empty).
+func InitHealthRPC(srv *JRPCServer, endpoint string) {
+	hs := &HealthRPC{
+		prof: &profiler{endpoint: strings.TrimPrefix(endpoint, "tcp:")},
+	}
+	if err := srv.RegisterService(hs, "Health"); err != nil {
+		logger.Printf(logger.ERROR, "[health] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"testing"
+)
+
+func TestCountGoroutines(t *testing.T) {
+	dump := `goroutine 1 [running]:
+main.main()
+	/src/main.go:10 +0x1d
+
+goroutine 7 [chan receive]:
+gnunet/service/dht.(*Module).heartbeat(0xc000010000)
+	/src/service/dht/module.go:300 +0x25
+created by gnunet/service/dht.NewModule in goroutine 1
+	/src/service/dht/module.go:150 +0x1a5
+
+goroutine 8 [select]:
+gnunet/service/dht.(*Module).Get.func1()
+	/src/service/dht/module.go:240 +0x25
+created by gnunet/service/dht.(*Module).Get in goroutine 7
+	/src/service/dht/module.go:238 +0x1a5
+
+goroutine 9 [IO wait]:
+net/http.(*conn).serve(0xc000020000)
+	/go/src/net/http/server.go:1990 +0x25
+created by net/http.(*Server).Serve in goroutine 1
+	/go/src/net/http/server.go:3285 +0x4b4
+`
+	got := countGoroutines(dump)
+	want := map[string]int{"main": 1, "service/dht": 2, "net/http": 1}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	// live dump counts this test
+	total := 0
+	for _, n := range Goroutines() {
+		total += n
+	}
+	if total == 0 {
+		t.Fatal("no goroutines counted")
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	RegisterIntrospector("test-health", func() *Introspection {
+		return &Introspection{Clients: 2, Queues: map[string]int{"in": 3}}
+	})
+	RegisterIntrospector("test-health", func() *Introspection {
+		return &Introspection{Handlers: 1, Queues: map[string]int{"in": 1, "out": 4}}
+	})
+	list := Introspect("test-health")
+	if len(list) != 1 {
+		t.Fatalf("got %d modules", len(list))
+	}
+	in := list[0]
+	if in.Module != "test-health" || in.Clients != 2 || in.Handlers != 1 ||
+		in.Queues["in"] != 4 || in.Queues["out"] != 4 || in.Caches != nil {
+		t.Fatalf("wrong merge: %+v", in)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	p := new(profiler)
+	if _, err := p.set(true); err != ErrNoProfiling {
+		t.Fatalf("expected no-profiling error, got %v", err)
+	}
+	p.endpoint = "127.0.0.1:0"
+	for _, on := range []bool{true, true, false, false} {
+		active, err := p.set(on)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != on {
+			t.Fatalf("profiling active=%v, want %v", active, on)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"context"
+	"errors"
+
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/service"
+)
+
+// ErrInvalidResponse is returned if the identity service responds with
+// an unexpected message.
+var ErrInvalidResponse = errors.New("invalid response from identity service")
+
+//----------------------------------------------------------------------
+// Client helpers for other services (GNS, zonemaster) talking to the
+// identity service socket.
+//----------------------------------------------------------------------
+
+// LookupEgo returns the private zone key of a named ego from the identity
+// service listening on 'socket'. An unknown ego is reported as
+// ErrEgoUnknown.
+func LookupEgo(ctx context.Context, caller, socket, name string) (*crypto.ZonePrivate, error) {
+	resp, err := service.RequestResponse(ctx, caller, "identity", socket, message.NewIdentityLookupMsg(name), true)
+	if err != nil {
+		return nil, err
+	}
+	switch m := resp.(type) {
+	case *message.IdentityUpdateMsg:
+		if m.ZoneKey != nil {
+			return m.ZoneKey, nil
+		}
+	case *message.IdentityResultCodeMsg:
+		return nil, ErrEgoUnknown
+	}
+	return nil, ErrInvalidResponse
+}
+
+// ListEgos returns all egos of the identity service listening on
+// 'socket'.
+func ListEgos(ctx context.Context, socket string) (list []*Ego, err error) {
+	conn, err := service.NewConnection(ctx, socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if err = conn.Send(ctx, message.NewIdentityStartMsg()); err != nil {
+		return
+	}
+	// receive updates until end-of-list
+	for {
+		var in message.Message
+		if in, err = conn.Receive(ctx); err != nil {
+			return
+		}
+		m, ok := in.(*message.IdentityUpdateMsg)
+		if !ok {
+			return nil, ErrInvalidResponse
+		}
+		if m.ZoneKey == nil {
+			return
+		}
+		list = append(list, &Ego{Name: m.Name(), Key: m.ZoneKey})
+	}
+}
@@ -0,0 +1,266 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+// Error codes
+var (
+	ErrEgoName    = errors.New("invalid ego name")
+	ErrEgoExists  = errors.New("ego name already in use")
+	ErrEgoUnknown = errors.New("unknown ego")
+	ErrEgoFile    = errors.New("invalid ego file")
+)
+
+//----------------------------------------------------------------------
+// Ego files:
+// Every ego is stored in a file named after the ego in the ego directory
+// (like the GNUnet C implementation does in '$GNUNET_CONFIG_HOME/identity/
+// egos'). The file holds the private key in the binary representation of
+// the C implementation: key type (4 bytes, big-endian) followed by the
+// key data (ECDSA scalars in little-endian order). Files of older C
+// versions (32 bytes ECDSA key without type) and files holding the key
+// as a string (like 'gnunet-identity -d -q -p') are read too.
+//----------------------------------------------------------------------
+
+// Ego is a named private zone key
+type Ego struct {
+	Name string              // ego name
+	Key  *crypto.ZonePrivate // private zone key
+}
+
+// EgoBytes returns the content of an ego file for a private zone key.
+func EgoBytes(zk *crypto.ZonePrivate) []byte {
+	kd := zk.KeyData
+	if zk.Type == enums.GNS_TYPE_PKEY {
+		kd = util.Reverse(kd)
+	}
+	buf := make([]byte, 4+len(kd))
+	binary.BigEndian.PutUint32(buf[:4], uint32(zk.Type))
+	copy(buf[4:], kd)
+	return buf
+}
+
+// ParseEgo returns the private zone key from the content of an ego file.
+func ParseEgo(buf []byte) (zk *crypto.ZonePrivate, err error) {
+	switch len(buf) {
+	case 36:
+		ztype := enums.GNSType(binary.BigEndian.Uint32(buf[:4]))
+		kd := util.Clone(buf[4:])
+		if ztype == enums.GNS_TYPE_PKEY {
+			kd = util.Reverse(kd)
+		}
+		zk, err = crypto.NewZonePrivate(ztype, kd)
+	case 32:
+		// legacy ECDSA key
+		zk, err = crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.Reverse(buf))
+	default:
+		zk, err = crypto.NewZonePrivateFromID(strings.TrimSpace(string(buf)))
+	}
+	if err != nil {
+		err = ErrEgoFile
+	}
+	return
+}
+
+// NormalizeName returns the canonical form of an ego name: ego names are
+// normalized like GNS labels (see util.NormalizeLabel). Names are used as
+// file names, so path separators are not allowed either.
+func NormalizeName(name string) (string, error) {
+	name, err := util.NormalizeLabel(strings.TrimSpace(name))
+	if err != nil || strings.ContainsAny(name, "/\\") {
+		return "", ErrEgoName
+	}
+	return name, nil
+}
+
+//----------------------------------------------------------------------
+
+// EgoStore keeps egos in memory and persists them as ego files.
+type EgoStore struct {
+	sync.RWMutex
+
+	dir  string          // ego directory (no persistence if empty)
+	egos map[string]*Ego // egos by name
+}
+
+// NewEgoStore reads all ego files from a directory. Files that can't be
+// parsed are skipped (and reported in the returned list).
+func NewEgoStore(dir string) (es *EgoStore, skipped []string, err error) {
+	es = &EgoStore{
+		dir:  dir,
+		egos: make(map[string]*Ego),
+	}
+	if len(dir) == 0 {
+		return
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+	var files []os.DirEntry
+	if files, err = os.ReadDir(dir); err != nil {
+		return
+	}
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		name, err := NormalizeName(f.Name())
+		if err != nil {
+			skipped = append(skipped, f.Name())
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			skipped = append(skipped, f.Name())
+			continue
+		}
+		zk, err := ParseEgo(buf)
+		if err != nil {
+			skipped = append(skipped, f.Name())
+			continue
+		}
+		es.egos[name] = &Ego{Name: name, Key: zk}
+	}
+	return
+}
+
+// Get an ego by name.
+func (es *EgoStore) Get(name string) (*Ego, error) {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	es.RLock()
+	defer es.RUnlock()
+	ego, ok := es.egos[name]
+	if !ok {
+		return nil, ErrEgoUnknown
+	}
+	return ego, nil
+}
+
+// List all egos (sorted by name).
+func (es *EgoStore) List() (list []*Ego) {
+	es.RLock()
+	defer es.RUnlock()
+	for _, ego := range es.egos {
+		list = append(list, ego)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+// Create a new ego.
+func (es *EgoStore) Create(name string, zk *crypto.ZonePrivate) (ego *Ego, err error) {
+	if name, err = NormalizeName(name); err != nil {
+		return
+	}
+	es.Lock()
+	defer es.Unlock()
+	if _, ok := es.egos[name]; ok {
+		return nil, ErrEgoExists
+	}
+	ego = &Ego{Name: name, Key: zk}
+	if err = es.write(ego); err != nil {
+		return nil, err
+	}
+	es.egos[name] = ego
+	return
+}
+
+// Rename an ego.
+func (es *EgoStore) Rename(oldName, newName string) (ego *Ego, err error) {
+	if oldName, err = NormalizeName(oldName); err != nil {
+		return
+	}
+	if newName, err = NormalizeName(newName); err != nil {
+		return
+	}
+	es.Lock()
+	defer es.Unlock()
+	old, ok := es.egos[oldName]
+	if !ok {
+		return nil, ErrEgoUnknown
+	}
+	if _, ok = es.egos[newName]; ok {
+		return nil, ErrEgoExists
+	}
+	if len(es.dir) > 0 {
+		if err = os.Rename(es.file(oldName), es.file(newName)); err != nil {
+			return
+		}
+	}
+	ego = &Ego{Name: newName, Key: old.Key}
+	delete(es.egos, oldName)
+	es.egos[newName] = ego
+	return
+}
+
+// Delete an ego (returns the deleted ego).
+func (es *EgoStore) Delete(name string) (ego *Ego, err error) {
+	if name, err = NormalizeName(name); err != nil {
+		return
+	}
+	es.Lock()
+	defer es.Unlock()
+	ego, ok := es.egos[name]
+	if !ok {
+		return nil, ErrEgoUnknown
+	}
+	if len(es.dir) > 0 {
+		if err = os.Remove(es.file(name)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	delete(es.egos, name)
+	return ego, nil
+}
+
+// file name for an ego
+func (es *EgoStore) file(name string) string {
+	return filepath.Join(es.dir, name)
+}
+
+// write ego file
+func (es *EgoStore) write(ego *Ego) error {
+	if len(es.dir) == 0 {
+		return nil
+	}
+	fname := es.file(ego.Name)
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, EgoBytes(ego.Key), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
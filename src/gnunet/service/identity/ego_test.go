@@ -0,0 +1,130 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+)
+
+func TestEgoFile(t *testing.T) {
+	for _, ztype := range []enums.GNSType{enums.GNS_TYPE_PKEY, enums.GNS_TYPE_EDKEY} {
+		zk, err := crypto.NewZonePrivate(ztype, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := EgoBytes(zk)
+		if len(buf) != 36 {
+			t.Fatalf("%s: ego file has %d bytes", ztype, len(buf))
+		}
+		// the file content is the binary form of the key identifier
+		id, err := util.DecodeStringToBinary(zk.ID(), 36)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, id) {
+			t.Errorf("%s: ego file does not match key identifier", ztype)
+		}
+		for _, in := range [][]byte{buf, []byte(zk.ID() + "\n")} {
+			zk2, err := ParseEgo(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if zk2.Type != zk.Type || !bytes.Equal(zk2.KeyData, zk.KeyData) {
+				t.Errorf("%s: key mismatch", ztype)
+			}
+		}
+	}
+	// legacy ECDSA key (little-endian, no type)
+	zk, _ := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, nil)
+	zk2, err := ParseEgo(util.Reverse(zk.KeyData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !zk2.Public().Equal(zk.Public()) {
+		t.Error("legacy key mismatch")
+	}
+	if _, err = ParseEgo([]byte("garbage")); err != ErrEgoFile {
+		t.Errorf("invalid file: got %v", err)
+	}
+}
+
+func TestEgoStore(t *testing.T) {
+	dir := t.TempDir()
+	es, _, err := NewEgoStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk, _ := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, nil)
+	if _, err = es.Create("Home", zk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = es.Create("home", zk); err != ErrEgoExists {
+		t.Errorf("duplicate: got %v", err)
+	}
+	for _, name := range []string{"", ".hidden", "a/b", "x.tmp", "a b", "a\\b"} {
+		if _, err = es.Create(name, zk); err != ErrEgoName {
+			t.Errorf("name '%s': got %v", name, err)
+		}
+	}
+	if _, err = es.Rename("home", "work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "work")); err != nil {
+		t.Fatal(err)
+	}
+	// add a file written by another implementation and an invalid one
+	zk2, _ := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, nil)
+	if err = os.WriteFile(filepath.Join(dir, "c-ego"), EgoBytes(zk2), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "broken"), []byte{1, 2, 3}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// reload
+	if es, skipped, err := NewEgoStore(dir); err != nil {
+		t.Fatal(err)
+	} else {
+		if len(skipped) != 1 || skipped[0] != "broken" {
+			t.Errorf("skipped files: %v", skipped)
+		}
+		list := es.List()
+		if len(list) != 2 || list[0].Name != "c-ego" || list[1].Name != "work" {
+			t.Fatalf("unexpected egos %v", list)
+		}
+		if !list[1].Key.Public().Equal(zk.Public()) || !list[0].Key.Public().Equal(zk2.Public()) {
+			t.Error("key mismatch after reload")
+		}
+		if _, err = es.Delete("work"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = es.Get("work"); err != ErrEgoUnknown {
+			t.Errorf("deleted ego: got %v", err)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dir, "work")); !os.IsNotExist(err) {
+		t.Error("ego file not removed")
+	}
+}
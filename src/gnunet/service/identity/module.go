@@ -0,0 +1,189 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"context"
+	"sync"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//======================================================================
+// "GNUnet Identity" implementation:
+// Manage named private zone keys (egos) for local subsystems. Egos are
+// kept as ego files compatible with the GNUnet C implementation.
+//======================================================================
+
+// Module handles the egos of the identity service
+type Module struct {
+	service.ModuleImpl
+
+	egos *EgoStore // ego storage
+
+	// clients following updates (by session)
+	mtx       *sync.Mutex
+	followers map[int]transport.Responder
+}
+
+// NewModule creates a new identity module with egos read from the
+// configured ego directory.
+func NewModule(ctx context.Context, cfg *config.IdentityConfig) *Module {
+	var dir string
+	if cfg != nil {
+		dir = cfg.EgoDir
+	}
+	es, skipped, err := NewEgoStore(dir)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[identity] Failed to read egos: %s", err.Error())
+		return nil
+	}
+	for _, name := range skipped {
+		logger.Printf(logger.WARN, "[identity] invalid ego file '%s' skipped", name)
+	}
+	logger.Printf(logger.INFO, "[identity] %d egos loaded", len(es.egos))
+	return newModule(es)
+}
+
+// create module for given ego storage
+func newModule(es *EgoStore) *Module {
+	return &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		egos:       es,
+		mtx:        new(sync.Mutex),
+		followers:  make(map[int]transport.Responder),
+	}
+}
+
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module: the identity service
+// only serves local clients.
+func (m *Module) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["identity:lookup"] = m.Lookup
+}
+
+// Import functions
+func (m *Module) Import(fcn map[string]any) {
+	// nothing to import now.
+}
+
+//----------------------------------------------------------------------
+
+// Lookup returns the private zone key of a named ego ["identity:lookup"]
+func (m *Module) Lookup(ctx context.Context, name string) (*crypto.ZonePrivate, error) {
+	ego, err := m.egos.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return ego.Key, nil
+}
+
+// Egos returns all egos (sorted by name).
+func (m *Module) Egos() []*Ego {
+	return m.egos.List()
+}
+
+// Create a new ego. A new random key of given type is generated if no key
+// is given.
+func (m *Module) Create(ctx context.Context, name string, zk *crypto.ZonePrivate) (err error) {
+	if zk == nil || zk.IsNull() {
+		ztype := enums.GNS_TYPE_PKEY
+		if zk != nil {
+			ztype = zk.Type
+		}
+		if zk, err = crypto.NewZonePrivate(ztype, nil); err != nil {
+			return
+		}
+	}
+	var ego *Ego
+	if ego, err = m.egos.Create(name, zk); err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[identity] ego '%s' created (%s)", ego.Name, zk.Public().ID())
+	m.notify(ctx, message.NewIdentityUpdateMsg(ego.Name, ego.Key))
+	return
+}
+
+// Rename an ego.
+func (m *Module) Rename(ctx context.Context, oldName, newName string) (err error) {
+	var ego *Ego
+	if ego, err = m.egos.Rename(oldName, newName); err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[identity] ego '%s' renamed to '%s'", oldName, ego.Name)
+	m.notify(ctx, message.NewIdentityUpdateMsg(ego.Name, ego.Key))
+	return
+}
+
+// Delete an ego: followers are notified with an update without name.
+func (m *Module) Delete(ctx context.Context, name string) (err error) {
+	var ego *Ego
+	if ego, err = m.egos.Delete(name); err != nil {
+		return
+	}
+	logger.Printf(logger.INFO, "[identity] ego '%s' deleted", ego.Name)
+	m.notify(ctx, message.NewIdentityUpdateMsg("", ego.Key))
+	return
+}
+
+//----------------------------------------------------------------------
+
+// follow adds a client session to the receivers of updates.
+func (m *Module) follow(id int, back transport.Responder) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.followers[id] = back
+}
+
+// unfollow removes a client session from the receivers of updates.
+func (m *Module) unfollow(id int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.followers, id)
+}
+
+// notify all following clients about a changed ego.
+func (m *Module) notify(ctx context.Context, msg *message.IdentityUpdateMsg) {
+	m.mtx.Lock()
+	list := make(map[int]transport.Responder, len(m.followers))
+	for id, back := range m.followers {
+		list[id] = back
+	}
+	m.mtx.Unlock()
+	for id, back := range list {
+		if err := back.Send(ctx, msg); err != nil {
+			logger.Printf(logger.WARN, "[identity:%d] update not sent: %s", id, err.Error())
+		}
+	}
+}
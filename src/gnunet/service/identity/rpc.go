@@ -0,0 +1,74 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"net/http"
+
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for identity-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to identity module
+}
+
+//----------------------------------------------------------------------
+// Command "Identity.List"
+//----------------------------------------------------------------------
+
+// ListRequest asks for the list of egos
+type ListRequest struct{}
+
+// EgoInfo describes an ego (without private key)
+type EgoInfo struct {
+	Name string `json:"name"` // ego name
+	Zone string `json:"zone"` // public zone key (zTLD)
+}
+
+// ListResponse lists the egos of the service
+type ListResponse struct {
+	Egos []*EgoInfo `json:"egos"`
+}
+
+// List returns the names and public zone keys of all egos.
+func (s *RPCService) List(r *http.Request, req *ListRequest, reply *ListResponse) error {
+	list := make([]*EgoInfo, 0)
+	for _, ego := range s.m.Egos() {
+		list = append(list, &EgoInfo{
+			Name: ego.Name,
+			Zone: ego.Key.Public().ID(),
+		})
+	}
+	*reply = ListResponse{Egos: list}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "Identity"); err != nil {
+		logger.Printf(logger.ERROR, "[identity] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Identity" socket service implementation:
+// Requests are answered with a RESULT_CODE (CREATE, RENAME, DELETE) or
+// an UPDATE message (LOOKUP). After START, a client receives an UPDATE
+// for every ego (terminated by an end-of-list UPDATE) and an UPDATE for
+// every later change; a deleted ego is reported without a name.
+//----------------------------------------------------------------------
+
+// Result codes
+const (
+	rcOK    = 0
+	rcError = 1
+)
+
+// Service implements an identity service
+type Service struct {
+	Module
+}
+
+// NewService creates a new identity service instance
+func NewService(ctx context.Context, cfg *config.IdentityConfig) service.Service {
+	mod := NewModule(ctx, cfg)
+	if mod == nil {
+		return nil
+	}
+	return &Service{
+		Module: *mod,
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[identity:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[identity:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[identity:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[identity:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[identity:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		valueCtx = context.WithValue(valueCtx, core.CtxKey("session"), id)
+		s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	s.unfollow(id)
+	mc.Close()
+
+	// cancel all tasks running for this session/connection
+	logger.Printf(logger.INFO, "[identity:%d] Start closing session...\n", id)
+	cancel()
+}
+
+// HandleMessage processes a single incoming message
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	// result code for a request
+	result := func(op string, err error) message.Message {
+		if err != nil {
+			logger.Printf(logger.WARN, "[identity%s] %s failed: %s", label, op, err.Error())
+			return message.NewIdentityResultCodeMsg(rcError)
+		}
+		return message.NewIdentityResultCodeMsg(rcOK)
+	}
+	var resp message.Message
+	switch m := msg.(type) {
+
+	case *message.IdentityStartMsg:
+		//----------------------------------------------------------
+		// START: send all egos and follow updates
+		//----------------------------------------------------------
+		for _, ego := range s.Egos() {
+			if err := back.Send(ctx, message.NewIdentityUpdateMsg(ego.Name, ego.Key)); err != nil {
+				logger.Printf(logger.ERROR, "[identity%s] Can't send update: %s", label, err.Error())
+				return false
+			}
+		}
+		resp = message.NewIdentityUpdateMsg("", nil)
+		if id, ok := ctx.Value(core.CtxKey("session")).(int); ok {
+			s.follow(id, back)
+		}
+
+	case *message.IdentityCreateMsg:
+		//----------------------------------------------------------
+		// CREATE: add ego with given key
+		//----------------------------------------------------------
+		resp = result("create", s.Create(ctx, m.Name(), m.ZoneKey))
+
+	case *message.IdentityRenameMsg:
+		//----------------------------------------------------------
+		// RENAME: change name of ego
+		//----------------------------------------------------------
+		resp = result("rename", s.Rename(ctx, m.OldName(), m.NewName()))
+
+	case *message.IdentityDeleteMsg:
+		//----------------------------------------------------------
+		// DELETE: remove ego
+		//----------------------------------------------------------
+		resp = result("delete", s.Delete(ctx, m.Name()))
+
+	case *message.IdentityLookupMsg:
+		//----------------------------------------------------------
+		// LOOKUP: an unknown ego is reported with a result code
+		//----------------------------------------------------------
+		ego, err := s.egos.Get(m.Name)
+		if err != nil {
+			resp = result("lookup", err)
+		} else {
+			resp = message.NewIdentityUpdateMsg(ego.Name, ego.Key)
+		}
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[identity%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	if err := back.Send(ctx, resp); err != nil {
+		logger.Printf(logger.ERROR, "[identity%s] Failed to send response: %s\n", label, err.Error())
+		return false
+	}
+	return true
+}
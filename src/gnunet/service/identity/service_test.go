@@ -0,0 +1,129 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package identity
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+)
+
+// mockResponder collects messages sent to a client.
+type mockResponder struct {
+	sync.Mutex
+	msgs []message.Message
+}
+
+func (r *mockResponder) Send(ctx context.Context, msg message.Message) error {
+	r.Lock()
+	defer r.Unlock()
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func (r *mockResponder) Receiver() *util.PeerID {
+	return nil
+}
+
+// last message sent to the client
+func (r *mockResponder) last(t *testing.T) message.Message {
+	t.Helper()
+	r.Lock()
+	defer r.Unlock()
+	if len(r.msgs) == 0 {
+		t.Fatal("no response")
+	}
+	return r.msgs[len(r.msgs)-1]
+}
+
+// result code of the last response
+func (r *mockResponder) rc(t *testing.T) uint32 {
+	t.Helper()
+	m, ok := r.last(t).(*message.IdentityResultCodeMsg)
+	if !ok {
+		t.Fatalf("unexpected response %s", r.last(t))
+	}
+	return m.ResultCode
+}
+
+func TestService(t *testing.T) {
+	es, _, err := NewEgoStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{Module: *newModule(es)}
+	ctx := context.Background()
+
+	// client #1 follows updates
+	follower := new(mockResponder)
+	fctx := context.WithValue(ctx, core.CtxKey("session"), 1)
+	s.HandleMessage(fctx, nil, message.NewIdentityStartMsg(), follower)
+	if m, ok := follower.last(t).(*message.IdentityUpdateMsg); !ok || m.ZoneKey != nil {
+		t.Fatal("no end-of-list")
+	}
+
+	// client #2 creates, renames and deletes an ego
+	back := new(mockResponder)
+	zk, _ := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, nil)
+	s.HandleMessage(ctx, nil, message.NewIdentityCreateMsg(zk, "alice"), back)
+	if rc := back.rc(t); rc != rcOK {
+		t.Fatalf("create: rc=%d", rc)
+	}
+	s.HandleMessage(ctx, nil, message.NewIdentityCreateMsg(zk, "alice"), back)
+	if rc := back.rc(t); rc != rcError {
+		t.Fatalf("duplicate create: rc=%d", rc)
+	}
+	s.HandleMessage(ctx, nil, message.NewIdentityLookupMsg("alice"), back)
+	if m, ok := back.last(t).(*message.IdentityUpdateMsg); !ok || m.Name() != "alice" || !m.ZoneKey.Public().Equal(zk.Public()) {
+		t.Fatalf("lookup: unexpected response %s", back.last(t))
+	}
+	s.HandleMessage(ctx, nil, message.NewIdentityRenameMsg("alice", "bob"), back)
+	if rc := back.rc(t); rc != rcOK {
+		t.Fatalf("rename: rc=%d", rc)
+	}
+	s.HandleMessage(ctx, nil, message.NewIdentityLookupMsg("alice"), back)
+	if rc := back.rc(t); rc != rcError {
+		t.Fatalf("lookup of renamed ego: rc=%d", rc)
+	}
+	s.HandleMessage(ctx, nil, message.NewIdentityDeleteMsg("bob"), back)
+	if rc := back.rc(t); rc != rcOK {
+		t.Fatalf("delete: rc=%d", rc)
+	}
+
+	// the follower got an update for every change
+	names := []string{"", "alice", "bob", ""}
+	if len(follower.msgs) != len(names) {
+		t.Fatalf("follower got %d messages", len(follower.msgs))
+	}
+	for i, name := range names[1:] {
+		m := follower.msgs[i+1].(*message.IdentityUpdateMsg)
+		if m.Name() != name || m.ZoneKey == nil {
+			t.Errorf("update #%d: %s", i+1, m)
+		}
+	}
+	if _, err = s.Lookup(ctx, "bob"); err != ErrEgoUnknown {
+		t.Errorf("deleted ego: got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"gnunet/config"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrLimitExceeded = errors.New("resource limit exceeded")
+)
+
+//----------------------------------------------------------------------
+// Resource limits: A service can be configured with soft limits on the
+// number of client sessions (each session is served by a goroutine), the
+// number of requests processed concurrently and the amount of memory
+// used by in-memory caches. If a limit is reached, new sessions and
+// requests are rejected or queued (depending on the configured mode),
+// so a single overloaded service can't exhaust the resources of a node
+// running all services in one process.
+//----------------------------------------------------------------------
+
+// LimitCounter holds statistics for a limited resource.
+type LimitCounter struct {
+	Max      int64 `json:"max"`      // limit (0 = unlimited)
+	Active   int64 `json:"active"`   // currently used
+	Peak     int64 `json:"peak"`     // max. used so far
+	Queued   int64 `json:"queued"`   // number of times a caller had to wait
+	Rejected int64 `json:"rejected"` // number of rejected allocations
+}
+
+// LimitStats are the statistics of a resource limiter.
+type LimitStats struct {
+	Service  string       `json:"service"`  // name of limited service
+	Sessions LimitCounter `json:"sessions"` // client sessions
+	Requests LimitCounter `json:"requests"` // active requests
+	Cache    LimitCounter `json:"cache"`    // cache memory (in bytes)
+}
+
+// Limiter enforces resource limits of a service. A nil limiter imposes
+// no limits.
+type Limiter struct {
+	sync.Mutex
+
+	name     string        // service name
+	queue    bool          // queue (instead of reject) if limit reached
+	sessions chan struct{} // slots for sessions (or nil)
+	requests chan struct{} // slots for requests (or nil)
+	stats    LimitStats    // usage statistics
+}
+
+// limiters of all services (in this process) for statistics
+var (
+	limiters     = make(map[string]*Limiter)
+	limitersLock sync.Mutex
+)
+
+// NewLimiter creates a resource limiter for a service from configuration.
+// Returns nil if no limits are defined.
+func NewLimiter(name string, cfg *config.LimitConfig) *Limiter {
+	if cfg == nil {
+		return nil
+	}
+	l := &Limiter{
+		name: name,
+	}
+	switch cfg.Mode {
+	case "", "reject":
+	case "queue":
+		l.queue = true
+	default:
+		logger.Printf(logger.WARN, "[%s] Unknown limit mode '%s' -- rejecting", name, cfg.Mode)
+	}
+	l.stats.Service = name
+	if cfg.MaxSessions > 0 {
+		l.sessions = make(chan struct{}, cfg.MaxSessions)
+		l.stats.Sessions.Max = int64(cfg.MaxSessions)
+	}
+	if cfg.MaxRequests > 0 {
+		l.requests = make(chan struct{}, cfg.MaxRequests)
+		l.stats.Requests.Max = int64(cfg.MaxRequests)
+	}
+	if cfg.MaxCache > 0 {
+		l.stats.Cache.Max = int64(cfg.MaxCache) * 1024
+	}
+	// register limiter for statistics
+	limitersLock.Lock()
+	limiters[name] = l
+	limitersLock.Unlock()
+	return l
+}
+
+// AcquireSession allocates a slot for a new client session. In queue mode
+// the call blocks until a slot is available or the context is done.
+func (l *Limiter) AcquireSession(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.acquire(ctx, l.sessions, &l.stats.Sessions)
+}
+
+// ReleaseSession frees the slot of a terminated session.
+func (l *Limiter) ReleaseSession() {
+	if l == nil {
+		return
+	}
+	l.release(l.sessions, &l.stats.Sessions)
+}
+
+// AcquireRequest allocates a slot for a new request. In queue mode the
+// call blocks until a slot is available or the context is done.
+func (l *Limiter) AcquireRequest(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.acquire(ctx, l.requests, &l.stats.Requests)
+}
+
+// ReleaseRequest frees the slot of a finished request.
+func (l *Limiter) ReleaseRequest() {
+	if l == nil {
+		return
+	}
+	l.release(l.requests, &l.stats.Requests)
+}
+
+// Reserve memory (in bytes) for a cache entry. Returns false if the
+// cache limit would be exceeded; caches don't wait for memory.
+func (l *Limiter) Reserve(n int) bool {
+	if l == nil {
+		return true
+	}
+	l.Lock()
+	defer l.Unlock()
+	c := &l.stats.Cache
+	if c.Max > 0 && c.Active+int64(n) > c.Max {
+		c.Rejected++
+		return false
+	}
+	c.Active += int64(n)
+	if c.Active > c.Peak {
+		c.Peak = c.Active
+	}
+	return true
+}
+
+// Free reserved cache memory (in bytes).
+func (l *Limiter) Free(n int) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	if l.stats.Cache.Active -= int64(n); l.stats.Cache.Active < 0 {
+		l.stats.Cache.Active = 0
+	}
+}
+
+// Stats returns a snapshot of the usage statistics.
+func (l *Limiter) Stats() (stats LimitStats) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	return l.stats
+}
+
+// acquire a slot from a semaphore channel (unlimited if nil)
+func (l *Limiter) acquire(ctx context.Context, sem chan struct{}, cnt *LimitCounter) error {
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+		default:
+			// limit reached
+			l.Lock()
+			if !l.queue {
+				cnt.Rejected++
+				l.Unlock()
+				return ErrLimitExceeded
+			}
+			cnt.Queued++
+			l.Unlock()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	l.Lock()
+	defer l.Unlock()
+	if cnt.Active++; cnt.Active > cnt.Peak {
+		cnt.Peak = cnt.Active
+	}
+	return nil
+}
+
+// release a slot to a semaphore channel
+func (l *Limiter) release(sem chan struct{}, cnt *LimitCounter) {
+	if sem != nil {
+		<-sem
+	}
+	l.Lock()
+	cnt.Active--
+	l.Unlock()
+}
+
+//----------------------------------------------------------------------
+// Limited services (and modules) get their limiter from the socket
+// handler to enforce request and cache limits.
+//----------------------------------------------------------------------
+
+// Limited is implemented by services that enforce resource limits on
+// requests or caches.
+type Limited interface {
+	SetLimiter(l *Limiter)
+}
+
+//----------------------------------------------------------------------
+// Command "Limits.Stats"
+//----------------------------------------------------------------------
+
+// LimitsRPC is a type for JSON-RPC requests on resource limits.
+type LimitsRPC struct{}
+
+// LimitStatsRequest asks for the statistics of named services (all
+// limited services if the list is empty).
+type LimitStatsRequest struct {
+	Services []string `json:"services"`
+}
+
+// LimitStatsResponse lists the statistics of limited services.
+type LimitStatsResponse struct {
+	Stats []LimitStats `json:"stats"`
+}
+
+// Stats returns the usage statistics of limited services.
+func (s *LimitsRPC) Stats(r *http.Request, req *LimitStatsRequest, reply *LimitStatsResponse) error {
+	limitersLock.Lock()
+	defer limitersLock.Unlock()
+	out := make([]LimitStats, 0)
+	if len(req.Services) == 0 {
+		for _, l := range limiters {
+			out = append(out, l.Stats())
+		}
+	} else {
+		for _, name := range req.Services {
+			if l, ok := limiters[name]; ok {
+				out = append(out, l.Stats())
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Service < out[j].Service
+	})
+	*reply = LimitStatsResponse{Stats: out}
+	return nil
+}
+
+// InitLimitsRPC registers the RPC command for resource limit statistics.
+func InitLimitsRPC(srv *JRPCServer) {
+	if err := srv.RegisterService(new(LimitsRPC), "Limits"); err != nil {
+		logger.Printf(logger.ERROR, "[limits] Failed to init RPC: %s", err.Error())
+	}
+}
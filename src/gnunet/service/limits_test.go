@@ -0,0 +1,109 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+)
+
+func TestLimiterReject(t *testing.T) {
+	l := NewLimiter("test-reject", &config.LimitConfig{MaxRequests: 2})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := l.AcquireRequest(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.AcquireRequest(ctx); err != ErrLimitExceeded {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	l.ReleaseRequest()
+	if err := l.AcquireRequest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stats := l.Stats()
+	if stats.Requests.Active != 2 || stats.Requests.Peak != 2 || stats.Requests.Rejected != 1 {
+		t.Fatalf("wrong statistics: %+v", stats.Requests)
+	}
+	// sessions are not limited
+	for i := 0; i < 10; i++ {
+		if err := l.AcquireSession(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := NewLimiter("test-queue", &config.LimitConfig{MaxSessions: 1, Mode: "queue"})
+	ctx := context.Background()
+	if err := l.AcquireSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// second session waits until the first is released
+	done := make(chan error)
+	go func() {
+		done <- l.AcquireSession(ctx)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("session not queued: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	l.ReleaseSession()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// queued session is canceled by context
+	cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := l.AcquireSession(cctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	stats := l.Stats()
+	if stats.Sessions.Active != 1 || stats.Sessions.Queued != 2 || stats.Sessions.Rejected != 0 {
+		t.Fatalf("wrong statistics: %+v", stats.Sessions)
+	}
+}
+
+func TestLimiterCache(t *testing.T) {
+	l := NewLimiter("test-cache", &config.LimitConfig{MaxCache: 1})
+	if !l.Reserve(1000) {
+		t.Fatal("reserve failed")
+	}
+	if l.Reserve(100) {
+		t.Fatal("cache limit exceeded")
+	}
+	l.Free(1000)
+	if !l.Reserve(100) {
+		t.Fatal("reserve failed after free")
+	}
+	stats := l.Stats()
+	if stats.Cache.Active != 100 || stats.Cache.Peak != 1000 || stats.Cache.Rejected != 1 {
+		t.Fatalf("wrong statistics: %+v", stats.Cache)
+	}
+	// no limits
+	var nl *Limiter
+	if !nl.Reserve(1<<30) || nl.AcquireRequest(context.Background()) != nil {
+		t.Fatal("nil limiter must not limit")
+	}
+}
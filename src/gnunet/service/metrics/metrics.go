@@ -0,0 +1,556 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gnunet/config"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Metrics: services register counters, gauges and histograms (with an
+// optional list of label names) in a registry. The registry is served on
+// a configurable HTTP endpoint in the Prometheus text exposition format,
+// so a node can be monitored by standard tools.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrMetricsLabels = errors.New("label count mismatch")
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets for durations
+// (in seconds) if no buckets are specified.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metric kinds (as named in the exposition format)
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// sample is a single value of a metric with label values.
+type sample struct {
+	suffix string   // name suffix ("_bucket", "_sum", "_count")
+	labels []string // label names
+	values []string // label values
+	value  float64  // sample value
+}
+
+// metric is implemented by all metric types.
+type metric interface {
+	// Name of the metric
+	Name() string
+
+	// Help text for the metric
+	Help() string
+
+	// Kind of metric (counter, gauge or histogram)
+	Kind() string
+
+	// Samples returns the current values of the metric
+	Samples() []*sample
+}
+
+//----------------------------------------------------------------------
+// Registry
+//----------------------------------------------------------------------
+
+// Registry of named metrics.
+type Registry struct {
+	sync.Mutex
+
+	list map[string]metric // metrics by name
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		list: make(map[string]metric),
+	}
+}
+
+// Default is the registry used by the package-level constructors.
+var Default = NewRegistry()
+
+// register a metric: if a metric with the same name and kind exists, it
+// is returned instead (gauge functions are replaced).
+func (r *Registry) register(m metric) metric {
+	r.Lock()
+	defer r.Unlock()
+	if old, ok := r.list[m.Name()]; ok && old.Kind() == m.Kind() {
+		if _, ok := m.(*GaugeFunc); !ok {
+			return old
+		}
+	}
+	r.list[m.Name()] = m
+	return m
+}
+
+// Unregister removes a named metric from the registry.
+func (r *Registry) Unregister(name string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.list, name)
+}
+
+// WriteText writes all metrics (sorted by name) in the Prometheus text
+// exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.Lock()
+	names := make([]string, 0, len(r.list))
+	for name := range r.list {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]metric, len(names))
+	for i, name := range names {
+		list[i] = r.list[name]
+	}
+	r.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, m := range list {
+		fmt.Fprintf(buf, "# HELP %s %s\n", m.Name(), escapeHelp(m.Help()))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", m.Name(), m.Kind())
+		for _, s := range m.Samples() {
+			buf.WriteString(m.Name() + s.suffix)
+			if len(s.labels) > 0 {
+				buf.WriteByte('{')
+				for i, label := range s.labels {
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					fmt.Fprintf(buf, "%s=\"%s\"", label, escapeValue(s.values[i]))
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(formatValue(s.value))
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Flush()
+}
+
+// Handler returns a HTTP handler serving the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			logger.Printf(logger.WARN, "[metrics] write failed: %s", err.Error())
+		}
+	})
+}
+
+// Start serves the default registry on the endpoint configured for the
+// named service (like "dht"); nothing is served if no endpoint is set.
+// The server runs until the context is cancelled.
+func Start(ctx context.Context, name string, cfg *config.MetricsConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	addr, ok := cfg.Endpoints[name]
+	if !ok || len(addr) == 0 {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	srv := &http.Server{
+		Handler:           mux,
+		Addr:              addr,
+		WriteTimeout:      5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	// start listening
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			logger.Printf(logger.WARN, "[metrics] server listen failed: %s", err.Error())
+		}
+	}()
+	// wait for shutdown
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			logger.Printf(logger.WARN, "[metrics] server shutdown failed: %s", err.Error())
+		}
+	}()
+	logger.Printf(logger.INFO, "[%s] Metrics served on http://%s/metrics", name, addr)
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Labeled values
+//----------------------------------------------------------------------
+
+// values of a metric by label values
+type values[T any] struct {
+	sync.Mutex
+
+	labels []string            // label names
+	list   map[string]*T       // values by (joined) label values
+	keys   map[string][]string // label values by key
+	create func() *T           // create a new value
+}
+
+// newValues returns an empty value set for given labels.
+func newValues[T any](labels []string, create func() *T) *values[T] {
+	return &values[T]{
+		labels: labels,
+		list:   make(map[string]*T),
+		keys:   make(map[string][]string),
+		create: create,
+	}
+}
+
+// get (or create) the value for label values; the caller must hold
+// the lock.
+func (v *values[T]) get(lv []string) *T {
+	if len(lv) != len(v.labels) {
+		panic(ErrMetricsLabels)
+	}
+	key := strings.Join(lv, "\x00")
+	val, ok := v.list[key]
+	if !ok {
+		val = v.create()
+		v.list[key] = val
+		v.keys[key] = append([]string{}, lv...)
+	}
+	return val
+}
+
+// each calls 'f' for all values sorted by label values; the caller must
+// hold the lock.
+func (v *values[T]) each(f func(lv []string, val *T)) {
+	keys := make([]string, 0, len(v.list))
+	for key := range v.list {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f(v.keys[key], v.list[key])
+	}
+}
+
+//----------------------------------------------------------------------
+// Counter
+//----------------------------------------------------------------------
+
+// Counter is a monotonically increasing value (per label values).
+type Counter struct {
+	name, help string
+	vals       *values[float64]
+}
+
+// NewCounter registers a counter with given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		name: name,
+		help: help,
+		vals: newValues(labels, func() *float64 { return new(float64) }),
+	}
+	return Default.register(c).(*Counter)
+}
+
+// Name of the counter
+func (c *Counter) Name() string { return c.name }
+
+// Help text of the counter
+func (c *Counter) Help() string { return c.help }
+
+// Kind of metric
+func (c *Counter) Kind() string { return kindCounter }
+
+// Inc increments the counter for given label values.
+func (c *Counter) Inc(lv ...string) {
+	c.Add(1, lv...)
+}
+
+// Add a (non-negative) value to the counter for given label values.
+func (c *Counter) Add(n float64, lv ...string) {
+	if n < 0 {
+		return
+	}
+	c.vals.Lock()
+	*c.vals.get(lv) += n
+	c.vals.Unlock()
+}
+
+// Value returns the counter for given label values.
+func (c *Counter) Value(lv ...string) float64 {
+	c.vals.Lock()
+	defer c.vals.Unlock()
+	return *c.vals.get(lv)
+}
+
+// Samples returns the current counter values.
+func (c *Counter) Samples() (list []*sample) {
+	c.vals.Lock()
+	defer c.vals.Unlock()
+	c.vals.each(func(lv []string, val *float64) {
+		list = append(list, &sample{labels: c.vals.labels, values: lv, value: *val})
+	})
+	return
+}
+
+//----------------------------------------------------------------------
+// Gauge
+//----------------------------------------------------------------------
+
+// Gauge is a value that can go up and down (per label values).
+type Gauge struct {
+	name, help string
+	vals       *values[float64]
+}
+
+// NewGauge registers a gauge with given label names.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		name: name,
+		help: help,
+		vals: newValues(labels, func() *float64 { return new(float64) }),
+	}
+	return Default.register(g).(*Gauge)
+}
+
+// Name of the gauge
+func (g *Gauge) Name() string { return g.name }
+
+// Help text of the gauge
+func (g *Gauge) Help() string { return g.help }
+
+// Kind of metric
+func (g *Gauge) Kind() string { return kindGauge }
+
+// Set the gauge for given label values.
+func (g *Gauge) Set(v float64, lv ...string) {
+	g.vals.Lock()
+	*g.vals.get(lv) = v
+	g.vals.Unlock()
+}
+
+// Reset removes all values of the gauge.
+func (g *Gauge) Reset() {
+	g.vals.Lock()
+	g.vals.list = make(map[string]*float64)
+	g.vals.keys = make(map[string][]string)
+	g.vals.Unlock()
+}
+
+// Samples returns the current gauge values.
+func (g *Gauge) Samples() (list []*sample) {
+	g.vals.Lock()
+	defer g.vals.Unlock()
+	g.vals.each(func(lv []string, val *float64) {
+		list = append(list, &sample{labels: g.vals.labels, values: lv, value: *val})
+	})
+	return
+}
+
+// GaugeFunc is a gauge with values computed on request. The values are
+// keyed by the value of a single label (or by an empty string if the
+// gauge has no label).
+type GaugeFunc struct {
+	name, help string
+	label      string
+	f          func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge with a single value computed by 'f'.
+func NewGaugeFunc(name, help string, f func() float64) *GaugeFunc {
+	return NewGaugeVecFunc(name, help, "", func() map[string]float64 {
+		return map[string]float64{"": f()}
+	})
+}
+
+// NewGaugeVecFunc registers a gauge with values (by label value) computed
+// by 'f'. A registered gauge function with the same name is replaced.
+func NewGaugeVecFunc(name, help, label string, f func() map[string]float64) *GaugeFunc {
+	g := &GaugeFunc{
+		name:  name,
+		help:  help,
+		label: label,
+		f:     f,
+	}
+	return Default.register(g).(*GaugeFunc)
+}
+
+// Name of the gauge
+func (g *GaugeFunc) Name() string { return g.name }
+
+// Help text of the gauge
+func (g *GaugeFunc) Help() string { return g.help }
+
+// Kind of metric
+func (g *GaugeFunc) Kind() string { return kindGauge }
+
+// Samples returns the current gauge values.
+func (g *GaugeFunc) Samples() (list []*sample) {
+	vals := g.f()
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := &sample{value: vals[k]}
+		if len(g.label) > 0 {
+			s.labels = []string{g.label}
+			s.values = []string{k}
+		}
+		list = append(list, s)
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Histogram
+//----------------------------------------------------------------------
+
+// histogram values (per label values)
+type histValues struct {
+	counts []uint64 // observations per bucket (not cumulative)
+	count  uint64   // number of observations
+	sum    float64  // sum of observations
+}
+
+// Histogram counts observations in buckets with given upper bounds.
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	vals       *values[histValues]
+}
+
+// NewHistogram registers a histogram with given bucket bounds (or the
+// default buckets if nil) and label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := append([]float64{}, buckets...)
+	sort.Float64s(bounds)
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		buckets: bounds,
+		vals: newValues(labels, func() *histValues {
+			return &histValues{counts: make([]uint64, len(bounds))}
+		}),
+	}
+	return Default.register(h).(*Histogram)
+}
+
+// Name of the histogram
+func (h *Histogram) Name() string { return h.name }
+
+// Help text of the histogram
+func (h *Histogram) Help() string { return h.help }
+
+// Kind of metric
+func (h *Histogram) Kind() string { return kindHistogram }
+
+// Observe a value for given label values.
+func (h *Histogram) Observe(v float64, lv ...string) {
+	h.vals.Lock()
+	defer h.vals.Unlock()
+	hv := h.vals.get(lv)
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// ObserveSince observes the time elapsed since 'start' (in seconds).
+func (h *Histogram) ObserveSince(start time.Time, lv ...string) {
+	h.Observe(time.Since(start).Seconds(), lv...)
+}
+
+// Samples returns the cumulative bucket counts, sum and count.
+func (h *Histogram) Samples() (list []*sample) {
+	h.vals.Lock()
+	defer h.vals.Unlock()
+	labels := append(append([]string{}, h.vals.labels...), "le")
+	h.vals.each(func(lv []string, hv *histValues) {
+		var acc uint64
+		for i, bound := range h.buckets {
+			acc += hv.counts[i]
+			list = append(list, &sample{
+				suffix: "_bucket",
+				labels: labels,
+				values: append(append([]string{}, lv...), formatValue(bound)),
+				value:  float64(acc),
+			})
+		}
+		list = append(list,
+			&sample{
+				suffix: "_bucket",
+				labels: labels,
+				values: append(append([]string{}, lv...), "+Inf"),
+				value:  float64(hv.count),
+			},
+			&sample{suffix: "_sum", labels: h.vals.labels, values: lv, value: hv.sum},
+			&sample{suffix: "_count", labels: h.vals.labels, values: lv, value: float64(hv.count)},
+		)
+	})
+	return
+}
+
+//----------------------------------------------------------------------
+// helpers
+//----------------------------------------------------------------------
+
+// formatValue returns the text representation of a sample value.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeHelp escapes backslashes and newlines in help texts.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeValue escapes backslashes, quotes and newlines in label values.
+func escapeValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
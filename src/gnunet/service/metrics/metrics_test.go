@@ -0,0 +1,116 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gnunet/config"
+)
+
+func TestMetricsText(t *testing.T) {
+	c := NewCounter("test_messages_total", "Messages by type.", "type", "dir")
+	c.Inc("MSG_A", "in")
+	c.Add(2, "MSG_A", "in")
+	c.Inc("MSG_\"B\"", "out")
+	if c.Value("MSG_A", "in") != 3 {
+		t.Fatal("counter mismatch")
+	}
+	// registering again returns the same counter
+	if NewCounter("test_messages_total", "", "type", "dir") != c {
+		t.Fatal("counter registered twice")
+	}
+	NewGaugeFunc("test_peers", "Connected peers.", func() float64 { return 7 })
+	NewGaugeVecFunc("test_buckets", "Peers per bucket.", "bucket", func() map[string]float64 {
+		return map[string]float64{"0": 1, "1": 2}
+	})
+	h := NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	buf := new(bytes.Buffer)
+	if err := Default.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, line := range []string{
+		"# TYPE test_messages_total counter",
+		`test_messages_total{type="MSG_A",dir="in"} 3`,
+		`test_messages_total{type="MSG_\"B\"",dir="out"} 1`,
+		"test_peers 7",
+		`test_buckets{bucket="1"} 2`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="0.1"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 5.55",
+		"test_duration_seconds_count 3",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Fatalf("missing line '%s' in:\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	NewGauge("test_store_blocks", "Stored blocks.", "type").Set(42, "HELLO")
+
+	// get a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &config.MetricsConfig{
+		Endpoints: map[string]string{"dht": addr},
+	}
+	if err = Start(ctx, "dht", cfg); err != nil {
+		t.Fatal(err)
+	}
+	var resp *http.Response
+	for i := 0; i < 20; i++ {
+		if resp, err = http.Get("http://" + addr + "/metrics"); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `test_store_blocks{type="HELLO"} 42`) {
+		t.Fatalf("unexpected response:\n%s", body)
+	}
+}
@@ -21,7 +21,6 @@ package service
 import (
 	"context"
 	"gnunet/core"
-	"time"
 )
 
 // ----------------------------------------------------------------------
@@ -35,10 +34,10 @@ import (
 // example):
 //
 //	// create module instances
-//	gnsMod = gns.NewModule(ctx, core)
-//	dhtMod = dht.NewModule(ctx, core)
-//	ncMod = namecache.NewModule(ctx, core)
-//	revMod = revocation.NewModule(ctx, core)
+//	gnsMod = gns.NewModule(ctx, core, cfg)
+//	dhtMod = dht.NewModule(ctx, core, cfg.DHT)
+//	ncMod = namecache.NewModule(ctx, cfg.Namecache)
+//	revMod = revocation.NewModule(ctx, core, cfg.Revocation)
 //
 //	// export module functions
 //	fcn := make(map[string]any)
@@ -77,9 +76,6 @@ type Module interface {
 // EventHandler is a function prototype for event handling
 type EventHandler func(context.Context, *core.Event)
 
-// Heartbeat is a function prototype for periodic tasks
-type Heartbeat func(context.Context)
-
 //----------------------------------------------------------------------
 // Generic module implementation
 //----------------------------------------------------------------------
@@ -88,6 +84,9 @@ type Heartbeat func(context.Context)
 type ModuleImpl struct {
 	// channel for core events.
 	ch chan *core.Event
+
+	// resource limits (or nil)
+	lim *Limiter
 }
 
 // NewModuleImplementation returns a new base module and starts
@@ -97,20 +96,22 @@ func NewModuleImpl() (m *ModuleImpl) {
 	}
 }
 
-// Run event handling loop
-func (m *ModuleImpl) Run(
-	ctx context.Context,
-	hdlr EventHandler, filter *core.EventFilter,
-	pulse time.Duration, heartbeat Heartbeat,
-) (listener *core.Listener) {
+// SetLimiter sets the resource limiter for the module.
+func (m *ModuleImpl) SetLimiter(l *Limiter) {
+	m.lim = l
+}
+
+// Limiter returns the resource limiter of the module (or nil).
+func (m *ModuleImpl) Limiter() *Limiter {
+	return m.lim
+}
+
+// Run event handling loop. Periodic tasks of a module are registered
+// with the maintenance scheduler (see Schedule).
+func (m *ModuleImpl) Run(ctx context.Context, hdlr EventHandler, filter *core.EventFilter) (listener *core.Listener) {
 	// listener for registration
 	listener = core.NewListener(m.ch, filter)
 
-	// if no heartbeat handler is defined, set pulse to near flatline.
-	if heartbeat == nil {
-		pulse = 365 * 24 * time.Hour // once a year
-	}
-	tick := time.NewTicker(pulse)
 	// run event loop
 	go func() {
 		for {
@@ -123,13 +124,6 @@ func (m *ModuleImpl) Run(
 			// wait for terminate signal
 			case <-ctx.Done():
 				return
-
-			// handle heartbeat
-			case <-tick.C:
-				// check for defined heartbeat handler
-				if heartbeat != nil {
-					heartbeat(ctx)
-				}
 			}
 		}
 	}()
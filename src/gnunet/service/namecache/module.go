@@ -21,40 +21,81 @@ package namecache
 import (
 	"context"
 	"errors"
+	"time"
+
 	"gnunet/config"
 	"gnunet/core"
+	"gnunet/crypto"
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
 	"gnunet/service/store"
 	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
 )
 
 //======================================================================
 // "GNS name cache" implementation
 //======================================================================
 
+// Error codes
+var (
+	ErrBlockInvalid = errors.New("invalid GNS block")
+)
+
+// CollectPeriod is the time between removals of expired blocks.
+var CollectPeriod = 15 * time.Minute
+
 //----------------------------------------------------------------------
 // Put and get GNS blocks into/from a cache (transient storage)
 //----------------------------------------------------------------------
 
-// Namecache handles the transient storage of GNS blocks under the query key.
+// Module handles the transient storage of GNS blocks under the query key.
 type Module struct {
 	service.ModuleImpl
 
-	cache *store.DHTStore // transient block cache
+	cache *store.NamecacheDB // transient block cache
+}
+
+// NewModule creates a new module instance with the configured block
+// cache; expired blocks are removed periodically.
+func NewModule(ctx context.Context, cfg *config.NamecacheConfig) *Module {
+	var spec util.ParameterSet
+	if cfg != nil {
+		spec = cfg.Storage
+	}
+	db, err := store.OpenNamecacheDB(spec)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[namecache] Failed to open block cache: %s", err.Error())
+		return nil
+	}
+	m := newModule(db)
+	if err = service.Schedule(ctx, "namecache:collect", CollectPeriod, m.collect); err != nil {
+		logger.Printf(logger.ERROR, "[namecache] job 'namecache:collect' not scheduled: %s", err.Error())
+	}
+	go func() {
+		<-ctx.Done()
+		m.cache.Close()
+	}()
+	return m
 }
 
-// NewModule creates a new module instance.
-func NewModule(ctx context.Context, c *core.Core) (m *Module) {
-	m = &Module{
+// create module for given block cache
+func newModule(db *store.NamecacheDB) *Module {
+	return &Module{
 		ModuleImpl: *service.NewModuleImpl(),
+		cache:      db,
 	}
-	m.cache, _ = store.NewDHTStore(config.Cfg.Namecache.Storage)
-	return
 }
 
 //----------------------------------------------------------------------
 
+// Filter returns the event filter for the module: the namecache only
+// serves local clients.
+func (m *Module) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
 // Export functions
 func (m *Module) Export(fcn map[string]any) {
 	// add exported functions from module
@@ -69,22 +110,52 @@ func (m *Module) Import(fcm map[string]any) {
 
 //----------------------------------------------------------------------
 
-// Get entry from the cache if available.
+// Get a cached block for a query; the block is verified and decrypted.
+// Returns nil if no (unexpired) block is cached ["namecache:get"]
 func (m *Module) Get(ctx context.Context, query *blocks.GNSQuery) (block *blocks.GNSBlock, err error) {
-	var e []*store.DHTEntry
-	rf := blocks.NewGenericResultFilter(128, util.RndUInt32())
-	if e, err = m.cache.Get("namecache", query, rf); err != nil {
+	if block, err = m.cache.Get(query.Key()); err != nil || block == nil {
 		return
 	}
-	if len(e) != 1 {
-		err = errors.New("only one namecache entry expected")
-	} else {
-		err = blocks.Unwrap(e[0].Blk, block)
+	if err = query.Verify(block); err == nil {
+		err = query.Decrypt(block)
+	}
+	if err != nil {
+		block = nil
 	}
 	return
 }
 
-// Put entry into the cache.
-func (m *Module) Put(ctx context.Context, query *blocks.GNSQuery, entry *store.DHTEntry) error {
-	return m.cache.Put(query, entry)
+// Put a block into the cache; the query key is derived from the block
+// ["namecache:put"]
+func (m *Module) Put(ctx context.Context, query *blocks.GNSQuery, block *blocks.GNSBlock) error {
+	return m.Cache(block)
+}
+
+// Cache a (signed) block under the query key derived from its signing
+// key. Blocks with an invalid signature are rejected; expired blocks
+// and blocks expiring earlier than a cached block are ignored.
+func (m *Module) Cache(block *blocks.GNSBlock) error {
+	if block.DerivedKeySig == nil {
+		return ErrBlockInvalid
+	}
+	if ok, err := block.Verify(); err != nil || !ok {
+		return ErrBlockInvalid
+	}
+	query := crypto.Hash(block.DerivedKeySig.Key().Bytes())
+	_, err := m.cache.Put(query, block)
+	return err
+}
+
+// Lookup returns the cached block for a query key (or nil).
+func (m *Module) Lookup(query *crypto.HashCode) (*blocks.GNSBlock, error) {
+	return m.cache.Get(query)
+}
+
+// collect expired blocks (scheduled job)
+func (m *Module) collect(ctx context.Context) error {
+	n, err := m.cache.Collect()
+	if err == nil && n > 0 {
+		logger.Printf(logger.INFO, "[namecache] %d expired blocks removed", n)
+	}
+	return err
 }
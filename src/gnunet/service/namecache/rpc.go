@@ -0,0 +1,65 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package namecache
+
+import (
+	"net/http"
+
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for namecache-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to namecache module
+}
+
+//----------------------------------------------------------------------
+// Command "Namecache.Status"
+//----------------------------------------------------------------------
+
+// StatusRequest asks for the state of the block cache
+type StatusRequest struct{}
+
+// StatusResponse returns the number of cached blocks
+type StatusResponse struct {
+	Blocks int `json:"blocks"` // number of cached blocks
+}
+
+// Status returns the state of the block cache.
+func (s *RPCService) Status(r *http.Request, req *StatusRequest, reply *StatusResponse) (err error) {
+	var n int
+	if n, err = s.m.cache.Count(); err != nil {
+		return
+	}
+	*reply = StatusResponse{Blocks: n}
+	return
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "Namecache"); err != nil {
+		logger.Printf(logger.ERROR, "[namecache] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,167 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package namecache
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Namecache" socket service implementation:
+// A LOOKUP_BLOCK request is answered with the cached block (or with an
+// empty response if no block is cached); a BLOCK_CACHE request with a
+// result code.
+//----------------------------------------------------------------------
+
+// Result codes
+const (
+	rcOK    = 0
+	rcError = -1
+)
+
+// Service implements a namecache service
+type Service struct {
+	Module
+}
+
+// NewService creates a new namecache service instance
+func NewService(ctx context.Context, cfg *config.NamecacheConfig) service.Service {
+	mod := NewModule(ctx, cfg)
+	if mod == nil {
+		return nil
+	}
+	return &Service{
+		Module: *mod,
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[namecache:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[namecache:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[namecache:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[namecache:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[namecache:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	mc.Close()
+
+	// cancel all tasks running for this session/connection
+	logger.Printf(logger.INFO, "[namecache:%d] Start closing session...\n", id)
+	cancel()
+}
+
+// HandleMessage processes a single incoming message
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	var resp message.Message
+	switch m := msg.(type) {
+
+	case *message.NamecacheLookupMsg:
+		//----------------------------------------------------------
+		// LOOKUP_BLOCK: return cached block
+		//----------------------------------------------------------
+		rm := message.NewNamecacheLookupResultMsg()
+		rm.ID = m.ID
+		blk, err := s.Lookup(m.Query)
+		if err != nil {
+			logger.Printf(logger.ERROR, "[namecache%s] Lookup failed: %s", label, err.Error())
+		} else if blk != nil {
+			rm.SetBlock(blk)
+		}
+		resp = rm
+
+	case *message.NamecacheCacheMsg:
+		//----------------------------------------------------------
+		// BLOCK_CACHE: add block to cache
+		//----------------------------------------------------------
+		rm := message.NewNamecacheCacheResponseMsg()
+		rm.ID = m.ID
+		blk, err := blockFromMsg(m)
+		if err == nil {
+			err = s.Cache(blk)
+		}
+		if err != nil {
+			logger.Printf(logger.WARN, "[namecache%s] Block not cached: %s", label, err.Error())
+			rm.Result = rcError
+		}
+		resp = rm
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[namecache%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	if err := back.Send(ctx, resp); err != nil {
+		logger.Printf(logger.ERROR, "[namecache%s] Failed to send response: %s\n", label, err.Error())
+		return false
+	}
+	return true
+}
+
+// blockFromMsg assembles the GNS block in a BLOCK_CACHE request.
+func blockFromMsg(m *message.NamecacheCacheMsg) (*blocks.GNSBlock, error) {
+	sig, err := crypto.NewZoneSignature(append(util.Clone(m.DerivedKey), m.DerivedSig...))
+	if err != nil {
+		return nil, err
+	}
+	blk, _ := blocks.NewGNSBlock().(*blocks.GNSBlock)
+	blk.DerivedKeySig = sig
+	blk.Body.Expire = m.Expire
+	blk.SetData(util.Clone(m.EncData))
+	return blk, nil
+}
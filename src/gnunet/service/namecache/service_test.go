@@ -0,0 +1,188 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package namecache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/servicetest"
+	"gnunet/service/store"
+	"gnunet/util"
+)
+
+// newTestModule returns a module with a block cache in a temp. directory.
+func newTestModule(t *testing.T, spec util.ParameterSet) *Module {
+	t.Helper()
+	spec["file"] = filepath.Join(t.TempDir(), "namecache.sqlite3")
+	db, err := store.OpenNamecacheDB(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return newModule(db)
+}
+
+// newTestBlock returns a block with a TXT record for a label in a zone.
+func newTestBlock(t *testing.T, zp *crypto.ZonePrivate, label, txt string, ttl time.Duration) *blocks.GNSBlock {
+	t.Helper()
+	expire := util.AbsoluteTimeNow().Add(ttl)
+	rs := blocks.NewRecordSet()
+	rs.AddRecord(&blocks.ResourceRecord{
+		Expire: expire,
+		Size:   uint16(len(txt)),
+		RType:  enums.GNS_TYPE_DNS_TXT,
+		Data:   []byte(txt),
+	})
+	blk, err := blocks.NewGNSBlockFromRecords(zp, label, rs, expire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+func TestService(t *testing.T) {
+	s := &Service{Module: *newTestModule(t, util.ParameterSet{})}
+	ctx := context.Background()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_EDKEY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := blocks.NewGNSQuery(zp.Public(), "www")
+	back := servicetest.NewResponder(t)
+
+	lookup := func() *message.NamecacheLookupResultMsg {
+		t.Helper()
+		req := servicetest.Wire(t, message.NewNamecacheLookupMsg(query.Key()))
+		if !s.HandleMessage(ctx, nil, req, back) {
+			t.Fatal("lookup not handled")
+		}
+		list := back.Take()
+		if len(list) != 1 {
+			t.Fatalf("%d responses", len(list))
+		}
+		resp, ok := list[0].(*message.NamecacheLookupResultMsg)
+		if !ok || resp.ID != req.(*message.NamecacheLookupMsg).ID {
+			t.Fatalf("unexpected response %v", list[0])
+		}
+		return resp
+	}
+	cache := func(blk *blocks.GNSBlock) int32 {
+		t.Helper()
+		req := servicetest.Wire(t, message.NewNamecacheCacheMsg(blk))
+		if !s.HandleMessage(ctx, nil, req, back) {
+			t.Fatal("cache request not handled")
+		}
+		list := back.Take()
+		if len(list) != 1 {
+			t.Fatalf("%d responses", len(list))
+		}
+		resp, ok := list[0].(*message.NamecacheCacheResponseMsg)
+		if !ok || resp.ID != req.(*message.NamecacheCacheMsg).ID {
+			t.Fatalf("unexpected response %v", list[0])
+		}
+		return resp.Result
+	}
+
+	// nothing cached yet
+	if resp := lookup(); len(resp.EncData) != 0 {
+		t.Fatal("block found in empty cache")
+	}
+	// cache block and look it up
+	blk := newTestBlock(t, zp, "www", "hello", time.Hour)
+	if rc := cache(blk); rc != rcOK {
+		t.Fatalf("caching failed with rc=%d", rc)
+	}
+	resp := lookup()
+	if resp.Expire.Compare(blk.Expire()) != 0 || !util.Equal(resp.EncData, blk.Body.Data) {
+		t.Fatal("wrong block returned")
+	}
+	// blocks expiring earlier don't replace cached blocks
+	if rc := cache(newTestBlock(t, zp, "www", "older", time.Minute)); rc != rcOK {
+		t.Fatalf("caching failed with rc=%d", rc)
+	}
+	if resp = lookup(); resp.Expire.Compare(blk.Expire()) != 0 {
+		t.Fatal("cached block replaced by older block")
+	}
+	// modified blocks are rejected
+	bad := newTestBlock(t, zp, "www", "evil", 2*time.Hour)
+	bad.Body.Data[0] ^= 0xff
+	if rc := cache(bad); rc != rcError {
+		t.Fatal("modified block cached")
+	}
+	// module lookup returns the decrypted block
+	out, err := s.Get(ctx, query)
+	if err != nil || out == nil {
+		t.Fatalf("module lookup failed: %v", err)
+	}
+	rs, err := out.Records(zp.Public(), "www")
+	if err != nil || rs.Count != 1 || string(rs.Records[0].Data) != "hello" {
+		t.Fatalf("wrong records in cached block: %v", err)
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	m := newTestModule(t, util.ParameterSet{"num": 2})
+	ctx := context.Background()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cache more blocks than allowed
+	for i, label := range []string{"a", "b", "c"} {
+		ttl := time.Duration(i+1) * time.Hour
+		if err = m.Put(ctx, nil, newTestBlock(t, zp, label, label, ttl)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the block expiring first is dropped from a full cache
+	for _, c := range []struct {
+		label  string
+		cached bool
+	}{{"a", false}, {"b", true}, {"c", true}} {
+		blk, err := m.Get(ctx, blocks.NewGNSQuery(zp.Public(), c.label))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (blk != nil) != c.cached {
+			t.Errorf("label '%s': cached=%v", c.label, blk != nil)
+		}
+	}
+	// expired blocks are collected
+	short := newTestBlock(t, zp, "d", "d", 500*time.Millisecond)
+	if err = m.Put(ctx, nil, short); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if blk, _ := m.Get(ctx, blocks.NewGNSQuery(zp.Public(), "d")); blk != nil {
+		t.Fatal("expired block returned")
+	}
+	if err = m.collect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.cache.Count(); n != 2 {
+		t.Fatalf("%d blocks cached after collection", n)
+	}
+}
@@ -0,0 +1,675 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nse
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//======================================================================
+// "GNUnet Network Size Estimation" implementation
+//======================================================================
+
+// Default parameters (used if not configured)
+var (
+	DefaultInterval  = time.Hour            // length of a round
+	DefaultWorkBits  = 15                   // leading zero bits of proof-of-work
+	DefaultWorkDelay = 5 * time.Millisecond // pause between proof-of-work batches
+)
+
+const (
+	// HistorySize is the number of rounds used for the estimate.
+	HistorySize = 64
+
+	// number of proof-of-work attempts between pauses
+	proofBatch = 10
+
+	// correction of the bias of the estimator (see the NSE paper)
+	sizeCorrection = 0.332747
+)
+
+// Estimate of the network size (as log2 value).
+type Estimate struct {
+	Timestamp util.AbsoluteTime // time of estimate
+	L2NSE     float64           // log2 of the network size
+	StdDev    float64           // standard deviation of L2NSE
+}
+
+// Size returns the estimated number of peers in the network.
+func (e *Estimate) Size() float64 {
+	return math.Exp2(e.L2NSE)
+}
+
+// Message returns the estimate as a client message.
+func (e *Estimate) Message() *message.NSEEstimateMsg {
+	return message.NewNSEEstimateMsg(e.Timestamp, e.L2NSE, e.StdDev)
+}
+
+//----------------------------------------------------------------------
+
+// Core is the set of core services used by the NSE module. It is
+// implemented by core.Core (and by mocks in unit tests).
+type Core interface {
+	PeerID() *util.PeerID
+	Sign(obj crypto.Signable) error
+	Send(ctx context.Context, peer *util.PeerID, msg message.Message) error
+	Register(name string, l *core.Listener)
+}
+
+// connected peer with state of pending transmissions: a new peer first
+// gets the best message of the previous round, then of the current one.
+type nsePeer struct {
+	id       *util.PeerID
+	timer    *time.Timer // pending transmission (or nil)
+	seq      uint64      // sequence number of scheduled transmission
+	previous bool        // done with previous round
+}
+
+// estimate subscriber: only the latest estimate is kept for delivery.
+type subscriber struct {
+	ch chan *Estimate
+}
+
+// push an estimate to a subscriber (replacing an undelivered one).
+func (s *subscriber) push(est *Estimate) {
+	for {
+		select {
+		case s.ch <- est:
+			return
+		default:
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	}
+}
+
+// Module floods proof-of-work messages of peers close to the current
+// round and derives an estimate of the network size from the proximity
+// of the best messages over the last rounds.
+type Module struct {
+	service.ModuleImpl
+
+	core      Core          // reference to core services
+	interval  time.Duration // length of a round
+	workBits  int           // required proof-of-work bits
+	workDelay time.Duration // pause between proof-of-work batches
+	proofFile string        // file for own proof-of-work (or empty)
+
+	mtx     *sync.Mutex                       // lock for round state
+	history [HistorySize]*message.NSEFloodMsg // best flood messages per round
+	idx     int                               // history index of current round
+	count   int                               // number of rounds in history
+	current util.AbsoluteTime                 // start of current round
+	next    *message.NSEFloodMsg              // best early message for next round
+	hopMax  uint32                            // max. hop count in history
+	mean    float64                           // mean of matching bits
+	est     *Estimate                         // current estimate
+	proof   uint64                            // own proof-of-work
+	proved  bool                              // proof-of-work complete?
+	peers   map[string]*nsePeer               // connected peers
+	subs    map[int]*subscriber               // estimate subscribers
+	lastSub int                               // last subscription id
+}
+
+// NewModule returns a new NSE module. The first round starts now; the
+// own proof-of-work is searched in the background (if not persisted).
+func NewModule(ctx context.Context, c Core, cfg *config.NSEConfig) *Module {
+	m := newModule(c, cfg)
+	m.loadProof()
+	m.init(time.Now())
+
+	// register as listener for core events
+	listener := m.Run(ctx, m.event, m.Filter())
+	c.Register("nse", listener)
+
+	// run rounds and proof-of-work
+	go m.rounds(ctx)
+	if !m.proved {
+		go m.findProof(ctx)
+	}
+	return m
+}
+
+// newModule assembles a module without registering it with core or
+// starting background tasks.
+func newModule(c Core, cfg *config.NSEConfig) *Module {
+	m := &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		core:       c,
+		interval:   DefaultInterval,
+		workBits:   DefaultWorkBits,
+		workDelay:  DefaultWorkDelay,
+		mtx:        new(sync.Mutex),
+		idx:        HistorySize - 1,
+		peers:      make(map[string]*nsePeer),
+		subs:       make(map[int]*subscriber),
+	}
+	if cfg != nil {
+		if cfg.Interval > 0 {
+			m.interval = time.Duration(cfg.Interval) * time.Second
+		}
+		if cfg.WorkBits > 0 {
+			m.workBits = cfg.WorkBits
+		}
+		if cfg.WorkDelay > 0 {
+			m.workDelay = time.Duration(cfg.WorkDelay) * time.Millisecond
+		}
+		m.proofFile = cfg.ProofFile
+	}
+	return m
+}
+
+// init round state for the round containing the given time.
+func (m *Module) init(now time.Time) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.current = m.roundOf(now)
+	m.setupOwn(m.idx, m.current)
+	m.count = 1
+	m.update()
+}
+
+//----------------------------------------------------------------------
+// Event handling
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module
+func (m *Module) Filter() *core.EventFilter {
+	f := core.NewEventFilter()
+	f.AddEvent(core.EV_CONNECT)
+	f.AddEvent(core.EV_DISCONNECT)
+	f.AddMsgType(enums.MSG_NSE_P2P_FLOOD)
+	return f
+}
+
+// Event handler for infrastructure signals
+func (m *Module) event(ctx context.Context, ev *core.Event) {
+	switch ev.ID {
+	case core.EV_CONNECT:
+		m.connect(ctx, ev.Peer)
+	case core.EV_DISCONNECT:
+		m.disconnect(ev.Peer)
+	case core.EV_MESSAGE:
+		msg, ok := ev.Msg.(*message.NSEFloodMsg)
+		if !ok {
+			logger.Printf(logger.WARN, "[nse] unexpected message %s from %s", ev.Msg.Type(), ev.Peer.Short())
+			return
+		}
+		if !m.handleFlood(ctx, ev.Peer, msg) {
+			logger.Printf(logger.WARN, "[nse] invalid flood message from %s dropped", ev.Peer.Short())
+		}
+	}
+}
+
+// connect a peer: it gets the best messages of the previous and the
+// current round.
+func (m *Module) connect(ctx context.Context, peer *util.PeerID) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	key := peer.String()
+	if _, ok := m.peers[key]; ok {
+		return
+	}
+	p := &nsePeer{id: peer}
+	m.peers[key] = p
+	m.schedule(ctx, p, m.delay(-1))
+}
+
+// disconnect a peer and cancel pending transmissions.
+func (m *Module) disconnect(peer *util.PeerID) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	key := peer.String()
+	if p, ok := m.peers[key]; ok {
+		m.cancel(p)
+		delete(m.peers, key)
+	}
+}
+
+//----------------------------------------------------------------------
+// Rounds and flooding
+//----------------------------------------------------------------------
+
+// roundOf returns the start of the round containing the given time.
+func (m *Module) roundOf(t time.Time) util.AbsoluteTime {
+	iv := uint64(m.interval.Microseconds())
+	now := uint64(t.UnixMicro())
+	return util.AbsoluteTime{Val: now - now%iv}
+}
+
+// rounds switches to the next round at the end of the current one.
+func (m *Module) rounds(ctx context.Context) {
+	for {
+		m.mtx.Lock()
+		next := m.current.Add(m.interval)
+		m.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			m.mtx.Lock()
+			for _, p := range m.peers {
+				m.cancel(p)
+			}
+			m.mtx.Unlock()
+			return
+		case <-time.After(time.Until(time.UnixMicro(int64(next.Val)))):
+			m.newRound(ctx)
+		}
+	}
+}
+
+// newRound starts the next round: the own message (or a better one that
+// arrived early) becomes the best message of the round.
+func (m *Module) newRound(ctx context.Context) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.current = m.current.Add(m.interval)
+	m.idx = (m.idx + 1) % HistorySize
+	if m.count < HistorySize {
+		m.count++
+	}
+	n := m.next
+	if n != nil && n.SignedBlock.Timestamp.Val == m.current.Val &&
+		n.SignedBlock.MatchingBits > MatchingBits(m.current, m.core.PeerID()) {
+		n.HopCount++
+		m.history[m.idx] = n
+	} else {
+		m.setupOwn(m.idx, m.current)
+	}
+	m.next = nil
+	m.hopMax = 0
+	for _, msg := range m.history {
+		if msg != nil && msg.HopCount > m.hopMax {
+			m.hopMax = msg.HopCount
+		}
+	}
+	m.update()
+	logger.Printf(logger.DBG, "[nse] new round %s (l2nse=%.3f)", m.current, m.est.L2NSE)
+
+	// schedule transmissions to peers: peers with a pending transmission
+	// have not seen the best message of the last round yet.
+	for _, p := range m.peers {
+		if p.timer != nil {
+			m.cancel(p)
+			p.previous = false
+		}
+		offset := 0
+		if !p.previous {
+			offset = -1
+		}
+		m.schedule(ctx, p, m.delay(offset))
+	}
+}
+
+// setupOwn creates the own flood message for a round.
+func (m *Module) setupOwn(idx int, ts util.AbsoluteTime) {
+	self := m.core.PeerID()
+	msg := message.NewNSEFloodMsg(ts, MatchingBits(ts, self), self, m.proof)
+	if m.proved {
+		if err := m.core.Sign(msg); err != nil {
+			logger.Printf(logger.ERROR, "[nse] failed to sign flood message: %s", err.Error())
+		}
+	}
+	m.history[idx] = msg
+}
+
+// bitsDelay returns the delay of a flood message with given matching
+// bits after the start of a round: f/2 - (f/π)·atan(bits - mean).
+func (m *Module) bitsDelay(bits float64) time.Duration {
+	f := float64(m.interval)
+	return time.Duration(f/2 - f/math.Pi*math.Atan(bits-m.mean))
+}
+
+// delay of a transmission to a peer: messages of the previous round are
+// sent immediately (with a small random delay); the best message of the
+// current round is sent at a time depending on its matching bits.
+func (m *Module) delay(offset int) time.Duration {
+	if offset < 0 {
+		return time.Duration(util.RndUInt64()%50) * time.Millisecond
+	}
+	bits := float64(m.history[m.idx].SignedBlock.MatchingBits)
+	d := m.bitsDelay(bits)
+
+	// randomize by the delay difference of one bit spread over max. hops
+	if r := int64(m.bitsDelay(bits-1)-d) / int64(m.hopMax+1); r > 0 {
+		d += time.Duration(util.RndUInt64() % uint64(r+1))
+	}
+	start := time.UnixMicro(int64(m.current.Val))
+	return time.Until(start.Add(d))
+}
+
+// schedule a transmission to a peer (replacing a pending one).
+func (m *Module) schedule(ctx context.Context, p *nsePeer, d time.Duration) {
+	m.cancel(p)
+	seq := p.seq
+	p.timer = time.AfterFunc(d, func() {
+		m.transmit(ctx, p, seq)
+	})
+}
+
+// cancel a pending transmission to a peer.
+func (m *Module) cancel(p *nsePeer) {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.seq++
+}
+
+// transmit the best message of a round to a peer.
+func (m *Module) transmit(ctx context.Context, p *nsePeer, seq uint64) {
+	m.mtx.Lock()
+	if p.seq != seq || ctx.Err() != nil {
+		m.mtx.Unlock()
+		return
+	}
+	p.timer = nil
+	idx := m.idx
+	if !p.previous {
+		idx = (idx + HistorySize - 1) % HistorySize
+		p.previous = true
+		m.schedule(ctx, p, m.delay(0))
+	}
+	msg := m.history[idx]
+	if msg == nil || (msg.HopCount == 0 && !m.proved) {
+		// no data or own proof-of-work not ready
+		m.mtx.Unlock()
+		return
+	}
+	m.mtx.Unlock()
+
+	if err := m.core.Send(ctx, p.id, msg); err != nil {
+		logger.Printf(logger.WARN, "[nse] failed to send flood message to %s: %s", p.id.Short(), err.Error())
+	}
+}
+
+// handleFlood processes a flood message from a connected peer. Returns
+// false if the message was invalid.
+func (m *Module) handleFlood(ctx context.Context, sender *util.PeerID, msg *message.NSEFloodMsg) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	p, ok := m.peers[sender.String()]
+	if !ok {
+		logger.Printf(logger.DBG, "[nse] flood message from unconnected peer %s", sender.Short())
+		return true
+	}
+	sb := msg.SignedBlock
+	iv := uint64(m.interval.Microseconds())
+	var idx int
+	switch sb.Timestamp.Val {
+	case m.current.Val:
+		idx = m.idx
+	case m.current.Val - iv:
+		idx = (m.idx + HistorySize - 1) % HistorySize
+	case m.current.Val + iv:
+		// early message for next round: keep the best
+		if m.next != nil && sb.MatchingBits <= m.next.SignedBlock.MatchingBits {
+			return true
+		}
+		if !m.valid(msg) {
+			return false
+		}
+		m.next = msg
+		return true
+	default:
+		logger.Printf(logger.DBG, "[nse] flood message for round %s ignored", sb.Timestamp)
+		return true
+	}
+	if sb.Origin.Equal(m.core.PeerID()) {
+		// our own message
+		return true
+	}
+	var best uint32
+	if m.history[idx] != nil {
+		best = m.history[idx].SignedBlock.MatchingBits
+	}
+	switch {
+	case sb.MatchingBits == best:
+		// peer knows the best message of the round already
+		if idx == m.idx {
+			m.cancel(p)
+		}
+		p.previous = true
+		return true
+
+	case sb.MatchingBits < best:
+		// push our better message to the peer now
+		if idx != m.idx {
+			p.previous = false
+		}
+		m.schedule(ctx, p, 0)
+		return true
+	}
+	if !m.valid(msg) {
+		return false
+	}
+	// the peer is up-to-date: no more messages of the previous round
+	p.previous = true
+	if idx != m.idx {
+		return true
+	}
+	m.cancel(p)
+
+	// store new best message and forward it to all other peers
+	msg.HopCount++
+	m.history[idx] = msg
+	if msg.HopCount > m.hopMax {
+		m.hopMax = msg.HopCount
+	}
+	m.update()
+	for _, q := range m.peers {
+		if q != p && q.previous {
+			m.schedule(ctx, q, m.delay(0))
+		}
+	}
+	return true
+}
+
+// valid checks matching bits, signature and proof-of-work of a flood
+// message.
+func (m *Module) valid(msg *message.NSEFloodMsg) bool {
+	sb := msg.SignedBlock
+	if sb.MatchingBits != MatchingBits(sb.Timestamp, sb.Origin) {
+		return false
+	}
+	if ok, err := msg.Verify(); !ok || err != nil {
+		return false
+	}
+	return CheckProof(sb.Origin, sb.PoW, m.workBits)
+}
+
+//----------------------------------------------------------------------
+// Estimate
+//----------------------------------------------------------------------
+
+// update the estimate from the history of best messages (weighted
+// incremental mean and variance, West 1979) and notify subscribers.
+func (m *Module) update() {
+	var sumW, mean, vsq float64
+	i := m.idx
+	for j := 0; j < m.count; j++ {
+		if msg := m.history[i]; msg != nil {
+			val := float64(msg.SignedBlock.MatchingBits)
+			w := float64(m.count + 1 - j)
+			tmp := w + sumW
+			q := val - mean
+			r := q * w / tmp
+			mean += r
+			vsq += sumW * q * r
+			sumW = tmp
+		}
+		i = (i + HistorySize - 1) % HistorySize
+	}
+	var stddev float64
+	if m.count > 1 {
+		if v := vsq * float64(m.count) / (sumW * float64(m.count-1)); v > 0 {
+			stddev = math.Sqrt(v)
+		}
+	}
+	m.mean = mean
+
+	// the estimate is at least the number of our neighbors
+	l2 := mean - sizeCorrection
+	if floor := math.Log2(float64(len(m.peers) + 1)); l2 < floor {
+		l2 = floor
+	}
+	m.est = &Estimate{
+		Timestamp: util.AbsoluteTimeNow(),
+		L2NSE:     l2,
+		StdDev:    stddev,
+	}
+	for _, s := range m.subs {
+		s.push(m.est)
+	}
+}
+
+// Current returns the current estimate ["nse:estimate"]
+func (m *Module) Current() *Estimate {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.est
+}
+
+// Subscribe to estimates ["nse:subscribe"]: the callback is called with
+// the current and all future estimates until the context is done.
+func (m *Module) Subscribe(ctx context.Context, fcn func(*Estimate)) {
+	s := &subscriber{ch: make(chan *Estimate, 1)}
+	m.mtx.Lock()
+	m.lastSub++
+	id := m.lastSub
+	m.subs[id] = s
+	if m.est != nil {
+		s.push(m.est)
+	}
+	m.mtx.Unlock()
+
+	go func() {
+		defer func() {
+			m.mtx.Lock()
+			delete(m.subs, id)
+			m.mtx.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case est := <-s.ch:
+				fcn(est)
+			}
+		}
+	}()
+}
+
+//----------------------------------------------------------------------
+// Proof-of-work
+//----------------------------------------------------------------------
+
+// findProof searches the own proof-of-work in batches.
+func (m *Module) findProof(ctx context.Context) {
+	self := m.core.PeerID()
+	m.mtx.Lock()
+	pow := m.proof
+	m.mtx.Unlock()
+	for {
+		for i := 0; i < proofBatch; i++ {
+			if CheckProof(self, pow, m.workBits) {
+				m.proofFound(pow)
+				return
+			}
+			pow++
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.workDelay):
+		}
+	}
+}
+
+// proofFound re-creates the own messages with a new proof-of-work.
+func (m *Module) proofFound(pow uint64) {
+	m.mtx.Lock()
+	m.proof = pow
+	m.proved = true
+	for _, idx := range []int{m.idx, (m.idx + HistorySize - 1) % HistorySize} {
+		if msg := m.history[idx]; msg != nil && msg.HopCount == 0 {
+			m.setupOwn(idx, msg.SignedBlock.Timestamp)
+		}
+	}
+	m.mtx.Unlock()
+	logger.Printf(logger.INFO, "[nse] proof-of-work %d found", pow)
+
+	if len(m.proofFile) > 0 {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], pow)
+		if err := os.WriteFile(m.proofFile, buf[:], 0o600); err != nil {
+			logger.Printf(logger.ERROR, "[nse] failed to save proof-of-work: %s", err.Error())
+		}
+	}
+}
+
+// loadProof reads a persisted proof-of-work.
+func (m *Module) loadProof() {
+	if len(m.proofFile) == 0 {
+		return
+	}
+	buf, err := os.ReadFile(m.proofFile)
+	if err != nil || len(buf) != 8 {
+		return
+	}
+	pow := binary.LittleEndian.Uint64(buf)
+	if CheckProof(m.core.PeerID(), pow, m.workBits) {
+		m.proof, m.proved = pow, true
+	}
+}
+
+//----------------------------------------------------------------------
+// Inter-module linkage helpers
+//----------------------------------------------------------------------
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["nse:estimate"] = m.Current
+	fcn["nse:subscribe"] = m.Subscribe
+}
+
+// Import functions
+func (m *Module) Import(fcn map[string]any) {
+	// nothing to import for now.
+}
@@ -0,0 +1,329 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nse
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+)
+
+//----------------------------------------------------------------------
+// Test harness: NSE modules connected by an in-memory network.
+//----------------------------------------------------------------------
+
+// mockNet delivers flood messages between modules.
+type mockNet struct {
+	sync.Mutex
+	mods map[string]*Module
+}
+
+// mockCore signs with a private key and sends via the mock network.
+type mockCore struct {
+	prv  *ed25519.PrivateKey
+	id   *util.PeerID
+	net  *mockNet
+	sent int
+}
+
+func (c *mockCore) PeerID() *util.PeerID {
+	return c.id
+}
+
+func (c *mockCore) Sign(obj crypto.Signable) error {
+	sig, err := c.prv.EdSign(obj.SignedData())
+	if err != nil {
+		return err
+	}
+	return obj.SetSignature(util.NewPeerSignature(sig.Bytes()))
+}
+
+// Send a message (marshalled and unmarshalled like on the wire).
+func (c *mockCore) Send(ctx context.Context, peer *util.PeerID, msg message.Message) error {
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	in := message.NewNSEFloodMsg(util.AbsoluteTimeNever(), 0, nil, 0)
+	if err = data.Unmarshal(in, buf); err != nil {
+		return err
+	}
+	c.net.Lock()
+	c.sent++
+	m := c.net.mods[peer.String()]
+	c.net.Unlock()
+	if m != nil {
+		go m.handleFlood(ctx, c.id, in)
+	}
+	return nil
+}
+
+func (c *mockCore) Register(name string, l *core.Listener) {}
+
+// newTestModule creates a module with a proof-of-work of given difficulty.
+func newTestModule(t *testing.T, n *mockNet, interval time.Duration, bits int) *Module {
+	t.Helper()
+	_, prv := ed25519.NewKeypair()
+	c := &mockCore{
+		prv: prv,
+		id:  util.NewPeerID(prv.Public().Bytes()),
+		net: n,
+	}
+	m := newModule(c, nil)
+	m.interval = interval
+	m.workBits = bits
+	for !CheckProof(c.id, m.proof, bits) {
+		m.proof++
+	}
+	m.proved = true
+	if n != nil {
+		n.Lock()
+		n.mods[c.id.String()] = m
+		n.Unlock()
+	}
+	return m
+}
+
+//----------------------------------------------------------------------
+
+func TestMatchingBits(t *testing.T) {
+	if n := leadingZeros([]byte{0, 0x10, 0xff}); n != 11 {
+		t.Fatalf("leading zeros: %d != 11", n)
+	}
+	if n := leadingZeros(make([]byte, 4)); n != 32 {
+		t.Fatalf("leading zeros: %d != 32", n)
+	}
+	// matching bits are deterministic and differ between rounds
+	peer := util.NewPeerID(util.NewRndArray(32))
+	ts := util.AbsoluteTimeNow()
+	seen := make(map[uint32]bool)
+	for i := 0; i < 32; i++ {
+		round := ts.Add(time.Duration(i) * time.Hour)
+		bits := MatchingBits(round, peer)
+		if bits != MatchingBits(round, peer) {
+			t.Fatal("matching bits not deterministic")
+		}
+		seen[bits] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("matching bits independent of round")
+	}
+}
+
+func TestProofOfWork(t *testing.T) {
+	peer := util.NewPeerID(util.NewRndArray(32))
+	var pow uint64
+	for !CheckProof(peer, pow, 4) {
+		pow++
+	}
+	if !CheckProof(peer, pow, 0) {
+		t.Fatal("proof fails lower difficulty")
+	}
+	if pow > 0 && CheckProof(peer, pow-1, 4) {
+		t.Fatal("proof search skipped a valid proof")
+	}
+	// proofs are bound to the peer
+	other := util.NewPeerID(util.NewRndArray(32))
+	fails := 0
+	for i := uint64(0); i < 4; i++ {
+		if !CheckProof(other, pow+i, 8) {
+			fails++
+		}
+	}
+	if fails == 0 {
+		t.Fatal("proof-of-work too easy")
+	}
+}
+
+func TestFloodMessage(t *testing.T) {
+	m := newTestModule(t, nil, time.Hour, 2)
+	m.init(time.Now())
+	msg := m.history[m.idx]
+
+	// wire format
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != int(msg.Size()) || len(buf) != 132 {
+		t.Fatalf("flood message size %d (header %d)", len(buf), msg.Size())
+	}
+	in := message.NewNSEFloodMsg(util.AbsoluteTimeNever(), 0, nil, 0)
+	if err = data.Unmarshal(in, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !m.valid(in) {
+		t.Fatal("own flood message invalid")
+	}
+	// hop count is not signed
+	in.HopCount = 5
+	if !m.valid(in) {
+		t.Fatal("flood message with hops invalid")
+	}
+	// forged matching bits
+	in.SignedBlock.MatchingBits++
+	if m.valid(in) {
+		t.Fatal("forged matching bits accepted")
+	}
+	in.SignedBlock.MatchingBits--
+	in.SignedBlock.PoW++
+	if m.valid(in) {
+		t.Fatal("modified proof-of-work accepted")
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	m := newTestModule(t, nil, time.Hour, 1)
+	setHistory := func(bits ...uint32) {
+		m.history = [HistorySize]*message.NSEFloodMsg{}
+		m.count = len(bits)
+		for i, b := range bits {
+			idx := (m.idx + HistorySize - i) % HistorySize
+			m.history[idx] = message.NewNSEFloodMsg(util.AbsoluteTimeNow(), b, nil, 0)
+		}
+		m.update()
+	}
+	// constant matching bits
+	setHistory(12, 12, 12, 12)
+	if est := m.Current(); math.Abs(est.L2NSE-(12-sizeCorrection)) > 1e-9 || est.StdDev != 0 {
+		t.Fatalf("estimate %v", est)
+	}
+	// varying matching bits: mean weighted towards recent rounds
+	setHistory(14, 10)
+	est := m.Current()
+	if est.L2NSE+sizeCorrection <= 12 || est.L2NSE+sizeCorrection >= 14 || est.StdDev == 0 {
+		t.Fatalf("estimate %v", est)
+	}
+	// estimate is at least the number of neighbors
+	for i := 0; i < 7; i++ {
+		m.peers[string(rune('a'+i))] = &nsePeer{}
+	}
+	setHistory(1)
+	if est := m.Current(); est.L2NSE != 3 {
+		t.Fatalf("estimate %v", est)
+	}
+}
+
+func TestFlooding(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// chain of peers: 0 - 1 - 2 - 3 - 4
+	n := &mockNet{mods: make(map[string]*Module)}
+	mods := make([]*Module, 5)
+	now := time.Now()
+	var best uint32
+	for i := range mods {
+		mods[i] = newTestModule(t, n, time.Second, 1)
+		mods[i].init(now)
+		if bits := mods[i].history[mods[i].idx].SignedBlock.MatchingBits; bits > best {
+			best = bits
+		}
+	}
+	ests := make(chan *Estimate, 16)
+	mods[0].Subscribe(ctx, func(est *Estimate) {
+		ests <- est
+	})
+	for i := 1; i < len(mods); i++ {
+		mods[i-1].connect(ctx, mods[i].core.PeerID())
+		mods[i].connect(ctx, mods[i-1].core.PeerID())
+	}
+	// wait for the best message to reach all peers
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		done := true
+		for _, m := range mods {
+			m.mtx.Lock()
+			if m.history[m.idx].SignedBlock.MatchingBits != best {
+				done = false
+			}
+			m.mtx.Unlock()
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("best flood message not received by all peers")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	// subscriber gets the final estimate
+	want := mods[0].Current().L2NSE
+	for {
+		select {
+		case est := <-ests:
+			if est.L2NSE == want {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("estimate not delivered to subscriber")
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+
+// responder collects messages sent to a client.
+type responder struct {
+	ch chan message.Message
+}
+
+func (r *responder) Send(ctx context.Context, msg message.Message) error {
+	r.ch <- msg
+	return nil
+}
+
+func (r *responder) Receiver() *util.PeerID {
+	return nil
+}
+
+func TestServiceStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := newTestModule(t, nil, time.Hour, 1)
+	m.init(time.Now())
+	srv := &Service{Module: m}
+	back := &responder{ch: make(chan message.Message, 1)}
+	if !srv.HandleMessage(ctx, nil, message.NewNSEStartMsg(), back) {
+		t.Fatal("start message not handled")
+	}
+	select {
+	case msg := <-back.ch:
+		em, ok := msg.(*message.NSEEstimateMsg)
+		if !ok {
+			t.Fatalf("unexpected message %v", msg)
+		}
+		if est, _ := em.Estimate(); est != m.Current().L2NSE {
+			t.Fatalf("estimate %f != %f", est, m.Current().L2NSE)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no estimate sent")
+	}
+}
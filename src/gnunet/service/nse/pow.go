@@ -0,0 +1,73 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nse
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"gnunet/crypto"
+	"gnunet/util"
+
+	"golang.org/x/crypto/argon2"
+)
+
+//----------------------------------------------------------------------
+// Proof-of-work and proximity of peers to a round
+//----------------------------------------------------------------------
+
+// powSalt is the salt for the Argon2id hash of proof-of-work values.
+const powSalt = "gnunet-nse-proof"
+
+// leadingZeros returns the number of leading zero bits in a byte array
+// (most significant bit first).
+func leadingZeros(buf []byte) int {
+	for i, b := range buf {
+		if b != 0 {
+			return 8*i + bits.LeadingZeros8(b)
+		}
+	}
+	return 8 * len(buf)
+}
+
+// MatchingBits returns the number of leading bits shared by the hash of
+// a round timestamp and the hash of a peer identity. The timestamp is
+// hashed in host byte order (little-endian) for compatibility with the
+// C implementation.
+func MatchingBits(ts util.AbsoluteTime, peer *util.PeerID) uint32 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], ts.Val)
+	h1 := crypto.Hash(buf[:]).Data
+	h2 := crypto.Hash(peer.Data).Data
+	diff := make([]byte, len(h1))
+	for i := range diff {
+		diff[i] = h1[i] ^ h2[i]
+	}
+	return uint32(leadingZeros(diff))
+}
+
+// CheckProof returns true if the proof-of-work value for a peer has at
+// least the required number of leading zero bits.
+func CheckProof(peer *util.PeerID, pow uint64, workBits int) bool {
+	buf := make([]byte, 8+len(peer.Data))
+	binary.LittleEndian.PutUint64(buf, pow)
+	copy(buf[8:], peer.Data)
+	res := argon2.IDKey(buf, []byte(powSalt), 3, 1024, 1, 64)
+	return leadingZeros(res) >= workBits
+}
@@ -0,0 +1,70 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nse
+
+import (
+	"net/http"
+
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for NSE-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to NSE module
+}
+
+//----------------------------------------------------------------------
+// Command "NSE.Estimate"
+//----------------------------------------------------------------------
+
+// EstimateRequest asks for the current network size estimate.
+type EstimateRequest struct{}
+
+// EstimateResponse returns the current network size estimate.
+type EstimateResponse struct {
+	Timestamp string  `json:"timestamp"` // time of estimate
+	L2NSE     float64 `json:"l2nse"`     // log2 of network size
+	StdDev    float64 `json:"stddev"`    // standard deviation of l2nse
+	Size      float64 `json:"size"`      // estimated number of peers
+}
+
+// Estimate returns the current network size estimate.
+func (s *RPCService) Estimate(r *http.Request, req *EstimateRequest, reply *EstimateResponse) error {
+	est := s.m.Current()
+	*reply = EstimateResponse{
+		Timestamp: est.Timestamp.String(),
+		L2NSE:     est.L2NSE,
+		StdDev:    est.StdDev,
+		Size:      est.Size(),
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "NSE"); err != nil {
+		logger.Printf(logger.ERROR, "[nse] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,115 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nse
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet NSE" service implementation
+//----------------------------------------------------------------------
+
+// Service implements a network size estimation service
+type Service struct {
+	*Module
+}
+
+// NewService creates a new NSE service instance
+func NewService(ctx context.Context, c *core.Core, cfg *config.NSEConfig) *Service {
+	return &Service{
+		Module: NewModule(ctx, c, cfg),
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[nse:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[nse:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[nse:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[nse:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[nse:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	mc.Close()
+
+	// cancel all tasks running for this session/connection (this ends
+	// the estimate subscription of the client)
+	logger.Printf(logger.INFO, "[nse:%d] Start closing session...\n", id)
+	cancel()
+}
+
+// HandleMessage handles a single incoming client message.
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	switch msg.(type) {
+	case *message.NSEStartMsg:
+		//----------------------------------------------------------
+		// NSE_START: push current and future estimates to client
+		//----------------------------------------------------------
+		logger.Printf(logger.INFO, "[nse%s] Start request received.\n", label)
+		s.Subscribe(ctx, func(est *Estimate) {
+			if err := back.Send(ctx, est.Message()); err != nil {
+				logger.Printf(logger.ERROR, "[nse%s] Failed to send estimate: %s\n", label, err.Error())
+			}
+		})
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[nse%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package peerstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/store"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//======================================================================
+// "Peerstore" implementation: Services store values for peers (HELLOs,
+// addresses, connection metrics,...) under a sub-system name and a key.
+// Records are kept by a persistence backend until they expire; clients
+// can watch a (sub-system,peer,key) triple for new records.
+//======================================================================
+
+// Error codes
+var (
+	ErrRecordInvalid = errors.New("invalid peerstore record")
+)
+
+// CollectPeriod is the time between removals of expired records.
+var CollectPeriod = 15 * time.Minute
+
+// WatchQueue is the number of notifications queued for a watcher; more
+// notifications are dropped until the watcher catches up.
+var WatchQueue = 32
+
+// watcher receives new records for a key hash.
+type watcher struct {
+	ch chan *store.PeerstoreRecord
+}
+
+// Module handles the storage of peer metadata.
+type Module struct {
+	service.ModuleImpl
+
+	db       store.PeerstoreDB           // persistence backend
+	mtx      sync.Mutex                  // lock for watchers
+	watchers map[string]map[int]*watcher // watchers per key hash
+	lastID   int                         // last watcher identifier
+}
+
+// NewModule creates a new module instance with the configured backend
+// (transient storage if no backend is configured); expired records are
+// removed periodically.
+func NewModule(ctx context.Context, cfg *config.PeerstoreConfig) *Module {
+	spec := util.ParameterSet{"backend": "memory"}
+	if cfg != nil && cfg.Storage != nil {
+		spec = cfg.Storage
+	}
+	db, err := store.NewPeerstoreDB(spec)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[peerstore] Failed to open backend: %s", err.Error())
+		return nil
+	}
+	m := newModule(db)
+	if err = service.Schedule(ctx, "peerstore:collect", CollectPeriod, m.collect); err != nil {
+		logger.Printf(logger.ERROR, "[peerstore] job 'peerstore:collect' not scheduled: %s", err.Error())
+	}
+	go func() {
+		<-ctx.Done()
+		m.db.Close()
+	}()
+	return m
+}
+
+// create module for given backend
+func newModule(db store.PeerstoreDB) *Module {
+	return &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		db:         db,
+		watchers:   make(map[string]map[int]*watcher),
+	}
+}
+
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module: the peerstore only
+// serves local clients.
+func (m *Module) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["peerstore:store"] = m.Store
+	fcn["peerstore:iterate"] = m.Iterate
+	fcn["peerstore:watch"] = m.Watch
+}
+
+// Import functions
+func (m *Module) Import(fcm map[string]any) {
+	// nothing to import now.
+}
+
+//----------------------------------------------------------------------
+
+// Store a record; existing values of the key are replaced if requested.
+// Watchers of the key are notified ["peerstore:store"]
+func (m *Module) Store(ctx context.Context, rec *store.PeerstoreRecord, replace bool) error {
+	if len(rec.SubSystem) == 0 || len(rec.Key) == 0 || rec.Peer == nil {
+		return ErrRecordInvalid
+	}
+	if rec.Expire.Expired() {
+		return nil
+	}
+	if err := m.db.Store(rec, replace); err != nil {
+		return err
+	}
+	m.notify(rec)
+	return nil
+}
+
+// Iterate returns the unexpired records of a sub-system; a nil peer or an
+// empty key match all records ["peerstore:iterate"]
+func (m *Module) Iterate(ctx context.Context, subSystem string, peer *util.PeerID, key string) ([]*store.PeerstoreRecord, error) {
+	return m.db.Iterate(subSystem, peer, key)
+}
+
+// Watch a key hash (see message.PeerstoreKeyHash): new records for the
+// key are passed to the callback until the context is done or the watch
+// is cancelled. Returns the watch identifier ["peerstore:watch"]
+func (m *Module) Watch(ctx context.Context, kh *crypto.HashCode, fcn func(*store.PeerstoreRecord)) int {
+	w := &watcher{ch: make(chan *store.PeerstoreRecord, WatchQueue)}
+	key := kh.String()
+	m.mtx.Lock()
+	m.lastID++
+	id := m.lastID
+	list, ok := m.watchers[key]
+	if !ok {
+		list = make(map[int]*watcher)
+		m.watchers[key] = list
+	}
+	list[id] = w
+	m.mtx.Unlock()
+
+	go func() {
+		defer m.Unwatch(kh, id)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rec, ok := <-w.ch:
+				if !ok {
+					return
+				}
+				fcn(rec)
+			}
+		}
+	}()
+	return id
+}
+
+// Unwatch cancels a watch.
+func (m *Module) Unwatch(kh *crypto.HashCode, id int) {
+	key := kh.String()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if w, ok := m.watchers[key][id]; ok {
+		delete(m.watchers[key], id)
+		if len(m.watchers[key]) == 0 {
+			delete(m.watchers, key)
+		}
+		close(w.ch)
+	}
+}
+
+// notify watchers of a key about a new record.
+func (m *Module) notify(rec *store.PeerstoreRecord) {
+	key := message.PeerstoreKeyHash(rec.SubSystem, rec.Peer, rec.Key).String()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for id, w := range m.watchers[key] {
+		select {
+		case w.ch <- rec:
+		default:
+			logger.Printf(logger.WARN, "[peerstore] watcher #%d busy -- record dropped", id)
+		}
+	}
+}
+
+// collect expired records (scheduled job)
+func (m *Module) collect(ctx context.Context) error {
+	n, err := m.db.Collect()
+	if err == nil && n > 0 {
+		logger.Printf(logger.INFO, "[peerstore] %d expired records removed", n)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package peerstore
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"gnunet/service"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for peerstore-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to peerstore module
+}
+
+//----------------------------------------------------------------------
+// Command "Peerstore.Status"
+//----------------------------------------------------------------------
+
+// StatusRequest asks for the state of the peerstore
+type StatusRequest struct{}
+
+// StatusResponse returns the number of stored records
+type StatusResponse struct {
+	Records int `json:"records"` // number of stored records
+	Watches int `json:"watches"` // number of watched keys
+}
+
+// Status returns the state of the peerstore.
+func (s *RPCService) Status(r *http.Request, req *StatusRequest, reply *StatusResponse) (err error) {
+	var n int
+	if n, err = s.m.db.Count(); err != nil {
+		return
+	}
+	s.m.mtx.Lock()
+	w := len(s.m.watchers)
+	s.m.mtx.Unlock()
+	*reply = StatusResponse{Records: n, Watches: w}
+	return
+}
+
+//----------------------------------------------------------------------
+// Command "Peerstore.Iterate"
+//----------------------------------------------------------------------
+
+// IterateRequest asks for the records of a sub-system (optionally for a
+// peer and/or a key only)
+type IterateRequest struct {
+	SubSystem string `json:"subsystem"`      // name of sub-system
+	Peer      string `json:"peer,omitempty"` // peer identity
+	Key       string `json:"key,omitempty"`  // record key
+}
+
+// Record is a peerstore record in a response
+type Record struct {
+	Peer   string `json:"peer"`   // peer identity
+	Key    string `json:"key"`    // record key
+	Value  string `json:"value"`  // record value (base64-encoded)
+	Expire string `json:"expire"` // expiration of record
+}
+
+// IterateResponse returns the matching records
+type IterateResponse struct {
+	Records []*Record `json:"records"`
+}
+
+// Iterate returns the matching records.
+func (s *RPCService) Iterate(r *http.Request, req *IterateRequest, reply *IterateResponse) (err error) {
+	var peer *util.PeerID
+	if len(req.Peer) > 0 {
+		var buf []byte
+		if buf, err = util.DecodeStringToBinary(req.Peer, 32); err != nil {
+			return
+		}
+		peer = util.NewPeerID(buf)
+	}
+	list, err := s.m.Iterate(r.Context(), req.SubSystem, peer, req.Key)
+	if err != nil {
+		return
+	}
+	reply.Records = make([]*Record, len(list))
+	for i, rec := range list {
+		reply.Records[i] = &Record{
+			Peer:   rec.Peer.String(),
+			Key:    rec.Key,
+			Value:  base64.StdEncoding.EncodeToString(rec.Value),
+			Expire: rec.Expire.String(),
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "Peerstore"); err != nil {
+		logger.Printf(logger.ERROR, "[peerstore] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,196 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package peerstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/store"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Peerstore" socket service implementation:
+// A STORE request is not answered; an ITERATE request is answered with
+// an ITERATE_RECORD message for each matching record followed by an
+// ITERATE_END message. After a WATCH request new records for the key
+// hash are sent as WATCH_RECORD messages until the watch is cancelled
+// (WATCH_CANCEL) or the client disconnects.
+//----------------------------------------------------------------------
+
+// Service implements a peerstore service
+type Service struct {
+	*Module
+
+	cmtx    sync.Mutex                             // lock for client watches
+	watches map[transport.Responder]map[string]int // watch ids of clients
+}
+
+// NewService creates a new peerstore service instance
+func NewService(ctx context.Context, cfg *config.PeerstoreConfig) service.Service {
+	mod := NewModule(ctx, cfg)
+	if mod == nil {
+		return nil
+	}
+	return newService(mod)
+}
+
+// create service for given module
+func newService(mod *Module) *Service {
+	return &Service{
+		Module:  mod,
+		watches: make(map[transport.Responder]map[string]int),
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[peerstore:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[peerstore:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[peerstore:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[peerstore:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[peerstore:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	mc.Close()
+
+	// cancel all tasks running for this session/connection (this ends
+	// the watches of the client)
+	logger.Printf(logger.INFO, "[peerstore:%d] Start closing session...\n", id)
+	cancel()
+	s.cmtx.Lock()
+	delete(s.watches, mc)
+	s.cmtx.Unlock()
+}
+
+// HandleMessage processes a single incoming message
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	send := func(resp message.Message) bool {
+		if err := back.Send(ctx, resp); err != nil {
+			logger.Printf(logger.ERROR, "[peerstore%s] Failed to send response: %s\n", label, err.Error())
+			return false
+		}
+		return true
+	}
+	switch m := msg.(type) {
+
+	case *message.PeerstoreStoreMsg:
+		//----------------------------------------------------------
+		// STORE: add record
+		//----------------------------------------------------------
+		rec := &store.PeerstoreRecord{
+			SubSystem: m.SubSystem(),
+			Peer:      m.PeerID(),
+			Key:       m.Key(),
+			Value:     m.Value,
+			Expire:    m.Expire,
+		}
+		if err := s.Store(ctx, rec, m.Options == message.PeerstoreReplace); err != nil {
+			logger.Printf(logger.WARN, "[peerstore%s] Record not stored: %s", label, err.Error())
+		}
+
+	case *message.PeerstoreIterateMsg:
+		//----------------------------------------------------------
+		// ITERATE: send matching records
+		//----------------------------------------------------------
+		list, err := s.Iterate(ctx, m.SubSystem(), m.PeerID(), m.Key())
+		if err != nil {
+			logger.Printf(logger.ERROR, "[peerstore%s] Iteration failed: %s", label, err.Error())
+		}
+		for _, rec := range list {
+			if !send(message.NewPeerstoreIterateRecordMsg(rec.SubSystem, rec.Peer, rec.Key, rec.Value, rec.Expire)) {
+				return false
+			}
+		}
+		return send(message.NewPeerstoreIterateEndMsg())
+
+	case *message.PeerstoreWatchMsg:
+		//----------------------------------------------------------
+		// WATCH: send new records for key hash
+		//----------------------------------------------------------
+		key := m.KeyHash.String()
+		s.cmtx.Lock()
+		list, ok := s.watches[back]
+		if !ok {
+			list = make(map[string]int)
+			s.watches[back] = list
+		}
+		if _, ok = list[key]; !ok {
+			list[key] = s.Watch(ctx, m.KeyHash, func(rec *store.PeerstoreRecord) {
+				send(message.NewPeerstoreWatchRecordMsg(rec.SubSystem, rec.Peer, rec.Key, rec.Value, rec.Expire))
+			})
+		}
+		s.cmtx.Unlock()
+
+	case *message.PeerstoreWatchCancelMsg:
+		//----------------------------------------------------------
+		// WATCH_CANCEL: stop sending records for key hash
+		//----------------------------------------------------------
+		key := m.KeyHash.String()
+		s.cmtx.Lock()
+		id, ok := s.watches[back][key]
+		delete(s.watches[back], key)
+		s.cmtx.Unlock()
+		if ok {
+			s.Unwatch(m.KeyHash, id)
+		}
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[peerstore%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package peerstore
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/servicetest"
+	"gnunet/service/store"
+	"gnunet/util"
+)
+
+// newPeer returns a random peer identity.
+func newPeer() *util.PeerID {
+	return util.NewPeerID(util.NewRndArray(32))
+}
+
+func TestService(t *testing.T) {
+	for _, backend := range []string{"memory", "sqlite3"} {
+		t.Run(backend, func(t *testing.T) {
+			spec := util.ParameterSet{
+				"backend": backend,
+				"file":    filepath.Join(t.TempDir(), "peerstore.sqlite3"),
+			}
+			db, err := store.NewPeerstoreDB(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			testService(t, newService(newModule(db)))
+		})
+	}
+}
+
+func testService(t *testing.T, s *Service) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	back := servicetest.NewResponder(t, enums.MSG_PEERSTORE_WATCH_RECORD)
+	p1, p2 := newPeer(), newPeer()
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+
+	handle := func(msg message.Message) {
+		t.Helper()
+		if !s.HandleMessage(ctx, nil, servicetest.Wire(t, msg), back) {
+			t.Fatalf("%s not handled", msg.Type())
+		}
+	}
+	iterate := func(peer *util.PeerID, key string) (values []string) {
+		t.Helper()
+		handle(message.NewPeerstoreIterateMsg("transport", peer, key))
+		list := back.Take()
+		if _, ok := list[len(list)-1].(*message.PeerstoreIterateEndMsg); !ok {
+			t.Fatalf("iteration not ended: %v", list)
+		}
+		for _, msg := range list[:len(list)-1] {
+			rec, ok := msg.(*message.PeerstoreIterateRecordMsg)
+			if !ok {
+				t.Fatalf("unexpected message %s", msg)
+			}
+			values = append(values, rec.Key()+"="+string(rec.Value))
+		}
+		return
+	}
+
+	// watch a key before storing records
+	kh := message.PeerstoreKeyHash("transport", p1, "rtt")
+	handle(message.NewPeerstoreWatchMsg(kh))
+
+	// store records (multiple values and replacement)
+	handle(message.NewPeerstoreStoreMsg("transport", p1, "addr", []byte("ip+udp://1.2.3.4:2086"), expire, message.PeerstoreMultiple))
+	handle(message.NewPeerstoreStoreMsg("transport", p1, "addr", []byte("ip+udp://[::1]:2086"), expire, message.PeerstoreMultiple))
+	handle(message.NewPeerstoreStoreMsg("transport", p1, "rtt", []byte("120"), expire, message.PeerstoreReplace))
+	handle(message.NewPeerstoreStoreMsg("transport", p1, "rtt", []byte("80"), expire, message.PeerstoreReplace))
+	handle(message.NewPeerstoreStoreMsg("transport", p2, "rtt", []byte("40"), expire, message.PeerstoreReplace))
+	handle(message.NewPeerstoreStoreMsg("dht", p1, "hello", []byte("gnunet://hello/..."), expire, message.PeerstoreReplace))
+	// expired records are ignored
+	handle(message.NewPeerstoreStoreMsg("transport", p2, "addr", []byte("old"), util.AbsoluteTimeNow(), message.PeerstoreReplace))
+
+	if n := len(iterate(nil, "")); n != 4 {
+		t.Fatalf("expected 4 records, got %d", n)
+	}
+	if n := len(iterate(p1, "addr")); n != 2 {
+		t.Fatalf("expected 2 addresses, got %d", n)
+	}
+	if v := iterate(p1, "rtt"); len(v) != 1 || v[0] != "rtt=80" {
+		t.Fatalf("unexpected rtt values %v", v)
+	}
+	if v := iterate(p2, ""); len(v) != 1 || v[0] != "rtt=40" {
+		t.Fatalf("unexpected records for p2 %v", v)
+	}
+
+	// watch notifications for both rtt records of p1
+	recs := back.WaitAsync(2)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 watch records, got %d", len(recs))
+	}
+	for i, val := range []string{"120", "80"} {
+		if rec, ok := recs[i].(*message.PeerstoreWatchRecordMsg); !ok || !rec.PeerID().Equal(p1) || rec.Key() != "rtt" || string(rec.Value) != val {
+			t.Fatalf("unexpected watch record %s", rec)
+		}
+	}
+
+	// no notifications after cancel
+	handle(message.NewPeerstoreWatchCancelMsg(kh))
+	handle(message.NewPeerstoreStoreMsg("transport", p1, "rtt", []byte("60"), expire, message.PeerstoreReplace))
+	time.Sleep(50 * time.Millisecond)
+	if list := back.TakeAsync(); len(list) != 0 {
+		t.Fatalf("unexpected records after cancel: %v", list)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	spec := util.ParameterSet{
+		"file": filepath.Join(t.TempDir(), "peerstore.sqlite3"),
+	}
+	ctx := context.Background()
+	peer := newPeer()
+	hello := []byte("gnunet://hello/...")
+
+	db, err := store.NewPeerstoreDB(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newModule(db)
+	rec := &store.PeerstoreRecord{
+		SubSystem: "dht",
+		Peer:      peer,
+		Key:       "hello",
+		Value:     hello,
+		Expire:    util.AbsoluteTimeNow().Add(time.Hour),
+	}
+	if err = m.Store(ctx, rec, true); err != nil {
+		t.Fatal(err)
+	}
+	// records with missing fields are rejected
+	if err = m.Store(ctx, &store.PeerstoreRecord{SubSystem: "dht", Peer: peer}, true); err != ErrRecordInvalid {
+		t.Fatalf("expected invalid record, got %v", err)
+	}
+	db.Close()
+
+	// records survive a restart
+	if db, err = store.NewPeerstoreDB(spec); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m = newModule(db)
+	list, err := m.Iterate(ctx, "dht", peer, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !bytes.Equal(list[0].Value, hello) || !list[0].Peer.Equal(peer) {
+		t.Fatalf("unexpected records %v", list)
+	}
+	if list[0].Expire.Compare(rec.Expire) != 0 {
+		t.Fatalf("expiration changed: %s != %s", list[0].Expire, rec.Expire)
+	}
+}
+
+func TestKeyHash(t *testing.T) {
+	peer := newPeer()
+	kh := message.PeerstoreKeyHash("transport", peer, "addr")
+	if !kh.Equal(message.PeerstoreKeyHash("transport", peer, "addr")) {
+		t.Fatal("key hash not deterministic")
+	}
+	for _, other := range []*crypto.HashCode{
+		message.PeerstoreKeyHash("transpor", peer, "taddr"),
+		message.PeerstoreKeyHash("transport", newPeer(), "addr"),
+		message.PeerstoreKeyHash("transport", peer, "add"),
+	} {
+		if kh.Equal(other) {
+			t.Fatal("key hash collision")
+		}
+	}
+}
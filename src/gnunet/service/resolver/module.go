@@ -0,0 +1,78 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package resolver
+
+import (
+	"context"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Module handles DNS lookups of local clients.
+type Module struct {
+	service.ModuleImpl
+	*Resolver
+}
+
+// NewModule creates a new module instance; expired results are purged
+// from the cache periodically.
+func NewModule(ctx context.Context, cfg *config.ResolverConfig) *Module {
+	m := &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		Resolver:   NewResolver(cfg),
+	}
+	if err := service.Schedule(ctx, "resolver:purge", m.TTL, m.purgeJob); err != nil {
+		logger.Printf(logger.ERROR, "[resolver] job 'resolver:purge' not scheduled: %s", err.Error())
+	}
+	return m
+}
+
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module: the resolver service
+// only serves local clients.
+func (m *Module) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["resolver:lookup"] = m.Lookup
+	fcn["resolver:reverse"] = m.Reverse
+}
+
+// Import functions
+func (m *Module) Import(fcm map[string]any) {
+	// nothing to import now.
+}
+
+//----------------------------------------------------------------------
+
+// purge expired results (scheduled job)
+func (m *Module) purgeJob(ctx context.Context) error {
+	if n := m.Purge(); n > 0 {
+		logger.Printf(logger.DBG, "[resolver] %d cached results expired", n)
+	}
+	return nil
+}
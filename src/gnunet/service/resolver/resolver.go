@@ -0,0 +1,246 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+	"gnunet/transport"
+)
+
+//======================================================================
+// DNS stub resolver backed by the system resolver: A and AAAA records
+// of a hostname are queried in parallel; results (and failures) are
+// cached for a limited time. All DNS lookups of hostnames (outside of
+// GNS resolution) should use this resolver.
+//======================================================================
+
+// Error codes
+var (
+	ErrResolverNoResult  = errors.New("no result")
+	ErrResolverFamily    = errors.New("unknown address family")
+	ErrResolverLocalOnly = errors.New("lookup refused (local-only mode)")
+)
+
+// Default settings of a resolver
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultTTL     = 5 * time.Minute
+	DefaultNegTTL  = 30 * time.Second
+)
+
+// cached result of a lookup
+type cacheEntry struct {
+	ips     []net.IP  // addresses of hostname
+	names   []string  // hostnames of address
+	err     error     // failed lookup
+	expires time.Time // end of lifetime
+}
+
+// Resolver looks up the IP addresses of hostnames (and the hostnames of
+// IP addresses) with the system resolver.
+type Resolver struct {
+	Timeout time.Duration // time limit for a lookup
+	TTL     time.Duration // lifetime of cached results
+	NegTTL  time.Duration // lifetime of cached failures
+
+	mtx   sync.Mutex
+	cache map[string]*cacheEntry
+
+	// lookup functions (system resolver)
+	lookupIP   func(ctx context.Context, network, host string) ([]net.IP, error)
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+}
+
+// NewResolver creates a resolver for the given configuration (defaults
+// are used for missing settings).
+func NewResolver(cfg *config.ResolverConfig) *Resolver {
+	r := &Resolver{
+		Timeout:    DefaultTimeout,
+		TTL:        DefaultTTL,
+		NegTTL:     DefaultNegTTL,
+		cache:      make(map[string]*cacheEntry),
+		lookupIP:   net.DefaultResolver.LookupIP,
+		lookupAddr: net.DefaultResolver.LookupAddr,
+	}
+	if cfg != nil {
+		if cfg.Timeout > 0 {
+			r.Timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		if cfg.TTL > 0 {
+			r.TTL = time.Duration(cfg.TTL) * time.Second
+		}
+		if cfg.NegTTL > 0 {
+			r.NegTTL = time.Duration(cfg.NegTTL) * time.Second
+		}
+	}
+	return r
+}
+
+// Lookup returns the IP addresses of a hostname in the given address
+// family (see message.ResolverAf*; IPv4 addresses first). IP address
+// literals are returned as-is. In local-only mode only "localhost" names
+// are resolved.
+func (r *Resolver) Lookup(ctx context.Context, host string, af uint32) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	if ip := net.ParseIP(host); ip != nil {
+		if !matchFamily(ip, af) {
+			return nil, ErrResolverNoResult
+		}
+		return []net.IP{ip}, nil
+	}
+	var networks []string
+	switch af {
+	case message.ResolverAfUnspec:
+		networks = []string{"ip4", "ip6"}
+	case message.ResolverAfInet:
+		networks = []string{"ip4"}
+	case message.ResolverAfInet6:
+		networks = []string{"ip6"}
+	default:
+		return nil, ErrResolverFamily
+	}
+	if transport.LocalOnly && host != "localhost" && !strings.HasSuffix(host, ".localhost") {
+		return nil, ErrResolverLocalOnly
+	}
+	key := networks[len(networks)-1] + "/" + host
+	if af == message.ResolverAfUnspec {
+		key = "ip/" + host
+	}
+	if e := r.cached(key); e != nil {
+		return e.ips, e.err
+	}
+	// query address families in parallel
+	lctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	type result struct {
+		ips []net.IP
+		err error
+	}
+	res := make([]chan *result, len(networks))
+	for i, netw := range networks {
+		res[i] = make(chan *result, 1)
+		go func(netw string, ch chan *result) {
+			ips, err := r.lookupIP(lctx, netw, host)
+			ch <- &result{ips, err}
+		}(netw, res[i])
+	}
+	var (
+		ips []net.IP
+		err error
+	)
+	for _, ch := range res {
+		rc := <-ch
+		if rc.err != nil {
+			if err == nil {
+				err = rc.err
+			}
+			continue
+		}
+		ips = append(ips, rc.ips...)
+	}
+	if len(ips) > 0 {
+		err = nil
+	} else if err == nil {
+		err = ErrResolverNoResult
+	}
+	r.store(ctx, key, &cacheEntry{ips: ips, err: err})
+	return ips, err
+}
+
+// Reverse returns the hostnames of an IP address.
+func (r *Resolver) Reverse(ctx context.Context, ip net.IP) ([]string, error) {
+	if transport.LocalOnly && !ip.IsLoopback() {
+		return nil, ErrResolverLocalOnly
+	}
+	key := "ptr/" + ip.String()
+	if e := r.cached(key); e != nil {
+		return e.names, e.err
+	}
+	lctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	names, err := r.lookupAddr(lctx, ip.String())
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+	if err == nil && len(names) == 0 {
+		err = ErrResolverNoResult
+	}
+	r.store(ctx, key, &cacheEntry{names: names, err: err})
+	return names, err
+}
+
+// Purge removes expired results from the cache.
+func (r *Resolver) Purge() (n int) {
+	now := time.Now()
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for key, e := range r.cache {
+		if now.After(e.expires) {
+			delete(r.cache, key)
+			n++
+		}
+	}
+	return
+}
+
+// get a valid cache entry (or nil)
+func (r *Resolver) cached(key string) *cacheEntry {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	e, ok := r.cache[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil
+	}
+	return e
+}
+
+// store a lookup result in the cache. Lookups interrupted by the caller
+// are not cached.
+func (r *Resolver) store(ctx context.Context, key string, e *cacheEntry) {
+	ttl := r.TTL
+	if e.err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		ttl = r.NegTTL
+	}
+	e.expires = time.Now().Add(ttl)
+	r.mtx.Lock()
+	r.cache[key] = e
+	r.mtx.Unlock()
+}
+
+// matchFamily returns true if an IP address is in the address family.
+func matchFamily(ip net.IP, af uint32) bool {
+	switch af {
+	case message.ResolverAfInet:
+		return ip.To4() != nil
+	case message.ResolverAfInet6:
+		return ip.To4() == nil
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/service/servicetest"
+	"gnunet/transport"
+)
+
+// fake system resolver with call counter
+type fakeDNS struct {
+	sync.Mutex
+	calls int
+}
+
+func (f *fakeDNS) lookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	f.Lock()
+	f.calls++
+	f.Unlock()
+	switch {
+	case host == "dual.example" && network == "ip4":
+		return []net.IP{net.ParseIP("192.0.2.1")}, nil
+	case host == "dual.example" && network == "ip6":
+		return []net.IP{net.ParseIP("2001:db8::1")}, nil
+	case host == "v4.example" && network == "ip4":
+		return []net.IP{net.ParseIP("192.0.2.2")}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (f *fakeDNS) lookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.Lock()
+	f.calls++
+	f.Unlock()
+	if addr == "192.0.2.1" {
+		return []string{"dual.example."}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func (f *fakeDNS) count() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.calls
+}
+
+func newFakeResolver() (*Resolver, *fakeDNS) {
+	f := new(fakeDNS)
+	r := NewResolver(nil)
+	r.lookupIP = f.lookupIP
+	r.lookupAddr = f.lookupAddr
+	return r, f
+}
+
+func TestLookup(t *testing.T) {
+	r, f := newFakeResolver()
+	ctx := context.Background()
+
+	// A and AAAA records (IPv4 first)
+	ips, err := r.Lookup(ctx, "Dual.Example.", message.ResolverAfUnspec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || ips[0].String() != "192.0.2.1" || ips[1].String() != "2001:db8::1" {
+		t.Fatalf("unexpected addresses %v", ips)
+	}
+	if f.count() != 2 {
+		t.Fatalf("%d lookups", f.count())
+	}
+	// cached results
+	if _, err = r.Lookup(ctx, "dual.example", message.ResolverAfUnspec); err != nil || f.count() != 2 {
+		t.Fatal("result not cached")
+	}
+	// address family
+	if ips, err = r.Lookup(ctx, "dual.example", message.ResolverAfInet6); err != nil || len(ips) != 1 || ips[0].To4() != nil {
+		t.Fatalf("unexpected IPv6 addresses %v", ips)
+	}
+	if ips, err = r.Lookup(ctx, "v4.example", message.ResolverAfUnspec); err != nil || len(ips) != 1 {
+		t.Fatalf("unexpected IPv4 addresses %v", ips)
+	}
+	if _, err = r.Lookup(ctx, "v4.example", 99); err != ErrResolverFamily {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// address literals
+	if ips, err = r.Lookup(ctx, "[2001:db8::2]", message.ResolverAfUnspec); err != nil || ips[0].String() != "2001:db8::2" {
+		t.Fatalf("unexpected literal %v", ips)
+	}
+	if _, err = r.Lookup(ctx, "192.0.2.3", message.ResolverAfInet6); err != ErrResolverNoResult {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// failed lookups are cached (with shorter lifetime)
+	n := f.count()
+	for i := 0; i < 2; i++ {
+		if _, err = r.Lookup(ctx, "unknown.example", message.ResolverAfInet); err == nil {
+			t.Fatal("unknown host resolved")
+		}
+	}
+	if f.count() != n+1 {
+		t.Fatal("failure not cached")
+	}
+	r.NegTTL = 0
+	r.store(ctx, "ip4/expired.example", &cacheEntry{err: errors.New("failed")})
+	time.Sleep(time.Millisecond)
+	if r.Purge() != 1 {
+		t.Fatal("expired result not purged")
+	}
+	// reverse lookups
+	names, err := r.Reverse(ctx, net.ParseIP("192.0.2.1"))
+	if err != nil || len(names) != 1 || names[0] != "dual.example" {
+		t.Fatalf("unexpected names %v (%v)", names, err)
+	}
+}
+
+func TestLocalOnly(t *testing.T) {
+	r, f := newFakeResolver()
+	transport.LocalOnly = true
+	defer func() { transport.LocalOnly = false }()
+
+	ctx := context.Background()
+	if _, err := r.Lookup(ctx, "dual.example", message.ResolverAfUnspec); err != ErrResolverLocalOnly {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.Reverse(ctx, net.ParseIP("192.0.2.1")); err != ErrResolverLocalOnly {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.Lookup(ctx, "127.0.0.1", message.ResolverAfUnspec); err != nil || f.count() != 0 {
+		t.Fatal("local address not resolved")
+	}
+}
+
+//----------------------------------------------------------------------
+
+func TestService(t *testing.T) {
+	rsv, _ := newFakeResolver()
+	s := &Service{Module: &Module{Resolver: rsv}}
+	ctx := context.Background()
+	back := servicetest.NewResponder(t)
+
+	// handle request and return responses
+	handle := func(msg message.Message) (list []*message.ResolverResponseMsg) {
+		t.Helper()
+		s.HandleMessage(ctx, nil, servicetest.Wire(t, msg), back)
+		for _, resp := range back.Take() {
+			list = append(list, resp.(*message.ResolverResponseMsg))
+		}
+		return
+	}
+
+	// forward lookup: addresses followed by end of results
+	resp := handle(message.NewResolverRequestMsg(7, "dual.example", message.ResolverAfUnspec))
+	if len(resp) != 3 || !resp[2].End() {
+		t.Fatalf("%d responses", len(resp))
+	}
+	for i, exp := range []string{"192.0.2.1", "2001:db8::1"} {
+		if resp[i].ClientID != 7 || resp[i].IP().String() != exp {
+			t.Fatalf("unexpected response %v", resp[i])
+		}
+	}
+	// reverse lookup
+	resp = handle(message.NewResolverReverseMsg(8, net.ParseIP("192.0.2.1")))
+	if len(resp) != 2 || resp[0].Hostname() != "dual.example" || !resp[1].End() {
+		t.Fatalf("unexpected responses %v", resp)
+	}
+	// failed lookup: only end of results
+	resp = handle(message.NewResolverRequestMsg(9, "unknown.example", message.ResolverAfUnspec))
+	if len(resp) != 1 || !resp[0].End() || resp[0].ClientID != 9 {
+		t.Fatalf("unexpected responses %v", resp)
+	}
+}
@@ -0,0 +1,81 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package resolver
+
+import (
+	"net"
+	"net/http"
+
+	"gnunet/message"
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for resolver-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to resolver module
+}
+
+//----------------------------------------------------------------------
+// Command "Resolver.Lookup"
+//----------------------------------------------------------------------
+
+// LookupRequest asks for the IP addresses of a hostname or (if the name
+// is an IP address) for the hostnames of an address.
+type LookupRequest struct {
+	Name string `json:"name"`
+}
+
+// LookupResponse returns the results of a lookup.
+type LookupResponse struct {
+	Addrs []string `json:"addrs,omitempty"` // IP addresses of hostname
+	Names []string `json:"names,omitempty"` // hostnames of IP address
+}
+
+// Lookup resolves a hostname or IP address.
+func (s *RPCService) Lookup(r *http.Request, req *LookupRequest, reply *LookupResponse) error {
+	if ip := net.ParseIP(req.Name); ip != nil {
+		names, err := s.m.Reverse(r.Context(), ip)
+		if err != nil {
+			return err
+		}
+		reply.Names = names
+		return nil
+	}
+	ips, err := s.m.Lookup(r.Context(), req.Name, message.ResolverAfUnspec)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		reply.Addrs = append(reply.Addrs, ip.String())
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "Resolver"); err != nil {
+		logger.Printf(logger.ERROR, "[resolver] Failed to init RPC: %s", err.Error())
+	}
+}
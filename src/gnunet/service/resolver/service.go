@@ -0,0 +1,152 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Resolver" socket service implementation:
+// A REQUEST is answered with a RESPONSE message for each result (IP
+// address or hostname) followed by an empty RESPONSE message. Requests
+// are processed concurrently; responses carry the client identifier of
+// the request.
+//----------------------------------------------------------------------
+
+// Service implements a resolver service
+type Service struct {
+	*Module
+}
+
+// NewService creates a new resolver service instance
+func NewService(ctx context.Context, cfg *config.ResolverConfig) service.Service {
+	return &Service{
+		Module: NewModule(ctx, cfg),
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[resolver:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[resolver:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[resolver:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[resolver:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[resolver:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		go s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	mc.Close()
+
+	// cancel all tasks running for this session/connection
+	logger.Printf(logger.INFO, "[resolver:%d] Start closing session...\n", id)
+	cancel()
+}
+
+// HandleMessage processes a single incoming message
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	send := func(resp message.Message) bool {
+		if err := back.Send(ctx, resp); err != nil {
+			logger.Printf(logger.ERROR, "[resolver%s] Failed to send response: %s\n", label, err.Error())
+			return false
+		}
+		return true
+	}
+	switch m := msg.(type) {
+
+	case *message.ResolverRequestMsg:
+		//----------------------------------------------------------
+		// REQUEST: send results of lookup
+		//----------------------------------------------------------
+		var (
+			results []message.Message
+			err     error
+		)
+		if m.Reverse() {
+			var ip net.IP
+			if ip, err = m.IP(); err == nil {
+				var names []string
+				names, err = s.Reverse(ctx, ip)
+				for _, name := range names {
+					results = append(results, message.NewResolverHostnameMsg(m.ClientID, name))
+				}
+			}
+		} else {
+			var name string
+			if name, err = m.Hostname(); err == nil {
+				var ips []net.IP
+				ips, err = s.Lookup(ctx, name, m.Af)
+				for _, ip := range ips {
+					results = append(results, message.NewResolverAddressMsg(m.ClientID, ip))
+				}
+			}
+		}
+		if err != nil {
+			logger.Printf(logger.INFO, "[resolver%s] Lookup failed: %s", label, err.Error())
+		}
+		for _, resp := range results {
+			if !send(resp) {
+				return false
+			}
+		}
+		return send(message.NewResolverEndMsg(m.ClientID))
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[resolver%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+}
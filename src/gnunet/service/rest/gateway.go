@@ -0,0 +1,207 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package rest implements a REST gateway: GNS lookups, the records of
+// zones in the namestore and the identities (egos) are accessible over
+// HTTP with JSON-encoded data. The resources and the JSON schema follow
+// the REST API of the GNUnet "gnunet-rest-server", so web applications
+// written for it can talk to the Go services.
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"gnunet/config"
+
+	"github.com/bfix/gospel/logger"
+	"github.com/gorilla/mux"
+)
+
+// Error codes
+var (
+	ErrNoService   = errors.New("service not configured")
+	ErrNoResult    = errors.New("no result from service")
+	ErrNotFound    = errors.New("not found")
+	ErrBadRequest  = errors.New("invalid request")
+	ErrConflict    = errors.New("already exists")
+	ErrServiceFail = errors.New("service request failed")
+)
+
+// DefaultTimeout is the time limit for service requests if not configured
+// otherwise.
+const DefaultTimeout = 10 * time.Second
+
+// Gateway answers REST requests by talking to the GNS service (lookups)
+// and the zonemaster service (namestore and identities).
+type Gateway struct {
+	gns     string        // GNS service socket
+	zm      string        // zonemaster service socket
+	origin  string        // allowed origin of cross-origin requests
+	timeout time.Duration // time limit for service requests
+
+	srv  *http.Server // HTTP server
+	addr net.Addr     // listen address
+}
+
+// NewGateway creates a new gateway for a node configuration. Resources
+// of services without configuration are not available.
+func NewGateway(nodeCfg *config.Config) *Gateway {
+	gw := &Gateway{
+		timeout: DefaultTimeout,
+	}
+	if nodeCfg.GNS != nil && nodeCfg.GNS.Service != nil {
+		gw.gns = nodeCfg.GNS.Service.Socket
+	}
+	if nodeCfg.ZoneMaster != nil && nodeCfg.ZoneMaster.Service != nil {
+		gw.zm = nodeCfg.ZoneMaster.Service.Socket
+	}
+	if cfg := nodeCfg.REST; cfg != nil {
+		gw.origin = cfg.Origin
+		if cfg.Timeout > 0 {
+			gw.timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+	}
+	return gw
+}
+
+// Handler returns the HTTP handler for REST requests.
+func (gw *Gateway) Handler() http.Handler {
+	router := mux.NewRouter()
+
+	// GNS lookups
+	router.HandleFunc("/gns/{name}", gw.gnsLookup).Methods(http.MethodGet)
+
+	// namestore records
+	router.HandleFunc("/namestore/{zone}", gw.namestoreList).Methods(http.MethodGet)
+	router.HandleFunc("/namestore/{zone}", gw.namestoreStore).Methods(http.MethodPost)
+	router.HandleFunc("/namestore/{zone}/{label}", gw.namestoreLookup).Methods(http.MethodGet)
+
+	// identities
+	router.HandleFunc("/identity", gw.identityList).Methods(http.MethodGet)
+	router.HandleFunc("/identity/all", gw.identityList).Methods(http.MethodGet)
+	router.HandleFunc("/identity/egos", gw.identityList).Methods(http.MethodGet)
+	router.HandleFunc("/identity", gw.identityCreate).Methods(http.MethodPost)
+	router.HandleFunc("/identity/name/{name}", gw.identityGet).Methods(http.MethodGet)
+	router.HandleFunc("/identity/name/{name}", gw.identityRename).Methods(http.MethodPut)
+	router.HandleFunc("/identity/name/{name}", gw.identityDelete).Methods(http.MethodDelete)
+	return gw.cors(router)
+}
+
+// Start listening for REST requests on the given address.
+func (gw *Gateway) Start(addr string) (err error) {
+	var l net.Listener
+	if l, err = net.Listen("tcp", addr); err != nil {
+		return
+	}
+	gw.addr = l.Addr()
+	gw.srv = &http.Server{
+		Handler:           gw.Handler(),
+		WriteTimeout:      gw.timeout + 5*time.Second,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := gw.srv.Serve(l); err != http.ErrServerClosed {
+			logger.Printf(logger.ERROR, "[rest] Server failed: %s\n", err.Error())
+		}
+	}()
+	logger.Printf(logger.INFO, "[rest] Listening on %s\n", gw.addr.String())
+	return
+}
+
+// Addr returns the listen address of the gateway (or nil if not started).
+func (gw *Gateway) Addr() net.Addr {
+	return gw.addr
+}
+
+// Stop the gateway.
+func (gw *Gateway) Stop() error {
+	if gw.srv == nil {
+		return nil
+	}
+	return gw.srv.Shutdown(context.Background())
+}
+
+//----------------------------------------------------------------------
+// helpers
+//----------------------------------------------------------------------
+
+// cors adds the headers for cross-origin requests (if an origin is
+// configured) and answers pre-flight requests of browsers.
+func (gw *Gateway) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(gw.origin) > 0 {
+			hdr := w.Header()
+			hdr.Set("Access-Control-Allow-Origin", gw.origin)
+			hdr.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			hdr.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// context for a service request
+func (gw *Gateway) context(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), gw.timeout)
+}
+
+// reply with a JSON-encoded object
+func reply(w http.ResponseWriter, status int, obj any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		logger.Printf(logger.WARN, "[rest] Reply failed: %s\n", err.Error())
+	}
+}
+
+// replyError sends an error object; the status code depends on the error.
+func replyError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrNoService):
+		status = http.StatusNotImplemented
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+	logger.Printf(logger.DBG, "[rest] Request failed: %s\n", err.Error())
+	reply(w, status, map[string]string{"error": err.Error()})
+}
+
+// decode a JSON-encoded request body
+func decode(r *http.Request, obj any) error {
+	if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
+		return fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/util"
+
+	"github.com/gorilla/mux"
+)
+
+//----------------------------------------------------------------------
+// GNS resource: GET /gns/{name}?record_type={type}
+// Names without zTLD are resolved in the start zone of their TLD. The
+// records are returned as a record set (404 if no records are found).
+//----------------------------------------------------------------------
+
+// gnsLookup handles a GNS lookup request.
+func (gw *Gateway) gnsLookup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	rtype, err := recordType(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	recs, err := gw.lookup(ctx, name, rtype)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	if len(recs) == 0 {
+		replyError(w, fmt.Errorf("%w: no records for '%s'", ErrNotFound, name))
+		return
+	}
+	reply(w, http.StatusOK, NewRecordSet(name, recs))
+}
+
+// lookup a name in GNS (via the GNS service)
+func (gw *Gateway) lookup(ctx context.Context, name string, rtype enums.GNSType) ([]*blocks.ResourceRecord, error) {
+	if len(gw.gns) == 0 {
+		return nil, fmt.Errorf("%w: gns", ErrNoService)
+	}
+	n, err := names.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+	}
+	zk := n.Zone
+	if zk != nil {
+		name = n.Path()
+	} else {
+		// the service maps the TLD to a start zone
+		zk, _ = crypto.NullZoneKey(enums.GNS_TYPE_PKEY)
+	}
+	req := message.NewGNSLookupMsg()
+	req.ID = uint32(util.NextID())
+	req.Zone = zk
+	req.RType = rtype
+	req.SetName(name)
+
+	resp, err := service.RequestResponse(ctx, "rest", "gns", gw.gns, req, true)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := resp.(*message.LookupResultMsg)
+	if !ok || res.ID != req.ID {
+		return nil, ErrNoResult
+	}
+	return res.Records, nil
+}
@@ -0,0 +1,194 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/gns/rr"
+	"gnunet/service/identity"
+
+	"github.com/gorilla/mux"
+)
+
+//----------------------------------------------------------------------
+// Identity resource: egos (zones) of the zonemaster.
+//   GET    /identity/all          list of egos (also: /identity/egos)
+//   GET    /identity/name/{name}  ego with given name
+//   POST   /identity              create ego {"name":...[,"type":...]}
+//   PUT    /identity/name/{name}  rename ego {"newname":...}
+//   DELETE /identity/name/{name}  delete ego
+//----------------------------------------------------------------------
+
+// Ego is the JSON representation of an ego (without private key)
+type Ego struct {
+	PubKey string `json:"pubkey"` // public zone key (zTLD)
+	Name   string `json:"name"`   // ego name
+}
+
+// EgoRequest is the body of requests to create or rename an ego
+type EgoRequest struct {
+	Name    string `json:"name,omitempty"`    // name of new ego
+	Type    string `json:"type,omitempty"`    // zone type of new ego (PKEY or EDKEY)
+	NewName string `json:"newname,omitempty"` // new name of ego
+}
+
+// identityList handles a request for all egos.
+func (gw *Gateway) identityList(w http.ResponseWriter, r *http.Request) {
+	if len(gw.zm) == 0 {
+		replyError(w, fmt.Errorf("%w: zonemaster", ErrNoService))
+		return
+	}
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	list, err := identity.ListEgos(ctx, gw.zm)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	out := make([]*Ego, 0, len(list))
+	for _, ego := range list {
+		out = append(out, &Ego{
+			PubKey: ego.Key.Public().ID(),
+			Name:   ego.Name,
+		})
+	}
+	reply(w, http.StatusOK, out)
+}
+
+// identityGet handles a request for a named ego.
+func (gw *Gateway) identityGet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	name := mux.Vars(r)["name"]
+	zk, err := gw.zoneKey(ctx, name)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	reply(w, http.StatusOK, &Ego{PubKey: zk.Public().ID(), Name: name})
+}
+
+// identityCreate handles a request to create an ego with a new zone key.
+func (gw *Gateway) identityCreate(w http.ResponseWriter, r *http.Request) {
+	req := new(EgoRequest)
+	if err := decode(r, req); err != nil {
+		replyError(w, err)
+		return
+	}
+	if len(req.Name) == 0 {
+		replyError(w, fmt.Errorf("%w: no ego name", ErrBadRequest))
+		return
+	}
+	ztype := enums.GNS_TYPE_PKEY
+	if len(req.Type) > 0 {
+		var err error
+		if ztype, err = rr.ParseType(req.Type); err != nil {
+			replyError(w, fmt.Errorf("%w: zone type '%s'", ErrBadRequest, req.Type))
+			return
+		}
+	}
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	if _, err := gw.zoneKey(ctx, req.Name); err == nil {
+		replyError(w, fmt.Errorf("%w: ego '%s'", ErrConflict, req.Name))
+		return
+	} else if !errors.Is(err, ErrNotFound) {
+		replyError(w, err)
+		return
+	}
+	zk, err := crypto.NewZonePrivate(ztype, nil)
+	if err != nil {
+		if errors.Is(err, crypto.ErrNoImplementation) {
+			err = fmt.Errorf("%w: zone type '%s'", ErrBadRequest, req.Type)
+		}
+		replyError(w, err)
+		return
+	}
+	if err = gw.identityRequest(ctx, message.NewIdentityCreateMsg(zk, req.Name)); err != nil {
+		replyError(w, err)
+		return
+	}
+	reply(w, http.StatusCreated, &Ego{PubKey: zk.Public().ID(), Name: req.Name})
+}
+
+// identityRename handles a request to rename an ego.
+func (gw *Gateway) identityRename(w http.ResponseWriter, r *http.Request) {
+	req := new(EgoRequest)
+	if err := decode(r, req); err != nil {
+		replyError(w, err)
+		return
+	}
+	if len(req.NewName) == 0 {
+		replyError(w, fmt.Errorf("%w: no new ego name", ErrBadRequest))
+		return
+	}
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	name := mux.Vars(r)["name"]
+	if _, err := gw.zoneKey(ctx, name); err != nil {
+		replyError(w, err)
+		return
+	}
+	if err := gw.identityRequest(ctx, message.NewIdentityRenameMsg(name, req.NewName)); err != nil {
+		replyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// identityDelete handles a request to delete an ego.
+func (gw *Gateway) identityDelete(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	name := mux.Vars(r)["name"]
+	if _, err := gw.zoneKey(ctx, name); err != nil {
+		replyError(w, err)
+		return
+	}
+	if err := gw.identityRequest(ctx, message.NewIdentityDeleteMsg(name)); err != nil {
+		replyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// identityRequest sends a request to the identity service and checks
+// the result code of the response.
+func (gw *Gateway) identityRequest(ctx context.Context, req message.Message) error {
+	resp, err := service.RequestResponse(ctx, "rest", "identity", gw.zm, req, true)
+	if err != nil {
+		return err
+	}
+	m, ok := resp.(*message.IdentityResultCodeMsg)
+	if !ok {
+		return ErrNoResult
+	}
+	if m.ResultCode != 0 {
+		return fmt.Errorf("%w: result code %d", ErrServiceFail, m.ResultCode)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/rr"
+	"gnunet/service/identity"
+	"gnunet/util"
+
+	"github.com/gorilla/mux"
+)
+
+//----------------------------------------------------------------------
+// Namestore resource: zones are addressed by the name of their ego.
+//   GET  /namestore/{zone}          all record sets of the zone
+//   GET  /namestore/{zone}/{label}  record set of a label
+//   POST /namestore/{zone}          add records (record set or list)
+// Listings can be restricted to a record type ("?record_type=A").
+// Records are added to the existing records of a label; the namestore
+// protocol has no operation to delete records. A POST request with an
+// "Idempotency-Key" header is only executed once: a repeated request
+// with the same key gets the result of the first one.
+//----------------------------------------------------------------------
+
+// namestoreList handles a request for all record sets of a zone.
+func (gw *Gateway) namestoreList(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	zk, err := gw.zoneKey(ctx, mux.Vars(r)["zone"])
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	rtype, err := recordType(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	list, err := gw.iterate(ctx, zk)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	out := make([]*RecordSet, 0, len(list))
+	for _, rs := range list {
+		if rs = filterRecords(rs, rtype); len(rs.Data) > 0 {
+			out = append(out, rs)
+		}
+	}
+	reply(w, http.StatusOK, out)
+}
+
+// namestoreLookup handles a request for the record set of a label.
+func (gw *Gateway) namestoreLookup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	vars := mux.Vars(r)
+	zk, err := gw.zoneKey(ctx, vars["zone"])
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	rtype, err := recordType(r)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	label := vars["label"]
+	req := message.NewNamestoreRecordLookupMsg(uint32(util.NextID()), zk, label, false)
+	resp, err := service.RequestResponse(ctx, "rest", "namestore", gw.zm, req, true)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	m, ok := resp.(*message.NamestoreRecordLookupRespMsg)
+	if !ok || m.ID != req.ID {
+		replyError(w, ErrNoResult)
+		return
+	}
+	switch enums.ResultCode(m.Found) {
+	case enums.RC_YES:
+		rs := m.GetRecords()
+		reply(w, http.StatusOK, filterRecords(NewRecordSet(label, rs.Records), rtype))
+	case enums.RC_NO:
+		replyError(w, fmt.Errorf("%w: label '%s'", ErrNotFound, label))
+	default:
+		replyError(w, ErrServiceFail)
+	}
+}
+
+// recordStore is a namestore store request (with or without
+// idempotency key).
+type recordStore interface {
+	message.Message
+	AddRecordSet(label string, rr *blocks.RecordSet) *message.NamestoreRecordSet
+}
+
+// namestoreStore handles a request to add records to a zone. The body
+// is a record set or a list of record sets.
+func (gw *Gateway) namestoreStore(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := gw.context(r)
+	defer cancel()
+	zk, err := gw.zoneKey(ctx, mux.Vars(r)["zone"])
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		replyError(w, fmt.Errorf("%w: %s", ErrBadRequest, err.Error()))
+		return
+	}
+	var list []*RecordSet
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &list)
+	} else {
+		rs := new(RecordSet)
+		err = json.Unmarshal(body, rs)
+		list = append(list, rs)
+	}
+	if err != nil {
+		replyError(w, fmt.Errorf("%w: %s", ErrBadRequest, err.Error()))
+		return
+	}
+	// assemble and send store request
+	id := uint32(util.NextID())
+	var req recordStore = message.NewNamestoreRecordStoreMsg(id, zk)
+	if key := r.Header.Get("Idempotency-Key"); len(key) > 0 {
+		req = message.NewNamestoreRecordStoreKeyedMsg(id, crypto.Hash([]byte(key)), zk)
+	}
+	for _, rs := range list {
+		set, err := rs.RecordSet()
+		if err != nil {
+			replyError(w, err)
+			return
+		}
+		req.AddRecordSet(rs.Name, set)
+	}
+	resp, err := service.RequestResponse(ctx, "rest", "namestore", gw.zm, req, true)
+	if err != nil {
+		replyError(w, err)
+		return
+	}
+	m, ok := resp.(*message.NamestoreRecordStoreRespMsg)
+	if !ok || m.ID != id {
+		replyError(w, ErrNoResult)
+		return
+	}
+	switch ec := enums.ErrorCode(m.Status); ec {
+	case enums.EC_NONE:
+		w.WriteHeader(http.StatusNoContent)
+	case enums.EC_NAMESTORE_ZONE_NOT_FOUND:
+		replyError(w, fmt.Errorf("%w: %s", ErrNotFound, ec))
+	case enums.EC_NAMESTORE_LABEL_INVALID,
+		enums.EC_NAMESTORE_RECORD_DATA_INVALID,
+		enums.EC_NAMESTORE_RECORD_TOO_BIG:
+		replyError(w, fmt.Errorf("%w: %s", ErrBadRequest, ec))
+	default:
+		replyError(w, fmt.Errorf("%w: %s", ErrServiceFail, ec))
+	}
+}
+
+//----------------------------------------------------------------------
+
+// zoneKey returns the private key of the zone of an ego.
+func (gw *Gateway) zoneKey(ctx context.Context, ego string) (*crypto.ZonePrivate, error) {
+	if len(gw.zm) == 0 {
+		return nil, fmt.Errorf("%w: zonemaster", ErrNoService)
+	}
+	zk, err := identity.LookupEgo(ctx, "rest", gw.zm, ego)
+	if errors.Is(err, identity.ErrEgoUnknown) {
+		err = fmt.Errorf("%w: ego '%s'", ErrNotFound, ego)
+	}
+	return zk, err
+}
+
+// iterate over all labels of a zone and return the record sets.
+func (gw *Gateway) iterate(ctx context.Context, zk *crypto.ZonePrivate) (list []*RecordSet, err error) {
+	var conn *service.Connection
+	if conn, err = service.NewConnection(ctx, gw.zm); err != nil {
+		return
+	}
+	defer conn.Close()
+	id := uint32(util.NextID())
+	if err = conn.Send(ctx, message.NewNamestoreZoneIterStartMsg(id, int(enums.GNS_FILTER_NONE), zk)); err != nil {
+		return
+	}
+	for {
+		var in message.Message
+		if in, err = conn.Receive(ctx); err != nil {
+			return
+		}
+		switch m := in.(type) {
+		case *message.NamestoreRecordResultMsg:
+			label, _ := util.ReadCString(m.Name, 0)
+			rs := m.GetRecords()
+			list = append(list, NewRecordSet(label, rs.Records))
+			if err = conn.Send(ctx, message.NewNamestoreZoneIterNextMsg(id, 1)); err != nil {
+				return
+			}
+		case *message.NamestoreZoneIterEndMsg:
+			return
+		default:
+			return nil, ErrNoResult
+		}
+	}
+}
+
+// recordType returns the record type of a request ("record_type"
+// parameter; ANY if undefined).
+func recordType(r *http.Request) (enums.GNSType, error) {
+	s := r.URL.Query().Get("record_type")
+	if len(s) == 0 {
+		return enums.GNS_TYPE_ANY, nil
+	}
+	t, err := rr.ParseType(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+	}
+	return t, nil
+}
+
+// filterRecords returns a record set with records of the given type only.
+func filterRecords(rs *RecordSet, t enums.GNSType) *RecordSet {
+	if t == enums.GNS_TYPE_ANY {
+		return rs
+	}
+	name := rr.TypeName(t)
+	out := &RecordSet{Name: rs.Name, Data: make([]*Record, 0)}
+	for _, r := range rs.Data {
+		if r.Type == name {
+			out.Data = append(out.Data, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rest
+
+import (
+	"fmt"
+
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/rr"
+	"gnunet/util"
+)
+
+//----------------------------------------------------------------------
+// JSON representation of resource records (as used by the GNUnet REST
+// API): expiration times are in microseconds (absolute or relative).
+//----------------------------------------------------------------------
+
+// Record is the JSON representation of a resource record
+type Record struct {
+	Value        string `json:"value"`                  // record data (text)
+	Type         string `json:"record_type"`            // record type
+	Expiration   uint64 `json:"expiration_time"`        // expiration (µs)
+	Relative     bool   `json:"is_relative_expiration"` // relative expiration?
+	Private      bool   `json:"is_private"`             // private record?
+	Supplemental bool   `json:"is_supplemental"`        // supplemental record?
+	Shadow       bool   `json:"is_shadow"`              // shadow record?
+	Critical     bool   `json:"is_critical"`            // critical record?
+}
+
+// RecordSet is the JSON representation of the records under a label
+type RecordSet struct {
+	Name string    `json:"record_name"` // label (or name)
+	Data []*Record `json:"data"`        // list of records
+}
+
+// recordFlags maps flags to fields of a JSON record
+func recordFlags(r *Record) map[enums.GNSFlag]*bool {
+	return map[enums.GNSFlag]*bool{
+		enums.GNS_FLAG_RELATIVE_EXPIRATION: &r.Relative,
+		enums.GNS_FLAG_PRIVATE:             &r.Private,
+		enums.GNS_FLAG_SUPPLEMENTAL:        &r.Supplemental,
+		enums.GNS_FLAG_SHADOW:              &r.Shadow,
+		enums.GNS_FLAG_CRITICAL:            &r.Critical,
+	}
+}
+
+// NewRecord returns the JSON representation of a resource record.
+func NewRecord(rec *blocks.ResourceRecord) *Record {
+	r := &Record{
+		Value:      rr.ToText(rec.RType, rec.Data),
+		Type:       rr.TypeName(rec.RType),
+		Expiration: rec.Expire.Val,
+	}
+	for flag, field := range recordFlags(r) {
+		*field = rec.Flags&flag != 0
+	}
+	return r
+}
+
+// ResourceRecord returns the resource record for a JSON record.
+func (r *Record) ResourceRecord() (*blocks.ResourceRecord, error) {
+	t, err := rr.ParseType(r.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+	}
+	data, err := rr.FromText(t, r.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+	}
+	rec := &blocks.ResourceRecord{
+		Expire: util.AbsoluteTime{Val: r.Expiration},
+		Size:   uint16(len(data)),
+		RType:  t,
+		Data:   data,
+	}
+	for flag, field := range recordFlags(r) {
+		if *field {
+			rec.Flags |= flag
+		}
+	}
+	return rec, nil
+}
+
+// NewRecordSet returns the JSON representation of records under a name.
+func NewRecordSet(name string, recs []*blocks.ResourceRecord) *RecordSet {
+	rs := &RecordSet{
+		Name: name,
+		Data: make([]*Record, 0, len(recs)),
+	}
+	for _, rec := range recs {
+		rs.Data = append(rs.Data, NewRecord(rec))
+	}
+	return rs
+}
+
+// RecordSet returns the resource records of a JSON record set.
+func (rs *RecordSet) RecordSet() (*blocks.RecordSet, error) {
+	if len(rs.Name) == 0 {
+		return nil, fmt.Errorf("%w: no record name", ErrBadRequest)
+	}
+	if len(rs.Data) == 0 {
+		return nil, fmt.Errorf("%w: no records for '%s'", ErrBadRequest, rs.Name)
+	}
+	set := blocks.NewRecordSet()
+	for _, r := range rs.Data {
+		rec, err := r.ResourceRecord()
+		if err != nil {
+			return nil, err
+		}
+		set.AddRecord(rec)
+	}
+	return set, nil
+}
@@ -0,0 +1,118 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+func TestRecordJSON(t *testing.T) {
+	rec := &blocks.ResourceRecord{
+		Expire: util.AbsoluteTimeNow().Add(time.Hour),
+		Size:   4,
+		Flags:  enums.GNS_FLAG_PRIVATE | enums.GNS_FLAG_SUPPLEMENTAL,
+		RType:  enums.GNS_TYPE_DNS_A,
+		Data:   []byte{10, 0, 0, 1},
+	}
+	rs := NewRecordSet("www", []*blocks.ResourceRecord{rec})
+	buf, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// C-compatible field names
+	var raw map[string]any
+	if err = json.Unmarshal(buf, &raw); err != nil {
+		t.Fatal(err)
+	}
+	data, ok := raw["data"].([]any)
+	if raw["record_name"] != "www" || !ok || len(data) != 1 {
+		t.Fatalf("unexpected JSON: %s", buf)
+	}
+	if r := data[0].(map[string]any); r["value"] != "10.0.0.1" || r["record_type"] != "A" || r["is_private"] != true || r["is_shadow"] != false {
+		t.Fatalf("unexpected record: %s", buf)
+	}
+	// decode back to resource records
+	dec := new(RecordSet)
+	if err = json.Unmarshal(buf, dec); err != nil {
+		t.Fatal(err)
+	}
+	set, err := dec.RecordSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := set.Records[0]
+	if set.Count != 1 || out.RType != rec.RType || out.Flags != rec.Flags ||
+		out.Expire.Val != rec.Expire.Val || !bytes.Equal(out.Data, rec.Data) {
+		t.Fatalf("record mismatch: %v", out)
+	}
+
+	// invalid records
+	for _, r := range []*Record{
+		{Value: "10.0.0.1", Type: "NOTYPE"},
+		{Value: "no address", Type: "A"},
+	} {
+		if _, err = r.ResourceRecord(); !errors.Is(err, ErrBadRequest) {
+			t.Fatalf("invalid record %v accepted: %v", r, err)
+		}
+	}
+	if _, err = (&RecordSet{Name: "www"}).RecordSet(); !errors.Is(err, ErrBadRequest) {
+		t.Fatal("empty record set accepted")
+	}
+}
+
+func TestGatewayRouting(t *testing.T) {
+	// gateway without services
+	gw := NewGateway(&config.Config{
+		REST: &config.RESTConfig{Origin: "https://example.org"},
+	})
+	h := gw.Handler()
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/gns/www.gnu", http.StatusNotImplemented},
+		{http.MethodGet, "/namestore/test", http.StatusNotImplemented},
+		{http.MethodGet, "/namestore/test/www?record_type=NOTYPE", http.StatusNotImplemented},
+		{http.MethodGet, "/identity/all", http.StatusNotImplemented},
+		{http.MethodDelete, "/namestore/test/www", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/unknown", http.StatusNotFound},
+		{http.MethodOptions, "/identity/all", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: got status %d, expected %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if tc.method == http.MethodOptions && rec.Header().Get("Access-Control-Allow-Origin") != "https://example.org" {
+			t.Errorf("%s %s: no CORS header", tc.method, tc.path)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"context"
+	"errors"
+
+	"gnunet/crypto"
+	"gnunet/message"
+	"gnunet/service"
+)
+
+// ErrInvalidResponse is returned if the revocation service responds with
+// an unexpected message.
+var ErrInvalidResponse = errors.New("invalid response from revocation service")
+
+//----------------------------------------------------------------------
+// Client helpers for programs talking to the revocation service socket.
+//----------------------------------------------------------------------
+
+// Publish submits revocation data to the revocation service listening on
+// 'socket'. It returns true if the revocation was accepted; accepted
+// revocations are flooded to the peers connected to the service.
+func Publish(ctx context.Context, caller, socket string, rd *RevData) (bool, error) {
+	resp, err := service.RequestResponse(ctx, caller, "revocation", socket, rd.Message(), true)
+	if err != nil {
+		return false, err
+	}
+	m, ok := resp.(*message.RevocationRevokeResponseMsg)
+	if !ok {
+		return false, ErrInvalidResponse
+	}
+	return m.Success == 1, nil
+}
+
+// IsRevoked asks the revocation service listening on 'socket' if a zone
+// key is revoked.
+func IsRevoked(ctx context.Context, caller, socket string, zk *crypto.ZoneKey) (bool, error) {
+	resp, err := service.RequestResponse(ctx, caller, "revocation", socket, message.NewRevocationQueryMsg(zk), true)
+	if err != nil {
+		return false, err
+	}
+	m, ok := resp.(*message.RevocationQueryResponseMsg)
+	if !ok {
+		return false, ErrInvalidResponse
+	}
+	return m.Valid == 0, nil
+}
@@ -0,0 +1,174 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"gnunet/crypto"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Revocation filter: the bloomfilter of revoked zone keys is written to
+// a file by the revocation service whenever it changes. Other services
+// (like GNS) read the file and only ask the revocation service about a
+// zone key if the filter contains it; for the vast majority of zone keys
+// (which are not revoked) no request is required.
+//----------------------------------------------------------------------
+
+// Revocation filter file format
+const (
+	FilterMagic   = 0x47525646 // "GRVF"
+	FilterVersion = 1          // current file format version
+)
+
+// Error codes
+var (
+	ErrFilterFormat  = errors.New("not a revocation filter file")
+	ErrFilterVersion = errors.New("unsupported revocation filter version")
+)
+
+// FilterCheckInterval is the min. time between checks for a modified
+// filter file.
+var FilterCheckInterval = time.Second
+
+// filterHeader is the start of a filter file; it is followed by the
+// binary bloomfilter.
+type filterHeader struct {
+	Magic   uint32 `order:"big"` // file magic
+	Version uint16 `order:"big"` // file format version
+}
+
+// WriteFilter writes a bloomfilter to a filter file.
+func WriteFilter(fname string, bf *data.BloomFilter) error {
+	hdr, err := data.Marshal(&filterHeader{
+		Magic:   FilterMagic,
+		Version: FilterVersion,
+	})
+	if err != nil {
+		return err
+	}
+	var buf []byte
+	if buf, err = data.Marshal(bf); err != nil {
+		return err
+	}
+	tmp := fname + ".tmp"
+	if err = os.WriteFile(tmp, append(hdr, buf...), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+// ReadFilter reads a bloomfilter from a filter file.
+func ReadFilter(fname string) (bf *data.BloomFilter, err error) {
+	var buf []byte
+	if buf, err = os.ReadFile(fname); err != nil {
+		return
+	}
+	hdr := new(filterHeader)
+	if len(buf) < 6 {
+		return nil, ErrFilterFormat
+	}
+	if err = data.Unmarshal(hdr, buf[:6]); err != nil || hdr.Magic != FilterMagic {
+		return nil, ErrFilterFormat
+	}
+	if hdr.Version != FilterVersion {
+		return nil, ErrFilterVersion
+	}
+	bf = new(data.BloomFilter)
+	if err = data.Unmarshal(bf, buf[6:]); err != nil {
+		return nil, ErrFilterFormat
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+
+// Filter is a read-only view on a filter file maintained by the
+// revocation service. The file is re-read if it has been modified.
+type Filter struct {
+	sync.Mutex
+
+	fname   string            // name of filter file
+	bf      *data.BloomFilter // current bloomfilter (or nil)
+	mtime   time.Time         // modification time of loaded file
+	checked time.Time         // time of last check for modification
+}
+
+// NewFilter creates a new filter for the given filter file.
+func NewFilter(fname string) *Filter {
+	return &Filter{
+		fname: fname,
+	}
+}
+
+// Contains returns true if the zone key might be revoked; the revocation
+// service must be asked for a definitive answer. If no filter is
+// available, all keys might be revoked.
+func (f *Filter) Contains(zkey *crypto.ZoneKey) bool {
+	f.Lock()
+	defer f.Unlock()
+	if time.Since(f.checked) >= FilterCheckInterval {
+		f.reload()
+	}
+	if f.bf == nil {
+		return true
+	}
+	return f.bf.Contains(zkey.Bytes())
+}
+
+// Refresh forces a re-read of the filter file on next use (e.g. after a
+// key has been revoked); the file can change within the resolution of
+// its modification time.
+func (f *Filter) Refresh() {
+	f.Lock()
+	defer f.Unlock()
+	f.checked = time.Time{}
+	f.mtime = time.Time{}
+}
+
+// reload the filter file if it has been modified (caller must hold the lock)
+func (f *Filter) reload() {
+	f.checked = time.Now()
+	fi, err := os.Stat(f.fname)
+	if err != nil {
+		if f.bf != nil {
+			logger.Printf(logger.WARN, "[revocation] filter file not available: %s", err.Error())
+		}
+		f.bf = nil
+		return
+	}
+	if f.bf != nil && fi.ModTime().Equal(f.mtime) {
+		return
+	}
+	bf, err := ReadFilter(f.fname)
+	if err != nil {
+		logger.Printf(logger.WARN, "[revocation] can't read filter file: %s", err.Error())
+		f.bf = nil
+		return
+	}
+	f.bf = bf
+	f.mtime = fi.ModTime()
+}
@@ -0,0 +1,114 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// newZoneKey returns a random public zone key
+func newZoneKey(t *testing.T) *crypto.ZoneKey {
+	t.Helper()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zp.Public()
+}
+
+func TestFilterFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "revocation.filter")
+	zk := newZoneKey(t)
+
+	bf := data.NewBloomFilter(1000, 1e-6)
+	bf.Add(zk.Bytes())
+	if err := WriteFilter(fname, bf); err != nil {
+		t.Fatal(err)
+	}
+	bf2, err := ReadFilter(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bf2.Contains(zk.Bytes()) {
+		t.Fatal("key not in filter")
+	}
+	if bf2.Contains(newZoneKey(t).Bytes()) {
+		t.Fatal("unexpected key in filter")
+	}
+
+	// unsupported version
+	buf, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[5] = FilterVersion + 1
+	if err = os.WriteFile(fname, buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadFilter(fname); err != ErrFilterVersion {
+		t.Fatalf("expected version error, got %v", err)
+	}
+	// not a filter file
+	if err = os.WriteFile(fname, []byte("some text"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadFilter(fname); err != ErrFilterFormat {
+		t.Fatalf("expected format error, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "revocation.filter")
+	zk1, zk2 := newZoneKey(t), newZoneKey(t)
+
+	// without filter file all keys might be revoked
+	f := NewFilter(fname)
+	if !f.Contains(zk1) {
+		t.Fatal("missing filter must contain all keys")
+	}
+
+	// filter with first key
+	bf := data.NewBloomFilter(1000, 1e-6)
+	bf.Add(zk1.Bytes())
+	if err := WriteFilter(fname, bf); err != nil {
+		t.Fatal(err)
+	}
+	f.Refresh()
+	if !f.Contains(zk1) || f.Contains(zk2) {
+		t.Fatal("wrong filter content")
+	}
+
+	// modified filter file is re-read
+	bf.Add(zk2.Bytes())
+	if err := WriteFilter(fname, bf); err != nil {
+		t.Fatal(err)
+	}
+	f.Refresh()
+	if !f.Contains(zk2) {
+		t.Fatal("modified filter not re-read")
+	}
+}
@@ -24,10 +24,14 @@ import (
 	"gnunet/core"
 	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/message"
 	"gnunet/service"
+	"gnunet/service/metrics"
 	"gnunet/service/store"
 	"gnunet/util"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/bfix/gospel/data"
 	"github.com/bfix/gospel/logger"
@@ -37,26 +41,80 @@ import (
 // "GNUnet Revocation" implementation
 //======================================================================
 
-// The minimum average difficulty acceptable for a set of revocation PoWs
-const MinAvgDifficulty = 23
+// MinAvgDifficulty is the minimum average difficulty acceptable for a set
+// of revocation PoWs (a configured policy can only raise it). It is a
+// variable (and not a constant) so test setups can work with revocations
+// of lower difficulty.
+var MinAvgDifficulty = 23
+
+// revocation metrics
+var (
+	queries = metrics.NewCounter("gnunet_revocation_queries_total",
+		"Revocation queries by result (valid, revoked).", "result")
+	revokes = metrics.NewCounter("gnunet_revocation_revokes_total",
+		"Revocation requests by result (accepted, rejected, failed).", "result")
+	floods = metrics.NewCounter("gnunet_revocation_floods_total",
+		"Revocations sent to connected peers by result (sent, failed).", "result")
+)
+
+// FloodTimeout is the time allowed for sending a revocation to a peer.
+var FloodTimeout = 10 * time.Second
+
+// propagationTTL is the time propagation records are kept.
+const propagationTTL = 24 * time.Hour
+
+// Core is the set of core services used by the revocation module. It is
+// implemented by core.Core (and by mocks in unit tests).
+type Core interface {
+	Connected() []*util.PeerID
+	Send(ctx context.Context, peer *util.PeerID, msg message.Message) error
+	Register(name string, l *core.Listener)
+	Invalid(peer *util.PeerID, msg message.Message)
+}
+
+// Propagation is the status of flooding a revocation to connected peers.
+type Propagation struct {
+	Zone    string    `json:"zone"`    // revoked zone (ID)
+	Started time.Time `json:"started"` // start of flooding
+	Peers   int       `json:"peers"`   // number of peers to send to
+	Sent    int       `json:"sent"`    // number of peers reached
+	Failed  int       `json:"failed"`  // number of failed sends
+	Done    bool      `json:"done"`    // flooding finished?
+}
 
 // Module handles the revocation-related calls to other modules.
 type Module struct {
 	service.ModuleImpl
 
-	bloomf *data.BloomFilter // bloomfilter for fast revocation check
-	kvs    store.KVStore     // storage for known revocations
+	bloomf *data.BloomFilter        // bloomfilter for fast revocation check
+	kvs    store.KVStore            // storage for known revocations
+	filter string                   // file for bloomfilter (or empty)
+	fmtx   *sync.Mutex              // serialize writes of filter file
+	replay *util.ReplayCache        // recently verified revocations
+	policy *config.RevocationPolicy // configured difficulty policy
+
+	ctx   context.Context         // module context (for flooding)
+	core  Core                    // reference to core services
+	pmtx  *sync.Mutex             // lock for propagation records
+	props map[string]*Propagation // propagation of revocations (by zone)
 }
 
 // NewModule returns an initialized revocation module
-func NewModule(ctx context.Context, c *core.Core) (m *Module) {
+func NewModule(ctx context.Context, c Core, cfg *config.RevocationConfig) (m *Module) {
 	// create and init instance
 	m = &Module{
 		ModuleImpl: *service.NewModuleImpl(),
+		fmtx:       new(sync.Mutex),
+		replay:     util.NewReplayCache(0),
+		policy:     cfg.Policy,
+		ctx:        ctx,
+		core:       c,
+		pmtx:       new(sync.Mutex),
+		props:      make(map[string]*Propagation),
 	}
 	init := func() (err error) {
 		// Initialize access to revocation data storage
-		if m.kvs, err = store.NewKVStore(config.Cfg.Revocation.Storage); err != nil {
+		if m.kvs, err = store.NewKVStore(cfg.Storage); err != nil {
 			return
 		}
 		// traverse the storage and build bloomfilter for all keys
@@ -66,8 +124,15 @@ func NewModule(ctx context.Context, c *core.Core) (m *Module) {
 			return
 		}
 		for _, key := range keys {
-			m.bloomf.Add([]byte(key))
+			var zk []byte
+			if zk, err = util.DecodeStringToBinary(key, len(key)*5/8); err != nil {
+				return
+			}
+			m.bloomf.Add(zk)
 		}
+		// share the bloomfilter with other services
+		m.filter = cfg.Filter
+		m.saveFilter()
 		return
 	}
 	if err := init(); err != nil {
@@ -75,8 +140,8 @@ func NewModule(ctx context.Context, c *core.Core) (m *Module) {
 		return nil
 	}
 	// register as listener for core events
-	listener := m.Run(ctx, m.event, m.Filter(), 0, nil)
-	c.Register("gns", listener)
+	listener := m.Run(ctx, m.event, m.Filter())
+	c.Register("revocation", listener)
 	return m
 }
 
@@ -94,7 +159,22 @@ func (m *Module) Filter() *core.EventFilter {
 
 // Event handler
 func (m *Module) event(ctx context.Context, ev *core.Event) {
-
+	if ev.ID != core.EV_MESSAGE {
+		return
+	}
+	// only revocations are flooded between peers
+	msg, ok := ev.Msg.(*message.RevocationRevokeMsg)
+	if !ok {
+		return
+	}
+	logger.Printf(logger.INFO, "[revocation] Revocation of zone %s from peer %s", msg.ZoneKeySig.ID(), ev.Peer.Short())
+	success, err := m.revoke(ctx, NewRevDataFromMsg(msg), ev.Peer)
+	switch {
+	case err != nil:
+		logger.Printf(logger.ERROR, "[revocation] Failed to handle revocation from peer %s: %s", ev.Peer.Short(), err.Error())
+	case !success:
+		m.core.Invalid(ev.Peer, msg)
+	}
 }
 
 //----------------------------------------------------------------------
@@ -116,6 +196,13 @@ func (m *Module) Import(fcm map[string]any) {
 // Query return true if the pkey is valid (not revoked) and false
 // if the pkey has been revoked ["rev:query"]
 func (m *Module) Query(ctx context.Context, zkey *crypto.ZoneKey) (valid bool, err error) {
+	defer func() {
+		if valid {
+			queries.Inc("valid")
+		} else {
+			queries.Inc("revoked")
+		}
+	}()
 	// fast check first: is the key in the bloomfilter?
 	data := zkey.Bytes()
 	if !m.bloomf.Contains(data) {
@@ -133,8 +220,42 @@ func (m *Module) Query(ctx context.Context, zkey *crypto.ZoneKey) (valid bool, e
 	return false, nil
 }
 
-// Revoke a key with given revocation data ["rev:revoke"]
+// Revoke a key with given revocation data ["rev:revoke"]. Accepted
+// revocations are flooded to all connected peers.
 func (m *Module) Revoke(ctx context.Context, rd *RevData) (success bool, err error) {
+	return m.revoke(ctx, rd, nil)
+}
+
+// revoke handles revocation data from a local client (sender is nil) or
+// from a peer. New revocations are flooded to all connected peers except
+// the sender; a revocation already known is only flooded again if it was
+// submitted by a local client.
+func (m *Module) revoke(ctx context.Context, rd *RevData, sender *util.PeerID) (success bool, err error) {
+	defer func() {
+		switch {
+		case err != nil:
+			revokes.Inc("failed")
+		case success:
+			revokes.Inc("accepted")
+		default:
+			revokes.Inc("rejected")
+		}
+	}()
+	// a replayed revocation (already verified and stored) is not
+	// verified again.
+	var buf []byte
+	if buf, err = data.Marshal(rd); err != nil {
+		return false, err
+	}
+	digest := crypto.Hash(buf).Data
+	if m.replay.Contains("revocation", digest) {
+		logger.Println(logger.INFO, "[revocation] Revoke: replayed revocation -- skipped")
+		service.Count("revocation:replay", 1)
+		if sender == nil {
+			m.flood(rd, nil)
+		}
+		return true, nil
+	}
 	// verify the revocation data
 	diff, rc := rd.Verify(true)
 	switch {
@@ -148,22 +269,116 @@ func (m *Module) Revoke(ctx context.Context, rd *RevData) (success bool, err err
 		logger.Println(logger.WARN, "[revocation] Revoke: Wrong PoW sequence order")
 		return false, nil
 	}
-	if diff < float64(MinAvgDifficulty) {
-		logger.Println(logger.WARN, "[revocation] Revoke: Difficulty to small")
+	if minDiff := NewPolicy(m.policy).MinDifficulty; diff < float64(minDiff) {
+		logger.Printf(logger.WARN, "[revocation] Revoke: Difficulty to small (%.2f < %d)", diff, minDiff)
 		return false, nil
 	}
 
+	// check if the key is already revoked
+	zk := rd.ZoneKeySig.ZoneKey.Bytes()
+	if m.bloomf.Contains(zk) {
+		if _, err := m.kvs.Get(rd.ZoneKeySig.ID()); err == nil {
+			logger.Println(logger.INFO, "[revocation] Revoke: key already revoked")
+			m.replay.Add("revocation", digest, rd.Timestamp.AddRelative(rd.TTL))
+			if sender == nil {
+				m.flood(rd, nil)
+			}
+			return true, nil
+		}
+	}
 	// store the revocation data
 	// (1) add it to the bloomfilter
-	m.bloomf.Add(rd.ZoneKeySig.KeyData)
+	m.bloomf.Add(zk)
 	// (2) add it to the store
-	var buf []byte
-	if buf, err = data.Marshal(rd); err != nil {
-		return false, err
-	}
 	value := util.EncodeBinaryToString(buf)
-	err = m.kvs.Put(rd.ZoneKeySig.ID(), value)
-	return true, err
+	if err = m.kvs.Put(rd.ZoneKeySig.ID(), value); err != nil {
+		return true, err
+	}
+	// (3) update the shared filter
+	m.saveFilter()
+	// (4) remember for replay detection
+	m.replay.Add("revocation", digest, rd.Timestamp.AddRelative(rd.TTL))
+	// (5) tell the other peers
+	m.flood(rd, sender)
+	return true, nil
+}
+
+// flood sends a revocation to all connected peers (except the sender)
+// in the background. The progress is recorded as a propagation status.
+func (m *Module) flood(rd *RevData, sender *util.PeerID) {
+	if m.core == nil {
+		return
+	}
+	var peers []*util.PeerID
+	for _, peer := range m.core.Connected() {
+		if !peer.Equal(sender) {
+			peers = append(peers, peer)
+		}
+	}
+	prop := &Propagation{
+		Zone:    rd.ZoneKeySig.ID(),
+		Started: time.Now(),
+		Peers:   len(peers),
+	}
+	m.pmtx.Lock()
+	for zone, p := range m.props {
+		if time.Since(p.Started) > propagationTTL {
+			delete(m.props, zone)
+		}
+	}
+	m.props[prop.Zone] = prop
+	m.pmtx.Unlock()
+
+	go func() {
+		msg := rd.Message()
+		for _, peer := range peers {
+			ctx, cancel := context.WithTimeout(m.ctx, FloodTimeout)
+			err := m.core.Send(ctx, peer, msg)
+			cancel()
+			m.pmtx.Lock()
+			if err != nil {
+				logger.Printf(logger.WARN, "[revocation] Failed to send revocation to %s: %s", peer.Short(), err.Error())
+				prop.Failed++
+				floods.Inc("failed")
+			} else {
+				prop.Sent++
+				floods.Inc("sent")
+			}
+			m.pmtx.Unlock()
+		}
+		m.pmtx.Lock()
+		prop.Done = true
+		m.pmtx.Unlock()
+		logger.Printf(logger.INFO, "[revocation] Revocation of zone %s sent to %d of %d peers",
+			prop.Zone, prop.Sent, prop.Peers)
+	}()
+}
+
+// Status returns the revocation status of a zone (ID) and the status of
+// the last propagation of its revocation (or nil if not flooded).
+func (m *Module) Status(zone string) (revoked bool, prop *Propagation) {
+	if _, err := m.kvs.Get(zone); err == nil {
+		revoked = true
+	}
+	m.pmtx.Lock()
+	defer m.pmtx.Unlock()
+	if p, ok := m.props[zone]; ok {
+		cp := *p
+		prop = &cp
+	}
+	return
+}
+
+// saveFilter writes the bloomfilter to the filter file (if configured)
+func (m *Module) saveFilter() {
+	if len(m.filter) == 0 {
+		return
+	}
+	m.fmtx.Lock()
+	defer m.fmtx.Unlock()
+	if err := WriteFilter(m.filter, m.bloomf); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] Failed to write filter file: %s", err.Error())
+	}
 }
 
 //----------------------------------------------------------------------
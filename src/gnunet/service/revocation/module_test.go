@@ -0,0 +1,264 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// revocation test vector (RFC draft, first testcase)
+const testProof = "0005feb46d865c1c" +
+	"0000395d1827c000" +
+	"e66a570bccd4b393e66a570bccd4b3eae66a570bccd4b536e66a570bccd4b542" +
+	"e66a570bccd4b613e66a570bccd4b65fe66a570bccd4b672e66a570bccd4b70a" +
+	"e66a570bccd4b71ae66a570bccd4b723e66a570bccd4b747e66a570bccd4b777" +
+	"e66a570bccd4b785e66a570bccd4b789e66a570bccd4b7cfe66a570bccd4b7dc" +
+	"e66a570bccd4b93ae66a570bccd4b956e66a570bccd4ba4ae66a570bccd4ba9d" +
+	"e66a570bccd4bb28e66a570bccd4bb5ae66a570bccd4bb92e66a570bccd4bba2" +
+	"e66a570bccd4bbd8e66a570bccd4bbe2e66a570bccd4bc93e66a570bccd4bc94" +
+	"e66a570bccd4bd0fe66a570bccd4bdcee66a570bccd4be6ae66a570bccd4be73" +
+	"000100002ca223e879ecc4bbdeb5da17319281d63b2e3b6955f1c3775c804a98d5f8ddaa" +
+	"044a878a158b40f0c841d9f978cb1372eaee5199a3d87e5e2bdbc72a6c8c73d0" +
+	"00181dfc39c3aaa481667b165b5844e450713d8ab6a3b2ba8fef447b65076a0f"
+
+// testRevData returns the revocation data from the test vector.
+func testRevData(t *testing.T) *RevData {
+	t.Helper()
+	buf, err := hex.DecodeString(testProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd := new(RevData)
+	if err = data.Unmarshal(rd, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = rd.ZoneKeySig.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return rd
+}
+
+// testStorage returns the specification of an empty revocation store.
+func testStorage(t *testing.T) util.ParameterSet {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), "revocation.db")
+	db, err := sql.Open("sqlite3", fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("create table store(key text primary key, value text)"); err != nil {
+		t.Fatal(err)
+	}
+	return util.ParameterSet{
+		"mode":    "sql",
+		"connect": "sqlite3:" + fname,
+	}
+}
+
+// testCore returns a core instance without endpoints.
+func testCore(ctx context.Context, t *testing.T) *core.Core {
+	t.Helper()
+	c, err := core.NewCore(ctx, &config.NodeConfig{
+		Name:        "test",
+		PrivateSeed: "iYK1wSi5XtCP774eNFk1LYXqKlOPEpwKBw+2/bMkE24=",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Test that revoked zone keys are found in the bloomfilter, both after
+// a revocation and after re-loading the revocations from storage.
+func TestRevocationQuery(t *testing.T) {
+	// the test vector has a low difficulty
+	minDiff := MinAvgDifficulty
+	MinAvgDifficulty = 5
+	defer func() { MinAvgDifficulty = minDiff }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := testCore(ctx, t)
+	cfg := &config.RevocationConfig{
+		Storage: testStorage(t),
+	}
+	rd := testRevData(t)
+	zkey := rd.ZoneKeySig.Key()
+
+	// revoke zone key
+	m := NewModule(ctx, c, cfg)
+	if m == nil {
+		t.Fatal("can't create module")
+	}
+	if valid, err := m.Query(ctx, zkey); err != nil || !valid {
+		t.Fatalf("zone revoked before revocation: %v, %v", valid, err)
+	}
+	if ok, err := m.Revoke(ctx, rd); err != nil || !ok {
+		t.Fatalf("revoke failed: %v, %v", ok, err)
+	}
+	if valid, err := m.Query(ctx, zkey); err != nil || valid {
+		t.Fatalf("zone not revoked: %v, %v", valid, err)
+	}
+	// re-load revocations from storage
+	if m = NewModule(ctx, c, cfg); m == nil {
+		t.Fatal("can't create module")
+	}
+	if valid, err := m.Query(ctx, zkey); err != nil || valid {
+		t.Fatalf("zone not revoked after reload: %v, %v", valid, err)
+	}
+}
+
+// mockCore records revocations sent to connected peers.
+type mockCore struct {
+	sync.Mutex
+	peers   []*util.PeerID
+	sent    map[string]int
+	invalid int
+}
+
+func (c *mockCore) Connected() []*util.PeerID { return c.peers }
+
+func (c *mockCore) Send(ctx context.Context, peer *util.PeerID, msg message.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := msg.(*message.RevocationRevokeMsg); ok {
+		c.sent[peer.String()]++
+	}
+	return nil
+}
+
+func (c *mockCore) Register(name string, l *core.Listener) {}
+
+func (c *mockCore) Invalid(peer *util.PeerID, msg message.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.invalid++
+}
+
+// count returns the number of revocations sent to a peer.
+func (c *mockCore) count(peer *util.PeerID) int {
+	c.Lock()
+	defer c.Unlock()
+	return c.sent[peer.String()]
+}
+
+// newTestModule returns a revocation module with an empty store.
+func newTestModule(t *testing.T, c Core) *Module {
+	t.Helper()
+	cfg := &config.RevocationConfig{
+		Storage: testStorage(t),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m := NewModule(ctx, c, cfg)
+	if m == nil {
+		t.Fatal("can't create module")
+	}
+	return m
+}
+
+// waitFlooded waits for the propagation of a revocation to finish.
+func waitFlooded(t *testing.T, m *Module, zone string) *Propagation {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if _, p := m.Status(zone); p != nil && p.Done {
+			return p
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("propagation not finished")
+	return nil
+}
+
+// Test flooding of revocations to connected peers
+func TestRevocationFlooding(t *testing.T) {
+	// the test vector has a low difficulty
+	minDiff := MinAvgDifficulty
+	MinAvgDifficulty = 5
+	defer func() { MinAvgDifficulty = minDiff }()
+
+	rd := testRevData(t)
+	zone := rd.ZoneKeySig.ID()
+
+	mc := &mockCore{sent: make(map[string]int)}
+	for i := 0; i < 3; i++ {
+		mc.peers = append(mc.peers, util.NewPeerID(util.NewRndArray(32)))
+	}
+	m := newTestModule(t, mc)
+	ctx := context.Background()
+
+	// revocation received from a peer is flooded to the other peers
+	ev := &core.Event{
+		ID:   core.EV_MESSAGE,
+		Peer: mc.peers[0],
+		Msg:  rd.Message(),
+	}
+	m.event(ctx, ev)
+	if revoked, _ := m.Status(zone); !revoked {
+		t.Fatal("zone not revoked")
+	}
+	if p := waitFlooded(t, m, zone); p.Peers != 2 || p.Sent != 2 || p.Failed != 0 {
+		t.Fatalf("unexpected propagation: %+v", p)
+	}
+	if mc.count(mc.peers[0]) != 0 || mc.count(mc.peers[1]) != 1 || mc.count(mc.peers[2]) != 1 {
+		t.Fatalf("unexpected sends: %v", mc.sent)
+	}
+
+	// a known revocation from a peer is not flooded again
+	ev.Peer = mc.peers[1]
+	m.event(ctx, ev)
+	time.Sleep(50 * time.Millisecond)
+	if mc.count(mc.peers[0]) != 0 || mc.count(mc.peers[2]) != 1 {
+		t.Fatalf("known revocation flooded: %v", mc.sent)
+	}
+
+	// a local submission is flooded to all peers
+	ok, err := m.Revoke(ctx, rd)
+	if err != nil || !ok {
+		t.Fatalf("revoke failed: %v, %v", ok, err)
+	}
+	if p := waitFlooded(t, m, zone); p.Peers != 3 || p.Sent != 3 {
+		t.Fatalf("unexpected propagation: %+v", p)
+	}
+	if mc.count(mc.peers[0]) != 1 {
+		t.Fatalf("unexpected sends: %v", mc.sent)
+	}
+
+	// invalid revocations are reported
+	msg := rd.Message()
+	msg.PoWs[0], msg.PoWs[1] = msg.PoWs[1], msg.PoWs[0]
+	m.event(ctx, &core.Event{ID: core.EV_MESSAGE, Peer: mc.peers[2], Msg: msg})
+	if mc.invalid != 1 {
+		t.Fatalf("invalid revocation not reported (%d)", mc.invalid)
+	}
+}
@@ -0,0 +1,73 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import "gnunet/config"
+
+//----------------------------------------------------------------------
+// Difficulty policy for revocations
+//----------------------------------------------------------------------
+
+// DefaultMargin is the default safety margin (in bits) added to the
+// policy difficulty when selecting the difficulty of a new revocation.
+var DefaultMargin = 1
+
+// Policy is the difficulty policy applied to revocations.
+type Policy struct {
+	MinDifficulty int `json:"minDifficulty"` // minimum average difficulty accepted
+	AvgDifficulty int `json:"avgDifficulty"` // typical difficulty of published revocations
+	Margin        int `json:"margin"`        // safety margin (bits) for new revocations
+}
+
+// NewPolicy returns the policy for a configuration (or the default
+// policy if no configuration is given). Missing values are set to
+// defaults; the minimum can't be lower than MinAvgDifficulty.
+func NewPolicy(cfg *config.RevocationPolicy) *Policy {
+	p := &Policy{
+		MinDifficulty: MinAvgDifficulty,
+		Margin:        DefaultMargin,
+	}
+	if cfg != nil {
+		if cfg.MinDifficulty > p.MinDifficulty {
+			p.MinDifficulty = cfg.MinDifficulty
+		}
+		p.AvgDifficulty = cfg.AvgDifficulty
+		if cfg.Margin > 0 {
+			p.Margin = cfg.Margin
+		}
+	}
+	if p.AvgDifficulty < p.MinDifficulty {
+		p.AvgDifficulty = p.MinDifficulty
+	}
+	return p
+}
+
+// CurrentPolicy returns the policy of a node configuration.
+func CurrentPolicy(cfg *config.Config) *Policy {
+	if cfg != nil && cfg.Revocation != nil {
+		return NewPolicy(cfg.Revocation.Policy)
+	}
+	return NewPolicy(nil)
+}
+
+// Target returns the difficulty for new revocations: the average policy
+// difficulty plus the safety margin.
+func (p *Policy) Target() int {
+	return p.AvgDifficulty + p.Margin
+}
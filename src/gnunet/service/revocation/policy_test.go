@@ -0,0 +1,62 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"gnunet/config"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	// defaults
+	p := NewPolicy(nil)
+	if p.MinDifficulty != MinAvgDifficulty || p.AvgDifficulty != MinAvgDifficulty || p.Target() != MinAvgDifficulty+DefaultMargin {
+		t.Fatalf("unexpected default policy: %+v", p)
+	}
+	// configured policy
+	p = NewPolicy(&config.RevocationPolicy{
+		MinDifficulty: 25,
+		AvgDifficulty: 27,
+		Margin:        2,
+	})
+	if p.MinDifficulty != 25 || p.Target() != 29 {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	// the configuration can't lower the minimum; the average is at
+	// least the minimum.
+	p = NewPolicy(&config.RevocationPolicy{
+		MinDifficulty: MinAvgDifficulty - 5,
+		AvgDifficulty: MinAvgDifficulty - 3,
+	})
+	if p.MinDifficulty != MinAvgDifficulty || p.Target() != MinAvgDifficulty+DefaultMargin {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	// policy of a node configuration
+	cfg := &config.Config{
+		Revocation: &config.RevocationConfig{
+			Policy: &config.RevocationPolicy{MinDifficulty: 26},
+		},
+	}
+	if p = CurrentPolicy(cfg); p.MinDifficulty != 26 {
+		t.Fatalf("unexpected node policy: %+v", p)
+	}
+	if p = CurrentPolicy(nil); p.MinDifficulty != MinAvgDifficulty {
+		t.Fatalf("unexpected default policy: %+v", p)
+	}
+}
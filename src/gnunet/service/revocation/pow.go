@@ -23,6 +23,7 @@ import (
 	"context"
 	"encoding/binary"
 	"sort"
+	"sync"
 	"time"
 
 	"gnunet/crypto"
@@ -133,14 +134,25 @@ type SignedRevData struct {
 func NewRevDataFromMsg(m *message.RevocationRevokeMsg) *RevData {
 	return &RevData{
 		Timestamp:  m.Timestamp,
+		TTL:        m.TTL,
 		ZoneKeySig: m.ZoneKeySig,
 		PoWs:       util.Clone(m.PoWs),
 	}
 }
 
+// Message returns a revocation message for the revocation data.
+func (rd *RevData) Message() *message.RevocationRevokeMsg {
+	msg := message.NewRevocationRevokeMsg(rd.ZoneKeySig)
+	msg.Timestamp = rd.Timestamp
+	msg.TTL = rd.TTL
+	copy(msg.PoWs, rd.PoWs)
+	return msg
+}
+
 // Size of a serialized RevData object.
 func (rd *RevData) Size() int {
-	return 16 + 8*len(rd.PoWs) + int(rd.ZoneKeySig.SigSize())
+	zs := rd.ZoneKeySig
+	return 16 + 8*len(rd.PoWs) + 4 + int(zs.KeySize()+zs.SigSize())
 }
 
 // Sign the revocation data
@@ -220,13 +232,16 @@ type RevDataCalc struct {
 	SmallestIdx byte     // index of smallest number of leading zeros
 }
 
-// NewRevDataCalc initializes a new RevDataCalc instance
+// NewRevDataCalc initializes a new RevDataCalc instance for a zone key
+// (with an empty signature).
 func NewRevDataCalc(zkey *crypto.ZoneKey) *RevDataCalc {
+	zs := &crypto.ZoneSignature{ZoneKey: *zkey}
+	zs.Signature = make([]byte, zs.SigSize())
 	rd := &RevDataCalc{
 		RevData: RevData{
 			Timestamp:  util.AbsoluteTimeNow(),
 			PoWs:       make([]uint64, 32),
-			ZoneKeySig: nil,
+			ZoneKeySig: zs,
 		},
 		Bits:        make([]uint16, 32),
 		SmallestIdx: 0,
@@ -249,8 +264,9 @@ func (rdc *RevDataCalc) Average() float64 {
 }
 
 // Insert a PoW that is "better than the worst" current PoW element.
+// PoW values already in the list are ignored.
 func (rdc *RevDataCalc) Insert(pow uint64, bits uint16) (float64, uint16) {
-	if bits > rdc.Bits[rdc.SmallestIdx] {
+	if bits > rdc.Bits[rdc.SmallestIdx] && !rdc.contains(pow) {
 		rdc.PoWs[rdc.SmallestIdx] = pow
 		rdc.Bits[rdc.SmallestIdx] = bits
 		rdc.sortBits()
@@ -258,6 +274,16 @@ func (rdc *RevDataCalc) Insert(pow uint64, bits uint16) (float64, uint16) {
 	return rdc.Average(), rdc.Bits[rdc.SmallestIdx]
 }
 
+// check if a PoW value is in the list
+func (rdc *RevDataCalc) contains(pow uint64) bool {
+	for _, p := range rdc.PoWs {
+		if p == pow {
+			return true
+		}
+	}
+	return false
+}
+
 // Get the smallest bit position
 func (rdc *RevDataCalc) sortBits() {
 	var (
@@ -273,16 +299,48 @@ func (rdc *RevDataCalc) sortBits() {
 	rdc.SmallestIdx = byte(pos)
 }
 
-// Compute tries to compute a valid Revocation; it returns the average number
-// of leading zero-bits and the last PoW value tried. The computation is
-// complete if the average above is greater or equal to 'bits'.
+// PoWChunk is the number of consecutive PoW values a worker tests
+// before it gets the next range of values.
+var PoWChunk uint64 = 64
+
+// ComputeOptions control the computation of PoWs: the search for PoW
+// values is split into disjoint ranges processed by parallel workers.
+// Checkpoints are consistent copies of the revocation data (and the
+// first PoW value not yet tested) to be saved by the caller.
+type ComputeOptions struct {
+	Workers    int                                      // number of workers (at least 1)
+	Period     time.Duration                            // time between checkpoints (0 = none)
+	Checkpoint func(snapshot *RevDataCalc, next uint64) // called for checkpoints
+}
+
+// Compute tries to compute a valid Revocation with a single worker (see
+// ComputeParallel).
 func (rdc *RevDataCalc) Compute(ctx context.Context, bits int, last uint64, cb func(float64, uint64)) (float64, uint64) {
+	return rdc.ComputeParallel(ctx, bits, last, nil, cb)
+}
+
+// ComputeParallel tries to compute a valid Revocation; it returns the
+// average number of leading zero-bits and the next PoW value to be
+// tested (all smaller values have been tested). The computation starts
+// at 'last' (e.g. the value saved with a checkpoint); if 'last' is 0 it
+// starts after the largest PoW value in the list. The computation is
+// complete if the average above is greater or equal to 'bits'. The
+// callback is invoked for every improved PoW (never concurrently).
+func (rdc *RevDataCalc) ComputeParallel(ctx context.Context, bits int, last uint64, opts *ComputeOptions, cb func(float64, uint64)) (float64, uint64) {
+	if opts == nil {
+		opts = new(ComputeOptions)
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
 	// find the largest PoW value in current work unit
 	work := NewPoWData(0, rdc.Timestamp, &rdc.ZoneKeySig.ZoneKey)
 	var max uint64
 	for i, pow := range rdc.PoWs {
 		if pow == 0 {
 			max++
+			rdc.PoWs[i] = max
 			work.SetPoW(max)
 			res := work.Compute()
 			rdc.Bits[i] = uint16(512 - res.BitLen())
@@ -290,36 +348,98 @@ func (rdc *RevDataCalc) Compute(ctx context.Context, bits int, last uint64, cb f
 			max = pow
 		}
 	}
-	// adjust 'last' value
-	if last <= max {
+	rdc.sortBits()
+	// start after the largest PoW value for new calculations; resumed
+	// calculations can have found PoWs above 'last' (in ranges that were
+	// pending at the checkpoint) and continue from 'last' as given.
+	if last == 0 {
 		last = max + 1
 	}
 
-	// Find PoW value in an (interruptable) loop
-	out := make(chan bool)
-	go func() {
-		work.SetPoW(last + 1)
-		smallest := rdc.Bits[rdc.SmallestIdx]
-		average := rdc.Average()
-		for average < float64(bits) {
-			res := work.Compute()
-			num := uint16(512 - res.BitLen())
-			if num > smallest {
-				pow := work.GetPoW()
-				average, smallest = rdc.Insert(pow, num)
-				cb(average, pow)
+	// shared state of workers: ranges are handed out in ascending order;
+	// all values below the start of the lowest pending range are tested.
+	var (
+		mtx     sync.Mutex
+		next    = last                  // start of next range
+		pending = make(map[uint64]bool) // ranges in progress
+		done    = rdc.Average() >= float64(bits)
+	)
+	tested := func() uint64 {
+		low := next
+		for start := range pending {
+			if start < low {
+				low = start
 			}
-			work.Next()
 		}
-		out <- true
+		return low
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// run workers on disjoint ranges of PoW values
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers && !done; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := NewPoWData(0, rdc.Timestamp, &rdc.ZoneKeySig.ZoneKey)
+			for {
+				// get next range
+				mtx.Lock()
+				start := next
+				next += PoWChunk
+				pending[start] = true
+				mtx.Unlock()
+
+				w.SetPoW(start)
+				for n := uint64(0); n < PoWChunk; n++ {
+					if wctx.Err() != nil {
+						return
+					}
+					num := uint16(512 - w.Compute().BitLen())
+					mtx.Lock()
+					if pow := w.GetPoW(); num > rdc.Bits[rdc.SmallestIdx] && !rdc.contains(pow) {
+						average, _ := rdc.Insert(pow, num)
+						cb(average, pow)
+						if average >= float64(bits) {
+							cancel()
+						}
+					}
+					mtx.Unlock()
+					w.Next()
+				}
+				// range completed
+				mtx.Lock()
+				delete(pending, start)
+				mtx.Unlock()
+			}
+		}()
+	}
+	// write checkpoints while workers are running
+	running := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(running)
 	}()
+	var tick <-chan time.Time
+	if opts.Period > 0 && opts.Checkpoint != nil {
+		ticker := time.NewTicker(opts.Period)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 loop:
 	for {
 		select {
-		case <-out:
-			break loop
-		case <-ctx.Done():
+		case <-running:
 			break loop
+		case <-tick:
+			mtx.Lock()
+			snapshot := *rdc
+			snapshot.PoWs = util.Clone(rdc.PoWs)
+			snapshot.Bits = util.Clone(rdc.Bits)
+			low := tested()
+			mtx.Unlock()
+			opts.Checkpoint(&snapshot, low)
 		}
 	}
 	// re-order the PoWs for compliance
@@ -329,7 +449,7 @@ loop:
 		rdc.Bits[i] = uint16(512 - work.Compute().BitLen())
 	}
 	rdc.sortBits()
-	return rdc.Average(), work.GetPoW()
+	return rdc.Average(), tested()
 }
 
 // Blob returns the binary data structure (wire format).
@@ -2,11 +2,15 @@ package revocation
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/util"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bfix/gospel/data"
 )
@@ -225,3 +229,119 @@ func TestRevocationRFC(t *testing.T) {
 		}
 	}
 }
+
+// Test serialization of new revocation calculations
+func TestRevDataCalc(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zk := zp.Public()
+	rdc := NewRevDataCalc(zk)
+	buf, err := data.Marshal(rdc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != rdc.Size() {
+		t.Fatalf("size mismatch: %d != %d", len(buf), rdc.Size())
+	}
+	rdc2 := new(RevDataCalc)
+	if err = data.Unmarshal(rdc2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !zk.Equal(&rdc2.ZoneKeySig.ZoneKey) {
+		t.Fatal("zone key mismatch")
+	}
+}
+
+// Test computation of a (low difficulty) revocation
+func TestRevDataCompute(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdc := NewRevDataCalc(zp.Public())
+	average, _ := rdc.Compute(context.Background(), 2, 0, func(float64, uint64) {})
+	if average < 2 {
+		t.Fatalf("average %.2f", average)
+	}
+	if zbits, rc := rdc.Verify(false); rc != 0 || zbits < 2 {
+		t.Fatalf("verify: %.2f, rc=%d", zbits, rc)
+	}
+}
+
+// Test parallel computation of PoWs (with checkpoints)
+func TestComputeParallel(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdc := NewRevDataCalc(zp.Public())
+
+	var (
+		mtx       sync.Mutex
+		snapshots int
+		lastNext  uint64
+	)
+	opts := &ComputeOptions{
+		Workers: 4,
+		Period:  10 * time.Millisecond,
+		Checkpoint: func(snapshot *RevDataCalc, next uint64) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if next < lastNext {
+				t.Errorf("checkpoint moved backwards: %d < %d", next, lastNext)
+			}
+			if len(snapshot.PoWs) != 32 || &snapshot.PoWs[0] == &rdc.PoWs[0] {
+				t.Error("checkpoint not a copy")
+			}
+			lastNext = next
+			snapshots++
+		},
+	}
+	improved := 0
+	average, next := rdc.ComputeParallel(context.Background(), 2, 0, opts, func(float64, uint64) {
+		improved++
+	})
+	if average < 2 {
+		t.Fatalf("average %.2f after %d improvements", average, improved)
+	}
+	if next < lastNext {
+		t.Fatalf("next PoW %d before checkpoint %d", next, lastNext)
+	}
+	if zbits, rc := rdc.Verify(false); rc != 0 || zbits < 2 {
+		t.Fatalf("verify: %.2f, rc=%d", zbits, rc)
+	}
+	if testing.Verbose() {
+		t.Logf("next=%d, %d improvements, %d checkpoints", next, improved, snapshots)
+	}
+
+	// interrupted computation resumes from the checkpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, n := rdc.ComputeParallel(ctx, 64, next, opts, func(float64, uint64) {}); n != next {
+		t.Fatalf("interrupted computation: next %d != %d", n, next)
+	}
+}
+
+// Test resuming a computation from a checkpoint below found PoWs
+func TestComputeResume(t *testing.T) {
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdc := NewRevDataCalc(zp.Public())
+	if average, _ := rdc.Compute(context.Background(), 2, 0, func(float64, uint64) {}); average < 2 {
+		t.Fatalf("average %.2f", average)
+	}
+	// resume from a value below the largest PoW (as saved by a checkpoint
+	// while ranges above were pending): PoWs found again are not added.
+	opts := &ComputeOptions{Workers: 4}
+	average, _ := rdc.ComputeParallel(context.Background(), 3, 1, opts, func(float64, uint64) {})
+	if average < 3 {
+		t.Fatalf("average %.2f", average)
+	}
+	if zbits, rc := rdc.Verify(false); rc != 0 || zbits < 3 {
+		t.Fatalf("verify: %.2f, rc=%d", zbits, rc)
+	}
+}
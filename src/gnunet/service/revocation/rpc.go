@@ -1,5 +1,5 @@
 // This file is part of gnunet-go, a GNUnet-implementation in Golang.
-// Copyright (C) 2022 Bernd Fix  >Y<
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
 //
 // gnunet-go is free software: you can redistribute it and/or modify it
 // under the terms of the GNU Affero General Public License as published
@@ -18,10 +18,77 @@
 
 package revocation
 
-import "gnunet/service"
+import (
+	"gnunet/config"
+	"gnunet/service"
+	"net/http"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for revocation-related JSON-RPC requests
+type RPCService struct {
+	policy *config.RevocationPolicy // configured difficulty policy
+	mod    *Module                  // revocation module
+}
+
+//----------------------------------------------------------------------
+// Command "Revocation.Policy"
+//----------------------------------------------------------------------
+
+// PolicyRequest asks for the difficulty policy of the service
+type PolicyRequest struct{}
+
+// PolicyResponse returns the difficulty policy and the recommended
+// difficulty for new revocations.
+type PolicyResponse struct {
+	Policy
+	Target int `json:"target"` // recommended difficulty
+}
+
+// Policy returns the current difficulty policy.
+func (s *RPCService) Policy(r *http.Request, req *PolicyRequest, reply *PolicyResponse) error {
+	p := NewPolicy(s.policy)
+	*reply = PolicyResponse{
+		Policy: *p,
+		Target: p.Target(),
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "Revocation.Status"
+//----------------------------------------------------------------------
+
+// StatusRequest asks for the revocation status of a zone
+type StatusRequest struct {
+	Zone string `json:"zone"` // zone key (ID)
+}
+
+// StatusResponse returns the revocation status of a zone and the
+// propagation of the revocation to connected peers.
+type StatusResponse struct {
+	Revoked     bool         `json:"revoked"`
+	Propagation *Propagation `json:"propagation,omitempty"`
+}
+
+// Status returns the revocation status of a zone.
+func (s *RPCService) Status(r *http.Request, req *StatusRequest, reply *StatusResponse) error {
+	revoked, prop := s.mod.Status(req.Zone)
+	*reply = StatusResponse{
+		Revoked:     revoked,
+		Propagation: prop,
+	}
+	return nil
+}
 
 //----------------------------------------------------------------------
 
 // InitRPC registers RPC commands for the module
 func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{policy: m.policy, mod: m}, "Revocation"); err != nil {
+		logger.Printf(logger.ERROR, "[revocation] Failed to init RPC: %s", err.Error())
+	}
 }
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 
+	"gnunet/config"
 	"gnunet/core"
 	"gnunet/message"
 	"gnunet/service"
@@ -42,9 +43,9 @@ type Service struct {
 }
 
 // NewService creates a new revocation service instance
-func NewService(ctx context.Context, c *core.Core) service.Service {
+func NewService(ctx context.Context, c *core.Core, cfg *config.RevocationConfig) service.Service {
 	// instantiate service
-	mod := NewModule(ctx, c)
+	mod := NewModule(ctx, c, cfg)
 	srv := &Service{
 		Module: *mod,
 	}
@@ -120,17 +121,27 @@ func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg me
 				}
 				return
 			}
-			resp = message.NewRevocationQueryResponseMsg(valid)
+			resp = message.NewRevocationQueryResponseMsg(!valid)
 		}(m)
 
 	case *message.RevocationRevokeMsg:
 		//----------------------------------------------------------
 		// REVOCATION_REVOKE
 		//----------------------------------------------------------
+		// check request limit (waits for a free slot in queue mode): the
+		// verification of revocation data is expensive.
+		if err := s.Limiter().AcquireRequest(ctx); err != nil {
+			logger.Printf(logger.WARN, "[revocation%s] Revoke request rejected: %s\n", label, err.Error())
+			if err = back.Send(ctx, message.NewRevocationRevokeResponseMsg(false)); err != nil {
+				logger.Printf(logger.ERROR, "[revocation%s] Failed to send response: %s\n", label, err.Error())
+			}
+			return true
+		}
 		go func(m *message.RevocationRevokeMsg) {
 			logger.Printf(logger.INFO, "[revocation%s] Revoke request received.\n", label)
 			var resp *message.RevocationRevokeResponseMsg
 			defer func() {
+				s.Limiter().ReleaseRequest()
 				// send response
 				if resp != nil {
 					if err := back.Send(ctx, resp); err != nil {
@@ -0,0 +1,84 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+// Test the status reported in responses to revocation queries.
+func TestServiceQuery(t *testing.T) {
+	// the test vector has a low difficulty
+	minDiff := MinAvgDifficulty
+	MinAvgDifficulty = 5
+	defer func() { MinAvgDifficulty = minDiff }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := testCore(ctx, t)
+	cfg := &config.RevocationConfig{
+		Storage: testStorage(t),
+	}
+	srv := NewService(ctx, c, cfg).(*Service)
+	rd := testRevData(t)
+
+	// collect responses
+	resp := make(chan message.Message, 1)
+	back := &transport.TransportResponder{
+		SendFcn: func(_ context.Context, _ *util.PeerID, msg message.Message) error {
+			resp <- msg
+			return nil
+		},
+	}
+	query := func() uint32 {
+		t.Helper()
+		if !srv.HandleMessage(ctx, nil, message.NewRevocationQueryMsg(rd.ZoneKeySig.Key()), back) {
+			t.Fatal("query not handled")
+		}
+		select {
+		case msg := <-resp:
+			qr, ok := msg.(*message.RevocationQueryResponseMsg)
+			if !ok {
+				t.Fatalf("unexpected response %v", msg)
+			}
+			return qr.Valid
+		case <-time.After(time.Second):
+			t.Fatal("no response")
+		}
+		return 0
+	}
+	// zone key is valid before revocation ...
+	if query() != 1 {
+		t.Fatal("valid zone reported as revoked")
+	}
+	// ... and revoked afterwards.
+	if ok, err := srv.Revoke(ctx, rd); err != nil || !ok {
+		t.Fatalf("revoke failed: %v, %v", ok, err)
+	}
+	if query() != 0 {
+		t.Fatal("revoked zone reported as valid")
+	}
+}
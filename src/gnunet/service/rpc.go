@@ -21,8 +21,11 @@ package service
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"time"
 
+	"gnunet/service/metrics"
+
 	"github.com/bfix/gospel/logger"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/rpc/v2"
@@ -37,6 +40,10 @@ type JRPCServer struct {
 	*rpc.Server
 }
 
+// JSON-RPC calls (by method and HTTP status)
+var rpcCalls = metrics.NewCounter("gnunet_rpc_calls_total",
+	"JSON-RPC calls by method and status.", "method", "status")
+
 //----------------------------------------------------------------------
 // JSON-RPC interface for services to be used as the primary client API
 // for perform, manage and monitor GNUnet activities.
@@ -47,6 +54,9 @@ func RunRPCServer(ctx context.Context, endpoint string) (srvRPC *JRPCServer, err
 	// instantiate RPC service
 	srvRPC = &JRPCServer{rpc.NewServer()}
 	srvRPC.RegisterCodec(json2.NewCodec(), "application/json")
+	srvRPC.RegisterAfterFunc(func(i *rpc.RequestInfo) {
+		rpcCalls.Inc(i.Method, strconv.Itoa(i.StatusCode))
+	})
 
 	// setup RPC request handler
 	router := mux.NewRouter()
@@ -0,0 +1,309 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrJobExists = errors.New("maintenance job already registered")
+	ErrJobPeriod = errors.New("invalid maintenance job period")
+)
+
+//----------------------------------------------------------------------
+// Maintenance scheduler: Modules register periodic maintenance jobs
+// (cache clean-up, re-publishing, routing table refresh, statistics)
+// with the scheduler instead of running their own tickers. Periods are
+// randomized (jitter), so jobs of different services and nodes don't run
+// in lock-step. A job that is still running when it is due again is not
+// started twice (the run is skipped and counted as overrun); each run is
+// cancelled if it takes longer than the job period.
+//----------------------------------------------------------------------
+
+// DefaultJitter is the default random deviation of job periods (fraction
+// of the period).
+const DefaultJitter = 0.1
+
+// StatsPeriod is the time between statistics logs of a service.
+var StatsPeriod = 5 * time.Minute
+
+// Job is a periodic maintenance task.
+type Job func(ctx context.Context) error
+
+// JobStats are the statistics of a maintenance job.
+type JobStats struct {
+	Name     string `json:"name"`     // job name
+	Period   string `json:"period"`   // (nominal) period
+	Runs     int64  `json:"runs"`     // number of completed runs
+	Failures int64  `json:"failures"` // number of failed runs
+	Overruns int64  `json:"overruns"` // number of skipped runs
+	LastRun  string `json:"lastRun"`  // start of last run
+	LastTime string `json:"lastTime"` // duration of last run
+	LastErr  string `json:"lastErr"`  // error of last failed run
+}
+
+// job is a registered maintenance job
+type job struct {
+	ctx     context.Context // job context
+	name    string          // job name
+	period  time.Duration   // nominal period
+	run     Job             // job function
+	running bool            // job is running
+	stats   JobStats        // job statistics
+}
+
+// Scheduler runs periodic maintenance jobs.
+type Scheduler struct {
+	sync.Mutex
+
+	cfg  *config.MaintenanceConfig // job settings (or nil)
+	jobs map[string]*job           // registered jobs
+}
+
+// NewScheduler creates a new (empty) scheduler with given job settings
+// (nil for defaults).
+func NewScheduler(cfg *config.MaintenanceConfig) *Scheduler {
+	return &Scheduler{
+		cfg:  cfg,
+		jobs: make(map[string]*job),
+	}
+}
+
+// Maintenance is the scheduler for all services in this process.
+var Maintenance = NewScheduler(nil)
+
+// Configure sets the job settings of the scheduler; they apply to jobs
+// registered afterwards.
+func (s *Scheduler) Configure(cfg *config.MaintenanceConfig) {
+	s.Lock()
+	defer s.Unlock()
+	s.cfg = cfg
+}
+
+// CtxScheduler is the context key for the scheduler of a node (value is
+// *Scheduler). Nodes running in the same process use their own
+// schedulers, so their jobs don't collide.
+const CtxScheduler = core.CtxKey("service:scheduler")
+
+// Schedule registers a maintenance job with the scheduler of the context
+// (or the process scheduler if the context has none).
+func Schedule(ctx context.Context, name string, period time.Duration, run Job) error {
+	s, ok := ctx.Value(CtxScheduler).(*Scheduler)
+	if !ok {
+		s = Maintenance
+	}
+	return s.Register(ctx, name, period, run)
+}
+
+// Register a maintenance job: the job runs every 'period' (randomized by
+// the configured jitter) until the context is cancelled; the job is then
+// removed from the scheduler. The period can be overridden in the
+// configuration.
+func (s *Scheduler) Register(ctx context.Context, name string, period time.Duration, run Job) error {
+	// apply configuration
+	jitter := DefaultJitter
+	s.Lock()
+	cfg := s.cfg
+	s.Unlock()
+	if cfg != nil {
+		if cfg.Jitter > 0 {
+			jitter = cfg.Jitter
+		}
+		if p, ok := cfg.Periods[name]; ok {
+			if p <= 0 {
+				logger.Printf(logger.INFO, "[maintenance] job '%s' disabled", name)
+				return nil
+			}
+			period = time.Duration(p) * time.Second
+		}
+	}
+	if period <= 0 {
+		return ErrJobPeriod
+	}
+	// register job
+	// (a job with a cancelled context is replaced)
+	s.Lock()
+	if old, ok := s.jobs[name]; ok && old.ctx.Err() == nil {
+		s.Unlock()
+		return ErrJobExists
+	}
+	j := &job{
+		ctx:    ctx,
+		name:   name,
+		period: period,
+		run:    run,
+		stats: JobStats{
+			Name:   name,
+			Period: period.String(),
+		},
+	}
+	s.jobs[name] = j
+	s.Unlock()
+
+	// run job periodically
+	go func() {
+		for {
+			delay := period
+			if jitter > 0 {
+				// random deviation in [-jitter,+jitter]
+				dev := (float64(util.RndUInt32())/float64(^uint32(0))*2 - 1) * jitter
+				delay += time.Duration(dev * float64(period))
+			}
+			select {
+			case <-ctx.Done():
+				s.Lock()
+				if s.jobs[name] == j {
+					delete(s.jobs, name)
+				}
+				s.Unlock()
+				return
+			case <-time.After(delay):
+			}
+			s.Lock()
+			if j.running {
+				j.stats.Overruns++
+				s.Unlock()
+				logger.Printf(logger.WARN, "[maintenance] job '%s' still running -- skipped", name)
+				continue
+			}
+			j.running = true
+			s.Unlock()
+			go s.execute(ctx, j)
+		}
+	}()
+	return nil
+}
+
+// execute a job run (with a deadline of one period)
+func (s *Scheduler) execute(ctx context.Context, j *job) {
+	start := time.Now()
+	jctx, cancel := context.WithTimeout(ctx, j.period)
+	err := j.run(jctx)
+	cancel()
+
+	s.Lock()
+	defer s.Unlock()
+	j.running = false
+	j.stats.Runs++
+	j.stats.LastRun = start.UTC().Format(time.RFC3339)
+	j.stats.LastTime = time.Since(start).String()
+	if err != nil {
+		j.stats.Failures++
+		j.stats.LastErr = err.Error()
+		logger.Printf(logger.WARN, "[maintenance] job '%s' failed: %s", j.name, err.Error())
+	}
+}
+
+// Jobs returns the statistics of named jobs (all jobs if no names are
+// given), sorted by name.
+func (s *Scheduler) Jobs(names ...string) []JobStats {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]JobStats, 0)
+	for name, j := range s.jobs {
+		if len(names) > 0 && !contains(names, name) {
+			continue
+		}
+		out = append(out, j.stats)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// contains returns true if the list contains the string
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// StatsJob returns a job that logs the statistics of the maintenance jobs
+// and resource limiters of the named service (prefix of job names).
+func StatsJob(name string) Job {
+	return func(ctx context.Context) error {
+		var list []string
+		for _, js := range Maintenance.Jobs() {
+			if strings.HasPrefix(js.Name, name+":") {
+				list = append(list, fmt.Sprintf("%s=%d/%d/%d", js.Name, js.Runs, js.Failures, js.Overruns))
+			}
+		}
+		logger.Printf(logger.INFO, "[%s] jobs (runs/failures/overruns): %s", name, strings.Join(list, ", "))
+		limitersLock.Lock()
+		l, ok := limiters[name]
+		limitersLock.Unlock()
+		if ok {
+			st := l.Stats()
+			logger.Printf(logger.INFO, "[%s] limits: sessions=%d/%d, requests=%d/%d, cache=%d/%d",
+				name, st.Sessions.Active, st.Sessions.Max, st.Requests.Active, st.Requests.Max,
+				st.Cache.Active, st.Cache.Max)
+		}
+		return nil
+	}
+}
+
+//----------------------------------------------------------------------
+// Command "Maintenance.Jobs"
+//----------------------------------------------------------------------
+
+// MaintenanceRPC is a type for JSON-RPC requests on maintenance jobs.
+type MaintenanceRPC struct{}
+
+// JobsRequest asks for the statistics of named jobs (all jobs if the
+// list is empty).
+type JobsRequest struct {
+	Names []string `json:"names"`
+}
+
+// JobsResponse lists the statistics of maintenance jobs.
+type JobsResponse struct {
+	Jobs []JobStats `json:"jobs"`
+}
+
+// Jobs returns the statistics of maintenance jobs.
+func (s *MaintenanceRPC) Jobs(r *http.Request, req *JobsRequest, reply *JobsResponse) error {
+	*reply = JobsResponse{Jobs: Maintenance.Jobs(req.Names...)}
+	return nil
+}
+
+// InitMaintenanceRPC registers the RPC command for maintenance jobs.
+func InitMaintenanceRPC(srv *JRPCServer) {
+	if err := srv.RegisterService(new(MaintenanceRPC), "Maintenance"); err != nil {
+		logger.Printf(logger.ERROR, "[maintenance] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,127 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gnunet/config"
+)
+
+func TestSchedulerRuns(t *testing.T) {
+	s := NewScheduler(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fail := errors.New("failed")
+	n := 0
+	err := s.Register(ctx, "test:run", 20*time.Millisecond, func(context.Context) error {
+		n++
+		if n%2 == 0 {
+			return fail
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Register(ctx, "test:run", time.Second, nil); err != ErrJobExists {
+		t.Fatalf("expected duplicate job error, got %v", err)
+	}
+	if err = s.Register(ctx, "test:zero", 0, nil); err != ErrJobPeriod {
+		t.Fatalf("expected period error, got %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	jobs := s.Jobs("test:run")
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(jobs))
+	}
+	if jobs[0].Runs < 2 || jobs[0].Failures == 0 || jobs[0].LastErr != "failed" {
+		t.Fatalf("unexpected job stats: %+v", jobs[0])
+	}
+	// cancelled jobs are removed
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if len(s.Jobs()) != 0 {
+		t.Fatal("job not removed")
+	}
+}
+
+func TestSchedulerOverrun(t *testing.T) {
+	s := NewScheduler(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// job ignores its deadline and blocks longer than three periods
+	err := s.Register(ctx, "test:slow", 20*time.Millisecond, func(context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	jobs := s.Jobs()
+	if len(jobs) != 1 || jobs[0].Overruns == 0 {
+		t.Fatalf("unexpected job stats: %+v", jobs)
+	}
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	s := NewScheduler(&config.MaintenanceConfig{
+		Periods: map[string]int{
+			"test:off": 0,
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := s.Register(ctx, "test:off", time.Millisecond, func(context.Context) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Jobs()) != 0 {
+		t.Fatal("disabled job registered")
+	}
+}
+
+func TestScheduleContext(t *testing.T) {
+	// two nodes register the same job with their own schedulers
+	run := func(context.Context) error { return nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var nodes [2]*Scheduler
+	for i := range nodes {
+		nodes[i] = NewScheduler(nil)
+		nctx := context.WithValue(ctx, CtxScheduler, nodes[i])
+		if err := Schedule(nctx, "test:node", time.Hour, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, s := range nodes {
+		if len(s.Jobs("test:node")) != 1 {
+			t.Fatalf("job not registered with scheduler of node %d", i)
+		}
+	}
+	if len(Maintenance.Jobs("test:node")) != 0 {
+		t.Fatal("job registered with process scheduler")
+	}
+}
@@ -21,9 +21,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"gnunet/config"
 	"gnunet/message"
 	"gnunet/transport"
 	"gnunet/util"
+	"sync/atomic"
 
 	"github.com/bfix/gospel/logger"
 )
@@ -53,16 +55,44 @@ type SocketHandler struct {
 	hdlr chan *Connection   // handler for incoming connections
 	cmgr *ConnectionManager // manager for client connections
 	name string             // service name
+	lim  *Limiter           // resource limits (or nil)
+	src  string             // link to source code of the node
+	open int32              // number of open client sessions
 }
 
 // NewSocketHandler instantiates a new socket handler.
 func NewSocketHandler(name string, srv Service) *SocketHandler {
-	return &SocketHandler{
+	h := &SocketHandler{
 		srv:  srv,
 		hdlr: make(chan *Connection),
 		cmgr: nil,
 		name: name,
 	}
+	RegisterIntrospector(name, func() *Introspection {
+		return &Introspection{
+			Clients: int(atomic.LoadInt32(&h.open)),
+		}
+	})
+	return h
+}
+
+// SetLimits configures resource limits for the service. Session limits
+// are enforced by the socket handler; the limiter is handed to services
+// that limit requests and caches themselves. Must be called before the
+// handler is started.
+func (h *SocketHandler) SetLimits(cfg *config.LimitConfig) *Limiter {
+	h.lim = NewLimiter(h.name, cfg)
+	if srv, ok := h.srv.(Limited); ok {
+		srv.SetLimiter(h.lim)
+	}
+	return h.lim
+}
+
+// SetSource sets the link to the source code of the node that is sent to
+// clients asking for it (REQUEST_AGPL). Must be called before the handler
+// is started.
+func (h *SocketHandler) SetSource(link string) {
+	h.src = link
 }
 
 // Start the socket handler by listening on a Unix domain socket specified
@@ -81,19 +111,29 @@ func (h *SocketHandler) Start(ctx context.Context, path string, params map[strin
 	}
 
 	// handle client connections
-	go func() {
+	go func(cmgr *ConnectionManager) {
 	loop:
 		for {
 			select {
 			// handle incoming connection
 			case conn := <-h.hdlr:
+				// check session limit (waits for a free slot in queue mode)
+				if err := h.lim.AcquireSession(ctx); err != nil {
+					logger.Printf(logger.WARN, "[%s] Session rejected: %s\n", h.name, err.Error())
+					conn.Close()
+					continue
+				}
 				// run a new session with context
+				conn.source = h.src
 				id := util.NextID()
 				logger.Printf(logger.INFO, "[%s] Session '%d' started.\n", h.name, id)
 
 				go func() {
 					// serve client on the message channel
+					atomic.AddInt32(&h.open, 1)
 					h.srv.ServeClient(ctx, id, conn)
+					atomic.AddInt32(&h.open, -1)
+					h.lim.ReleaseSession()
 					// session is done now.
 					logger.Printf(logger.INFO, "[%s] Session with client '%d' ended.\n", h.name, id)
 				}()
@@ -107,8 +147,8 @@ func (h *SocketHandler) Start(ctx context.Context, path string, params map[strin
 
 		// close-down service
 		logger.Printf(logger.INFO, "[%s] Service closing.\n", h.name)
-		h.cmgr.Close()
-	}()
+		cmgr.Close()
+	}(h.cmgr)
 	return nil
 }
 
@@ -0,0 +1,63 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/transport"
+	"gnunet/util"
+)
+
+// testService is a service without functionality.
+type testService struct{}
+
+func (s *testService) Export(map[string]any)     {}
+func (s *testService) Import(map[string]any)     {}
+func (s *testService) InitRPC(*JRPCServer)       {}
+func (s *testService) Filter() *core.EventFilter { return core.NewEventFilter() }
+
+func (s *testService) ServeClient(ctx context.Context, id int, mc *Connection) {}
+
+func (s *testService) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, resp transport.Responder) bool {
+	return false
+}
+
+// Test stopping a socket handler before its context is cancelled.
+func TestSocketHandlerStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h := NewSocketHandler("test", new(testService))
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	if err := h.Start(ctx, socket, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// terminating the handler must only close its own connection manager
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+}
@@ -0,0 +1,137 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package servicetest provides helpers for testing the message handling
+// of services: requests and responses are passed through their wire
+// format, so that size and serialization errors of messages are caught.
+package servicetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+)
+
+// WaitTimeout is the max. time to wait for asynchronous messages.
+var WaitTimeout = 5 * time.Second
+
+// Wire marshals a message and parses it again (as received from a
+// client or service).
+func Wire(t testing.TB, msg message.Message) message.Message {
+	t.Helper()
+	buf, err := data.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(msg.Size()) != len(buf) {
+		t.Fatalf("%s: size %d, marshalled %d bytes", msg.Type(), msg.Size(), len(buf))
+	}
+	out, err := message.Parse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// Responder collects responses of a service (passed through their wire
+// format). Messages of asynchronous types (like watch notifications)
+// are kept separately.
+type Responder struct {
+	sync.Mutex
+
+	t     testing.TB
+	types map[enums.MsgType]bool // asynchronous message types
+	resp  []message.Message      // responses
+	async []message.Message      // asynchronous messages
+}
+
+// NewResponder creates a responder for a test; messages of the given
+// types are collected as asynchronous messages.
+func NewResponder(t testing.TB, async ...enums.MsgType) *Responder {
+	r := &Responder{
+		t:     t,
+		types: make(map[enums.MsgType]bool),
+	}
+	for _, mt := range async {
+		r.types[mt] = true
+	}
+	return r
+}
+
+// Send a message to the responder ('transport.Responder' interface)
+func (r *Responder) Send(ctx context.Context, msg message.Message) error {
+	out := Wire(r.t, msg)
+	r.Lock()
+	if r.types[out.Type()] {
+		r.async = append(r.async, out)
+	} else {
+		r.resp = append(r.resp, out)
+	}
+	r.Unlock()
+	return nil
+}
+
+// Receiver returns nil (local client).
+func (r *Responder) Receiver() *util.PeerID {
+	return nil
+}
+
+// Take returns (and clears) the received responses.
+func (r *Responder) Take() []message.Message {
+	r.Lock()
+	defer r.Unlock()
+	list := r.resp
+	r.resp = nil
+	return list
+}
+
+// TakeAsync returns (and clears) the received asynchronous messages.
+func (r *Responder) TakeAsync() []message.Message {
+	r.Lock()
+	defer r.Unlock()
+	list := r.async
+	r.async = nil
+	return list
+}
+
+// WaitAsync waits for (at least) 'n' asynchronous messages and returns
+// (and clears) them. The test fails if the messages are not received
+// within WaitTimeout.
+func (r *Responder) WaitAsync(n int) []message.Message {
+	r.t.Helper()
+	for deadline := time.Now().Add(WaitTimeout); time.Now().Before(deadline); {
+		r.Lock()
+		if len(r.async) >= n {
+			list := r.async
+			r.async = nil
+			r.Unlock()
+			return list
+		}
+		r.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.t.Fatalf("expected %d asynchronous messages", n)
+	return nil
+}
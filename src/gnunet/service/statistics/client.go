@@ -0,0 +1,157 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gnunet/config"
+	"gnunet/message"
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Client for the statistics service: Go services publish their values
+// to a (Go or C) statistics service.
+//----------------------------------------------------------------------
+
+// Client of a statistics service
+type Client struct {
+	conn *service.Connection
+}
+
+// NewClient connects to the statistics service listening on a socket.
+func NewClient(ctx context.Context, socket string) (*Client, error) {
+	conn, err := service.NewConnection(ctx, socket)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Set a value.
+func (c *Client) Set(ctx context.Context, subSystem, name string, value uint64, persistent bool) error {
+	return c.conn.Send(ctx, message.NewStatisticsSetMsg(subSystem, name, value, setFlags(message.StatisticsSetAbsolute, persistent)))
+}
+
+// Update a value by a (signed) delta.
+func (c *Client) Update(ctx context.Context, subSystem, name string, delta int64, persistent bool) error {
+	return c.conn.Send(ctx, message.NewStatisticsSetMsg(subSystem, name, uint64(delta), setFlags(message.StatisticsSetRelative, persistent)))
+}
+
+// Get values (an empty sub-system or name matches all values).
+func (c *Client) Get(ctx context.Context, subSystem, name string) (list []*Value, err error) {
+	if err = c.conn.Send(ctx, message.NewStatisticsGetMsg(subSystem, name)); err != nil {
+		return
+	}
+	for {
+		var msg message.Message
+		if msg, err = c.conn.Receive(ctx); err != nil {
+			return
+		}
+		switch m := msg.(type) {
+		case *message.StatisticsValueMsg:
+			list = append(list, &Value{
+				SubSystem:  m.SubSystem(),
+				Name:       m.Name(),
+				Value:      m.Value,
+				Persistent: m.Persistent(),
+				uid:        m.UID &^ message.StatisticsPersistBit,
+			})
+		case *message.StatisticsEndMsg:
+			return
+		default:
+			return nil, fmt.Errorf("unexpected response %s", msg)
+		}
+	}
+}
+
+// Close the connection after all requests are processed by the service.
+func (c *Client) Close(ctx context.Context) error {
+	defer c.conn.Close()
+	if err := c.conn.Send(ctx, message.NewStatisticsDisconnectMsg()); err != nil {
+		return err
+	}
+	for {
+		msg, err := c.conn.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*message.StatisticsDisconnectConfirmMsg); ok {
+			return nil
+		}
+	}
+}
+
+// setFlags returns the flags of a SET request.
+func setFlags(flags uint32, persistent bool) uint32 {
+	if persistent {
+		flags |= message.StatisticsSetPersistent
+	}
+	return flags
+}
+
+//----------------------------------------------------------------------
+// Publishing service counters
+//----------------------------------------------------------------------
+
+// PublishTimeout is the time-out for publishing counters.
+var PublishTimeout = 10 * time.Second
+
+// PublishJob returns a job that sets the event counters and the health
+// details of modules (see service.Metrics) as values of the named
+// sub-system in the statistics service. Names are prefixed with "# "
+// (like the values of C services): counter "dht:get" is published as
+// "# dht:get".
+func PublishJob(socket, subSystem string) service.Job {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, PublishTimeout)
+		defer cancel()
+		c, err := NewClient(ctx, socket)
+		if err != nil {
+			return err
+		}
+		for name, val := range service.Metrics() {
+			if val < 0 {
+				continue
+			}
+			if err = c.Set(ctx, subSystem, "# "+name, uint64(val), false); err != nil {
+				c.conn.Close()
+				return err
+			}
+		}
+		return c.Close(ctx)
+	}
+}
+
+// StartPublisher publishes the counters of a service periodically (job
+// "<name>:statistics") if a statistics service and a period are
+// configured.
+func StartPublisher(ctx context.Context, name string, cfg *config.StatisticsConfig) error {
+	if cfg == nil || cfg.Publish <= 0 || cfg.Service == nil || len(cfg.Service.Socket) == 0 {
+		return nil
+	}
+	logger.Printf(logger.INFO, "[%s] Publishing statistics to %s", name, cfg.Service.Socket)
+	period := time.Duration(cfg.Publish) * time.Second
+	return service.Schedule(ctx, name+":statistics", period, PublishJob(cfg.Service.Socket, name))
+}
@@ -0,0 +1,356 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statistics
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//======================================================================
+// "Statistics" implementation: Services set (or update) named values of
+// a sub-system; clients get values and watch values for changes.
+// Persistent values are kept across restarts in a file of SET messages
+// (like the file of the GNUnet C service).
+//======================================================================
+
+// Error codes
+var (
+	ErrStatisticsName = errors.New("missing sub-system or name")
+	ErrStatisticsFile = errors.New("invalid statistics file")
+)
+
+// SavePeriod is the time between saving changed persistent values.
+var SavePeriod = 5 * time.Minute
+
+// WatchQueue is the number of notifications queued for a watcher; more
+// notifications are dropped until the watcher catches up.
+var WatchQueue = 32
+
+// Value is a named value of a sub-system.
+type Value struct {
+	SubSystem  string `json:"subsystem"`  // name of sub-system
+	Name       string `json:"name"`       // name of value
+	Value      uint64 `json:"value"`      // current value
+	Persistent bool   `json:"persistent"` // value is kept across restarts
+
+	uid uint32 // unique identifier
+}
+
+// watcher receives changes of a value.
+type watcher struct {
+	ch chan *Value
+}
+
+// Module handles the statistical values of services.
+type Module struct {
+	service.ModuleImpl
+
+	mtx      sync.Mutex                  // lock for values and watchers
+	file     string                      // file for persistent values
+	values   map[string]*Value           // values by key
+	lastUID  uint32                      // last value identifier
+	watchers map[string]map[int]*watcher // watchers per key
+	lastID   int                         // last watcher identifier
+	dirty    bool                        // persistent values changed
+}
+
+// NewModule creates a new module instance: persistent values are read
+// from the configured file, saved periodically and on termination.
+func NewModule(ctx context.Context, cfg *config.StatisticsConfig) *Module {
+	m := newModule()
+	if cfg == nil || len(cfg.File) == 0 {
+		return m
+	}
+	m.file = cfg.File
+	if err := m.load(); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] Failed to load values: %s", err.Error())
+	}
+	if err := service.Schedule(ctx, "statistics:save", SavePeriod, m.saveJob); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] job 'statistics:save' not scheduled: %s", err.Error())
+	}
+	go func() {
+		<-ctx.Done()
+		if err := m.save(); err != nil {
+			logger.Printf(logger.ERROR, "[statistics] Failed to save values: %s", err.Error())
+		}
+	}()
+	return m
+}
+
+// create an empty module
+func newModule() *Module {
+	return &Module{
+		ModuleImpl: *service.NewModuleImpl(),
+		values:     make(map[string]*Value),
+		watchers:   make(map[string]map[int]*watcher),
+	}
+}
+
+//----------------------------------------------------------------------
+
+// Filter returns the event filter for the module: the statistics
+// service only serves local clients.
+func (m *Module) Filter() *core.EventFilter {
+	return core.NewEventFilter()
+}
+
+// Export functions
+func (m *Module) Export(fcn map[string]any) {
+	// add exported functions from module
+	fcn["statistics:set"] = m.Set
+	fcn["statistics:get"] = m.Get
+}
+
+// Import functions
+func (m *Module) Import(fcm map[string]any) {
+	// nothing to import now.
+}
+
+//----------------------------------------------------------------------
+
+// valueKey returns the key of a value.
+func valueKey(subSystem, name string) string {
+	return subSystem + "\x00" + name
+}
+
+// Set a value (see message.StatisticsSet* for flags): a relative value
+// is added (as signed integer) to the current value; the result is never
+// below zero. Watchers are notified if the value changed ["statistics:set"]
+func (m *Module) Set(subSystem, name string, value uint64, flags uint32) error {
+	if len(subSystem) == 0 || len(name) == 0 {
+		return ErrStatisticsName
+	}
+	key := valueKey(subSystem, name)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		m.lastUID++
+		v = &Value{SubSystem: subSystem, Name: name, uid: m.lastUID}
+		m.values[key] = v
+	}
+	old := v.Value
+	if flags&message.StatisticsSetRelative == 0 {
+		v.Value = value
+	} else if delta := int64(value); delta < 0 && v.Value < uint64(-delta) {
+		v.Value = 0
+	} else {
+		v.Value += uint64(delta)
+	}
+	persistent := flags&message.StatisticsSetPersistent != 0
+	if persistent || v.Persistent {
+		m.dirty = m.dirty || persistent != v.Persistent || old != v.Value
+	}
+	v.Persistent = persistent
+	if !ok || old != v.Value {
+		m.notify(key, v)
+	}
+	return nil
+}
+
+// Get values of a sub-system (sorted by sub-system and name): an empty
+// sub-system or name matches all values ["statistics:get"]
+func (m *Module) Get(subSystem, name string) []*Value {
+	m.mtx.Lock()
+	list := make([]*Value, 0)
+	for _, v := range m.values {
+		if (len(subSystem) == 0 || v.SubSystem == subSystem) && (len(name) == 0 || v.Name == name) {
+			val := *v
+			list = append(list, &val)
+		}
+	}
+	m.mtx.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].SubSystem != list[j].SubSystem {
+			return list[i].SubSystem < list[j].SubSystem
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Watch a value: changes are passed to the callback until the context is
+// done or the watch is cancelled; the current value is passed if the
+// value exists. Returns the watch identifier.
+func (m *Module) Watch(ctx context.Context, subSystem, name string, fcn func(*Value)) int {
+	w := &watcher{ch: make(chan *Value, WatchQueue)}
+	key := valueKey(subSystem, name)
+	m.mtx.Lock()
+	m.lastID++
+	id := m.lastID
+	list, ok := m.watchers[key]
+	if !ok {
+		list = make(map[int]*watcher)
+		m.watchers[key] = list
+	}
+	list[id] = w
+	if v, ok := m.values[key]; ok {
+		val := *v
+		w.ch <- &val
+	}
+	m.mtx.Unlock()
+
+	go func() {
+		defer m.Unwatch(subSystem, name, id)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-w.ch:
+				if !ok {
+					return
+				}
+				fcn(v)
+			}
+		}
+	}()
+	return id
+}
+
+// Unwatch cancels a watch.
+func (m *Module) Unwatch(subSystem, name string, id int) {
+	key := valueKey(subSystem, name)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if w, ok := m.watchers[key][id]; ok {
+		delete(m.watchers[key], id)
+		if len(m.watchers[key]) == 0 {
+			delete(m.watchers, key)
+		}
+		close(w.ch)
+	}
+}
+
+// notify watchers of a key about a changed value (caller must hold the
+// lock).
+func (m *Module) notify(key string, v *Value) {
+	for id, w := range m.watchers[key] {
+		val := *v
+		select {
+		case w.ch <- &val:
+		default:
+			logger.Printf(logger.WARN, "[statistics] watcher #%d busy -- change dropped", id)
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Persistence
+//----------------------------------------------------------------------
+
+// load persistent values from file (a missing file is not an error).
+func (m *Module) load() error {
+	buf, err := os.ReadFile(m.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for pos := 0; pos < len(buf); {
+		if len(buf)-pos < 4 {
+			return ErrStatisticsFile
+		}
+		size := int(binary.BigEndian.Uint16(buf[pos:]))
+		if size < 4 || pos+size > len(buf) {
+			return ErrStatisticsFile
+		}
+		var out message.Message
+		if out, err = message.NewEmptyMessage(enums.MSG_STATISTICS_SET); err != nil {
+			return err
+		}
+		if err = data.Unmarshal(out, buf[pos:pos+size]); err != nil {
+			return err
+		}
+		if err = out.Init(); err != nil {
+			return err
+		}
+		msg, _ := out.(*message.StatisticsSetMsg)
+		if err = m.Set(msg.SubSystem(), msg.Name(), msg.Value, message.StatisticsSetPersistent); err != nil {
+			return err
+		}
+		pos += size
+	}
+	m.mtx.Lock()
+	m.dirty = false
+	m.mtx.Unlock()
+	return nil
+}
+
+// save persistent values to file (if changed).
+func (m *Module) save() error {
+	m.mtx.Lock()
+	if !m.dirty || len(m.file) == 0 {
+		m.mtx.Unlock()
+		return nil
+	}
+	var buf []byte
+	for _, v := range m.values {
+		if !v.Persistent {
+			continue
+		}
+		msg := message.NewStatisticsSetMsg(v.SubSystem, v.Name, v.Value, message.StatisticsSetPersistent)
+		out, err := data.Marshal(msg)
+		if err != nil {
+			m.mtx.Unlock()
+			return err
+		}
+		buf = append(buf, out...)
+	}
+	m.dirty = false
+	m.mtx.Unlock()
+
+	// write to temporary file first
+	tmp := m.file + ".tmp"
+	err := os.MkdirAll(filepath.Dir(m.file), 0o700)
+	if err == nil {
+		err = os.WriteFile(tmp, buf, 0o600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, m.file)
+	}
+	if err != nil {
+		// try again next time
+		m.mtx.Lock()
+		m.dirty = true
+		m.mtx.Unlock()
+	}
+	return err
+}
+
+// save values (scheduled job)
+func (m *Module) saveJob(ctx context.Context) error {
+	return m.save()
+}
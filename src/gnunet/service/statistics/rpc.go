@@ -0,0 +1,65 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statistics
+
+import (
+	"net/http"
+
+	"gnunet/service"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for statistics-related JSON-RPC requests
+type RPCService struct {
+	m *Module // reference to statistics module
+}
+
+//----------------------------------------------------------------------
+// Command "Statistics.Get"
+//----------------------------------------------------------------------
+
+// GetRequest asks for values (an empty sub-system or name matches all
+// values)
+type GetRequest struct {
+	SubSystem string `json:"subsystem,omitempty"` // name of sub-system
+	Name      string `json:"name,omitempty"`      // name of value
+}
+
+// GetResponse returns the matching values
+type GetResponse struct {
+	Values []*Value `json:"values"`
+}
+
+// Get returns the matching values.
+func (s *RPCService) Get(r *http.Request, req *GetRequest, reply *GetResponse) error {
+	reply.Values = s.m.Get(req.SubSystem, req.Name)
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the module
+func (m *Module) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{m: m}, "Statistics"); err != nil {
+		logger.Printf(logger.ERROR, "[statistics] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,172 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/transport"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// "GNUnet Statistics" socket service implementation:
+// A SET request is not answered; a GET request is answered with a VALUE
+// message for each matching value followed by an END message. After a
+// WATCH request changes of the value are sent as WATCH_VALUE messages
+// (with the watch identifier counting the WATCH requests of the client)
+// until the client disconnects. A DISCONNECT request is confirmed after
+// all previous requests are processed.
+//----------------------------------------------------------------------
+
+// Service implements a statistics service
+type Service struct {
+	*Module
+
+	cmtx    sync.Mutex                     // lock for client watches
+	watches map[transport.Responder]uint32 // number of watches of clients
+}
+
+// NewService creates a new statistics service instance
+func NewService(ctx context.Context, cfg *config.StatisticsConfig) service.Service {
+	return newService(NewModule(ctx, cfg))
+}
+
+// create service for given module
+func newService(mod *Module) *Service {
+	return &Service{
+		Module:  mod,
+		watches: make(map[transport.Responder]uint32),
+	}
+}
+
+// ServeClient processes a client channel.
+func (s *Service) ServeClient(ctx context.Context, id int, mc *service.Connection) {
+	reqID := 0
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+
+	for {
+		// receive next message from client
+		reqID++
+		logger.Printf(logger.DBG, "[statistics:%d:%d] Waiting for client request...\n", id, reqID)
+		msg, err := mc.Receive(ctx)
+		if err != nil {
+			if err == io.EOF {
+				logger.Printf(logger.INFO, "[statistics:%d:%d] Client channel closed.\n", id, reqID)
+			} else if err == service.ErrConnectionInterrupted {
+				logger.Printf(logger.INFO, "[statistics:%d:%d] Service operation interrupted.\n", id, reqID)
+			} else {
+				logger.Printf(logger.ERROR, "[statistics:%d:%d] Message-receive failed: %s\n", id, reqID, err.Error())
+			}
+			break
+		}
+		logger.Printf(logger.INFO, "[statistics:%d:%d] Received request: %v\n", id, reqID, msg)
+
+		// handle message
+		valueCtx := context.WithValue(ctx, core.CtxKey("label"), fmt.Sprintf(":%d:%d", id, reqID))
+		s.HandleMessage(valueCtx, nil, msg, mc)
+	}
+	// close client connection
+	mc.Close()
+
+	// cancel all tasks running for this session/connection (this ends
+	// the watches of the client)
+	logger.Printf(logger.INFO, "[statistics:%d] Start closing session...\n", id)
+	cancel()
+	s.cmtx.Lock()
+	delete(s.watches, mc)
+	s.cmtx.Unlock()
+}
+
+// HandleMessage processes a single incoming message
+func (s *Service) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
+	// assemble log label
+	label := ""
+	if v := ctx.Value(core.CtxKey("label")); v != nil {
+		label, _ = v.(string)
+	}
+	send := func(resp message.Message) bool {
+		if err := back.Send(ctx, resp); err != nil {
+			logger.Printf(logger.ERROR, "[statistics%s] Failed to send response: %s\n", label, err.Error())
+			return false
+		}
+		return true
+	}
+	switch m := msg.(type) {
+
+	case *message.StatisticsSetMsg:
+		//----------------------------------------------------------
+		// SET: set or update value
+		//----------------------------------------------------------
+		if err := s.Set(m.SubSystem(), m.Name(), m.Value, m.Flags); err != nil {
+			logger.Printf(logger.WARN, "[statistics%s] Value not set: %s", label, err.Error())
+		}
+
+	case *message.StatisticsGetMsg:
+		//----------------------------------------------------------
+		// GET: send matching values
+		//----------------------------------------------------------
+		for _, v := range s.Get(m.SubSystem(), m.Name()) {
+			if !send(message.NewStatisticsValueMsg(v.SubSystem, v.Name, v.Value, v.uid, v.Persistent)) {
+				return false
+			}
+		}
+		return send(message.NewStatisticsEndMsg())
+
+	case *message.StatisticsWatchMsg:
+		//----------------------------------------------------------
+		// WATCH: send changes of value
+		//----------------------------------------------------------
+		if len(m.SubSystem()) == 0 || len(m.Name()) == 0 {
+			logger.Printf(logger.WARN, "[statistics%s] Watch rejected: %s", label, ErrStatisticsName.Error())
+			return false
+		}
+		s.cmtx.Lock()
+		wid := s.watches[back]
+		s.watches[back] = wid + 1
+		s.cmtx.Unlock()
+		s.Watch(ctx, m.SubSystem(), m.Name(), func(v *Value) {
+			send(message.NewStatisticsWatchValueMsg(wid, v.Value, v.Persistent))
+		})
+
+	case *message.StatisticsDisconnectMsg:
+		//----------------------------------------------------------
+		// DISCONNECT: confirm end of requests
+		//----------------------------------------------------------
+		return send(message.NewStatisticsDisconnectConfirmMsg())
+
+	default:
+		//----------------------------------------------------------
+		// UNKNOWN message type received
+		//----------------------------------------------------------
+		logger.Printf(logger.ERROR, "[statistics%s] Unhandled message of type (%s)\n", label, msg.Type())
+		return false
+	}
+	return true
+}
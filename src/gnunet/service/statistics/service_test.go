@@ -0,0 +1,193 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package statistics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"gnunet/config"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service"
+	"gnunet/service/servicetest"
+
+	"github.com/bfix/gospel/data"
+)
+
+func TestService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newService(newModule())
+	back := servicetest.NewResponder(t, enums.MSG_STATISTICS_WATCH_VALUE)
+
+	handle := func(msg message.Message) {
+		t.Helper()
+		if !s.HandleMessage(ctx, nil, servicetest.Wire(t, msg), back) {
+			t.Fatalf("%s not handled", msg.Type())
+		}
+	}
+	get := func(subSystem, name string) (list []*message.StatisticsValueMsg) {
+		t.Helper()
+		handle(message.NewStatisticsGetMsg(subSystem, name))
+		resp := back.Take()
+		if _, ok := resp[len(resp)-1].(*message.StatisticsEndMsg); !ok {
+			t.Fatalf("values not ended: %v", resp)
+		}
+		for _, msg := range resp[:len(resp)-1] {
+			v, ok := msg.(*message.StatisticsValueMsg)
+			if !ok {
+				t.Fatalf("unexpected message %s", msg)
+			}
+			list = append(list, v)
+		}
+		return
+	}
+
+	// watch values (the second one before it is set)
+	handle(message.NewStatisticsSetMsg("dht", "# GET requests", 5, message.StatisticsSetAbsolute))
+	handle(message.NewStatisticsWatchMsg("dht", "# GET requests"))
+	handle(message.NewStatisticsWatchMsg("dht", "# peers"))
+
+	// set and update values
+	handle(message.NewStatisticsSetMsg("dht", "# GET requests", 3, message.StatisticsSetRelative))
+	handle(message.NewStatisticsSetMsg("dht", "# peers", 12, message.StatisticsSetPersistent))
+	handle(message.NewStatisticsSetMsg("gns", "# lookups", 7, message.StatisticsSetAbsolute))
+	// a negative update never drops below zero
+	delta := int64(-10)
+	handle(message.NewStatisticsSetMsg("gns", "# lookups", uint64(delta), message.StatisticsSetRelative))
+
+	if n := len(get("", "")); n != 3 {
+		t.Fatalf("expected 3 values, got %d", n)
+	}
+	list := get("dht", "")
+	if len(list) != 2 || list[0].Name() != "# GET requests" || list[0].Value != 8 {
+		t.Fatalf("unexpected values %v", list)
+	}
+	if !list[1].Persistent() || list[0].Persistent() {
+		t.Fatalf("unexpected persistence %v", list)
+	}
+	if list = get("gns", "# lookups"); len(list) != 1 || list[0].Value != 0 {
+		t.Fatalf("unexpected values %v", list)
+	}
+
+	// watch values: identifiers count the watch requests (changes of
+	// different watches are not ordered)
+	var changes []*message.StatisticsWatchValueMsg
+	for _, msg := range back.WaitAsync(3) {
+		changes = append(changes, msg.(*message.StatisticsWatchValueMsg))
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].WID < changes[j].WID
+	})
+	for i, exp := range []struct {
+		wid   uint32
+		value uint64
+	}{{0, 5}, {0, 8}, {1, 12}} {
+		if changes[i].WID != exp.wid || changes[i].Value != exp.value {
+			t.Fatalf("unexpected watch value %s", changes[i])
+		}
+	}
+	// disconnect is confirmed
+	handle(message.NewStatisticsDisconnectMsg())
+	if resp := back.Take(); len(resp) != 1 || resp[0].Type() != enums.MSG_STATISTICS_DISCONNECT_CONFIRM {
+		t.Fatalf("unexpected disconnect response %v", resp)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := &config.StatisticsConfig{
+		File: filepath.Join(t.TempDir(), "statistics.data"),
+	}
+	m := NewModule(ctx, cfg)
+	if err := m.Set("dht", "# peers", 12, message.StatisticsSetPersistent); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("dht", "# GET requests", 3, message.StatisticsSetAbsolute); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("", "# peers", 1, 0); err != ErrStatisticsName {
+		t.Fatalf("expected name error, got %v", err)
+	}
+	if err := m.save(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	// the file is a sequence of SET messages
+	buf, err := os.ReadFile(cfg.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := servicetest.Wire(t, message.NewStatisticsSetMsg("dht", "# peers", 12, message.StatisticsSetPersistent))
+	out, _ := data.Marshal(msg)
+	if string(buf) != string(out) {
+		t.Fatalf("unexpected file content %x", buf)
+	}
+	// persistent values survive a restart
+	m = NewModule(context.Background(), cfg)
+	list := m.Get("", "")
+	if len(list) != 1 || list[0].Name != "# peers" || list[0].Value != 12 || !list[0].Persistent {
+		t.Fatalf("unexpected values %v", list)
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newService(newModule())
+	socket := filepath.Join(t.TempDir(), "statistics.sock")
+	hdlr := service.NewSocketHandler("statistics", s)
+	if err := hdlr.Start(ctx, socket, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := hdlr.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// publish service counters
+	service.Count("test:events", 4)
+	events := uint64(service.Metrics()["test:events"])
+	if err := PublishJob(socket, "test")(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(ctx, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Update(ctx, "test", "# test:events", 2, false); err != nil {
+		t.Fatal(err)
+	}
+	list, err := c.Get(ctx, "test", "# test:events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Value != events+2 {
+		t.Fatalf("unexpected values %v", list)
+	}
+	if err = c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
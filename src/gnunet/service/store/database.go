@@ -72,8 +72,70 @@ func (db *DBConn) Exec(query string, args ...any) (sql.Result, error) {
 	return db.conn.ExecContext(DBPool.ctx, query, args...)
 }
 
+// Prepare a SQL statement for repeated execution
+func (db *DBConn) Prepare(query string) (*sql.Stmt, error) {
+	return db.conn.PrepareContext(DBPool.ctx, query)
+}
+
+// Begin a transaction on a new connection to the same database instance;
+// statements on other connections are not part of the transaction.
+func (db *DBConn) Begin() (tx *DBTx, err error) {
+	tx = new(DBTx)
+	if tx.conn, err = DBPool.Connect(db.key); err != nil {
+		return nil, err
+	}
+	if tx.tx, err = tx.conn.conn.BeginTx(DBPool.ctx, nil); err != nil {
+		tx.conn.Close()
+		return nil, err
+	}
+	return
+}
+
 // TODO: add more SQL methods
 
+//----------------------------------------------------------------------
+// Transaction on a database connection (see DBConn.Begin)
+//----------------------------------------------------------------------
+
+// DBTx is a database transaction suitable for executing SQL commands.
+type DBTx struct {
+	conn *DBConn // connection for transaction
+	tx   *sql.Tx // transaction on connection
+}
+
+// QueryRow returns a single record for a query
+func (t *DBTx) QueryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(DBPool.ctx, query, args...)
+}
+
+// Query returns all matching records for a query
+func (t *DBTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(DBPool.ctx, query, args...)
+}
+
+// Exec a SQL statement
+func (t *DBTx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(DBPool.ctx, query, args...)
+}
+
+// Commit the transaction and close its connection.
+func (t *DBTx) Commit() error {
+	err := t.tx.Commit()
+	if cErr := t.conn.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// Rollback the transaction and close its connection.
+func (t *DBTx) Rollback() error {
+	err := t.tx.Rollback()
+	if cErr := t.conn.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
 //----------------------------------------------------------------------
 // DbPool holds all database instances used: Connecting with the same
 // connect string returns the same instance.
@@ -141,6 +203,7 @@ func (p *dbPool) Connect(spec string) (db *DBConn, err error) {
 	err = p.insts.Process(func(pid int) error {
 		// check if we have a connection to this database.
 		db = new(DBConn)
+		db.key = spec
 		inst, ok := p.insts.Get(spec, pid)
 		if !ok {
 			inst = new(DBPoolEntry)
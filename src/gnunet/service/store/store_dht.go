@@ -26,7 +26,9 @@ import (
 	"gnunet/service/dht/blocks"
 	"gnunet/service/dht/path"
 	"gnunet/util"
+	"gnunet/util/keyspace"
 	"os"
+	"time"
 
 	"github.com/bfix/gospel/data"
 	"github.com/bfix/gospel/logger"
@@ -124,13 +126,71 @@ func (rl *SortedDHTResults) GetResults() []*DHTResult {
 // DHT store
 //------------------------------------------------------------
 
-// DHTStore implements a filesystem-based storage mechanism for
+// DHTStore is the storage interface for DHT blocks (with their put
+// paths) under a query key.
+type DHTStore interface {
+	// Put block into storage under given key
+	Put(query blocks.Query, entry *DHTEntry) error
+
+	// Get blocks with given key from storage (not filtered out)
+	Get(label string, query blocks.Query, rf blocks.ResultFilter) ([]*DHTEntry, error)
+
+	// GetApprox returns the best-matching blocks for a key (not filtered out)
+	GetApprox(label string, query blocks.Query, rf blocks.ResultFilter) ([]*DHTResult, error)
+
+	// Traverse all stored (non-expired) entries with keys accepted by
+	// the filter (nil accepts all keys); stops if the handler returns false
+	Traverse(filter func(*crypto.HashCode) bool, hdlr func(*crypto.HashCode, *DHTEntry) bool) error
+
+	// ExpiredEntries calls the handler for all entries that are expired
+	// or exceed the lifetime for their block type
+	ExpiredEntries(lifetimes map[enums.BlockType]time.Duration, hdlr func(*crypto.HashCode, enums.BlockType) bool) error
+
+	// Collect removes expired entries; returns evictions per block type
+	Collect(lifetimes map[enums.BlockType]time.Duration) (map[enums.BlockType]int, error)
+
+	// Close store
+	Close() error
+}
+
+// NewDHTStore creates a DHT store for the backend selected by the "backend"
+// parameter: "file" (default) for filesystem storage or "sqlite3" for a
+// SQLite3 database.
+func NewDHTStore(spec util.ParameterSet) (DHTStore, error) {
+	backend, ok := util.GetParam[string](spec, "backend")
+	if !ok {
+		backend = "file"
+	}
+	// don't return typed nil pointers as interface values
+	switch backend {
+	case "file":
+		s, err := NewFileDHTStore(spec)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "sqlite3":
+		s, err := NewSQLDHTStore(spec)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, ErrStoreUnknown
+}
+
+//------------------------------------------------------------
+// Filesystem-based DHT store
+//------------------------------------------------------------
+
+// FileDHTStore implements a filesystem-based storage mechanism for
 // DHT queries and blocks.
-type DHTStore struct {
-	path      string            // storage path
-	cache     bool              // storage works as cache
-	args      util.ParameterSet // arguments / settings
-	totalSize uint64            // total storage size (logical, not physical)
+type FileDHTStore struct {
+	path      string              // storage path
+	cache     bool                // storage works as cache
+	args      util.ParameterSet   // arguments / settings
+	totalSize uint64              // total storage size (logical, not physical)
+	faults    *util.FaultInjector // fault injection (chaos testing)
 
 	// storage-mode metadata
 	meta     *FileMetaDB // database for metadata
@@ -142,11 +202,12 @@ type DHTStore struct {
 	size      int             // size of cache (number of entries)
 }
 
-// NewDHTStore instantiates a new file storage handler.
-func NewDHTStore(spec util.ParameterSet) (*DHTStore, error) {
+// NewFileDHTStore instantiates a new file storage handler.
+func NewFileDHTStore(spec util.ParameterSet) (*FileDHTStore, error) {
 	// create file store handler
-	fs := new(DHTStore)
+	fs := new(FileDHTStore)
 	fs.args = spec
+	fs.faults = storeFaults(spec)
 
 	// get parameter
 	var ok bool
@@ -182,7 +243,7 @@ func NewDHTStore(spec util.ParameterSet) (*DHTStore, error) {
 }
 
 // Close file storage.
-func (s *DHTStore) Close() (err error) {
+func (s *FileDHTStore) Close() (err error) {
 	if !s.cache {
 		// close database connection
 		err = s.meta.Close()
@@ -191,7 +252,10 @@ func (s *DHTStore) Close() (err error) {
 }
 
 // Put block into storage under given key
-func (s *DHTStore) Put(query blocks.Query, entry *DHTEntry) (err error) {
+func (s *FileDHTStore) Put(query blocks.Query, entry *DHTEntry) (err error) {
+	if s.faults.Error() {
+		return ErrStoreFault
+	}
 	// check for free space
 	if !s.cache {
 		if int(s.totalSize>>30) > s.maxSpace {
@@ -239,7 +303,10 @@ func (s *DHTStore) Put(query blocks.Query, entry *DHTEntry) (err error) {
 }
 
 // Get block with given key from storage
-func (s *DHTStore) Get(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTEntry, err error) {
+func (s *FileDHTStore) Get(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTEntry, err error) {
+	if s.faults.Error() {
+		return nil, ErrStoreFault
+	}
 	// check if we have metadata for the query
 	var mds []*FileMetadata
 	if mds, err = s.meta.Get(query); err != nil || len(mds) == 0 {
@@ -278,7 +345,10 @@ func (s *DHTStore) Get(label string, query blocks.Query, rf blocks.ResultFilter)
 
 // GetApprox returns the best-matching values with given key from storage
 // that are not excluded
-func (s *DHTStore) GetApprox(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTResult, err error) {
+func (s *FileDHTStore) GetApprox(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTResult, err error) {
+	if s.faults.Error() {
+		return nil, ErrStoreFault
+	}
 	btype := query.Type()
 
 	// List of possible results (size limited)
@@ -297,7 +367,7 @@ func (s *DHTStore) GetApprox(label string, query blocks.Query, rf blocks.ResultF
 			return
 		}
 		// check distance in result list
-		dist := util.Distance(md.key.Data, query.Key().Data)
+		dist := keyspace.Distance(md.key.Data, query.Key().Data)
 		if pos := list.Accepts(dist); pos != -1 {
 
 			// read entry from storage
@@ -320,6 +390,108 @@ func (s *DHTStore) GetApprox(label string, query blocks.Query, rf blocks.ResultF
 	return
 }
 
+// Traverse all stored (non-expired) entries with keys accepted by the
+// filter and call the handler for each of them. A nil filter accepts
+// all keys. The traversal stops if the handler returns false.
+func (s *FileDHTStore) Traverse(filter func(*crypto.HashCode) bool, hdlr func(*crypto.HashCode, *DHTEntry) bool) (err error) {
+	// collect matching metadata
+	var mds []*FileMetadata
+	collect := func(md *FileMetadata) {
+		if md == nil || md.expires.Expired() {
+			return
+		}
+		if filter == nil || filter(md.key) {
+			// metadata instance is re-used in traversal: copy it
+			mdc := *md
+			mdc.key = md.key.Clone()
+			mds = append(mds, &mdc)
+		}
+	}
+	if s.cache {
+		for _, md := range s.cacheMeta {
+			collect(md)
+		}
+	} else if err = s.meta.Traverse(collect); err != nil {
+		return
+	}
+	// process entries
+	for _, md := range mds {
+		var entry *DHTEntry
+		if entry, err = s.readEntry(md); err != nil {
+			logger.Printf(logger.ERROR, "[dht-store] can't read entry %s: %s", md.key.Short(), err.Error())
+			continue
+		}
+		if !hdlr(md.key, entry) {
+			break
+		}
+	}
+	return nil
+}
+
+// ExpiredEntries calls the handler for all stored entries that are expired
+// or older than the lifetime for their block type (entries of types without
+// a lifetime are only checked for expiration). The traversal stops if the
+// handler returns false.
+func (s *FileDHTStore) ExpiredEntries(lifetimes map[enums.BlockType]time.Duration, hdlr func(*crypto.HashCode, enums.BlockType) bool) error {
+	mds, err := s.expired(lifetimes)
+	if err != nil {
+		return err
+	}
+	for _, md := range mds {
+		if !hdlr(md.key, md.btype) {
+			break
+		}
+	}
+	return nil
+}
+
+// Collect removes all entries that are expired or exceed the lifetime for
+// their block type (see ExpiredEntries). Returns the number of removed
+// entries per block type.
+func (s *FileDHTStore) Collect(lifetimes map[enums.BlockType]time.Duration) (evicted map[enums.BlockType]int, err error) {
+	var mds []*FileMetadata
+	if mds, err = s.expired(lifetimes); err != nil {
+		return
+	}
+	evicted = make(map[enums.BlockType]int)
+	for _, md := range mds {
+		if s.cache {
+			s.dropCached(md)
+		} else if err = s.dropFile(md); err != nil {
+			return
+		}
+		evicted[md.btype]++
+	}
+	return
+}
+
+// expired collects the metadata of entries to be removed.
+func (s *FileDHTStore) expired(lifetimes map[enums.BlockType]time.Duration) (mds []*FileMetadata, err error) {
+	check := func(md *FileMetadata) {
+		if md == nil {
+			return
+		}
+		if !md.expires.Expired() {
+			lt, ok := lifetimes[md.btype]
+			if !ok || lt <= 0 || !md.stored.Add(lt).Expired() {
+				return
+			}
+		}
+		// metadata instance is re-used in traversal: copy it
+		mdc := *md
+		mdc.key = md.key.Clone()
+		mds = append(mds, &mdc)
+	}
+	if s.cache {
+		for _, md := range s.cacheMeta {
+			check(md)
+		}
+	} else {
+		err = s.meta.Traverse(check)
+	}
+	return
+}
+
 //----------------------------------------------------------------------
 
 type _EntryLayout struct {
@@ -330,7 +502,7 @@ type _EntryLayout struct {
 }
 
 // read entry from storage for given key
-func (s *DHTStore) readEntry(md *FileMetadata) (entry *DHTEntry, err error) {
+func (s *FileDHTStore) readEntry(md *FileMetadata) (entry *DHTEntry, err error) {
 	// get path and filename from key
 	folder, fname := s.expandPath(md.key.Data)
 
@@ -360,7 +532,7 @@ func (s *DHTStore) readEntry(md *FileMetadata) (entry *DHTEntry, err error) {
 }
 
 // write entry to storage for given key
-func (s *DHTStore) writeEntry(key []byte, entry *DHTEntry) (err error) {
+func (s *FileDHTStore) writeEntry(key []byte, entry *DHTEntry) (err error) {
 	// get folder and filename from key
 	folder, fname := s.expandPath(key)
 	// make sure the folder exists
@@ -392,14 +564,14 @@ func (s *DHTStore) writeEntry(key []byte, entry *DHTEntry) (err error) {
 //----------------------------------------------------------------------
 
 // expandPath returns the full path to the file for given key.
-func (s *DHTStore) expandPath(key []byte) (string, string) {
+func (s *FileDHTStore) expandPath(key []byte) (string, string) {
 	h := hex.EncodeToString(key)
 	return fmt.Sprintf("%s/%s/%s", s.path, h[:2], h[2:4]), h[4:]
 }
 
 // Prune list of file headers so we drop at least n entries.
 // returns number of removed entries.
-func (s *DHTStore) prune(n int) (del int) {
+func (s *FileDHTStore) prune(n int) (del int) {
 	// collect obsolete records
 	obsolete, err := s.meta.Obsolete(n)
 	if err != nil {
@@ -415,8 +587,30 @@ func (s *DHTStore) prune(n int) (del int) {
 	return
 }
 
+// dropCached removes an entry from the cache list; the file is removed if
+// no other entry refers to the same key.
+func (s *FileDHTStore) dropCached(md *FileMetadata) {
+	shared := false
+	for i, cm := range s.cacheMeta {
+		if cm == nil || !cm.key.Equal(md.key) {
+			continue
+		}
+		if cm.btype == md.btype && cm.stored.Compare(md.stored) == 0 {
+			s.cacheMeta[i] = nil
+		} else {
+			shared = true
+		}
+	}
+	if !shared {
+		folder, fname := s.expandPath(md.key.Data)
+		if err := os.Remove(folder + "/" + fname); err != nil {
+			logger.Printf(logger.ERROR, "[store] can't remove file %s: %s", fname, err.Error())
+		}
+	}
+}
+
 // drop file removes a file from metadatabase and the physical storage.
-func (s *DHTStore) dropFile(md *FileMetadata) (err error) {
+func (s *FileDHTStore) dropFile(md *FileMetadata) (err error) {
 	// adjust total size
 	s.totalSize -= md.size
 	// remove from database
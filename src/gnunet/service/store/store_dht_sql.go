@@ -0,0 +1,451 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package store
+
+import (
+	"database/sql"
+	_ "embed" // use embedded filesystem
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/dht/path"
+	"gnunet/util"
+	"gnunet/util/keyspace"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//============================================================
+// SQLite3-based DHT storage: blocks, put paths and metadata
+// are kept in a single database file.
+//============================================================
+
+// Error codes
+var (
+	ErrStoreSchema = errors.New("unsupported database schema version")
+)
+
+//go:embed store_dht_sql.sql
+var dhtSchemaV1 string
+
+// dhtMigrations are the scripts to upgrade the database schema. The
+// schema version of a database (number of applied scripts) is kept in
+// the SQLite3 "user_version" field.
+var dhtMigrations = []string{
+	dhtSchemaV1,
+}
+
+// dhtStatements are prepared when the store is opened.
+var dhtStatements = map[string]string{
+	"put": "replace into entries(qkey,btype,bhash,block,path,size,stored,expires,lastUsed,usedCount) " +
+		"values(?,?,?,?,?,?,?,?,?,1)",
+	"get":   "select rowid,btype,bhash,stored,expires from entries where qkey=?",
+	"getT":  "select rowid,btype,bhash,stored,expires from entries where qkey=? and btype=?",
+	"all":   "select rowid,qkey,btype,bhash,stored,expires from entries",
+	"entry": "select block,path from entries where rowid=?",
+	"used":  "update entries set usedCount=usedCount+1,lastUsed=? where rowid=?",
+	"drop":  "delete from entries where rowid=?",
+	"size":  "select coalesce(sum(size),0) from entries",
+	"prune": "select rowid from entries order by usedCount,lastUsed limit ?",
+}
+
+// sqlEntryMeta is the metadata of a database entry.
+type sqlEntryMeta struct {
+	rowid   int64             // database row
+	key     *crypto.HashCode  // query key
+	btype   enums.BlockType   // block type
+	bhash   *crypto.HashCode  // block hash
+	stored  util.AbsoluteTime // time added to store
+	expires util.AbsoluteTime // expiration time
+}
+
+// SQLDHTStore implements a DHT store in a SQLite3 database.
+type SQLDHTStore struct {
+	db       *DBConn              // database connection
+	stmts    map[string]*sql.Stmt // prepared statements
+	maxSpace int                  // max. storage space in GB
+	faults   *util.FaultInjector  // fault injection (chaos testing)
+}
+
+// NewSQLDHTStore opens (or creates) the database "dht.db" in the directory
+// specified by the "path" parameter; the schema is upgraded if required.
+func NewSQLDHTStore(spec util.ParameterSet) (s *SQLDHTStore, err error) {
+	dir, ok := util.GetParam[string](spec, "path")
+	if !ok {
+		return nil, ErrStoreInvalidSpec
+	}
+	s = &SQLDHTStore{
+		stmts:  make(map[string]*sql.Stmt),
+		faults: storeFaults(spec),
+	}
+	if s.maxSpace, ok = util.GetParam[int](spec, "maxGB"); !ok {
+		s.maxSpace = 10
+	}
+	// connect to database (create file if missing)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	dbFile := dir + "/dht.db"
+	if _, err = os.Stat(dbFile); err != nil {
+		var file *os.File
+		if file, err = os.Create(dbFile); err != nil {
+			return nil, err
+		}
+		file.Close()
+	}
+	if s.db, err = DBPool.Connect("sqlite3:" + dbFile); err != nil {
+		return nil, err
+	}
+	// upgrade schema and prepare statements
+	if err = s.migrate(); err == nil {
+		for name, stmt := range dhtStatements {
+			if s.stmts[name], err = s.db.Prepare(stmt); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate the database schema to the current version.
+func (s *SQLDHTStore) migrate() (err error) {
+	var version int
+	if err = s.db.QueryRow("pragma user_version").Scan(&version); err != nil {
+		return
+	}
+	if version > len(dhtMigrations) {
+		return fmt.Errorf("%w: %d", ErrStoreSchema, version)
+	}
+	for ; version < len(dhtMigrations); version++ {
+		if _, err = s.db.Exec(dhtMigrations[version]); err != nil {
+			return
+		}
+		if _, err = s.db.Exec(fmt.Sprintf("pragma user_version=%d", version+1)); err != nil {
+			return
+		}
+		logger.Printf(logger.INFO, "[dht-store] database schema upgraded to version %d", version+1)
+	}
+	return
+}
+
+// Close database (and prepared statements).
+func (s *SQLDHTStore) Close() error {
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+	return s.db.Close()
+}
+
+// Put block into storage under given key
+func (s *SQLDHTStore) Put(query blocks.Query, entry *DHTEntry) (err error) {
+	if s.faults.Error() {
+		return ErrStoreFault
+	}
+	// check for free space
+	var total uint64
+	if err = s.stmts["size"].QueryRow().Scan(&total); err != nil {
+		return
+	}
+	if int(total>>30) > s.maxSpace {
+		s.prune(20)
+	}
+	// assemble and store entry
+	blk := entry.Blk.Bytes()
+	var pth []byte
+	if entry.Path != nil {
+		pth = entry.Path.Bytes()
+	}
+	now := util.AbsoluteTimeNow()
+	logger.Printf(logger.INFO, "[dht-store] storing %d bytes @ %s (path %s), expires %s",
+		len(blk), query.Key().Short(), entry.Path, entry.Blk.Expire())
+	_, err = s.stmts["put"].Exec(
+		query.Key().Data, query.Type(), crypto.Hash(blk).Data, blk, pth, len(blk),
+		now.Val, sqlExpire(entry.Blk.Expire()), now.Epoch())
+	return
+}
+
+// Get blocks with given key from storage
+func (s *SQLDHTStore) Get(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTEntry, err error) {
+	if s.faults.Error() {
+		return nil, ErrStoreFault
+	}
+	// collect metadata of matching entries
+	var rows *sql.Rows
+	btype := query.Type()
+	if btype == enums.BLOCK_TYPE_ANY {
+		rows, err = s.stmts["get"].Query(query.Key().Data)
+	} else {
+		rows, err = s.stmts["getT"].Query(query.Key().Data, btype)
+	}
+	if err != nil {
+		return
+	}
+	var mds []*sqlEntryMeta
+	for rows.Next() {
+		md := &sqlEntryMeta{key: query.Key()}
+		if err = scanEntryMeta(rows, md, false); err != nil {
+			rows.Close()
+			return
+		}
+		mds = append(mds, md)
+	}
+	rows.Close()
+
+	// process entries
+	now := util.AbsoluteTimeNow().Epoch()
+	for _, md := range mds {
+		if md.expires.Expired() {
+			if _, err := s.stmts["drop"].Exec(md.rowid); err != nil {
+				logger.Printf(logger.ERROR, "[%s] can't drop DHT entry: %s", label, err)
+			}
+			continue
+		}
+		if rf != nil && rf.ContainsHash(md.bhash) {
+			continue
+		}
+		var entry *DHTEntry
+		if entry, err = s.readEntry(md); err != nil {
+			logger.Printf(logger.ERROR, "[%s] can't read DHT entry: %s", label, err)
+			continue
+		}
+		results = append(results, entry)
+		if _, err = s.stmts["used"].Exec(now, md.rowid); err != nil {
+			logger.Printf(logger.ERROR, "[%s] can't flag DHT entry as used: %s", label, err)
+			continue
+		}
+		logger.Printf(logger.INFO, "[dht-store] retrieving %d bytes @ %s (path %s)",
+			len(entry.Blk.Bytes()), query.Key().Short(), entry.Path)
+	}
+	return
+}
+
+// GetApprox returns the best-matching values with given key from storage
+// that are not excluded
+func (s *SQLDHTStore) GetApprox(label string, query blocks.Query, rf blocks.ResultFilter) (results []*DHTResult, err error) {
+	if s.faults.Error() {
+		return nil, ErrStoreFault
+	}
+	btype := query.Type()
+	var mds []*sqlEntryMeta
+	if mds, err = s.entries(); err != nil {
+		return
+	}
+	// List of possible results (size limited)
+	list := NewSortedDHTResults(10)
+	for _, md := range mds {
+		if btype != enums.BLOCK_TYPE_ANY && btype != md.btype {
+			continue
+		}
+		if md.expires.Expired() || (rf != nil && rf.ContainsHash(md.bhash)) {
+			continue
+		}
+		dist := keyspace.Distance(md.key.Data, query.Key().Data)
+		if pos := list.Accepts(dist); pos != -1 {
+			var entry *DHTEntry
+			if entry, err = s.readEntry(md); err != nil {
+				logger.Printf(logger.ERROR, "[%s] failed to retrieve block for %s", label, md.key.String())
+				continue
+			}
+			list.Add(&DHTResult{Entry: entry, Dist: dist}, pos)
+		}
+	}
+	return list.GetResults(), nil
+}
+
+// Traverse all stored (non-expired) entries with keys accepted by the
+// filter and call the handler for each of them. A nil filter accepts
+// all keys. The traversal stops if the handler returns false.
+func (s *SQLDHTStore) Traverse(filter func(*crypto.HashCode) bool, hdlr func(*crypto.HashCode, *DHTEntry) bool) error {
+	mds, err := s.entries()
+	if err != nil {
+		return err
+	}
+	for _, md := range mds {
+		if md.expires.Expired() || (filter != nil && !filter(md.key)) {
+			continue
+		}
+		entry, err := s.readEntry(md)
+		if err != nil {
+			logger.Printf(logger.ERROR, "[dht-store] can't read entry %s: %s", md.key.Short(), err.Error())
+			continue
+		}
+		if !hdlr(md.key, entry) {
+			break
+		}
+	}
+	return nil
+}
+
+// ExpiredEntries calls the handler for all stored entries that are expired
+// or older than the lifetime for their block type. The traversal stops if
+// the handler returns false.
+func (s *SQLDHTStore) ExpiredEntries(lifetimes map[enums.BlockType]time.Duration, hdlr func(*crypto.HashCode, enums.BlockType) bool) error {
+	mds, err := s.expired(lifetimes)
+	if err != nil {
+		return err
+	}
+	for _, md := range mds {
+		if !hdlr(md.key, md.btype) {
+			break
+		}
+	}
+	return nil
+}
+
+// Collect removes all entries that are expired or exceed the lifetime for
+// their block type. Returns the number of removed entries per block type.
+func (s *SQLDHTStore) Collect(lifetimes map[enums.BlockType]time.Duration) (evicted map[enums.BlockType]int, err error) {
+	var mds []*sqlEntryMeta
+	if mds, err = s.expired(lifetimes); err != nil {
+		return
+	}
+	evicted = make(map[enums.BlockType]int)
+	for _, md := range mds {
+		if _, err = s.stmts["drop"].Exec(md.rowid); err != nil {
+			return
+		}
+		evicted[md.btype]++
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+
+// entries returns the metadata of all entries in the database.
+func (s *SQLDHTStore) entries() (mds []*sqlEntryMeta, err error) {
+	var rows *sql.Rows
+	if rows, err = s.stmts["all"].Query(); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		md := new(sqlEntryMeta)
+		if err = scanEntryMeta(rows, md, true); err != nil {
+			return
+		}
+		mds = append(mds, md)
+	}
+	return
+}
+
+// expired returns the metadata of entries to be removed.
+func (s *SQLDHTStore) expired(lifetimes map[enums.BlockType]time.Duration) (list []*sqlEntryMeta, err error) {
+	var mds []*sqlEntryMeta
+	if mds, err = s.entries(); err != nil {
+		return
+	}
+	for _, md := range mds {
+		if !md.expires.Expired() {
+			lt, ok := lifetimes[md.btype]
+			if !ok || lt <= 0 || !md.stored.Add(lt).Expired() {
+				continue
+			}
+		}
+		list = append(list, md)
+	}
+	return
+}
+
+// readEntry reads block and put path of an entry.
+func (s *SQLDHTStore) readEntry(md *sqlEntryMeta) (entry *DHTEntry, err error) {
+	var blk, pth []byte
+	if err = s.stmts["entry"].QueryRow(md.rowid).Scan(&blk, &pth); err != nil {
+		return
+	}
+	entry = new(DHTEntry)
+	if entry.Blk, err = blocks.NewBlock(md.btype, md.expires, blk); err != nil {
+		return
+	}
+	entry.Path, err = path.NewPathFromBytes(pth)
+	return
+}
+
+// prune drops (at least) n least-used entries; returns number of removed
+// entries.
+func (s *SQLDHTStore) prune(n int) (del int) {
+	rows, err := s.stmts["prune"].Query(n)
+	if err != nil {
+		logger.Println(logger.ERROR, "[dht-store] failed to collect obsolete entries: "+err.Error())
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	for _, id := range ids {
+		if _, err = s.stmts["drop"].Exec(id); err != nil {
+			logger.Println(logger.ERROR, "[dht-store] failed to drop entry: "+err.Error())
+			return
+		}
+		del++
+	}
+	return
+}
+
+// scanEntryMeta reads entry metadata from a result row (with or
+// without the query key).
+func scanEntryMeta(rows *sql.Rows, md *sqlEntryMeta, withKey bool) (err error) {
+	var (
+		key, bhash []byte
+		btype      uint32
+		stored     uint64
+		expires    *uint64
+	)
+	if withKey {
+		err = rows.Scan(&md.rowid, &key, &btype, &bhash, &stored, &expires)
+		md.key = crypto.NewHashCode(key)
+	} else {
+		err = rows.Scan(&md.rowid, &btype, &bhash, &stored, &expires)
+	}
+	if err != nil {
+		return
+	}
+	md.btype = enums.BlockType(btype)
+	md.bhash = crypto.NewHashCode(bhash)
+	md.stored = util.AbsoluteTime{Val: stored}
+	if expires != nil {
+		md.expires = util.AbsoluteTime{Val: *expires}
+	} else {
+		md.expires = util.AbsoluteTimeNever()
+	}
+	return
+}
+
+// sqlExpire returns the database value for an expiration time: SQLite3
+// can't store uint64 values with the high bit set, so "never" is null.
+func sqlExpire(t util.AbsoluteTime) *uint64 {
+	if t.IsNever() {
+		return nil
+	}
+	return &t.Val
+}
@@ -0,0 +1,35 @@
+-- This file is part of gnunet-go, a GNUnet-implementation in Golang.
+-- Copyright (C) 2019-2022 Bernd Fix  >Y<
+--
+-- gnunet-go is free software: you can redistribute it and/or modify it
+-- under the terms of the GNU Affero General Public License as published
+-- by the Free Software Foundation, either version 3 of the License,
+-- or (at your option) any later version.
+--
+-- gnunet-go is distributed in the hope that it will be useful, but
+-- WITHOUT ANY WARRANTY; without even the implied warranty of
+-- MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+-- Affero General Public License for more details.
+--
+-- You should have received a copy of the GNU Affero General Public License
+-- along with this program.  If not, see <http://www.gnu.org/licenses/>.
+--
+-- SPDX-License-Identifier: AGPL3.0-or-later
+
+-- Schema version 1: DHT entries with block and put path
+
+create table entries (
+    qkey      blob,         -- query key (SHA512 hash)
+    btype     integer,      -- block type
+    bhash     blob,         -- block hash
+    block     blob,         -- block data
+    path      blob,         -- put path (or null)
+    size      integer,      -- size of block data
+    stored    integer,      -- time added to store (microseconds)
+    expires   integer,      -- expiration (microseconds, null = never)
+    lastUsed  integer,      -- time last used (epoch seconds)
+    usedCount integer,      -- usage count
+
+    unique(qkey,btype,bhash) -- unique entry in database
+);
+create index entries_qkey on entries(qkey);
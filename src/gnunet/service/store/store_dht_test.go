@@ -20,6 +20,7 @@ package store
 
 import (
 	"encoding/hex"
+	"errors"
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/service/dht/blocks"
@@ -27,6 +28,7 @@ import (
 	"math/rand"
 	"os"
 	"testing"
+	"time"
 )
 
 // test constants
@@ -121,3 +123,247 @@ func TestDHTFilesStore(t *testing.T) {
 func TestDHTEntryStore(t *testing.T) {
 	// pth, sender, local := path.GenerateTestPath(10)
 }
+
+// TestDHTStoreFaults checks that injected storage errors are returned.
+func TestDHTStoreFaults(t *testing.T) {
+	cfg := make(util.ParameterSet)
+	cfg["mode"] = "file"
+	cfg["cache"] = true
+	cfg["path"] = t.TempDir()
+	cfg["faultRate"] = 1.0
+
+	fs, err := NewDHTStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNever(), []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := blocks.NewGenericQuery(crypto.Hash(blk.Bytes()), enums.BLOCK_TYPE_TEST, 0)
+	if err = fs.Put(key, &DHTEntry{Blk: blk}); err != ErrStoreFault {
+		t.Fatalf("expected injected fault, got %v", err)
+	}
+	if _, err = fs.Get("test", key, nil); err != ErrStoreFault {
+		t.Fatalf("expected injected fault, got %v", err)
+	}
+}
+
+// TestDHTStoreTraverse checks traversal of stored entries with key filter.
+func TestDHTStoreTraverse(t *testing.T) {
+	cfg := make(util.ParameterSet)
+	cfg["mode"] = "file"
+	cfg["cache"] = false
+	cfg["path"] = t.TempDir()
+	cfg["maxGB"] = 1
+
+	fs, err := NewDHTStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	// store blocks
+	keys := make(map[string]bool)
+	for i := 0; i < fsNumBlocks; i++ {
+		blk, err := blocks.NewBlock(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNever(), util.NewRndArray(64))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := blocks.NewGenericQuery(crypto.Hash(blk.Bytes()), enums.BLOCK_TYPE_TEST, 0)
+		if err = fs.Put(key, &DHTEntry{Blk: blk}); err != nil {
+			t.Fatal(err)
+		}
+		keys[key.Key().String()] = true
+	}
+	// traverse all entries
+	num := 0
+	err = fs.Traverse(nil, func(key *crypto.HashCode, entry *DHTEntry) bool {
+		if !keys[key.String()] {
+			t.Errorf("unknown key %s", key.Short())
+		}
+		if !crypto.Hash(entry.Blk.Bytes()).Equal(key) {
+			t.Errorf("key/value mismatch for %s", key.Short())
+		}
+		num++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num != fsNumBlocks {
+		t.Fatalf("expected %d entries, got %d", fsNumBlocks, num)
+	}
+	// traverse with filter
+	num = 0
+	err = fs.Traverse(func(key *crypto.HashCode) bool {
+		return key.Data[0]&1 == 0
+	}, func(key *crypto.HashCode, _ *DHTEntry) bool {
+		if key.Data[0]&1 != 0 {
+			t.Errorf("filtered key %s traversed", key.Short())
+		}
+		num++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num > fsNumBlocks {
+		t.Fatalf("too many entries: %d", num)
+	}
+}
+
+// TestDHTStoreCollect checks the removal of expired entries and of entries
+// exceeding the lifetime for their block type (in both storage modes and
+// in the database backend).
+func TestDHTStoreCollect(t *testing.T) {
+	for _, mode := range []string{"file", "cache", "sqlite3"} {
+		cfg := make(util.ParameterSet)
+		cfg["mode"] = "file"
+		cfg["cache"] = (mode == "cache")
+		if mode == "sqlite3" {
+			cfg["backend"] = mode
+		}
+		cfg["path"] = t.TempDir()
+		cfg["maxGB"] = 1
+		cfg["num"] = 100
+
+		fs, err := NewDHTStore(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// store expired, unexpired and short-lived entries
+		put := func(btype enums.BlockType, expire util.AbsoluteTime) {
+			blk, err := blocks.NewBlock(btype, expire, util.NewRndArray(64))
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := blocks.NewGenericQuery(crypto.Hash(blk.Bytes()), btype, 0)
+			if err = fs.Put(key, &DHTEntry{Blk: blk}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		past := util.AbsoluteTimeNow().Add(-time.Hour)
+		for i := 0; i < 3; i++ {
+			put(enums.BLOCK_TYPE_TEST, past)
+			put(enums.BLOCK_TYPE_TEST, util.AbsoluteTimeNever())
+			put(enums.BLOCK_TYPE_FS_DBLOCK, util.AbsoluteTimeNever())
+		}
+		time.Sleep(10 * time.Millisecond)
+		lifetimes := map[enums.BlockType]time.Duration{
+			enums.BLOCK_TYPE_FS_DBLOCK: time.Millisecond,
+		}
+		// iterate expired entries
+		num := 0
+		err = fs.ExpiredEntries(lifetimes, func(_ *crypto.HashCode, btype enums.BlockType) bool {
+			num++
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if num != 6 {
+			t.Fatalf("%s: expected 6 expired entries, got %d", mode, num)
+		}
+		// remove expired entries
+		evicted, err := fs.Collect(lifetimes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evicted[enums.BLOCK_TYPE_TEST] != 3 || evicted[enums.BLOCK_TYPE_FS_DBLOCK] != 3 {
+			t.Fatalf("%s: unexpected evictions %v", mode, evicted)
+		}
+		num = 0
+		if err = fs.Traverse(nil, func(*crypto.HashCode, *DHTEntry) bool { num++; return true }); err != nil {
+			t.Fatal(err)
+		}
+		if num != 3 {
+			t.Fatalf("%s: expected 3 remaining entries, got %d", mode, num)
+		}
+		if evicted, _ = fs.Collect(lifetimes); len(evicted) != 0 {
+			t.Fatalf("%s: unexpected second eviction %v", mode, evicted)
+		}
+		fs.Close()
+	}
+}
+
+// TestDHTSQLStore stores blocks in the SQLite3 backend and retrieves them
+// by exact and approximate lookups; the database is re-opened to check
+// persistence and schema versioning.
+func TestDHTSQLStore(t *testing.T) {
+	cfg := make(util.ParameterSet)
+	cfg["backend"] = "sqlite3"
+	cfg["path"] = t.TempDir()
+
+	fs, err := NewDHTStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// store blocks
+	btype := enums.BLOCK_TYPE_TEST
+	keys := make([]blocks.Query, 0, fsNumBlocks)
+	for i := 0; i < fsNumBlocks; i++ {
+		blk, err := blocks.NewBlock(btype, util.AbsoluteTimeNever(), util.NewRndArray(512))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := blocks.NewGenericQuery(crypto.Hash(blk.Bytes()), btype, 0)
+		if err = fs.Put(key, &DHTEntry{Blk: blk}); err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+		keys = append(keys, key)
+	}
+	// exact lookups (with and without result filter)
+	for i, key := range keys {
+		rf := blocks.NewGenericResultFilter(128, 236742)
+		vals, err := fs.Get("test", key, rf)
+		if err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+		if len(vals) != 1 || !crypto.Hash(vals[0].Blk.Bytes()).Equal(key.Key()) {
+			t.Fatalf("[%d] key/value mismatch", i)
+		}
+		rf.Add(vals[0].Blk)
+		if vals, _ = fs.Get("test", key, rf); len(vals) != 0 {
+			t.Fatalf("[%d] filtered block returned", i)
+		}
+	}
+	// approximate lookup: the exact match comes first
+	res, err := fs.GetApprox("test", keys[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) == 0 || res[0].Dist.Sign() != 0 {
+		t.Fatal("exact match not first")
+	}
+	for i := 1; i < len(res); i++ {
+		if res[i-1].Dist.Cmp(res[i].Dist) > 0 {
+			t.Fatal("results not sorted by distance")
+		}
+	}
+	fs.Close()
+
+	// re-open database: entries persist, schema is current
+	if fs, err = NewDHTStore(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if vals, err := fs.Get("test", keys[1], nil); err != nil || len(vals) != 1 {
+		t.Fatalf("entry not persistent: %v", err)
+	}
+	var version int
+	db := fs.(*SQLDHTStore).db
+	if err = db.QueryRow("pragma user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(dhtMigrations) {
+		t.Fatalf("schema version %d, expected %d", version, len(dhtMigrations))
+	}
+	// unknown (newer) schema version is rejected
+	if _, err = db.Exec("pragma user_version=99"); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+	if _, err = NewDHTStore(cfg); !errors.Is(err, ErrStoreSchema) {
+		t.Fatalf("expected schema error, got %v", err)
+	}
+}
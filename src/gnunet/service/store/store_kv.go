@@ -36,6 +36,7 @@ var (
 	ErrStoreNotAvailable = fmt.Errorf("store not available")
 	ErrStoreNoApprox     = fmt.Errorf("no approx search for store defined")
 	ErrStoreNoList       = fmt.Errorf("no key listing for store defined")
+	ErrStoreFault        = fmt.Errorf("injected store fault")
 )
 
 //------------------------------------------------------------
@@ -59,12 +60,18 @@ type KVStore interface {
 // ------------------------------------------------------------
 // NewKVStore creates a new storage handler with given spec
 // for use with key/value string pairs.
-func NewKVStore(spec util.ParameterSet) (KVStore, error) {
+func NewKVStore(spec util.ParameterSet) (kvs KVStore, err error) {
 	// get the mode parameter
 	mode, ok := util.GetParam[string](spec, "mode")
 	if !ok {
 		return nil, ErrStoreInvalidSpec
 	}
+	// wrap store for fault injection if requested
+	defer func() {
+		if faults := storeFaults(spec); faults != nil && err == nil {
+			kvs = &FaultyKVStore{KVStore: kvs, faults: faults}
+		}
+	}()
 	switch mode {
 	//--------------------------------------------------------------
 	// Redis service
@@ -191,9 +198,9 @@ func NewSQLStore(spec util.ParameterSet) (s KVStore, err error) {
 	return kvs, nil
 }
 
-// Put a key/value pair into the store
+// Put a key/value pair into the store (replaces existing values)
 func (s *SQLStore) Put(key string, value string) error {
-	_, err := s.db.Exec("insert into store(key,value) values(?,?)", key, value)
+	_, err := s.db.Exec("replace into store(key,value) values(?,?)", key, value)
 	return err
 }
 
@@ -227,3 +234,49 @@ func (s *SQLStore) List() (keys []string, err error) {
 func (s *SQLStore) Close() error {
 	return s.db.Close()
 }
+
+//------------------------------------------------------------
+// Fault-injecting key-value-store (chaos testing)
+//------------------------------------------------------------
+
+// FaultyKVStore wraps a key/value store and fails a configurable
+// fraction of operations with ErrStoreFault.
+type FaultyKVStore struct {
+	KVStore
+
+	faults *util.FaultInjector
+}
+
+// Put value into storage under given key
+func (s *FaultyKVStore) Put(key string, val string) error {
+	if s.faults.Error() {
+		return ErrStoreFault
+	}
+	return s.KVStore.Put(key, val)
+}
+
+// Get value with given key from storage
+func (s *FaultyKVStore) Get(key string) (string, error) {
+	if s.faults.Error() {
+		return "", ErrStoreFault
+	}
+	return s.KVStore.Get(key)
+}
+
+// List all store keys
+func (s *FaultyKVStore) List() ([]string, error) {
+	if s.faults.Error() {
+		return nil, ErrStoreFault
+	}
+	return s.KVStore.List()
+}
+
+// storeFaults returns a fault injector for a storage specification
+// if the "faultRate" parameter (fraction of failing operations) is set.
+func storeFaults(spec util.ParameterSet) *util.FaultInjector {
+	rate, ok := util.GetParam[float64](spec, "faultRate")
+	if !ok || rate <= 0 {
+		return nil
+	}
+	return util.NewFaultInjector(&util.FaultConfig{Error: rate})
+}
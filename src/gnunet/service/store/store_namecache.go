@@ -0,0 +1,191 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package store
+
+import (
+	"database/sql"
+	_ "embed" // use embedded filesystem
+	"os"
+	"path/filepath"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+)
+
+//============================================================
+// Namecache: SQLite3-based cache for GNS blocks. Blocks are kept
+// until they expire (or until a configured max. caching time has
+// passed); if the cache is full, the blocks expiring first are
+// removed.
+//============================================================
+
+//go:embed store_namecache.sql
+var initScriptNC string
+
+// NamecacheDB is a SQLite3 database for cached GNS blocks.
+type NamecacheDB struct {
+	conn   *DBConn       // database connection
+	max    int           // max. number of cached blocks (0 = unlimited)
+	maxAge time.Duration // max. caching time (0 = until block expires)
+}
+
+// OpenNamecacheDB opens (or creates) the namecache database specified
+// by the "file" parameter. Optional parameters are "num" (max. number of
+// cached blocks) and "expire" (max. caching time in seconds).
+func OpenNamecacheDB(spec util.ParameterSet) (db *NamecacheDB, err error) {
+	fname, ok := util.GetParam[string](spec, "file")
+	if !ok {
+		return nil, ErrStoreInvalidSpec
+	}
+	db = &NamecacheDB{
+		max:    intParam(spec, "num"),
+		maxAge: time.Duration(intParam(spec, "expire")) * time.Second,
+	}
+	// connect to database (create file if missing)
+	if _, err = os.Stat(fname); err != nil {
+		if err = os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			return nil, err
+		}
+		var file *os.File
+		if file, err = os.Create(fname); err != nil {
+			return nil, err
+		}
+		file.Close()
+	}
+	if db.conn, err = DBPool.Connect("sqlite3:" + fname); err != nil {
+		return nil, err
+	}
+	// check for initialized database
+	res := db.conn.QueryRow("select name from sqlite_master where type='table' and name='blocks'")
+	var s string
+	if res.Scan(&s) != nil {
+		if _, err = db.conn.Exec(initScriptNC); err != nil {
+			db.conn.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// intParam returns an integer parameter (0 if missing); numbers in
+// parameter sets read from JSON are floats.
+func intParam(spec util.ParameterSet, key string) int {
+	switch v := spec[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// Close namecache database
+func (db *NamecacheDB) Close() error {
+	return db.conn.Close()
+}
+
+// Put a GNS block into the cache. A cached block for the same query is
+// only replaced by a block that expires later. Returns false if the
+// block was not cached (expired or outdated).
+func (db *NamecacheDB) Put(query *crypto.HashCode, blk *blocks.GNSBlock) (ok bool, err error) {
+	expire := blk.Expire()
+	if expire.Expired() {
+		return false, nil
+	}
+	// check for a cached block that expires later
+	var cur sql.NullInt64
+	row := db.conn.QueryRow("select expires from blocks where qkey=?", query.Data)
+	switch err = row.Scan(&cur); err {
+	case sql.ErrNoRows:
+	case nil:
+		if !cur.Valid || (!expire.IsNever() && uint64(cur.Int64) >= expire.Val) {
+			return false, nil
+		}
+	default:
+		return
+	}
+	now := util.AbsoluteTimeNow()
+	if _, err = db.conn.Exec("replace into blocks(qkey,block,expires,stored) values(?,?,?,?)",
+		query.Data, blk.RRBLOCK(), sqlExpire(expire), now.Val); err != nil {
+		return
+	}
+	// remove the blocks expiring first if the cache is full
+	if db.max > 0 {
+		_, err = db.conn.Exec("delete from blocks where qkey in "+
+			"(select qkey from blocks order by expires is null, expires limit max(0,(select count(*) from blocks)-?))", db.max)
+	}
+	return err == nil, err
+}
+
+// Get a cached GNS block for a query; returns nil if no block is cached
+// or the cached block has expired. The block is neither verified nor
+// decrypted.
+func (db *NamecacheDB) Get(query *crypto.HashCode) (blk *blocks.GNSBlock, err error) {
+	var (
+		buf     []byte
+		expires sql.NullInt64
+		stored  int64
+	)
+	row := db.conn.QueryRow("select block,expires,stored from blocks where qkey=?", query.Data)
+	if err = row.Scan(&buf, &expires, &stored); err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+		return
+	}
+	if db.outdated(expires, stored, util.AbsoluteTimeNow()) {
+		_, err = db.conn.Exec("delete from blocks where qkey=?", query.Data)
+		return
+	}
+	return blocks.NewGNSBlockFromRRBLOCK(buf)
+}
+
+// Collect removes all expired blocks from the cache and returns the
+// number of removed blocks.
+func (db *NamecacheDB) Collect() (n int, err error) {
+	now := util.AbsoluteTimeNow()
+	limit := int64(0)
+	if db.maxAge > 0 {
+		limit = int64(now.Val) - db.maxAge.Microseconds()
+	}
+	var res sql.Result
+	if res, err = db.conn.Exec("delete from blocks where expires<=? or stored<?", now.Val, limit); err != nil {
+		return
+	}
+	var num int64
+	num, err = res.RowsAffected()
+	return int(num), err
+}
+
+// Count returns the number of cached blocks.
+func (db *NamecacheDB) Count() (n int, err error) {
+	err = db.conn.QueryRow("select count(*) from blocks").Scan(&n)
+	return
+}
+
+// outdated returns true if a cached block has expired or has been cached
+// for longer than the max. caching time.
+func (db *NamecacheDB) outdated(expires sql.NullInt64, stored int64, now util.AbsoluteTime) bool {
+	if expires.Valid && uint64(expires.Int64) <= now.Val {
+		return true
+	}
+	return db.maxAge > 0 && stored+db.maxAge.Microseconds() < int64(now.Val)
+}
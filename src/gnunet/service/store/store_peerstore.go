@@ -0,0 +1,289 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package store
+
+import (
+	"bytes"
+	"database/sql"
+	_ "embed" // use embedded filesystem
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gnunet/util"
+)
+
+//============================================================
+// Peerstore: values (HELLOs, addresses, metrics,...) stored for peers
+// by sub-systems. A key can have multiple values; storing an existing
+// value updates its expiration. Expired records are removed by Collect.
+//============================================================
+
+// PeerstoreRecord is a value stored for a peer by a sub-system.
+type PeerstoreRecord struct {
+	SubSystem string            // name of sub-system
+	Peer      *util.PeerID      // peer identity
+	Key       string            // record key
+	Value     []byte            // record value
+	Expire    util.AbsoluteTime // expiration of record
+}
+
+// PeerstoreDB is the persistence backend of the peerstore.
+type PeerstoreDB interface {
+	// Store a record; existing values of the key are removed first if
+	// replace is set.
+	Store(rec *PeerstoreRecord, replace bool) error
+
+	// Iterate returns the unexpired records of a sub-system; a nil peer
+	// or an empty key match all records.
+	Iterate(subSystem string, peer *util.PeerID, key string) ([]*PeerstoreRecord, error)
+
+	// Collect removes expired records and returns their number.
+	Collect() (int, error)
+
+	// Count returns the number of stored records.
+	Count() (int, error)
+
+	// Close the backend.
+	Close() error
+}
+
+// NewPeerstoreDB creates a peerstore backend selected by the "backend"
+// parameter: "sqlite3" (default) for a SQLite3 database in the file given
+// by the "file" parameter or "memory" for transient storage.
+func NewPeerstoreDB(spec util.ParameterSet) (PeerstoreDB, error) {
+	backend, ok := util.GetParam[string](spec, "backend")
+	if !ok {
+		backend = "sqlite3"
+	}
+	// don't return typed nil pointers as interface values
+	switch backend {
+	case "sqlite3":
+		db, err := OpenSQLPeerstoreDB(spec)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case "memory":
+		return NewMemPeerstoreDB(), nil
+	}
+	return nil, ErrStoreUnknown
+}
+
+// matches returns true if a record matches the iteration filter.
+func (rec *PeerstoreRecord) matches(subSystem string, peer *util.PeerID, key string) bool {
+	return rec.SubSystem == subSystem &&
+		(peer == nil || rec.Peer.Equal(peer)) &&
+		(len(key) == 0 || rec.Key == key)
+}
+
+//------------------------------------------------------------
+// Transient peerstore
+//------------------------------------------------------------
+
+// MemPeerstoreDB keeps peerstore records in memory.
+type MemPeerstoreDB struct {
+	sync.Mutex
+	recs []*PeerstoreRecord // list of records
+}
+
+// NewMemPeerstoreDB creates an empty transient peerstore.
+func NewMemPeerstoreDB() *MemPeerstoreDB {
+	return new(MemPeerstoreDB)
+}
+
+// Store a record (see PeerstoreDB).
+func (db *MemPeerstoreDB) Store(rec *PeerstoreRecord, replace bool) error {
+	db.Lock()
+	defer db.Unlock()
+	list := make([]*PeerstoreRecord, 0, len(db.recs)+1)
+	for _, r := range db.recs {
+		if r.matches(rec.SubSystem, rec.Peer, rec.Key) && (replace || bytes.Equal(r.Value, rec.Value)) {
+			continue
+		}
+		list = append(list, r)
+	}
+	db.recs = append(list, &PeerstoreRecord{
+		SubSystem: rec.SubSystem,
+		Peer:      rec.Peer,
+		Key:       rec.Key,
+		Value:     util.Clone(rec.Value),
+		Expire:    rec.Expire,
+	})
+	return nil
+}
+
+// Iterate returns matching records (see PeerstoreDB).
+func (db *MemPeerstoreDB) Iterate(subSystem string, peer *util.PeerID, key string) (list []*PeerstoreRecord, err error) {
+	db.Lock()
+	defer db.Unlock()
+	for _, r := range db.recs {
+		if r.matches(subSystem, peer, key) && !r.Expire.Expired() {
+			list = append(list, r)
+		}
+	}
+	return
+}
+
+// Collect removes expired records (see PeerstoreDB).
+func (db *MemPeerstoreDB) Collect() (n int, err error) {
+	db.Lock()
+	defer db.Unlock()
+	list := make([]*PeerstoreRecord, 0, len(db.recs))
+	for _, r := range db.recs {
+		if r.Expire.Expired() {
+			n++
+			continue
+		}
+		list = append(list, r)
+	}
+	db.recs = list
+	return
+}
+
+// Count returns the number of records (see PeerstoreDB).
+func (db *MemPeerstoreDB) Count() (int, error) {
+	db.Lock()
+	defer db.Unlock()
+	return len(db.recs), nil
+}
+
+// Close the store (see PeerstoreDB).
+func (db *MemPeerstoreDB) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------
+// SQLite3-based peerstore
+//------------------------------------------------------------
+
+//go:embed store_peerstore.sql
+var initScriptPS string
+
+// SQLPeerstoreDB keeps peerstore records in a SQLite3 database.
+type SQLPeerstoreDB struct {
+	conn *DBConn // database connection
+}
+
+// OpenSQLPeerstoreDB opens (or creates) the peerstore database specified
+// by the "file" parameter.
+func OpenSQLPeerstoreDB(spec util.ParameterSet) (db *SQLPeerstoreDB, err error) {
+	fname, ok := util.GetParam[string](spec, "file")
+	if !ok {
+		return nil, ErrStoreInvalidSpec
+	}
+	// connect to database (create file if missing)
+	if _, err = os.Stat(fname); err != nil {
+		if err = os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			return nil, err
+		}
+		var file *os.File
+		if file, err = os.Create(fname); err != nil {
+			return nil, err
+		}
+		file.Close()
+	}
+	db = new(SQLPeerstoreDB)
+	if db.conn, err = DBPool.Connect("sqlite3:" + fname); err != nil {
+		return nil, err
+	}
+	// check for initialized database
+	res := db.conn.QueryRow("select name from sqlite_master where type='table' and name='records'")
+	var s string
+	if res.Scan(&s) != nil {
+		if _, err = db.conn.Exec(initScriptPS); err != nil {
+			db.conn.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Store a record (see PeerstoreDB).
+func (db *SQLPeerstoreDB) Store(rec *PeerstoreRecord, replace bool) (err error) {
+	if replace {
+		if _, err = db.conn.Exec("delete from records where subsystem=? and peer=? and key=?",
+			rec.SubSystem, rec.Peer.Bytes(), rec.Key); err != nil {
+			return
+		}
+	}
+	_, err = db.conn.Exec("replace into records(subsystem,peer,key,value,expires) values(?,?,?,?,?)",
+		rec.SubSystem, rec.Peer.Bytes(), rec.Key, rec.Value, sqlExpire(rec.Expire))
+	return
+}
+
+// Iterate returns matching records (see PeerstoreDB).
+func (db *SQLPeerstoreDB) Iterate(subSystem string, peer *util.PeerID, key string) (list []*PeerstoreRecord, err error) {
+	query := "select peer,key,value,expires from records where subsystem=? and (expires is null or expires>?)"
+	args := []any{subSystem, util.AbsoluteTimeNow().Val}
+	if peer != nil {
+		query += " and peer=?"
+		args = append(args, peer.Bytes())
+	}
+	if len(key) > 0 {
+		query += " and key=?"
+		args = append(args, key)
+	}
+	var rows *sql.Rows
+	if rows, err = db.conn.Query(query, args...); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			pid     []byte
+			expires sql.NullInt64
+		)
+		rec := &PeerstoreRecord{
+			SubSystem: subSystem,
+			Expire:    util.AbsoluteTimeNever(),
+		}
+		if err = rows.Scan(&pid, &rec.Key, &rec.Value, &expires); err != nil {
+			return nil, err
+		}
+		rec.Peer = util.NewPeerID(pid)
+		if expires.Valid {
+			rec.Expire = util.AbsoluteTime{Val: uint64(expires.Int64)}
+		}
+		list = append(list, rec)
+	}
+	return list, rows.Err()
+}
+
+// Collect removes expired records (see PeerstoreDB).
+func (db *SQLPeerstoreDB) Collect() (n int, err error) {
+	var res sql.Result
+	if res, err = db.conn.Exec("delete from records where expires<=?", util.AbsoluteTimeNow().Val); err != nil {
+		return
+	}
+	var num int64
+	num, err = res.RowsAffected()
+	return int(num), err
+}
+
+// Count returns the number of records (see PeerstoreDB).
+func (db *SQLPeerstoreDB) Count() (n int, err error) {
+	err = db.conn.QueryRow("select count(*) from records").Scan(&n)
+	return
+}
+
+// Close the database (see PeerstoreDB).
+func (db *SQLPeerstoreDB) Close() error {
+	return db.conn.Close()
+}
@@ -0,0 +1,29 @@
+-- This file is part of gnunet-go, a GNUnet-implementation in Golang.
+-- Copyright (C) 2019-2022 Bernd Fix  >Y<
+--
+-- gnunet-go is free software: you can redistribute it and/or modify it
+-- under the terms of the GNU Affero General Public License as published
+-- by the Free Software Foundation, either version 3 of the License,
+-- or (at your option) any later version.
+--
+-- gnunet-go is distributed in the hope that it will be useful, but
+-- WITHOUT ANY WARRANTY; without even the implied warranty of
+-- MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+-- Affero General Public License for more details.
+--
+-- You should have received a copy of the GNU Affero General Public License
+-- along with this program.  If not, see <http://www.gnu.org/licenses/>.
+--
+-- SPDX-License-Identifier: AGPL3.0-or-later
+
+-- Peerstore: values stored for peers by sub-systems (services)
+
+create table records (
+    subsystem text not null, -- name of sub-system
+    peer      blob not null, -- peer identity
+    key       text not null, -- record key
+    value     blob not null, -- record value
+    expires   integer,       -- record expiration (microseconds, null = never)
+    primary key(subsystem, peer, key, value)
+);
+create index records_expires on records(expires);
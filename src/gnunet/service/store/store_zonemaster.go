@@ -31,6 +31,11 @@ import (
 	// "https://github.com/go-zeromq/zmq4"
 )
 
+// Error messages related to the zone database
+var (
+	ErrLabelVersionConflict = errors.New("label version conflict (record set modified concurrently)")
+)
+
 //============================================================
 // Zones are named ZonePrivate keys that act as a container
 // for labeled resource record sets in GNS.
@@ -67,6 +72,7 @@ type Label struct {
 	Created  util.AbsoluteTime // date of creation
 	Modified util.AbsoluteTime // date of last modification
 	KeyHash  *crypto.HashCode  // hashcode of the label under zone
+	Version  int64             // version of record set (increased on change)
 }
 
 // NewLabel returns a new label with given name. It is not
@@ -78,6 +84,7 @@ func NewLabel(label string) *Label {
 	lbl.Name = label
 	lbl.Created = util.AbsoluteTimeNow()
 	lbl.Modified = util.AbsoluteTimeNow()
+	lbl.Version = 1
 	return lbl
 }
 
@@ -94,6 +101,44 @@ func (l *Label) SetZone(z *Zone) error {
 
 //----------------------------------------------------------------------
 
+// Publication is the journal entry for the publication of a label: it
+// records the outcome of the last attempt and when the label is due
+// for publication again.
+type Publication struct {
+	Label     int64             // database ID of label
+	Published util.AbsoluteTime // time of last successful publication
+	Attempted util.AbsoluteTime // time of last attempt
+	Failures  int               // number of failed attempts since last success
+	Error     string            // error of last failed attempt
+	Next      util.AbsoluteTime // time the label is due for publication
+}
+
+//----------------------------------------------------------------------
+
+// Revocation is a precomputed (signed) revocation of a zone key kept for
+// emergencies: if the zone is marked as compromised, the revocation is
+// published and the zone is no longer published.
+type Revocation struct {
+	Zone        int64             // database ID of zone
+	Data        []byte            // revocation data (wire format)
+	Compromised util.AbsoluteTime // time zone was marked compromised (0 = not compromised)
+	Published   util.AbsoluteTime // time revocation was published (0 = not published)
+}
+
+//----------------------------------------------------------------------
+
+// StoreResult is the result of a store request with an idempotency key:
+// a repeated request with the same key (for the same zone) is answered
+// with the original result.
+type StoreResult struct {
+	Zone    int64             // database ID of zone
+	Key     []byte            // idempotency key
+	Status  uint32            // result of the store request
+	Created util.AbsoluteTime // time of the store request
+}
+
+//----------------------------------------------------------------------
+
 // Record for GNS resource in a zone (generic). It is the responsibility
 // of the caller to provide valid resource data in binary form.
 type Record struct {
@@ -129,9 +174,17 @@ func NewRecord(expire util.AbsoluteTime, rtype enums.GNSType, flags enums.GNSFla
 //go:embed store_zonemaster.sql
 var initScriptZM []byte
 
+// sqlExec executes SQL statements (on a connection or in a transaction)
+type sqlExec interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 // ZoneDB is a SQLite3 database for locally managed zones
 type ZoneDB struct {
-	conn *DBConn // database connection
+	conn sqlExec // database connection (or transaction)
+	base *DBConn // database connection (nil in a transaction)
 }
 
 // OpenZoneDB opens a zone database in the given filename (including
@@ -147,9 +200,10 @@ func OpenZoneDB(fname string) (db *ZoneDB, err error) {
 		file.Close()
 	}
 	db = new(ZoneDB)
-	if db.conn, err = DBPool.Connect("sqlite3:" + fname); err != nil {
+	if db.base, err = DBPool.Connect("sqlite3:" + fname); err != nil {
 		return
 	}
+	db.conn = db.base
 	// check for initialized database
 	res := db.conn.QueryRow("select name from sqlite_master where type='table' and name='zones'")
 	var s string
@@ -159,12 +213,50 @@ func OpenZoneDB(fname string) (db *ZoneDB, err error) {
 			return
 		}
 	}
+	// upgrade older databases: add version column to labels
+	if _, err = db.conn.Exec("select version from labels limit 1"); err != nil {
+		if _, err = db.conn.Exec("alter table labels add column version integer not null default 1"); err != nil {
+			return
+		}
+	}
+	// upgrade older databases: add publication journal
+	if _, err = db.conn.Exec("select lid from publications limit 1"); err != nil {
+		if _, err = db.conn.Exec(createPublications); err != nil {
+			return
+		}
+	}
+	// upgrade older databases: add stored revocations
+	if _, err = db.conn.Exec("select zid from revocations limit 1"); err != nil {
+		if _, err = db.conn.Exec(createRevocations); err != nil {
+			return
+		}
+	}
+	// upgrade older databases: add results of store requests
+	if _, err = db.conn.Exec("select zid from stores limit 1"); err != nil {
+		_, err = db.conn.Exec(createStores)
+	}
 	return
 }
 
 // Close zone database
 func (db *ZoneDB) Close() error {
-	return db.conn.Close()
+	return db.base.Close()
+}
+
+// Atomic runs a function on the zone database in a transaction: changes
+// made through the database passed to the function are committed if the
+// function succeeds and rolled back otherwise. Changes are not visible to
+// other users of the database until they are committed.
+func (db *ZoneDB) Atomic(fn func(tx *ZoneDB) error) error {
+	tx, err := db.base.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(&ZoneDB{conn: tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 //----------------------------------------------------------------------
@@ -316,8 +408,11 @@ func (db *ZoneDB) GetZoneByPublicKey(zk *crypto.ZoneKey) (ident *Zone, err error
 func (db *ZoneDB) SetLabel(l *Label) error {
 	// check for label insert
 	if l.ID == 0 {
-		stmt := "insert into labels(zid,name,created,modified,keyhash) values(?,?,?,?,?)"
-		result, err := db.conn.Exec(stmt, l.Zone, l.Name, l.Created.Val, l.Modified.Val, l.KeyHash.Data)
+		if l.Version == 0 {
+			l.Version = 1
+		}
+		stmt := "insert into labels(zid,name,created,modified,keyhash,version) values(?,?,?,?,?,?)"
+		result, err := db.conn.Exec(stmt, l.Zone, l.Name, l.Created.Val, l.Modified.Val, l.KeyHash.Data, l.Version)
 		if err != nil {
 			return err
 		}
@@ -344,6 +439,9 @@ func (db *ZoneDB) SetLabel(l *Label) error {
 	if _, err := db.conn.Exec("update records set lid=null where lid=?", l.ID); err != nil {
 		return err
 	}
+	if _, err := db.conn.Exec("delete from publications where lid=?", l.ID); err != nil {
+		return err
+	}
 	_, err := db.conn.Exec("delete from labels where id=?", l.ID)
 	return err
 }
@@ -351,11 +449,12 @@ func (db *ZoneDB) SetLabel(l *Label) error {
 // GetLabel gets a label with given identifier
 func (db *ZoneDB) GetLabel(id int64) (label *Label, err error) {
 	// assemble label from database row
-	stmt := "select zid,name,created,modified,keyhash from labels where id=?"
+	stmt := "select zid,name,created,modified,keyhash,version from labels where id=?"
 	label = new(Label)
+	label.ID = id
 	row := db.conn.QueryRow(stmt, id)
 	var query []byte
-	if err = row.Scan(&label.Zone, &label.Name, &label.Created.Val, &label.Modified.Val, &query); err == nil {
+	if err = row.Scan(&label.Zone, &label.Name, &label.Created.Val, &label.Modified.Val, &query, &label.Version); err == nil {
 		label.KeyHash = crypto.NewHashCode(query)
 	}
 	return
@@ -364,11 +463,11 @@ func (db *ZoneDB) GetLabel(id int64) (label *Label, err error) {
 // GetLabelByKeyHash returns a label with given query hash
 func (db *ZoneDB) GetLabelByKeyHash(hsh *crypto.HashCode) (label *Label, err error) {
 	// assemble label from database row
-	stmt := "select id,zid,name,created,modified from labels where keyhash=?"
+	stmt := "select id,zid,name,created,modified,version from labels where keyhash=?"
 	label = new(Label)
 	label.KeyHash = hsh
 	row := db.conn.QueryRow(stmt, hsh)
-	err = row.Scan(&label.ID, &label.Zone, &label.Name, &label.Created.Val, &label.Modified.Val)
+	err = row.Scan(&label.ID, &label.Zone, &label.Name, &label.Created.Val, &label.Modified.Val, &label.Version)
 	return
 }
 
@@ -376,17 +475,18 @@ func (db *ZoneDB) GetLabelByKeyHash(hsh *crypto.HashCode) (label *Label, err err
 // demand ('create' flag) if 'zid' is not 0.
 func (db *ZoneDB) GetLabelByName(name string, zid int64, create bool) (label *Label, err error) {
 	// assemble label from database row
-	stmt := "select id,created,modified from labels where name=? and zid=?"
+	stmt := "select id,created,modified,version from labels where name=? and zid=?"
 	label = new(Label)
 	label.Name = name
 	label.Zone = zid
 	row := db.conn.QueryRow(stmt, name, zid)
-	if err = row.Scan(&label.ID, &label.Created.Val, &label.Modified.Val); err != nil {
+	if err = row.Scan(&label.ID, &label.Created.Val, &label.Modified.Val, &label.Version); err != nil {
 		// check for "does not exist"
 		if err == sql.ErrNoRows && create {
 			err = nil
 			label.Created = util.AbsoluteTimeNow()
 			label.Modified = util.AbsoluteTimeNow()
+			label.Version = 1
 			if zid != 0 {
 				// yes: create label
 				label.Zone = zid
@@ -406,7 +506,7 @@ func (db *ZoneDB) GetLabelByName(name string, zid int64, create bool) (label *La
 // ("where" clause)
 func (db *ZoneDB) GetLabels(filter string, args ...any) (list []*Label, err error) {
 	// assemble querey
-	stmt := "select id,zid,name,created,modified from labels"
+	stmt := "select id,zid,name,created,modified,version from labels"
 	if len(filter) > 0 {
 		stmt += " where " + fmt.Sprintf(filter, args...)
 	}
@@ -420,7 +520,7 @@ func (db *ZoneDB) GetLabels(filter string, args ...any) (list []*Label, err erro
 	for rows.Next() {
 		// assemble label from database row
 		lbl := new(Label)
-		if err = rows.Scan(&lbl.ID, &lbl.Zone, &lbl.Name, &lbl.Created.Val, &lbl.Modified.Val); err != nil {
+		if err = rows.Scan(&lbl.ID, &lbl.Zone, &lbl.Name, &lbl.Created.Val, &lbl.Modified.Val, &lbl.Version); err != nil {
 			// terminate on error; return list so far
 			return
 		}
@@ -430,6 +530,29 @@ func (db *ZoneDB) GetLabels(filter string, args ...any) (list []*Label, err erro
 	return
 }
 
+// UpdateLabelVersion increments the version of a label record set if the
+// current version matches the expected version (compare-and-swap). Callers
+// must bump the version whenever they change the record set of a label;
+// ErrLabelVersionConflict is returned if the record set was changed by
+// someone else in the meantime. Returns the new version of the label.
+func (db *ZoneDB) UpdateLabelVersion(lid, expected int64) (version int64, err error) {
+	stmt := "update labels set version=version+1,modified=? where id=? and version=?"
+	var result sql.Result
+	if result, err = db.conn.Exec(stmt, util.AbsoluteTimeNow().Val, lid, expected); err != nil {
+		return
+	}
+	var num int64
+	if num, err = result.RowsAffected(); err != nil {
+		return
+	}
+	if num != 1 {
+		err = ErrLabelVersionConflict
+		return
+	}
+	return expected + 1, nil
+}
+
+// GetLabelIDs returns the database identifiers of all labels in a zone.
 func (db *ZoneDB) GetLabelIDs(zk *crypto.ZonePrivate) (list []int64, zid int64, err error) {
 	// get zone database id
 	row := db.conn.QueryRow("select id from zones where ztype=? and zdata=?", zk.Type, zk.KeyData)
@@ -452,6 +575,143 @@ func (db *ZoneDB) GetLabelIDs(zk *crypto.ZonePrivate) (list []int64, zid int64,
 	return
 }
 
+//----------------------------------------------------------------------
+// Publication journal
+//----------------------------------------------------------------------
+
+// table definition for databases created before the journal existed
+// (see store_zonemaster.sql)
+const createPublications = `create table publications (
+    lid       integer primary key references labels(id),
+    published integer not null default 0,
+    attempted integer not null default 0,
+    failures  integer not null default 0,
+    error     text not null default '',
+    next      integer not null default 0
+)`
+
+// SetPublication inserts or replaces the journal entry for a label.
+func (db *ZoneDB) SetPublication(p *Publication) error {
+	stmt := "replace into publications(lid,published,attempted,failures,error,next) values(?,?,?,?,?,?)"
+	_, err := db.conn.Exec(stmt, p.Label, p.Published.Val, p.Attempted.Val, p.Failures, p.Error, p.Next.Val)
+	return err
+}
+
+// GetPublication returns the journal entry for a label. A label without
+// entry has never been published and is due immediately.
+func (db *ZoneDB) GetPublication(lid int64) (p *Publication, err error) {
+	stmt := "select published,attempted,failures,error,next from publications where lid=?"
+	p = &Publication{Label: lid}
+	row := db.conn.QueryRow(stmt, lid)
+	if err = row.Scan(&p.Published.Val, &p.Attempted.Val, &p.Failures, &p.Error, &p.Next.Val); err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+// GetPublications returns all journal entries matching a filter ("where"
+// clause).
+func (db *ZoneDB) GetPublications(filter string, args ...any) (list []*Publication, err error) {
+	stmt := "select lid,published,attempted,failures,error,next from publications"
+	if len(filter) > 0 {
+		stmt += " where " + fmt.Sprintf(filter, args...)
+	}
+	var rows *sql.Rows
+	if rows, err = db.conn.Query(stmt); err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p := new(Publication)
+		if err = rows.Scan(&p.Label, &p.Published.Val, &p.Attempted.Val, &p.Failures, &p.Error, &p.Next.Val); err != nil {
+			return
+		}
+		list = append(list, p)
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Stored revocations
+//----------------------------------------------------------------------
+
+// table definition for databases created before revocations were stored
+// (see store_zonemaster.sql)
+const createRevocations = `create table revocations (
+    zid         integer primary key references zones(id),
+    rdata       blob,
+    compromised integer not null default 0,
+    published   integer not null default 0
+)`
+
+// SetRevocation inserts or replaces the stored revocation for a zone.
+func (db *ZoneDB) SetRevocation(r *Revocation) error {
+	stmt := "replace into revocations(zid,rdata,compromised,published) values(?,?,?,?)"
+	_, err := db.conn.Exec(stmt, r.Zone, r.Data, r.Compromised.Val, r.Published.Val)
+	return err
+}
+
+// GetRevocation returns the stored revocation for a zone. A zone without
+// stored revocation has no revocation data and is not compromised.
+func (db *ZoneDB) GetRevocation(zid int64) (r *Revocation, err error) {
+	stmt := "select rdata,compromised,published from revocations where zid=?"
+	r = &Revocation{Zone: zid}
+	row := db.conn.QueryRow(stmt, zid)
+	if err = row.Scan(&r.Data, &r.Compromised.Val, &r.Published.Val); err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Results of store requests (idempotency keys)
+//----------------------------------------------------------------------
+
+// table definition for databases created before idempotency keys were
+// supported (see store_zonemaster.sql)
+const createStores = `create table stores (
+    zid     integer references zones(id),
+    key     blob,
+    status  integer not null default 0,
+    created integer not null default 0,
+    primary key (zid,key)
+)`
+
+// SetStoreResult inserts the result of a store request. Returns false if
+// a result for the key already exists (the result is not changed).
+func (db *ZoneDB) SetStoreResult(r *StoreResult) (ok bool, err error) {
+	stmt := "insert or ignore into stores(zid,key,status,created) values(?,?,?,?)"
+	var res sql.Result
+	if res, err = db.conn.Exec(stmt, r.Zone, r.Key, r.Status, r.Created.Val); err != nil {
+		return
+	}
+	var n int64
+	n, err = res.RowsAffected()
+	return n == 1, err
+}
+
+// GetStoreResult returns the result of a store request with given key
+// (or nil if no such request was stored).
+func (db *ZoneDB) GetStoreResult(zid int64, key []byte) (r *StoreResult, err error) {
+	stmt := "select status,created from stores where zid=? and key=?"
+	r = &StoreResult{Zone: zid, Key: key}
+	row := db.conn.QueryRow(stmt, zid, key)
+	if err = row.Scan(&r.Status, &r.Created.Val); err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return
+}
+
+// PurgeStoreResults removes the results of store requests older than
+// the given time. Returns the number of removed results.
+func (db *ZoneDB) PurgeStoreResults(before util.AbsoluteTime) (n int64, err error) {
+	var res sql.Result
+	if res, err = db.conn.Exec("delete from stores where created<?", before.Val); err != nil {
+		return
+	}
+	return res.RowsAffected()
+}
+
 //----------------------------------------------------------------------
 // Record handling
 //----------------------------------------------------------------------
@@ -33,6 +33,7 @@ create table labels (
     created  integer,
     modified integer,
     keyhash  blob,
+    version  integer not null default 1,
     unique (zid,name)
 );
 
@@ -46,3 +47,27 @@ create table records (
     rtype    integer,
     rdata    blob
 );
+
+create table publications (
+    lid       integer primary key references labels(id),
+    published integer not null default 0,
+    attempted integer not null default 0,
+    failures  integer not null default 0,
+    error     text not null default '',
+    next      integer not null default 0
+);
+
+create table revocations (
+    zid         integer primary key references zones(id),
+    rdata       blob,
+    compromised integer not null default 0,
+    published   integer not null default 0
+);
+
+create table stores (
+    zid     integer references zones(id),
+    key     blob,
+    status  integer not null default 0,
+    created integer not null default 0,
+    primary key (zid,key)
+);
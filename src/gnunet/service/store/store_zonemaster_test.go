@@ -81,6 +81,55 @@ func TestZoneMaster(t *testing.T) {
 		t.Fatalf("record: got %d records, expected 1", len(recs))
 	}
 
+	//------------------------------------------------------------------
+	// update label version (compare-and-swap)
+	if label, err = zdb.GetLabel(label.ID); err != nil {
+		t.Fatal(err)
+	}
+	if label.Version != 1 {
+		t.Fatalf("label: got version %d, expected 1", label.Version)
+	}
+	ver, err := zdb.UpdateLabelVersion(label.ID, label.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver != 2 {
+		t.Fatalf("label: got version %d, expected 2", ver)
+	}
+	if _, err = zdb.UpdateLabelVersion(label.ID, label.Version); err != ErrLabelVersionConflict {
+		t.Fatalf("label: expected version conflict, got %v", err)
+	}
+
+	//------------------------------------------------------------------
+	// publication journal
+	pub, err := zdb.GetPublication(label.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.Next.Val != 0 || pub.Failures != 0 {
+		t.Fatal("publication: unpublished label not due")
+	}
+	pub.Attempted = util.AbsoluteTimeNow()
+	pub.Failures = 1
+	pub.Error = "no peers"
+	if err = zdb.SetPublication(pub); err != nil {
+		t.Fatal(err)
+	}
+	pub.Published = pub.Attempted
+	pub.Failures = 0
+	pub.Error = ""
+	pub.Next = pub.Attempted.Add(time.Hour)
+	if err = zdb.SetPublication(pub); err != nil {
+		t.Fatal(err)
+	}
+	pubs, err := zdb.GetPublications("next>%d", util.AbsoluteTimeNow().Val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pubs) != 1 || pubs[0].Label != label.ID || pubs[0].Failures != 0 || pubs[0].Published.Val != pub.Published.Val {
+		t.Fatalf("publication: unexpected journal %v", pubs)
+	}
+
 	//------------------------------------------------------------------
 	// rename zone
 	zone.Name = "MyZone"
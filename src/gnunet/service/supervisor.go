@@ -0,0 +1,352 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gnunet/config"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrUnitUnknown  = errors.New("unknown service")
+	ErrUnitExists   = errors.New("service already supervised")
+	ErrUnitRunning  = errors.New("service already running")
+	ErrUnitStopped  = errors.New("service not running")
+	ErrUnitNeeds    = errors.New("required service not running")
+	ErrUnitRequired = errors.New("service required by running service")
+)
+
+//----------------------------------------------------------------------
+// Supervisor: A process running more than one service (like the DHT
+// service with core, NSE and CADET, or a test bed running all services)
+// can stop, start and restart its services individually, e.g. to restart
+// a single service after a configuration change without restarting the
+// whole node. Each service (unit) runs in its own context; a unit can
+// only be started if all services it needs are running, and a unit can
+// only be stopped (or restarted) if no running unit needs it.
+//----------------------------------------------------------------------
+
+// Unit is a service under control of a supervisor.
+type Unit struct {
+	Name  string                          // name of unit
+	Needs []string                        // names of required units
+	Start func(ctx context.Context) error // start unit (runs until context is done)
+	Stop  func() error                    // stop unit (optional)
+}
+
+// UnitStatus is the state of a supervised unit.
+type UnitStatus struct {
+	Name    string   `json:"name"`              // name of unit
+	Needs   []string `json:"needs,omitempty"`   // names of required units
+	Running bool     `json:"running"`           // unit is running
+	Since   string   `json:"since"`             // time of last start or stop
+	Starts  int      `json:"starts"`            // number of starts
+	LastErr string   `json:"lastErr,omitempty"` // error of last start or stop
+}
+
+// unit is a registered unit with state
+type unit struct {
+	*Unit
+	cancel context.CancelFunc // cancel unit context (nil if stopped)
+	since  time.Time          // time of last start or stop
+	starts int                // number of starts
+	err    error              // error of last start or stop
+}
+
+// Supervisor controls the units (services) of a process.
+type Supervisor struct {
+	sync.Mutex
+
+	ctx   context.Context  // parent context of all units
+	units map[string]*unit // units by name
+	order []string         // unit names in order of registration
+}
+
+// NewSupervisor creates a supervisor for units running in context 'ctx'.
+func NewSupervisor(ctx context.Context) *Supervisor {
+	return &Supervisor{
+		ctx:   ctx,
+		units: make(map[string]*unit),
+		order: make([]string, 0),
+	}
+}
+
+// Add a unit to the supervisor (the unit is not started). All units
+// needed by the new unit must be added before, so there are no cyclic
+// dependencies between units.
+func (s *Supervisor) Add(u *Unit) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.units[u.Name]; ok {
+		return ErrUnitExists
+	}
+	for _, name := range u.Needs {
+		if _, ok := s.units[name]; !ok {
+			return fmt.Errorf("%w: '%s' needs '%s'", ErrUnitUnknown, u.Name, name)
+		}
+	}
+	s.units[u.Name] = &unit{Unit: u}
+	s.order = append(s.order, u.Name)
+	return nil
+}
+
+// Start a unit. All units it needs must be running.
+func (s *Supervisor) Start(name string) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.start(name)
+}
+
+// Stop a unit. No running unit may need it.
+func (s *Supervisor) Stop(name string) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.stop(name)
+}
+
+// Restart a running unit. No running unit may need it.
+func (s *Supervisor) Restart(name string) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.stop(name); err != nil {
+		return err
+	}
+	return s.start(name)
+}
+
+// StartAll starts all units (in order of registration). Units that
+// fail to start (or need a unit that failed) are skipped; the first
+// error is returned.
+func (s *Supervisor) StartAll() (err error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, name := range s.order {
+		if s.units[name].cancel != nil {
+			continue
+		}
+		if rc := s.start(name); rc != nil && err == nil {
+			err = rc
+		}
+	}
+	return
+}
+
+// StopAll stops all running units (in reverse order of registration).
+func (s *Supervisor) StopAll() {
+	s.Lock()
+	defer s.Unlock()
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if name := s.order[i]; s.units[name].cancel != nil {
+			_ = s.stop(name)
+		}
+	}
+}
+
+// Status returns the state of named units (all units if no names are
+// given) in order of registration.
+func (s *Supervisor) Status(names ...string) []UnitStatus {
+	s.Lock()
+	defer s.Unlock()
+	out := make([]UnitStatus, 0)
+	for _, name := range s.order {
+		if len(names) > 0 && !contains(names, name) {
+			continue
+		}
+		u := s.units[name]
+		st := UnitStatus{
+			Name:    name,
+			Needs:   u.Needs,
+			Running: u.cancel != nil,
+			Starts:  u.starts,
+		}
+		if !u.since.IsZero() {
+			st.Since = u.since.Format(time.RFC3339)
+		}
+		if u.err != nil {
+			st.LastErr = u.err.Error()
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// start a unit (caller must hold the lock)
+func (s *Supervisor) start(name string) error {
+	u, ok := s.units[name]
+	if !ok {
+		return ErrUnitUnknown
+	}
+	if u.cancel != nil {
+		return ErrUnitRunning
+	}
+	for _, dep := range u.Needs {
+		if s.units[dep].cancel == nil {
+			return fmt.Errorf("%w: '%s' needs '%s'", ErrUnitNeeds, name, dep)
+		}
+	}
+	logger.Printf(logger.INFO, "[supervisor] Starting service '%s'", name)
+	ctx, cancel := context.WithCancel(s.ctx)
+	u.since = time.Now()
+	if u.err = u.Start(ctx); u.err != nil {
+		logger.Printf(logger.ERROR, "[supervisor] Service '%s' failed to start: %s", name, u.err.Error())
+		cancel()
+		return u.err
+	}
+	u.cancel = cancel
+	u.starts++
+	return nil
+}
+
+// stop a unit (caller must hold the lock)
+func (s *Supervisor) stop(name string) error {
+	u, ok := s.units[name]
+	if !ok {
+		return ErrUnitUnknown
+	}
+	if u.cancel == nil {
+		return ErrUnitStopped
+	}
+	for _, other := range s.units {
+		if other.cancel != nil && contains(other.Needs, name) {
+			return fmt.Errorf("%w: '%s' needs '%s'", ErrUnitRequired, other.Name, name)
+		}
+	}
+	logger.Printf(logger.INFO, "[supervisor] Stopping service '%s'", name)
+	u.err = nil
+	if u.Stop != nil {
+		if u.err = u.Stop(); u.err != nil {
+			logger.Printf(logger.WARN, "[supervisor] Service '%s' stopped with error: %s", name, u.err.Error())
+		}
+	}
+	u.cancel()
+	u.cancel = nil
+	u.since = time.Now()
+	return nil
+}
+
+// SocketUnit returns a unit that serves a service on its socket. The
+// service is created by 'create' on every start (the function can return
+// the same instance if the service keeps running between restarts); the
+// socket and limits are taken from the service configuration on start.
+// Clients asking for the source code get the link 'source'.
+func SocketUnit(name string, needs []string, cfg *config.ServiceConfig, source string, create func(ctx context.Context) (Service, error)) *Unit {
+	var hdlr *SocketHandler
+	return &Unit{
+		Name:  name,
+		Needs: needs,
+		Start: func(ctx context.Context) error {
+			srv, err := create(ctx)
+			if err != nil {
+				return err
+			}
+			h := NewSocketHandler(name, srv)
+			h.SetLimits(cfg.Limits)
+			h.SetSource(source)
+			if err = h.Start(ctx, cfg.Socket, cfg.Params); err != nil {
+				return err
+			}
+			hdlr = h
+			return nil
+		},
+		Stop: func() error {
+			h := hdlr
+			hdlr = nil
+			return h.Stop()
+		},
+	}
+}
+
+//----------------------------------------------------------------------
+// Commands "Services.List", "Services.Start", "Services.Stop" and
+// "Services.Restart"
+//----------------------------------------------------------------------
+
+// ServicesRPC is a type for JSON-RPC requests on supervised services.
+type ServicesRPC struct {
+	sup *Supervisor
+}
+
+// ServicesListRequest asks for the state of named services (all services
+// if the list is empty).
+type ServicesListRequest struct {
+	Services []string `json:"services"`
+}
+
+// ServicesListResponse lists the state of supervised services.
+type ServicesListResponse struct {
+	Services []UnitStatus `json:"services"`
+}
+
+// List returns the state of supervised services.
+func (s *ServicesRPC) List(r *http.Request, req *ServicesListRequest, reply *ServicesListResponse) error {
+	*reply = ServicesListResponse{Services: s.sup.Status(req.Services...)}
+	return nil
+}
+
+// ServiceRequest names the service to be stopped, started or restarted.
+type ServiceRequest struct {
+	Service string `json:"service"`
+}
+
+// ServiceResponse is the state of a service after the request.
+type ServiceResponse struct {
+	Status UnitStatus `json:"status"`
+}
+
+// Start a stopped service.
+func (s *ServicesRPC) Start(r *http.Request, req *ServiceRequest, reply *ServiceResponse) error {
+	return s.control(s.sup.Start, req, reply)
+}
+
+// Stop a running service.
+func (s *ServicesRPC) Stop(r *http.Request, req *ServiceRequest, reply *ServiceResponse) error {
+	return s.control(s.sup.Stop, req, reply)
+}
+
+// Restart a running service.
+func (s *ServicesRPC) Restart(r *http.Request, req *ServiceRequest, reply *ServiceResponse) error {
+	return s.control(s.sup.Restart, req, reply)
+}
+
+// control a service and report its new state.
+func (s *ServicesRPC) control(op func(string) error, req *ServiceRequest, reply *ServiceResponse) error {
+	if err := op(req.Service); err != nil {
+		return err
+	}
+	if st := s.sup.Status(req.Service); len(st) > 0 {
+		reply.Status = st[0]
+	}
+	return nil
+}
+
+// InitServicesRPC registers the RPC commands for supervised services.
+func InitServicesRPC(srv *JRPCServer, sup *Supervisor) {
+	if err := srv.RegisterService(&ServicesRPC{sup: sup}, "Services"); err != nil {
+		logger.Printf(logger.ERROR, "[supervisor] Failed to init RPC: %s", err.Error())
+	}
+}
@@ -0,0 +1,141 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSupervisor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewSupervisor(ctx)
+
+	// units record their state
+	running := make(map[string]context.Context)
+	newUnit := func(name string, needs ...string) *Unit {
+		return &Unit{
+			Name:  name,
+			Needs: needs,
+			Start: func(ctx context.Context) error {
+				running[name] = ctx
+				return nil
+			},
+			Stop: func() error {
+				delete(running, name)
+				return nil
+			},
+		}
+	}
+	for _, u := range []*Unit{
+		newUnit("core"),
+		newUnit("dht", "core"),
+		newUnit("namecache"),
+		newUnit("gns", "dht", "namecache"),
+	} {
+		if err := s.Add(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Add(newUnit("gns")); err != ErrUnitExists {
+		t.Fatalf("expected duplicate unit error, got %v", err)
+	}
+	if err := s.Add(newUnit("zonemaster", "identity")); !errors.Is(err, ErrUnitUnknown) {
+		t.Fatalf("expected unknown unit error, got %v", err)
+	}
+	// required units must be running
+	if err := s.Start("gns"); !errors.Is(err, ErrUnitNeeds) {
+		t.Fatalf("expected missing dependency error, got %v", err)
+	}
+	if err := s.StartAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(running) != 4 {
+		t.Fatalf("%d units running", len(running))
+	}
+	// required units can't be stopped or restarted
+	if err := s.Stop("dht"); !errors.Is(err, ErrUnitRequired) {
+		t.Fatalf("expected required unit error, got %v", err)
+	}
+	if err := s.Restart("namecache"); !errors.Is(err, ErrUnitRequired) {
+		t.Fatalf("expected required unit error, got %v", err)
+	}
+	// restart a unit: the old context is cancelled
+	old := running["gns"]
+	if err := s.Restart("gns"); err != nil {
+		t.Fatal(err)
+	}
+	if old.Err() == nil || running["gns"].Err() != nil {
+		t.Fatal("unit context not renewed")
+	}
+	if err := s.Start("gns"); err != ErrUnitRunning {
+		t.Fatalf("expected running unit error, got %v", err)
+	}
+	// stop dependent unit first
+	if err := s.Stop("gns"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop("namecache"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop("namecache"); err != ErrUnitStopped {
+		t.Fatalf("expected stopped unit error, got %v", err)
+	}
+	for _, st := range s.Status() {
+		want := st.Name == "core" || st.Name == "dht"
+		if st.Running != want {
+			t.Errorf("%s: running=%v", st.Name, st.Running)
+		}
+		if st.Name == "gns" && st.Starts != 2 {
+			t.Errorf("gns started %d times", st.Starts)
+		}
+	}
+	// stop all units (in reverse order)
+	s.StopAll()
+	if len(running) != 0 {
+		t.Fatalf("%d units still running", len(running))
+	}
+}
+
+func TestSupervisorStartFailure(t *testing.T) {
+	s := NewSupervisor(context.Background())
+	fail := errors.New("failed")
+	var unitCtx context.Context
+	if err := s.Add(&Unit{
+		Name: "broken",
+		Start: func(ctx context.Context) error {
+			unitCtx = ctx
+			return fail
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("broken"); err != fail {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	st := s.Status("broken")
+	if len(st) != 1 || st[0].Running || st[0].LastErr != "failed" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if unitCtx.Err() == nil {
+		t.Fatal("context of failed unit not cancelled")
+	}
+}
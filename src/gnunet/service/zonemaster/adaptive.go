@@ -0,0 +1,193 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"context"
+	"gnunet/config"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/store"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Adaptive republish intervals:
+// Before a published label is republished, the zonemaster probes the
+// DHT for its block. The observed availability (moving average of the
+// probe outcomes) drives the republish interval within the configured
+// bounds: the interval grows slowly while blocks are still found when
+// they are due and is cut in half if a block has gone missing. Stable
+// data is republished less often without dropping below the target.
+//----------------------------------------------------------------------
+
+// Adaptation parameters
+var (
+	AdaptiveGrow   = 1.25             // interval factor for a found block
+	AdaptiveShrink = 0.5              // interval factor for a missing block
+	AdaptiveWeight = 0.1              // weight of a probe in the moving average
+	ProbeTimeout   = 10 * time.Second // time-out for a DHT probe
+)
+
+// AdaptiveStats are the statistics of adaptive republishing.
+type AdaptiveStats struct {
+	Enabled      bool    `json:"enabled"`      // adaptive intervals configured
+	Interval     string  `json:"interval"`     // current republish interval
+	MinPeriod    string  `json:"minPeriod"`    // lower bound of interval
+	MaxPeriod    string  `json:"maxPeriod"`    // upper bound of interval
+	Target       float64 `json:"target"`       // availability target
+	Availability float64 `json:"availability"` // observed availability
+	Probes       int64   `json:"probes"`       // number of probes
+	Found        int64   `json:"found"`        // number of blocks found
+}
+
+// Adaptive controls the republish interval of labels.
+type Adaptive struct {
+	sync.Mutex
+
+	enabled  bool          // adaptive intervals configured
+	min, max time.Duration // bounds of interval
+	target   float64       // availability target
+	interval time.Duration // current interval
+	avail    float64       // observed availability (moving average)
+	probes   int64         // number of probes
+	found    int64         // number of blocks found
+}
+
+// NewAdaptive creates a controller for republish intervals that starts
+// with the given period. Without configuration (or with invalid bounds)
+// the period is fixed.
+func NewAdaptive(period time.Duration, cfg *config.AdaptiveConfig) *Adaptive {
+	a := &Adaptive{
+		min:      period,
+		max:      period,
+		interval: period,
+		avail:    1,
+	}
+	if cfg == nil || cfg.MinPeriod <= 0 || cfg.MaxPeriod < cfg.MinPeriod {
+		return a
+	}
+	a.enabled = true
+	a.min = time.Duration(cfg.MinPeriod) * time.Second
+	a.max = time.Duration(cfg.MaxPeriod) * time.Second
+	if a.target = cfg.Target; a.target <= 0 || a.target > 1 {
+		a.target = 1
+	}
+	a.interval = a.clamp(period)
+	return a
+}
+
+// Interval returns the current republish interval.
+func (a *Adaptive) Interval() time.Duration {
+	a.Lock()
+	defer a.Unlock()
+	return a.interval
+}
+
+// Enabled returns true if republish intervals are adapted.
+func (a *Adaptive) Enabled() bool {
+	return a.enabled
+}
+
+// Observe the outcome of a probe and adapt the interval: a missing block
+// always shortens the interval; a found block lengthens it only if the
+// availability meets the target.
+func (a *Adaptive) Observe(found bool) {
+	if !a.enabled {
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.probes++
+	v := 0.
+	if found {
+		a.found++
+		v = 1
+	}
+	a.avail += AdaptiveWeight * (v - a.avail)
+	switch {
+	case !found:
+		a.interval = a.clamp(time.Duration(float64(a.interval) * AdaptiveShrink))
+	case a.avail >= a.target:
+		a.interval = a.clamp(time.Duration(float64(a.interval) * AdaptiveGrow))
+	}
+}
+
+// Stats returns the statistics of adaptive republishing.
+func (a *Adaptive) Stats() *AdaptiveStats {
+	a.Lock()
+	defer a.Unlock()
+	return &AdaptiveStats{
+		Enabled:      a.enabled,
+		Interval:     a.interval.String(),
+		MinPeriod:    a.min.String(),
+		MaxPeriod:    a.max.String(),
+		Target:       a.target,
+		Availability: a.avail,
+		Probes:       a.probes,
+		Found:        a.found,
+	}
+}
+
+// clamp an interval to the configured bounds
+func (a *Adaptive) clamp(d time.Duration) time.Duration {
+	if d < a.min {
+		return a.min
+	}
+	if d > a.max {
+		return a.max
+	}
+	return d
+}
+
+//----------------------------------------------------------------------
+
+// probe checks if the published block of a label is still available in
+// the DHT and feeds the outcome to the interval controller. Probes that
+// fail for other reasons (DHT service not reachable, cycle cancelled)
+// are not counted.
+func (zm *ZoneMaster) probe(ctx context.Context, zone *store.Zone, label *store.Label) {
+	if !zm.adaptive.Enabled() || zm.LookupRemote == nil {
+		return
+	}
+	name, err := names.Normalize(label.Name)
+	if err != nil {
+		return
+	}
+	query := blocks.NewGNSQuery(zone.Key.Public(), name)
+	pctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+	blk, err := zm.LookupRemote(pctx, query)
+	if ctx.Err() != nil {
+		return
+	}
+	// a probe without result within the time-out counts as missing
+	if err != nil && pctx.Err() == nil {
+		logger.Printf(logger.DBG, "[zonemaster] probe for label '%s' not counted: %s", label.Name, err.Error())
+		return
+	}
+	found := err == nil && blk != nil
+	if !found {
+		logger.Printf(logger.INFO, "[zonemaster] Label '%s' no longer available in DHT", label.Name)
+	}
+	zm.adaptive.Observe(found)
+}
@@ -0,0 +1,76 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"gnunet/config"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveFixed(t *testing.T) {
+	a := NewAdaptive(5*time.Minute, nil)
+	a.Observe(false)
+	a.Observe(true)
+	if a.Enabled() || a.Interval() != 5*time.Minute {
+		t.Fatalf("fixed interval changed: %s", a.Interval())
+	}
+	if st := a.Stats(); st.Probes != 0 {
+		t.Fatalf("fixed interval counts probes: %d", st.Probes)
+	}
+}
+
+func TestAdaptiveBounds(t *testing.T) {
+	cfg := &config.AdaptiveConfig{
+		MinPeriod: 60,
+		MaxPeriod: 600,
+		Target:    0.9,
+	}
+	a := NewAdaptive(5*time.Minute, cfg)
+	if !a.Enabled() || a.Interval() != 5*time.Minute {
+		t.Fatalf("unexpected start interval %s", a.Interval())
+	}
+	// stable data: interval grows up to the upper bound
+	for i := 0; i < 20; i++ {
+		a.Observe(true)
+	}
+	if a.Interval() != 10*time.Minute {
+		t.Fatalf("interval not at upper bound: %s", a.Interval())
+	}
+	// missing blocks: interval shrinks down to the lower bound
+	a.Observe(false)
+	if a.Interval() != 5*time.Minute {
+		t.Fatalf("interval not halved: %s", a.Interval())
+	}
+	for i := 0; i < 5; i++ {
+		a.Observe(false)
+	}
+	if a.Interval() != time.Minute {
+		t.Fatalf("interval not at lower bound: %s", a.Interval())
+	}
+	// found blocks don't lengthen the interval below target availability
+	a.Observe(true)
+	if a.Interval() != time.Minute {
+		t.Fatalf("interval grew below target: %s", a.Interval())
+	}
+	st := a.Stats()
+	if st.Probes != 27 || st.Found != 21 || st.Availability >= cfg.Target {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,153 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gnunet/service/revocation"
+	"gnunet/service/store"
+	"gnunet/util"
+
+	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Key compromise:
+// A signed revocation for a zone key can be computed in advance (see
+// "revoke-zonekey") and stored in the zone database. If the private
+// zone key is compromised, the zone is marked as such: the revocation
+// is published immediately and the labels of the zone are no longer
+// published.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrNoRevocationService = errors.New("revocation service not configured")
+	ErrNoRevocation        = errors.New("no revocation stored for zone")
+	ErrRevocationInvalid   = errors.New("invalid revocation")
+	ErrRevocationRejected  = errors.New("revocation rejected by revocation service")
+	ErrConfirmMismatch     = errors.New("confirmation does not match zone key")
+)
+
+// SetRevocation stores a signed revocation (wire format) for a zone after
+// checking that it revokes the zone key.
+func (zm *ZoneMaster) SetRevocation(zone string, buf []byte) error {
+	z, err := zm.zoneByName(zone)
+	if err != nil {
+		return err
+	}
+	rd := new(revocation.RevData)
+	if err = data.Unmarshal(rd, buf); err != nil {
+		return fmt.Errorf("%w: %s", ErrRevocationInvalid, err.Error())
+	}
+	if !rd.ZoneKeySig.ZoneKey.Equal(z.Key.Public()) {
+		return fmt.Errorf("%w: zone key mismatch", ErrRevocationInvalid)
+	}
+	if _, rc := rd.Verify(true); rc != 0 {
+		return fmt.Errorf("%w: verification failed (%d)", ErrRevocationInvalid, rc)
+	}
+	r, err := zm.zdb.GetRevocation(z.ID)
+	if err != nil {
+		return err
+	}
+	r.Data = buf
+	logger.Printf(logger.INFO, "[zonemaster] Revocation for zone '%s' stored", zone)
+	return zm.zdb.SetRevocation(r)
+}
+
+// MarkCompromised marks a zone as compromised: publication of the zone
+// stops and the stored revocation is published. To guard against
+// accidental use, the zone key (ID) must be given as confirmation. If
+// publishing the revocation fails, the call can be repeated.
+func (zm *ZoneMaster) MarkCompromised(ctx context.Context, zone, confirm string) (r *store.Revocation, err error) {
+	var z *store.Zone
+	if z, err = zm.zoneByName(zone); err != nil {
+		return
+	}
+	if confirm != z.Key.Public().ID() {
+		return nil, ErrConfirmMismatch
+	}
+	if r, err = zm.zdb.GetRevocation(z.ID); err != nil {
+		return
+	}
+	if len(r.Data) == 0 {
+		return nil, ErrNoRevocation
+	}
+	rd := new(revocation.RevData)
+	if err = data.Unmarshal(rd, r.Data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRevocationInvalid, err.Error())
+	}
+	// stop publication first
+	if r.Compromised.Val == 0 {
+		r.Compromised = util.AbsoluteTimeNow()
+		if err = zm.zdb.SetRevocation(r); err != nil {
+			return
+		}
+		logger.Printf(logger.WARN, "[zonemaster] Zone '%s' marked as compromised", zone)
+	}
+	// publish revocation
+	if zm.Revoke == nil {
+		return r, ErrNoRevocationService
+	}
+	var ok bool
+	if ok, err = zm.Revoke(ctx, rd); err != nil {
+		return
+	}
+	if !ok {
+		return r, ErrRevocationRejected
+	}
+	if r.Published.Val == 0 {
+		r.Published = util.AbsoluteTimeNow()
+		if err = zm.zdb.SetRevocation(r); err != nil {
+			return
+		}
+	}
+	logger.Printf(logger.WARN, "[zonemaster] Revocation for zone '%s' published", zone)
+	return
+}
+
+// compromised returns true if a zone is marked as compromised.
+func (zm *ZoneMaster) compromised(zid int64) bool {
+	r, err := zm.zdb.GetRevocation(zid)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] revocation state of zone %d: %s", zid, err.Error())
+		return false
+	}
+	return r.Compromised.Val != 0
+}
+
+// zoneByName returns the zone with given name.
+func (zm *ZoneMaster) zoneByName(name string) (*store.Zone, error) {
+	if zm.zdb == nil {
+		return nil, ErrNoDatabase
+	}
+	zones, err := zm.zdb.GetZones("")
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zones {
+		if z.Name == name {
+			return z, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: '%s'", ErrUnknownZone, name)
+}
@@ -0,0 +1,143 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"context"
+	"errors"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/revocation"
+	"gnunet/service/store"
+	"gnunet/util"
+	"testing"
+	"time"
+
+	"github.com/bfix/gospel/data"
+)
+
+// newTestRevocation returns a signed revocation (wire format) for a zone
+// key. The PoWs don't meet any difficulty.
+func newTestRevocation(t *testing.T, zp *crypto.ZonePrivate) []byte {
+	t.Helper()
+	rd := &revocation.RevData{
+		Timestamp:  util.AbsoluteTimeNow(),
+		PoWs:       make([]uint64, 32),
+		ZoneKeySig: &crypto.ZoneSignature{ZoneKey: *zp.Public()},
+	}
+	for i := range rd.PoWs {
+		rd.PoWs[i] = uint64(i + 1)
+	}
+	if err := rd.Sign(zp); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := data.Marshal(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestMarkCompromised(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+
+	// zone with a single label
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	lbl := store.NewLabel("www")
+	if err = lbl.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	if err = zdb.SetLabel(lbl); err != nil {
+		t.Fatal(err)
+	}
+	rec := store.NewRecord(util.AbsoluteTimeNow().Add(time.Hour), enums.GNS_TYPE_DNS_TXT, 0, []byte("test"))
+	rec.Label = lbl.ID
+	if err = zdb.SetRecord(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	// zonemaster with stubbed revocation service
+	revoked := 0
+	zm := &ZoneMaster{zdb: zdb, adaptive: NewAdaptive(time.Hour, nil)}
+	zm.Revoke = func(_ context.Context, rd *revocation.RevData) (bool, error) {
+		if !rd.ZoneKeySig.ZoneKey.Equal(zp.Public()) {
+			t.Fatal("wrong zone key revoked")
+		}
+		revoked++
+		return true, nil
+	}
+	ctx := context.Background()
+	zkey := zp.Public().ID()
+
+	// no revocation stored; revocation for other zone is rejected
+	if _, err = zm.MarkCompromised(ctx, "test", zkey); !errors.Is(err, ErrNoRevocation) {
+		t.Fatalf("expected missing revocation, got %v", err)
+	}
+	other, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = zm.SetRevocation("test", newTestRevocation(t, other)); !errors.Is(err, ErrRevocationInvalid) {
+		t.Fatalf("expected invalid revocation, got %v", err)
+	}
+	if err = zm.SetRevocation("test", newTestRevocation(t, zp)); err != nil {
+		t.Fatal(err)
+	}
+	// guarded operation: confirmation must match
+	if _, err = zm.MarkCompromised(ctx, "test", "wrong"); !errors.Is(err, ErrConfirmMismatch) {
+		t.Fatalf("expected confirmation mismatch, got %v", err)
+	}
+	r, err := zm.MarkCompromised(ctx, "test", zkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 1 || r.Compromised.Val == 0 || r.Published.Val == 0 {
+		t.Fatalf("revocation not published: %d, %+v", revoked, r)
+	}
+	// compromised zone is no longer published (skipped before the block
+	// is stored in the DHT)
+	if err = zm.PublishZoneLabel(ctx, zone, lbl); err != nil {
+		t.Fatal(err)
+	}
+	p, err := zdb.GetPublication(lbl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Published.Val != 0 {
+		t.Fatal("compromised zone published")
+	}
+	rpt, err := zm.Report(ctx, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr := rpt.Zones[0]; zr.OK || len(zr.Revoked) == 0 {
+		t.Fatalf("compromised zone not reported: %+v", zr)
+	}
+}
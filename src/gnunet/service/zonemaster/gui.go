@@ -24,9 +24,9 @@ import (
 	"embed"
 	"errors"
 	"fmt"
-	"gnunet/config"
 	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/service/gns/names"
 	"gnunet/service/gns/rr"
 	"gnunet/service/store"
 	"gnunet/util"
@@ -165,9 +165,10 @@ func (zm *ZoneMaster) startGUI(ctx context.Context) {
 	router.HandleFunc("/edit/{mode}/{id}", zm.edit)
 	router.HandleFunc("/del/{mode}/{id}", zm.remove)
 	router.HandleFunc("/action/{cmd}/{mode}/{id}", zm.action)
+	router.HandleFunc("/api/report", zm.report)
 	router.HandleFunc("/", zm.dashboard)
 	srv := &http.Server{
-		Addr:              config.Cfg.ZoneMaster.GUI,
+		Addr:              zm.cfg.ZoneMaster.GUI,
 		ReadTimeout:       10 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
 		Handler:           router,
@@ -271,7 +272,10 @@ func (zm *ZoneMaster) actionNew(w http.ResponseWriter, r *http.Request, mode str
 
 	// new label
 	case "label":
-		name := r.FormValue("name")
+		var name string
+		if name, err = names.Normalize(r.FormValue("name")); err != nil {
+			return
+		}
 		// get zone
 		var zone *store.Zone
 		if zone, err = zm.zdb.GetZone(id); err != nil {
@@ -314,9 +318,17 @@ func (zm *ZoneMaster) newRec(w http.ResponseWriter, r *http.Request, label int64
 	exp, flags := guiParse(params, pf)
 	rrdata, err := Map2RRData(t, params)
 	if err == nil {
-		// assemble record and store in database
+		// assemble record and check size of label
 		rr := store.NewRecord(exp, t, flags, rrdata)
 		rr.Label = label
+		if err = zm.CheckLabelSize(label, rr); err != nil {
+			return err
+		}
+		// check and update label version
+		if err = zm.bumpVersion(label, params["lver"]); err != nil {
+			return err
+		}
+		// store record in database
 		err = zm.zdb.SetRecord(rr)
 
 		// notify listeners
@@ -347,7 +359,11 @@ func (zm *ZoneMaster) actionUpd(w http.ResponseWriter, r *http.Request, mode str
 
 	case "label":
 		// update label name
-		label := store.NewLabel(r.FormValue("name"))
+		var name string
+		if name, err = names.Normalize(r.FormValue("name")); err != nil {
+			return
+		}
+		label := store.NewLabel(name)
 		label.ID = id
 		label.Modified = util.AbsoluteTimeNow()
 		err = zm.zdb.SetLabel(label)
@@ -403,6 +419,14 @@ func (zm *ZoneMaster) updRec(w http.ResponseWriter, r *http.Request, id int64) e
 		rec := store.NewRecord(exp, t, flags, rrData)
 		rec.ID = id
 		rec.Label, _ = util.CastFromString[int64](newParams["lid"])
+		if err = zm.CheckLabelSize(rec.Label, rec); err != nil {
+			return err
+		}
+
+		// check and update label version
+		if err = zm.bumpVersion(rec.Label, newParams["lver"]); err != nil {
+			return err
+		}
 
 		// update in database
 		if err := zm.zdb.SetRecord(rec); err != nil {
@@ -517,9 +541,14 @@ func (zm *ZoneMaster) new(w http.ResponseWriter, r *http.Request) {
 				data.RRspecs = compatibleRR(rrs, label)
 				templ = "new_record"
 			}
+			var lbl *store.Label
+			if lbl, err = zm.zdb.GetLabel(id); err != nil {
+				break
+			}
 			data.Ref = id
 			data.Params["label"] = label
 			data.Params["lid"] = util.CastToString(id)
+			data.Params["lver"] = util.CastToString(lbl.Version)
 			renderPage(w, data, templ)
 			return
 		}
@@ -649,8 +678,13 @@ func (zm *ZoneMaster) editRec(w http.ResponseWriter, r *http.Request, data *NewE
 	data.Params["type"] = util.CastToString(int(rec.RType))
 	data.Params["created"] = guiTime(rec.Created)
 	data.Params["modified"] = guiTime(rec.Modified)
-	data.Params["label"], _ = zm.zdb.GetName("labels", rec.Label)
+	var lbl *store.Label
+	if lbl, err = zm.zdb.GetLabel(rec.Label); err != nil {
+		return
+	}
+	data.Params["label"] = lbl.Name
 	data.Params["lid"] = util.CastToString(rec.Label)
+	data.Params["lver"] = util.CastToString(lbl.Version)
 	if rec.Flags&enums.GNS_FLAG_RELATIVE_EXPIRATION != 0 {
 		data.Params[pf+"ttl"] = "on"
 		data.Params[pf+"ttl_value"] = guiDuration(rec.Expire)
@@ -731,7 +765,14 @@ func (zm *ZoneMaster) remove(w http.ResponseWriter, r *http.Request) {
 
 		// remove resource record
 		case "rr":
-			rec := new(store.Record)
+			var rec *store.Record
+			if rec, err = zm.zdb.GetRecord(id); err != nil {
+				break
+			}
+			// check and update label version
+			if err = zm.bumpVersion(rec.Label, r.URL.Query().Get("ver")); err != nil {
+				break
+			}
 			rec.ID = id
 			rec.Label = 0
 			if err = zm.zdb.SetRecord(rec); err != nil {
@@ -753,6 +794,18 @@ func (zm *ZoneMaster) remove(w http.ResponseWriter, r *http.Request) {
 // Helper methods
 //======================================================================
 
+// bumpVersion increments the version of a label record set if the version
+// (as shown in the GUI dialog) is still current. Edits based on an outdated
+// version of the record set are rejected.
+func (zm *ZoneMaster) bumpVersion(lid int64, version string) error {
+	ver, ok := util.CastFromString[int64](version)
+	if !ok {
+		return errors.New("missing label version")
+	}
+	_, err := zm.zdb.UpdateLabelVersion(lid, ver)
+	return err
+}
+
 // MainData for the template "main"
 type MainData struct {
 	Content string // Page content
@@ -0,0 +1,154 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"errors"
+	"gnunet/service/store"
+	"gnunet/util"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Publication journal:
+// The outcome of each label publication is kept in the zone database,
+// so a restarted zonemaster only publishes labels that are due (instead
+// of starting a new cycle) and the publication health of zones can be
+// reported.
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrNoDatabase = errors.New("zone database not available")
+)
+
+// publication period of labels
+func (zm *ZoneMaster) publishPeriod() time.Duration {
+	return time.Duration(zm.cfg.ZoneMaster.Period) * time.Second
+}
+
+// isDue returns true if a label should be published in the current cycle:
+// labels that would be late in the next cycle are published early.
+func (zm *ZoneMaster) isDue(p *store.Publication, now util.AbsoluteTime) bool {
+	return p.Next.Compare(now.Add(zm.publishPeriod()/2)) <= 0
+}
+
+// isOverdue returns true if a label has missed a cycle.
+func (zm *ZoneMaster) isOverdue(p *store.Publication, now util.AbsoluteTime) bool {
+	return p.Next.Add(zm.publishPeriod()).Compare(now) < 0
+}
+
+// journal records the outcome of a label publication.
+func (zm *ZoneMaster) journal(lid int64, published bool, err error) {
+	p, jErr := zm.zdb.GetPublication(lid)
+	if jErr != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] journal for label %d: %s", lid, jErr.Error())
+		return
+	}
+	now := util.AbsoluteTimeNow()
+	p.Attempted = now
+	if err != nil {
+		// failed labels are due again in the next cycle
+		p.Failures++
+		p.Error = err.Error()
+		p.Next = now
+	} else {
+		if published {
+			p.Published = now
+		}
+		p.Failures = 0
+		p.Error = ""
+		p.Next = now.Add(zm.adaptive.Interval())
+	}
+	if jErr = zm.zdb.SetPublication(p); jErr != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] journal for label %d: %s", lid, jErr.Error())
+	}
+}
+
+//----------------------------------------------------------------------
+
+// LabelStatus is the publication status of a label
+type LabelStatus struct {
+	Zone      string `json:"zone"`
+	Label     string `json:"label"`
+	Published string `json:"published,omitempty"` // time of last publication
+	Next      string `json:"next"`                // time label is due
+	Failures  int    `json:"failures"`            // failed attempts since last success
+	Error     string `json:"error,omitempty"`     // last error
+}
+
+// Health is a summary of the publication state of all zones
+type Health struct {
+	Labels      int            `json:"labels"`      // number of labels
+	Unpublished int            `json:"unpublished"` // labels never published
+	Overdue     []*LabelStatus `json:"overdue"`     // labels that missed a cycle
+	Failing     []*LabelStatus `json:"failing"`     // labels with failed attempts
+}
+
+// Health reports the publication state of all zones.
+func (zm *ZoneMaster) Health() (h *Health, err error) {
+	if zm.zdb == nil {
+		return nil, ErrNoDatabase
+	}
+	h = new(Health)
+	zones, err := zm.zdb.GetZones("")
+	if err != nil {
+		return
+	}
+	now := util.AbsoluteTimeNow()
+	for _, z := range zones {
+		var labels []*store.Label
+		if labels, err = zm.zdb.GetLabels("zid=%d", z.ID); err != nil {
+			return
+		}
+		for _, l := range labels {
+			var p *store.Publication
+			if p, err = zm.zdb.GetPublication(l.ID); err != nil {
+				return
+			}
+			h.Labels++
+			if p.Published.Val == 0 {
+				h.Unpublished++
+			}
+			overdue, failing := zm.isOverdue(p, now), p.Failures > 0
+			if !overdue && !failing {
+				continue
+			}
+			ls := &LabelStatus{
+				Zone:     z.Name,
+				Label:    l.Name,
+				Next:     p.Next.String(),
+				Failures: p.Failures,
+				Error:    p.Error,
+			}
+			if p.Published.Val != 0 {
+				ls.Published = p.Published.String()
+			}
+			if overdue {
+				h.Overdue = append(h.Overdue, ls)
+			}
+			if failing {
+				h.Failing = append(h.Failing, ls)
+			}
+		}
+	}
+	return
+}
@@ -25,6 +25,7 @@ import (
 	"gnunet/enums"
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/revocation"
 )
 
 //======================================================================
@@ -36,8 +37,10 @@ type Module struct {
 	service.ModuleImpl
 
 	// Use function references for calls to methods in other modules:
-	StoreLocal  func(ctx context.Context, query *blocks.GNSQuery, block *blocks.GNSBlock) error
-	StoreRemote func(ctx context.Context, query blocks.Query, block blocks.Block) error
+	StoreLocal   func(ctx context.Context, query *blocks.GNSQuery, block *blocks.GNSBlock) error
+	StoreRemote  func(ctx context.Context, query blocks.Query, block blocks.Block) error
+	LookupRemote func(ctx context.Context, query blocks.Query) (blocks.Block, error)
+	Revoke       func(ctx context.Context, rd *revocation.RevData) (success bool, err error)
 }
 
 // NewModule instantiates a new GNS module.
@@ -47,7 +50,7 @@ func NewModule(ctx context.Context, c *core.Core) (m *Module) {
 	}
 	if c != nil {
 		// register as listener for core events
-		listener := m.ModuleImpl.Run(ctx, m.event, m.Filter(), 0, nil)
+		listener := m.ModuleImpl.Run(ctx, m.event, m.Filter())
 		c.Register("zonemaster", listener)
 	}
 	return
@@ -79,6 +82,8 @@ func (m *Module) Import(fcn map[string]any) {
 	// resolve imports from other modules
 	m.StoreLocal, _ = fcn["namecache:put"].(func(ctx context.Context, query *blocks.GNSQuery, block *blocks.GNSBlock) error)
 	m.StoreRemote, _ = fcn["dht:put"].(func(ctx context.Context, query blocks.Query, block blocks.Block) error)
+	m.LookupRemote, _ = fcn["dht:get"].(func(ctx context.Context, query blocks.Query) (blocks.Block, error))
+	m.Revoke, _ = fcn["rev:revoke"].(func(ctx context.Context, rd *revocation.RevData) (success bool, err error))
 }
 
 //----------------------------------------------------------------------
@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/bfix/gospel/data"
+	"github.com/bfix/gospel/logger"
 )
 
 var (
@@ -385,6 +386,9 @@ func (zm *ZoneMaster) GetRecordSet(label int64, filter enums.GNSFilter) (rs *blo
 		if filter&enums.GNS_FILTER_OMIT_PRIVATE != 0 && r.Flags&enums.GNS_FLAG_PRIVATE != 0 {
 			continue
 		}
+		if filter&enums.GNS_FILTER_INCLUDE_MAINTENANCE == 0 && r.RType == enums.GNS_TYPE_TOMBSTONE {
+			continue
+		}
 		// skip TTL expiry when determining earliest expiry
 		if r.Flags&enums.GNS_FLAG_RELATIVE_EXPIRATION == 0 && r.Expire.Compare(expire) < 0 {
 			expire = r.Expire
@@ -394,3 +398,93 @@ func (zm *ZoneMaster) GetRecordSet(label int64, filter enums.GNSFilter) (rs *blo
 	// do not add padding yet as record set may be filtered before use.
 	return
 }
+
+// CheckLabelSize returns an error naming the label if its records (with
+// new or changed records) don't fit into a GNS block. Stored records are
+// replaced by changed records with the same ID.
+func (zm *ZoneMaster) CheckLabelSize(label int64, recs ...*store.Record) error {
+	return zm.checkLabelSize(zm.zdb, label, recs...)
+}
+
+// checkLabelSize for a label in the given database (or transaction).
+func (zm *ZoneMaster) checkLabelSize(db *store.ZoneDB, label int64, recs ...*store.Record) error {
+	stored, err := db.GetRecords("lid=%d", label)
+	if err != nil {
+		return err
+	}
+	changed := make(map[int64]bool)
+	for _, r := range recs {
+		if r.ID != 0 {
+			changed[r.ID] = true
+		}
+	}
+	rs := blocks.NewRecordSet()
+	for _, r := range append(stored, recs...) {
+		if r.RType == enums.GNS_TYPE_TOMBSTONE || (changed[r.ID] && !contains(recs, r)) {
+			continue
+		}
+		rs.AddRecord(&r.ResourceRecord)
+	}
+	if err = rs.CheckSize(); err != nil {
+		name := util.CastToString(label)
+		if lbl, lErr := db.GetLabel(label); lErr == nil {
+			name = lbl.Name
+		}
+		return fmt.Errorf("label '%s': %w", name, err)
+	}
+	return nil
+}
+
+// contains returns true if the record is in the list.
+func contains(list []*store.Record, rec *store.Record) bool {
+	for _, r := range list {
+		if r == rec {
+			return true
+		}
+	}
+	return false
+}
+
+//----------------------------------------------------------------------
+// Default record lifetimes
+//----------------------------------------------------------------------
+
+// defaultTTLs parses the configured default lifetimes of records (seconds
+// by type name) into relative expirations by record type. The entry for
+// "*" is used for all other types and stored under GNS_TYPE_ANY.
+func defaultTTLs(cfg map[string]int) map[enums.GNSType]uint64 {
+	ttls := make(map[enums.GNSType]uint64)
+	for name, secs := range cfg {
+		if secs <= 0 {
+			logger.Printf(logger.WARN, "[zonemaster] invalid default TTL %d for '%s' -- ignored", secs, name)
+			continue
+		}
+		t := enums.GNS_TYPE_ANY
+		if name != "*" {
+			var err error
+			if t, err = rr.ParseType(name); err != nil || t == enums.GNS_TYPE_ANY {
+				logger.Printf(logger.WARN, "[zonemaster] unknown record type '%s' for default TTL -- ignored", name)
+				continue
+			}
+		}
+		ttls[t] = uint64((time.Duration(secs) * time.Second).Microseconds())
+	}
+	return ttls
+}
+
+// applyDefaultTTL sets the configured default lifetime (as relative
+// expiration) on a record stored without expiration. Records of types
+// without a configured default are left unchanged.
+func (zm *ZoneMaster) applyDefaultTTL(rec *store.Record) {
+	if rec.Expire.Val != 0 {
+		return
+	}
+	ttl, ok := zm.ttls[rec.RType]
+	if !ok {
+		if ttl, ok = zm.ttls[enums.GNS_TYPE_ANY]; !ok {
+			return
+		}
+	}
+	rec.Expire = util.AbsoluteTime{Val: ttl}
+	rec.Flags |= enums.GNS_FLAG_RELATIVE_EXPIRATION
+}
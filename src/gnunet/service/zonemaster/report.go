@@ -0,0 +1,298 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/gns/rr"
+	"gnunet/service/store"
+	"gnunet/util"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Zone status report:
+// A summary per zone for zone operators -- are the names of a zone
+// actually resolvable? The report lists the labels of a zone that can't
+// be published (validation errors), records that are about to expire and
+// the outcome of lookups of published labels in the DHT (spot-checks).
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrUnknownZone = errors.New("unknown zone")
+)
+
+// Report parameters
+var (
+	ReportExpiry = 24 * time.Hour // blocks expiring within are reported
+	ReportChecks = 3              // default number of spot-checks per zone
+)
+
+// LabelIssue is a problem with a label in a zone report
+type LabelIssue struct {
+	Label string `json:"label"`
+	Issue string `json:"issue"`
+}
+
+// LabelExpiry is a label with a block that expires soon
+type LabelExpiry struct {
+	Label  string `json:"label"`
+	Expire string `json:"expire"` // expiration of block
+}
+
+// SpotCheck is the outcome of a DHT lookup for a published label
+type SpotCheck struct {
+	Label string `json:"label"`
+	Found bool   `json:"found"`
+	Error string `json:"error,omitempty"` // lookup failed (not just missing)
+	Took  string `json:"took"`            // duration of lookup
+}
+
+// ZoneReport summarizes the state of a zone.
+type ZoneReport struct {
+	Zone      string         `json:"zone"`
+	Key       string         `json:"key"`                 // zone key (ID)
+	Labels    int            `json:"labels"`              // number of labels
+	Published string         `json:"published,omitempty"` // time of last publication
+	Expiring  []*LabelExpiry `json:"expiring"`            // blocks expiring soon
+	Invalid   []*LabelIssue  `json:"invalid"`             // labels failing validation
+	Checks    []*SpotCheck   `json:"checks"`              // DHT spot-checks
+	Revoked   string         `json:"revoked,omitempty"`   // time zone was marked compromised
+	OK        bool           `json:"ok"`                  // no issues found
+}
+
+// Report of the state of zones
+type Report struct {
+	Created string        `json:"created"`
+	Zones   []*ZoneReport `json:"zones"`
+}
+
+// Report on the state of a zone (or of all zones if the name is empty).
+// Up to 'checks' randomly selected published labels of each zone are
+// looked up in the DHT.
+func (zm *ZoneMaster) Report(ctx context.Context, zone string, checks int) (rpt *Report, err error) {
+	if zm.zdb == nil {
+		return nil, ErrNoDatabase
+	}
+	var zones []*store.Zone
+	if len(zone) > 0 {
+		var z *store.Zone
+		if z, err = zm.zoneByName(zone); err != nil {
+			return
+		}
+		zones = []*store.Zone{z}
+	} else if zones, err = zm.zdb.GetZones(""); err != nil {
+		return
+	}
+	now := util.AbsoluteTimeNow()
+	rpt = &Report{
+		Created: now.String(),
+		Zones:   make([]*ZoneReport, 0, len(zones)),
+	}
+	for _, z := range zones {
+		var zr *ZoneReport
+		if zr, err = zm.zoneReport(ctx, z, checks, now); err != nil {
+			return
+		}
+		rpt.Zones = append(rpt.Zones, zr)
+	}
+	return
+}
+
+// zoneReport assembles the report for a single zone.
+func (zm *ZoneMaster) zoneReport(ctx context.Context, z *store.Zone, checks int, now util.AbsoluteTime) (zr *ZoneReport, err error) {
+	zr = &ZoneReport{
+		Zone:     z.Name,
+		Key:      z.Key.Public().ID(),
+		Expiring: make([]*LabelExpiry, 0),
+		Invalid:  make([]*LabelIssue, 0),
+		Checks:   make([]*SpotCheck, 0),
+	}
+	var labels []*store.Label
+	if labels, err = zm.zdb.GetLabels("zid=%d", z.ID); err != nil {
+		return
+	}
+	zr.Labels = len(labels)
+	var r *store.Revocation
+	if r, err = zm.zdb.GetRevocation(z.ID); err != nil {
+		return
+	}
+	if r.Compromised.Val != 0 {
+		zr.Revoked = r.Compromised.String()
+	}
+	var (
+		last      util.AbsoluteTime
+		published []*store.Label
+	)
+	for _, l := range labels {
+		var p *store.Publication
+		if p, err = zm.zdb.GetPublication(l.ID); err != nil {
+			return
+		}
+		if p.Published.Val != 0 {
+			published = append(published, l)
+			if p.Published.Compare(last) > 0 {
+				last = p.Published
+			}
+		}
+		// check records of label
+		var (
+			rrSet  *blocks.RecordSet
+			expire util.AbsoluteTime
+		)
+		if rrSet, expire, err = zm.GetRecordSet(l.ID, enums.GNS_FILTER_NONE); err != nil {
+			return
+		}
+		if rrSet.Count == 0 {
+			continue
+		}
+		if issue := validateLabel(l.Name, rrSet); issue != nil {
+			zr.Invalid = append(zr.Invalid, &LabelIssue{Label: l.Name, Issue: issue.Error()})
+		}
+		if !expire.IsNever() && expire.Compare(now.Add(ReportExpiry)) < 0 {
+			zr.Expiring = append(zr.Expiring, &LabelExpiry{Label: l.Name, Expire: expire.String()})
+		}
+	}
+	if last.Val != 0 {
+		zr.Published = last.String()
+	}
+	// look up randomly selected published labels
+	rand.Shuffle(len(published), func(i, j int) { //nolint:gosec // no crypto
+		published[i], published[j] = published[j], published[i]
+	})
+	if checks < len(published) {
+		published = published[:checks]
+	}
+	zr.Checks = zm.spotChecks(ctx, z, published)
+
+	zr.OK = len(zr.Revoked) == 0 && len(zr.Invalid) == 0 && len(zr.Expiring) == 0
+	for _, sc := range zr.Checks {
+		zr.OK = zr.OK && sc.Found
+	}
+	return
+}
+
+// validateLabel returns an error if the records of a label can't be
+// published: invalid label name, records exceeding the block size or
+// conflicting record types.
+func validateLabel(label string, rrSet *blocks.RecordSet) error {
+	if _, err := names.Normalize(label); err != nil {
+		return err
+	}
+	if err := rrSet.CheckSize(); err != nil {
+		return err
+	}
+	for i, rec := range rrSet.Records {
+		others := make([]*enums.GNSSpec, 0, len(rrSet.Records)-1)
+		for j, r := range rrSet.Records {
+			if i != j {
+				others = append(others, &enums.GNSSpec{Type: r.RType, Flags: r.Flags})
+			}
+		}
+		if ok, _ := rr.CanCoexist(rec.RType, others, label); !ok {
+			return fmt.Errorf("record of type %s conflicts with other records", rec.RType)
+		}
+	}
+	return nil
+}
+
+// spotChecks looks up the blocks of labels in the DHT (in parallel).
+func (zm *ZoneMaster) spotChecks(ctx context.Context, z *store.Zone, labels []*store.Label) []*SpotCheck {
+	list := make([]*SpotCheck, len(labels))
+	if zm.LookupRemote == nil {
+		return list[:0]
+	}
+	zk := z.Key.Public()
+	var wg sync.WaitGroup
+	for i, l := range labels {
+		sc := &SpotCheck{Label: l.Name}
+		list[i] = sc
+		name, err := names.Normalize(l.Name)
+		if err != nil {
+			sc.Error = err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			cctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+			defer cancel()
+			blk, err := zm.LookupRemote(cctx, blocks.NewGNSQuery(zk, name))
+			sc.Took = time.Since(start).Round(time.Millisecond).String()
+			switch {
+			// no result within the time-out: block is missing
+			case err != nil && cctx.Err() != nil && ctx.Err() == nil:
+			case err != nil:
+				sc.Error = err.Error()
+			default:
+				sc.Found = blk != nil
+			}
+		}()
+	}
+	wg.Wait()
+	return list
+}
+
+//----------------------------------------------------------------------
+
+// report handles the REST request "GET /api/report": the optional query
+// parameters are 'zone' (name of zone) and 'checks' (number of spot-checks
+// per zone).
+func (zm *ZoneMaster) report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	checks := ReportChecks
+	if v := r.URL.Query().Get("checks"); len(v) > 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid number of checks", http.StatusBadRequest)
+			return
+		}
+		checks = n
+	}
+	rpt, err := zm.Report(r.Context(), r.URL.Query().Get("zone"), checks)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownZone) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(rpt); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] zone report: %s", err.Error())
+	}
+}
@@ -0,0 +1,147 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestZoneReport(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+
+	// zone with a healthy, an expiring, an invalid and a missing label
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	now := util.AbsoluteTimeNow()
+	week := now.Add(7 * 24 * time.Hour)
+	addLabel := func(name string, published bool, recs ...*store.Record) {
+		lbl := store.NewLabel(name)
+		if err := lbl.SetZone(zone); err != nil {
+			t.Fatal(err)
+		}
+		if err := zdb.SetLabel(lbl); err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range recs {
+			rec.Label = lbl.ID
+			if err := zdb.SetRecord(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if published {
+			if err := zdb.SetPublication(&store.Publication{Label: lbl.ID, Published: now, Next: week}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	txt := func(exp util.AbsoluteTime) *store.Record {
+		return store.NewRecord(exp, enums.GNS_TYPE_DNS_TXT, 0, []byte("test"))
+	}
+	addLabel("ok", true, txt(week))
+	addLabel("soon", false, txt(now.Add(time.Hour)))
+	addLabel("bad", false, txt(week), store.NewRecord(week, enums.GNS_TYPE_PKEY, 0, util.NewRndArray(36)))
+	addLabel("gone", true, txt(week))
+
+	// DHT lookups: only label "ok" is found
+	okQuery := blocks.NewGNSQuery(zp.Public(), "ok")
+	zm := &ZoneMaster{zdb: zdb}
+	zm.LookupRemote = func(ctx context.Context, query blocks.Query) (blocks.Block, error) {
+		if query.Key().Equal(okQuery.Key()) {
+			return blocks.NewGNSBlock(), nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	timeout := ProbeTimeout
+	ProbeTimeout = 100 * time.Millisecond
+	defer func() { ProbeTimeout = timeout }()
+
+	rpt, err := zm.Report(context.Background(), "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rpt.Zones) != 1 {
+		t.Fatalf("expected one zone, got %d", len(rpt.Zones))
+	}
+	zr := rpt.Zones[0]
+	if zr.OK || zr.Labels != 4 || len(zr.Published) == 0 {
+		t.Fatalf("unexpected zone summary %+v", zr)
+	}
+	if len(zr.Expiring) != 1 || zr.Expiring[0].Label != "soon" {
+		t.Fatalf("unexpected expiring labels %+v", zr.Expiring)
+	}
+	if len(zr.Invalid) != 1 || zr.Invalid[0].Label != "bad" {
+		t.Fatalf("unexpected invalid labels %+v", zr.Invalid)
+	}
+	if len(zr.Checks) != 2 {
+		t.Fatalf("expected two spot-checks, got %d", len(zr.Checks))
+	}
+	for _, sc := range zr.Checks {
+		if sc.Found != (sc.Label == "ok") || len(sc.Error) > 0 {
+			t.Fatalf("unexpected spot-check %+v", sc)
+		}
+	}
+	// limit number of spot-checks; unknown zone
+	if rpt, err = zm.Report(context.Background(), "test", 1); err != nil || len(rpt.Zones[0].Checks) != 1 {
+		t.Fatalf("spot-checks not limited: %v", err)
+	}
+	if _, err = zm.Report(context.Background(), "none", 0); !errors.Is(err, ErrUnknownZone) {
+		t.Fatalf("expected unknown zone, got %v", err)
+	}
+
+	// REST endpoint
+	rec := httptest.NewRecorder()
+	zm.report(rec, httptest.NewRequest(http.MethodGet, "/api/report?zone=test&checks=0", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	rpt = new(Report)
+	if err = json.Unmarshal(rec.Body.Bytes(), rpt); err != nil {
+		t.Fatal(err)
+	}
+	if len(rpt.Zones) != 1 || len(rpt.Zones[0].Checks) != 0 || len(rpt.Zones[0].Invalid) != 1 {
+		t.Fatalf("unexpected REST report %+v", rpt.Zones[0])
+	}
+	rec = httptest.NewRecorder()
+	zm.report(rec, httptest.NewRequest(http.MethodGet, "/api/report?zone=none", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown zone: unexpected status %d", rec.Code)
+	}
+}
@@ -18,7 +18,147 @@
 
 package zonemaster
 
-import "gnunet/service"
+import (
+	"gnunet/service"
+	"net/http"
 
-func (zm *ZoneMaster) InitRPC(rpc *service.JRPCServer) {
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+
+// RPCService is a type for zonemaster-related JSON-RPC requests
+type RPCService struct {
+	zm *ZoneMaster // reference to zonemaster
+}
+
+//----------------------------------------------------------------------
+// Command "ZoneMaster.Health"
+//----------------------------------------------------------------------
+
+// HealthRequest asks for the publication state of all zones
+type HealthRequest struct{}
+
+// HealthResponse lists overdue and failing labels.
+type HealthResponse struct {
+	Health
+}
+
+// Health returns the publication state of all zones.
+func (s *RPCService) Health(r *http.Request, req *HealthRequest, reply *HealthResponse) error {
+	h, err := s.zm.Health()
+	if err != nil {
+		return err
+	}
+	*reply = HealthResponse{Health: *h}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "ZoneMaster.Adaptive"
+//----------------------------------------------------------------------
+
+// AdaptiveRequest asks for the statistics of adaptive republishing
+type AdaptiveRequest struct{}
+
+// AdaptiveResponse holds the current republish interval and the observed
+// availability of published blocks.
+type AdaptiveResponse struct {
+	AdaptiveStats
+}
+
+// Adaptive returns the statistics of adaptive republishing.
+func (s *RPCService) Adaptive(r *http.Request, req *AdaptiveRequest, reply *AdaptiveResponse) error {
+	*reply = AdaptiveResponse{AdaptiveStats: *s.zm.adaptive.Stats()}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "ZoneMaster.Report"
+//----------------------------------------------------------------------
+
+// ReportRequest asks for a status report of a zone (or all zones if no
+// zone name is given). Checks is the number of DHT spot-checks per zone
+// (default if negative).
+type ReportRequest struct {
+	Zone   string `json:"zone,omitempty"`
+	Checks int    `json:"checks"`
+}
+
+// ReportResponse holds the status reports of zones.
+type ReportResponse struct {
+	Report
+}
+
+// Report returns the status report of zones.
+func (s *RPCService) Report(r *http.Request, req *ReportRequest, reply *ReportResponse) error {
+	checks := req.Checks
+	if checks < 0 {
+		checks = ReportChecks
+	}
+	rpt, err := s.zm.Report(r.Context(), req.Zone, checks)
+	if err != nil {
+		return err
+	}
+	*reply = ReportResponse{Report: *rpt}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Command "ZoneMaster.SetRevocation"
+//----------------------------------------------------------------------
+
+// SetRevocationRequest stores a signed revocation (wire format, base64
+// in JSON) for a zone.
+type SetRevocationRequest struct {
+	Zone       string `json:"zone"`
+	Revocation []byte `json:"revocation"`
+}
+
+// SetRevocationResponse is empty (success).
+type SetRevocationResponse struct{}
+
+// SetRevocation stores a signed revocation for a zone.
+func (s *RPCService) SetRevocation(r *http.Request, req *SetRevocationRequest, reply *SetRevocationResponse) error {
+	return s.zm.SetRevocation(req.Zone, req.Revocation)
+}
+
+//----------------------------------------------------------------------
+// Command "ZoneMaster.Compromised"
+//----------------------------------------------------------------------
+
+// CompromisedRequest marks a zone as compromised. Confirm must be the
+// zone key (ID) of the zone.
+type CompromisedRequest struct {
+	Zone    string `json:"zone"`
+	Confirm string `json:"confirm"`
+}
+
+// CompromisedResponse returns the time the zone was marked compromised
+// and the time the revocation was published.
+type CompromisedResponse struct {
+	Compromised string `json:"compromised"`
+	Published   string `json:"published"`
+}
+
+// Compromised marks a zone as compromised and publishes its revocation.
+func (s *RPCService) Compromised(r *http.Request, req *CompromisedRequest, reply *CompromisedResponse) error {
+	rev, err := s.zm.MarkCompromised(r.Context(), req.Zone, req.Confirm)
+	if err != nil {
+		return err
+	}
+	*reply = CompromisedResponse{
+		Compromised: rev.Compromised.String(),
+		Published:   rev.Published.String(),
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+
+// InitRPC registers RPC commands for the zonemaster
+func (zm *ZoneMaster) InitRPC(srv *service.JRPCServer) {
+	if err := srv.RegisterService(&RPCService{zm: zm}, "ZoneMaster"); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] Failed to init RPC: %s", err.Error())
+	}
 }
@@ -20,17 +20,21 @@ package zonemaster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
-	"gnunet/config"
 	"gnunet/core"
+	"gnunet/enums"
 	"gnunet/message"
 	"gnunet/service"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/revocation"
 	"gnunet/transport"
 	"gnunet/util"
 
+	"github.com/bfix/gospel/data"
 	"github.com/bfix/gospel/logger"
 )
 
@@ -76,6 +80,7 @@ func (zm *ZoneMaster) ServeClient(ctx context.Context, id int, mc *service.Conne
 	}
 	// inform sub.services about closed session
 	zm.identity.CloseSession(id)
+	zm.namestore.CloseSession(id)
 
 	// close client connection
 	mc.Close()
@@ -115,7 +120,9 @@ func (zm *ZoneMaster) HandleMessage(ctx context.Context, sender *util.PeerID, ms
 	case *message.NamestoreZoneIterStartMsg,
 		*message.NamestoreZoneIterNextMsg,
 		*message.NamestoreRecordStoreMsg,
+		*message.NamestoreRecordStoreKeyedMsg,
 		*message.NamestoreRecordLookupMsg,
+		*message.NamestoreTxControlMsg,
 		*message.NamestoreZoneToNameMsg,
 		*message.NamestoreZoneToNameRespMsg,
 		*message.NamestoreMonitorStartMsg,
@@ -132,25 +139,143 @@ func (zm *ZoneMaster) HandleMessage(ctx context.Context, sender *util.PeerID, ms
 	return true
 }
 
-// storeDHT stores a GNS block in the DHT.
+// ErrNotConfirmed is returned if a stored block was not found by any
+// sample GET of the DHT service.
+var ErrNotConfirmed = errors.New("stored block not confirmed")
+
+// storeDHT stores a GNS block in the DHT. If configured, the DHT service
+// confirms that the block can be retrieved.
 func (zm *ZoneMaster) StoreDHT(ctx context.Context, query blocks.Query, block blocks.Block) (err error) {
+	if zm.cfg.ZoneMaster.Verify {
+		return zm.storeVerified(ctx, query, block)
+	}
 	// assemble DHT request
 	req := message.NewDHTP2PPutMsg(block)
+	req.ReplLvl = uint16(zm.cfg.GNS.ReplLevel)
 	req.Flags = query.Flags()
 	req.Key = query.Key().Clone()
 
 	// store block
-	_, err = service.RequestResponse(ctx, "zonemaster", "dht", config.Cfg.DHT.Service.Socket, req, false)
+	_, err = service.RequestResponse(ctx, "zonemaster", "dht", zm.cfg.DHT.Service.Socket, req, false)
 	return
 }
 
+// storeVerified stores a GNS block with a client PUT request and waits
+// for the confirmation of the DHT service. A block not found by any of
+// the sample GETs is reported as failed (and published again in the
+// next cycle).
+func (zm *ZoneMaster) storeVerified(ctx context.Context, query blocks.Query, block blocks.Block) (err error) {
+	conn, err := service.NewConnection(ctx, zm.cfg.DHT.Service.Socket)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// send PUT request (announced for confirmation)
+	req := message.NewDHTClientPutMsg(query.Key(), block.Type(), block.Bytes())
+	req.Expire = block.Expire()
+	req.Options = uint32(query.Flags())
+	req.ReplLevel = uint32(zm.cfg.GNS.ReplLevel)
+	if err = conn.Send(ctx, message.NewDHTClientPutVerifyMsg(req.Key)); err != nil {
+		return
+	}
+	if err = conn.Send(ctx, req); err != nil {
+		return
+	}
+	// wait for confirmation
+	for {
+		var in message.Message
+		if in, err = conn.Receive(ctx); err != nil {
+			return
+		}
+		cm, ok := in.(*message.DHTClientPutConfirmMsg)
+		if !ok || !cm.Key.Equal(req.Key) {
+			continue
+		}
+		logger.Printf(logger.INFO, "[zonemaster] block %s confirmed by %d of %d samples (%d retries, confidence %.2f)",
+			req.Key.Short(), cm.Confirmed, cm.Samples, cm.Retries, cm.Confidence())
+		if cm.Samples > 0 && cm.Confirmed == 0 {
+			err = ErrNotConfirmed
+		}
+		return
+	}
+}
+
+// LookupDHT asks the DHT for the first (exact) result for a query. If no
+// block is found before the context is done, the lookup is stopped.
+func (zm *ZoneMaster) LookupDHT(ctx context.Context, query blocks.Query) (block blocks.Block, err error) {
+	// client-connect to the DHT service
+	cl, err := service.NewClient(ctx, zm.cfg.DHT.Service.Socket)
+	if err != nil {
+		return nil, err
+	}
+	defer cl.Close()
+	cl.SetReconnect(true, nil)
+
+	// send DHT GET request and wait for response
+	req := message.NewDHTClientGetMsg(query.Key())
+	req.ID = uint64(util.NextID())
+	req.ReplLevel = uint32(enums.GNS_REPLICATION_LEVEL)
+	req.BType = enums.BLOCK_TYPE_GNS_NAMERECORD
+	req.Options = uint32(enums.DHT_RO_DEMULTIPLEX_EVERYWHERE | enums.DHT_RO_FIRST_RESULT)
+	if err = cl.SendRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	var resp message.Message
+	if resp, err = cl.ReceiveResponse(ctx); err != nil {
+		if err == service.ErrConnectionInterrupted {
+			// stop the lookup in the DHT
+			stop := message.NewDHTClientGetStopMsg(query.Key())
+			stop.ID = req.ID
+			sctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if sErr := cl.SendRequest(sctx, stop); sErr != nil {
+				logger.Printf(logger.WARN, "[zonemaster] DHT lookup not stopped: %s", sErr.Error())
+			}
+		}
+		return nil, err
+	}
+	// check result
+	m, ok := resp.(*message.DHTClientResultMsg)
+	if !ok || m.ID != req.ID || len(m.Data) == 0 || m.Expire.Expired() {
+		return nil, nil
+	}
+	blk := new(blocks.GNSBlock)
+	if err = data.Unmarshal(blk, m.Data); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
+
 // storeNamecache stores a GNS block in the local namecache.
 func (zm *ZoneMaster) StoreNamecache(ctx context.Context, query *blocks.GNSQuery, block *blocks.GNSBlock) (err error) {
 	// assemble Namecache request
 	req := message.NewNamecacheCacheMsg(block)
 
 	// get response from Namecache service
-	_, err = service.RequestResponse(ctx, "zonemaster", "namecache", config.Cfg.Namecache.Service.Socket, req, false)
+	_, err = service.RequestResponse(ctx, "zonemaster", "namecache", zm.cfg.Namecache.Service.Socket, req, false)
+	return
+}
+
+// RevokeZone publishes a revocation via the revocation service.
+func (zm *ZoneMaster) RevokeZone(ctx context.Context, rd *revocation.RevData) (success bool, err error) {
+	if zm.cfg.Revocation == nil || zm.cfg.Revocation.Service == nil {
+		return false, ErrNoRevocationService
+	}
+	// assemble request
+	req := message.NewRevocationRevokeMsg(rd.ZoneKeySig)
+	req.Timestamp = rd.Timestamp
+	req.TTL = rd.TTL
+	copy(req.PoWs, rd.PoWs)
+
+	// get response from revocation service
+	var resp message.Message
+	if resp, err = service.RequestResponse(ctx, "zonemaster", "revocation", zm.cfg.Revocation.Service.Socket, req, true); err != nil {
+		return
+	}
+	if m, ok := resp.(*message.RevocationRevokeResponseMsg); ok {
+		success = (m.Success == 1)
+	}
 	return
 }
 
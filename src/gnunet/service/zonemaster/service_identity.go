@@ -20,10 +20,12 @@ package zonemaster
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"gnunet/core"
 	"gnunet/crypto"
 	"gnunet/message"
+	"gnunet/service/identity"
 	"gnunet/service/store"
 	"gnunet/transport"
 	"gnunet/util"
@@ -199,15 +201,57 @@ func (ident *IdentityService) HandleMessage(ctx context.Context, sender *util.Pe
 
 	// lookup identity
 	case *message.IdentityLookupMsg:
+		// an unknown identity is reported with a result code
+		var resp message.Message
 		id, err := ident.zm.zdb.GetZoneByName(m.Name)
 		if err != nil {
-			logger.Printf(logger.ERROR, "[identity%s] Identity lookup failed: %v\n", label, err)
-			return false
+			logger.Printf(logger.DBG, "[identity%s] Identity lookup failed: %v\n", label, err)
+			resp = message.NewIdentityResultCodeMsg(1)
+		} else {
+			resp = message.NewIdentityUpdateMsg(id.Name, id.Key)
 		}
-		resp := message.NewIdentityUpdateMsg(id.Name, id.Key)
 		if !sendResponse(ctx, "identity"+label, resp, back) {
 			return false
 		}
 	}
 	return true
 }
+
+//----------------------------------------------------------------------
+// Egos of the identity service
+//----------------------------------------------------------------------
+
+// ImportEgos adds the egos of the identity service (if configured) as
+// zones, so they are managed and published by the zonemaster. Egos with
+// a name already used by another zone are skipped.
+func (zm *ZoneMaster) ImportEgos(ctx context.Context) (err error) {
+	cfg := zm.cfg.Identity
+	if cfg == nil || cfg.Service == nil {
+		return
+	}
+	var egos []*identity.Ego
+	if egos, err = identity.ListEgos(ctx, cfg.Service.Socket); err != nil {
+		return
+	}
+	for _, ego := range egos {
+		// skip known zones
+		if _, err = zm.zdb.GetZoneByKey(ego.Key); err != sql.ErrNoRows {
+			if err != nil {
+				return
+			}
+			continue
+		}
+		if _, err = zm.zdb.GetZoneByName(ego.Name); err != sql.ErrNoRows {
+			if err != nil {
+				return
+			}
+			logger.Printf(logger.WARN, "[zonemaster] ego '%s' not imported: zone name in use", ego.Name)
+			continue
+		}
+		if err = zm.zdb.SetZone(store.NewZone(ego.Name, ego.Key)); err != nil {
+			return
+		}
+		logger.Printf(logger.INFO, "[zonemaster] ego '%s' imported as zone", ego.Name)
+	}
+	return nil
+}
@@ -20,14 +20,19 @@ package zonemaster
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"gnunet/core"
 	"gnunet/crypto"
 	"gnunet/enums"
 	"gnunet/message"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
 	"gnunet/service/store"
 	"gnunet/transport"
 	"gnunet/util"
+	"sync"
+	"time"
 
 	"github.com/bfix/gospel/logger"
 )
@@ -45,6 +50,7 @@ type ZoneIterator struct {
 	id       uint32              // request ID
 	zid      int64               // database ID of zone
 	zk       *crypto.ZonePrivate // private zone key
+	filter   enums.GNSFilter     // record filter
 	lastUsed util.AbsoluteTime   // last time iterator was used
 	zm       *ZoneMaster         // reference to zone master
 	labels   []int64             // list of label ids in database for zone
@@ -52,7 +58,7 @@ type ZoneIterator struct {
 }
 
 // NewZoneIterator initialize an iterator to traverse the zone labels
-func NewZoneIterator(id uint32, zk *crypto.ZonePrivate, zm *ZoneMaster) (zi *ZoneIterator, err error) {
+func NewZoneIterator(id uint32, zk *crypto.ZonePrivate, filter enums.GNSFilter, zm *ZoneMaster) (zi *ZoneIterator, err error) {
 	// get list of labels to handle
 	var labels []int64
 	var zid int64
@@ -64,6 +70,7 @@ func NewZoneIterator(id uint32, zk *crypto.ZonePrivate, zm *ZoneMaster) (zi *Zon
 		id:       id,
 		zid:      zid,
 		zk:       zk,
+		filter:   filter,
 		lastUsed: util.AbsoluteTimeNow(),
 		zm:       zm,
 		pos:      0,
@@ -88,7 +95,7 @@ func (zi *ZoneIterator) Next() (msg message.Message, done bool) {
 		logger.Printf(logger.ERROR, "[zone_iter] label name: %s", err.Error())
 		return
 	}
-	rrSet, expire, err := zi.zm.GetRecordSet(lid, enums.GNS_FILTER_NONE)
+	rrSet, expire, err := zi.zm.GetRecordSet(lid, zi.filter)
 	if err != nil {
 		logger.Printf(logger.ERROR, "[zone_iter] records: %s", err.Error())
 		return
@@ -105,10 +112,43 @@ func (zi *ZoneIterator) Next() (msg message.Message, done bool) {
 // Namestore service
 //----------------------------------------------------------------------
 
+// editKey identifies a label in a zone
+type editKey struct {
+	zid   int64  // database ID of zone
+	label string // normalized label name
+}
+
+// nsUpdate is a store request (record sets for a zone)
+type nsUpdate struct {
+	zk   *crypto.ZonePrivate           // zone key
+	list []*message.NamestoreRecordSet // labeled record sets
+}
+
+// nsTx is an open transaction of a session: store requests are kept
+// until the transaction is committed or rolled back.
+type nsTx struct {
+	sync.Mutex
+	updates []*nsUpdate // pending store requests
+}
+
+// errors to end database transactions
+var (
+	errTxCheck  = errors.New("check only")
+	errTxFailed = errors.New("store failed")
+)
+
+// StoreKeyTTL is the time the result of a store request with an
+// idempotency key is kept: a repeated request within that time is
+// answered with the original result.
+var StoreKeyTTL = 24 * time.Hour
+
 // NamestoreService to handle namestore requests
 type NamestoreService struct {
 	zm    *ZoneMaster
 	iters *util.Map[uint32, *ZoneIterator]
+	locks *util.Map[editKey, int] // labels locked for editing (by session)
+	txs   *util.Map[int, *nsTx]   // open transactions (by session)
+	txMtx sync.Mutex              // serialize database transactions
 }
 
 // NewNamestoreService creates a new namestore service handler
@@ -116,12 +156,21 @@ func NewNamestoreService(zm *ZoneMaster) *NamestoreService {
 	return &NamestoreService{
 		zm:    zm,
 		iters: util.NewMap[uint32, *ZoneIterator](),
+		locks: util.NewMap[editKey, int](),
+		txs:   util.NewMap[int, *nsTx](),
 	}
 }
 
+// CloseSession releases all edit locks held by a client session and
+// drops an open transaction (uncommitted changes are lost).
+func (s *NamestoreService) CloseSession(sid int) {
+	s.txs.Delete(sid, 0)
+	s.unlockAll(sid)
+}
+
 // NewIterator creates a new iterator for zone traversal
-func (s *NamestoreService) NewIterator(id uint32, zk *crypto.ZonePrivate) *ZoneIterator {
-	zi, err := NewZoneIterator(id, zk, s.zm)
+func (s *NamestoreService) NewIterator(id uint32, zk *crypto.ZonePrivate, filter enums.GNSFilter) *ZoneIterator {
+	zi, err := NewZoneIterator(id, zk, filter, s.zm)
 	if err != nil {
 		logger.Printf(logger.ERROR, "[namestore] new zone iterator: %s", err.Error())
 		return nil
@@ -140,50 +189,303 @@ func (s *NamestoreService) DropIterator(id uint32) {
 	s.iters.Delete(id, 0)
 }
 
-// Store labeled recordsets to zone
-func (s *NamestoreService) Store(zk *crypto.ZonePrivate, list []*message.NamestoreRecordSet) bool {
+// Store labeled recordsets to zone. If a record set specifies a label
+// version, it must match the current version of the label; otherwise the
+// store request is rejected (conflicting edits). Labels locked for editing
+// by another session can't be stored. In a transaction, the record sets
+// are checked (together with the pending record sets of the transaction)
+// and stored on commit.
+func (s *NamestoreService) Store(sid int, zk *crypto.ZonePrivate, list []*message.NamestoreRecordSet) enums.ErrorCode {
+	upd := &nsUpdate{zk, list}
+	tx, ok := s.txs.Get(sid, 0)
+	if !ok {
+		return s.apply(sid, []*nsUpdate{upd}, true)
+	}
+	tx.Lock()
+	defer tx.Unlock()
+	updates := append(util.Clone(tx.updates), upd)
+	if ec := s.apply(sid, updates, false); ec != enums.EC_NONE {
+		return ec
+	}
+	tx.updates = updates
+	return enums.EC_NONE
+}
+
+// StoreKeyed stores record sets like Store, but only once for a given
+// idempotency key: a repeated request with the same key for the zone is
+// answered with the result of the first request. Failures of the
+// database backend are not recorded (a repeated request is tried
+// again). In a session transaction the key is not used.
+func (s *NamestoreService) StoreKeyed(sid int, key *crypto.HashCode, zk *crypto.ZonePrivate, list []*message.NamestoreRecordSet) (ec enums.ErrorCode) {
+	if _, ok := s.txs.Get(sid, 0); ok || key == nil {
+		return s.Store(sid, zk, list)
+	}
+	s.txMtx.Lock()
+	defer s.txMtx.Unlock()
+
+	zdb := s.zm.zdb
+	zone, err := zdb.GetZoneByKey(zk)
+	if err != nil {
+		logger.Printf(logger.ERROR, "[namestore] zone from key: %s", err.Error())
+		return enums.EC_NAMESTORE_ZONE_NOT_FOUND
+	}
+	// forget old requests
+	now := util.AbsoluteTimeNow()
+	if _, err = zdb.PurgeStoreResults(now.Add(-StoreKeyTTL)); err != nil {
+		logger.Printf(logger.WARN, "[namestore] purge store results: %s", err.Error())
+	}
+	// store records and result in one database transaction
+	res := &store.StoreResult{
+		Zone:    zone.ID,
+		Key:     key.Data,
+		Created: now,
+	}
+	var known *store.StoreResult
+	ec = enums.EC_NONE
+	err = zdb.Atomic(func(db *store.ZoneDB) (err error) {
+		if known, err = db.GetStoreResult(zone.ID, key.Data); err != nil || known != nil {
+			return
+		}
+		if ec = s.store(db, sid, zk, list); ec != enums.EC_NONE {
+			return errTxFailed
+		}
+		_, err = db.SetStoreResult(res)
+		return
+	})
+	switch {
+	case known != nil:
+		ec = enums.ErrorCode(known.Status)
+		logger.Printf(logger.INFO, "[namestore] repeated store request %s: %s", key.Short(), ec)
+	case ec != enums.EC_NONE:
+		// record failed request
+		res.Status = uint32(ec)
+		if _, err = zdb.SetStoreResult(res); err != nil {
+			logger.Printf(logger.WARN, "[namestore] store result: %s", err.Error())
+		}
+	case err != nil:
+		logger.Printf(logger.ERROR, "[namestore] transaction: %s", err.Error())
+		ec = enums.EC_NAMESTORE_BACKEND_FAILED
+	}
+	return
+}
+
+// apply store requests in a database transaction: either all or none of
+// the record sets are stored. If 'commit' is false, the requests are only
+// checked (the transaction is rolled back).
+func (s *NamestoreService) apply(sid int, updates []*nsUpdate, commit bool) (ec enums.ErrorCode) {
+	s.txMtx.Lock()
+	defer s.txMtx.Unlock()
+
+	ec = enums.EC_NONE
+	err := s.zm.zdb.Atomic(func(db *store.ZoneDB) error {
+		for _, upd := range updates {
+			if ec = s.store(db, sid, upd.zk, upd.list); ec != enums.EC_NONE {
+				return errTxFailed
+			}
+		}
+		if !commit {
+			return errTxCheck
+		}
+		return nil
+	})
+	if err != nil && ec == enums.EC_NONE && err != errTxCheck {
+		logger.Printf(logger.ERROR, "[namestore] transaction: %s", err.Error())
+		ec = enums.EC_NAMESTORE_BACKEND_FAILED
+	}
+	return
+}
+
+// store labeled recordsets to zone (in a database transaction). The label
+// versions are checked and updated in the same transaction as the records:
+// if a record set fails, no version is changed and no records are stored.
+func (s *NamestoreService) store(db *store.ZoneDB, sid int, zk *crypto.ZonePrivate, list []*message.NamestoreRecordSet) enums.ErrorCode {
 	// get the zone with given key
-	zone, err := s.zm.zdb.GetZoneByKey(zk)
+	zone, err := db.GetZoneByKey(zk)
 	if err != nil {
 		logger.Printf(logger.ERROR, "[namestore] zone from key: %s", err.Error())
-		return false
+		return enums.EC_NAMESTORE_ZONE_NOT_FOUND
 	}
 	// add all record sets
 	for _, entry := range list {
 		// get labeled resource records
-		label, _ := util.ReadCString(entry.Name, 0)
+		name, _ := util.ReadCString(entry.Name, 0)
+		var label string
+		if label, err = names.Normalize(name); err != nil {
+			logger.Printf(logger.WARN, "[namestore] label '%s': %s", name, err.Error())
+			return enums.EC_NAMESTORE_LABEL_INVALID
+		}
+		// check for edit lock
+		if owner, ok := s.locks.Get(editKey{zone.ID, label}, 0); ok && owner != sid {
+			logger.Printf(logger.WARN, "[namestore] label '%s' locked by session %d", label, owner)
+			return enums.EC_NAMESTORE_STORE_FAILED
+		}
 		// get label object from database
 		var lbl *store.Label
-		if lbl, err = s.zm.zdb.GetLabelByName(label, zone.ID, true); err != nil {
+		if lbl, err = db.GetLabelByName(label, zone.ID, true); err != nil {
 			logger.Printf(logger.ERROR, "[namestore] label from name: %s", err.Error())
-			return false
+			return enums.EC_NAMESTORE_BACKEND_FAILED
+		}
+		// check label version
+		if entry.Version != 0 && entry.Version != message.LabelVersion(lbl.Version) {
+			logger.Printf(logger.WARN, "[namestore] label '%s': version %d expected, got %d",
+				label, message.LabelVersion(lbl.Version), entry.Version)
+			return enums.EC_NAMESTORE_STORE_FAILED
 		}
 		// disassemble record set data
 		rr, err := blocks.NewRecordSetFromRDATA(uint32(entry.RdCount), entry.RecData)
 		if err != nil {
 			logger.Printf(logger.ERROR, "[namestore] record from data: %s", err.Error())
-			return false
+			return enums.EC_NAMESTORE_RECORD_DATA_INVALID
 		}
+		// check size of label with new records
+		recs := make([]*store.Record, 0, len(rr.Records))
 		for _, rr := range rr.Records {
-			// assemble record and store in database
 			rec := store.NewRecord(rr.Expire, rr.RType, rr.Flags, rr.Data)
 			rec.Label = lbl.ID
-			if err = s.zm.zdb.SetRecord(rec); err != nil {
+			s.zm.applyDefaultTTL(rec)
+			recs = append(recs, rec)
+		}
+		if err = s.zm.checkLabelSize(db, lbl.ID, recs...); err != nil {
+			logger.Printf(logger.WARN, "[namestore] %s", err.Error())
+			return enums.EC_NAMESTORE_RECORD_TOO_BIG
+		}
+		for _, rec := range recs {
+			// store record in database
+			if err = db.SetRecord(rec); err != nil {
 				logger.Printf(logger.ERROR, "[namestore] add record: %s", err.Error())
-				return false
+				return enums.EC_NAMESTORE_BACKEND_FAILED
 			}
 		}
+		// update label version after all records are written (the
+		// version change is committed with the records)
+		if _, err = db.UpdateLabelVersion(lbl.ID, lbl.Version); err != nil {
+			logger.Printf(logger.WARN, "[namestore] label '%s': %s", label, err.Error())
+			return enums.EC_NAMESTORE_STORE_FAILED
+		}
 	}
-	return true
+	return enums.EC_NONE
+}
+
+// Lookup records for a label in a zone. The response is "found" if the
+// label exists in the zone (even if the filter removed all its records);
+// it is "not found" for unknown zones or labels and an error (RC_SYSERR)
+// if the lookup failed. An edit lookup locks the label for the session
+// until the transaction ends or the session is closed; it fails if the
+// label is locked by another session.
+func (s *NamestoreService) Lookup(sid int, m *message.NamestoreRecordLookupMsg) *message.NamestoreRecordLookupRespMsg {
+	resp := message.NewNamestoreRecordLookupRespMsg(m.ID, m.ZoneKey, string(m.Label))
+	resp.Found = int16(enums.RC_SYSERR)
+
+	// get zone and normalized label
+	zone, err := s.zm.zdb.GetZoneByKey(m.ZoneKey)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Printf(logger.ERROR, "[namestore] zone lookup: %s", err.Error())
+			return resp
+		}
+		resp.Found = int16(enums.RC_NO)
+		return resp
+	}
+	name, err := names.Normalize(string(m.Label))
+	if err != nil {
+		logger.Printf(logger.WARN, "[namestore] label '%s': %s", string(m.Label), err.Error())
+		return resp
+	}
+	// lock label for editing
+	if m.IsEdit != 0 && !s.lock(sid, editKey{zone.ID, name}) {
+		logger.Printf(logger.WARN, "[namestore] label '%s' already locked for editing", name)
+		return resp
+	}
+	// get label and (filtered) resource records
+	lbl, err := s.zm.zdb.GetLabelByName(name, zone.ID, false)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Printf(logger.ERROR, "[namestore] label lookup: %s", err.Error())
+			return resp
+		}
+		resp.Found = int16(enums.RC_NO)
+		return resp
+	}
+	rrSet, _, err := s.zm.GetRecordSet(lbl.ID, enums.GNSFilter(m.Filter))
+	if err != nil {
+		logger.Printf(logger.ERROR, "[namestore] records: %s", err.Error())
+		return resp
+	}
+	resp.AddRecords(rrSet)
+	resp.Found = int16(enums.RC_YES)
+	resp.Version = message.LabelVersion(lbl.Version)
+	return resp
+}
+
+// TxControl handles the begin and end of a transaction for a session.
+// Record sets stored in a transaction are stored together on commit; if
+// one of them fails, none is stored. Ending a transaction releases all
+// edit locks of the session. Lookups in a transaction don't see the
+// uncommitted record sets.
+func (s *NamestoreService) TxControl(sid int, ctrl uint16) enums.ErrorCode {
+	switch ctrl {
+	case message.NamestoreTxBegin:
+		if _, ok := s.txs.Get(sid, 0); ok {
+			logger.Println(logger.WARN, "[namestore] transaction already started")
+			return enums.EC_NAMESTORE_BACKEND_FAILED
+		}
+		s.txs.Put(sid, new(nsTx), 0)
+		return enums.EC_NONE
+
+	case message.NamestoreTxCommit:
+		defer s.unlockAll(sid)
+		tx, ok := s.txs.Get(sid, 0)
+		if !ok {
+			return enums.EC_NONE
+		}
+		s.txs.Delete(sid, 0)
+		tx.Lock()
+		defer tx.Unlock()
+		return s.apply(sid, tx.updates, true)
+
+	case message.NamestoreTxRollback:
+		s.txs.Delete(sid, 0)
+		s.unlockAll(sid)
+		return enums.EC_NONE
+	}
+	return enums.EC_UNKNOWN
+}
+
+// lock a label for editing by a session. Returns false if the label is
+// locked by another session.
+func (s *NamestoreService) lock(sid int, key editKey) (ok bool) {
+	_ = s.locks.Process(func(pid int) error {
+		var owner int
+		if owner, ok = s.locks.Get(key, pid); ok && owner != sid {
+			ok = false
+			return nil
+		}
+		s.locks.Put(key, sid, pid)
+		ok = true
+		return nil
+	}, false)
+	return
+}
+
+// unlockAll releases all edit locks of a session.
+func (s *NamestoreService) unlockAll(sid int) {
+	_ = s.locks.ProcessRange(func(key editKey, owner int, pid int) error {
+		if owner == sid {
+			s.locks.Delete(key, pid)
+		}
+		return nil
+	}, false)
 }
 
 // HandleMessage processes a single incoming message
 func (s *NamestoreService) HandleMessage(ctx context.Context, sender *util.PeerID, msg message.Message, back transport.Responder) bool {
-	// assemble log label
+	// assemble log label and get session id
 	var label string
+	var sid int
 	if v := ctx.Value(core.CtxKey("params")); v != nil {
 		if ps, ok := v.(util.ParameterSet); ok {
 			label, _ = util.GetParam[string](ps, "label")
+			sid, _ = util.GetParam[int](ps, "id")
 		}
 	}
 	// perform lookup
@@ -192,7 +494,7 @@ func (s *NamestoreService) HandleMessage(ctx context.Context, sender *util.PeerI
 	// start new zone iteration
 	case *message.NamestoreZoneIterStartMsg:
 		// setup iterator
-		iter := s.NewIterator(m.ID, m.ZoneKey)
+		iter := s.NewIterator(m.ID, m.ZoneKey, enums.GNSFilter(m.Filter))
 		// return first result
 		resp, done := iter.Next()
 		if done {
@@ -230,45 +532,33 @@ func (s *NamestoreService) HandleMessage(ctx context.Context, sender *util.PeerI
 
 	// store record in zone database
 	case *message.NamestoreRecordStoreMsg:
-		var rc uint32
-		if !s.Store(m.ZoneKey, m.RSets) {
-			rc = 1
+		rc := s.Store(sid, m.ZoneKey, m.RSets)
+		storeRequests.Inc(rc.String())
+		resp := message.NewNamestoreRecordStoreRespMsg(m.ID, uint32(rc))
+		if !sendResponse(ctx, "namestore"+label, resp, back) {
+			return false
 		}
-		resp := message.NewNamestoreRecordStoreRespMsg(m.ID, rc)
+
+	// store record in zone database (with idempotency key)
+	case *message.NamestoreRecordStoreKeyedMsg:
+		rc := s.StoreKeyed(sid, m.Key, m.ZoneKey, m.RSets)
+		storeRequests.Inc(rc.String())
+		resp := message.NewNamestoreRecordStoreRespMsg(m.ID, uint32(rc))
 		if !sendResponse(ctx, "namestore"+label, resp, back) {
 			return false
 		}
 
 	// lookup records in zone under given label
 	case *message.NamestoreRecordLookupMsg:
-		// get resource records
-		getRecs := func() *blocks.RecordSet {
-			zone, err := s.zm.zdb.GetZoneByKey(m.ZoneKey)
-			if err != nil {
-				logger.Printf(logger.ERROR, "[namestore%s] zone lookup: %s", label, err.Error())
-				return nil
-			}
-			lbl, err := s.zm.zdb.GetLabelByName(string(m.Label), zone.ID, false)
-			if err != nil {
-				logger.Printf(logger.ERROR, "[namestore%s] label lookup: %s", label, err.Error())
-				return nil
-			}
-			rrSet, _, err := s.zm.GetRecordSet(lbl.ID, enums.GNSFilter(m.Filter))
-			if err != nil {
-				logger.Printf(logger.ERROR, "[namestore%s] records: %s", label, err.Error())
-				return nil
-			}
-			return rrSet
-		}
-		recs := getRecs()
-		// assemble response
-		resp := message.NewNamestoreRecordLookupRespMsg(m.ID, m.ZoneKey, string(m.Label))
-		if recs != nil {
-			resp.AddRecords(recs)
-			resp.Found = int16(enums.RC_YES)
-		} else {
-			resp.Found = int16(enums.RC_NO)
+		resp := s.Lookup(sid, m)
+		if !sendResponse(ctx, "namestore"+label, resp, back) {
+			return false
 		}
+
+	// begin or end a transaction
+	case *message.NamestoreTxControlMsg:
+		ec := s.TxControl(sid, m.Control)
+		resp := message.NewNamestoreTxControlResultMsg(m.ID, ec)
 		if !sendResponse(ctx, "namestore"+label, resp, back) {
 			return false
 		}
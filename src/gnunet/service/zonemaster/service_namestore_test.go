@@ -0,0 +1,413 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"fmt"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/message"
+	"gnunet/service/dht/blocks"
+	"gnunet/service/store"
+	"gnunet/util"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNamestoreTx(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	s := NewNamestoreService(&ZoneMaster{zdb: zdb})
+
+	// record set for a label (with expected label version)
+	recordSet := func(label string, version uint16) []*message.NamestoreRecordSet {
+		rs := blocks.NewRecordSet()
+		rs.AddRecord(&blocks.ResourceRecord{
+			Expire: util.AbsoluteTimeNow().Add(time.Hour),
+			Size:   uint16(len(label)),
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   []byte(label),
+		})
+		msg := message.NewNamestoreRecordStoreMsg(0, zp)
+		msg.AddRecordSet(label, rs).Version = version
+		return msg.RSets
+	}
+	// number of (committed) records under a label
+	count := func(label string) int {
+		lbl, err := zdb.GetLabelByName(label, zone.ID, false)
+		if err != nil {
+			return 0
+		}
+		recs, err := zdb.GetRecords("lid=%d", lbl.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(recs)
+	}
+	txControl := func(sid int, ctrl uint16, want enums.ErrorCode) {
+		t.Helper()
+		if ec := s.TxControl(sid, ctrl); ec != want {
+			t.Fatalf("session %d: control %d returned %s, expected %s", sid, ctrl, ec, want)
+		}
+	}
+	storeSet := func(sid int, label string, version uint16, want enums.ErrorCode) {
+		t.Helper()
+		if ec := s.Store(sid, zp, recordSet(label, version)); ec != want {
+			t.Fatalf("session %d: store '%s' returned %s, expected %s", sid, label, ec, want)
+		}
+	}
+
+	// two interleaved transactions: changes are only visible after commit
+	txControl(1, message.NamestoreTxBegin, enums.EC_NONE)
+	txControl(1, message.NamestoreTxBegin, enums.EC_NAMESTORE_BACKEND_FAILED)
+	txControl(2, message.NamestoreTxBegin, enums.EC_NONE)
+	storeSet(1, "a", 0, enums.EC_NONE)
+	storeSet(2, "b", 0, enums.EC_NONE)
+	storeSet(1, "a2", 0, enums.EC_NONE)
+	if count("a") != 0 || count("a2") != 0 || count("b") != 0 {
+		t.Fatal("uncommitted records stored")
+	}
+	txControl(1, message.NamestoreTxCommit, enums.EC_NONE)
+	if count("a") != 1 || count("a2") != 1 || count("b") != 0 {
+		t.Fatal("first transaction not committed (alone)")
+	}
+	txControl(2, message.NamestoreTxCommit, enums.EC_NONE)
+	if count("b") != 1 {
+		t.Fatal("second transaction not committed")
+	}
+
+	// rollback and closed session discard changes
+	txControl(3, message.NamestoreTxBegin, enums.EC_NONE)
+	storeSet(3, "c", 0, enums.EC_NONE)
+	txControl(3, message.NamestoreTxRollback, enums.EC_NONE)
+	txControl(3, message.NamestoreTxCommit, enums.EC_NONE)
+	txControl(4, message.NamestoreTxBegin, enums.EC_NONE)
+	storeSet(4, "c", 0, enums.EC_NONE)
+	s.CloseSession(4)
+	txControl(4, message.NamestoreTxCommit, enums.EC_NONE)
+	if count("c") != 0 {
+		t.Fatal("discarded records stored")
+	}
+
+	// failed commit (label changed outside of transaction) stores nothing
+	lbl, err := zdb.GetLabelByName("a", zone.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver := message.LabelVersion(lbl.Version)
+	txControl(5, message.NamestoreTxBegin, enums.EC_NONE)
+	storeSet(5, "d", 0, enums.EC_NONE)
+	storeSet(5, "a", ver+1, enums.EC_NAMESTORE_STORE_FAILED)
+	storeSet(5, "a", ver, enums.EC_NONE)
+	storeSet(6, "a", 0, enums.EC_NONE)
+	txControl(5, message.NamestoreTxCommit, enums.EC_NAMESTORE_STORE_FAILED)
+	if count("d") != 0 || count("a") != 2 {
+		t.Fatal("failed transaction not rolled back")
+	}
+
+	// concurrent transactions
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for sid := 10; sid < 18; sid++ {
+		wg.Add(1)
+		go func(sid int) {
+			defer wg.Done()
+			if ec := s.TxControl(sid, message.NamestoreTxBegin); ec != enums.EC_NONE {
+				errs <- fmt.Errorf("session %d: begin %s", sid, ec)
+				return
+			}
+			for i := 0; i < 3; i++ {
+				label := fmt.Sprintf("s%d-%d", sid, i)
+				if ec := s.Store(sid, zp, recordSet(label, 0)); ec != enums.EC_NONE {
+					errs <- fmt.Errorf("session %d: store %s", sid, ec)
+					return
+				}
+			}
+			if ec := s.TxControl(sid, message.NamestoreTxCommit); ec != enums.EC_NONE {
+				errs <- fmt.Errorf("session %d: commit %s", sid, ec)
+			}
+		}(sid)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	for sid := 10; sid < 18; sid++ {
+		for i := 0; i < 3; i++ {
+			if n := count(fmt.Sprintf("s%d-%d", sid, i)); n != 1 {
+				t.Fatalf("session %d: %d records for label %d", sid, n, i)
+			}
+		}
+	}
+}
+
+// Test that a store request failing in a later record set leaves the
+// label versions and records of earlier record sets unchanged.
+func TestNamestoreStoreFailed(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	s := NewNamestoreService(&ZoneMaster{zdb: zdb})
+
+	// add record set for a label to a store request
+	addSet := func(msg *message.NamestoreRecordStoreMsg, label string, version uint16) *message.NamestoreRecordSet {
+		rs := blocks.NewRecordSet()
+		rs.AddRecord(&blocks.ResourceRecord{
+			Expire: util.AbsoluteTimeNow().Add(time.Hour),
+			Size:   uint16(len(label)),
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   []byte(label),
+		})
+		rset := msg.AddRecordSet(label, rs)
+		rset.Version = version
+		return rset
+	}
+	// current version and number of records of a label
+	state := func(label string) (uint16, int) {
+		lbl, err := zdb.GetLabelByName(label, zone.ID, false)
+		if err != nil {
+			return 0, 0
+		}
+		recs, err := zdb.GetRecords("lid=%d", lbl.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return message.LabelVersion(lbl.Version), len(recs)
+	}
+
+	// initial record set
+	msg := message.NewNamestoreRecordStoreMsg(0, zp)
+	addSet(msg, "a", 0)
+	if ec := s.Store(1, zp, msg.RSets); ec != enums.EC_NONE {
+		t.Fatalf("store returned %s", ec)
+	}
+	ver, num := state("a")
+
+	// store request with a broken second record set
+	msg = message.NewNamestoreRecordStoreMsg(0, zp)
+	addSet(msg, "a", ver)
+	addSet(msg, "b", 0).RdCount++
+	if ec := s.Store(1, zp, msg.RSets); ec != enums.EC_NAMESTORE_RECORD_DATA_INVALID {
+		t.Fatalf("store returned %s", ec)
+	}
+	if v, n := state("a"); v != ver || n != num {
+		t.Fatalf("failed store changed label: version %d -> %d, %d -> %d records", ver, v, num, n)
+	}
+	if _, n := state("b"); n != 0 {
+		t.Fatal("records of failed store request stored")
+	}
+	// the client can store with the old version
+	msg = message.NewNamestoreRecordStoreMsg(0, zp)
+	addSet(msg, "a", ver)
+	if ec := s.Store(1, zp, msg.RSets); ec != enums.EC_NONE {
+		t.Fatalf("store with unchanged version returned %s", ec)
+	}
+	if v, n := state("a"); v == ver || n != num+1 {
+		t.Fatalf("store not applied: version %d, %d records", v, n)
+	}
+}
+
+func TestNamestoreDefaultTTL(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	zm := &ZoneMaster{
+		zdb: zdb,
+		ttls: defaultTTLs(map[string]int{
+			"A":     3600,
+			"TXT":   600,
+			"*":     86400,
+			"BOGUS": 60,
+		}),
+	}
+	s := NewNamestoreService(zm)
+
+	// store records without expiration (except for the AAAA record)
+	expire := util.AbsoluteTimeNow().Add(time.Hour)
+	rs := blocks.NewRecordSet()
+	add := func(rtype enums.GNSType, exp util.AbsoluteTime, data []byte) {
+		rs.AddRecord(&blocks.ResourceRecord{
+			Expire: exp,
+			Size:   uint16(len(data)),
+			RType:  rtype,
+			Data:   data,
+		})
+	}
+	add(enums.GNS_TYPE_DNS_A, util.AbsoluteTime{}, []byte{192, 0, 2, 1})
+	add(enums.GNS_TYPE_DNS_TXT, util.AbsoluteTime{}, []byte("text"))
+	add(enums.GNS_TYPE_NICK, util.AbsoluteTime{}, []byte("nick\x00"))
+	add(enums.GNS_TYPE_DNS_AAAA, expire, make([]byte, 16))
+	msg := message.NewNamestoreRecordStoreMsg(0, zp)
+	msg.AddRecordSet("www", rs)
+	if ec := s.Store(1, zp, msg.RSets); ec != enums.EC_NONE {
+		t.Fatalf("store returned %s", ec)
+	}
+
+	// check expirations of stored records
+	lbl, err := zdb.GetLabelByName("www", zone.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := zdb.GetRecords("lid=%d", lbl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[enums.GNSType]time.Duration{
+		enums.GNS_TYPE_DNS_A:   time.Hour,
+		enums.GNS_TYPE_DNS_TXT: 10 * time.Minute,
+		enums.GNS_TYPE_NICK:    24 * time.Hour,
+	}
+	for _, rec := range recs {
+		if rec.RType == enums.GNS_TYPE_DNS_AAAA {
+			if rec.Flags&enums.GNS_FLAG_RELATIVE_EXPIRATION != 0 || rec.Expire.Val != expire.Val {
+				t.Fatal("explicit expiration changed")
+			}
+			continue
+		}
+		if rec.Flags&enums.GNS_FLAG_RELATIVE_EXPIRATION == 0 {
+			t.Fatalf("record of type %s: no relative expiration", rec.RType)
+		}
+		if d := time.Duration(rec.Expire.Val) * time.Microsecond; d != want[rec.RType] {
+			t.Fatalf("record of type %s: expiration %s, expected %s", rec.RType, d, want[rec.RType])
+		}
+	}
+	if len(recs) != 4 {
+		t.Fatalf("%d records stored, expected 4", len(recs))
+	}
+}
+
+func TestNamestoreStoreKeyed(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := store.NewZone("test", zp)
+	if err = zdb.SetZone(zone); err != nil {
+		t.Fatal(err)
+	}
+	s := NewNamestoreService(&ZoneMaster{zdb: zdb})
+
+	// store a TXT record under a label (with expected label version)
+	storeKeyed := func(sid int, key, label string, version uint16, want enums.ErrorCode) {
+		t.Helper()
+		rs := blocks.NewRecordSet()
+		rs.AddRecord(&blocks.ResourceRecord{
+			Expire: util.AbsoluteTimeNow().Add(time.Hour),
+			Size:   uint16(len(label)),
+			RType:  enums.GNS_TYPE_DNS_TXT,
+			Data:   []byte(label),
+		})
+		msg := message.NewNamestoreRecordStoreKeyedMsg(0, crypto.Hash([]byte(key)), zp)
+		msg.AddRecordSet(label, rs).Version = version
+		if ec := s.StoreKeyed(sid, msg.Key, zp, msg.RSets); ec != want {
+			t.Fatalf("store '%s' (key '%s') returned %s, expected %s", label, key, ec, want)
+		}
+	}
+	count := func(label string) int {
+		lbl, err := zdb.GetLabelByName(label, zone.ID, false)
+		if err != nil {
+			return 0
+		}
+		recs, err := zdb.GetRecords("lid=%d", lbl.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(recs)
+	}
+
+	// repeated requests (also from other sessions) store records once
+	storeKeyed(1, "k1", "a", 0, enums.EC_NONE)
+	storeKeyed(1, "k1", "a", 0, enums.EC_NONE)
+	storeKeyed(2, "k1", "a", 0, enums.EC_NONE)
+	if n := count("a"); n != 1 {
+		t.Fatalf("%d records stored", n)
+	}
+	storeKeyed(1, "k2", "a", 0, enums.EC_NONE)
+	if n := count("a"); n != 2 {
+		t.Fatalf("%d records stored", n)
+	}
+	// a failed request keeps failing with the original result
+	lbl, err := zdb.GetLabelByName("a", zone.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver := message.LabelVersion(lbl.Version)
+	storeKeyed(1, "k3", "a", ver+1, enums.EC_NAMESTORE_STORE_FAILED)
+	storeKeyed(1, "k3", "a", ver, enums.EC_NAMESTORE_STORE_FAILED)
+	if n := count("a"); n != 2 {
+		t.Fatalf("%d records stored", n)
+	}
+	// keys are not used in session transactions
+	if ec := s.TxControl(3, message.NamestoreTxBegin); ec != enums.EC_NONE {
+		t.Fatal(ec)
+	}
+	storeKeyed(3, "k1", "b", 0, enums.EC_NONE)
+	if ec := s.TxControl(3, message.NamestoreTxCommit); ec != enums.EC_NONE {
+		t.Fatal(ec)
+	}
+	if n := count("b"); n != 1 {
+		t.Fatalf("%d records stored", n)
+	}
+	// expired keys are forgotten
+	defer func(ttl time.Duration) { StoreKeyTTL = ttl }(StoreKeyTTL)
+	StoreKeyTTL = 0
+	time.Sleep(time.Millisecond)
+	storeKeyed(1, "k1", "a", 0, enums.EC_NONE)
+	if n := count("a"); n != 3 {
+		t.Fatalf("%d records stored", n)
+	}
+}
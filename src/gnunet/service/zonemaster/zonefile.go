@@ -0,0 +1,385 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"gnunet/enums"
+	"gnunet/service/gns/names"
+	"gnunet/service/gns/rr"
+	"gnunet/service/store"
+	"gnunet/util"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------
+// Zonefiles:
+// A textual representation of the records in a zone (e.g. for migration
+// from the C namestore). The format follows RFC 1035 master files:
+//
+//     $ORIGIN <zone>
+//     $TTL <expiration>
+//     <label> [<expiration>] [<flags>] <type> <value>
+//
+// Lines starting with ';' are comments; a line starting with whitespace
+// continues the label of the previous line; '@' is the apex label. The
+// expiration is 'never', a relative lifetime ("3600" seconds or a
+// duration like "1h30m") or an absolute time (RFC3339); it defaults to
+// the last $TTL. Flags are listed in brackets ("[private,shadow]").
+// Expirations, flags and values are written as in record lines (see
+// rr.RecordLine).
+//----------------------------------------------------------------------
+
+// Error codes
+var (
+	ErrZonefileSyntax = errors.New("zonefile syntax error")
+	ErrZonefileLabel  = errors.New("zonefile record without label")
+	ErrZonefileFlag   = rr.ErrUnknownFlag
+)
+
+// DefaultZonefileTTL is the (relative) expiration of records in a
+// zonefile if neither the record nor a $TTL directive sets one.
+var DefaultZonefileTTL = time.Hour
+
+// ExportZone writes the records of a zone as a zonefile. It returns
+// the number of exported records.
+func (zm *ZoneMaster) ExportZone(name string, w io.Writer) (n int, err error) {
+	zone, err := zm.zdb.GetZoneByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownZone, name)
+	}
+	labels, err := zm.zdb.GetLabels("zid=%d", zone.ID)
+	if err != nil {
+		return
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "; zone '%s' (%s)\n", zone.Name, zone.Key.Public().ID())
+	fmt.Fprintf(bw, "$ORIGIN %s\n", zone.Name)
+	for _, label := range labels {
+		var recs []*store.Record
+		if recs, err = zm.zdb.GetRecords("lid=%d", label.ID); err != nil {
+			return
+		}
+		first := true
+		for _, rec := range recs {
+			if rec.RType == enums.GNS_TYPE_TOMBSTONE {
+				continue
+			}
+			// only the first record of a label names it
+			name := ""
+			if first {
+				name = label.Name
+				first = false
+			}
+			fmt.Fprintf(bw, "%-15s %s", name, rr.FormatExpiration(rec.Expire, rec.Flags))
+			if f := rr.FormatFlags(rec.Flags); len(f) > 0 {
+				fmt.Fprintf(bw, " %s", f)
+			}
+			fmt.Fprintf(bw, " %s %s\n", rr.TypeName(rec.RType), rr.FormatValue(rec.RType, rec.Data))
+			n++
+		}
+	}
+	err = bw.Flush()
+	return
+}
+
+// ImportZone reads records from a zonefile and adds them to a zone in
+// the database. The zone (named or $ORIGIN if name is empty) must
+// exist; missing labels are created. Records already stored under a
+// label (same type, flags and value) are skipped. The zonefile is
+// parsed completely before any record is stored. Returns the number
+// of imported records.
+func (zm *ZoneMaster) ImportZone(name string, r io.Reader) (n int, err error) {
+	origin, entries, err := ReadZonefile(r)
+	if err != nil {
+		return
+	}
+	if len(name) == 0 {
+		name = origin
+	}
+	zone, err := zm.zdb.GetZoneByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownZone, name)
+	}
+	// group records by label (in order of appearance)
+	var order []string
+	sets := make(map[string][]*store.Record)
+	for _, e := range entries {
+		if _, ok := sets[e.Label]; !ok {
+			order = append(order, e.Label)
+		}
+		sets[e.Label] = append(sets[e.Label], e.Record)
+	}
+	for _, label := range order {
+		var num int
+		num, err = zm.addRecords(zone, label, sets[label])
+		n += num
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// AddRecords adds records to a label in a zone; a missing label is
+// created. Records already stored under the label are skipped. Returns
+// the number of added records.
+func (zm *ZoneMaster) AddRecords(name, label string, recs ...*store.Record) (n int, err error) {
+	zone, err := zm.zdb.GetZoneByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownZone, name)
+	}
+	if label, err = names.Normalize(label); err != nil {
+		return
+	}
+	return zm.addRecords(zone, label, recs)
+}
+
+// add records to a (normalized) label in a zone
+func (zm *ZoneMaster) addRecords(zone *store.Zone, label string, list []*store.Record) (n int, err error) {
+	var lbl *store.Label
+	if lbl, err = zm.zdb.GetLabelByName(label, zone.ID, true); err != nil {
+		return
+	}
+	// skip records already stored under the label
+	var stored []*store.Record
+	if stored, err = zm.zdb.GetRecords("lid=%d", lbl.ID); err != nil {
+		return
+	}
+	var recs []*store.Record
+	for _, rec := range list {
+		if !hasRecord(stored, rec) {
+			rec.Label = lbl.ID
+			recs = append(recs, rec)
+		}
+	}
+	if len(recs) == 0 {
+		return
+	}
+	if err = zm.CheckLabelSize(lbl.ID, recs...); err != nil {
+		return
+	}
+	if _, err = zm.zdb.UpdateLabelVersion(lbl.ID, lbl.Version); err != nil {
+		return
+	}
+	for _, rec := range recs {
+		if err = zm.zdb.SetRecord(rec); err != nil {
+			return
+		}
+		n++
+	}
+	return
+}
+
+// ListZone writes the records of a zone as record lines with labels
+// ("<label> <record line>"; see rr.RecordLine). It returns the number of
+// listed records.
+func (zm *ZoneMaster) ListZone(name string, w io.Writer) (n int, err error) {
+	zone, err := zm.zdb.GetZoneByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownZone, name)
+	}
+	labels, err := zm.zdb.GetLabels("zid=%d", zone.ID)
+	if err != nil {
+		return
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	bw := bufio.NewWriter(w)
+	for _, label := range labels {
+		var recs []*store.Record
+		if recs, err = zm.zdb.GetRecords("lid=%d", label.ID); err != nil {
+			return
+		}
+		for _, rec := range recs {
+			if rec.RType == enums.GNS_TYPE_TOMBSTONE {
+				continue
+			}
+			fmt.Fprintf(bw, "%s %s\n", label.Name, rr.RecordLine(&rec.ResourceRecord))
+			n++
+		}
+	}
+	err = bw.Flush()
+	return
+}
+
+// hasRecord returns true if an equivalent record is in the list.
+func hasRecord(list []*store.Record, rec *store.Record) bool {
+	for _, r := range list {
+		if r.RType == rec.RType && r.Flags == rec.Flags && bytes.Equal(r.Data, rec.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+//----------------------------------------------------------------------
+
+// ZoneEntry is a labeled record read from a zonefile.
+type ZoneEntry struct {
+	Label  string        // normalized label
+	Record *store.Record // resource record (not associated with a label)
+}
+
+// ReadZonefile parses a zonefile and returns its origin ($ORIGIN, if
+// set) and the list of records.
+func ReadZonefile(r io.Reader) (origin string, entries []*ZoneEntry, err error) {
+	var (
+		label string
+		ttl   = zoneTTL(DefaultZonefileTTL)
+		num   int
+	)
+	rdr := bufio.NewScanner(r)
+	for rdr.Scan() {
+		num++
+		line := strings.TrimRight(rdr.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == ';' {
+			continue
+		}
+		// handle directives
+		if trimmed[0] == '$' {
+			dir, arg := nextField(trimmed)
+			switch strings.ToUpper(dir) {
+			case "$ORIGIN":
+				origin = strings.TrimSuffix(arg, ".")
+			case "$TTL":
+				var exp *zoneExpire
+				if exp, err = parseZoneExpiration(arg); err != nil {
+					return "", nil, fmt.Errorf("line %d: %w", num, err)
+				}
+				ttl = *exp
+			default:
+				return "", nil, fmt.Errorf("line %d: %w: directive '%s'", num, ErrZonefileSyntax, dir)
+			}
+			continue
+		}
+		// parse record line
+		var e *ZoneEntry
+		if e, err = parseZoneLine(line, label, ttl); err != nil {
+			return "", nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		label = e.Label
+		entries = append(entries, e)
+	}
+	err = rdr.Err()
+	return
+}
+
+// parse a record line (with given previous label and default expiration)
+func parseZoneLine(line, prev string, ttl zoneExpire) (e *ZoneEntry, err error) {
+	e = new(ZoneEntry)
+	// label (or previous label if line starts with whitespace)
+	rest := line
+	if line[0] == ' ' || line[0] == '\t' {
+		if len(prev) == 0 {
+			return nil, ErrZonefileLabel
+		}
+		e.Label = prev
+	} else {
+		var label string
+		label, rest = nextField(line)
+		if e.Label, err = names.Normalize(label); err != nil {
+			return
+		}
+	}
+	// optional expiration (a number is a type if followed by the value)
+	// and flags
+	exp := &ttl
+	field, rest := nextField(rest)
+	if x, xErr := parseZoneExpiration(field); xErr == nil {
+		next, tail := nextField(rest)
+		if _, tErr := rr.ParseType(next); tErr == nil || strings.HasPrefix(next, "[") {
+			exp = x
+			field, rest = next, tail
+		}
+	}
+	var flags enums.GNSFlag
+	if strings.HasPrefix(field, "[") {
+		if flags, err = parseZoneFlags(field); err != nil {
+			return
+		}
+		field, rest = nextField(rest)
+	}
+	// record type and value
+	var t enums.GNSType
+	if t, err = rr.ParseType(field); err != nil {
+		return nil, fmt.Errorf("%w: type '%s'", ErrZonefileSyntax, field)
+	}
+	var data []byte
+	if data, err = rr.ParseValue(t, rest); err != nil {
+		return
+	}
+	if exp.relative {
+		flags |= enums.GNS_FLAG_RELATIVE_EXPIRATION
+	}
+	e.Record = store.NewRecord(exp.ts, t, flags, data)
+	return
+}
+
+// nextField splits off the first whitespace-separated field.
+func nextField(s string) (field, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	if pos := strings.IndexAny(s, " \t"); pos != -1 {
+		return s[:pos], strings.TrimLeft(s[pos:], " \t")
+	}
+	return s, ""
+}
+
+//----------------------------------------------------------------------
+// Zonefile fields
+//----------------------------------------------------------------------
+
+// zoneExpire is the expiration of records in a zonefile
+type zoneExpire struct {
+	ts       util.AbsoluteTime // absolute time or lifetime (relative)
+	relative bool              // relative expiration
+}
+
+// zoneTTL returns a relative expiration
+func zoneTTL(d time.Duration) zoneExpire {
+	return zoneExpire{util.AbsoluteTime{Val: uint64(d.Microseconds())}, true}
+}
+
+// parse expiration ("never", seconds, duration or RFC3339 time)
+func parseZoneExpiration(s string) (*zoneExpire, error) {
+	ts, flags, err := rr.ParseExpiration(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: expiration '%s'", ErrZonefileSyntax, s)
+	}
+	return &zoneExpire{ts, flags&enums.GNS_FLAG_RELATIVE_EXPIRATION != 0}, nil
+}
+
+// parse flags list ("[private,critical]")
+func parseZoneFlags(s string) (flags enums.GNSFlag, err error) {
+	if flags, err = rr.ParseFlags(s); err != nil && !errors.Is(err, rr.ErrUnknownFlag) {
+		err = fmt.Errorf("%w: flags '%s'", ErrZonefileSyntax, s)
+	}
+	return
+}
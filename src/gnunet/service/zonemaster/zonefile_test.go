@@ -0,0 +1,220 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package zonemaster
+
+import (
+	"bytes"
+	"errors"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/gns/rr"
+	"gnunet/service/store"
+	"gnunet/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZonefile(t *testing.T) {
+	zdb, err := store.OpenZoneDB(t.TempDir() + "/zones.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zdb.Close()
+	zm := &ZoneMaster{zdb: zdb}
+
+	// source and target zone
+	newZone := func(name string) *store.Zone {
+		zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zone := store.NewZone(name, zp)
+		if err = zdb.SetZone(zone); err != nil {
+			t.Fatal(err)
+		}
+		return zone
+	}
+	src := newZone("source")
+	newZone("target")
+
+	// records of different types, expirations and flags
+	text := func(rtype enums.GNSType, s string) []byte {
+		buf, err := rr.FromText(rtype, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+	expire := util.NewAbsoluteTime(time.Now().Add(time.Hour).Truncate(time.Second))
+	ttl := util.AbsoluteTime{Val: uint64((90 * time.Minute).Microseconds())}
+	records := map[string][]*store.Record{
+		"@": {
+			store.NewRecord(util.AbsoluteTimeNever(), enums.GNS_TYPE_NICK, 0, text(enums.GNS_TYPE_NICK, "source")),
+		},
+		"www": {
+			store.NewRecord(ttl, enums.GNS_TYPE_DNS_A, enums.GNS_FLAG_RELATIVE_EXPIRATION, text(enums.GNS_TYPE_DNS_A, "192.0.2.1")),
+			store.NewRecord(expire, enums.GNS_TYPE_DNS_AAAA, enums.GNS_FLAG_SHADOW, text(enums.GNS_TYPE_DNS_AAAA, "2001:db8::1")),
+			store.NewRecord(expire, enums.GNS_TYPE_DNS_TXT, enums.GNS_FLAG_PRIVATE, text(enums.GNS_TYPE_DNS_TXT, "hello; world ")),
+		},
+		"mail": {
+			store.NewRecord(expire, enums.GNS_TYPE_DNS_MX, 0, text(enums.GNS_TYPE_DNS_MX, "10 mx.example.com")),
+			store.NewRecord(expire, enums.GNS_TYPE_BOX, enums.GNS_FLAG_CRITICAL, text(enums.GNS_TYPE_BOX, "6 25 A 192.0.2.25")),
+		},
+		"legacy": {
+			store.NewRecord(expire, enums.GNS_TYPE_GNS2DNS, 0, text(enums.GNS_TYPE_GNS2DNS, "example.com@192.0.2.53")),
+			store.NewRecord(expire, enums.GNSType(65500), 0, []byte{1, 2, 3, 4}),
+		},
+	}
+	for name, recs := range records {
+		lbl, err := zdb.GetLabelByName(name, src.ID, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range recs {
+			rec.Label = lbl.ID
+			if err = zdb.SetRecord(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// export source zone
+	buf := new(bytes.Buffer)
+	n, err := zm.ExportZone("source", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 {
+		t.Fatalf("exported %d records, expected 8", n)
+	}
+	zonefile := buf.String()
+
+	// import into target zone (twice: duplicates are skipped)
+	for i, want := range []int{8, 0} {
+		if n, err = zm.ImportZone("target", strings.NewReader(zonefile)); err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("import #%d: %d records, expected %d", i+1, n, want)
+		}
+	}
+	// export of target zone must match (except header)
+	buf.Reset()
+	if _, err = zm.ExportZone("target", buf); err != nil {
+		t.Fatal(err)
+	}
+	body := func(s string) string {
+		return s[strings.Index(s, "\n$ORIGIN"):]
+	}
+	got := strings.Replace(body(buf.String()), "$ORIGIN target", "$ORIGIN source", 1)
+	if got != body(zonefile) {
+		t.Fatalf("zonefile mismatch:\n%s\n--- vs ---\n%s", got, zonefile)
+	}
+
+	// records added as record lines are listed as such
+	rec, err := rr.ParseRecordLine("A 192.0.2.80 3600 [private]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{1, 0} {
+		if n, err = zm.AddRecords("target", "web", store.NewRecord(rec.Expire, rec.RType, rec.Flags, rec.Data)); err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("add #%d: %d records, expected %d", i+1, n, want)
+		}
+	}
+	buf.Reset()
+	if n, err = zm.ListZone("target", buf); err != nil {
+		t.Fatal(err)
+	}
+	if n != 9 || !strings.Contains(buf.String(), "\nweb A 192.0.2.80 3600 [private]\n") {
+		t.Fatalf("unexpected listing (%d records):\n%s", n, buf.String())
+	}
+
+	// import into unknown zone
+	if _, err = zm.ImportZone("unknown", strings.NewReader(zonefile)); !errors.Is(err, ErrUnknownZone) {
+		t.Fatalf("unexpected import result: %v", err)
+	}
+}
+
+func TestReadZonefile(t *testing.T) {
+	zonefile := `; test zone
+$ORIGIN example.
+$TTL 1h
+@            NICK example
+www          A 192.0.2.1
+             300 [private] AAAA 2001:db8::1
+             never TXT v=spf1 -all
+mx  2030-01-01T00:00:00Z  MX  10 mail.example.com
+raw 65500 \# 3 01 02 03
+`
+	origin, entries, err := ReadZonefile(strings.NewReader(zonefile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != "example" {
+		t.Fatalf("origin '%s'", origin)
+	}
+	want := []struct {
+		label string
+		rtype enums.GNSType
+		flags enums.GNSFlag
+		ttl   time.Duration
+	}{
+		{"@", enums.GNS_TYPE_NICK, enums.GNS_FLAG_RELATIVE_EXPIRATION, time.Hour},
+		{"www", enums.GNS_TYPE_DNS_A, enums.GNS_FLAG_RELATIVE_EXPIRATION, time.Hour},
+		{"www", enums.GNS_TYPE_DNS_AAAA, enums.GNS_FLAG_RELATIVE_EXPIRATION | enums.GNS_FLAG_PRIVATE, 5 * time.Minute},
+		{"www", enums.GNS_TYPE_DNS_TXT, 0, 0},
+		{"mx", enums.GNS_TYPE_DNS_MX, 0, 0},
+		{"raw", enums.GNSType(65500), enums.GNS_FLAG_RELATIVE_EXPIRATION, time.Hour},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("%d entries, expected %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Label != w.label || e.Record.RType != w.rtype || e.Record.Flags != w.flags {
+			t.Fatalf("entry #%d: %s %d %d", i, e.Label, e.Record.RType, e.Record.Flags)
+		}
+		if w.ttl != 0 && e.Record.Expire.Val != uint64(w.ttl.Microseconds()) {
+			t.Fatalf("entry #%d: lifetime %d", i, e.Record.Expire.Val)
+		}
+	}
+	if !entries[3].Record.Expire.IsNever() {
+		t.Fatal("TXT record expires")
+	}
+	if !bytes.Equal(entries[5].Record.Data, []byte{1, 2, 3}) {
+		t.Fatal("generic value mismatch")
+	}
+
+	// invalid zonefiles
+	for _, s := range []string{
+		"  A 192.0.2.1",
+		"www FOO bar",
+		"www [unknown] A 192.0.2.1",
+		"www A 300.1.1.1",
+		"$INCLUDE other.zone",
+	} {
+		if _, _, err = ReadZonefile(strings.NewReader(s)); err == nil {
+			t.Fatalf("zonefile '%s' accepted", s)
+		}
+	}
+}
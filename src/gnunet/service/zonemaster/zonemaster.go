@@ -20,19 +20,32 @@ package zonemaster
 
 import (
 	"context"
+	"fmt"
 	"gnunet/config"
 	"gnunet/core"
-	"gnunet/crypto"
 	"gnunet/enums"
+	"gnunet/script"
+	"gnunet/service"
 	"gnunet/service/dht/blocks"
+	"gnunet/service/gns/names"
+	"gnunet/service/metrics"
 	"gnunet/service/store"
 	"gnunet/util"
 	"plugin"
+	"strings"
 	"time"
 
 	"github.com/bfix/gospel/logger"
 )
 
+// ZoneMaster metrics
+var (
+	publications = metrics.NewCounter("gnunet_zonemaster_publications_total",
+		"Label publications by result (published, skipped, failed).", "result")
+	storeRequests = metrics.NewCounter("gnunet_zonemaster_store_requests_total",
+		"Namestore store requests by error code.", "code")
+)
+
 //======================================================================
 // "GNS ZoneMaster" implementation (extended):
 // Manage local identities for subsystems. Manage and publish
@@ -48,20 +61,33 @@ type ZoneMaster struct {
 	hdlrs     map[enums.GNSType]Plugin // maps record types to handling plugin
 	namestore *NamestoreService        // namestore subservice
 	identity  *IdentityService         // identity subservice
+	adaptive  *Adaptive                // republish interval controller
+	ttls      map[enums.GNSType]uint64 // default record lifetimes (relative)
+	cfg       *config.Config           // node configuration
 }
 
-// NewService initializes a new zone master service.
-func NewService(ctx context.Context, c *core.Core, plugins []string) *ZoneMaster {
+// NewService initializes a new zone master service for a node
+// configuration (the zonemaster section is required).
+func NewService(ctx context.Context, c *core.Core, cfg *config.Config, plugins []string) *ZoneMaster {
 	mod := NewModule(ctx, c)
 	srv := &ZoneMaster{
 		Module:  *mod,
 		plugins: make([]Plugin, 0),
 		hdlrs:   make(map[enums.GNSType]Plugin),
+		cfg:     cfg,
 	}
 
 	// set external function references (external services)
 	srv.StoreLocal = srv.StoreNamecache
 	srv.StoreRemote = srv.StoreDHT
+	srv.LookupRemote = srv.LookupDHT
+	srv.Revoke = srv.RevokeZone
+
+	// republish intervals (adaptive if configured)
+	srv.adaptive = NewAdaptive(srv.publishPeriod(), cfg.ZoneMaster.Adaptive)
+
+	// default lifetimes of records stored without expiration
+	srv.ttls = defaultTTLs(cfg.ZoneMaster.DefaultTTL)
 
 	// instantiate sub-services
 	srv.namestore = NewNamestoreService(srv)
@@ -108,9 +134,8 @@ func NewService(ctx context.Context, c *core.Core, plugins []string) *ZoneMaster
 func (zm *ZoneMaster) Run(ctx context.Context) {
 	// connect to database
 	logger.Println(logger.INFO, "[zonemaster] Connecting to zone database...")
-	var err error
-	dbFile, _ := util.GetParam[string](config.Cfg.ZoneMaster.Storage, "file")
-	if zm.zdb, err = store.OpenZoneDB(dbFile); err != nil {
+	err := zm.OpenDatabase()
+	if err != nil {
 		logger.Printf(logger.ERROR, "[zonemaster] open database: %v", err)
 		return
 	}
@@ -119,26 +144,41 @@ func (zm *ZoneMaster) Run(ctx context.Context) {
 	// start HTTP GUI
 	zm.startGUI(ctx)
 
+	// import egos from the identity service
+	if err = zm.ImportEgos(ctx); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] ego import failed: %s", err.Error())
+	}
+
 	// publish on start-up
 	if err = zm.Publish(ctx); err != nil {
 		logger.Printf(logger.ERROR, "[zonemaster] initial publish failed: %s", err.Error())
 	}
 
 	// periodically publish GNS blocks to the DHT
-	tick := time.NewTicker(time.Duration(config.Cfg.ZoneMaster.Period) * time.Second)
-loop:
-	for {
-		select {
-		case <-tick.C:
-			if err := zm.Publish(ctx); err != nil {
-				logger.Printf(logger.ERROR, "[zonemaster] periodic publish failed: %s", err.Error())
-			}
-
-		// check for termination
-		case <-ctx.Done():
-			break loop
+	period := time.Duration(zm.cfg.ZoneMaster.Period) * time.Second
+	if err = service.Schedule(ctx, "zonemaster:publish", period, zm.Publish); err != nil {
+		logger.Printf(logger.ERROR, "[zonemaster] periodic publish not scheduled: %s", err.Error())
+	}
+	if zm.cfg.Identity != nil {
+		if err = service.Schedule(ctx, "zonemaster:egos", period, zm.ImportEgos); err != nil {
+			logger.Printf(logger.ERROR, "[zonemaster] ego import not scheduled: %s", err.Error())
 		}
 	}
+	<-ctx.Done()
+}
+
+// OpenDatabase connects to the zone database (as configured). It is
+// called by Run; a zonemaster that isn't running (e.g. for zonefile
+// import and export) must open and close the database itself.
+func (zm *ZoneMaster) OpenDatabase() (err error) {
+	dbFile, _ := util.GetParam[string](zm.cfg.ZoneMaster.Storage, "file")
+	zm.zdb, err = store.OpenZoneDB(dbFile)
+	return
+}
+
+// CloseDatabase closes the connection to the zone database.
+func (zm *ZoneMaster) CloseDatabase() error {
+	return zm.zdb.Close()
 }
 
 // OnChange is called if a zone or record has changed or was inserted
@@ -218,42 +258,104 @@ func (zm *ZoneMaster) OnChange(table string, id int64, mode int) {
 	}
 }
 
-// Publish all zone labels to the DHT
+// Publish all zone labels that are due (see publication journal) to the
+// DHT. A failed label doesn't stop the cycle; it is retried in the next.
 func (zm *ZoneMaster) Publish(ctx context.Context) error {
 	// collect all zones
 	zones, err := zm.zdb.GetZones("")
 	if err != nil {
 		return err
 	}
+	now := util.AbsoluteTimeNow()
+	var failed []string
 	for _, z := range zones {
+		// compromised zones are not published
+		if zm.compromised(z.ID) {
+			continue
+		}
 		// collect labels for zone
 		var labels []*store.Label
 		if labels, err = zm.zdb.GetLabels("zid=%d", z.ID); err != nil {
 			return err
 		}
 		for _, l := range labels {
+			// skip labels that are not due
+			var p *store.Publication
+			if p, err = zm.zdb.GetPublication(l.ID); err != nil {
+				return err
+			}
+			if !zm.isDue(p, now) {
+				continue
+			}
+			// check availability of previously published block
+			if p.Published.Val != 0 && p.Failures == 0 {
+				zm.probe(ctx, z, l)
+			}
 			// publish label
 			if err = zm.PublishZoneLabel(ctx, z, l); err != nil {
-				return err
+				logger.Printf(logger.WARN, "[zonemaster] Publishing label '%s' failed: %s", l.Name, err.Error())
+				failed = append(failed, l.Name)
 			}
 		}
 	}
+	if zm.adaptive.Enabled() {
+		st := zm.adaptive.Stats()
+		logger.Printf(logger.INFO, "[zonemaster] republish interval %s (availability %.2f, %d/%d found)",
+			st.Interval, st.Availability, st.Found, st.Probes)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("publishing %d label(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
 	return nil
 }
 
-// PublishZoneLabel with public records
+// PublishZoneLabel with public records. The outcome is recorded in the
+// publication journal.
 func (zm *ZoneMaster) PublishZoneLabel(ctx context.Context, zone *store.Zone, label *store.Label) error {
+	published, err := zm.publishZoneLabel(ctx, zone, label)
+	zm.journal(label.ID, published, err)
+	switch {
+	case err != nil:
+		publications.Inc("failed")
+	case published:
+		publications.Inc("published")
+	default:
+		publications.Inc("skipped")
+	}
+	return err
+}
+
+// publish a label; returns false if the label was skipped.
+func (zm *ZoneMaster) publishZoneLabel(ctx context.Context, zone *store.Zone, label *store.Label) (bool, error) {
 	zk := zone.Key.Public()
+	if zm.compromised(zone.ID) {
+		logger.Printf(logger.WARN, "[zonemaster] Zone %s is compromised -- label '%s' skipped", zk.ID(), label.Name)
+		return false, nil
+	}
 	logger.Printf(logger.INFO, "[zonemaster] Publishing label '%s' of zone %s", label.Name, zk.ID())
 
 	// collect all records for label
 	rrSet, expire, err := zm.GetRecordSet(label.ID, enums.GNS_FILTER_NONE)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if rrSet.Count == 0 {
 		logger.Println(logger.INFO, "[zonemaster] No resource records -- skipped")
-		return nil
+		return false, nil
+	}
+	// a record set that exceeds the block size can't be published
+	if err = rrSet.CheckSize(); err != nil {
+		return false, fmt.Errorf("label '%s': %w", label.Name, err)
+	}
+	// ask event scripts if the label should be published
+	if !script.Run(script.HookZonePublish, map[string]any{
+		"zone":    zone.Name,
+		"zkey":    zk.ID(),
+		"label":   label.Name,
+		"records": rrSet.Count,
+	}) {
+		logger.Println(logger.INFO, "[zonemaster] Publication rejected by script -- skipped")
+		return false, nil
 	}
 	// post-process records for publication
 	for _, rec := range rrSet.Records {
@@ -265,8 +367,13 @@ func (zm *ZoneMaster) PublishZoneLabel(ctx context.Context, zone *store.Zone, la
 		}
 	}
 
+	// normalize label name (used for query, encryption and key derivation)
+	var name string
+	if name, err = names.Normalize(label.Name); err != nil {
+		return false, err
+	}
 	// assemble GNS query (common for DHT and Namecache)
-	query := blocks.NewGNSQuery(zk, label.Name)
+	query := blocks.NewGNSQuery(zk, name)
 
 	//------------------------------------------------------------------
 	// Publish to DHT
@@ -287,25 +394,15 @@ func (zm *ZoneMaster) PublishZoneLabel(ctx context.Context, zone *store.Zone, la
 		Records: recsDHT,
 		Padding: nil,
 	}
-	rrsDHT.SetPadding()
 
-	// build block for DHT
-	blkDHT, _ := blocks.NewGNSBlock().(*blocks.GNSBlock)
-	blkDHT.Body.Expire = expire
-	blkDHT.Body.Data, err = zk.Encrypt(rrSet.RDATA(), label.Name, expire)
+	// build (encrypted and signed) block for DHT
+	blkDHT, err := blocks.NewGNSBlockFromRecords(zone.Key, name, rrsDHT, expire)
 	if err != nil {
-		return err
-	}
-	var dzk *crypto.ZonePrivate
-	if dzk, _, err = zone.Key.Derive(label.Name, "gns"); err != nil {
-		return err
-	}
-	if err = blkDHT.Sign(dzk); err != nil {
-		return err
+		return false, err
 	}
 	// publish GNS block to DHT
 	if err = zm.StoreDHT(ctx, query, blkDHT); err != nil {
-		return err
+		return false, err
 	}
 
 	// DEBUG
@@ -319,18 +416,16 @@ func (zm *ZoneMaster) PublishZoneLabel(ctx context.Context, zone *store.Zone, la
 	// Publish to Namecache
 	//------------------------------------------------------------------
 
-	// build block for Namecache
-	blkNC, _ := blocks.NewGNSBlock().(*blocks.GNSBlock)
-	blkNC.Body.Expire = expire
-	blkNC.Body.Data = rrSet.RDATA()
-	// sign block
-	if err = blkNC.Sign(dzk); err != nil {
-		return err
+	// build (encrypted and signed) block for Namecache: it includes
+	// private records for local lookups.
+	blkNC, err := blocks.NewGNSBlockFromRecords(zone.Key, name, rrSet, expire)
+	if err != nil {
+		return false, err
 	}
 
 	// publish GNS block to namecache
 	if err = zm.StoreNamecache(ctx, query, blkNC); err != nil {
-		return err
+		return false, err
 	}
-	return nil
+	return true, nil
 }
@@ -0,0 +1,44 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package testnet is a harness for tests on a network of nodes running
+// in Docker containers. It launches a number of gnunet-go nodes (and
+// optional nodes of the C implementation, like DHTU testbed nodes) on a
+// private container network, waits for the DHT to converge and provides
+// helpers to inspect the nodes in assertions:
+//
+//	net, err := testnet.Start(ctx, &testnet.Config{NumNodes: 5})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer net.Close()
+//	if err = net.WaitConverged(ctx, 2); err != nil {
+//		t.Fatal(err)
+//	}
+//	peers, err := net.Nodes()[0].Peers(ctx)
+//
+// The harness uses the "docker" command line tool; it needs no further
+// dependencies and works the same in CI and on a contributor's machine.
+// Tests using the harness are guarded by the "docker" build tag; use
+//
+//	make test-network
+//
+// to run them. The image for gnunet-go nodes is built from the Dockerfile
+// in this directory (with the module root as build context) unless an
+// existing image is specified.
+package testnet
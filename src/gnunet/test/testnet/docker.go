@@ -0,0 +1,61 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package testnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrNoDocker     = errors.New("docker not available")
+	ErrNotConverged = errors.New("network not converged")
+	ErrNoRPC        = errors.New("node has no RPC endpoint")
+	ErrNoHello      = errors.New("no HELLO for node")
+)
+
+// Available returns true if the docker command can reach a docker daemon.
+func Available(ctx context.Context) bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	_, err := docker(ctx, "info", "--format", "{{.ServerVersion}}")
+	return err == nil
+}
+
+// docker runs a docker command and returns its (trimmed) output.
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) == 0 {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("docker %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,289 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package testnet
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+)
+
+//----------------------------------------------------------------------
+// HELLO compatibility checks: HELLOs are exchanged between gnunet-go and
+// the C implementation as URLs (bootstrapping, command-line tools). A
+// HELLO that doesn't validate on the other side breaks bootstrapping
+// silently, so the checks try to name the cause of a failure.
+//----------------------------------------------------------------------
+
+// Names of HELLO checks
+const (
+	CheckFormat     = "format"     // URL syntax and encoding of elements
+	CheckAddresses  = "addresses"  // address formats
+	CheckExpiration = "expiration" // expiration time
+	CheckSignature  = "signature"  // signature (purpose and signed data)
+)
+
+// MaxHelloTTL is the maximum accepted lifetime of a HELLO.
+var MaxHelloTTL = 30 * 24 * time.Hour
+
+// HelloCheck is the result of a single HELLO check.
+type HelloCheck struct {
+	Name string // name of the check
+	Err  error  // failure (nil if passed)
+}
+
+// HelloReport is the result of all checks for a HELLO URL.
+type HelloReport struct {
+	URL    string        // checked HELLO URL
+	Peer   *util.PeerID  // peer of the HELLO (nil if not parsed)
+	Checks []*HelloCheck // results of the checks
+}
+
+// CheckHello runs the compatibility checks on a HELLO URL.
+func CheckHello(u string) *HelloReport {
+	r := &HelloReport{URL: u}
+	hu, err := uri.ParseHello(u)
+	r.add(CheckFormat, err)
+	if err != nil {
+		return r
+	}
+	r.Peer = hu.Peer
+	r.add(CheckAddresses, checkAddresses(u, hu.Addrs))
+	r.add(CheckExpiration, checkExpiration(hu.Expire))
+	r.add(CheckSignature, checkSignature(hu))
+	return r
+}
+
+// add the result of a check
+func (r *HelloReport) add(name string, err error) {
+	r.Checks = append(r.Checks, &HelloCheck{Name: name, Err: err})
+}
+
+// Failed returns the failed checks.
+func (r *HelloReport) Failed() (list []*HelloCheck) {
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			list = append(list, c)
+		}
+	}
+	return
+}
+
+// Err returns an error describing all failed checks (or nil).
+func (r *HelloReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(failed))
+	for i, c := range failed {
+		msgs[i] = c.Name + ": " + c.Err.Error()
+	}
+	return fmt.Errorf("invalid HELLO: %s", strings.Join(msgs, "; "))
+}
+
+// String returns a human-readable report (one line per check).
+func (r *HelloReport) String() string {
+	buf := new(strings.Builder)
+	buf.WriteString("HELLO " + r.URL + "\n")
+	if r.Peer != nil {
+		buf.WriteString("  peer: " + r.Peer.String() + "\n")
+	}
+	for _, c := range r.Checks {
+		res := "ok"
+		if c.Err != nil {
+			res = "FAILED: " + c.Err.Error()
+		}
+		fmt.Fprintf(buf, "  %-10s %s\n", c.Name+":", res)
+	}
+	return buf.String()
+}
+
+//----------------------------------------------------------------------
+
+// checkAddresses makes sure all addresses have a known format and are
+// signed in the form they are transmitted in.
+func checkAddresses(u string, addrs []*util.Address) error {
+	if len(addrs) == 0 {
+		return fmt.Errorf("no addresses")
+	}
+	// raw (decoded) addresses as found in the URL
+	var raw []string
+	if q := strings.SplitN(u, "?", 2); len(q) == 2 {
+		for _, a := range strings.Split(q[1], "&") {
+			ap := strings.SplitN(a, "=", 2)
+			as, _ := url.QueryUnescape(ap[1])
+			raw = append(raw, ap[0]+"://"+as)
+		}
+	}
+	usable := 0
+	for i, addr := range addrs {
+		// the signature covers the address strings: an address that
+		// changes when parsed can't be verified.
+		if i < len(raw) && raw[i] != addr.URI() {
+			return fmt.Errorf("address '%s' not in canonical form ('%s')", raw[i], addr.URI())
+		}
+		switch addr.Netw {
+		case "ip+udp", "ip+tcp":
+			host, port, err := net.SplitHostPort(addr.String())
+			if err != nil {
+				return fmt.Errorf("address '%s': %s", addr.URI(), err.Error())
+			}
+			if _, err = netip.ParseAddr(host); err != nil {
+				return fmt.Errorf("address '%s': invalid IP '%s'", addr.URI(), host)
+			}
+			if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+				return fmt.Errorf("address '%s': invalid port '%s'", addr.URI(), port)
+			}
+			usable++
+		}
+	}
+	if usable == 0 {
+		return fmt.Errorf("no address usable by gnunet-go (ip+udp, ip+tcp)")
+	}
+	return nil
+}
+
+// checkExpiration makes sure a HELLO is neither expired nor valid for
+// an unreasonable time.
+func checkExpiration(exp util.AbsoluteTime) error {
+	if exp.ExpiredSkew(util.DefaultClockSkew) {
+		return fmt.Errorf("expired at %s", exp)
+	}
+	if exp.Compare(util.AbsoluteTimeNow().Add(MaxHelloTTL)) > 0 {
+		return fmt.Errorf("expiration %s too far in the future (encoded in seconds since epoch?)", exp)
+	}
+	return nil
+}
+
+// checkSignature verifies the HELLO signature. If the signature is
+// invalid, variations of the signed data are checked to name the
+// likely cause of the failure.
+func checkSignature(hu *uri.HelloURI) error {
+	hb := &blocks.HelloBlock{
+		PeerID:    hu.Peer,
+		Signature: hu.Signature,
+		Expire_:   hu.Expire,
+	}
+	hb.SetAddresses(hu.Addrs)
+	ok, err := hb.Verify()
+	if err != nil {
+		return fmt.Errorf("malformed signature: %s", err.Error())
+	}
+	if ok {
+		return nil
+	}
+	pub := ed25519.NewPublicKeyFromBytes(hu.Peer.Data)
+	sig, err := ed25519.NewEdSignatureFromBytes(hu.Signature.Data)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %s", err.Error())
+	}
+	verify := func(purpose enums.SigPurpose, size uint32, addrs []byte) bool {
+		sd := &helloSignedData{
+			Purpose: &crypto.SignaturePurpose{
+				Size:    size,
+				Purpose: purpose,
+			},
+			Expire:   hu.Expire,
+			AddrHash: crypto.Hash(addrs),
+		}
+		buf, err := data.Marshal(sd)
+		if err != nil {
+			return false
+		}
+		ok, err := pub.EdVerify(buf, sig)
+		return err == nil && ok
+	}
+	addrs := helloAddrs(hu.Addrs, false, true)
+	// signature purpose
+	for p := enums.SigPurpose(0); p < 64; p++ {
+		if p != enums.SIG_HELLO && verify(p, helloSignedSize, addrs) {
+			return fmt.Errorf("signed with purpose %s (%d) instead of %s (%d)", p, p, enums.SIG_HELLO, enums.SIG_HELLO)
+		}
+	}
+	// size of signed data
+	for _, size := range []uint32{0, 16, 72, 88} {
+		if verify(enums.SIG_HELLO, size, addrs) {
+			return fmt.Errorf("signed data size %d instead of %d", size, helloSignedSize)
+		}
+	}
+	// address hash
+	variants := []struct {
+		hint  string
+		addrs []byte
+	}{
+		{"addresses hashed in sorted order", helloAddrs(hu.Addrs, true, true)},
+		{"addresses hashed without terminating 0-bytes", helloAddrs(hu.Addrs, false, false)},
+		{"addresses hashed in sorted order without terminating 0-bytes", helloAddrs(hu.Addrs, true, false)},
+		{"no addresses hashed", nil},
+	}
+	for _, v := range variants {
+		if verify(enums.SIG_HELLO, helloSignedSize, v.addrs) {
+			return fmt.Errorf("%s", v.hint)
+		}
+	}
+	// the URL carries the expiration in seconds: a signature over a more
+	// precise timestamp can't be verified.
+	return fmt.Errorf("signature mismatch (expiration signed with sub-second precision or different addresses)")
+}
+
+// helloSignedData is the data signed for a HELLO (see blocks.HelloBlock)
+type helloSignedData struct {
+	Purpose  *crypto.SignaturePurpose // signature purpose
+	Expire   util.AbsoluteTime        // expiration time
+	AddrHash *crypto.HashCode         // address hash
+}
+
+// size of the signed HELLO data
+const helloSignedSize = 80
+
+// helloAddrs returns the binary representation of addresses used for
+// the address hash (optionally sorted and 0-terminated).
+func helloAddrs(addrs []*util.Address, sorted, term bool) []byte {
+	list := make([]string, len(addrs))
+	for i, a := range addrs {
+		list[i] = a.URI()
+	}
+	if sorted {
+		sort.Strings(list)
+	}
+	buf := new(bytes.Buffer)
+	for _, a := range list {
+		buf.WriteString(a)
+		if term {
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package testnet
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/service/dht/blocks"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/bfix/gospel/crypto/ed25519"
+	"github.com/bfix/gospel/data"
+)
+
+// testHello returns a HELLO URL signed with given purpose and address
+// data (sorted and/or 0-terminated).
+func testHello(t *testing.T, addrs []string, ttl time.Duration, purpose enums.SigPurpose, sorted bool) string {
+	t.Helper()
+	pk, sk := ed25519.NewKeypair()
+	list := make([]*util.Address, len(addrs))
+	for i, a := range addrs {
+		p := strings.SplitN(a, "://", 2)
+		list[i] = util.NewAddress(p[0], p[1])
+	}
+	hu := &uri.HelloURI{
+		Peer:   util.NewPeerID(pk.Bytes()),
+		Expire: util.NewAbsoluteTimeEpoch(uint64(time.Now().Add(ttl).Unix())),
+		Addrs:  list,
+	}
+	sd := &helloSignedData{
+		Purpose: &crypto.SignaturePurpose{
+			Size:    helloSignedSize,
+			Purpose: purpose,
+		},
+		Expire:   hu.Expire,
+		AddrHash: crypto.Hash(helloAddrs(list, sorted, true)),
+	}
+	buf, err := data.Marshal(sd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sk.EdSign(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hu.Signature = util.NewPeerSignature(sig.Bytes())
+	return hu.String()
+}
+
+// Test HELLO of gnunet-go
+func TestCheckHelloGo(t *testing.T) {
+	pk, sk := ed25519.NewKeypair()
+	hb := blocks.InitHelloBlock(util.NewPeerID(pk.Bytes()), []*util.Address{
+		util.NewAddress("ip+udp", "172.17.0.6:2086"),
+		util.NewAddress("ip+tcp", "[2001:db8::1]:2086"),
+	}, time.Hour)
+	sig, err := sk.EdSign(hb.SignedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb.Signature = util.NewPeerSignature(sig.Bytes())
+	if r := CheckHello(hb.URL()); r.Err() != nil {
+		t.Fatal(r)
+	}
+}
+
+// Test diagnostics of HELLO checks
+func TestCheckHello(t *testing.T) {
+	addrs := []string{"ip+udp://172.17.0.6:2086", "ip+udp://10.0.0.1:10000"}
+	for _, tc := range []struct {
+		name  string
+		url   string
+		check string // failed check (empty if valid)
+		hint  string // expected part of the diagnostics
+	}{
+		{"valid", testHello(t, addrs, time.Hour, enums.SIG_HELLO, false), "", ""},
+		{"purpose", testHello(t, addrs, time.Hour, enums.SIG_TRANSPORT_PONG_OWN, false), CheckSignature, "purpose"},
+		{"order", testHello(t, addrs, time.Hour, enums.SIG_HELLO, true), CheckSignature, "sorted order"},
+		{"expired", testHello(t, addrs, -time.Hour, enums.SIG_HELLO, false), CheckExpiration, "expired"},
+		{"lifetime", testHello(t, addrs, 365*24*time.Hour, enums.SIG_HELLO, false), CheckExpiration, "future"},
+		{"address", testHello(t, []string{"ip+udp://172.17.0.6"}, time.Hour, enums.SIG_HELLO, false), CheckAddresses, "172.17.0.6"},
+		{"format", "gnunet://hello/ABC/DEF/0?", CheckFormat, ""},
+	} {
+		r := CheckHello(tc.url)
+		failed := r.Failed()
+		if len(tc.check) == 0 {
+			if err := r.Err(); err != nil {
+				t.Errorf("%s: %s", tc.name, err.Error())
+			}
+			continue
+		}
+		if len(failed) != 1 || failed[0].Name != tc.check {
+			t.Errorf("%s: unexpected result:\n%s", tc.name, r)
+			continue
+		}
+		if !strings.Contains(failed[0].Err.Error(), tc.hint) {
+			t.Errorf("%s: missing hint '%s' in '%s'", tc.name, tc.hint, failed[0].Err.Error())
+		}
+	}
+}
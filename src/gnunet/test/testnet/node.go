@@ -0,0 +1,179 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package testnet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gnunet/service/dht"
+	"gnunet/util"
+	"gnunet/util/uri"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+// Node in a test network (running in a container).
+type Node struct {
+	Name   string // container name
+	Kind   string // node kind (KindGo or KindDHTU)
+	IP     string // address in the container network
+	PeerID string // peer identifier (gnunet-go nodes only)
+
+	rpc   string   // local JSON-RPC endpoint (gnunet-go nodes only)
+	hello []string // HELLO command (DHTU nodes only)
+}
+
+// Call a JSON-RPC method on the node.
+func (nd *Node) Call(ctx context.Context, method string, args, reply any) error {
+	if len(nd.rpc) == 0 {
+		return ErrNoRPC
+	}
+	buf, err := json2.EncodeClientRequest(method, args)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+nd.rpc+"/", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json2.DecodeClientResponse(resp.Body, reply)
+}
+
+// Status returns the DHT status of the node for given topics.
+func (nd *Node) Status(ctx context.Context, topics ...string) (map[string]string, error) {
+	reply := new(dht.StatusResponse)
+	if err := nd.Call(ctx, "DHT.Status", &dht.StatusRequest{Topics: topics}, reply); err != nil {
+		return nil, err
+	}
+	return reply.Messages, nil
+}
+
+// Peers returns the number of peers in the routing table of the node.
+func (nd *Node) Peers(ctx context.Context) (int, error) {
+	status, err := nd.Status(ctx, "peers")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(status["peers"])
+}
+
+// Blocks returns the content of the local DHT store of the node.
+func (nd *Node) Blocks(ctx context.Context) (list []*dht.DumpEntry, err error) {
+	const fname = "/tmp/testnet-dump.json"
+	if err = nd.Call(ctx, "DHT.Export", &dht.ExportRequest{File: fname}, new(dht.ExportResponse)); err != nil {
+		return
+	}
+	var out string
+	if out, err = nd.Exec(ctx, "cat", fname); err != nil {
+		return
+	}
+	rdr := bufio.NewScanner(strings.NewReader(out))
+	rdr.Buffer(make([]byte, 65536), 1<<20)
+	for rdr.Scan() {
+		entry := new(dht.DumpEntry)
+		if err = json.Unmarshal(rdr.Bytes(), entry); err != nil {
+			return
+		}
+		list = append(list, entry)
+	}
+	err = rdr.Err()
+	return
+}
+
+// Hello returns the HELLO URL of the node: gnunet-go nodes report it over
+// JSON-RPC, DHTU nodes print it with the HELLO command.
+func (nd *Node) Hello(ctx context.Context) (string, error) {
+	if nd.Kind == KindDHTU {
+		return nd.Exec(ctx, nd.hello...)
+	}
+	reply := new(dht.HellosResponse)
+	if err := nd.Call(ctx, "DHT.Hellos", &dht.HellosRequest{Self: true}, reply); err != nil {
+		return "", err
+	}
+	for _, u := range reply.Hellos {
+		if hu, err := uri.ParseHello(u); err == nil && hu.Peer.String() == nd.PeerID {
+			return u, nil
+		}
+	}
+	return "", ErrNoHello
+}
+
+// Hellos returns the HELLO URLs of all peers validated by a gnunet-go node.
+func (nd *Node) Hellos(ctx context.Context) ([]string, error) {
+	reply := new(dht.HellosResponse)
+	if err := nd.Call(ctx, "DHT.Hellos", &dht.HellosRequest{All: true}, reply); err != nil {
+		return nil, err
+	}
+	return reply.Hellos, nil
+}
+
+// OfferHello passes a HELLO URL to a DHTU node with the HELLO command.
+// The command fails (with diagnostics in the returned error) if the node
+// rejects the HELLO.
+func (nd *Node) OfferHello(ctx context.Context, u string) error {
+	if nd.Kind != KindDHTU {
+		return ErrNoHello
+	}
+	args := append(util.Clone(nd.hello), u)
+	_, err := nd.Exec(ctx, args...)
+	return err
+}
+
+// Exec runs a command in the container of the node and returns its output.
+func (nd *Node) Exec(ctx context.Context, args ...string) (string, error) {
+	return docker(ctx, append([]string{"exec", nd.Name}, args...)...)
+}
+
+// Logs returns the log output of the node.
+func (nd *Node) Logs(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", "logs", nd.Name).CombinedOutput()
+	return string(out), err
+}
+
+// Stop the node (the container is kept and can be restarted).
+func (nd *Node) Stop(ctx context.Context) error {
+	_, err := docker(ctx, "stop", nd.Name)
+	return err
+}
+
+// Restart a stopped node.
+func (nd *Node) Restart(ctx context.Context) (err error) {
+	if _, err = docker(ctx, "start", nd.Name); err != nil || len(nd.rpc) == 0 {
+		return
+	}
+	// the published RPC port changes on restart
+	var ep string
+	if ep, err = docker(ctx, "port", nd.Name, strconv.Itoa(RPCPort)+"/tcp"); err == nil {
+		nd.rpc = strings.SplitN(ep, "\n", 2)[0]
+	}
+	return
+}
@@ -0,0 +1,346 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package testnet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gnunet/config"
+	"gnunet/core"
+	"gnunet/util"
+)
+
+// Default settings
+const (
+	DefaultImage  = "gnunet-go-testnet" // image for gnunet-go nodes
+	DefaultSubnet = "10.231.0.0/24"     // subnet of the container network
+	NodePort      = 2086                // UDP port of gnunet-go nodes
+	RPCPort       = 8080                // JSON-RPC port of gnunet-go nodes
+	DHTUPort      = 10000               // default UDP port of DHTU nodes
+)
+
+// Node kinds
+const (
+	KindGo   = "gnunet-go" // gnunet-go node
+	KindDHTU = "dhtu"      // DHTU node (C implementation)
+)
+
+//----------------------------------------------------------------------
+// Configuration
+//----------------------------------------------------------------------
+
+// Config for a test network.
+type Config struct {
+	Name     string      // name of network and prefix for containers (random if empty)
+	Image    string      // image for gnunet-go nodes (default: DefaultImage)
+	Build    string      // build context (module root) for the image; no build if empty
+	NumNodes int         // number of gnunet-go nodes
+	DHTU     *DHTUConfig // DHTU nodes (optional)
+	Subnet   string      // subnet of the container network (default: DefaultSubnet)
+	Dir      string      // directory for node configurations (temporary if empty)
+	LogLevel int         // log level of gnunet-go nodes (0 = default)
+}
+
+// DHTUConfig for nodes of the C implementation (DHTU testbed). The image
+// must start a single DHTU node listening on all addresses.
+type DHTUConfig struct {
+	Image string   // image with the DHTU testbed
+	Cmd   []string // command to start a node (image default if empty)
+	Num   int      // number of nodes
+	Port  int      // UDP port of nodes (default: DHTUPort)
+	Hello []string // command to print/offer HELLO URLs (default: DHTUHelloCmd)
+}
+
+// DHTUHelloCmd is the default command in DHTU containers to print the
+// HELLO URL of the node (or to offer a HELLO URL given as argument).
+var DHTUHelloCmd = []string{"gnunet-dht-hello"}
+
+//----------------------------------------------------------------------
+// Network of nodes in containers
+//----------------------------------------------------------------------
+
+// Network of running nodes.
+type Network struct {
+	cfg    *Config // network configuration
+	tmpDir bool    // configuration directory is temporary
+	nodes  []*Node // gnunet-go nodes
+	dhtu   []*Node // DHTU nodes
+	netOK  bool    // container network created
+}
+
+// Start a test network: builds the node image (if requested), creates a
+// container network and starts all nodes. The first gnunet-go node and
+// all DHTU nodes are bootstrap peers for the gnunet-go nodes.
+func Start(ctx context.Context, cfg *Config) (n *Network, err error) {
+	if !Available(ctx) {
+		return nil, ErrNoDocker
+	}
+	// apply defaults
+	c := *cfg
+	if len(c.Name) == 0 {
+		c.Name = "gnunet-testnet-" + hex.EncodeToString(util.NewRndArray(4))
+	}
+	if len(c.Image) == 0 {
+		c.Image = DefaultImage
+	}
+	if len(c.Subnet) == 0 {
+		c.Subnet = DefaultSubnet
+	}
+	n = &Network{cfg: &c}
+	if len(c.Dir) == 0 {
+		if c.Dir, err = os.MkdirTemp("", c.Name+"-"); err != nil {
+			return nil, err
+		}
+		n.tmpDir = true
+	}
+	// clean up on failure
+	defer func() {
+		if err != nil {
+			n.Close()
+			n = nil
+		}
+	}()
+
+	// build image for gnunet-go nodes
+	if len(c.Build) > 0 {
+		dockerfile := filepath.Join(c.Build, "test", "testnet", "Dockerfile")
+		if _, err = docker(ctx, "build", "-q", "-t", c.Image, "-f", dockerfile, c.Build); err != nil {
+			return
+		}
+	}
+	// create container network
+	var prefix netip.Prefix
+	if prefix, err = netip.ParsePrefix(c.Subnet); err != nil {
+		return
+	}
+	if _, err = docker(ctx, "network", "create", "--subnet", c.Subnet, c.Name); err != nil {
+		return
+	}
+	n.netOK = true
+
+	// assign addresses (starting at host 10)
+	ip := prefix.Addr()
+	for i := 0; i < 10; i++ {
+		ip = ip.Next()
+	}
+	nextIP := func() (addr string, err error) {
+		if !prefix.Contains(ip) {
+			return "", fmt.Errorf("subnet %s exhausted", c.Subnet)
+		}
+		addr, ip = ip.String(), ip.Next()
+		return
+	}
+
+	// start DHTU nodes
+	bootstrap := make([]string, 0)
+	if d := c.DHTU; d != nil {
+		port := d.Port
+		if port == 0 {
+			port = DHTUPort
+		}
+		hello := d.Hello
+		if len(hello) == 0 {
+			hello = DHTUHelloCmd
+		}
+		for i := 0; i < d.Num; i++ {
+			node := &Node{
+				Name:  fmt.Sprintf("%s-dhtu-%d", c.Name, i),
+				Kind:  KindDHTU,
+				hello: hello,
+			}
+			if node.IP, err = nextIP(); err != nil {
+				return
+			}
+			n.dhtu = append(n.dhtu, node)
+			args := []string{"run", "-d", "--name", node.Name, "--network", c.Name, "--ip", node.IP, d.Image}
+			if _, err = docker(ctx, append(args, d.Cmd...)...); err != nil {
+				return
+			}
+			bootstrap = append(bootstrap, fmt.Sprintf("ip+udp://%s:%d", node.IP, port))
+		}
+	}
+	// start gnunet-go nodes
+	numPeers := c.NumNodes + len(n.dhtu)
+	for i := 0; i < c.NumNodes; i++ {
+		node := &Node{
+			Name: fmt.Sprintf("%s-node-%d", c.Name, i),
+			Kind: KindGo,
+		}
+		if node.IP, err = nextIP(); err != nil {
+			return
+		}
+		n.nodes = append(n.nodes, node)
+		if err = n.startNode(ctx, node, bootstrap, numPeers); err != nil {
+			return
+		}
+		// the first node bootstraps all others
+		if i == 0 {
+			bootstrap = append(bootstrap, fmt.Sprintf("ip+udp://%s:%d", node.IP, NodePort))
+		}
+	}
+	return
+}
+
+// startNode writes the configuration for a gnunet-go node and starts its
+// container.
+func (n *Network) startNode(ctx context.Context, node *Node, bootstrap []string, numPeers int) (err error) {
+	// generate node configuration
+	seed := base64.StdEncoding.EncodeToString(util.NewRndArray(32))
+	cfg := nodeConfig(node, seed, bootstrap, numPeers, n.cfg.LogLevel)
+	var peer *core.Peer
+	if peer, err = core.NewLocalPeer(cfg.Local); err != nil {
+		return
+	}
+	node.PeerID = peer.GetIDString()
+
+	// write configuration file
+	dir := filepath.Join(n.cfg.Dir, node.Name)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	var buf []byte
+	if buf, err = json.MarshalIndent(cfg, "", "    "); err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(dir, "gnunet-config.json"), buf, 0o644); err != nil {
+		return
+	}
+	// start container (publish RPC port on a random local port)
+	if _, err = docker(ctx, "run", "-d",
+		"--name", node.Name,
+		"--network", n.cfg.Name,
+		"--ip", node.IP,
+		"-p", fmt.Sprintf("127.0.0.1::%d/tcp", RPCPort),
+		"-v", dir+":/etc/gnunet:ro",
+		n.cfg.Image,
+	); err != nil {
+		return
+	}
+	var ep string
+	if ep, err = docker(ctx, "port", node.Name, fmt.Sprintf("%d/tcp", RPCPort)); err != nil {
+		return
+	}
+	// use first mapping only
+	node.rpc = strings.SplitN(ep, "\n", 2)[0]
+	return
+}
+
+// nodeConfig returns the configuration of a gnunet-go node.
+func nodeConfig(node *Node, seed string, bootstrap []string, numPeers, logLevel int) *config.Config {
+	return &config.Config{
+		Local: &config.NodeConfig{
+			Name:        node.Name,
+			PrivateSeed: seed,
+			Endpoints: []*config.EndpointConfig{
+				{
+					ID:      "r5n",
+					Network: "ip+udp",
+					Address: node.IP,
+					Port:    NodePort,
+					TTL:     86400,
+				},
+			},
+		},
+		Network: &config.NetworkConfig{
+			Bootstrap: bootstrap,
+			NumPeers:  numPeers,
+		},
+		DHT: &config.DHTConfig{
+			Service: &config.ServiceConfig{
+				Socket: "/tmp/gnunet-service-dht-go.sock",
+				Params: map[string]string{"perm": "0770"},
+			},
+			Storage: util.ParameterSet{
+				"mode":  "file",
+				"cache": false,
+				"path":  "/data/dht",
+				"maxGB": 1,
+			},
+			Routing: &config.RoutingConfig{
+				PeerTTL:   10800,
+				ReplLevel: 5,
+			},
+			Heartbeat: 60,
+		},
+		RPC: &config.RPCConfig{
+			Endpoint: fmt.Sprintf("0.0.0.0:%d", RPCPort),
+		},
+		Logging: &config.LoggingConfig{
+			Level: logLevel,
+		},
+	}
+}
+
+// Nodes returns the gnunet-go nodes of the network.
+func (n *Network) Nodes() []*Node {
+	return n.nodes
+}
+
+// DHTUNodes returns the DHTU nodes of the network.
+func (n *Network) DHTUNodes() []*Node {
+	return n.dhtu
+}
+
+// WaitConverged waits until every gnunet-go node has at least minPeers
+// peers in its routing table (or the context is done).
+func (n *Network) WaitConverged(ctx context.Context, minPeers int) error {
+	for {
+		missing := make([]string, 0)
+		for _, node := range n.nodes {
+			num, err := node.Peers(ctx)
+			if err != nil || num < minPeers {
+				missing = append(missing, fmt.Sprintf("%s (%d peers)", node.Name, num))
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s", ErrNotConverged, strings.Join(missing, ", "))
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Close stops and removes all containers and the container network.
+func (n *Network) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, list := range [][]*Node{n.nodes, n.dhtu} {
+		for _, node := range list {
+			_, _ = docker(ctx, "rm", "-f", node.Name)
+		}
+	}
+	if n.netOK {
+		_, _ = docker(ctx, "network", "rm", n.cfg.Name)
+	}
+	if n.tmpDir {
+		os.RemoveAll(n.cfg.Dir)
+	}
+}
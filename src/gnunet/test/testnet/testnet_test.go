@@ -0,0 +1,177 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+//go:build docker
+
+package testnet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestNetworkConverge starts a small network of gnunet-go nodes and
+// waits for all nodes to know each other.
+func TestNetworkConverge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if !Available(ctx) {
+		t.Skip(ErrNoDocker)
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := Start(ctx, &Config{
+		Build:    root,
+		NumNodes: 3,
+		Dir:      t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer net.Close()
+
+	wctx, wcancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer wcancel()
+	if err = net.WaitConverged(wctx, 2); err != nil {
+		for _, node := range net.Nodes() {
+			if logs, err := node.Logs(ctx); err == nil {
+				t.Logf("%s:\n%s", node.Name, logs)
+			}
+		}
+		t.Fatal(err)
+	}
+	// local stores are accessible for assertions
+	for _, node := range net.Nodes() {
+		if _, err = node.Blocks(ctx); err != nil {
+			t.Fatalf("%s: %s", node.Name, err.Error())
+		}
+	}
+}
+
+// TestHelloExchangeDHTU checks that HELLOs of gnunet-go nodes validate in
+// DHTU nodes of the C implementation and vice versa. The image of the
+// DHTU nodes is taken from the environment variable GNUNET_DHTU_IMAGE;
+// the test is skipped if it is not set.
+func TestHelloExchangeDHTU(t *testing.T) {
+	image := os.Getenv("GNUNET_DHTU_IMAGE")
+	if len(image) == 0 {
+		t.Skip("GNUNET_DHTU_IMAGE not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if !Available(ctx) {
+		t.Skip(ErrNoDocker)
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	net, err := Start(ctx, &Config{
+		Build:    root,
+		NumNodes: 1,
+		DHTU:     &DHTUConfig{Image: image, Num: 2},
+		Dir:      t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer net.Close()
+	node := net.Nodes()[0]
+
+	// dump logs of all nodes on failure
+	defer func() {
+		if !t.Failed() {
+			return
+		}
+		for _, nd := range append(net.Nodes(), net.DHTUNodes()...) {
+			if logs, err := nd.Logs(ctx); err == nil {
+				t.Logf("%s:\n%s", nd.Name, logs)
+			}
+		}
+	}()
+
+	// HELLO of the gnunet-go node: passes the checks and is accepted by
+	// the C nodes.
+	wctx, wcancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer wcancel()
+	var goHello string
+	for {
+		if goHello, err = node.Hello(wctx); err == nil {
+			break
+		}
+		select {
+		case <-wctx.Done():
+			t.Fatalf("no HELLO from %s: %s", node.Name, err.Error())
+		case <-time.After(time.Second):
+		}
+	}
+	if r := CheckHello(goHello); r.Err() != nil {
+		t.Fatalf("HELLO of gnunet-go node:\n%s", r)
+	}
+	for _, dhtu := range net.DHTUNodes() {
+		if err = dhtu.OfferHello(ctx, goHello); err != nil {
+			t.Errorf("HELLO of gnunet-go node rejected by %s: %s\n%s", dhtu.Name, err.Error(), CheckHello(goHello))
+		}
+	}
+
+	// HELLOs of the C nodes: pass the checks and are validated by the
+	// gnunet-go node.
+	peers := make(map[string]string)
+	for _, dhtu := range net.DHTUNodes() {
+		u, err := dhtu.Hello(ctx)
+		if err != nil {
+			t.Errorf("no HELLO from %s: %s", dhtu.Name, err.Error())
+			continue
+		}
+		r := CheckHello(u)
+		if r.Err() != nil {
+			t.Errorf("HELLO of %s:\n%s", dhtu.Name, r)
+			continue
+		}
+		peers[r.Peer.String()] = dhtu.Name
+	}
+	if t.Failed() {
+		return
+	}
+	for len(peers) > 0 {
+		list, err := node.Hellos(wctx)
+		if err == nil {
+			for _, u := range list {
+				if r := CheckHello(u); r.Peer != nil {
+					delete(peers, r.Peer.String())
+				}
+			}
+		}
+		if len(peers) == 0 {
+			break
+		}
+		select {
+		case <-wctx.Done():
+			for _, name := range peers {
+				t.Errorf("HELLO of %s not validated by %s", name, node.Name)
+			}
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -22,10 +22,12 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"gnunet/message"
 	"gnunet/util"
 	"io"
 	"net"
+	"os"
 	"sync"
 	"time"
 
@@ -42,6 +44,7 @@ var (
 	ErrEndpMaybeSent        = errors.New("message may have been sent - can't know")
 	ErrEndpWriteShort       = errors.New("write too short")
 	ErrEndpReadShort        = errors.New("read too short")
+	ErrEndpBusy             = errors.New("endpoint busy - send queue full")
 )
 
 // Endpoint represents a local endpoint that can send and receive messages.
@@ -66,19 +69,46 @@ type Endpoint interface {
 
 //----------------------------------------------------------------------
 
-// NewEndpoint returns a suitable endpoint for the address.
-func NewEndpoint(addr net.Addr) (ep Endpoint, err error) {
+// NewEndpoint returns a suitable endpoint for the address. The TLS
+// configuration is only used by HTTPS endpoints, the fragmentation
+// settings only by packet-oriented endpoints (defaults if nil).
+func NewEndpoint(addr net.Addr, tlsCfg *util.TLSConfig, fragCfg *util.FragmentConfig) (ep Endpoint, err error) {
 	switch epMode(addr.Network()) {
 	case "packet":
-		ep, err = newPacketEndpoint(addr)
+		ep, err = newPacketEndpoint(addr, fragCfg)
 	case "stream":
 		ep, err = newStreamEndpoint(addr)
+	case "http":
+		ep, err = newHTTPEndpoint(addr, tlsCfg)
 	default:
 		err = ErrEndpNotAvailable
 	}
 	return
 }
 
+// failures reports the failure of a running endpoint (like a broken
+// listener) to a monitor. Only the first failure is kept until it is
+// read.
+type failures chan error
+
+// newFailures creates a new failure channel.
+func newFailures() failures {
+	return make(chan error, 1)
+}
+
+// report a failure of the endpoint
+func (f failures) report(err error) {
+	select {
+	case f <- err:
+	default:
+	}
+}
+
+// Failed returns a channel for failures of the running endpoint.
+func (f failures) Failed() <-chan error {
+	return f
+}
+
 //----------------------------------------------------------------------
 // Packet-oriented endpoint
 //----------------------------------------------------------------------
@@ -86,12 +116,13 @@ func NewEndpoint(addr net.Addr) (ep Endpoint, err error) {
 // PacketEndpoint for packet-oriented network protocols
 type PaketEndpoint struct {
 	sync.Mutex
+	failures
 
 	id   int            // endpoint identifier
 	netw string         // network identifier ("udp", "udp4", "udp6", ...)
 	addr net.Addr       // endpoint address
 	conn net.PacketConn // packet connection
-	buf  []byte         // buffer for read/write operations
+	frag *fragmenter    // fragmentation of large messages
 }
 
 // Run packet endpoint: send incoming messages to the handler.
@@ -99,82 +130,124 @@ func (ep *PaketEndpoint) Run(ctx context.Context, hdlr chan *Message) (err error
 	// create listener
 	var lc net.ListenConfig
 	xproto := ep.addr.Network()
-	if ep.conn, err = lc.ListenPacket(ctx, EpProtocol(xproto), ep.addr.String()); err != nil {
+	var conn net.PacketConn
+	if conn, err = lc.ListenPacket(ctx, EpProtocol(xproto), ep.addr.String()); err != nil {
 		return
 	}
+	ep.Lock()
+	ep.conn = conn
 	// use the actual listening address
-	ep.addr = util.NewAddress(xproto, ep.conn.LocalAddr().String())
-
+	ep.addr = util.NewAddress(xproto, conn.LocalAddr().String())
+	label := ep.addr.String()
 	// save more information to detect compatible send-to addresses
-	ep.netw = ep.conn.LocalAddr().Network()
+	ep.netw = conn.LocalAddr().Network()
+	ep.Unlock()
 
 	// run watch dog for termination
-	active := true
 	go func() {
 		<-ctx.Done()
-		active = false
-		ep.conn.Close()
+		ep.frag.stop()
+		conn.Close()
 	}()
 	// run go routine to handle messages from clients
 	go func() {
+		buf := make([]byte, 65536)
 		for {
 			// read next message
-			tm, err := ep.read()
+			tm, err := ep.read(conn, buf)
 			if err != nil {
 				// leave go routine if already dead or closed by client
-				if !active || err == io.EOF {
+				if ctx.Err() != nil || err == io.EOF {
+					break
+				}
+				// a closed connection is a failure of the endpoint
+				if errors.Is(err, net.ErrClosed) {
+					ep.report(err)
 					break
 				}
 				logger.Println(logger.WARN, "[pkt_ep] read failed: "+err.Error())
 				// gracefully ignore failed messages
 				continue
 			}
+			// fragments are handled by the endpoint
+			if tm == nil {
+				continue
+			}
 			// label message
-			tm.Label = ep.addr.String()
+			tm.Label = label
 			// send transport message to handler
 			go func() {
 				hdlr <- tm
 			}()
 		}
 		// connection ended.
-		ep.conn.Close()
+		conn.Close()
 	}()
 	return
 }
 
 // Read a transport message from endpoint based on extended protocol
-func (ep *PaketEndpoint) read() (tm *Message, err error) {
+func (ep *PaketEndpoint) read(conn net.PacketConn, buf []byte) (tm *Message, err error) {
 	// read next packet (assuming that it contains one complete message)
-	var n int
-	if n, _, err = ep.conn.ReadFrom(ep.buf); err != nil {
+	var (
+		n    int
+		from net.Addr
+	)
+	if n, from, err = conn.ReadFrom(buf); err != nil {
 		return
 	}
-	// parse transport message based on extended protocol
+	// parse transport message
 	var (
 		peer *util.PeerID
 		msg  message.Message
 	)
-	switch ep.addr.Network() {
-	case "ip+udp":
-		// check for minimum size (32 byte peer id + 4 byte header)
-		if n < 36 {
-			err = ErrEndpReadShort
+	if peer, msg, err = ep.parse(util.Clone(buf[:n]), buf); err != nil {
+		return
+	}
+	// handle fragmentation
+	switch m := msg.(type) {
+	case *message.FragmentAckMsg:
+		// retransmit fragments reported missing
+		if addr, list := ep.frag.acknowledged(m); len(list) > 0 {
+			ep.sendPackets(addr, list)
+		}
+		return
+	case *message.FragmentMsg:
+		// reassemble packet (and acknowledge fragments)
+		var (
+			pkt []byte
+			ack *message.FragmentAckMsg
+		)
+		if pkt, ack, err = ep.frag.assemble(from, m); err != nil {
 			return
 		}
-		// parse peer id and message in sequence
-		peer = util.NewPeerID(ep.buf[:32])
-		rdr := bytes.NewBuffer(util.Clone(ep.buf[32:n]))
-		if msg, err = ReadMessageDirect(rdr, ep.buf); err != nil {
+		if ack != nil {
+			if a, ok := from.(*net.UDPAddr); ok {
+				var out []byte
+				if out, err = NewTransportMessage(nil, ack).Bytes(); err != nil {
+					return
+				}
+				ep.sendPackets(a, [][]byte{out})
+			}
+		}
+		if pkt == nil {
 			return
 		}
-	default:
-		panic(ErrEndpProtocolUnknown)
+		if peer, msg, err = ep.parse(pkt, nil); err != nil {
+			return
+		}
+		switch msg.(type) {
+		case *message.FragmentMsg, *message.FragmentAckMsg:
+			// no nested fragmentation
+			return nil, ErrFragInvalid
+		}
 	}
 	// return transport message
 	return &Message{
 		Peer:  peer,
 		Msg:   msg,
 		Resp:  nil,
+		Addr:  util.NewAddress(ep.addr.Network(), from.String()),
 		Label: "",
 	}, nil
 }
@@ -212,41 +285,100 @@ func (ep *PaketEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message)
 		return ErrEndpProtocolUnknown
 	}
 
+	// send large messages in fragments
+	if ep.frag.needed(len(buf)) {
+		var list [][]byte
+		if list, err = ep.frag.split(msg.Peer, a, buf); err != nil {
+			return
+		}
+		for _, pkt := range list {
+			if err = ep.write(a, pkt); err != nil {
+				return
+			}
+		}
+		return ErrEndpMaybeSent
+	}
+	if err = ep.write(a, buf); err != nil {
+		return
+	}
+	return ErrEndpMaybeSent
+}
+
+// write a packet to address (endpoint must be locked by caller)
+func (ep *PaketEndpoint) write(a *net.UDPAddr, buf []byte) (err error) {
 	// timeout after 1 second
 	if err = ep.conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
 		logger.Println(logger.DBG, "[pkt_ep] SetWriteDeadline failed: "+err.Error())
 		return
 	}
 	var n int
-	n, err = ep.conn.WriteTo(buf, a)
-	if n != len(buf) {
+	if n, err = ep.conn.WriteTo(buf, a); err == nil && n != len(buf) {
 		err = ErrEndpWriteShort
 	}
-	return ErrEndpMaybeSent
+	return
+}
+
+// sendPackets writes a list of packets to address (used for fragment
+// handling outside of Send()).
+func (ep *PaketEndpoint) sendPackets(a *net.UDPAddr, list [][]byte) {
+	ep.Lock()
+	defer ep.Unlock()
+	if ep.conn == nil {
+		return
+	}
+	for _, pkt := range list {
+		if err := ep.write(a, pkt); err != nil {
+			logger.Println(logger.WARN, "[pkt_ep] fragment write failed: "+err.Error())
+			return
+		}
+	}
+}
+
+// parse a packet (peer id and message) based on extended protocol.
+func (ep *PaketEndpoint) parse(pkt, buf []byte) (peer *util.PeerID, msg message.Message, err error) {
+	switch ep.addr.Network() {
+	case "ip+udp":
+		// check for minimum size (32 byte peer id + 4 byte header)
+		if len(pkt) < 36 {
+			err = ErrEndpReadShort
+			return
+		}
+		// parse peer id and message in sequence
+		peer = util.NewPeerID(pkt[:32])
+		msg, err = ReadMessageDirect(bytes.NewBuffer(pkt[32:]), buf)
+	default:
+		panic(ErrEndpProtocolUnknown)
+	}
+	return
 }
 
 // Address returms the
 func (ep *PaketEndpoint) Address() net.Addr {
+	ep.Lock()
+	defer ep.Unlock()
 	return ep.addr
 }
 
 // CanSendTo returns true if the endpoint can sent to address
 func (ep *PaketEndpoint) CanSendTo(addr net.Addr) (ok bool) {
-	ok = EpProtocol(addr.Network()) == EpProtocol(ep.addr.Network())
+	ep.Lock()
+	own, netw := ep.addr, ep.netw
+	ep.Unlock()
+	ok = EpProtocol(addr.Network()) == EpProtocol(own.Network())
 	if ok {
 		// try to convert addr to compatible type
-		switch ep.netw {
+		switch netw {
 		case "udp", "udp4", "udp6":
 			var err error
-			if _, err = net.ResolveUDPAddr(ep.netw, addr.String()); err != nil {
+			if _, err = net.ResolveUDPAddr(netw, addr.String()); err != nil {
 				ok = false
 			}
 		default:
-			logger.Printf(logger.WARN, "[pkt_ep] unknown network %s", ep.netw)
+			logger.Printf(logger.WARN, "[pkt_ep] unknown network %s", netw)
 			ok = false
 		}
 	} else {
-		logger.Printf(logger.DBG, "[pkt_ep] protocol mismatch %s -- %s", EpProtocol(addr.Network()), EpProtocol(ep.addr.Network()))
+		logger.Printf(logger.DBG, "[pkt_ep] protocol mismatch %s -- %s", EpProtocol(addr.Network()), EpProtocol(own.Network()))
 	}
 	return
 }
@@ -257,7 +389,7 @@ func (ep *PaketEndpoint) ID() int {
 }
 
 // create a new packet endpoint for protcol and address
-func newPacketEndpoint(addr net.Addr) (ep *PaketEndpoint, err error) {
+func newPacketEndpoint(addr net.Addr, fragCfg *util.FragmentConfig) (ep *PaketEndpoint, err error) {
 	// check for matching protocol
 	if epMode(addr.Network()) != "packet" {
 		err = ErrEndpProtocolMismatch
@@ -265,10 +397,11 @@ func newPacketEndpoint(addr net.Addr) (ep *PaketEndpoint, err error) {
 	}
 	// create endpoint
 	ep = &PaketEndpoint{
-		id:   util.NextID(),
-		addr: addr,
-		buf:  make([]byte, 65536),
+		failures: newFailures(),
+		id:       util.NextID(),
+		addr:     addr,
 	}
+	ep.frag = newFragmenter(fragCfg, ep.sendPackets)
 	return
 }
 
@@ -276,82 +409,219 @@ func newPacketEndpoint(addr net.Addr) (ep *PaketEndpoint, err error) {
 // Stream-oriented endpoint
 //----------------------------------------------------------------------
 
-// StreamEndpoint for stream-oriented network protocols
+// Stream endpoint parameters
+var (
+	StreamQueueSize    = 64               // max. number of queued messages per connection
+	StreamDialTimeout  = 5 * time.Second  // timeout for new outgoing connections
+	StreamWriteTimeout = 10 * time.Second // max. wait for a free queue slot or a write
+	StreamIdleTimeout  = 5 * time.Minute  // connections without traffic are closed
+)
+
+// streamConn is a connection of a stream endpoint to a remote address.
+// Outgoing messages are queued and written by a separate go routine; a
+// full queue blocks senders (backpressure).
+type streamConn struct {
+	conn  net.Conn      // network connection
+	queue chan []byte   // outgoing messages
+	done  chan struct{} // closed when the connection ends
+	once  sync.Once     // close only once
+}
+
+// close the connection
+func (sc *streamConn) close() {
+	sc.once.Do(func() {
+		close(sc.done)
+		sc.conn.Close()
+	})
+}
+
+// StreamEndpoint for stream-oriented network protocols. A stream carries
+// a sequence of frames, each consisting of the sender peer id followed by
+// a GNUnet message (framed by the size in its header). Connections are
+// reused for all messages to and from a remote address.
 type StreamEndpoint struct {
-	id       int                      // endpoint identifier
-	addr     net.Addr                 // listening address
-	listener net.Listener             // listener instance
-	conns    *util.Map[int, net.Conn] // active connections
-	buf      []byte                   // read/write buffer
+	sync.Mutex
+	failures
+
+	id       int                    // endpoint identifier
+	addr     net.Addr               // listening address
+	listener net.Listener           // listener instance
+	conns    map[string]*streamConn // active connections (by remote address)
+	ctx      context.Context        // endpoint context
+	hdlr     chan *Message          // handler for incoming messages
 }
 
-// Run packet endpoint: send incoming messages to the handler.
+// Run stream endpoint: send incoming messages to the handler.
 func (ep *StreamEndpoint) Run(ctx context.Context, hdlr chan *Message) (err error) {
-	// create listener
+	// create listener (a socket file left by a terminated node is removed)
 	var lc net.ListenConfig
 	xproto := ep.addr.Network()
-	if ep.listener, err = lc.Listen(ctx, EpProtocol(xproto), ep.addr.String()); err != nil {
+	if EpProtocol(xproto) == "unix" {
+		removeStaleSocket(ep.addr.String())
+	}
+	var listener net.Listener
+	if listener, err = lc.Listen(ctx, EpProtocol(xproto), ep.addr.String()); err != nil {
 		return
 	}
 	// get actual listening address
-	ep.addr = util.NewAddress(xproto, ep.listener.Addr().String())
+	ep.Lock()
+	ep.listener = listener
+	ep.addr = util.NewAddress(xproto, listener.Addr().String())
+	ep.ctx, ep.hdlr = ctx, hdlr
+	ep.Unlock()
 
 	// run watch dog for termination
 	go func() {
 		<-ctx.Done()
-		ep.listener.Close()
+		listener.Close()
+		ep.Lock()
+		for _, sc := range ep.conns {
+			sc.close()
+		}
+		ep.Unlock()
 	}()
-	// run go routine to handle messages from clients
+	// run go routine to handle connections from clients
 	go func() {
 		for {
 			// get next client connection
-			conn, err := ep.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
+				// a closed listener is a failure of the endpoint
+				if ctx.Err() == nil {
+					ep.report(err)
+				}
 				return
 			}
-			session := util.NextID()
-			ep.conns.Put(session, conn, 0)
-			go func() {
-				for {
-					// read next message from connection
-					tm, err := ep.read(ctx, conn)
-					if err != nil {
-						break
-					}
-					// send transport message to handler
-					go func() {
-						hdlr <- tm
-					}()
-				}
-				// connection ended.
-				conn.Close()
-				ep.conns.Delete(session, 0)
-			}()
+			// unnamed clients (unix domain sockets) get a unique key
+			key := conn.RemoteAddr().String()
+			if len(key) == 0 {
+				key = fmt.Sprintf("@%d", util.NextID())
+			}
+			ep.Lock()
+			ep.serve(key, conn)
+			ep.Unlock()
 		}
 	}()
 	return
 }
 
-// Read a transport message from endpoint based on extended protocol
-func (ep *StreamEndpoint) read(ctx context.Context, conn net.Conn) (tm *Message, err error) {
-	// parse transport message based on extended protocol
-	var (
-		peer *util.PeerID
-		msg  message.Message
-	)
-	switch ep.addr.Network() {
-	case "ip+udp":
-		// parse peer id
-		peer = util.NewPeerID(nil)
-		if _, err = conn.Read(peer.Data); err != nil {
-			return
+// serve a connection: start reader and writer for it (caller must hold
+// the lock).
+func (ep *StreamEndpoint) serve(key string, conn net.Conn) *streamConn {
+	sc := &streamConn{
+		conn:  conn,
+		queue: make(chan []byte, StreamQueueSize),
+		done:  make(chan struct{}),
+	}
+	if old, ok := ep.conns[key]; ok {
+		old.close()
+	}
+	ep.conns[key] = sc
+	from := util.NewAddress(ep.addr.Network(), key)
+	label := ep.addr.String()
+	ctx, hdlr := ep.ctx, ep.hdlr
+
+	// write queued messages
+	go func() {
+		for {
+			select {
+			case buf := <-sc.queue:
+				if err := conn.SetWriteDeadline(time.Now().Add(StreamWriteTimeout)); err != nil {
+					sc.close()
+					return
+				}
+				if _, err := conn.Write(buf); err != nil {
+					logger.Printf(logger.DBG, "[stream_ep] write to %s failed: %s", key, err.Error())
+					sc.close()
+					return
+				}
+			case <-sc.done:
+				return
+			}
 		}
-		// read next message from connection
-		if msg, err = ReadMessage(ctx, conn, ep.buf); err != nil {
-			break
+	}()
+	// read incoming messages: a slow handler stops reading from the
+	// connection, so the sender is slowed down as well (backpressure).
+	go func() {
+		defer func() {
+			sc.close()
+			ep.Lock()
+			if ep.conns[key] == sc {
+				delete(ep.conns, key)
+			}
+			ep.Unlock()
+		}()
+		buf := make([]byte, 65536)
+		for {
+			if err := conn.SetReadDeadline(time.Now().Add(StreamIdleTimeout)); err != nil {
+				return
+			}
+			tm, err := readFrame(conn, buf)
+			if err != nil {
+				if err != io.EOF {
+					logger.Printf(logger.DBG, "[stream_ep] read from %s failed: %s", key, err.Error())
+				}
+				return
+			}
+			tm.Addr = from
+			tm.Label = label
+			select {
+			case hdlr <- tm:
+			case <-sc.done:
+				return
+			case <-ctx.Done():
+				return
+			}
 		}
-	default:
-		panic(ErrEndpProtocolUnknown)
+	}()
+	return sc
+}
+
+// removeStaleSocket removes a unix domain socket file nobody is listening
+// on anymore.
+func removeStaleSocket(path string) {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		// socket is in use
+		conn.Close()
+		return
+	}
+	logger.Printf(logger.INFO, "[stream_ep] removing stale socket '%s'", path)
+	if err = os.Remove(path); err != nil {
+		logger.Printf(logger.WARN, "[stream_ep] can't remove socket: %s", err.Error())
+	}
+}
+
+// readFrame reads a transport message (frame) from a stream; the buffer
+// must hold a message of maximum size.
+func readFrame(rdr io.Reader, buf []byte) (tm *Message, err error) {
+	// read peer id of sender
+	peer := util.NewPeerID(nil)
+	if _, err = io.ReadFull(rdr, peer.Data); err != nil {
+		return
+	}
+	// read message header and body
+	if _, err = io.ReadFull(rdr, buf[:4]); err != nil {
+		return
+	}
+	var mh *message.MsgHeader
+	if mh, err = message.GetMsgHeader(buf[:4]); err != nil {
+		return
+	}
+	if mh.MsgSize < 4 {
+		err = ErrEndpReadShort
+		return
+	}
+	if _, err = io.ReadFull(rdr, buf[4:mh.MsgSize]); err != nil {
+		return
+	}
+	var msg message.Message
+	if msg, err = message.Parse(buf[:mh.MsgSize]); err != nil {
+		return
 	}
 	// return transport message
 	return &Message{
@@ -360,19 +630,88 @@ func (ep *StreamEndpoint) read(ctx context.Context, conn net.Conn) (tm *Message,
 	}, nil
 }
 
-// Send message to address from endpoint
-func (ep *StreamEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message) error {
-	return nil
+// connect returns a connection to the address: an existing connection
+// is reused, otherwise a new connection is opened.
+func (ep *StreamEndpoint) connect(ctx context.Context, addr net.Addr) (*streamConn, error) {
+	key := addr.String()
+	ep.Lock()
+	sc, ok := ep.conns[key]
+	running := ep.listener != nil
+	ep.Unlock()
+	if !running {
+		return nil, ErrEndpNoConnection
+	}
+	if ok {
+		return sc, nil
+	}
+	// open new connection (without holding the lock)
+	dialer := net.Dialer{Timeout: StreamDialTimeout}
+	conn, err := dialer.DialContext(ctx, EpProtocol(addr.Network()), key)
+	if err != nil {
+		return nil, err
+	}
+	ep.Lock()
+	defer ep.Unlock()
+	if sc, ok = ep.conns[key]; ok {
+		// connection was opened concurrently
+		conn.Close()
+		return sc, nil
+	}
+	return ep.serve(key, conn), nil
+}
+
+// Send message to address from endpoint. The message is queued on the
+// connection to the address; if the queue is full, Send blocks until
+// the message is queued or the timeout is reached.
+func (ep *StreamEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message) (err error) {
+	// get message content (TransportMessage)
+	var buf []byte
+	if buf, err = msg.Bytes(); err != nil {
+		return
+	}
+	var sc *streamConn
+	if sc, err = ep.connect(ctx, addr); err != nil {
+		return
+	}
+	select {
+	case sc.queue <- buf:
+		return nil
+	case <-sc.done:
+		return ErrEndpNoConnection
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(StreamWriteTimeout):
+		return ErrEndpBusy
+	}
 }
 
 // Address returns the actual listening endpoint address
 func (ep *StreamEndpoint) Address() net.Addr {
+	ep.Lock()
+	defer ep.Unlock()
 	return ep.addr
 }
 
 // CanSendTo returns true if the endpoint can sent to address
-func (ep *StreamEndpoint) CanSendTo(addr net.Addr) bool {
-	return epMode(addr.Network()) == "stream"
+func (ep *StreamEndpoint) CanSendTo(addr net.Addr) (ok bool) {
+	proto := EpProtocol(ep.Address().Network())
+	if epMode(addr.Network()) != "stream" {
+		return false
+	}
+	if ok = EpProtocol(addr.Network()) == proto; ok && proto == "tcp" {
+		// try to convert addr to compatible type
+		if _, err := net.ResolveTCPAddr(proto, addr.String()); err != nil {
+			ok = false
+		}
+	}
+	return
+}
+
+// Connections returns the number of active connections.
+func (ep *StreamEndpoint) Connections() int {
+	ep.Lock()
+	defer ep.Unlock()
+	return len(ep.conns)
 }
 
 // ID returns the endpoint identifier
@@ -389,10 +728,10 @@ func newStreamEndpoint(addr net.Addr) (ep *StreamEndpoint, err error) {
 	}
 	// create endpoint
 	ep = &StreamEndpoint{
-		id:    util.NextID(),
-		addr:  addr,
-		conns: util.NewMap[int, net.Conn](),
-		buf:   make([]byte, 65536),
+		failures: newFailures(),
+		id:       util.NextID(),
+		addr:     addr,
+		conns:    make(map[string]*streamConn),
 	}
 	return
 }
@@ -408,7 +747,7 @@ func EpProtocol(netw string) string {
 	switch netw {
 	case "udp", "udp4", "udp6", "ip+udp":
 		return "udp"
-	case "tcp", "tcp4", "tcp6":
+	case "tcp", "tcp4", "tcp6", "ip+tcp", "http", "https":
 		return "tcp"
 	case "unix":
 		return "unix"
@@ -416,8 +755,13 @@ func EpProtocol(netw string) string {
 	return ""
 }
 
-// epMode returns the endpoint mode (packet or stream) for a given network
+// epMode returns the endpoint mode (packet, stream or http) for a given
+// network
 func epMode(netw string) string {
+	switch netw {
+	case "http", "https":
+		return "http"
+	}
 	switch EpProtocol(netw) {
 	case "udp":
 		return "packet"
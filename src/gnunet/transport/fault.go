@@ -0,0 +1,207 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"gnunet/util"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Fault-injecting endpoint (chaos testing)
+//----------------------------------------------------------------------
+
+// DefaultHoldTime is the max. time a message is held back for reordering
+// if no max. delay is configured.
+var DefaultHoldTime = 100 * time.Millisecond
+
+// FaultyEndpoint wraps an endpoint and injects faults (drop, delay,
+// reorder or corrupt) into the message flow as specified in the fault
+// configuration. Incoming messages are subject to all fault types;
+// outgoing messages can be dropped or delayed. A message held back for
+// reordering is delivered after the next message, but not later than
+// the max. delay (or when the endpoint is closed).
+type FaultyEndpoint struct {
+	Endpoint
+	sync.Mutex
+
+	faults *util.FaultInjector // fault injector
+	held   *Message            // message held back for reordering
+	timer  *time.Timer         // delivery timer for held message
+}
+
+// NewFaultyEndpoint creates a fault-injecting wrapper for an endpoint.
+func NewFaultyEndpoint(ep Endpoint, cfg *util.FaultConfig) *FaultyEndpoint {
+	return &FaultyEndpoint{
+		Endpoint: ep,
+		faults:   util.NewFaultInjector(cfg),
+	}
+}
+
+// Run the wrapped endpoint and inject faults into received messages
+// before they are forwarded to the handler.
+func (ep *FaultyEndpoint) Run(ctx context.Context, hdlr chan *Message) (err error) {
+	in := make(chan *Message)
+	if err = ep.Endpoint.Run(ctx, in); err != nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ep.close(hdlr)
+				return
+			case tm := <-in:
+				ep.receive(ctx, tm, hdlr)
+			}
+		}
+	}()
+	return
+}
+
+// holdTime returns the max. time a message is held back for reordering.
+func (ep *FaultyEndpoint) holdTime() time.Duration {
+	if d := ep.faults.MaxDelay(); d > 0 {
+		return d
+	}
+	return DefaultHoldTime
+}
+
+// release the held back message (if any). If 'only' is set, the held
+// message is only released if it is that message.
+func (ep *FaultyEndpoint) release(only *Message) (m *Message) {
+	ep.Lock()
+	defer ep.Unlock()
+	if only != nil && ep.held != only {
+		return nil
+	}
+	if ep.timer != nil {
+		ep.timer.Stop()
+		ep.timer = nil
+	}
+	m, ep.held = ep.held, nil
+	return
+}
+
+// close delivers a held back message when the endpoint is closed (if
+// the handler accepts it within the hold time).
+func (ep *FaultyEndpoint) close(hdlr chan *Message) {
+	if m := ep.release(nil); m != nil {
+		go func() {
+			select {
+			case hdlr <- m:
+			case <-time.After(ep.holdTime()):
+			}
+		}()
+	}
+}
+
+// receive handles an incoming message with fault injection.
+func (ep *FaultyEndpoint) receive(ctx context.Context, tm *Message, hdlr chan *Message) {
+	// drop message
+	if ep.faults.Drop() {
+		logger.Printf(logger.DBG, "[fault] dropped incoming %s", tm.Msg.Type())
+		return
+	}
+	// corrupt message: a message that can't be parsed after corruption
+	// is dropped (as an endpoint would do).
+	if buf, err := tm.Bytes(); err == nil && ep.faults.Corrupt(buf) {
+		if tm.Msg, err = ReadMessageDirect(bytes.NewBuffer(buf[32:]), nil); err != nil {
+			logger.Printf(logger.DBG, "[fault] dropped corrupted message: %s", err.Error())
+			return
+		}
+		tm.Peer = util.NewPeerID(buf[:32])
+		logger.Println(logger.DBG, "[fault] corrupted incoming message")
+	}
+	// reorder messages: hold message back until the next one is received
+	// (or the hold time has passed)
+	out := []*Message{tm}
+	if m := ep.release(nil); m != nil {
+		out = append(out, m)
+	} else if ep.faults.Reorder() {
+		logger.Printf(logger.DBG, "[fault] holding back %s", tm.Msg.Type())
+		ep.Lock()
+		ep.held = tm
+		ep.timer = time.AfterFunc(ep.holdTime(), func() {
+			if m := ep.release(tm); m != nil {
+				ep.deliver(ctx, []*Message{m}, hdlr)
+			}
+		})
+		ep.Unlock()
+		return
+	}
+	ep.deliver(ctx, out, hdlr)
+}
+
+// deliver messages to the handler in given order (with injected delays).
+func (ep *FaultyEndpoint) deliver(ctx context.Context, out []*Message, hdlr chan *Message) {
+	go func() {
+		for _, m := range out {
+			if d := ep.faults.Delay(); d > 0 {
+				logger.Printf(logger.DBG, "[fault] delaying incoming %s by %s", m.Msg.Type(), d)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(d):
+				}
+			}
+			select {
+			case hdlr <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Send message to address with fault injection.
+func (ep *FaultyEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message) error {
+	// drop message
+	if ep.faults.Drop() {
+		logger.Printf(logger.DBG, "[fault] dropped outgoing %s", msg.Msg.Type())
+		return ErrEndpMaybeSent
+	}
+	// delay message
+	if d := ep.faults.Delay(); d > 0 {
+		logger.Printf(logger.DBG, "[fault] delaying outgoing %s by %s", msg.Msg.Type(), d)
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(d):
+				_ = ep.Endpoint.Send(ctx, addr, msg)
+			}
+		}()
+		return ErrEndpMaybeSent
+	}
+	return ep.Endpoint.Send(ctx, addr, msg)
+}
+
+// Failed returns the failures reported by the wrapped endpoint.
+func (ep *FaultyEndpoint) Failed() <-chan error {
+	if fr, ok := ep.Endpoint.(failureReporter); ok {
+		return fr.Failed()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/util"
+)
+
+// stubEndpoint forwards messages from a channel to the handler.
+type stubEndpoint struct {
+	Endpoint
+	in chan *Message
+}
+
+func (s *stubEndpoint) Run(ctx context.Context, hdlr chan *Message) error {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case tm := <-s.in:
+				hdlr <- tm
+			}
+		}
+	}()
+	return nil
+}
+
+func TestFaultReorder(t *testing.T) {
+	peer := util.NewPeerID(util.NewRndArray(32))
+	newMsg := func() *Message {
+		return NewTransportMessage(peer, message.NewSessionKeepAliveMsg())
+	}
+	received := func(hdlr chan *Message, tm *Message, wait time.Duration) bool {
+		select {
+		case m := <-hdlr:
+			return m == tm
+		case <-time.After(wait):
+			return false
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// held message is delivered after its successor
+	ep := NewFaultyEndpoint(nil, &util.FaultConfig{Reorder: 1, MaxDelay: 10000})
+	hdlr := make(chan *Message, 2)
+	first, second := newMsg(), newMsg()
+	ep.receive(ctx, first, hdlr)
+	ep.receive(ctx, second, hdlr)
+	if !received(hdlr, second, time.Second) || !received(hdlr, first, time.Second) {
+		t.Fatal("messages not reordered")
+	}
+	// held message is delivered after the max. delay
+	ep = NewFaultyEndpoint(nil, &util.FaultConfig{Reorder: 1, MaxDelay: 50})
+	last := newMsg()
+	ep.receive(ctx, last, hdlr)
+	if !received(hdlr, last, time.Second) {
+		t.Fatal("held message not delivered")
+	}
+	// held message is delivered when the endpoint is closed
+	stub := &stubEndpoint{in: make(chan *Message)}
+	ep = NewFaultyEndpoint(stub, &util.FaultConfig{Reorder: 1, MaxDelay: 10000})
+	runCtx, stop := context.WithCancel(ctx)
+	if err := ep.Run(runCtx, hdlr); err != nil {
+		t.Fatal(err)
+	}
+	stub.in <- last
+	for i := 0; i < 100; i++ {
+		ep.Lock()
+		held := ep.held != nil
+		ep.Unlock()
+		if held {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	if !received(hdlr, last, time.Second) {
+		t.Fatal("held message not delivered on close")
+	}
+}
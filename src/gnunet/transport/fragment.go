@@ -0,0 +1,325 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"gnunet/message"
+	"gnunet/util"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrFragTooLarge = errors.New("message too large for fragmentation")
+	ErrFragInvalid  = errors.New("invalid fragment")
+)
+
+// Fragmentation parameters
+var (
+	FragmentMaxCount          = 64               // max. number of fragments per message
+	FragmentMaxReassembly     = 64               // max. number of concurrent reassemblies
+	FragmentReassemblyTimeout = 30 * time.Second // incomplete messages are dropped after timeout
+)
+
+// fragment header size (peer id and fragment message header)
+const fragOverhead = 32 + 12
+
+//----------------------------------------------------------------------
+// Fragmentation of packets on packet-oriented endpoints:
+// A packet (peer id and message) that exceeds the max. packet size is
+// split into up to 64 fragments, each sent in its own packet (with peer
+// id and FRAGMENT message). The receiver reassembles the packet and
+// acknowledges the fragments it has received (FRAGMENT_ACK). Fragments
+// not acknowledged are retransmitted (with exponential backoff) until
+// all fragments are received or the max. number of retries is reached.
+//----------------------------------------------------------------------
+
+// outgoing fragmented packet
+type fragSend struct {
+	addr    *net.UDPAddr  // target address
+	packets [][]byte      // packets with fragments
+	missing uint64        // fragments not acknowledged (bits by index)
+	retries int           // number of retransmissions
+	timeout time.Duration // current retransmission timeout
+	timer   *time.Timer   // retransmission timer
+}
+
+// incoming fragmented packet
+type fragRecv struct {
+	data    []byte       // reassembled packet
+	chunk   int          // size of fragment data (if known)
+	offsets map[int]bool // offsets of received fragments
+	count   int          // number of bytes received
+	started time.Time    // start of reassembly
+}
+
+// missing returns the bitmask of fragments not yet received (only if
+// the size of fragments is known).
+func (r *fragRecv) missing() (bits uint64, ok bool) {
+	if r.chunk == 0 {
+		return 0, false
+	}
+	for i := 0; i*r.chunk < len(r.data); i++ {
+		if !r.offsets[i*r.chunk] {
+			bits |= 1 << i
+		}
+	}
+	return bits, true
+}
+
+// aligned returns true if the received fragments fit the given fragment
+// size: all fragments start at multiples of the size, don't exceed it
+// and the packet has no more than the max. number of fragments.
+func (r *fragRecv) aligned(chunk int) bool {
+	if (len(r.data)+chunk-1)/chunk > FragmentMaxCount {
+		return false
+	}
+	for offset := range r.offsets {
+		if offset%chunk != 0 || len(r.data)-offset > chunk {
+			return false
+		}
+	}
+	return true
+}
+
+// fragmenter splits outgoing packets into fragments and reassembles
+// incoming fragments into packets.
+type fragmenter struct {
+	sync.Mutex
+
+	cfg     *util.FragmentConfig         // fragmentation settings
+	resend  func(*net.UDPAddr, [][]byte) // retransmit packets
+	pending map[uint32]*fragSend         // outgoing fragmented packets
+	reasm   map[string]*fragRecv         // incoming fragmented packets
+	done    map[string]time.Time         // completed reassemblies
+}
+
+// newFragmenter creates a new fragmenter for given settings. The resend
+// function is called for packets that need to be retransmitted.
+func newFragmenter(cfg *util.FragmentConfig, resend func(*net.UDPAddr, [][]byte)) *fragmenter {
+	return &fragmenter{
+		cfg:     cfg.Effective(),
+		resend:  resend,
+		pending: make(map[uint32]*fragSend),
+		reasm:   make(map[string]*fragRecv),
+		done:    make(map[string]time.Time),
+	}
+}
+
+// needed returns true if a packet of given size must be fragmented.
+func (f *fragmenter) needed(size int) bool {
+	return size > f.cfg.MaxSize
+}
+
+// split a packet into fragments and return the packets to be sent. The
+// fragments are retransmitted to the address until acknowledged.
+func (f *fragmenter) split(peer *util.PeerID, addr *net.UDPAddr, buf []byte) (packets [][]byte, err error) {
+	if len(buf) > 0xffff {
+		return nil, ErrFragTooLarge
+	}
+	// size of fragment data (limited number of fragments)
+	chunk := f.cfg.MaxSize - fragOverhead
+	if chunk < 1 {
+		chunk = 1
+	}
+	if n := (len(buf) + FragmentMaxCount - 1) / FragmentMaxCount; chunk < n {
+		chunk = n
+	}
+	// assemble fragment packets
+	id := util.RndUInt32()
+	fs := &fragSend{
+		addr:    addr,
+		timeout: time.Duration(f.cfg.Timeout) * time.Millisecond,
+	}
+	for i, pos := 0, 0; pos < len(buf); i, pos = i+1, pos+chunk {
+		end := pos + chunk
+		if end > len(buf) {
+			end = len(buf)
+		}
+		msg := message.NewFragmentMsg(id, uint16(len(buf)), uint16(pos), buf[pos:end])
+		var pkt []byte
+		if pkt, err = NewTransportMessage(peer, msg).Bytes(); err != nil {
+			return
+		}
+		fs.packets = append(fs.packets, pkt)
+		fs.missing |= 1 << i
+	}
+	// schedule retransmission
+	f.Lock()
+	defer f.Unlock()
+	f.pending[id] = fs
+	fs.timer = time.AfterFunc(fs.timeout, func() { f.retransmit(id) })
+	return fs.packets, nil
+}
+
+// retransmit fragments not acknowledged yet.
+func (f *fragmenter) retransmit(id uint32) {
+	f.Lock()
+	fs, ok := f.pending[id]
+	if !ok {
+		f.Unlock()
+		return
+	}
+	if fs.retries >= f.cfg.Retries {
+		logger.Printf(logger.WARN, "[frag] message %d to %s not acknowledged - dropped", id, fs.addr)
+		delete(f.pending, id)
+		f.Unlock()
+		return
+	}
+	fs.retries++
+	fs.timeout *= 2
+	fs.timer = time.AfterFunc(fs.timeout, func() { f.retransmit(id) })
+	list := fs.unacked()
+	f.Unlock()
+
+	logger.Printf(logger.DBG, "[frag] retransmitting %d fragments of message %d", len(list), id)
+	f.resend(fs.addr, list)
+}
+
+// unacked returns the packets of fragments not acknowledged yet.
+func (fs *fragSend) unacked() (list [][]byte) {
+	for i, pkt := range fs.packets {
+		if fs.missing&(1<<i) != 0 {
+			list = append(list, pkt)
+		}
+	}
+	return
+}
+
+// acknowledged handles an acknowledgement of fragments. Returns the
+// fragments that are reported missing (to be sent again).
+func (f *fragmenter) acknowledged(ack *message.FragmentAckMsg) (addr *net.UDPAddr, list [][]byte) {
+	f.Lock()
+	defer f.Unlock()
+	fs, ok := f.pending[ack.ID]
+	if !ok {
+		return
+	}
+	// acknowledged bits only (never re-open acknowledged fragments)
+	fs.missing &= ack.Bits
+	if fs.missing == 0 {
+		fs.timer.Stop()
+		delete(f.pending, ack.ID)
+		return
+	}
+	return fs.addr, fs.unacked()
+}
+
+// assemble an incoming fragment. Returns the reassembled packet if the
+// fragment completes it. If an acknowledgement is to be sent to the
+// sender, it is returned as well.
+func (f *fragmenter) assemble(from net.Addr, msg *message.FragmentMsg) (buf []byte, ack *message.FragmentAckMsg, err error) {
+	total, offset, size := int(msg.Total), int(msg.Offset), len(msg.Data)
+	if size == 0 || offset+size > total {
+		return nil, nil, ErrFragInvalid
+	}
+	key := fmt.Sprintf("%s/%d", from, msg.ID)
+
+	f.Lock()
+	defer f.Unlock()
+	f.purge()
+
+	// duplicate fragment of a completed packet: acknowledge again
+	if _, ok := f.done[key]; ok {
+		return nil, message.NewFragmentAckMsg(msg.ID, 0), nil
+	}
+	// get reassembly buffer
+	r, ok := f.reasm[key]
+	if !ok {
+		if len(f.reasm) >= FragmentMaxReassembly {
+			return nil, nil, ErrEndpBusy
+		}
+		r = &fragRecv{
+			data:    make([]byte, total),
+			offsets: make(map[int]bool),
+			started: time.Now(),
+		}
+		f.reasm[key] = r
+	} else if len(r.data) != total {
+		return nil, nil, ErrFragInvalid
+	}
+	// the size of fragments is known from all fragments but the last
+	if offset+size < total {
+		if r.chunk == 0 {
+			// fragments received so far must fit the fragment size
+			if !r.aligned(size) {
+				delete(f.reasm, key)
+				return nil, nil, ErrFragInvalid
+			}
+			r.chunk = size
+		} else if r.chunk != size {
+			return nil, nil, ErrFragInvalid
+		}
+	}
+	// fragments start at multiples of the fragment size (the last
+	// fragment is not larger than the others)
+	if r.chunk > 0 && (offset%r.chunk != 0 || size > r.chunk) {
+		return nil, nil, ErrFragInvalid
+	}
+	dup := r.offsets[offset]
+	if !dup {
+		copy(r.data[offset:], msg.Data)
+		r.offsets[offset] = true
+		r.count += size
+	}
+	// check for completed packet
+	if r.count == total {
+		delete(f.reasm, key)
+		f.done[key] = time.Now()
+		return r.data, message.NewFragmentAckMsg(msg.ID, 0), nil
+	}
+	// acknowledge received fragments on retransmissions and after the
+	// last fragment (to speed up recovery of lost fragments)
+	if dup || offset+size == total {
+		if bits, ok := r.missing(); ok {
+			ack = message.NewFragmentAckMsg(msg.ID, bits)
+		}
+	}
+	return
+}
+
+// purge expired reassemblies (incomplete and completed)
+func (f *fragmenter) purge() {
+	for key, r := range f.reasm {
+		if time.Since(r.started) > FragmentReassemblyTimeout {
+			delete(f.reasm, key)
+		}
+	}
+	for key, t := range f.done {
+		if time.Since(t) > FragmentReassemblyTimeout {
+			delete(f.done, key)
+		}
+	}
+}
+
+// stop all retransmissions
+func (f *fragmenter) stop() {
+	f.Lock()
+	defer f.Unlock()
+	for id, fs := range f.pending {
+		fs.timer.Stop()
+		delete(f.pending, id)
+	}
+}
@@ -0,0 +1,222 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/util"
+)
+
+func TestFragmentLoss(t *testing.T) {
+	resent := make(chan [][]byte, 1)
+	cfg := &util.FragmentConfig{MaxSize: 100, Timeout: 20, Retries: 1}
+	f1 := newFragmenter(cfg, func(_ *net.UDPAddr, list [][]byte) { resent <- list })
+	f2 := newFragmenter(cfg, nil)
+	from := util.NewAddress("udp", "127.0.0.1:2086")
+	peer := util.NewPeerID(util.NewRndArray(32))
+
+	// fragment data
+	assemble := func(pkt []byte) ([]byte, *message.FragmentAckMsg) {
+		msg, err := ReadMessageDirect(bytes.NewBuffer(pkt[32:]), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, ack, err := f2.assemble(from, msg.(*message.FragmentMsg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf, ack
+	}
+	data := util.NewRndArray(1000)
+	if !f1.needed(len(data)) {
+		t.Fatal("fragmentation not needed")
+	}
+	list, err := f1.split(peer, nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 18 {
+		t.Fatalf("unexpected number of fragments: %d", len(list))
+	}
+	// lose third fragment
+	var ack *message.FragmentAckMsg
+	for i, pkt := range list {
+		if i == 2 {
+			continue
+		}
+		if len(pkt) > cfg.MaxSize {
+			t.Fatalf("fragment too large: %d", len(pkt))
+		}
+		var buf []byte
+		if buf, ack = assemble(pkt); buf != nil {
+			t.Fatal("incomplete message assembled")
+		}
+	}
+	if ack == nil || ack.Bits != 1<<2 {
+		t.Fatalf("unexpected ack %v", ack)
+	}
+	// retransmit missing fragment
+	_, miss := f1.acknowledged(ack)
+	if len(miss) != 1 || !bytes.Equal(miss[0], list[2]) {
+		t.Fatal("missing fragment not retransmitted")
+	}
+	buf, ack := assemble(miss[0])
+	if !bytes.Equal(buf, data) {
+		t.Fatal("message not reassembled")
+	}
+	if ack == nil || ack.Bits != 0 {
+		t.Fatalf("unexpected ack %v", ack)
+	}
+	if _, miss = f1.acknowledged(ack); len(miss) != 0 || len(f1.pending) != 0 {
+		t.Fatal("fragments not acknowledged")
+	}
+	// duplicates of a completed message are acknowledged again
+	if buf, ack = assemble(list[0]); buf != nil || ack == nil || ack.Bits != 0 {
+		t.Fatal("duplicate fragment not acknowledged")
+	}
+	// unacknowledged fragments are retransmitted by timer, then dropped
+	if list, err = f1.split(peer, nil, data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case miss = <-resent:
+		if len(miss) != len(list) {
+			t.Fatalf("unexpected retransmission of %d fragments", len(miss))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no retransmission")
+	}
+	time.Sleep(100 * time.Millisecond)
+	f1.Lock()
+	defer f1.Unlock()
+	if len(f1.pending) != 0 {
+		t.Fatal("unacknowledged message not dropped")
+	}
+}
+
+func TestFragmentMisaligned(t *testing.T) {
+	f := newFragmenter(&util.FragmentConfig{MaxSize: 100}, nil)
+	from := util.NewAddress("udp", "127.0.0.1:2086")
+	data := util.NewRndArray(100)
+	assemble := func(id uint32, offset, size int) ([]byte, error) {
+		msg := message.NewFragmentMsg(id, uint16(len(data)), uint16(offset), data[offset:offset+size])
+		buf, _, err := f.assemble(from, msg)
+		return buf, err
+	}
+	// fragment size is known from the first fragment
+	if _, err := assemble(1, 0, 40); err != nil {
+		t.Fatal(err)
+	}
+	for _, frag := range [][2]int{{20, 40}, {40, 30}, {60, 40}} {
+		if _, err := assemble(1, frag[0], frag[1]); err != ErrFragInvalid {
+			t.Fatalf("fragment %v accepted", frag)
+		}
+	}
+	if _, err := assemble(1, 80, 20); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := assemble(1, 40, 40); err != nil || !bytes.Equal(buf, data) {
+		t.Fatal("message not reassembled")
+	}
+	// misaligned last fragment received first: reassembly is dropped
+	if _, err := assemble(2, 70, 30); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assemble(2, 0, 40); err != ErrFragInvalid {
+		t.Fatal("misaligned fragment accepted")
+	}
+	for _, frag := range [][2]int{{0, 40}, {80, 20}, {40, 40}} {
+		buf, err := assemble(2, frag[0], frag[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frag[0] == 40 && !bytes.Equal(buf, data) {
+			t.Fatal("message not reassembled")
+		}
+	}
+}
+
+func TestFragmentEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &util.FragmentConfig{MaxSize: 512}
+	run := func() (Endpoint, chan *Message) {
+		addr, _ := util.ParseAddress("ip+udp://127.0.0.1:0")
+		ep, err := NewEndpoint(addr, nil, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan *Message)
+		if err = ep.Run(ctx, ch); err != nil {
+			t.Fatal(err)
+		}
+		return ep, ch
+	}
+	ep1, _ := run()
+	ep2, ch2 := run()
+
+	// HELLO with many addresses
+	peer := util.NewPeerID(util.NewRndArray(32))
+	hello := message.NewHelloMsg(peer)
+	var list []*message.HelloAddress
+	for i := 0; i < 200; i++ {
+		addr := util.NewAddress("ip+udp", fmt.Sprintf("192.0.2.%d:%d", i, 2086+i))
+		list = append(list, message.NewHelloAddress(addr))
+	}
+	hello.SetAddresses(list)
+	if int(hello.MsgSize) < 4*cfg.MaxSize {
+		t.Fatalf("message too small: %d", hello.MsgSize)
+	}
+	tm := NewTransportMessage(peer, hello)
+	if err := ep1.Send(ctx, ep2.Address(), tm); err != nil && err != ErrEndpMaybeSent {
+		t.Fatal(err)
+	}
+	select {
+	case in := <-ch2:
+		out, _ := tm.Bytes()
+		buf, _ := in.Bytes()
+		if !in.Peer.Equal(peer) || !bytes.Equal(buf, out) {
+			t.Fatalf("unexpected message %v", in.Msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	// all fragments are acknowledged
+	pe := ep1.(*PaketEndpoint)
+	for i := 0; ; i++ {
+		pe.frag.Lock()
+		n := len(pe.frag.pending)
+		pe.frag.Unlock()
+		if n == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("fragments not acknowledged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
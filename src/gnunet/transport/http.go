@@ -0,0 +1,525 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gnunet/util"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+// Error codes
+var (
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+)
+
+//----------------------------------------------------------------------
+// HTTP(S) endpoint
+//
+// Peers behind restrictive firewalls (that only allow outgoing HTTP(S)
+// connections) exchange messages with HTTP requests to a remote HTTP(S)
+// endpoint: frames (like on stream endpoints) are uploaded in the body
+// of POST requests; frames for the client are downloaded with long-
+// polling GET requests. All requests of a client carry a session
+// identifier, so the endpoint can send messages (like PONG responses) to
+// clients that are not reachable themselves.
+//----------------------------------------------------------------------
+
+// HTTP endpoint parameters
+var (
+	HTTPPath        = "/gnunet"        // request path for message exchange
+	HTTPPollTimeout = 30 * time.Second // max. duration of a polling request
+)
+
+// header field for the session identifier
+const httpSessionHeader = "X-GNUnet-Session"
+
+// httpSession is a message exchange between a client and an HTTP endpoint.
+// Outgoing frames are queued; a full queue blocks senders (backpressure).
+type httpSession struct {
+	id     string        // session identifier (chosen by client)
+	queue  chan []byte   // outgoing frames
+	done   chan struct{} // closed when the session ends
+	once   sync.Once     // close only once
+	active int64         // time of last activity (unix nano)
+	keys   []string      // remote addresses of the session (server side)
+}
+
+// newHTTPSession creates a new session with given identifier.
+func newHTTPSession(id string) *httpSession {
+	s := &httpSession{
+		id:    id,
+		queue: make(chan []byte, StreamQueueSize),
+		done:  make(chan struct{}),
+	}
+	s.touch()
+	return s
+}
+
+// touch records activity on the session
+func (s *httpSession) touch() {
+	atomic.StoreInt64(&s.active, time.Now().UnixNano())
+}
+
+// idle returns true if there was no activity in the session for the
+// given duration.
+func (s *httpSession) idle(d time.Duration) bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&s.active))) > d
+}
+
+// close the session
+func (s *httpSession) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// HTTPEndpoint exchanges messages over HTTP or HTTPS (network "http" or
+// "https"). The endpoint is server for incoming sessions and client for
+// sessions to other HTTP endpoints.
+type HTTPEndpoint struct {
+	sync.Mutex
+	failures
+
+	id       int                     // endpoint identifier
+	addr     net.Addr                // listening address
+	tlsCfg   *util.TLSConfig         // certificate settings (HTTPS)
+	srv      *http.Server            // HTTP server instance
+	client   *http.Client            // HTTP client for outgoing sessions
+	sessions map[string]*httpSession // sessions by remote address
+	incoming map[string]*httpSession // sessions of clients by identifier
+	ctx      context.Context         // endpoint context
+	hdlr     chan *Message           // handler for incoming messages
+}
+
+// Run HTTP endpoint: send incoming messages to the handler.
+func (ep *HTTPEndpoint) Run(ctx context.Context, hdlr chan *Message) (err error) {
+	// create listener
+	var lc net.ListenConfig
+	xproto := ep.addr.Network()
+	var listener net.Listener
+	if listener, err = lc.Listen(ctx, EpProtocol(xproto), ep.addr.String()); err != nil {
+		return
+	}
+	// set up TLS (client and server)
+	var clientCfg *tls.Config
+	if clientCfg, err = ep.tlsCfg.ClientConfig(); err != nil {
+		listener.Close()
+		return
+	}
+	if xproto == "https" {
+		host, _, _ := net.SplitHostPort(listener.Addr().String())
+		var serverCfg *tls.Config
+		if serverCfg, err = ep.tlsCfg.ServerConfig(host); err != nil {
+			listener.Close()
+			return
+		}
+		listener = tls.NewListener(listener, serverCfg)
+	}
+	dialer := &net.Dialer{Timeout: StreamDialTimeout}
+	ep.Lock()
+	ep.addr = util.NewAddress(xproto, listener.Addr().String())
+	ep.ctx, ep.hdlr = ctx, hdlr
+	ep.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       clientCfg,
+			TLSHandshakeTimeout:   StreamDialTimeout,
+			ResponseHeaderTimeout: StreamWriteTimeout,
+			MaxIdleConnsPerHost:   2,
+		},
+	}
+	ep.srv = &http.Server{
+		Handler:           ep,
+		ReadHeaderTimeout: StreamDialTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	srv := ep.srv
+	ep.Unlock()
+
+	// serve requests
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Printf(logger.ERROR, "[http_ep] server failed: %s", err.Error())
+			ep.report(err)
+		}
+	}()
+	// run watch dog for termination and idle sessions
+	go func() {
+		tick := time.NewTicker(HTTPPollTimeout)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				srv.Close()
+				ep.Lock()
+				for _, s := range ep.sessions {
+					s.close()
+				}
+				for _, s := range ep.incoming {
+					s.close()
+				}
+				ep.sessions = make(map[string]*httpSession)
+				ep.incoming = make(map[string]*httpSession)
+				ep.Unlock()
+				return
+			case <-tick.C:
+				ep.Lock()
+				for _, s := range ep.incoming {
+					if s.idle(StreamIdleTimeout) {
+						ep.drop(s)
+					}
+				}
+				ep.Unlock()
+			}
+		}
+	}()
+	return
+}
+
+// drop a server-side session (caller must hold the lock).
+func (ep *HTTPEndpoint) drop(s *httpSession) {
+	s.close()
+	delete(ep.incoming, s.id)
+	for _, key := range s.keys {
+		if ep.sessions[key] == s {
+			delete(ep.sessions, key)
+		}
+	}
+}
+
+// ServeHTTP handles requests of clients: POST requests deliver frames,
+// GET requests poll for frames.
+func (ep *HTTPEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != HTTPPath {
+		http.NotFound(w, r)
+		return
+	}
+	id := r.Header.Get(httpSessionHeader)
+	if len(id) == 0 || len(id) > 64 {
+		http.Error(w, "missing session", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// get session of client: messages to the remote address of the
+	// request are sent in the session.
+	ep.Lock()
+	s, ok := ep.incoming[id]
+	if !ok {
+		s = newHTTPSession(id)
+		ep.incoming[id] = s
+	}
+	if ep.sessions[r.RemoteAddr] != s {
+		ep.sessions[r.RemoteAddr] = s
+		s.keys = append(s.keys, r.RemoteAddr)
+	}
+	ctx, hdlr := ep.ctx, ep.hdlr
+	label := ep.addr.String()
+	from := util.NewAddress(ep.addr.Network(), r.RemoteAddr)
+	ep.Unlock()
+	s.touch()
+
+	if r.Method == http.MethodPost {
+		// read frames from request body; a slow handler slows down the
+		// client (backpressure).
+		buf := make([]byte, 65536)
+		for {
+			tm, err := readFrame(r.Body, buf)
+			if err != nil {
+				if err != io.EOF {
+					logger.Printf(logger.DBG, "[http_ep] read from %s failed: %s", r.RemoteAddr, err.Error())
+					http.Error(w, "invalid frame", http.StatusBadRequest)
+					return
+				}
+				break
+			}
+			tm.Addr = from
+			tm.Label = label
+			select {
+			case hdlr <- tm:
+			case <-r.Context().Done():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		s.touch()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// long polling: stream queued frames to the client until the poll
+	// times out.
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	timeout := time.NewTimer(HTTPPollTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case buf := <-s.queue:
+			if _, err := w.Write(buf); err != nil {
+				logger.Printf(logger.DBG, "[http_ep] write to %s failed: %s", r.RemoteAddr, err.Error())
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			s.touch()
+		case <-timeout.C:
+			return
+		case <-s.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// connect returns the session for a remote address: sessions of clients
+// and existing sessions to the address are reused, otherwise a new
+// session to the remote endpoint is started.
+func (ep *HTTPEndpoint) connect(addr net.Addr) (*httpSession, error) {
+	key := addr.String()
+	ep.Lock()
+	defer ep.Unlock()
+	if ep.srv == nil {
+		return nil, ErrEndpNoConnection
+	}
+	if s, ok := ep.sessions[key]; ok {
+		return s, nil
+	}
+	s := newHTTPSession(hex.EncodeToString(util.NewRndArray(16)))
+	ep.sessions[key] = s
+	url := fmt.Sprintf("%s://%s%s", addr.Network(), key, HTTPPath)
+	go ep.upload(key, url, s)
+	go ep.poll(addr, url, s)
+	return s, nil
+}
+
+// end a client-side session
+func (ep *HTTPEndpoint) end(key string, s *httpSession) {
+	s.close()
+	ep.Lock()
+	if ep.sessions[key] == s {
+		delete(ep.sessions, key)
+	}
+	ep.Unlock()
+}
+
+// upload queued frames of a client session in POST requests; all frames
+// available are sent in one request.
+func (ep *HTTPEndpoint) upload(key, url string, s *httpSession) {
+	defer ep.end(key, s)
+	for {
+		var body bytes.Buffer
+		select {
+		case buf := <-s.queue:
+			body.Write(buf)
+		case <-s.done:
+			return
+		}
+	collect:
+		for body.Len() < 65536 {
+			select {
+			case buf := <-s.queue:
+				body.Write(buf)
+			default:
+				break collect
+			}
+		}
+		if err := ep.request(s, http.MethodPost, url, &body, func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("%w %d", ErrHTTPStatus, resp.StatusCode)
+			}
+			return nil
+		}); err != nil {
+			logger.Printf(logger.DBG, "[http_ep] upload to %s failed: %s", key, err.Error())
+			return
+		}
+		s.touch()
+	}
+}
+
+// poll for frames from the remote endpoint in a client session; the
+// session ends if it is idle or the remote endpoint is not available.
+func (ep *HTTPEndpoint) poll(addr net.Addr, url string, s *httpSession) {
+	key := addr.String()
+	defer ep.end(key, s)
+	ep.Lock()
+	ctx, hdlr := ep.ctx, ep.hdlr
+	label := ep.addr.String()
+	ep.Unlock()
+	from := util.NewAddress(addr.Network(), key)
+	buf := make([]byte, 65536)
+	for !s.idle(StreamIdleTimeout) {
+		if err := ep.request(s, http.MethodGet, url, nil, func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("%w %d", ErrHTTPStatus, resp.StatusCode)
+			}
+			for {
+				tm, err := readFrame(resp.Body, buf)
+				if err != nil {
+					if err == io.EOF || errors.Is(err, context.Canceled) {
+						return nil
+					}
+					return err
+				}
+				s.touch()
+				tm.Addr = from
+				tm.Label = label
+				select {
+				case hdlr <- tm:
+				case <-s.done:
+					return nil
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}); err != nil {
+			select {
+			case <-s.done:
+			default:
+				logger.Printf(logger.DBG, "[http_ep] polling %s failed: %s", key, err.Error())
+			}
+			return
+		}
+	}
+}
+
+// request performs an HTTP request in a session; the response is
+// processed by the callback. The request is cancelled when the session
+// ends.
+func (ep *HTTPEndpoint) request(s *httpSession, method, url string, body io.Reader, proc func(*http.Response) error) error {
+	ctx, cancel := context.WithCancel(ep.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(httpSessionHeader, s.id)
+	resp, err := ep.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return proc(resp)
+}
+
+// Send message to address from endpoint. The message is queued in the
+// session for the address; if the queue is full, Send blocks until the
+// message is queued or the timeout is reached.
+func (ep *HTTPEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message) (err error) {
+	// get message content (TransportMessage)
+	var buf []byte
+	if buf, err = msg.Bytes(); err != nil {
+		return
+	}
+	var s *httpSession
+	if s, err = ep.connect(addr); err != nil {
+		return
+	}
+	select {
+	case s.queue <- buf:
+		return nil
+	case <-s.done:
+		return ErrEndpNoConnection
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(StreamWriteTimeout):
+		return ErrEndpBusy
+	}
+}
+
+// Address returns the actual listening endpoint address
+func (ep *HTTPEndpoint) Address() net.Addr {
+	ep.Lock()
+	defer ep.Unlock()
+	return ep.addr
+}
+
+// CanSendTo returns true if the endpoint can sent to address
+func (ep *HTTPEndpoint) CanSendTo(addr net.Addr) bool {
+	if epMode(addr.Network()) != "http" {
+		return false
+	}
+	_, err := net.ResolveTCPAddr("tcp", addr.String())
+	return err == nil
+}
+
+// Sessions returns the number of active sessions (incoming and outgoing).
+func (ep *HTTPEndpoint) Sessions() int {
+	ep.Lock()
+	defer ep.Unlock()
+	n := len(ep.incoming)
+	for _, s := range ep.sessions {
+		if ep.incoming[s.id] != s {
+			n++
+		}
+	}
+	return n
+}
+
+// ID returns the endpoint identifier
+func (ep *HTTPEndpoint) ID() int {
+	return ep.id
+}
+
+// create a new HTTP endpoint for an address
+func newHTTPEndpoint(addr net.Addr, tlsCfg *util.TLSConfig) (ep *HTTPEndpoint, err error) {
+	// check for matching protocol
+	if epMode(addr.Network()) != "http" {
+		err = ErrEndpProtocolMismatch
+		return
+	}
+	// create endpoint
+	ep = &HTTPEndpoint{
+		failures: newFailures(),
+		id:       util.NextID(),
+		addr:     addr,
+		tlsCfg:   tlsCfg,
+		sessions: make(map[string]*httpSession),
+		incoming: make(map[string]*httpSession),
+	}
+	return
+}
@@ -0,0 +1,213 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Monitored endpoint
+//----------------------------------------------------------------------
+
+// Endpoint restart parameters: a failed endpoint is restarted after a
+// delay that doubles with every failed attempt (up to the maximum).
+var (
+	EndpointRestartDelay    = time.Second
+	EndpointRestartMaxDelay = 5 * time.Minute
+)
+
+// failureReporter is implemented by endpoints that report failures while
+// running (like a broken listener).
+type failureReporter interface {
+	Failed() <-chan error
+}
+
+// EndpointStatus is the state of an endpoint.
+type EndpointStatus struct {
+	Address   string `json:"address"`             // bound address
+	Listening bool   `json:"listening"`           // endpoint is running
+	MsgsIn    uint64 `json:"msgsIn"`              // number of received messages
+	MsgsOut   uint64 `json:"msgsOut"`             // number of sent messages
+	BytesIn   uint64 `json:"bytesIn"`             // size of received messages
+	BytesOut  uint64 `json:"bytesOut"`            // size of sent messages
+	LastError string `json:"lastError,omitempty"` // last error (send or run)
+	ErrorTime string `json:"errorTime,omitempty"` // time of last error
+	Restarts  int    `json:"restarts"`            // number of restarts
+}
+
+// MonitoredEndpoint wraps an endpoint: it counts the messages sent and
+// received, records errors and restarts the endpoint if it fails.
+type MonitoredEndpoint struct {
+	Endpoint
+
+	mtx    sync.Mutex     // lock for status
+	status EndpointStatus // current status
+}
+
+// NewMonitoredEndpoint creates a monitoring wrapper for an endpoint.
+func NewMonitoredEndpoint(ep Endpoint) *MonitoredEndpoint {
+	return &MonitoredEndpoint{
+		Endpoint: ep,
+	}
+}
+
+// Run the wrapped endpoint: received messages are counted before they
+// are forwarded to the handler. If the endpoint reports a failure, it is
+// restarted with increasing delays until it runs again.
+func (ep *MonitoredEndpoint) Run(ctx context.Context, hdlr chan *Message) (err error) {
+	in := make(chan *Message)
+	epCtx, cancel := context.WithCancel(ctx)
+	if err = ep.Endpoint.Run(epCtx, in); err != nil {
+		cancel()
+		ep.failed(err)
+		return
+	}
+	ep.running()
+
+	// forward incoming messages
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case tm := <-in:
+				ep.mtx.Lock()
+				ep.status.MsgsIn++
+				ep.status.BytesIn += uint64(tm.Msg.Size())
+				ep.mtx.Unlock()
+				select {
+				case hdlr <- tm:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	// watch for failures (if the endpoint reports them)
+	fr, ok := ep.Endpoint.(failureReporter)
+	if !ok || fr.Failed() == nil {
+		go func() {
+			<-ctx.Done()
+			cancel()
+		}()
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case err := <-fr.Failed():
+				logger.Printf(logger.WARN, "[monitor] endpoint %s failed: %s", ep.Address(), err.Error())
+				cancel()
+				ep.failed(err)
+				ep.mtx.Lock()
+				ep.status.Listening = false
+				ep.mtx.Unlock()
+				if epCtx, cancel = ep.restart(ctx, in, fr); epCtx == nil {
+					return
+				}
+			}
+		}
+	}()
+	return
+}
+
+// restart a failed endpoint (with increasing delays between attempts).
+// Returns the context of the running endpoint (nil if the monitor is
+// terminated).
+func (ep *MonitoredEndpoint) restart(ctx context.Context, in chan *Message, fr failureReporter) (context.Context, context.CancelFunc) {
+	delay := EndpointRestartDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(delay):
+		}
+		// drop failures of the last run
+		select {
+		case <-fr.Failed():
+		default:
+		}
+		epCtx, cancel := context.WithCancel(ctx)
+		err := ep.Endpoint.Run(epCtx, in)
+		if err == nil {
+			ep.mtx.Lock()
+			ep.status.Restarts++
+			ep.mtx.Unlock()
+			ep.running()
+			logger.Printf(logger.INFO, "[monitor] endpoint %s restarted", ep.Address())
+			return epCtx, cancel
+		}
+		cancel()
+		ep.failed(err)
+		logger.Printf(logger.WARN, "[monitor] restart of endpoint failed: %s", err.Error())
+		if delay *= 2; delay > EndpointRestartMaxDelay {
+			delay = EndpointRestartMaxDelay
+		}
+	}
+}
+
+// Send message to address: sent messages and errors are recorded.
+func (ep *MonitoredEndpoint) Send(ctx context.Context, addr net.Addr, msg *Message) error {
+	err := ep.Endpoint.Send(ctx, addr, msg)
+	if err != nil && err != ErrEndpMaybeSent {
+		ep.failed(err)
+		return err
+	}
+	ep.mtx.Lock()
+	ep.status.MsgsOut++
+	ep.status.BytesOut += uint64(msg.Msg.Size())
+	ep.mtx.Unlock()
+	return err
+}
+
+// Status returns the current state of the endpoint.
+func (ep *MonitoredEndpoint) Status() *EndpointStatus {
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	st := ep.status
+	return &st
+}
+
+// running records a started endpoint
+func (ep *MonitoredEndpoint) running() {
+	addr := ep.Address()
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	ep.status.Listening = true
+	ep.status.Address = util.URI(addr.Network(), []byte(addr.String()))
+}
+
+// failed records an error of the endpoint.
+func (ep *MonitoredEndpoint) failed(err error) {
+	ep.mtx.Lock()
+	defer ep.mtx.Unlock()
+	ep.status.LastError = err.Error()
+	ep.status.ErrorTime = time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,244 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nat
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"gnunet/util"
+
+	"github.com/bfix/gospel/logger"
+	"github.com/bfix/gospel/network"
+)
+
+// Default check interval for the external address and port mappings
+var DefaultInterval = 5 * time.Minute
+
+// Mapping of a local port to a port on the external address.
+type Mapping struct {
+	ID      string // identifier of mapping (set by caller)
+	Network string // network protocol ("tcp", "udp")
+	Port    int    // local port
+	ExtPort int    // external port (0 if not mapped)
+
+	upnpID string // UPnP mapping identifier
+	upnpIP net.IP // external address reported by UPnP
+}
+
+// Manager discovers the external address of a node behind a NAT and
+// maps local ports on the gateway. Changes of the external address or
+// of the mapped ports are reported to a callback.
+type Manager struct {
+	sync.Mutex
+
+	name     string              // name used for UPnP mappings
+	cfg      *util.NATConfig     // NAT settings
+	pmp      *PMPClient          // NAT-PMP client (optional)
+	upnp     *network.PortMapper // UPnP port mapper (optional)
+	interval time.Duration       // check interval
+	extIP    net.IP              // current external address
+	maps     []*Mapping          // port mappings
+}
+
+// NewManager creates a new NAT manager for the given settings.
+func NewManager(name string, cfg *util.NATConfig) *Manager {
+	m := &Manager{
+		name:     name,
+		cfg:      cfg,
+		interval: DefaultInterval,
+	}
+	if cfg.Interval > 0 {
+		m.interval = time.Duration(cfg.Interval) * time.Second
+	}
+	if len(cfg.NATPMP) > 0 {
+		m.pmp = NewPMPClient(cfg.NATPMP)
+	}
+	return m
+}
+
+// Add a local port to be mapped on the gateway. Without UPnP or NAT-PMP
+// the external port is the local port.
+func (m *Manager) Add(id, netw string, port int) {
+	m.Lock()
+	defer m.Unlock()
+	m.maps = append(m.maps, &Mapping{
+		ID:      id,
+		Network: netw,
+		Port:    port,
+	})
+}
+
+// ExternalIP returns the current external address (nil if unknown).
+func (m *Manager) ExternalIP() net.IP {
+	m.Lock()
+	defer m.Unlock()
+	return m.extIP
+}
+
+// Mappings returns a copy of the current port mappings.
+func (m *Manager) Mappings() (list []Mapping) {
+	m.Lock()
+	defer m.Unlock()
+	for _, mp := range m.maps {
+		list = append(list, *mp)
+	}
+	return
+}
+
+// Run the manager: the external address is checked and the port mappings
+// are refreshed periodically; 'changed' is called if the external
+// address or a mapped port changed. Mappings are removed when the
+// context is done.
+func (m *Manager) Run(ctx context.Context, changed func(ip net.IP, maps []Mapping)) {
+	go func() {
+		if m.cfg.UPnP {
+			// UPnP discovery can take some time...
+			pm, err := network.NewPortMapper(m.name)
+			if err != nil {
+				logger.Printf(logger.WARN, "[nat] UPnP not available: %s", err.Error())
+			} else {
+				m.Lock()
+				m.upnp = pm
+				m.Unlock()
+			}
+		}
+		tick := time.NewTicker(m.interval)
+		defer tick.Stop()
+		for {
+			if m.check(ctx) {
+				changed(m.ExternalIP(), m.Mappings())
+			}
+			select {
+			case <-ctx.Done():
+				m.release()
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+}
+
+// check the external address and refresh the port mappings. Returns
+// true if the address or a mapping changed.
+func (m *Manager) check(ctx context.Context) (changed bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	// refresh mappings
+	for _, mp := range m.maps {
+		port := mp.Port
+		switch {
+		case m.pmp != nil:
+			// NAT-PMP mappings expire: refresh with a lifetime spanning
+			// the next check.
+			var err error
+			if port, err = m.pmp.Map(ctx, mp.Network, mp.Port, 2*m.interval); err != nil {
+				logger.Printf(logger.WARN, "[nat] NAT-PMP mapping of %s:%d failed: %s", mp.Network, mp.Port, err.Error())
+				port = 0
+			}
+		case m.upnp != nil:
+			// UPnP mappings are permanent
+			if len(mp.upnpID) == 0 {
+				id, ext, _, err := m.upnp.Assign(mp.Network, mp.Port)
+				if err != nil {
+					logger.Printf(logger.WARN, "[nat] UPnP mapping of %s:%d failed: %s", mp.Network, mp.Port, err.Error())
+					port = 0
+				} else {
+					mp.upnpID = id
+					host, p, _ := net.SplitHostPort(ext)
+					mp.upnpIP = net.ParseIP(host)
+					if port, err = strconv.Atoi(p); err != nil {
+						port = mp.Port
+					}
+				}
+			} else {
+				port = mp.ExtPort
+			}
+		}
+		if port != mp.ExtPort {
+			mp.ExtPort = port
+			changed = true
+		}
+	}
+	// discover external address
+	ip := m.discover(ctx)
+	if ip == nil {
+		logger.Println(logger.WARN, "[nat] External address not found")
+		return false
+	}
+	if !ip.Equal(m.extIP) {
+		logger.Printf(logger.INFO, "[nat] External address is %s", ip)
+		m.extIP = ip
+		changed = true
+	}
+	return
+}
+
+// discover the external address: STUN servers are queried first, then
+// the gateway is asked (caller holds the lock).
+func (m *Manager) discover(ctx context.Context) net.IP {
+	for _, srv := range m.cfg.STUN {
+		addr, err := STUNQuery(ctx, srv)
+		if err == nil {
+			return addr.IP
+		}
+		logger.Printf(logger.WARN, "[nat] STUN query on %s failed: %s", srv, err.Error())
+	}
+	if m.pmp != nil {
+		ip, err := m.pmp.ExternalIP(ctx)
+		if err == nil {
+			return ip
+		}
+		logger.Printf(logger.WARN, "[nat] NAT-PMP query failed: %s", err.Error())
+	}
+	// the external address is reported by UPnP mappings
+	for _, mp := range m.maps {
+		if mp.upnpIP != nil {
+			return mp.upnpIP
+		}
+	}
+	return nil
+}
+
+// release all port mappings on the gateway.
+func (m *Manager) release() {
+	m.Lock()
+	defer m.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, mp := range m.maps {
+		var err error
+		switch {
+		case m.pmp != nil:
+			_, err = m.pmp.Map(ctx, mp.Network, mp.Port, 0)
+		case m.upnp != nil && len(mp.upnpID) > 0:
+			err = m.upnp.Unassign(mp.upnpID)
+		}
+		if err != nil {
+			logger.Printf(logger.WARN, "[nat] Failed to remove mapping of %s:%d: %s", mp.Network, mp.Port, err.Error())
+		}
+	}
+	if m.upnp != nil {
+		m.upnp.Close()
+	}
+}
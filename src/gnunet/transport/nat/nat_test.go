@@ -0,0 +1,233 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nat
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"gnunet/util"
+)
+
+// run a fake STUN server answering with the given mapped address
+func stunServer(t *testing.T, ctx context.Context, mapped func() *net.UDPAddr) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderSize || binary.BigEndian.Uint16(buf) != stunBindingRequest {
+				continue
+			}
+			addr := mapped()
+			resp := make([]byte, stunHeaderSize+12)
+			binary.BigEndian.PutUint16(resp[0:], stunBindingResponse)
+			binary.BigEndian.PutUint16(resp[2:], 12)
+			copy(resp[4:20], buf[4:20])
+			// XOR-MAPPED-ADDRESS (IPv4)
+			attr := resp[stunHeaderSize:]
+			binary.BigEndian.PutUint16(attr[0:], stunXorMappedAddr)
+			binary.BigEndian.PutUint16(attr[2:], 8)
+			attr[5] = 1
+			binary.BigEndian.PutUint16(attr[6:], uint16(addr.Port)^uint16(stunMagic>>16))
+			ip := addr.IP.To4()
+			for i := range ip {
+				attr[8+i] = ip[i] ^ resp[4+i]
+			}
+			if _, err = conn.WriteTo(resp, from); err != nil {
+				return
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// run a fake NAT-PMP gateway (external port is local port + 1000)
+func pmpGateway(t *testing.T, ctx context.Context, ext net.IP, mapped map[int]int, mtx *sync.Mutex) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil || n < 2 {
+				return
+			}
+			var resp []byte
+			switch buf[1] {
+			case pmpOpAddress:
+				resp = make([]byte, 12)
+				copy(resp[8:], ext.To4())
+			case pmpOpMapUDP, pmpOpMapTCP:
+				port := int(binary.BigEndian.Uint16(buf[4:]))
+				lifetime := binary.BigEndian.Uint32(buf[8:])
+				resp = make([]byte, 16)
+				copy(resp[8:10], buf[4:6])
+				mtx.Lock()
+				if lifetime == 0 {
+					delete(mapped, port)
+				} else {
+					mapped[port] = port + 1000
+					binary.BigEndian.PutUint16(resp[10:], uint16(port+1000))
+				}
+				mtx.Unlock()
+				binary.BigEndian.PutUint32(resp[12:], lifetime)
+			default:
+				continue
+			}
+			resp[1] = buf[1] + pmpOpResponse
+			if _, err = conn.WriteTo(resp, from); err != nil {
+				return
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestSTUNQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ext := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 7).To4(), Port: 40123}
+	srv := stunServer(t, ctx, func() *net.UDPAddr { return ext })
+	addr, err := STUNQuery(ctx, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !addr.IP.Equal(ext.IP) || addr.Port != ext.Port {
+		t.Fatalf("unexpected mapped address %s", addr)
+	}
+	// invalid responses
+	if _, err = parseSTUNResponse(make([]byte, stunHeaderSize)); err != ErrSTUNResponse {
+		t.Fatalf("invalid response accepted: %v", err)
+	}
+}
+
+func TestPMPClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mtx sync.Mutex
+	mapped := make(map[int]int)
+	ext := net.IPv4(203, 0, 113, 9)
+	c := NewPMPClient(pmpGateway(t, ctx, ext, mapped, &mtx))
+	ip, err := c.ExternalIP(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(ext) {
+		t.Fatalf("unexpected external address %s", ip)
+	}
+	port, err := c.Map(ctx, "tcp", 2086, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 3086 {
+		t.Fatalf("unexpected external port %d", port)
+	}
+	if _, err = c.Map(ctx, "sctp", 2086, time.Hour); err != ErrPMPProtocol {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// external address changes with the STUN answer
+	var mtx sync.Mutex
+	ext := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 7).To4(), Port: 40123}
+	stun := stunServer(t, ctx, func() *net.UDPAddr {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return ext
+	})
+	mapped := make(map[int]int)
+	gw := pmpGateway(t, ctx, net.IPv4(203, 0, 113, 9), mapped, &mtx)
+
+	m := NewManager("test", &util.NATConfig{
+		STUN:   []string{stun},
+		NATPMP: gw,
+	})
+	m.interval = 50 * time.Millisecond
+	m.Add("udp", "udp", 2086)
+	m.Add("tcp", "tcp", 2087)
+
+	type update struct {
+		ip   net.IP
+		maps []Mapping
+	}
+	updates := make(chan update, 10)
+	runCtx, stop := context.WithCancel(ctx)
+	m.Run(runCtx, func(ip net.IP, maps []Mapping) {
+		updates <- update{ip, maps}
+	})
+	next := func() update {
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("no update")
+		}
+		return update{}
+	}
+	u := next()
+	if !u.ip.Equal(ext.IP) || len(u.maps) != 2 || u.maps[0].ExtPort != 3086 || u.maps[1].ExtPort != 3087 {
+		t.Fatalf("unexpected update %v", u)
+	}
+	// change of external address is reported
+	mtx.Lock()
+	ext = &net.UDPAddr{IP: net.IPv4(198, 51, 100, 8).To4(), Port: 40123}
+	mtx.Unlock()
+	if u = next(); !u.ip.Equal(net.IPv4(198, 51, 100, 8)) {
+		t.Fatalf("unexpected update %v", u)
+	}
+	// mappings are removed on shutdown
+	stop()
+	for i := 0; ; i++ {
+		mtx.Lock()
+		n := len(mapped)
+		mtx.Unlock()
+		if n == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("mappings not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
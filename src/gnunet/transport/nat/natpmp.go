@@ -0,0 +1,142 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nat
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Error codes
+var (
+	ErrPMPResponse = errors.New("invalid NAT-PMP response")
+	ErrPMPProtocol = errors.New("unsupported protocol for NAT-PMP")
+)
+
+// NAT-PMP constants (RFC 6886)
+const (
+	pmpPort        = "5351"
+	pmpOpAddress   = 0
+	pmpOpMapUDP    = 1
+	pmpOpMapTCP    = 2
+	pmpOpResponse  = 128
+	pmpMaxAttempts = 4
+)
+
+// PMPTimeout is the time to wait for the first response of a NAT-PMP
+// gateway (doubled for each retry).
+var PMPTimeout = 250 * time.Millisecond
+
+// PMPClient talks to a NAT-PMP gateway.
+type PMPClient struct {
+	gateway string // gateway address ("host:port")
+}
+
+// NewPMPClient creates a client for a gateway ("host" or "host:port";
+// the NAT-PMP port is used if no port is given).
+func NewPMPClient(gateway string) *PMPClient {
+	if _, _, err := net.SplitHostPort(gateway); err != nil {
+		gateway = net.JoinHostPort(gateway, pmpPort)
+	}
+	return &PMPClient{gateway: gateway}
+}
+
+// ExternalIP asks the gateway for its external address.
+func (c *PMPClient) ExternalIP(ctx context.Context) (net.IP, error) {
+	resp, err := c.request(ctx, []byte{0, pmpOpAddress}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IP(resp[8:12]), nil
+}
+
+// Map requests a mapping of a local port ("tcp" or "udp") for the given
+// lifetime (in seconds, rounded up; a zero lifetime removes the
+// mapping). Returns the external
+// port assigned by the gateway.
+func (c *PMPClient) Map(ctx context.Context, proto string, port int, lifetime time.Duration) (int, error) {
+	var op byte
+	switch proto {
+	case "udp":
+		op = pmpOpMapUDP
+	case "tcp":
+		op = pmpOpMapTCP
+	default:
+		return 0, ErrPMPProtocol
+	}
+	req := make([]byte, 12)
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:], uint16(port))
+	binary.BigEndian.PutUint16(req[6:], uint16(port))
+	binary.BigEndian.PutUint32(req[8:], uint32((lifetime+time.Second-1)/time.Second))
+	resp, err := c.request(ctx, req, 16)
+	if err != nil {
+		return 0, err
+	}
+	if int(binary.BigEndian.Uint16(resp[8:])) != port {
+		return 0, ErrPMPResponse
+	}
+	return int(binary.BigEndian.Uint16(resp[10:])), nil
+}
+
+// request sends a request to the gateway (with retries) and returns the
+// response of expected size.
+func (c *PMPClient) request(ctx context.Context, req []byte, size int) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", c.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	buf := make([]byte, 16)
+	timeout := PMPTimeout
+	for i := 0; i < pmpMaxAttempts; i++ {
+		if _, err = conn.Write(req); err != nil {
+			return nil, err
+		}
+		if err = conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+		timeout *= 2
+		for {
+			var n int
+			if n, err = conn.Read(buf); err != nil {
+				break
+			}
+			if n < size || buf[0] != 0 || buf[1] != req[1]+pmpOpResponse {
+				continue
+			}
+			if rc := binary.BigEndian.Uint16(buf[2:]); rc != 0 {
+				return nil, fmt.Errorf("%w: result code %d", ErrPMPResponse, rc)
+			}
+			return buf[:n], nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+			return nil, err
+		}
+	}
+	return nil, err
+}
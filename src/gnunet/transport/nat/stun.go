@@ -0,0 +1,155 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package nat
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"gnunet/util"
+)
+
+// Error codes
+var (
+	ErrSTUNResponse = errors.New("invalid STUN response")
+	ErrSTUNNoAddr   = errors.New("no mapped address in STUN response")
+)
+
+// STUN message constants (RFC 5389)
+const (
+	stunMagic           = 0x2112A442
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMappedAddr      = 0x0001
+	stunXorMappedAddr   = 0x0020
+	stunHeaderSize      = 20
+)
+
+// STUNTimeout is the time limit for a STUN query.
+var STUNTimeout = 5 * time.Second
+
+// STUNQuery sends a binding request to a STUN server ("host:port") and
+// returns the external (mapped) address of the local UDP socket.
+func STUNQuery(ctx context.Context, server string) (*net.UDPAddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, STUNTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(dl); err != nil {
+			return nil, err
+		}
+	}
+	// send binding request
+	txID := util.NewRndArray(12)
+	req := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagic)
+	copy(req[8:], txID)
+	if _, err = conn.Write(req); err != nil {
+		return nil, err
+	}
+	// read response (ignore unrelated messages)
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n < stunHeaderSize || !bytes.Equal(buf[8:20], txID) {
+			continue
+		}
+		return parseSTUNResponse(buf[:n])
+	}
+}
+
+// parseSTUNResponse returns the mapped address from a binding response.
+func parseSTUNResponse(buf []byte) (*net.UDPAddr, error) {
+	if binary.BigEndian.Uint16(buf[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(buf[4:]) != stunMagic {
+		return nil, ErrSTUNResponse
+	}
+	size := int(binary.BigEndian.Uint16(buf[2:]))
+	if stunHeaderSize+size > len(buf) {
+		return nil, ErrSTUNResponse
+	}
+	var mapped *net.UDPAddr
+	attrs := buf[stunHeaderSize : stunHeaderSize+size]
+	for len(attrs) >= 4 {
+		aType := binary.BigEndian.Uint16(attrs[0:])
+		aLen := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+aLen > len(attrs) {
+			return nil, ErrSTUNResponse
+		}
+		val := attrs[4 : 4+aLen]
+		switch aType {
+		case stunXorMappedAddr:
+			// XOR-MAPPED-ADDRESS is preferred
+			if addr := parseSTUNAddr(val, buf[4:20]); addr != nil {
+				return addr, nil
+			}
+		case stunMappedAddr:
+			mapped = parseSTUNAddr(val, nil)
+		}
+		// attributes are padded to multiples of 4 bytes
+		attrs = attrs[4+(aLen+3)&^3:]
+	}
+	if mapped == nil {
+		return nil, ErrSTUNNoAddr
+	}
+	return mapped, nil
+}
+
+// parseSTUNAddr decodes an address attribute; if a key (magic cookie and
+// transaction ID) is given, the address is XOR'ed with it.
+func parseSTUNAddr(val, key []byte) *net.UDPAddr {
+	if len(val) < 4 {
+		return nil
+	}
+	var size int
+	switch val[1] {
+	case 1:
+		size = net.IPv4len
+	case 2:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(val) < 4+size {
+		return nil
+	}
+	port := binary.BigEndian.Uint16(val[2:])
+	ip := make(net.IP, size)
+	copy(ip, val[4:4+size])
+	if key != nil {
+		port ^= uint16(stunMagic >> 16)
+		for i := range ip {
+			ip[i] ^= key[i]
+		}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
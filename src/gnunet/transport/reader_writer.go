@@ -109,17 +109,7 @@ func ReadMessage(ctx context.Context, rdr io.ReadCloser, buf []byte) (msg messag
 	if err = get(4, int(mh.MsgSize)-4); err != nil {
 		return
 	}
-	if msg, err = message.NewEmptyMessage(mh.MsgType); err != nil {
-		return
-	}
-	if msg == nil {
-		err = fmt.Errorf("message{%d} is nil", mh.MsgType)
-		return
-	}
-	if err = data.Unmarshal(msg, buf[:mh.MsgSize]); err != nil {
-		return
-	}
-	err = msg.Init()
+	msg, err = message.Parse(buf[:mh.MsgSize])
 	/*
 		// DEBUG: incoming messages
 		if mh.MsgType == enums.MSG_DHT_P2P_RESULT {
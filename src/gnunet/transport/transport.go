@@ -34,8 +34,15 @@ import (
 var (
 	ErrTransNoEndpoint = errors.New("no matching endpoint found")
 	ErrTransNoUPNP     = errors.New("no UPnP available")
+	ErrTransNotLocal   = errors.New("non-local address in local-only mode")
 )
 
+// LocalOnly restricts all transports to local addresses (loopback and
+// unix domain sockets): endpoints must listen on local addresses, no
+// messages are sent to other addresses and UPnP is disabled. Must be set
+// before the transport is created (hermetic development and CI runs).
+var LocalOnly bool
+
 //======================================================================
 // Network-oriented transport implementation
 //======================================================================
@@ -59,6 +66,10 @@ type Message struct {
 	// resolving the return path). Set to nil if not used.
 	Resp Responder
 
+	// Addr is the (optional) network address the message was received
+	// from; used to answer requests directly (e.g. PONG for a PING).
+	Addr *util.Address
+
 	// Label for log messages during message processing
 	Label string
 }
@@ -106,10 +117,13 @@ type Transport struct {
 
 // NewTransport creates and runs a new transport layer implementation.
 func NewTransport(ctx context.Context, tag string, ch chan *Message) (t *Transport) {
-	// create transport instance
-	mngr, err := network.NewPortMapper(tag)
-	if err != nil {
-		mngr = nil
+	// create transport instance (UPnP is not used in local-only mode)
+	var mngr *network.PortMapper
+	if !LocalOnly {
+		var err error
+		if mngr, err = network.NewPortMapper(tag); err != nil {
+			mngr = nil
+		}
 	}
 	return &Transport{
 		incoming:  ch,
@@ -127,6 +141,9 @@ func (t *Transport) Shutdown() {
 
 // Send a message over suitable endpoint
 func (t *Transport) Send(ctx context.Context, addr net.Addr, msg *Message) (err error) {
+	if LocalOnly && !IsLocalAddress(addr) {
+		return ErrTransNotLocal
+	}
 	// select best endpoint able to handle address
 	var bestEp Endpoint
 	err = t.endpoints.ProcessRange(func(_ int, ep Endpoint, _ int) error {
@@ -144,21 +161,45 @@ func (t *Transport) Send(ctx context.Context, addr net.Addr, msg *Message) (err
 	if err != nil {
 		return
 	}
+	if bestEp == nil {
+		return ErrEndpNotAvailable
+	}
 	return bestEp.Send(ctx, addr, msg)
 }
 
+// CanSendTo returns true if an endpoint can handle the address.
+func (t *Transport) CanSendTo(addr net.Addr) (ok bool) {
+	if LocalOnly && !IsLocalAddress(addr) {
+		return false
+	}
+	_ = t.endpoints.ProcessRange(func(_ int, ep Endpoint, _ int) error {
+		ok = ok || ep.CanSendTo(addr)
+		return nil
+	}, true)
+	return
+}
+
 //----------------------------------------------------------------------
 // Endpoint handling
 //----------------------------------------------------------------------
 
 // AddEndpoint instantiates and run a new endpoint handler for the
-// given address (must map to a network interface).
-func (t *Transport) AddEndpoint(ctx context.Context, addr *util.Address) (ep Endpoint, err error) {
+// given address (must map to a network interface). If a fault
+// configuration is specified, faults are injected into the message
+// flow on the endpoint (chaos testing). The TLS configuration applies to
+// HTTPS endpoints only, the fragmentation settings to packet-oriented
+// endpoints only. The endpoint is monitored and restarted if it fails
+// while running.
+func (t *Transport) AddEndpoint(ctx context.Context, addr *util.Address, faults *util.FaultConfig, tlsCfg *util.TLSConfig, fragCfg *util.FragmentConfig) (ep Endpoint, err error) {
 	// check for valid address
 	if addr == nil {
 		err = ErrEndpNoAddress
 		return
 	}
+	if LocalOnly && !IsLocalAddress(addr) {
+		err = ErrTransNotLocal
+		return
+	}
 	// check if endpoint is already available
 	as := addr.Network() + "://" + addr.String()
 	if err = t.endpoints.ProcessRange(func(_ int, ep Endpoint, _ int) error {
@@ -171,9 +212,14 @@ func (t *Transport) AddEndpoint(ctx context.Context, addr *util.Address) (ep End
 		return
 	}
 	// register new endpoint
-	if ep, err = NewEndpoint(addr); err != nil {
+	if ep, err = NewEndpoint(addr, tlsCfg, fragCfg); err != nil {
 		return
 	}
+	if faults != nil {
+		ep = NewFaultyEndpoint(ep, faults)
+	}
+	// monitor endpoint (counters, restart on failure)
+	ep = NewMonitoredEndpoint(ep)
 	// add endpoint to list and run it
 	t.endpoints.Put(ep.ID(), ep, 0)
 	err = ep.Run(ctx, t.incoming)
@@ -210,13 +256,37 @@ func (t *Transport) ForwardClose(id string) error {
 //----------------------------------------------------------------------
 
 // CanHandleAddress returns true, if a given address can be handled by the
-// transport framework
+// transport framework: local addresses are filtered out (only local
+// addresses are accepted in local-only mode). Unix domain socket
+// addresses are local addresses.
 func CanHandleAddress(addr *util.Address) bool {
-	// filter out local addresses
+	if strings.HasPrefix(addr.Network(), "unix") {
+		return LocalOnly
+	}
 	s := addr.String()
 	if idx := strings.LastIndex(s, ":"); idx != -1 {
 		s = s[:idx]
 	}
 	ip := net.ParseIP(s)
+	if LocalOnly {
+		return ip != nil && ip.IsLoopback()
+	}
 	return !(ip == nil || ip.IsLoopback())
 }
+
+// IsLocalAddress returns true for loopback and unix domain socket
+// addresses.
+func IsLocalAddress(addr net.Addr) bool {
+	if strings.HasPrefix(addr.Network(), "unix") {
+		return true
+	}
+	h, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		h = addr.String()
+	}
+	if h == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(h)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,351 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"gnunet/message"
+	"gnunet/util"
+)
+
+func TestLocalOnly(t *testing.T) {
+	local, _ := util.ParseAddress("ip+udp://127.0.0.1:2086")
+	remote, _ := util.ParseAddress("ip+udp://192.0.2.1:2086")
+	tests := []struct {
+		addr         *util.Address
+		isLocal      bool
+		handle, only bool // CanHandleAddress in normal and local-only mode
+	}{
+		{local, true, false, true},
+		{remote, false, true, false},
+	}
+	defer func() { LocalOnly = false }()
+	for _, tc := range tests {
+		if IsLocalAddress(tc.addr) != tc.isLocal {
+			t.Errorf("%s: local=%v expected", tc.addr.URI(), tc.isLocal)
+		}
+		LocalOnly = false
+		if CanHandleAddress(tc.addr) != tc.handle {
+			t.Errorf("%s: handle=%v expected", tc.addr.URI(), tc.handle)
+		}
+		LocalOnly = true
+		if CanHandleAddress(tc.addr) != tc.only {
+			t.Errorf("%s: handle=%v expected in local-only mode", tc.addr.URI(), tc.only)
+		}
+	}
+	// no endpoints on (and no messages to) non-local addresses
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trans := NewTransport(ctx, "test", make(chan *Message))
+	if _, err := trans.AddEndpoint(ctx, remote, nil, nil, nil); err != ErrTransNotLocal {
+		t.Fatalf("non-local endpoint added: %v", err)
+	}
+	msg := NewTransportMessage(nil, message.NewTransportPingMsg(nil, nil))
+	if err := trans.Send(ctx, remote, msg); err != ErrTransNotLocal {
+		t.Fatalf("message sent to non-local address: %v", err)
+	}
+}
+
+func TestStreamEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := func() (*StreamEndpoint, chan *Message) {
+		addr, _ := util.ParseAddress("tcp://127.0.0.1:0")
+		ep, err := newStreamEndpoint(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan *Message)
+		if err = ep.Run(ctx, ch); err != nil {
+			t.Fatal(err)
+		}
+		return ep, ch
+	}
+	receive := func(ch chan *Message) *Message {
+		t.Helper()
+		select {
+		case tm := <-ch:
+			return tm
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+		return nil
+	}
+	ep1, ch1 := run()
+	ep2, ch2 := run()
+	peer1 := util.NewPeerID(util.NewRndArray(32))
+	peer2 := util.NewPeerID(util.NewRndArray(32))
+
+	// messages arrive in order on a single connection
+	var sent []uint32
+	for i := 0; i < 100; i++ {
+		ping := message.NewTransportPingMsg(peer2, nil)
+		sent = append(sent, ping.Challenge)
+		if err := ep1.Send(ctx, ep2.Address(), NewTransportMessage(peer1, ping)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var last *Message
+	for _, c := range sent {
+		last = receive(ch2)
+		ping, ok := last.Msg.(*message.TransportPingMsg)
+		if !ok || ping.Challenge != c || !last.Peer.Equal(peer1) {
+			t.Fatalf("unexpected message %v", last.Msg)
+		}
+	}
+	// the response reuses the connection
+	pong := message.NewTransportPongMsg(sent[0], last.Addr)
+	if err := ep2.Send(ctx, last.Addr, NewTransportMessage(peer2, pong)); err != nil {
+		t.Fatal(err)
+	}
+	if tm := receive(ch1); !tm.Peer.Equal(peer2) || tm.Msg.Type() != pong.Type() {
+		t.Fatalf("unexpected response %v", tm.Msg)
+	}
+	if ep1.Connections() != 1 || ep2.Connections() != 1 {
+		t.Fatalf("connections not reused: %d/%d", ep1.Connections(), ep2.Connections())
+	}
+
+	// a receiver that doesn't process messages blocks the sender
+	defer func(d time.Duration) { StreamWriteTimeout = d }(StreamWriteTimeout)
+	StreamWriteTimeout = 200 * time.Millisecond
+	for i := 0; ; i++ {
+		if i == 1000 {
+			t.Fatal("sender not blocked")
+		}
+		msg := message.NewDHTP2PPutMsg(nil)
+		msg.Block = make([]byte, 60000)
+		msg.MsgSize += uint16(len(msg.Block))
+		err := ep1.Send(ctx, ep2.Address(), NewTransportMessage(peer1, msg))
+		if err == nil {
+			continue
+		}
+		// the queue is full or the stalled connection was dropped
+		if err != ErrEndpBusy && err != ErrEndpNoConnection {
+			t.Fatal(err)
+		}
+		break
+	}
+}
+
+func TestUnixEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// socket file left by a terminated node
+	path := t.TempDir() + "/peer1.sock"
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	run := func(path string) (Endpoint, chan *Message) {
+		addr, _ := util.ParseAddress("unix://" + path)
+		ep, err := NewEndpoint(addr, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan *Message)
+		if err = ep.Run(ctx, ch); err != nil {
+			t.Fatal(err)
+		}
+		return ep, ch
+	}
+	ep1, ch1 := run(path)
+	ep2, ch2 := run(t.TempDir() + "/peer2.sock")
+	if !ep1.CanSendTo(ep2.Address()) {
+		t.Fatal("can't send to unix address")
+	}
+	if !IsLocalAddress(ep2.Address()) {
+		t.Fatal("unix address not local")
+	}
+	// message and response
+	peer1 := util.NewPeerID(util.NewRndArray(32))
+	peer2 := util.NewPeerID(util.NewRndArray(32))
+	ping := message.NewTransportPingMsg(peer2, nil)
+	if err = ep1.Send(ctx, ep2.Address(), NewTransportMessage(peer1, ping)); err != nil {
+		t.Fatal(err)
+	}
+	var tm *Message
+	select {
+	case tm = <-ch2:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	pong := message.NewTransportPongMsg(ping.Challenge, tm.Addr)
+	if err = ep2.Send(ctx, tm.Addr, NewTransportMessage(peer2, pong)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case tm = <-ch1:
+		if !tm.Peer.Equal(peer2) || tm.Msg.Type() != pong.Type() {
+			t.Fatalf("unexpected response %v", tm.Msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response received")
+	}
+}
+
+func TestMonitoredEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	EndpointRestartDelay = 10 * time.Millisecond
+
+	run := func() (*MonitoredEndpoint, chan *Message) {
+		addr, _ := util.ParseAddress("ip+tcp://127.0.0.1:0")
+		ep, err := NewEndpoint(addr, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mon := NewMonitoredEndpoint(ep)
+		ch := make(chan *Message)
+		if err = mon.Run(ctx, ch); err != nil {
+			t.Fatal(err)
+		}
+		return mon, ch
+	}
+	ep1, _ := run()
+	ep2, ch2 := run()
+	peer := util.NewPeerID(util.NewRndArray(32))
+	send := func() {
+		ping := message.NewTransportPingMsg(peer, nil)
+		if err := ep1.Send(ctx, ep2.Address(), NewTransportMessage(peer, ping)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ch2:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+	}
+	send()
+	st1, st2 := ep1.Status(), ep2.Status()
+	if st1.MsgsOut != 1 || st1.BytesOut == 0 || st2.MsgsIn != 1 || st2.BytesIn != st1.BytesOut {
+		t.Fatalf("unexpected counters: %v / %v", st1, st2)
+	}
+	if !st2.Listening || st2.Address != "ip+tcp://"+ep2.Address().String() {
+		t.Fatalf("unexpected state %v", st2)
+	}
+	// failing endpoint is restarted on the same address
+	addr := ep2.Address().String()
+	ep2.Endpoint.(*StreamEndpoint).report(errors.New("interface gone"))
+	for i := 0; ep2.Status().Restarts == 0; i++ {
+		if i == 500 {
+			t.Fatal("endpoint not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st2 = ep2.Status(); st2.LastError != "interface gone" || !st2.Listening || ep2.Address().String() != addr {
+		t.Fatalf("unexpected state after restart: %v", st2)
+	}
+	send()
+	if st2 = ep2.Status(); st2.MsgsIn != 2 {
+		t.Fatalf("unexpected counters after restart: %v", st2)
+	}
+}
+
+func TestHTTPEndpoint(t *testing.T) {
+	for _, netw := range []string{"http", "https"} {
+		t.Run(netw, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			addr, _ := util.ParseAddress(netw + "://127.0.0.1:0")
+
+			// server endpoint (self-signed certificate for HTTPS)
+			srv, err := NewEndpoint(addr, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chSrv := make(chan *Message)
+			if err = srv.Run(ctx, chSrv); err != nil {
+				t.Fatal(err)
+			}
+			// client endpoint
+			cl, err := NewEndpoint(addr, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chCl := make(chan *Message)
+			if err = cl.Run(ctx, chCl); err != nil {
+				t.Fatal(err)
+			}
+			if !cl.CanSendTo(srv.Address()) {
+				t.Fatal("can't send to server")
+			}
+			tcp, _ := util.ParseAddress("tcp://127.0.0.1:2086")
+			if cl.CanSendTo(tcp) {
+				t.Fatal("can send to stream address")
+			}
+			receive := func(ch chan *Message) *Message {
+				t.Helper()
+				select {
+				case tm := <-ch:
+					return tm
+				case <-time.After(5 * time.Second):
+					t.Fatal("no message received")
+				}
+				return nil
+			}
+			peerCl := util.NewPeerID(util.NewRndArray(32))
+			peerSrv := util.NewPeerID(util.NewRndArray(32))
+
+			// messages from client arrive in order
+			var sent []uint32
+			for i := 0; i < 50; i++ {
+				ping := message.NewTransportPingMsg(peerSrv, nil)
+				sent = append(sent, ping.Challenge)
+				if err := cl.Send(ctx, srv.Address(), NewTransportMessage(peerCl, ping)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var last *Message
+			for _, c := range sent {
+				last = receive(chSrv)
+				ping, ok := last.Msg.(*message.TransportPingMsg)
+				if !ok || ping.Challenge != c || !last.Peer.Equal(peerCl) {
+					t.Fatalf("unexpected message %v", last.Msg)
+				}
+			}
+			// the response is delivered in the session of the client
+			pong := message.NewTransportPongMsg(sent[0], last.Addr)
+			if err := srv.Send(ctx, last.Addr, NewTransportMessage(peerSrv, pong)); err != nil {
+				t.Fatal(err)
+			}
+			tm := receive(chCl)
+			if !tm.Peer.Equal(peerSrv) || tm.Msg.Type() != pong.Type() {
+				t.Fatalf("unexpected response %v", tm.Msg)
+			}
+			if tm.Addr.URI() != srv.Address().(*util.Address).URI() {
+				t.Fatalf("response from %s", tm.Addr.URI())
+			}
+			if n := srv.(*HTTPEndpoint).Sessions(); n != 1 {
+				t.Fatalf("%d sessions on server", n)
+			}
+			if n := cl.(*HTTPEndpoint).Sessions(); n != 1 {
+				t.Fatalf("%d sessions on client", n)
+			}
+		})
+	}
+}
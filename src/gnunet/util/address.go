@@ -31,6 +31,7 @@ type Address struct {
 	Options uint32       // address options
 	Expire  AbsoluteTime // expiration date for address
 	Address []byte       // address data (protocol-dependent)
+	Class   string       // address class (local tag; not transmitted)
 }
 
 // NewAddress returns a new Address for the given transport and specs
@@ -56,13 +57,19 @@ func NewAddressWrap(addr net.Addr) *Address {
 
 // ParseAddress translates a GNUnet address string like
 // "ip+udp://1.2.3.4:6789" or "gnunet+tcp://12.3.4.5/".
-// It can also handle standard strings like "udp:127.0.0.1:6735".
+// It can also handle standard strings like "udp:127.0.0.1:6735". Unix
+// domain socket addresses keep their (absolute) path, like in
+// "unix:///run/gnunet/peer.sock".
 func ParseAddress(s string) (addr *Address, err error) {
 	p := strings.SplitN(s, ":", 2)
 	if len(p) != 2 {
 		err = fmt.Errorf("invalid address format: '%s'", s)
 		return
 	}
+	if strings.HasPrefix(p[0], "unix") {
+		addr = NewAddress(p[0], strings.TrimPrefix(p[1], "//"))
+		return
+	}
 	addr = NewAddress(p[0], strings.Trim(p[1], "/"))
 	return
 }
@@ -59,3 +59,27 @@ func TestAddrList(t *testing.T) {
 		t.Fatal("list size not matching")
 	}
 }
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		s, netw, addr string
+	}{
+		{"ip+udp://127.0.0.1:10000", "ip+udp", "127.0.0.1:10000"},
+		{"udp:127.0.0.1:6735", "udp", "127.0.0.1:6735"},
+		{"gnunet+tcp://12.3.4.5/", "gnunet+tcp", "12.3.4.5"},
+		{"unix:///run/gnunet/peer.sock", "unix", "/run/gnunet/peer.sock"},
+	}
+	for _, c := range cases {
+		addr, err := ParseAddress(c.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.Network() != c.netw || addr.String() != c.addr {
+			t.Fatalf("%s: got %s %s", c.s, addr.Network(), addr.String())
+		}
+	}
+	addr, _ := ParseAddress("unix:///run/gnunet/peer.sock")
+	if u, _ := ParseAddress(addr.URI()); u.String() != addr.String() {
+		t.Fatalf("unix address changed: %s", u.String())
+	}
+}
@@ -0,0 +1,89 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import "sync"
+
+//----------------------------------------------------------------------
+// Peer aliases: human-friendly names for peers used in log output and
+// listings (see PeerID.Short). Aliases are either configured or learned
+// at runtime (e.g. from NICK records); learned aliases never replace
+// configured ones.
+//----------------------------------------------------------------------
+
+// peer alias entry
+type peerAlias struct {
+	name       string // alias
+	configured bool   // alias set by configuration
+}
+
+// registry of peer aliases (keyed by peer ID string)
+var (
+	aliasLock sync.RWMutex
+	aliases   = make(map[string]*peerAlias)
+)
+
+// SetPeerAlias sets the configured alias for a peer. An empty alias
+// removes the entry.
+func SetPeerAlias(p *PeerID, alias string) {
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+	if len(alias) == 0 {
+		delete(aliases, p.String())
+		return
+	}
+	aliases[p.String()] = &peerAlias{name: alias, configured: true}
+}
+
+// LearnPeerAlias sets an alias for a peer unless the peer has a
+// configured alias. Returns true if the alias was set.
+func LearnPeerAlias(p *PeerID, alias string) bool {
+	if len(alias) == 0 {
+		return false
+	}
+	aliasLock.Lock()
+	defer aliasLock.Unlock()
+	key := p.String()
+	if e, ok := aliases[key]; ok && e.configured {
+		return false
+	}
+	aliases[key] = &peerAlias{name: alias}
+	return true
+}
+
+// PeerAlias returns the alias of a peer (if any).
+func PeerAlias(p *PeerID) (string, bool) {
+	aliasLock.RLock()
+	defer aliasLock.RUnlock()
+	if e, ok := aliases[p.String()]; ok {
+		return e.name, true
+	}
+	return "", false
+}
+
+// PeerAliases returns all known aliases (keyed by peer ID string).
+func PeerAliases() map[string]string {
+	aliasLock.RLock()
+	defer aliasLock.RUnlock()
+	list := make(map[string]string, len(aliases))
+	for k, e := range aliases {
+		list[k] = e.name
+	}
+	return list
+}
@@ -21,6 +21,7 @@ package util
 import (
 	"errors"
 	"strings"
+	"unicode"
 )
 
 //------------------------------------------------------------------------
@@ -86,7 +87,8 @@ func DecodeStringToBinary(s string, num int) ([]byte, error) {
 	for {
 		if n < 8 {
 			if rpos < size {
-				c := rune(s[rpos])
+				// decoding is case-insensitive
+				c := unicode.ToUpper(rune(s[rpos]))
 				rpos++
 				v := strings.IndexRune(xlate, c)
 				if v == -1 {
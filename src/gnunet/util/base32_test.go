@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
 	"testing"
 )
 
@@ -76,6 +77,13 @@ func TestBase32Preset(t *testing.T) {
 		if !bytes.Equal(x.bin, e) {
 			t.Fatalf("Decoding mismatch: '%s' != '%s' for '%s'\n", hex.EncodeToString(e), hex.EncodeToString(x.bin), x.str)
 		}
+		// decoding is case-insensitive
+		if e, err = DecodeStringToBinary(strings.ToLower(x.str), len(x.bin)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(x.bin, e) {
+			t.Fatalf("Decoding mismatch (lower case) for '%s'\n", x.str)
+		}
 	}
 }
 
@@ -0,0 +1,132 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bfix/gospel/logger"
+)
+
+//----------------------------------------------------------------------
+// Clock skew:
+// Expirations in data received from other peers are set by their clocks.
+// Expirations are judged with a tolerance (clock skew) so a node with a
+// slightly wrong clock doesn't reject all data from the network. A clock
+// monitor compares the expirations seen in received data with their
+// maximum lifetime: if most of them appear expired (or expire too late),
+// the local clock is probably off and a warning is logged.
+//----------------------------------------------------------------------
+
+// Clock skew settings
+var (
+	DefaultClockSkew    = 5 * time.Minute // tolerated clock skew
+	ClockMonitorSamples = 32              // number of observations kept
+	ClockMonitorMin     = 8               // min. observations for an estimate
+	ClockWarnInterval   = time.Hour       // min. time between warnings
+)
+
+// ExpiredSkew returns true if the timestamp is in the past by more than
+// the tolerated clock skew.
+func (t AbsoluteTime) ExpiredSkew(skew time.Duration) bool {
+	if t.IsNever() {
+		return false
+	}
+	return t.Add(skew).Expired()
+}
+
+// ClockMonitor estimates the offset of the local clock from expirations
+// in received data.
+type ClockMonitor struct {
+	sync.Mutex
+
+	skew    time.Duration   // tolerated clock skew (warning threshold)
+	samples []time.Duration // observed offsets (ring)
+	pos     int             // next write position in ring
+	num     int             // number of observations in ring
+	warned  time.Time       // time of last warning
+}
+
+// NewClockMonitor creates a monitor that warns if the estimated offset
+// of the local clock exceeds the tolerated clock skew.
+func NewClockMonitor(skew time.Duration) *ClockMonitor {
+	if skew <= 0 {
+		skew = DefaultClockSkew
+	}
+	return &ClockMonitor{
+		skew:    skew,
+		samples: make([]time.Duration, ClockMonitorSamples),
+	}
+}
+
+// Observe an expiration in data received from another peer. The data was
+// created (by the clock of the sender) with a lifetime of at most 'ttl':
+// an expiration in the past indicates a local clock running ahead, an
+// expiration beyond 'ttl' from now a local clock running behind.
+func (c *ClockMonitor) Observe(expire AbsoluteTime, ttl time.Duration) {
+	if expire.IsNever() {
+		return
+	}
+	now := time.Now()
+	exp := time.UnixMicro(int64(expire.Val))
+	var offset time.Duration
+	switch {
+	case exp.Before(now):
+		offset = now.Sub(exp)
+	case exp.After(now.Add(ttl)):
+		offset = -exp.Sub(now.Add(ttl))
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.samples[c.pos] = offset
+	c.pos = (c.pos + 1) % len(c.samples)
+	if c.num < len(c.samples) {
+		c.num++
+	}
+	// warn if the local clock is off
+	if off, ok := c.offset(); ok && (off > c.skew || off < -c.skew) {
+		if now.Sub(c.warned) >= ClockWarnInterval {
+			c.warned = now
+			logger.Printf(logger.WARN, "[clock] local clock seems to be off by %s (from %d observations) -- check time synchronization", off, c.num)
+		}
+	}
+}
+
+// Offset returns the estimated offset of the local clock (positive if
+// the local clock is ahead) and the number of observations. The estimate
+// is only valid ('ok') with enough observations.
+func (c *ClockMonitor) Offset() (offset time.Duration, num int, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	offset, ok = c.offset()
+	return offset, c.num, ok
+}
+
+// offset is the median of observed offsets (locked by caller)
+func (c *ClockMonitor) offset() (time.Duration, bool) {
+	if c.num < ClockMonitorMin {
+		return 0, false
+	}
+	list := make([]time.Duration, c.num)
+	copy(list, c.samples[:c.num])
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list[c.num/2], true
+}
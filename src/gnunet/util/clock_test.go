@@ -0,0 +1,71 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiredSkew(t *testing.T) {
+	now := AbsoluteTimeNow()
+	if now.Add(-time.Minute).ExpiredSkew(5 * time.Minute) {
+		t.Fatal("expiration within tolerance rejected")
+	}
+	if !now.Add(-time.Hour).ExpiredSkew(5 * time.Minute) {
+		t.Fatal("expiration beyond tolerance accepted")
+	}
+	if AbsoluteTimeNever().ExpiredSkew(time.Hour) {
+		t.Fatal("'never' expired")
+	}
+}
+
+func TestClockMonitor(t *testing.T) {
+	ttl := 12 * time.Hour
+	cases := []struct {
+		name   string
+		expire time.Duration // expiration relative to now
+		offset time.Duration // expected offset (approx.)
+	}{
+		{"in sync", 6 * time.Hour, 0},
+		{"ahead", -time.Hour, time.Hour},
+		{"behind", ttl + 2*time.Hour, -2 * time.Hour},
+	}
+	for _, c := range cases {
+		cm := NewClockMonitor(5 * time.Minute)
+		if _, _, ok := cm.Offset(); ok {
+			t.Fatalf("%s: estimate without observations", c.name)
+		}
+		for i := 0; i < ClockMonitorSamples; i++ {
+			exp := c.expire
+			// a few outliers don't change the estimate
+			if i%8 == 0 {
+				exp = -24 * time.Hour
+			}
+			cm.Observe(AbsoluteTimeNow().Add(exp), ttl)
+		}
+		off, num, ok := cm.Offset()
+		if !ok || num != ClockMonitorSamples {
+			t.Fatalf("%s: no estimate (%d observations)", c.name, num)
+		}
+		if d := off - c.offset; d < -time.Second || d > time.Second {
+			t.Fatalf("%s: offset %s, expected %s", c.name, off, c.offset)
+		}
+	}
+}
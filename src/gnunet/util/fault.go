@@ -0,0 +1,107 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------
+// Fault injection (chaos testing)
+//----------------------------------------------------------------------
+
+// FaultConfig defines the fraction (0..1) of operations that are affected
+// by an injected fault. A zero value disables the fault type.
+type FaultConfig struct {
+	Drop     float64 `json:"drop"`     // drop messages
+	Delay    float64 `json:"delay"`    // delay messages
+	MaxDelay int     `json:"maxDelay"` // max. delay (in milliseconds)
+	Reorder  float64 `json:"reorder"`  // reorder messages
+	Corrupt  float64 `json:"corrupt"`  // corrupt message data
+	Error    float64 `json:"error"`    // fail operations (storage)
+}
+
+// FaultInjector decides (randomly, based on configured rates) if a fault
+// is to be injected into an operation. All methods can be called on a
+// nil instance (no faults are injected).
+type FaultInjector struct {
+	cfg *FaultConfig
+}
+
+// NewFaultInjector creates a new fault injector for given configuration.
+// Returns nil if no configuration is specified.
+func NewFaultInjector(cfg *FaultConfig) *FaultInjector {
+	if cfg == nil {
+		return nil
+	}
+	return &FaultInjector{cfg: cfg}
+}
+
+// Drop returns true if a message should be dropped.
+func (fi *FaultInjector) Drop() bool {
+	return fi != nil && hit(fi.cfg.Drop)
+}
+
+// Delay returns the time a message should be delayed (zero for no delay).
+func (fi *FaultInjector) Delay() time.Duration {
+	if fi == nil || fi.cfg.MaxDelay <= 0 || !hit(fi.cfg.Delay) {
+		return 0
+	}
+	ms := int64(RndUInt32()) % int64(fi.cfg.MaxDelay)
+	return time.Duration(ms+1) * time.Millisecond
+}
+
+// Reorder returns true if a message should be held back and delivered
+// after its successor.
+func (fi *FaultInjector) Reorder() bool {
+	return fi != nil && hit(fi.cfg.Reorder)
+}
+
+// MaxDelay returns the configured max. delay of messages (zero if not
+// set).
+func (fi *FaultInjector) MaxDelay() time.Duration {
+	if fi == nil || fi.cfg.MaxDelay <= 0 {
+		return 0
+	}
+	return time.Duration(fi.cfg.MaxDelay) * time.Millisecond
+}
+
+// Corrupt flips a random bit in the buffer if the data should be
+// corrupted. Returns true if the buffer was modified.
+func (fi *FaultInjector) Corrupt(buf []byte) bool {
+	if fi == nil || len(buf) == 0 || !hit(fi.cfg.Corrupt) {
+		return false
+	}
+	pos := int(RndUInt32() % uint32(len(buf)*8))
+	buf[pos/8] ^= 1 << (pos % 8)
+	return true
+}
+
+// Error returns true if an operation should fail.
+func (fi *FaultInjector) Error() bool {
+	return fi != nil && hit(fi.cfg.Error)
+}
+
+// hit returns true with given probability.
+func hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	return float64(RndUInt32()) < rate*float64(1<<32)
+}
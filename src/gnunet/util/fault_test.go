@@ -0,0 +1,76 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"testing"
+)
+
+func TestFaultInjectorNil(t *testing.T) {
+	var fi *FaultInjector
+	if fi.Drop() || fi.Reorder() || fi.Error() || fi.Delay() != 0 {
+		t.Fatal("nil injector injected fault")
+	}
+	if fi.Corrupt([]byte{1, 2, 3}) {
+		t.Fatal("nil injector corrupted data")
+	}
+}
+
+func TestFaultInjectorRates(t *testing.T) {
+	fi := NewFaultInjector(&FaultConfig{
+		Drop:     1,
+		Delay:    1,
+		MaxDelay: 10,
+		Corrupt:  1,
+	})
+	if !fi.Drop() {
+		t.Fatal("drop not injected")
+	}
+	if fi.Reorder() || fi.Error() {
+		t.Fatal("disabled fault injected")
+	}
+	if d := fi.Delay(); d <= 0 || d.Milliseconds() > 10 {
+		t.Fatalf("invalid delay %s", d)
+	}
+	buf := make([]byte, 16)
+	if !fi.Corrupt(buf) {
+		t.Fatal("data not corrupted")
+	}
+	n := 0
+	for _, b := range buf {
+		for ; b != 0; b &= b - 1 {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("expected one flipped bit, got %d", n)
+	}
+
+	// check statistical rate
+	fi = NewFaultInjector(&FaultConfig{Error: 0.25})
+	hits := 0
+	for i := 0; i < 10000; i++ {
+		if fi.Error() {
+			hits++
+		}
+	}
+	if hits < 2000 || hits > 3000 {
+		t.Fatalf("error rate out of range: %d/10000", hits)
+	}
+}
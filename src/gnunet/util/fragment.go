@@ -0,0 +1,61 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+//----------------------------------------------------------------------
+// Fragmentation of large messages (packet-oriented endpoints)
+//----------------------------------------------------------------------
+
+// FragmentConfig defines how messages that exceed the size of a packet
+// are split into fragments and retransmitted if fragments get lost.
+type FragmentConfig struct {
+	MaxSize int `json:"maxSize"` // max. size of a packet (in bytes)
+	Timeout int `json:"timeout"` // initial retransmission timeout (in milliseconds)
+	Retries int `json:"retries"` // max. number of retransmissions
+}
+
+// Default fragmentation settings; a maximum packet size of 1232 bytes
+// fits into the minimum IPv6 MTU (1280 bytes) without IP fragmentation.
+const (
+	FragmentDefaultMaxSize = 1232
+	FragmentDefaultTimeout = 500
+	FragmentDefaultRetries = 5
+)
+
+// Effective returns the fragmentation settings with defaults applied for
+// unset values; a nil configuration yields the default settings.
+func (c *FragmentConfig) Effective() *FragmentConfig {
+	cfg := &FragmentConfig{
+		MaxSize: FragmentDefaultMaxSize,
+		Timeout: FragmentDefaultTimeout,
+		Retries: FragmentDefaultRetries,
+	}
+	if c != nil {
+		if c.MaxSize > 0 {
+			cfg.MaxSize = c.MaxSize
+		}
+		if c.Timeout > 0 {
+			cfg.Timeout = c.Timeout
+		}
+		if c.Retries > 0 {
+			cfg.Retries = c.Retries
+		}
+	}
+	return cfg
+}
@@ -18,13 +18,14 @@
 
 package util
 
+import "sync/atomic"
+
 var (
-	_id = 0
+	_id int64 = 0
 )
 
 // NextID generates the next unique identifier (unique in the running
 // process/application)
 func NextID() int {
-	_id++
-	return _id
+	return int(atomic.AddInt64(&_id, 1))
 }
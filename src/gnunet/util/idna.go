@@ -0,0 +1,113 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+//----------------------------------------------------------------------
+// Internationalized labels and names:
+//
+// GNS labels are UTF-8 strings; to make sure identical names always
+// result in the same query keys, labels are normalized before use (the
+// same way the GNUnet C implementation does): the label is converted to
+// lower case and then into Unicode Normalization Form C (NFC). Labels
+// must not contain dots, whitespace or control characters.
+//
+// NormalizeLabel is the canonical normalizer: all other packages (GNS
+// names, identity egos, DHT queries) use it, so a label accepted by one
+// component is never rejected by another.
+//
+// Size limits are checked on the UTF-8 encoding (bytes), not on the
+// number of characters: a label like "天下無敵" has 4 characters but
+// is 12 bytes long.
+//
+// Names resolved in DNS (GNS2DNS delegation) are converted to IDNA
+// A-labels ("punycode") before the DNS query is sent.
+//----------------------------------------------------------------------
+
+// Size limits for labels and names (in bytes)
+const (
+	MaxLabelSize = 63  // max. size of a single label
+	MaxNameSize  = 253 // max. size of a name (dotted labels)
+)
+
+// Error codes
+var (
+	ErrLabelEmpty    = errors.New("empty label")
+	ErrLabelEncoding = errors.New("label is not valid UTF-8")
+	ErrLabelSize     = errors.New("label too long")
+	ErrLabelChar     = errors.New("invalid character in label")
+	ErrNameSize      = errors.New("name too long")
+)
+
+// NormalizeLabel returns the canonical form of a label.
+func NormalizeLabel(label string) (string, error) {
+	if len(label) == 0 {
+		return "", ErrLabelEmpty
+	}
+	if !utf8.ValidString(label) {
+		return "", ErrLabelEncoding
+	}
+	label = norm.NFC.String(strings.ToLower(label))
+	if len(label) > MaxLabelSize {
+		return "", ErrLabelSize
+	}
+	for _, r := range label {
+		if r == '.' || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", ErrLabelChar
+		}
+	}
+	return label, nil
+}
+
+// NormalizeName returns the canonical form of a name by normalizing all
+// its labels ('.' as separator).
+func NormalizeName(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		var err error
+		if labels[i], err = NormalizeLabel(label); err != nil {
+			return "", err
+		}
+	}
+	name = strings.Join(labels, ".")
+	if len(name) > MaxNameSize {
+		return "", ErrNameSize
+	}
+	return name, nil
+}
+
+// NameToASCII converts a (normalized) name to its IDNA representation
+// for use in DNS: non-ASCII labels are converted to A-labels ("xn--...").
+func NameToASCII(name string) (string, error) {
+	return idna.Lookup.ToASCII(name)
+}
+
+// NameToUnicode converts IDNA A-labels in a DNS name back to Unicode.
+func NameToUnicode(name string) (string, error) {
+	return idna.Lookup.ToUnicode(name)
+}
@@ -0,0 +1,92 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeLabel(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		err     error
+	}{
+		{"www", "www", nil},
+		{"WwW", "www", nil},
+		{"天下無敵", "天下無敵", nil},
+		{"@", "@", nil},
+		// decomposed "é" (e + combining acute accent) --> NFC
+		{"cafe\u0301", "caf\u00e9", nil},
+		{"CAFÉ", "café", nil},
+		{"", "", ErrLabelEmpty},
+		{"\xff\xfe", "", ErrLabelEncoding},
+		{"a.b", "", ErrLabelChar},
+		{"a b", "", ErrLabelChar},
+		{"a\x00b", "", ErrLabelChar},
+		{strings.Repeat("a", 63), strings.Repeat("a", 63), nil},
+		{strings.Repeat("a", 64), "", ErrLabelSize},
+		// 21 characters, but 63 bytes
+		{strings.Repeat("無", 21), strings.Repeat("無", 21), nil},
+		// 22 characters, but 66 bytes
+		{strings.Repeat("無", 22), "", ErrLabelSize},
+	} {
+		out, err := NormalizeLabel(tc.in)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("'%s': unexpected error %v", tc.in, err)
+		}
+		if out != tc.out {
+			t.Fatalf("'%s': got '%s', expected '%s'", tc.in, out, tc.out)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	name, err := NormalizeName("Café.天下無敵.GNU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "café.天下無敵.gnu" {
+		t.Fatalf("got '%s'", name)
+	}
+	if _, err = NormalizeName("www..gnu"); !errors.Is(err, ErrLabelEmpty) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	long := strings.Repeat(strings.Repeat("a", 63)+".", 4) + "gnu"
+	if _, err = NormalizeName(long); !errors.Is(err, ErrNameSize) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestNameToASCII(t *testing.T) {
+	name, err := NameToASCII("bücher.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "xn--bcher-kva.example" {
+		t.Fatalf("got '%s'", name)
+	}
+	if name, err = NameToUnicode(name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "bücher.example" {
+		t.Fatalf("got '%s'", name)
+	}
+}
@@ -0,0 +1,103 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package keyspace implements the metric of the R5N DHT key space: keys
+// (SHA-512 hashes of peer identities and block queries) are compared by
+// their XOR distance; the routing table sorts peers into buckets by the
+// number of leading bits they share with the local peer. Tools like
+// analyzers and simulators use the package to place keys exactly like
+// the DHT service does.
+package keyspace
+
+import (
+	"crypto/rand"
+	"math/bits"
+	"sort"
+
+	"github.com/bfix/gospel/math"
+)
+
+// Bits is the size of keys (SHA-512) in bits; it is the number of
+// buckets in a routing table.
+const Bits = 512
+
+// Distance returns the XOR distance between two keys of equal length.
+func Distance(a, b []byte) *math.Int {
+	d := make([]byte, len(a))
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return math.NewIntFromBytes(d)
+}
+
+// BucketIndex returns the number of leading bits two keys have in common
+// (the number of leading zero bits in their distance). In a routing table
+// for key 'a' the peer 'b' is in the bucket with that index (smaller index
+// = more distant). Identical keys return the key size in bits.
+func BucketIndex(a, b []byte) int {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			return 8*i + bits.LeadingZeros8(x)
+		}
+	}
+	return 8 * len(a)
+}
+
+// Compare returns -1 if key 'a' is closer to 'key' than key 'b', +1 if
+// 'b' is closer and 0 if both are equally distant (a == b).
+func Compare(key, a, b []byte) int {
+	for i := range key {
+		da, db := key[i]^a[i], key[i]^b[i]
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Closer returns true if key 'a' is closer to 'key' than key 'b'.
+func Closer(key, a, b []byte) bool {
+	return Compare(key, a, b) < 0
+}
+
+// Sort orders a list of keys by their distance to 'key' (closest first).
+func Sort(key []byte, list [][]byte) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return Closer(key, list[i], list[j])
+	})
+}
+
+// Random returns a random key in the bucket with given index for the
+// reference key: it shares the first 'idx' bits with the reference key,
+// differs in the next bit and the remaining bits are random. The index
+// must be less than the key size in bits.
+func Random(ref []byte, idx int) []byte {
+	key := make([]byte, len(ref))
+	_, _ = rand.Read(key)
+	pos, bit := idx/8, idx%8
+	copy(key[:pos], ref[:pos])
+
+	// keep leading bits of the byte, flip the bit at index
+	mask := byte(0xff) << (8 - bit)
+	flip := byte(0x80) >> bit
+	key[pos] = (ref[pos] & mask) | (^ref[pos] & flip) | (key[pos] &^ (mask | flip))
+	return key
+}
@@ -0,0 +1,106 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package keyspace
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func rndKey() []byte {
+	key := make([]byte, Bits/8)
+	_, _ = rand.Read(key)
+	return key
+}
+
+func TestBucketIndex(t *testing.T) {
+	a := rndKey()
+	if idx := BucketIndex(a, a); idx != Bits {
+		t.Fatalf("identical keys: index %d", idx)
+	}
+	for i := 0; i < Bits; i++ {
+		b := append([]byte{}, a...)
+		b[i/8] ^= 0x80 >> (i % 8)
+		if idx := BucketIndex(a, b); idx != i {
+			t.Fatalf("bit %d: index %d", i, idx)
+		}
+		// the index is the number of leading zero bits in the distance
+		if idx := Bits - Distance(a, b).BitLen(); idx != i {
+			t.Fatalf("bit %d: distance index %d", i, idx)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		key, a, b := rndKey(), rndKey(), rndKey()
+		if c := Compare(key, a, b); c != Distance(key, a).Cmp(Distance(key, b)) {
+			t.Fatalf("compare %d doesn't match distance", c)
+		}
+		if Compare(key, a, a) != 0 || Closer(key, a, a) {
+			t.Fatal("equal keys not equally distant")
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	key := rndKey()
+	list := make([][]byte, 20)
+	for i := range list {
+		list[i] = rndKey()
+	}
+	Sort(key, list)
+	for i := 1; i < len(list); i++ {
+		if Closer(key, list[i], list[i-1]) {
+			t.Fatalf("list not sorted at %d", i)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	ref := rndKey()
+	for idx := 0; idx < Bits; idx++ {
+		key := Random(ref, idx)
+		if n := BucketIndex(ref, key); n != idx {
+			t.Fatalf("random key for bucket %d is in bucket %d", idx, n)
+		}
+	}
+}
+
+func BenchmarkDistance(b *testing.B) {
+	k1, k2 := rndKey(), rndKey()
+	for i := 0; i < b.N; i++ {
+		Distance(k1, k2)
+	}
+}
+
+func BenchmarkBucketIndex(b *testing.B) {
+	k1 := rndKey()
+	k2 := Random(k1, 100)
+	for i := 0; i < b.N; i++ {
+		BucketIndex(k1, k2)
+	}
+}
+
+func BenchmarkCompare(b *testing.B) {
+	key, k1, k2 := rndKey(), rndKey(), rndKey()
+	for i := 0; i < b.N; i++ {
+		Compare(key, k1, k2)
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"strings"
 
 	"github.com/bfix/gospel/data"
-	"github.com/bfix/gospel/math"
 )
 
 //----------------------------------------------------------------------
@@ -93,16 +92,6 @@ func CastToString(v any) string {
 // additional helpers
 //----------------------------------------------------------------------
 
-// Distance returns the XOR distance between to byte arrays
-func Distance(a, b []byte) *math.Int {
-	size := len(a)
-	d := make([]byte, size)
-	for i := range d {
-		d[i] = a[i] ^ b[i]
-	}
-	return math.NewIntFromBytes(d)
-}
-
 // StripPathRight returns a dot-separated path without
 // its last (right-most) element.
 func StripPathRight(s string) string {
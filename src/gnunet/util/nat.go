@@ -0,0 +1,33 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+//----------------------------------------------------------------------
+// NAT traversal settings
+//----------------------------------------------------------------------
+
+// NATConfig holds the settings for NAT traversal of a node: the external
+// address is discovered with STUN (or from the gateway); ports of the
+// endpoints are mapped on the gateway with UPnP or NAT-PMP if enabled.
+type NATConfig struct {
+	STUN     []string `json:"stun,omitempty"`     // STUN servers ("host:port")
+	UPnP     bool     `json:"upnp,omitempty"`     // map ports with UPnP
+	NATPMP   string   `json:"natpmp,omitempty"`   // address of NAT-PMP gateway (empty: not used)
+	Interval int      `json:"interval,omitempty"` // seconds between checks (default: 300)
+}
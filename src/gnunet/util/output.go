@@ -0,0 +1,82 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Output formats for command-line tools
+const (
+	OutputText = "text" // human-readable text (default)
+	OutputJSON = "json" // one JSON object per line
+)
+
+// ErrOutputFormat is returned for unknown output formats
+var ErrOutputFormat = errors.New("unknown output format")
+
+// Output writes the results of a command-line tool either as plain text
+// or as JSON objects (one per line). The JSON objects are the stable,
+// machine-readable interface of a tool; log messages are never part of
+// the output.
+type Output struct {
+	format string    // output format
+	w      io.Writer // output destination
+}
+
+// NewOutput creates a new output handler for given format ("text" or
+// "json"; an empty format defaults to "text").
+func NewOutput(format string, w io.Writer) (*Output, error) {
+	switch format {
+	case "":
+		format = OutputText
+	case OutputText, OutputJSON:
+	default:
+		return nil, fmt.Errorf("%w '%s'", ErrOutputFormat, format)
+	}
+	return &Output{
+		format: format,
+		w:      w,
+	}, nil
+}
+
+// IsJSON returns true if the output is machine-readable.
+func (o *Output) IsJSON() bool {
+	return o.format == OutputJSON
+}
+
+// Emit a result: in JSON mode the object is written, in text mode the
+// formatted text (if not empty).
+func (o *Output) Emit(obj any, format string, args ...any) (err error) {
+	if o.format == OutputJSON {
+		var buf []byte
+		if buf, err = json.Marshal(obj); err != nil {
+			return
+		}
+		_, err = fmt.Fprintln(o.w, string(buf))
+		return
+	}
+	if len(format) > 0 {
+		_, err = fmt.Fprintf(o.w, format, args...)
+	}
+	return
+}
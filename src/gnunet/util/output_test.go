@@ -0,0 +1,63 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	type result struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	res := &result{Name: "test", Count: 3}
+
+	// text output
+	buf := new(bytes.Buffer)
+	out, err := NewOutput("", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = out.Emit(res, "%s: %d\n", res.Name, res.Count); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "test: 3\n" {
+		t.Fatalf("text output: '%s'", buf.String())
+	}
+
+	// JSON output
+	buf.Reset()
+	if out, err = NewOutput(OutputJSON, buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = out.Emit(res, "%s: %d\n", res.Name, res.Count); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `{"name":"test","count":3}`+"\n" {
+		t.Fatalf("JSON output: '%s'", buf.String())
+	}
+
+	// unknown format
+	if _, err = NewOutput("xml", buf); !errors.Is(err, ErrOutputFormat) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
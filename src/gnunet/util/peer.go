@@ -96,12 +96,17 @@ func (p *PeerID) String() string {
 	return EncodeBinaryToString(p.Data)
 }
 
-// SHort returns a shortened peer id for display
+// Short returns a shortened peer id for display. Peers with an alias
+// are shown as "alias(XXXX…)".
 func (p *PeerID) Short() string {
 	if p == nil {
 		return "local"
 	}
-	return p.String()[:8]
+	id := p.String()
+	if alias, ok := PeerAlias(p); ok {
+		return alias + "(" + id[:4] + "…)"
+	}
+	return id[:8]
 }
 
 // Bytes returns the binary representation of a peer identifier.
@@ -137,6 +142,11 @@ func NewPeerSignature(data []byte) *PeerSignature {
 	return s
 }
 
+// Init is called after unmarshalling a signature (nothing to set up).
+func (s *PeerSignature) Init() error {
+	return nil
+}
+
 // Size returns the length of the binary data
 func (s *PeerSignature) Size() uint {
 	return PeerSignatureSize
@@ -36,3 +36,27 @@ func TestPeerIDString(t *testing.T) {
 	id := NewPeerID(pub.Bytes())
 	t.Log(id)
 }
+
+func TestPeerAlias(t *testing.T) {
+	id := NewPeerID(NewRndArray(32))
+	short := id.String()[:8]
+	if id.Short() != short {
+		t.Fatalf("unexpected short ID %s", id.Short())
+	}
+	// learned alias is replaced by configured alias
+	if !LearnPeerAlias(id, "nick") || id.Short() != "nick("+short[:4]+"…)" {
+		t.Fatalf("learned alias not used: %s", id.Short())
+	}
+	SetPeerAlias(id, "alice")
+	if LearnPeerAlias(id, "bob") {
+		t.Fatal("learned alias replaced configured alias")
+	}
+	if alias, ok := PeerAlias(id); !ok || alias != "alice" || PeerAliases()[id.String()] != "alice" {
+		t.Fatalf("unexpected alias %s", alias)
+	}
+	// remove alias
+	SetPeerAlias(id, "")
+	if _, ok := PeerAlias(id); ok || id.Short() != short {
+		t.Fatal("alias not removed")
+	}
+}
@@ -0,0 +1,151 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"encoding/hex"
+	"sync"
+)
+
+//----------------------------------------------------------------------
+// Replay protection for signed control messages: a bounded cache of
+// recently seen objects (by kind and identifier) with their timestamps.
+// Captured messages that are replayed later are recognized as duplicates
+// (same signature), as outdated (older than an already seen timestamp)
+// or as expired; such messages are dropped and counted per kind.
+//----------------------------------------------------------------------
+
+// DefaultReplayCacheSize is the default number of remembered objects.
+var DefaultReplayCacheSize = 4096
+
+// ReplayCache remembers recently seen objects. If the cache is full, the
+// oldest entry is evicted.
+type ReplayCache struct {
+	sync.Mutex
+
+	seen  map[string]AbsoluteTime // timestamps of seen objects
+	order []string                // insertion order (ring)
+	pos   int                     // next write position in ring
+	drops map[string]uint64       // dropped replays per kind
+}
+
+// NewReplayCache creates a cache for (at most) 'size' objects.
+func NewReplayCache(size int) *ReplayCache {
+	if size <= 0 {
+		size = DefaultReplayCacheSize
+	}
+	return &ReplayCache{
+		seen:  make(map[string]AbsoluteTime),
+		order: make([]string, size),
+		drops: make(map[string]uint64),
+	}
+}
+
+// Seen returns true (and counts a drop) if the object was seen before
+// (and its remembered entry has not yet expired) or if it is expired
+// itself; otherwise the object is remembered until 'expire'. Use for
+// objects with a unique identifier (like a signature).
+func (rc *ReplayCache) Seen(kind string, id []byte, expire AbsoluteTime) bool {
+	rc.Lock()
+	defer rc.Unlock()
+	key := replayKey(kind, id)
+	last, ok := rc.seen[key]
+	if (ok && !last.Expired()) || expire.Expired() {
+		rc.drops[kind]++
+		return true
+	}
+	if ok {
+		rc.seen[key] = expire
+	} else {
+		rc.add(key, expire)
+	}
+	return false
+}
+
+// Older returns true (and counts a drop) if an object with the same
+// identifier and a later timestamp was seen before or if the object is
+// expired; otherwise the timestamp is remembered. Use for objects that
+// are superseded by newer versions (like the HELLO of a peer).
+func (rc *ReplayCache) Older(kind string, id []byte, ts AbsoluteTime) bool {
+	rc.Lock()
+	defer rc.Unlock()
+	key := replayKey(kind, id)
+	last, ok := rc.seen[key]
+	if (ok && last.Compare(ts) > 0) || ts.Expired() {
+		rc.drops[kind]++
+		return true
+	}
+	if ok {
+		rc.seen[key] = ts
+	} else {
+		rc.add(key, ts)
+	}
+	return false
+}
+
+// Contains returns true (and counts a drop) if the object was seen before
+// and is not expired.
+func (rc *ReplayCache) Contains(kind string, id []byte) bool {
+	rc.Lock()
+	defer rc.Unlock()
+	expire, ok := rc.seen[replayKey(kind, id)]
+	if !ok || expire.Expired() {
+		return false
+	}
+	rc.drops[kind]++
+	return true
+}
+
+// Add remembers an object (e.g. after successful verification).
+func (rc *ReplayCache) Add(kind string, id []byte, expire AbsoluteTime) {
+	rc.Lock()
+	defer rc.Unlock()
+	key := replayKey(kind, id)
+	if _, ok := rc.seen[key]; ok {
+		rc.seen[key] = expire
+		return
+	}
+	rc.add(key, expire)
+}
+
+// Drops returns the number of dropped replays per kind.
+func (rc *ReplayCache) Drops() map[string]uint64 {
+	rc.Lock()
+	defer rc.Unlock()
+	out := make(map[string]uint64, len(rc.drops))
+	for k, v := range rc.drops {
+		out[k] = v
+	}
+	return out
+}
+
+// add a new entry (evicting the oldest entry if the cache is full)
+func (rc *ReplayCache) add(key string, ts AbsoluteTime) {
+	if old := rc.order[rc.pos]; len(old) > 0 {
+		delete(rc.seen, old)
+	}
+	rc.order[rc.pos] = key
+	rc.pos = (rc.pos + 1) % len(rc.order)
+	rc.seen[key] = ts
+}
+
+// replayKey returns the cache key for an object.
+func replayKey(kind string, id []byte) string {
+	return kind + ":" + hex.EncodeToString(id)
+}
@@ -0,0 +1,74 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayCache(t *testing.T) {
+	rc := NewReplayCache(2)
+	future := AbsoluteTimeNow().Add(time.Hour)
+	past := AbsoluteTimeNow().Add(-time.Hour)
+
+	// unique objects
+	if rc.Seen("sig", []byte{1}, future) {
+		t.Fatal("new object reported as replay")
+	}
+	if !rc.Seen("sig", []byte{1}, future) {
+		t.Fatal("replay not detected")
+	}
+	if !rc.Seen("sig", []byte{2}, past) {
+		t.Fatal("expired object not dropped")
+	}
+	// versioned objects
+	if rc.Older("hello", []byte{1}, future) {
+		t.Fatal("new HELLO reported as replay")
+	}
+	if rc.Older("hello", []byte{1}, future) {
+		t.Fatal("repeated HELLO reported as replay")
+	}
+	if !rc.Older("hello", []byte{1}, future.Add(-time.Minute)) {
+		t.Fatal("outdated HELLO not dropped")
+	}
+	if rc.Older("hello", []byte{1}, future.Add(time.Minute)) {
+		t.Fatal("newer HELLO reported as replay")
+	}
+	// bounded size: oldest entry is evicted
+	rc.Add("rev", []byte{1}, future)
+	if rc.Seen("sig", []byte{1}, future) {
+		t.Fatal("evicted object reported as replay")
+	}
+	if !rc.Contains("rev", []byte{1}) {
+		t.Fatal("added object not found")
+	}
+	// expired entries are not replays
+	rc.Add("win", []byte{1}, past)
+	if rc.Seen("win", []byte{1}, future) {
+		t.Fatal("object with expired entry reported as replay")
+	}
+	if !rc.Seen("win", []byte{1}, future) {
+		t.Fatal("replay after renewed entry not detected")
+	}
+	drops := rc.Drops()
+	if drops["sig"] != 2 || drops["hello"] != 1 || drops["rev"] != 1 || drops["win"] != 1 {
+		t.Fatalf("unexpected drop counters: %v", drops)
+	}
+}
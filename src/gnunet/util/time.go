@@ -82,7 +82,7 @@ func (t AbsoluteTime) String() string {
 // Add a duration to an absolute time yielding a new absolute time.
 func (t AbsoluteTime) Add(d time.Duration) AbsoluteTime {
 	return AbsoluteTime{
-		Val: t.Val + uint64(d.Milliseconds()),
+		Val: t.Val + uint64(d.Microseconds()),
 	}
 }
 
@@ -42,3 +42,18 @@ func TestTimeCompare(t *testing.T) {
 		t.Fatal("(4)")
 	}
 }
+
+func TestTimeAdd(t *testing.T) {
+	// absolute times are in microseconds
+	t1 := AbsoluteTime{Val: 1000000}
+	if t2 := t1.Add(time.Second); t2.Val != 2000000 {
+		t.Fatalf("Add(1s): got %d", t2.Val)
+	}
+	if t2 := t1.Add(time.Millisecond); t2.Val != 1001000 {
+		t.Fatalf("Add(1ms): got %d", t2.Val)
+	}
+	// relative time must match the duration added
+	if d, _ := t1.Diff(t1.Add(time.Hour)); d.Val != uint64(time.Hour.Microseconds()) {
+		t.Fatalf("Diff: got %d", d.Val)
+	}
+}
@@ -0,0 +1,132 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// Error codes
+var (
+	ErrTLSNoCA = errors.New("no CA certificates found")
+)
+
+// TLSConfig holds the certificate settings of a TLS-secured endpoint.
+// Without certificate and key files a self-signed certificate is created
+// when the endpoint starts. Peers are authenticated by their signed HELLOs
+// and not by certificates, so certificates of remote endpoints are only
+// verified if requested.
+type TLSConfig struct {
+	Cert   string `json:"cert"`         // certificate file (PEM)
+	Key    string `json:"key"`          // private key file (PEM)
+	Verify bool   `json:"verify"`       // verify certificates of remote endpoints
+	CA     string `json:"ca,omitempty"` // CA certificates for verification (PEM; default: system)
+}
+
+// ServerConfig returns the TLS configuration for a listener; a
+// self-signed certificate is created for the given hosts if no
+// certificate is configured. A nil config creates a self-signed
+// certificate as well.
+func (c *TLSConfig) ServerConfig(hosts ...string) (cfg *tls.Config, err error) {
+	var cert tls.Certificate
+	if c != nil && len(c.Cert) > 0 {
+		cert, err = tls.LoadX509KeyPair(c.Cert, c.Key)
+	} else {
+		cert, err = SelfSignedCert(hosts...)
+	}
+	if err != nil {
+		return
+	}
+	cfg = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return
+}
+
+// ClientConfig returns the TLS configuration for outgoing connections.
+func (c *TLSConfig) ClientConfig() (cfg *tls.Config, err error) {
+	cfg = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if c == nil || !c.Verify {
+		cfg.InsecureSkipVerify = true
+		return
+	}
+	if len(c.CA) > 0 {
+		var pem []byte
+		if pem, err = os.ReadFile(c.CA); err != nil {
+			return
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			err = ErrTLSNoCA
+		}
+	}
+	return
+}
+
+// SelfSignedCert creates a self-signed certificate (valid for a year)
+// for a list of host names and IP addresses.
+func SelfSignedCert(hosts ...string) (cert tls.Certificate, err error) {
+	var key *ecdsa.PrivateKey
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+	var serial *big.Int
+	if serial, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"gnunet-go"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if len(h) > 0 {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key); err != nil {
+		return
+	}
+	cert = tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+	return
+}
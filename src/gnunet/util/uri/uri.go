@@ -0,0 +1,394 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package uri parses and generates the 'gnunet://' URIs used by the
+// project:
+//
+//	gnunet://hello/<peer>/<signature>/<expire>?<proto>=<addr>&...
+//	gnunet://gns/<zTLD>[/<label>]
+//	gnunet://fs/chk/<key>.<query>.<size>
+//	gnunet://fs/sks/<namespace>/<identifier>
+//	gnunet://fs/loc/<key>.<query>.<size>.<peer>.<signature>.<expire>
+//
+// Binary elements are encoded in GNUnet base32; expiration times are
+// seconds since the epoch.
+package uri
+
+import (
+	"errors"
+	"fmt"
+	"gnunet/crypto"
+	"gnunet/util"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrURIScheme  = errors.New("not a GNUnet URI")
+	ErrURIUnknown = errors.New("unknown GNUnet URI type")
+	ErrURIFormat  = errors.New("malformed GNUnet URI")
+)
+
+// URI prefixes
+const (
+	Scheme      = "gnunet://"
+	PrefixHello = Scheme + "hello/"
+	PrefixGNS   = Scheme + "gns/"
+	PrefixCHK   = Scheme + "fs/chk/"
+	PrefixSKS   = Scheme + "fs/sks/"
+	PrefixLOC   = Scheme + "fs/loc/"
+)
+
+// URI is a parsed GNUnet URI.
+type URI interface {
+	// String returns the (canonical) URI string.
+	String() string
+}
+
+// Parse any GNUnet URI. The result is one of *HelloURI, *GNSURI, *CHKURI,
+// *SKSURI or *LOCURI.
+func Parse(s string) (URI, error) {
+	switch {
+	case !strings.HasPrefix(s, Scheme):
+		return nil, ErrURIScheme
+	case strings.HasPrefix(s, PrefixHello):
+		return ParseHello(s)
+	case strings.HasPrefix(s, PrefixGNS):
+		return ParseGNS(s)
+	case strings.HasPrefix(s, PrefixCHK):
+		return ParseCHK(s)
+	case strings.HasPrefix(s, PrefixSKS):
+		return ParseSKS(s)
+	case strings.HasPrefix(s, PrefixLOC):
+		return ParseLOC(s)
+	}
+	return nil, ErrURIUnknown
+}
+
+//----------------------------------------------------------------------
+// HELLO
+//----------------------------------------------------------------------
+
+// HelloURI holds the elements of a HELLO URI.
+type HelloURI struct {
+	Peer      *util.PeerID        // peer identifier
+	Signature *util.PeerSignature // signature over HELLO data
+	Expire    util.AbsoluteTime   // expiration (second precision)
+	Addrs     []*util.Address     // peer addresses
+}
+
+// ParseHello parses a HELLO URI.
+func ParseHello(s string) (u *HelloURI, err error) {
+	var p []string
+	if p, err = split(s, PrefixHello, "/", 3); err != nil {
+		return
+	}
+	q := strings.SplitN(p[2], "?", 2)
+	if len(q) != 2 {
+		return nil, formatError("missing address list in '%s'", s)
+	}
+	u = new(HelloURI)
+	var buf []byte
+	if buf, err = decode(p[0], util.PeerPublicKeySize); err != nil {
+		return
+	}
+	u.Peer = util.NewPeerID(buf)
+	if buf, err = decode(p[1], util.PeerSignatureSize); err != nil {
+		return
+	}
+	u.Signature = util.NewPeerSignature(buf)
+	if u.Expire, err = parseExpire(q[0]); err != nil {
+		return
+	}
+	u.Addrs = make([]*util.Address, 0)
+	if len(q[1]) == 0 {
+		return
+	}
+	for _, a := range strings.Split(q[1], "&") {
+		ap := strings.SplitN(a, "=", 2)
+		if len(ap) != 2 || len(ap[0]) == 0 {
+			return nil, formatError("invalid address '%s'", a)
+		}
+		var as string
+		if as, err = url.QueryUnescape(ap[1]); err != nil {
+			return
+		}
+		var addr *util.Address
+		if addr, err = util.ParseAddress(ap[0] + "://" + as); err != nil {
+			return
+		}
+		u.Addrs = append(u.Addrs, addr)
+	}
+	return
+}
+
+// String returns the HELLO URI.
+func (u *HelloURI) String() string {
+	s := fmt.Sprintf("%s%s/%s/%d?",
+		PrefixHello,
+		u.Peer.String(),
+		util.EncodeBinaryToString(u.Signature.Data),
+		u.Expire.Epoch(),
+	)
+	for i, a := range u.Addrs {
+		if i > 0 {
+			s += "&"
+		}
+		p := strings.SplitN(a.URI(), "://", 2)
+		s += p[0] + "=" + url.QueryEscape(p[1])
+	}
+	return s
+}
+
+//----------------------------------------------------------------------
+// GNS
+//----------------------------------------------------------------------
+
+// GNSURI references a zone (or a label in a zone).
+type GNSURI struct {
+	Zone  *crypto.ZoneKey // zone key
+	Label string          // label in zone (can be empty)
+}
+
+// ParseGNS parses a GNS URI.
+func ParseGNS(s string) (u *GNSURI, err error) {
+	if !strings.HasPrefix(s, PrefixGNS) {
+		return nil, formatError("expected '%s' in '%s'", PrefixGNS, s)
+	}
+	p := strings.Split(s[len(PrefixGNS):], "/")
+	if len(p) > 2 {
+		return nil, formatError("invalid GNS URI '%s'", s)
+	}
+	u = new(GNSURI)
+	// zTLD is the encoding of zone type (4 bytes) and key data
+	var buf []byte
+	if buf, err = util.DecodeStringToBinary(p[0], (len(p[0])*5)/8); err != nil {
+		return
+	}
+	if u.Zone, err = crypto.NewZoneKey(buf); err != nil {
+		return
+	}
+	if !strings.EqualFold(u.Zone.ID(), p[0]) {
+		return nil, formatError("invalid zone '%s'", p[0])
+	}
+	if len(p) == 2 {
+		if u.Label, err = url.PathUnescape(p[1]); err != nil {
+			return
+		}
+		if len(u.Label) == 0 || strings.Contains(u.Label, ".") {
+			return nil, formatError("invalid label '%s'", p[1])
+		}
+	}
+	return
+}
+
+// String returns the GNS URI.
+func (u *GNSURI) String() string {
+	s := PrefixGNS + strings.ToLower(u.Zone.ID())
+	if len(u.Label) > 0 {
+		s += "/" + url.PathEscape(u.Label)
+	}
+	return s
+}
+
+//----------------------------------------------------------------------
+// File-sharing: CHK, SKS and LOC
+//----------------------------------------------------------------------
+
+// CHKURI references a file by content hash key.
+type CHKURI struct {
+	Key   *crypto.HashCode // hash of the plaintext (encryption key)
+	Query *crypto.HashCode // hash of the encrypted root block
+	Size  uint64           // file size
+}
+
+// ParseCHK parses a CHK URI.
+func ParseCHK(s string) (u *CHKURI, err error) {
+	var p []string
+	if p, err = split(s, PrefixCHK, ".", 3); err != nil {
+		return
+	}
+	return parseCHK(p)
+}
+
+// parse CHK elements (key, query, size)
+func parseCHK(p []string) (u *CHKURI, err error) {
+	u = new(CHKURI)
+	if u.Key, err = decodeHash(p[0]); err != nil {
+		return
+	}
+	if u.Query, err = decodeHash(p[1]); err != nil {
+		return
+	}
+	if u.Size, err = parseUint(p[2]); err != nil {
+		return
+	}
+	return
+}
+
+// elements of a CHK
+func (u *CHKURI) elements() string {
+	return fmt.Sprintf("%s.%s.%d",
+		util.EncodeBinaryToString(u.Key.Data),
+		util.EncodeBinaryToString(u.Query.Data),
+		u.Size)
+}
+
+// String returns the CHK URI.
+func (u *CHKURI) String() string {
+	return PrefixCHK + u.elements()
+}
+
+// SKSURI references content in a namespace.
+type SKSURI struct {
+	Namespace  []byte // public key of namespace (32 bytes)
+	Identifier string // identifier in namespace
+}
+
+// ParseSKS parses a SKS URI.
+func ParseSKS(s string) (u *SKSURI, err error) {
+	var p []string
+	if p, err = split(s, PrefixSKS, "/", 2); err != nil {
+		return
+	}
+	u = new(SKSURI)
+	if u.Namespace, err = decode(p[0], 32); err != nil {
+		return
+	}
+	if u.Identifier, err = url.PathUnescape(p[1]); err != nil {
+		return
+	}
+	if len(u.Identifier) == 0 {
+		return nil, formatError("empty SKS identifier")
+	}
+	return
+}
+
+// String returns the SKS URI.
+func (u *SKSURI) String() string {
+	return PrefixSKS + util.EncodeBinaryToString(u.Namespace) + "/" + url.PathEscape(u.Identifier)
+}
+
+// LOCURI references a file (CHK) available from a peer.
+type LOCURI struct {
+	CHKURI
+	Peer      *util.PeerID        // peer offering the file
+	Signature *util.PeerSignature // signature of peer
+	Expire    util.AbsoluteTime   // expiration (second precision)
+}
+
+// ParseLOC parses a LOC URI.
+func ParseLOC(s string) (u *LOCURI, err error) {
+	var p []string
+	if p, err = split(s, PrefixLOC, ".", 6); err != nil {
+		return
+	}
+	u = new(LOCURI)
+	var chk *CHKURI
+	if chk, err = parseCHK(p[:3]); err != nil {
+		return
+	}
+	u.CHKURI = *chk
+	var buf []byte
+	if buf, err = decode(p[3], util.PeerPublicKeySize); err != nil {
+		return
+	}
+	u.Peer = util.NewPeerID(buf)
+	if buf, err = decode(p[4], util.PeerSignatureSize); err != nil {
+		return
+	}
+	u.Signature = util.NewPeerSignature(buf)
+	u.Expire, err = parseExpire(p[5])
+	return
+}
+
+// String returns the LOC URI.
+func (u *LOCURI) String() string {
+	return fmt.Sprintf("%s%s.%s.%s.%d",
+		PrefixLOC,
+		u.elements(),
+		u.Peer.String(),
+		util.EncodeBinaryToString(u.Signature.Data),
+		u.Expire.Epoch())
+}
+
+//----------------------------------------------------------------------
+// helpers
+//----------------------------------------------------------------------
+
+// wrap a format error
+func formatError(f string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrURIFormat, fmt.Sprintf(f, args...))
+}
+
+// split URI (without prefix) into a given number of non-empty parts
+func split(s, prefix, sep string, num int) ([]string, error) {
+	if !strings.HasPrefix(s, prefix) {
+		return nil, formatError("expected '%s' in '%s'", prefix, s)
+	}
+	p := strings.Split(s[len(prefix):], sep)
+	if len(p) != num {
+		return nil, formatError("expected %d elements in '%s'", num, s)
+	}
+	for _, e := range p {
+		if len(e) == 0 {
+			return nil, formatError("empty element in '%s'", s)
+		}
+	}
+	return p, nil
+}
+
+// decode base32 string of exact length for given number of bytes
+func decode(s string, num int) ([]byte, error) {
+	if len(s) != (num*8+4)/5 {
+		return nil, formatError("invalid length of '%s'", s)
+	}
+	return util.DecodeStringToBinary(s, num)
+}
+
+// decode a hash value
+func decodeHash(s string) (*crypto.HashCode, error) {
+	buf, err := decode(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewHashCode(buf), nil
+}
+
+// parse unsigned decimal (no sign, no leading zeros)
+func parseUint(s string) (uint64, error) {
+	if len(s) > 1 && s[0] == '0' {
+		return 0, formatError("invalid number '%s'", s)
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, formatError("invalid number '%s'", s)
+	}
+	return v, nil
+}
+
+// parse expiration (seconds since epoch)
+func parseExpire(s string) (t util.AbsoluteTime, err error) {
+	var v uint64
+	if v, err = parseUint(s); err == nil {
+		t = util.NewAbsoluteTimeEpoch(v)
+	}
+	return
+}
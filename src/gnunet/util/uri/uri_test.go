@@ -0,0 +1,129 @@
+// This file is part of gnunet-go, a GNUnet-implementation in Golang.
+// Copyright (C) 2019-2022 Bernd Fix  >Y<
+//
+// gnunet-go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// gnunet-go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package uri
+
+import (
+	"errors"
+	"gnunet/crypto"
+	"gnunet/enums"
+	"gnunet/util"
+	"strings"
+	"testing"
+)
+
+var (
+	// HELLO URIs of real peers
+	helloURIs = []string{
+		"gnunet://hello" +
+			"/RBVQWST48N9YDVHYM7KYR1YDBZN7X4KG1SJJZHGHGX5HFHX5P010" +
+			"/Y4YEXZBBKS1HFGGHZW5QWQTX20QJ5BBEQZB8PNA85VCASRR60P741X28E8HS6P20HQED43RAQFADJTVREFQ37W1YQFN29TCC2AT4R2R" +
+			"/1654964519" +
+			"?ip+udp=127.0.0.1%3A10000" +
+			"&ip+udp=%5B%3A%3A1%5D%3A10000",
+		"gnunet://hello" +
+			"/6SR91X40JHTTSKTEY04KC920MDJBVDDNJ9Y2KPVY1RJK40KC1SVG" +
+			"/7H3BX1XDYXKXDR20X1GPCYY1CT68GGH1CC9FSDBW4MZ4H5GFB3K7PMJZTEWK3NVVJ0FXBBG6QFBWFM233F5YTQZGZ8JV5MEPNBWP800" +
+			"/1654953178?",
+	}
+	hash1 = util.EncodeBinaryToString(util.NewRndArray(64))
+	hash2 = util.EncodeBinaryToString(util.NewRndArray(64))
+	peer  = util.EncodeBinaryToString(util.NewRndArray(32))
+	sig   = util.EncodeBinaryToString(util.NewRndArray(64))
+)
+
+// generate a zTLD for a random zone
+func newZone(t *testing.T) string {
+	t.Helper()
+	zp, err := crypto.NewZonePrivate(enums.GNS_TYPE_PKEY, util.NewRndArray(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ToLower(zp.Public().ID())
+}
+
+func TestRoundTrip(t *testing.T) {
+	zone := newZone(t)
+	list := append(helloURIs,
+		"gnunet://gns/"+zone,
+		"gnunet://gns/"+zone+"/www",
+		"gnunet://gns/"+zone+"/caf%C3%A9",
+		"gnunet://fs/chk/"+hash1+"."+hash2+".1048576",
+		"gnunet://fs/sks/"+peer+"/my%20file",
+		"gnunet://fs/loc/"+hash1+"."+hash2+".42."+peer+"."+sig+".1700000000",
+	)
+	for _, s := range list {
+		u, err := Parse(s)
+		if err != nil {
+			t.Fatalf("%s: %s", s, err.Error())
+		}
+		if u.String() != s {
+			t.Fatalf("round-trip failed:\n%s\n%s", s, u.String())
+		}
+	}
+}
+
+func TestParseTypes(t *testing.T) {
+	u, err := Parse("gnunet://fs/loc/" + hash1 + "." + hash2 + ".42." + peer + "." + sig + ".1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, ok := u.(*LOCURI)
+	if !ok {
+		t.Fatalf("wrong URI type %T", u)
+	}
+	if loc.Size != 42 || loc.Expire.Epoch() != 1700000000 || loc.Peer.String() != peer {
+		t.Fatal("wrong LOC elements")
+	}
+	hu, err := ParseHello(helloURIs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hu.Addrs) != 2 || hu.Addrs[1].URI() != "ip+udp://[::1]:10000" {
+		t.Fatalf("wrong HELLO addresses: %v", hu.Addrs)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	zone := newZone(t)
+	list := []string{
+		"http://example.org/",
+		"gnunet://fs/ksk/word",
+		"gnunet://hello/" + peer + "/" + sig + "/1654953178",
+		"gnunet://hello/" + peer[1:] + "/" + sig + "/1654953178?",
+		"gnunet://hello/" + peer + "/" + sig + "/-1?",
+		"gnunet://hello/" + peer + "/" + sig + "/1654953178?ip+udp",
+		"gnunet://gns/" + zone[1:],
+		"gnunet://gns/" + zone + "/a.b",
+		"gnunet://gns/" + zone + "/www/x",
+		"gnunet://fs/chk/" + hash1 + "." + hash2,
+		"gnunet://fs/chk/" + hash1 + "." + hash2 + ".012",
+		"gnunet://fs/chk/" + hash1 + "." + hash2 + ".1.2",
+		"gnunet://fs/chk/" + hash1 + "!." + hash2 + ".1",
+		"gnunet://fs/sks/" + peer + "/",
+		"gnunet://fs/loc/" + hash1 + "." + hash2 + ".42." + peer + "." + sig,
+	}
+	for _, s := range list {
+		if _, err := Parse(s); err == nil {
+			t.Fatalf("invalid URI accepted: %s", s)
+		}
+	}
+	if _, err := Parse("gnunet://fs/ksk/word"); !errors.Is(err, ErrURIUnknown) {
+		t.Fatalf("expected unknown URI error, got %v", err)
+	}
+}